@@ -0,0 +1,67 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/hashicorp/terraform/tfdiags"
+)
+
+// Analyzer is the interface implemented by plan analyzers.
+type Analyzer interface {
+	// Name returns the name of the analyzer, for use in messages and as the
+	// Analyzer of its annotations.
+	Name() string
+
+	// Analyze analyzes the given plan, in the JSON form of "terraform plan
+	// -json", returning annotations of its changes. An error means that
+	// the plan couldn't be analyzed.
+	Analyze(plan []byte) ([]*terraform.PlanAnnotation, error)
+}
+
+// Annotate analyzes the given plan with each of the given analyzers, in
+// order, and returns their annotations of the changes of the resource
+// instances with the given addresses.
+//
+// Since annotations are only informational, an analyzer that fails to
+// analyze the plan gives a warning rather than an error, as does each
+// annotation that isn't of a change in the plan or has no summary, which
+// is left out.
+func Annotate(analyzers []Analyzer, plan []byte, addrs []string) ([]*terraform.PlanAnnotation, tfdiags.Diagnostics) {
+	var diags tfdiags.Diagnostics
+
+	changes := make(map[string]struct{}, len(addrs))
+	for _, addr := range addrs {
+		changes[addr] = struct{}{}
+	}
+
+	var ret []*terraform.PlanAnnotation
+	for _, a := range analyzers {
+		annotations, err := a.Analyze(plan)
+		if err != nil {
+			diags = diags.Append(tfdiags.SimpleWarning(
+				fmt.Sprintf("Plan analyzer %q failed, so its annotations are missing: %s", a.Name(), err),
+			))
+			continue
+		}
+
+		for _, annotation := range annotations {
+			if _, ok := changes[annotation.Addr]; !ok {
+				diags = diags.Append(tfdiags.SimpleWarning(
+					fmt.Sprintf("Plan analyzer %q annotated %q, which has no change in the plan, so the annotation was ignored.", a.Name(), annotation.Addr),
+				))
+				continue
+			}
+			if annotation.Summary == "" {
+				diags = diags.Append(tfdiags.SimpleWarning(
+					fmt.Sprintf("Plan analyzer %q annotated %s without a summary, so the annotation was ignored.", a.Name(), annotation.Addr),
+				))
+				continue
+			}
+			annotation.Analyzer = a.Name()
+			ret = append(ret, annotation)
+		}
+	}
+
+	return ret, diags.WithCode(tfdiags.CodePlanAnalysis)
+}
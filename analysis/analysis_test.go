@@ -0,0 +1,138 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAnnotate(t *testing.T) {
+	program := testAnalyzerProgram(t)
+
+	cases := map[string]struct {
+		Analyzers   []Analyzer
+		Plan        string
+		Annotations []*terraform.PlanAnnotation
+		Warnings    []string
+	}{
+		"no analyzers": {
+			nil,
+			`{"resource_changes":[]}`,
+			nil,
+			nil,
+		},
+		"none": {
+			[]Analyzer{ProgramAnalyzer("a", "/bin/sh", program, "none")},
+			`{"resource_changes":[]}`,
+			nil,
+			nil,
+		},
+		"annotations": {
+			[]Analyzer{
+				ProgramAnalyzer("a", "/bin/sh", program, "none"),
+				ProgramAnalyzer("b", "/bin/sh", program, "security"),
+			},
+			`{"resource_changes":[{"address":"test_instance.foo"}]}`,
+			[]*terraform.PlanAnnotation{
+				{
+					Addr:     "test_instance.foo",
+					Analyzer: "b",
+					Kind:     "security",
+					Summary:  "port 22 is open",
+					Detail:   "Allow SSH only from the VPN.",
+				},
+			},
+			nil,
+		},
+		"from plan": {
+			[]Analyzer{ProgramAnalyzer("a", "/bin/sh", program, "none")},
+			`{"resource_changes":[{"address":"test_instance.big"}]}`,
+			[]*terraform.PlanAnnotation{
+				{
+					Addr:     "test_instance.big",
+					Analyzer: "a",
+					Kind:     "cost",
+					Summary:  "+$100/month",
+				},
+			},
+			nil,
+		},
+		"invalid": {
+			[]Analyzer{ProgramAnalyzer("a", "/bin/sh", program, "invalid")},
+			`{"resource_changes":[{"address":"test_instance.foo"}]}`,
+			nil,
+			[]string{
+				`Plan analyzer "a" annotated "test_instance.gone", which has no change in the plan, so the annotation was ignored.`,
+				`Plan analyzer "a" annotated test_instance.foo without a summary, so the annotation was ignored.`,
+			},
+		},
+		"error": {
+			[]Analyzer{
+				ProgramAnalyzer("a", "/bin/sh", program, "fail"),
+				ProgramAnalyzer("b", "/bin/sh", program, "security"),
+			},
+			`{"resource_changes":[{"address":"test_instance.foo"}]}`,
+			[]*terraform.PlanAnnotation{
+				{
+					Addr:     "test_instance.foo",
+					Analyzer: "b",
+					Kind:     "security",
+					Summary:  "port 22 is open",
+					Detail:   "Allow SSH only from the VPN.",
+				},
+			},
+			[]string{
+				"Plan analyzer \"a\" failed, so its annotations are missing: error in /bin/sh: failing because you told me to fail\n",
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			addrs := []string{"test_instance.foo", "test_instance.big"}
+			annotations, diags := Annotate(tc.Analyzers, []byte(tc.Plan), addrs)
+			if diags.HasErrors() {
+				t.Fatalf("unexpected errors: %s", diags.Err())
+			}
+			if !reflect.DeepEqual(annotations, tc.Annotations) {
+				t.Errorf("wrong annotations\ngot:  %#v\nwant: %#v", annotations, tc.Annotations)
+			}
+
+			var warnings []string
+			for _, diag := range diags {
+				warnings = append(warnings, diag.Description().Summary)
+			}
+			if !reflect.DeepEqual(warnings, tc.Warnings) {
+				t.Errorf("wrong warnings\ngot:  %#v\nwant: %#v", warnings, tc.Warnings)
+			}
+		})
+	}
+}
+
+func TestProgramAnalyzer_badOutput(t *testing.T) {
+	program := testAnalyzerProgram(t)
+
+	_, err := ProgramAnalyzer("a", "/bin/sh", program, "malformed").Analyze([]byte("{}"))
+	if err == nil {
+		t.Fatal("succeeded; want error")
+	}
+	if want := "malformed output"; !strings.Contains(err.Error(), want) {
+		t.Errorf("wrong error %q; want it to contain %q", err, want)
+	}
+}
+
+func testAnalyzerProgram(t *testing.T) string {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("plan analyzer tests require /bin/sh")
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(wd, "test-fixtures/analyzer.sh")
+}
@@ -0,0 +1,9 @@
+// Package analysis annotates the changes of plans with the findings of
+// user-supplied analyzers, such as cost estimates and security findings,
+// once the plans are created.
+//
+// A plan is analyzed by an Analyzer, which is given the plan in the JSON
+// form of "terraform plan -json" and returns annotations of its resource
+// changes. Annotations are informational: unlike policy hooks, analyzers
+// can't stop a plan from being applied.
+package analysis
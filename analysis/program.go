@@ -0,0 +1,43 @@
+package analysis
+
+import (
+	"github.com/hashicorp/terraform/helper/program"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+type programAnalyzer struct {
+	name    string
+	program *program.Program
+}
+
+// ProgramAnalyzer returns an Analyzer that runs the given program with the
+// given arguments in order to analyze a plan. The executable path must be
+// absolute, as for program.New.
+//
+// The plan is written to the standard input of the program, which must
+// write a JSON object to its standard output with the property
+// "annotations", a list of objects with the properties "address", the
+// address of an annotated resource change, "summary" and optionally "kind"
+// and "detail". If the program exits with a non-zero status, the plan
+// couldn't be analyzed.
+func ProgramAnalyzer(name, executable string, args ...string) Analyzer {
+	return &programAnalyzer{
+		name:    name,
+		program: program.New("ProgramAnalyzer", executable, args...),
+	}
+}
+
+func (a *programAnalyzer) Name() string {
+	return a.name
+}
+
+func (a *programAnalyzer) Analyze(plan []byte) ([]*terraform.PlanAnnotation, error) {
+	var out struct {
+		Annotations []*terraform.PlanAnnotation `json:"annotations"`
+	}
+	if err := a.program.RunJSON(plan, &out); err != nil {
+		return nil, err
+	}
+
+	return out.Annotations, nil
+}
@@ -0,0 +1,28 @@
+#!/bin/sh
+# An analyzer program for testing, which gives the annotations named by its
+# first argument. A plan that creates a "big" resource is always annotated
+# with its cost.
+
+if grep -q '"address":"test_instance.big"' -; then
+  echo '{"annotations":[{"address":"test_instance.big","kind":"cost","summary":"+$100/month"}]}'
+  exit 0
+fi
+
+case "$1" in
+  none)
+    echo '{"annotations":[]}'
+    ;;
+  security)
+    echo '{"annotations":[{"address":"test_instance.foo","kind":"security","summary":"port 22 is open","detail":"Allow SSH only from the VPN."}]}'
+    ;;
+  invalid)
+    echo '{"annotations":[{"address":"test_instance.gone","summary":"gone"},{"address":"test_instance.foo"}]}'
+    ;;
+  malformed)
+    echo 'not json'
+    ;;
+  *)
+    echo "failing because you told me to fail" >&2
+    exit 1
+    ;;
+esac
@@ -12,6 +12,7 @@ import (
 	"github.com/hashicorp/terraform/config/module"
 	"github.com/hashicorp/terraform/state"
 	"github.com/hashicorp/terraform/terraform"
+	"github.com/hashicorp/terraform/tfdiags"
 )
 
 // This is the name of the default, initial state that every backend
@@ -112,6 +113,19 @@ type Operation struct {
 	PlanOutPath    string // PlanOutPath is the path to save the plan
 	PlanOutBackend *terraform.BackendState
 
+	// PlanMode selects the kind of plan to create for a plan operation.
+	PlanMode PlanMode
+
+	// Explain, for plan, is the address of a resource whose planned values
+	// are explained: where each of them came from, and why the unknown ones
+	// are unknown. The explanations are in RunningOperation.Explanations.
+	Explain string
+
+	// PlanJSON, if set, indicates that the caller will render the plan
+	// returned in RunningOperation.Plan as JSON, and so the backend should
+	// not render the plan for display itself.
+	PlanJSON bool
+
 	// Module settings specify the root module to use for operations.
 	Module *module.Tree
 
@@ -123,10 +137,43 @@ type Operation struct {
 	// behavior of the operation.
 	Destroy      bool
 	Targets      []string
+	ForceReplace []string
 	Variables    map[string]interface{}
 	AutoApprove  bool
 	DestroyForce bool
 
+	// TargetModules scopes the operation to the given modules, such as
+	// "module.foo": only the resources within them, and what they depend
+	// on, are planned and applied.
+	TargetModules []string
+
+	// DryRun, for apply, gives each planned change to its provider to check
+	// without applying anything or changing the state.
+	DryRun bool
+
+	// Resume resumes the apply that last failed, applying only the changes
+	// of its plan that didn't complete. See the local backend for details.
+	Resume bool
+
+	// ApproveBy, for apply, asks for approval of each group of the planned
+	// changes instead of all of them at once, grouping them by one of the
+	// ApproveBy constants. The changes that aren't approved are skipped,
+	// along with the changes that depend on them, and if
+	// ResidualPlanOutPath is set, they're saved to a plan there once the
+	// approved changes are applied.
+	ApproveBy           string
+	ResidualPlanOutPath string
+
+	// InterruptGrace, for apply, is how long the changes in progress are
+	// given to complete when the operation is cancelled. No new changes are
+	// started once it is cancelled, and the providers are stopped once the
+	// grace period has passed.
+	InterruptGrace time.Duration
+
+	// PolicyOverride allows a plan that soft-fails a policy hook to be
+	// applied. Plans that hard-fail a hook are never applied.
+	PolicyOverride bool
+
 	// Input/output/control options.
 	UIIn  terraform.UIInput
 	UIOut terraform.UIOutput
@@ -138,11 +185,26 @@ type Operation struct {
 	// The duration to retry obtaining a State lock.
 	StateLockTimeout time.Duration
 
+	// LockResources, for apply with LockState, locks only the resources
+	// that the plan changes instead of the whole state, if the state
+	// supports it, so that applies of other resources can run at the same
+	// time. See the local backend for details.
+	LockResources bool
+
 	// Workspace is the name of the workspace that this operation should run
 	// in, which controls which named state is used.
 	Workspace string
 }
 
+// The kinds of groups that Operation.ApproveBy can ask to approve planned
+// changes by: each resource instance, the changes within each module, or
+// each kind of change, such as all of the destroys.
+const (
+	ApproveByResource = "resource"
+	ApproveByModule   = "module"
+	ApproveByAction   = "action"
+)
+
 // RunningOperation is the result of starting an operation.
 type RunningOperation struct {
 	// Context should be used to track Done and Err for errors.
@@ -162,6 +224,20 @@ type RunningOperation struct {
 	// to note whether a plan is empty or has changes.
 	PlanEmpty bool
 
+	// Plan is populated after a Plan operation completes without error,
+	// and is the plan that was produced.
+	Plan *terraform.Plan
+
+	// Explanations are the explanations of the planned values requested
+	// with Operation.Explain, populated after a Plan operation completes
+	// without error.
+	Explanations []*terraform.Explanation
+
+	// Diagnostics are the warnings of a Plan operation that the backend
+	// didn't show because the caller renders the plan itself, as requested
+	// with Operation.PlanJSON.
+	Diagnostics tfdiags.Diagnostics
+
 	// State is the final state after the operation completed. Persisting
 	// this state is managed by the backend. This should only be read
 	// after the operation completes to avoid read/write races.
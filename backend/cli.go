@@ -1,6 +1,11 @@
 package backend
 
 import (
+	"github.com/hashicorp/terraform/analysis"
+	"github.com/hashicorp/terraform/command/format"
+	"github.com/hashicorp/terraform/policy"
+	"github.com/hashicorp/terraform/svchost/auth"
+	"github.com/hashicorp/terraform/svchost/disco"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
 	"github.com/mitchellh/colorstring"
@@ -48,6 +53,9 @@ type CLIOpts struct {
 	CLI      cli.Ui
 	CLIColor *colorstring.Colorize
 
+	// Redaction selects how sensitive values are shown in the CLI output.
+	Redaction format.Redaction
+
 	// StatePath is the local path where state is read from.
 	//
 	// StateOutPath is the local path where the state will be written.
@@ -80,4 +88,23 @@ type CLIOpts struct {
 	// some sort of workflow automation tool that abstracts away the
 	// exact commands that are being run.
 	RunningInAutomation bool
+
+	// ApplyCheckpointPath is the path where backends that support resuming
+	// failed applies record their progress.
+	ApplyCheckpointPath string
+
+	// PolicyHooks check plans before they are applied. A plan that
+	// hard-fails a hook, or soft-fails one without the policies being
+	// overridden, must not be applied.
+	PolicyHooks []policy.Hook
+
+	// PlanAnalyzers annotate the changes of plans once they're created,
+	// before they're shown and checked by PolicyHooks.
+	PlanAnalyzers []analysis.Analyzer
+
+	// Services and Credentials are used by backends that talk to
+	// Terraform-native services, to discover the services of a host and
+	// to authenticate with them.
+	Services    *disco.Disco
+	Credentials auth.CredentialsSource
 }
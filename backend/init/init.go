@@ -11,6 +11,7 @@ import (
 	backendatlas "github.com/hashicorp/terraform/backend/atlas"
 	backendlegacy "github.com/hashicorp/terraform/backend/legacy"
 	backendlocal "github.com/hashicorp/terraform/backend/local"
+	backendremote "github.com/hashicorp/terraform/backend/remote"
 	backendAzure "github.com/hashicorp/terraform/backend/remote-state/azure"
 	backendconsul "github.com/hashicorp/terraform/backend/remote-state/consul"
 	backendetcdv3 "github.com/hashicorp/terraform/backend/remote-state/etcdv3"
@@ -28,10 +29,9 @@ import (
 // To read an available backend, use the Backend function. This ensures
 // safe concurrent read access to the list of built-in backends.
 //
-// Backends are hardcoded into Terraform because the API for backends uses
-// complex structures and supporting that over the plugin system is currently
-// prohibitively difficult. For those wanting to implement a custom backend,
-// they can do so with recompilation.
+// Only the state storage part of a backend can be served over the plugin
+// system (see plugin.BackendPlugin), so enhanced backends must still be
+// built in to Terraform.
 var backends map[string]func() backend.Backend
 var backendsLock sync.Mutex
 
@@ -41,6 +41,7 @@ func init() {
 	backends = map[string]func() backend.Backend{
 		"atlas":  func() backend.Backend { return &backendatlas.Backend{} },
 		"local":  func() backend.Backend { return &backendlocal.Local{} },
+		"remote": func() backend.Backend { return backendremote.New() },
 		"consul": func() backend.Backend { return backendconsul.New() },
 		"inmem":  func() backend.Backend { return backendinmem.New() },
 		"swift":  func() backend.Backend { return backendSwift.New() },
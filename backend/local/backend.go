@@ -11,9 +11,13 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/hashicorp/terraform/analysis"
 	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/command/format"
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/policy"
 	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/encryption"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
 	"github.com/mitchellh/colorstring"
@@ -24,6 +28,10 @@ const (
 	DefaultWorkspaceFile   = "environment"
 	DefaultStateFilename   = "terraform.tfstate"
 	DefaultBackupExtension = ".backup"
+
+	// DefaultHistoryExtension is appended to the path that the state is
+	// written to for the directory of its previous versions.
+	DefaultHistoryExtension = ".history"
 )
 
 // Local is an implementation of EnhancedBackend that performs all operations
@@ -54,6 +62,13 @@ type Local struct {
 	StateBackupPath   string
 	StateWorkspaceDir string
 
+	// StateHistory, if set, stores every version of the state that's
+	// written in a directory next to StateOutPath, named with
+	// DefaultHistoryExtension, so that it can be rolled back. It is set by
+	// the "history" config option, and is off by default since the
+	// directory is never pruned.
+	StateHistory bool
+
 	// We only want to create a single instance of a local state, so store them
 	// here as they're loaded.
 	states map[string]state.State
@@ -87,6 +102,25 @@ type Local struct {
 	// exact commands that are being run.
 	RunningInAutomation bool
 
+	// Redaction selects how sensitive values are shown in rendered plans.
+	Redaction format.Redaction
+
+	// ApplyCheckpointPath is the path of the checkpoint written when an
+	// apply fails, which records the changes of its plan that completed so
+	// that the apply can be resumed. If empty, applies aren't checkpointed.
+	ApplyCheckpointPath string
+
+	// PolicyHooks check plans before they are applied. See checkPolicy.
+	PolicyHooks []policy.Hook
+
+	// PlanAnalyzers annotate the changes of plans. See annotatePlan.
+	PlanAnalyzers []analysis.Analyzer
+
+	// StateEncryption, if non-nil, encrypts all states written by this
+	// backend and decrypts them when read, whether they are stored locally
+	// or by Backend.
+	StateEncryption *encryption.Encryption
+
 	schema *schema.Backend
 	opLock sync.Mutex
 	once   sync.Once
@@ -182,7 +216,14 @@ func (b *Local) State(name string) (state.State, error) {
 
 	// If we have a backend handling state, delegate to that.
 	if b.Backend != nil {
-		return b.Backend.State(name)
+		s, err := b.Backend.State(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.encryptState(s); err != nil {
+			return nil, err
+		}
+		return s, nil
 	}
 
 	if s, ok := b.states[name]; ok {
@@ -194,10 +235,14 @@ func (b *Local) State(name string) (state.State, error) {
 	}
 
 	// Otherwise, we need to load the state.
-	var s state.State = &state.LocalState{
+	ls := &state.LocalState{
 		Path:    statePath,
 		PathOut: stateOutPath,
 	}
+	if b.StateHistory {
+		ls.HistoryDir = stateOutPath + DefaultHistoryExtension
+	}
+	var s state.State = ls
 
 	// If we are backing up the state, wrap it
 	if backupPath != "" {
@@ -207,6 +252,10 @@ func (b *Local) State(name string) (state.State, error) {
 		}
 	}
 
+	if err := b.encryptState(s); err != nil {
+		return nil, err
+	}
+
 	if b.states == nil {
 		b.states = map[string]state.State{}
 	}
@@ -214,6 +263,20 @@ func (b *Local) State(name string) (state.State, error) {
 	return s, nil
 }
 
+// encryptState enables StateEncryption, if set, on the given state.
+func (b *Local) encryptState(s state.State) error {
+	if b.StateEncryption == nil {
+		return nil
+	}
+
+	e, ok := s.(state.Encrypter)
+	if !ok {
+		return fmt.Errorf("state storage %T does not support state encryption", s)
+	}
+	e.SetEncryption(b.StateEncryption)
+	return nil
+}
+
 // Operation implements backend.Enhanced
 //
 // This will initialize an in-memory terraform.Context to perform the
@@ -295,6 +358,12 @@ func (b *Local) init() {
 
 				Deprecated: "workspace_dir should be used instead, with the same meaning",
 			},
+
+			"history": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
 		},
 
 		ConfigureFunc: b.schemaConfigure,
@@ -331,6 +400,8 @@ func (b *Local) schemaConfigure(ctx context.Context) error {
 		}
 	}
 
+	b.StateHistory = d.Get("history").(bool)
+
 	return nil
 }
 
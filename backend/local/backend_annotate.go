@@ -0,0 +1,48 @@
+package local
+
+import (
+	"log"
+
+	"github.com/hashicorp/terraform/analysis"
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/command/format"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/hashicorp/terraform/tfdiags"
+)
+
+// annotatePlan analyzes the given plan, whose display plan is dispPlan,
+// with the plan analyzers and records their annotations in the plan,
+// returning the display plan of the annotated plan.
+//
+// Analyzers can't stop a plan from being applied, so their problems are
+// only warnings: they are shown, unless the caller renders the plan
+// itself, in which case they are added to the diagnostics of runningOp.
+func (b *Local) annotatePlan(op *backend.Operation, runningOp *backend.RunningOperation, plan *terraform.Plan, dispPlan *format.Plan) *format.Plan {
+	if len(b.PlanAnalyzers) == 0 || dispPlan.Empty() {
+		return dispPlan
+	}
+
+	var diags tfdiags.Diagnostics
+	js, err := dispPlan.JSON()
+	if err != nil {
+		// Should never happen, since the plan was just created.
+		log.Printf("[ERROR] backend/local: failed to serialize plan for plan analyzers: %s", err)
+		return dispPlan
+	}
+
+	addrs := make([]string, 0, len(dispPlan.Resources))
+	for _, r := range dispPlan.Resources {
+		addrs = append(addrs, r.Addr.String())
+	}
+	plan.Annotations, diags = analysis.Annotate(b.PlanAnalyzers, js, addrs)
+
+	if op.PlanJSON {
+		runningOp.Diagnostics = runningOp.Diagnostics.Append(diags)
+	} else if b.CLI != nil {
+		for _, diag := range diags {
+			b.CLI.Warn(format.Diagnostic(diag, b.Colorize(), 72))
+		}
+	}
+
+	return format.NewPlan(plan)
+}
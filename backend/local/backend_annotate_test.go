@@ -0,0 +1,120 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/analysis"
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/mitchellh/cli"
+)
+
+func TestLocal_planAnnotations(t *testing.T) {
+	b := TestLocal(t)
+	TestLocalProvider(t, b, "test")
+
+	ui := cli.NewMockUi()
+	b.CLI = ui
+
+	analyzer := &testPlanAnalyzer{}
+	b.PlanAnalyzers = []analysis.Analyzer{analyzer}
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/plan")
+	defer modCleanup()
+
+	outDir := testTempDir(t)
+	defer os.RemoveAll(outDir)
+	planPath := filepath.Join(outDir, "plan.tfplan")
+
+	op := testOperationPlan()
+	op.Module = mod
+	op.PlanOutPath = planPath
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", run.Err)
+	}
+
+	if !strings.Contains(string(analyzer.plan), `"address":"test_instance.foo"`) {
+		t.Fatalf("analyzer wasn't given the plan: %s", analyzer.plan)
+	}
+
+	want := []*terraform.PlanAnnotation{
+		{Addr: "test_instance.foo", Analyzer: "test", Kind: "cost", Summary: "+$5/month"},
+	}
+	if got := run.Plan.Annotations; !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong annotations\ngot:  %#v\nwant: %#v", got, want)
+	}
+	if got := testReadPlan(t, planPath).Annotations; !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong annotations in plan file\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	if output := ui.OutputWriter.String(); !strings.Contains(output, "# cost (test): +$5/month") {
+		t.Fatalf("annotation not shown: %s", output)
+	}
+	if output := ui.ErrorWriter.String(); !strings.Contains(output, `annotated "test_instance.gone"`) {
+		t.Fatalf("ignored annotation not warned of: %s", output)
+	}
+}
+
+func TestLocal_planAnnotationsJSON(t *testing.T) {
+	b := TestLocal(t)
+	TestLocalProvider(t, b, "test")
+
+	ui := cli.NewMockUi()
+	b.CLI = ui
+	b.PlanAnalyzers = []analysis.Analyzer{&testPlanAnalyzer{}}
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/plan")
+	defer modCleanup()
+
+	op := testOperationPlan()
+	op.Module = mod
+	op.PlanJSON = true
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", run.Err)
+	}
+
+	if len(run.Plan.Annotations) != 1 {
+		t.Fatalf("wrong annotations %#v", run.Plan.Annotations)
+	}
+	if output := ui.ErrorWriter.String(); output != "" {
+		t.Fatalf("warnings shown when the caller renders the plan: %s", output)
+	}
+	if len(run.Diagnostics) != 1 || tfdiags.GetCode(run.Diagnostics[0]) != tfdiags.CodePlanAnalysis {
+		t.Fatalf("wrong diagnostics %#v", run.Diagnostics)
+	}
+}
+
+type testPlanAnalyzer struct {
+	plan []byte
+}
+
+func (a *testPlanAnalyzer) Name() string {
+	return "test"
+}
+
+func (a *testPlanAnalyzer) Analyze(plan []byte) ([]*terraform.PlanAnnotation, error) {
+	a.plan = plan
+
+	return []*terraform.PlanAnnotation{
+		{Addr: "test_instance.foo", Kind: "cost", Summary: "+$5/month"},
+		{Addr: "test_instance.gone", Kind: "cost", Summary: "-$5/month"},
+	}, nil
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/hashicorp/terraform/config/module"
 	"github.com/hashicorp/terraform/state"
 	"github.com/hashicorp/terraform/terraform"
+	"github.com/hashicorp/terraform/tfdiags"
 )
 
 func (b *Local) opApply(
@@ -31,21 +32,43 @@ func (b *Local) opApply(
 		return
 	}
 
+	// The residual plan is a plan file, which is checked before anything
+	// is applied rather than when it's written.
+	if op.ResidualPlanOutPath != "" && b.StateEncryption != nil {
+		runningOp.Err = fmt.Errorf(strings.TrimSpace(planErrEncryptedPlanFile))
+		return
+	}
+
 	// If we have a nil module at this point, then set it to an empty tree
 	// to avoid any potential crashes.
 	if op.Module == nil {
 		op.Module = module.NewEmptyTree()
 	}
 
+	// If we're resuming a failed apply, only the changes that didn't
+	// complete are planned, without refreshing.
+	var checkpoint *applyCheckpoint
+	if op.Resume {
+		var err error
+		checkpoint, err = b.resumeCheckpoint(op)
+		if err != nil {
+			runningOp.Err = err
+			return
+		}
+		op.Targets = checkpoint.Targets()
+		op.PlanRefresh = false
+	}
+
 	// Setup our count hook that keeps track of resource changes
 	countHook := new(CountHook)
 	stateHook := new(StateHook)
+	checkpointHook := new(checkpointHook)
 	if b.ContextOpts == nil {
 		b.ContextOpts = new(terraform.ContextOpts)
 	}
 	old := b.ContextOpts.Hooks
 	defer func() { b.ContextOpts.Hooks = old }()
-	b.ContextOpts.Hooks = append(b.ContextOpts.Hooks, countHook, stateHook)
+	b.ContextOpts.Hooks = append(b.ContextOpts.Hooks, countHook, stateHook, checkpointHook)
 
 	// Get our context
 	tfCtx, opState, err := b.context(op)
@@ -54,7 +77,21 @@ func (b *Local) opApply(
 		return
 	}
 
-	if op.LockState {
+	// With resource locking the state isn't locked while planning. Instead,
+	// the resources that the plan changes are locked before applying it. A
+	// refresh-only plan replaces the whole state, so it always locks it.
+	lockResources := op.LockState && op.LockResources &&
+		!(op.Plan != nil && op.Plan.RefreshOnly)
+	if lockResources && !state.ResourceLockingSupported(opState) {
+		log.Printf("[INFO] backend/local: state doesn't support resource locking, locking the whole state")
+		if b.CLI != nil {
+			b.CLI.Warn("The state storage doesn't support locking resources, so the whole state is locked.")
+		}
+		lockResources = false
+	}
+	readState := opState.State()
+
+	if op.LockState && !lockResources {
 		lockCtx, cancel := context.WithTimeout(ctx, op.StateLockTimeout)
 		defer cancel()
 
@@ -77,7 +114,9 @@ func (b *Local) opApply(
 	runningOp.State = tfCtx.State()
 
 	// If we weren't given a plan, then we refresh/plan
-	if op.Plan == nil {
+	plan := op.Plan
+	var residual *terraform.Plan
+	if plan == nil {
 		// If we're refreshing before apply, perform that
 		if op.PlanRefresh {
 			log.Printf("[INFO] backend/local: apply calling Refresh")
@@ -90,17 +129,43 @@ func (b *Local) opApply(
 
 		// Perform the plan
 		log.Printf("[INFO] backend/local: apply calling Plan")
-		plan, err := tfCtx.Plan()
+		var err error
+		plan, err = tfCtx.Plan()
 		if err != nil {
 			runningOp.Err = errwrap.Wrapf("Error running plan: {{err}}", err)
 			return
 		}
 
-		dispPlan := format.NewPlan(plan)
+		if checkpoint != nil {
+			if err := checkpoint.Validate(runningOp.State, planChanges(plan)); err != nil {
+				runningOp.Err = err
+				return
+			}
+		}
+
+		dispPlan := b.annotatePlan(op, runningOp, plan, format.NewPlan(plan))
+		b.warnModuleScope(dispPlan)
 		trivialPlan := dispPlan.Empty()
 		hasUI := op.UIOut != nil && op.UIIn != nil
-		mustConfirm := hasUI && ((op.Destroy && !op.DestroyForce) || (!op.Destroy && !op.AutoApprove && !trivialPlan))
-		if mustConfirm {
+		mustConfirm := hasUI && !op.DryRun && ((op.Destroy && !op.DestroyForce) || (!op.Destroy && !op.AutoApprove && !trivialPlan))
+		if (mustConfirm || op.DryRun && b.CLI != nil) && !trivialPlan {
+			// Display the plan of what we are going to apply/destroy.
+			b.renderPlan(dispPlan)
+			b.CLI.Output("")
+		}
+
+		if err := b.checkPolicy(op, dispPlan); err != nil {
+			runningOp.Err = err
+			return
+		}
+
+		if mustConfirm && op.ApproveBy != "" {
+			residual, err = b.approveChanges(op, plan, dispPlan)
+			if err != nil {
+				runningOp.Err = err
+				return
+			}
+		} else if mustConfirm {
 			var desc, query string
 			if op.Destroy {
 				// Default destroy message
@@ -113,12 +178,6 @@ func (b *Local) opApply(
 				query = "Do you want to perform these actions?"
 			}
 
-			if !trivialPlan {
-				// Display the plan of what we are going to apply/destroy.
-				b.renderPlan(dispPlan)
-				b.CLI.Output("")
-			}
-
 			v, err := op.UIIn.Input(&terraform.InputOpts{
 				Id:          "approve",
 				Query:       query,
@@ -139,8 +198,53 @@ func (b *Local) opApply(
 		}
 	}
 
-	// Setup our hook for continuous state updates
+	// A dry run only checks the changes with the providers, so nothing is
+	// applied and the state isn't written.
+	if op.DryRun {
+		b.opApplyDryRun(tfCtx, runningOp)
+		return
+	}
+
+	// A refresh-only plan has nothing to apply, so applying it just
+	// replaces the state with the refreshed state that the plan recorded.
+	if op.Plan != nil && op.Plan.RefreshOnly {
+		b.opApplyRefreshOnly(op.Plan, opState, runningOp)
+		return
+	}
+
+	// A saved plan may have been created before the policies changed, so
+	// it's checked again.
+	if op.Plan != nil {
+		dispPlan := format.NewPlan(op.Plan)
+		b.warnModuleScope(dispPlan)
+		if err := b.checkPolicy(op, dispPlan); err != nil {
+			runningOp.Err = err
+			return
+		}
+	}
+
+	// The planned changes are kept for checkpointing a failed apply, since
+	// applying removes them from the diff of the plan.
+	changes := planChanges(plan)
+
+	if lockResources {
+		lockedState, unlock, err := b.lockResources(ctx, op, opState, readState, changes)
+		if err != nil {
+			runningOp.Err = err
+			return
+		}
+		defer func() {
+			if err := unlock(); err != nil {
+				runningOp.Err = multierror.Append(runningOp.Err, err)
+			}
+		}()
+		opState = lockedState
+	}
+
+	// Setup our hook for continuous state updates, persisting each one so
+	// that an interrupted apply loses as little as possible.
 	stateHook.State = opState
+	stateHook.Persist = true
 
 	// Start the apply in a goroutine so that we can be interrupted.
 	var applyState *terraform.State
@@ -156,10 +260,14 @@ func (b *Local) opApply(
 	// Wait for the apply to finish or for us to be interrupted so
 	// we can handle it properly.
 	err = nil
+	interrupted := false
 	select {
 	case <-ctx.Done():
+		interrupted = true
 		if b.CLI != nil {
-			b.CLI.Output("stopping apply operation...")
+			b.CLI.Output(fmt.Sprintf(
+				"Stopping apply operation: no new changes will be started, and the changes\n"+
+					"in progress have %s to complete...", op.InterruptGrace))
 		}
 
 		// try to force a PersistState just in case the process is terminated
@@ -173,8 +281,8 @@ func (b *Local) opApply(
 			}
 		}
 
-		// Stop execution
-		go tfCtx.Stop()
+		// Stop execution once the changes in progress complete
+		go tfCtx.Interrupt(op.InterruptGrace)
 
 		// Wait for completion still
 		<-doneCh
@@ -194,20 +302,53 @@ func (b *Local) opApply(
 		return
 	}
 
-	if applyErr != nil {
+	// An interrupted apply fails only if some of the changes didn't get to
+	// complete.
+	cp := newApplyCheckpoint(op.Workspace, plan.Destroy, changes, checkpointHook, opState.State())
+	pending := cp.Pending()
+	if applyErr != nil || (interrupted && len(pending) > 0) {
+		// The changes of the plan are checkpointed so that the apply can be
+		// resumed. A resumed apply that fails again is checkpointed with the
+		// changes of its own plan.
+		var resumeHelp string
+		if err := b.writeApplyCheckpoint(cp); err != nil {
+			log.Printf("[WARN] backend/local: failed to write apply checkpoint: %s", err)
+		} else if b.ApplyCheckpointPath != "" {
+			resumeHelp = "\n\n" + strings.TrimSpace(applyCheckpointHelp)
+		}
+
+		if interrupted {
+			runningOp.Err = interruptedApplyError(cp, pending, applyErr, resumeHelp)
+			return
+		}
+
 		runningOp.Err = fmt.Errorf(
 			"Error applying plan:\n\n"+
 				"%s\n\n"+
 				"Terraform does not automatically rollback in the face of errors.\n"+
 				"Instead, your Terraform state file has been partially updated with\n"+
 				"any resources that successfully completed. Please address the error\n"+
-				"above and apply again to incrementally change your infrastructure.",
-			multierror.Flatten(applyErr))
+				"above and apply again to incrementally change your infrastructure.%s",
+			multierror.Flatten(applyErr), resumeHelp)
 		return
 	}
 
+	b.removeApplyCheckpoint()
+
+	// The changes that weren't approved are saved for applying later.
+	if residual != nil && op.ResidualPlanOutPath != "" {
+		if err := b.writeResidualPlan(op, residual, opState.State()); err != nil {
+			runningOp.Err = err
+			return
+		}
+	}
+
 	// If we have a UI, output the results
 	if b.CLI != nil {
+		for _, diag := range format.CheckWarnings(applyState.Checks()) {
+			b.CLI.Warn(format.Diagnostic(diag, b.Colorize(), 72))
+		}
+
 		if op.Destroy {
 			b.CLI.Output(b.Colorize().Color(fmt.Sprintf(
 				"[reset][bold][green]\n"+
@@ -222,6 +363,30 @@ func (b *Local) opApply(
 				countHook.Removed)))
 		}
 
+		if residual != nil {
+			skippedHelp := "Run \"terraform apply\" again to plan them again."
+			if op.ResidualPlanOutPath != "" {
+				skippedHelp = fmt.Sprintf(
+					"They were saved to the plan file %q, which\n"+
+						"applies them with \"terraform apply %s\".",
+					op.ResidualPlanOutPath, op.ResidualPlanOutPath)
+			}
+			b.CLI.Output(b.Colorize().Color(fmt.Sprintf(
+				"[reset][yellow]\n"+
+					"Changes skipped: %d. These changes weren't approved, or depend on\n"+
+					"changes that weren't. %s",
+				len(planChanges(residual)), skippedHelp)))
+		}
+
+		if len(plan.Deferred) > 0 {
+			b.CLI.Output(b.Colorize().Color(fmt.Sprintf(
+				"[reset][yellow]\n"+
+					"Changes deferred: %d. These resources depend on values that were only\n"+
+					"known after this apply. Run \"terraform apply\" again to apply their\n"+
+					"changes.",
+				len(plan.Deferred))))
+		}
+
 		// only show the state file help message if the state is local.
 		if (countHook.Added > 0 || countHook.Changed > 0) && b.StateOutPath != "" {
 			b.CLI.Output(b.Colorize().Color(fmt.Sprintf(
@@ -236,6 +401,97 @@ func (b *Local) opApply(
 	}
 }
 
+// interruptedApplyError returns the error of an apply that was interrupted
+// before the given pending changes of the given checkpoint completed,
+// summarizing which of the planned changes completed.
+func interruptedApplyError(cp *applyCheckpoint, pending []*applyCheckpointChange, applyErr error, resumeHelp string) error {
+	lines := make([]string, len(pending))
+	for i, c := range pending {
+		lines[i] = fmt.Sprintf("%s (%s)", c.Address, actionName(c.Action))
+	}
+
+	var errText string
+	if applyErr != nil {
+		errText = fmt.Sprintf("\n\nThe changes in progress reported errors:\n\n%s", multierror.Flatten(applyErr))
+	}
+
+	return fmt.Errorf(
+		"Apply interrupted! Changes: %d completed, %d pending.\n\n"+
+			"The following planned changes were pending when the apply stopped:\n\n"+
+			"  %s%s\n\n"+
+			"Your Terraform state file has been updated with the changes that\n"+
+			"completed. Apply again to apply the pending changes.%s",
+		len(cp.Changes)-len(pending), len(pending),
+		strings.Join(lines, "\n  "), errText, resumeHelp)
+}
+
+// opApplyRefreshOnly applies a refresh-only plan by persisting the
+// refreshed state recorded in the plan.
+func (b *Local) opApplyRefreshOnly(
+	plan *terraform.Plan,
+	opState state.State,
+	runningOp *backend.RunningOperation) {
+	newState := plan.State
+	if newState == nil {
+		newState = terraform.NewState()
+	}
+	if current := opState.State(); current != nil && !current.SameLineage(newState) {
+		runningOp.Err = fmt.Errorf(
+			"The saved plan was created for a different state lineage (%q) than the current state (%q). Create a new plan and try again.",
+			newState.Lineage, current.Lineage,
+		)
+		return
+	}
+
+	runningOp.State = newState
+	if err := opState.WriteState(newState); err != nil {
+		runningOp.Err = b.backupStateForError(newState, err)
+		return
+	}
+	if err := opState.PersistState(); err != nil {
+		runningOp.Err = b.backupStateForError(newState, err)
+		return
+	}
+
+	if b.CLI != nil {
+		b.CLI.Output(b.Colorize().Color(
+			"[reset][bold][green]\n" +
+				"Apply complete! The state was updated to match the remote objects.\n" +
+				"No infrastructure was changed."))
+	}
+}
+
+// opApplyDryRun checks each change of the plan with its provider without
+// applying it, reporting the problems that the providers find.
+func (b *Local) opApplyDryRun(
+	tfCtx *terraform.Context,
+	runningOp *backend.RunningOperation) {
+	log.Printf("[INFO] backend/local: apply calling ValidateApply")
+	diags := tfCtx.ValidateApply()
+	for _, diag := range diags {
+		if diag.Severity() != tfdiags.Warning {
+			continue
+		}
+		if b.CLI != nil {
+			b.CLI.Warn(format.Diagnostic(diag, b.Colorize(), 72))
+		} else {
+			desc := diag.Description()
+			log.Printf("[WARN] backend/local: %s", desc.Summary)
+		}
+	}
+	if diags.HasErrors() {
+		runningOp.Err = errwrap.Wrapf("Error checking plan: {{err}}", diags.Err())
+		return
+	}
+
+	if b.CLI != nil {
+		b.CLI.Output(b.Colorize().Color(
+			"[reset][bold][green]\n" +
+				"Dry run complete! The providers found no problems with the changes.\n" +
+				"Nothing was applied."))
+	}
+}
+
 // backupStateForError is called in a scenario where we're unable to persist the
 // state for some reason, and will attempt to save a backup copy of the state
 // to local disk to help the user recover. This is a "last ditch effort" sort
@@ -319,9 +575,29 @@ importing each resource using its id from the target system.
 This is a serious bug in Terraform and should be reported.
 `
 
+const applyCheckpointHelp = `
+The changes that completed have been checkpointed. To apply only the
+remaining changes, without planning every resource again, run apply with
+the -resume flag once the error is addressed.
+`
+
 const earlyStateWriteErrorFmt = `Error saving current state: %s
 
 Terraform encountered an error attempting to save the state before canceling
 the current operation. Once the operation is complete another attempt will be
 made to save the final state.
 `
+
+// warnModuleScope shows the warnings of a plan that's scoped to modules
+// before it's applied, so that the dependents outside of the scope that it
+// skips aren't left behind unnoticed.
+func (b *Local) warnModuleScope(dispPlan *format.Plan) {
+	if b.CLI == nil {
+		return
+	}
+	for _, diag := range dispPlan.Warnings() {
+		if tfdiags.GetCode(diag) == tfdiags.CodePlanModuleScoped {
+			b.CLI.Warn(format.Diagnostic(diag, b.Colorize(), 72))
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package local
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/command/format"
+	"github.com/hashicorp/terraform/planfile"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// approvalGroup is a group of planned changes that are approved or skipped
+// together, as selected by Operation.ApproveBy.
+type approvalGroup struct {
+	Name    string
+	Changes []*format.InstanceDiff
+}
+
+// approvalGroups returns the groups of the given planned changes for
+// approving them by the given kind of group, in order of name. Imports,
+// moves and removals from the state without a change are left out, since
+// they're always applied with the plan.
+func approvalGroups(changes []*format.InstanceDiff, by string) []*approvalGroup {
+	groups := make(map[string]*approvalGroup)
+	for _, r := range changes {
+		if r.Action == terraform.DiffNone || (r.Action == terraform.DiffRefresh && r.Importing) {
+			continue
+		}
+
+		var name string
+		switch by {
+		case backend.ApproveByModule:
+			name = "the changes of the root module"
+			if len(r.Addr.Path) > 0 {
+				name = "the changes of " + r.Addr.WholeModuleAddress().String()
+			}
+		case backend.ApproveByAction:
+			name = "the " + approvalActionName(r.Action)
+		default:
+			name = "the changes of " + r.Addr.String()
+		}
+
+		g, ok := groups[name]
+		if !ok {
+			g = &approvalGroup{Name: name}
+			groups[name] = g
+		}
+		g.Changes = append(g.Changes, r)
+	}
+
+	ret := make([]*approvalGroup, 0, len(groups))
+	for _, g := range groups {
+		ret = append(ret, g)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+func approvalActionName(a terraform.DiffChangeType) string {
+	switch a {
+	case terraform.DiffCreate:
+		return "creates"
+	case terraform.DiffDestroy:
+		return "destroys"
+	case terraform.DiffDestroyCreate:
+		return "replacements"
+	case terraform.DiffRefresh:
+		return "reads of data sources"
+	default:
+		return "updates in-place"
+	}
+}
+
+// approveChanges asks for approval of each group of the changes of the
+// given plan, as selected by op.ApproveBy, and removes the changes that
+// aren't approved from the plan, along with the changes that depend on
+// them. It returns a residual plan of the removed changes, or nil if all of
+// the changes were approved.
+func (b *Local) approveChanges(op *backend.Operation, plan *terraform.Plan, dispPlan *format.Plan) (*terraform.Plan, error) {
+	colorize := b.Colorize()
+
+	var approved, skip []string
+	for _, g := range approvalGroups(dispPlan.Resources, op.ApproveBy) {
+		var buf bytes.Buffer
+		for _, r := range g.Changes {
+			fmt.Fprintf(&buf, "  %s %s\n", colorize.Color(format.DiffActionSymbol(r.Action)), r.Addr)
+		}
+		buf.WriteString("\nOnly 'yes' will be accepted to approve these changes. Any other\n" +
+			"answer skips them.")
+
+		v, err := op.UIIn.Input(&terraform.InputOpts{
+			Id:          "approve",
+			Query:       fmt.Sprintf("Do you want to apply %s?", g.Name),
+			Description: buf.String(),
+		})
+		if err != nil {
+			return nil, errwrap.Wrapf("Error asking for approval: {{err}}", err)
+		}
+
+		for _, r := range g.Changes {
+			if v == "yes" {
+				approved = append(approved, r.Addr.String())
+			} else {
+				skip = append(skip, r.Addr.String())
+			}
+		}
+	}
+	if len(skip) == 0 {
+		return nil, nil
+	}
+	if len(approved) == 0 {
+		return nil, errors.New("Apply cancelled. None of the changes were approved.")
+	}
+
+	residual, skipped, err := plan.Split(skip)
+	if err != nil {
+		return nil, err
+	}
+
+	// Approved changes that depend on skipped changes are skipped too, and
+	// since that changes what was approved, it's confirmed again.
+	skippedSet := make(map[string]bool, len(skipped))
+	for _, addr := range skipped {
+		skippedSet[addr] = true
+	}
+	var dependents []string
+	remaining := 0
+	for _, addr := range approved {
+		if skippedSet[addr] {
+			dependents = append(dependents, addr)
+		} else {
+			remaining++
+		}
+	}
+	if remaining == 0 {
+		return nil, errors.New("Apply cancelled. All of the approved changes depend on skipped changes.")
+	}
+	if len(dependents) > 0 {
+		sort.Strings(dependents)
+		v, err := op.UIIn.Input(&terraform.InputOpts{
+			Id:    "approve",
+			Query: "Do you want to apply the remaining approved changes?",
+			Description: fmt.Sprintf(
+				"The following approved changes depend on the changes that were\n"+
+					"skipped, and so are skipped too:\n\n  %s\n\n"+
+					"Only 'yes' will be accepted to approve.",
+				strings.Join(dependents, "\n  ")),
+		})
+		if err != nil {
+			return nil, errwrap.Wrapf("Error asking for approval: {{err}}", err)
+		}
+		if v != "yes" {
+			return nil, errors.New("Apply cancelled.")
+		}
+	}
+
+	log.Printf("[INFO] backend/local: skipping changes that weren't approved: %s", strings.Join(skipped, ", "))
+	return residual, nil
+}
+
+// writeResidualPlan saves the given residual plan of the changes that
+// weren't approved, for applying to the given state once the approved
+// changes were applied.
+func (b *Local) writeResidualPlan(op *backend.Operation, residual *terraform.Plan, state *terraform.State) error {
+	residual.State = state.DeepCopy()
+	residual.Backend = op.PlanOutBackend
+	if residual.Backend != nil && residual.State != nil {
+		residual.State.Remote = nil
+	}
+
+	var snap planfile.ConfigSnapshot
+	if op.Module != nil && op.Module.Config() != nil && op.Module.Config().Dir != "" {
+		var err error
+		snap, err = planfile.SnapshotDir(op.Module.Config().Dir)
+		if err != nil {
+			return fmt.Errorf("Error reading configuration for residual plan file: %s", err)
+		}
+	}
+
+	log.Printf("[INFO] backend/local: writing residual plan to: %s", op.ResidualPlanOutPath)
+	if err := planfile.Create(op.ResidualPlanOutPath, snap, residual, format.NewPlan(residual).Changes()); err != nil {
+		return fmt.Errorf("Error writing residual plan file: %s", err)
+	}
+	return nil
+}
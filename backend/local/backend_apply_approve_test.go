@@ -0,0 +1,187 @@
+package local
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/command/format"
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/planfile"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
+)
+
+func TestLocal_applyApproveBy(t *testing.T) {
+	b := TestLocal(t)
+	b.CLI = cli.NewMockUi()
+	p := testCheckpointProvider(t, b)
+	p.errored = true
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/apply-approve")
+	defer modCleanup()
+
+	var queries []string
+	op := testOperationApply()
+	op.Module = mod
+	op.ApproveBy = backend.ApproveByResource
+	op.ResidualPlanOutPath = filepath.Join(filepath.Dir(b.StatePath), "residual.tfplan")
+	op.UIOut = new(terraform.MockUIOutput)
+	op.UIIn = &terraform.MockUIInput{
+		InputFn: func(opts *terraform.InputOpts) (string, error) {
+			queries = append(queries, opts.Query)
+			if strings.Contains(opts.Query, "test_instance.foo") {
+				return "no", nil
+			}
+			return "yes", nil
+		},
+	}
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", run.Err)
+	}
+
+	// Each resource is asked about, and since test_instance.bar depends on
+	// the skipped test_instance.foo, the remaining changes are confirmed.
+	expected := []string{
+		"Do you want to apply the changes of test_instance.bar?",
+		"Do you want to apply the changes of test_instance.baz?",
+		"Do you want to apply the changes of test_instance.foo?",
+		"Do you want to apply the remaining approved changes?",
+	}
+	if strings.Join(queries, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("wrong queries:\n%s", strings.Join(queries, "\n"))
+	}
+	if actual := strings.Join(p.applied, ","); actual != "test_instance.baz" {
+		t.Fatalf("bad: %s", actual)
+	}
+	checkState(t, b.StateOutPath, `
+test_instance.baz:
+  ID = foo
+  provider = provider.test
+	`)
+
+	// The skipped changes are applied with the residual plan.
+	pr, err := planfile.Open(op.ResidualPlanOutPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	plan, err := pr.ReadPlan()
+	pr.Close()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var addrs []string
+	for _, r := range format.NewPlan(plan).Resources {
+		addrs = append(addrs, r.Addr.String())
+	}
+	if actual := strings.Join(addrs, ","); actual != "test_instance.bar,test_instance.foo" {
+		t.Fatalf("wrong residual changes: %s", actual)
+	}
+
+	p.applied = nil
+	op = testOperationApply()
+	op.Plan = plan
+	run, err = b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", run.Err)
+	}
+	if actual := strings.Join(p.applied, ","); actual != "test_instance.bar,test_instance.foo" {
+		t.Fatalf("bad: %s", actual)
+	}
+	checkState(t, b.StateOutPath, `
+test_instance.bar:
+  ID = foo
+  provider = provider.test
+
+  Dependencies:
+    test_instance.foo
+test_instance.baz:
+  ID = foo
+  provider = provider.test
+test_instance.foo:
+  ID = foo
+  provider = provider.test
+	`)
+}
+
+func TestLocal_applyApproveByNone(t *testing.T) {
+	b := TestLocal(t)
+	b.CLI = cli.NewMockUi()
+	p := testCheckpointProvider(t, b)
+	p.errored = true
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/apply-approve")
+	defer modCleanup()
+
+	op := testOperationApply()
+	op.Module = mod
+	op.ApproveBy = backend.ApproveByAction
+	op.UIOut = new(terraform.MockUIOutput)
+	op.UIIn = &terraform.MockUIInput{InputReturnString: "no"}
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err == nil || !strings.Contains(run.Err.Error(), "None of the changes were approved") {
+		t.Fatalf("bad: %v", run.Err)
+	}
+	if len(p.applied) > 0 {
+		t.Fatalf("bad: %v", p.applied)
+	}
+}
+
+func TestApprovalGroups(t *testing.T) {
+	addr := func(s string) *terraform.ResourceAddress {
+		a, err := terraform.ParseResourceAddress(s)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		return a
+	}
+	changes := []*format.InstanceDiff{
+		{Addr: addr("test_instance.foo"), Action: terraform.DiffCreate},
+		{Addr: addr("module.child.test_instance.bar[0]"), Action: terraform.DiffDestroy},
+		{Addr: addr("module.child.test_instance.bar[1]"), Action: terraform.DiffCreate},
+		{Addr: addr("test_instance.imported"), Action: terraform.DiffRefresh, Importing: true},
+		{Addr: addr("test_instance.moved"), Action: terraform.DiffNone},
+	}
+
+	cases := map[string][]string{
+		backend.ApproveByResource: {
+			"the changes of module.child.test_instance.bar[0]",
+			"the changes of module.child.test_instance.bar[1]",
+			"the changes of test_instance.foo",
+		},
+		backend.ApproveByModule: {
+			"the changes of module.child",
+			"the changes of the root module",
+		},
+		backend.ApproveByAction: {
+			"the creates",
+			"the destroys",
+		},
+	}
+	for by, expected := range cases {
+		var names []string
+		for _, g := range approvalGroups(changes, by) {
+			names = append(names, g.Name)
+		}
+		if strings.Join(names, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("wrong groups by %s:\n%s", by, strings.Join(names, "\n"))
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package local
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/command/format"
+	"github.com/hashicorp/terraform/diffs"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// applyCheckpointVersion is the version of the apply checkpoint format.
+const applyCheckpointVersion = 1
+
+// applyCheckpoint records the changes of an apply that failed, and which
+// of them completed, so that the apply can be resumed with the -resume
+// flag without planning every resource again.
+type applyCheckpoint struct {
+	Version   int    `json:"version"`
+	Workspace string `json:"workspace"`
+	Destroy   bool   `json:"destroy,omitempty"`
+
+	// Lineage and Serial identify the state that the failed apply left,
+	// which must not have changed when the apply is resumed.
+	Lineage string `json:"lineage"`
+	Serial  int64  `json:"serial"`
+
+	Changes []*applyCheckpointChange `json:"changes"`
+}
+
+// applyCheckpointChange is a change of the plan of a failed apply.
+//
+// The fingerprint identifies what the change alters without recording the
+// values themselves, so that the checkpoint holds no sensitive values.
+type applyCheckpointChange struct {
+	Address     string       `json:"address"`
+	Action      diffs.Action `json:"action"`
+	Fingerprint string       `json:"fingerprint"`
+	Completed   bool         `json:"completed"`
+}
+
+// newApplyCheckpoint returns the checkpoint of the given planned changes,
+// with the changes that the hook saw completing marked as completed, for an
+// apply that left the given state.
+func newApplyCheckpoint(
+	workspace string,
+	destroy bool,
+	changes diffs.ChangeSet,
+	hook *checkpointHook,
+	state *terraform.State) *applyCheckpoint {
+	cp := &applyCheckpoint{
+		Version:   applyCheckpointVersion,
+		Workspace: workspace,
+		Destroy:   destroy,
+	}
+	if state != nil {
+		cp.Lineage = state.Lineage
+		cp.Serial = state.Serial
+	}
+
+	for _, addr := range changes.Keys() {
+		c := changes[addr]
+
+		// Data sources are read again whenever they are needed.
+		if c.Action == diffs.Read {
+			continue
+		}
+
+		cp.Changes = append(cp.Changes, &applyCheckpointChange{
+			Address:     addr,
+			Action:      c.Action,
+			Fingerprint: c.ChangedFingerprint(),
+			Completed:   hook.completed(addr, c.Action),
+		})
+	}
+
+	return cp
+}
+
+func planChanges(plan *terraform.Plan) diffs.ChangeSet {
+	return format.NewPlan(plan).Changes().OmitNoOp()
+}
+
+// Targets returns the addresses of the resources with changes that didn't
+// complete, for targeting the plan that resumes the apply.
+func (cp *applyCheckpoint) Targets() []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, c := range cp.Changes {
+		addr := strings.TrimSuffix(c.Address, " (deposed)")
+		if c.Completed || seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		result = append(result, addr)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Pending returns the changes that didn't complete, in order of address.
+func (cp *applyCheckpoint) Pending() []*applyCheckpointChange {
+	var result []*applyCheckpointChange
+	for _, c := range cp.Changes {
+		if !c.Completed {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// Validate checks that the given state is the one that the failed apply
+// left and that the given changes, planned to resume the apply, are the
+// changes that didn't complete.
+//
+// A change may differ from the recorded one only where the failed apply
+// left it partly done: a replacement whose destroy completed is now a
+// create, an object whose create or update failed is now replaced because
+// it's tainted, and a deposed object may remain to be destroyed.
+func (cp *applyCheckpoint) Validate(state *terraform.State, changes diffs.ChangeSet) error {
+	var lineage string
+	var serial int64
+	if state != nil {
+		lineage = state.Lineage
+		serial = state.Serial
+	}
+	if lineage != cp.Lineage || serial != cp.Serial {
+		return fmt.Errorf(
+			"The state has changed since the apply failed (lineage %q serial %d, "+
+				"now lineage %q serial %d), so the apply can't be resumed. "+
+				"Run apply again without -resume to plan all changes.",
+			cp.Lineage, cp.Serial, lineage, serial)
+	}
+
+	byAddr := make(map[string]*applyCheckpointChange, len(cp.Changes))
+	for _, c := range cp.Changes {
+		byAddr[c.Address] = c
+	}
+
+	var problems []string
+	for _, addr := range changes.Keys() {
+		c := changes[addr]
+		if c.Action == diffs.Read {
+			continue
+		}
+
+		base := strings.TrimSuffix(addr, " (deposed)")
+		recorded, ok := byAddr[addr]
+		if !ok && base != addr {
+			recorded, ok = byAddr[base]
+		}
+
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf(
+				"%s has a %s change, which wasn't planned by the failed apply", addr, actionName(c.Action)))
+		case base != addr:
+			// The deposed objects of create_before_destroy replacements
+			// are destroyed after the replacements are created.
+		case recorded.Completed:
+			problems = append(problems, fmt.Sprintf(
+				"%s has a %s change, but its change completed", addr, actionName(c.Action)))
+		case c.ChangedFingerprint() == recorded.Fingerprint:
+		case recorded.Action == diffs.Replace && c.Action == diffs.Create:
+		case recorded.Action != diffs.Delete && c.Action == diffs.Replace &&
+			c.ReplaceReason == diffs.ReplaceBecauseTainted:
+		default:
+			problems = append(problems, fmt.Sprintf(
+				"%s has a %s change that differs from the planned %s change",
+				addr, actionName(c.Action), actionName(recorded.Action)))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf(
+			"The remaining changes of the failed apply are no longer applicable:\n\n"+
+				"  %s\n\n"+
+				"Run apply again without -resume to plan all changes.",
+			strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+func actionName(a diffs.Action) string {
+	js, err := a.MarshalJSON()
+	if err != nil {
+		return a.String()
+	}
+	return strings.Trim(string(js), `"`)
+}
+
+// readApplyCheckpoint reads the apply checkpoint, returning nil if there
+// is none.
+func (b *Local) readApplyCheckpoint() (*applyCheckpoint, error) {
+	if b.ApplyCheckpointPath == "" {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(b.ApplyCheckpointPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading apply checkpoint: %s", err)
+	}
+
+	var cp applyCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("Error reading apply checkpoint %s: %s", b.ApplyCheckpointPath, err)
+	}
+	if cp.Version != applyCheckpointVersion {
+		return nil, fmt.Errorf(
+			"The apply checkpoint %s has unsupported version %d",
+			b.ApplyCheckpointPath, cp.Version)
+	}
+
+	return &cp, nil
+}
+
+// writeApplyCheckpoint writes the given apply checkpoint, replacing any
+// previous one.
+func (b *Local) writeApplyCheckpoint(cp *applyCheckpoint) error {
+	if b.ApplyCheckpointPath == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(b.ApplyCheckpointPath), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(b.ApplyCheckpointPath, data, 0644)
+}
+
+// removeApplyCheckpoint removes the apply checkpoint, if any.
+func (b *Local) removeApplyCheckpoint() {
+	if b.ApplyCheckpointPath == "" {
+		return
+	}
+
+	if err := os.Remove(b.ApplyCheckpointPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("[WARN] backend/local: failed to remove apply checkpoint: %s", err)
+	}
+}
+
+// checkpointHook is a hook that records which resources were applied, to
+// find the changes that completed when an apply fails.
+type checkpointHook struct {
+	// applied and destroyed are keyed by the address of the resource
+	// instance, as in the changes of a plan.
+	applied   map[string]bool
+	destroyed map[string]bool
+
+	sync.Mutex
+	terraform.NilHook
+}
+
+func (h *checkpointHook) PostApply(
+	n *terraform.InstanceInfo,
+	s *terraform.InstanceState,
+	e error) (terraform.HookAction, error) {
+	if e != nil {
+		return terraform.HookActionContinue, nil
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	if h.applied == nil {
+		h.applied = make(map[string]bool)
+		h.destroyed = make(map[string]bool)
+	}
+
+	addr := n.ResourceAddress().Copy()
+	deposed := addr.InstanceType == terraform.TypeDeposed
+	addr.InstanceTypeSet = false
+	addr.InstanceType = terraform.TypeInvalid
+	key := addr.String()
+	if deposed {
+		key += " (deposed)"
+	}
+
+	if s != nil && s.ID != "" {
+		h.applied[key] = true
+	} else {
+		h.destroyed[key] = true
+	}
+
+	return terraform.HookActionContinue, nil
+}
+
+// completed returns true if the change with the given action to the
+// resource with the given address completed. A replacement is complete
+// once its new object is created.
+func (h *checkpointHook) completed(addr string, action diffs.Action) bool {
+	h.Lock()
+	defer h.Unlock()
+
+	if action == diffs.Delete || action == diffs.Forget {
+		return h.destroyed[addr]
+	}
+	return h.applied[addr]
+}
+
+// resumeCheckpoint returns the checkpoint of the failed apply that the
+// given operation resumes.
+func (b *Local) resumeCheckpoint(op *backend.Operation) (*applyCheckpoint, error) {
+	if op.Plan != nil {
+		return nil, errors.New("A saved plan can't be applied with -resume.")
+	}
+
+	cp, err := b.readApplyCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	if cp == nil {
+		return nil, errors.New(strings.TrimSpace(applyErrNoCheckpoint))
+	}
+
+	if cp.Workspace != op.Workspace {
+		return nil, fmt.Errorf(
+			"The failed apply was in the workspace %q, not %q. "+
+				"Select that workspace to resume it.",
+			cp.Workspace, op.Workspace)
+	}
+	if cp.Destroy != op.Destroy {
+		cmd := "apply"
+		if cp.Destroy {
+			cmd = "destroy"
+		}
+		return nil, fmt.Errorf(
+			"The failed apply was a %s. Run \"terraform %s -resume\" to resume it.",
+			cmd, cmd)
+	}
+
+	if len(cp.Targets()) == 0 {
+		b.removeApplyCheckpoint()
+		return nil, errors.New(
+			"All of the changes of the failed apply completed, so there is " +
+				"nothing to resume. Run apply again without -resume to plan " +
+				"any other changes.")
+	}
+
+	return cp, nil
+}
+
+const applyErrNoCheckpoint = `
+There is no failed apply to resume.
+
+An apply can only be resumed after it fails, and only until an apply
+succeeds. Run apply without -resume to plan and apply all changes.
+`
@@ -0,0 +1,234 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/diffs"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestLocal_applyResume(t *testing.T) {
+	b := TestLocal(t)
+	b.ApplyCheckpointPath = filepath.Join(filepath.Dir(b.StatePath), "apply-checkpoint.json")
+	p := testCheckpointProvider(t, b)
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/apply-error")
+	defer modCleanup()
+
+	op := testOperationApply()
+	op.Module = mod
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err == nil {
+		t.Fatal("should error")
+	}
+	if !strings.Contains(run.Err.Error(), "-resume") {
+		t.Fatalf("error doesn't mention -resume: %s", run.Err)
+	}
+
+	cp, err := b.readApplyCheckpoint()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if cp == nil {
+		t.Fatal("checkpoint should be written")
+	}
+	completed := make(map[string]bool)
+	for _, c := range cp.Changes {
+		completed[c.Address] = c.Completed
+	}
+	expected := map[string]bool{
+		"test_instance.bar": false,
+		"test_instance.foo": true,
+	}
+	if fmt.Sprint(completed) != fmt.Sprint(expected) {
+		t.Fatalf("bad: %#v", completed)
+	}
+
+	p.applied = nil
+	op = testOperationApply()
+	op.Module = mod
+	op.Resume = true
+
+	run, err = b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", run.Err)
+	}
+
+	if actual := strings.Join(p.applied, ","); actual != "test_instance.bar" {
+		t.Fatalf("bad: %s", actual)
+	}
+	if _, err := os.Stat(b.ApplyCheckpointPath); !os.IsNotExist(err) {
+		t.Fatalf("checkpoint should be removed: %v", err)
+	}
+
+	checkState(t, b.StateOutPath, `
+test_instance.bar:
+  ID = foo
+  provider = provider.test
+test_instance.foo:
+  ID = foo
+  provider = provider.test
+	`)
+}
+
+func TestLocal_applyResumeStateChanged(t *testing.T) {
+	b := TestLocal(t)
+	b.ApplyCheckpointPath = filepath.Join(filepath.Dir(b.StatePath), "apply-checkpoint.json")
+	p := testCheckpointProvider(t, b)
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/apply-error")
+	defer modCleanup()
+
+	op := testOperationApply()
+	op.Module = mod
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err == nil {
+		t.Fatal("should error")
+	}
+
+	// Change the state as another apply would.
+	f, err := os.Open(b.StatePath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	state, err := terraform.ReadState(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	state.Serial++
+	terraform.TestStateFile(t, b.StatePath, state)
+
+	p.applied = nil
+	op = testOperationApply()
+	op.Module = mod
+	op.Resume = true
+
+	run, err = b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err == nil {
+		t.Fatal("should error")
+	}
+	if !strings.Contains(run.Err.Error(), "state has changed") {
+		t.Fatalf("bad: %s", run.Err)
+	}
+	if len(p.applied) > 0 {
+		t.Fatalf("nothing should be applied: %v", p.applied)
+	}
+}
+
+func TestLocal_applyResumeNoCheckpoint(t *testing.T) {
+	b := TestLocal(t)
+	b.ApplyCheckpointPath = filepath.Join(filepath.Dir(b.StatePath), "apply-checkpoint.json")
+	p := TestLocalProvider(t, b, "test")
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/apply")
+	defer modCleanup()
+
+	op := testOperationApply()
+	op.Module = mod
+	op.Resume = true
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err == nil {
+		t.Fatal("should error")
+	}
+	if !strings.Contains(run.Err.Error(), "no failed apply") {
+		t.Fatalf("bad: %s", run.Err)
+	}
+	if p.ApplyCalled {
+		t.Fatal("apply should not be called")
+	}
+}
+
+// checkpointTestProvider is a provider whose first apply of
+// test_instance.bar fails, and which records the resources it applies.
+type checkpointTestProvider struct {
+	*terraform.MockResourceProvider
+
+	sync.Mutex
+	errored bool
+	applied []string
+}
+
+func testCheckpointProvider(t *testing.T, b *Local) *checkpointTestProvider {
+	p := &checkpointTestProvider{MockResourceProvider: TestLocalProvider(t, b, "test")}
+	p.ApplyFn = func(
+		info *terraform.InstanceInfo,
+		s *terraform.InstanceState,
+		d *terraform.InstanceDiff) (*terraform.InstanceState, error) {
+		p.Lock()
+		defer p.Unlock()
+
+		if !p.errored && info.Id == "test_instance.bar" {
+			p.errored = true
+			return nil, fmt.Errorf("error")
+		}
+
+		p.applied = append(p.applied, info.Id)
+		sort.Strings(p.applied)
+		return &terraform.InstanceState{ID: "foo"}, nil
+	}
+	p.DiffFn = func(
+		info *terraform.InstanceInfo,
+		s *terraform.InstanceState,
+		c *terraform.ResourceConfig) (*terraform.InstanceDiff, error) {
+		if s != nil && s.ID != "" {
+			return nil, nil
+		}
+		return &terraform.InstanceDiff{
+			Attributes: map[string]*terraform.ResourceAttrDiff{
+				"ami": &terraform.ResourceAttrDiff{
+					New: "bar",
+				},
+			},
+		}, nil
+	}
+	return p
+}
+
+func TestCheckpointHook_deposed(t *testing.T) {
+	h := &checkpointHook{}
+	n := &terraform.InstanceInfo{
+		Id:         "test_instance.foo (deposed #0)",
+		ModulePath: []string{"root"},
+		Type:       "test_instance",
+	}
+	h.PostApply(n, nil, nil)
+
+	if !h.completed("test_instance.foo (deposed)", diffs.Delete) {
+		t.Fatal("destroy of the deposed object should be completed")
+	}
+	if h.completed("test_instance.foo", diffs.Delete) {
+		t.Fatal("destroy of the current object shouldn't be completed")
+	}
+}
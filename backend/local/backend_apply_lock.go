@@ -0,0 +1,107 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/command/clistate"
+	"github.com/hashicorp/terraform/diffs"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// lockResources locks the resources that the given changes touch, for an
+// apply with resource locking, and returns a state that only writes those
+// resources to the given state, along with the function that releases the
+// lock.
+//
+// The plan was created from readState without holding a lock, so another
+// operation may have changed the resources before they were locked. In
+// that case the lock is released and an error is returned, since the plan
+// is out of date.
+func (b *Local) lockResources(
+	ctx context.Context,
+	op *backend.Operation,
+	s state.State,
+	readState *terraform.State,
+	changes diffs.ChangeSet) (state.State, func() error, error) {
+	addrs, err := changedResources(changes)
+	if err != nil {
+		return nil, nil, err
+	}
+	locker := s.(state.ResourceLocker)
+
+	lockCtx, cancel := context.WithTimeout(ctx, op.StateLockTimeout)
+	defer cancel()
+
+	lockInfo := state.NewLockInfo()
+	lockInfo.Operation = op.Type.String()
+	lockInfo.Info = "resources: " + strings.Join(addrs, ", ")
+	lockID, err := clistate.LockResources(lockCtx, locker, lockInfo, addrs, b.CLI, b.Colorize())
+	if err != nil {
+		return nil, nil, errwrap.Wrapf("Error locking resources: {{err}}", err)
+	}
+	unlock := func() error {
+		return clistate.UnlockResources(locker, lockID, b.CLI, b.Colorize())
+	}
+
+	if err := s.RefreshState(); err != nil {
+		unlock()
+		return nil, nil, errwrap.Wrapf("Error refreshing state: {{err}}", err)
+	}
+	current := s.State()
+	if current != nil && readState != nil && !current.SameLineage(readState) {
+		unlock()
+		return nil, nil, errors.New(strings.TrimSpace(errResourcesChanged))
+	}
+	equal, err := state.ResourcesEqual(readState, current, addrs)
+	if err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	if !equal {
+		unlock()
+		return nil, nil, errors.New(strings.TrimSpace(errResourcesChanged))
+	}
+
+	return &state.ResourceLockedState{
+		Inner:  s,
+		LockID: lockID,
+		Base:   readState,
+	}, unlock, nil
+}
+
+// changedResources returns the sorted addresses of the resources that the
+// given changes touch. Whole resources are locked, rather than instances,
+// so that changes of count are covered.
+func changedResources(changes diffs.ChangeSet) ([]string, error) {
+	seen := make(map[string]bool)
+	var result []string
+	for key := range changes {
+		addr, err := terraform.ParseResourceAddress(strings.TrimSuffix(key, " (deposed)"))
+		if err != nil {
+			return nil, err
+		}
+		addr.Index = -1
+		addr.InstanceTypeSet = false
+		addr.InstanceType = terraform.TypeInvalid
+
+		resource := addr.String()
+		if seen[resource] {
+			continue
+		}
+		seen[resource] = true
+		result = append(result, resource)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+const errResourcesChanged = `
+The resources to change were changed by another operation after they were
+planned. Please run the apply again to plan the changes again.
+`
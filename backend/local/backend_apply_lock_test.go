@@ -0,0 +1,187 @@
+package local
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestLocal_applyLockResources(t *testing.T) {
+	s := testResourceLockState(t)
+
+	// Another operation holds a lock of a resource that this apply doesn't
+	// change.
+	otherID, err := s.LockResources(state.NewLockInfo(), []string{"test_instance.other"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	b := TestLocal(t)
+	b.Backend = &backendWithResourceLocking{state: s}
+	p := TestLocalProvider(t, b, "test")
+	p.ApplyReturn = &terraform.InstanceState{ID: "yes"}
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/apply-lock-resources")
+	defer modCleanup()
+
+	op := testOperationApply()
+	op.Module = mod
+	op.LockState = true
+	op.LockResources = true
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", run.Err)
+	}
+
+	// The other operation writes its resource after this apply, which
+	// doesn't undo this apply's changes.
+	other := s.State()
+	other.RootModule().Resources["test_instance.other"].Primary.ID = "changed"
+	if err := s.WriteResources(otherID, nil, other); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := strings.TrimSpace(s.State().String())
+	expected := strings.TrimSpace(`
+test_instance.foo:
+  ID = yes
+  provider = provider.test
+test_instance.other:
+  ID = changed
+	`)
+	if actual != expected {
+		t.Fatalf("wrong state\ngot:\n%s\n\nwant:\n%s", actual, expected)
+	}
+
+	// The apply released its lock.
+	id, err := s.LockResources(state.NewLockInfo(), []string{"test_instance.foo"})
+	if err != nil {
+		t.Fatalf("test_instance.foo is still locked: %s", err)
+	}
+	s.UnlockResources(id)
+}
+
+func TestLocal_applyLockResourcesConflict(t *testing.T) {
+	s := testResourceLockState(t)
+	if _, err := s.LockResources(state.NewLockInfo(), []string{"test_instance.foo"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	b := TestLocal(t)
+	b.Backend = &backendWithResourceLocking{state: s}
+	p := TestLocalProvider(t, b, "test")
+	p.ApplyReturn = &terraform.InstanceState{ID: "yes"}
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/apply-lock-resources")
+	defer modCleanup()
+
+	op := testOperationApply()
+	op.Module = mod
+	op.LockState = true
+	op.LockResources = true
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err == nil {
+		t.Fatal("apply succeeded; want error")
+	}
+	if !strings.Contains(run.Err.Error(), "test_instance.foo is locked") {
+		t.Fatalf("wrong error: %s", run.Err)
+	}
+	if p.ApplyCalled {
+		t.Fatal("apply should not be called")
+	}
+}
+
+func TestLocal_applyLockResourcesChanged(t *testing.T) {
+	s := testResourceLockState(t)
+
+	b := TestLocal(t)
+	b.Backend = &backendWithResourceLocking{state: s}
+	p := TestLocalProvider(t, b, "test")
+	p.ApplyReturn = &terraform.InstanceState{ID: "yes"}
+
+	// Another operation creates test_instance.foo while it's planned.
+	p.DiffFn = func(
+		info *terraform.InstanceInfo,
+		is *terraform.InstanceState,
+		c *terraform.ResourceConfig) (*terraform.InstanceDiff, error) {
+		if info.Id != "test_instance.foo" {
+			return &terraform.InstanceDiff{}, nil
+		}
+		changed := s.State()
+		changed.RootModule().Resources["test_instance.foo"] = &terraform.ResourceState{
+			Type:    "test_instance",
+			Primary: &terraform.InstanceState{ID: "other"},
+		}
+		if err := s.WriteState(changed); err != nil {
+			return nil, err
+		}
+		return &terraform.InstanceDiff{
+			Attributes: map[string]*terraform.ResourceAttrDiff{
+				"ami": &terraform.ResourceAttrDiff{New: "bar"},
+			},
+		}, nil
+	}
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/apply-lock-resources")
+	defer modCleanup()
+
+	op := testOperationApply()
+	op.Module = mod
+	op.LockState = true
+	op.LockResources = true
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err == nil {
+		t.Fatal("apply succeeded; want error")
+	}
+	if !strings.Contains(run.Err.Error(), "changed by another operation") {
+		t.Fatalf("wrong error: %s", run.Err)
+	}
+	if p.ApplyCalled {
+		t.Fatal("apply should not be called")
+	}
+}
+
+// testResourceLockState returns an in-memory state, which supports
+// resource locking, with test_instance.other, which the apply fixture
+// doesn't change.
+func testResourceLockState(t *testing.T) *state.InmemState {
+	s := terraform.NewState()
+	s.RootModule().Resources["test_instance.other"] = &terraform.ResourceState{
+		Type:    "test_instance",
+		Primary: &terraform.InstanceState{ID: "other"},
+	}
+
+	result := new(state.InmemState)
+	if err := result.WriteState(s); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return result
+}
+
+type backendWithResourceLocking struct {
+	Local
+	state *state.InmemState
+}
+
+func (b *backendWithResourceLocking) State(name string) (state.State, error) {
+	return b.state, nil
+}
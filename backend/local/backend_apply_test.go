@@ -57,6 +57,95 @@ test_instance.foo:
 	`)
 }
 
+func TestLocal_applyDryRun(t *testing.T) {
+	b := TestLocal(t)
+	p := TestLocalProvider(t, b, "test")
+
+	p.ApplyReturn = &terraform.InstanceState{ID: "yes"}
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/apply")
+	defer modCleanup()
+
+	op := testOperationApply()
+	op.Module = mod
+	op.DryRun = true
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", run.Err)
+	}
+
+	if !p.ValidateApplyCalled {
+		t.Fatal("validate apply should be called")
+	}
+	if p.ApplyCalled {
+		t.Fatal("apply should not be called")
+	}
+	if _, err := os.Stat(b.StateOutPath); !os.IsNotExist(err) {
+		t.Fatalf("state should not be written: %v", err)
+	}
+
+	// Errors found by the provider fail the operation.
+	p.ValidateApplyReturnErrors = []error{errors.New("quota exceeded")}
+	run, err = b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err == nil || !strings.Contains(run.Err.Error(), "quota exceeded") {
+		t.Fatalf("bad: %v", run.Err)
+	}
+	if p.ApplyCalled {
+		t.Fatal("apply should not be called")
+	}
+}
+
+func TestLocal_applyRefreshOnlyPlan(t *testing.T) {
+	b := TestLocal(t)
+	p := TestLocalProvider(t, b, "test")
+	terraform.TestStateFile(t, b.StatePath, testPlanState())
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/apply")
+	defer modCleanup()
+
+	refreshed := testPlanState()
+	refreshed.RootModule().Resources["test_instance.foo"].Primary.ID = "baz"
+
+	op := testOperationApply()
+	op.Module = mod
+	op.Plan = &terraform.Plan{
+		Diff:        &terraform.Diff{},
+		Module:      mod,
+		State:       refreshed,
+		RefreshOnly: true,
+	}
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", run.Err)
+	}
+
+	if p.DiffCalled {
+		t.Fatal("diff should not be called")
+	}
+	if p.ApplyCalled {
+		t.Fatal("apply should not be called")
+	}
+
+	checkState(t, b.StateOutPath, `
+test_instance.foo:
+  ID = baz
+	`)
+}
+
 func TestLocal_applyEmptyDir(t *testing.T) {
 	b := TestLocal(t)
 	p := TestLocalProvider(t, b, "test")
@@ -44,6 +44,9 @@ func (b *Local) context(op *backend.Operation) (*terraform.Context, state.State,
 	opts.Destroy = op.Destroy
 	opts.Module = op.Module
 	opts.Targets = op.Targets
+	opts.TargetModules = op.TargetModules
+	opts.ForceReplace = op.ForceReplace
+	opts.Explain = op.Explain
 	opts.UIInput = op.UIIn
 	if op.Variables != nil {
 		opts.Variables = op.Variables
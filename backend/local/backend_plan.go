@@ -5,7 +5,6 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
 	"strings"
 
 	"github.com/hashicorp/errwrap"
@@ -14,6 +13,7 @@ import (
 	"github.com/hashicorp/terraform/command/clistate"
 	"github.com/hashicorp/terraform/command/format"
 	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/planfile"
 	"github.com/hashicorp/terraform/state"
 	"github.com/hashicorp/terraform/terraform"
 )
@@ -39,6 +39,11 @@ func (b *Local) opPlan(
 		return
 	}
 
+	if op.PlanOutPath != "" && b.StateEncryption != nil {
+		runningOp.Err = fmt.Errorf(strings.TrimSpace(planErrEncryptedPlanFile))
+		return
+	}
+
 	// If we have a nil module at this point, then set it to an empty tree
 	// to avoid any potential crashes.
 	if op.Module == nil {
@@ -83,8 +88,15 @@ func (b *Local) opPlan(
 	// Setup the state
 	runningOp.State = tfCtx.State()
 
-	// If we're refreshing before plan, perform that
-	if op.PlanRefresh {
+	refreshOnly := op.PlanMode == backend.RefreshOnlyMode || (op.Plan != nil && op.Plan.RefreshOnly)
+	if refreshOnly && op.Destroy {
+		runningOp.Err = fmt.Errorf("A refresh-only plan cannot also be a destroy plan.")
+		return
+	}
+
+	// If we're refreshing before plan, perform that. A refresh-only plan
+	// always refreshes as part of planning, so it is skipped here.
+	if op.PlanRefresh && !refreshOnly {
 		log.Printf("[INFO] backend/local: plan calling Refresh")
 
 		if b.CLI != nil {
@@ -101,12 +113,21 @@ func (b *Local) opPlan(
 		}
 	}
 
+	if refreshOnly && b.CLI != nil {
+		b.CLI.Output(b.Colorize().Color(strings.TrimSpace(planRefreshingOnly) + "\n"))
+	}
+
 	// Perform the plan in a goroutine so we can be interrupted
 	var plan *terraform.Plan
 	var planErr error
 	doneCh := make(chan struct{})
 	go func() {
 		defer close(doneCh)
+		if refreshOnly {
+			log.Printf("[INFO] backend/local: plan calling PlanRefreshOnly")
+			plan, planErr = tfCtx.PlanRefreshOnly()
+			return
+		}
 		log.Printf("[INFO] backend/local: plan calling Plan")
 		plan, planErr = tfCtx.Plan()
 	}()
@@ -129,8 +150,19 @@ func (b *Local) opPlan(
 		runningOp.Err = errwrap.Wrapf("Error running plan: {{err}}", planErr)
 		return
 	}
+	runningOp.Explanations = tfCtx.Explanations()
+	// A refresh-only plan has an empty diff, so its changes come from
+	// comparing the refreshed state with the state we started from.
+	var dispPlan *format.Plan
+	if refreshOnly {
+		dispPlan = format.NewRefreshOnlyPlan(runningOp.State, plan)
+	} else {
+		dispPlan = b.annotatePlan(op, runningOp, plan, format.NewPlan(plan))
+	}
+
 	// Record state
-	runningOp.PlanEmpty = plan.Diff.Empty()
+	runningOp.PlanEmpty = dispPlan.Empty()
+	runningOp.Plan = plan
 
 	// Save the plan to disk
 	if path := op.PlanOutPath; path != "" {
@@ -143,28 +175,46 @@ func (b *Local) opPlan(
 			plan.State.Remote = nil
 		}
 
-		log.Printf("[INFO] backend/local: writing plan output to: %s", path)
-		f, err := os.Create(path)
-		if err == nil {
-			err = terraform.WritePlan(plan, f)
+		// Include a snapshot of the root module configuration, so that the
+		// plan file records what the plan was created from.
+		var snap planfile.ConfigSnapshot
+		if op.Module != nil && op.Module.Config() != nil && op.Module.Config().Dir != "" {
+			snap, err = planfile.SnapshotDir(op.Module.Config().Dir)
+			if err != nil {
+				runningOp.Err = fmt.Errorf("Error reading configuration for plan file: %s", err)
+				return
+			}
 		}
-		f.Close()
+
+		log.Printf("[INFO] backend/local: writing plan output to: %s", path)
+		err = planfile.Create(path, snap, plan, dispPlan.Changes())
 		if err != nil {
 			runningOp.Err = fmt.Errorf("Error writing plan file: %s", err)
 			return
 		}
 	}
 
-	// Perform some output tasks if we have a CLI to output to.
-	if b.CLI != nil {
-		dispPlan := format.NewPlan(plan)
-		if dispPlan.Empty() {
+	// Perform some output tasks if we have a CLI to output to, unless the
+	// caller will render the plan itself.
+	if b.CLI != nil && !op.PlanJSON {
+		for _, diag := range dispPlan.Warnings() {
+			b.CLI.Warn(format.Diagnostic(diag, b.Colorize(), 72))
+		}
+		switch {
+		case dispPlan.Empty() && refreshOnly:
+			b.CLI.Output("\n" + b.Colorize().Color(strings.TrimSpace(planRefreshOnlyNoChanges)))
+			return
+		case dispPlan.Empty():
 			b.CLI.Output("\n" + b.Colorize().Color(strings.TrimSpace(planNoChanges)))
+			b.renderExplanations(op, runningOp.Explanations)
 			return
+		case refreshOnly:
+			b.renderRefreshOnlyPlan(dispPlan)
+		default:
+			b.renderPlan(dispPlan)
+			b.renderExplanations(op, runningOp.Explanations)
 		}
 
-		b.renderPlan(dispPlan)
-
 		// Give the user some next-steps, unless we're running in an automation
 		// tool which is presumed to provide its own UI for further actions.
 		if !b.RunningInAutomation {
@@ -186,7 +236,22 @@ func (b *Local) opPlan(
 	}
 }
 
+// renderExplanations renders the given explanations of the planned values
+// of the resource given by op.Explain, if it's set.
+func (b *Local) renderExplanations(op *backend.Operation, expls []*terraform.Explanation) {
+	if op.Explain == "" {
+		return
+	}
+	b.CLI.Output("\n------------------------------------------------------------------------\n")
+	if len(expls) == 0 {
+		b.CLI.Output(fmt.Sprintf("No instances of %s were planned, so there is nothing to explain.", op.Explain))
+		return
+	}
+	b.CLI.Output(format.Explanations(expls, b.Colorize()))
+}
+
 func (b *Local) renderPlan(dispPlan *format.Plan) {
+	dispPlan.Redaction = b.Redaction
 
 	headerBuf := &bytes.Buffer{}
 	fmt.Fprintf(headerBuf, "\n%s\n", strings.TrimSpace(planHeaderIntro))
@@ -214,10 +279,32 @@ func (b *Local) renderPlan(dispPlan *format.Plan) {
 	b.CLI.Output(dispPlan.Format(b.Colorize()))
 
 	stats := dispPlan.Stats()
+	var extraStr string
+	if stats.ToMove > 0 {
+		extraStr += fmt.Sprintf("%d to move, ", stats.ToMove)
+	}
+	if stats.ToForget > 0 {
+		extraStr += fmt.Sprintf("%d to forget, ", stats.ToForget)
+	}
+	if stats.ToImport > 0 {
+		extraStr += fmt.Sprintf("%d to import, ", stats.ToImport)
+	}
 	b.CLI.Output(b.Colorize().Color(fmt.Sprintf(
 		"[reset][bold]Plan:[reset] "+
-			"%d to add, %d to change, %d to destroy.",
-		stats.ToAdd, stats.ToChange, stats.ToDestroy,
+			"%s%d to add, %d to change, %d to destroy.",
+		extraStr, stats.ToAdd, stats.ToChange, stats.ToDestroy,
+	)))
+}
+
+func (b *Local) renderRefreshOnlyPlan(dispPlan *format.Plan) {
+	b.CLI.Output(b.Colorize().Color("\n" + strings.TrimSpace(planRefreshOnlyIntro) + "\n"))
+
+	b.CLI.Output(dispPlan.Format(b.Colorize()))
+
+	b.CLI.Output(b.Colorize().Color(fmt.Sprintf(
+		"[reset][bold]Plan:[reset] "+
+			"%d to update in state. No infrastructure will be changed.",
+		len(dispPlan.Resources),
 	)))
 }
 
@@ -231,6 +318,15 @@ flag or create a single empty configuration file. Otherwise, please create
 a Terraform configuration file in the path being executed and try again.
 `
 
+const planErrEncryptedPlanFile = `
+Plan files can't be written while state encryption is configured.
+
+A plan file holds the prior state and the planned values of each resource,
+and plan files are not encrypted, so writing one would store unencrypted
+what the state encryption protects. Apply the configuration without saving
+the plan instead.
+`
+
 const planHeaderIntro = `
 An execution plan has been generated and is shown below.
 Resource actions are indicated with the following symbols:
@@ -262,3 +358,22 @@ const planRefreshing = `
 The refreshed state will be used to calculate this plan, but will not be
 persisted to local or remote state storage.
 `
+
+const planRefreshingOnly = `
+[reset][bold]Refreshing Terraform state in-memory to detect changes made outside of Terraform...[reset]
+The refreshed state will be persisted only if the plan is saved and applied.
+`
+
+const planRefreshOnlyIntro = `
+Terraform detected the following changes made outside of Terraform since the
+last time the state was updated. Applying this plan will update the state to
+match, without changing any infrastructure:
+`
+
+const planRefreshOnlyNoChanges = `
+[reset][bold][green]No changes. The state matches the remote objects.[reset][green]
+
+This means that Terraform did not detect any changes made outside of
+Terraform since the last time the state was updated. As a result, there is
+nothing to update in the state.
+`
@@ -10,6 +10,7 @@ import (
 
 	"github.com/hashicorp/terraform/backend"
 	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/planfile"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
 )
@@ -230,6 +231,97 @@ func TestLocal_planOutPathNoChange(t *testing.T) {
 	}
 }
 
+func TestLocal_planRefreshOnly(t *testing.T) {
+	b := TestLocal(t)
+	b.CLI = cli.NewMockUi()
+	p := TestLocalProvider(t, b, "test")
+	terraform.TestStateFile(t, b.StatePath, testPlanState())
+	p.RefreshFn = func(
+		info *terraform.InstanceInfo,
+		s *terraform.InstanceState) (*terraform.InstanceState, error) {
+		return &terraform.InstanceState{
+			ID: s.ID,
+			Attributes: map[string]string{
+				"id":  s.ID,
+				"ami": "ami-changed",
+			},
+		}, nil
+	}
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/plan")
+	defer modCleanup()
+
+	outDir := testTempDir(t)
+	defer os.RemoveAll(outDir)
+	planPath := filepath.Join(outDir, "plan.tfplan")
+
+	op := testOperationPlan()
+	op.Module = mod
+	op.PlanRefresh = true
+	op.PlanMode = backend.RefreshOnlyMode
+	op.PlanOutPath = planPath
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", run.Err)
+	}
+
+	if !p.RefreshCalled {
+		t.Fatal("refresh should be called")
+	}
+	if p.DiffCalled {
+		t.Fatal("diff should not be called")
+	}
+	if run.PlanEmpty {
+		t.Fatal("plan should not be empty")
+	}
+
+	plan := testReadPlan(t, planPath)
+	if !plan.RefreshOnly {
+		t.Fatal("plan should be refresh-only")
+	}
+	if !plan.Diff.Empty() {
+		t.Fatalf("plan should have an empty diff; got:\n%s", plan.Diff)
+	}
+
+	output := b.CLI.(*cli.MockUi).OutputWriter.String()
+	if !strings.Contains(output, "ami-changed") {
+		t.Fatalf("refreshed attribute missing from output:\n%s", output)
+	}
+
+	// The state must not be updated until the plan is applied.
+	checkState(t, b.StatePath, `
+test_instance.foo:
+  ID = bar
+	`)
+}
+
+func TestLocal_planRefreshOnlyDestroy(t *testing.T) {
+	b := TestLocal(t)
+	TestLocalProvider(t, b, "test")
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/plan")
+	defer modCleanup()
+
+	op := testOperationPlan()
+	op.Module = mod
+	op.Destroy = true
+	op.PlanMode = backend.RefreshOnlyMode
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err == nil {
+		t.Fatal("expected error")
+	}
+}
+
 // TestLocal_planScaleOutNoDupeCount tests a Refresh/Plan sequence when a
 // resource count is scaled out. The scaled out node needs to exist in the
 // graph and run through a plan-style sequence during the refresh phase, but
@@ -324,13 +416,7 @@ func testPlanState() *terraform.State {
 }
 
 func testReadPlan(t *testing.T, path string) *terraform.Plan {
-	f, err := os.Open(path)
-	if err != nil {
-		t.Fatalf("err: %s", err)
-	}
-	defer f.Close()
-
-	p, err := terraform.ReadPlan(f)
+	p, err := planfile.ReadPlan(path)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -0,0 +1,85 @@
+package local
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/command/format"
+	"github.com/hashicorp/terraform/policy"
+	"github.com/hashicorp/terraform/tfdiags"
+)
+
+// checkPolicy checks the given plan with the policy hooks and shows their
+// verdicts, returning an error if the plan must not be applied: when a
+// hook hard-fails, or when one soft-fails and the operation doesn't
+// override the policies.
+func (b *Local) checkPolicy(op *backend.Operation, dispPlan *format.Plan) error {
+	if len(b.PolicyHooks) == 0 {
+		return nil
+	}
+
+	js, err := dispPlan.JSON()
+	if err != nil {
+		return fmt.Errorf("Error serializing plan for policy hooks: %s", err)
+	}
+
+	results := policy.Check(b.PolicyHooks, js)
+	if b.CLI != nil {
+		for _, r := range results {
+			b.showPolicyResult(r)
+		}
+	}
+
+	switch results.Verdict() {
+	case policy.HardFail:
+		return errors.New(strings.TrimSpace(policyErrHardFail))
+	case policy.SoftFail:
+		if !op.PolicyOverride {
+			return errors.New(strings.TrimSpace(policyErrSoftFail))
+		}
+	}
+
+	return nil
+}
+
+func (b *Local) showPolicyResult(r *policy.Result) {
+	if r.Verdict == policy.Pass {
+		b.CLI.Output(b.Colorize().Color(fmt.Sprintf(
+			"[reset][bold][green]Policy %q passed.", r.Hook)))
+		return
+	}
+
+	diag := &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  fmt.Sprintf("Policy %q hard-failed", r.Hook),
+		Detail:   strings.Join(r.Messages, "\n"),
+	}
+	if r.Verdict == policy.SoftFail {
+		diag.Severity = hcl.DiagWarning
+		diag.Summary = fmt.Sprintf("Policy %q soft-failed", r.Hook)
+	}
+
+	var diags tfdiags.Diagnostics
+	diags = diags.Append(diag)
+	if diag.Severity == hcl.DiagWarning {
+		b.CLI.Warn(format.Diagnostic(diags[0], b.Colorize(), 72))
+	} else {
+		b.CLI.Error(format.Diagnostic(diags[0], b.Colorize(), 72))
+	}
+}
+
+const policyErrHardFail = `
+The plan hard-failed a policy hook, so it can't be applied.
+
+The plan must be changed to satisfy the policies shown above.
+`
+
+const policyErrSoftFail = `
+The plan soft-failed a policy hook, so it wasn't applied.
+
+To apply the plan anyway, run the apply again with the -policy-override
+flag.
+`
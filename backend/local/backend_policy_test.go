@@ -0,0 +1,92 @@
+package local
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/policy"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
+)
+
+func TestLocal_applyPolicy(t *testing.T) {
+	cases := map[string]struct {
+		Verdict  policy.Verdict
+		Override bool
+		Applied  bool
+	}{
+		"pass":               {policy.Pass, false, true},
+		"soft-fail":          {policy.SoftFail, false, false},
+		"soft-fail override": {policy.SoftFail, true, true},
+		"hard-fail":          {policy.HardFail, false, false},
+		"hard-fail override": {policy.HardFail, true, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b := TestLocal(t)
+			p := TestLocalProvider(t, b, "test")
+			p.ApplyReturn = &terraform.InstanceState{ID: "yes"}
+
+			ui := new(cli.MockUi)
+			b.CLI = ui
+
+			hook := &testPolicyHook{verdict: tc.Verdict}
+			b.PolicyHooks = []policy.Hook{hook}
+
+			mod, modCleanup := module.TestTree(t, "./test-fixtures/apply")
+			defer modCleanup()
+
+			op := testOperationApply()
+			op.Module = mod
+			op.PolicyOverride = tc.Override
+
+			run, err := b.Operation(context.Background(), op)
+			if err != nil {
+				t.Fatalf("bad: %s", err)
+			}
+			<-run.Done()
+
+			if !strings.Contains(string(hook.plan), `"address":"test_instance.foo"`) {
+				t.Fatalf("hook wasn't given the plan: %s", hook.plan)
+			}
+			if p.ApplyCalled != tc.Applied {
+				t.Fatalf("apply called %t; want %t", p.ApplyCalled, tc.Applied)
+			}
+			if tc.Applied && run.Err != nil {
+				t.Fatalf("err: %s", run.Err)
+			}
+			if !tc.Applied && run.Err == nil {
+				t.Fatal("succeeded; want error")
+			}
+
+			if tc.Verdict != policy.Pass {
+				output := ui.ErrorWriter.String() + ui.OutputWriter.String()
+				if !strings.Contains(output, "test_instance.foo is not allowed") {
+					t.Fatalf("policy message not shown: %s", output)
+				}
+			}
+		})
+	}
+}
+
+type testPolicyHook struct {
+	verdict policy.Verdict
+	plan    []byte
+}
+
+func (h *testPolicyHook) Name() string {
+	return "test"
+}
+
+func (h *testPolicyHook) Check(plan []byte) (*policy.Result, error) {
+	h.plan = plan
+
+	r := &policy.Result{Verdict: h.verdict}
+	if h.verdict != policy.Pass {
+		r.Messages = []string{"test_instance.foo is not allowed"}
+	}
+	return r, nil
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/hashicorp/terraform/backend"
 	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/encryption"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -230,6 +231,54 @@ func TestLocal_multiStateBackend(t *testing.T) {
 	}
 }
 
+func TestLocal_stateEncryption(t *testing.T) {
+	defer testTmpDir(t)()
+	b := &Local{StateEncryption: encryption.TestEncryption()}
+
+	s, err := b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.WriteState(state.TestStateInitial()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	raw, err := ioutil.ReadFile(DefaultStateFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), "encrypted_state") {
+		t.Fatalf("state file is not encrypted:\n%s", raw)
+	}
+}
+
+func TestLocal_stateHistory(t *testing.T) {
+	defer testTmpDir(t)()
+
+	for _, enabled := range []bool{false, true} {
+		b := &Local{StateHistory: enabled}
+		s, err := b.State(backend.DefaultStateName)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if err := s.WriteState(state.TestStateInitial()); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		_, err = os.Stat(DefaultStateFilename + DefaultHistoryExtension)
+		if enabled && err != nil {
+			t.Fatalf("history not stored with history enabled: %s", err)
+		}
+		if !enabled && !os.IsNotExist(err) {
+			t.Fatalf("history stored with history disabled: %v", err)
+		}
+		s.Unlock("")
+	}
+}
+
 // change into a tmp dir and return a deferable func to change back and cleanup
 func testTmpDir(t *testing.T) func() {
 	tmp, err := ioutil.TempDir("", "tf")
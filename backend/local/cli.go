@@ -8,10 +8,14 @@ import (
 func (b *Local) CLIInit(opts *backend.CLIOpts) error {
 	b.CLI = opts.CLI
 	b.CLIColor = opts.CLIColor
+	b.Redaction = opts.Redaction
 	b.ContextOpts = opts.ContextOpts
 	b.OpInput = opts.Input
 	b.OpValidation = opts.Validation
 	b.RunningInAutomation = opts.RunningInAutomation
+	b.PolicyHooks = opts.PolicyHooks
+	b.PlanAnalyzers = opts.PlanAnalyzers
+	b.ApplyCheckpointPath = opts.ApplyCheckpointPath
 
 	// Only configure state paths if we didn't do so via the configure func.
 	if b.StatePath == "" {
@@ -1,6 +1,7 @@
 package local
 
 import (
+	"log"
 	"sync"
 
 	"github.com/hashicorp/terraform/state"
@@ -14,6 +15,10 @@ type StateHook struct {
 	sync.Mutex
 
 	State state.State
+
+	// Persist, if set, persists the state after each update, so that the
+	// changes that completed are saved even if Terraform is terminated.
+	Persist bool
 }
 
 func (h *StateHook) PostStateUpdate(
@@ -26,6 +31,14 @@ func (h *StateHook) PostStateUpdate(
 		if err := h.State.WriteState(s); err != nil {
 			return terraform.HookActionHalt, err
 		}
+
+		// The state is persisted again once the operation ends, which
+		// reports any error that remains.
+		if h.Persist {
+			if err := h.State.PersistState(); err != nil {
+				log.Printf("[WARN] backend/local: failed to persist state update: %s", err)
+			}
+		}
 	}
 
 	// Continue forth
@@ -27,3 +27,31 @@ func TestStateHook(t *testing.T) {
 		t.Fatalf("bad state: %#v", is.State())
 	}
 }
+
+func TestStateHook_persist(t *testing.T) {
+	is := &persistCountState{}
+	var hook terraform.Hook = &StateHook{State: is, Persist: true}
+
+	s := state.TestStateInitial()
+	if _, err := hook.PostStateUpdate(s); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := hook.PostStateUpdate(s); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if is.persisted != 2 {
+		t.Fatalf("state persisted %d times, want 2", is.persisted)
+	}
+}
+
+// persistCountState is an in-memory state that counts how many times it
+// is persisted.
+type persistCountState struct {
+	state.InmemState
+	persisted int
+}
+
+func (s *persistCountState) PersistState() error {
+	s.persisted++
+	return nil
+}
@@ -0,0 +1,11 @@
+resource "test_instance" "foo" {
+    ami = "bar"
+}
+
+resource "test_instance" "bar" {
+    ami = "${test_instance.foo.id}"
+}
+
+resource "test_instance" "baz" {
+    ami = "bar"
+}
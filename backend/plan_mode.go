@@ -0,0 +1,19 @@
+package backend
+
+//go:generate stringer -type=PlanMode plan_mode.go
+
+// PlanMode is an enum used with Operation to select the kind of plan that
+// a plan or apply operation should create.
+type PlanMode uint
+
+const (
+	// NormalMode is the default mode, where the plan includes the changes
+	// needed to make the infrastructure match the configuration, or to
+	// destroy it if Operation.Destroy is set.
+	NormalMode PlanMode = iota
+
+	// RefreshOnlyMode creates a plan that changes no infrastructure.
+	// Instead, applying the plan only updates the state to match the
+	// remote objects, as they were found when refreshing during the plan.
+	RefreshOnlyMode
+)
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,6 +22,7 @@ import (
 var (
 	states stateMap
 	locks  lockMap
+	shards shardMap
 )
 
 func init() {
@@ -32,11 +34,16 @@ func init() {
 // tests.
 func Reset() {
 	states = stateMap{
-		m: map[string]*remote.State{},
+		m: map[string]state.State{},
+	}
+
+	shards = shardMap{
+		m: map[string]*RemoteClient{},
 	}
 
 	locks = lockMap{
-		m: map[string]*state.LockInfo{},
+		m:         map[string]*state.LockInfo{},
+		resources: map[string]*state.ResourceLocks{},
 	}
 }
 
@@ -50,6 +57,18 @@ func New() backend.Backend {
 				Optional:    true,
 				Description: "initializes the state in a locked configuration",
 			},
+
+			"shard_by": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "stores each state as shards, by \"module\" or \"resource_type\"",
+				ValidateFunc: func(v interface{}, k string) ([]string, []error) {
+					if _, err := remote.ParseShardBy(v.(string)); err != nil {
+						return nil, []error{err}
+					}
+					return nil, nil
+				},
+			},
 		},
 	}
 	backend := &Backend{Backend: s}
@@ -59,22 +78,22 @@ func New() backend.Backend {
 
 type Backend struct {
 	*schema.Backend
+
+	// shardBy is how states are divided between shards, or empty if they
+	// aren't sharded.
+	shardBy remote.ShardBy
 }
 
 func (b *Backend) configure(ctx context.Context) error {
 	states.Lock()
 	defer states.Unlock()
 
-	defaultClient := &RemoteClient{
-		Name: backend.DefaultStateName,
-	}
+	data := schema.FromContextBackendConfig(ctx)
+	b.shardBy = remote.ShardBy(data.Get("shard_by").(string))
 
-	states.m[backend.DefaultStateName] = &remote.State{
-		Client: defaultClient,
-	}
+	states.m[backend.DefaultStateName] = b.newState(backend.DefaultStateName)
 
 	// set the default client lock info per the test config
-	data := schema.FromContextBackendConfig(ctx)
 	if v, ok := data.GetOk("lock_id"); ok && v.(string) != "" {
 		info := state.NewLockInfo()
 		info.ID = v.(string)
@@ -110,6 +129,7 @@ func (b *Backend) DeleteState(name string) error {
 	}
 
 	delete(states.m, name)
+	shards.deleteState(name)
 	return nil
 }
 
@@ -119,11 +139,7 @@ func (b *Backend) State(name string) (state.State, error) {
 
 	s := states.m[name]
 	if s == nil {
-		s = &remote.State{
-			Client: &RemoteClient{
-				Name: name,
-			},
-		}
+		s = b.newState(name)
 		states.m[name] = s
 
 		// to most closely replicate other implementations, we are going to
@@ -150,15 +166,64 @@ func (b *Backend) State(name string) (state.State, error) {
 	return s, nil
 }
 
+// newState returns the state manager of the state with the given name.
+func (b *Backend) newState(name string) state.State {
+	client := &RemoteClient{
+		Name: name,
+	}
+	if b.shardBy == "" {
+		return &remote.State{Client: client}
+	}
+
+	return &remote.ShardedState{
+		Manifest: client,
+		Shard: func(shard string) (remote.Client, error) {
+			return shards.client(name + "/" + shard), nil
+		},
+		ShardBy: b.shardBy,
+	}
+}
+
 type stateMap struct {
 	sync.Mutex
-	m map[string]*remote.State
+	m map[string]state.State
+}
+
+// shardMap holds the clients of the shards of sharded states.
+type shardMap struct {
+	sync.Mutex
+	m map[string]*RemoteClient
+}
+
+// deleteState removes the shards of the state with the given name.
+func (s *shardMap) deleteState(name string) {
+	s.Lock()
+	defer s.Unlock()
+
+	for k := range s.m {
+		if strings.HasPrefix(k, name+"/") {
+			delete(s.m, k)
+		}
+	}
+}
+
+func (s *shardMap) client(name string) *RemoteClient {
+	s.Lock()
+	defer s.Unlock()
+
+	c := s.m[name]
+	if c == nil {
+		c = &RemoteClient{Name: name}
+		s.m[name] = c
+	}
+	return c
 }
 
 // Global level locks for inmem backends.
 type lockMap struct {
 	sync.Mutex
-	m map[string]*state.LockInfo
+	m         map[string]*state.LockInfo
+	resources map[string]*state.ResourceLocks
 }
 
 func (l *lockMap) lock(name string, info *state.LockInfo) (string, error) {
@@ -177,6 +242,15 @@ func (l *lockMap) lock(name string, info *state.LockInfo) (string, error) {
 		return "", lockErr
 	}
 
+	if r := l.resources[name]; r != nil {
+		if lockInfo := r.Info(); lockInfo != nil {
+			return "", &state.LockError{
+				Info: lockInfo,
+				Err:  errors.New("state resources locked"),
+			}
+		}
+	}
+
 	info.Created = time.Now().UTC()
 	l.m[name] = info
 
@@ -206,3 +280,35 @@ func (l *lockMap) unlock(name, id string) error {
 	delete(l.m, name)
 	return nil
 }
+
+func (l *lockMap) lockResources(name string, info *state.LockInfo, addrs []string) (string, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if lockInfo := l.m[name]; lockInfo != nil {
+		lockErr := &state.LockError{
+			Info: &state.LockInfo{},
+			Err:  errors.New("state locked"),
+		}
+		*lockErr.Info = *lockInfo
+		return "", lockErr
+	}
+
+	r := l.resources[name]
+	if r == nil {
+		r = new(state.ResourceLocks)
+		l.resources[name] = r
+	}
+	return r.Lock(info, addrs)
+}
+
+func (l *lockMap) unlockResources(name, id string) error {
+	l.Lock()
+	defer l.Unlock()
+
+	r := l.resources[name]
+	if r == nil {
+		return errors.New("state resources not locked")
+	}
+	return r.Unlock(id)
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/state/remote"
 	"github.com/hashicorp/terraform/terraform"
 )
@@ -51,6 +52,39 @@ func TestBackendLocked(t *testing.T) {
 	backend.TestBackend(t, b1, b2)
 }
 
+func TestBackendSharded(t *testing.T) {
+	defer Reset()
+	config := map[string]interface{}{
+		"shard_by": "resource_type",
+	}
+	b1 := backend.TestBackendConfig(t, New(), config).(*Backend)
+	b2 := backend.TestBackendConfig(t, New(), config).(*Backend)
+
+	backend.TestBackend(t, b1, b2)
+
+	s, err := b1.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.(*remote.ShardedState); !ok {
+		t.Fatalf("wrong state manager %T", s)
+	}
+}
+
+func TestBackendShardedBad(t *testing.T) {
+	rc, err := config.NewRawConfig(map[string]interface{}{
+		"shard_by": "workspace",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, errs := New().Validate(terraform.NewResourceConfig(rc))
+	if len(errs) == 0 {
+		t.Fatal("expected error for invalid shard_by")
+	}
+}
+
 // use the this backen to test the remote.State implementation
 func TestRemoteState(t *testing.T) {
 	defer Reset()
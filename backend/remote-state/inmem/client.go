@@ -2,6 +2,7 @@ package inmem
 
 import (
 	"crypto/md5"
+	"sync"
 
 	"github.com/hashicorp/terraform/state"
 	"github.com/hashicorp/terraform/state/remote"
@@ -12,6 +13,9 @@ type RemoteClient struct {
 	Data []byte
 	MD5  []byte
 	Name string
+
+	// mu serializes Update calls.
+	mu sync.Mutex
 }
 
 func (c *RemoteClient) Get() (*remote.Payload, error) {
@@ -45,3 +49,26 @@ func (c *RemoteClient) Lock(info *state.LockInfo) (string, error) {
 func (c *RemoteClient) Unlock(id string) error {
 	return locks.unlock(c.Name, id)
 }
+
+func (c *RemoteClient) LockResources(info *state.LockInfo, addrs []string) (string, error) {
+	return locks.lockResources(c.Name, info, addrs)
+}
+
+func (c *RemoteClient) UnlockResources(id string) error {
+	return locks.unlockResources(c.Name, id)
+}
+
+func (c *RemoteClient) Update(f func(*remote.Payload) ([]byte, error)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	payload, err := c.Get()
+	if err != nil {
+		return err
+	}
+	data, err := f(payload)
+	if err != nil {
+		return err
+	}
+	return c.Put(data)
+}
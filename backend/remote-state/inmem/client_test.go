@@ -4,12 +4,15 @@ import (
 	"testing"
 
 	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/state"
 	"github.com/hashicorp/terraform/state/remote"
+	"github.com/hashicorp/terraform/terraform"
 )
 
 func TestRemoteClient_impl(t *testing.T) {
 	var _ remote.Client = new(RemoteClient)
 	var _ remote.ClientLocker = new(RemoteClient)
+	var _ remote.ClientResourceLocker = new(RemoteClient)
 }
 
 func TestRemoteClient(t *testing.T) {
@@ -33,3 +36,119 @@ func TestInmemLocks(t *testing.T) {
 
 	remote.TestRemoteLocks(t, s.(*remote.State).Client, s.(*remote.State).Client)
 }
+
+func TestInmemResourceLocks(t *testing.T) {
+	defer Reset()
+	s, err := backend.TestBackendConfig(t, New(), nil).State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.ResourceLockingSupported(s) {
+		t.Fatal("resource locking isn't supported")
+	}
+	rs := s.(*remote.State)
+
+	id, err := rs.LockResources(state.NewLockInfo(), []string{"test_instance.foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Resource locks and the lock of the whole state exclude each other.
+	if _, err := rs.Lock(state.NewLockInfo()); err == nil {
+		t.Fatal("locked the state while its resources are locked")
+	}
+	if _, err := rs.LockResources(state.NewLockInfo(), []string{"test_instance.foo[0]"}); err == nil {
+		t.Fatal("locked a locked resource")
+	}
+
+	// Only the locked resource is written.
+	v := terraform.NewState()
+	for _, name := range []string{"foo", "bar"} {
+		v.RootModule().Resources["test_instance."+name] = &terraform.ResourceState{
+			Type:    "test_instance",
+			Primary: &terraform.InstanceState{ID: name},
+		}
+	}
+	if err := rs.WriteResources(id, nil, v); err != nil {
+		t.Fatal(err)
+	}
+
+	// The write is persisted.
+	fresh := &remote.State{Client: rs.Client}
+	if err := fresh.RefreshState(); err != nil {
+		t.Fatal(err)
+	}
+	resources := fresh.State().RootModule().Resources
+	if _, ok := resources["test_instance.foo"]; !ok {
+		t.Fatal("test_instance.foo wasn't written")
+	}
+	if _, ok := resources["test_instance.bar"]; ok {
+		t.Fatal("test_instance.bar was written")
+	}
+
+	if err := rs.UnlockResources(id); err != nil {
+		t.Fatal(err)
+	}
+	lockID, err := rs.Lock(state.NewLockInfo())
+	if err != nil {
+		t.Fatalf("couldn't lock the state after unlocking its resources: %s", err)
+	}
+	rs.Unlock(lockID)
+}
+
+func TestInmemShardedResourceLocks(t *testing.T) {
+	defer Reset()
+	config := map[string]interface{}{
+		"shard_by": "resource_type",
+	}
+	s, err := backend.TestBackendConfig(t, New(), config).State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.ResourceLockingSupported(s) {
+		t.Fatal("resource locking isn't supported")
+	}
+	rs := s.(*remote.ShardedState)
+
+	// Each write only changes the shard of its locked resources.
+	base := terraform.NewState()
+	for _, typ := range []string{"test_instance", "test_volume"} {
+		addr := typ + ".foo"
+		id, err := rs.LockResources(state.NewLockInfo(), []string{addr})
+		if err != nil {
+			t.Fatal(err)
+		}
+		v := base.DeepCopy()
+		v.RootModule().Resources[addr] = &terraform.ResourceState{
+			Type:    typ,
+			Primary: &terraform.InstanceState{ID: typ},
+		}
+		if err := rs.WriteResources(id, nil, v); err != nil {
+			t.Fatal(err)
+		}
+		if err := rs.UnlockResources(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fresh := &remote.ShardedState{Manifest: rs.Manifest, Shard: rs.Shard}
+	if err := fresh.RefreshState(); err != nil {
+		t.Fatal(err)
+	}
+	resources := fresh.State().RootModule().Resources
+	for _, addr := range []string{"test_instance.foo", "test_volume.foo"} {
+		if _, ok := resources[addr]; !ok {
+			t.Fatalf("%s wasn't written", addr)
+		}
+	}
+
+	stored := 0
+	for _, c := range shards.m {
+		if c.Data != nil {
+			stored++
+		}
+	}
+	if stored != 2 {
+		t.Fatalf("%d shards are stored; want 2", stored)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/hashicorp/terraform/backend"
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/state/remote"
 
 	terraformAWS "github.com/terraform-providers/terraform-provider-aws/aws"
 )
@@ -101,6 +102,21 @@ func New() backend.Backend {
 				Default:     "",
 			},
 
+			"lock_strategy": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "How to lock the state: \"dynamodb\", \"s3\" or \"both\"",
+				Default:     "",
+				ValidateFunc: func(v interface{}, k string) ([]string, []error) {
+					switch v.(string) {
+					case "", lockStrategyDynamoDB, lockStrategyS3, lockStrategyBoth:
+						return nil, nil
+					}
+					return nil, []error{fmt.Errorf(
+						"%s must be %q, %q or %q", k, lockStrategyDynamoDB, lockStrategyS3, lockStrategyBoth)}
+				},
+			},
+
 			"profile": {
 				Type:        schema.TypeString,
 				Optional:    true,
@@ -192,6 +208,22 @@ func New() backend.Backend {
 				Default:     "env:",
 			},
 
+			"shard_by": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Store each state as shards, by \"module\" or \"resource_type\".",
+				Default:     "",
+				ValidateFunc: func(v interface{}, k string) ([]string, []error) {
+					if v.(string) == "" {
+						return nil, nil
+					}
+					if _, err := remote.ParseShardBy(v.(string)); err != nil {
+						return nil, []error{err}
+					}
+					return nil, nil
+				},
+			},
+
 			"force_path_style": {
 				Type:        schema.TypeBool,
 				Optional:    true,
@@ -206,6 +238,16 @@ func New() backend.Backend {
 	return result
 }
 
+// The strategies for locking states. States are locked with DynamoDB, with
+// a lock object in S3 that's written with a conditional write, or with both,
+// which is used while moving from DynamoDB to S3 so that Terraform versions
+// that only lock with DynamoDB are still excluded.
+const (
+	lockStrategyDynamoDB = "dynamodb"
+	lockStrategyS3       = "s3"
+	lockStrategyBoth     = "both"
+)
+
 type Backend struct {
 	*schema.Backend
 
@@ -219,7 +261,9 @@ type Backend struct {
 	acl                  string
 	kmsKeyID             string
 	ddbTable             string
+	s3Lock               bool
 	workspaceKeyPrefix   string
+	shardBy              remote.ShardBy
 }
 
 func (b *Backend) configure(ctx context.Context) error {
@@ -236,6 +280,7 @@ func (b *Backend) configure(ctx context.Context) error {
 	b.acl = data.Get("acl").(string)
 	b.kmsKeyID = data.Get("kms_key_id").(string)
 	b.workspaceKeyPrefix = data.Get("workspace_key_prefix").(string)
+	b.shardBy = remote.ShardBy(data.Get("shard_by").(string))
 
 	b.ddbTable = data.Get("dynamodb_table").(string)
 	if b.ddbTable == "" {
@@ -243,6 +288,19 @@ func (b *Backend) configure(ctx context.Context) error {
 		b.ddbTable = data.Get("lock_table").(string)
 	}
 
+	switch strategy := data.Get("lock_strategy").(string); strategy {
+	case lockStrategyS3:
+		if b.ddbTable != "" {
+			return fmt.Errorf(strings.TrimSpace(errLockStrategyS3WithTable), lockStrategyBoth)
+		}
+		b.s3Lock = true
+	case lockStrategyDynamoDB, lockStrategyBoth:
+		if b.ddbTable == "" {
+			return fmt.Errorf("lock_strategy %q requires dynamodb_table to be set", strategy)
+		}
+		b.s3Lock = strategy == lockStrategyBoth
+	}
+
 	cfg := &terraformAWS.Config{
 		AccessKey:               data.Get("access_key").(string),
 		AssumeRoleARN:           data.Get("role_arn").(string),
@@ -273,3 +331,13 @@ func (b *Backend) configure(ctx context.Context) error {
 
 	return nil
 }
+
+const errLockStrategyS3WithTable = `
+lock_strategy is "s3", but dynamodb_table is also set.
+
+Terraform versions that don't support locking with S3 lock the state with
+DynamoDB, and aren't excluded by an S3 lock. While any of them may still
+use this state, set lock_strategy to %q, so that both locks are taken. Once
+every Terraform version that uses the state locks with S3, remove
+dynamodb_table.
+`
@@ -88,9 +88,57 @@ func (b *Backend) DeleteState(name string) error {
 		return err
 	}
 
+	if err := b.deleteShards(name); err != nil {
+		return err
+	}
 	return client.Delete()
 }
 
+// shardPath returns the path of the shard with the given name of the state
+// with the given name, or the prefix of its shards if the shard name is
+// empty.
+func (b *Backend) shardPath(name, shard string) string {
+	return b.path(name) + ".shards/" + shard
+}
+
+// shardClient returns a function that returns the clients of the shards of
+// the state with the given name. Shards are only ever read after their
+// manifest, so they have no digests or locks in DynamoDB.
+func (b *Backend) shardClient(name string) func(string) (remote.Client, error) {
+	return func(shard string) (remote.Client, error) {
+		return &RemoteClient{
+			s3Client:             b.s3Client,
+			bucketName:           b.bucketName,
+			path:                 b.shardPath(name, shard),
+			serverSideEncryption: b.serverSideEncryption,
+			acl:                  b.acl,
+			kmsKeyID:             b.kmsKeyID,
+		}, nil
+	}
+}
+
+// deleteShards deletes the shards of the state with the given name.
+func (b *Backend) deleteShards(name string) error {
+	resp, err := b.s3Client.ListObjects(&s3.ListObjectsInput{
+		Bucket: &b.bucketName,
+		Prefix: aws.String(b.shardPath(name, "")),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range resp.Contents {
+		_, err := b.s3Client.DeleteObject(&s3.DeleteObjectInput{
+			Bucket: &b.bucketName,
+			Key:    obj.Key,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // get a remote client configured for this state
 func (b *Backend) remoteClient(name string) (*RemoteClient, error) {
 	if name == "" {
@@ -106,6 +154,7 @@ func (b *Backend) remoteClient(name string) (*RemoteClient, error) {
 		acl:                  b.acl,
 		kmsKeyID:             b.kmsKeyID,
 		ddbTable:             b.ddbTable,
+		s3Lock:               b.s3Lock,
 	}
 
 	return client, nil
@@ -117,7 +166,14 @@ func (b *Backend) State(name string) (state.State, error) {
 		return nil, err
 	}
 
-	stateMgr := &remote.State{Client: client}
+	var stateMgr state.State = &remote.State{Client: client}
+	if b.shardBy != "" {
+		stateMgr = &remote.ShardedState{
+			Manifest: client,
+			Shard:    b.shardClient(name),
+			ShardBy:  b.shardBy,
+		}
+	}
 	// Check to see if this state already exists.
 	// If we're trying to force-unlock a state, we can't take the lock before
 	// fetching the state. If the state doesn't exist, we have to assume this
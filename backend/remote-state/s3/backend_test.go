@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -88,6 +89,55 @@ func TestBackendConfig_invalidKey(t *testing.T) {
 	}
 }
 
+func TestBackendConfig_lockStrategy(t *testing.T) {
+	cases := map[string]struct {
+		Config map[string]interface{}
+		Err    string
+	}{
+		"s3 with table": {
+			map[string]interface{}{
+				"lock_strategy":  "s3",
+				"dynamodb_table": "dynamoTable",
+			},
+			`set lock_strategy to "both"`,
+		},
+		"dynamodb without table": {
+			map[string]interface{}{
+				"lock_strategy": "dynamodb",
+			},
+			"requires dynamodb_table",
+		},
+		"both without table": {
+			map[string]interface{}{
+				"lock_strategy": "both",
+			},
+			"requires dynamodb_table",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := map[string]interface{}{
+				"region": "us-west-1",
+				"bucket": "tf-test",
+				"key":    "state",
+			}
+			for k, v := range tc.Config {
+				cfg[k] = v
+			}
+
+			rawCfg, err := config.NewRawConfig(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = New().Configure(terraform.NewResourceConfig(rawCfg))
+			if err == nil || !strings.Contains(err.Error(), tc.Err) {
+				t.Fatalf("wrong error %v; want %q", err, tc.Err)
+			}
+		})
+	}
+}
+
 func TestBackend(t *testing.T) {
 	testACC(t)
 
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"strconv"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -27,6 +28,15 @@ const (
 	s3ErrCodeInternalError = "InternalError"
 )
 
+// The lock of a state locked with S3 is an object at the state path with
+// this suffix, which is only written if it doesn't already exist.
+const (
+	lockSuffix = ".tflock"
+
+	s3ErrCodePreconditionFailed         = "PreconditionFailed"
+	s3ErrCodeConditionalRequestConflict = "ConditionalRequestConflict"
+)
+
 type RemoteClient struct {
 	s3Client             *s3.S3
 	dynClient            *dynamodb.DynamoDB
@@ -36,6 +46,10 @@ type RemoteClient struct {
 	acl                  string
 	kmsKeyID             string
 	ddbTable             string
+
+	// s3Lock is true if the state is locked with a lock object in S3, as
+	// well as with DynamoDB if ddbTable is set.
+	s3Lock bool
 }
 
 var (
@@ -50,15 +64,23 @@ var (
 // test hook called when checksums don't match
 var testChecksumHook func()
 
-func (c *RemoteClient) Get() (payload *remote.Payload, err error) {
+func (c *RemoteClient) Get() (*remote.Payload, error) {
+	payload, _, err := c.getConsistent()
+	return payload, err
+}
+
+// getConsistent returns the stored state and the ETag of its object, which
+// is empty if there's no object, waiting for the state to match the
+// checksum recorded in DynamoDB, if any.
+func (c *RemoteClient) getConsistent() (payload *remote.Payload, etag string, err error) {
 	deadline := time.Now().Add(consistencyRetryTimeout)
 
 	// If we have a checksum, and the returned payload doesn't match, we retry
 	// up until deadline.
 	for {
-		payload, err = c.get()
+		payload, etag, err = c.get()
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 
 		// If the remote state was manually removed the payload will be nil,
@@ -85,16 +107,16 @@ func (c *RemoteClient) Get() (payload *remote.Payload, err error) {
 				continue
 			}
 
-			return nil, fmt.Errorf(errBadChecksumFmt, digest)
+			return nil, "", fmt.Errorf(errBadChecksumFmt, digest)
 		}
 
 		break
 	}
 
-	return payload, err
+	return payload, etag, err
 }
 
-func (c *RemoteClient) get() (*remote.Payload, error) {
+func (c *RemoteClient) get() (*remote.Payload, string, error) {
 	var output *s3.GetObjectOutput
 	var err error
 
@@ -110,25 +132,26 @@ func (c *RemoteClient) get() (*remote.Payload, error) {
 			if awserr, ok := err.(awserr.Error); ok {
 				switch awserr.Code() {
 				case s3.ErrCodeNoSuchKey:
-					return nil, nil
+					return nil, "", nil
 				case s3ErrCodeInternalError:
 					if retryCount > maxRetries {
-						return nil, err
+						return nil, "", err
 					}
 					log.Println("[WARN] s3 internal error, retrying...")
 					continue
 				}
 			}
-			return nil, err
+			return nil, "", err
 		}
 		break
 	}
 
 	defer output.Body.Close()
+	etag := aws.StringValue(output.ETag)
 
 	buf := bytes.NewBuffer(nil)
 	if _, err := io.Copy(buf, output.Body); err != nil {
-		return nil, fmt.Errorf("Failed to read remote state: %s", err)
+		return nil, "", fmt.Errorf("Failed to read remote state: %s", err)
 	}
 
 	sum := md5.Sum(buf.Bytes())
@@ -139,13 +162,23 @@ func (c *RemoteClient) get() (*remote.Payload, error) {
 
 	// If there was no data, then return nil
 	if len(payload.Data) == 0 {
-		return nil, nil
+		return nil, etag, nil
 	}
 
-	return payload, nil
+	return payload, etag, nil
 }
 
 func (c *RemoteClient) Put(data []byte) error {
+	return c.put(data, nil)
+}
+
+// errStateChanged is returned by put when S3 rejects a conditional write
+// because the state changed.
+var errStateChanged = errors.New("the state changed while it was being updated")
+
+// put stores the state, sending the given headers with the request to S3,
+// which may make the write conditional.
+func (c *RemoteClient) put(data []byte, header map[string]string) error {
 	contentType := "application/json"
 	contentLength := int64(len(data))
 
@@ -175,15 +208,22 @@ func (c *RemoteClient) Put(data []byte) error {
 
 		log.Printf("[DEBUG] Uploading remote state to S3: %#v", i)
 
-		_, err := c.s3Client.PutObject(i)
+		req, _ := c.s3Client.PutObjectRequest(i)
+		for k, v := range header {
+			req.HTTPRequest.Header.Set(k, v)
+		}
+		err := req.Send()
 		if err != nil {
 			if awserr, ok := err.(awserr.Error); ok {
-				if awserr.Code() == s3ErrCodeInternalError {
+				switch awserr.Code() {
+				case s3ErrCodeInternalError:
 					if retryCount > maxRetries {
 						return fmt.Errorf("failed to upload state: %s", err)
 					}
 					log.Println("[WARN] s3 internal error, retrying...")
 					continue
+				case s3ErrCodePreconditionFailed, s3ErrCodeConditionalRequestConflict:
+					return errStateChanged
 				}
 			}
 			return fmt.Errorf("failed to upload state: %s", err)
@@ -220,7 +260,7 @@ func (c *RemoteClient) Delete() error {
 }
 
 func (c *RemoteClient) Lock(info *state.LockInfo) (string, error) {
-	if c.ddbTable == "" {
+	if c.ddbTable == "" && !c.s3Lock {
 		return "", nil
 	}
 
@@ -235,6 +275,30 @@ func (c *RemoteClient) Lock(info *state.LockInfo) (string, error) {
 		info.ID = lockID
 	}
 
+	// While both locks are used, the S3 lock is taken first, so that it's
+	// released if the DynamoDB lock is held by a Terraform version that
+	// only knows about that one.
+	if c.s3Lock {
+		if err := c.lockS3(info); err != nil {
+			return "", err
+		}
+	}
+
+	if c.ddbTable != "" {
+		if err := c.lockDynamoDB(info); err != nil {
+			if c.s3Lock {
+				if uerr := c.unlockS3(info.ID); uerr != nil {
+					log.Printf("[WARN] failed to release S3 lock: %s", uerr)
+				}
+			}
+			return "", err
+		}
+	}
+
+	return info.ID, nil
+}
+
+func (c *RemoteClient) lockDynamoDB(info *state.LockInfo) error {
 	putParams := &dynamodb.PutItemInput{
 		Item: map[string]*dynamodb.AttributeValue{
 			"LockID": {S: aws.String(c.lockPath())},
@@ -255,10 +319,98 @@ func (c *RemoteClient) Lock(info *state.LockInfo) (string, error) {
 			Err:  err,
 			Info: lockInfo,
 		}
-		return "", lockErr
+		return lockErr
 	}
 
-	return info.ID, nil
+	return nil
+}
+
+// lockS3 writes the lock object of the state with a conditional write,
+// which S3 rejects if the object already exists.
+func (c *RemoteClient) lockS3(info *state.LockInfo) error {
+	contentType := "application/json"
+	i := &s3.PutObjectInput{
+		ContentType: &contentType,
+		Body:        bytes.NewReader(info.Marshal()),
+		Bucket:      &c.bucketName,
+		Key:         aws.String(c.lockKey()),
+	}
+	if c.serverSideEncryption {
+		if c.kmsKeyID != "" {
+			i.SSEKMSKeyId = &c.kmsKeyID
+			i.ServerSideEncryption = aws.String("aws:kms")
+		} else {
+			i.ServerSideEncryption = aws.String("AES256")
+		}
+	}
+	if c.acl != "" {
+		i.ACL = aws.String(c.acl)
+	}
+
+	req, _ := c.s3Client.PutObjectRequest(i)
+	req.HTTPRequest.Header.Set("If-None-Match", "*")
+	err := req.Send()
+	if err == nil {
+		return nil
+	}
+
+	if awserr, ok := err.(awserr.Error); ok {
+		switch awserr.Code() {
+		case s3ErrCodePreconditionFailed, s3ErrCodeConditionalRequestConflict:
+			err = errors.New("the lock object already exists")
+		}
+	}
+	lockInfo, infoErr := c.getS3LockInfo()
+	if infoErr != nil {
+		err = multierror.Append(err, infoErr)
+	}
+	return &state.LockError{
+		Err:  err,
+		Info: lockInfo,
+	}
+}
+
+func (c *RemoteClient) getS3LockInfo() (*state.LockInfo, error) {
+	output, err := c.s3Client.GetObject(&s3.GetObjectInput{
+		Bucket: &c.bucketName,
+		Key:    aws.String(c.lockKey()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+
+	lockInfo := &state.LockInfo{}
+	if err := json.NewDecoder(output.Body).Decode(lockInfo); err != nil {
+		return nil, err
+	}
+	return lockInfo, nil
+}
+
+func (c *RemoteClient) unlockS3(id string) error {
+	lockErr := &state.LockError{}
+
+	lockInfo, err := c.getS3LockInfo()
+	if err != nil {
+		lockErr.Err = fmt.Errorf("failed to retrieve lock info: %s", err)
+		return lockErr
+	}
+	lockErr.Info = lockInfo
+
+	if lockInfo.ID != id {
+		lockErr.Err = fmt.Errorf("lock id %q does not match existing lock", id)
+		return lockErr
+	}
+
+	_, err = c.s3Client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: &c.bucketName,
+		Key:    aws.String(c.lockKey()),
+	})
+	if err != nil {
+		lockErr.Err = err
+		return lockErr
+	}
+	return nil
 }
 
 func (c *RemoteClient) getMD5() ([]byte, error) {
@@ -341,7 +493,7 @@ func (c *RemoteClient) getLockInfo() (*state.LockInfo, error) {
 		Key: map[string]*dynamodb.AttributeValue{
 			"LockID": {S: aws.String(c.lockPath())},
 		},
-		ProjectionExpression: aws.String("LockID, Info"),
+		ProjectionExpression: aws.String("LockID, Info, " + resourceLocksAttr),
 		TableName:            aws.String(c.ddbTable),
 		ConsistentRead:       aws.Bool(true),
 	}
@@ -351,6 +503,19 @@ func (c *RemoteClient) getLockInfo() (*state.LockInfo, error) {
 		return nil, err
 	}
 
+	// While resources are locked, the info is that of one of their locks.
+	if _, ok := resp.Item["Info"]; !ok {
+		if v, ok := resp.Item[resourceLocksAttr]; ok && v.S != nil {
+			var locks state.ResourceLocks
+			if err := json.Unmarshal([]byte(*v.S), &locks); err != nil {
+				return nil, err
+			}
+			if info := locks.Info(); info != nil {
+				return info, nil
+			}
+		}
+	}
+
 	var infoData string
 	if v, ok := resp.Item["Info"]; ok && v.S != nil {
 		infoData = *v.S
@@ -366,10 +531,18 @@ func (c *RemoteClient) getLockInfo() (*state.LockInfo, error) {
 }
 
 func (c *RemoteClient) Unlock(id string) error {
-	if c.ddbTable == "" {
-		return nil
+	if c.ddbTable != "" {
+		if err := c.unlockDynamoDB(id); err != nil {
+			return err
+		}
 	}
+	if c.s3Lock {
+		return c.unlockS3(id)
+	}
+	return nil
+}
 
+func (c *RemoteClient) unlockDynamoDB(id string) error {
 	lockErr := &state.LockError{}
 
 	// TODO: store the path and lock ID in separate fields, and have proper
@@ -402,10 +575,217 @@ func (c *RemoteClient) Unlock(id string) error {
 	return nil
 }
 
+// The resource locks of a state are stored as JSON in an attribute of the
+// state's DynamoDB lock item, along with a version that every change
+// increments, so that changes are made with conditional writes. While any
+// resources are locked, the item exists without the Info attribute of a
+// lock of the whole state, so that locking the whole state fails.
+const (
+	resourceLocksAttr        = "ResourceLocks"
+	resourceLocksVersionAttr = "ResourceLocksVersion"
+)
+
+// The number of times that a change of the resource locks or an update of
+// the state is attempted when it conflicts with another one.
+const conflictMaxAttempts = 10
+
+// ResourceLockingSupported returns true if resources can be locked, which
+// requires a DynamoDB table.
+func (c *RemoteClient) ResourceLockingSupported() bool {
+	return c.ddbTable != ""
+}
+
+func (c *RemoteClient) LockResources(info *state.LockInfo, addrs []string) (string, error) {
+	if c.ddbTable == "" {
+		return "", errors.New("locking resources requires a DynamoDB table")
+	}
+
+	info.Path = c.lockPath()
+
+	if info.ID == "" {
+		lockID, err := uuid.GenerateUUID()
+		if err != nil {
+			return "", err
+		}
+
+		info.ID = lockID
+	}
+
+	err := c.updateResourceLocks(func(locks *state.ResourceLocks) error {
+		_, err := locks.Lock(info, addrs)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return info.ID, nil
+}
+
+func (c *RemoteClient) UnlockResources(id string) error {
+	if c.ddbTable == "" {
+		return errors.New("locking resources requires a DynamoDB table")
+	}
+
+	return c.updateResourceLocks(func(locks *state.ResourceLocks) error {
+		return locks.Unlock(id)
+	})
+}
+
+// updateResourceLocks changes the resource locks of the state with f,
+// retrying if they're changed concurrently.
+func (c *RemoteClient) updateResourceLocks(f func(*state.ResourceLocks) error) error {
+	for attempt := 1; ; attempt++ {
+		locks, version, err := c.getResourceLocks()
+		if err != nil {
+			return err
+		}
+		if err := f(locks); err != nil {
+			return err
+		}
+
+		err = c.putResourceLocks(locks, version)
+		if awserr, ok := err.(awserr.Error); ok && awserr.Code() == dynamodb.ErrCodeConditionalCheckFailedException && attempt < conflictMaxAttempts {
+			log.Printf("[DEBUG] resource locks changed concurrently, retrying")
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to store resource locks: %s", err)
+		}
+		return nil
+	}
+}
+
+// getResourceLocks returns the resource locks of the state and their
+// version, which is 0 if no resources are locked. It returns a
+// *state.LockError if the whole state is locked.
+func (c *RemoteClient) getResourceLocks() (*state.ResourceLocks, int64, error) {
+	getParams := &dynamodb.GetItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"LockID": {S: aws.String(c.lockPath())},
+		},
+		TableName:      aws.String(c.ddbTable),
+		ConsistentRead: aws.Bool(true),
+	}
+
+	resp, err := c.dynClient.GetItem(getParams)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	locks := new(state.ResourceLocks)
+	if resp.Item == nil {
+		return locks, 0, nil
+	}
+
+	if v, ok := resp.Item["Info"]; ok && v.S != nil {
+		lockInfo := &state.LockInfo{}
+		if err := json.Unmarshal([]byte(*v.S), lockInfo); err != nil {
+			return nil, 0, err
+		}
+		return nil, 0, &state.LockError{
+			Err:  errors.New("state locked"),
+			Info: lockInfo,
+		}
+	}
+
+	var version int64
+	if v, ok := resp.Item[resourceLocksVersionAttr]; ok && v.N != nil {
+		version, err = strconv.ParseInt(*v.N, 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid resource locks version: %s", err)
+		}
+	}
+	if v, ok := resp.Item[resourceLocksAttr]; ok && v.S != nil {
+		if err := json.Unmarshal([]byte(*v.S), locks); err != nil {
+			return nil, 0, fmt.Errorf("invalid resource locks: %s", err)
+		}
+	}
+
+	return locks, version, nil
+}
+
+// putResourceLocks stores the resource locks of the state, unless they were
+// changed since the given version was read, deleting the lock item if no
+// resources are locked.
+func (c *RemoteClient) putResourceLocks(locks *state.ResourceLocks, version int64) error {
+	condition := aws.String("attribute_not_exists(LockID)")
+	var names map[string]*string
+	var values map[string]*dynamodb.AttributeValue
+	if version > 0 {
+		condition = aws.String("#version = :version")
+		names = map[string]*string{"#version": aws.String(resourceLocksVersionAttr)}
+		values = map[string]*dynamodb.AttributeValue{
+			":version": {N: aws.String(strconv.FormatInt(version, 10))},
+		}
+	}
+
+	if locks.Len() == 0 {
+		_, err := c.dynClient.DeleteItem(&dynamodb.DeleteItemInput{
+			Key: map[string]*dynamodb.AttributeValue{
+				"LockID": {S: aws.String(c.lockPath())},
+			},
+			TableName:                 aws.String(c.ddbTable),
+			ConditionExpression:       condition,
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+		})
+		return err
+	}
+
+	data, err := json.Marshal(locks)
+	if err != nil {
+		return err
+	}
+	_, err = c.dynClient.PutItem(&dynamodb.PutItemInput{
+		Item: map[string]*dynamodb.AttributeValue{
+			"LockID":                 {S: aws.String(c.lockPath())},
+			resourceLocksAttr:        {S: aws.String(string(data))},
+			resourceLocksVersionAttr: {N: aws.String(strconv.FormatInt(version+1, 10))},
+		},
+		TableName:                 aws.String(c.ddbTable),
+		ConditionExpression:       condition,
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	return err
+}
+
+// Update replaces the state with the result of f. The state is written
+// with a conditional write, which S3 rejects if the state was changed since
+// it was read, in which case the update is retried.
+func (c *RemoteClient) Update(f func(*remote.Payload) ([]byte, error)) error {
+	for attempt := 1; ; attempt++ {
+		payload, etag, err := c.getConsistent()
+		if err != nil {
+			return err
+		}
+		data, err := f(payload)
+		if err != nil {
+			return err
+		}
+
+		header := map[string]string{"If-None-Match": "*"}
+		if etag != "" {
+			header = map[string]string{"If-Match": etag}
+		}
+		err = c.put(data, header)
+		if err == errStateChanged && attempt < conflictMaxAttempts {
+			log.Printf("[DEBUG] state changed while it was being updated, retrying")
+			continue
+		}
+		return err
+	}
+}
+
 func (c *RemoteClient) lockPath() string {
 	return fmt.Sprintf("%s/%s", c.bucketName, c.path)
 }
 
+// lockKey is the key of the lock object of the state within the bucket.
+func (c *RemoteClient) lockKey() string {
+	return c.path + lockSuffix
+}
+
 const errBadChecksumFmt = `state data in S3 does not have the expected content.
 
 This may be caused by unusually long delays in S3 processing a previous state
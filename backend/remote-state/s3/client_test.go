@@ -3,11 +3,21 @@ package s3
 import (
 	"bytes"
 	"crypto/md5"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/hashicorp/terraform/backend"
 	"github.com/hashicorp/terraform/state"
 	"github.com/hashicorp/terraform/state/remote"
@@ -78,6 +88,82 @@ func TestRemoteClientLocks(t *testing.T) {
 	remote.TestRemoteLocks(t, s1.(*remote.State).Client, s2.(*remote.State).Client)
 }
 
+func TestRemoteClientLocks_s3(t *testing.T) {
+	testACC(t)
+	bucketName := fmt.Sprintf("terraform-remote-s3-test-%x", time.Now().Unix())
+	keyName := "testState"
+
+	b1 := backend.TestBackendConfig(t, New(), map[string]interface{}{
+		"bucket":        bucketName,
+		"key":           keyName,
+		"encrypt":       true,
+		"lock_strategy": "s3",
+	}).(*Backend)
+
+	b2 := backend.TestBackendConfig(t, New(), map[string]interface{}{
+		"bucket":        bucketName,
+		"key":           keyName,
+		"encrypt":       true,
+		"lock_strategy": "s3",
+	}).(*Backend)
+
+	createS3Bucket(t, b1.s3Client, bucketName)
+	defer deleteS3Bucket(t, b1.s3Client, bucketName)
+
+	s1, err := b1.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := b2.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote.TestRemoteLocks(t, s1.(*remote.State).Client, s2.(*remote.State).Client)
+}
+
+// verify that while both locks are used, a state is excluded from clients
+// that only lock with DynamoDB
+func TestRemoteClientLocks_both(t *testing.T) {
+	testACC(t)
+	bucketName := fmt.Sprintf("terraform-remote-s3-test-%x", time.Now().Unix())
+	keyName := "testState"
+
+	b1 := backend.TestBackendConfig(t, New(), map[string]interface{}{
+		"bucket":         bucketName,
+		"key":            keyName,
+		"encrypt":        true,
+		"dynamodb_table": bucketName,
+		"lock_strategy":  "both",
+	}).(*Backend)
+
+	b2 := backend.TestBackendConfig(t, New(), map[string]interface{}{
+		"bucket":         bucketName,
+		"key":            keyName,
+		"encrypt":        true,
+		"dynamodb_table": bucketName,
+	}).(*Backend)
+
+	createS3Bucket(t, b1.s3Client, bucketName)
+	defer deleteS3Bucket(t, b1.s3Client, bucketName)
+	createDynamoDBTable(t, b1.dynClient, bucketName)
+	defer deleteDynamoDBTable(t, b1.dynClient, bucketName)
+
+	s1, err := b1.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := b2.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote.TestRemoteLocks(t, s1.(*remote.State).Client, s2.(*remote.State).Client)
+	remote.TestRemoteLocks(t, s2.(*remote.State).Client, s1.(*remote.State).Client)
+}
+
 // verify that we can unlock a state with an existing lock
 func TestForceUnlock(t *testing.T) {
 	testACC(t)
@@ -314,3 +400,323 @@ func TestRemoteClient_stateChecksum(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// verify that S3 locks are taken with conditional writes, against a fake S3
+// endpoint that implements If-None-Match
+func TestRemoteClient_s3Lock(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case "PUT":
+			if _, ok := objects[r.URL.Path]; ok && r.Header.Get("If-None-Match") == "*" {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				fmt.Fprint(w, `<Error><Code>PreconditionFailed</Code></Error>`)
+				return
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			objects[r.URL.Path] = body
+		case "GET":
+			body, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `<Error><Code>NoSuchKey</Code></Error>`)
+				return
+			}
+			w.Write(body)
+		case "DELETE":
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	sess := session.New(&aws.Config{
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+		Endpoint:         aws.String(ts.URL),
+		Region:           aws.String("us-west-2"),
+		S3ForcePathStyle: aws.Bool(true),
+	})
+	newClient := func() *RemoteClient {
+		return &RemoteClient{
+			s3Client:   s3.New(sess),
+			bucketName: "bucket",
+			path:       "state",
+			s3Lock:     true,
+		}
+	}
+
+	remote.TestRemoteLocks(t, newClient(), newClient())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(objects) != 0 {
+		t.Fatalf("lock objects weren't removed: %v", objects)
+	}
+}
+
+// fakeS3 is a fake S3 endpoint that implements the conditional writes of
+// If-None-Match and If-Match.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.objects == nil {
+		f.objects = map[string][]byte{}
+	}
+
+	etag := func(body []byte) string {
+		return fmt.Sprintf(`"%x"`, md5.Sum(body))
+	}
+	switch r.Method {
+	case "PUT":
+		body, ok := f.objects[r.URL.Path]
+		if (ok && r.Header.Get("If-None-Match") == "*") ||
+			(r.Header.Get("If-Match") != "" && (!ok || r.Header.Get("If-Match") != etag(body))) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			fmt.Fprint(w, `<Error><Code>PreconditionFailed</Code></Error>`)
+			return
+		}
+		body, _ = ioutil.ReadAll(r.Body)
+		f.objects[r.URL.Path] = body
+	case "GET":
+		body, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<Error><Code>NoSuchKey</Code></Error>`)
+			return
+		}
+		w.Header().Set("ETag", etag(body))
+		w.Write(body)
+	case "DELETE":
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// fakeDynamoDB is a fake DynamoDB endpoint that implements the items and
+// conditions used to lock states.
+type fakeDynamoDB struct {
+	mu    sync.Mutex
+	items map[string]map[string]*dynamodb.AttributeValue
+}
+
+func (f *fakeDynamoDB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.items == nil {
+		f.items = map[string]map[string]*dynamodb.AttributeValue{}
+	}
+
+	var req struct {
+		Key                       map[string]*dynamodb.AttributeValue
+		Item                      map[string]*dynamodb.AttributeValue
+		ConditionExpression       string
+		ExpressionAttributeNames  map[string]string
+		ExpressionAttributeValues map[string]*dynamodb.AttributeValue
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"__type":"ValidationException","message":%q}`, err)
+		return
+	}
+	key := req.Key
+	if key == nil {
+		key = req.Item
+	}
+	id := *key["LockID"].S
+	item, exists := f.items[id]
+
+	// Only the conditions that the client uses are supported.
+	ok := true
+	switch cond := req.ConditionExpression; {
+	case cond == "":
+	case cond == "attribute_not_exists(LockID)":
+		ok = !exists
+	case strings.HasPrefix(cond, "#"):
+		parts := strings.Split(cond, " = ")
+		v := item[req.ExpressionAttributeNames[parts[0]]]
+		ok = exists && v != nil && v.N != nil && *v.N == *req.ExpressionAttributeValues[parts[1]].N
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"__type":"ValidationException","message":"unsupported condition %s"}`, cond)
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"__type":"com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException","message":"The conditional request failed"}`)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	switch target := r.Header.Get("X-Amz-Target"); target {
+	case "DynamoDB_20120810.GetItem":
+		if !exists {
+			fmt.Fprint(w, `{}`)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"Item": item})
+	case "DynamoDB_20120810.PutItem":
+		f.items[id] = req.Item
+		fmt.Fprint(w, `{}`)
+	case "DynamoDB_20120810.DeleteItem":
+		delete(f.items, id)
+		fmt.Fprint(w, `{}`)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"__type":"ValidationException","message":"unsupported operation %s"}`, target)
+	}
+}
+
+// newFakeClient returns a client of the given fake S3 and DynamoDB
+// endpoints.
+func newFakeClient(s3URL, dynURL string) *RemoteClient {
+	config := &aws.Config{
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+		Region:           aws.String("us-west-2"),
+		S3ForcePathStyle: aws.Bool(true),
+	}
+	sess := session.New(config)
+	return &RemoteClient{
+		s3Client:   s3.New(sess, &aws.Config{Endpoint: aws.String(s3URL)}),
+		dynClient:  dynamodb.New(sess, &aws.Config{Endpoint: aws.String(dynURL)}),
+		bucketName: "bucket",
+		path:       "state",
+		ddbTable:   "locks",
+	}
+}
+
+// verify that resources are locked with the DynamoDB table, excluding locks
+// of overlapping resources and of the whole state
+func TestRemoteClient_resourceLocks(t *testing.T) {
+	s3Server := httptest.NewServer(new(fakeS3))
+	defer s3Server.Close()
+	dyn := new(fakeDynamoDB)
+	dynServer := httptest.NewServer(dyn)
+	defer dynServer.Close()
+
+	var _ remote.ClientResourceLocker = new(RemoteClient)
+	c1 := newFakeClient(s3Server.URL, dynServer.URL)
+	c2 := newFakeClient(s3Server.URL, dynServer.URL)
+
+	s1 := &remote.State{Client: c1}
+	if !state.ResourceLockingSupported(s1) {
+		t.Fatal("resource locking isn't supported")
+	}
+	if state.ResourceLockingSupported(&remote.State{Client: &RemoteClient{}}) {
+		t.Fatal("resource locking is supported without a DynamoDB table")
+	}
+
+	fooID, err := c1.LockResources(state.NewLockInfo(), []string{"test_instance.foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	barID, err := c2.LockResources(state.NewLockInfo(), []string{"test_instance.bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c2.LockResources(state.NewLockInfo(), []string{"test_instance.foo[0]"}); err == nil {
+		t.Fatal("locked a locked resource")
+	} else if lockErr, ok := err.(*state.LockError); !ok || lockErr.Info == nil || lockErr.Info.ID != fooID {
+		t.Fatalf("wrong error: %#v", err)
+	}
+	if _, err := c2.Lock(state.NewLockInfo()); err == nil {
+		t.Fatal("locked the state while its resources are locked")
+	}
+
+	if err := c1.UnlockResources(fooID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c2.Lock(state.NewLockInfo()); err == nil {
+		t.Fatal("locked the state while a resource is locked")
+	}
+	if err := c2.UnlockResources(barID); err != nil {
+		t.Fatal(err)
+	}
+
+	dyn.mu.Lock()
+	if len(dyn.items) != 0 {
+		t.Fatalf("lock items weren't removed: %v", dyn.items)
+	}
+	dyn.mu.Unlock()
+
+	lockID, err := c2.Lock(state.NewLockInfo())
+	if err != nil {
+		t.Fatalf("couldn't lock the state after unlocking its resources: %s", err)
+	}
+	if _, err := c1.LockResources(state.NewLockInfo(), []string{"test_instance.foo"}); err == nil {
+		t.Fatal("locked a resource while the state is locked")
+	}
+	if err := c2.Unlock(lockID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// verify that concurrent writes of resource locks each write their own
+// resources
+func TestRemoteClient_writeResourcesConcurrent(t *testing.T) {
+	s3Server := httptest.NewServer(new(fakeS3))
+	defer s3Server.Close()
+	dynServer := httptest.NewServer(new(fakeDynamoDB))
+	defer dynServer.Close()
+
+	base := terraform.NewState()
+	initial := &remote.State{Client: newFakeClient(s3Server.URL, dynServer.URL)}
+	if err := initial.WriteState(base); err != nil {
+		t.Fatal(err)
+	}
+	if err := initial.PersistState(); err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 5
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			s := &remote.State{Client: newFakeClient(s3Server.URL, dynServer.URL)}
+			addr := fmt.Sprintf("test_instance.foo%d", i)
+			id, err := s.LockResources(state.NewLockInfo(), []string{addr})
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer s.UnlockResources(id)
+
+			v := base.DeepCopy()
+			v.RootModule().Resources[addr] = &terraform.ResourceState{
+				Type:    "test_instance",
+				Primary: &terraform.InstanceState{ID: addr},
+			}
+			if err := s.WriteResources(id, base, v); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	fresh := &remote.State{Client: newFakeClient(s3Server.URL, dynServer.URL)}
+	if err := fresh.RefreshState(); err != nil {
+		t.Fatal(err)
+	}
+	resources := fresh.State().RootModule().Resources
+	for i := 0; i < n; i++ {
+		if _, ok := resources[fmt.Sprintf("test_instance.foo%d", i)]; !ok {
+			t.Fatalf("test_instance.foo%d wasn't written", i)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// Package remote implements the remote backend, which runs operations on a
+// service that implements the remote operations protocol and stores the
+// state of its workspaces there.
+//
+// The protocol is documented in website/docs/backends/types/remote.html.md,
+// so that any service can provide remote runs to Terraform.
+package remote
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/hashicorp/terraform/svchost"
+	"github.com/hashicorp/terraform/svchost/auth"
+	"github.com/hashicorp/terraform/svchost/disco"
+	"github.com/mitchellh/cli"
+	"github.com/mitchellh/colorstring"
+)
+
+// defaultPollInterval is how often the status and log of a run are read
+// while it's in progress.
+const defaultPollInterval = time.Second
+
+// Remote is an implementation of EnhancedBackend that performs operations
+// as runs on a remote service, which also stores the state of the
+// workspaces.
+type Remote struct {
+	*schema.Backend
+
+	// CLI and Colorize control the CLI output. If CLI is nil then no CLI
+	// output will be done. If CLIColor is nil then no coloring will be done.
+	CLI      cli.Ui
+	CLIColor *colorstring.Colorize
+
+	// Services is used to discover the remote operations service of the
+	// configured host, and Credentials to authenticate with it when no
+	// token is configured.
+	Services    *disco.Disco
+	Credentials auth.CredentialsSource
+
+	//---------------------------------------------------------------
+	// Internal fields, do not set
+	//---------------------------------------------------------------
+	hostname     svchost.Hostname
+	organization string
+	token        string
+
+	// Exactly one of workspace and prefix is set. A workspace is a single
+	// remote workspace used as the default workspace, and a prefix maps
+	// each workspace to the remote workspace with the prefixed name.
+	workspace string
+	prefix    string
+
+	// pollInterval is how often runs are polled, which tests shorten.
+	pollInterval time.Duration
+
+	// client is created on first use, since discovery needs the Services
+	// from CLIInit, which may be called after Configure.
+	clientLock sync.Mutex
+	client     *client
+
+	// opLock locks operations
+	opLock sync.Mutex
+}
+
+// New creates a new remote backend.
+func New() backend.Backend {
+	s := &schema.Backend{
+		Schema: map[string]*schema.Schema{
+			"hostname": &schema.Schema{
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: schemaDescriptions["hostname"],
+			},
+
+			"organization": &schema.Schema{
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: schemaDescriptions["organization"],
+			},
+
+			"token": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: schemaDescriptions["token"],
+			},
+
+			"workspaces": &schema.Schema{
+				Type:        schema.TypeList,
+				Required:    true,
+				MaxItems:    1,
+				Description: schemaDescriptions["workspaces"],
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": &schema.Schema{
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: schemaDescriptions["name"],
+						},
+
+						"prefix": &schema.Schema{
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: schemaDescriptions["prefix"],
+						},
+					},
+				},
+			},
+		},
+	}
+
+	b := &Remote{Backend: s, pollInterval: defaultPollInterval}
+	b.Backend.ConfigureFunc = b.configure
+	return b
+}
+
+func (b *Remote) configure(ctx context.Context) error {
+	d := schema.FromContextBackendConfig(ctx)
+
+	hostname, err := svchost.ForComparison(d.Get("hostname").(string))
+	if err != nil {
+		return fmt.Errorf("invalid hostname: %s", err)
+	}
+
+	workspace := d.Get("workspaces.0.name").(string)
+	prefix := d.Get("workspaces.0.prefix").(string)
+	if (workspace == "") == (prefix == "") {
+		return fmt.Errorf("workspaces: exactly one of name and prefix must be set")
+	}
+
+	b.hostname = hostname
+	b.organization = d.Get("organization").(string)
+	b.token = d.Get("token").(string)
+	b.workspace = workspace
+	b.prefix = prefix
+
+	b.clientLock.Lock()
+	b.client = nil
+	b.clientLock.Unlock()
+
+	return nil
+}
+
+func (b *Remote) States() ([]string, error) {
+	if b.prefix == "" {
+		return nil, backend.ErrNamedStatesNotSupported
+	}
+
+	c, err := b.apiClient()
+	if err != nil {
+		return nil, err
+	}
+	names, err := c.Workspaces()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []string{backend.DefaultStateName}
+	for _, name := range names {
+		if !strings.HasPrefix(name, b.prefix) {
+			continue
+		}
+		if name = strings.TrimPrefix(name, b.prefix); name != backend.DefaultStateName {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result[1:])
+	return result, nil
+}
+
+func (b *Remote) DeleteState(name string) error {
+	if name == backend.DefaultStateName {
+		return fmt.Errorf("can't delete default state")
+	}
+	if b.prefix == "" {
+		return backend.ErrNamedStatesNotSupported
+	}
+
+	c, err := b.apiClient()
+	if err != nil {
+		return err
+	}
+	return c.DeleteWorkspace(b.prefix + name)
+}
+
+func (b *Remote) State(name string) (state.State, error) {
+	workspace, err := b.remoteWorkspace(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := b.apiClient()
+	if err != nil {
+		return nil, err
+	}
+	return &remote.State{Client: c.StateClient(workspace)}, nil
+}
+
+// remoteWorkspace returns the name of the remote workspace of the given
+// workspace.
+func (b *Remote) remoteWorkspace(name string) (string, error) {
+	if name == "" {
+		name = backend.DefaultStateName
+	}
+	if b.prefix != "" {
+		return b.prefix + name, nil
+	}
+	if name != backend.DefaultStateName {
+		return "", backend.ErrNamedStatesNotSupported
+	}
+	return b.workspace, nil
+}
+
+// apiClient returns the client of the remote operations service of the
+// configured host, discovering it on first use.
+func (b *Remote) apiClient() (*client, error) {
+	b.clientLock.Lock()
+	defer b.clientLock.Unlock()
+
+	if b.client != nil {
+		return b.client, nil
+	}
+	if b.hostname == "" {
+		return nil, fmt.Errorf("the remote backend isn't configured")
+	}
+
+	services := b.Services
+	if services == nil {
+		services = disco.NewDisco()
+		services.SetCredentialsSource(b.Credentials)
+	}
+	serviceURL := services.DiscoverServiceURL(b.hostname, serviceID)
+	if serviceURL == nil {
+		return nil, fmt.Errorf("host %s does not provide remote operations", b.hostname)
+	}
+
+	var creds auth.HostCredentials
+	if b.token != "" {
+		creds = auth.HostCredentialsToken(b.token)
+	} else if b.Credentials != nil {
+		var err error
+		creds, err = b.Credentials.ForHost(b.hostname)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get credentials for %s: %s", b.hostname, err)
+		}
+	}
+
+	b.client = newClient(serviceURL, b.organization, creds)
+	return b.client, nil
+}
+
+// Colorize returns the Colorize structure that can be used for colorizing
+// output. This is gauranteed to always return a non-nil value and so is useful
+// as a helper to wrap any potentially colored strings.
+func (b *Remote) Colorize() *colorstring.Colorize {
+	if b.CLIColor != nil {
+		return b.CLIColor
+	}
+
+	return &colorstring.Colorize{
+		Colors:  colorstring.DefaultColors,
+		Disable: true,
+	}
+}
+
+var schemaDescriptions = map[string]string{
+	"hostname": "The hostname of the service that runs operations, such as\n" +
+		"'runs.example.com'. The service is found with service discovery.",
+	"organization": "The name of the organization containing the workspaces.",
+	"token": "The token used to authenticate with the service. If it isn't set,\n" +
+		"the credentials for the hostname from the CLI configuration are used.",
+	"workspaces": "The remote workspaces that the workspaces of this\n" +
+		"configuration use.",
+	"name": "The name of the single remote workspace that the default workspace\n" +
+		"uses. Named workspaces aren't supported with this option.",
+	"prefix": "The prefix of the names of the remote workspaces that each\n" +
+		"workspace uses: the workspace 'prod' uses the remote workspace\n" +
+		"with the prefix followed by 'prod'.",
+}
@@ -0,0 +1,310 @@
+package remote
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
+)
+
+func (b *Remote) Operation(ctx context.Context, op *backend.Operation) (*backend.RunningOperation, error) {
+	var operation string
+	switch op.Type {
+	case backend.OperationTypeRefresh:
+		operation = "refresh"
+	case backend.OperationTypePlan:
+		operation = "plan"
+	case backend.OperationTypeApply:
+		operation = "apply"
+	default:
+		return nil, fmt.Errorf("Unsupported operation type: %s", op.Type)
+	}
+
+	switch {
+	case op.Plan != nil:
+		return nil, fmt.Errorf("Applying a saved plan isn't supported by the remote backend.")
+	case op.PlanOutPath != "":
+		return nil, fmt.Errorf("Saving a plan isn't supported by the remote backend.")
+	case op.PlanJSON:
+		return nil, fmt.Errorf("JSON plan output isn't supported by the remote backend.")
+	case op.DryRun:
+		return nil, fmt.Errorf("Dry runs aren't supported by the remote backend.")
+	case op.Resume:
+		return nil, fmt.Errorf("Resuming an apply isn't supported by the remote backend.")
+	case len(op.TargetModules) > 0:
+		return nil, fmt.Errorf("Scoping to modules isn't supported by the remote backend.")
+	case op.ApproveBy != "":
+		return nil, fmt.Errorf("Approving changes by %s isn't supported by the remote backend.", op.ApproveBy)
+	case op.ResidualPlanOutPath != "":
+		return nil, fmt.Errorf("Saving a residual plan isn't supported by the remote backend.")
+	case op.Module == nil:
+		return nil, fmt.Errorf("A configuration is required to run %s remotely.", operation)
+	}
+
+	// Lock
+	b.opLock.Lock()
+
+	// Build our running operation
+	runningCtx, runningCtxCancel := context.WithCancel(context.Background())
+	runningOp := &backend.RunningOperation{Context: runningCtx}
+
+	// Do it
+	go func() {
+		defer b.opLock.Unlock()
+		defer runningCtxCancel()
+		runningOp.Err = b.opRun(ctx, op, operation, runningOp)
+	}()
+
+	// Return
+	return runningOp, nil
+}
+
+// opRun runs the operation remotely, streaming the log of the run to the
+// CLI until the run finishes.
+func (b *Remote) opRun(ctx context.Context, op *backend.Operation, operation string, runningOp *backend.RunningOperation) error {
+	workspace, err := b.remoteWorkspace(op.Workspace)
+	if err != nil {
+		return err
+	}
+	c, err := b.apiClient()
+	if err != nil {
+		return err
+	}
+
+	var archive bytes.Buffer
+	if err := archiveConfig(op.Module.Config().Dir, &archive); err != nil {
+		return errwrap.Wrapf("Error archiving the configuration: {{err}}", err)
+	}
+
+	r, err := c.CreateRun(workspace, &runRequest{
+		Operation:   operation,
+		Destroy:     op.Destroy,
+		RefreshOnly: op.PlanMode == backend.RefreshOnlyMode,
+		Refresh:     op.PlanRefresh,
+		Targets:     op.Targets,
+		Replace:     op.ForceReplace,
+		Variables:   op.Variables,
+	})
+	if err != nil {
+		return err
+	}
+	if err := c.UploadConfiguration(r.ID, &archive); err != nil {
+		return err
+	}
+
+	if b.CLI != nil {
+		b.CLI.Output(b.Colorize().Color(fmt.Sprintf(
+			"[reset][bold]Running %s in the remote workspace %q.[reset]\n"+
+				"Stopping Terraform cancels the remote run.\n", operation, workspace)))
+	}
+
+	log := &logWriter{ui: b.CLI}
+	defer log.Flush()
+
+	var offset int64
+	var confirmed, cancelled bool
+	done := ctx.Done()
+	for {
+		select {
+		case <-done:
+			// Stop waiting for the context, and keep streaming the log
+			// until the run has been cancelled.
+			done = nil
+			cancelled = true
+			if b.CLI != nil {
+				b.CLI.Output("Cancelling the remote run...")
+			}
+			if err := c.RunAction(r.ID, "cancel"); err != nil {
+				return err
+			}
+		case <-time.After(b.pollInterval):
+		}
+
+		if r, err = c.Run(r.ID); err != nil {
+			return err
+		}
+
+		// The log is read after the status, so that once the run has
+		// finished all of its log is read.
+		chunk, err := c.RunLog(r.ID, offset)
+		if err != nil {
+			return err
+		}
+		log.Write(chunk)
+		offset += int64(len(chunk))
+
+		if r.finished() {
+			break
+		}
+		if r.Status != runPlanned || confirmed || cancelled {
+			continue
+		}
+
+		log.Flush()
+		confirmed = true
+		ok, err := b.confirm(op, workspace)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if err := c.RunAction(r.ID, "discard"); err != nil {
+				return err
+			}
+			if op.Destroy {
+				return errors.New("Destroy discarded.")
+			}
+			return errors.New("Apply discarded.")
+		}
+		if err := c.RunAction(r.ID, "apply"); err != nil {
+			return err
+		}
+	}
+	log.Flush()
+
+	switch r.Status {
+	case runErrored:
+		if r.Message != "" {
+			return fmt.Errorf("The remote run failed: %s", r.Message)
+		}
+		return errors.New("The remote run failed; see its log above for details.")
+	case runCanceled:
+		return errors.New("The remote run was cancelled.")
+	case runDiscarded:
+		return errors.New("The remote run was discarded.")
+	}
+
+	runningOp.PlanEmpty = !r.HasChanges
+
+	// Read the resulting state, such as for displaying outputs.
+	s, err := b.State(op.Workspace)
+	if err != nil {
+		return err
+	}
+	if err := s.RefreshState(); err != nil {
+		return errwrap.Wrapf("Error reading the state of the remote workspace: {{err}}", err)
+	}
+	runningOp.State = s.State()
+	return nil
+}
+
+// confirm asks whether the plan of a run should be applied, unless the
+// operation doesn't require approval.
+func (b *Remote) confirm(op *backend.Operation, workspace string) (bool, error) {
+	if op.Destroy && op.DestroyForce || !op.Destroy && op.AutoApprove {
+		return true, nil
+	}
+	if op.UIIn == nil {
+		return false, errors.New("The plan must be approved, but there's no input to approve it with.")
+	}
+
+	var desc, query string
+	if op.Destroy {
+		desc = "Terraform will destroy all your managed infrastructure, as shown above.\n" +
+			"There is no undo. Only 'yes' will be accepted to confirm."
+		query = fmt.Sprintf("Do you really want to destroy the remote workspace %q?", workspace)
+	} else {
+		desc = "Terraform will perform the actions described above.\n" +
+			"Only 'yes' will be accepted to approve."
+		query = fmt.Sprintf("Do you want to perform these actions in the remote workspace %q?", workspace)
+	}
+
+	v, err := op.UIIn.Input(&terraform.InputOpts{
+		Id:          "approve",
+		Query:       query,
+		Description: desc,
+	})
+	if err != nil {
+		return false, errwrap.Wrapf("Error asking for approval: {{err}}", err)
+	}
+	return v == "yes", nil
+}
+
+// archiveConfig writes a gzipped tar archive of the configuration in the
+// given directory to w. The .terraform and .git directories are left out,
+// since the service initializes the configuration itself.
+func archiveConfig(dir string, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if name := info.Name(); rel != "." && (name == ".terraform" || name == ".git") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// logWriter writes the log of a run to the CLI a line at a time.
+type logWriter struct {
+	ui  cli.Ui
+	buf []byte
+}
+
+func (w *logWriter) Write(p []byte) {
+	w.buf = append(w.buf, p...)
+	i := bytes.LastIndexByte(w.buf, '\n')
+	if i < 0 {
+		return
+	}
+	if w.ui != nil {
+		w.ui.Output(string(w.buf[:i]))
+	}
+	w.buf = append(w.buf[:0], w.buf[i+1:]...)
+}
+
+// Flush writes the last line of the log, if it didn't end with a newline.
+func (w *logWriter) Flush() {
+	if len(w.buf) > 0 && w.ui != nil {
+		w.ui.Output(strings.TrimRight(string(w.buf), "\r"))
+	}
+	w.buf = w.buf[:0]
+}
@@ -0,0 +1,591 @@
+package remote
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/hashicorp/terraform/svchost"
+	"github.com/hashicorp/terraform/svchost/disco"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
+)
+
+func TestRemote_impl(t *testing.T) {
+	var _ backend.Enhanced = new(Remote)
+	var _ backend.CLI = new(Remote)
+}
+
+func TestRemote_config(t *testing.T) {
+	cases := map[string]struct {
+		Workspaces map[string]interface{}
+		Err        string
+	}{
+		"name":    {map[string]interface{}{"name": "app"}, ""},
+		"prefix":  {map[string]interface{}{"prefix": "app-"}, ""},
+		"neither": {map[string]interface{}{}, "exactly one of name and prefix"},
+		"both":    {map[string]interface{}{"name": "app", "prefix": "app-"}, "exactly one of name and prefix"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rc, err := config.NewRawConfig(map[string]interface{}{
+				"hostname":     "runs.example.com",
+				"organization": "example",
+				"workspaces":   []interface{}{tc.Workspaces},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = New().Configure(terraform.NewResourceConfig(rc))
+			if tc.Err == "" && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if tc.Err != "" && (err == nil || !strings.Contains(err.Error(), tc.Err)) {
+				t.Fatalf("wrong error %v; want %q", err, tc.Err)
+			}
+		})
+	}
+}
+
+func TestRemote_noService(t *testing.T) {
+	s := newTestService(t)
+	defer s.Close()
+
+	b := testRemote(t, s, map[string]interface{}{"name": "app"})
+	b.Services.ForceHostServices(svchost.Hostname("runs.example.com"), nil)
+	b.client = nil
+
+	_, err := b.State(backend.DefaultStateName)
+	if err == nil || !strings.Contains(err.Error(), "does not provide remote operations") {
+		t.Fatalf("wrong error %v", err)
+	}
+}
+
+func TestRemote_unauthorized(t *testing.T) {
+	s := newTestService(t)
+	defer s.Close()
+
+	b := testRemote(t, s, map[string]interface{}{"name": "app"})
+	b.token = "wrong"
+	b.client = nil
+
+	sMgr, err := b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sMgr.RefreshState(); err == nil {
+		t.Fatal("succeeded with the wrong token; want error")
+	}
+}
+
+func TestRemote_backend(t *testing.T) {
+	s := newTestService(t)
+	defer s.Close()
+
+	b1 := testRemote(t, s, map[string]interface{}{"prefix": "app-"})
+	b2 := testRemote(t, s, map[string]interface{}{"prefix": "app-"})
+	backend.TestBackend(t, b1, b2)
+}
+
+func TestRemote_stateClient(t *testing.T) {
+	s := newTestService(t)
+	defer s.Close()
+
+	b := testRemote(t, s, map[string]interface{}{"name": "app"})
+	c, err := b.apiClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote.TestClient(t, c.StateClient("app"))
+	remote.TestRemoteLocks(t, c.StateClient("app"), c.StateClient("app"))
+}
+
+func TestRemote_workspaceName(t *testing.T) {
+	s := newTestService(t)
+	defer s.Close()
+
+	b := testRemote(t, s, map[string]interface{}{"name": "app"})
+	if _, err := b.States(); err != backend.ErrNamedStatesNotSupported {
+		t.Fatalf("wrong error %v", err)
+	}
+	if _, err := b.State("foo"); err != backend.ErrNamedStatesNotSupported {
+		t.Fatalf("wrong error %v", err)
+	}
+
+	sMgr, err := b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sMgr.WriteState(terraform.NewState()); err != nil {
+		t.Fatal(err)
+	}
+	if err := sMgr.PersistState(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.states["app"]; !ok {
+		t.Fatalf("state wasn't stored in the remote workspace; have %v", s.workspaces())
+	}
+}
+
+func TestRemote_plan(t *testing.T) {
+	s := newTestService(t)
+	defer s.Close()
+
+	b := testRemote(t, s, map[string]interface{}{"prefix": "app-"})
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/simple")
+	defer modCleanup()
+
+	op := &backend.Operation{
+		Type:        backend.OperationTypePlan,
+		Module:      mod,
+		PlanRefresh: true,
+		Targets:     []string{"null_resource.foo"},
+		Variables:   map[string]interface{}{"region": "us-east-1"},
+		Workspace:   "prod",
+	}
+	run := testOperation(t, b, context.Background(), op)
+	if run.Err != nil {
+		t.Fatalf("unexpected error: %s", run.Err)
+	}
+	if run.PlanEmpty {
+		t.Fatal("plan should not be empty")
+	}
+
+	r := s.lastRun()
+	if r.workspace != "app-prod" {
+		t.Fatalf("run in workspace %q; want app-prod", r.workspace)
+	}
+	if r.req.Operation != "plan" || !r.req.Refresh || r.req.Targets[0] != "null_resource.foo" || r.req.Variables["region"] != "us-east-1" {
+		t.Fatalf("wrong run request %#v", r.req)
+	}
+	if _, ok := r.files["main.tf"]; !ok {
+		t.Fatalf("main.tf wasn't uploaded; have %v", r.files)
+	}
+	if _, ok := r.files[".terraform/terraform.tfstate"]; ok {
+		t.Fatal("the .terraform directory was uploaded")
+	}
+
+	output := b.CLI.(*cli.MockUi).OutputWriter.String()
+	if !strings.Contains(output, "Plan: 1 to add") {
+		t.Fatalf("log wasn't streamed:\n%s", output)
+	}
+}
+
+func TestRemote_apply(t *testing.T) {
+	s := newTestService(t)
+	defer s.Close()
+
+	b := testRemote(t, s, map[string]interface{}{"name": "app"})
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/simple")
+	defer modCleanup()
+
+	input := &terraform.MockUIInput{InputReturnString: "yes"}
+	op := &backend.Operation{
+		Type:   backend.OperationTypeApply,
+		Module: mod,
+		UIIn:   input,
+	}
+	run := testOperation(t, b, context.Background(), op)
+	if run.Err != nil {
+		t.Fatalf("unexpected error: %s", run.Err)
+	}
+	if !input.InputCalled || !strings.Contains(input.InputOpts.Query, `remote workspace "app"`) {
+		t.Fatalf("wrong approval %#v", input.InputOpts)
+	}
+
+	r := s.lastRun()
+	if got, want := r.actions, []string{"apply"}; !stringsEqual(got, want) {
+		t.Fatalf("wrong actions %v; want %v", got, want)
+	}
+	if run.State == nil || run.State.RootModule().Outputs["id"] == nil {
+		t.Fatalf("wrong state after apply: %s", run.State)
+	}
+
+	output := b.CLI.(*cli.MockUi).OutputWriter.String()
+	if !strings.Contains(output, "Apply complete!") {
+		t.Fatalf("log wasn't streamed:\n%s", output)
+	}
+}
+
+func TestRemote_applyDiscard(t *testing.T) {
+	s := newTestService(t)
+	defer s.Close()
+
+	b := testRemote(t, s, map[string]interface{}{"name": "app"})
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/simple")
+	defer modCleanup()
+
+	op := &backend.Operation{
+		Type:   backend.OperationTypeApply,
+		Module: mod,
+		UIIn:   &terraform.MockUIInput{InputReturnString: "no"},
+	}
+	run := testOperation(t, b, context.Background(), op)
+	if run.Err == nil || run.Err.Error() != "Apply discarded." {
+		t.Fatalf("wrong error %v", run.Err)
+	}
+
+	r := s.lastRun()
+	if got, want := r.actions, []string{"discard"}; !stringsEqual(got, want) {
+		t.Fatalf("wrong actions %v; want %v", got, want)
+	}
+	if _, ok := s.states["app"]; ok {
+		t.Fatal("discarded run wrote state")
+	}
+}
+
+func TestRemote_applyAutoApprove(t *testing.T) {
+	s := newTestService(t)
+	defer s.Close()
+
+	b := testRemote(t, s, map[string]interface{}{"name": "app"})
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/simple")
+	defer modCleanup()
+
+	input := &terraform.MockUIInput{}
+	op := &backend.Operation{
+		Type:        backend.OperationTypeApply,
+		Module:      mod,
+		AutoApprove: true,
+		UIIn:        input,
+	}
+	run := testOperation(t, b, context.Background(), op)
+	if run.Err != nil {
+		t.Fatalf("unexpected error: %s", run.Err)
+	}
+	if input.InputCalled {
+		t.Fatal("asked for approval with auto-approve")
+	}
+}
+
+func TestRemote_applyCancel(t *testing.T) {
+	s := newTestService(t)
+	defer s.Close()
+
+	b := testRemote(t, s, map[string]interface{}{"name": "app"})
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/simple")
+	defer modCleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	op := &backend.Operation{
+		Type:   backend.OperationTypeApply,
+		Module: mod,
+		UIIn: &terraform.MockUIInput{
+			InputFn: func(*terraform.InputOpts) (string, error) {
+				// Cancel while waiting for approval, as on an interrupt.
+				cancel()
+				return "yes", nil
+			},
+		},
+	}
+	s.holdApply = true
+	run := testOperation(t, b, ctx, op)
+	if run.Err == nil || run.Err.Error() != "The remote run was cancelled." {
+		t.Fatalf("wrong error %v", run.Err)
+	}
+
+	r := s.lastRun()
+	if got, want := r.actions, []string{"apply", "cancel"}; !stringsEqual(got, want) {
+		t.Fatalf("wrong actions %v; want %v", got, want)
+	}
+}
+
+func TestRemote_unsupported(t *testing.T) {
+	s := newTestService(t)
+	defer s.Close()
+
+	b := testRemote(t, s, map[string]interface{}{"name": "app"})
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/simple")
+	defer modCleanup()
+
+	ops := map[string]*backend.Operation{
+		"plan out":      {Type: backend.OperationTypePlan, Module: mod, PlanOutPath: "foo.tfplan"},
+		"plan json":     {Type: backend.OperationTypePlan, Module: mod, PlanJSON: true},
+		"dry run":       {Type: backend.OperationTypeApply, Module: mod, DryRun: true},
+		"resume":        {Type: backend.OperationTypeApply, Module: mod, Resume: true},
+		"saved":         {Type: backend.OperationTypeApply, Module: mod, Plan: &terraform.Plan{}},
+		"approve by":    {Type: backend.OperationTypeApply, Module: mod, ApproveBy: backend.ApproveByResource},
+		"residual plan": {Type: backend.OperationTypeApply, Module: mod, ResidualPlanOutPath: "foo.tfplan"},
+		"module":        {Type: backend.OperationTypePlan},
+	}
+	for name, op := range ops {
+		if _, err := b.Operation(context.Background(), op); err == nil {
+			t.Errorf("%s: succeeded; want error", name)
+		}
+	}
+}
+
+func testRemote(t *testing.T, s *testService, workspaces map[string]interface{}) *Remote {
+	t.Helper()
+
+	b := backend.TestBackendConfig(t, New(), map[string]interface{}{
+		"hostname":     "runs.example.com",
+		"organization": "example",
+		"token":        testToken,
+		"workspaces":   []interface{}{workspaces},
+	}).(*Remote)
+
+	services := disco.NewDisco()
+	services.ForceHostServices(svchost.Hostname("runs.example.com"), map[string]interface{}{
+		serviceID: s.URL + "/api/v1/",
+	})
+	if err := b.CLIInit(&backend.CLIOpts{CLI: cli.NewMockUi(), Services: services}); err != nil {
+		t.Fatal(err)
+	}
+	b.pollInterval = time.Millisecond
+	return b
+}
+
+func testOperation(t *testing.T, b *Remote, ctx context.Context, op *backend.Operation) *backend.RunningOperation {
+	t.Helper()
+
+	run, err := b.Operation(ctx, op)
+	if err != nil {
+		t.Fatalf("error starting operation: %s", err)
+	}
+
+	select {
+	case <-run.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("operation didn't finish")
+	}
+	return run
+}
+
+func stringsEqual(a, b []string) bool {
+	return strings.Join(a, ",") == strings.Join(b, ",")
+}
+
+const testToken = "test-token"
+
+// testService is a remote operations service for testing, which runs each
+// run instantly once its configuration is uploaded.
+type testService struct {
+	*httptest.Server
+	t *testing.T
+
+	// holdApply keeps confirmed runs applying until they're cancelled.
+	holdApply bool
+
+	mu     sync.Mutex
+	states map[string][]byte
+	locks  map[string][]byte
+	runs   []*testRun
+}
+
+type testRun struct {
+	run
+	workspace string
+	req       runRequest
+	files     map[string]string
+	log       bytes.Buffer
+	actions   []string
+}
+
+func newTestService(t *testing.T) *testService {
+	s := &testService{
+		t:      t,
+		states: make(map[string][]byte),
+		locks:  make(map[string][]byte),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *testService) workspaces() []string {
+	var names []string
+	for name := range s.states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *testService) lastRun() *testRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.runs[len(s.runs)-1]
+}
+
+func (s *testService) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+testToken {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Header.Get(xTerraformVersion) == "" {
+		http.Error(w, "missing version", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/")
+	switch {
+	case len(parts) >= 3 && parts[0] == "organizations" && parts[1] == "example" && parts[2] == "workspaces":
+		s.serveWorkspaces(w, r, parts[3:])
+	case len(parts) >= 2 && parts[0] == "runs":
+		id, err := strconv.Atoi(parts[1])
+		if err != nil || id >= len(s.runs) {
+			http.NotFound(w, r)
+			return
+		}
+		s.serveRun(w, r, s.runs[id], parts[2:])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *testService) serveWorkspaces(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case len(parts) == 0 && r.Method == "GET":
+		var resp struct {
+			Workspaces []map[string]string `json:"workspaces"`
+		}
+		for _, name := range s.workspaces() {
+			resp.Workspaces = append(resp.Workspaces, map[string]string{"name": name})
+		}
+		json.NewEncoder(w).Encode(resp)
+	case len(parts) == 1 && r.Method == "DELETE":
+		delete(s.states, parts[0])
+	case len(parts) == 2 && parts[1] == "state":
+		switch r.Method {
+		case "GET":
+			data, ok := s.states[parts[0]]
+			if !ok {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Write(data)
+		case "PUT":
+			data, _ := ioutil.ReadAll(r.Body)
+			s.states[parts[0]] = data
+		case "DELETE":
+			delete(s.states, parts[0])
+		}
+	case len(parts) == 2 && parts[1] == "lock":
+		switch r.Method {
+		case "POST":
+			if lock, ok := s.locks[parts[0]]; ok {
+				w.WriteHeader(http.StatusConflict)
+				w.Write(lock)
+				return
+			}
+			s.locks[parts[0]], _ = ioutil.ReadAll(r.Body)
+		case "DELETE":
+			delete(s.locks, parts[0])
+		}
+	case len(parts) == 2 && parts[1] == "runs" && r.Method == "POST":
+		tr := &testRun{workspace: parts[0]}
+		if err := json.NewDecoder(r.Body).Decode(&tr.req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tr.run = run{ID: strconv.Itoa(len(s.runs)), Status: "pending"}
+		s.runs = append(s.runs, tr)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(tr.run)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *testService) serveRun(w http.ResponseWriter, r *http.Request, tr *testRun, parts []string) {
+	switch {
+	case len(parts) == 0 && r.Method == "GET":
+		json.NewEncoder(w).Encode(tr.run)
+	case len(parts) == 1 && parts[0] == "configuration" && r.Method == "PUT":
+		files, err := testUnarchive(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tr.files = files
+		tr.log.WriteString("Planning...\nPlan: 1 to add, 0 to change, 0 to destroy.\n")
+		tr.HasChanges = true
+		switch tr.req.Operation {
+		case "apply":
+			tr.Status = runPlanned
+		default:
+			tr.Status = runPlannedAndFinished
+		}
+	case len(parts) == 1 && parts[0] == "log" && r.Method == "GET":
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		w.Write(tr.log.Bytes()[offset:])
+	case len(parts) == 2 && parts[0] == "actions" && r.Method == "POST":
+		tr.actions = append(tr.actions, parts[1])
+		switch parts[1] {
+		case "apply":
+			if s.holdApply {
+				tr.Status = "applying"
+				return
+			}
+			s.states[tr.workspace] = testAppliedState(s.t)
+			tr.log.WriteString("Apply complete! Resources: 1 added, 0 changed, 0 destroyed.")
+			tr.Status = runApplied
+		case "discard":
+			tr.Status = runDiscarded
+		case "cancel":
+			tr.log.WriteString("Cancelled.\n")
+			tr.Status = runCanceled
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func testUnarchive(r io.Reader) (map[string]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+
+	files := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[hdr.Name] = string(data)
+	}
+}
+
+func testAppliedState(t *testing.T) []byte {
+	st := terraform.NewState()
+	st.RootModule().Outputs["id"] = &terraform.OutputState{
+		Type:  "string",
+		Value: "foo",
+	}
+
+	var buf bytes.Buffer
+	if err := terraform.WriteState(st, &buf); err != nil {
+		t.Fatalf("error writing state: %s", err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,14 @@
+package remote
+
+import (
+	"github.com/hashicorp/terraform/backend"
+)
+
+// backend.CLI impl.
+func (b *Remote) CLIInit(opts *backend.CLIOpts) error {
+	b.CLI = opts.CLI
+	b.CLIColor = opts.CLIColor
+	b.Services = opts.Services
+	b.Credentials = opts.Credentials
+	return nil
+}
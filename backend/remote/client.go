@@ -0,0 +1,283 @@
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/hashicorp/terraform/svchost/auth"
+	"github.com/hashicorp/terraform/version"
+)
+
+const (
+	// serviceID is the service that hosts running remote operations
+	// publish in their discovery documents.
+	serviceID = "operations.v1"
+
+	xTerraformVersion = "X-Terraform-Version"
+)
+
+// The statuses of runs. A run with any other status is in progress.
+const (
+	// runPlanned is the status of an apply run whose plan has changes and
+	// is waiting to be confirmed or discarded.
+	runPlanned = "planned"
+
+	// The statuses of runs that have finished.
+	runPlannedAndFinished = "planned_and_finished"
+	runApplied            = "applied"
+	runDiscarded          = "discarded"
+	runCanceled           = "canceled"
+	runErrored            = "errored"
+)
+
+// run is a run of an operation, as returned by the service.
+type run struct {
+	ID         string `json:"id"`
+	Status     string `json:"status"`
+	HasChanges bool   `json:"has_changes"`
+
+	// Message explains the status, such as why the run errored.
+	Message string `json:"message,omitempty"`
+}
+
+// finished returns true if the run won't change status again.
+func (r *run) finished() bool {
+	switch r.Status {
+	case runPlannedAndFinished, runApplied, runDiscarded, runCanceled, runErrored:
+		return true
+	default:
+		return false
+	}
+}
+
+// runRequest is the body of a request to create a run.
+type runRequest struct {
+	// Operation is "plan", "apply" or "refresh".
+	Operation string `json:"operation"`
+
+	Destroy     bool                   `json:"destroy,omitempty"`
+	RefreshOnly bool                   `json:"refresh_only,omitempty"`
+	Refresh     bool                   `json:"refresh"`
+	Targets     []string               `json:"targets,omitempty"`
+	Replace     []string               `json:"replace,omitempty"`
+	Variables   map[string]interface{} `json:"variables,omitempty"`
+}
+
+// client is a client of the remote operations service of a host, for the
+// workspaces of one organization.
+type client struct {
+	baseURL      *url.URL
+	organization string
+	http         *http.Client
+}
+
+func newClient(baseURL *url.URL, organization string, creds auth.HostCredentials) *client {
+	u := *baseURL
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	httpClient := cleanhttp.DefaultPooledClient()
+	httpClient.Transport = &credentialsTransport{
+		base:  httpClient.Transport,
+		creds: creds,
+	}
+
+	return &client{
+		baseURL:      &u,
+		organization: organization,
+		http:         httpClient,
+	}
+}
+
+// Workspaces returns the names of the remote workspaces of the
+// organization.
+func (c *client) Workspaces() ([]string, error) {
+	var resp struct {
+		Workspaces []struct {
+			Name string `json:"name"`
+		} `json:"workspaces"`
+	}
+	if err := c.do("GET", c.workspacesURL(""), nil, "", &resp); err != nil {
+		return nil, fmt.Errorf("failed to list workspaces: %s", err)
+	}
+
+	names := make([]string, len(resp.Workspaces))
+	for i, w := range resp.Workspaces {
+		names[i] = w.Name
+	}
+	return names, nil
+}
+
+// DeleteWorkspace deletes the given remote workspace and its state.
+func (c *client) DeleteWorkspace(name string) error {
+	if err := c.do("DELETE", c.workspacesURL(name), nil, "", nil); err != nil {
+		return fmt.Errorf("failed to delete workspace %q: %s", name, err)
+	}
+	return nil
+}
+
+// StateClient returns the client of the state of the given remote
+// workspace, which is locked as with the http backend.
+func (c *client) StateClient(workspace string) remote.Client {
+	lockURL := c.workspacesURL(workspace, "lock")
+	return &remote.HTTPClient{
+		URL:          c.workspacesURL(workspace, "state"),
+		UpdateMethod: "PUT",
+		LockURL:      lockURL,
+		LockMethod:   "POST",
+		UnlockURL:    lockURL,
+		UnlockMethod: "DELETE",
+		Client:       c.http,
+	}
+}
+
+// CreateRun creates a run in the given remote workspace, which starts once
+// its configuration is uploaded.
+func (c *client) CreateRun(workspace string, req *runRequest) (*run, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var r run
+	if err := c.do("POST", c.workspacesURL(workspace, "runs"), bytes.NewReader(body), "application/json", &r); err != nil {
+		return nil, fmt.Errorf("failed to create run: %s", err)
+	}
+	return &r, nil
+}
+
+// UploadConfiguration uploads the configuration of a run, as a gzipped tar
+// archive.
+func (c *client) UploadConfiguration(id string, archive io.Reader) error {
+	if err := c.do("PUT", c.runURL(id, "configuration"), archive, "application/gzip", nil); err != nil {
+		return fmt.Errorf("failed to upload configuration: %s", err)
+	}
+	return nil
+}
+
+// Run returns the current status of a run.
+func (c *client) Run(id string) (*run, error) {
+	var r run
+	if err := c.do("GET", c.runURL(id), nil, "", &r); err != nil {
+		return nil, fmt.Errorf("failed to read run: %s", err)
+	}
+	return &r, nil
+}
+
+// RunLog returns the log of a run from the given offset, up to what has
+// been written so far.
+func (c *client) RunLog(id string, offset int64) ([]byte, error) {
+	u := c.runURL(id, "log")
+	u.RawQuery = url.Values{"offset": {strconv.FormatInt(offset, 10)}}.Encode()
+
+	var buf bytes.Buffer
+	if err := c.do("GET", u, nil, "", &buf); err != nil {
+		return nil, fmt.Errorf("failed to read run log: %s", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// RunAction performs the given action on a run: "apply" confirms its plan,
+// "discard" discards its plan and "cancel" cancels it.
+func (c *client) RunAction(id, action string) error {
+	if err := c.do("POST", c.runURL(id, "actions", action), nil, "", nil); err != nil {
+		return fmt.Errorf("failed to %s run: %s", action, err)
+	}
+	return nil
+}
+
+func (c *client) workspacesURL(name string, elems ...string) *url.URL {
+	p := path.Join("organizations", url.PathEscape(c.organization), "workspaces")
+	if name != "" {
+		p = path.Join(append([]string{p, url.PathEscape(name)}, elems...)...)
+	}
+	return c.url(p)
+}
+
+func (c *client) runURL(id string, elems ...string) *url.URL {
+	return c.url(path.Join(append([]string{"runs", url.PathEscape(id)}, elems...)...))
+}
+
+func (c *client) url(p string) *url.URL {
+	ref, err := url.Parse(p)
+	if err != nil {
+		// The path is built from escaped elements, so this is a bug.
+		panic(err)
+	}
+	return c.baseURL.ResolveReference(ref)
+}
+
+// do makes a request, and decodes the response into out: as JSON, or as is
+// if out is a *bytes.Buffer.
+func (c *client) do(method string, u *url.URL, body io.Reader, contentType string, out interface{}) error {
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("the service requires authentication")
+	case resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("the credentials aren't authorized")
+	case resp.StatusCode/100 != 2:
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		if len(bytes.TrimSpace(msg)) == 0 {
+			return fmt.Errorf("unexpected response %s", resp.Status)
+		}
+		return fmt.Errorf("unexpected response %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	switch out := out.(type) {
+	case nil:
+		return nil
+	case *bytes.Buffer:
+		_, err := io.Copy(out, resp.Body)
+		return err
+	default:
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+}
+
+// credentialsTransport is an http.RoundTripper that adds the credentials
+// and Terraform version to each request.
+type credentialsTransport struct {
+	base  http.RoundTripper
+	creds auth.HostCredentials
+}
+
+func (t *credentialsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the given request.
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+
+	r.Header.Set(xTerraformVersion, version.String())
+	if t.creds != nil {
+		t.creds.PrepareRequest(r)
+	}
+	return t.base.RoundTrip(r)
+}
@@ -0,0 +1,11 @@
+variable "region" {}
+
+resource "null_resource" "foo" {
+  triggers {
+    region = "${var.region}"
+  }
+}
+
+output "id" {
+  value = "${null_resource.foo.id}"
+}
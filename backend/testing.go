@@ -50,6 +50,7 @@ func TestBackend(t *testing.T, b1, b2 Backend) {
 	t.Helper()
 
 	testBackendStates(t, b1)
+	testBackendWorkspaceMetadata(t, b1)
 
 	if b2 != nil {
 		testBackendStateLock(t, b1, b2)
@@ -236,6 +237,50 @@ func testBackendStates(t *testing.T, b Backend) {
 	}
 }
 
+func testBackendWorkspaceMetadata(t *testing.T, b Backend) {
+	t.Helper()
+
+	meta := map[string]string{"owner": "alice", "env": "test"}
+	if err := SetWorkspaceMetadata(b, DefaultStateName, meta); err != nil {
+		t.Fatalf("error setting metadata: %s", err)
+	}
+
+	actual, err := WorkspaceMetadata(b, DefaultStateName)
+	if err != nil {
+		t.Fatalf("error reading metadata: %s", err)
+	}
+	if !reflect.DeepEqual(actual, meta) {
+		t.Fatalf("wrong metadata %#v; want %#v", actual, meta)
+	}
+
+	names, err := WorkspacesWithMetadata(b, "owner", "alice")
+	if err != nil {
+		t.Fatalf("error finding workspaces: %s", err)
+	}
+	if expected := []string{DefaultStateName}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("wrong workspaces %#v; want %#v", names, expected)
+	}
+	names, err = WorkspacesWithMetadata(b, "owner", "bob")
+	if err != nil {
+		t.Fatalf("error finding workspaces: %s", err)
+	}
+	if len(names) != 0 {
+		t.Fatalf("unexpected workspaces %#v", names)
+	}
+
+	// Clearing the metadata leaves it empty.
+	if err := SetWorkspaceMetadata(b, DefaultStateName, nil); err != nil {
+		t.Fatalf("error clearing metadata: %s", err)
+	}
+	actual, err = WorkspaceMetadata(b, DefaultStateName)
+	if err != nil {
+		t.Fatalf("error reading metadata: %s", err)
+	}
+	if len(actual) != 0 {
+		t.Fatalf("unexpected metadata %#v", actual)
+	}
+}
+
 func testBackendStateLock(t *testing.T, b1, b2 Backend) {
 	t.Helper()
 
@@ -0,0 +1,120 @@
+package backend
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// MetadataIndexer is implemented by backends that index workspaces by
+// their metadata, so that the workspaces with some metadata can be found
+// without reading the state of every workspace.
+//
+// The metadata itself is always stored in the state of the workspace, so
+// backends don't need to implement this to support metadata.
+type MetadataIndexer interface {
+	// IndexWorkspaceMetadata is called after the metadata of the named
+	// workspace is set, with the new metadata.
+	IndexWorkspaceMetadata(name string, meta map[string]string) error
+
+	// WorkspacesWithMetadata returns the names of the workspaces whose
+	// metadata has the given value for the given key.
+	WorkspacesWithMetadata(key, value string) ([]string, error)
+}
+
+// WorkspaceMetadata returns the metadata of the named workspace, which is
+// empty if none has been set.
+func WorkspaceMetadata(b Backend, name string) (map[string]string, error) {
+	sMgr, err := b.State(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := sMgr.RefreshState(); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string)
+	if s := sMgr.State(); s != nil {
+		for k, v := range s.Metadata {
+			result[k] = v
+		}
+	}
+	return result, nil
+}
+
+// SetWorkspaceMetadata replaces the metadata of the named workspace with
+// the given metadata, and updates the index of the backend if it is a
+// MetadataIndexer.
+//
+// The metadata is written to the state of the workspace. It's the
+// responsibility of the caller to hold a lock on the state.
+func SetWorkspaceMetadata(b Backend, name string, meta map[string]string) error {
+	sMgr, err := b.State(name)
+	if err != nil {
+		return err
+	}
+	if err := sMgr.RefreshState(); err != nil {
+		return err
+	}
+
+	s := sMgr.State()
+	if s == nil {
+		s = terraform.NewState()
+	}
+	s.Metadata = nil
+	if len(meta) > 0 {
+		s.Metadata = make(map[string]string, len(meta))
+		for k, v := range meta {
+			s.Metadata[k] = v
+		}
+	}
+
+	if err := sMgr.WriteState(s); err != nil {
+		return err
+	}
+	if err := sMgr.PersistState(); err != nil {
+		return err
+	}
+
+	if idx, ok := b.(MetadataIndexer); ok {
+		if err := idx.IndexWorkspaceMetadata(name, meta); err != nil {
+			return fmt.Errorf("Error indexing the metadata of workspace %q: %s", name, err)
+		}
+	}
+
+	return nil
+}
+
+// WorkspacesWithMetadata returns the sorted names of the workspaces whose
+// metadata has the given value for the given key. If the backend is a
+// MetadataIndexer its index is used, otherwise the state of each workspace
+// is read.
+func WorkspacesWithMetadata(b Backend, key, value string) ([]string, error) {
+	if idx, ok := b.(MetadataIndexer); ok {
+		result, err := idx.WorkspacesWithMetadata(key, value)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(result)
+		return result, nil
+	}
+
+	names, err := b.States()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, name := range names {
+		meta, err := WorkspaceMetadata(b, name)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading the metadata of workspace %q: %s", name, err)
+		}
+		if v, ok := meta[key]; ok && v == value {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
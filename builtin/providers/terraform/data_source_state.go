@@ -64,44 +64,16 @@ func dataSourceRemoteState() *schema.Resource {
 }
 
 func dataSourceRemoteStateRead(d *schema.ResourceData, meta interface{}) error {
-	backend := d.Get("backend").(string)
-
-	// Get the configuration in a type we want.
-	rawConfig, err := config.NewRawConfig(d.Get("config").(map[string]interface{}))
-	if err != nil {
-		return fmt.Errorf("error initializing backend: %s", err)
-	}
-
-	// Don't break people using the old _local syntax - but note warning above
-	if backend == "_local" {
-		log.Println(`[INFO] Switching old (unsupported) backend "_local" to "local"`)
-		backend = "local"
-	}
-
-	// Create the client to access our remote state
-	log.Printf("[DEBUG] Initializing remote state backend: %s", backend)
-	f := backendinit.Backend(backend)
-	if f == nil {
-		return fmt.Errorf("Unknown backend type: %s", backend)
-	}
-	b := f()
-
-	// Configure the backend
-	if err := b.Configure(terraform.NewResourceConfig(rawConfig)); err != nil {
-		return fmt.Errorf("error initializing backend: %s", err)
-	}
-
 	// environment is deprecated in favour of workspace.
 	// If both keys are set workspace should win.
 	name := d.Get("environment").(string)
 	if ws, ok := d.GetOk("workspace"); ok {
 		name = ws.(string)
 	}
-	state, err := b.State(name)
+
+	remoteState, err := readRemoteState(
+		d.Get("backend").(string), d.Get("config").(map[string]interface{}), name)
 	if err != nil {
-		return fmt.Errorf("error loading the remote state: %s", err)
-	}
-	if err := state.RefreshState(); err != nil {
 		return err
 	}
 	d.SetId(time.Now().UTC().String())
@@ -113,7 +85,6 @@ func dataSourceRemoteStateRead(d *schema.ResourceData, meta interface{}) error {
 		outputMap[key] = val
 	}
 
-	remoteState := state.State()
 	if remoteState.Empty() {
 		log.Println("[DEBUG] empty remote state")
 	} else {
@@ -130,3 +101,41 @@ func dataSourceRemoteStateRead(d *schema.ResourceData, meta interface{}) error {
 
 	return nil
 }
+
+// readRemoteState configures the backend of the given type with the given
+// configuration, and returns the latest state of the given workspace.
+func readRemoteState(backend string, cfg map[string]interface{}, workspace string) (*terraform.State, error) {
+	// Get the configuration in a type we want.
+	rawConfig, err := config.NewRawConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing backend: %s", err)
+	}
+
+	// Don't break people using the old _local syntax - but note warning above
+	if backend == "_local" {
+		log.Println(`[INFO] Switching old (unsupported) backend "_local" to "local"`)
+		backend = "local"
+	}
+
+	// Create the client to access our remote state
+	log.Printf("[DEBUG] Initializing remote state backend: %s", backend)
+	f := backendinit.Backend(backend)
+	if f == nil {
+		return nil, fmt.Errorf("Unknown backend type: %s", backend)
+	}
+	b := f()
+
+	// Configure the backend
+	if err := b.Configure(terraform.NewResourceConfig(rawConfig)); err != nil {
+		return nil, fmt.Errorf("error initializing backend: %s", err)
+	}
+
+	state, err := b.State(workspace)
+	if err != nil {
+		return nil, fmt.Errorf("error loading the remote state: %s", err)
+	}
+	if err := state.RefreshState(); err != nil {
+		return nil, err
+	}
+	return state.State(), nil
+}
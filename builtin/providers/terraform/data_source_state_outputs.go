@@ -0,0 +1,159 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func dataSourceStateOutputs() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceStateOutputsRead,
+
+		Schema: map[string]*schema.Schema{
+			"backend": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+
+			"config": {
+				Type:     schema.TypeMap,
+				Optional: true,
+			},
+
+			"workspace": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  backend.DefaultStateName,
+			},
+
+			"outputs": {
+				Type:     schema.TypeList,
+				Required: true,
+				MinItems: 1,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+
+			"defaults": {
+				Type:     schema.TypeMap,
+				Optional: true,
+			},
+
+			"allow_sensitive": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+
+			"__has_dynamic_attributes": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func dataSourceStateOutputsRead(d *schema.ResourceData, meta interface{}) error {
+	backendType := d.Get("backend").(string)
+	cfg := d.Get("config").(map[string]interface{})
+	workspace := d.Get("workspace").(string)
+
+	state, err := stateOutputsCache.read(backendType, cfg, workspace)
+	if err != nil {
+		return err
+	}
+
+	var outputs map[string]*terraform.OutputState
+	if !state.Empty() {
+		outputs = state.RootModule().Outputs
+	}
+	defaults := d.Get("defaults").(map[string]interface{})
+
+	// Only the selected outputs are exposed, and all of them must be
+	// available, so that a change to the other state's outputs is caught
+	// here rather than further along.
+	reserved := dataSourceStateOutputs().Schema
+	outputMap := make(map[string]interface{})
+	var missing []string
+	for _, raw := range d.Get("outputs").([]interface{}) {
+		name := raw.(string)
+		if _, ok := reserved[name]; ok {
+			return fmt.Errorf("output %q can't be read, since its name is reserved by terraform_state_outputs", name)
+		}
+
+		os, ok := outputs[name]
+		if !ok {
+			if v, ok := defaults[name]; ok {
+				outputMap[name] = v
+				continue
+			}
+			missing = append(missing, name)
+			continue
+		}
+		if os.Sensitive && !d.Get("allow_sensitive").(bool) {
+			return fmt.Errorf("output %q is sensitive, so it can only be read with allow_sensitive = true", name)
+		}
+		outputMap[name] = os.Value
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf(
+			"the state of workspace %q has no output named %s, and no default is set",
+			workspace, strings.Join(missing, ", "))
+	}
+
+	d.SetId(time.Now().UTC().String())
+	for key, val := range remoteStateFlatten(outputMap) {
+		d.UnsafeSetFieldRaw(key, val)
+	}
+
+	return nil
+}
+
+// stateOutputsCache is the cache of the states read by
+// terraform_state_outputs, so that a state is fetched from its backend at
+// most once per Terraform run however many data sources read it.
+var stateOutputsCache = &remoteStateCache{
+	states: make(map[string]*terraform.State),
+}
+
+// remoteStateCache caches the states read by readRemoteState, keyed by the
+// backend type, its configuration and the workspace. The cached states must
+// not be modified.
+type remoteStateCache struct {
+	mu     sync.Mutex
+	states map[string]*terraform.State
+}
+
+func (c *remoteStateCache) read(backendType string, cfg map[string]interface{}, workspace string) (*terraform.State, error) {
+	// Maps are marshaled with their keys in order, so equal configurations
+	// have equal keys.
+	rawCfg, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing backend: %s", err)
+	}
+	key := fmt.Sprintf("%s\x00%s\x00%s", backendType, rawCfg, workspace)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if state, ok := c.states[key]; ok {
+		log.Printf("[DEBUG] Using cached state of workspace %q from backend %s", workspace, backendType)
+		return state, nil
+	}
+
+	state, err := readRemoteState(backendType, cfg, workspace)
+	if err != nil {
+		return nil, err
+	}
+	c.states[key] = state
+	return state, nil
+}
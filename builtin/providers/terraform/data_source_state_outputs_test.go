@@ -0,0 +1,167 @@
+package terraform
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestStateOutputs_basic(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccStateOutputs_basic,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckStateValue(
+						"data.terraform_state_outputs.foo", "vpc_id", "vpc-1234"),
+					testAccCheckStateValue(
+						"data.terraform_state_outputs.foo", "subnet_ids.#", "2"),
+					testAccCheckStateValue(
+						"data.terraform_state_outputs.foo", "subnet_ids.1", "subnet-2"),
+					testAccCheckStateValue(
+						"data.terraform_state_outputs.foo", "region", "us-east-1"),
+					testAccCheckStateNoValue(
+						"data.terraform_state_outputs.foo", "db_password"),
+				),
+			},
+		},
+	})
+}
+
+func TestStateOutputs_missing(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccStateOutputs_missing,
+				ExpectError: regexp.MustCompile(`no output named nope, and no default is set`),
+			},
+		},
+	})
+}
+
+func TestStateOutputs_sensitive(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      fmt.Sprintf(testAccStateOutputs_sensitive, false),
+				ExpectError: regexp.MustCompile(`output "db_password" is sensitive`),
+			},
+			{
+				Config: fmt.Sprintf(testAccStateOutputs_sensitive, true),
+				Check: testAccCheckStateValue(
+					"data.terraform_state_outputs.foo", "db_password", "hunter2"),
+			},
+		},
+	})
+}
+
+func TestRemoteStateCache(t *testing.T) {
+	td, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+	path := filepath.Join(td, "terraform.tfstate")
+
+	writeState := func(value string) {
+		s := terraform.NewState()
+		s.RootModule().Outputs["foo"] = &terraform.OutputState{Type: "string", Value: value}
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		defer f.Close()
+		if err := terraform.WriteState(s, f); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	cache := &remoteStateCache{states: make(map[string]*terraform.State)}
+	cfg := map[string]interface{}{"path": path}
+
+	writeState("first")
+	if _, err := cache.read("local", cfg, "default"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	writeState("second")
+	state, err := cache.read("local", cfg, "default")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if got := state.RootModule().Outputs["foo"].Value; got != "first" {
+		t.Fatalf("state wasn't cached: got %#v", got)
+	}
+
+	// A different configuration is a different state.
+	cfg = map[string]interface{}{"path": path, "lock": "false"}
+	state, err = cache.read("local", cfg, "default")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if got := state.RootModule().Outputs["foo"].Value; got != "second" {
+		t.Fatalf("wrong state: got %#v", got)
+	}
+}
+
+func testAccCheckStateNoValue(id, name string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[id]
+		if !ok {
+			return fmt.Errorf("Not found: %s", id)
+		}
+		if v, ok := rs.Primary.Attributes[name]; ok {
+			return fmt.Errorf("%s is set to %s", name, v)
+		}
+		return nil
+	}
+}
+
+const testAccStateOutputs_basic = `
+data "terraform_state_outputs" "foo" {
+	backend = "local"
+
+	config {
+		path = "./test-fixtures/outputs.tfstate"
+	}
+
+	outputs = ["vpc_id", "subnet_ids", "region"]
+
+	defaults {
+		region = "us-east-1"
+	}
+}`
+
+const testAccStateOutputs_missing = `
+data "terraform_state_outputs" "foo" {
+	backend = "local"
+
+	config {
+		path = "./test-fixtures/outputs.tfstate"
+	}
+
+	outputs = ["vpc_id", "nope"]
+}`
+
+const testAccStateOutputs_sensitive = `
+data "terraform_state_outputs" "foo" {
+	backend = "local"
+
+	config {
+		path = "./test-fixtures/outputs.tfstate"
+	}
+
+	outputs         = ["db_password"]
+	allow_sensitive = %t
+}`
@@ -15,7 +15,8 @@ func Provider() terraform.ResourceProvider {
 			),
 		},
 		DataSourcesMap: map[string]*schema.Resource{
-			"terraform_remote_state": dataSourceRemoteState(),
+			"terraform_remote_state":  dataSourceRemoteState(),
+			"terraform_state_outputs": dataSourceStateOutputs(),
 		},
 	}
 }
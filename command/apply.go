@@ -7,11 +7,13 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform/tfdiags"
 
 	"github.com/hashicorp/go-getter"
 	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/command/format"
 	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/config/module"
 	"github.com/hashicorp/terraform/terraform"
@@ -28,7 +30,9 @@ type ApplyCommand struct {
 }
 
 func (c *ApplyCommand) Run(args []string) int {
-	var destroyForce, refresh, autoApprove bool
+	var destroyForce, refresh, autoApprove, policyOverride, resume, dryRun, jsonOutput, lockResources bool
+	var approveBy, residualPlanPath string
+	var interruptGrace time.Duration
 	args, err := c.Meta.process(args, true)
 	if err != nil {
 		return 1
@@ -44,21 +48,50 @@ func (c *ApplyCommand) Run(args []string) int {
 		cmdFlags.BoolVar(&destroyForce, "force", false, "force")
 	}
 	cmdFlags.BoolVar(&refresh, "refresh", true, "refresh")
+	cmdFlags.BoolVar(&policyOverride, "policy-override", false, "policy-override")
+	cmdFlags.BoolVar(&resume, "resume", false, "resume")
+	cmdFlags.BoolVar(&dryRun, "dry-run", false, "dry-run")
+	cmdFlags.StringVar(&approveBy, "approve-by", "", "group")
+	cmdFlags.StringVar(&residualPlanPath, "residual-plan", "", "path")
+	cmdFlags.BoolVar(&jsonOutput, "json", false, "json")
+	cmdFlags.StringVar(&c.Meta.hookCommand, "hook-command", "", "path")
 	if !c.Destroy {
 		cmdFlags.BoolVar(&autoApprove, "auto-approve", false, "skip interactive approval of plan before applying")
 	}
 	cmdFlags.IntVar(
 		&c.Meta.parallelism, "parallelism", DefaultParallelism, "parallelism")
+	cmdFlags.IntVar(
+		&c.Meta.refreshParallelism, "refresh-parallelism", 0, "refresh-parallelism")
 	cmdFlags.StringVar(&c.Meta.statePath, "state", "", "path")
 	cmdFlags.StringVar(&c.Meta.stateOutPath, "state-out", "", "path")
 	cmdFlags.StringVar(&c.Meta.backupPath, "backup", "", "path")
 	cmdFlags.BoolVar(&c.Meta.stateLock, "lock", true, "lock state")
 	cmdFlags.DurationVar(&c.Meta.stateLockTimeout, "lock-timeout", 0, "lock timeout")
+	cmdFlags.BoolVar(&lockResources, "lock-resources", false, "lock resources")
+	cmdFlags.DurationVar(&interruptGrace, "interrupt-grace", DefaultInterruptGrace, "interrupt grace")
+	c.addTargetModuleFlag(cmdFlags)
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := cmdFlags.Parse(args); err != nil {
 		return 1
 	}
 
+	// In JSON mode only the progress events are written to stdout, so that
+	// they can be parsed, and the diagnostics are written as events too.
+	// Everything else goes to stderr.
+	var jsonHook *JSONHook
+	if jsonOutput {
+		jsonHook = &JSONHook{Ui: c.Ui}
+		c.Meta.ExtraHooks = append(c.Meta.ExtraHooks, jsonHook)
+		c.Ui = &stderrUi{Ui: c.Ui}
+	}
+	showDiagnostics := func(diags tfdiags.Diagnostics) {
+		if jsonHook != nil {
+			jsonHook.Diagnostics(diags)
+			return
+		}
+		c.showDiagnostics(diags)
+	}
+
 	// Get the args. The "maybeInit" flag tracks whether we may need to
 	// initialize the configuration from a remote path. This is true as long
 	// as we have an argument.
@@ -114,6 +147,43 @@ func (c *ApplyCommand) Run(args []string) int {
 			"Destroy can't be called with a plan file."))
 		return 1
 	}
+	if jsonOutput && plan == nil && !dryRun && !autoApprove && !destroyForce {
+		flag := "-auto-approve"
+		if c.Destroy {
+			flag = "-force"
+		}
+		c.Ui.Error(fmt.Sprintf(
+			"The -json option requires %s, since the %s can't be\n"+
+				"confirmed interactively while its progress is streamed.", flag, cmdName))
+		return 1
+	}
+	if resume && plan != nil {
+		c.Ui.Error("A plan file can't be applied with -resume.")
+		return 1
+	}
+	if resume && len(c.Meta.targets) > 0 {
+		c.Ui.Error("The -target flag can't be used with -resume, which targets\n" +
+			"the changes of the failed apply that didn't complete.")
+		return 1
+	}
+	if resume && len(c.Meta.targetModules) > 0 {
+		c.Ui.Error("The -target-module flag can't be used with -resume, which targets\n" +
+			"the changes of the failed apply that didn't complete.")
+		return 1
+	}
+	if plan != nil && len(c.Meta.targetModules) > 0 {
+		c.Ui.Error("The -target-module flag can't be used with a plan file, which\n" +
+			"records the modules that it was scoped to.")
+		return 1
+	}
+	if err := c.checkApproveBy(approveBy, residualPlanPath, plan != nil, autoApprove || destroyForce, resume, dryRun, jsonOutput); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	if err := c.checkTargetModules(); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
 	if plan != nil {
 		// Reset the config path for backend loading
 		configPath = ""
@@ -128,7 +198,7 @@ func (c *ApplyCommand) Run(args []string) int {
 		mod, modDiags = c.Module(configPath)
 		diags = diags.Append(modDiags)
 		if modDiags.HasErrors() {
-			c.showDiagnostics(diags)
+			showDiagnostics(diags)
 			return 1
 		}
 	}
@@ -157,6 +227,13 @@ func (c *ApplyCommand) Run(args []string) int {
 	opReq.Type = backend.OperationTypeApply
 	opReq.AutoApprove = autoApprove
 	opReq.DestroyForce = destroyForce
+	opReq.PolicyOverride = policyOverride
+	opReq.Resume = resume
+	opReq.DryRun = dryRun
+	opReq.ApproveBy = approveBy
+	opReq.ResidualPlanOutPath = residualPlanPath
+	opReq.LockResources = lockResources
+	opReq.InterruptGrace = interruptGrace
 
 	// Perform the operation
 	ctx, ctxCancel := context.WithCancel(context.Background())
@@ -185,6 +262,9 @@ func (c *ApplyCommand) Run(args []string) int {
 					"loss may have occurred.")
 			return 1
 		case <-op.Done():
+			if err := op.Err; err != nil {
+				diags = diags.Append(err)
+			}
 		}
 	case <-op.Done():
 		if err := op.Err; err != nil {
@@ -192,7 +272,7 @@ func (c *ApplyCommand) Run(args []string) int {
 		}
 	}
 
-	c.showDiagnostics(diags)
+	showDiagnostics(diags)
 	if diags.HasErrors() {
 		return 1
 	}
@@ -204,7 +284,7 @@ func (c *ApplyCommand) Run(args []string) int {
 			mod = plan.Module
 		}
 
-		if outputs := outputsAsString(op.State, terraform.RootModulePath, mod.Config().Outputs, true); outputs != "" {
+		if outputs := outputsAsString(op.State, terraform.RootModulePath, mod.Config().Outputs, true, c.Redaction); outputs != "" {
 			c.Ui.Output(c.Colorize().Color(outputs))
 		}
 	}
@@ -212,6 +292,47 @@ func (c *ApplyCommand) Run(args []string) int {
 	return 0
 }
 
+// checkApproveBy returns an error if the given -approve-by and
+// -residual-plan options can't be used with the other given options, which
+// apply without asking for approval or don't prompt for it at all.
+func (c *ApplyCommand) checkApproveBy(approveBy, residualPlanPath string, planFile, noApproval, resume, dryRun, jsonOutput bool) error {
+	if approveBy == "" {
+		if residualPlanPath != "" {
+			return fmt.Errorf("The -residual-plan option requires -approve-by, since only the\n" +
+				"changes that weren't approved are saved to the residual plan.")
+		}
+		return nil
+	}
+
+	switch approveBy {
+	case backend.ApproveByResource, backend.ApproveByModule, backend.ApproveByAction:
+	default:
+		return fmt.Errorf("Invalid -approve-by value %q: must be %q, %q or %q.",
+			approveBy, backend.ApproveByResource, backend.ApproveByModule, backend.ApproveByAction)
+	}
+
+	flag := ""
+	switch {
+	case planFile:
+		return fmt.Errorf("The -approve-by option can't be used with a plan file, whose\n" +
+			"changes are applied as they were planned.")
+	case noApproval && c.Destroy:
+		flag = "-force"
+	case noApproval:
+		flag = "-auto-approve"
+	case resume:
+		flag = "-resume"
+	case dryRun:
+		flag = "-dry-run"
+	case jsonOutput:
+		flag = "-json"
+	}
+	if flag != "" {
+		return fmt.Errorf("The -approve-by option can't be used with %s.", flag)
+	}
+	return nil
+}
+
 func (c *ApplyCommand) Help() string {
 	if c.Destroy {
 		return c.helpDestroy()
@@ -242,16 +363,46 @@ Usage: terraform apply [options] [DIR-OR-PLAN]
 
 Options:
 
+  -approve-by=group      Ask for approval of each group of the planned
+                         changes instead of all of them at once, where group
+                         is "resource", "module" or "action". The changes
+                         that aren't approved are skipped, along with the
+                         changes that depend on them.
+
   -backup=path           Path to backup the existing state file before
                          modifying. Defaults to the "-state-out" path with
                          ".backup" extension. Set to "-" to disable backup.
 
+  -hook-command=path     Run the program at the given path before and after
+                         each resource is refreshed and applied, with a JSON
+                         description of the event on its stdin.
+
+  -interrupt-grace=30s   When interrupted, how long to wait for the changes
+                         in progress to complete before stopping them. No
+                         new changes are started once interrupted.
+
+  -json                  Write the progress of the apply and its errors and
+                         warnings to stdout as a stream of JSON events, one
+                         per line, instead of the human output, which goes
+                         to stderr. Requires -auto-approve unless a plan
+                         file is given.
+
   -lock=true             Lock the state file when locking is supported.
 
   -lock-timeout=0s       Duration to retry a state lock.
 
+  -lock-resources        Lock only the resources that the plan changes,
+                         instead of the whole state, so that applies of
+                         other resources can run at the same time. This is
+                         only supported by some backends; the whole state
+                         is locked otherwise.
+
   -auto-approve          Skip interactive approval of plan before applying.
 
+  -dry-run               Have the providers check each change of the plan,
+                         for errors such as exceeded quotas or missing
+                         permissions, without applying anything.
+
   -input=true            Ask for input for variables if not directly set.
 
   -no-color              If specified, output won't contain any color.
@@ -259,9 +410,23 @@ Options:
   -parallelism=n         Limit the number of parallel resource operations.
                          Defaults to 10.
 
+  -policy-override       Apply the plan even if it soft-fails a policy hook
+                         of the CLI configuration.
+
   -refresh=true          Update state prior to checking for differences. This
                          has no effect if a plan file is given to apply.
 
+  -refresh-parallelism=n Limit the number of concurrent operations while
+                         refreshing state. Defaults to the -parallelism value.
+
+  -resume                Resume the last apply if it failed, applying only the
+                         changes of its plan that didn't complete. The state
+                         must not have changed since.
+
+  -residual-plan=path    With -approve-by, save the changes that were skipped
+                         to a plan file at the given path, which can be
+                         applied later.
+
   -state=path            Path to read and save state (unless state-out
                          is specified). Defaults to "terraform.tfstate".
 
@@ -273,6 +438,13 @@ Options:
                          resource and its dependencies. This flag can be used
                          multiple times.
 
+  -target-module=module.foo
+                         Module to scope the apply to. Only the resources in
+                         this module and its descendants, and their
+                         dependencies, will be changed, with a warning about
+                         the resources and modules outside of it that depend
+                         on it. This flag can be used multiple times.
+
   -var 'foo=bar'         Set a variable in the Terraform configuration. This
                          flag can be set multiple times.
 
@@ -293,24 +465,65 @@ Usage: terraform destroy [options] [DIR]
 
 Options:
 
+  -approve-by=group      Ask for approval of each group of the planned
+                         destroys instead of all of them at once, where
+                         group is "resource", "module" or "action". The
+                         destroys that aren't approved are skipped, along
+                         with the destroys of what they depend on.
+
   -backup=path           Path to backup the existing state file before
                          modifying. Defaults to the "-state-out" path with
                          ".backup" extension. Set to "-" to disable backup.
 
+  -dry-run               Have the providers check each destroy, for errors
+                         such as missing permissions, without destroying
+                         anything.
+
   -force                 Don't ask for input for destroy confirmation.
 
+  -hook-command=path     Run the program at the given path before and after
+                         each resource is refreshed and destroyed, with a JSON
+                         description of the event on its stdin.
+
+  -interrupt-grace=30s   When interrupted, how long to wait for the destroys
+                         in progress to complete before stopping them. No
+                         new destroys are started once interrupted.
+
+  -json                  Write the progress of the destroy and its errors and
+                         warnings to stdout as a stream of JSON events, one
+                         per line, instead of the human output, which goes
+                         to stderr. Requires -force.
+
   -lock=true             Lock the state file when locking is supported.
 
   -lock-timeout=0s       Duration to retry a state lock.
 
+  -lock-resources        Lock only the resources to destroy, instead of the
+                         whole state. This is only supported by some
+                         backends; the whole state is locked otherwise.
+
   -no-color              If specified, output won't contain any color.
 
   -parallelism=n         Limit the number of concurrent operations.
                          Defaults to 10.
 
+  -policy-override       Destroy even if the plan soft-fails a policy hook
+                         of the CLI configuration.
+
   -refresh=true          Update state prior to checking for differences. This
                          has no effect if a plan file is given to apply.
 
+  -refresh-parallelism=n Limit the number of concurrent operations while
+                         refreshing state. Defaults to the -parallelism value.
+
+  -resume                Resume the last destroy if it failed, destroying
+                         only what it didn't. The state must not have changed
+                         since.
+
+  -residual-plan=path    With -approve-by, save the destroys that were
+                         skipped to a plan file at the given path, which can
+                         be applied later.
+
   -state=path            Path to read and save state (unless state-out
                          is specified). Defaults to "terraform.tfstate".
 
@@ -322,6 +535,12 @@ Options:
                          resource and its dependencies. This flag can be used
                          multiple times.
 
+  -target-module=module.foo
+                         Module to scope the destroy to. Only the resources in
+                         this module and its descendants, and the resources
+                         that depend on them, will be destroyed. This flag can
+                         be used multiple times.
+
   -var 'foo=bar'         Set a variable in the Terraform configuration. This
                          flag can be set multiple times.
 
@@ -334,7 +553,11 @@ Options:
 	return strings.TrimSpace(helpText)
 }
 
-func outputsAsString(state *terraform.State, modPath []string, schema []*config.Output, includeHeader bool) string {
+// outputsAsString returns the outputs of the module with the given path in
+// the given state as they're shown in the CLI, with the values of the
+// outputs that are sensitive in the state or in the given schema shown as
+// selected by the given redaction.
+func outputsAsString(state *terraform.State, modPath []string, schema []*config.Output, includeHeader bool, redaction format.Redaction) string {
 	if state == nil {
 		return ""
 	}
@@ -370,13 +593,13 @@ func outputsAsString(state *terraform.State, modPath []string, schema []*config.
 		sort.Strings(ks)
 
 		for _, k := range ks {
+			v := outputs[k]
 			schema, ok := schemaMap[k]
-			if ok && schema.Sensitive {
-				outputBuf.WriteString(fmt.Sprintf("%s = <sensitive>\n", k))
+			if v.Sensitive || (ok && schema.Sensitive) {
+				outputBuf.WriteString(fmt.Sprintf("%s = %s\n", k, redaction.Value(v.Value)))
 				continue
 			}
 
-			v := outputs[k]
 			switch typedV := v.Value.(type) {
 			case string:
 				outputBuf.WriteString(fmt.Sprintf("%s = %s\n", k, typedV))
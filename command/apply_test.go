@@ -16,7 +16,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hashicorp/terraform/helper/copy"
 	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/encryption"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
 )
@@ -251,6 +253,81 @@ func TestApply_configInvalid(t *testing.T) {
 	}
 }
 
+func TestApply_approveByInvalid(t *testing.T) {
+	cases := map[string][]string{
+		"unknown group":      {"-approve-by=nope"},
+		"auto-approve":       {"-approve-by=resource", "-auto-approve"},
+		"residual plan only": {"-residual-plan=residual.tfplan"},
+		"dry run":            {"-approve-by=module", "-dry-run"},
+	}
+	for name, flags := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := testProvider()
+			ui := new(cli.MockUi)
+			c := &ApplyCommand{
+				Meta: Meta{
+					testingOverrides: metaOverridesForProvider(p),
+					Ui:               ui,
+				},
+			}
+
+			args := append([]string{"-state", testTempFile(t)}, flags...)
+			args = append(args, testFixturePath("apply"))
+			if code := c.Run(args); code != 1 {
+				t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+			}
+			if p.ApplyCalled {
+				t.Fatal("apply should not be called")
+			}
+			if !strings.Contains(ui.ErrorWriter.String(), "-approve-by") {
+				t.Fatalf("bad: %s", ui.ErrorWriter.String())
+			}
+		})
+	}
+}
+
+// When state encryption is configured, "-residual-plan" is refused before
+// anything is applied, since the residual plan would hold the state
+// unencrypted.
+func TestApply_residualPlanStateEncryption(t *testing.T) {
+	// Create a temporary working directory that is empty
+	td := tempDir(t)
+	copy.CopyDir(testFixturePath("backend-new-encrypted"), td)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	defer os.Setenv(encryption.PassphraseEnvVar, os.Getenv(encryption.PassphraseEnvVar))
+	os.Setenv(encryption.PassphraseEnvVar, "correct horse battery staple")
+
+	// Initialize the backend
+	if _, err := testMetaBackend(t, nil).Backend(&BackendOpts{Init: true}); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &ApplyCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-approve-by=resource",
+		"-residual-plan=residual.tfplan",
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if p.ApplyCalled {
+		t.Fatal("apply should not be called")
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "Plan files can't be written while state encryption is configured") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
+
 func TestApply_defaultState(t *testing.T) {
 	td, err := ioutil.TempDir("", "tf")
 	if err != nil {
@@ -875,14 +952,20 @@ func TestApply_shutdown(t *testing.T) {
 		}, nil
 	}
 
+	// Without a grace period the providers are stopped immediately, and
+	// the change that depends on the interrupted one is still pending.
 	args := []string{
 		"-state", statePath,
 		"-auto-approve",
+		"-interrupt-grace=0s",
 		testFixturePath("apply-shutdown"),
 	}
-	if code := c.Run(args); code != 0 {
+	if code := c.Run(args); code != 1 {
 		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
 	}
+	if errOut := ui.ErrorWriter.String(); !strings.Contains(errOut, "test_instance.bar (create)") {
+		t.Fatalf("pending change not reported\n\n%s", errOut)
+	}
 
 	if _, err := os.Stat(statePath); err != nil {
 		t.Fatalf("err: %s", err)
@@ -898,6 +981,92 @@ func TestApply_shutdown(t *testing.T) {
 	}
 }
 
+func TestApply_interrupt(t *testing.T) {
+	statePath := testTempFile(t)
+	p := testProvider()
+	shutdownCh := make(chan struct{})
+
+	ui := cli.NewMockUi()
+	c := &ApplyCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+			ShutdownCh:       shutdownCh,
+		},
+	}
+
+	p.DiffFn = func(
+		*terraform.InstanceInfo,
+		*terraform.InstanceState,
+		*terraform.ResourceConfig) (*terraform.InstanceDiff, error) {
+		return &terraform.InstanceDiff{
+			Attributes: map[string]*terraform.ResourceAttrDiff{
+				"ami": &terraform.ResourceAttrDiff{
+					New: "bar",
+				},
+			},
+		}, nil
+	}
+	var applied []string
+	p.ApplyFn = func(
+		info *terraform.InstanceInfo,
+		s *terraform.InstanceState,
+		d *terraform.InstanceDiff) (*terraform.InstanceState, error) {
+		applied = append(applied, info.Id)
+
+		// The change in progress completes after the interrupt, once the
+		// apply has started stopping.
+		shutdownCh <- struct{}{}
+		for !strings.Contains(ui.OutputWriter.String(), "Stopping apply operation") {
+			time.Sleep(10 * time.Millisecond)
+		}
+		time.Sleep(100 * time.Millisecond)
+
+		return &terraform.InstanceState{
+			ID: "foo",
+			Attributes: map[string]string{
+				"ami": "2",
+			},
+		}, nil
+	}
+
+	args := []string{
+		"-state", statePath,
+		"-auto-approve",
+		testFixturePath("apply-shutdown"),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	if p.StopCalled {
+		t.Fatal("provider should not be stopped")
+	}
+	if len(applied) != 1 || applied[0] != "test_instance.foo" {
+		t.Fatalf("bad: %#v", applied)
+	}
+
+	errOut := ui.ErrorWriter.String()
+	for _, want := range []string{
+		"Apply interrupted! Changes: 1 completed, 1 pending.",
+		"test_instance.bar (create)",
+	} {
+		if !strings.Contains(errOut, want) {
+			t.Errorf("error output doesn't contain %q\n\n%s", want, errOut)
+		}
+	}
+
+	// The completed change was saved.
+	state := testStateRead(t, statePath)
+	rs := state.RootModule().Resources["test_instance.foo"]
+	if rs == nil || rs.Primary.ID != "foo" {
+		t.Fatalf("bad: %s", state)
+	}
+	if rs := state.RootModule().Resources["test_instance.bar"]; rs != nil {
+		t.Fatalf("bad: %s", state)
+	}
+}
+
 func TestApply_state(t *testing.T) {
 	originalState := &terraform.State{
 		Modules: []*terraform.ModuleState{
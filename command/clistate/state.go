@@ -71,12 +71,43 @@ func Lock(ctx context.Context, s state.State, info *state.LockInfo, ui cli.Ui, c
 	return lockID, err
 }
 
+// LockResources locks the resources with the given addresses like Lock
+// locks the whole state.
+func LockResources(ctx context.Context, s state.ResourceLocker, info *state.LockInfo, addrs []string, ui cli.Ui, color *colorstring.Colorize) (string, error) {
+	var lockID string
+	err := slowmessage.Do(LockThreshold, func() error {
+		id, err := state.LockResourcesWithContext(ctx, s, info, addrs)
+		lockID = id
+		return err
+	}, func() {
+		if ui != nil {
+			ui.Output(color.Color(LockMessage))
+		}
+	})
+	if err != nil {
+		err = errwrap.Wrapf(strings.TrimSpace(LockErrorMessage), err)
+	}
+	return lockID, err
+}
+
 // Unlock unlocks the given state and outputs to the user if the
 // unlock fails what can be done.
 func Unlock(s state.State, id string, ui cli.Ui, color *colorstring.Colorize) error {
-	err := slowmessage.Do(LockThreshold, func() error {
+	return unlock(func() error {
 		return s.Unlock(id)
-	}, func() {
+	}, ui, color)
+}
+
+// UnlockResources releases the resource lock with the given ID like Unlock
+// releases the lock of the whole state.
+func UnlockResources(s state.ResourceLocker, id string, ui cli.Ui, color *colorstring.Colorize) error {
+	return unlock(func() error {
+		return s.UnlockResources(id)
+	}, ui, color)
+}
+
+func unlock(f func() error, ui cli.Ui, color *colorstring.Colorize) error {
+	err := slowmessage.Do(LockThreshold, f, func() {
 		if ui != nil {
 			ui.Output(color.Color(UnlockMessage))
 		}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"time"
 
 	"github.com/hashicorp/terraform/terraform"
 )
@@ -30,6 +31,14 @@ const DefaultPluginVendorDir = "terraform.d/plugins/" + pluginMachineName
 // DefaultStateFilename is the default filename used for the state file.
 const DefaultStateFilename = "terraform.tfstate"
 
+// DefaultApplyCheckpointFilename is the filename in the data directory of
+// the checkpoint written when an apply fails, for resuming it.
+const DefaultApplyCheckpointFilename = "apply-checkpoint.json"
+
+// DefaultAuditLogFilename is the filename in the data directory of the
+// audit log of revealed sensitive outputs, unless another is configured.
+const DefaultAuditLogFilename = "audit.log"
+
 // DefaultVarsFilename is the default filename used for vars
 const DefaultVarsFilename = "terraform.tfvars"
 
@@ -40,6 +49,14 @@ const DefaultBackupExtension = ".backup"
 // operations as it walks the dependency graph.
 const DefaultParallelism = 10
 
+// DefaultInstallParallelism is the limit Terraform places on the provider
+// plugins that init installs at the same time.
+const DefaultInstallParallelism = 4
+
+// DefaultInterruptGrace is how long an interrupted apply waits for the
+// changes in progress to complete before stopping them.
+const DefaultInterruptGrace = 30 * time.Second
+
 // ErrUnsupportedLocalOp is the common error message shown for operations
 // that require a backend.Local.
 const ErrUnsupportedLocalOp = `The configured backend doesn't support this operation.
@@ -21,6 +21,7 @@ import (
 
 	"github.com/hashicorp/terraform/config/module"
 	"github.com/hashicorp/terraform/helper/logging"
+	"github.com/hashicorp/terraform/planfile"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -153,13 +154,7 @@ func testPlanFile(t *testing.T, plan *terraform.Plan) string {
 func testReadPlan(t *testing.T, path string) *terraform.Plan {
 	t.Helper()
 
-	f, err := os.Open(path)
-	if err != nil {
-		t.Fatalf("err: %s", err)
-	}
-	defer f.Close()
-
-	p, err := terraform.ReadPlan(f)
+	p, err := planfile.ReadPlan(path)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -2,7 +2,10 @@ package command
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/hashicorp/terraform/backend"
@@ -26,8 +29,10 @@ func (c *ConsoleCommand) Run(args []string) int {
 		return 1
 	}
 
+	var scriptPath string
 	cmdFlags := c.Meta.flagSet("console")
 	cmdFlags.StringVar(&c.Meta.statePath, "state", DefaultStateFilename, "path")
+	cmdFlags.StringVar(&scriptPath, "script", "", "path")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := cmdFlags.Parse(args); err != nil {
 		return 1
@@ -92,6 +97,10 @@ func (c *ConsoleCommand) Run(args []string) int {
 		Interpolater: ctx.Interpolater(),
 	}
 
+	if scriptPath != "" {
+		return c.modeScript(session, ui, scriptPath)
+	}
+
 	// Determine if stdin is a pipe. If so, we evaluate directly.
 	if c.StdinPiped() {
 		return c.modePiped(session, ui)
@@ -101,11 +110,19 @@ func (c *ConsoleCommand) Run(args []string) int {
 }
 
 func (c *ConsoleCommand) modePiped(session *repl.Session, ui cli.Ui) int {
+	inputs, err := readConsoleInputs(wrappedstreams.Stdin())
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error reading input: %s", err))
+		return 1
+	}
+
 	var lastResult string
-	scanner := bufio.NewScanner(wrappedstreams.Stdin())
-	for scanner.Scan() {
+	for _, input := range inputs {
 		// Handle it. If there is an error exit immediately
-		result, err := session.Handle(strings.TrimSpace(scanner.Text()))
+		result, err := session.Handle(input.Text)
+		if err == repl.ErrSessionExit {
+			break
+		}
 		if err != nil {
 			ui.Error(err.Error())
 			return 1
@@ -121,6 +138,134 @@ func (c *ConsoleCommand) modePiped(session *repl.Session, ui cli.Ui) int {
 	return 0
 }
 
+// ConsoleScriptJSONFormatVersion is the version of the document written by
+// console -script. It will be incremented if the document changes in a way
+// that is not backward-compatible.
+const ConsoleScriptJSONFormatVersion = "0.1"
+
+type consoleScriptJSON struct {
+	FormatVersion string                 `json:"format_version"`
+	Results       []*consoleScriptResult `json:"results"`
+	ErrorCount    int                    `json:"error_count"`
+}
+
+type consoleScriptResult struct {
+	Expression string      `json:"expression"`
+	Line       int         `json:"line"`
+	Value      interface{} `json:"value,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// modeScript evaluates each of the expressions in the file at the given
+// path and writes their values, or the errors evaluating them, as a JSON
+// document. Unlike the other modes, it carries on after an error so that
+// every expression is reported, and then exits with status 1.
+func (c *ConsoleCommand) modeScript(session *repl.Session, ui cli.Ui, path string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error reading script: %s", err))
+		return 1
+	}
+	defer f.Close()
+
+	inputs, err := readConsoleInputs(f)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error reading script %s: %s", path, err))
+		return 1
+	}
+
+	doc := consoleScriptJSON{
+		FormatVersion: ConsoleScriptJSONFormatVersion,
+		Results:       make([]*consoleScriptResult, 0, len(inputs)),
+	}
+	for _, input := range inputs {
+		if strings.TrimSpace(input.Text) == "exit" {
+			break
+		}
+
+		result := &consoleScriptResult{
+			Expression: input.Text,
+			Line:       input.Line,
+		}
+		value, err := session.Eval(input.Text)
+		if err != nil {
+			result.Error = err.Error()
+			doc.ErrorCount++
+		} else {
+			result.Value = value
+		}
+		doc.Results = append(doc.Results, result)
+	}
+
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error rendering results as JSON: %s", err))
+		return 1
+	}
+	ui.Output(string(buf))
+
+	if doc.ErrorCount > 0 {
+		return 1
+	}
+	return 0
+}
+
+// consoleInput is an expression or command read by the console, which may
+// span several lines.
+type consoleInput struct {
+	Text string
+
+	// Line is the number of the line that the input starts on.
+	Line int
+}
+
+// readConsoleInputs reads the expressions and commands from the given
+// reader, one per line except where an expression continues on the next
+// line because of unclosed brackets or quotes. Blank lines and lines that
+// start with "#" are skipped.
+func readConsoleInputs(r io.Reader) ([]consoleInput, error) {
+	var result []consoleInput
+	var current []string
+	var start int
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if len(current) == 0 {
+			trimmed := strings.TrimSpace(text)
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			start = line
+		}
+
+		current = append(current, text)
+		joined := strings.Join(current, "\n")
+		if repl.Incomplete(joined) {
+			continue
+		}
+		result = append(result, consoleInput{
+			Text: strings.TrimSpace(joined),
+			Line: start,
+		})
+		current = nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// An expression that is still incomplete at the end of the input is
+	// evaluated anyway so that its error is reported.
+	if len(current) > 0 {
+		result = append(result, consoleInput{
+			Text: strings.TrimSpace(strings.Join(current, "\n")),
+			Line: start,
+		})
+	}
+
+	return result, nil
+}
+
 func (c *ConsoleCommand) Help() string {
 	helpText := `
 Usage: terraform console [options] [DIR]
@@ -138,8 +283,22 @@ Usage: terraform console [options] [DIR]
   DIR can be set to a directory with a Terraform state to load. By
   default, this will default to the current working directory.
 
+  An interpolation with unclosed brackets or quotes continues on the next
+  line. In the interactive console, <tab> completes the addresses of the
+  resources in the state and the names of variables, locals and modules.
+
+  If standard input isn't a terminal then the interpolations are read from
+  it and the value of the last one is printed. The exit status is 1 if any
+  of them fails.
+
 Options:
 
+  -script=path           Evaluate each interpolation in the given file and
+                         write their values, or the errors evaluating them,
+                         to stdout as a JSON document. The exit status is 1
+                         if any of them fails. Blank lines and lines that
+                         start with "#" are skipped.
+
   -state=path            Path to read state. Defaults to "terraform.tfstate"
 
   -var 'foo=bar'         Set a variable in the Terraform configuration. This
@@ -8,6 +8,7 @@ package command
 import (
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/hashicorp/terraform/helper/wrappedreadline"
 	"github.com/hashicorp/terraform/repl"
@@ -19,10 +20,11 @@ import (
 func (c *ConsoleCommand) modeInteractive(session *repl.Session, ui cli.Ui) int {
 	// Configure input
 	l, err := readline.NewEx(wrappedreadline.Override(&readline.Config{
-		Prompt:            "> ",
+		Prompt:            consolePrompt,
 		InterruptPrompt:   "^C",
 		EOFPrompt:         "exit",
 		HistorySearchFold: true,
+		AutoComplete:      &consoleCompleter{session: session},
 	}))
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf(
@@ -32,20 +34,34 @@ func (c *ConsoleCommand) modeInteractive(session *repl.Session, ui cli.Ui) int {
 	}
 	defer l.Close()
 
+	// pending holds the lines of an expression that continues on the next
+	// line because of unclosed brackets or quotes.
+	var pending []string
 	for {
 		// Read a line
 		line, err := l.Readline()
 		if err == readline.ErrInterrupt {
-			if len(line) == 0 {
+			if len(line) == 0 && len(pending) == 0 {
 				break
 			} else {
+				pending = nil
+				l.SetPrompt(consolePrompt)
 				continue
 			}
 		} else if err == io.EOF {
 			break
 		}
 
-		out, err := session.Handle(line)
+		pending = append(pending, line)
+		input := strings.Join(pending, "\n")
+		if repl.Incomplete(input) {
+			l.SetPrompt(consoleContinuationPrompt)
+			continue
+		}
+		pending = nil
+		l.SetPrompt(consolePrompt)
+
+		out, err := session.Handle(input)
 		if err == repl.ErrSessionExit {
 			break
 		}
@@ -59,3 +75,26 @@ func (c *ConsoleCommand) modeInteractive(session *repl.Session, ui cli.Ui) int {
 
 	return 0
 }
+
+const (
+	consolePrompt             = "> "
+	consoleContinuationPrompt = ". "
+)
+
+// consoleCompleter is a readline.AutoCompleter that completes references
+// with the completions of a session.
+type consoleCompleter struct {
+	session *repl.Session
+}
+
+func (c *consoleCompleter) Do(line []rune, pos int) ([][]rune, int) {
+	completions, length := c.session.Complete(string(line[:pos]))
+
+	// Readline wants the rest of each completion after the part that has
+	// already been typed, which is always ASCII.
+	result := make([][]rune, len(completions))
+	for i, completion := range completions {
+		result[i] = []rune(completion[length:])
+	}
+	return result, length
+}
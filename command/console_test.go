@@ -2,8 +2,10 @@ package command
 
 import (
 	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -84,3 +86,85 @@ func TestConsole_tfvars(t *testing.T) {
 		t.Fatalf("bad: %q", actual)
 	}
 }
+
+func TestConsole_multiline(t *testing.T) {
+	tmp, cwd := testCwd(t)
+	defer testFixCwd(t, tmp, cwd)
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &ConsoleCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	var output bytes.Buffer
+	defer testStdinPipe(t, strings.NewReader("max(\n  1,\n  5\n)\n\nexit\n1+\n"))()
+	outCloser := testStdoutCapture(t, &output)
+
+	code := c.Run([]string{})
+	outCloser()
+	if code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	actual := output.String()
+	if actual != "5\n" {
+		t.Fatalf("bad: %q", actual)
+	}
+}
+
+func TestConsole_script(t *testing.T) {
+	tmp, cwd := testCwd(t)
+	defer testFixCwd(t, tmp, cwd)
+
+	scriptPath := filepath.Join(tmp, "test.tfconsole")
+	script := "# The variable is set with -var\nvar.foo\n\nlist(\n  \"a\",\n  \"b\"\n)\nvar.missing\n"
+	if err := ioutil.WriteFile(scriptPath, []byte(script), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &ConsoleCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	var output bytes.Buffer
+	outCloser := testStdoutCapture(t, &output)
+
+	args := []string{
+		"-var", "foo=bar",
+		"-script", scriptPath,
+		testFixturePath("apply-vars"),
+	}
+	code := c.Run(args)
+	outCloser()
+	if code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var doc consoleScriptJSON
+	if err := json.Unmarshal(output.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %s\n\n%s", err, output.String())
+	}
+	if doc.ErrorCount != 1 || len(doc.Results) != 3 {
+		t.Fatalf("wrong result: %s", output.String())
+	}
+
+	if r := doc.Results[0]; r.Line != 2 || r.Value != "bar" || r.Error != "" {
+		t.Fatalf("wrong first result: %#v", r)
+	}
+	r := doc.Results[1]
+	if r.Line != 4 || !reflect.DeepEqual(r.Value, []interface{}{"a", "b"}) {
+		t.Fatalf("wrong second result: %#v", r)
+	}
+	if r := doc.Results[2]; r.Line != 8 || r.Error == "" || r.Value != nil {
+		t.Fatalf("wrong third result: %#v", r)
+	}
+}
@@ -0,0 +1,271 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/command/format"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/mitchellh/cli"
+	"github.com/mitchellh/colorstring"
+)
+
+// DriftCommand is a Command implementation that refreshes the state and
+// reports the changes made to the remote objects outside of Terraform,
+// without planning any changes from the configuration.
+type DriftCommand struct {
+	Meta
+}
+
+func (c *DriftCommand) Run(args []string) int {
+	var detailed, jsonOutput bool
+
+	args, err := c.Meta.process(args, true)
+	if err != nil {
+		return 1
+	}
+
+	cmdFlags := c.Meta.flagSet("drift")
+	cmdFlags.IntVar(
+		&c.Meta.parallelism, "parallelism", DefaultParallelism, "parallelism")
+	cmdFlags.IntVar(
+		&c.Meta.refreshParallelism, "refresh-parallelism", 0, "refresh-parallelism")
+	cmdFlags.StringVar(&c.Meta.statePath, "state", "", "path")
+	cmdFlags.BoolVar(&detailed, "detailed-exitcode", false, "detailed-exitcode")
+	cmdFlags.BoolVar(&jsonOutput, "json", false, "json")
+	cmdFlags.BoolVar(&c.Meta.stateLock, "lock", true, "lock state")
+	cmdFlags.DurationVar(&c.Meta.stateLockTimeout, "lock-timeout", 0, "lock timeout")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+
+	// In JSON mode only the report itself is written to stdout, so that it
+	// can be parsed. Everything else goes to stderr.
+	out := c.Ui
+	if jsonOutput {
+		c.Ui = &stderrUi{Ui: c.Ui}
+	}
+
+	configPath, err := ModulePath(cmdFlags.Args())
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	// Check for user-supplied plugin path
+	if c.pluginPath, err = c.loadPluginPath(); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error loading plugin path: %s", err))
+		return 1
+	}
+
+	mod, diags := c.Module(configPath)
+	if diags.HasErrors() {
+		c.showDriftDiagnostics(out, jsonOutput, diags)
+		return 1
+	}
+
+	var conf *config.Config
+	if mod != nil {
+		conf = mod.Config()
+	}
+	b, err := c.Backend(&BackendOpts{
+		Config: conf,
+	})
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to load backend: %s", err))
+		return 1
+	}
+
+	// A refresh-only plan is never saved or applied here, so the refreshed
+	// state is never persisted. The backend doesn't render the plan, since
+	// the report is rendered below.
+	opReq := c.Operation()
+	opReq.Module = mod
+	opReq.PlanRefresh = true
+	opReq.PlanMode = backend.RefreshOnlyMode
+	opReq.PlanJSON = true
+	opReq.Type = backend.OperationTypePlan
+
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	op, err := b.Operation(ctx, opReq)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error starting operation: %s", err))
+		return 1
+	}
+
+	select {
+	case <-c.ShutdownCh:
+		// Cancel our context so we can start gracefully exiting
+		ctxCancel()
+
+		// Notify the user
+		c.Ui.Output(outputInterrupt)
+
+		// Still get the result, since there is still one
+		select {
+		case <-c.ShutdownCh:
+			c.Ui.Error(
+				"Two interrupts received. Exiting immediately")
+			return 1
+		case <-op.Done():
+		}
+	case <-op.Done():
+		if err := op.Err; err != nil {
+			diags = diags.Append(err)
+		}
+	}
+
+	if diags.HasErrors() || op.Plan == nil {
+		c.showDriftDiagnostics(out, jsonOutput, diags)
+		return 1
+	}
+
+	// The state of a plan operation is the state the plan was created
+	// against, before refreshing.
+	dispPlan := format.NewRefreshOnlyPlan(op.State, op.Plan)
+	dispPlan.Diagnostics = diags
+	dispPlan.Redaction = c.Redaction
+
+	if jsonOutput {
+		buf, err := dispPlan.DriftJSON()
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error rendering drift report as JSON: %s", err))
+			return 1
+		}
+		out.Output(string(buf))
+	} else {
+		c.showDiagnostics(diags)
+		for _, diag := range dispPlan.Warnings() {
+			c.Ui.Warn(format.Diagnostic(diag, c.Colorize(), 72))
+		}
+		c.Ui.Output(formatDrift(dispPlan, c.Colorize()))
+	}
+
+	if detailed && !dispPlan.Empty() {
+		return 2
+	}
+	return 0
+}
+
+// showDriftDiagnostics shows the given diagnostics of a drift check that
+// failed. In JSON mode they're written to out as a JSON document in place
+// of the report.
+func (c *DriftCommand) showDriftDiagnostics(out cli.Ui, jsonOutput bool, diags tfdiags.Diagnostics) {
+	if !jsonOutput {
+		c.showDiagnostics(diags)
+		return
+	}
+	buf, err := json.Marshal(planErroredJSON{
+		FormatVersion: format.DriftJSONFormatVersion,
+		Errored:       true,
+		Diagnostics:   format.NewJSONDiagnostics(diags),
+	})
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error rendering diagnostics as JSON: %s", err))
+		return
+	}
+	out.Output(string(buf))
+}
+
+// formatDrift returns the human-readable drift report of the given
+// refresh-only plan.
+func formatDrift(dispPlan *format.Plan, color *colorstring.Colorize) string {
+	if dispPlan.Empty() {
+		return color.Color(strings.TrimSpace(driftNone))
+	}
+
+	var deleted int
+	for _, r := range dispPlan.Resources {
+		if r.Drift() == format.DriftDeleted {
+			deleted++
+		}
+	}
+
+	return fmt.Sprintf("%s\n\n%s\n\n%s",
+		color.Color(strings.TrimSpace(driftIntro)),
+		dispPlan.Format(color),
+		color.Color(fmt.Sprintf(
+			"[reset][bold]Drift:[reset] %d resources changed outside of Terraform, %d of them deleted.",
+			len(dispPlan.Resources), deleted)),
+	)
+}
+
+func (c *DriftCommand) Help() string {
+	helpText := `
+Usage: terraform drift [options] [DIR]
+
+  Refreshes the state in-memory and reports the changes that were made to
+  the remote objects outside of Terraform since the state was last
+  updated, without comparing them to the configuration. Unlike plan, the
+  report never includes changes that the configuration would make, so it
+  is suitable for scheduled drift checks.
+
+  The refreshed state is never persisted. To update the state to match
+  the remote objects, use "terraform plan -refresh-only" and apply the
+  plan.
+
+Options:
+
+  -detailed-exitcode  Return detailed exit codes when the command exits. This
+                      will change the meaning of exit codes to:
+                      0 - Succeeded, no drift was detected
+                      1 - Errored
+                      2 - Succeeded, drift was detected
+
+  -input=true         Ask for input for variables if not directly set.
+
+  -json               If specified, the report is written to stdout as a JSON
+                      document with a Read change for each resource that has
+                      drifted, rather than in a human-readable form. Other
+                      messages are written to stderr.
+
+  -lock=true          Lock the state file when locking is supported.
+
+  -lock-timeout=0s    Duration to retry a state lock.
+
+  -no-color           If specified, output won't contain any color.
+
+  -parallelism=n      Limit the number of concurrent operations. Defaults to 10.
+
+  -refresh-parallelism=n
+                      Limit the number of concurrent operations while
+                      refreshing state. Defaults to the -parallelism value.
+
+  -state=statefile    Path to a Terraform state file to use to look
+                      up Terraform-managed resources. By default it will
+                      use the state "terraform.tfstate" if it exists.
+
+  -target=resource    Resource to target. Operation will be limited to this
+                      resource and its dependencies. This flag can be used
+                      multiple times.
+
+  -var 'foo=bar'      Set a variable in the Terraform configuration. This
+                      flag can be set multiple times.
+
+  -var-file=foo       Set variables in the Terraform configuration from
+                      a file. If "terraform.tfvars" or any ".auto.tfvars"
+                      files are present, they will be automatically loaded.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *DriftCommand) Synopsis() string {
+	return "Report changes made outside of Terraform"
+}
+
+const driftIntro = `
+[reset]
+Terraform detected the following changes made outside of Terraform since the
+last time the state was updated:
+`
+
+const driftNone = `
+[reset][bold][green]No drift detected. The state matches the remote objects.[reset][green]
+`
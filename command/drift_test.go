@@ -0,0 +1,152 @@
+package command
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
+)
+
+func TestDrift(t *testing.T) {
+	statePath := testStateFile(t, testDriftState())
+	before, err := ioutil.ReadFile(statePath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	p := testProvider()
+	p.RefreshFn = nil
+	p.RefreshReturn = &terraform.InstanceState{
+		ID: "bar",
+		Attributes: map[string]string{
+			"id":  "bar",
+			"ami": "changed",
+		},
+	}
+
+	ui := new(cli.MockUi)
+	c := &DriftCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-no-color",
+		"-detailed-exitcode",
+		"-state", statePath,
+		testFixturePath("refresh"),
+	}
+	if code := c.Run(args); code != 2 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	for _, want := range []string{
+		"test_instance.foo",
+		`ami: "bar" => "changed"`,
+		"Drift: 1 resources changed outside of Terraform, 0 of them deleted.",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output doesn't contain %q\n\n%s", want, output)
+		}
+	}
+
+	after, err := ioutil.ReadFile(statePath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(after) != string(before) {
+		t.Fatalf("state was modified\n\n%s", after)
+	}
+}
+
+func TestDrift_none(t *testing.T) {
+	statePath := testStateFile(t, testDriftState())
+
+	p := testProvider()
+	p.RefreshFn = func(info *terraform.InstanceInfo, s *terraform.InstanceState) (*terraform.InstanceState, error) {
+		return s, nil
+	}
+
+	ui := new(cli.MockUi)
+	c := &DriftCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-no-color",
+		"-detailed-exitcode",
+		"-state", statePath,
+		testFixturePath("refresh"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if output := ui.OutputWriter.String(); !strings.Contains(output, "No drift detected.") {
+		t.Fatalf("bad: %s", output)
+	}
+}
+
+func TestDrift_json(t *testing.T) {
+	statePath := testStateFile(t, testDriftState())
+
+	p := testProvider()
+	p.RefreshFn = func(info *terraform.InstanceInfo, s *terraform.InstanceState) (*terraform.InstanceState, error) {
+		return nil, nil
+	}
+
+	ui := new(cli.MockUi)
+	c := &DriftCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-json",
+		"-state", statePath,
+		testFixturePath("refresh"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var doc struct {
+		FormatVersion string `json:"format_version"`
+		Drifted       bool   `json:"drifted"`
+		ResourceDrift []struct {
+			Address string `json:"address"`
+			Drift   string `json:"drift"`
+			Change  struct {
+				Action string `json:"action"`
+			} `json:"change"`
+		} `json:"resource_drift"`
+	}
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %s\n\n%s", err, ui.OutputWriter.String())
+	}
+	if doc.FormatVersion != "0.1" || !doc.Drifted || len(doc.ResourceDrift) != 1 {
+		t.Fatalf("wrong result: %s", ui.OutputWriter.String())
+	}
+	if got := doc.ResourceDrift[0]; got.Address != "test_instance.foo" || got.Drift != "deleted" || got.Change.Action != "read" {
+		t.Fatalf("wrong resource drift: %s", ui.OutputWriter.String())
+	}
+}
+
+func testDriftState() *terraform.State {
+	state := testState()
+	state.RootModule().Resources["test_instance.foo"].Primary.Attributes = map[string]string{
+		"id":  "bar",
+		"ami": "bar",
+	}
+	return state
+}
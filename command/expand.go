@@ -0,0 +1,226 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// ExpandCommand is a Command implementation that shows the resource
+// instances that the counts of the configuration expand to, without
+// planning.
+type ExpandCommand struct {
+	Meta
+}
+
+func (c *ExpandCommand) Run(args []string) int {
+	var detailed, jsonOutput bool
+
+	args, err := c.Meta.process(args, true)
+	if err != nil {
+		return 1
+	}
+
+	cmdFlags := c.Meta.flagSet("expand")
+	cmdFlags.BoolVar(&detailed, "detailed-exitcode", false, "detailed-exitcode")
+	cmdFlags.BoolVar(&jsonOutput, "json", false, "json")
+	cmdFlags.StringVar(&c.Meta.statePath, "state", "", "path")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+
+	configPath, err := ModulePath(cmdFlags.Args())
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	mod, diags := c.Module(configPath)
+	if diags.HasErrors() {
+		c.showDiagnostics(diags)
+		return 1
+	}
+	if mod == nil {
+		c.Ui.Error(fmt.Sprintf(
+			"No configuration files found in the directory: %s\n\n"+
+				"This command requires configuration to run.",
+			configPath))
+		return 1
+	}
+
+	b, err := c.Backend(&BackendOpts{
+		Config: mod.Config(),
+	})
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to load backend: %s", err))
+		return 1
+	}
+
+	workspace := c.Workspace()
+	st, err := b.State(workspace)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to load state: %s", err))
+		return 1
+	}
+	if err := st.RefreshState(); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to load state: %s", err))
+		return 1
+	}
+
+	vars, err := terraform.Variables(mod, c.inputVariables())
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error loading variables: %s", err))
+		return 1
+	}
+
+	expansion, err := terraform.Expand(&terraform.ExpandOpts{
+		Module:    mod,
+		State:     st.State(),
+		Variables: vars,
+		Workspace: workspace,
+	})
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error expanding the configuration: %s", err))
+		return 1
+	}
+
+	if jsonOutput {
+		js, err := json.MarshalIndent(newExpansionJSON(expansion), "", "  ")
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error rendering the expansion as JSON: %s", err))
+			return 1
+		}
+		c.Ui.Output(string(js))
+	} else {
+		c.showDiagnostics(diags)
+		c.Ui.Output(c.Colorize().Color(formatExpansion(expansion)))
+	}
+
+	if detailed {
+		for _, i := range expansion.Instances {
+			if i.Status == terraform.ExpandRemoved {
+				return 2
+			}
+		}
+	}
+	return 0
+}
+
+// formatExpansion returns the human form of the given expansion.
+func formatExpansion(expansion *terraform.Expansion) string {
+	var buf strings.Builder
+	if len(expansion.Instances) == 0 {
+		buf.WriteString("The configuration has no resource instances.\n")
+	} else {
+		buf.WriteString("[reset][bold]The configuration expands to these resource instances:[reset]\n\n")
+	}
+
+	var created, removed int
+	for _, i := range expansion.Instances {
+		switch i.Status {
+		case terraform.ExpandNew:
+			created++
+			fmt.Fprintf(&buf, "  [green]+[reset] %s\n", i.Addr)
+		case terraform.ExpandRemoved:
+			removed++
+			fmt.Fprintf(&buf, "  [red]-[reset] %s [red](no longer configured, so a plan would destroy it)[reset]\n", i.Addr)
+		case terraform.ExpandUnknown:
+			fmt.Fprintf(&buf, "  [yellow]?[reset] %s [yellow](its count isn't known)[reset]\n", i.Addr)
+		default:
+			fmt.Fprintf(&buf, "    %s\n", i.Addr)
+		}
+	}
+
+	if len(expansion.Unknown) > 0 {
+		buf.WriteString("\n[reset][bold]The counts of these resources aren't known until a plan is made:[reset]\n\n")
+		for _, u := range expansion.Unknown {
+			fmt.Fprintf(&buf, "  %s, since count refers to %s\n", u.Addr, strings.Join(u.References, ", "))
+		}
+	}
+
+	fmt.Fprintf(&buf, "\n%d new, %d no longer configured.", created, removed)
+	return buf.String()
+}
+
+// expansionJSON is the JSON form of an expansion.
+type expansionJSON struct {
+	Instances []*expandedInstanceJSON `json:"instances"`
+	Unknown   []*unknownExpansionJSON `json:"unknown"`
+}
+
+type expandedInstanceJSON struct {
+	Address string                 `json:"address"`
+	Status  terraform.ExpandStatus `json:"status"`
+}
+
+type unknownExpansionJSON struct {
+	Address    string   `json:"address"`
+	References []string `json:"references"`
+}
+
+func newExpansionJSON(expansion *terraform.Expansion) *expansionJSON {
+	js := &expansionJSON{
+		Instances: make([]*expandedInstanceJSON, 0, len(expansion.Instances)),
+		Unknown:   make([]*unknownExpansionJSON, 0, len(expansion.Unknown)),
+	}
+	for _, i := range expansion.Instances {
+		js.Instances = append(js.Instances, &expandedInstanceJSON{
+			Address: i.Addr.String(),
+			Status:  i.Status,
+		})
+	}
+	for _, u := range expansion.Unknown {
+		js.Unknown = append(js.Unknown, &unknownExpansionJSON{
+			Address:    u.Addr.String(),
+			References: u.References,
+		})
+	}
+	return js
+}
+
+func (c *ExpandCommand) Help() string {
+	helpText := `
+Usage: terraform expand [options] [DIR]
+
+  Shows the resource instances that the counts of the configuration expand
+  to, compared with the state, without making a plan and without starting
+  any providers.
+
+  This can be used to check that a change to a count doesn't destroy
+  instances by accident before making a plan. The counts can refer to
+  variables, local values, module outputs and terraform.workspace; a count
+  that refers to a resource or a data source is only known once planned.
+
+Options:
+
+  -detailed-exitcode  Return detailed exit codes when the command exits. This
+                      will change the meaning of exit codes to:
+                      0 - Succeeded, no instances are no longer configured
+                      1 - Errored
+                      2 - Succeeded, some instances are no longer configured
+
+  -json               If specified, the expansion is written as a JSON
+                      document.
+
+  -no-color           If specified, output won't contain any color.
+
+  -state=path         Path to the state file. Defaults to "terraform.tfstate".
+                      Ignored when remote state is used.
+
+  -var 'foo=bar'      Set a variable in the Terraform configuration. This
+                      flag can be set multiple times.
+
+  -var-file=foo       Set variables in the Terraform configuration from
+                      a file. If "terraform.tfvars" or any ".auto.tfvars"
+                      files are present, they will be automatically loaded.
+
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ExpandCommand) Synopsis() string {
+	return "Shows the resource instances that counts expand to"
+}
@@ -0,0 +1,98 @@
+package command
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
+)
+
+func TestExpand(t *testing.T) {
+	statePath := testStateFile(t, testExpandState())
+
+	ui := new(cli.MockUi)
+	c := &ExpandCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+	}
+
+	args := []string{
+		"-no-color",
+		"-detailed-exitcode",
+		"-state", statePath,
+		"-var", "n=1",
+		testFixturePath("expand"),
+	}
+	if code := c.Run(args); code != 2 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	for _, want := range []string{
+		"    test_instance.foo\n",
+		"  - test_instance.foo[1] (no longer configured, so a plan would destroy it)",
+		"0 new, 1 no longer configured.",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("output should contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestExpand_json(t *testing.T) {
+	statePath := testStateFile(t, testExpandState())
+
+	ui := new(cli.MockUi)
+	c := &ExpandCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+	}
+
+	args := []string{
+		"-json",
+		"-detailed-exitcode",
+		"-state", statePath,
+		testFixturePath("expand"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var result expansionJSON
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &result); err != nil {
+		t.Fatalf("err: %s\n\n%s", err, ui.OutputWriter.String())
+	}
+	expected := []*expandedInstanceJSON{
+		{Address: "test_instance.foo[0]", Status: terraform.ExpandExisting},
+		{Address: "test_instance.foo[1]", Status: terraform.ExpandExisting},
+		{Address: "test_instance.foo[2]", Status: terraform.ExpandNew},
+	}
+	if !reflect.DeepEqual(result.Instances, expected) {
+		t.Fatalf("bad: %s", ui.OutputWriter.String())
+	}
+}
+
+func testExpandState() *terraform.State {
+	return &terraform.State{
+		Modules: []*terraform.ModuleState{
+			{
+				Path: []string{"root"},
+				Resources: map[string]*terraform.ResourceState{
+					"test_instance.foo.0": {
+						Type:    "test_instance",
+						Primary: &terraform.InstanceState{ID: "foo0"},
+					},
+					"test_instance.foo.1": {
+						Type:    "test_instance",
+						Primary: &terraform.InstanceState{ID: "foo1"},
+					},
+				},
+			},
+		},
+	}
+}
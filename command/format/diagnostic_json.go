@@ -0,0 +1,138 @@
+package format
+
+import (
+	"bytes"
+	"io/ioutil"
+
+	"github.com/hashicorp/terraform/tfdiags"
+)
+
+// JSONDiagnostic is the machine-readable representation of a diagnostic,
+// as written by the -json option of validate, plan and apply.
+type JSONDiagnostic struct {
+	Severity string       `json:"severity"`
+	Code     tfdiags.Code `json:"code"`
+	Summary  string       `json:"summary"`
+	Detail   string       `json:"detail,omitempty"`
+	Range    *JSONRange   `json:"range,omitempty"`
+	Snippet  *JSONSnippet `json:"snippet,omitempty"`
+}
+
+// JSONRange is the range of configuration source that a diagnostic refers
+// to. Positions are 1-based for lines and columns and 0-based for bytes, and
+// the end is exclusive.
+type JSONRange struct {
+	Filename string  `json:"filename"`
+	Start    JSONPos `json:"start"`
+	End      JSONPos `json:"end"`
+}
+
+// JSONPos is a position in configuration source.
+type JSONPos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Byte   int `json:"byte"`
+}
+
+// JSONSnippet is the source code of the expression that a diagnostic
+// refers to, along with the lines around it that the diagnostic gives as
+// its context.
+type JSONSnippet struct {
+	// Code is the complete lines of source from StartLine that contain the
+	// context of the diagnostic.
+	Code      string `json:"code"`
+	StartLine int    `json:"start_line"`
+
+	// HighlightStartOffset and HighlightEndOffset are the byte offsets
+	// within Code of the range that the diagnostic refers to.
+	HighlightStartOffset int `json:"highlight_start_offset"`
+	HighlightEndOffset   int `json:"highlight_end_offset"`
+}
+
+// NewJSONDiagnostic returns the machine-readable representation of the
+// given diagnostic. If the diagnostic refers to a range of source that can
+// still be read then the snippet of that source is included.
+func NewJSONDiagnostic(diag tfdiags.Diagnostic) *JSONDiagnostic {
+	desc := diag.Description()
+	ret := &JSONDiagnostic{
+		Code:    tfdiags.GetCode(diag),
+		Summary: desc.Summary,
+		Detail:  desc.Detail,
+	}
+	switch diag.Severity() {
+	case tfdiags.Error:
+		ret.Severity = "error"
+	case tfdiags.Warning:
+		ret.Severity = "warning"
+	}
+
+	source := diag.Source()
+	if source.Subject == nil {
+		return ret
+	}
+	subject := *source.Subject
+	ret.Range = &JSONRange{
+		Filename: subject.Filename,
+		Start:    jsonPos(subject.Start),
+		End:      jsonPos(subject.End),
+	}
+
+	context := subject
+	if source.Context != nil && source.Context.Filename == subject.Filename {
+		context = *source.Context
+	}
+	src, err := ioutil.ReadFile(subject.Filename)
+	if err == nil {
+		ret.Snippet = jsonSnippet(src, context, subject)
+	}
+
+	return ret
+}
+
+// NewJSONDiagnostics returns the machine-readable representations of the
+// given diagnostics, which is never nil so that it is written as an empty
+// array rather than null.
+func NewJSONDiagnostics(diags tfdiags.Diagnostics) []*JSONDiagnostic {
+	ret := make([]*JSONDiagnostic, 0, len(diags))
+	for _, diag := range diags {
+		ret = append(ret, NewJSONDiagnostic(diag))
+	}
+	return ret
+}
+
+func jsonPos(pos tfdiags.SourcePos) JSONPos {
+	return JSONPos{
+		Line:   pos.Line,
+		Column: pos.Column,
+		Byte:   pos.Byte,
+	}
+}
+
+// jsonSnippet returns the complete lines of the given source that contain
+// the given context, widened if needed to contain the given subject. It
+// returns nil if the ranges don't fit the source, such as if the file has
+// changed since it was loaded.
+func jsonSnippet(src []byte, context, subject tfdiags.SourceRange) *JSONSnippet {
+	if context.Start.Byte > subject.Start.Byte {
+		context.Start = subject.Start
+	}
+	if context.End.Byte < subject.End.Byte {
+		context.End = subject.End
+	}
+	if context.Start.Byte < 0 || context.End.Byte > len(src) || context.Start.Byte > context.End.Byte {
+		return nil
+	}
+
+	start := bytes.LastIndexByte(src[:context.Start.Byte], '\n') + 1
+	end := len(src)
+	if i := bytes.IndexByte(src[context.End.Byte:], '\n'); i >= 0 {
+		end = context.End.Byte + i
+	}
+
+	return &JSONSnippet{
+		Code:                 string(src[start:end]),
+		StartLine:            bytes.Count(src[:start], []byte{'\n'}) + 1,
+		HighlightStartOffset: subject.Start.Byte - start,
+		HighlightEndOffset:   subject.End.Byte - start,
+	}
+}
@@ -0,0 +1,92 @@
+package format
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/terraform/tfdiags"
+)
+
+func TestNewJSONDiagnostic(t *testing.T) {
+	td, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	filename := filepath.Join(td, "main.tf")
+	src := "resource \"test\" \"foo\" {\n  name = var.nme\n}\n"
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var diags tfdiags.Diagnostics
+	diags = diags.Append(&hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Reference to undeclared input variable",
+		Detail:   `An input variable with the name "nme" has not been declared.`,
+		Subject: &hcl.Range{
+			Filename: filename,
+			Start:    hcl.Pos{Line: 2, Column: 10, Byte: 33},
+			End:      hcl.Pos{Line: 2, Column: 17, Byte: 40},
+		},
+	})
+	diags = diags.Append(errors.New("something went wrong"))
+
+	got := NewJSONDiagnostics(diags.WithCode(tfdiags.CodeConfigInvalid))
+	want := []*JSONDiagnostic{
+		{
+			Severity: "error",
+			Code:     tfdiags.CodeConfigInvalid,
+			Summary:  "Reference to undeclared input variable",
+			Detail:   `An input variable with the name "nme" has not been declared.`,
+			Range: &JSONRange{
+				Filename: filename,
+				Start:    JSONPos{Line: 2, Column: 10, Byte: 33},
+				End:      JSONPos{Line: 2, Column: 17, Byte: 40},
+			},
+			Snippet: &JSONSnippet{
+				Code:                 "  name = var.nme",
+				StartLine:            2,
+				HighlightStartOffset: 9,
+				HighlightEndOffset:   16,
+			},
+		},
+		{
+			Severity: "error",
+			Code:     tfdiags.CodeConfigInvalid,
+			Summary:  "something went wrong",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestNewJSONDiagnostic_unclassified(t *testing.T) {
+	got := NewJSONDiagnostic(tfdiags.SimpleWarning("be careful"))
+	want := &JSONDiagnostic{
+		Severity: "warning",
+		Code:     tfdiags.CodeUnclassified,
+		Summary:  "be careful",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestJSONSnippet_outOfRange(t *testing.T) {
+	// The file may have changed since the diagnostic was made.
+	subject := tfdiags.SourceRange{
+		Start: tfdiags.SourcePos{Line: 5, Column: 1, Byte: 80},
+		End:   tfdiags.SourcePos{Line: 5, Column: 4, Byte: 83},
+	}
+	if got := jsonSnippet([]byte("short\n"), subject, subject); got != nil {
+		t.Fatalf("unexpected snippet %#v", got)
+	}
+}
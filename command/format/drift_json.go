@@ -0,0 +1,100 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/terraform/diffs"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/hashicorp/terraform/tfdiags"
+)
+
+// DriftJSONFormatVersion is the version of the document produced by
+// Plan.DriftJSON. It will be incremented if the document changes in a way
+// that is not backward-compatible.
+const DriftJSONFormatVersion = "0.1"
+
+// The kinds of drift of a resource instance in the drift report.
+const (
+	// DriftModified is for an object whose attributes were changed outside
+	// of Terraform.
+	DriftModified = "modified"
+
+	// DriftDeleted is for an object that was deleted outside of Terraform.
+	DriftDeleted = "deleted"
+)
+
+type driftJSON struct {
+	FormatVersion string              `json:"format_version"`
+	Drifted       bool                `json:"drifted"`
+	Incomplete    bool                `json:"incomplete"`
+	Targets       []string            `json:"targets,omitempty"`
+	ResourceDrift []resourceDriftJSON `json:"resource_drift"`
+	Diagnostics   []*JSONDiagnostic   `json:"diagnostics"`
+}
+
+type resourceDriftJSON struct {
+	Address string          `json:"address"`
+	Drift   string          `json:"drift"`
+	Change  json.RawMessage `json:"change"`
+
+	Redacted map[string]*redactedChangeJSON `json:"redacted,omitempty"`
+}
+
+// DriftJSON produces the machine-readable drift report of the receiving
+// plan, which must be a refresh-only plan produced by NewRefreshOnlyPlan.
+//
+// Each resource instance whose remote object differs from its state is
+// described by whether it was modified or deleted, along with a Read change
+// in the same representation as Plan.JSON, from the attributes in the state
+// to those of the remote object. Sensitive values are always written as
+// null, and what the plan's Redaction shows of them is described in
+// "redacted" as it is by Plan.JSON.
+func (p *Plan) DriftJSON() ([]byte, error) {
+	if !p.RefreshOnly {
+		return nil, fmt.Errorf("a drift report can only be produced from a refresh-only plan")
+	}
+
+	doc := driftJSON{
+		FormatVersion: DriftJSONFormatVersion,
+		Drifted:       len(p.Resources) > 0,
+		Incomplete:    p.Incomplete(),
+		Targets:       p.Targets,
+		ResourceDrift: make([]resourceDriftJSON, 0, len(p.Resources)),
+	}
+	var diags tfdiags.Diagnostics
+	diags = diags.Append(p.Diagnostics, p.Warnings())
+	doc.Diagnostics = NewJSONDiagnostics(diags)
+
+	for _, r := range p.Resources {
+		addr := r.Addr.String()
+		change, err := r.change().MarshalJSONWith(diffs.MarshalOpts{
+			RedactSensitive: true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize change for %s: %s", addr, err)
+		}
+		doc.ResourceDrift = append(doc.ResourceDrift, resourceDriftJSON{
+			Address: addr,
+			Drift:   r.Drift(),
+			Change:  change,
+
+			Redacted: redactedAttrs(r, p.Redaction),
+		})
+	}
+
+	return json.Marshal(doc)
+}
+
+// Drift returns the kind of drift that the receiver describes, which must
+// be an instance diff of a refresh-only plan: DriftDeleted if every
+// attribute was removed, since the remote object no longer exists, and
+// DriftModified otherwise.
+func (r *InstanceDiff) Drift() string {
+	for _, attr := range r.Attributes {
+		if attr.Action != terraform.DiffDestroy {
+			return DriftModified
+		}
+	}
+	return DriftDeleted
+}
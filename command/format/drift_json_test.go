@@ -0,0 +1,62 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestPlanDriftJSON(t *testing.T) {
+	plan := &Plan{
+		RefreshOnly: true,
+		Resources: []*InstanceDiff{
+			{
+				Addr:   mustParseResourceAddress("test_resource.changed"),
+				Action: terraform.DiffRefresh,
+				Attributes: []*AttributeDiff{
+					{
+						Path:     "name",
+						Action:   terraform.DiffUpdate,
+						OldValue: "before",
+						NewValue: "after",
+					},
+					{
+						Path:      "password",
+						Action:    terraform.DiffUpdate,
+						OldValue:  "hunter2",
+						NewValue:  "hunter3",
+						Sensitive: true,
+					},
+				},
+			},
+			{
+				Addr:   mustParseResourceAddress("test_resource.deleted"),
+				Action: terraform.DiffRefresh,
+				Attributes: []*AttributeDiff{
+					{
+						Path:     "id",
+						Action:   terraform.DiffDestroy,
+						OldValue: "c",
+					},
+				},
+			},
+		},
+	}
+
+	got, err := plan.DriftJSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := `{"format_version":"0.1","drifted":true,"incomplete":false,"resource_drift":[` +
+		`{"address":"test_resource.changed","drift":"modified","change":{"action":"read","type":["map","string"],"old":{"name":"before","password":null},"new":{"name":"after","password":null},"sensitive":[[["password"]]]}},` +
+		`{"address":"test_resource.deleted","drift":"deleted","change":{"action":"read","type":["map","string"],"old":{"id":"c"},"new":{}}}` +
+		`],"diagnostics":[]}`
+	if string(got) != want {
+		t.Errorf("wrong result\ngot:  %s\nwant: %s", got, want)
+	}
+
+	if _, err := (&Plan{}).DriftJSON(); err == nil {
+		t.Fatal("succeeded for a plan that isn't refresh-only")
+	}
+}
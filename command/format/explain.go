@@ -0,0 +1,131 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/colorstring"
+)
+
+// Explanations returns the human-readable representation of the given
+// explanations of the planned values of resource instances, as produced by
+// terraform.Context.Explanations, for the output of "terraform plan
+// -explain".
+func Explanations(expls []*terraform.Explanation, color *colorstring.Colorize) string {
+	var buf bytes.Buffer
+	for i, expl := range expls {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(color.Color(fmt.Sprintf("[reset][bold]Explanation of %s:[reset]\n", expl.Addr)))
+		if expl.Deferred != "" {
+			fmt.Fprintf(&buf, "\n  The changes of the resource were deferred:\n  %s\n", expl.Deferred)
+		}
+		for _, arg := range expl.Arguments {
+			buf.WriteString("\n")
+			if arg.Unknown {
+				buf.WriteString(color.Color(fmt.Sprintf("  [bold]%s[reset] (known after apply)\n", arg.Name)))
+			} else {
+				buf.WriteString(color.Color(fmt.Sprintf("  [bold]%s[reset]\n", arg.Name)))
+			}
+
+			if !arg.Configured {
+				buf.WriteString("    not set in the configuration\n")
+			} else if arg.Range.Start.Line != 0 {
+				fmt.Fprintf(&buf, "    set at %s to %s\n", arg.Range.StartString(), explainExpression(arg.Expression))
+			} else {
+				fmt.Fprintf(&buf, "    set to %s\n", explainExpression(arg.Expression))
+			}
+
+			for _, ref := range arg.References {
+				if ref.Unknown {
+					fmt.Fprintf(&buf, "    refers to %s, which is unknown\n", ref.Text)
+				} else {
+					fmt.Fprintf(&buf, "    refers to %s\n", ref.Text)
+				}
+			}
+			if len(arg.Attributes) > 0 {
+				fmt.Fprintf(&buf, "    changes %s\n", strings.Join(arg.Attributes, ", "))
+			}
+			if arg.UnknownReason != "" {
+				fmt.Fprintf(&buf, "    unknown because %s\n", arg.UnknownReason)
+			}
+		}
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// explainExpression returns the given expression of an argument as it's
+// shown in an explanation.
+func explainExpression(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	js, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%#v", v)
+	}
+	return string(js)
+}
+
+type explanationJSON struct {
+	Address   string                    `json:"address"`
+	Deferred  string                    `json:"deferred,omitempty"`
+	Arguments []argumentExplanationJSON `json:"arguments"`
+}
+
+type argumentExplanationJSON struct {
+	Name          string                     `json:"name"`
+	Configured    bool                       `json:"configured"`
+	Expression    interface{}                `json:"expression,omitempty"`
+	Range         *JSONRange                 `json:"range,omitempty"`
+	References    []referenceExplanationJSON `json:"references,omitempty"`
+	Attributes    []string                   `json:"attributes,omitempty"`
+	Unknown       bool                       `json:"unknown"`
+	UnknownReason string                     `json:"unknown_reason,omitempty"`
+}
+
+type referenceExplanationJSON struct {
+	Text    string `json:"text"`
+	Unknown bool   `json:"unknown"`
+}
+
+func newExplanationsJSON(expls []*terraform.Explanation) []explanationJSON {
+	var ret []explanationJSON
+	for _, expl := range expls {
+		ej := explanationJSON{
+			Address:   expl.Addr,
+			Deferred:  expl.Deferred,
+			Arguments: make([]argumentExplanationJSON, 0, len(expl.Arguments)),
+		}
+		for _, arg := range expl.Arguments {
+			aj := argumentExplanationJSON{
+				Name:          arg.Name,
+				Configured:    arg.Configured,
+				Expression:    arg.Expression,
+				Attributes:    arg.Attributes,
+				Unknown:       arg.Unknown,
+				UnknownReason: arg.UnknownReason,
+			}
+			if arg.Range.Start.Line != 0 {
+				aj.Range = &JSONRange{
+					Filename: arg.Range.Filename,
+					Start:    jsonPos(arg.Range.Start),
+					End:      jsonPos(arg.Range.End),
+				}
+			}
+			for _, ref := range arg.References {
+				aj.References = append(aj.References, referenceExplanationJSON{
+					Text:    ref.Text,
+					Unknown: ref.Unknown,
+				})
+			}
+			ej.Arguments = append(ej.Arguments, aj)
+		}
+		ret = append(ret, ej)
+	}
+	return ret
+}
@@ -0,0 +1,56 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/mitchellh/colorstring"
+)
+
+func TestExplanations(t *testing.T) {
+	expls := []*terraform.Explanation{
+		{
+			Addr: "aws_instance.bar",
+			Arguments: []*terraform.ArgumentExplanation{
+				{
+					Name:       "ami",
+					Configured: true,
+					Expression: "${var.ami}",
+					Range: tfdiags.SourceRange{
+						Filename: "main.tf",
+						Start:    tfdiags.SourcePos{Line: 11, Column: 3},
+						End:      tfdiags.SourcePos{Line: 11, Column: 21},
+					},
+					References: []*terraform.ReferenceExplanation{{Text: "var.ami"}},
+					Attributes: []string{"ami"},
+				},
+				{
+					Name:          "id",
+					Attributes:    []string{"id"},
+					Unknown:       true,
+					UnknownReason: "it isn't set in the configuration, and the provider computes it during apply",
+				},
+			},
+		},
+	}
+
+	got := Explanations(expls, &colorstring.Colorize{Colors: colorstring.DefaultColors, Disable: true})
+	want := strings.TrimSpace(`
+Explanation of aws_instance.bar:
+
+  ami
+    set at main.tf:11,3 to "${var.ami}"
+    refers to var.ami
+    changes ami
+
+  id (known after apply)
+    not set in the configuration
+    changes id
+    unknown because it isn't set in the configuration, and the provider computes it during apply
+`)
+	if got != want {
+		t.Errorf("wrong result\ngot:\n%s\n\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,75 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/colorstring"
+)
+
+// Fingerprint returns a description of the given fingerprint of the run
+// that produced a plan or state, for terraform show.
+func Fingerprint(fp *terraform.Fingerprint, color *colorstring.Colorize) string {
+	if color == nil {
+		panic("colorize not given")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("[reset][bold]Fingerprint:[reset]\n\n")
+	fmt.Fprintf(&buf, "  Terraform version: %s\n", fp.TerraformVersion)
+	fmt.Fprintf(&buf, "  Platform:          %s\n", fp.Platform)
+
+	if len(fp.Providers) > 0 {
+		buf.WriteString("\n  Providers:\n")
+		names := make([]string, 0, len(fp.Providers))
+		for name := range fp.Providers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		width := maxLen(names)
+
+		for _, name := range names {
+			p := fp.Providers[name]
+			var desc []string
+			if p.Version != "" {
+				desc = append(desc, p.Version)
+			}
+			if p.SHA256 != "" {
+				desc = append(desc, "sha256:"+p.SHA256)
+			}
+			if len(desc) == 0 {
+				desc = append(desc, "(unknown version)")
+			}
+			fmt.Fprintf(&buf, "    %-*s  %s\n", width, name, strings.Join(desc, " "))
+		}
+	}
+
+	if len(fp.Variables) > 0 {
+		buf.WriteString("\n  Variables:\n")
+		names := make([]string, 0, len(fp.Variables))
+		for name := range fp.Variables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		width := maxLen(names)
+
+		for _, name := range names {
+			fmt.Fprintf(&buf, "    %-*s  sha256:%s\n", width, name, fp.Variables[name])
+		}
+	}
+
+	return color.Color(strings.TrimRight(buf.String(), "\n"))
+}
+
+func maxLen(ss []string) int {
+	n := 0
+	for _, s := range ss {
+		if len(s) > n {
+			n = len(s)
+		}
+	}
+	return n
+}
@@ -6,8 +6,10 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/hashicorp/hcl2/hcl"
 	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/terraform"
+	"github.com/hashicorp/terraform/tfdiags"
 	"github.com/mitchellh/colorstring"
 )
 
@@ -19,6 +21,51 @@ import (
 // there only to clean up the state).
 type Plan struct {
 	Resources []*InstanceDiff
+
+	// Targets is the set of resource addresses that the plan was restricted
+	// to using resource targeting, or nil if targeting was not used.
+	Targets []string
+
+	// TargetModules is the set of modules that the plan was scoped to
+	// using -target-module, and ScopeDependents are the resources and module
+	// calls outside of them that depend on them, whose changes the plan
+	// doesn't include.
+	TargetModules   []string
+	ScopeDependents []string
+
+	// RefreshOnly is true if the plan was created in refresh-only mode, in
+	// which case all of its resources have the DiffRefresh action.
+	RefreshOnly bool
+
+	// Checks is the status of each check block as of planning, keyed by
+	// the address of the check.
+	Checks map[string]*terraform.CheckState
+
+	// Deferred are the resources whose changes couldn't be planned because
+	// they depend on values that are only known after apply, and so aren't
+	// in Resources.
+	Deferred []*terraform.DeferredChange
+
+	// UnavailableDestroyRefs are the destroy-time provisioners that refer
+	// to values which destroying removes before they run.
+	UnavailableDestroyRefs []*terraform.DestroyProvisionerRefs
+
+	// Diagnostics are the diagnostics from creating the plan, which JSON
+	// includes along with the plan's own warnings.
+	Diagnostics tfdiags.Diagnostics
+
+	// Fingerprint describes the environment of the run that created the
+	// plan, if it was recorded.
+	Fingerprint *terraform.Fingerprint
+
+	// Explanations explain the planned values of the resource instances
+	// given by "terraform plan -explain", which JSON includes. They're set
+	// by the caller.
+	Explanations []*terraform.Explanation
+
+	// Redaction selects how sensitive values are shown by Format and JSON.
+	// It's set by the caller, and defaults to RedactHidden.
+	Redaction Redaction
 }
 
 // InstanceDiff is a representation of an instance diff optimized
@@ -34,6 +81,36 @@ type InstanceDiff struct {
 
 	Tainted bool
 	Deposed bool
+
+	// ReplaceRequested is true if the instance is being replaced because
+	// the user requested it, rather than because of a change that requires
+	// a new resource.
+	ReplaceRequested bool
+
+	// Importing is true if the instance will be imported, as requested by
+	// an import block in the configuration, with the given ImportID.
+	//
+	// An instance that is imported without also being changed has the
+	// DiffRefresh action, with the imported attributes.
+	Importing bool
+	ImportID  string
+
+	// MovedFrom is the previous address of the instance if it was moved, as
+	// requested by a moved block in the configuration.
+	//
+	// An instance that is moved without also being changed has the
+	// DiffNone action and no attributes.
+	MovedFrom string
+
+	// Forget is true if the instance will be removed from the state without
+	// being destroyed, as requested by a removed block in the
+	// configuration. It has the DiffNone action, and the attributes are
+	// those of the object that will no longer be managed.
+	Forget bool
+
+	// Annotations are the notes that plan analyzers attached to the change,
+	// in the order they were made.
+	Annotations []*terraform.PlanAnnotation
 }
 
 // AttributeDiff is a representation of an attribute diff optimized
@@ -51,22 +128,67 @@ type AttributeDiff struct {
 	NewComputed bool
 	Sensitive   bool
 	ForcesNew   bool
+
+	// UnknownSources are the references that a computed new value was
+	// derived from, where those are known.
+	UnknownSources []string
 }
 
 // PlanStats gives summary counts for a Plan.
 type PlanStats struct {
-	ToAdd, ToChange, ToDestroy int
+	ToMove, ToForget, ToImport, ToAdd, ToChange, ToDestroy int
 }
 
 // NewPlan produces a display-oriented Plan from a terraform.Plan.
 func NewPlan(plan *terraform.Plan) *Plan {
 	ret := &Plan{}
-	if plan == nil || plan.Diff == nil || plan.Diff.Empty() {
+	if plan != nil && len(plan.Targets) > 0 {
+		ret.Targets = plan.Targets
+	}
+	if plan == nil {
 		// Nothing to do!
 		return ret
 	}
+	if len(plan.TargetModules) > 0 {
+		ret.TargetModules = plan.TargetModules
+		ret.ScopeDependents = plan.ScopeDependents
+	}
+	ret.Checks = plan.Checks
+	ret.Deferred = plan.Deferred
+	ret.UnavailableDestroyRefs = plan.UnavailableDestroyRefs
+	ret.Fingerprint = plan.Fingerprint
+
+	// Imports are keyed by the string form of their parsed address, so
+	// that they match the addresses of the instance diffs below.
+	imports := make(map[string]*terraform.ImportTarget, len(plan.Imports))
+	for _, i := range plan.Imports {
+		addr, err := terraform.ParseResourceAddress(i.Addr)
+		if err != nil {
+			// should never happen; the address was validated before import
+			panic("invalid resource address in plan imports")
+		}
+		imports[addr.String()] = i
+	}
+
+	// Annotations are keyed by the addresses that the analyzers were given,
+	// which are those of the instance diffs below.
+	annotations := make(map[string][]*terraform.PlanAnnotation)
+	for _, a := range plan.Annotations {
+		annotations[a.Addr] = append(annotations[a.Addr], a)
+	}
+
+	// Moves are keyed by their new addresses, which are already in the
+	// same form.
+	moves := make(map[string]string, len(plan.Moves))
+	for _, m := range plan.Moves {
+		moves[m.To] = m.From
+	}
 
-	for _, m := range plan.Diff.Modules {
+	var modules []*terraform.ModuleDiff
+	if plan.Diff != nil {
+		modules = plan.Diff.Modules
+	}
+	for _, m := range modules {
 		var modulePath []string
 		if !m.IsRoot() {
 			// trim off the leading "root" path segment, since it's implied
@@ -94,65 +216,70 @@ func NewPlan(plan *terraform.Plan) *Plan {
 				continue
 			}
 
-			did := &InstanceDiff{
-				Addr:    addr,
-				Action:  r.ChangeType(),
-				Tainted: r.DestroyTainted,
-				Deposed: r.DestroyDeposed,
+			did := newInstanceDiff(addr, r)
+			if i, ok := imports[addr.String()]; ok && !did.Deposed {
+				did.Importing = true
+				did.ImportID = i.ID
+				delete(imports, addr.String())
 			}
-
-			if dataSource && did.Action == terraform.DiffCreate {
-				// Use "refresh" as the action for display, since core
-				// currently uses Create for this.
-				did.Action = terraform.DiffRefresh
+			if from, ok := moves[addr.String()]; ok && !did.Deposed {
+				did.MovedFrom = from
+				delete(moves, addr.String())
 			}
 
 			ret.Resources = append(ret.Resources, did)
+		}
+	}
 
-			if did.Action == terraform.DiffDestroy {
-				// Don't show any outputs for destroy actions
-				continue
-			}
-
-			for k, a := range r.Attributes {
-				var action terraform.DiffChangeType
-				switch {
-				case a.NewRemoved:
-					action = terraform.DiffDestroy
-				case did.Action == terraform.DiffCreate:
-					action = terraform.DiffCreate
-				default:
-					action = terraform.DiffUpdate
-				}
-
-				did.Attributes = append(did.Attributes, &AttributeDiff{
-					Path:   k,
-					Action: action,
-
-					OldValue: a.Old,
-					NewValue: a.New,
-
-					Sensitive:   a.Sensitive,
-					ForcesNew:   a.RequiresNew,
-					NewComputed: a.NewComputed,
-				})
-			}
-
-			// Sort the attributes by their paths for display
-			sort.Slice(did.Attributes, func(i, j int) bool {
-				iPath := did.Attributes[i].Path
-				jPath := did.Attributes[j].Path
+	// Any imports left over have no changes planned after they are
+	// imported, so we show them as reads of the imported objects.
+	for key, i := range imports {
+		addr, _ := terraform.ParseResourceAddress(key)
+		ret.Resources = append(ret.Resources, &InstanceDiff{
+			Addr:       addr,
+			Action:     terraform.DiffRefresh,
+			Attributes: refreshedAttributes(nil, importedAttrs(plan.State, key)),
+			Importing:  true,
+			ImportID:   i.ID,
+		})
+	}
 
-				// as a special case, "id" is always first
-				switch {
-				case iPath != jPath && (iPath == "id" || jPath == "id"):
-					return iPath == "id"
-				default:
-					return iPath < jPath
-				}
-			})
+	// Similarly, moves left over have no changes after they are moved.
+	for to, from := range moves {
+		addr, err := terraform.ParseResourceAddress(to)
+		if err != nil {
+			// should never happen; indicates invalid moves in the plan
+			panic("invalid resource address in plan moves")
+		}
+		ret.Resources = append(ret.Resources, &InstanceDiff{
+			Addr:      addr,
+			Action:    terraform.DiffNone,
+			MovedFrom: from,
+		})
+	}
 
+	// Removed instances have no diffs, since they are no longer in the
+	// state or the configuration.
+	for _, r := range plan.Removed {
+		addr, err := terraform.ParseResourceAddress(r.Addr)
+		if err != nil {
+			// should never happen; indicates invalid removals in the plan
+			panic("invalid resource address in plan removals")
 		}
+		var attrs map[string]string
+		if r.Primary != nil {
+			attrs = r.Primary.Attributes
+		}
+		ret.Resources = append(ret.Resources, &InstanceDiff{
+			Addr:       addr,
+			Action:     terraform.DiffNone,
+			Attributes: forgottenAttributes(attrs),
+			Forget:     true,
+		})
+	}
+
+	for _, r := range ret.Resources {
+		r.Annotations = annotations[r.Addr.String()]
 	}
 
 	// Sort the instance diffs by their addresses for display.
@@ -165,6 +292,93 @@ func NewPlan(plan *terraform.Plan) *Plan {
 	return ret
 }
 
+func newInstanceDiff(addr *terraform.ResourceAddress, r *terraform.InstanceDiff) *InstanceDiff {
+	did := &InstanceDiff{
+		Addr:             addr,
+		Action:           r.ChangeType(),
+		Tainted:          r.DestroyTainted,
+		Deposed:          r.DestroyDeposed,
+		ReplaceRequested: r.ReplaceRequested,
+	}
+
+	if addr.Mode == config.DataResourceMode && did.Action == terraform.DiffCreate {
+		// Use "refresh" as the action for display, since core
+		// currently uses Create for this.
+		did.Action = terraform.DiffRefresh
+	}
+
+	if did.Action == terraform.DiffDestroy {
+		// Don't show any outputs for destroy actions
+		return did
+	}
+
+	for k, a := range r.Attributes {
+		var action terraform.DiffChangeType
+		switch {
+		case a.NewRemoved:
+			action = terraform.DiffDestroy
+		case did.Action == terraform.DiffCreate:
+			action = terraform.DiffCreate
+		default:
+			action = terraform.DiffUpdate
+		}
+
+		did.Attributes = append(did.Attributes, &AttributeDiff{
+			Path:   k,
+			Action: action,
+
+			OldValue: a.Old,
+			NewValue: a.New,
+
+			Sensitive:   a.Sensitive,
+			ForcesNew:   a.RequiresNew,
+			NewComputed: a.NewComputed,
+
+			UnknownSources: r.UnknownSources[k],
+		})
+	}
+
+	// Sort the attributes by their paths for display
+	sort.Slice(did.Attributes, func(i, j int) bool {
+		iPath := did.Attributes[i].Path
+		jPath := did.Attributes[j].Path
+
+		// as a special case, "id" is always first
+		switch {
+		case iPath != jPath && (iPath == "id" || jPath == "id"):
+			return iPath == "id"
+		default:
+			return iPath < jPath
+		}
+	})
+
+	return did
+}
+
+// forgottenAttributes returns the attribute diffs of an instance that is
+// removed from the state, which show its attributes as they were, sorted by
+// their paths with "id" first.
+func forgottenAttributes(attrs map[string]string) []*AttributeDiff {
+	ret := make([]*AttributeDiff, 0, len(attrs))
+	for k, v := range attrs {
+		ret = append(ret, &AttributeDiff{
+			Path:     k,
+			Action:   terraform.DiffNone,
+			OldValue: v,
+			NewValue: v,
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		iPath := ret[i].Path
+		jPath := ret[j].Path
+		if iPath != jPath && (iPath == "id" || jPath == "id") {
+			return iPath == "id"
+		}
+		return iPath < jPath
+	})
+	return ret
+}
+
 // Format produces and returns a text representation of the receiving plan
 // intended for display in a terminal.
 //
@@ -196,7 +410,7 @@ func (p *Plan) Format(color *colorstring.Colorize) string {
 
 	buf := new(bytes.Buffer)
 	for _, r := range p.Resources {
-		formatPlanInstanceDiff(buf, r, keyLen, color)
+		formatPlanInstanceDiff(buf, r, keyLen, p.Redaction, color)
 	}
 
 	return strings.TrimSpace(buf.String())
@@ -206,6 +420,15 @@ func (p *Plan) Format(color *colorstring.Colorize) string {
 func (p *Plan) Stats() PlanStats {
 	var ret PlanStats
 	for _, r := range p.Resources {
+		if r.MovedFrom != "" {
+			ret.ToMove++
+		}
+		if r.Forget {
+			ret.ToForget++
+		}
+		if r.Importing {
+			ret.ToImport++
+		}
 		switch r.Action {
 		case terraform.DiffCreate:
 			ret.ToAdd++
@@ -235,6 +458,111 @@ func (p *Plan) Empty() bool {
 	return len(p.Resources) == 0
 }
 
+// Incomplete returns true if the receiving plan was created using resource
+// targeting or module scoping, or deferred some changes, and so may not
+// include all of the changes needed to make the infrastructure match the
+// configuration.
+func (p *Plan) Incomplete() bool {
+	return len(p.Targets) > 0 || len(p.TargetModules) > 0 || len(p.Deferred) > 0
+}
+
+// Warnings returns warnings about the receiving plan that should be shown
+// to the user along with it, such as that the plan is incomplete due to
+// resource targeting.
+func (p *Plan) Warnings() tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+	if len(p.Targets) > 0 {
+		diags = diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagWarning,
+			Summary:  "Resource targeting is in effect",
+			Detail: fmt.Sprintf(
+				"This plan includes only the changes for %s and the resources they depend on, so it may be incomplete.\n\nResource targeting is intended for exceptional situations such as recovering from errors or mistakes. Run \"terraform plan\" without -target to see all of the changes that the configuration requires.",
+				strings.Join(p.Targets, ", "),
+			),
+		}).WithCode(tfdiags.CodePlanTargeted)
+	}
+	if len(p.TargetModules) > 0 {
+		diags = diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagWarning,
+			Summary:  "Module scoping is in effect",
+			Detail: fmt.Sprintf(
+				"This plan includes only the changes for the resources in %s and the objects they depend on, so it may be incomplete.",
+				strings.Join(p.TargetModules, ", "),
+			),
+		}).WithCode(tfdiags.CodePlanModuleScoped)
+	}
+	if len(p.ScopeDependents) > 0 {
+		lines := make([]string, len(p.ScopeDependents))
+		for i, addr := range p.ScopeDependents {
+			lines[i] = "  - " + addr
+		}
+		diags = diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagWarning,
+			Summary:  "Dependents outside of the module scope were skipped",
+			Detail: fmt.Sprintf(
+				"These depend on %s, but are outside of the scope, so this plan doesn't include any changes that they need:\n\n%s\n\nOnce this plan is applied, run \"terraform plan\" without -target-module to plan their changes.",
+				strings.Join(p.TargetModules, ", "),
+				strings.Join(lines, "\n"),
+			),
+		}).WithCode(tfdiags.CodePlanModuleScoped)
+	}
+	if len(p.Deferred) > 0 {
+		lines := make([]string, len(p.Deferred))
+		for i, d := range p.Deferred {
+			lines[i] = "  - " + d.String()
+		}
+		diags = diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagWarning,
+			Summary:  "Some changes were deferred",
+			Detail: fmt.Sprintf(
+				"The changes of these resources can't be planned until some values are known, so this plan doesn't include them:\n\n%s\n\nOnce this plan is applied, run \"terraform plan\" again to plan the deferred changes.",
+				strings.Join(lines, "\n"),
+			),
+		}).WithCode(tfdiags.CodePlanDeferred)
+	}
+	if len(p.UnavailableDestroyRefs) > 0 {
+		lines := make([]string, len(p.UnavailableDestroyRefs))
+		for i, r := range p.UnavailableDestroyRefs {
+			lines[i] = fmt.Sprintf("  - %s, provisioner %q: %s", r.Resource, r.Provisioner, strings.Join(r.Refs, ", "))
+		}
+		diags = diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagWarning,
+			Summary:  "Destroy-time provisioners refer to unavailable values",
+			Detail: fmt.Sprintf(
+				"These destroy-time provisioners refer to local values, module outputs or data sources, which are removed while destroying, so their values may be unknown when the provisioners run:\n\n%s\n\nRefer to the resource's own attributes with self, and declare the resources that a provisioner needs with depends_on so that they're destroyed after it.",
+				strings.Join(lines, "\n"),
+			),
+		}).WithCode(tfdiags.CodePlanDestroyRefs)
+	}
+	diags = diags.Append(CheckWarnings(p.Checks))
+	return diags
+}
+
+// CheckWarnings returns a warning for each failed check in the given
+// statuses of check blocks, keyed by the address of the check.
+func CheckWarnings(checks map[string]*terraform.CheckState) tfdiags.Diagnostics {
+	addrs := make([]string, 0, len(checks))
+	for addr, cs := range checks {
+		if cs.Status == terraform.ConditionFail {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Strings(addrs)
+
+	var diags tfdiags.Diagnostics
+	for _, addr := range addrs {
+		diags = diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagWarning,
+			Summary:  "Check block assertion failed",
+			Detail: fmt.Sprintf(
+				"%s failed:\n\n%s",
+				addr, strings.Join(checks[addr].FailureMessages, "\n"),
+			),
+		}).WithCode(tfdiags.CodeCheckFailed)
+	}
+	return diags
+}
+
 // DiffActionSymbol returns a string that, once passed through a
 // colorstring.Colorize, will produce a result that can be written
 // to a terminal to produce a symbol made of three printable
@@ -249,14 +577,16 @@ func DiffActionSymbol(action terraform.DiffChangeType) string {
 		return "  [red]-[reset]"
 	case terraform.DiffRefresh:
 		return " [cyan]<=[reset]"
+	case terraform.DiffNone:
+		return "   "
 	default:
 		return "  [yellow]~[reset]"
 	}
 }
 
 // formatPlanInstanceDiff writes the text representation of the given instance diff
-// to the given buffer, using the given colorizer.
-func formatPlanInstanceDiff(buf *bytes.Buffer, r *InstanceDiff, keyLen int, colorizer *colorstring.Colorize) {
+// to the given buffer, using the given redaction and colorizer.
+func formatPlanInstanceDiff(buf *bytes.Buffer, r *InstanceDiff, keyLen int, redaction Redaction, colorizer *colorstring.Colorize) {
 	addrStr := r.Addr.String()
 
 	// Determine the color for the text (green for adding, yellow
@@ -275,7 +605,13 @@ func formatPlanInstanceDiff(buf *bytes.Buffer, r *InstanceDiff, keyLen int, colo
 		color = "red"
 	case terraform.DiffRefresh:
 		color = "cyan"
-		oldValues = false
+		// Only a managed resource, as in a refresh-only plan, has a prior
+		// value to show, unless it is being imported and so has none yet.
+		oldValues = r.Addr.Mode == config.ManagedResourceMode && !r.Importing
+	case terraform.DiffNone:
+		// A forgotten object's attributes are unchanged, so they are shown
+		// as they are.
+		oldValues = !r.Forget
 	}
 
 	var extraStr string
@@ -285,7 +621,19 @@ func formatPlanInstanceDiff(buf *bytes.Buffer, r *InstanceDiff, keyLen int, colo
 	if r.Deposed {
 		extraStr = extraStr + " (deposed)"
 	}
-	if r.Action == terraform.DiffDestroyCreate {
+	if r.Importing {
+		extraStr = extraStr + fmt.Sprintf(" (import id: %q)", r.ImportID)
+	}
+	if r.MovedFrom != "" {
+		extraStr = extraStr + fmt.Sprintf(" (moved from %s)", r.MovedFrom)
+	}
+	if r.Forget {
+		extraStr = extraStr + " (removed from state, will not be destroyed)"
+	}
+	switch {
+	case r.Action == terraform.DiffDestroyCreate && r.ReplaceRequested:
+		extraStr = extraStr + colorizer.Color(" [red][bold](replace requested by user)")
+	case r.Action == terraform.DiffDestroyCreate:
 		extraStr = extraStr + colorizer.Color(" [red][bold](new resource required)")
 	}
 
@@ -301,10 +649,12 @@ func formatPlanInstanceDiff(buf *bytes.Buffer, r *InstanceDiff, keyLen int, colo
 		v := attr.NewValue
 		var dispV string
 		switch {
+		case v == "" && attr.NewComputed && len(attr.UnknownSources) > 0:
+			dispV = fmt.Sprintf("<computed> (from %s)", strings.Join(attr.UnknownSources, ", "))
 		case v == "" && attr.NewComputed:
 			dispV = "<computed>"
 		case attr.Sensitive:
-			dispV = "<sensitive>"
+			dispV = redaction.Value(v)
 		default:
 			dispV = fmt.Sprintf("%q", v)
 		}
@@ -322,7 +672,7 @@ func formatPlanInstanceDiff(buf *bytes.Buffer, r *InstanceDiff, keyLen int, colo
 			var dispU string
 			switch {
 			case attr.Sensitive:
-				dispU = "<sensitive>"
+				dispU = redaction.Value(u)
 			default:
 				dispU = fmt.Sprintf("%q", u)
 			}
@@ -344,6 +694,37 @@ func formatPlanInstanceDiff(buf *bytes.Buffer, r *InstanceDiff, keyLen int, colo
 		}
 	}
 
+	for _, a := range r.Annotations {
+		label := a.Analyzer
+		if a.Kind != "" {
+			label = fmt.Sprintf("%s (%s)", a.Kind, a.Analyzer)
+		}
+		buf.WriteString(colorizer.Color(fmt.Sprintf(
+			"      [reset][bold]# %s:[reset] %s\n", label, a.Summary,
+		)))
+		if a.Detail != "" {
+			for _, line := range strings.Split(strings.TrimSpace(a.Detail), "\n") {
+				buf.WriteString(fmt.Sprintf("      #   %s\n", line))
+			}
+		}
+	}
+
 	// Write the reset color so we don't bleed color into later text
 	buf.WriteString(colorizer.Color("[reset]\n"))
 }
+
+// importedAttrs returns the attributes of the root module instance with the
+// given address in the given state, or nil if there is no such instance.
+func importedAttrs(s *terraform.State, addr string) map[string]string {
+	root := moduleState(s, []string{"root"})
+	if root == nil {
+		return nil
+	}
+	for k := range root.Resources {
+		kAddr, err := terraform.ParseResourceAddressForInstanceDiff(nil, k)
+		if err == nil && kAddr.String() == addr {
+			return instanceAttrs(root, k)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform/diffs"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// PlanJSONFormatVersion is the version of the document produced by
+// Plan.JSON. It will be incremented if the document changes in a way that
+// is not backward-compatible.
+const PlanJSONFormatVersion = "0.1"
+
+// JSON produces a machine-readable representation of the receiving plan,
+// describing each resource change using the JSON serialization of the
+// diffs package.
+//
+// Since this plan is produced from flatmap-based instance diffs, the value
+// of each change is a map of strings keyed by the flattened attribute path,
+// and includes only the attributes that the diff touches. Destroy changes
+// have no attributes. Sensitive values are always written as null, with
+// their paths listed in the change's "sensitive" property. Unless the
+// plan's Redaction is RedactHidden, what it shows of them is described in
+// "redacted", by attribute path.
+//
+// If the plan was created using resource targeting then the "incomplete"
+// property is true, and the targets and the corresponding warning are
+// included. Likewise if the plan was scoped to modules, which are listed in
+// "target_modules" along with the skipped dependents outside of them in
+// "scope_dependents", and if the plan deferred changes, which are listed in
+// "deferred_changes". The status of each check block is listed in "checks",
+// sorted by address, the explanations of the planned values requested with
+// -explain are in "explanations", and the fingerprint of the run that
+// created the plan is in "fingerprint". The notes that plan analyzers
+// attached to each change are in its "annotations".
+//
+// Every diagnostic, including the plan's own warnings, is listed with its
+// code in "diagnostics".
+func (p *Plan) JSON() ([]byte, error) {
+	doc := planJSON{
+		FormatVersion:   PlanJSONFormatVersion,
+		RefreshOnly:     p.RefreshOnly,
+		Incomplete:      p.Incomplete(),
+		Targets:         p.Targets,
+		TargetModules:   p.TargetModules,
+		ScopeDependents: p.ScopeDependents,
+		ResourceChanges: make([]resourceChangeJSON, 0, len(p.Resources)),
+		Fingerprint:     p.Fingerprint,
+	}
+	for _, diag := range p.Warnings() {
+		desc := diag.Description()
+		doc.Warnings = append(doc.Warnings, warningJSON{
+			Summary: desc.Summary,
+			Detail:  desc.Detail,
+		})
+	}
+	var diags tfdiags.Diagnostics
+	diags = diags.Append(p.Diagnostics, p.Warnings())
+	doc.Diagnostics = NewJSONDiagnostics(diags)
+	for _, r := range p.Resources {
+		addr := r.Addr.String()
+		change, err := r.change().MarshalJSONWith(diffs.MarshalOpts{
+			RedactSensitive: true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize change for %s: %s", addr, err)
+		}
+		rc := resourceChangeJSON{
+			Address: addr,
+			Tainted: r.Tainted,
+			Deposed: r.Deposed,
+			Change:  change,
+		}
+		if r.Importing {
+			rc.Importing = &importingJSON{ID: r.ImportID}
+		}
+		rc.PreviousAddress = r.MovedFrom
+		for _, a := range r.Annotations {
+			rc.Annotations = append(rc.Annotations, annotationJSON{
+				Analyzer: a.Analyzer,
+				Kind:     a.Kind,
+				Summary:  a.Summary,
+				Detail:   a.Detail,
+			})
+		}
+		rc.Redacted = redactedAttrs(r, p.Redaction)
+		doc.ResourceChanges = append(doc.ResourceChanges, rc)
+	}
+	for _, d := range p.Deferred {
+		doc.DeferredChanges = append(doc.DeferredChanges, deferredChangeJSON{
+			Address:   d.Addr,
+			Reason:    string(d.Reason),
+			DependsOn: d.DependsOn,
+		})
+	}
+	for addr, cs := range p.Checks {
+		doc.Checks = append(doc.Checks, checkJSON{
+			Address:         addr,
+			Status:          string(cs.Status),
+			FailureMessages: cs.FailureMessages,
+		})
+	}
+	sort.Slice(doc.Checks, func(i, j int) bool {
+		return doc.Checks[i].Address < doc.Checks[j].Address
+	})
+	doc.Explanations = newExplanationsJSON(p.Explanations)
+	return json.Marshal(doc)
+}
+
+// Changes returns the changes described by the receiving plan as a
+// diffs.ChangeSet keyed by resource address, in the same representation
+// used by JSON. Deposed objects are keyed by their address followed by
+// " (deposed)", so they do not collide with the current object.
+//
+// Unlike JSON, sensitive values are not redacted, so the result should not
+// be shown to the user without first redacting each change.
+func (p *Plan) Changes() diffs.ChangeSet {
+	ret := make(diffs.ChangeSet, len(p.Resources))
+	for _, r := range p.Resources {
+		key := r.Addr.String()
+		if r.Deposed {
+			key += " (deposed)"
+		}
+		ret[key] = r.change()
+	}
+	return ret
+}
+
+type planJSON struct {
+	FormatVersion   string               `json:"format_version"`
+	RefreshOnly     bool                 `json:"refresh_only,omitempty"`
+	Incomplete      bool                 `json:"incomplete"`
+	Targets         []string             `json:"targets,omitempty"`
+	TargetModules   []string             `json:"target_modules,omitempty"`
+	ScopeDependents []string             `json:"scope_dependents,omitempty"`
+	Warnings        []warningJSON        `json:"warnings,omitempty"`
+	Diagnostics     []*JSONDiagnostic    `json:"diagnostics"`
+	ResourceChanges []resourceChangeJSON `json:"resource_changes"`
+	DeferredChanges []deferredChangeJSON `json:"deferred_changes,omitempty"`
+	Checks          []checkJSON          `json:"checks,omitempty"`
+	Explanations    []explanationJSON    `json:"explanations,omitempty"`
+
+	Fingerprint *terraform.Fingerprint `json:"fingerprint,omitempty"`
+}
+
+type deferredChangeJSON struct {
+	Address   string `json:"address"`
+	Reason    string `json:"reason"`
+	DependsOn string `json:"depends_on,omitempty"`
+}
+
+type warningJSON struct {
+	Summary string `json:"summary"`
+	Detail  string `json:"detail"`
+}
+
+type checkJSON struct {
+	Address         string   `json:"address"`
+	Status          string   `json:"status"`
+	FailureMessages []string `json:"failure_messages,omitempty"`
+}
+
+type resourceChangeJSON struct {
+	Address         string          `json:"address"`
+	PreviousAddress string          `json:"previous_address,omitempty"`
+	Tainted         bool            `json:"tainted,omitempty"`
+	Deposed         bool            `json:"deposed,omitempty"`
+	Importing       *importingJSON  `json:"importing,omitempty"`
+	Change          json.RawMessage `json:"change"`
+
+	Redacted    map[string]*redactedChangeJSON `json:"redacted,omitempty"`
+	Annotations []annotationJSON               `json:"annotations,omitempty"`
+}
+
+type annotationJSON struct {
+	Analyzer string `json:"analyzer"`
+	Kind     string `json:"kind,omitempty"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+type redactedChangeJSON struct {
+	Old *RedactedJSON `json:"old,omitempty"`
+	New *RedactedJSON `json:"new,omitempty"`
+}
+
+// redactedAttrs returns what the given redaction shows of the sensitive
+// attributes of the given instance diff, by attribute path, or nil if it
+// shows nothing.
+func redactedAttrs(r *InstanceDiff, redaction Redaction) map[string]*redactedChangeJSON {
+	var ret map[string]*redactedChangeJSON
+	for _, attr := range r.Attributes {
+		if !attr.Sensitive {
+			continue
+		}
+		rc := &redactedChangeJSON{}
+		if r.Action != terraform.DiffCreate {
+			rc.Old = redaction.JSON(attr.OldValue)
+		}
+		if !attr.NewComputed {
+			rc.New = redaction.JSON(attr.NewValue)
+		}
+		if rc.Old == nil && rc.New == nil {
+			continue
+		}
+		if ret == nil {
+			ret = make(map[string]*redactedChangeJSON)
+		}
+		ret[attr.Path] = rc
+	}
+	return ret
+}
+
+type importingJSON struct {
+	ID string `json:"id"`
+}
+
+// InstanceChange returns the change described by the diff of the instance
+// with the given address, in the representation used by Plan.Changes.
+func InstanceChange(addr *terraform.ResourceAddress, r *terraform.InstanceDiff) *diffs.Change {
+	return newInstanceDiff(addr, r).change()
+}
+
+// RefreshChange returns the change made by refreshing an instance from the
+// given prior state, in the representation used by Plan.Changes. Either
+// state may be nil, if the instance didn't exist.
+func RefreshChange(prior, refreshed *terraform.InstanceState) *diffs.Change {
+	var old, new map[string]string
+	if prior != nil {
+		old = prior.Attributes
+	}
+	if refreshed != nil {
+		new = refreshed.Attributes
+	}
+	r := &InstanceDiff{
+		Action:     terraform.DiffRefresh,
+		Attributes: refreshedAttributes(old, new),
+	}
+	return r.change()
+}
+
+// change converts the receiver to the equivalent diffs.Change, whose value
+// type is a map of strings keyed by flattened attribute path.
+func (r *InstanceDiff) change() *diffs.Change {
+	ty := cty.Map(cty.String)
+	oldAttrs := map[string]cty.Value{}
+	newAttrs := map[string]cty.Value{}
+	forced := diffs.NewPathSet()
+	sensitive := diffs.NewPathSet()
+	sources := diffs.NewPathSources()
+
+	for _, attr := range r.Attributes {
+		path := cty.Path{cty.IndexStep{Key: cty.StringVal(attr.Path)}}
+
+		oldV := cty.StringVal(attr.OldValue)
+		newV := cty.StringVal(attr.NewValue)
+		if attr.NewComputed {
+			newV = cty.UnknownVal(cty.String)
+			if len(attr.UnknownSources) > 0 {
+				sources.Set(path, attr.UnknownSources)
+			}
+		}
+		if attr.Sensitive {
+			sensitive.Add(path)
+		}
+		if attr.ForcesNew {
+			forced.Add(path)
+		}
+
+		if attr.Action != terraform.DiffCreate {
+			oldAttrs[attr.Path] = oldV
+		}
+		if attr.Action != terraform.DiffDestroy {
+			newAttrs[attr.Path] = newV
+		}
+	}
+
+	old := flatmapVal(oldAttrs)
+	new := flatmapVal(newAttrs)
+
+	var ret *diffs.Change
+	switch r.Action {
+	case terraform.DiffCreate:
+		ret = diffs.NewCreate(ty, new)
+	case terraform.DiffRefresh:
+		if r.Importing {
+			ret = diffs.NewImport(ty, new)
+			break
+		}
+		prior := cty.NullVal(ty)
+		if len(oldAttrs) > 0 {
+			// A refresh of an existing object, rather than a data source
+			// read, so we know what the value was before.
+			prior = old
+		}
+		ret = diffs.NewRead(ty, prior, new)
+	case terraform.DiffNone:
+		if r.Forget {
+			ret = diffs.NewForget(ty, old)
+			break
+		}
+		ret = diffs.NewNoOp(ty, old)
+	case terraform.DiffDestroy:
+		ret = diffs.NewDelete(ty, old)
+	case terraform.DiffDestroyCreate:
+		ret = diffs.NewReplace(ty, old, new, forced)
+		switch {
+		case r.ReplaceRequested:
+			ret = ret.WithReplaceReason(diffs.ReplaceByRequest)
+		case r.Tainted:
+			ret = ret.WithReplaceReason(diffs.ReplaceBecauseTainted)
+		}
+	default:
+		ret = diffs.NewUpdate(ty, old, new)
+	}
+	ret.Sensitive = sensitive
+	ret.UnknownSources = sources
+	return ret
+}
+
+func flatmapVal(attrs map[string]cty.Value) cty.Value {
+	if len(attrs) == 0 {
+		return cty.MapValEmpty(cty.String)
+	}
+	return cty.MapVal(attrs)
+}
@@ -0,0 +1,268 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestPlanJSON(t *testing.T) {
+	plan := &Plan{
+		Resources: []*InstanceDiff{
+			{
+				Addr:   mustParseResourceAddress("test_resource.foo"),
+				Action: terraform.DiffCreate,
+				Attributes: []*AttributeDiff{
+					{
+						Path:        "id",
+						Action:      terraform.DiffCreate,
+						NewComputed: true,
+					},
+					{
+						Path:     "name",
+						Action:   terraform.DiffCreate,
+						NewValue: "foo",
+					},
+				},
+			},
+			{
+				Addr:   mustParseResourceAddress("test_resource.bar"),
+				Action: terraform.DiffDestroyCreate,
+				Attributes: []*AttributeDiff{
+					{
+						Path:      "ami",
+						Action:    terraform.DiffUpdate,
+						OldValue:  "ami-1",
+						NewValue:  "ami-2",
+						ForcesNew: true,
+					},
+					{
+						Path:      "password",
+						Action:    terraform.DiffUpdate,
+						OldValue:  "hunter2",
+						NewValue:  "hunter3",
+						Sensitive: true,
+					},
+				},
+			},
+			{
+				Addr:    mustParseResourceAddress("test_resource.baz"),
+				Action:  terraform.DiffDestroy,
+				Deposed: true,
+			},
+		},
+	}
+
+	got, err := plan.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := `{"format_version":"0.1","incomplete":false,"diagnostics":[],"resource_changes":[` +
+		`{"address":"test_resource.foo","change":{"action":"create","type":["map","string"],"old":null,"new":{"id":null,"name":"foo"},"new_unknown":[[["id"]]]}},` +
+		`{"address":"test_resource.bar","change":{"action":"replace","type":["map","string"],"old":{"ami":"ami-1","password":null},"new":{"ami":"ami-2","password":null},"forced_replace":[[["ami"]]],"sensitive":[[["password"]]],"replace_reason":"cannot_update"}},` +
+		`{"address":"test_resource.baz","deposed":true,"change":{"action":"delete","type":["map","string"],"old":{},"new":null}}` +
+		`]}`
+	if string(got) != want {
+		t.Errorf("wrong result\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestPlanJSON_targeted(t *testing.T) {
+	plan := NewPlan(&terraform.Plan{
+		Targets: []string{"test_resource.foo"},
+	})
+
+	got, err := plan.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := `{"format_version":"0.1","incomplete":true,"targets":["test_resource.foo"],"warnings":[` +
+		`{"summary":"Resource targeting is in effect","detail":"This plan includes only the changes for test_resource.foo and the resources they depend on, so it may be incomplete.\n\nResource targeting is intended for exceptional situations such as recovering from errors or mistakes. Run \"terraform plan\" without -target to see all of the changes that the configuration requires."}` +
+		`],"diagnostics":[` +
+		`{"severity":"warning","code":"plan.targeted","summary":"Resource targeting is in effect","detail":"This plan includes only the changes for test_resource.foo and the resources they depend on, so it may be incomplete.\n\nResource targeting is intended for exceptional situations such as recovering from errors or mistakes. Run \"terraform plan\" without -target to see all of the changes that the configuration requires."}` +
+		`],"resource_changes":[]}`
+	if string(got) != want {
+		t.Errorf("wrong result\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestPlanJSON_targetModules(t *testing.T) {
+	plan := NewPlan(&terraform.Plan{
+		TargetModules:   []string{"module.network"},
+		ScopeDependents: []string{"module.app"},
+	})
+
+	got, err := plan.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	scoped := `"summary":"Module scoping is in effect","detail":"This plan includes only the changes for the resources in module.network and the objects they depend on, so it may be incomplete."`
+	skipped := `"summary":"Dependents outside of the module scope were skipped","detail":"These depend on module.network, but are outside of the scope, so this plan doesn't include any changes that they need:\n\n  - module.app\n\nOnce this plan is applied, run \"terraform plan\" without -target-module to plan their changes."`
+	want := `{"format_version":"0.1","incomplete":true,"target_modules":["module.network"],"scope_dependents":["module.app"],"warnings":[` +
+		`{` + scoped + `},{` + skipped + `}` +
+		`],"diagnostics":[` +
+		`{"severity":"warning","code":"plan.module_scoped",` + scoped + `},` +
+		`{"severity":"warning","code":"plan.module_scoped",` + skipped + `}` +
+		`],"resource_changes":[]}`
+	if string(got) != want {
+		t.Errorf("wrong result\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestPlanJSON_checks(t *testing.T) {
+	plan := NewPlan(&terraform.Plan{
+		Checks: map[string]*terraform.CheckState{
+			"check.size": &terraform.CheckState{Status: terraform.ConditionPass},
+			"check.health": &terraform.CheckState{
+				Status:          terraform.ConditionFail,
+				FailureMessages: []string{"The status must be ok."},
+			},
+		},
+	})
+
+	got, err := plan.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := `{"format_version":"0.1","incomplete":false,"warnings":[` +
+		`{"summary":"Check block assertion failed","detail":"check.health failed:\n\nThe status must be ok."}` +
+		`],"diagnostics":[` +
+		`{"severity":"warning","code":"check.failed","summary":"Check block assertion failed","detail":"check.health failed:\n\nThe status must be ok."}` +
+		`],"resource_changes":[],"checks":[` +
+		`{"address":"check.health","status":"fail","failure_messages":["The status must be ok."]},` +
+		`{"address":"check.size","status":"pass"}` +
+		`]}`
+	if string(got) != want {
+		t.Errorf("wrong result\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestPlanJSON_deferred(t *testing.T) {
+	plan := NewPlan(&terraform.Plan{
+		Deferred: []*terraform.DeferredChange{
+			{Addr: "test_resource.bar", Reason: terraform.DeferredCountUnknown},
+			{Addr: "test_resource.baz", Reason: terraform.DeferredDependency, DependsOn: "test_resource.bar"},
+		},
+	})
+
+	got, err := plan.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := `{"format_version":"0.1","incomplete":true,"warnings":[` +
+		`{"summary":"Some changes were deferred","detail":"The changes of these resources can't be planned until some values are known, so this plan doesn't include them:\n\n` +
+		`  - test_resource.bar: count depends on values that are only known after apply\n` +
+		`  - test_resource.baz: depends on test_resource.bar, whose changes were deferred\n\n` +
+		`Once this plan is applied, run \"terraform plan\" again to plan the deferred changes."}` +
+		`],"diagnostics":[` +
+		`{"severity":"warning","code":"plan.deferred","summary":"Some changes were deferred","detail":"The changes of these resources can't be planned until some values are known, so this plan doesn't include them:\n\n` +
+		`  - test_resource.bar: count depends on values that are only known after apply\n` +
+		`  - test_resource.baz: depends on test_resource.bar, whose changes were deferred\n\n` +
+		`Once this plan is applied, run \"terraform plan\" again to plan the deferred changes."}` +
+		`],"resource_changes":[],"deferred_changes":[` +
+		`{"address":"test_resource.bar","reason":"count_unknown"},` +
+		`{"address":"test_resource.baz","reason":"dependency","depends_on":"test_resource.bar"}` +
+		`]}`
+	if string(got) != want {
+		t.Errorf("wrong result\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestPlanJSON_redacted(t *testing.T) {
+	plan := &Plan{
+		Redaction: RedactLength,
+		Resources: []*InstanceDiff{
+			{
+				Addr:   mustParseResourceAddress("test_resource.foo"),
+				Action: terraform.DiffUpdate,
+				Attributes: []*AttributeDiff{
+					{
+						Path:      "password",
+						Action:    terraform.DiffUpdate,
+						OldValue:  "hunter2",
+						NewValue:  "correct horse",
+						Sensitive: true,
+					},
+				},
+			},
+		},
+	}
+
+	got, err := plan.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := `{"format_version":"0.1","incomplete":false,"diagnostics":[],"resource_changes":[` +
+		`{"address":"test_resource.foo","change":{"action":"update","type":["map","string"],"old":{"password":null},"new":{"password":null},"sensitive":[[["password"]]]},` +
+		`"redacted":{"password":{"old":{"characters":7},"new":{"characters":13}}}}` +
+		`]}`
+	if string(got) != want {
+		t.Errorf("wrong result\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestPlanJSON_unknownSources(t *testing.T) {
+	plan := &Plan{
+		Resources: []*InstanceDiff{
+			{
+				Addr:   mustParseResourceAddress("test_resource.foo"),
+				Action: terraform.DiffCreate,
+				Attributes: []*AttributeDiff{
+					{
+						Path:           "A",
+						Action:         terraform.DiffCreate,
+						NewComputed:    true,
+						UnknownSources: []string{"test_resource.bar.id"},
+					},
+				},
+			},
+		},
+	}
+
+	got, err := plan.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := `{"format_version":"0.1","incomplete":false,"diagnostics":[],"resource_changes":[` +
+		`{"address":"test_resource.foo","change":{"action":"create","type":["map","string"],"old":null,"new":{"A":null},"new_unknown":[[["A"]]],` +
+		`"unknown_sources":[{"path":[["A"]],"refs":["test_resource.bar.id"]}]}}` +
+		`]}`
+	if string(got) != want {
+		t.Errorf("wrong result\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestPlanJSON_annotations(t *testing.T) {
+	plan := &Plan{
+		Resources: []*InstanceDiff{
+			{
+				Addr:   mustParseResourceAddress("test_resource.foo"),
+				Action: terraform.DiffDestroy,
+				Annotations: []*terraform.PlanAnnotation{
+					{Addr: "test_resource.foo", Analyzer: "costs", Kind: "cost", Summary: "-$10/month"},
+					{Addr: "test_resource.foo", Analyzer: "notes", Summary: "last one", Detail: "No more remain."},
+				},
+			},
+		},
+	}
+
+	got, err := plan.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := `{"format_version":"0.1","incomplete":false,"diagnostics":[],"resource_changes":[` +
+		`{"address":"test_resource.foo","change":{"action":"delete","type":["map","string"],"old":{},"new":null},` +
+		`"annotations":[{"analyzer":"costs","kind":"cost","summary":"-$10/month"},{"analyzer":"notes","summary":"last one","detail":"No more remain."}]}` +
+		`]}`
+	if string(got) != want {
+		t.Errorf("wrong result\ngot:  %s\nwant: %s", got, want)
+	}
+}
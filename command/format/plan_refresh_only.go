@@ -0,0 +1,165 @@
+package format
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// NewRefreshOnlyPlan produces a display-oriented Plan from a refresh-only
+// terraform.Plan, whose diff is always empty.
+//
+// Each managed resource instance whose refreshed state in the plan differs
+// from the given prior state is included as a DiffRefresh action, with the
+// attributes that changed outside of Terraform. Instances that were not
+// found when refreshing have all of their attributes removed.
+func NewRefreshOnlyPlan(prior *terraform.State, plan *terraform.Plan) *Plan {
+	ret := &Plan{RefreshOnly: true}
+	if plan == nil {
+		return ret
+	}
+	if len(plan.Targets) > 0 {
+		ret.Targets = plan.Targets
+	}
+	if len(plan.TargetModules) > 0 {
+		ret.TargetModules = plan.TargetModules
+	}
+
+	refreshed := plan.State
+	seen := map[string]bool{}
+	var modules [][]string
+	for _, s := range []*terraform.State{prior, refreshed} {
+		if s == nil {
+			continue
+		}
+		for _, m := range s.Modules {
+			key := strings.Join(m.Path, ".")
+			if !seen[key] {
+				seen[key] = true
+				modules = append(modules, m.Path)
+			}
+		}
+	}
+
+	for _, path := range modules {
+		var modulePath []string
+		if len(path) > 1 {
+			// trim off the leading "root" path segment, since it's implied
+			// when we use a path in a resource address.
+			modulePath = path[1:]
+		}
+
+		priorMod := moduleState(prior, path)
+		newMod := moduleState(refreshed, path)
+
+		keys := map[string]bool{}
+		for _, m := range []*terraform.ModuleState{priorMod, newMod} {
+			if m == nil {
+				continue
+			}
+			for k := range m.Resources {
+				keys[k] = true
+			}
+		}
+
+		for k := range keys {
+			addr, err := terraform.ParseResourceAddressForInstanceDiff(modulePath, k)
+			if err != nil {
+				// should never happen; indicates invalid state
+				panic("invalid resource address in state")
+			}
+			if addr.Mode == config.DataResourceMode {
+				// Data resources are read again on every refresh, so their
+				// changes are not interesting here.
+				continue
+			}
+
+			oldAttrs := instanceAttrs(priorMod, k)
+			newAttrs := instanceAttrs(newMod, k)
+			attrs := refreshedAttributes(oldAttrs, newAttrs)
+			if len(attrs) == 0 {
+				continue
+			}
+
+			ret.Resources = append(ret.Resources, &InstanceDiff{
+				Addr:       addr,
+				Action:     terraform.DiffRefresh,
+				Attributes: attrs,
+			})
+		}
+	}
+
+	// Sort the instance diffs by their addresses for display.
+	sort.Slice(ret.Resources, func(i, j int) bool {
+		return ret.Resources[i].Addr.Less(ret.Resources[j].Addr)
+	})
+
+	return ret
+}
+
+// refreshedAttributes returns the attribute diffs between the given
+// flatmap attributes, sorted with "id" first and then by path.
+func refreshedAttributes(old, new map[string]string) []*AttributeDiff {
+	var ret []*AttributeDiff
+	for k, ov := range old {
+		nv, ok := new[k]
+		switch {
+		case !ok:
+			ret = append(ret, &AttributeDiff{
+				Path:     k,
+				Action:   terraform.DiffDestroy,
+				OldValue: ov,
+			})
+		case nv != ov:
+			ret = append(ret, &AttributeDiff{
+				Path:     k,
+				Action:   terraform.DiffUpdate,
+				OldValue: ov,
+				NewValue: nv,
+			})
+		}
+	}
+	for k, nv := range new {
+		if _, ok := old[k]; !ok {
+			ret = append(ret, &AttributeDiff{
+				Path:     k,
+				Action:   terraform.DiffCreate,
+				NewValue: nv,
+			})
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		iPath := ret[i].Path
+		jPath := ret[j].Path
+
+		// as a special case, "id" is always first
+		switch {
+		case iPath != jPath && (iPath == "id" || jPath == "id"):
+			return iPath == "id"
+		default:
+			return iPath < jPath
+		}
+	})
+	return ret
+}
+
+func moduleState(s *terraform.State, path []string) *terraform.ModuleState {
+	if s == nil {
+		return nil
+	}
+	return s.ModuleByPath(path)
+}
+
+func instanceAttrs(m *terraform.ModuleState, key string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	rs := m.Resources[key]
+	if rs == nil || rs.Primary == nil {
+		return nil
+	}
+	return rs.Primary.Attributes
+}
@@ -0,0 +1,93 @@
+package format
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestNewRefreshOnlyPlan(t *testing.T) {
+	instance := func(attrs map[string]string) *terraform.ResourceState {
+		return &terraform.ResourceState{
+			Type: "test_resource",
+			Primary: &terraform.InstanceState{
+				ID:         attrs["id"],
+				Attributes: attrs,
+			},
+		}
+	}
+	prior := &terraform.State{
+		Modules: []*terraform.ModuleState{
+			{
+				Path: []string{"root"},
+				Resources: map[string]*terraform.ResourceState{
+					"test_resource.changed": instance(map[string]string{
+						"id":      "a",
+						"name":    "before",
+						"removed": "gone",
+					}),
+					"test_resource.same": instance(map[string]string{
+						"id": "b",
+					}),
+					"test_resource.deleted": instance(map[string]string{
+						"id": "c",
+					}),
+					"data.test_data.ignored": instance(map[string]string{
+						"id": "d",
+					}),
+				},
+			},
+		},
+	}
+	refreshed := &terraform.State{
+		Modules: []*terraform.ModuleState{
+			{
+				Path: []string{"root"},
+				Resources: map[string]*terraform.ResourceState{
+					"test_resource.changed": instance(map[string]string{
+						"id":    "a",
+						"name":  "after",
+						"added": "new",
+					}),
+					"test_resource.same": instance(map[string]string{
+						"id": "b",
+					}),
+					"data.test_data.ignored": instance(map[string]string{
+						"id": "e",
+					}),
+				},
+			},
+		},
+	}
+
+	got := NewRefreshOnlyPlan(prior, &terraform.Plan{
+		Diff:        &terraform.Diff{},
+		State:       refreshed,
+		RefreshOnly: true,
+	})
+	want := &Plan{
+		RefreshOnly: true,
+		Resources: []*InstanceDiff{
+			{
+				Addr:   mustParseResourceAddress("test_resource.changed"),
+				Action: terraform.DiffRefresh,
+				Attributes: []*AttributeDiff{
+					{Path: "added", Action: terraform.DiffCreate, NewValue: "new"},
+					{Path: "name", Action: terraform.DiffUpdate, OldValue: "before", NewValue: "after"},
+					{Path: "removed", Action: terraform.DiffDestroy, OldValue: "gone"},
+				},
+			},
+			{
+				Addr:   mustParseResourceAddress("test_resource.deleted"),
+				Action: terraform.DiffRefresh,
+				Attributes: []*AttributeDiff{
+					{Path: "id", Action: terraform.DiffDestroy, OldValue: "c"},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/hashicorp/terraform/diffs"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/colorstring"
 )
@@ -544,6 +545,307 @@ func TestPlan_forcesNewResource(t *testing.T) {
 	}
 }
 
+// Ensure that a replacement requested by the user is explained as such
+func TestPlan_replaceRequested(t *testing.T) {
+	plan := &terraform.Plan{
+		Diff: &terraform.Diff{
+			Modules: []*terraform.ModuleDiff{
+				&terraform.ModuleDiff{
+					Path: []string{"root"},
+					Resources: map[string]*terraform.InstanceDiff{
+						"test_resource.foo": &terraform.InstanceDiff{
+							Destroy:          true,
+							ReplaceRequested: true,
+							Attributes: map[string]*terraform.ResourceAttrDiff{
+								"id": &terraform.ResourceAttrDiff{
+									Old:         "bar",
+									NewComputed: true,
+									RequiresNew: true,
+								},
+								"A": &terraform.ResourceAttrDiff{
+									New: "B",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	dispPlan := NewPlan(plan)
+	actual := dispPlan.Format(disabledColorize)
+
+	expected := strings.TrimSpace(`
+-/+ test_resource.foo (replace requested by user)
+      id: "bar" => <computed> (forces new resource)
+      A:  "" => "B"
+	`)
+	if actual != expected {
+		t.Fatalf("expected:\n\n%s\n\ngot:\n\n%s", expected, actual)
+	}
+
+	change := dispPlan.Changes()["test_resource.foo"]
+	if change.ReplaceReason != diffs.ReplaceByRequest {
+		t.Fatalf("wrong replace reason %s for test_resource.foo; want %s", change.ReplaceReason, diffs.ReplaceByRequest)
+	}
+}
+
+func TestPlan_importing(t *testing.T) {
+	plan := &terraform.Plan{
+		Diff: &terraform.Diff{
+			Modules: []*terraform.ModuleDiff{
+				&terraform.ModuleDiff{
+					Path: []string{"root"},
+					Resources: map[string]*terraform.InstanceDiff{
+						"test_resource.foo": &terraform.InstanceDiff{
+							Attributes: map[string]*terraform.ResourceAttrDiff{
+								"A": &terraform.ResourceAttrDiff{
+									Old: "B",
+									New: "C",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		State: &terraform.State{
+			Modules: []*terraform.ModuleState{
+				&terraform.ModuleState{
+					Path: []string{"root"},
+					Resources: map[string]*terraform.ResourceState{
+						"test_resource.foo": &terraform.ResourceState{
+							Type: "test_resource",
+							Primary: &terraform.InstanceState{
+								ID: "foo-1",
+								Attributes: map[string]string{
+									"id": "foo-1",
+									"A":  "B",
+								},
+							},
+						},
+						"test_resource.bar": &terraform.ResourceState{
+							Type: "test_resource",
+							Primary: &terraform.InstanceState{
+								ID: "bar-1",
+								Attributes: map[string]string{
+									"id": "bar-1",
+									"A":  "B",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Imports: []*terraform.ImportTarget{
+			&terraform.ImportTarget{Addr: "test_resource.foo", ID: "foo-1"},
+			&terraform.ImportTarget{Addr: "test_resource.bar", ID: "bar-1"},
+		},
+	}
+	dispPlan := NewPlan(plan)
+	actual := dispPlan.Format(disabledColorize)
+
+	expected := strings.TrimSpace(`
+ <= test_resource.bar (import id: "bar-1")
+      id: "bar-1"
+      A:  "B"
+
+  ~ test_resource.foo (import id: "foo-1")
+      A:  "B" => "C"
+	`)
+	if actual != expected {
+		t.Fatalf("expected:\n\n%s\n\ngot:\n\n%s", expected, actual)
+	}
+
+	stats := dispPlan.Stats()
+	if stats.ToImport != 2 || stats.ToChange != 1 {
+		t.Fatalf("wrong stats %#v", stats)
+	}
+
+	change := dispPlan.Changes()["test_resource.bar"]
+	if change.Action != diffs.Read || !change.Old.IsNull() {
+		t.Fatalf("wrong change %s for test_resource.bar; want read from null", change.Action)
+	}
+}
+
+func TestPlan_moved(t *testing.T) {
+	plan := &terraform.Plan{
+		Diff: &terraform.Diff{
+			Modules: []*terraform.ModuleDiff{
+				&terraform.ModuleDiff{
+					Path: []string{"root"},
+					Resources: map[string]*terraform.InstanceDiff{
+						"test_resource.foo": &terraform.InstanceDiff{
+							Attributes: map[string]*terraform.ResourceAttrDiff{
+								"A": &terraform.ResourceAttrDiff{
+									Old: "B",
+									New: "C",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Moves: []*diffs.Move{
+			&diffs.Move{From: "test_resource.old_foo", To: "test_resource.foo"},
+			&diffs.Move{From: "test_resource.old_bar", To: "test_resource.bar"},
+		},
+	}
+	dispPlan := NewPlan(plan)
+	actual := dispPlan.Format(disabledColorize)
+
+	expected := strings.TrimSpace(`
+    test_resource.bar (moved from test_resource.old_bar)
+
+  ~ test_resource.foo (moved from test_resource.old_foo)
+      A: "B" => "C"
+	`)
+	if actual != expected {
+		t.Fatalf("expected:\n\n%s\n\ngot:\n\n%s", expected, actual)
+	}
+
+	stats := dispPlan.Stats()
+	if stats.ToMove != 2 || stats.ToChange != 1 {
+		t.Fatalf("wrong stats %#v", stats)
+	}
+
+	change := dispPlan.Changes()["test_resource.bar"]
+	if change.Action != diffs.NoOp {
+		t.Fatalf("wrong action %s for test_resource.bar; want %s", change.Action, diffs.NoOp)
+	}
+}
+
+func TestPlan_annotations(t *testing.T) {
+	plan := &terraform.Plan{
+		Diff: &terraform.Diff{
+			Modules: []*terraform.ModuleDiff{
+				&terraform.ModuleDiff{
+					Path: []string{"root"},
+					Resources: map[string]*terraform.InstanceDiff{
+						"test_resource.foo": &terraform.InstanceDiff{
+							Attributes: map[string]*terraform.ResourceAttrDiff{
+								"A": &terraform.ResourceAttrDiff{
+									Old: "B",
+									New: "C",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Annotations: []*terraform.PlanAnnotation{
+			{
+				Addr:     "test_resource.foo",
+				Analyzer: "costs",
+				Kind:     "cost",
+				Summary:  "+$10/month",
+			},
+			{
+				Addr:     "test_resource.foo",
+				Analyzer: "scanner",
+				Summary:  "A is public",
+				Detail:   "Anyone can read A.\nSet it to \"private\".",
+			},
+		},
+	}
+	dispPlan := NewPlan(plan)
+	actual := dispPlan.Format(disabledColorize)
+
+	expected := strings.TrimSpace(`
+~ test_resource.foo
+      A: "B" => "C"
+      # cost (costs): +$10/month
+      # scanner: A is public
+      #   Anyone can read A.
+      #   Set it to "private".
+	`)
+	if actual != expected {
+		t.Fatalf("expected:\n\n%s\n\ngot:\n\n%s", expected, actual)
+	}
+}
+
+func TestPlan_unknownSources(t *testing.T) {
+	plan := &terraform.Plan{
+		Diff: &terraform.Diff{
+			Modules: []*terraform.ModuleDiff{
+				&terraform.ModuleDiff{
+					Path: []string{"root"},
+					Resources: map[string]*terraform.InstanceDiff{
+						"test_resource.foo": &terraform.InstanceDiff{
+							Attributes: map[string]*terraform.ResourceAttrDiff{
+								"A": &terraform.ResourceAttrDiff{
+									Old:         "B",
+									NewComputed: true,
+								},
+								"id": &terraform.ResourceAttrDiff{
+									Old:         "foo",
+									NewComputed: true,
+								},
+							},
+							UnknownSources: map[string][]string{
+								"A": {"test_resource.bar.id"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	dispPlan := NewPlan(plan)
+	actual := dispPlan.Format(disabledColorize)
+
+	expected := strings.TrimSpace(`
+~ test_resource.foo
+      id: "foo" => <computed>
+      A:  "B" => <computed> (from test_resource.bar.id)
+	`)
+	if actual != expected {
+		t.Fatalf("expected:\n\n%s\n\ngot:\n\n%s", expected, actual)
+	}
+}
+
+func TestPlan_removed(t *testing.T) {
+	plan := &terraform.Plan{
+		Diff: &terraform.Diff{},
+		Removed: []*terraform.RemovedObject{
+			&terraform.RemovedObject{
+				Addr: "test_resource.foo",
+				Primary: &terraform.InstanceState{
+					ID: "abc",
+					Attributes: map[string]string{
+						"id": "abc",
+						"A":  "B",
+					},
+				},
+			},
+		},
+	}
+	dispPlan := NewPlan(plan)
+	actual := dispPlan.Format(disabledColorize)
+
+	expected := strings.TrimSpace(`
+    test_resource.foo (removed from state, will not be destroyed)
+      id: "abc"
+      A:  "B"
+	`)
+	if actual != expected {
+		t.Fatalf("expected:\n\n%s\n\ngot:\n\n%s", expected, actual)
+	}
+
+	stats := dispPlan.Stats()
+	if stats.ToForget != 1 || stats.ToDestroy != 0 {
+		t.Fatalf("wrong stats %#v", stats)
+	}
+
+	change := dispPlan.Changes()["test_resource.foo"]
+	if change.Action != diffs.Forget {
+		t.Fatalf("wrong action %s for test_resource.foo; want %s", change.Action, diffs.Forget)
+	}
+}
+
 // Test that a root level data source gets a special plan output on create
 func TestPlan_rootDataSource(t *testing.T) {
 	plan := &terraform.Plan{
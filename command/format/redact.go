@@ -0,0 +1,108 @@
+package format
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// Redaction selects how sensitive values are shown in output, where they
+// are never shown as they are.
+type Redaction int
+
+const (
+	// RedactHidden, the default, shows a sensitive value only as
+	// "<sensitive>".
+	RedactHidden Redaction = iota
+
+	// RedactHash shows the first 16 hex digits of the hash of a sensitive
+	// value, as computed by terraform.VariableHash, so that it can be told
+	// whether two values are the same. The hash of a value that is easily
+	// guessed still reveals it to anyone who tries the right guess.
+	RedactHash
+
+	// RedactLength shows the number of characters of a sensitive string,
+	// or the number of elements of a sensitive list or map.
+	RedactLength
+)
+
+var redactionNames = map[Redaction]string{
+	RedactHidden: "hidden",
+	RedactHash:   "hash",
+	RedactLength: "length",
+}
+
+// ParseRedaction returns the redaction with the given name, which is
+// "hidden", "hash" or "length". The empty string selects RedactHidden.
+func ParseRedaction(s string) (Redaction, error) {
+	if s == "" {
+		return RedactHidden, nil
+	}
+	for r, name := range redactionNames {
+		if s == name {
+			return r, nil
+		}
+	}
+	return RedactHidden, fmt.Errorf("invalid sensitive value redaction %q: must be \"hidden\", \"hash\" or \"length\"", s)
+}
+
+func (r Redaction) String() string {
+	if name, ok := redactionNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("Redaction(%d)", int(r))
+}
+
+// Value returns how the given sensitive value is shown in human output,
+// such as "<sensitive>" or "<sensitive, 12 characters>".
+func (r Redaction) Value(v interface{}) string {
+	rv := r.JSON(v)
+	switch {
+	case rv == nil:
+		return "<sensitive>"
+	case rv.SHA256 != "":
+		return fmt.Sprintf("<sensitive sha256:%s>", rv.SHA256)
+	case rv.Characters != nil:
+		return fmt.Sprintf("<sensitive, %d characters>", *rv.Characters)
+	default:
+		return fmt.Sprintf("<sensitive, %d elements>", *rv.Elements)
+	}
+}
+
+// RedactedJSON describes a sensitive value in JSON output, whose value
+// itself is written as null, as selected by a Redaction.
+type RedactedJSON struct {
+	SHA256     string `json:"sha256,omitempty"`
+	Characters *int   `json:"characters,omitempty"`
+	Elements   *int   `json:"elements,omitempty"`
+}
+
+// JSON returns the description of the given sensitive value for JSON
+// output, or nil if nothing is shown of it.
+func (r Redaction) JSON(v interface{}) *RedactedJSON {
+	switch r {
+	case RedactHash:
+		h, err := terraform.VariableHash(v)
+		if err != nil {
+			return nil
+		}
+		return &RedactedJSON{SHA256: h[:16]}
+	case RedactLength:
+		var n int
+		switch tv := v.(type) {
+		case string:
+			n = utf8.RuneCountInString(tv)
+			return &RedactedJSON{Characters: &n}
+		case []interface{}:
+			n = len(tv)
+		case map[string]interface{}:
+			n = len(tv)
+		default:
+			return nil
+		}
+		return &RedactedJSON{Elements: &n}
+	default:
+		return nil
+	}
+}
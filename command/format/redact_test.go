@@ -0,0 +1,52 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestRedactionValue(t *testing.T) {
+	hash, err := terraform.VariableHash("hunter2")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	cases := []struct {
+		Redaction Redaction
+		Value     interface{}
+		Want      string
+	}{
+		{RedactHidden, "hunter2", "<sensitive>"},
+		{RedactHash, "hunter2", "<sensitive sha256:" + hash[:16] + ">"},
+		{RedactLength, "hunter2", "<sensitive, 7 characters>"},
+		{RedactLength, "héllo", "<sensitive, 5 characters>"},
+		{RedactLength, []interface{}{"a", "b"}, "<sensitive, 2 elements>"},
+		{RedactLength, map[string]interface{}{"a": "b"}, "<sensitive, 1 elements>"},
+		{RedactLength, 42, "<sensitive>"},
+	}
+
+	for _, tc := range cases {
+		if got := tc.Redaction.Value(tc.Value); got != tc.Want {
+			t.Errorf("%s of %#v: got %q, want %q", tc.Redaction, tc.Value, got, tc.Want)
+		}
+	}
+}
+
+func TestParseRedaction(t *testing.T) {
+	for _, name := range []string{"hidden", "hash", "length"} {
+		r, err := ParseRedaction(name)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", name, err)
+		}
+		if r.String() != name {
+			t.Errorf("wrong redaction for %q: %s", name, r)
+		}
+	}
+	if r, err := ParseRedaction(""); err != nil || r != RedactHidden {
+		t.Errorf("wrong redaction for empty string: %s, %v", r, err)
+	}
+	if _, err := ParseRedaction("scrambled"); err == nil {
+		t.Error("expected error for unknown redaction")
+	}
+}
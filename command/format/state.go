@@ -21,6 +21,9 @@ type StateOpts struct {
 	// ModuleDepth is the depth of the modules to expand. By default this
 	// is zero which will not expand modules at all.
 	ModuleDepth int
+
+	// Redaction selects how sensitive outputs are shown.
+	Redaction Redaction
 }
 
 // State takes a state and returns a string
@@ -61,6 +64,10 @@ func State(opts *StateOpts) string {
 		// Output each output k/v pair
 		for _, k := range ks {
 			v := m.Outputs[k]
+			if v.Sensitive {
+				buf.WriteString(fmt.Sprintf("%s = %s\n", k, opts.Redaction.Value(v.Value)))
+				continue
+			}
 			switch output := v.Value.(type) {
 			case string:
 				buf.WriteString(fmt.Sprintf("%s = %s", k, output))
@@ -40,7 +40,7 @@ func (c *GetCommand) Run(args []string) int {
 		mode = module.GetModeUpdate
 	}
 
-	if err := getModules(&c.Meta, path, mode); err != nil {
+	if err := getModules(&c.Meta, path, mode, false); err != nil {
 		c.Ui.Error(err.Error())
 		return 1
 	}
@@ -63,7 +63,8 @@ Usage: terraform get [options] PATH
 Options:
 
   -update=false       If true, modules already downloaded will be checked
-                      for updates and updated if necessary.
+                      for updates and updated if necessary. Modules locked
+                      in .terraform.lock.hcl keep their locked versions.
 
   -no-color           If specified, output won't contain any color.
 
@@ -75,16 +76,46 @@ func (c *GetCommand) Synopsis() string {
 	return "Download and install modules for the configuration"
 }
 
-func getModules(m *Meta, path string, mode module.GetMode) error {
+// getModules installs the modules of the configuration in the given
+// directory and records them in its module lock file. Locked modules are
+// installed at their locked versions and must have their locked contents,
+// unless upgrade is set, in which case all of the modules are locked
+// again as they are installed.
+func getModules(m *Meta, path string, mode module.GetMode, upgrade bool) error {
 	mod, err := module.NewTreeModule("", path)
 	if err != nil {
 		return fmt.Errorf("Error loading configuration: %s", err)
 	}
 
-	err = mod.Load(m.moduleStorage(m.DataDir(), mode))
+	lockFile := m.moduleLock(path)
+	locked, err := lockFile.Read()
+	if err != nil {
+		return fmt.Errorf("Error reading module lock file: %s", err)
+	}
+
+	s := m.moduleStorage(m.DataDir(), mode)
+	if !upgrade {
+		s.Locks = locked
+	}
+	err = mod.Load(s)
 	if err != nil {
 		return fmt.Errorf("Error loading modules: %s", err)
 	}
 
+	current, err := mod.Locks()
+	if err != nil {
+		return fmt.Errorf("Error locking modules: %s", err)
+	}
+	if !upgrade {
+		if err := checkModuleLocks(locked, current); err != nil {
+			return err
+		}
+	}
+	if !sameModuleLocks(locked, current) {
+		if err := lockFile.Write(current); err != nil {
+			return fmt.Errorf("Error writing module lock file: %s", err)
+		}
+	}
+
 	return nil
 }
@@ -24,6 +24,7 @@ func (c *GraphCommand) Run(args []string) int {
 	var moduleDepth int
 	var verbose bool
 	var drawCycles bool
+	var jsonOutput bool
 	var graphTypeStr string
 
 	args, err := c.Meta.process(args, false)
@@ -35,6 +36,7 @@ func (c *GraphCommand) Run(args []string) int {
 	c.addModuleDepthFlag(cmdFlags, &moduleDepth)
 	cmdFlags.BoolVar(&verbose, "verbose", false, "verbose")
 	cmdFlags.BoolVar(&drawCycles, "draw-cycles", false, "draw-cycles")
+	cmdFlags.BoolVar(&jsonOutput, "json", false, "json")
 	cmdFlags.StringVar(&graphTypeStr, "type", "", "type")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := cmdFlags.Parse(args); err != nil {
@@ -139,11 +141,18 @@ func (c *GraphCommand) Run(args []string) int {
 		return 1
 	}
 
-	graphStr, err := terraform.GraphDot(g, &dag.DotOpts{
+	dotOpts := &dag.DotOpts{
 		DrawCycles: drawCycles,
 		MaxDepth:   moduleDepth,
 		Verbose:    verbose,
-	})
+	}
+
+	var graphStr string
+	if jsonOutput {
+		graphStr, err = terraform.GraphJSON(g, dotOpts)
+	} else {
+		graphStr, err = terraform.GraphDot(g, dotOpts)
+	}
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Error converting graph: %s", err))
 		return 1
@@ -152,7 +161,7 @@ func (c *GraphCommand) Run(args []string) int {
 	if diags.HasErrors() {
 		// For this command we only show diagnostics if there are errors,
 		// because printing out naked warnings could upset a naive program
-		// consuming our dot output.
+		// consuming our dot or JSON output.
 		c.showDiagnostics(diags)
 		return 1
 	}
@@ -171,7 +180,9 @@ Usage: terraform graph [options] [DIR]
 
   The graph is outputted in DOT format. The typical program that can
   read this format is GraphViz, but many web services are also available
-  to read this format.
+  to read this format. With -json, the graph is outputted as JSON instead,
+  listing each node with its address, module and provider, and each edge
+  with its type.
 
   The -type flag can be used to control the type of graph shown. Terraform
   creates different graphs for different operations. See the options below
@@ -181,13 +192,20 @@ Usage: terraform graph [options] [DIR]
 
 Options:
 
-  -draw-cycles   Highlight any cycles in the graph with colored edges.
-                 This helps when diagnosing cycle errors.
+  -draw-cycles       Highlight any cycles in the graph with colored edges.
+                     This helps when diagnosing cycle errors.
+
+  -json              If specified, output the graph as JSON instead of DOT.
+
+  -module-depth=n    Specifies the depth of modules to show in the output.
+                     Resources in deeper modules are shown as a single node
+                     for their module. By default, this is -1, which will
+                     expand all.
 
-  -no-color      If specified, output won't contain any color.
+  -no-color          If specified, output won't contain any color.
 
-  -type=plan     Type of graph to output. Can be: plan, plan-destroy, apply,
-                 validate, input, refresh.
+  -type=plan         Type of graph to output. Can be: plan, plan-destroy, apply,
+                     validate, input, refresh.
 
 
 `
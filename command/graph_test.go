@@ -1,6 +1,7 @@
 package command
 
 import (
+	"encoding/json"
 	"os"
 	"strings"
 	"testing"
@@ -34,6 +35,36 @@ func TestGraph(t *testing.T) {
 	}
 }
 
+func TestGraph_json(t *testing.T) {
+	tmp, cwd := testCwd(t)
+	defer testFixCwd(t, tmp, cwd)
+
+	ui := new(cli.MockUi)
+	c := &GraphCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-json",
+		testFixturePath("graph"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: \n%s", ui.ErrorWriter.String())
+	}
+
+	var doc terraform.GraphJSONDoc
+	output := ui.OutputWriter.String()
+	if err := json.Unmarshal([]byte(output), &doc); err != nil {
+		t.Fatalf("doesn't look like JSON: %s\n\n%s", err, output)
+	}
+	if len(doc.Nodes) == 0 {
+		t.Fatalf("no nodes: %s", output)
+	}
+}
+
 func TestGraph_multipleArgs(t *testing.T) {
 	ui := new(cli.MockUi)
 	c := &GraphCommand{
@@ -0,0 +1,202 @@
+package command
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform/command/format"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/diffs"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/mitchellh/cli"
+)
+
+// JSONHook is a hook that reports the progress of an apply as a stream of
+// JSON events, one per line, for tools that show it in place of the UI.
+type JSONHook struct {
+	terraform.NilHook
+
+	Ui cli.Ui
+
+	l         sync.Mutex
+	resources map[string]jsonResourceState
+}
+
+// jsonResourceState tracks a resource that is being applied.
+type jsonResourceState struct {
+	Action diffs.Action
+	Start  time.Time
+}
+
+// The types of the events of the JSON progress stream.
+const (
+	jsonEventApplyStart        = "apply_start"
+	jsonEventApplyComplete     = "apply_complete"
+	jsonEventApplyErrored      = "apply_errored"
+	jsonEventProvisionStart    = "provision_start"
+	jsonEventProvisionComplete = "provision_complete"
+	jsonEventProvisionErrored  = "provision_errored"
+	jsonEventDiagnostic        = "diagnostic"
+)
+
+// jsonEvent is an event of the JSON progress stream.
+type jsonEvent struct {
+	Type      string       `json:"type"`
+	Timestamp string       `json:"timestamp"`
+	Address   string       `json:"address,omitempty"`
+	Action    diffs.Action `json:"action,omitempty"`
+
+	// Provisioner is set for the provisioning events.
+	Provisioner string `json:"provisioner,omitempty"`
+
+	// ElapsedSeconds is the time since the change started, set for the
+	// events that end it.
+	ElapsedSeconds *float64 `json:"elapsed_seconds,omitempty"`
+
+	Error string `json:"error,omitempty"`
+
+	// Diagnostic is set for the diagnostic events, which have no address
+	// or action.
+	Diagnostic *format.JSONDiagnostic `json:"diagnostic,omitempty"`
+}
+
+func (h *JSONHook) PreApply(
+	n *terraform.InstanceInfo,
+	s *terraform.InstanceState,
+	d *terraform.InstanceDiff) (terraform.HookAction, error) {
+	// if there's no diff, there's nothing to output
+	if d.Empty() {
+		return terraform.HookActionContinue, nil
+	}
+
+	addr := n.ResourceAddress()
+
+	var action diffs.Action
+	switch d.ChangeType() {
+	case terraform.DiffCreate:
+		action = diffs.Create
+	case terraform.DiffDestroy:
+		action = diffs.Delete
+	case terraform.DiffDestroyCreate:
+		action = diffs.Replace
+	default:
+		action = diffs.Update
+	}
+	if addr.Mode == config.DataResourceMode {
+		action = diffs.Read
+	}
+
+	h.l.Lock()
+	if h.resources == nil {
+		h.resources = make(map[string]jsonResourceState)
+	}
+	h.resources[n.HumanId()] = jsonResourceState{
+		Action: action,
+		Start:  time.Now(),
+	}
+	h.l.Unlock()
+
+	h.emit(&jsonEvent{
+		Type:    jsonEventApplyStart,
+		Address: addr.String(),
+		Action:  action,
+	})
+
+	return terraform.HookActionContinue, nil
+}
+
+func (h *JSONHook) PostApply(
+	n *terraform.InstanceInfo,
+	s *terraform.InstanceState,
+	applyerr error) (terraform.HookAction, error) {
+	id := n.HumanId()
+
+	h.l.Lock()
+	state, ok := h.resources[id]
+	delete(h.resources, id)
+	h.l.Unlock()
+
+	// Only the changes that started are reported
+	if !ok {
+		return terraform.HookActionContinue, nil
+	}
+
+	elapsed := time.Since(state.Start).Seconds()
+	e := &jsonEvent{
+		Type:           jsonEventApplyComplete,
+		Address:        n.ResourceAddress().String(),
+		Action:         state.Action,
+		ElapsedSeconds: &elapsed,
+	}
+	if applyerr != nil {
+		e.Type = jsonEventApplyErrored
+		e.Error = applyerr.Error()
+	}
+	h.emit(e)
+
+	return terraform.HookActionContinue, nil
+}
+
+func (h *JSONHook) PreProvision(
+	n *terraform.InstanceInfo,
+	provId string) (terraform.HookAction, error) {
+	h.emit(&jsonEvent{
+		Type:        jsonEventProvisionStart,
+		Address:     n.ResourceAddress().String(),
+		Action:      h.action(n),
+		Provisioner: provId,
+	})
+	return terraform.HookActionContinue, nil
+}
+
+func (h *JSONHook) PostProvision(
+	n *terraform.InstanceInfo,
+	provId string,
+	err error) (terraform.HookAction, error) {
+	e := &jsonEvent{
+		Type:        jsonEventProvisionComplete,
+		Address:     n.ResourceAddress().String(),
+		Action:      h.action(n),
+		Provisioner: provId,
+	}
+	if err != nil {
+		e.Type = jsonEventProvisionErrored
+		e.Error = err.Error()
+	}
+	h.emit(e)
+	return terraform.HookActionContinue, nil
+}
+
+// Diagnostics reports each of the given diagnostics as an event.
+func (h *JSONHook) Diagnostics(diags tfdiags.Diagnostics) {
+	for _, diag := range diags {
+		h.emit(&jsonEvent{
+			Type:       jsonEventDiagnostic,
+			Diagnostic: format.NewJSONDiagnostic(diag),
+		})
+	}
+}
+
+// action returns the action of the change to the given resource that is
+// being applied.
+func (h *JSONHook) action(n *terraform.InstanceInfo) diffs.Action {
+	h.l.Lock()
+	defer h.l.Unlock()
+	return h.resources[n.HumanId()].Action
+}
+
+func (h *JSONHook) emit(e *jsonEvent) {
+	e.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+
+	js, err := json.Marshal(e)
+	if err != nil {
+		// should never happen; the actions are known and diagnostics are
+		// only strings
+		log.Printf("[ERROR] command: failed to encode apply event: %s", err)
+		return
+	}
+	h.Ui.Output(string(js))
+}
@@ -0,0 +1,117 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/mitchellh/cli"
+)
+
+func TestJSONHook(t *testing.T) {
+	ui := cli.NewMockUi()
+	h := &JSONHook{Ui: ui}
+
+	n := &terraform.InstanceInfo{
+		Id:         "aws_instance.foo",
+		ModulePath: []string{"root", "child"},
+		Type:       "aws_instance",
+	}
+	d := &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"ami": &terraform.ResourceAttrDiff{New: "bar"},
+		},
+	}
+
+	if _, err := h.PreApply(n, &terraform.InstanceState{}, d); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.PreProvision(n, "local-exec"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.PostProvision(n, "local-exec", errors.New("exit 1")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.PostApply(n, nil, errors.New("provisioning failed")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A destroy that completes.
+	d = &terraform.InstanceDiff{Destroy: true}
+	if _, err := h.PreApply(n, &terraform.InstanceState{ID: "foo"}, d); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.PostApply(n, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(ui.OutputWriter.String()), "\n")
+	expected := []struct {
+		Type, Action, Provisioner, Error string
+		Elapsed                          bool
+	}{
+		{"apply_start", "update", "", "", false},
+		{"provision_start", "update", "local-exec", "", false},
+		{"provision_errored", "update", "local-exec", "exit 1", false},
+		{"apply_errored", "update", "", "provisioning failed", true},
+		{"apply_start", "delete", "", "", false},
+		{"apply_complete", "delete", "", "", true},
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("bad: %s", ui.OutputWriter.String())
+	}
+
+	for i, line := range lines {
+		var e map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("bad event %q: %s", line, err)
+		}
+
+		ex := expected[i]
+		if e["type"] != ex.Type || e["action"] != ex.Action {
+			t.Fatalf("bad event %d: %s", i, line)
+		}
+		if e["address"] != "module.child.aws_instance.foo" {
+			t.Fatalf("bad address in event %d: %s", i, line)
+		}
+		if p, _ := e["provisioner"].(string); p != ex.Provisioner {
+			t.Fatalf("bad provisioner in event %d: %s", i, line)
+		}
+		if msg, _ := e["error"].(string); msg != ex.Error {
+			t.Fatalf("bad error in event %d: %s", i, line)
+		}
+		if _, ok := e["elapsed_seconds"]; ok != ex.Elapsed {
+			t.Fatalf("bad elapsed time in event %d: %s", i, line)
+		}
+		if _, ok := e["timestamp"].(string); !ok {
+			t.Fatalf("no timestamp in event %d: %s", i, line)
+		}
+	}
+}
+
+func TestJSONHookDiagnostics(t *testing.T) {
+	ui := cli.NewMockUi()
+	h := &JSONHook{Ui: ui}
+
+	var diags tfdiags.Diagnostics
+	diags = diags.Append(tfdiags.SimpleWarning("be careful"))
+	h.Diagnostics(diags.WithCode(tfdiags.CodePlanTargeted))
+
+	var e map[string]interface{}
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &e); err != nil {
+		t.Fatalf("bad event %q: %s", ui.OutputWriter.String(), err)
+	}
+	if e["type"] != "diagnostic" {
+		t.Fatalf("bad event: %s", ui.OutputWriter.String())
+	}
+	if _, ok := e["address"]; ok {
+		t.Fatalf("unexpected address in event: %s", ui.OutputWriter.String())
+	}
+	diag, _ := e["diagnostic"].(map[string]interface{})
+	if diag["severity"] != "warning" || diag["code"] != "plan.targeted" || diag["summary"] != "be careful" {
+		t.Fatalf("bad diagnostic in event: %s", ui.OutputWriter.String())
+	}
+}
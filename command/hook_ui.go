@@ -10,6 +10,7 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/hashicorp/terraform/command/format"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
 	"github.com/mitchellh/colorstring"
@@ -25,6 +26,9 @@ type UiHook struct {
 	Ui              cli.Ui
 	PeriodicUiTimer time.Duration
 
+	// Redaction selects how sensitive attribute values are shown.
+	Redaction format.Redaction
+
 	l         sync.Mutex
 	once      sync.Once
 	resources map[string]uiResourceState
@@ -118,8 +122,11 @@ func (h *UiHook) PreApply(
 		}
 
 		if attrDiff.Sensitive {
-			u = "<sensitive>"
-			v = "<sensitive>"
+			u = h.Redaction.Value(attrDiff.Old)
+			v = h.Redaction.Value(attrDiff.New)
+			if attrDiff.NewComputed {
+				v = "<computed>"
+			}
 		}
 
 		attrBuf.WriteString(fmt.Sprintf(
@@ -6,7 +6,9 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"sync"
 
+	"github.com/mitchellh/cli"
 	"github.com/posener/complete"
 
 	multierror "github.com/hashicorp/go-multierror"
@@ -27,6 +29,9 @@ type InitCommand struct {
 	// getPlugins is for the -get-plugins flag
 	getPlugins bool
 
+	// installParallelism is for the -install-parallelism flag
+	installParallelism int
+
 	// providerInstaller is used to download and install providers that
 	// aren't found locally. This uses a discovery.ProviderInstaller instance
 	// by default, but it can be overridden here as a way to mock fetching
@@ -51,6 +56,7 @@ func (c *InitCommand) Run(args []string) int {
 	cmdFlags.StringVar(&flagFromModule, "from-module", "", "copy the source of the given module into the directory before init")
 	cmdFlags.BoolVar(&flagGet, "get", true, "")
 	cmdFlags.BoolVar(&c.getPlugins, "get-plugins", true, "")
+	cmdFlags.IntVar(&c.installParallelism, "install-parallelism", DefaultInstallParallelism, "install parallelism")
 	cmdFlags.BoolVar(&c.forceInitCopy, "force-copy", false, "suppress prompts about copying state data")
 	cmdFlags.BoolVar(&c.Meta.stateLock, "lock", true, "lock state")
 	cmdFlags.DurationVar(&c.Meta.stateLockTimeout, "lock-timeout", 0, "lock timeout")
@@ -69,6 +75,16 @@ func (c *InitCommand) Run(args []string) int {
 		c.getPlugins = false
 	}
 
+	if c.installParallelism < 1 {
+		c.Ui.Error("The -install-parallelism option must be at least 1.\n")
+		cmdFlags.Usage()
+		return 1
+	}
+
+	// Providers are installed concurrently, and each reports its progress
+	// as it goes.
+	c.Ui = &cli.ConcurrentUi{Ui: c.Ui}
+
 	// set providerInstaller if we don't have a test version already
 	if c.providerInstaller == nil {
 		c.providerInstaller = &discovery.ProviderInstaller{
@@ -76,6 +92,8 @@ func (c *InitCommand) Run(args []string) int {
 			Cache: c.pluginCache(),
 			PluginProtocolVersion: plugin.Handshake.ProtocolVersion,
 			SkipVerify:            !flagVerifyPlugins,
+			OCIMirrors:            c.ProviderOCIMirrors,
+			SigstorePolicies:      c.ProviderSigstorePolicies,
 			Ui:                    c.Ui,
 		}
 	}
@@ -130,6 +148,7 @@ func (c *InitCommand) Run(args []string) int {
 		header = true
 
 		s := module.NewStorage("", c.Services, c.Credentials)
+		s.Resolvers = c.moduleSourceResolvers()
 		if err := s.GetModule(path, src); err != nil {
 			c.Ui.Error(fmt.Sprintf("Error copying source module: %s", err))
 			return 1
@@ -175,7 +194,7 @@ func (c *InitCommand) Run(args []string) int {
 					"[reset][bold]Initializing modules...")))
 			}
 
-			if err := getModules(&c.Meta, path, getMode); err != nil {
+			if err := getModules(&c.Meta, path, getMode, flagUpgrade); err != nil {
 				c.Ui.Error(fmt.Sprintf(
 					"Error downloading modules: %s", err))
 				return 1
@@ -315,44 +334,73 @@ func (c *InitCommand) getProviders(path string, state *terraform.State, upgrade
 				discovery.GetReleaseHost()))
 		}
 
-		for provider, reqd := range missing {
+		var names []string
+		for provider := range missing {
 			if _, isInternal := internal[provider]; isInternal {
 				// Ignore internal providers; they are not eligible for
 				// installation.
 				continue
 			}
+			names = append(names, provider)
+		}
+		sort.Strings(names)
 
-			_, err := c.providerInstaller.Get(provider, reqd.Versions)
-
+		// The providers are downloaded and verified concurrently, and the
+		// errors are reported once they're all done, in order.
+		installErrs := make([]error, len(names))
+		var installed int
+		var installedLock sync.Mutex
+		parallel(len(names), c.installParallelism, func(i int) {
+			provider := names[i]
+			meta, err := c.providerInstaller.Get(provider, missing[provider].Versions)
 			if err != nil {
-				switch err {
-				case discovery.ErrorNoSuchProvider:
+				installErrs[i] = err
+				return
+			}
+
+			installedLock.Lock()
+			installed++
+			n := installed
+			installedLock.Unlock()
+			if len(names) > 1 {
+				c.Ui.Output(fmt.Sprintf("- Installed plugin for provider %q (%s) [%d/%d]", provider, meta.Version, n, len(names)))
+			}
+		})
+
+		for i, provider := range names {
+			err := installErrs[i]
+			if err == nil {
+				continue
+			}
+
+			reqd := missing[provider]
+			switch err {
+			case discovery.ErrorNoSuchProvider:
+				c.Ui.Error(fmt.Sprintf(errProviderNotFound, provider, DefaultPluginVendorDir))
+			case discovery.ErrorNoSuitableVersion:
+				if reqd.Versions.Unconstrained() {
+					// This should never happen, but might crop up if we catch
+					// the releases server in a weird state where the provider's
+					// directory is present but does not yet contain any
+					// versions. We'll treat it like ErrorNoSuchProvider, then.
 					c.Ui.Error(fmt.Sprintf(errProviderNotFound, provider, DefaultPluginVendorDir))
-				case discovery.ErrorNoSuitableVersion:
-					if reqd.Versions.Unconstrained() {
-						// This should never happen, but might crop up if we catch
-						// the releases server in a weird state where the provider's
-						// directory is present but does not yet contain any
-						// versions. We'll treat it like ErrorNoSuchProvider, then.
-						c.Ui.Error(fmt.Sprintf(errProviderNotFound, provider, DefaultPluginVendorDir))
-					} else {
-						c.Ui.Error(fmt.Sprintf(errProviderVersionsUnsuitable, provider, reqd.Versions))
-					}
-				case discovery.ErrorNoVersionCompatible:
-					// FIXME: This error message is sub-awesome because we don't
-					// have enough information here to tell the user which versions
-					// we considered and which versions might be compatible.
-					constraint := reqd.Versions.String()
-					if constraint == "" {
-						constraint = "(any version)"
-					}
-					c.Ui.Error(fmt.Sprintf(errProviderIncompatible, provider, constraint))
-				default:
-					c.Ui.Error(fmt.Sprintf(errProviderInstallError, provider, err.Error(), DefaultPluginVendorDir))
+				} else {
+					c.Ui.Error(fmt.Sprintf(errProviderVersionsUnsuitable, provider, reqd.Versions))
 				}
-
-				errs = multierror.Append(errs, err)
+			case discovery.ErrorNoVersionCompatible:
+				// FIXME: This error message is sub-awesome because we don't
+				// have enough information here to tell the user which versions
+				// we considered and which versions might be compatible.
+				constraint := reqd.Versions.String()
+				if constraint == "" {
+					constraint = "(any version)"
+				}
+				c.Ui.Error(fmt.Sprintf(errProviderIncompatible, provider, constraint))
+			default:
+				c.Ui.Error(fmt.Sprintf(errProviderInstallError, provider, err.Error(), DefaultPluginVendorDir))
 			}
+
+			errs = multierror.Append(errs, err)
 		}
 
 		if errs != nil {
@@ -380,14 +428,26 @@ func (c *InitCommand) getProviders(path string, state *terraform.State, upgrade
 	// fail with an error instructing the user to re-run this command.
 	available = c.providerPluginSet() // re-discover to see newly-installed plugins
 	chosen := choosePlugins(available, internal, requirements)
+	var chosenNames []string
+	for name := range chosen {
+		chosenNames = append(chosenNames, name)
+	}
+	sort.Strings(chosenNames)
+
+	// Large plugins take a while to hash, so they're hashed concurrently.
+	digestList := make([][]byte, len(chosenNames))
+	digestErrs := make([]error, len(chosenNames))
+	parallel(len(chosenNames), c.installParallelism, func(i int) {
+		digestList[i], digestErrs[i] = chosen[chosenNames[i]].SHA256()
+	})
+
 	digests := map[string][]byte{}
-	for name, meta := range chosen {
-		digest, err := meta.SHA256()
-		if err != nil {
-			c.Ui.Error(fmt.Sprintf("failed to read provider plugin %s: %s", meta.Path, err))
+	for i, name := range chosenNames {
+		if err := digestErrs[i]; err != nil {
+			c.Ui.Error(fmt.Sprintf("failed to read provider plugin %s: %s", chosen[name].Path, err))
 			return err
 		}
-		digests[name] = digest
+		digests[name] = digestList[i]
 		if c.ignorePluginChecksum {
 			digests[name] = nil
 		}
@@ -452,20 +512,21 @@ func (c *InitCommand) AutocompleteArgs() complete.Predictor {
 
 func (c *InitCommand) AutocompleteFlags() complete.Flags {
 	return complete.Flags{
-		"-backend":        completePredictBoolean,
-		"-backend-config": complete.PredictFiles("*.tfvars"), // can also be key=value, but we can't "predict" that
-		"-force-copy":     complete.PredictNothing,
-		"-from-module":    completePredictModuleSource,
-		"-get":            completePredictBoolean,
-		"-get-plugins":    completePredictBoolean,
-		"-input":          completePredictBoolean,
-		"-lock":           completePredictBoolean,
-		"-lock-timeout":   complete.PredictAnything,
-		"-no-color":       complete.PredictNothing,
-		"-plugin-dir":     complete.PredictDirs(""),
-		"-reconfigure":    complete.PredictNothing,
-		"-upgrade":        completePredictBoolean,
-		"-verify-plugins": completePredictBoolean,
+		"-backend":             completePredictBoolean,
+		"-backend-config":      complete.PredictFiles("*.tfvars"), // can also be key=value, but we can't "predict" that
+		"-force-copy":          complete.PredictNothing,
+		"-from-module":         completePredictModuleSource,
+		"-get":                 completePredictBoolean,
+		"-get-plugins":         completePredictBoolean,
+		"-input":               completePredictBoolean,
+		"-install-parallelism": complete.PredictAnything,
+		"-lock":                completePredictBoolean,
+		"-lock-timeout":        complete.PredictAnything,
+		"-no-color":            complete.PredictNothing,
+		"-plugin-dir":          complete.PredictDirs(""),
+		"-reconfigure":         complete.PredictNothing,
+		"-upgrade":             completePredictBoolean,
+		"-verify-plugins":      completePredictBoolean,
 	}
 }
 
@@ -514,6 +575,10 @@ Options:
   -input=true          Ask for input if necessary. If false, will error if
                        input was required.
 
+  -install-parallelism=4
+                       Limit the number of plugins that are downloaded and
+                       verified at the same time.
+
   -lock=true           Lock the state file when locking is supported.
 
   -lock-timeout=0s     Duration to retry a state lock.
@@ -531,6 +596,7 @@ Options:
   -upgrade=false       If installing modules (-get) or plugins (-get-plugins),
                        ignore previously-downloaded objects and install the
                        latest version allowed within configured constraints.
+                       Modules are locked again in .terraform.lock.hcl.
 
   -verify-plugins=true Verify the authenticity and integrity of automatically
                        downloaded plugins.
@@ -674,3 +740,26 @@ by -plugin-dir on the command line, or in the following directory if custom
 plugin directories are not set:
     %[2]s
 `
+
+// parallel calls f with each index from 0 to n-1, running at most limit
+// calls at a time, and returns once they've all returned.
+func parallel(n, limit int, f func(i int)) {
+	if limit < 1 {
+		limit = 1
+	}
+
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			f(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -9,7 +9,9 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform/helper/copy"
 	"github.com/hashicorp/terraform/plugin/discovery"
@@ -210,6 +212,85 @@ func TestInit_getUpgradeModules(t *testing.T) {
 	}
 }
 
+func TestInit_moduleLock(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	// The module comes from an absolute path, so that it is locked rather
+	// than being treated as part of the configuration.
+	modDir := filepath.Join(td, "mod")
+	if err := os.MkdirAll(modDir, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(modDir, "main.tf"), []byte(`output "a" { value = "a" }`), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cfgDir := filepath.Join(td, "cfg")
+	if err := os.MkdirAll(cfgDir, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cfg := fmt.Sprintf("module \"foo\" {\n  source = %q\n}\n", modDir)
+	if err := ioutil.WriteFile(filepath.Join(cfgDir, "main.tf"), []byte(cfg), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	run := func(args ...string) (int, *cli.MockUi) {
+		ui := new(cli.MockUi)
+		c := &InitCommand{
+			Meta: Meta{
+				testingOverrides: metaOverridesForProvider(testProvider()),
+				Ui:               ui,
+			},
+		}
+		args = append([]string{"-get-plugins=false"}, args...)
+		return c.Run(append(args, cfgDir)), ui
+	}
+	readLock := func() string {
+		buf, err := ioutil.ReadFile(filepath.Join(cfgDir, moduleLockFilename))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		return string(buf)
+	}
+
+	if code, ui := run(); code != 0 {
+		t.Fatalf("command did not complete successfully:\n%s", ui.ErrorWriter.String())
+	}
+	lock := readLock()
+	if !strings.Contains(lock, `module "foo" {`) || !strings.Contains(lock, `hash    = "h1:`) {
+		t.Fatalf("wrong lock file:\n%s", lock)
+	}
+
+	// Changing the module makes init fail, rather than silently using a
+	// different module than when it was locked
+	if err := ioutil.WriteFile(filepath.Join(modDir, "main.tf"), []byte(`output "b" { value = "b" }`), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	code, ui := run()
+	if code != 1 {
+		t.Fatalf("expected failure, got %d", code)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "don't match the lock file") {
+		t.Fatalf("wrong error:\n%s", ui.ErrorWriter.String())
+	}
+	if readLock() != lock {
+		t.Fatalf("lock file should not change:\n%s", readLock())
+	}
+
+	// Upgrading locks the module as it is now
+	if code, ui := run("-upgrade"); code != 0 {
+		t.Fatalf("command did not complete successfully:\n%s", ui.ErrorWriter.String())
+	}
+	if readLock() == lock {
+		t.Fatal("lock file should be updated")
+	}
+	if code, ui := run(); code != 0 {
+		t.Fatalf("command did not complete successfully:\n%s", ui.ErrorWriter.String())
+	}
+}
+
 func TestInit_backend(t *testing.T) {
 	// Create a temporary working directory that is empty
 	td := tempDir(t)
@@ -633,6 +714,75 @@ func TestInit_getProvider(t *testing.T) {
 	}
 }
 
+func TestInit_getProviderParallelism(t *testing.T) {
+	// Create a temporary working directory that is empty
+	td := tempDir(t)
+	copy.CopyDir(testFixturePath("init-get-providers"), td)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	ui := new(cli.MockUi)
+	m := Meta{
+		testingOverrides: metaOverridesForProvider(testProvider()),
+		Ui:               ui,
+	}
+
+	installer := &slowProviderInstaller{
+		mockProviderInstaller: &mockProviderInstaller{
+			Providers: map[string][]string{
+				"exact":        []string{"1.2.3"},
+				"greater_than": []string{"2.3.4"},
+				"between":      []string{"2.3.4"},
+			},
+			Dir: m.pluginDir(),
+		},
+	}
+
+	c := &InitCommand{
+		Meta:              m,
+		providerInstaller: installer,
+	}
+
+	args := []string{"-backend=false", "-install-parallelism=2"}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: \n%s", ui.ErrorWriter.String())
+	}
+
+	if installer.max != 2 {
+		t.Fatalf("installed %d providers at the same time; want 2", installer.max)
+	}
+	output := ui.OutputWriter.String()
+	if !strings.Contains(output, "[3/3]") {
+		t.Fatalf("missing progress in output:\n%s", output)
+	}
+}
+
+// slowProviderInstaller is a mockProviderInstaller that takes a while to
+// install each provider, recording the most that it installs at once.
+type slowProviderInstaller struct {
+	*mockProviderInstaller
+
+	mu      sync.Mutex
+	running int
+	max     int
+}
+
+func (i *slowProviderInstaller) Get(provider string, req discovery.Constraints) (discovery.PluginMeta, error) {
+	i.mu.Lock()
+	i.running++
+	if i.running > i.max {
+		i.max = i.running
+	}
+	i.mu.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.running--
+	return i.mockProviderInstaller.Get(provider, req)
+}
+
 // make sure we can locate providers in various paths
 func TestInit_findVendoredProviders(t *testing.T) {
 	// Create a temporary working directory that is empty
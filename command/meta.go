@@ -16,6 +16,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/hashicorp/terraform/analysis"
 	"github.com/hashicorp/terraform/backend"
 	"github.com/hashicorp/terraform/backend/local"
 	"github.com/hashicorp/terraform/command/format"
@@ -24,10 +25,16 @@ import (
 	"github.com/hashicorp/terraform/helper/experiment"
 	"github.com/hashicorp/terraform/helper/variables"
 	"github.com/hashicorp/terraform/helper/wrappedstreams"
+	"github.com/hashicorp/terraform/hooks"
+	"github.com/hashicorp/terraform/plugin/discovery"
+	"github.com/hashicorp/terraform/plugin/host"
+	"github.com/hashicorp/terraform/policy"
+	"github.com/hashicorp/terraform/svchost"
 	"github.com/hashicorp/terraform/svchost/auth"
 	"github.com/hashicorp/terraform/svchost/disco"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/hashicorp/terraform/tracing"
 	"github.com/mitchellh/cli"
 	"github.com/mitchellh/colorstring"
 )
@@ -67,10 +74,43 @@ type Meta struct {
 	// the specific commands being run.
 	RunningInAutomation bool
 
+	// PolicyHooks check plans before they are applied.
+	PolicyHooks []policy.Hook
+
+	// PlanAnalyzers annotate the changes of plans once they're created.
+	PlanAnalyzers []analysis.Analyzer
+
 	// PluginCacheDir, if non-empty, enables caching of downloaded plugins
 	// into the given directory.
 	PluginCacheDir string
 
+	// ProviderHost, if set, keeps provider plugins running between commands
+	// in a provider host, which stops once no commands have used it for
+	// ProviderHostIdleTimeout.
+	ProviderHost            bool
+	ProviderHostIdleTimeout time.Duration
+
+	// ProviderOCIMirrors, if non-empty, are the OCI registry repositories
+	// that providers are installed from instead of the release host.
+	ProviderOCIMirrors []*discovery.OCIMirror
+
+	// ProviderSigstorePolicies are the provenance policies for providers
+	// released from each host.
+	ProviderSigstorePolicies map[svchost.Hostname]*discovery.SigstorePolicy
+
+	// Secrets reads the values of secret variables from the secrets
+	// backends in the CLI configuration.
+	Secrets terraform.SecretsSource
+
+	// Redaction selects how sensitive values are shown in output.
+	//
+	// AllowUnveil allows "terraform output -unveil" and "-show-sensitive"
+	// to reveal sensitive outputs, recording each time it does in the audit log at
+	// AuditLogPath, or in the data directory if that's empty.
+	Redaction    format.Redaction
+	AllowUnveil  bool
+	AuditLogPath string
+
 	// OverrideDataDir, if non-empty, overrides the return value of the
 	// DataDir method for situations where the local .terraform/ directory
 	// is not suitable, e.g. because of a read-only filesystem.
@@ -79,6 +119,10 @@ type Meta struct {
 	// When this channel is closed, the command will be cancelled.
 	ShutdownCh <-chan struct{}
 
+	// Tracer records the spans of the operations run by the command, if
+	// tracing is enabled in the CLI configuration.
+	Tracer *tracing.Tracer
+
 	//----------------------------------------------------------
 	// Protected: commands can set these
 	//----------------------------------------------------------
@@ -105,6 +149,10 @@ type Meta struct {
 	// backendState is the currently active backend state
 	backendState *terraform.BackendState
 
+	// providerHostClient is the connection to the provider host, once it's
+	// been made.
+	providerHostClient *host.Client
+
 	// Variables for the context (private)
 	autoKey       string
 	autoVariables map[string]interface{}
@@ -114,6 +162,9 @@ type Meta struct {
 	// Targets for this context (private)
 	targets []string
 
+	// Modules that the operation is scoped to, with -target-module (private)
+	targetModules []string
+
 	// Internal fields
 	color bool
 	oldUi cli.Ui
@@ -136,6 +187,8 @@ type Meta struct {
 	// parallelism is used to control the number of concurrent operations
 	// allowed when walking the graph
 	//
+	// refreshParallelism, if set, overrides parallelism for the refresh walk
+	//
 	// shadow is used to enable/disable the shadow graph
 	//
 	// provider is to specify specific resource providers
@@ -149,16 +202,21 @@ type Meta struct {
 	// init.
 	//
 	// reconfigure forces init to ignore any stored configuration.
-	statePath        string
-	stateOutPath     string
-	backupPath       string
-	parallelism      int
-	shadow           bool
-	provider         string
-	stateLock        bool
-	stateLockTimeout time.Duration
-	forceInitCopy    bool
-	reconfigure      bool
+	//
+	// hookCommand is the path of a program to run for each of the lifecycle
+	// events of the resources, as with the hooks package.
+	statePath          string
+	stateOutPath       string
+	backupPath         string
+	parallelism        int
+	refreshParallelism int
+	shadow             bool
+	provider           string
+	stateLock          bool
+	stateLockTimeout   time.Duration
+	forceInitCopy      bool
+	reconfigure        bool
+	hookCommand        string
 
 	// errWriter is the write side of a pipe for the FlagSet output. We need to
 	// keep track of this to close previous pipes between tests. Normal
@@ -270,28 +328,39 @@ const (
 	ProviderSkipVerifyEnvVar = "TF_SKIP_PROVIDER_VERIFY"
 )
 
-// contextOpts returns the options to use to initialize a Terraform
-// context with the settings from this Meta.
-func (m *Meta) contextOpts() *terraform.ContextOpts {
-	var opts terraform.ContextOpts
-	opts.Hooks = []terraform.Hook{m.uiHook(), &terraform.DebugHook{}}
-	opts.Hooks = append(opts.Hooks, m.ExtraHooks...)
-
+// inputVariables returns the variables set by the -var and -var-file
+// flags and by the automatically loaded variable files, where the flags
+// take precedence.
+func (m *Meta) inputVariables() map[string]interface{} {
 	vs := make(map[string]interface{})
-	for k, v := range opts.Variables {
-		vs[k] = v
-	}
 	for k, v := range m.autoVariables {
 		vs[k] = v
 	}
 	for k, v := range m.variables {
 		vs[k] = v
 	}
-	opts.Variables = vs
+	return vs
+}
+
+// contextOpts returns the options to use to initialize a Terraform
+// context with the settings from this Meta.
+func (m *Meta) contextOpts() *terraform.ContextOpts {
+	var opts terraform.ContextOpts
+	opts.Hooks = []terraform.Hook{m.uiHook(), &terraform.DebugHook{}}
+	opts.Hooks = append(opts.Hooks, m.ExtraHooks...)
+	if m.hookCommand != "" {
+		opts.Hooks = append(opts.Hooks, hooks.New(hooks.Command(m.hookCommand)))
+	}
+
+	opts.Variables = m.inputVariables()
 
 	opts.Targets = m.targets
+	opts.TargetModules = m.targetModules
+	opts.Secrets = m.Secrets
+	opts.Tracer = m.Tracer
 	opts.UIInput = m.UIInput()
 	opts.Parallelism = m.parallelism
+	opts.RefreshParallelism = m.refreshParallelism
 	opts.Shadow = m.shadow
 
 	// If testingOverrides are set, we'll skip the plugin discovery process
@@ -378,6 +447,7 @@ func (m *Meta) moduleStorage(root string, mode module.GetMode) *module.Storage {
 	s := module.NewStorage(filepath.Join(root, "modules"), m.Services, m.Credentials)
 	s.Ui = m.Ui
 	s.Mode = mode
+	s.Resolvers = m.moduleSourceResolvers()
 	return s
 }
 
@@ -466,8 +536,9 @@ func (m *Meta) process(args []string, vars bool) ([]string, error) {
 // uiHook returns the UiHook to use with the context.
 func (m *Meta) uiHook() *UiHook {
 	return &UiHook{
-		Colorize: m.Colorize(),
-		Ui:       m.Ui,
+		Colorize:  m.Colorize(),
+		Ui:        m.Ui,
+		Redaction: m.Redaction,
 	}
 }
 
@@ -543,6 +614,23 @@ func (m *Meta) addModuleDepthFlag(flags *flag.FlagSet, moduleDepth *int) {
 	}
 }
 
+// addTargetModuleFlag adds the -target-module flag, for the commands that
+// plan and apply.
+func (m *Meta) addTargetModuleFlag(flags *flag.FlagSet) {
+	flags.Var((*FlagStringSlice)(&m.targetModules), "target-module", "module to scope to")
+}
+
+// checkTargetModules returns an error if one of the -target-module flags
+// isn't the address of a module.
+func (m *Meta) checkTargetModules() error {
+	for _, raw := range m.targetModules {
+		if _, err := terraform.ParseTargetModule(raw); err != nil {
+			return fmt.Errorf("Invalid module address %q for -target-module: %s", raw, err)
+		}
+	}
+	return nil
+}
+
 // outputShadowError outputs the error from ctx.ShadowError. If the
 // error is nil then nothing happens. If output is false then it isn't
 // outputted to the user (you can define logic to guard against outputting).
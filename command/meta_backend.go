@@ -10,6 +10,7 @@ import (
 	"io/ioutil"
 	"log"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/hashicorp/go-multierror"
@@ -18,10 +19,10 @@ import (
 	"github.com/hashicorp/terraform/command/clistate"
 	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/encryption"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/mapstructure"
 
-	backendinit "github.com/hashicorp/terraform/backend/init"
 	backendlocal "github.com/hashicorp/terraform/backend/local"
 )
 
@@ -98,12 +99,18 @@ func (m *Meta) Backend(opts *BackendOpts) (backend.Enhanced, error) {
 	cliOpts := &backend.CLIOpts{
 		CLI:                 m.Ui,
 		CLIColor:            m.Colorize(),
+		Redaction:           m.Redaction,
 		StatePath:           m.statePath,
 		StateOutPath:        m.stateOutPath,
 		StateBackupPath:     m.backupPath,
 		ContextOpts:         m.contextOpts(),
 		Input:               m.Input(),
 		RunningInAutomation: m.RunningInAutomation,
+		PolicyHooks:         m.PolicyHooks,
+		PlanAnalyzers:       m.PlanAnalyzers,
+		ApplyCheckpointPath: filepath.Join(m.DataDir(), DefaultApplyCheckpointFilename),
+		Services:            m.Services,
+		Credentials:         m.Credentials,
 	}
 
 	// Don't validate if we have a plan.  Validation is normally harmless here,
@@ -111,13 +118,20 @@ func (m *Meta) Backend(opts *BackendOpts) (backend.Enhanced, error) {
 	// not have the original files in the current execution context.
 	cliOpts.Validation = opts.Plan == nil
 
-	// If the backend supports CLI initialization, do it.
-	if cli, ok := b.(backend.CLI); ok {
-		if err := cli.CLIInit(cliOpts); err != nil {
-			return nil, fmt.Errorf(
-				"Error initializing backend %T: %s\n\n"+
-					"This is a bug, please report it to the backend developer",
-				b, err)
+	// If the backend supports CLI initialization, do it. That includes a
+	// backend wrapped in the local backend to encrypt its states.
+	initB := []backend.Backend{b}
+	if local, ok := b.(*backendlocal.Local); ok && local.Backend != nil {
+		initB = []backend.Backend{local.Backend, b}
+	}
+	for _, b := range initB {
+		if cli, ok := b.(backend.CLI); ok {
+			if err := cli.CLIInit(cliOpts); err != nil {
+				return nil, fmt.Errorf(
+					"Error initializing backend %T: %s\n\n"+
+						"This is a bug, please report it to the backend developer",
+					b, err)
+			}
 		}
 	}
 
@@ -168,6 +182,7 @@ func (m *Meta) Operation() *backend.Operation {
 	return &backend.Operation{
 		PlanOutBackend:   m.backendState,
 		Targets:          m.targets,
+		TargetModules:    m.targetModules,
 		UIIn:             m.UIInput(),
 		UIOut:            m.Ui,
 		Workspace:        m.Workspace(),
@@ -746,7 +761,10 @@ func (m *Meta) backend_c_R_s(
 	config := terraform.NewResourceConfig(rawC)
 
 	// Get the backend
-	f := backendinit.Backend(s.Remote.Type)
+	f, err := m.backendFactory(s.Remote.Type)
+	if err != nil {
+		return nil, fmt.Errorf("Error starting backend plugin %q: %s", s.Remote.Type, err)
+	}
 	if f == nil {
 		return nil, fmt.Errorf(strings.TrimSpace(errBackendLegacyUnknown), s.Remote.Type)
 	}
@@ -1105,14 +1123,21 @@ func (m *Meta) backend_C_r_S_unchanged(
 	// Create the config. We do this from the backend state since this
 	// has the complete configuration data whereas the config itself
 	// may require input.
-	rawC, err := config.NewRawConfig(s.Backend.Config)
+	configMap, enc, err := m.backendStateEncryption(s.Backend.Config)
+	if err != nil {
+		return nil, err
+	}
+	rawC, err := config.NewRawConfig(configMap)
 	if err != nil {
 		return nil, fmt.Errorf("Error configuring backend: %s", err)
 	}
 	config := terraform.NewResourceConfig(rawC)
 
 	// Get the backend
-	f := backendinit.Backend(s.Backend.Type)
+	f, err := m.backendFactory(s.Backend.Type)
+	if err != nil {
+		return nil, fmt.Errorf("Error starting backend plugin %q: %s", s.Backend.Type, err)
+	}
 	if f == nil {
 		return nil, fmt.Errorf(strings.TrimSpace(errBackendSavedUnknown), s.Backend.Type)
 	}
@@ -1123,7 +1148,7 @@ func (m *Meta) backend_C_r_S_unchanged(
 		return nil, fmt.Errorf(errBackendSavedConfig, s.Backend.Type, err)
 	}
 
-	return b, nil
+	return backendWithStateEncryption(b, enc)
 }
 
 // Initiailizing a changed saved backend with legacy remote state.
@@ -1232,11 +1257,29 @@ func (m *Meta) backend_C_R_S_unchanged(
 //-------------------------------------------------------------------
 
 func (m *Meta) backendInitFromConfig(c *config.Backend) (backend.Backend, error) {
-	// Create the config.
-	config := terraform.NewResourceConfig(c.RawConfig)
+	// Create the config, without any state encryption settings since those
+	// are handled here rather than by the backend.
+	rawC := c.RawConfig
+	var enc *encryption.Encryption
+	if _, ok := rawC.Raw[stateEncryptionKey]; ok {
+		var configMap map[string]interface{}
+		var err error
+		configMap, enc, err = m.backendStateEncryption(rawC.Raw)
+		if err != nil {
+			return nil, err
+		}
+		rawC, err = config.NewRawConfig(configMap)
+		if err != nil {
+			return nil, fmt.Errorf("Error configuring backend: %s", err)
+		}
+	}
+	config := terraform.NewResourceConfig(rawC)
 
 	// Get the backend
-	f := backendinit.Backend(c.Type)
+	f, err := m.backendFactory(c.Type)
+	if err != nil {
+		return nil, fmt.Errorf("Error starting backend plugin %q: %s", c.Type, err)
+	}
 	if f == nil {
 		return nil, fmt.Errorf(strings.TrimSpace(errBackendNewUnknown), c.Type)
 	}
@@ -1274,7 +1317,7 @@ func (m *Meta) backendInitFromConfig(c *config.Backend) (backend.Backend, error)
 		return nil, fmt.Errorf(errBackendNewConfig, c.Type, err)
 	}
 
-	return b, nil
+	return backendWithStateEncryption(b, enc)
 }
 
 func (m *Meta) backendInitFromLegacy(s *terraform.RemoteState) (backend.Backend, error) {
@@ -1293,7 +1336,10 @@ func (m *Meta) backendInitFromLegacy(s *terraform.RemoteState) (backend.Backend,
 	config := terraform.NewResourceConfig(rawC)
 
 	// Get the backend
-	f := backendinit.Backend(s.Type)
+	f, err := m.backendFactory(s.Type)
+	if err != nil {
+		return nil, fmt.Errorf("Error starting backend plugin %q: %s", s.Type, err)
+	}
 	if f == nil {
 		return nil, fmt.Errorf(strings.TrimSpace(errBackendLegacyUnknown), s.Type)
 	}
@@ -1311,14 +1357,21 @@ func (m *Meta) backendInitFromSaved(s *terraform.BackendState) (backend.Backend,
 	// Create the config. We do this from the backend state since this
 	// has the complete configuration data whereas the config itself
 	// may require input.
-	rawC, err := config.NewRawConfig(s.Config)
+	configMap, enc, err := m.backendStateEncryption(s.Config)
+	if err != nil {
+		return nil, err
+	}
+	rawC, err := config.NewRawConfig(configMap)
 	if err != nil {
 		return nil, fmt.Errorf("Error configuring backend: %s", err)
 	}
 	config := terraform.NewResourceConfig(rawC)
 
 	// Get the backend
-	f := backendinit.Backend(s.Type)
+	f, err := m.backendFactory(s.Type)
+	if err != nil {
+		return nil, fmt.Errorf("Error starting backend plugin %q: %s", s.Type, err)
+	}
 	if f == nil {
 		return nil, fmt.Errorf(strings.TrimSpace(errBackendSavedUnknown), s.Type)
 	}
@@ -1329,7 +1382,117 @@ func (m *Meta) backendInitFromSaved(s *terraform.BackendState) (backend.Backend,
 		return nil, fmt.Errorf(errBackendSavedConfig, s.Type, err)
 	}
 
-	return b, nil
+	return backendWithStateEncryption(b, enc)
+}
+
+// stateEncryptionKey is the backend configuration block that configures
+// state encryption.
+const stateEncryptionKey = "state_encryption"
+
+// backendStateEncryption returns the state encryption configured by the
+// state_encryption block in the given backend configuration, if any, and
+// the rest of the configuration for the backend itself.
+func (m *Meta) backendStateEncryption(raw map[string]interface{}) (map[string]interface{}, *encryption.Encryption, error) {
+	v, ok := raw[stateEncryptionKey]
+	if !ok {
+		return raw, nil, nil
+	}
+
+	result := make(map[string]interface{}, len(raw))
+	for k, v := range raw {
+		if k != stateEncryptionKey {
+			result[k] = v
+		}
+	}
+
+	// A block may be decoded as a list of maps, depending on whether it
+	// came from the configuration or from the saved backend state.
+	var block map[string]interface{}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		block = v
+	case []map[string]interface{}:
+		if len(v) == 1 {
+			block = v[0]
+		}
+	case []interface{}:
+		if len(v) == 1 {
+			block, _ = v[0].(map[string]interface{})
+		}
+	}
+	if block == nil {
+		return nil, nil, fmt.Errorf(
+			"Error configuring state encryption: only one %s block is allowed",
+			stateEncryptionKey)
+	}
+
+	var name string
+	var allowUnencrypted bool
+	conf := make(map[string]string)
+	for k, v := range block {
+		if k == "allow_unencrypted" {
+			// It may be given as a boolean or as a string.
+			var err error
+			switch v := v.(type) {
+			case bool:
+				allowUnencrypted = v
+			case string:
+				allowUnencrypted, err = strconv.ParseBool(v)
+			default:
+				err = fmt.Errorf("invalid type %T", v)
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf(
+					"Error configuring state encryption: allow_unencrypted must be a boolean")
+			}
+			continue
+		}
+
+		s, ok := v.(string)
+		if !ok {
+			return nil, nil, fmt.Errorf(
+				"Error configuring state encryption: %q must be a string", k)
+		}
+		if k == "key_provider" {
+			name = s
+			continue
+		}
+		conf[k] = s
+	}
+	if name == "" {
+		return nil, nil, fmt.Errorf(
+			"Error configuring state encryption: key_provider is required")
+	}
+
+	enc, err := encryption.New(name, conf)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error configuring state encryption: %s", err)
+	}
+	enc.SetAllowUnencrypted(allowUnencrypted)
+
+	return result, enc, nil
+}
+
+// backendWithStateEncryption returns the given backend with its states
+// encrypted using enc, if it isn't nil. A backend that doesn't store its
+// states itself is wrapped in the local backend, which encrypts the states
+// of the backend it wraps, so that they are also encrypted when they're
+// migrated.
+func backendWithStateEncryption(b backend.Backend, enc *encryption.Encryption) (backend.Backend, error) {
+	if enc == nil {
+		return b, nil
+	}
+
+	switch b := b.(type) {
+	case *backendlocal.Local:
+		b.StateEncryption = enc
+		return b, nil
+	case backend.Enhanced:
+		return nil, fmt.Errorf(
+			"Backend %T does not support the state_encryption block.", b)
+	default:
+		return &backendlocal.Local{Backend: b, StateEncryption: enc}, nil
+	}
 }
 
 func (m *Meta) backendInitRequired(reason string) {
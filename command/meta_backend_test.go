@@ -14,6 +14,7 @@ import (
 	backendlocal "github.com/hashicorp/terraform/backend/local"
 	"github.com/hashicorp/terraform/helper/copy"
 	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/encryption"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
 )
@@ -351,6 +352,163 @@ func TestMetaBackend_configureNew(t *testing.T) {
 	}
 }
 
+// Newly configured backend with state encryption
+func TestMetaBackend_configureNewStateEncryption(t *testing.T) {
+	// Create a temporary working directory that is empty
+	td := tempDir(t)
+	copy.CopyDir(testFixturePath("backend-new-encrypted"), td)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	defer os.Setenv(encryption.PassphraseEnvVar, os.Getenv(encryption.PassphraseEnvVar))
+	os.Setenv(encryption.PassphraseEnvVar, "correct horse battery staple")
+
+	// Setup the meta
+	m := testMetaBackend(t, nil)
+
+	// Get the backend
+	b, err := m.Backend(&BackendOpts{Init: true})
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	local, ok := b.(*backendlocal.Local)
+	if !ok {
+		t.Fatalf("bad: %T", b)
+	}
+	if local.StateEncryption == nil {
+		t.Fatal("state encryption should be set")
+	}
+
+	// Write some state
+	s, err := b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	state := terraform.NewState()
+	state.Lineage = "changing"
+	s.WriteState(state)
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+
+	// Verify the state is written encrypted
+	data, err := ioutil.ReadFile("local-state.tfstate")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.Contains(string(data), "encrypted_state") {
+		t.Fatalf("state should be encrypted:\n%s", data)
+	}
+	if strings.Contains(string(data), state.Lineage) {
+		t.Fatalf("state should not contain the lineage:\n%s", data)
+	}
+
+	// Verify the state reads back
+	s, err = b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if err := s.RefreshState(); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if actual := s.State(); actual == nil || actual.Lineage != state.Lineage {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+// Newly configured backend with state encryption refuses unencrypted state
+func TestMetaBackend_configureNewStateEncryptionUnencrypted(t *testing.T) {
+	// Create a temporary working directory that is empty
+	td := tempDir(t)
+	copy.CopyDir(testFixturePath("backend-new-encrypted"), td)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	defer os.Setenv(encryption.PassphraseEnvVar, os.Getenv(encryption.PassphraseEnvVar))
+	os.Setenv(encryption.PassphraseEnvVar, "correct horse battery staple")
+
+	// Write an unencrypted state where the backend reads its state
+	state := terraform.NewState()
+	state.Lineage = "unencrypted"
+	f, err := os.Create("local-state.tfstate")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	err = terraform.WriteState(state, f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Setup the meta
+	m := testMetaBackend(t, nil)
+
+	// Get the backend
+	b, err := m.Backend(&BackendOpts{Init: true})
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+
+	s, err := b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	err = s.RefreshState()
+	if err == nil || !strings.Contains(err.Error(), "not encrypted") {
+		t.Fatalf("expected an unencrypted state error, got: %v", err)
+	}
+}
+
+// Newly configured backend with state encryption and existing local state
+func TestMetaBackend_configureNewStateEncryptionWithState(t *testing.T) {
+	// Create a temporary working directory that is empty
+	td := tempDir(t)
+	copy.CopyDir(testFixturePath("backend-new-encrypted"), td)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	defer os.Setenv(encryption.PassphraseEnvVar, os.Getenv(encryption.PassphraseEnvVar))
+	os.Setenv(encryption.PassphraseEnvVar, "correct horse battery staple")
+
+	// Write the local state to migrate
+	state := terraform.NewState()
+	state.Lineage = "backend-new-encrypted"
+	testStateFileDefault(t, state)
+
+	// Ask input
+	defer testInteractiveInput(t, []string{"yes"})()
+
+	// Setup the meta
+	m := testMetaBackend(t, nil)
+
+	// Get the backend
+	b, err := m.Backend(&BackendOpts{Init: true})
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+
+	// Verify the migrated state is encrypted
+	data, err := ioutil.ReadFile("local-state.tfstate")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.Contains(string(data), "encrypted_state") {
+		t.Fatalf("state should be encrypted:\n%s", data)
+	}
+
+	// Check the state
+	s, err := b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if err := s.RefreshState(); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if actual := s.State(); actual == nil || actual.Lineage != state.Lineage {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
 // Newly configured backend with prior local state and no remote state
 func TestMetaBackend_configureNewWithState(t *testing.T) {
 	// Create a temporary working directory that is empty
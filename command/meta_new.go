@@ -10,6 +10,7 @@ import (
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/planfile"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/hashicorp/terraform/tfdiags"
 )
@@ -50,17 +51,17 @@ func (m *Meta) Module(path string) (*module.Tree, tfdiags.Diagnostics) {
 			return nil, nil
 		}
 
-		diags = diags.Append(err)
+		diags = diags.Append(err).WithCode(tfdiags.CodeConfigLoad)
 		return nil, diags
 	}
 
 	err = mod.Load(m.moduleStorage(m.DataDir(), module.GetModeNone))
 	if err != nil {
-		diags = diags.Append(errwrap.Wrapf("Error loading modules: {{err}}", err))
+		diags = diags.Append(errwrap.Wrapf("Error loading modules: {{err}}", err)).WithCode(tfdiags.CodeConfigModules)
 		return nil, diags
 	}
 
-	diags = diags.Append(mod.Validate())
+	diags = diags.Append(mod.Validate().WithCode(tfdiags.CodeConfigInvalid))
 
 	return mod, diags
 }
@@ -155,7 +156,7 @@ func (m *Meta) Plan(path string) (*terraform.Plan, error) {
 	}
 
 	// Read the plan
-	p, err := terraform.ReadPlan(f)
+	p, err := planfile.ReadPlan(path)
 	if err != nil {
 		return nil, err
 	}
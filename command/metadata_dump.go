@@ -0,0 +1,114 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform/command/format"
+	"github.com/hashicorp/terraform/config/introspect"
+	"github.com/hashicorp/terraform/tfdiags"
+)
+
+// MetadataDumpCommand is a Command implementation that writes the fully
+// decoded configuration as JSON.
+type MetadataDumpCommand struct {
+	Meta
+}
+
+// MetadataJSONFormatVersion is the version of the document written by
+// "terraform metadata dump". It will be incremented if the document changes
+// in a way that is not backward-compatible.
+const MetadataJSONFormatVersion = "0.1"
+
+type metadataJSON struct {
+	FormatVersion string                   `json:"format_version"`
+	RootModule    *introspect.Module       `json:"root_module"`
+	Diagnostics   []*format.JSONDiagnostic `json:"diagnostics"`
+}
+
+func (c *MetadataDumpCommand) Run(args []string) int {
+	args, err := c.Meta.process(args, false)
+	if err != nil {
+		return 1
+	}
+
+	cmdFlags := c.Meta.flagSet("metadata dump")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = cmdFlags.Args()
+	if len(args) > 1 {
+		c.Ui.Error("The metadata dump command expects at most one argument.")
+		cmdFlags.Usage()
+		return 1
+	}
+
+	dirPath := "."
+	if len(args) == 1 {
+		dirPath = args[0]
+	}
+	dir, err := filepath.Abs(dirPath)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Unable to locate directory %s: %s", dirPath, err))
+		return 1
+	}
+
+	// The configuration is described even if it isn't valid, since tools
+	// such as editors are mostly used on configuration that is being
+	// written. It can't be if it couldn't be loaded at all.
+	var diags tfdiags.Diagnostics
+	mod, modDiags := c.Module(dir)
+	diags = diags.Append(modDiags)
+	if mod == nil && !diags.HasErrors() {
+		diags = diags.Append(fmt.Errorf(
+			"No configuration files found in %s", dir)).WithCode(tfdiags.CodeConfigLoad)
+	}
+
+	doc := metadataJSON{
+		FormatVersion: MetadataJSONFormatVersion,
+		Diagnostics:   format.NewJSONDiagnostics(diags),
+	}
+	if mod != nil {
+		doc.RootModule = introspect.Describe(mod)
+	}
+
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error rendering configuration metadata as JSON: %s", err))
+		return 1
+	}
+	c.Ui.Output(string(buf))
+
+	if diags.HasErrors() {
+		return 1
+	}
+	return 0
+}
+
+func (c *MetadataDumpCommand) Help() string {
+	helpText := `
+Usage: terraform metadata dump [dir]
+
+  Writes a JSON description of the configuration in the given directory, or
+  the current directory if none is given, to stdout.
+
+  The description includes the variables, locals, outputs, providers,
+  resources and module calls of the root module and each of its child
+  modules, where each is declared, the expressions of their arguments and
+  the references between them.
+
+  The child modules must already have been installed with "terraform init"
+  or "terraform get". The configuration is described even if it isn't
+  valid, with the problems found given as diagnostics, and the exit status
+  is then 1.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *MetadataDumpCommand) Synopsis() string {
+	return "Write the decoded configuration as JSON"
+}
@@ -0,0 +1,65 @@
+package command
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/mitchellh/cli"
+)
+
+func TestMetadataDump(t *testing.T) {
+	ui := new(cli.MockUi)
+	c := &MetadataDumpCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+
+	if code := c.Run([]string{testFixturePath("validate-valid")}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var doc metadataJSON
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %s\n\n%s", err, ui.OutputWriter.String())
+	}
+	if doc.FormatVersion != MetadataJSONFormatVersion || len(doc.Diagnostics) != 0 {
+		t.Fatalf("wrong result: %s", ui.OutputWriter.String())
+	}
+
+	root := doc.RootModule
+	if root == nil || len(root.Resources) != 1 || len(root.Variables) != 1 {
+		t.Fatalf("wrong root module: %s", ui.OutputWriter.String())
+	}
+	r := root.Resources[0]
+	if r.Address != "test_instance.foo" || r.Range == nil || r.Range.Start.Line != 6 {
+		t.Fatalf("wrong resource: %s", ui.OutputWriter.String())
+	}
+}
+
+func TestMetadataDump_invalid(t *testing.T) {
+	ui := new(cli.MockUi)
+	c := &MetadataDumpCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+
+	if code := c.Run([]string{testFixturePath("validate-invalid/missing_var")}); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var doc metadataJSON
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %s\n\n%s", err, ui.OutputWriter.String())
+	}
+	if doc.RootModule == nil {
+		t.Fatalf("invalid configuration wasn't described: %s", ui.OutputWriter.String())
+	}
+	if len(doc.Diagnostics) != 1 || doc.Diagnostics[0].Code != tfdiags.CodeConfigInvalid {
+		t.Fatalf("wrong diagnostics: %s", ui.OutputWriter.String())
+	}
+}
@@ -0,0 +1,139 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/terraform/config/module"
+)
+
+// moduleLockFilename is the name of the file in the root module directory
+// that records the versions and hashes of the modules installed for the
+// configuration. Unlike the plugin lock, it's meant to be kept in version
+// control, so that everyone working on the configuration installs the same
+// modules.
+const moduleLockFilename = ".terraform.lock.hcl"
+
+const moduleLockHeader = `# This file is maintained automatically by "terraform init".
+# Manual edits may be lost in future updates.
+`
+
+func (m *Meta) moduleLock(path string) *moduleLockFile {
+	return &moduleLockFile{
+		Filename: filepath.Join(path, moduleLockFilename),
+	}
+}
+
+type moduleLockFile struct {
+	Filename string
+}
+
+// moduleLockBlock is the structure of a "module" block in the lock file.
+type moduleLockBlock struct {
+	Source  string `hcl:"source"`
+	Version string `hcl:"version"`
+	Hash    string `hcl:"hash"`
+}
+
+// Read loads the module locks from the file. If the file doesn't exist
+// then there are no locks, since no modules have been installed yet.
+func (f *moduleLockFile) Read() (map[string]*module.ModuleLock, error) {
+	locks := make(map[string]*module.ModuleLock)
+
+	buf, err := ioutil.ReadFile(f.Filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return locks, nil
+		}
+		return nil, err
+	}
+
+	var raw struct {
+		Modules map[string]*moduleLockBlock `hcl:"module"`
+	}
+	if err := hcl.Decode(&raw, string(buf)); err != nil {
+		return nil, fmt.Errorf("Error parsing %s: %s", f.Filename, err)
+	}
+
+	for name, b := range raw.Modules {
+		locks[name] = &module.ModuleLock{
+			Source:  b.Source,
+			Version: b.Version,
+			Hash:    b.Hash,
+		}
+	}
+	return locks, nil
+}
+
+// Write persists the given locks to the file, sorted by module path. This
+// entirely replaces any previous locks, so the given map must be
+// comprehensive.
+func (f *moduleLockFile) Write(locks map[string]*module.ModuleLock) error {
+	names := make([]string, 0, len(locks))
+	for name := range locks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.WriteString(moduleLockHeader)
+	for _, name := range names {
+		l := locks[name]
+		fmt.Fprintf(&buf, "\nmodule %q {\n", name)
+		fmt.Fprintf(&buf, "  source  = %q\n", l.Source)
+		if l.Version != "" {
+			fmt.Fprintf(&buf, "  version = %q\n", l.Version)
+		}
+		fmt.Fprintf(&buf, "  hash    = %q\n", l.Hash)
+		buf.WriteString("}\n")
+	}
+
+	return ioutil.WriteFile(f.Filename, buf.Bytes(), 0644)
+}
+
+// checkModuleLocks returns an error if a module that the given locks apply
+// to was installed with different contents than when it was locked, such
+// as when a branch of a Git repository has moved since.
+func checkModuleLocks(locked, current map[string]*module.ModuleLock) error {
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		c := current[name]
+		l, ok := locked[name]
+		if !ok || l.Source != c.Source {
+			continue
+		}
+		if l.Hash != c.Hash {
+			return fmt.Errorf(errModuleLockMismatch, name, c.Source, l.Hash, c.Hash)
+		}
+	}
+	return nil
+}
+
+// sameModuleLocks returns true if the given locks are the same, so that the
+// lock file is only written when it changes.
+func sameModuleLocks(a, b map[string]*module.ModuleLock) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+const errModuleLockMismatch = `module %s: the contents of %q don't match the lock file
+
+The module was locked with the hash %s, but the installed module has the
+hash %s. The module has changed since it was locked, so a different module
+would be used than everyone else working on this configuration uses.
+
+If the change is expected, run "terraform init -upgrade" to lock the module
+as it is now.`
@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+
+	"github.com/hashicorp/terraform/command/format"
+	"github.com/hashicorp/terraform/terraform"
 )
 
 // OutputCommand is a Command implementation that reads an output
@@ -21,10 +24,14 @@ func (c *OutputCommand) Run(args []string) int {
 		return 1
 	}
 
-	var module string
-	var jsonOutput bool
+	var module, strictPath string
+	var jsonOutput, schemaOutput, unveil, showSensitive bool
 	cmdFlags := flag.NewFlagSet("output", flag.ContinueOnError)
 	cmdFlags.BoolVar(&jsonOutput, "json", false, "json")
+	cmdFlags.BoolVar(&unveil, "unveil", false, "unveil")
+	cmdFlags.BoolVar(&showSensitive, "show-sensitive", false, "show-sensitive")
+	cmdFlags.BoolVar(&schemaOutput, "schema", false, "schema")
+	cmdFlags.StringVar(&strictPath, "strict", "", "path")
 	cmdFlags.StringVar(&c.Meta.statePath, "state", DefaultStateFilename, "path")
 	cmdFlags.StringVar(&module, "module", "", "module")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
@@ -46,6 +53,18 @@ func (c *OutputCommand) Run(args []string) int {
 		name = args[0]
 	}
 
+	if unveil {
+		if name == "" {
+			c.Ui.Error("The -unveil option requires the name of an output.\n")
+			cmdFlags.Usage()
+			return 1
+		}
+	}
+	if (unveil || showSensitive) && !c.AllowUnveil {
+		c.Ui.Error(strings.TrimSpace(outputUnveilNotAllowed))
+		return 1
+	}
+
 	// Load the backend
 	b, err := c.Backend(nil)
 	if err != nil {
@@ -85,6 +104,42 @@ func (c *OutputCommand) Run(args []string) int {
 		return 1
 	}
 
+	// In strict mode the outputs must conform to the given schema before
+	// anything is output, so that consumers never see values that break
+	// the contract.
+	if strictPath != "" {
+		schema, err := readOutputSchema(strictPath)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to read output schema: %s", err))
+			return 1
+		}
+		if problems := validateOutputs(schema, mod.Outputs, name); len(problems) > 0 {
+			c.Ui.Error(fmt.Sprintf(
+				"The outputs do not conform to the schema in %s:\n\n  %s",
+				strictPath, strings.Join(problems, "\n  ")))
+			return 1
+		}
+	}
+
+	if schemaOutput {
+		outputs := mod.Outputs
+		if name != "" {
+			v, ok := mod.Outputs[name]
+			if !ok {
+				c.Ui.Error(fmt.Sprintf("The output variable %q could not be found in the state.", name))
+				return 1
+			}
+			outputs = map[string]*terraform.OutputState{name: v}
+		}
+		buf, err := json.MarshalIndent(newOutputSchema(outputs), "", "    ")
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error rendering output schema: %s", err))
+			return 1
+		}
+		c.Ui.Output(string(buf))
+		return 0
+	}
+
 	if state.Empty() || len(mod.Outputs) == 0 {
 		c.Ui.Error(
 			"The state file either has no outputs defined, or all the defined\n" +
@@ -98,7 +153,20 @@ func (c *OutputCommand) Run(args []string) int {
 
 	if name == "" {
 		if jsonOutput {
-			jsonOutputs, err := json.MarshalIndent(mod.Outputs, "", "    ")
+			var jsonOutputs []byte
+			if showSensitive {
+				if err := c.auditShowSensitive(mod.Outputs, module, env, state); err != nil {
+					c.Ui.Error(err.Error())
+					return 1
+				}
+				jsonOutputs, err = json.MarshalIndent(mod.Outputs, "", "    ")
+			} else {
+				outputs := make(map[string]*outputJSON, len(mod.Outputs))
+				for k, v := range mod.Outputs {
+					outputs[k] = c.outputJSON(v)
+				}
+				jsonOutputs, err = json.MarshalIndent(outputs, "", "    ")
+			}
 			if err != nil {
 				return 1
 			}
@@ -106,7 +174,7 @@ func (c *OutputCommand) Run(args []string) int {
 			c.Ui.Output(string(jsonOutputs))
 			return 0
 		} else {
-			c.Ui.Output(outputsAsString(state, modPath, nil, false))
+			c.Ui.Output(outputsAsString(state, modPath, nil, false, c.Redaction))
 			return 0
 		}
 	}
@@ -121,6 +189,32 @@ func (c *OutputCommand) Run(args []string) int {
 		return 1
 	}
 
+	if v.Sensitive {
+		if showSensitive {
+			outputs := map[string]*terraform.OutputState{name: v}
+			if err := c.auditShowSensitive(outputs, module, env, state); err != nil {
+				c.Ui.Error(err.Error())
+				return 1
+			}
+		} else if !unveil {
+			if jsonOutput {
+				jsonOutputs, err := json.MarshalIndent(c.outputJSON(v), "", "    ")
+				if err != nil {
+					return 1
+				}
+				c.Ui.Output(string(jsonOutputs))
+			} else {
+				c.Ui.Output(c.Redaction.Value(v.Value))
+			}
+			return 0
+		} else if err := c.auditUnveil(name, module, env, "unveil", state); err != nil {
+			// The value is only revealed once it's recorded that it was, so
+			// that it's never revealed without a record.
+			c.Ui.Error(fmt.Sprintf("Failed to record the unveiling of output %q in the audit log, so it isn't revealed: %s", name, err))
+			return 1
+		}
+	}
+
 	if jsonOutput {
 		jsonOutputs, err := json.MarshalIndent(v, "", "    ")
 		if err != nil {
@@ -148,6 +242,30 @@ func (c *OutputCommand) Run(args []string) int {
 	return 0
 }
 
+// outputJSON is the JSON representation of an output in the output of the
+// output command, which is that of terraform.OutputState except that the
+// values of sensitive outputs are written as null, with what the redaction
+// shows of them in "redacted".
+type outputJSON struct {
+	Sensitive bool                 `json:"sensitive"`
+	Type      string               `json:"type"`
+	Value     interface{}          `json:"value"`
+	Redacted  *format.RedactedJSON `json:"redacted,omitempty"`
+}
+
+func (c *OutputCommand) outputJSON(v *terraform.OutputState) *outputJSON {
+	ret := &outputJSON{
+		Sensitive: v.Sensitive,
+		Type:      v.Type,
+		Value:     v.Value,
+	}
+	if v.Sensitive {
+		ret.Value = nil
+		ret.Redacted = c.Redaction.JSON(v.Value)
+	}
+	return ret
+}
+
 func formatNestedList(indent string, outputList []interface{}) string {
 	outputBuf := new(bytes.Buffer)
 	outputBuf.WriteString(fmt.Sprintf("%s[", indent))
@@ -270,6 +388,10 @@ Usage: terraform output [options] [NAME]
   the outputs for the root module.  If NAME is not specified, all
   outputs are printed.
 
+  The values of sensitive outputs are redacted, as selected by the
+  sensitive_redaction setting of the CLI configuration, unless
+  -unveil or -show-sensitive is given.
+
 Options:
 
   -state=path      Path to the state file to read. Defaults to
@@ -281,7 +403,29 @@ Options:
                    specific module
 
   -json            If specified, machine readable output will be
-                   printed in JSON format
+                   printed in JSON format. The values of sensitive
+                   outputs are written as null.
+
+  -unveil          Reveal the value of the sensitive output NAME. This
+                   must be allowed with the allow_unveil setting of the
+                   CLI configuration or the TF_ALLOW_UNVEIL environment
+                   variable, and each time a value is revealed it is
+                   recorded in the audit log.
+
+  -show-sensitive  Print the values of sensitive outputs when NAME or
+                   -json is given, as Terraform did before sensitive
+                   outputs were redacted. This must be allowed as for
+                   -unveil, and each value revealed is recorded in the
+                   audit log.
+
+  -schema          If specified, the types of the outputs are printed
+                   as a JSON schema document instead of their values.
+                   The schema can be saved and used with -strict.
+
+  -strict=path     Check that the outputs conform to the schema in
+                   the given file, as written by -schema, before
+                   printing them. Fails if any output is missing, is
+                   not in the schema, or has a value of another type.
 
 `
 	return strings.TrimSpace(helpText)
@@ -290,3 +434,12 @@ Options:
 func (c *OutputCommand) Synopsis() string {
 	return "Read an output from a state file"
 }
+
+const outputUnveilNotAllowed = `
+Revealing sensitive outputs is not allowed.
+
+To allow "terraform output -unveil" and "terraform output -show-sensitive",
+set allow_unveil in the CLI configuration or set the TF_ALLOW_UNVEIL
+environment variable to 1. Each time a sensitive output is revealed, it is
+recorded in the audit log.
+`
@@ -0,0 +1,274 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// OutputSchemaFormatVersion is the version of the document written by
+// "terraform output -schema". It will be incremented if the document
+// changes in a way that is not backward-compatible.
+const OutputSchemaFormatVersion = "0.1"
+
+// outputSchemaJSON is the document written by "terraform output -schema",
+// and read back by "terraform output -strict".
+type outputSchemaJSON struct {
+	FormatVersion string                       `json:"format_version"`
+	Outputs       map[string]*outputSchemaItem `json:"outputs"`
+}
+
+type outputSchemaItem struct {
+	Type      cty.Type `json:"type"`
+	Sensitive bool     `json:"sensitive"`
+}
+
+// newOutputSchema returns the schema of the given outputs, keyed by name.
+func newOutputSchema(outputs map[string]*terraform.OutputState) *outputSchemaJSON {
+	doc := &outputSchemaJSON{
+		FormatVersion: OutputSchemaFormatVersion,
+		Outputs:       make(map[string]*outputSchemaItem, len(outputs)),
+	}
+	for name, os := range outputs {
+		doc.Outputs[name] = &outputSchemaItem{
+			Type:      outputValueType(os.Value),
+			Sensitive: os.Sensitive,
+		}
+	}
+	return doc
+}
+
+// readOutputSchema reads a document written by "terraform output -schema"
+// from the file with the given name.
+func readOutputSchema(filename string) (*outputSchemaJSON, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var doc outputSchemaJSON
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return nil, fmt.Errorf("invalid output schema: %s", err)
+	}
+	if doc.FormatVersion != OutputSchemaFormatVersion {
+		return nil, fmt.Errorf("unsupported output schema format version %q", doc.FormatVersion)
+	}
+	return &doc, nil
+}
+
+// outputValueType returns the type of the given output value, as stored in
+// the state.
+//
+// The state doesn't record the types of the elements of lists and maps, so
+// they're derived from the values: a list or map whose elements all have
+// the same type is a list or map of that type, and otherwise it is a tuple
+// or object type. The element type of an empty list or map is
+// cty.DynamicPseudoType, since any element type conforms to it.
+func outputValueType(v interface{}) cty.Type {
+	switch tv := v.(type) {
+	case string:
+		return cty.String
+	case bool:
+		return cty.Bool
+	case int, float64:
+		return cty.Number
+	case []interface{}:
+		etys := make([]cty.Type, len(tv))
+		for i, ev := range tv {
+			etys[i] = outputValueType(ev)
+		}
+		if len(etys) == 0 {
+			return cty.List(cty.DynamicPseudoType)
+		}
+		if ety, ok := commonOutputType(etys); ok {
+			return cty.List(ety)
+		}
+		return cty.Tuple(etys)
+	case map[string]interface{}:
+		atys := make(map[string]cty.Type, len(tv))
+		etys := make([]cty.Type, 0, len(tv))
+		for k, ev := range tv {
+			atys[k] = outputValueType(ev)
+			etys = append(etys, atys[k])
+		}
+		if len(etys) == 0 {
+			return cty.Map(cty.DynamicPseudoType)
+		}
+		if ety, ok := commonOutputType(etys); ok {
+			return cty.Map(ety)
+		}
+		return cty.Object(atys)
+	default:
+		return cty.DynamicPseudoType
+	}
+}
+
+func commonOutputType(tys []cty.Type) (cty.Type, bool) {
+	for _, ty := range tys[1:] {
+		if !ty.Equals(tys[0]) {
+			return cty.NilType, false
+		}
+	}
+	return tys[0], true
+}
+
+// validateOutputs checks the given outputs against the given schema, as
+// for "terraform output -strict", and returns a description of each
+// problem in order of output name. If only is set, then only the output of
+// that name is checked.
+//
+// An output conforms if its value has exactly the structure that its type
+// describes: a string must be a string rather than a number, and an object
+// must have every attribute of its type and no others. Any value conforms
+// to cty.DynamicPseudoType. An output that isn't in the schema is a problem
+// just like one that is missing from the state, so that a schema can be used
+// as the contract of a module's outputs.
+func validateOutputs(schema *outputSchemaJSON, outputs map[string]*terraform.OutputState, only string) []string {
+	names := make(map[string]bool)
+	for name := range schema.Outputs {
+		names[name] = true
+	}
+	for name := range outputs {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		if only == "" || name == only {
+			sorted = append(sorted, name)
+		}
+	}
+	sort.Strings(sorted)
+
+	var problems []string
+	for _, name := range sorted {
+		want, inSchema := schema.Outputs[name]
+		got, inState := outputs[name]
+		switch {
+		case !inSchema:
+			problems = append(problems, fmt.Sprintf("Output %q is not in the schema.", name))
+		case !inState:
+			problems = append(problems, fmt.Sprintf("Output %q is missing.", name))
+		default:
+			if want.Sensitive != got.Sensitive {
+				problems = append(problems, fmt.Sprintf(
+					"Output %q must have sensitive = %t.", name, want.Sensitive))
+			}
+			for _, err := range outputConformance(got.Value, want.Type, nil) {
+				problems = append(problems, fmt.Sprintf("Output %q: %s.", name, outputPathError(err)))
+			}
+		}
+	}
+	return problems
+}
+
+// outputConformance returns an error for each part of the given output
+// value, at the given path, that doesn't conform to the given type.
+func outputConformance(v interface{}, ty cty.Type, path cty.Path) []error {
+	if ty.Equals(cty.DynamicPseudoType) {
+		return nil
+	}
+	mismatch := func() []error {
+		return []error{path.NewErrorf("%s required, but have %s",
+			ty.FriendlyName(), outputValueType(v).FriendlyName())}
+	}
+
+	var errs []error
+	switch {
+	case ty.Equals(cty.String):
+		if _, ok := v.(string); !ok {
+			return mismatch()
+		}
+	case ty.Equals(cty.Bool):
+		if _, ok := v.(bool); !ok {
+			return mismatch()
+		}
+	case ty.Equals(cty.Number):
+		switch v.(type) {
+		case int, float64:
+		default:
+			return mismatch()
+		}
+	case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+		list, ok := v.([]interface{})
+		if !ok {
+			return mismatch()
+		}
+		if ty.IsTupleType() && len(list) != len(ty.TupleElementTypes()) {
+			return []error{path.NewErrorf("%d elements required, but have %d",
+				len(ty.TupleElementTypes()), len(list))}
+		}
+		for i, ev := range list {
+			var ety cty.Type
+			if ty.IsTupleType() {
+				ety = ty.TupleElementTypes()[i]
+			} else {
+				ety = ty.ElementType()
+			}
+			errs = append(errs, outputConformance(ev, ety, path.Index(cty.NumberIntVal(int64(i))))...)
+		}
+	case ty.IsMapType() || ty.IsObjectType():
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		if ty.IsObjectType() {
+			names := make([]string, 0, len(ty.AttributeTypes()))
+			for k := range ty.AttributeTypes() {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+			for _, k := range names {
+				if _, exists := m[k]; !exists {
+					errs = append(errs, path.NewErrorf("missing required attribute %q", k))
+				}
+			}
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			var ety cty.Type
+			if ty.IsObjectType() {
+				if !ty.HasAttribute(k) {
+					errs = append(errs, path.NewErrorf("unsupported attribute %q", k))
+					continue
+				}
+				ety = ty.AttributeType(k)
+			} else {
+				ety = ty.ElementType()
+			}
+			errs = append(errs, outputConformance(m[k], ety, path.Index(cty.StringVal(k)))...)
+		}
+	default:
+		return mismatch()
+	}
+	return errs
+}
+
+// outputPathError returns the message of the given error from
+// outputConformance, prefixed by the path it applies to, if any.
+func outputPathError(err error) string {
+	pe, ok := err.(cty.PathError)
+	if !ok || len(pe.Path) == 0 {
+		return err.Error()
+	}
+	var buf strings.Builder
+	for _, step := range pe.Path {
+		switch ts := step.(type) {
+		case cty.IndexStep:
+			if ts.Key.Type() == cty.String {
+				buf.WriteString("[" + strconv.Quote(ts.Key.AsString()) + "]")
+			} else {
+				buf.WriteString("[" + ts.Key.AsBigFloat().Text('f', -1) + "]")
+			}
+		}
+	}
+	return fmt.Sprintf("%s: %s", buf.String(), pe.Error())
+}
@@ -1,12 +1,15 @@
 package command
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform/command/format"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
 )
@@ -236,6 +239,205 @@ func TestOutput_json(t *testing.T) {
 	}
 }
 
+func TestOutput_sensitive(t *testing.T) {
+	statePath := testStateFile(t, testOutputSchemaState())
+
+	ui := new(cli.MockUi)
+	c := &OutputCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+			Redaction:        format.RedactLength,
+		},
+	}
+
+	args := []string{
+		"-state", statePath,
+		"password",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: \n%s", ui.ErrorWriter.String())
+	}
+	if got, want := strings.TrimSpace(ui.OutputWriter.String()), "<sensitive, 7 characters>"; got != want {
+		t.Fatalf("wrong output\ngot:  %s\nwant: %s", got, want)
+	}
+
+	ui.OutputWriter.Reset()
+	args = []string{
+		"-state", statePath,
+		"-json",
+		"password",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: \n%s", ui.ErrorWriter.String())
+	}
+	want := "{\n    \"sensitive\": true,\n    \"type\": \"string\",\n    \"value\": null,\n    \"redacted\": {\n        \"characters\": 7\n    }\n}"
+	if got := strings.TrimSpace(ui.OutputWriter.String()); got != want {
+		t.Fatalf("wrong output\ngot:  %s\nwant: %s", got, want)
+	}
+	if strings.Contains(ui.OutputWriter.String(), "hunter2") {
+		t.Fatal("sensitive value was revealed")
+	}
+}
+
+func TestOutput_unveilNotAllowed(t *testing.T) {
+	statePath := testStateFile(t, testOutputSchemaState())
+	auditPath := filepath.Join(testTempDir(t), "audit.log")
+
+	ui := new(cli.MockUi)
+	c := &OutputCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+			AuditLogPath:     auditPath,
+		},
+	}
+
+	args := []string{
+		"-state", statePath,
+		"-unveil",
+		"password",
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if strings.Contains(ui.OutputWriter.String(), "hunter2") {
+		t.Fatal("sensitive value was revealed")
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "Revealing sensitive outputs is not allowed") {
+		t.Fatalf("wrong error: %s", ui.ErrorWriter.String())
+	}
+	if _, err := os.Stat(auditPath); !os.IsNotExist(err) {
+		t.Fatalf("audit log was written: %v", err)
+	}
+}
+
+func TestOutput_unveil(t *testing.T) {
+	state := testOutputSchemaState()
+	state.Lineage = "lineage-1"
+	statePath := testStateFile(t, state)
+	auditPath := filepath.Join(testTempDir(t), "audit.log")
+
+	ui := new(cli.MockUi)
+	c := &OutputCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+			AllowUnveil:      true,
+			AuditLogPath:     auditPath,
+		},
+	}
+
+	args := []string{
+		"-state", statePath,
+		"-unveil",
+		"password",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: \n%s", ui.ErrorWriter.String())
+	}
+	if got := strings.TrimSpace(ui.OutputWriter.String()); got != "hunter2" {
+		t.Fatalf("wrong output: %s", got)
+	}
+
+	data, err := ioutil.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var event auditEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if event.Event != "unveil" || event.Output != "password" || event.Module != "root" ||
+		event.Workspace != "default" || event.Lineage != "lineage-1" || event.Timestamp == "" {
+		t.Fatalf("wrong audit event: %s", data)
+	}
+
+	// Without a name, -unveil is an error.
+	ui.ErrorWriter.Reset()
+	if code := c.Run([]string{"-state", statePath, "-unveil"}); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+}
+
+func TestOutput_showSensitive(t *testing.T) {
+	statePath := testStateFile(t, testOutputSchemaState())
+	auditPath := filepath.Join(testTempDir(t), "audit.log")
+
+	ui := new(cli.MockUi)
+	c := &OutputCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+			AuditLogPath:     auditPath,
+		},
+	}
+
+	args := []string{
+		"-state", statePath,
+		"-show-sensitive",
+		"password",
+	}
+
+	// Like -unveil, -show-sensitive must be allowed.
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if strings.Contains(ui.OutputWriter.String(), "hunter2") {
+		t.Fatal("sensitive value was revealed")
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "Revealing sensitive outputs is not allowed") {
+		t.Fatalf("wrong error: %s", ui.ErrorWriter.String())
+	}
+	if _, err := os.Stat(auditPath); !os.IsNotExist(err) {
+		t.Fatalf("audit log was written: %v", err)
+	}
+
+	c.AllowUnveil = true
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: \n%s", ui.ErrorWriter.String())
+	}
+	if got := strings.TrimSpace(ui.OutputWriter.String()); got != "hunter2" {
+		t.Fatalf("wrong output: %s", got)
+	}
+
+	ui.OutputWriter.Reset()
+	args = []string{
+		"-state", statePath,
+		"-show-sensitive",
+		"-json",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: \n%s", ui.ErrorWriter.String())
+	}
+	var outputs map[string]*terraform.OutputState
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &outputs); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v := outputs["password"]; v == nil || v.Value != "hunter2" || !v.Sensitive {
+		t.Fatalf("wrong password output: %#v", v)
+	}
+
+	// Each sensitive value shown is recorded.
+	data, err := ioutil.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("wrong number of audit events %d; want 2\n%s", len(lines), data)
+	}
+	for _, line := range lines {
+		var event auditEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if event.Event != "show-sensitive" || event.Output != "password" {
+			t.Fatalf("wrong audit event: %s", line)
+		}
+	}
+}
+
 func TestMissingModuleOutput(t *testing.T) {
 	originalState := &terraform.State{
 		Modules: []*terraform.ModuleState{
@@ -497,3 +699,156 @@ func TestOutput_stateDefault(t *testing.T) {
 		t.Fatalf("bad: %#v", actual)
 	}
 }
+
+func TestOutput_schema(t *testing.T) {
+	statePath := testStateFile(t, testOutputSchemaState())
+
+	ui := new(cli.MockUi)
+	c := &OutputCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-state", statePath,
+		"-schema",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: \n%s", ui.ErrorWriter.String())
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &got); err != nil {
+		t.Fatalf("output is not valid JSON: %s\n\n%s", err, ui.OutputWriter.String())
+	}
+	var want map[string]interface{}
+	json.Unmarshal([]byte(`{
+		"format_version": "0.1",
+		"outputs": {
+			"name": {"type": "string", "sensitive": false},
+			"password": {"type": "string", "sensitive": true},
+			"ids": {"type": ["list", "string"], "sensitive": false},
+			"empty": {"type": ["list", "dynamic"], "sensitive": false},
+			"server": {
+				"type": ["object", {
+					"name": "string",
+					"ports": ["list", "string"],
+					"tags": ["map", "string"]
+				}],
+				"sensitive": false
+			}
+		}
+	}`), &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong schema\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestOutput_strict(t *testing.T) {
+	statePath := testStateFile(t, testOutputSchemaState())
+	schemaPath := testTempFile(t)
+
+	ui := new(cli.MockUi)
+	c := &OutputCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+	if code := c.Run([]string{"-state", statePath, "-schema"}); code != 0 {
+		t.Fatalf("bad: \n%s", ui.ErrorWriter.String())
+	}
+	if err := ioutil.WriteFile(schemaPath, ui.OutputWriter.Bytes(), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The outputs conform to their own schema.
+	ui = new(cli.MockUi)
+	c = &OutputCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+	if code := c.Run([]string{"-state", statePath, "-strict", schemaPath, "name"}); code != 0 {
+		t.Fatalf("bad: \n%s", ui.ErrorWriter.String())
+	}
+	if actual := strings.TrimSpace(ui.OutputWriter.String()); actual != "web" {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Change the outputs so that they no longer do.
+	state := testOutputSchemaState()
+	outputs := state.RootModule().Outputs
+	delete(outputs, "name")
+	outputs["extra"] = &terraform.OutputState{Type: "string", Value: "x"}
+	outputs["ids"].Value = []interface{}{"a", []interface{}{"b"}}
+	outputs["server"].Value.(map[string]interface{})["ports"] = []interface{}{float64(80)}
+	delete(outputs["server"].Value.(map[string]interface{}), "tags")
+	statePath = testStateFile(t, state)
+
+	ui = new(cli.MockUi)
+	c = &OutputCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+	if code := c.Run([]string{"-state", statePath, "-strict", schemaPath, "-json"}); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if ui.OutputWriter.String() != "" {
+		t.Fatalf("outputs were printed: %s", ui.OutputWriter.String())
+	}
+	errOutput := ui.ErrorWriter.String()
+	for _, want := range []string{
+		`Output "extra" is not in the schema.`,
+		`Output "ids": [1]: string required, but have list of string.`,
+		`Output "name" is missing.`,
+		`Output "server": missing required attribute "tags".`,
+		`Output "server": ["ports"][0]: string required, but have number.`,
+	} {
+		if !strings.Contains(errOutput, want) {
+			t.Errorf("errors don't contain %q\n\n%s", want, errOutput)
+		}
+	}
+}
+
+func testOutputSchemaState() *terraform.State {
+	return &terraform.State{
+		Modules: []*terraform.ModuleState{
+			{
+				Path: []string{"root"},
+				Outputs: map[string]*terraform.OutputState{
+					"name": {
+						Type:  "string",
+						Value: "web",
+					},
+					"password": {
+						Type:      "string",
+						Value:     "hunter2",
+						Sensitive: true,
+					},
+					"ids": {
+						Type:  "list",
+						Value: []interface{}{"a", "b"},
+					},
+					"empty": {
+						Type:  "list",
+						Value: []interface{}{},
+					},
+					"server": {
+						Type: "map",
+						Value: map[string]interface{}{
+							"name":  "web",
+							"ports": []interface{}{"80", "443"},
+							"tags":  map[string]interface{}{"env": "prod"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,91 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// auditEvent is an event recorded in the audit log, which has one JSON
+// object per line.
+type auditEvent struct {
+	Timestamp string `json:"timestamp"`
+	Event     string `json:"event"`
+	Output    string `json:"output"`
+	Module    string `json:"module"`
+	Workspace string `json:"workspace"`
+	User      string `json:"user,omitempty"`
+	Hostname  string `json:"hostname,omitempty"`
+	Lineage   string `json:"lineage,omitempty"`
+}
+
+// auditUnveil records in the audit log that the sensitive output with the
+// given name of the module with the given path, such as "root.foo", in the
+// given state of the given workspace is about to be revealed by the given
+// event: "unveil" for -unveil, or "show-sensitive" for -show-sensitive.
+func (c *OutputCommand) auditUnveil(name, module, workspace, eventName string, state *terraform.State) error {
+	event := &auditEvent{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Event:     eventName,
+		Output:    name,
+		Module:    module,
+		Workspace: workspace,
+		Lineage:   state.Lineage,
+	}
+	if u, err := user.Current(); err == nil {
+		event.User = u.Username
+	}
+	if h, err := os.Hostname(); err == nil {
+		event.Hostname = h
+	}
+
+	path := c.AuditLogPath
+	if path == "" {
+		path = filepath.Join(c.DataDir(), DefaultAuditLogFilename)
+	}
+	log.Printf("[WARN] Revealing sensitive output %q of %s, recorded in %s", name, module, path)
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// auditShowSensitive records in the audit log that the sensitive outputs
+// among the given ones are about to be revealed by -show-sensitive, and
+// returns an error to show if any of them can't be.
+func (c *OutputCommand) auditShowSensitive(outputs map[string]*terraform.OutputState, module, workspace string, state *terraform.State) error {
+	names := make([]string, 0, len(outputs))
+	for k, v := range outputs {
+		if v.Sensitive {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := c.auditUnveil(name, module, workspace, "show-sensitive", state); err != nil {
+			return fmt.Errorf("Failed to record the showing of output %q in the audit log, so it isn't shown: %s", name, err)
+		}
+	}
+	return nil
+}
@@ -2,13 +2,20 @@ package command
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/command/format"
 	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/terraform"
 	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/mitchellh/cli"
 )
 
 // PlanCommand is a Command implementation that compares a Terraform
@@ -18,9 +25,10 @@ type PlanCommand struct {
 }
 
 func (c *PlanCommand) Run(args []string) int {
-	var destroy, refresh, detailed bool
-	var outPath string
+	var destroy, refresh, refreshOnly, detailed, jsonOutput bool
+	var outPath, genConfigPath, explain string
 	var moduleDepth int
+	var replaceAddrs []string
 
 	args, err := c.Meta.process(args, true)
 	if err != nil {
@@ -30,12 +38,21 @@ func (c *PlanCommand) Run(args []string) int {
 	cmdFlags := c.Meta.flagSet("plan")
 	cmdFlags.BoolVar(&destroy, "destroy", false, "destroy")
 	cmdFlags.BoolVar(&refresh, "refresh", true, "refresh")
+	cmdFlags.BoolVar(&refreshOnly, "refresh-only", false, "refresh-only")
+	cmdFlags.Var((*FlagStringSlice)(&replaceAddrs), "replace", "resource to replace")
 	c.addModuleDepthFlag(cmdFlags, &moduleDepth)
+	c.addTargetModuleFlag(cmdFlags)
 	cmdFlags.StringVar(&outPath, "out", "", "path")
+	cmdFlags.StringVar(&genConfigPath, "generate-config-out", "", "path")
+	cmdFlags.StringVar(&explain, "explain", "", "resource")
 	cmdFlags.IntVar(
 		&c.Meta.parallelism, "parallelism", DefaultParallelism, "parallelism")
+	cmdFlags.IntVar(
+		&c.Meta.refreshParallelism, "refresh-parallelism", 0, "refresh-parallelism")
 	cmdFlags.StringVar(&c.Meta.statePath, "state", "", "path")
 	cmdFlags.BoolVar(&detailed, "detailed-exitcode", false, "detailed-exitcode")
+	cmdFlags.BoolVar(&jsonOutput, "json", false, "json")
+	cmdFlags.StringVar(&c.Meta.hookCommand, "hook-command", "", "path")
 	cmdFlags.BoolVar(&c.Meta.stateLock, "lock", true, "lock state")
 	cmdFlags.DurationVar(&c.Meta.stateLockTimeout, "lock-timeout", 0, "lock timeout")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
@@ -43,6 +60,59 @@ func (c *PlanCommand) Run(args []string) int {
 		return 1
 	}
 
+	// In JSON mode only the plan document itself is written to stdout, so
+	// that it can be parsed. Everything else goes to stderr.
+	out := c.Ui
+	if jsonOutput {
+		c.Ui = &stderrUi{Ui: c.Ui}
+	}
+
+	if refreshOnly && destroy {
+		c.Ui.Error("The -refresh-only and -destroy options are mutually exclusive.")
+		return 1
+	}
+	if refreshOnly && !refresh {
+		c.Ui.Error("The -refresh-only option cannot be used with -refresh=false, since a refresh-only plan is created by refreshing.")
+		return 1
+	}
+
+	if err := c.checkTargetModules(); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	if len(replaceAddrs) > 0 && (destroy || refreshOnly) {
+		c.Ui.Error("The -replace option cannot be used with -destroy or -refresh-only, since those plans never replace resources.")
+		return 1
+	}
+	for _, raw := range replaceAddrs {
+		addr, err := terraform.ParseResourceAddress(raw)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Invalid resource address %q for -replace: %s", raw, err))
+			return 1
+		}
+		if !addr.HasResourceSpec() || addr.Mode != config.ManagedResourceMode {
+			c.Ui.Error(fmt.Sprintf("Invalid resource address %q for -replace: only managed resources can be replaced.", raw))
+			return 1
+		}
+	}
+
+	if explain != "" {
+		if destroy || refreshOnly {
+			c.Ui.Error("The -explain option cannot be used with -destroy or -refresh-only, since those plans don't plan values from the configuration.")
+			return 1
+		}
+		addr, err := terraform.ParseResourceAddress(explain)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Invalid resource address %q for -explain: %s", explain, err))
+			return 1
+		}
+		if !addr.HasResourceSpec() || addr.Mode == config.EphemeralResourceMode {
+			c.Ui.Error(fmt.Sprintf("Invalid resource address %q for -explain: only managed and data resources can be explained.", explain))
+			return 1
+		}
+	}
+
 	configPath, err := ModulePath(cmdFlags.Args())
 	if err != nil {
 		c.Ui.Error(err.Error())
@@ -62,6 +132,23 @@ func (c *PlanCommand) Run(args []string) int {
 		return 1
 	}
 	if plan != nil {
+		if len(replaceAddrs) > 0 {
+			c.Ui.Error("The -replace option cannot be used when showing a saved plan.")
+			return 1
+		}
+		if genConfigPath != "" {
+			c.Ui.Error("The -generate-config-out option cannot be used when showing a saved plan.")
+			return 1
+		}
+		if explain != "" {
+			c.Ui.Error("The -explain option cannot be used when showing a saved plan.")
+			return 1
+		}
+		if len(c.Meta.targetModules) > 0 {
+			c.Ui.Error("The -target-module option cannot be used when showing a saved plan.")
+			return 1
+		}
+
 		// Disable refreshing no matter what since we only want to show the plan
 		refresh = false
 
@@ -76,8 +163,17 @@ func (c *PlanCommand) Run(args []string) int {
 	if plan == nil {
 		var modDiags tfdiags.Diagnostics
 		mod, modDiags = c.Module(configPath)
+		if genConfigPath != "" {
+			// The configuration of undeclared resources is generated
+			// below, so their imports aren't errors yet.
+			modDiags = withoutCode(modDiags, tfdiags.CodeConfigImportUndeclared)
+		}
 		diags = diags.Append(modDiags)
 		if modDiags.HasErrors() {
+			if jsonOutput {
+				c.showErroredJSON(out, diags)
+				return 1
+			}
 			c.showDiagnostics(diags)
 			return 1
 		}
@@ -97,6 +193,20 @@ func (c *PlanCommand) Run(args []string) int {
 		return 1
 	}
 
+	if genConfigPath != "" {
+		var genDiags tfdiags.Diagnostics
+		mod, genDiags = c.generateConfig(b, mod, configPath, genConfigPath)
+		diags = diags.Append(genDiags)
+		if genDiags.HasErrors() {
+			if jsonOutput {
+				c.showErroredJSON(out, diags)
+				return 1
+			}
+			c.showDiagnostics(diags)
+			return 1
+		}
+	}
+
 	// Build the operation
 	opReq := c.Operation()
 	opReq.Destroy = destroy
@@ -104,6 +214,12 @@ func (c *PlanCommand) Run(args []string) int {
 	opReq.Plan = plan
 	opReq.PlanRefresh = refresh
 	opReq.PlanOutPath = outPath
+	opReq.PlanJSON = jsonOutput
+	opReq.ForceReplace = replaceAddrs
+	opReq.Explain = explain
+	if refreshOnly {
+		opReq.PlanMode = backend.RefreshOnlyMode
+	}
 	opReq.Type = backend.OperationTypePlan
 
 	// Perform the operation
@@ -138,11 +254,40 @@ func (c *PlanCommand) Run(args []string) int {
 		}
 	}
 
-	c.showDiagnostics(diags)
+	if !jsonOutput {
+		c.showDiagnostics(diags)
+	}
 	if diags.HasErrors() {
+		if jsonOutput {
+			c.showErroredJSON(out, diags)
+		}
 		return 1
 	}
 
+	if jsonOutput {
+		if op.Plan == nil {
+			// Backends that don't run the plan locally may not return it.
+			c.showErroredJSON(out, diags.Append(errors.New(
+				"The backend did not return the plan, so it can't be written as JSON.")))
+			return 1
+		}
+		dispPlan := format.NewPlan(op.Plan)
+		if op.Plan.RefreshOnly {
+			// The state of a plan operation is the state the plan was
+			// created against, before refreshing.
+			dispPlan = format.NewRefreshOnlyPlan(op.State, op.Plan)
+		}
+		dispPlan.Diagnostics = diags.Append(op.Diagnostics)
+		dispPlan.Explanations = op.Explanations
+		dispPlan.Redaction = c.Redaction
+		buf, err := dispPlan.JSON()
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error rendering plan as JSON: %s", err))
+			return 1
+		}
+		out.Output(string(buf))
+	}
+
 	if detailed && !op.PlanEmpty {
 		return 2
 	}
@@ -150,6 +295,112 @@ func (c *PlanCommand) Run(args []string) int {
 	return 0
 }
 
+// generateConfig writes configuration for the undeclared resources of the
+// import blocks of mod to a new file at outPath, and returns the module
+// loaded again to include it.
+func (c *PlanCommand) generateConfig(b backend.Backend, mod *module.Tree, configPath, outPath string) (*module.Tree, tfdiags.Diagnostics) {
+	var diags tfdiags.Diagnostics
+
+	// The file must be loaded as part of the configuration, for the plan
+	// to include the resources.
+	dir, err := filepath.Abs(configPath)
+	if err == nil {
+		outPath, err = filepath.Abs(outPath)
+	}
+	if err != nil {
+		return nil, diags.Append(err)
+	}
+	if filepath.Dir(outPath) != dir || filepath.Ext(outPath) != ".tf" {
+		return nil, diags.Append(fmt.Errorf(
+			"The -generate-config-out option must be the path of a .tf file in the configuration directory %s.", dir))
+	}
+	if mod == nil {
+		return nil, diags.Append(fmt.Errorf(
+			"The -generate-config-out option requires a configuration with import blocks."))
+	}
+	if _, err := os.Stat(outPath); err == nil {
+		return nil, diags.Append(fmt.Errorf(
+			"The file %s already exists. The -generate-config-out option only writes to new files, so that no configuration is overwritten.", outPath))
+	}
+
+	// We require a backend.Local to build a context.
+	local, ok := b.(backend.Local)
+	if !ok {
+		return nil, diags.Append(errors.New(ErrUnsupportedLocalOp))
+	}
+	opReq := c.Operation()
+	opReq.Module = mod
+	ctx, _, err := local.Context(opReq)
+	if err != nil {
+		return nil, diags.Append(err)
+	}
+
+	src, err := ctx.GenerateImportConfig()
+	if err != nil {
+		return nil, diags.Append(err)
+	}
+	if src == nil {
+		c.Ui.Output("All the resources of the import blocks are declared, so no configuration was generated.\n")
+		return mod, diags
+	}
+
+	f, err := os.OpenFile(outPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return nil, diags.Append(fmt.Errorf(
+			"The file %s already exists. The -generate-config-out option only writes to new files, so that no configuration is overwritten.", outPath))
+	}
+	if err != nil {
+		return nil, diags.Append(fmt.Errorf("Error writing generated configuration: %s", err))
+	}
+	_, err = f.Write(src)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, diags.Append(fmt.Errorf("Error writing generated configuration: %s", err))
+	}
+	c.Ui.Output(c.Colorize().Color(fmt.Sprintf(
+		"[reset][bold]Wrote configuration for the imported resources to %s.[reset]\n"+
+			"Review it before applying the plan.\n", outPath)))
+
+	return c.Module(configPath)
+}
+
+// withoutCode returns the given diagnostics except those with the given
+// code.
+func withoutCode(diags tfdiags.Diagnostics, code tfdiags.Code) tfdiags.Diagnostics {
+	var ret tfdiags.Diagnostics
+	for _, diag := range diags {
+		if tfdiags.GetCode(diag) != code {
+			ret = append(ret, diag)
+		}
+	}
+	return ret
+}
+
+// planErroredJSON is the document that plan -json writes in place of the
+// plan when planning fails.
+type planErroredJSON struct {
+	FormatVersion string                   `json:"format_version"`
+	Errored       bool                     `json:"errored"`
+	Diagnostics   []*format.JSONDiagnostic `json:"diagnostics"`
+}
+
+// showErroredJSON writes the given diagnostics of a plan that failed to the
+// given UI as a JSON document.
+func (c *PlanCommand) showErroredJSON(out cli.Ui, diags tfdiags.Diagnostics) {
+	buf, err := json.Marshal(planErroredJSON{
+		FormatVersion: format.PlanJSONFormatVersion,
+		Errored:       true,
+		Diagnostics:   format.NewJSONDiagnostics(diags),
+	})
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error rendering diagnostics as JSON: %s", err))
+		return
+	}
+	out.Output(string(buf))
+}
+
 func (c *PlanCommand) Help() string {
 	helpText := `
 Usage: terraform plan [options] [DIR-OR-PLAN]
@@ -162,7 +413,8 @@ Usage: terraform plan [options] [DIR-OR-PLAN]
   this plan exactly.
 
   If a saved plan is passed as an argument, this command will output
-  the saved plan contents. It will not modify the given plan.
+  the saved plan contents. It will not modify the given plan. To compare
+  the changes of two saved plans, use "terraform plan diff".
 
 Options:
 
@@ -175,8 +427,27 @@ Options:
                       1 - Errored
                       2 - Succeeded, there is a diff
 
+  -explain=resource   Explain where each planned value of the given resource
+                      came from: the expression that sets it, what the
+                      expression refers to, and why it is unknown if it is
+                      only known after apply.
+
+  -generate-config-out=path
+                      Write configuration for the resources of import blocks
+                      that aren't declared to a new file at the given path,
+                      from the objects being imported, and then plan with it.
+
+  -hook-command=path  Run the program at the given path before and after
+                      each resource is refreshed, with a JSON description of
+                      the event on its stdin.
+
   -input=true         Ask for input for variables if not directly set.
 
+  -json               If specified, the plan is written to stdout as a JSON
+                      document describing each resource change and each
+                      error or warning, rather than in a human-readable
+                      form. Other messages are written to stderr.
+
   -lock=true          Lock the state file when locking is supported.
 
   -lock-timeout=0s    Duration to retry a state lock.
@@ -194,6 +465,19 @@ Options:
 
   -refresh=true       Update state prior to checking for differences.
 
+  -refresh-parallelism=n
+                      Limit the number of concurrent operations while
+                      refreshing state. Defaults to the -parallelism value.
+
+  -refresh-only       If set, a plan will be generated that only updates the
+                      state to match any changes made to remote objects
+                      outside of Terraform, without changing any
+                      infrastructure.
+
+  -replace=resource   Resource to replace. The plan will replace this resource
+                      even if it has no changes. This flag can be used
+                      multiple times.
+
   -state=statefile    Path to a Terraform state file to use to look
                       up Terraform-managed resources. By default it will
                       use the state "terraform.tfstate" if it exists.
@@ -202,6 +486,13 @@ Options:
                       resource and its dependencies. This flag can be used
                       multiple times.
 
+  -target-module=module.foo
+                      Module to scope the plan to. The plan will include only
+                      the resources in this module and its descendants, and
+                      their dependencies, and will warn about the resources
+                      and modules outside of it that depend on it. This flag
+                      can be used multiple times.
+
   -var 'foo=bar'      Set a variable in the Terraform configuration. This
                       flag can be set multiple times.
 
@@ -215,3 +506,17 @@ Options:
 func (c *PlanCommand) Synopsis() string {
 	return "Generate and show an execution plan"
 }
+
+// stderrUi is a cli.Ui that writes normal output to the wrapped Ui's error
+// stream, for use when stdout is reserved for machine-readable output.
+type stderrUi struct {
+	cli.Ui
+}
+
+func (u *stderrUi) Output(msg string) {
+	u.Ui.Error(msg)
+}
+
+func (u *stderrUi) Info(msg string) {
+	u.Ui.Error(msg)
+}
@@ -0,0 +1,249 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/command/format"
+	"github.com/hashicorp/terraform/diffs"
+	"github.com/hashicorp/terraform/planfile"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// PlanDiffCommand is a Command implementation that compares the changes of
+// two saved plans.
+type PlanDiffCommand struct {
+	Meta
+}
+
+// PlanDiffJSONFormatVersion is the version of the document written by
+// "terraform plan diff -json". It will be incremented if the document
+// changes in a way that is not backward-compatible.
+const PlanDiffJSONFormatVersion = "0.1"
+
+type planDiffJSON struct {
+	FormatVersion string                `json:"format_version"`
+	Differs       bool                  `json:"differs"`
+	Changes       []*planDiffChangeJSON `json:"resource_changes"`
+}
+
+type planDiffChangeJSON struct {
+	Address   string              `json:"address"`
+	Delta     string              `json:"delta"`
+	OldAction string              `json:"old_action"`
+	NewAction string              `json:"new_action"`
+	Paths     []*planDiffPathJSON `json:"paths,omitempty"`
+}
+
+type planDiffPathJSON struct {
+	Path  string `json:"path"`
+	Delta string `json:"delta"`
+}
+
+func (c *PlanDiffCommand) Run(args []string) int {
+	var detailed, jsonOutput bool
+
+	args, err := c.Meta.process(args, false)
+	if err != nil {
+		return 1
+	}
+
+	cmdFlags := c.Meta.flagSet("plan diff")
+	cmdFlags.BoolVar(&detailed, "detailed-exitcode", false, "detailed-exitcode")
+	cmdFlags.BoolVar(&jsonOutput, "json", false, "json")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = cmdFlags.Args()
+	if len(args) != 2 {
+		c.Ui.Error("The plan diff command expects two plan files.")
+		cmdFlags.Usage()
+		return 1
+	}
+
+	changesA, err := readPlanChanges(args[0])
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to read plan file %s: %s", args[0], err))
+		return 1
+	}
+	changesB, err := readPlanChanges(args[1])
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to read plan file %s: %s", args[1], err))
+		return 1
+	}
+
+	deltas := diffs.CompareChangeSets(changesA, changesB)
+
+	if jsonOutput {
+		buf, err := json.Marshal(newPlanDiffJSON(deltas))
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error rendering plan comparison as JSON: %s", err))
+			return 1
+		}
+		c.Ui.Output(string(buf))
+	} else {
+		c.Ui.Output(c.Colorize().Color(formatPlanDiff(args[0], args[1], deltas)))
+	}
+
+	if detailed && len(deltas) > 0 {
+		return 2
+	}
+	return 0
+}
+
+// readPlanChanges reads the changes of the plan file with the given
+// filename. The changes of a plan that was saved directly with
+// terraform.WritePlan by older versions are derived from its diff.
+func readPlanChanges(filename string) (diffs.ChangeSet, error) {
+	r, err := planfile.Open(filename)
+	switch {
+	case err == planfile.ErrNotPlanFile:
+		plan, err := planfile.ReadPlan(filename)
+		if err != nil {
+			return nil, err
+		}
+		return format.NewPlan(plan).Changes(), nil
+	case err != nil:
+		return nil, err
+	}
+	defer r.Close()
+	return r.ReadChanges()
+}
+
+func newPlanDiffJSON(deltas []*diffs.ChangeDelta) *planDiffJSON {
+	doc := &planDiffJSON{
+		FormatVersion: PlanDiffJSONFormatVersion,
+		Differs:       len(deltas) > 0,
+		Changes:       make([]*planDiffChangeJSON, 0, len(deltas)),
+	}
+	for _, d := range deltas {
+		change := &planDiffChangeJSON{
+			Address:   d.Key,
+			Delta:     planDiffDeltaName(d.Kind),
+			OldAction: planDiffActionName(d.OldAction),
+			NewAction: planDiffActionName(d.NewAction),
+		}
+		for _, p := range d.Paths {
+			change.Paths = append(change.Paths, &planDiffPathJSON{
+				Path:  planDiffPath(p),
+				Delta: planDiffDeltaName(p.Kind),
+			})
+		}
+		doc.Changes = append(doc.Changes, change)
+	}
+	return doc
+}
+
+// formatPlanDiff returns the human-readable comparison of the plans with
+// the given filenames, for colorstring.Colorize.
+func formatPlanDiff(a, b string, deltas []*diffs.ChangeDelta) string {
+	if len(deltas) == 0 {
+		return fmt.Sprintf("[reset][green]The plans %s and %s make the same changes.", a, b)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "[reset]Comparing the changes of %s with those of %s:\n\n", a, b)
+
+	var added, removed, altered int
+	for _, d := range deltas {
+		switch d.Kind {
+		case diffs.DeltaAdded:
+			added++
+			fmt.Fprintf(&buf, "  [green]+[reset] [bold]%s[reset] (%s, only in %s)\n", d.Key, planDiffActionName(d.NewAction), b)
+		case diffs.DeltaRemoved:
+			removed++
+			fmt.Fprintf(&buf, "  [red]-[reset] [bold]%s[reset] (%s, only in %s)\n", d.Key, planDiffActionName(d.OldAction), a)
+		default:
+			altered++
+			fmt.Fprintf(&buf, "  [yellow]~[reset] [bold]%s[reset]", d.Key)
+			if d.OldAction != d.NewAction {
+				fmt.Fprintf(&buf, " (%s, now %s)", planDiffActionName(d.OldAction), planDiffActionName(d.NewAction))
+			}
+			buf.WriteString("\n")
+			for _, p := range d.Paths {
+				fmt.Fprintf(&buf, "      %s %s\n", planDiffPathSymbol(p.Kind), planDiffPath(p))
+			}
+		}
+	}
+
+	fmt.Fprintf(&buf, "\n%d resource changes differ: %d added, %d removed, %d altered.",
+		len(deltas), added, removed, altered)
+	return buf.String()
+}
+
+// planDiffPath returns the path of the given leaf change. The changes of
+// resource instances are keyed by flattened attribute, so their paths are
+// shown as just the attribute, such as "tags.Name".
+func planDiffPath(p diffs.PathDelta) string {
+	if len(p.Path) == 1 {
+		if step, ok := p.Path[0].(cty.IndexStep); ok && step.Key.Type() == cty.String && step.Key.IsKnown() {
+			return step.Key.AsString()
+		}
+	}
+	return p.String()
+}
+
+func planDiffPathSymbol(kind diffs.DeltaKind) string {
+	switch kind {
+	case diffs.DeltaAdded:
+		return "[green]+[reset]"
+	case diffs.DeltaRemoved:
+		return "[red]-[reset]"
+	default:
+		return "[yellow]~[reset]"
+	}
+}
+
+func planDiffDeltaName(kind diffs.DeltaKind) string {
+	switch kind {
+	case diffs.DeltaAdded:
+		return "added"
+	case diffs.DeltaRemoved:
+		return "removed"
+	default:
+		return "altered"
+	}
+}
+
+func planDiffActionName(action diffs.Action) string {
+	if action == diffs.NoOp {
+		return "no-op"
+	}
+	return strings.ToLower(action.String())
+}
+
+func (c *PlanDiffCommand) Help() string {
+	helpText := `
+Usage: terraform plan diff [options] PLAN_A PLAN_B
+
+  Compares the changes of two saved plans, such as the plan of a pull
+  request and the plan made when it's merged, and shows which resource
+  changes are only in one of them, and which are in both but differ. A
+  resource change differs if its action differs or if the plans change
+  any of its attributes differently.
+
+  The values of the attributes aren't shown, only their paths, so the
+  output never reveals sensitive values.
+
+Options:
+
+  -detailed-exitcode  Return detailed exit codes when the command exits. This
+                      will change the meaning of exit codes to:
+                      0 - Succeeded, the plans make the same changes
+                      1 - Errored
+                      2 - Succeeded, the plans make different changes
+
+  -json               Write the comparison to stdout as a JSON document.
+
+  -no-color           If specified, output won't contain any color.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *PlanDiffCommand) Synopsis() string {
+	return "Compare the changes of two saved plans"
+}
@@ -0,0 +1,119 @@
+package command
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
+)
+
+func TestPlanDiff(t *testing.T) {
+	planA := testPlanFile(t, testPlanDiffPlan(map[string]*terraform.InstanceDiff{
+		"test_instance.same": &terraform.InstanceDiff{
+			Attributes: map[string]*terraform.ResourceAttrDiff{
+				"ami": &terraform.ResourceAttrDiff{Old: "", New: "bar"},
+			},
+		},
+		"test_instance.changed": &terraform.InstanceDiff{
+			Attributes: map[string]*terraform.ResourceAttrDiff{
+				"ami":  &terraform.ResourceAttrDiff{Old: "", New: "bar"},
+				"size": &terraform.ResourceAttrDiff{Old: "", New: "1"},
+			},
+		},
+		"test_instance.gone": &terraform.InstanceDiff{
+			Attributes: map[string]*terraform.ResourceAttrDiff{
+				"ami": &terraform.ResourceAttrDiff{Old: "", New: "bar"},
+			},
+		},
+	}))
+	planB := testPlanFile(t, testPlanDiffPlan(map[string]*terraform.InstanceDiff{
+		"test_instance.same": &terraform.InstanceDiff{
+			Attributes: map[string]*terraform.ResourceAttrDiff{
+				"ami": &terraform.ResourceAttrDiff{Old: "", New: "bar"},
+			},
+		},
+		"test_instance.changed": &terraform.InstanceDiff{
+			Attributes: map[string]*terraform.ResourceAttrDiff{
+				"ami":  &terraform.ResourceAttrDiff{Old: "", New: "baz"},
+				"size": &terraform.ResourceAttrDiff{Old: "", New: "1"},
+			},
+		},
+		"test_instance.new": &terraform.InstanceDiff{
+			Attributes: map[string]*terraform.ResourceAttrDiff{
+				"ami": &terraform.ResourceAttrDiff{Old: "", New: "bar"},
+			},
+		},
+	}))
+
+	ui := new(cli.MockUi)
+	c := &PlanDiffCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+
+	if code := c.Run([]string{"-no-color", "-detailed-exitcode", planA, planB}); code != 2 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	for _, want := range []string{
+		"+ test_instance.new (update, only in",
+		"- test_instance.gone (update, only in",
+		"~ test_instance.changed\n      ~ ami\n",
+		"3 resource changes differ: 1 added, 1 removed, 1 altered.",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output doesn't contain %q\n\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "test_instance.same") || strings.Contains(output, "baz") {
+		t.Errorf("output includes unchanged resource or values\n\n%s", output)
+	}
+}
+
+func TestPlanDiff_json(t *testing.T) {
+	plan := testPlanFile(t, testPlanDiffPlan(map[string]*terraform.InstanceDiff{
+		"test_instance.foo": &terraform.InstanceDiff{
+			Attributes: map[string]*terraform.ResourceAttrDiff{
+				"ami": &terraform.ResourceAttrDiff{Old: "", New: "bar"},
+			},
+		},
+	}))
+
+	ui := new(cli.MockUi)
+	c := &PlanDiffCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+
+	if code := c.Run([]string{"-json", "-detailed-exitcode", plan, plan}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var doc planDiffJSON
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %s\n\n%s", err, ui.OutputWriter.String())
+	}
+	if doc.Differs || doc.Changes == nil || len(doc.Changes) != 0 {
+		t.Fatalf("wrong result: %s", ui.OutputWriter.String())
+	}
+}
+
+func testPlanDiffPlan(resources map[string]*terraform.InstanceDiff) *terraform.Plan {
+	return &terraform.Plan{
+		Diff: &terraform.Diff{
+			Modules: []*terraform.ModuleDiff{
+				&terraform.ModuleDiff{
+					Path:      []string{"root"},
+					Resources: resources,
+				},
+			},
+		},
+	}
+}
@@ -2,6 +2,7 @@ package command
 
 import (
 	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -9,9 +10,13 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform/config/configschema"
 	"github.com/hashicorp/terraform/helper/copy"
+	"github.com/hashicorp/terraform/state/encryption"
 	"github.com/hashicorp/terraform/terraform"
+	"github.com/hashicorp/terraform/tfdiags"
 	"github.com/mitchellh/cli"
+	"github.com/zclconf/go-cty/cty"
 )
 
 func TestPlan(t *testing.T) {
@@ -39,6 +44,172 @@ func TestPlan(t *testing.T) {
 	}
 }
 
+func TestPlan_json(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := os.Chdir(testFixturePath("plan")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Chdir(cwd)
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{"-json"}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var doc struct {
+		FormatVersion   string `json:"format_version"`
+		ResourceChanges []struct {
+			Address string `json:"address"`
+			Change  struct {
+				Action string `json:"action"`
+			} `json:"change"`
+		} `json:"resource_changes"`
+	}
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %s\n\n%s", err, ui.OutputWriter.String())
+	}
+	if got, want := len(doc.ResourceChanges), 1; got != want {
+		t.Fatalf("wrong number of resource changes %d; want %d", got, want)
+	}
+	rc := doc.ResourceChanges[0]
+	if got, want := rc.Address, "test_instance.foo"; got != want {
+		t.Errorf("wrong address %q; want %q", got, want)
+	}
+	if got, want := rc.Change.Action, "create"; got != want {
+		t.Errorf("wrong action %q; want %q", got, want)
+	}
+}
+
+func TestPlan_explain(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := os.Chdir(testFixturePath("plan")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Chdir(cwd)
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{"-explain=test_instance.foo", "-json"}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var doc struct {
+		Explanations []struct {
+			Address   string `json:"address"`
+			Arguments []struct {
+				Name       string      `json:"name"`
+				Configured bool        `json:"configured"`
+				Expression interface{} `json:"expression"`
+				Range      *struct {
+					Start struct {
+						Line int `json:"line"`
+					} `json:"start"`
+				} `json:"range"`
+			} `json:"arguments"`
+		} `json:"explanations"`
+	}
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %s\n\n%s", err, ui.OutputWriter.String())
+	}
+	if len(doc.Explanations) != 1 || doc.Explanations[0].Address != "test_instance.foo" {
+		t.Fatalf("wrong explanations\n\n%s", ui.OutputWriter.String())
+	}
+	var found bool
+	for _, arg := range doc.Explanations[0].Arguments {
+		if arg.Name != "ami" {
+			continue
+		}
+		found = true
+		if !arg.Configured || arg.Expression != "bar" || arg.Range == nil || arg.Range.Start.Line != 2 {
+			t.Fatalf("wrong explanation of ami\n\n%s", ui.OutputWriter.String())
+		}
+	}
+	if !found {
+		t.Fatalf("ami isn't explained\n\n%s", ui.OutputWriter.String())
+	}
+}
+
+func TestPlan_explainInvalid(t *testing.T) {
+	for _, args := range [][]string{
+		{"-explain=module.foo"},
+		{"-explain=test_instance.foo", "-destroy"},
+	} {
+		ui := new(cli.MockUi)
+		c := &PlanCommand{
+			Meta: Meta{
+				testingOverrides: metaOverridesForProvider(testProvider()),
+				Ui:               ui,
+			},
+		}
+		if code := c.Run(append(args, testFixturePath("plan"))); code != 1 {
+			t.Fatalf("%v: bad: %d\n\n%s", args, code, ui.OutputWriter.String())
+		}
+		if !strings.Contains(ui.ErrorWriter.String(), "-explain") {
+			t.Fatalf("%v: wrong error: %s", args, ui.ErrorWriter.String())
+		}
+	}
+}
+
+func TestPlan_jsonErrored(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := os.Chdir(testFixturePath("validate-invalid/missing_var")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Chdir(cwd)
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{"-json"}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("wrong exit status %d; want 1\n\n%s", code, ui.OutputWriter.String())
+	}
+
+	var doc planErroredJSON
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %s\n\n%s", err, ui.OutputWriter.String())
+	}
+	if !doc.Errored || len(doc.Diagnostics) == 0 {
+		t.Fatalf("wrong result: %s", ui.OutputWriter.String())
+	}
+	diag := doc.Diagnostics[0]
+	if diag.Severity != "error" || diag.Code != tfdiags.CodeConfigInvalid {
+		t.Fatalf("wrong diagnostic: %s", ui.OutputWriter.String())
+	}
+}
+
 func TestPlan_lockedState(t *testing.T) {
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -222,15 +393,7 @@ func TestPlan_outPath(t *testing.T) {
 		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
 	}
 
-	f, err := os.Open(outPath)
-	if err != nil {
-		t.Fatalf("err: %s", err)
-	}
-	defer f.Close()
-
-	if _, err := terraform.ReadPlan(f); err != nil {
-		t.Fatalf("err: %s", err)
-	}
+	testReadPlan(t, outPath)
 }
 
 func TestPlan_outPathNoChange(t *testing.T) {
@@ -282,6 +445,119 @@ func TestPlan_outPathNoChange(t *testing.T) {
 	}
 }
 
+func TestPlan_targetModuleInvalid(t *testing.T) {
+	for _, addr := range []string{"aws_instance.foo", "module.foo.aws_instance.bar", "data.aws_ami.foo"} {
+		p := testProvider()
+		ui := cli.NewMockUi()
+		c := &PlanCommand{
+			Meta: Meta{
+				testingOverrides: metaOverridesForProvider(p),
+				Ui:               ui,
+			},
+		}
+
+		args := []string{"-target-module", addr, testFixturePath("plan")}
+		if code := c.Run(args); code != 1 {
+			t.Fatalf("%s: bad: %d\n\n%s", addr, code, ui.OutputWriter.String())
+		}
+		if errOut := ui.ErrorWriter.String(); !strings.Contains(errOut, "Invalid module address") {
+			t.Fatalf("%s: bad error output:\n%s", addr, errOut)
+		}
+	}
+}
+
+func TestPlan_generateConfigOut(t *testing.T) {
+	td := tempDir(t)
+	copy.CopyDir(testFixturePath("plan-generate-config"), td)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	p := testProvider()
+	p.GetSchemaReturn = &terraform.ProviderSchema{
+		ResourceTypes: map[string]*configschema.Block{
+			"test_instance": {
+				Attributes: map[string]*configschema.Attribute{
+					"id":  {Type: cty.String, Computed: true},
+					"ami": {Type: cty.String, Optional: true},
+				},
+			},
+		},
+	}
+	p.ImportStateReturn = []*terraform.InstanceState{
+		&terraform.InstanceState{
+			ID:        "bar",
+			Ephemeral: terraform.EphemeralState{Type: "test_instance"},
+		},
+	}
+	p.RefreshFn = func(info *terraform.InstanceInfo, s *terraform.InstanceState) (*terraform.InstanceState, error) {
+		return &terraform.InstanceState{
+			ID:         s.ID,
+			Attributes: map[string]string{"id": s.ID, "ami": "ami-123"},
+		}, nil
+	}
+
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{"-generate-config-out=generated.tf"}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	src, err := ioutil.ReadFile("generated.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(src), `resource "test_instance" "foo" {
+  ami = "ami-123"
+}`) {
+		t.Fatalf("wrong generated configuration:\n%s", src)
+	}
+	if output := ui.OutputWriter.String(); !strings.Contains(output, "Wrote configuration") {
+		t.Fatalf("bad output:\n%s", output)
+	}
+
+	// The file is never overwritten.
+	ui = cli.NewMockUi()
+	c = &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if errOut := ui.ErrorWriter.String(); !strings.Contains(errOut, "already exists") {
+		t.Fatalf("bad error output:\n%s", errOut)
+	}
+}
+
+func TestPlan_generateConfigOutUndeclared(t *testing.T) {
+	p := testProvider()
+	ui := cli.NewMockUi()
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	// Without the option, imports to undeclared resources are errors.
+	args := []string{testFixturePath("plan-generate-config")}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if errOut := ui.ErrorWriter.String(); !strings.Contains(errOut, "unknown resource") {
+		t.Fatalf("bad error output:\n%s", errOut)
+	}
+}
+
 // When using "-out" with a backend, the plan should encode the backend config
 func TestPlan_outBackend(t *testing.T) {
 	// Create a temporary working directory that is empty
@@ -343,6 +619,47 @@ func TestPlan_outBackend(t *testing.T) {
 	}
 }
 
+// When state encryption is configured, "-out" is refused since the plan
+// file would hold the state unencrypted.
+func TestPlan_outStateEncryption(t *testing.T) {
+	// Create a temporary working directory that is empty
+	td := tempDir(t)
+	copy.CopyDir(testFixturePath("backend-new-encrypted"), td)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	defer os.Setenv(encryption.PassphraseEnvVar, os.Getenv(encryption.PassphraseEnvVar))
+	os.Setenv(encryption.PassphraseEnvVar, "correct horse battery staple")
+
+	// Initialize the backend
+	if _, err := testMetaBackend(t, nil).Backend(&BackendOpts{Init: true}); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+
+	outPath := "foo"
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-out", outPath,
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "Plan files can't be written while state encryption is configured") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+	if _, err := os.Stat(outPath); err == nil {
+		t.Fatal("plan file should not exist")
+	}
+}
+
 // When using "-out" with a legacy remote state, the plan should encode
 // the backend config
 func TestPlan_outBackendLegacy(t *testing.T) {
@@ -613,13 +930,15 @@ func TestPlan_validate(t *testing.T) {
 		},
 	}
 
+	// The count of test_instance.bar can't be computed, so its changes are
+	// deferred, with a warning.
 	args := []string{}
-	if code := c.Run(args); code != 1 {
+	if code := c.Run(args); code != 0 {
 		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
 	}
 
 	actual := ui.ErrorWriter.String()
-	if !strings.Contains(actual, "cannot be computed") {
+	if !strings.Contains(actual, "Some changes were deferred") || !strings.Contains(actual, "test_instance.bar") {
 		t.Fatalf("bad: %s", actual)
 	}
 }
@@ -1,6 +1,7 @@
 package command
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,12 +12,18 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 
 	plugin "github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/terraform/backend"
+	backendinit "github.com/hashicorp/terraform/backend/init"
 	terraformProvider "github.com/hashicorp/terraform/builtin/providers/terraform"
+	"github.com/hashicorp/terraform/config/module"
 	tfplugin "github.com/hashicorp/terraform/plugin"
 	"github.com/hashicorp/terraform/plugin/discovery"
+	"github.com/hashicorp/terraform/plugin/host"
 	"github.com/hashicorp/terraform/terraform"
+	"github.com/hashicorp/terraform/version"
 	"github.com/kardianos/osext"
 )
 
@@ -33,6 +40,12 @@ type multiVersionProviderResolver struct {
 	// exceptional circumstances since it forces the provider's release
 	// schedule to be tied to that of Terraform Core.
 	Internal map[string]terraform.ResourceProviderFactory
+
+	// Host, if set, is the provider host that keeps the plugins of the
+	// providers running between commands.
+	Host *host.Client
+
+	fingerprints map[string]*terraform.ProviderFingerprint
 }
 
 func choosePlugins(avail discovery.PluginMetaSet, internal map[string]terraform.ResourceProviderFactory, reqd discovery.PluginRequirements) map[string]discovery.PluginMeta {
@@ -57,6 +70,7 @@ func (r *multiVersionProviderResolver) ResolveProviders(
 	reqd discovery.PluginRequirements,
 ) (map[string]terraform.ResourceProviderFactory, []error) {
 	factories := make(map[string]terraform.ResourceProviderFactory, len(reqd))
+	r.fingerprints = make(map[string]*terraform.ProviderFingerprint, len(reqd))
 	var errs []error
 
 	chosen := choosePlugins(r.Available, r.Internal, reqd)
@@ -67,6 +81,9 @@ func (r *multiVersionProviderResolver) ResolveProviders(
 				continue
 			}
 			factories[name] = factory
+			r.fingerprints[name] = &terraform.ProviderFingerprint{
+				Version: version.String(),
+			}
 			continue
 		}
 
@@ -80,6 +97,19 @@ func (r *multiVersionProviderResolver) ResolveProviders(
 				errs = append(errs, fmt.Errorf("provider.%s: new or changed plugin executable", name))
 				continue
 			}
+			r.fingerprints[name] = &terraform.ProviderFingerprint{
+				Version: string(newest.Version),
+				SHA256:  hex.EncodeToString(digest),
+			}
+
+			if r.Host != nil {
+				client, err := r.Host.Acquire(newest)
+				if err == nil {
+					factories[name] = hostedProviderFactory(client, r.Host, digest)
+					continue
+				}
+				log.Printf("[WARN] provider.%s: failed to use provider host, so starting the plugin: %s", name, err)
+			}
 
 			client := tfplugin.Client(newest)
 			factories[name] = providerFactory(client)
@@ -111,6 +141,11 @@ func (r *multiVersionProviderResolver) ResolveProviders(
 	return factories, errs
 }
 
+// ProviderFingerprints implements terraform.ResourceProviderFingerprinter.
+func (r *multiVersionProviderResolver) ProviderFingerprints() map[string]*terraform.ProviderFingerprint {
+	return r.fingerprints
+}
+
 // store the user-supplied path for plugin discovery
 func (m *Meta) storePluginPath(pluginPath []string) error {
 	if len(pluginPath) == 0 {
@@ -264,6 +299,7 @@ func (m *Meta) providerResolver() terraform.ResourceProviderResolver {
 	return &multiVersionProviderResolver{
 		Available: m.providerPluginSet(),
 		Internal:  m.internalProviders(),
+		Host:      m.providerHost(),
 	}
 }
 
@@ -331,6 +367,46 @@ func (m *Meta) provisionerFactories() map[string]terraform.ResourceProvisionerFa
 	return factories
 }
 
+// backendFactory returns the initialization factory for the named backend,
+// or nil if there is no such backend. Backends built in to Terraform take
+// precedence over any backend plugin of the same name.
+func (m *Meta) backendFactory(name string) (func() backend.Backend, error) {
+	if f := backendinit.Backend(name); f != nil {
+		return f, nil
+	}
+
+	plugins := discovery.FindPlugins("backend", m.pluginDirs(true)).WithName(name)
+	plugins, _ = plugins.ValidateVersions()
+	if plugins.Count() == 0 {
+		return nil, nil
+	}
+
+	// As with provisioners, we use the newest version of the plugin that
+	// we find, since backends have no version constraints.
+	newest := plugins.Newest()
+	log.Printf("[DEBUG] using backend plugin %q, %q, %q", newest.Name, newest.Version, newest.Path)
+	return backendPluginFactory(tfplugin.Client(newest))
+}
+
+// moduleSourceResolvers returns the factories of the resolvers of module
+// sources served by plugins, keyed by the scheme of the sources that they
+// resolve, which is the name of the plugin. Each plugin is only started the
+// first time that its factory is called.
+func (m *Meta) moduleSourceResolvers() map[string]module.SourceResolverFactory {
+	plugins := discovery.FindPlugins("modsource", m.pluginDirs(true))
+	plugins, _ = plugins.ValidateVersions()
+
+	factories := make(map[string]module.SourceResolverFactory)
+	for name, metas := range plugins.ByName() {
+		// As with provisioners, we use the newest version of the plugin that
+		// we find, since module sources have no version constraints.
+		newest := metas.Newest()
+		factories[name] = moduleSourceFactory(newest)
+	}
+
+	return factories
+}
+
 func internalPluginClient(kind, name string) (*plugin.Client, error) {
 	cmdLine, err := BuildPluginCommandString(kind, name)
 	if err != nil {
@@ -369,6 +445,23 @@ func providerFactory(client *plugin.Client) terraform.ResourceProviderFactory {
 	}
 }
 
+// hostedProviderFactory is like providerFactory, for the client of a plugin
+// process that's kept running by the given provider host.
+func hostedProviderFactory(client *plugin.Client, h *host.Client, sum []byte) terraform.ResourceProviderFactory {
+	factory := providerFactory(client)
+	return func() (terraform.ResourceProvider, error) {
+		p, err := factory()
+		if err != nil {
+			return nil, err
+		}
+		return &hostedResourceProvider{
+			ResourceProvider: p,
+			Host:             h,
+			SHA256:           sum,
+		}, nil
+	}
+}
+
 func provisionerFactory(client *plugin.Client) terraform.ResourceProvisionerFactory {
 	return func() (terraform.ResourceProvisioner, error) {
 		// Request the RPC client so we can get the provisioner
@@ -386,3 +479,46 @@ func provisionerFactory(client *plugin.Client) terraform.ResourceProvisionerFact
 		return raw.(terraform.ResourceProvisioner), nil
 	}
 }
+
+func backendPluginFactory(client *plugin.Client) (func() backend.Backend, error) {
+	// Request the RPC client so we can get the backend so we can build
+	// the actual RPC-implemented backend. We do this up front so that any
+	// error starting the plugin can be reported.
+	rpcClient, err := client.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := rpcClient.Dispense(tfplugin.BackendPluginName)
+	if err != nil {
+		return nil, err
+	}
+
+	b := raw.(backend.Backend)
+	return func() backend.Backend { return b }, nil
+}
+
+func moduleSourceFactory(meta discovery.PluginMeta) module.SourceResolverFactory {
+	var once sync.Once
+	var r module.SourceResolver
+	var err error
+	return func() (module.SourceResolver, error) {
+		once.Do(func() {
+			log.Printf("[DEBUG] using module source plugin %q, %q, %q", meta.Name, meta.Version, meta.Path)
+
+			var rpcClient plugin.ClientProtocol
+			rpcClient, err = tfplugin.Client(meta).Client()
+			if err != nil {
+				return
+			}
+
+			var raw interface{}
+			raw, err = rpcClient.Dispense(tfplugin.ModuleSourcePluginName)
+			if err != nil {
+				return
+			}
+			r = raw.(module.SourceResolver)
+		})
+		return r, err
+	}
+}
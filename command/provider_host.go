@@ -0,0 +1,241 @@
+package command
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/wrappedstreams"
+	"github.com/hashicorp/terraform/plugin/host"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/kardianos/osext"
+	"github.com/mitchellh/panicwrap"
+)
+
+// providerHostStartTimeout is how long a command waits for a provider host
+// that it started to begin listening.
+const providerHostStartTimeout = 5 * time.Second
+
+// ProviderHostCommand is a Command implementation that runs the provider
+// host of the working directory, which keeps provider plugins running
+// between commands. It's started by other commands when the provider host
+// is enabled in the CLI configuration.
+type ProviderHostCommand struct {
+	Meta
+}
+
+func (c *ProviderHostCommand) Run(args []string) int {
+	var idleTimeout time.Duration
+	cmdFlags := c.Meta.flagSet("provider-host")
+	cmdFlags.DurationVar(&idleTimeout, "idle-timeout", host.DefaultIdleTimeout, "idle-timeout")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+
+	log.SetPrefix("provider-host ")
+
+	// The host is started in the background by another command, and
+	// inherits that command's standard streams if it's wrapped by
+	// panicwrap. They're closed, so that the command's output ends when it
+	// exits rather than when the host stops.
+	if panicwrap.Wrapped(nil) {
+		wrappedstreams.Stdin().Close()
+		wrappedstreams.Stdout().Close()
+		wrappedstreams.Stderr().Close()
+	}
+
+	if err := os.MkdirAll(c.DataDir(), 0755); err != nil {
+		log.Printf("[ERROR] Failed to create data directory: %s", err)
+		return 1
+	}
+	l, err := host.Listen(host.SocketPath(c.DataDir()))
+	if err == host.ErrRunning {
+		// Another command started a host at the same time.
+		log.Printf("[INFO] %s", err)
+		return 0
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to listen: %s", err)
+		return 1
+	}
+
+	// The host outlives the command that started it, so it ignores the
+	// signals sent to that command's process group when it's interrupted
+	// or its terminal is closed.
+	signal.Ignore(os.Interrupt, syscall.SIGHUP)
+
+	server := host.NewServer(idleTimeout)
+	go func() {
+		<-c.ShutdownCh
+		log.Printf("[INFO] Stopping provider host")
+		server.Stop()
+	}()
+
+	log.Printf("[INFO] Starting provider host with idle timeout %s", idleTimeout)
+	if err := server.Serve(l); err != nil {
+		log.Printf("[ERROR] %s", err)
+		return 1
+	}
+	return 0
+}
+
+func (c *ProviderHostCommand) Help() string {
+	helpText := `
+Usage: terraform provider-host [options]
+
+  Runs the provider host of the working directory, which keeps provider
+  plugins running between commands. It's started automatically when the
+  provider_host setting is enabled in the CLI configuration, and so
+  shouldn't be run directly.
+
+Options:
+
+  -idle-timeout=10m   How long to keep running once no commands are using
+                      the provider host.
+
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ProviderHostCommand) Synopsis() string {
+	return "Runs the provider host of the working directory"
+}
+
+// providerHost returns a connection to the provider host of the working
+// directory, starting the host if it isn't already running, or nil if the
+// provider host isn't enabled or can't be used.
+func (m *Meta) providerHost() *host.Client {
+	if !m.ProviderHost {
+		return nil
+	}
+	if m.providerHostClient != nil {
+		return m.providerHostClient
+	}
+
+	socket := host.SocketPath(m.DataDir())
+	client, err := host.Dial(socket)
+	if err != nil {
+		if err := m.startProviderHost(); err != nil {
+			log.Printf("[WARN] Failed to start provider host, so providers are started by this command: %s", err)
+			return nil
+		}
+
+		deadline := time.Now().Add(providerHostStartTimeout)
+		for {
+			client, err = host.Dial(socket)
+			if err == nil || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		if err != nil {
+			log.Printf("[WARN] Failed to connect to provider host, so providers are started by this command: %s", err)
+			return nil
+		}
+	}
+
+	m.providerHostClient = client
+	return client
+}
+
+// startProviderHost starts the provider host of the working directory in
+// the background, where it keeps running after this command exits.
+func (m *Meta) startProviderHost() error {
+	exe, err := osext.Executable()
+	if err != nil {
+		return err
+	}
+
+	args := []string{"provider-host"}
+	if m.ProviderHostIdleTimeout > 0 {
+		args = append(args, fmt.Sprintf("-idle-timeout=%s", m.ProviderHostIdleTimeout))
+	}
+	cmd := exec.Command(exe, args...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Started provider host with pid %d", cmd.Process.Pid)
+	return cmd.Process.Release()
+}
+
+// hostedResourceProvider is a ResourceProvider whose plugin process is kept
+// running by the provider host. The host caches its schema, so that it's
+// only read from the provider once.
+type hostedResourceProvider struct {
+	terraform.ResourceProvider
+
+	Host *host.Client
+
+	// SHA256 is the checksum of the provider's plugin, which identifies its
+	// schema.
+	SHA256 []byte
+}
+
+func (p *hostedResourceProvider) GetSchema(req *terraform.ProviderSchemaRequest) (*terraform.ProviderSchema, error) {
+	key := strings.Join([]string{
+		hex.EncodeToString(p.SHA256),
+		strings.Join(req.ResourceTypes, ","),
+		strings.Join(req.DataSources, ","),
+	}, "\x00")
+
+	schema, err := p.Host.Schema(key)
+	if err != nil {
+		log.Printf("[WARN] Failed to read schema from provider host: %s", err)
+	}
+	if schema != nil {
+		return schema, nil
+	}
+
+	schema, err = p.ResourceProvider.GetSchema(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Host.PutSchema(key, schema); err != nil {
+		log.Printf("[WARN] Failed to cache schema in provider host: %s", err)
+	}
+	return schema, nil
+}
+
+func (p *hostedResourceProvider) ApplyContext(
+	ctx context.Context,
+	info *terraform.InstanceInfo,
+	s *terraform.InstanceState,
+	d *terraform.InstanceDiff) (*terraform.InstanceState, error) {
+	return terraform.ApplyWithContext(ctx, p.ResourceProvider, info, s, d)
+}
+
+func (p *hostedResourceProvider) Close() error {
+	if c, ok := p.ResourceProvider.(terraform.ResourceProviderCloser); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (p *hostedResourceProvider) MoveResourceState(req *terraform.MoveResourceStateRequest) (*terraform.InstanceState, error) {
+	if m, ok := p.ResourceProvider.(terraform.ResourceProviderStateMover); ok {
+		return m.MoveResourceState(req)
+	}
+	return nil, fmt.Errorf("provider doesn't support moving state from other resource types")
+}
+
+func (p *hostedResourceProvider) RefreshBatch(
+	infos []*terraform.InstanceInfo,
+	states []*terraform.InstanceState) ([]*terraform.InstanceState, error) {
+	if b, ok := p.ResourceProvider.(terraform.ResourceProviderBatchRefresher); ok {
+		return b.RefreshBatch(infos, states)
+	}
+	return nil, fmt.Errorf("provider doesn't support refreshing instances in batches")
+}
+
+func (p *hostedResourceProvider) Capabilities() ([]terraform.ProviderCapability, error) {
+	return terraform.ProviderCapabilitiesOf(p.ResourceProvider)
+}
@@ -0,0 +1,74 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform/config/configschema"
+	"github.com/hashicorp/terraform/plugin/host"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestHostedResourceProvider_schema(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := host.Listen(host.SocketPath(dir))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer l.Close()
+	go host.NewServer(time.Hour).Serve(l)
+
+	client, err := host.Dial(host.SocketPath(dir))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer client.Close()
+
+	req := &terraform.ProviderSchemaRequest{
+		ResourceTypes: []string{"test_instance"},
+	}
+	schema := &terraform.ProviderSchema{
+		Provider: &configschema.Block{},
+		ResourceTypes: map[string]*configschema.Block{
+			"test_instance": &configschema.Block{},
+		},
+	}
+
+	first := testProvider()
+	first.GetSchemaReturn = schema
+	p := &hostedResourceProvider{ResourceProvider: first, Host: client, SHA256: []byte{1}}
+	if got, err := p.GetSchema(req); err != nil || got != schema {
+		t.Fatalf("wrong result %#v, %v", got, err)
+	}
+
+	// Another command's instance of the same provider reads the cached
+	// schema from the host.
+	second := testProvider()
+	p = &hostedResourceProvider{ResourceProvider: second, Host: client, SHA256: []byte{1}}
+	got, err := p.GetSchema(req)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if second.GetSchemaCalled {
+		t.Fatal("schema was read from the provider again")
+	}
+	if got == nil || got.ResourceTypes["test_instance"] == nil {
+		t.Fatalf("wrong schema %#v", got)
+	}
+
+	// A different version of the provider has its own schema.
+	p = &hostedResourceProvider{ResourceProvider: second, Host: client, SHA256: []byte{2}}
+	if _, err := p.GetSchema(req); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !second.GetSchemaCalled {
+		t.Fatal("schema of another version wasn't read from the provider")
+	}
+}
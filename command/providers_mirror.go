@@ -0,0 +1,608 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/plugin/discovery"
+	"github.com/posener/complete"
+)
+
+// The statuses of the plugins in a provider mirror, as reported by the
+// "terraform providers mirror" commands.
+const (
+	// The mirror has the plugin with the locked digest.
+	mirrorStatusPresent = "present"
+
+	// The mirror doesn't have the plugin.
+	mirrorStatusMissing = "missing"
+
+	// The mirror has a plugin of the same name and version, but it doesn't
+	// have the locked digest.
+	mirrorStatusMismatch = "mismatch"
+
+	// The plugin was copied into the mirror, or would be with -dry-run.
+	mirrorStatusAdded = "added"
+
+	// A plugin with the wrong digest was replaced in the mirror, or would be
+	// with -dry-run.
+	mirrorStatusReplaced = "replaced"
+
+	// The plugin isn't in the mirror and there is no installed plugin with
+	// the locked digest to copy into it.
+	mirrorStatusUnavailable = "unavailable"
+
+	// The plugin isn't locked, and was removed from the mirror, or would
+	// be with -dry-run.
+	mirrorStatusPruned = "pruned"
+)
+
+// providerMirror is a directory of provider plugins laid out like the
+// plugin cache, with a subdirectory for each OS and architecture, so that
+// it can be used as the plugin_cache_dir of the CLI configuration, or one of
+// its subdirectories can be given to "terraform init" with -plugin-dir.
+type providerMirror struct {
+	Dir string
+}
+
+// PlatformDir returns the directory of the mirror for the plugins of the
+// current OS and architecture, which are the ones that the plugin lock
+// applies to.
+func (m *providerMirror) PlatformDir() string {
+	return filepath.Join(m.Dir, pluginMachineName)
+}
+
+// providerMirrorEntry is a provider plugin in the report of the
+// "terraform providers mirror" commands.
+type providerMirrorEntry struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	File    string `json:"file"`
+	SHA256  string `json:"sha256,omitempty"`
+	Status  string `json:"status"`
+
+	// installed is the path of the installed plugin with the locked digest,
+	// if any.
+	installed string
+}
+
+// providerMirrorReport is the machine-readable summary of the "terraform
+// providers mirror" commands with -json.
+type providerMirrorReport struct {
+	Mirror    string                 `json:"mirror"`
+	Platform  string                 `json:"platform"`
+	DryRun    bool                   `json:"dry_run"`
+	Providers []*providerMirrorEntry `json:"providers"`
+	Counts    map[string]int         `json:"counts"`
+}
+
+// lockedProviderPlugins returns an entry for each provider plugin of the
+// plugin lock of the working directory, sorted by name. The version and
+// the file name come from the installed plugin with the locked digest.
+func (m *Meta) lockedProviderPlugins() ([]*providerMirrorEntry, error) {
+	digests := m.providerPluginsLock().Read()
+	if len(digests) == 0 {
+		return nil, fmt.Errorf("no provider plugins are locked; run \"terraform init\" first")
+	}
+
+	available := m.providerPluginSet()
+	entries := make([]*providerMirrorEntry, 0, len(digests))
+	for name, digest := range digests {
+		e := &providerMirrorEntry{
+			Name:   name,
+			SHA256: fmt.Sprintf("%x", digest),
+		}
+		for meta := range available.WithName(name) {
+			sum, err := meta.SHA256()
+			if err != nil || !bytes.Equal(sum, digest) {
+				continue
+			}
+			e.Version = string(meta.Version)
+			e.File = filepath.Base(meta.Path)
+			e.installed = meta.Path
+			break
+		}
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// check sets the status of each of the given locked plugins in the mirror.
+// A plugin that isn't installed locally is found in the mirror by its
+// digest.
+func (m *providerMirror) check(entries []*providerMirrorEntry) error {
+	mirrored := discovery.FindPlugins("provider", []string{m.PlatformDir()})
+	for _, e := range entries {
+		e.Status = mirrorStatusMissing
+		for meta := range mirrored.WithName(e.Name) {
+			// Plugins with other versions are someone else's concern, but a
+			// plugin with the locked name and version has to match.
+			if e.File != "" && filepath.Base(meta.Path) != e.File {
+				continue
+			}
+
+			sum, err := meta.SHA256()
+			if err != nil {
+				return err
+			}
+			if fmt.Sprintf("%x", sum) == e.SHA256 {
+				e.Status = mirrorStatusPresent
+				e.File = filepath.Base(meta.Path)
+				e.Version = string(meta.Version)
+				break
+			}
+			if e.File != "" {
+				e.Status = mirrorStatusMismatch
+			}
+		}
+		if e.File == "" {
+			e.File = "terraform-provider-" + e.Name
+		}
+	}
+	return nil
+}
+
+// unlocked returns an entry for each plugin in the mirror for the current
+// platform that isn't one of the given locked plugins with its locked
+// digest, sorted by file name.
+func (m *providerMirror) unlocked(entries []*providerMirrorEntry) ([]*providerMirrorEntry, error) {
+	locked := make(map[string]string)
+	for _, e := range entries {
+		locked[e.File] = e.SHA256
+	}
+
+	var result []*providerMirrorEntry
+	for meta := range discovery.FindPlugins("provider", []string{m.PlatformDir()}) {
+		file := filepath.Base(meta.Path)
+		sum, err := meta.SHA256()
+		if err != nil {
+			return nil, err
+		}
+		if digest, ok := locked[file]; ok && digest == fmt.Sprintf("%x", sum) {
+			continue
+		}
+		result = append(result, &providerMirrorEntry{
+			Name:    meta.Name,
+			Version: string(meta.Version),
+			File:    file,
+			Status:  mirrorStatusPruned,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].File < result[j].File
+	})
+	return result, nil
+}
+
+// copyInstalled copies the installed plugin of the given entry into the
+// mirror, replacing any plugin of the same name.
+func (m *providerMirror) copyInstalled(e *providerMirrorEntry) error {
+	if err := os.MkdirAll(m.PlatformDir(), 0755); err != nil {
+		return err
+	}
+
+	src, err := os.Open(e.installed)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// We copy into a temporary file first, so that a failed copy can't leave
+	// a partial plugin in the mirror.
+	tmp, err := ioutil.TempFile(m.PlatformDir(), ".tmp-"+e.File)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+
+	target := filepath.Join(m.PlatformDir(), e.File)
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return err
+	}
+
+	// Make sure that what landed in the mirror is what was locked.
+	sum, err := discovery.PluginMeta{Path: target}.SHA256()
+	if err != nil {
+		return err
+	}
+	if fmt.Sprintf("%x", sum) != e.SHA256 {
+		return fmt.Errorf("copied plugin %s doesn't match the locked digest", e.File)
+	}
+	return nil
+}
+
+// outputMirrorReport shows the given report, either as JSON or as a line
+// for each plugin along with a summary.
+func (m *Meta) outputMirrorReport(r *providerMirrorReport, jsonOutput bool) error {
+	r.Platform = pluginMachineName
+	r.Counts = make(map[string]int)
+	for _, e := range r.Providers {
+		r.Counts[e.Status]++
+	}
+
+	if jsonOutput {
+		js, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		m.Ui.Output(string(js))
+		return nil
+	}
+
+	for _, e := range r.Providers {
+		version := e.Version
+		if version == "" {
+			version = "unknown version"
+		}
+		m.Ui.Output(fmt.Sprintf("%-12s %s (%s)", e.Status, e.Name, version))
+	}
+
+	statuses := make([]string, 0, len(r.Counts))
+	for status := range r.Counts {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	counts := make([]string, len(statuses))
+	for i, status := range statuses {
+		counts[i] = fmt.Sprintf("%d %s", r.Counts[status], status)
+	}
+
+	summary := strings.Join(counts, ", ")
+	if len(counts) == 0 {
+		summary = "nothing to report"
+	}
+	if r.DryRun {
+		summary += " (dry run)"
+	}
+	m.Ui.Output(fmt.Sprintf("\nMirror %s: %s.", r.Mirror, summary))
+	return nil
+}
+
+// ProvidersMirrorCommand is a Command implementation that copies the
+// provider plugins of the plugin lock into a mirror directory, copying only
+// the plugins that the mirror doesn't already have.
+type ProvidersMirrorCommand struct {
+	Meta
+}
+
+func (c *ProvidersMirrorCommand) Run(args []string) int {
+	args, err := c.Meta.process(args, false)
+	if err != nil {
+		return 1
+	}
+
+	var dryRun, jsonOutput bool
+	cmdFlags := c.Meta.flagSet("providers mirror")
+	cmdFlags.BoolVar(&dryRun, "dry-run", false, "dry-run")
+	cmdFlags.BoolVar(&jsonOutput, "json", false, "json")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+
+	mirror, ok := c.mirrorArg(cmdFlags.Args())
+	if !ok {
+		return 1
+	}
+
+	entries, err := c.lockedProviderPlugins()
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	if err := mirror.check(entries); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading mirror: %s", err))
+		return 1
+	}
+
+	failed := false
+	for _, e := range entries {
+		if e.Status == mirrorStatusPresent {
+			continue
+		}
+		if e.installed == "" {
+			e.Status = mirrorStatusUnavailable
+			failed = true
+			continue
+		}
+
+		if e.Status == mirrorStatusMismatch {
+			e.Status = mirrorStatusReplaced
+		} else {
+			e.Status = mirrorStatusAdded
+		}
+		if dryRun {
+			continue
+		}
+		if err := mirror.copyInstalled(e); err != nil {
+			c.Ui.Error(fmt.Sprintf("Error copying %s into the mirror: %s", e.File, err))
+			return 1
+		}
+	}
+
+	report := &providerMirrorReport{
+		Mirror:    mirror.Dir,
+		DryRun:    dryRun,
+		Providers: entries,
+	}
+	if err := c.outputMirrorReport(report, jsonOutput); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error encoding report: %s", err))
+		return 1
+	}
+
+	if failed {
+		c.Ui.Error(strings.TrimSpace(errProvidersMirrorUnavailable))
+		return 1
+	}
+	return 0
+}
+
+func (c *ProvidersMirrorCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictDirs("")
+}
+
+func (c *ProvidersMirrorCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-dry-run": complete.PredictNothing,
+		"-json":    complete.PredictNothing,
+	}
+}
+
+func (c *ProvidersMirrorCommand) Help() string {
+	helpText := `
+Usage: terraform providers mirror [options] DIR
+
+  Copies the provider plugins locked by "terraform init" in the current
+  working directory into the mirror directory DIR, so that they can be
+  installed from there in environments without access to the Internet.
+
+  Only the plugins that the mirror doesn't already have with their locked
+  digests are copied, so the mirror can be kept in sync by running this
+  command again after each "terraform init -upgrade". The plugins are
+  placed in a subdirectory for the current OS and architecture, such as
+  DIR/linux_amd64, as in the plugin cache.
+
+  See also "terraform providers mirror verify" and "terraform providers
+  mirror prune".
+
+Options:
+
+  -dry-run            Report which plugins would be copied, without copying
+                      them.
+
+  -json               Report the status of each plugin as a JSON object.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ProvidersMirrorCommand) Synopsis() string {
+	return "Copies the locked provider plugins into a mirror directory"
+}
+
+// ProvidersMirrorVerifyCommand is a Command implementation that checks
+// that a mirror directory has the provider plugins of the plugin lock.
+type ProvidersMirrorVerifyCommand struct {
+	Meta
+}
+
+func (c *ProvidersMirrorVerifyCommand) Run(args []string) int {
+	args, err := c.Meta.process(args, false)
+	if err != nil {
+		return 1
+	}
+
+	var jsonOutput bool
+	cmdFlags := c.Meta.flagSet("providers mirror verify")
+	cmdFlags.BoolVar(&jsonOutput, "json", false, "json")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+
+	mirror, ok := c.mirrorArg(cmdFlags.Args())
+	if !ok {
+		return 1
+	}
+
+	entries, err := c.lockedProviderPlugins()
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	if err := mirror.check(entries); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading mirror: %s", err))
+		return 1
+	}
+
+	report := &providerMirrorReport{
+		Mirror:    mirror.Dir,
+		Providers: entries,
+	}
+	if err := c.outputMirrorReport(report, jsonOutput); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error encoding report: %s", err))
+		return 1
+	}
+
+	for _, e := range entries {
+		if e.Status != mirrorStatusPresent {
+			return 1
+		}
+	}
+	return 0
+}
+
+func (c *ProvidersMirrorVerifyCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictDirs("")
+}
+
+func (c *ProvidersMirrorVerifyCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-json": complete.PredictNothing,
+	}
+}
+
+func (c *ProvidersMirrorVerifyCommand) Help() string {
+	helpText := `
+Usage: terraform providers mirror verify [options] DIR
+
+  Checks that the mirror directory DIR has each of the provider plugins
+  locked by "terraform init" in the current working directory, with its
+  locked digest. Exits with status 1 if any plugin is missing or has a
+  different digest.
+
+Options:
+
+  -json               Report the status of each plugin as a JSON object.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ProvidersMirrorVerifyCommand) Synopsis() string {
+	return "Checks that a mirror directory has the locked provider plugins"
+}
+
+// ProvidersMirrorPruneCommand is a Command implementation that removes the
+// provider plugins from a mirror directory that the plugin lock doesn't
+// refer to.
+type ProvidersMirrorPruneCommand struct {
+	Meta
+}
+
+func (c *ProvidersMirrorPruneCommand) Run(args []string) int {
+	args, err := c.Meta.process(args, false)
+	if err != nil {
+		return 1
+	}
+
+	var dryRun, jsonOutput bool
+	cmdFlags := c.Meta.flagSet("providers mirror prune")
+	cmdFlags.BoolVar(&dryRun, "dry-run", false, "dry-run")
+	cmdFlags.BoolVar(&jsonOutput, "json", false, "json")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+
+	mirror, ok := c.mirrorArg(cmdFlags.Args())
+	if !ok {
+		return 1
+	}
+
+	entries, err := c.lockedProviderPlugins()
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	if err := mirror.check(entries); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading mirror: %s", err))
+		return 1
+	}
+	pruned, err := mirror.unlocked(entries)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading mirror: %s", err))
+		return 1
+	}
+
+	if !dryRun {
+		for _, e := range pruned {
+			if err := os.Remove(filepath.Join(mirror.PlatformDir(), e.File)); err != nil {
+				c.Ui.Error(fmt.Sprintf("Error removing %s from the mirror: %s", e.File, err))
+				return 1
+			}
+		}
+	}
+
+	report := &providerMirrorReport{
+		Mirror:    mirror.Dir,
+		DryRun:    dryRun,
+		Providers: pruned,
+	}
+	if err := c.outputMirrorReport(report, jsonOutput); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error encoding report: %s", err))
+		return 1
+	}
+	return 0
+}
+
+func (c *ProvidersMirrorPruneCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictDirs("")
+}
+
+func (c *ProvidersMirrorPruneCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-dry-run": complete.PredictNothing,
+		"-json":    complete.PredictNothing,
+	}
+}
+
+func (c *ProvidersMirrorPruneCommand) Help() string {
+	helpText := `
+Usage: terraform providers mirror prune [options] DIR
+
+  Removes the provider plugins for the current OS and architecture from the
+  mirror directory DIR that aren't locked by "terraform init" in the current
+  working directory, including plugins of the locked versions with other
+  digests. Plugins for other platforms are left alone.
+
+  A mirror shared by several configurations should only be pruned from the
+  working directory of a configuration that locks all of its plugins.
+
+Options:
+
+  -dry-run            Report which plugins would be removed, without
+                      removing them.
+
+  -json               Report the removed plugins as a JSON object.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ProvidersMirrorPruneCommand) Synopsis() string {
+	return "Removes unlocked provider plugins from a mirror directory"
+}
+
+// mirrorArg returns the mirror of the single DIR argument of the provider
+// mirror commands, reporting an error if there isn't exactly one.
+func (m *Meta) mirrorArg(args []string) (*providerMirror, bool) {
+	if len(args) != 1 {
+		m.Ui.Error("The mirror directory must be given as the only argument.")
+		return nil, false
+	}
+	dir, err := filepath.Abs(args[0])
+	if err != nil {
+		m.Ui.Error(fmt.Sprintf("Invalid mirror directory: %s", err))
+		return nil, false
+	}
+	return &providerMirror{Dir: dir}, true
+}
+
+const errProvidersMirrorUnavailable = `
+Some locked provider plugins aren't installed in this working directory, so
+they couldn't be copied into the mirror.
+
+The plugin lock only records the digests of the plugins, so the plugins must
+be installed with "terraform init" before they can be mirrored.
+`
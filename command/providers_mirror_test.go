@@ -0,0 +1,234 @@
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+// testProvidersMirrorWorkingDir sets up a working directory with an
+// installed and locked provider plugin, returning the path of the plugin.
+func testProvidersMirrorWorkingDir(t *testing.T, m *Meta) string {
+	t.Helper()
+
+	if err := os.MkdirAll(m.pluginDir(), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	path := filepath.Join(m.pluginDir(), "terraform-provider-test_v1.0.0_x4")
+	content := []byte("test plugin 1.0.0")
+	if err := ioutil.WriteFile(path, content, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	sum := sha256.Sum256(content)
+	if err := m.providerPluginsLock().Write(map[string][]byte{"test": sum[:]}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return path
+}
+
+func TestProvidersMirror(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	meta := Meta{Ui: new(cli.MockUi)}
+	testProvidersMirrorWorkingDir(t, &meta)
+	mirrorDir := filepath.Join(td, "mirror")
+	mirrored := filepath.Join(mirrorDir, pluginMachineName, "terraform-provider-test_v1.0.0_x4")
+
+	run := func(args ...string) (int, *cli.MockUi) {
+		ui := new(cli.MockUi)
+		c := &ProvidersMirrorCommand{Meta: Meta{Ui: ui}}
+		return c.Run(append(args, mirrorDir)), ui
+	}
+
+	// A dry run copies nothing
+	code, ui := run("-dry-run")
+	if code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if !strings.Contains(ui.OutputWriter.String(), "added") {
+		t.Fatalf("wrong output:\n%s", ui.OutputWriter.String())
+	}
+	if _, err := os.Stat(mirrored); !os.IsNotExist(err) {
+		t.Fatalf("plugin should not be copied: %v", err)
+	}
+
+	code, ui = run()
+	if code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	content, err := ioutil.ReadFile(mirrored)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(content) != "test plugin 1.0.0" {
+		t.Fatalf("wrong plugin content: %q", content)
+	}
+
+	// Syncing again has nothing to copy
+	code, ui = run("-json")
+	if code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	var report providerMirrorReport
+	if err := json.Unmarshal([]byte(ui.OutputWriter.String()), &report); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(report.Providers) != 1 || report.Providers[0].Status != mirrorStatusPresent || report.Providers[0].Version != "1.0.0" {
+		t.Fatalf("wrong report: %s", ui.OutputWriter.String())
+	}
+	if report.Counts[mirrorStatusPresent] != 1 {
+		t.Fatalf("wrong counts: %#v", report.Counts)
+	}
+
+	// A corrupted plugin is replaced
+	if err := ioutil.WriteFile(mirrored, []byte("corrupted"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	code, ui = run()
+	if code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if !strings.Contains(ui.OutputWriter.String(), "replaced") {
+		t.Fatalf("wrong output:\n%s", ui.OutputWriter.String())
+	}
+	content, err = ioutil.ReadFile(mirrored)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(content) != "test plugin 1.0.0" {
+		t.Fatalf("wrong plugin content: %q", content)
+	}
+}
+
+func TestProvidersMirror_unavailable(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	meta := Meta{Ui: new(cli.MockUi)}
+	path := testProvidersMirrorWorkingDir(t, &meta)
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := new(cli.MockUi)
+	c := &ProvidersMirrorCommand{Meta: Meta{Ui: ui}}
+	if code := c.Run([]string{filepath.Join(td, "mirror")}); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if !strings.Contains(ui.OutputWriter.String(), "unavailable") {
+		t.Fatalf("wrong output:\n%s", ui.OutputWriter.String())
+	}
+}
+
+func TestProvidersMirrorVerify(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	meta := Meta{Ui: new(cli.MockUi)}
+	testProvidersMirrorWorkingDir(t, &meta)
+	mirrorDir := filepath.Join(td, "mirror")
+	mirrored := filepath.Join(mirrorDir, pluginMachineName, "terraform-provider-test_v1.0.0_x4")
+
+	verify := func() (int, *providerMirrorReport) {
+		ui := new(cli.MockUi)
+		c := &ProvidersMirrorVerifyCommand{Meta: Meta{Ui: ui}}
+		code := c.Run([]string{"-json", mirrorDir})
+		var report providerMirrorReport
+		if err := json.Unmarshal([]byte(ui.OutputWriter.String()), &report); err != nil {
+			t.Fatalf("err: %s\n\n%s", err, ui.ErrorWriter.String())
+		}
+		return code, &report
+	}
+
+	code, report := verify()
+	if code != 1 || report.Providers[0].Status != mirrorStatusMissing {
+		t.Fatalf("expected the plugin to be missing, got %d %#v", code, report.Providers[0])
+	}
+
+	if err := os.MkdirAll(filepath.Dir(mirrored), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := ioutil.WriteFile(mirrored, []byte("corrupted"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	code, report = verify()
+	if code != 1 || report.Providers[0].Status != mirrorStatusMismatch {
+		t.Fatalf("expected the plugin to mismatch, got %d %#v", code, report.Providers[0])
+	}
+
+	if err := ioutil.WriteFile(mirrored, []byte("test plugin 1.0.0"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	code, report = verify()
+	if code != 0 || report.Providers[0].Status != mirrorStatusPresent {
+		t.Fatalf("expected the plugin to be present, got %d %#v", code, report.Providers[0])
+	}
+}
+
+func TestProvidersMirrorPrune(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	meta := Meta{Ui: new(cli.MockUi)}
+	testProvidersMirrorWorkingDir(t, &meta)
+	mirrorDir := filepath.Join(td, "mirror")
+	platformDir := filepath.Join(mirrorDir, pluginMachineName)
+	if err := os.MkdirAll(platformDir, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	files := map[string]string{
+		"terraform-provider-test_v1.0.0_x4": "test plugin 1.0.0",
+		"terraform-provider-test_v0.9.0_x4": "test plugin 0.9.0",
+		"terraform-provider-old_v1.0.0_x4":  "old plugin 1.0.0",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(platformDir, name), []byte(content), 0755); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// A dry run removes nothing
+	ui := new(cli.MockUi)
+	c := &ProvidersMirrorPruneCommand{Meta: Meta{Ui: ui}}
+	if code := c.Run([]string{"-dry-run", mirrorDir}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	output := ui.OutputWriter.String()
+	if !strings.Contains(output, "2 pruned (dry run)") {
+		t.Fatalf("wrong output:\n%s", output)
+	}
+	for name := range files {
+		if _, err := os.Stat(filepath.Join(platformDir, name)); err != nil {
+			t.Fatalf("%s should not be removed: %s", name, err)
+		}
+	}
+
+	ui = new(cli.MockUi)
+	c = &ProvidersMirrorPruneCommand{Meta: Meta{Ui: ui}}
+	if code := c.Run([]string{mirrorDir}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	remaining, err := ioutil.ReadDir(platformDir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(remaining) != 1 || remaining[0].Name() != "terraform-provider-test_v1.0.0_x4" {
+		t.Fatalf("wrong remaining plugins: %v", remaining)
+	}
+}
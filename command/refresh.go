@@ -26,6 +26,8 @@ func (c *RefreshCommand) Run(args []string) int {
 	cmdFlags := c.Meta.flagSet("refresh")
 	cmdFlags.StringVar(&c.Meta.statePath, "state", DefaultStateFilename, "path")
 	cmdFlags.IntVar(&c.Meta.parallelism, "parallelism", 0, "parallelism")
+	cmdFlags.IntVar(&c.Meta.refreshParallelism, "refresh-parallelism", 0, "refresh-parallelism")
+	cmdFlags.StringVar(&c.Meta.hookCommand, "hook-command", "", "path")
 	cmdFlags.StringVar(&c.Meta.stateOutPath, "state-out", "", "path")
 	cmdFlags.StringVar(&c.Meta.backupPath, "backup", "", "path")
 	cmdFlags.BoolVar(&c.Meta.stateLock, "lock", true, "lock state")
@@ -94,7 +96,7 @@ func (c *RefreshCommand) Run(args []string) int {
 	}
 
 	// Output the outputs
-	if outputs := outputsAsString(op.State, terraform.RootModulePath, nil, true); outputs != "" {
+	if outputs := outputsAsString(op.State, terraform.RootModulePath, nil, true, c.Redaction); outputs != "" {
 		c.Ui.Output(c.Colorize().Color(outputs))
 	}
 
@@ -118,6 +120,10 @@ Options:
                       modifying. Defaults to the "-state-out" path with
                       ".backup" extension. Set to "-" to disable backup.
 
+  -hook-command=path  Run the program at the given path before and after
+                      each resource is refreshed, with a JSON description of
+                      the event on its stdin.
+
   -input=true         Ask for input for variables if not directly set.
 
   -lock=true          Lock the state file when locking is supported.
@@ -126,6 +132,13 @@ Options:
 
   -no-color           If specified, output won't contain any color.
 
+  -parallelism=n      Limit the number of concurrent operations. Defaults
+                      to 10.
+
+  -refresh-parallelism=n
+                      Limit the number of concurrent operations while
+                      refreshing state. Defaults to the -parallelism value.
+
   -state=path         Path to read and save state (unless state-out
                       is specified). Defaults to "terraform.tfstate".
 
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/terraform/command/format"
+	"github.com/hashicorp/terraform/planfile"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -53,7 +54,7 @@ func (c *ShowCommand) Run(args []string) int {
 		}
 		defer f.Close()
 
-		plan, err = terraform.ReadPlan(f)
+		plan, err = planfile.ReadPlan(path)
 		if err != nil {
 			if _, err := f.Seek(0, 0); err != nil {
 				c.Ui.Error(fmt.Sprintf("Error reading file: %s", err))
@@ -111,7 +112,9 @@ func (c *ShowCommand) Run(args []string) int {
 
 	if plan != nil {
 		dispPlan := format.NewPlan(plan)
+		dispPlan.Redaction = c.Redaction
 		c.Ui.Output(dispPlan.Format(c.Colorize()))
+		c.showFingerprint(plan.Fingerprint)
 		return 0
 	}
 
@@ -119,10 +122,21 @@ func (c *ShowCommand) Run(args []string) int {
 		State:       state,
 		Color:       c.Colorize(),
 		ModuleDepth: moduleDepth,
+		Redaction:   c.Redaction,
 	}))
+	c.showFingerprint(state.Fingerprint)
 	return 0
 }
 
+// showFingerprint outputs the given fingerprint of the run that produced
+// the plan or state being shown, if it was recorded.
+func (c *ShowCommand) showFingerprint(fp *terraform.Fingerprint) {
+	if fp == nil {
+		return
+	}
+	c.Ui.Output("\n" + format.Fingerprint(fp, c.Colorize()))
+}
+
 func (c *ShowCommand) Help() string {
 	helpText := `
 Usage: terraform show [options] [path]
@@ -130,6 +144,10 @@ Usage: terraform show [options] [path]
   Reads and outputs a Terraform state or plan file in a human-readable
   form. If no path is specified, the current state will be shown.
 
+  The fingerprint of the run that created the plan or last wrote the state
+  is shown after it: the versions of Terraform and of the providers, the
+  platform, and the hashes of the values of the variables.
+
 Options:
 
   -module-depth=n     Specifies the depth of modules to show in the output.
@@ -174,3 +174,44 @@ func TestShow_state(t *testing.T) {
 		t.Fatalf("bad: \n%s", ui.ErrorWriter.String())
 	}
 }
+
+func TestShow_fingerprint(t *testing.T) {
+	originalState := testState()
+	originalState.Fingerprint = &terraform.Fingerprint{
+		TerraformVersion: "0.11.0",
+		Platform:         "linux_amd64",
+		Providers: map[string]*terraform.ProviderFingerprint{
+			"test": {Version: "1.0.0", SHA256: "abc"},
+		},
+		Variables: map[string]string{"region": "def"},
+	}
+	statePath := testStateFile(t, originalState)
+
+	ui := new(cli.MockUi)
+	c := &ShowCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-no-color",
+		statePath,
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: \n%s", ui.ErrorWriter.String())
+	}
+
+	actual := ui.OutputWriter.String()
+	for _, expected := range []string{
+		"Terraform version: 0.11.0",
+		"Platform:          linux_amd64",
+		"test  1.0.0 sha256:abc",
+		"region  sha256:def",
+	} {
+		if !strings.Contains(actual, expected) {
+			t.Fatalf("expected:\n%s\n\nto include: %q", actual, expected)
+		}
+	}
+}
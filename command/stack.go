@@ -0,0 +1,357 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform/stacks"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/kardianos/osext"
+	"github.com/mitchellh/cli"
+	"github.com/mitchellh/panicwrap"
+)
+
+// DefaultStackParallelism is the default number of components of a stack
+// whose operations run at once.
+const DefaultStackParallelism = 2
+
+// StackCommand is a Command implementation that only shows the help of the
+// stack subcommands.
+type StackCommand struct {
+	Meta
+}
+
+func (c *StackCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+func (c *StackCommand) Help() string {
+	helpText := `
+Usage: terraform stack <subcommand> [options]
+
+  This command has subcommands for plans and applies of stacks.
+
+  A stack is a manifest of components, each of which is a root module
+  with a workspace. The inputs of a component can be set from the outputs
+  of other components, and the components are planned or applied in the
+  order of their dependencies, with the components that don't depend on
+  each other run in parallel.
+
+  The manifest is "terraform.tfstack.hcl" in the current directory unless
+  -manifest is given. The root module of each component must have been
+  initialized with "terraform init", and its workspace must exist.
+
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *StackCommand) Synopsis() string {
+	return "Plans and applies stacks of root modules"
+}
+
+// StackMeta is the meta of the stack subcommands.
+type StackMeta struct {
+	Meta
+
+	// executor runs the operations of the components. If it's nil, they
+	// are run by this executable in separate processes.
+	executor stackPlanExecutor
+}
+
+// stackPlanExecutor is a stacks.Executor that can also save the plan of a
+// component to a file, and apply a saved plan.
+type stackPlanExecutor interface {
+	stacks.Executor
+
+	// Plan is like Run with stacks.OperationPlan, except that the plan is
+	// saved to the file at the given path.
+	Plan(c *stacks.Component, vars map[string]interface{}, path string) (*stacks.ExecResult, error)
+
+	// ApplyPlan applies the plan of the given component saved at the given
+	// path.
+	ApplyPlan(c *stacks.Component, path string) (*stacks.ExecResult, error)
+}
+
+// stackFlags are the flags shared by the stack subcommands.
+type stackFlags struct {
+	manifest    string
+	parallelism int
+	json        bool
+}
+
+func (c *StackMeta) flagSet(f *stackFlags, name string) *flag.FlagSet {
+	cmdFlags := c.Meta.flagSet(name)
+	cmdFlags.StringVar(&f.manifest, "manifest", stacks.ManifestFilename, "path")
+	cmdFlags.IntVar(&f.parallelism, "parallelism", DefaultStackParallelism, "parallelism")
+	cmdFlags.BoolVar(&f.json, "json", false, "json")
+	return cmdFlags
+}
+
+// runner returns the runner of the given flags' manifest, whose executor
+// writes the results of the components as they're done unless the output
+// is JSON.
+func (c *StackMeta) runner(f *stackFlags) (*stacks.Runner, error) {
+	if f.parallelism < 1 {
+		return nil, fmt.Errorf("The parallelism must be at least 1.")
+	}
+
+	m, err := stacks.LoadManifest(f.manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	e := c.executor
+	if e == nil {
+		exe, err := osext.Executable()
+		if err != nil {
+			return nil, fmt.Errorf("Error finding the Terraform executable: %s", err)
+		}
+		e = &stackExecutor{exe: exe, color: c.Meta.color && !f.json}
+	}
+
+	r := &stacks.Runner{
+		Manifest:    m,
+		Executor:    e,
+		Parallelism: f.parallelism,
+	}
+	if !f.json {
+		r.Progress = c.outputComponentResult
+	}
+	return r, nil
+}
+
+func (c *StackMeta) outputComponentResult(cr *stacks.ComponentResult) {
+	status := stackStatusColor(cr.Status)
+	if cr.Status == stacks.StatusOK && cr.Changes {
+		status += ", with changes"
+	}
+	c.Ui.Output(c.Colorize().Color(fmt.Sprintf(
+		"component %q (workspace %q)... %s", cr.Name, cr.Workspace, status)))
+
+	if cr.Log != "" {
+		c.Ui.Output(indentString(cr.Log, "  "))
+	}
+	if cr.Reason != "" {
+		c.Ui.Output(fmt.Sprintf("  Skipped, since %s.", cr.Reason))
+	}
+	for _, w := range cr.Warnings {
+		c.Ui.Warn(fmt.Sprintf("  Warning: %s.", w))
+	}
+	if cr.Err != nil {
+		c.Ui.Error(indentString(cr.Err.Error(), "  "))
+	}
+}
+
+// outputResult writes the summary of the given result, or the whole result
+// as JSON, and returns the exit status.
+func (c *StackMeta) outputResult(result *stacks.Result, f *stackFlags) int {
+	if f.json {
+		js, err := json.MarshalIndent(newStackResultJSON(result), "", "  ")
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error rendering the result as JSON: %s", err))
+			return 1
+		}
+		c.Ui.Output(string(js))
+	} else {
+		var ok, failed, skipped int
+		for _, cr := range result.Components {
+			switch cr.Status {
+			case stacks.StatusOK:
+				ok++
+			case stacks.StatusError:
+				failed++
+			default:
+				skipped++
+			}
+		}
+
+		summary := fmt.Sprintf("%d ok, %d failed, %d skipped.", ok, failed, skipped)
+		if failed > 0 {
+			c.Ui.Error(c.Colorize().Color("\n[reset][bold][red]Failure![reset] " + summary))
+		} else {
+			c.Ui.Output(c.Colorize().Color("\n[reset][bold][green]Success![reset] " + summary))
+		}
+	}
+
+	if result.Status() == stacks.StatusError {
+		return 1
+	}
+	return 0
+}
+
+func stackStatusColor(status stacks.Status) string {
+	switch status {
+	case stacks.StatusOK:
+		return "[green]ok[reset]"
+	case stacks.StatusError:
+		return "[red]error[reset]"
+	default:
+		return fmt.Sprintf("[yellow]%s[reset]", status)
+	}
+}
+
+// stackResultJSON is the JSON form of the result of a stack operation.
+type stackResultJSON struct {
+	Operation  stacks.Operation      `json:"operation"`
+	Status     stacks.Status         `json:"status"`
+	Components []*stackComponentJSON `json:"components"`
+}
+
+type stackComponentJSON struct {
+	Name      string        `json:"name"`
+	Workspace string        `json:"workspace"`
+	Status    stacks.Status `json:"status"`
+	Changes   bool          `json:"changes"`
+	Reason    string        `json:"reason,omitempty"`
+	Warnings  []string      `json:"warnings,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	Log       string        `json:"log,omitempty"`
+}
+
+func newStackResultJSON(result *stacks.Result) *stackResultJSON {
+	js := &stackResultJSON{
+		Operation:  result.Operation,
+		Status:     result.Status(),
+		Components: make([]*stackComponentJSON, 0, len(result.Components)),
+	}
+	for _, cr := range result.Components {
+		cjs := &stackComponentJSON{
+			Name:      cr.Name,
+			Workspace: cr.Workspace,
+			Status:    cr.Status,
+			Changes:   cr.Changes,
+			Reason:    cr.Reason,
+			Warnings:  cr.Warnings,
+			Log:       cr.Log,
+		}
+		if cr.Err != nil {
+			cjs.Error = cr.Err.Error()
+		}
+		js.Components = append(js.Components, cjs)
+	}
+	return js
+}
+
+// stackExecutor is a stacks.Executor that runs the operation of each
+// component with the given Terraform executable, in the directory of its
+// root module and with its workspace selected by TF_WORKSPACE.
+type stackExecutor struct {
+	exe   string
+	color bool
+}
+
+func (e *stackExecutor) Run(op stacks.Operation, c *stacks.Component, vars map[string]interface{}) (*stacks.ExecResult, error) {
+	if op == stacks.OperationApply {
+		return e.run(op, c, vars, "-auto-approve")
+	}
+	return e.run(op, c, vars)
+}
+
+func (e *stackExecutor) Plan(c *stacks.Component, vars map[string]interface{}, path string) (*stacks.ExecResult, error) {
+	return e.run(stacks.OperationPlan, c, vars, "-out="+path)
+}
+
+func (e *stackExecutor) ApplyPlan(c *stacks.Component, path string) (*stacks.ExecResult, error) {
+	args := []string{"apply", "-input=false"}
+	if !e.color {
+		args = append(args, "-no-color")
+	}
+	args = append(args, path)
+
+	out, err := e.command(c, args...).CombinedOutput()
+	result := &stacks.ExecResult{Log: string(out)}
+	if err != nil {
+		return result, fmt.Errorf("terraform apply failed: %s", err)
+	}
+	return result, nil
+}
+
+// run runs the given operation for the given component with the given
+// variables, and any other arguments.
+func (e *stackExecutor) run(op stacks.Operation, c *stacks.Component, vars map[string]interface{}, extra ...string) (*stacks.ExecResult, error) {
+	// The variables are passed in a file, where they keep their types.
+	dir, err := ioutil.TempDir("", "tfstack")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	varFile := filepath.Join(dir, "stack.tfvars.json")
+	js, err := json.Marshal(vars)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(varFile, js, 0600); err != nil {
+		return nil, err
+	}
+
+	args := []string{string(op), "-input=false", "-var-file=" + varFile}
+	if op == stacks.OperationPlan {
+		args = append(args, "-detailed-exitcode")
+	}
+	args = append(args, extra...)
+	if !e.color {
+		args = append(args, "-no-color")
+	}
+
+	out, err := e.command(c, args...).CombinedOutput()
+	result := &stacks.ExecResult{Log: string(out)}
+	if exitErr, ok := err.(*exec.ExitError); ok && op == stacks.OperationPlan && exitErr.ExitCode() == 2 {
+		result.Changes = true
+		err = nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("terraform %s failed: %s", op, err)
+	}
+	return result, nil
+}
+
+func (e *stackExecutor) Outputs(c *stacks.Component) (map[string]interface{}, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := e.command(c, "state", "pull")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("terraform state pull failed: %s\n\n%s", err, stderr.String())
+	}
+
+	result := make(map[string]interface{})
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return result, nil
+	}
+	state, err := terraform.ReadState(&stdout)
+	if err != nil {
+		return nil, err
+	}
+	if root := state.RootModule(); root != nil {
+		for k, o := range root.Outputs {
+			result[k] = o.Value
+		}
+	}
+	return result, nil
+}
+
+func (e *stackExecutor) command(c *stacks.Component, args ...string) *exec.Cmd {
+	cmd := exec.Command(e.exe, args...)
+	cmd.Dir = c.Dir
+
+	// This process was started by panicwrap, which marks its child with a
+	// cookie in the environment. The command must be wrapped in the same
+	// way, or it would write its logs and its prefixed output as they are.
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, panicwrap.DEFAULT_COOKIE_KEY+"=") {
+			cmd.Env = append(cmd.Env, kv)
+		}
+	}
+	cmd.Env = append(cmd.Env,
+		WorkspaceNameEnvVar+"="+c.Workspace,
+		"TF_IN_AUTOMATION=1")
+	return cmd
+}
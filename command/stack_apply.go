@@ -0,0 +1,247 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform/stacks"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// StackApplyCommand is a Command implementation that applies the components
+// of a stack.
+type StackApplyCommand struct {
+	StackMeta
+}
+
+func (c *StackApplyCommand) Run(args []string) int {
+	args, err := c.Meta.process(args, false)
+	if err != nil {
+		return 1
+	}
+
+	var f stackFlags
+	var autoApprove bool
+	cmdFlags := c.StackMeta.flagSet(&f, "stack apply")
+	cmdFlags.BoolVar(&autoApprove, "auto-approve", false, "skip interactive approval")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+	if len(cmdFlags.Args()) > 0 {
+		c.Ui.Error("The stack apply command expects no arguments.\n")
+		cmdFlags.Usage()
+		return 1
+	}
+	if f.json && !autoApprove {
+		c.Ui.Error(
+			"The -json option requires -auto-approve, since the apply can't be\n" +
+				"confirmed interactively when only its result is written.")
+		return 1
+	}
+
+	out := c.Ui
+	if f.json {
+		c.Ui = &stderrUi{Ui: c.Ui}
+	}
+
+	r, err := c.runner(&f)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	// Without -auto-approve the stack is planned first, and the plans of
+	// the components are confirmed together. The saved plan of each
+	// component is then applied, unless it was skipped or its inputs have
+	// changed since, in which case it's planned and confirmed again.
+	if !autoApprove {
+		dir, err := ioutil.TempDir("", "tfstack-plans")
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error creating the directory of the plans: %s", err))
+			return 1
+		}
+		defer os.RemoveAll(dir)
+
+		r.Executor = &stackPlanFiles{
+			stackPlanExecutor: r.Executor.(stackPlanExecutor),
+			dir:               dir,
+			confirm:           c.confirmComponent,
+		}
+
+		plan, err := r.Run(stacks.OperationPlan)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+		if code := c.outputResult(plan, &f); code != 0 {
+			return code
+		}
+
+		v, err := c.UIInput().Input(&terraform.InputOpts{
+			Id:    "approve",
+			Query: "\nDo you want to apply the stack?",
+			Description: "Terraform will apply the plan of each component in the order of their\n" +
+				"dependencies. Components that were skipped above, or whose inputs change,\n" +
+				"are planned again once the components they depend on are applied, and\n" +
+				"their changes are confirmed before they're applied.\n" +
+				"Only 'yes' will be accepted to approve.",
+		})
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+		if v != "yes" {
+			c.Ui.Output("Apply cancelled.")
+			return 1
+		}
+		c.Ui.Output("")
+	}
+
+	result, err := r.Run(stacks.OperationApply)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	c.Ui = out
+	return c.outputResult(result, &f)
+}
+
+// confirmComponent asks whether to apply the given component, which was
+// planned again with the given result.
+func (c *StackApplyCommand) confirmComponent(sc *stacks.Component, plan *stacks.ExecResult) (bool, error) {
+	c.Ui.Output(fmt.Sprintf(
+		"The inputs of %s have changed since the stack was planned,\n"+
+			"so it was planned again.\n\n%s", sc, indentString(plan.Log, "  ")))
+
+	v, err := c.UIInput().Input(&terraform.InputOpts{
+		Id:          "approve-" + sc.Name,
+		Query:       fmt.Sprintf("\nDo you want to apply %s?", sc),
+		Description: "Only 'yes' will be accepted to approve.",
+	})
+	if err != nil {
+		return false, err
+	}
+	c.Ui.Output("")
+	return v == "yes", nil
+}
+
+func (c *StackApplyCommand) Help() string {
+	helpText := `
+Usage: terraform stack apply [options]
+
+  Applies the components of a stack, in the order of their dependencies.
+
+  The inputs of a component are set from the outputs of the components
+  that it depends on once they're applied. A component is skipped when a
+  component that it depends on fails or is skipped.
+
+  Unless -auto-approve is given, the stack is planned first, and the
+  plans of all of the components are approved together. The saved plan of
+  each component is then applied. Components that were skipped, or whose
+  inputs have changed once the components they depend on are applied, are
+  planned again, and their changes are approved before they're applied.
+  Since plans can't be saved for components with state encryption, their
+  stacks can only be applied with -auto-approve.
+
+Options:
+
+  -auto-approve       Skip interactive approval of the stack before applying.
+
+  -json               If specified, the results of the components are written
+                      to stdout as a JSON document once they're all done,
+                      including the apply output of each component. Requires
+                      -auto-approve.
+
+  -manifest=path      Path to the stack manifest. Defaults to
+                      "terraform.tfstack.hcl".
+
+  -no-color           If specified, output won't contain any color.
+
+  -parallelism=2      Limit the number of components applied at once.
+
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *StackApplyCommand) Synopsis() string {
+	return "Applies the components of a stack"
+}
+
+// stackPlanFiles is a stacks.Executor that saves the plan of each component
+// to a file in dir, and applies the saved plan of a component unless it
+// wasn't planned or its variables have changed since. Those components are
+// planned again, and their changes are applied once confirm approves them.
+type stackPlanFiles struct {
+	stackPlanExecutor
+
+	dir     string
+	confirm func(*stacks.Component, *stacks.ExecResult) (bool, error)
+
+	lock sync.Mutex
+
+	// planned are the encodings of the variables that the saved plans
+	// were planned with, by component.
+	planned map[string]string
+
+	// confirmLock keeps the confirmations of components from overlapping.
+	confirmLock sync.Mutex
+}
+
+func (e *stackPlanFiles) Run(op stacks.Operation, c *stacks.Component, vars map[string]interface{}) (*stacks.ExecResult, error) {
+	path := filepath.Join(e.dir, c.Name+".tfplan")
+	js, err := json.Marshal(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	if op == stacks.OperationPlan {
+		return e.plan(c, vars, path, string(js))
+	}
+
+	e.lock.Lock()
+	planned, ok := e.planned[c.Name]
+	e.lock.Unlock()
+	if !ok || planned != string(js) {
+		result, err := e.plan(c, vars, path, string(js))
+		if err != nil {
+			return result, err
+		}
+		if result.Changes {
+			e.confirmLock.Lock()
+			ok, err := e.confirm(c, result)
+			e.confirmLock.Unlock()
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, fmt.Errorf("the apply of %s wasn't approved", c)
+			}
+		}
+	}
+
+	return e.ApplyPlan(c, path)
+}
+
+// plan saves the plan of the given component to the given path, recording
+// the given encoding of the variables it's planned with.
+func (e *stackPlanFiles) plan(c *stacks.Component, vars map[string]interface{}, path, js string) (*stacks.ExecResult, error) {
+	result, err := e.Plan(c, vars, path)
+	if err != nil {
+		return result, err
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.planned == nil {
+		e.planned = make(map[string]string)
+	}
+	e.planned[c.Name] = js
+	return result, nil
+}
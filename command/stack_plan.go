@@ -0,0 +1,104 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/hashicorp/terraform/stacks"
+)
+
+// StackPlanCommand is a Command implementation that plans the components
+// of a stack.
+type StackPlanCommand struct {
+	StackMeta
+}
+
+func (c *StackPlanCommand) Run(args []string) int {
+	args, err := c.Meta.process(args, false)
+	if err != nil {
+		return 1
+	}
+
+	var f stackFlags
+	var detailed bool
+	cmdFlags := c.StackMeta.flagSet(&f, "stack plan")
+	cmdFlags.BoolVar(&detailed, "detailed-exitcode", false, "detailed-exitcode")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+	if len(cmdFlags.Args()) > 0 {
+		c.Ui.Error("The stack plan command expects no arguments.\n")
+		cmdFlags.Usage()
+		return 1
+	}
+
+	// In JSON mode only the result is written to stdout.
+	out := c.Ui
+	if f.json {
+		c.Ui = &stderrUi{Ui: c.Ui}
+	}
+
+	r, err := c.runner(&f)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	result, err := r.Run(stacks.OperationPlan)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	c.Ui = out
+	if code := c.outputResult(result, &f); code != 0 {
+		return code
+	}
+	if detailed {
+		for _, cr := range result.Components {
+			if cr.Changes {
+				return 2
+			}
+		}
+	}
+	return 0
+}
+
+func (c *StackPlanCommand) Help() string {
+	helpText := `
+Usage: terraform stack plan [options]
+
+  Plans the components of a stack, in the order of their dependencies.
+
+  The inputs of a component are set from the current outputs of the
+  components that it depends on. A component is skipped if an output that
+  it takes an input from doesn't exist yet, since the component that it
+  comes from hasn't been applied, and when a component that it depends on
+  fails or is skipped.
+
+Options:
+
+  -detailed-exitcode  Return detailed exit codes when the command exits. This
+                      will change the meaning of exit codes to:
+                      0 - Succeeded, no components have changes
+                      1 - Errored
+                      2 - Succeeded, some components have changes
+
+  -json               If specified, the results of the components are written
+                      to stdout as a JSON document once they're all done,
+                      including the plan output of each component.
+
+  -manifest=path      Path to the stack manifest. Defaults to
+                      "terraform.tfstack.hcl".
+
+  -no-color           If specified, output won't contain any color.
+
+  -parallelism=2      Limit the number of components planned at once.
+
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *StackPlanCommand) Synopsis() string {
+	return "Plans the components of a stack"
+}
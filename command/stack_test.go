@@ -0,0 +1,301 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform/stacks"
+	"github.com/mitchellh/cli"
+)
+
+func TestStackPlan(t *testing.T) {
+	ui := new(cli.MockUi)
+	e := &testStackExecutor{
+		changes: true,
+		outputs: map[string]interface{}{"subnet_id": "subnet-123"},
+	}
+	c := &StackPlanCommand{
+		StackMeta: StackMeta{
+			Meta:     Meta{Ui: ui},
+			executor: e,
+		},
+	}
+
+	args := []string{
+		"-no-color",
+		"-detailed-exitcode",
+		"-manifest", filepath.Join(testFixturePath("stack"), stacks.ManifestFilename),
+	}
+	if code := c.Run(args); code != 2 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	for _, want := range []string{
+		`component "network" (workspace "default")... ok, with changes`,
+		`component "app" (workspace "prod")... ok, with changes`,
+		"Success! 2 ok, 0 failed, 0 skipped.",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("output should contain %q:\n%s", want, output)
+		}
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "network.subnet_id") {
+		t.Fatalf("should warn about the input:\n%s", ui.ErrorWriter.String())
+	}
+
+	expected := []string{"plan network", "plan app"}
+	if !reflect.DeepEqual(e.ops, expected) {
+		t.Fatalf("bad: %#v", e.ops)
+	}
+}
+
+func TestStackPlan_json(t *testing.T) {
+	ui := new(cli.MockUi)
+	e := &testStackExecutor{
+		errs: map[string]error{"network": errors.New("boom")},
+	}
+	c := &StackPlanCommand{
+		StackMeta: StackMeta{
+			Meta:     Meta{Ui: ui},
+			executor: e,
+		},
+	}
+
+	args := []string{
+		"-json",
+		"-manifest", filepath.Join(testFixturePath("stack"), stacks.ManifestFilename),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var result stackResultJSON
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &result); err != nil {
+		t.Fatalf("err: %s\n\n%s", err, ui.OutputWriter.String())
+	}
+	if result.Operation != stacks.OperationPlan || result.Status != stacks.StatusError {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	expected := []*stackComponentJSON{
+		{
+			Name:      "app",
+			Workspace: "prod",
+			Status:    stacks.StatusSkip,
+			Reason:    `it depends on component "network", which didn't succeed`,
+		},
+		{
+			Name:      "network",
+			Workspace: "default",
+			Status:    stacks.StatusError,
+			Error:     "boom",
+		},
+	}
+	if !reflect.DeepEqual(result.Components, expected) {
+		js, _ := json.MarshalIndent(result.Components, "", "  ")
+		t.Fatalf("bad: %s", js)
+	}
+}
+
+func TestStackApply(t *testing.T) {
+	defer testInputMap(t, map[string]string{
+		"approve": "yes",
+	})()
+
+	ui := new(cli.MockUi)
+	e := &testStackExecutor{
+		outputs: map[string]interface{}{"subnet_id": "subnet-123"},
+	}
+	c := &StackApplyCommand{
+		StackMeta: StackMeta{
+			Meta:     Meta{Ui: ui},
+			executor: e,
+		},
+	}
+
+	args := []string{
+		"-no-color",
+		"-manifest", filepath.Join(testFixturePath("stack"), stacks.ManifestFilename),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	// The stack is planned before it's applied, and the saved plans are
+	// applied.
+	expected := []string{
+		"plan network network.tfplan",
+		"plan app app.tfplan",
+		"apply network network.tfplan",
+		"apply app app.tfplan",
+	}
+	if !reflect.DeepEqual(e.ops, expected) {
+		t.Fatalf("bad: %#v", e.ops)
+	}
+}
+
+func TestStackApply_planAgain(t *testing.T) {
+	cases := map[string]struct {
+		outputs map[string]interface{}
+	}{
+		// The app is skipped when it's planned, since the output of the
+		// network that it takes an input from doesn't exist yet.
+		"skipped": {nil},
+
+		// The output of the network that the app was planned with changes
+		// when the network is applied.
+		"changed": {map[string]interface{}{"subnet_id": "subnet-old"}},
+	}
+	for name, tc := range cases {
+		for _, answer := range []string{"yes", "no"} {
+			t.Run(name+" "+answer, func(t *testing.T) {
+				defer testInputMap(t, map[string]string{
+					"approve":     "yes",
+					"approve-app": answer,
+				})()
+
+				ui := new(cli.MockUi)
+				e := &testStackExecutor{
+					changes:        true,
+					outputs:        tc.outputs,
+					appliedOutputs: map[string]interface{}{"subnet_id": "subnet-123"},
+				}
+				c := &StackApplyCommand{
+					StackMeta: StackMeta{
+						Meta:     Meta{Ui: ui},
+						executor: e,
+					},
+				}
+
+				args := []string{
+					"-no-color",
+					"-manifest", filepath.Join(testFixturePath("stack"), stacks.ManifestFilename),
+				}
+				code := c.Run(args)
+
+				expected := []string{"plan network network.tfplan"}
+				if tc.outputs != nil {
+					expected = append(expected, "plan app app.tfplan")
+				}
+				expected = append(expected, "apply network network.tfplan", "plan app app.tfplan")
+				wantCode := 1
+				if answer == "yes" {
+					expected = append(expected, "apply app app.tfplan")
+					wantCode = 0
+				}
+				if code != wantCode {
+					t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+				}
+				if !reflect.DeepEqual(e.ops, expected) {
+					t.Fatalf("bad: %#v", e.ops)
+				}
+				if !strings.Contains(ui.OutputWriter.String(), `The inputs of component "app" have changed`) {
+					t.Fatalf("bad: %s", ui.OutputWriter.String())
+				}
+			})
+		}
+	}
+}
+
+func TestStackApply_cancelled(t *testing.T) {
+	defer testInputMap(t, map[string]string{
+		"approve": "no",
+	})()
+
+	ui := new(cli.MockUi)
+	e := &testStackExecutor{
+		outputs: map[string]interface{}{"subnet_id": "subnet-123"},
+	}
+	c := &StackApplyCommand{
+		StackMeta: StackMeta{
+			Meta:     Meta{Ui: ui},
+			executor: e,
+		},
+	}
+
+	args := []string{
+		"-no-color",
+		"-manifest", filepath.Join(testFixturePath("stack"), stacks.ManifestFilename),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if !strings.Contains(ui.OutputWriter.String(), "Apply cancelled.") {
+		t.Fatalf("bad: %s", ui.OutputWriter.String())
+	}
+
+	expected := []string{"plan network network.tfplan", "plan app app.tfplan"}
+	if !reflect.DeepEqual(e.ops, expected) {
+		t.Fatalf("bad: %#v", e.ops)
+	}
+}
+
+func TestStackApply_jsonWithoutAutoApprove(t *testing.T) {
+	ui := new(cli.MockUi)
+	c := &StackApplyCommand{
+		StackMeta: StackMeta{
+			Meta:     Meta{Ui: ui},
+			executor: &testStackExecutor{},
+		},
+	}
+
+	args := []string{
+		"-json",
+		"-manifest", filepath.Join(testFixturePath("stack"), stacks.ManifestFilename),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "requires -auto-approve") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
+
+// testStackExecutor is a stacks.Executor that records the operations it
+// runs, and where every component has the same outputs. Once a component
+// is applied, the outputs are appliedOutputs if they're set.
+type testStackExecutor struct {
+	changes        bool
+	outputs        map[string]interface{}
+	appliedOutputs map[string]interface{}
+	errs           map[string]error
+
+	lock sync.Mutex
+	ops  []string
+}
+
+func (e *testStackExecutor) Run(op stacks.Operation, c *stacks.Component, vars map[string]interface{}) (*stacks.ExecResult, error) {
+	return e.run(string(op)+" "+c.Name, op, c)
+}
+
+func (e *testStackExecutor) Plan(c *stacks.Component, vars map[string]interface{}, path string) (*stacks.ExecResult, error) {
+	return e.run("plan "+c.Name+" "+filepath.Base(path), stacks.OperationPlan, c)
+}
+
+func (e *testStackExecutor) ApplyPlan(c *stacks.Component, path string) (*stacks.ExecResult, error) {
+	return e.run("apply "+c.Name+" "+filepath.Base(path), stacks.OperationApply, c)
+}
+
+func (e *testStackExecutor) run(desc string, op stacks.Operation, c *stacks.Component) (*stacks.ExecResult, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.ops = append(e.ops, desc)
+	if op == stacks.OperationApply && e.appliedOutputs != nil {
+		e.outputs = e.appliedOutputs
+	}
+	return &stacks.ExecResult{Changes: e.changes && op == stacks.OperationPlan}, e.errs[c.Name]
+}
+
+func (e *testStackExecutor) Outputs(c *stacks.Component) (map[string]interface{}, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return e.outputs, nil
+}
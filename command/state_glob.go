@@ -0,0 +1,137 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// stateGlob is an address pattern of the state mv and rm commands, in
+// which each "*" matches any sequence of characters other than ".". So
+// "module.*.aws_instance.web" matches the aws_instance.web resources of
+// the child modules of the root module, and "aws_instance.*" matches the
+// aws_instance resources of the root module.
+//
+// Patterns match whole resources and modules, not individual instances.
+type stateGlob struct {
+	Pattern string
+	re      *regexp.Regexp
+}
+
+// stateGlobMatch is an address that matched a stateGlob, with the text
+// matched by each "*" of the pattern.
+type stateGlobMatch struct {
+	Addr     string
+	Captures []string
+}
+
+// isStateGlob returns true if the given address is a pattern rather than
+// a single address.
+func isStateGlob(addr string) bool {
+	return strings.Contains(addr, "*")
+}
+
+func newStateGlob(pattern string) *stateGlob {
+	parts := strings.Split(pattern, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return &stateGlob{
+		Pattern: pattern,
+		re:      regexp.MustCompile("^" + strings.Join(parts, "([^.]*)") + "$"),
+	}
+}
+
+// Wildcards returns the number of "*" in the pattern.
+func (g *stateGlob) Wildcards() int {
+	return strings.Count(g.Pattern, "*")
+}
+
+// Match returns the addresses of the resources and modules of the given
+// state that match the pattern, sorted.
+func (g *stateGlob) Match(s *terraform.State) ([]*stateGlobMatch, error) {
+	addrs, err := stateGlobCandidates(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*stateGlobMatch
+	for _, addr := range addrs {
+		m := g.re.FindStringSubmatch(addr)
+		if m == nil {
+			continue
+		}
+		result = append(result, &stateGlobMatch{Addr: addr, Captures: m[1:]})
+	}
+	return result, nil
+}
+
+// Expand returns the pattern with each "*" replaced by the corresponding
+// capture of a match of another pattern with the same number of "*".
+func (g *stateGlob) Expand(captures []string) string {
+	parts := strings.Split(g.Pattern, "*")
+	var buf strings.Builder
+	for i, p := range parts {
+		buf.WriteString(p)
+		if i < len(captures) {
+			buf.WriteString(captures[i])
+		}
+	}
+	return buf.String()
+}
+
+// stateGlobCandidates returns the sorted addresses of all of the modules
+// other than the root module, and all of the resources, of the given
+// state.
+func stateGlobCandidates(s *terraform.State) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, ms := range s.Modules {
+		path := ms.Path[1:]
+		if len(path) > 0 {
+			seen["module."+strings.Join(path, ".module.")] = true
+		}
+		for key := range ms.Resources {
+			addr, err := terraform.ParseResourceAddressForInstanceDiff(path, key)
+			if err != nil {
+				return nil, err
+			}
+			addr.Index = -1
+			seen[addr.String()] = true
+		}
+	}
+
+	result := make([]string, 0, len(seen))
+	for addr := range seen {
+		result = append(result, addr)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// expandStateGlobs returns the given addresses with each pattern replaced
+// by the addresses of the given state that it matches. It's an error for
+// a pattern to match nothing, since that's most likely a mistake.
+func expandStateGlobs(s *terraform.State, addrs []string) ([]string, error) {
+	var result []string
+	for _, addr := range addrs {
+		if !isStateGlob(addr) {
+			result = append(result, addr)
+			continue
+		}
+
+		matches, err := newStateGlob(addr).Match(s)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no resources or modules match the pattern %q", addr)
+		}
+		for _, m := range matches {
+			result = append(result, m.Addr)
+		}
+	}
+	return result, nil
+}
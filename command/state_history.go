@@ -0,0 +1,124 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/state"
+	"github.com/mitchellh/cli"
+	"github.com/ryanuber/columnize"
+)
+
+// StateHistoryCommand is a Command implementation that lists the stored
+// versions of the state.
+type StateHistoryCommand struct {
+	StateMeta
+}
+
+func (c *StateHistoryCommand) Run(args []string) int {
+	args, err := c.Meta.process(args, true)
+	if err != nil {
+		return 1
+	}
+
+	cmdFlags := c.Meta.flagSet("state history")
+	cmdFlags.StringVar(&c.statePath, "state", "", "path")
+	if err := cmdFlags.Parse(args); err != nil {
+		return cli.RunResultHelp
+	}
+	if len(cmdFlags.Args()) > 0 {
+		c.Ui.Error("The history command expects no arguments.")
+		return cli.RunResultHelp
+	}
+
+	sMgr, err := c.State()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(errStateLoadingState, err))
+		return 1
+	}
+	if err := sMgr.RefreshState(); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to load state: %s", err))
+		return 1
+	}
+
+	versions, err := stateVersions(sMgr)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	if len(versions) == 0 {
+		c.Ui.Output("No previous versions of the state are stored.")
+		return 0
+	}
+
+	current := sMgr.State()
+	output := []string{"  SERIAL | STORED | LINEAGE"}
+	for _, v := range versions {
+		marker := " "
+		if current != nil && v.Serial == current.Serial && v.Lineage == current.Lineage {
+			marker = "*"
+		}
+		output = append(output, fmt.Sprintf("%s %d | %s | %s",
+			marker, v.Serial, v.Time.UTC().Format(time.RFC3339), v.Lineage))
+	}
+	c.Ui.Output(columnize.SimpleFormat(output))
+
+	return 0
+}
+
+// stateVersions returns the versions of the state stored by the given state
+// manager, or an error if it doesn't store them.
+func stateVersions(s state.State) ([]*state.StateVersion, error) {
+	h, ok := s.(state.History)
+	if !ok {
+		return nil, errors.New(strings.TrimSpace(errStateHistoryNotSupported))
+	}
+
+	versions, err := h.StateVersions()
+	if err == state.ErrHistoryNotSupported {
+		return nil, errors.New(strings.TrimSpace(errStateHistoryNotSupported))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the state history: %s", err)
+	}
+	return versions, nil
+}
+
+func (c *StateHistoryCommand) Help() string {
+	helpText := `
+Usage: terraform state history [options]
+
+  List the stored versions of the state, newest first.
+
+  Each version is identified by its serial, which can be given to
+  "terraform state rollback" to restore it. The current version is marked
+  with an asterisk (*).
+
+  The local backend, if its "history" option is enabled, stores every
+  version of the state that it writes in a directory next to the state
+  file, with the extension ".history". Other backends only support this
+  command if they store previous versions.
+
+Options:
+
+  -state=statefile    Path to a Terraform state file to use to look
+                      up the state history. If unspecified, the state
+                      will be retrieved from the configured backend.
+
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *StateHistoryCommand) Synopsis() string {
+	return "List the stored versions of the state"
+}
+
+const errStateHistoryNotSupported = `
+The state storage doesn't keep previous versions of the state.
+
+State history is only available for the local backend, with its "history"
+option enabled, and for other backends that store previous versions of the
+state.
+`
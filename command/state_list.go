@@ -81,8 +81,9 @@ Usage: terraform state list [options] [pattern...]
   is given, all resources are listed.
 
   The pattern argument is meant to provide very simple filtering. For
-  advanced filtering, please use tools such as "grep". The output of this
-  command is designed to be friendly for this usage.
+  advanced filtering, please use "terraform state query", or tools such
+  as "grep". The output of this command is designed to be friendly for
+  this usage.
 
   The pattern argument accepts any resource targeting syntax. Please
   refer to the documentation on resource targeting syntax for more
@@ -3,6 +3,7 @@ package command
 import (
 	"errors"
 	"fmt"
+	"os"
 	"time"
 
 	backendlocal "github.com/hashicorp/terraform/backend/local"
@@ -26,9 +27,17 @@ func (c *StateMeta) State() (state.State, error) {
 
 	// use the specified state
 	if c.statePath != "" {
-		realState = &state.LocalState{
+		ls := &state.LocalState{
 			Path: c.statePath,
 		}
+		// The history of a state file is only kept if it was enabled for
+		// the local backend that wrote it, in which case its directory
+		// already exists.
+		historyDir := c.statePath + backendlocal.DefaultHistoryExtension
+		if info, err := os.Stat(historyDir); err == nil && info.IsDir() {
+			ls.HistoryDir = historyDir
+		}
+		realState = ls
 	} else {
 		// Load the backend
 		b, err := c.Backend(nil)
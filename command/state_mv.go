@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/hashicorp/terraform/diffs"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
 )
@@ -21,10 +22,12 @@ func (c *StateMvCommand) Run(args []string) int {
 
 	// We create two metas to track the two states
 	var backupPathOut, statePathOut string
+	var dryRun bool
 
 	cmdFlags := c.Meta.flagSet("state mv")
 	cmdFlags.StringVar(&c.backupPath, "backup", "-", "backup")
 	cmdFlags.StringVar(&c.statePath, "state", "", "path")
+	cmdFlags.BoolVar(&dryRun, "dry-run", false, "dry-run")
 	cmdFlags.StringVar(&backupPathOut, "backup-out", "-", "backup")
 	cmdFlags.StringVar(&statePathOut, "state-out", "", "path")
 	if err := cmdFlags.Parse(args); err != nil {
@@ -78,30 +81,55 @@ func (c *StateMvCommand) Run(args []string) int {
 		}
 	}
 
-	// Filter what we're moving
-	filter := &terraform.StateFilter{State: stateFromReal}
-	results, err := filter.Filter(args[0])
+	moves, err := c.moves(stateFromReal, args[0], args[1])
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf(errStateMv, err))
 		return cli.RunResultHelp
 	}
-	if len(results) == 0 {
-		c.Ui.Output(fmt.Sprintf("Item to move doesn't exist: %s", args[0]))
-		return 1
+
+	// Filter what we're moving. Everything is found before anything is
+	// moved, so that one move of a pattern can't move the result of
+	// another.
+	adds := make([]interface{}, len(moves))
+	for i, m := range moves {
+		filter := &terraform.StateFilter{State: stateFromReal}
+		results, err := filter.Filter(m.From)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf(errStateMv, err))
+			return cli.RunResultHelp
+		}
+		if len(results) == 0 {
+			c.Ui.Output(fmt.Sprintf("Item to move doesn't exist: %s", m.From))
+			return 1
+		}
+
+		// Get the item to add to the state
+		adds[i] = c.addableResult(results)
 	}
 
-	// Get the item to add to the state
-	add := c.addableResult(results)
+	if dryRun {
+		for _, m := range moves {
+			c.Ui.Output(m.String())
+		}
+		c.Ui.Output(fmt.Sprintf(
+			"\n%d items would be moved. This was a dry run, so the state was not changed.",
+			len(moves)))
+		return 0
+	}
 
 	// Do the actual move
-	if err := stateFromReal.Remove(args[0]); err != nil {
-		c.Ui.Error(fmt.Sprintf(errStateMv, err))
-		return 1
+	for _, m := range moves {
+		if err := stateFromReal.Remove(m.From); err != nil {
+			c.Ui.Error(fmt.Sprintf(errStateMv, err))
+			return 1
+		}
 	}
 
-	if err := stateToReal.Add(args[0], args[1], add); err != nil {
-		c.Ui.Error(fmt.Sprintf(errStateMv, err))
-		return 1
+	for i, m := range moves {
+		if err := stateToReal.Add(m.From, m.To, adds[i]); err != nil {
+			c.Ui.Error(fmt.Sprintf(errStateMv, err))
+			return 1
+		}
 	}
 
 	// Write the new state
@@ -128,11 +156,52 @@ func (c *StateMvCommand) Run(args []string) int {
 		}
 	}
 
-	c.Ui.Output(fmt.Sprintf(
-		"Moved %s to %s", args[0], args[1]))
+	for _, m := range moves {
+		c.Ui.Output(fmt.Sprintf("Moved %s to %s", m.From, m.To))
+	}
 	return 0
 }
 
+// moves returns the moves that the given source and destination addresses
+// describe. If the source is a pattern then the destination must have the
+// same number of "*", each of which is replaced by the text matched by the
+// corresponding "*" of the source, giving one move for each match.
+func (c *StateMvCommand) moves(s *terraform.State, from, to string) ([]*diffs.Move, error) {
+	if !isStateGlob(from) {
+		if isStateGlob(to) {
+			return nil, fmt.Errorf("the destination %q can only be a pattern if the source is", to)
+		}
+		return []*diffs.Move{{From: from, To: to}}, nil
+	}
+
+	fromGlob := newStateGlob(from)
+	toGlob := newStateGlob(to)
+	if fromGlob.Wildcards() != toGlob.Wildcards() {
+		return nil, fmt.Errorf(
+			"the destination %q must have as many \"*\" as the source %q", to, from)
+	}
+
+	matches, err := fromGlob.Match(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no resources or modules match the pattern %q", from)
+	}
+
+	moves := make([]*diffs.Move, len(matches))
+	seen := make(map[string]string)
+	for i, m := range matches {
+		moves[i] = &diffs.Move{From: m.Addr, To: toGlob.Expand(m.Captures)}
+		if other, ok := seen[moves[i].To]; ok {
+			return nil, fmt.Errorf(
+				"both %s and %s would be moved to %s", other, m.Addr, moves[i].To)
+		}
+		seen[moves[i].To] = m.Addr
+	}
+	return moves, nil
+}
+
 // addableResult takes the result from a filter operation and returns what to
 // call State.Add with. The reason we do this is because in the module case
 // we must add the list of all modules returned versus just the root module.
@@ -201,6 +270,14 @@ Usage: terraform state mv [options] SOURCE DESTINATION
  If you're moving an item to a different state file, a backup will be created
  for each state file.
 
+ The source can be a pattern in which each "*" matches any part of an
+ address between dots, to move all of the matching resources and modules
+ at once. The destination must then have the same number of "*", which
+ are replaced by the matched parts in order. For example, this moves
+ each aws_instance resource into the module "web":
+
+     terraform state mv 'aws_instance.*' 'module.web.aws_instance.*'
+
 Options:
 
   -backup=PATH        Path where Terraform should write the backup for the original
@@ -208,6 +285,9 @@ Options:
                       will write it to the same path as the statefile with
                       a ".backup" extension.
 
+  -dry-run            Show the moves that would be made, without changing
+                      the state.
+
   -backup-out=PATH    Path where Terraform should write the backup for the destination
                       state. This can't be disabled. If not set, Terraform
                       will write it to the same path as the destination state
@@ -0,0 +1,163 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/command/statequery"
+	"github.com/mitchellh/cli"
+)
+
+// StateQueryCommand is a Command implementation that lists the resources
+// within a state file that match a query.
+type StateQueryCommand struct {
+	Meta
+	StateMeta
+}
+
+func (c *StateQueryCommand) Run(args []string) int {
+	args, err := c.Meta.process(args, true)
+	if err != nil {
+		return 1
+	}
+
+	var jsonOutput bool
+	cmdFlags := c.Meta.flagSet("state query")
+	cmdFlags.StringVar(&c.Meta.statePath, "state", DefaultStateFilename, "path")
+	cmdFlags.BoolVar(&jsonOutput, "json", false, "json")
+	if err := cmdFlags.Parse(args); err != nil {
+		return cli.RunResultHelp
+	}
+	args = cmdFlags.Args()
+
+	if len(args) != 1 {
+		c.Ui.Error("Exactly one argument expected: the query.")
+		return cli.RunResultHelp
+	}
+	query, err := statequery.Parse(args[0])
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Invalid query: %s", err))
+		return 1
+	}
+
+	// Load the backend
+	b, err := c.Backend(nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to load backend: %s", err))
+		return 1
+	}
+
+	// Get the state
+	env := c.Workspace()
+	state, err := b.State(env)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to load state: %s", err))
+		return 1
+	}
+	if err := state.RefreshState(); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to load state: %s", err))
+		return 1
+	}
+
+	stateReal := state.State()
+	if stateReal == nil {
+		c.Ui.Error(fmt.Sprintf(errStateNotFound))
+		return 1
+	}
+
+	results, err := query.Filter(stateReal)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(errStateFilter, err))
+		return 1
+	}
+
+	if !jsonOutput {
+		for _, o := range results {
+			c.Ui.Output(o.Address)
+		}
+		return 0
+	}
+
+	objects := make([]*stateQueryObjectJSON, len(results))
+	for i, o := range results {
+		objects[i] = &stateQueryObjectJSON{
+			Address:    o.Address,
+			Module:     o.Module,
+			Mode:       o.Mode,
+			Type:       o.Type,
+			Name:       o.Name,
+			Provider:   o.Provider,
+			ID:         o.ID,
+			Tainted:    o.Tainted,
+			Attributes: o.Attributes,
+		}
+		if o.Index >= 0 {
+			index := o.Index
+			objects[i].Index = &index
+		}
+	}
+	out, err := json.MarshalIndent(objects, "", "  ")
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to encode the results: %s", err))
+		return 1
+	}
+	c.Ui.Output(string(out))
+
+	return 0
+}
+
+// stateQueryObjectJSON is a resource instance in the output of
+// "terraform state query -json".
+type stateQueryObjectJSON struct {
+	Address    string            `json:"address"`
+	Module     string            `json:"module"`
+	Mode       string            `json:"mode"`
+	Type       string            `json:"type"`
+	Name       string            `json:"name"`
+	Index      *int              `json:"index"`
+	Provider   string            `json:"provider"`
+	ID         string            `json:"id"`
+	Tainted    bool              `json:"tainted"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+func (c *StateQueryCommand) Help() string {
+	helpText := `
+Usage: terraform state query [options] QUERY
+
+  List the resources in the Terraform state that match the given query.
+
+  A query compares fields of each resource instance with values, and the
+  comparisons can be combined with "and", "or", "not" and parentheses.
+  The operators are == and !=, and =~ and !~ for regular expressions.
+  Values with spaces or other special characters must be double quoted.
+  For example:
+
+      type == aws_instance and attr.tags.Env == prod
+      module =~ "^module.network" or provider == aws.west
+      not (mode == data or tainted == true)
+
+  The fields are address, id, mode ("managed" or "data"), module (empty
+  in the root module), name, provider (with its alias, if any), tainted
+  ("true" or "false") and type. Attributes are compared with attr.NAME,
+  using the names of "terraform state show", and missing attributes are
+  empty.
+
+Options:
+
+  -json               Output the matching instances as a JSON array of
+                      objects with their fields and attributes, instead
+                      of their addresses.
+
+  -state=statefile    Path to a Terraform state file to use to look
+                      up Terraform-managed resources. By default it will
+                      use the state "terraform.tfstate" if it exists.
+
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *StateQueryCommand) Synopsis() string {
+	return "List resources in the state that match a query"
+}
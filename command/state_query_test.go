@@ -0,0 +1,138 @@
+package command
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
+)
+
+func TestStateQuery(t *testing.T) {
+	state := testStateQueryState()
+	statePath := testStateFile(t, state)
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &StateQueryCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-state", statePath,
+		"type == test_instance and attr.tags.Env == prod",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	expected := "test_instance.foo\n"
+	actual := ui.OutputWriter.String()
+	if actual != expected {
+		t.Fatalf("Expected:\n%q\n\nTo equal: %q", actual, expected)
+	}
+}
+
+func TestStateQuery_json(t *testing.T) {
+	state := testStateQueryState()
+	statePath := testStateFile(t, state)
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &StateQueryCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-state", statePath,
+		"-json",
+		"name =~ ^ba",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var objects []*stateQueryObjectJSON
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &objects); err != nil {
+		t.Fatalf("err: %s\n\n%s", err, ui.OutputWriter.String())
+	}
+	if len(objects) != 2 {
+		t.Fatalf("wrong number of objects %d:\n%s", len(objects), ui.OutputWriter.String())
+	}
+
+	bar := objects[0]
+	if bar.Address != "test_instance.bar[0]" || bar.Index == nil || *bar.Index != 0 {
+		t.Fatalf("wrong object: %#v", bar)
+	}
+	if bar.Type != "test_instance" || bar.Name != "bar" || bar.Mode != "managed" || bar.Provider != "test" {
+		t.Fatalf("wrong object: %#v", bar)
+	}
+	if bar.Attributes["tags.Env"] != "dev" {
+		t.Fatalf("wrong attributes: %#v", bar.Attributes)
+	}
+
+	if objects[1].Address != "test_instance.baz" || objects[1].Index != nil {
+		t.Fatalf("wrong object: %#v", objects[1])
+	}
+}
+
+func TestStateQuery_invalid(t *testing.T) {
+	state := testStateQueryState()
+	statePath := testStateFile(t, state)
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &StateQueryCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-state", statePath,
+		"kind == test_instance",
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "unknown field \"kind\"") {
+		t.Fatalf("wrong error:\n%s", ui.ErrorWriter.String())
+	}
+}
+
+func testStateQueryState() *terraform.State {
+	state := testState()
+	root := state.RootModule()
+	root.Resources["test_instance.foo"].Provider = "provider.test"
+	root.Resources["test_instance.foo"].Primary.Attributes = map[string]string{
+		"tags.%":   "1",
+		"tags.Env": "prod",
+	}
+	root.Resources["test_instance.bar.0"] = &terraform.ResourceState{
+		Type:     "test_instance",
+		Provider: "provider.test",
+		Primary: &terraform.InstanceState{
+			ID: "bar",
+			Attributes: map[string]string{
+				"tags.%":   "1",
+				"tags.Env": "dev",
+			},
+		},
+	}
+	root.Resources["test_instance.baz"] = &terraform.ResourceState{
+		Type:     "test_instance",
+		Provider: "provider.test",
+		Primary: &terraform.InstanceState{
+			ID: "baz",
+		},
+	}
+	return state
+}
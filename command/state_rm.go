@@ -4,7 +4,12 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/hashicorp/terraform/command/statequery"
+	"github.com/hashicorp/terraform/diffs"
+	"github.com/hashicorp/terraform/diffs/render"
+	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
+	"github.com/zclconf/go-cty/cty"
 )
 
 // StateRmCommand is a Command implementation that shows a single resource.
@@ -18,8 +23,10 @@ func (c *StateRmCommand) Run(args []string) int {
 		return 1
 	}
 
+	var dryRun bool
 	cmdFlags := c.Meta.flagSet("state show")
 	cmdFlags.StringVar(&c.backupPath, "backup", "-", "backup")
+	cmdFlags.BoolVar(&dryRun, "dry-run", false, "dry-run")
 	cmdFlags.StringVar(&c.statePath, "state", "", "path")
 	if err := cmdFlags.Parse(args); err != nil {
 		return cli.RunResultHelp
@@ -47,12 +54,40 @@ func (c *StateRmCommand) Run(args []string) int {
 		return 1
 	}
 
-	if err := stateReal.Remove(args...); err != nil {
+	addrs, err := expandStateGlobs(stateReal, args)
+	if err != nil {
 		c.Ui.Error(fmt.Sprintf(errStateRm, err))
 		return 1
 	}
 
-	c.Ui.Output(fmt.Sprintf("%d items removed.", len(args)))
+	if dryRun {
+		after := stateReal.DeepCopy()
+		if err := after.Remove(addrs...); err != nil {
+			c.Ui.Error(fmt.Sprintf(errStateRm, err))
+			return 1
+		}
+		changes, err := stateRmChanges(stateReal, after)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf(errStateRm, err))
+			return 1
+		}
+		opts := render.Opts{Color: c.Colorize()}
+		for _, addr := range changes.Keys() {
+			c.Ui.Output(fmt.Sprintf("# %s will be removed from the state", addr))
+			c.Ui.Output(strings.TrimSuffix(render.Change(changes[addr], opts), "\n"))
+		}
+		c.Ui.Output(fmt.Sprintf(
+			"\n%d items would be removed. This was a dry run, so the state was not changed.",
+			len(addrs)))
+		return 0
+	}
+
+	if err := stateReal.Remove(addrs...); err != nil {
+		c.Ui.Error(fmt.Sprintf(errStateRm, err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("%d items removed.", len(addrs)))
 
 	if err := state.WriteState(stateReal); err != nil {
 		c.Ui.Error(fmt.Sprintf(errStateRmPersist, err))
@@ -68,6 +103,41 @@ func (c *StateRmCommand) Run(args []string) int {
 	return 0
 }
 
+// stateRmChanges returns the changes that removing items from the state
+// before makes to give the state after, as a set of Forget changes of the
+// instances that are no longer in the state, keyed by address.
+func stateRmChanges(before, after *terraform.State) (diffs.ChangeSet, error) {
+	remaining, err := statequery.Objects(after)
+	if err != nil {
+		return nil, err
+	}
+	kept := make(map[string]bool, len(remaining))
+	for _, o := range remaining {
+		kept[o.Address] = true
+	}
+
+	objects, err := statequery.Objects(before)
+	if err != nil {
+		return nil, err
+	}
+	changes := make(diffs.ChangeSet)
+	for _, o := range objects {
+		if kept[o.Address] {
+			continue
+		}
+		attrs := make(map[string]cty.Value, len(o.Attributes))
+		for k, v := range o.Attributes {
+			attrs[k] = cty.StringVal(v)
+		}
+		old := cty.MapValEmpty(cty.String)
+		if len(attrs) > 0 {
+			old = cty.MapVal(attrs)
+		}
+		changes[o.Address] = diffs.NewForget(cty.Map(cty.String), old)
+	}
+	return changes, nil
+}
+
 func (c *StateRmCommand) Help() string {
 	helpText := `
 Usage: terraform state rm [options] ADDRESS...
@@ -78,6 +148,10 @@ Usage: terraform state rm [options] ADDRESS...
   on the address given. You can view and list the available resources
   with "terraform state list".
 
+  An address can be a pattern in which each "*" matches any part of an
+  address between dots, such as "module.*.aws_instance.web", to remove
+  all of the matching resources and modules at once.
+
   This command creates a timestamped backup of the state on every invocation.
   This can't be disabled. Due to the destructive nature of this command,
   the backup is ensured by Terraform for safety reasons.
@@ -89,6 +163,9 @@ Options:
                       will write it to the same path as the statefile with
                       a backup extension.
 
+  -dry-run            Show the instances that would be removed, without
+                      changing the state.
+
   -state=PATH         Path to the source state file. Defaults to the configured
                       backend, or "terraform.tfstate"
 
@@ -0,0 +1,192 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform/command/clistate"
+	"github.com/hashicorp/terraform/state"
+	"github.com/mitchellh/cli"
+)
+
+// StateRollbackCommand is a Command implementation that restores a stored
+// version of the state.
+type StateRollbackCommand struct {
+	StateMeta
+}
+
+func (c *StateRollbackCommand) Run(args []string) int {
+	args, err := c.Meta.process(args, true)
+	if err != nil {
+		return 1
+	}
+
+	cmdFlags := c.Meta.flagSet("state rollback")
+	cmdFlags.StringVar(&c.backupPath, "backup", "-", "backup")
+	cmdFlags.StringVar(&c.statePath, "state", "", "path")
+	cmdFlags.BoolVar(&c.Meta.stateLock, "lock", true, "lock state")
+	cmdFlags.DurationVar(&c.Meta.stateLockTimeout, "lock-timeout", 0, "lock timeout")
+	if err := cmdFlags.Parse(args); err != nil {
+		return cli.RunResultHelp
+	}
+	args = cmdFlags.Args()
+
+	if len(args) != 1 {
+		c.Ui.Error("Exactly one argument expected: the serial of the version to restore.")
+		return cli.RunResultHelp
+	}
+	serial, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Invalid serial %q: it must be a number.", args[0]))
+		return 1
+	}
+
+	sMgr, err := c.State()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(errStateLoadingState, err))
+		return 1
+	}
+
+	if c.stateLock {
+		lockCtx, cancel := context.WithTimeout(context.Background(), c.stateLockTimeout)
+		defer cancel()
+
+		lockInfo := state.NewLockInfo()
+		lockInfo.Operation = "state rollback"
+		lockID, err := clistate.Lock(lockCtx, sMgr, lockInfo, c.Ui, c.Colorize())
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error locking state: %s", err))
+			return 1
+		}
+		defer clistate.Unlock(sMgr, lockID, c.Ui, c.Colorize())
+	}
+
+	// The state is read once it's locked, so that it can't change before
+	// it's replaced.
+	if err := sMgr.RefreshState(); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to load state: %s", err))
+		return 1
+	}
+	current := sMgr.State()
+	if current == nil {
+		c.Ui.Error(errStateNotFound)
+		return 1
+	}
+
+	versions, err := stateVersions(sMgr)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	// Only versions of the current lineage can be restored, since versions
+	// of other lineages are of different infrastructure.
+	var version *state.StateVersion
+	var otherLineage bool
+	for _, v := range versions {
+		if v.Serial != serial {
+			continue
+		}
+		if v.Lineage != current.Lineage {
+			otherLineage = true
+			continue
+		}
+		version = v
+		break
+	}
+	switch {
+	case version == nil && otherLineage:
+		c.Ui.Error(fmt.Sprintf(strings.TrimSpace(errStateRollbackLineage), serial, current.Lineage))
+		return 1
+	case version == nil:
+		c.Ui.Error(fmt.Sprintf(
+			"No version of the state with serial %d is stored. Run \"terraform state history\" "+
+				"to list the stored versions.", serial))
+		return 1
+	case serial >= current.Serial:
+		c.Ui.Error(fmt.Sprintf(
+			"Serial %d is the current version of the state, or newer, so there is "+
+				"nothing to roll back.", serial))
+		return 1
+	}
+
+	restored, err := sMgr.(state.History).ReadStateVersion(version)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to read version %d of the state: %s", serial, err))
+		return 1
+	}
+	if !restored.SameLineage(current) {
+		// should never happen, since the lineage is in the file name
+		c.Ui.Error(fmt.Sprintf(strings.TrimSpace(errStateRollbackLineage), serial, current.Lineage))
+		return 1
+	}
+
+	// The restored state is written as a new version, so the versions since
+	// it remain in the history and the rollback can itself be undone.
+	if err := sMgr.WriteState(restored); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to write state: %s", err))
+		return 1
+	}
+	if err := sMgr.PersistState(); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to write state: %s", err))
+		return 1
+	}
+
+	newSerial := serial
+	if s := sMgr.State(); s != nil {
+		newSerial = s.Serial
+	}
+	c.Ui.Output(fmt.Sprintf(
+		"Restored version %d of the state, which is now stored as version %d.", serial, newSerial))
+	return 0
+}
+
+func (c *StateRollbackCommand) Help() string {
+	helpText := `
+Usage: terraform state rollback [options] SERIAL
+
+  Restore the stored version of the state with the given serial, as listed
+  by "terraform state history".
+
+  The state is locked while it is restored, and only versions with the same
+  lineage as the current state can be restored. The restored state is
+  written as a new version, so the versions since it stay in the history.
+
+  This command doesn't change any infrastructure, only the state. Objects
+  created since the restored version are no longer tracked, so they must be
+  imported or destroyed by hand, and objects destroyed since are removed
+  from the state again by the next refresh.
+
+Options:
+
+  -backup=PATH        Path where Terraform should write the backup
+                      state. This can't be disabled. If not set, Terraform
+                      will write it to the same path as the statefile with
+                      a backup extension.
+
+  -lock=true          Lock the state file when locking is supported.
+
+  -lock-timeout=0s    Duration to retry a state lock.
+
+  -state=statefile    Path to a Terraform state file to roll back. If
+                      unspecified, the state of the configured backend is
+                      rolled back.
+
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *StateRollbackCommand) Synopsis() string {
+	return "Restore a previous version of the state"
+}
+
+const errStateRollbackLineage = `
+The stored version with serial %d has a different lineage from the
+current state (%s), so it can't be restored.
+
+A state's lineage changes when it's replaced with an unrelated state, for
+example when the state is deleted and its infrastructure is created again.
+Versions from before then describe different infrastructure.
+`
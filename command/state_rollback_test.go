@@ -0,0 +1,168 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/backend/local"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
+)
+
+// testStateHistory writes a state file with two versions in its history,
+// the first with test_instance.foo and the second, current, version with
+// test_instance.bar, returning the path and the serials of the versions.
+func testStateHistory(t *testing.T) (string, int64, int64) {
+	t.Helper()
+
+	path := testTempFile(t)
+	sMgr := &state.LocalState{
+		Path:       path,
+		HistoryDir: path + local.DefaultHistoryExtension,
+	}
+
+	var serials []int64
+	for _, id := range []string{"foo", "bar"} {
+		if err := sMgr.RefreshState(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		s := sMgr.State()
+		if s == nil {
+			s = terraform.NewState()
+		}
+		s.RootModule().Resources = map[string]*terraform.ResourceState{
+			"test_instance." + id: &terraform.ResourceState{
+				Type:    "test_instance",
+				Primary: &terraform.InstanceState{ID: id},
+			},
+		}
+		if err := sMgr.WriteState(s); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		serials = append(serials, sMgr.State().Serial)
+	}
+	sMgr.Unlock("")
+
+	return path, serials[0], serials[1]
+}
+
+func TestStateRollback(t *testing.T) {
+	statePath, first, second := testStateHistory(t)
+
+	ui := new(cli.MockUi)
+	c := &StateRollbackCommand{
+		StateMeta{
+			Meta: Meta{
+				Ui: ui,
+			},
+		},
+	}
+	args := []string{
+		"-state", statePath,
+		"-lock=false",
+		formatSerial(first),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	actual := testStateRead(t, statePath)
+	if _, ok := actual.RootModule().Resources["test_instance.foo"]; !ok {
+		t.Fatalf("the first version wasn't restored:\n%s", actual)
+	}
+	if _, ok := actual.RootModule().Resources["test_instance.bar"]; ok {
+		t.Fatalf("the current version wasn't replaced:\n%s", actual)
+	}
+	if actual.Serial != second+1 {
+		t.Fatalf("wrong serial %d; want %d", actual.Serial, second+1)
+	}
+
+	// The history lists the restored state as the current version.
+	ui = new(cli.MockUi)
+	hc := &StateHistoryCommand{
+		StateMeta{
+			Meta: Meta{
+				Ui: ui,
+			},
+		},
+	}
+	if code := hc.Run([]string{"-state", statePath}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	lines := strings.Split(strings.TrimSpace(ui.OutputWriter.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("wrong history:\n%s", ui.OutputWriter.String())
+	}
+	for i, serial := range []int64{second + 1, second, first} {
+		fields := strings.Fields(lines[i+1])
+		if i == 0 {
+			if fields[0] != "*" {
+				t.Fatalf("the current version isn't marked:\n%s", ui.OutputWriter.String())
+			}
+			fields = fields[1:]
+		}
+		if fields[0] != formatSerial(serial) {
+			t.Fatalf("wrong serial on line %d:\n%s", i+2, ui.OutputWriter.String())
+		}
+	}
+}
+
+func TestStateRollback_current(t *testing.T) {
+	statePath, _, second := testStateHistory(t)
+
+	ui := new(cli.MockUi)
+	c := &StateRollbackCommand{
+		StateMeta{
+			Meta: Meta{
+				Ui: ui,
+			},
+		},
+	}
+	args := []string{
+		"-state", statePath,
+		"-lock=false",
+		formatSerial(second),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "nothing to roll back") {
+		t.Fatalf("wrong error:\n%s", ui.ErrorWriter.String())
+	}
+}
+
+func TestStateRollback_lineage(t *testing.T) {
+	statePath, first, _ := testStateHistory(t)
+
+	// Replace the state with one of a different lineage.
+	other := terraform.NewState()
+	if err := (&state.LocalState{Path: statePath}).WriteState(other); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := new(cli.MockUi)
+	c := &StateRollbackCommand{
+		StateMeta{
+			Meta: Meta{
+				Ui: ui,
+			},
+		},
+	}
+	args := []string{
+		"-state", statePath,
+		"-lock=false",
+		formatSerial(first),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "different lineage") {
+		t.Fatalf("wrong error:\n%s", ui.ErrorWriter.String())
+	}
+}
+
+func formatSerial(serial int64) string {
+	return strconv.FormatInt(serial, 10)
+}
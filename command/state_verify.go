@@ -0,0 +1,343 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	backendlocal "github.com/hashicorp/terraform/backend/local"
+	"github.com/hashicorp/terraform/command/clistate"
+	"github.com/hashicorp/terraform/command/stateverify"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
+)
+
+// StateVerifyCommand is a Command implementation that checks the integrity
+// of the state and optionally repairs the problems it finds that are safe
+// to repair.
+type StateVerifyCommand struct {
+	StateMeta
+}
+
+func (c *StateVerifyCommand) Run(args []string) int {
+	var jsonOutput, repair bool
+
+	args, err := c.Meta.process(args, true)
+	if err != nil {
+		return 1
+	}
+
+	cmdFlags := c.Meta.flagSet("state verify")
+	cmdFlags.StringVar(&c.backupPath, "backup", "-", "backup")
+	cmdFlags.StringVar(&c.statePath, "state", "", "path")
+	cmdFlags.BoolVar(&jsonOutput, "json", false, "json")
+	cmdFlags.BoolVar(&repair, "repair", false, "repair")
+	cmdFlags.BoolVar(&c.Meta.stateLock, "lock", true, "lock state")
+	cmdFlags.DurationVar(&c.Meta.stateLockTimeout, "lock-timeout", 0, "lock timeout")
+	if err := cmdFlags.Parse(args); err != nil {
+		return cli.RunResultHelp
+	}
+
+	configPath, err := ModulePath(cmdFlags.Args())
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	// Check for user-supplied plugin path
+	if c.pluginPath, err = c.loadPluginPath(); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error loading plugin path: %s", err))
+		return 1
+	}
+
+	// The configuration is only used to find module entries that are no
+	// longer configured, so it's fine for there to be none.
+	mod, diags := c.Module(configPath)
+	if diags.HasErrors() {
+		c.showDiagnostics(diags)
+		return 1
+	}
+
+	sMgr, err := c.State()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(errStateLoadingState, err))
+		return 1
+	}
+
+	// The state is only locked if it's to be repaired, since otherwise it's
+	// only read.
+	if repair && c.stateLock {
+		lockCtx, cancel := context.WithTimeout(context.Background(), c.stateLockTimeout)
+		defer cancel()
+
+		lockInfo := state.NewLockInfo()
+		lockInfo.Operation = "state verify"
+		lockID, err := clistate.Lock(lockCtx, sMgr, lockInfo, c.Ui, c.Colorize())
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error locking state: %s", err))
+			return 1
+		}
+		defer clistate.Unlock(sMgr, lockID, c.Ui, c.Colorize())
+	}
+
+	if err := sMgr.RefreshState(); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to load state: %s", err))
+		return 1
+	}
+	s := sMgr.State()
+	if s == nil {
+		c.Ui.Error(errStateNotFound)
+		return 1
+	}
+
+	copies, err := c.stateCopies(sMgr)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	report := stateverify.Verify(s, &stateverify.Opts{
+		Providers: c.verifyProviders(s),
+		Copies:    copies,
+		Config:    mod,
+	})
+
+	var repaired int
+	if repair {
+		repaired = report.Repair(s)
+	}
+	if repaired > 0 {
+		if err := sMgr.WriteState(s); err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to write state: %s", err))
+			return 1
+		}
+		if err := sMgr.PersistState(); err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to write state: %s", err))
+			return 1
+		}
+	}
+
+	if jsonOutput {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to marshal the report: %s", err))
+			return 1
+		}
+		c.Ui.Output(string(out))
+	} else {
+		c.Ui.Output(formatStateVerifyReport(report, repair, repaired))
+	}
+
+	if report.HasErrors() {
+		return 1
+	}
+	return 0
+}
+
+// stateCopies returns the other copies of the given state: its stored
+// versions, if it stores them, and the backup file that's written beside a
+// local state file, if there is one.
+func (c *StateVerifyCommand) stateCopies(sMgr state.State) ([]*stateverify.Copy, error) {
+	copies := []*stateverify.Copy{}
+
+	if h, ok := sMgr.(state.History); ok {
+		versions, err := h.StateVersions()
+		switch {
+		case err == state.ErrHistoryNotSupported:
+		case err != nil:
+			return nil, fmt.Errorf("Failed to read the state history: %s", err)
+		}
+		for _, v := range versions {
+			s, err := h.ReadStateVersion(v)
+			copies = append(copies, &stateverify.Copy{
+				Name:    fmt.Sprintf("version %d", v.Serial),
+				Version: v,
+				State:   s,
+				Err:     err,
+			})
+		}
+	}
+
+	if path := c.stateBackupFile(); path != "" {
+		f, err := os.Open(path)
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			copies = append(copies, &stateverify.Copy{Name: path, Err: err})
+		default:
+			s, err := terraform.ReadState(f)
+			f.Close()
+			copies = append(copies, &stateverify.Copy{Name: path, State: s, Err: err})
+		}
+	}
+
+	return copies, nil
+}
+
+// stateBackupFile returns the path of the backup file that's written beside
+// the state file when the state is local, or "" if it isn't.
+func (c *StateVerifyCommand) stateBackupFile() string {
+	if c.statePath != "" {
+		return c.statePath + DefaultBackupExtension
+	}
+	b, err := c.Backend(nil)
+	if err != nil {
+		return ""
+	}
+	local, ok := b.(*backendlocal.Local)
+	if !ok || local.Backend != nil {
+		// The local state of a remote backend is only a cache.
+		return ""
+	}
+	_, _, backupPath := local.StatePaths(c.Workspace())
+	return backupPath
+}
+
+// verifyProviders returns the installed providers of the resources of the
+// given state, with the current schema versions of their resource types.
+// Providers that aren't installed are left out.
+func (c *StateVerifyCommand) verifyProviders(s *terraform.State) map[string]*stateverify.Provider {
+	types := make(map[string]map[string]bool)
+	for _, ms := range s.Modules {
+		for _, rs := range ms.Resources {
+			name := config.ResourceProviderFullName(rs.Type, rs.Provider)
+			if i := strings.IndexByte(name, '.'); i >= 0 {
+				name = name[:i]
+			}
+			if types[name] == nil {
+				types[name] = make(map[string]bool)
+			}
+			types[name][rs.Type] = true
+		}
+	}
+
+	var resolver terraform.ResourceProviderResolver
+	if c.testingOverrides != nil {
+		resolver = c.testingOverrides.ProviderResolver
+	} else {
+		resolver = c.providerResolver()
+	}
+	reqd := terraform.ModuleTreeDependencies(nil, s).AllPluginRequirements()
+	factories, errs := resolver.ResolveProviders(reqd)
+	for _, err := range errs {
+		log.Printf("[DEBUG] state verify: %s", err)
+	}
+
+	ret := make(map[string]*stateverify.Provider)
+	for name, f := range factories {
+		pv := &stateverify.Provider{}
+		ret[name] = pv
+
+		p, err := f()
+		if err != nil {
+			log.Printf("[WARN] state verify: failed to start provider %q: %s", name, err)
+			continue
+		}
+		var req terraform.ProviderSchemaRequest
+		for t := range types[name] {
+			req.ResourceTypes = append(req.ResourceTypes, t)
+		}
+		sort.Strings(req.ResourceTypes)
+		schema, err := p.GetSchema(&req)
+		if err != nil {
+			log.Printf("[WARN] state verify: failed to get the schema of provider %q: %s", name, err)
+		} else if schema != nil {
+			pv.SchemaVersions = schema.ResourceTypeSchemaVersions
+		}
+		if closer, ok := p.(terraform.ResourceProviderCloser); ok {
+			closer.Close()
+		}
+	}
+	return ret
+}
+
+// formatStateVerifyReport formats the given report for humans.
+func formatStateVerifyReport(r *stateverify.Report, repair bool, repaired int) string {
+	var buf strings.Builder
+	if len(r.Problems) == 0 {
+		fmt.Fprintf(&buf, "No problems were found in serial %d of the state.", r.Serial)
+		return buf.String()
+	}
+
+	var errs, warnings, repairable int
+	for _, p := range r.Problems {
+		severity := "Warning"
+		if p.Severity == stateverify.SeverityError {
+			severity = "Error"
+			errs++
+		} else {
+			warnings++
+		}
+		note := ""
+		switch {
+		case p.Repaired:
+			note = " (repaired)"
+		case p.Repairable:
+			note = " (repairable)"
+			repairable++
+		}
+		fmt.Fprintf(&buf, "%s: %s: %s%s\n", severity, p.Addr, p.Summary, note)
+	}
+
+	fmt.Fprintf(&buf, "\nFound %d error(s) and %d warning(s) in serial %d of the state.", errs, warnings, r.Serial)
+	switch {
+	case repaired > 0:
+		fmt.Fprintf(&buf, " Repaired %d of them.", repaired)
+	case repairable > 0 && !repair:
+		fmt.Fprintf(&buf, " Run with -repair to repair the %d that can be repaired.", repairable)
+	}
+	return buf.String()
+}
+
+func (c *StateVerifyCommand) Help() string {
+	helpText := `
+Usage: terraform state verify [options] [DIR]
+
+  Check the integrity of the state, and report any problems found.
+
+  The schema version recorded for each object is checked against the
+  installed provider of its resource, which must support it. The lineage
+  and serial of the state are checked against its stored versions, as
+  listed by "terraform state history", and against its backup file, if
+  it's local. The dependencies of each resource must be in the state, and
+  each module entry must be unique, not empty, and, if the configuration
+  in DIR is given, configured.
+
+  The command exits with status 1 if any problem found is an error. Some
+  warnings can be repaired with -repair, which only removes entries from
+  the state that Terraform doesn't use. Errors are never repaired.
+
+Options:
+
+  -backup=PATH        Path where Terraform should write the backup
+                      state when repairing. This can't be disabled. If not
+                      set, Terraform will write it to the same path as the
+                      statefile with a backup extension.
+
+  -json               Write the report as a JSON document.
+
+  -lock=true          Lock the state file when repairing, when locking is
+                      supported.
+
+  -lock-timeout=0s    Duration to retry a state lock.
+
+  -repair             Repair the problems that are safe to repair, and
+                      write the repaired state.
+
+  -state=statefile    Path to a Terraform state file to verify. If
+                      unspecified, the state of the configured backend is
+                      verified.
+
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *StateVerifyCommand) Synopsis() string {
+	return "Check the integrity of the state"
+}
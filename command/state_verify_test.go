@@ -0,0 +1,136 @@
+package command
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/command/stateverify"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
+)
+
+func TestStateVerify(t *testing.T) {
+	statePath, _, second := testStateHistory(t)
+
+	ui := cli.NewMockUi()
+	c := &StateVerifyCommand{
+		StateMeta{
+			Meta: Meta{
+				testingOverrides: metaOverridesForProvider(testProvider()),
+				Ui:               ui,
+			},
+		},
+	}
+	args := []string{
+		"-state", statePath,
+		testTempDir(t),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	expected := "No problems were found in serial " + formatSerial(second) + " of the state."
+	if actual := strings.TrimSpace(ui.OutputWriter.String()); actual != expected {
+		t.Fatalf("wrong output\ngot:  %s\nwant: %s", actual, expected)
+	}
+}
+
+func TestStateVerify_schemaVersion(t *testing.T) {
+	s := terraform.NewState()
+	s.RootModule().Resources = map[string]*terraform.ResourceState{
+		"test_instance.foo": &terraform.ResourceState{
+			Type: "test_instance",
+			Primary: &terraform.InstanceState{
+				ID:   "foo",
+				Meta: map[string]interface{}{"schema_version": "2"},
+			},
+		},
+	}
+	statePath := testStateFile(t, s)
+
+	p := testProvider()
+	p.GetSchemaReturn = &terraform.ProviderSchema{
+		ResourceTypeSchemaVersions: map[string]uint64{"test_instance": 1},
+	}
+	ui := cli.NewMockUi()
+	c := &StateVerifyCommand{
+		StateMeta{
+			Meta: Meta{
+				testingOverrides: metaOverridesForProvider(p),
+				Ui:               ui,
+			},
+		},
+	}
+	args := []string{
+		"-state", statePath,
+		testTempDir(t),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("wrong exit status %d; want 1\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	if got, want := p.GetSchemaRequest.ResourceTypes, []string{"test_instance"}; len(got) != 1 || got[0] != want[0] {
+		t.Fatalf("wrong resource types requested %#v; want %#v", got, want)
+	}
+	output := ui.OutputWriter.String()
+	if !strings.Contains(output, "Error: test_instance.foo: The object was written with schema version 2") {
+		t.Fatalf("schema version error not reported:\n%s", output)
+	}
+}
+
+func TestStateVerify_repair(t *testing.T) {
+	s := terraform.NewState()
+	s.RootModule().Resources = map[string]*terraform.ResourceState{
+		"test_instance.foo": &terraform.ResourceState{
+			Type:         "test_instance",
+			Dependencies: []string{"test_instance.gone"},
+			Primary:      &terraform.InstanceState{ID: "foo"},
+		},
+	}
+	s.AddModule([]string{"root", "empty"})
+	statePath := testStateFile(t, s)
+
+	ui := cli.NewMockUi()
+	c := &StateVerifyCommand{
+		StateMeta{
+			Meta: Meta{
+				testingOverrides: metaOverridesForProvider(testProvider()),
+				Ui:               ui,
+			},
+		},
+	}
+	args := []string{
+		"-state", statePath,
+		"-repair",
+		"-json",
+		testTempDir(t),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var report stateverify.Report
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &report); err != nil {
+		t.Fatalf("invalid report: %s\n%s", err, ui.OutputWriter.String())
+	}
+	if len(report.Problems) != 2 {
+		t.Fatalf("wrong number of problems %d; want 2\n%s", len(report.Problems), ui.OutputWriter.String())
+	}
+	for _, p := range report.Problems {
+		if !p.Repaired {
+			t.Fatalf("problem not repaired: %#v", p)
+		}
+	}
+
+	actual := testStateRead(t, statePath)
+	if deps := actual.RootModule().Resources["test_instance.foo"].Dependencies; len(deps) != 0 {
+		t.Fatalf("dangling dependency not removed: %#v", deps)
+	}
+	if actual.ModuleByPath([]string{"root", "empty"}) != nil {
+		t.Fatalf("empty module not removed:\n%s", actual)
+	}
+	if actual.Serial <= s.Serial {
+		t.Fatalf("serial %d not incremented from %d", actual.Serial, s.Serial)
+	}
+}
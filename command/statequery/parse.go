@@ -0,0 +1,219 @@
+package statequery
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// tokenType is the type of a token of a query.
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenLParen
+	tokenRParen
+	tokenOp
+	tokenString
+	tokenWord
+)
+
+type token struct {
+	Type  tokenType
+	Value string
+	Pos   int
+}
+
+func (t token) String() string {
+	switch t.Type {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return strconv.Quote(t.Value)
+	default:
+		return fmt.Sprintf("%q", t.Value)
+	}
+}
+
+// lex splits the given query into tokens, ending with a tokenEOF.
+func lex(src string) ([]token, error) {
+	var result []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			result = append(result, token{tokenLParen, "(", i})
+			i++
+		case c == ')':
+			result = append(result, token{tokenRParen, ")", i})
+			i++
+		case c == '=' || c == '!':
+			if i+1 < len(src) && (src[i+1] == '=' || src[i+1] == '~') {
+				result = append(result, token{tokenOp, src[i : i+2], i})
+				i += 2
+				continue
+			}
+			return nil, fmt.Errorf("unexpected %q at position %d", c, i+1)
+		case c == '"':
+			end := i + 1
+			for ; end < len(src) && src[end] != '"'; end++ {
+				if src[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("unterminated string at position %d", i+1)
+			}
+			v, err := strconv.Unquote(src[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %s", i+1, err)
+			}
+			result = append(result, token{tokenString, v, i})
+			i = end + 1
+		case isWordChar(c):
+			end := i
+			for end < len(src) && isWordChar(src[end]) {
+				end++
+			}
+			result = append(result, token{tokenWord, src[i:end], i})
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected %q at position %d", c, i+1)
+		}
+	}
+	return append(result, token{tokenEOF, "", len(src)}), nil
+}
+
+// isWordChar returns true for the characters of unquoted fields and
+// values, which include those of resource addresses and IDs, and the
+// anchors of regular expressions.
+func isWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		strings.IndexByte("_-.:/*[]%#@+^$", c) >= 0
+}
+
+// parser is a recursive descent parser of the grammar:
+//
+//	expr       = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" expr ")" | comparison
+//	comparison = field ( "==" | "!=" | "=~" | "!~" ) value
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.Type != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) keyword(word string) bool {
+	t := p.peek()
+	if t.Type == tokenWord && t.Value == word {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseExpr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.keyword("not") {
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{n}, nil
+	}
+
+	if p.peek().Type == tokenLParen {
+		p.next()
+		n, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.Type != tokenRParen {
+			return nil, unexpected(t, "\")\"")
+		}
+		return n, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	t := p.next()
+	if t.Type != tokenWord || t.Value == "and" || t.Value == "or" {
+		return nil, unexpected(t, "a field")
+	}
+	field := t.Value
+	if !validField(field) {
+		return nil, fmt.Errorf(
+			"unknown field %q at position %d; the fields are %s, and attr.NAME for attributes",
+			field, t.Pos+1, strings.Join(fields, ", "))
+	}
+
+	op := p.next()
+	if op.Type != tokenOp {
+		return nil, unexpected(op, "an operator: ==, !=, =~ or !~")
+	}
+
+	v := p.next()
+	if v.Type != tokenWord && v.Type != tokenString {
+		return nil, unexpected(v, "a value")
+	}
+
+	n := &compareNode{Field: field, Op: op.Value, Value: v.Value}
+	if op.Value == "=~" || op.Value == "!~" {
+		re, err := regexp.Compile(v.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression at position %d: %s", v.Pos+1, err)
+		}
+		n.Regexp = re
+	}
+	return n, nil
+}
+
+func unexpected(t token, want string) error {
+	return fmt.Errorf("expected %s at position %d, but found %s", want, t.Pos+1, t)
+}
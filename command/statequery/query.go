@@ -0,0 +1,268 @@
+// Package statequery implements the small filter language of the
+// "terraform state query" command, which selects the resource instances of
+// a state by their type, provider, module, attributes and so on.
+//
+// A query is a comparison of a field with a value, such as
+//
+//	type == aws_instance
+//
+// and comparisons can be combined with "and", "or", "not" and parentheses.
+// The operators are == and != for exact matches, and =~ and !~ for regular
+// expression matches. Values containing spaces or other special characters
+// must be double quoted.
+package statequery
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// fields are the names of the fields of an Object that a query can
+// compare, other than its attributes.
+var fields = []string{"address", "id", "mode", "module", "name", "provider", "tainted", "type"}
+
+// attrPrefix is the prefix of fields that compare an attribute.
+const attrPrefix = "attr."
+
+func validField(name string) bool {
+	if strings.HasPrefix(name, attrPrefix) && len(name) > len(attrPrefix) {
+		return true
+	}
+	for _, f := range fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Query is a parsed query.
+type Query struct {
+	root node
+}
+
+// Parse parses the given query.
+func Parse(src string) (*Query, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	if tokens[0].Type == tokenEOF {
+		return nil, fmt.Errorf("the query is empty")
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.next(); t.Type != tokenEOF {
+		return nil, unexpected(t, "\"and\", \"or\" or the end of the query")
+	}
+	return &Query{root: root}, nil
+}
+
+// Match returns true if the given object matches the query.
+func (q *Query) Match(o *Object) bool {
+	return q.root.match(o)
+}
+
+// Filter returns the objects of the given state that match the query,
+// sorted by address.
+func (q *Query) Filter(s *terraform.State) ([]*Object, error) {
+	objects, err := Objects(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Object
+	for _, o := range objects {
+		if q.Match(o) {
+			result = append(result, o)
+		}
+	}
+	return result, nil
+}
+
+// Object is a resource instance of a state, with the fields that a query
+// can compare.
+type Object struct {
+	Address string
+
+	// Module is the address of the module of the instance, such as
+	// "module.foo.module.bar", or "" in the root module.
+	Module string
+
+	Mode string
+	Type string
+	Name string
+
+	// Index is the index of the instance, or -1 if its resource doesn't
+	// have a count.
+	Index int
+
+	// Provider is the name of the provider of the instance, with its alias
+	// if any, such as "aws" or "aws.west".
+	Provider string
+
+	ID         string
+	Tainted    bool
+	Attributes map[string]string
+}
+
+// field returns the value of the field with the given name. A missing
+// attribute has the value "".
+func (o *Object) field(name string) string {
+	switch name {
+	case "address":
+		return o.Address
+	case "id":
+		return o.ID
+	case "mode":
+		return o.Mode
+	case "module":
+		return o.Module
+	case "name":
+		return o.Name
+	case "provider":
+		return o.Provider
+	case "tainted":
+		if o.Tainted {
+			return "true"
+		}
+		return "false"
+	case "type":
+		return o.Type
+	default:
+		return o.Attributes[strings.TrimPrefix(name, attrPrefix)]
+	}
+}
+
+// Objects returns the primary resource instances of the given state,
+// sorted by address.
+func Objects(s *terraform.State) ([]*Object, error) {
+	var objects []*Object
+	var addrs []*terraform.ResourceAddress
+	for _, ms := range s.Modules {
+		for key, rs := range ms.Resources {
+			if rs.Primary == nil {
+				continue
+			}
+			addr, err := terraform.ParseResourceAddressForInstanceDiff(ms.Path[1:], key)
+			if err != nil {
+				return nil, err
+			}
+
+			o := &Object{
+				Address:    addr.String(),
+				Module:     moduleAddress(addr.Path),
+				Mode:       "managed",
+				Type:       addr.Type,
+				Name:       addr.Name,
+				Index:      addr.Index,
+				Provider:   providerName(rs.Provider),
+				ID:         rs.Primary.ID,
+				Tainted:    rs.Primary.Tainted,
+				Attributes: rs.Primary.Attributes,
+			}
+			if addr.Mode == config.DataResourceMode {
+				o.Mode = "data"
+			}
+			if o.Attributes == nil {
+				o.Attributes = map[string]string{}
+			}
+			objects = append(objects, o)
+			addrs = append(addrs, addr)
+		}
+	}
+
+	sort.Sort(&objectsByAddress{objects, addrs})
+	return objects, nil
+}
+
+type objectsByAddress struct {
+	objects []*Object
+	addrs   []*terraform.ResourceAddress
+}
+
+func (s *objectsByAddress) Len() int { return len(s.objects) }
+func (s *objectsByAddress) Swap(i, j int) {
+	s.objects[i], s.objects[j] = s.objects[j], s.objects[i]
+	s.addrs[i], s.addrs[j] = s.addrs[j], s.addrs[i]
+}
+func (s *objectsByAddress) Less(i, j int) bool { return s.addrs[i].Less(s.addrs[j]) }
+
+func moduleAddress(path []string) string {
+	parts := make([]string, len(path))
+	for i, name := range path {
+		parts[i] = "module." + name
+	}
+	return strings.Join(parts, ".")
+}
+
+// providerName returns the name of the provider from the reference to it
+// stored in the state, such as "provider.aws.west" or
+// "module.foo.provider.aws".
+func providerName(ref string) string {
+	if i := strings.LastIndex(ref, "provider."); i >= 0 {
+		return ref[i+len("provider."):]
+	}
+	return ref
+}
+
+// node is a node of the syntax tree of a query.
+type node interface {
+	match(*Object) bool
+}
+
+type andNode struct {
+	Left, Right node
+}
+
+func (n *andNode) match(o *Object) bool {
+	return n.Left.match(o) && n.Right.match(o)
+}
+
+type orNode struct {
+	Left, Right node
+}
+
+func (n *orNode) match(o *Object) bool {
+	return n.Left.match(o) || n.Right.match(o)
+}
+
+type notNode struct {
+	Node node
+}
+
+func (n *notNode) match(o *Object) bool {
+	return !n.Node.match(o)
+}
+
+type compareNode struct {
+	Field  string
+	Op     string
+	Value  string
+	Regexp *regexp.Regexp
+}
+
+func (n *compareNode) match(o *Object) bool {
+	v := o.field(n.Field)
+	switch n.Op {
+	case "==":
+		return v == n.Value
+	case "!=":
+		return v != n.Value
+	case "=~":
+		return n.Regexp.MatchString(v)
+	case "!~":
+		return !n.Regexp.MatchString(v)
+	default:
+		panic(fmt.Sprintf("unknown operator %q", n.Op))
+	}
+}
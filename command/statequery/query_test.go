@@ -0,0 +1,173 @@
+package statequery
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestQuery(t *testing.T) {
+	s := testQueryState()
+
+	cases := []struct {
+		Query string
+		Want  []string
+	}{
+		{
+			"type == aws_instance",
+			[]string{"aws_instance.web[0]", "aws_instance.web[1]", "module.network.aws_instance.nat"},
+		},
+		{
+			"type == aws_instance and attr.tags.Env == prod",
+			[]string{"aws_instance.web[0]"},
+		},
+		{
+			`module =~ "^module.network"`,
+			[]string{"module.network.aws_instance.nat"},
+		},
+		{
+			"module == \"\" and not mode == data",
+			[]string{"aws_instance.web[0]", "aws_instance.web[1]"},
+		},
+		{
+			"provider == aws.west or mode == data",
+			[]string{"data.aws_ami.ubuntu", "module.network.aws_instance.nat"},
+		},
+		{
+			"not (mode == data or tainted == true)",
+			[]string{"aws_instance.web[0]", "module.network.aws_instance.nat"},
+		},
+		{
+			"attr.missing != \"\"",
+			nil,
+		},
+		{
+			"id !~ ^i-",
+			[]string{"data.aws_ami.ubuntu"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Query, func(t *testing.T) {
+			q, err := Parse(tc.Query)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			results, err := q.Filter(s)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			var got []string
+			for _, o := range results {
+				got = append(got, o.Address)
+			}
+			if !reflect.DeepEqual(got, tc.Want) {
+				t.Fatalf("wrong results\ngot:  %#v\nwant: %#v", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	cases := map[string]string{
+		"":                        "empty",
+		"type":                    "expected an operator",
+		"type ==":                 "expected a value",
+		"color == red":            "unknown field \"color\"",
+		"type == a type == b":     "expected \"and\", \"or\"",
+		"(type == a":              "expected \")\"",
+		"type = a":                "unexpected '='",
+		`type == "a`:              "unterminated string",
+		"type =~ \"(\"":           "invalid regular expression",
+		"type == a and or b == c": "expected a field",
+	}
+
+	for src, want := range cases {
+		_, err := Parse(src)
+		if err == nil {
+			t.Fatalf("%s: no error; want %q", src, want)
+		}
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: wrong error %q; want %q", src, err, want)
+		}
+	}
+}
+
+func TestObjects(t *testing.T) {
+	objects, err := Objects(testQueryState())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var nat *Object
+	for _, o := range objects {
+		if o.Address == "module.network.aws_instance.nat" {
+			nat = o
+		}
+	}
+	want := &Object{
+		Address:    "module.network.aws_instance.nat",
+		Module:     "module.network",
+		Mode:       "managed",
+		Type:       "aws_instance",
+		Name:       "nat",
+		Index:      -1,
+		Provider:   "aws.west",
+		ID:         "i-nat",
+		Attributes: map[string]string{"id": "i-nat"},
+	}
+	if !reflect.DeepEqual(nat, want) {
+		t.Fatalf("wrong object\ngot:  %#v\nwant: %#v", nat, want)
+	}
+}
+
+func testQueryState() *terraform.State {
+	s := terraform.NewState()
+	root := s.RootModule()
+	root.Resources["aws_instance.web.0"] = &terraform.ResourceState{
+		Type:     "aws_instance",
+		Provider: "provider.aws",
+		Primary: &terraform.InstanceState{
+			ID: "i-web0",
+			Attributes: map[string]string{
+				"id":       "i-web0",
+				"tags.%":   "1",
+				"tags.Env": "prod",
+			},
+		},
+	}
+	root.Resources["aws_instance.web.1"] = &terraform.ResourceState{
+		Type:     "aws_instance",
+		Provider: "provider.aws",
+		Primary: &terraform.InstanceState{
+			ID:      "i-web1",
+			Tainted: true,
+			Attributes: map[string]string{
+				"id":       "i-web1",
+				"tags.%":   "1",
+				"tags.Env": "dev",
+			},
+		},
+	}
+	root.Resources["data.aws_ami.ubuntu"] = &terraform.ResourceState{
+		Type:     "aws_ami",
+		Provider: "provider.aws",
+		Primary: &terraform.InstanceState{
+			ID: "ami-123",
+		},
+	}
+
+	network := s.AddModule([]string{"root", "network"})
+	network.Resources["aws_instance.nat"] = &terraform.ResourceState{
+		Type:     "aws_instance",
+		Provider: "module.network.provider.aws.west",
+		Primary: &terraform.InstanceState{
+			ID:         "i-nat",
+			Attributes: map[string]string{"id": "i-nat"},
+		},
+	}
+	return s
+}
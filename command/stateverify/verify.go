@@ -0,0 +1,511 @@
+// Package stateverify implements the checks of "terraform state verify",
+// which checks the integrity of a state: that the installed providers
+// support the schema versions of its objects, that it's consistent with the
+// other copies of it that are stored, and that its dependencies and module
+// entries refer to things that exist.
+//
+// Each problem found is either an error, which Terraform may fail on or
+// which may mean that changes to the state were lost, or a warning, which
+// is harmless but stale. Some warnings can be repaired by Repair, which
+// only removes entries that Terraform doesn't use.
+package stateverify
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// Check is the name of one of the checks of Verify.
+type Check string
+
+const (
+	// CheckSchemaVersions checks that the schema version recorded for each
+	// object is supported by the installed provider of its resource.
+	CheckSchemaVersions Check = "schema_versions"
+
+	// CheckCopies checks that the lineage and serial of the state are
+	// consistent with those of its stored versions and backups.
+	CheckCopies Check = "copies"
+
+	// CheckDependencies checks that the dependencies of each resource
+	// are in the state.
+	CheckDependencies Check = "dependencies"
+
+	// CheckModules checks that each module entry is unique, has something
+	// in it and is in the configuration.
+	CheckModules Check = "modules"
+)
+
+// Severity is how serious a problem is.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Problem is a problem found by one of the checks.
+type Problem struct {
+	Check    Check    `json:"check"`
+	Severity Severity `json:"severity"`
+
+	// Addr is the address of the resource instance or module that the
+	// problem is with, or the name of the copy of the state, if it's with
+	// one of those.
+	Addr string `json:"address,omitempty"`
+
+	Summary string `json:"summary"`
+
+	// Repairable is set if Repair can repair the problem, and Repaired is
+	// set once it has.
+	Repairable bool `json:"repairable"`
+	Repaired   bool `json:"repaired"`
+
+	repair func(s *terraform.State)
+}
+
+// Report is the result of Verify.
+type Report struct {
+	Lineage string `json:"lineage"`
+	Serial  int64  `json:"serial"`
+
+	// Checks are the checks that were made, which exclude those whose
+	// inputs weren't given.
+	Checks []Check `json:"checks"`
+
+	// Problems are the problems found, in the order of the checks that
+	// found them and then of their addresses.
+	Problems []*Problem `json:"problems"`
+}
+
+// HasErrors returns true if any of the problems of the report are errors.
+func (r *Report) HasErrors() bool {
+	for _, p := range r.Problems {
+		if p.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Repair repairs the repairable problems of the report in the given state,
+// which must be the state that was verified, and returns how many were
+// repaired.
+func (r *Report) Repair(s *terraform.State) int {
+	n := 0
+	for _, p := range r.Problems {
+		if p.repair == nil || p.Repaired {
+			continue
+		}
+		p.repair(s)
+		p.Repaired = true
+		n++
+	}
+	return n
+}
+
+// Provider describes an installed provider, to check the objects of its
+// resources against.
+type Provider struct {
+	// SchemaVersions are the current schema versions of the resource types
+	// of the provider. It's nil if the provider doesn't report them.
+	SchemaVersions map[string]uint64
+}
+
+// Copy is another copy of a state, such as a stored version of it or a
+// backup file.
+type Copy struct {
+	// Name describes where the copy is, such as "version 3" or the path of
+	// a backup file.
+	Name string
+
+	// Version is what the copy is stored as, if it's a stored version of
+	// the state, in which case the copy's own lineage and serial must
+	// match it.
+	Version *state.StateVersion
+
+	// State is the copy, or nil if it couldn't be read, in which case Err
+	// is why.
+	State *terraform.State
+	Err   error
+}
+
+// Opts are the inputs of Verify other than the state. Checks whose inputs
+// aren't given are skipped.
+type Opts struct {
+	// Providers are the installed providers, by name, such as "aws". If
+	// it's nil, the schema versions aren't checked.
+	Providers map[string]*Provider
+
+	// Copies are the other copies of the state. If it's nil, the copies
+	// aren't checked.
+	Copies []*Copy
+
+	// Config is the configuration of the state. If it's nil, the module
+	// entries aren't checked against it.
+	Config *module.Tree
+}
+
+// Verify checks the integrity of the given state, which must not be nil.
+func Verify(s *terraform.State, opts *Opts) *Report {
+	if opts == nil {
+		opts = &Opts{}
+	}
+	r := &Report{
+		Lineage:  s.Lineage,
+		Serial:   s.Serial,
+		Problems: []*Problem{},
+	}
+
+	if opts.Providers != nil {
+		r.Checks = append(r.Checks, CheckSchemaVersions)
+		r.Problems = append(r.Problems, checkSchemaVersions(s, opts.Providers)...)
+	}
+	if opts.Copies != nil {
+		r.Checks = append(r.Checks, CheckCopies)
+		r.Problems = append(r.Problems, checkCopies(s, opts.Copies)...)
+	}
+	r.Checks = append(r.Checks, CheckDependencies, CheckModules)
+	r.Problems = append(r.Problems, checkDependencies(s)...)
+	r.Problems = append(r.Problems, checkModules(s, opts.Config)...)
+	return r
+}
+
+func checkSchemaVersions(s *terraform.State, providers map[string]*Provider) []*Problem {
+	var ret []*Problem
+	missing := make(map[string]bool)
+	for _, ms := range s.Modules {
+		for _, key := range sortedKeys(ms.Resources) {
+			rs := ms.Resources[key]
+			if rs == nil {
+				continue
+			}
+			addr := resourceAddr(ms.Path, key)
+			name := config.ResourceProviderFullName(rs.Type, rs.Provider)
+			if i := strings.IndexByte(name, '.'); i >= 0 {
+				name = name[:i]
+			}
+
+			p, ok := providers[name]
+			if !ok {
+				if !missing[name] {
+					missing[name] = true
+					ret = append(ret, &Problem{
+						Check:    CheckSchemaVersions,
+						Severity: SeverityWarning,
+						Addr:     "provider." + name,
+						Summary: fmt.Sprintf(
+							"The provider %q isn't installed, so the schema versions of its resources can't be checked.",
+							name),
+					})
+				}
+				continue
+			}
+
+			instances := []*terraform.InstanceState{rs.Primary}
+			instances = append(instances, rs.Deposed...)
+			for _, is := range instances {
+				if is == nil {
+					continue
+				}
+				raw, ok := is.Meta["schema_version"]
+				if !ok {
+					continue
+				}
+				v, err := strconv.ParseUint(fmt.Sprint(raw), 10, 64)
+				if err != nil {
+					ret = append(ret, &Problem{
+						Check:    CheckSchemaVersions,
+						Severity: SeverityError,
+						Addr:     addr,
+						Summary:  fmt.Sprintf("The recorded schema version %q isn't a valid version.", fmt.Sprint(raw)),
+					})
+					continue
+				}
+				current, ok := p.SchemaVersions[rs.Type]
+				if !ok || v <= current {
+					// Older versions are upgraded by the provider when
+					// the object is refreshed.
+					continue
+				}
+				ret = append(ret, &Problem{
+					Check:    CheckSchemaVersions,
+					Severity: SeverityError,
+					Addr:     addr,
+					Summary: fmt.Sprintf(
+						"The object was written with schema version %d of %s, but the installed provider %q only supports up to version %d. A newer version of the provider must be installed.",
+						v, rs.Type, name, current),
+				})
+				break
+			}
+		}
+	}
+	return ret
+}
+
+func checkCopies(s *terraform.State, copies []*Copy) []*Problem {
+	var ret []*Problem
+	seen := make(map[int64]*terraform.State)
+	for _, c := range copies {
+		problem := func(severity Severity, format string, args ...interface{}) {
+			ret = append(ret, &Problem{
+				Check:    CheckCopies,
+				Severity: severity,
+				Addr:     c.Name,
+				Summary:  fmt.Sprintf(format, args...),
+			})
+		}
+
+		if c.State == nil {
+			problem(SeverityError, "The copy can't be read: %s", c.Err)
+			continue
+		}
+		if v := c.Version; v != nil && (v.Lineage != c.State.Lineage || v.Serial != c.State.Serial) {
+			problem(SeverityError,
+				"The copy is stored as serial %d of lineage %s, but it's serial %d of lineage %s.",
+				v.Serial, v.Lineage, c.State.Serial, c.State.Lineage)
+			continue
+		}
+		if c.State.Lineage != s.Lineage {
+			// Copies of other lineages are of different infrastructure,
+			// such as from before the state was replaced, so they can't
+			// be compared.
+			if c.Version == nil {
+				problem(SeverityWarning,
+					"The copy has the lineage %s, which is different from the state's, so it isn't a copy of this state.",
+					c.State.Lineage)
+			}
+			continue
+		}
+
+		other, ok := seen[c.State.Serial]
+		switch {
+		case c.State.Serial > s.Serial:
+			problem(SeverityError,
+				"The copy has serial %d, which is newer than the state's serial %d, so changes to the state may have been lost.",
+				c.State.Serial, s.Serial)
+		case c.State.Serial == s.Serial && !c.State.Equal(s):
+			problem(SeverityError,
+				"The copy has the same serial as the state, %d, but different contents, so the state was changed without its serial being incremented.",
+				s.Serial)
+		case ok && !c.State.Equal(other):
+			problem(SeverityError,
+				"The copy has the same serial as another copy, %d, but different contents.",
+				c.State.Serial)
+		}
+		seen[c.State.Serial] = c.State
+	}
+	return ret
+}
+
+func checkDependencies(s *terraform.State) []*Problem {
+	var ret []*Problem
+	for _, ms := range s.Modules {
+		ms := ms
+		exists := make(map[string]bool)
+		for key := range ms.Resources {
+			exists[resourceName(key)] = true
+		}
+		dangling := func(dep string) bool {
+			name, ok := dependencyResource(dep)
+			return ok && !exists[name]
+		}
+
+		for _, key := range sortedKeys(ms.Resources) {
+			rs := ms.Resources[key]
+			if rs == nil {
+				continue
+			}
+			addr := resourceAddr(ms.Path, key)
+			for _, dep := range rs.Dependencies {
+				if !dangling(dep) {
+					continue
+				}
+				rs, dep := rs, dep
+				ret = append(ret, &Problem{
+					Check:    CheckDependencies,
+					Severity: SeverityWarning,
+					Addr:     addr,
+					Summary: fmt.Sprintf(
+						"The resource depends on %s, which isn't in the state, so the dependency has no effect.",
+						dep),
+					Repairable: true,
+					repair: func(*terraform.State) {
+						rs.Dependencies = removeString(rs.Dependencies, dep)
+					},
+				})
+			}
+		}
+		for _, dep := range ms.Dependencies {
+			if !dangling(dep) {
+				continue
+			}
+			dep := dep
+			ret = append(ret, &Problem{
+				Check:    CheckDependencies,
+				Severity: SeverityWarning,
+				Addr:     moduleAddr(ms.Path),
+				Summary: fmt.Sprintf(
+					"The module depends on %s, which isn't in the state, so the dependency has no effect.",
+					dep),
+				Repairable: true,
+				repair: func(*terraform.State) {
+					ms.Dependencies = removeString(ms.Dependencies, dep)
+				},
+			})
+		}
+	}
+	return ret
+}
+
+func checkModules(s *terraform.State, root *module.Tree) []*Problem {
+	var ret []*Problem
+	seen := make(map[string]bool)
+	for _, ms := range s.Modules {
+		if len(ms.Path) == 0 || ms.Path[0] != "root" {
+			ret = append(ret, &Problem{
+				Check:    CheckModules,
+				Severity: SeverityError,
+				Addr:     strings.Join(ms.Path, "."),
+				Summary:  "The module entry's path doesn't begin with the root module.",
+			})
+			continue
+		}
+		if len(ms.Path) == 1 {
+			continue
+		}
+		addr := moduleAddr(ms.Path)
+
+		if len(ms.Resources) == 0 && len(ms.Outputs) == 0 {
+			ms := ms
+			ret = append(ret, &Problem{
+				Check:      CheckModules,
+				Severity:   SeverityWarning,
+				Addr:       addr,
+				Summary:    "The module entry is empty, so it has no effect.",
+				Repairable: true,
+				repair: func(s *terraform.State) {
+					s.Modules = removeModule(s.Modules, ms)
+				},
+			})
+			continue
+		}
+
+		key := strings.Join(ms.Path, ".")
+		if seen[key] {
+			ret = append(ret, &Problem{
+				Check:    CheckModules,
+				Severity: SeverityError,
+				Addr:     addr,
+				Summary:  "There's more than one entry of the module, and only the first is used.",
+			})
+			continue
+		}
+		seen[key] = true
+
+		if root != nil && root.Child(ms.Path[1:]) == nil && len(ms.Resources) > 0 {
+			ret = append(ret, &Problem{
+				Check:    CheckModules,
+				Severity: SeverityWarning,
+				Addr:     addr,
+				Summary: fmt.Sprintf(
+					"The module isn't in the configuration, so its %d resources will be destroyed by the next apply.",
+					len(ms.Resources)),
+			})
+		}
+	}
+	return ret
+}
+
+// dependencyResource returns the name of the resource that the given
+// dependency is of, such as "aws_instance.web" or "data.aws_ami.ubuntu",
+// and whether it's of a resource in the same module at all.
+func dependencyResource(dep string) (string, bool) {
+	parts := strings.Split(dep, ".")
+	mode := ""
+	if parts[0] == "data" {
+		mode = "data."
+		parts = parts[1:]
+	}
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", false
+	}
+	switch parts[0] {
+	case "count", "local", "module", "path", "self", "terraform", "var":
+		return "", false
+	}
+	if len(parts) == 3 && parts[2] != "*" {
+		if _, err := strconv.Atoi(parts[2]); err != nil {
+			// An attribute of a resource written before dependencies
+			// were normalized, which we can't tell from anything else.
+			return "", false
+		}
+	}
+	return mode + parts[0] + "." + parts[1], true
+}
+
+// resourceName returns the name of the resource of the given state key,
+// without its index.
+func resourceName(key string) string {
+	parts := strings.Split(key, ".")
+	n := 2
+	if parts[0] == "data" {
+		n = 3
+	}
+	if len(parts) > n {
+		parts = parts[:n]
+	}
+	return strings.Join(parts, ".")
+}
+
+func resourceAddr(path []string, key string) string {
+	var modPath []string
+	if len(path) > 0 {
+		modPath = path[1:]
+	}
+	addr, err := terraform.ParseResourceAddressForInstanceDiff(modPath, key)
+	if err != nil {
+		return key
+	}
+	return addr.String()
+}
+
+func moduleAddr(path []string) string {
+	return "module." + strings.Join(path[1:], ".module.")
+}
+
+func removeModule(list []*terraform.ModuleState, ms *terraform.ModuleState) []*terraform.ModuleState {
+	ret := list[:0]
+	for _, v := range list {
+		if v != ms {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+func removeString(list []string, s string) []string {
+	ret := list[:0]
+	for _, v := range list {
+		if v != s {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+func sortedKeys(m map[string]*terraform.ResourceState) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,143 @@
+package stateverify
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestVerify(t *testing.T) {
+	s := testVerifyState()
+
+	copies := []*Copy{
+		{
+			Name:    "version 3",
+			Version: &state.StateVersion{Serial: 3, Lineage: "abc"},
+			State:   &terraform.State{Version: terraform.StateVersion, Serial: 3, Lineage: "abc"},
+		},
+		{
+			Name:    "version 2",
+			Version: &state.StateVersion{Serial: 2, Lineage: "abc"},
+			State:   &terraform.State{Version: terraform.StateVersion, Serial: 1, Lineage: "abc"},
+		},
+		{
+			Name:    "version 1",
+			Version: &state.StateVersion{Serial: 1, Lineage: "old"},
+			State:   &terraform.State{Version: terraform.StateVersion, Serial: 1, Lineage: "old"},
+		},
+		{
+			Name: "terraform.tfstate.backup",
+			Err:  errors.New("unexpected EOF"),
+		},
+	}
+	providers := map[string]*Provider{
+		"aws": {SchemaVersions: map[string]uint64{"aws_instance": 1}},
+	}
+
+	r := Verify(s, &Opts{Providers: providers, Copies: copies})
+
+	if got, want := r.Checks, []Check{CheckSchemaVersions, CheckCopies, CheckDependencies, CheckModules}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong checks %#v; want %#v", got, want)
+	}
+
+	type problem struct {
+		Check      Check
+		Severity   Severity
+		Addr       string
+		Repairable bool
+	}
+	var got []problem
+	for _, p := range r.Problems {
+		got = append(got, problem{p.Check, p.Severity, p.Addr, p.Repairable})
+	}
+	want := []problem{
+		{CheckSchemaVersions, SeverityError, "aws_instance.web", false},
+		{CheckSchemaVersions, SeverityWarning, "provider.google", false},
+		{CheckCopies, SeverityError, "version 3", false},
+		{CheckCopies, SeverityError, "version 2", false},
+		{CheckCopies, SeverityError, "terraform.tfstate.backup", false},
+		{CheckDependencies, SeverityWarning, "aws_instance.web", true},
+		{CheckModules, SeverityWarning, "module.empty", true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong problems\ngot:  %#v\nwant: %#v", got, want)
+	}
+	if !r.HasErrors() {
+		t.Fatal("report has no errors")
+	}
+
+	if n := r.Repair(s); n != 2 {
+		t.Fatalf("repaired %d problems; want 2", n)
+	}
+	if got, want := s.RootModule().Resources["aws_instance.web"].Dependencies, []string{"aws_subnet.main", "sg-123456"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong dependencies after repair %#v; want %#v", got, want)
+	}
+	if s.ModuleByPath([]string{"root", "empty"}) != nil {
+		t.Fatal("empty module not removed by repair")
+	}
+
+	r = Verify(s, nil)
+	if len(r.Problems) != 0 {
+		t.Fatalf("unexpected problems after repair: %#v", r.Problems)
+	}
+}
+
+func TestDependencyResource(t *testing.T) {
+	cases := map[string]string{
+		"aws_instance.web":      "aws_instance.web",
+		"aws_instance.web.*":    "aws_instance.web",
+		"aws_instance.web.2":    "aws_instance.web",
+		"data.aws_ami.ubuntu":   "data.aws_ami.ubuntu",
+		"data.aws_ami.ubuntu.0": "data.aws_ami.ubuntu",
+		"aws_instance.web.id":   "",
+		"module.network.vpc_id": "",
+		"local.name":            "",
+		"sg-123456":             "",
+	}
+	for dep, want := range cases {
+		got, ok := dependencyResource(dep)
+		if got != want || ok != (want != "") {
+			t.Errorf("dependencyResource(%q) = %q, %t; want %q", dep, got, ok, want)
+		}
+	}
+}
+
+func testVerifyState() *terraform.State {
+	return &terraform.State{
+		Version: terraform.StateVersion,
+		Serial:  3,
+		Lineage: "abc",
+		Modules: []*terraform.ModuleState{
+			{
+				Path: []string{"root"},
+				Resources: map[string]*terraform.ResourceState{
+					"aws_instance.web": {
+						Type:         "aws_instance",
+						Dependencies: []string{"aws_subnet.main", "aws_security_group.gone.*", "sg-123456"},
+						Primary: &terraform.InstanceState{
+							ID:   "i-abc123",
+							Meta: map[string]interface{}{"schema_version": "2"},
+						},
+					},
+					"aws_subnet.main": {
+						Type: "aws_subnet",
+						Primary: &terraform.InstanceState{
+							ID:   "subnet-abc123",
+							Meta: map[string]interface{}{"schema_version": "1"},
+						},
+					},
+					"google_compute_instance.web": {
+						Type:    "google_compute_instance",
+						Primary: &terraform.InstanceState{ID: "web"},
+					},
+				},
+			},
+			{
+				Path: []string{"root", "empty"},
+			},
+		},
+	}
+}
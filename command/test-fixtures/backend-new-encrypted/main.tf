@@ -0,0 +1,13 @@
+terraform {
+    backend "local" {
+        path = "local-state.tfstate"
+
+        state_encryption {
+            key_provider = "passphrase"
+        }
+    }
+}
+
+resource "test_instance" "foo" {
+    ami = "bar"
+}
@@ -0,0 +1,7 @@
+variable "n" {
+  default = 3
+}
+
+resource "test_instance" "foo" {
+  count = "${var.n}"
+}
@@ -0,0 +1,4 @@
+import {
+  to = "test_instance.foo"
+  id = "bar"
+}
@@ -0,0 +1,12 @@
+component "network" {
+  source = "./network"
+}
+
+component "app" {
+  source    = "./app"
+  workspace = "prod"
+
+  inputs {
+    subnet_id = "network.subnet_id"
+  }
+}
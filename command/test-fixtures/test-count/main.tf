@@ -0,0 +1,12 @@
+variable "n" {
+    default = 2
+}
+
+resource "test_instance" "a" {
+    count = "${var.n}"
+    ami   = "bar"
+}
+
+output "ids" {
+    value = "${test_instance.a.*.id}"
+}
@@ -0,0 +1,15 @@
+variables {
+    n = 2
+}
+
+run "create" {
+    assert {
+        resource = "test_instance.a[1]"
+        action   = "create"
+    }
+
+    assert {
+        output = "ids"
+        equals = ["foo", "foo"]
+    }
+}
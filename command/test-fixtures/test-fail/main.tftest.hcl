@@ -0,0 +1,7 @@
+run "create" {
+    assert {
+        output        = "ami"
+        equals        = "baz"
+        error_message = "The AMI must be baz."
+    }
+}
@@ -0,0 +1,11 @@
+variable "ami" {
+    default = "bar"
+}
+
+resource "test_instance" "foo" {
+    ami = "${var.ami}"
+}
+
+output "ami" {
+    value = "${test_instance.foo.ami}"
+}
@@ -0,0 +1,11 @@
+run "create" {
+    assert {
+        resource = "test_instance.foo"
+        action   = "create"
+    }
+
+    assert {
+        output = "ami"
+        equals = "bar"
+    }
+}
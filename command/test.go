@@ -0,0 +1,172 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/moduletest"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// TestCommand is a Command implementation that runs the test files of a
+// module.
+type TestCommand struct {
+	Meta
+}
+
+func (c *TestCommand) Run(args []string) int {
+	args, err := c.Meta.process(args, true)
+	if err != nil {
+		return 1
+	}
+
+	cmdFlags := c.Meta.flagSet("test")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+
+	configPath, err := ModulePath(cmdFlags.Args())
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	// Check for user-supplied plugin path
+	if c.pluginPath, err = c.loadPluginPath(); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error loading plugin path: %s", err))
+		return 1
+	}
+
+	files, err := moduletest.LoadDir(configPath)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	if len(files) == 0 {
+		c.Ui.Error(fmt.Sprintf(
+			"No test files found in %s or its \"tests\" directory.\n\n"+
+				"Test files have the extension %q.",
+			configPath, moduletest.FileExtension))
+		return 1
+	}
+
+	mod, diags := c.Module(configPath)
+	if diags.HasErrors() {
+		c.showDiagnostics(diags)
+		return 1
+	}
+	c.showDiagnostics(diags)
+
+	runner := &moduletest.Runner{
+		Module: mod,
+		ContextOpts: func() *terraform.ContextOpts {
+			opts := c.contextOpts()
+
+			// The progress of each operation isn't shown, only the
+			// results of the runs.
+			opts.Hooks = c.ExtraHooks
+			return opts
+		},
+	}
+
+	var passed, failed int
+	for _, f := range files {
+		result := runner.RunFile(f)
+		c.outputFileResult(result)
+
+		if result.Status() == moduletest.StatusPass {
+			passed++
+		} else {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		c.Ui.Error(c.Colorize().Color(fmt.Sprintf(
+			"\n[reset][bold][red]Failure![reset] %d passed, %d failed.", passed, failed)))
+		return 1
+	}
+
+	c.Ui.Output(c.Colorize().Color(fmt.Sprintf(
+		"\n[reset][bold][green]Success![reset] %d passed, %d failed.", passed, failed)))
+	return 0
+}
+
+func (c *TestCommand) outputFileResult(result *moduletest.FileResult) {
+	c.Ui.Output(c.Colorize().Color(fmt.Sprintf(
+		"%s... %s", result.Path, testStatusColor(result.Status()))))
+
+	for _, run := range result.Runs {
+		c.Ui.Output(c.Colorize().Color(fmt.Sprintf(
+			"  run %q... %s", run.Name, testStatusColor(run.Status))))
+
+		for _, msg := range run.Failures {
+			c.Ui.Output(fmt.Sprintf("    %s", msg))
+		}
+		if run.Err != nil {
+			c.Ui.Error(indentString(run.Err.Error(), "    "))
+		}
+	}
+
+	if result.DestroyErr != nil {
+		c.Ui.Error(indentString(result.DestroyErr.Error(), "  "))
+		c.Ui.Error(fmt.Sprintf(
+			"  Terraform left the following objects, which must be destroyed by hand:\n\n%s",
+			indentString(result.State.String(), "    ")))
+	}
+}
+
+func testStatusColor(status moduletest.Status) string {
+	switch status {
+	case moduletest.StatusPass:
+		return "[green]pass[reset]"
+	case moduletest.StatusFail, moduletest.StatusError:
+		return fmt.Sprintf("[red]%s[reset]", status)
+	default:
+		return fmt.Sprintf("[yellow]%s[reset]", status)
+	}
+}
+
+func indentString(s, indent string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i, l := range lines {
+		lines[i] = indent + l
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (c *TestCommand) Help() string {
+	helpText := `
+Usage: terraform test [options] [DIR]
+
+  Runs the test files of the module in the given directory, or the
+  current directory if none is given.
+
+  The test files are the files with the extension ".tftest.hcl" in the
+  directory and in its "tests" subdirectory. The runs of each file plan,
+  and optionally apply, the module and check their assertions, after
+  which everything they created is destroyed.
+
+  The module must have been initialized with "terraform init", and tests
+  that apply create real infrastructure.
+
+Options:
+
+  -no-color           If specified, output won't contain any color.
+
+  -var 'foo=bar'      Set a variable in the Terraform configuration for
+                      every run. This flag can be set multiple times.
+                      Variables set in the test files override it.
+
+  -var-file=foo       Set variables in the Terraform configuration from
+                      a file. If "terraform.tfvars" or any ".auto.tfvars"
+                      files are present, they will be automatically loaded.
+
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *TestCommand) Synopsis() string {
+	return "Runs the tests of a module"
+}
@@ -0,0 +1,148 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
+)
+
+func TestTest(t *testing.T) {
+	p := testTestProvider()
+	ui := new(cli.MockUi)
+	c := &TestCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-no-color",
+		testFixturePath("test"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	for _, want := range []string{`run "create"... pass`, "Success! 1 passed, 0 failed."} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("output should contain %q:\n%s", want, output)
+		}
+	}
+
+	if !p.ApplyCalled {
+		t.Fatal("apply should be called")
+	}
+}
+
+func TestTest_fail(t *testing.T) {
+	ui := new(cli.MockUi)
+	c := &TestCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testTestProvider()),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-no-color",
+		testFixturePath("test-fail"),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	for _, want := range []string{`run "create"... fail`, "The AMI must be baz."} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("output should contain %q:\n%s", want, output)
+		}
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "Failure! 0 passed, 1 failed.") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
+
+func TestTest_noFiles(t *testing.T) {
+	ui := new(cli.MockUi)
+	c := &TestCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		testFixturePath("graph"),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "No test files found") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
+
+// testTestProvider returns a mock provider that creates objects with the
+// attributes of their configuration.
+func testTestProvider() *terraform.MockResourceProvider {
+	p := testProvider()
+	p.DiffFn = func(
+		info *terraform.InstanceInfo,
+		s *terraform.InstanceState,
+		c *terraform.ResourceConfig) (*terraform.InstanceDiff, error) {
+		if s != nil && s.ID != "" {
+			return nil, nil
+		}
+
+		ami, _ := c.Config["ami"].(string)
+		return &terraform.InstanceDiff{
+			Attributes: map[string]*terraform.ResourceAttrDiff{
+				"ami": &terraform.ResourceAttrDiff{New: ami},
+			},
+		}, nil
+	}
+	p.ApplyFn = func(
+		info *terraform.InstanceInfo,
+		s *terraform.InstanceState,
+		d *terraform.InstanceDiff) (*terraform.InstanceState, error) {
+		if d.Destroy {
+			return nil, nil
+		}
+
+		return &terraform.InstanceState{
+			ID:         "foo",
+			Attributes: map[string]string{"ami": d.Attributes["ami"].New},
+		}, nil
+	}
+
+	return p
+}
+
+func TestTest_count(t *testing.T) {
+	ui := cli.NewMockUi()
+	c := &TestCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testTestProvider()),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-no-color",
+		testFixturePath("test-count"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s\n%s", code, ui.OutputWriter.String(), ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	for _, want := range []string{`run "create"... pass`, "Success! 1 passed, 0 failed."} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("output should contain %q:\n%s", want, output)
+		}
+	}
+}
@@ -1,12 +1,14 @@
 package command
 
 import (
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"strings"
 
 	"github.com/hashicorp/terraform/tfdiags"
 
+	"github.com/hashicorp/terraform/command/format"
 	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/terraform"
 )
@@ -23,10 +25,11 @@ func (c *ValidateCommand) Run(args []string) int {
 	if err != nil {
 		return 1
 	}
-	var checkVars bool
+	var checkVars, jsonOutput bool
 
 	cmdFlags := c.Meta.flagSet("validate")
 	cmdFlags.BoolVar(&checkVars, "check-variables", true, "check-variables")
+	cmdFlags.BoolVar(&jsonOutput, "json", false, "json")
 	cmdFlags.Usage = func() {
 		c.Ui.Error(c.Help())
 	}
@@ -54,9 +57,18 @@ func (c *ValidateCommand) Run(args []string) int {
 		return 1
 	}
 
-	rtnCode := c.validate(dir, checkVars)
+	diags := c.validate(dir, checkVars)
 
-	return rtnCode
+	if jsonOutput {
+		return c.showJSON(diags)
+	}
+
+	c.showDiagnostics(diags)
+	if diags.HasErrors() {
+		return 1
+	}
+
+	return 0
 }
 
 func (c *ValidateCommand) Synopsis() string {
@@ -84,6 +96,11 @@ Options:
   -check-variables=true If set to true (default), the command will check
                         whether all required variables have been specified.
 
+  -json                 Write the result of validation to stdout as a JSON
+                        document, with each error and warning described by
+                        its code and the range of configuration it refers
+                        to, instead of the human-readable messages.
+
   -no-color             If specified, output won't contain any color.
 
   -var 'foo=bar'        Set a variable in the Terraform configuration. This
@@ -96,29 +113,26 @@ Options:
 	return strings.TrimSpace(helpText)
 }
 
-func (c *ValidateCommand) validate(dir string, checkVars bool) int {
+func (c *ValidateCommand) validate(dir string, checkVars bool) tfdiags.Diagnostics {
 	var diags tfdiags.Diagnostics
 
 	cfg, err := config.LoadDir(dir)
 	if err != nil {
-		diags = diags.Append(err)
-		c.showDiagnostics(err)
-		return 1
+		diags = diags.Append(err).WithCode(tfdiags.CodeConfigLoad)
+		return diags
 	}
 
-	diags = diags.Append(cfg.Validate())
+	diags = diags.Append(cfg.Validate().WithCode(tfdiags.CodeConfigInvalid))
 
 	if diags.HasErrors() {
-		c.showDiagnostics(diags)
-		return 1
+		return diags
 	}
 
 	if checkVars {
 		mod, modDiags := c.Module(dir)
 		diags = diags.Append(modDiags)
 		if modDiags.HasErrors() {
-			c.showDiagnostics(diags)
-			return 1
+			return diags
 		}
 
 		opts := c.contextOpts()
@@ -127,17 +141,54 @@ func (c *ValidateCommand) validate(dir string, checkVars bool) int {
 		tfCtx, err := terraform.NewContext(opts)
 		if err != nil {
 			diags = diags.Append(err)
-			c.showDiagnostics(diags)
-			return 1
+			return diags
 		}
 
 		diags = diags.Append(tfCtx.Validate())
 	}
 
-	c.showDiagnostics(diags)
-	if diags.HasErrors() {
+	return diags
+}
+
+// ValidateJSONFormatVersion is the version of the document written by
+// validate -json. It will be incremented if the document changes in a way
+// that is not backward-compatible.
+const ValidateJSONFormatVersion = "0.1"
+
+type validateJSON struct {
+	FormatVersion string                   `json:"format_version"`
+	Valid         bool                     `json:"valid"`
+	ErrorCount    int                      `json:"error_count"`
+	WarningCount  int                      `json:"warning_count"`
+	Diagnostics   []*format.JSONDiagnostic `json:"diagnostics"`
+}
+
+// showJSON writes the result of validation with the given diagnostics as a
+// JSON document, returning the exit status.
+func (c *ValidateCommand) showJSON(diags tfdiags.Diagnostics) int {
+	doc := validateJSON{
+		FormatVersion: ValidateJSONFormatVersion,
+		Valid:         !diags.HasErrors(),
+		Diagnostics:   format.NewJSONDiagnostics(diags),
+	}
+	for _, diag := range diags {
+		switch diag.Severity() {
+		case tfdiags.Error:
+			doc.ErrorCount++
+		case tfdiags.Warning:
+			doc.WarningCount++
+		}
+	}
+
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error rendering validation result as JSON: %s", err))
 		return 1
 	}
+	c.Ui.Output(string(buf))
 
+	if !doc.Valid {
+		return 1
+	}
 	return 0
 }
@@ -1,11 +1,13 @@
 package command
 
 import (
+	"encoding/json"
 	"os"
 	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/copy"
+	"github.com/hashicorp/terraform/tfdiags"
 	"github.com/mitchellh/cli"
 )
 
@@ -164,3 +166,43 @@ func TestMissingDefinedVarConfigOnly(t *testing.T) {
 		t.Fatalf("Should have passed: %d\n\n%s", code, ui.ErrorWriter.String())
 	}
 }
+
+func TestValidateCommand_json(t *testing.T) {
+	ui, code := setupTest("validate-valid", "-json")
+	if code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var doc validateJSON
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %s\n\n%s", err, ui.OutputWriter.String())
+	}
+	if !doc.Valid || doc.ErrorCount != 0 || len(doc.Diagnostics) != 0 {
+		t.Fatalf("wrong result: %s", ui.OutputWriter.String())
+	}
+}
+
+func TestValidateFailingCommand_json(t *testing.T) {
+	ui, code := setupTest("validate-invalid/missing_var", "-json")
+	if code != 1 {
+		t.Fatalf("Should have failed: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if errOut := ui.ErrorWriter.String(); errOut != "" {
+		t.Fatalf("unexpected messages on stderr: %s", errOut)
+	}
+
+	var doc validateJSON
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %s\n\n%s", err, ui.OutputWriter.String())
+	}
+	if doc.Valid || doc.ErrorCount != 1 || len(doc.Diagnostics) != 1 {
+		t.Fatalf("wrong result: %s", ui.OutputWriter.String())
+	}
+	diag := doc.Diagnostics[0]
+	if diag.Severity != "error" || diag.Code != tfdiags.CodeConfigInvalid {
+		t.Fatalf("wrong diagnostic: %s", ui.OutputWriter.String())
+	}
+	if !strings.Contains(diag.Summary, "unknown variable referenced: 'description'") {
+		t.Fatalf("wrong summary %q", diag.Summary)
+	}
+}
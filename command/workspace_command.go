@@ -43,6 +43,7 @@ Subcommands:
     select    Select a workspace.
     new       Create a new workspace.
     delete    Delete an existing workspace.
+    meta      Show or change the metadata of the current workspace.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -1,9 +1,11 @@
 package command
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -388,3 +390,65 @@ func TestWorkspace_deleteWithState(t *testing.T) {
 		t.Fatal("env 'test' still exists!")
 	}
 }
+
+func TestWorkspace_metadata(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	for _, args := range [][]string{
+		{"-meta", "owner=alice", "-meta", "env=prod", "prod"},
+		{"-meta", "owner=bob", "dev"},
+	} {
+		ui := new(cli.MockUi)
+		newCmd := &WorkspaceNewCommand{
+			Meta: Meta{Ui: ui},
+		}
+		if code := newCmd.Run(args); code != 0 {
+			t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter)
+		}
+	}
+
+	// dev is now the current workspace
+	ui := new(cli.MockUi)
+	metaCmd := &WorkspaceMetaCommand{
+		Meta: Meta{Ui: ui},
+	}
+	if code := metaCmd.Run([]string{"-set", "ttl=24h", "-unset", "owner"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter)
+	}
+	if actual, expected := strings.TrimSpace(ui.OutputWriter.String()), "ttl = 24h"; actual != expected {
+		t.Fatalf("\nexpected: %q\nactual:  %q", expected, actual)
+	}
+
+	ui = new(cli.MockUi)
+	showCmd := &WorkspaceShowCommand{
+		Meta: Meta{Ui: ui},
+	}
+	if code := showCmd.Run([]string{"-json"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter)
+	}
+	var shown workspaceJSON
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &shown); err != nil {
+		t.Fatalf("err: %s\n\n%s", err, ui.OutputWriter)
+	}
+	expected := workspaceJSON{
+		Name:     "dev",
+		Metadata: map[string]string{"ttl": "24h"},
+	}
+	if !reflect.DeepEqual(shown, expected) {
+		t.Fatalf("\nexpected: %#v\nactual:   %#v", expected, shown)
+	}
+
+	ui = new(cli.MockUi)
+	listCmd := &WorkspaceListCommand{
+		Meta: Meta{Ui: ui},
+	}
+	if code := listCmd.Run([]string{"-meta", "owner=alice"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter)
+	}
+	if actual, expected := strings.TrimSpace(ui.OutputWriter.String()), "prod"; actual != expected {
+		t.Fatalf("\nexpected: %q\nactual:  %q", expected, actual)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/hashicorp/terraform/backend"
 	"github.com/posener/complete"
 )
 
@@ -21,7 +22,10 @@ func (c *WorkspaceListCommand) Run(args []string) int {
 
 	envCommandShowWarning(c.Ui, c.LegacyName)
 
+	var meta FlagStringKV
+
 	cmdFlags := c.Meta.flagSet("workspace list")
+	cmdFlags.Var(&meta, "meta", "key=value")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := cmdFlags.Parse(args); err != nil {
 		return 1
@@ -56,6 +60,16 @@ func (c *WorkspaceListCommand) Run(args []string) int {
 		return 1
 	}
 
+	// Only list the workspaces that have all of the given metadata.
+	for k, v := range meta {
+		matching, err := backend.WorkspacesWithMetadata(b, k, v)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+		states = intersectStrings(states, matching)
+	}
+
 	env, isOverridden := c.WorkspaceOverridden()
 
 	var out bytes.Buffer
@@ -77,19 +91,44 @@ func (c *WorkspaceListCommand) Run(args []string) int {
 	return 0
 }
 
+// intersectStrings returns the strings of a that are also in b, in the
+// order of a.
+func intersectStrings(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, s := range b {
+		inB[s] = true
+	}
+
+	var result []string
+	for _, s := range a {
+		if inB[s] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
 func (c *WorkspaceListCommand) AutocompleteArgs() complete.Predictor {
 	return complete.PredictDirs("")
 }
 
 func (c *WorkspaceListCommand) AutocompleteFlags() complete.Flags {
-	return nil
+	return complete.Flags{
+		"-meta": complete.PredictAnything,
+	}
 }
 
 func (c *WorkspaceListCommand) Help() string {
 	helpText := `
-Usage: terraform workspace list [DIR]
+Usage: terraform workspace list [OPTIONS] [DIR]
 
   List Terraform workspaces.
+
+Options:
+
+    -meta=key=value    List only the workspaces whose metadata has the given
+                       value for the given key. This flag can be set multiple
+                       times, to list the workspaces that match them all.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -0,0 +1,166 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/command/clistate"
+	"github.com/hashicorp/terraform/state"
+	"github.com/posener/complete"
+)
+
+// WorkspaceMetaCommand is a Command implementation that shows and changes
+// the metadata of the current workspace.
+type WorkspaceMetaCommand struct {
+	Meta
+}
+
+func (c *WorkspaceMetaCommand) Run(args []string) int {
+	args, err := c.Meta.process(args, true)
+	if err != nil {
+		return 1
+	}
+
+	var set FlagStringKV
+	var unset []string
+
+	cmdFlags := c.Meta.flagSet("workspace meta")
+	cmdFlags.Var(&set, "set", "key=value")
+	cmdFlags.Var((*FlagStringSlice)(&unset), "unset", "key")
+	cmdFlags.BoolVar(&c.stateLock, "lock", true, "lock state")
+	cmdFlags.DurationVar(&c.stateLockTimeout, "lock-timeout", 0, "lock timeout")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+
+	for k := range set {
+		if k == "" {
+			c.Ui.Error("Metadata keys must not be empty.")
+			return 1
+		}
+	}
+
+	configPath, err := ModulePath(cmdFlags.Args())
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	conf, err := c.Config(configPath)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to load root config module: %s", err))
+		return 1
+	}
+
+	// Load the backend
+	b, err := c.Backend(&BackendOpts{
+		Config: conf,
+	})
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to load backend: %s", err))
+		return 1
+	}
+
+	workspace := c.Workspace()
+
+	if len(set) > 0 || len(unset) > 0 {
+		if c.stateLock {
+			sMgr, err := b.State(workspace)
+			if err != nil {
+				c.Ui.Error(err.Error())
+				return 1
+			}
+
+			lockCtx, cancel := context.WithTimeout(context.Background(), c.stateLockTimeout)
+			defer cancel()
+
+			lockInfo := state.NewLockInfo()
+			lockInfo.Operation = "workspace meta"
+			lockID, err := clistate.Lock(lockCtx, sMgr, lockInfo, c.Ui, c.Colorize())
+			if err != nil {
+				c.Ui.Error(fmt.Sprintf("Error locking state: %s", err))
+				return 1
+			}
+			defer clistate.Unlock(sMgr, lockID, c.Ui, c.Colorize())
+		}
+
+		meta, err := backend.WorkspaceMetadata(b, workspace)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error reading workspace metadata: %s", err))
+			return 1
+		}
+		for _, k := range unset {
+			delete(meta, k)
+		}
+		for k, v := range set {
+			meta[k] = v
+		}
+
+		if err := backend.SetWorkspaceMetadata(b, workspace, meta); err != nil {
+			c.Ui.Error(fmt.Sprintf("Error saving workspace metadata: %s", err))
+			return 1
+		}
+	}
+
+	meta, err := backend.WorkspaceMetadata(b, workspace)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading workspace metadata: %s", err))
+		return 1
+	}
+
+	keys := make([]string, 0, len(meta))
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		c.Ui.Output(fmt.Sprintf("%s = %s", k, meta[k]))
+	}
+
+	return 0
+}
+
+func (c *WorkspaceMetaCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictDirs("")
+}
+
+func (c *WorkspaceMetaCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-set":   complete.PredictAnything,
+		"-unset": complete.PredictAnything,
+	}
+}
+
+func (c *WorkspaceMetaCommand) Help() string {
+	helpText := `
+Usage: terraform workspace meta [OPTIONS] [DIR]
+
+  Show or change the metadata of the current workspace.
+
+  Workspace metadata is a set of arbitrary key/value pairs, such as the
+  owner or environment of the workspace, that is stored with its state.
+  Terraform doesn't interpret it. The metadata is shown after any changes,
+  one "key = value" pair per line.
+
+Options:
+
+    -set=key=value      Set the given key to the given value. This flag can
+                        be set multiple times.
+
+    -unset=key          Remove the given key. This flag can be set multiple
+                        times.
+
+    -lock=true          Lock the state file when locking is supported.
+
+    -lock-timeout=0s    Duration to retry a state lock.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *WorkspaceMetaCommand) Synopsis() string {
+	return "Show or change the metadata of the current workspace"
+}
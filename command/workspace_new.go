@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/hashicorp/terraform/backend"
 	"github.com/hashicorp/terraform/command/clistate"
 	"github.com/hashicorp/terraform/state"
 	"github.com/hashicorp/terraform/terraform"
@@ -27,9 +28,11 @@ func (c *WorkspaceNewCommand) Run(args []string) int {
 	envCommandShowWarning(c.Ui, c.LegacyName)
 
 	statePath := ""
+	var meta FlagStringKV
 
 	cmdFlags := c.Meta.flagSet("workspace new")
 	cmdFlags.StringVar(&statePath, "state", "", "terraform state file")
+	cmdFlags.Var(&meta, "meta", "key=value")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := cmdFlags.Parse(args); err != nil {
 		return 1
@@ -102,8 +105,8 @@ func (c *WorkspaceNewCommand) Run(args []string) int {
 	c.Ui.Output(c.Colorize().Color(fmt.Sprintf(
 		strings.TrimSpace(envCreated), newEnv)))
 
-	if statePath == "" {
-		// if we're not loading a state, then we're done
+	if statePath == "" && len(meta) == 0 {
+		// if we're not loading a state or setting metadata, then we're done
 		return 0
 	}
 
@@ -129,29 +132,47 @@ func (c *WorkspaceNewCommand) Run(args []string) int {
 		defer clistate.Unlock(sMgr, lockID, c.Ui, c.Colorize())
 	}
 
-	// read the existing state file
-	stateFile, err := os.Open(statePath)
-	if err != nil {
-		c.Ui.Error(err.Error())
-		return 1
-	}
+	if statePath != "" {
+		// read the existing state file
+		stateFile, err := os.Open(statePath)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
 
-	s, err := terraform.ReadState(stateFile)
-	if err != nil {
-		c.Ui.Error(err.Error())
-		return 1
-	}
+		s, err := terraform.ReadState(stateFile)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
 
-	// save the existing state in the new Backend.
-	err = sMgr.WriteState(s)
-	if err != nil {
-		c.Ui.Error(err.Error())
-		return 1
+		// save the existing state in the new Backend.
+		err = sMgr.WriteState(s)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+		err = sMgr.PersistState()
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
 	}
-	err = sMgr.PersistState()
-	if err != nil {
-		c.Ui.Error(err.Error())
-		return 1
+
+	if len(meta) > 0 {
+		// Metadata is added to any that the copied state has.
+		current, err := backend.WorkspaceMetadata(b, newEnv)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error reading workspace metadata: %s", err))
+			return 1
+		}
+		for k, v := range meta {
+			current[k] = v
+		}
+		if err := backend.SetWorkspaceMetadata(b, newEnv, current); err != nil {
+			c.Ui.Error(fmt.Sprintf("Error saving workspace metadata: %s", err))
+			return 1
+		}
 	}
 
 	return 0
@@ -167,6 +188,7 @@ func (c *WorkspaceNewCommand) AutocompleteArgs() complete.Predictor {
 
 func (c *WorkspaceNewCommand) AutocompleteFlags() complete.Flags {
 	return complete.Flags{
+		"-meta":  complete.PredictAnything,
 		"-state": complete.PredictFiles("*.tfstate"),
 	}
 }
@@ -180,7 +202,10 @@ Usage: terraform workspace new [OPTIONS] NAME [DIR]
 
 Options:
 
-    -state=path    Copy an existing state file into the new workspace.
+    -meta=key=value    Set the given metadata key of the new workspace to
+                       the given value. This flag can be set multiple times.
+
+    -state=path        Copy an existing state file into the new workspace.
 `
 	return strings.TrimSpace(helpText)
 }
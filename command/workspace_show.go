@@ -1,8 +1,11 @@
 package command
 
 import (
+	"encoding/json"
+	"fmt"
 	"strings"
 
+	"github.com/hashicorp/terraform/backend"
 	"github.com/posener/complete"
 )
 
@@ -16,31 +19,88 @@ func (c *WorkspaceShowCommand) Run(args []string) int {
 		return 1
 	}
 
+	var jsonOutput bool
+
 	cmdFlags := c.Meta.flagSet("workspace show")
+	cmdFlags.BoolVar(&jsonOutput, "json", false, "json")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := cmdFlags.Parse(args); err != nil {
 		return 1
 	}
 
 	workspace := c.Workspace()
-	c.Ui.Output(workspace)
+	if !jsonOutput {
+		c.Ui.Output(workspace)
+		return 0
+	}
+
+	// The metadata is stored with the state, so the backend is needed to
+	// show it.
+	configPath, err := ModulePath(cmdFlags.Args())
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	conf, err := c.Config(configPath)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to load root config module: %s", err))
+		return 1
+	}
+
+	b, err := c.Backend(&BackendOpts{
+		Config: conf,
+	})
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to load backend: %s", err))
+		return 1
+	}
+
+	meta, err := backend.WorkspaceMetadata(b, workspace)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading workspace metadata: %s", err))
+		return 1
+	}
+
+	js, err := json.MarshalIndent(&workspaceJSON{
+		Name:     workspace,
+		Metadata: meta,
+	}, "", "  ")
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error encoding workspace: %s", err))
+		return 1
+	}
+	c.Ui.Output(string(js))
 
 	return 0
 }
 
+// workspaceJSON is the output of "terraform workspace show -json".
+type workspaceJSON struct {
+	Name     string            `json:"name"`
+	Metadata map[string]string `json:"metadata"`
+}
+
 func (c *WorkspaceShowCommand) AutocompleteArgs() complete.Predictor {
 	return complete.PredictNothing
 }
 
 func (c *WorkspaceShowCommand) AutocompleteFlags() complete.Flags {
-	return nil
+	return complete.Flags{
+		"-json": complete.PredictNothing,
+	}
 }
 
 func (c *WorkspaceShowCommand) Help() string {
 	helpText := `
-Usage: terraform workspace show
+Usage: terraform workspace show [OPTIONS] [DIR]
 
   Show the name of the current workspace.
+
+Options:
+
+    -json    Show the name and metadata of the workspace as a JSON object
+             instead, reading the metadata from the backend.
 `
 	return strings.TrimSpace(helpText)
 }
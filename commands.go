@@ -1,15 +1,29 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"sort"
+	"time"
 
+	"github.com/hashicorp/terraform/analysis"
 	"github.com/hashicorp/terraform/command"
+	"github.com/hashicorp/terraform/command/format"
+	tfconfig "github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/wasmfunc"
 	pluginDiscovery "github.com/hashicorp/terraform/plugin/discovery"
+	"github.com/hashicorp/terraform/plugin/host"
+	"github.com/hashicorp/terraform/policy"
+	"github.com/hashicorp/terraform/secrets"
 	"github.com/hashicorp/terraform/svchost"
 	"github.com/hashicorp/terraform/svchost/auth"
 	"github.com/hashicorp/terraform/svchost/disco"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/hashicorp/terraform/tracing"
 	"github.com/mitchellh/cli"
 )
 
@@ -25,6 +39,10 @@ var PlumbingCommands map[string]struct{}
 // Ui is the cli.Ui used for communicating to the outside world.
 var Ui cli.Ui
 
+// Tracer records the trace of the run, or is nil if the CLI configuration
+// doesn't enable tracing.
+var Tracer *tracing.Tracer
+
 const (
 	ErrorPrefix  = "e:"
 	OutputPrefix = "o:"
@@ -51,6 +69,8 @@ func initCommands(config *Config) {
 
 	dataDir := os.Getenv("TF_DATA_DIR")
 
+	Tracer = newTracer(config)
+
 	meta := command.Meta{
 		Color:            true,
 		GlobalPluginDirs: globalPluginDirs(),
@@ -61,10 +81,23 @@ func initCommands(config *Config) {
 		Credentials: credsSrc,
 
 		RunningInAutomation: inAutomation,
+		PolicyHooks:         policyHooks(config),
+		PlanAnalyzers:       planAnalyzers(config),
 		PluginCacheDir:      config.PluginCacheDir,
+		ProviderHost:        config.ProviderHost,
+		ProviderOCIMirrors:  providerOCIMirrors(config, credsSrc),
 		OverrideDataDir:     dataDir,
 
+		ProviderHostIdleTimeout:  providerHostIdleTimeout(config),
+		ProviderSigstorePolicies: providerSigstorePolicies(config),
+		Secrets:                  secretsSource(config),
+
+		Redaction:    sensitiveRedaction(config),
+		AllowUnveil:  config.AllowUnveil,
+		AuditLogPath: config.AuditLog,
+
 		ShutdownCh: makeShutdownCh(),
+		Tracer:     Tracer,
 	}
 
 	// The command list is included in the terraform -help
@@ -77,6 +110,7 @@ func initCommands(config *Config) {
 		"state":        struct{}{}, // includes all subcommands
 		"debug":        struct{}{}, // includes all subcommands
 		"force-unlock": struct{}{},
+		"metadata":     struct{}{}, // includes all subcommands
 	}
 
 	Commands = map[string]cli.CommandFactory{
@@ -99,6 +133,12 @@ func initCommands(config *Config) {
 			}, nil
 		},
 
+		"drift": func() (cli.Command, error) {
+			return &command.DriftCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"env": func() (cli.Command, error) {
 			return &command.WorkspaceCommand{
 				Meta:       meta,
@@ -134,6 +174,12 @@ func initCommands(config *Config) {
 			}, nil
 		},
 
+		"expand": func() (cli.Command, error) {
+			return &command.ExpandCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"fmt": func() (cli.Command, error) {
 			return &command.FmtCommand{
 				Meta: meta,
@@ -170,6 +216,12 @@ func initCommands(config *Config) {
 			}, nil
 		},
 
+		"provider-host": func() (cli.Command, error) {
+			return &command.ProviderHostCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"output": func() (cli.Command, error) {
 			return &command.OutputCommand{
 				Meta: meta,
@@ -182,12 +234,36 @@ func initCommands(config *Config) {
 			}, nil
 		},
 
+		"plan diff": func() (cli.Command, error) {
+			return &command.PlanDiffCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"providers": func() (cli.Command, error) {
 			return &command.ProvidersCommand{
 				Meta: meta,
 			}, nil
 		},
 
+		"providers mirror": func() (cli.Command, error) {
+			return &command.ProvidersMirrorCommand{
+				Meta: meta,
+			}, nil
+		},
+
+		"providers mirror prune": func() (cli.Command, error) {
+			return &command.ProvidersMirrorPruneCommand{
+				Meta: meta,
+			}, nil
+		},
+
+		"providers mirror verify": func() (cli.Command, error) {
+			return &command.ProvidersMirrorVerifyCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"push": func() (cli.Command, error) {
 			return &command.PushCommand{
 				Meta: meta,
@@ -212,6 +288,34 @@ func initCommands(config *Config) {
 			}, nil
 		},
 
+		"stack": func() (cli.Command, error) {
+			return &command.StackCommand{
+				Meta: meta,
+			}, nil
+		},
+
+		"stack apply": func() (cli.Command, error) {
+			return &command.StackApplyCommand{
+				StackMeta: command.StackMeta{
+					Meta: meta,
+				},
+			}, nil
+		},
+
+		"stack plan": func() (cli.Command, error) {
+			return &command.StackPlanCommand{
+				StackMeta: command.StackMeta{
+					Meta: meta,
+				},
+			}, nil
+		},
+
+		"test": func() (cli.Command, error) {
+			return &command.TestCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"validate": func() (cli.Command, error) {
 			return &command.ValidateCommand{
 				Meta: meta,
@@ -258,6 +362,12 @@ func initCommands(config *Config) {
 			}, nil
 		},
 
+		"workspace meta": func() (cli.Command, error) {
+			return &command.WorkspaceMetaCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"workspace new": func() (cli.Command, error) {
 			return &command.WorkspaceNewCommand{
 				Meta: meta,
@@ -292,16 +402,50 @@ func initCommands(config *Config) {
 			}, nil
 		},
 
+		"metadata": func() (cli.Command, error) {
+			return &command.MetadataCommand{
+				Meta: meta,
+			}, nil
+		},
+
+		"metadata dump": func() (cli.Command, error) {
+			return &command.MetadataDumpCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"state": func() (cli.Command, error) {
 			return &command.StateCommand{}, nil
 		},
 
+		"state history": func() (cli.Command, error) {
+			return &command.StateHistoryCommand{
+				StateMeta: command.StateMeta{
+					Meta: meta,
+				},
+			}, nil
+		},
+
 		"state list": func() (cli.Command, error) {
 			return &command.StateListCommand{
 				Meta: meta,
 			}, nil
 		},
 
+		"state query": func() (cli.Command, error) {
+			return &command.StateQueryCommand{
+				Meta: meta,
+			}, nil
+		},
+
+		"state rollback": func() (cli.Command, error) {
+			return &command.StateRollbackCommand{
+				StateMeta: command.StateMeta{
+					Meta: meta,
+				},
+			}, nil
+		},
+
 		"state rm": func() (cli.Command, error) {
 			return &command.StateRmCommand{
 				StateMeta: command.StateMeta{
@@ -335,6 +479,14 @@ func initCommands(config *Config) {
 				Meta: meta,
 			}, nil
 		},
+
+		"state verify": func() (cli.Command, error) {
+			return &command.StateVerifyCommand{
+				StateMeta: command.StateMeta{
+					Meta: meta,
+				},
+			}, nil
+		},
 	}
 }
 
@@ -397,3 +549,209 @@ func credentialsSource(config *Config) auth.CredentialsSource {
 
 	return creds
 }
+
+func policyHooks(config *Config) []policy.Hook {
+	names := make([]string, 0, len(config.PolicyHooks))
+	for name := range config.PolicyHooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hooks := make([]policy.Hook, 0, len(names))
+	for _, name := range names {
+		hookConfig := config.PolicyHooks[name]
+
+		path, err := exec.LookPath(hookConfig.Command)
+		if err == nil {
+			path, err = filepath.Abs(path)
+		}
+		if err != nil {
+			// The hook must not be skipped, so it's kept with the path as
+			// given and fails with the error running it when checking a
+			// plan.
+			log.Printf("[ERROR] Unable to find policy hook %q command %q: %s", name, hookConfig.Command, err)
+			path, _ = filepath.Abs(hookConfig.Command)
+		}
+
+		hooks = append(hooks, policy.ProgramHook(name, path, hookConfig.Args...))
+	}
+
+	return hooks
+}
+
+func planAnalyzers(config *Config) []analysis.Analyzer {
+	names := make([]string, 0, len(config.PlanAnalyzers))
+	for name := range config.PlanAnalyzers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	analyzers := make([]analysis.Analyzer, 0, len(names))
+	for _, name := range names {
+		analyzerConfig := config.PlanAnalyzers[name]
+
+		path, err := exec.LookPath(analyzerConfig.Command)
+		if err == nil {
+			path, err = filepath.Abs(path)
+		}
+		if err != nil {
+			// The analyzer is kept with the path as given, so that it fails
+			// with the error running it and the plan is shown with a
+			// warning that its annotations are missing.
+			log.Printf("[ERROR] Unable to find plan analyzer %q command %q: %s", name, analyzerConfig.Command, err)
+			path, _ = filepath.Abs(analyzerConfig.Command)
+		}
+
+		analyzers = append(analyzers, analysis.ProgramAnalyzer(name, path, analyzerConfig.Args...))
+	}
+
+	return analyzers
+}
+
+func providerOCIMirrors(config *Config, creds auth.CredentialsSource) []*pluginDiscovery.OCIMirror {
+	repos := make([]string, 0, len(config.ProviderOCIMirrors))
+	for repo := range config.ProviderOCIMirrors {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+
+	mirrors := make([]*pluginDiscovery.OCIMirror, 0, len(repos))
+	for _, repo := range repos {
+		mirrorConfig := config.ProviderOCIMirrors[repo]
+		mirrors = append(mirrors, &pluginDiscovery.OCIMirror{
+			Repository:    repo,
+			PublicKeyFile: mirrorConfig.PublicKey,
+			Username:      mirrorConfig.Username,
+			Credentials:   creds,
+		})
+	}
+
+	return mirrors
+}
+
+func providerSigstorePolicies(config *Config) map[svchost.Hostname]*pluginDiscovery.SigstorePolicy {
+	policies := make(map[svchost.Hostname]*pluginDiscovery.SigstorePolicy)
+	for userHost, policyConfig := range config.ProviderSigstore {
+		host, err := svchost.ForComparison(userHost)
+		if err != nil {
+			// We expect the config was already validated by the time we get
+			// here, so we'll just ignore invalid hostnames.
+			continue
+		}
+		policies[host] = &pluginDiscovery.SigstorePolicy{
+			Identity:           policyConfig.Identity,
+			Issuer:             policyConfig.Issuer,
+			RootsFile:          policyConfig.Roots,
+			RekorPublicKeyFile: policyConfig.RekorPublicKey,
+		}
+	}
+
+	return policies
+}
+
+func secretsSource(config *Config) *secrets.Source {
+	backends := make(map[string]secrets.Backend)
+	for name, backendConfig := range config.SecretsBackends {
+		switch backendConfig.Type {
+		case "vault":
+			backends[name] = secrets.Vault(backendConfig.Address, backendConfig.Token)
+		case "env":
+			backends[name] = secrets.Env()
+		default:
+			log.Printf("[DEBUG] Searching for secrets plugin named %q", backendConfig.Type)
+			available := pluginDiscovery.FindPlugins("secrets", globalPluginDirs())
+			available = available.WithName(backendConfig.Type)
+			if available.Count() == 0 {
+				// The backend is left out, so that reading a secret from it
+				// fails with an error naming it.
+				log.Printf("[ERROR] Unable to find secrets plugin %q for secrets_backend %q", backendConfig.Type, name)
+				continue
+			}
+			selected := available.Newest()
+			backends[name] = secrets.ProgramBackend(selected.Path, backendConfig.Args...)
+		}
+	}
+
+	return secrets.NewSource(backends)
+}
+
+func newTracer(config *Config) *tracing.Tracer {
+	tracingConfig, ok := config.Tracing["otlp"]
+	if !ok {
+		return nil
+	}
+
+	exporter, err := tracing.OTLP(tracingConfig.Endpoint, tracingConfig.Headers, tracingConfig.ServiceName)
+	if err != nil {
+		// Tracing is only for observing the run, so the run continues
+		// without it.
+		log.Printf("[ERROR] Unable to enable tracing: %s", err)
+		return nil
+	}
+
+	return tracing.NewTracer(exporter)
+}
+
+// registerWASMFunctions loads the modules of the "wasm_functions" blocks of
+// the given configuration and registers their functions for use in
+// interpolations. A module that can't be loaded is left out.
+func registerWASMFunctions(config *Config) tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+
+	names := make([]string, 0, len(config.WASMFunctions))
+	for name := range config.WASMFunctions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		funcsConfig := config.WASMFunctions[name]
+		m, err := wasmfunc.Load(&wasmfunc.LoadOpts{
+			Name:   name,
+			Path:   funcsConfig.Path,
+			SHA256: funcsConfig.SHA256,
+		})
+		if err != nil {
+			diags = diags.Append(
+				fmt.Errorf("The wasm_functions %q block could not be loaded: %s", name, err),
+			)
+			continue
+		}
+		if err := tfconfig.RegisterFuncs(m.ASTFunctions()); err != nil {
+			m.Close()
+			diags = diags.Append(
+				fmt.Errorf("The functions of the wasm_functions %q block could not be registered: %s", name, err),
+			)
+			continue
+		}
+		log.Printf("[DEBUG] Registered %d functions from WASM module %q", len(m.Functions), name)
+	}
+
+	return diags
+}
+
+// sensitiveRedaction returns how sensitive values are shown in output.
+func sensitiveRedaction(config *Config) format.Redaction {
+	// The redaction was already validated with the rest of the
+	// configuration.
+	r, err := format.ParseRedaction(config.SensitiveRedaction)
+	if err != nil {
+		return format.RedactHidden
+	}
+	return r
+}
+
+// providerHostIdleTimeout returns how long the provider host keeps
+// provider plugins running once no commands are using them.
+func providerHostIdleTimeout(config *Config) time.Duration {
+	if config.ProviderHostIdleTimeout == "" {
+		return host.DefaultIdleTimeout
+	}
+
+	// The timeout was already validated with the rest of the configuration.
+	d, err := time.ParseDuration(config.ProviderHostIdleTimeout)
+	if err != nil {
+		return host.DefaultIdleTimeout
+	}
+	return d
+}
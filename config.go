@@ -2,20 +2,29 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/hcl"
 
 	"github.com/hashicorp/terraform/command"
+	"github.com/hashicorp/terraform/command/format"
 	"github.com/hashicorp/terraform/svchost"
 	"github.com/hashicorp/terraform/tfdiags"
 )
 
 const pluginCacheDirEnvVar = "TF_PLUGIN_CACHE_DIR"
+const providerHostEnvVar = "TF_PROVIDER_HOST"
+const sensitiveRedactionEnvVar = "TF_SENSITIVE_REDACTION"
+const allowUnveilEnvVar = "TF_ALLOW_UNVEIL"
+const auditLogEnvVar = "TF_AUDIT_LOG"
 
 // Config is the structure of the configuration for the Terraform CLI.
 //
@@ -32,10 +41,38 @@ type Config struct {
 	// avoid repeatedly re-downloading over the Internet.
 	PluginCacheDir string `hcl:"plugin_cache_dir"`
 
+	// If set, provider plugins are kept running between commands by a
+	// provider host, which stops once it's been idle for the given timeout.
+	ProviderHost            bool   `hcl:"provider_host"`
+	ProviderHostIdleTimeout string `hcl:"provider_host_idle_timeout"`
+
+	// SensitiveRedaction selects how sensitive values are shown in output:
+	// "hidden", the default, "hash" or "length".
+	SensitiveRedaction string `hcl:"sensitive_redaction"`
+
+	// If set, "terraform output -unveil" and "-show-sensitive" may reveal
+	// sensitive outputs, and record each time they do in the audit log, which defaults to
+	// "audit.log" in the data directory.
+	AllowUnveil bool   `hcl:"allow_unveil"`
+	AuditLog    string `hcl:"audit_log"`
+
 	Hosts map[string]*ConfigHost `hcl:"host"`
 
 	Credentials        map[string]map[string]interface{}   `hcl:"credentials"`
 	CredentialsHelpers map[string]*ConfigCredentialsHelper `hcl:"credentials_helper"`
+
+	PolicyHooks map[string]*ConfigPolicyHook `hcl:"policy_hook"`
+
+	PlanAnalyzers map[string]*ConfigPlanAnalyzer `hcl:"plan_analyzer"`
+
+	ProviderOCIMirrors map[string]*ConfigProviderOCIMirror `hcl:"provider_oci_mirror"`
+	ProviderSigstore   map[string]*ConfigProviderSigstore  `hcl:"provider_sigstore"`
+
+	SecretsBackends map[string]*ConfigSecretsBackend `hcl:"secrets_backend"`
+
+	Tracing map[string]*ConfigTracing `hcl:"tracing"`
+
+	WASMFunctions map[string]*ConfigWASMFunctions `hcl:"wasm_functions"`
 }
 
 // ConfigHost is the structure of the "host" nested block within the CLI
@@ -51,6 +88,72 @@ type ConfigCredentialsHelper struct {
 	Args []string `hcl:"args"`
 }
 
+// ConfigPolicyHook is the structure of the "policy_hook" nested block
+// within the CLI configuration, which declares a program that checks plans
+// before they are applied.
+type ConfigPolicyHook struct {
+	Command string   `hcl:"command"`
+	Args    []string `hcl:"args"`
+}
+
+// ConfigPlanAnalyzer is the structure of the "plan_analyzer" nested block
+// within the CLI configuration, which declares a program that annotates the
+// changes of plans once they're created.
+type ConfigPlanAnalyzer struct {
+	Command string   `hcl:"command"`
+	Args    []string `hcl:"args"`
+}
+
+// ConfigProviderOCIMirror is the structure of the "provider_oci_mirror"
+// nested block within the CLI configuration, which declares an OCI registry
+// repository that providers are installed from instead of the release host.
+type ConfigProviderOCIMirror struct {
+	PublicKey string `hcl:"public_key"`
+	Username  string `hcl:"username"`
+}
+
+// ConfigProviderSigstore is the structure of the "provider_sigstore" nested
+// block within the CLI configuration, which declares the identity that must
+// have made keyless signatures of the providers released from a host.
+type ConfigProviderSigstore struct {
+	Identity       string `hcl:"identity"`
+	Issuer         string `hcl:"issuer"`
+	Roots          string `hcl:"roots"`
+	RekorPublicKey string `hcl:"rekor_public_key"`
+}
+
+// ConfigSecretsBackend is the structure of the "secrets_backend" nested
+// block within the CLI configuration, which declares a backend that the
+// values of secret variables are read from.
+//
+// Type is "vault", "env", or the name of a secrets plugin. Address and
+// Token are used only by the "vault" type, and Args only by plugins.
+type ConfigSecretsBackend struct {
+	Type    string   `hcl:"type"`
+	Address string   `hcl:"address"`
+	Token   string   `hcl:"token"`
+	Args    []string `hcl:"args"`
+}
+
+// ConfigTracing is the structure of the "tracing" nested block within the
+// CLI configuration, which declares where the traces of Terraform runs are
+// exported to. The label of the block is the protocol, which must be
+// "otlp".
+type ConfigTracing struct {
+	Endpoint    string            `hcl:"endpoint"`
+	Headers     map[string]string `hcl:"headers"`
+	ServiceName string            `hcl:"service_name"`
+}
+
+// ConfigWASMFunctions is the structure of the "wasm_functions" nested block
+// within the CLI configuration, which declares a WebAssembly module whose
+// functions can be called in interpolations. If SHA256 is set, the module
+// is only loaded if its checksum matches.
+type ConfigWASMFunctions struct {
+	Path   string `hcl:"path"`
+	SHA256 string `hcl:"sha256"`
+}
+
 // BuiltinConfig is the built-in defaults for the configuration. These
 // can be overridden by user configurations.
 var BuiltinConfig Config
@@ -193,6 +296,22 @@ func EnvConfig() *Config {
 		config.PluginCacheDir = envPluginCacheDir
 	}
 
+	if envProviderHost := os.Getenv(providerHostEnvVar); envProviderHost != "" {
+		config.ProviderHost = envProviderHost != "0" && envProviderHost != "false"
+	}
+
+	if envRedaction := os.Getenv(sensitiveRedactionEnvVar); envRedaction != "" {
+		config.SensitiveRedaction = envRedaction
+	}
+
+	if envAllowUnveil := os.Getenv(allowUnveilEnvVar); envAllowUnveil != "" {
+		config.AllowUnveil = envAllowUnveil != "0" && envAllowUnveil != "false"
+	}
+
+	if envAuditLog := os.Getenv(auditLogEnvVar); envAuditLog != "" {
+		config.AuditLog = envAuditLog
+	}
+
 	return config
 }
 
@@ -240,6 +359,110 @@ func (c *Config) Validate() tfdiags.Diagnostics {
 		)
 	}
 
+	// Check that all "policy_hook" blocks have a command.
+	for name, hook := range c.PolicyHooks {
+		if hook == nil || hook.Command == "" {
+			diags = diags.Append(
+				fmt.Errorf("The policy_hook %q block must set command", name),
+			)
+		}
+	}
+
+	// Check that all "plan_analyzer" blocks have a command.
+	for name, analyzer := range c.PlanAnalyzers {
+		if analyzer == nil || analyzer.Command == "" {
+			diags = diags.Append(
+				fmt.Errorf("The plan_analyzer %q block must set command", name),
+			)
+		}
+	}
+
+	// Check that all "provider_oci_mirror" blocks name a repository on a
+	// registry host.
+	for repo := range c.ProviderOCIMirrors {
+		parts := strings.SplitN(repo, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			diags = diags.Append(
+				fmt.Errorf("The provider_oci_mirror %q block must name a registry host and repository, such as \"ghcr.io/example/providers\"", repo),
+			)
+		}
+	}
+
+	// Check that all "provider_sigstore" blocks are for valid hostnames and
+	// set everything needed to verify signatures.
+	for givenHost, policy := range c.ProviderSigstore {
+		_, err := svchost.ForComparison(givenHost)
+		if err != nil {
+			diags = diags.Append(
+				fmt.Errorf("The provider_sigstore %q block has an invalid hostname: %s", givenHost, err),
+			)
+		}
+		if policy == nil || policy.Identity == "" || policy.Issuer == "" || policy.Roots == "" || policy.RekorPublicKey == "" {
+			diags = diags.Append(
+				fmt.Errorf("The provider_sigstore %q block must set identity, issuer, roots and rekor_public_key", givenHost),
+			)
+		}
+	}
+
+	// Should have zero or one "tracing" blocks, for a supported protocol.
+	if len(c.Tracing) > 1 {
+		diags = diags.Append(
+			fmt.Errorf("No more than one tracing block may be specified"),
+		)
+	}
+	for protocol, tracing := range c.Tracing {
+		if protocol != "otlp" {
+			diags = diags.Append(
+				fmt.Errorf("The tracing %q block has an unsupported protocol; only \"otlp\" is supported", protocol),
+			)
+			continue
+		}
+		if tracing == nil || tracing.Endpoint == "" {
+			diags = diags.Append(
+				fmt.Errorf("The tracing %q block must set endpoint", protocol),
+			)
+		}
+	}
+
+	if c.ProviderHostIdleTimeout != "" {
+		if d, err := time.ParseDuration(c.ProviderHostIdleTimeout); err != nil || d <= 0 {
+			diags = diags.Append(
+				fmt.Errorf("The provider_host_idle_timeout setting must be a positive duration, such as \"10m\""),
+			)
+		}
+	}
+
+	if _, err := format.ParseRedaction(c.SensitiveRedaction); err != nil {
+		diags = diags.Append(
+			fmt.Errorf("The sensitive_redaction setting must be \"hidden\", \"hash\" or \"length\""),
+		)
+	}
+
+	// Check that all "wasm_functions" blocks have a path, and a SHA256
+	// checksum if they set one.
+	for name, funcs := range c.WASMFunctions {
+		if funcs == nil || funcs.Path == "" {
+			diags = diags.Append(
+				fmt.Errorf("The wasm_functions %q block must set path", name),
+			)
+			continue
+		}
+		if sum, err := hex.DecodeString(funcs.SHA256); funcs.SHA256 != "" && (err != nil || len(sum) != sha256.Size) {
+			diags = diags.Append(
+				fmt.Errorf("The wasm_functions %q block has an invalid sha256; it must be 64 hexadecimal digits", name),
+			)
+		}
+	}
+
+	// Check that all "secrets_backend" blocks have a type.
+	for name, backend := range c.SecretsBackends {
+		if backend == nil || backend.Type == "" {
+			diags = diags.Append(
+				fmt.Errorf("The secrets_backend %q block must set type", name),
+			)
+		}
+	}
+
 	return diags
 }
 
@@ -275,6 +498,22 @@ func (c1 *Config) Merge(c2 *Config) *Config {
 		result.PluginCacheDir = c2.PluginCacheDir
 	}
 
+	result.ProviderHost = c1.ProviderHost || c2.ProviderHost
+	result.ProviderHostIdleTimeout = c1.ProviderHostIdleTimeout
+	if result.ProviderHostIdleTimeout == "" {
+		result.ProviderHostIdleTimeout = c2.ProviderHostIdleTimeout
+	}
+
+	result.SensitiveRedaction = c1.SensitiveRedaction
+	if result.SensitiveRedaction == "" {
+		result.SensitiveRedaction = c2.SensitiveRedaction
+	}
+	result.AllowUnveil = c1.AllowUnveil || c2.AllowUnveil
+	result.AuditLog = c1.AuditLog
+	if result.AuditLog == "" {
+		result.AuditLog = c2.AuditLog
+	}
+
 	if (len(c1.Hosts) + len(c2.Hosts)) > 0 {
 		result.Hosts = make(map[string]*ConfigHost)
 		for name, host := range c1.Hosts {
@@ -308,5 +547,75 @@ func (c1 *Config) Merge(c2 *Config) *Config {
 		}
 	}
 
+	if (len(c1.PolicyHooks) + len(c2.PolicyHooks)) > 0 {
+		result.PolicyHooks = make(map[string]*ConfigPolicyHook)
+		for name, hook := range c1.PolicyHooks {
+			result.PolicyHooks[name] = hook
+		}
+		for name, hook := range c2.PolicyHooks {
+			result.PolicyHooks[name] = hook
+		}
+	}
+
+	if (len(c1.PlanAnalyzers) + len(c2.PlanAnalyzers)) > 0 {
+		result.PlanAnalyzers = make(map[string]*ConfigPlanAnalyzer)
+		for name, analyzer := range c1.PlanAnalyzers {
+			result.PlanAnalyzers[name] = analyzer
+		}
+		for name, analyzer := range c2.PlanAnalyzers {
+			result.PlanAnalyzers[name] = analyzer
+		}
+	}
+
+	if (len(c1.ProviderOCIMirrors) + len(c2.ProviderOCIMirrors)) > 0 {
+		result.ProviderOCIMirrors = make(map[string]*ConfigProviderOCIMirror)
+		for repo, mirror := range c1.ProviderOCIMirrors {
+			result.ProviderOCIMirrors[repo] = mirror
+		}
+		for repo, mirror := range c2.ProviderOCIMirrors {
+			result.ProviderOCIMirrors[repo] = mirror
+		}
+	}
+
+	if (len(c1.ProviderSigstore) + len(c2.ProviderSigstore)) > 0 {
+		result.ProviderSigstore = make(map[string]*ConfigProviderSigstore)
+		for host, policy := range c1.ProviderSigstore {
+			result.ProviderSigstore[host] = policy
+		}
+		for host, policy := range c2.ProviderSigstore {
+			result.ProviderSigstore[host] = policy
+		}
+	}
+
+	if (len(c1.SecretsBackends) + len(c2.SecretsBackends)) > 0 {
+		result.SecretsBackends = make(map[string]*ConfigSecretsBackend)
+		for name, backend := range c1.SecretsBackends {
+			result.SecretsBackends[name] = backend
+		}
+		for name, backend := range c2.SecretsBackends {
+			result.SecretsBackends[name] = backend
+		}
+	}
+
+	if (len(c1.Tracing) + len(c2.Tracing)) > 0 {
+		result.Tracing = make(map[string]*ConfigTracing)
+		for protocol, tracing := range c1.Tracing {
+			result.Tracing[protocol] = tracing
+		}
+		for protocol, tracing := range c2.Tracing {
+			result.Tracing[protocol] = tracing
+		}
+	}
+
+	if (len(c1.WASMFunctions) + len(c2.WASMFunctions)) > 0 {
+		result.WASMFunctions = make(map[string]*ConfigWASMFunctions)
+		for name, funcs := range c1.WASMFunctions {
+			result.WASMFunctions[name] = funcs
+		}
+		for name, funcs := range c2.WASMFunctions {
+			result.WASMFunctions[name] = funcs
+		}
+	}
+
 	return &result
 }
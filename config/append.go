@@ -88,5 +88,35 @@ func Append(c1, c2 *Config) (*Config, error) {
 		c.Locals = append(c.Locals, c2.Locals...)
 	}
 
+	if len(c1.Imports) > 0 || len(c2.Imports) > 0 {
+		c.Imports = make([]*Import, 0, len(c1.Imports)+len(c2.Imports))
+		c.Imports = append(c.Imports, c1.Imports...)
+		c.Imports = append(c.Imports, c2.Imports...)
+	}
+
+	if len(c1.Moved) > 0 || len(c2.Moved) > 0 {
+		c.Moved = make([]*Moved, 0, len(c1.Moved)+len(c2.Moved))
+		c.Moved = append(c.Moved, c1.Moved...)
+		c.Moved = append(c.Moved, c2.Moved...)
+	}
+
+	if len(c1.Removed) > 0 || len(c2.Removed) > 0 {
+		c.Removed = make([]*Removed, 0, len(c1.Removed)+len(c2.Removed))
+		c.Removed = append(c.Removed, c1.Removed...)
+		c.Removed = append(c.Removed, c2.Removed...)
+	}
+
+	if len(c1.EphemeralResources) > 0 || len(c2.EphemeralResources) > 0 {
+		c.EphemeralResources = make([]*Resource, 0, len(c1.EphemeralResources)+len(c2.EphemeralResources))
+		c.EphemeralResources = append(c.EphemeralResources, c1.EphemeralResources...)
+		c.EphemeralResources = append(c.EphemeralResources, c2.EphemeralResources...)
+	}
+
+	if len(c1.Checks) > 0 || len(c2.Checks) > 0 {
+		c.Checks = make([]*Check, 0, len(c1.Checks)+len(c2.Checks))
+		c.Checks = append(c.Checks, c1.Checks...)
+		c.Checks = append(c.Checks, c2.Checks...)
+	}
+
 	return c, nil
 }
@@ -5,8 +5,10 @@ package config
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	hcl2 "github.com/hashicorp/hcl2/hcl"
 	"github.com/hashicorp/hil/ast"
@@ -20,6 +22,11 @@ import (
 // resources, etc.) must follow.
 var NameRegexp = regexp.MustCompile(`(?i)\A[A-Z0-9_][A-Z0-9\-\_]*\z`)
 
+// resourceInstanceRegexp matches the address of a resource instance given
+// in an import, moved or removed block, capturing the resource id without
+// any index.
+var resourceInstanceRegexp = regexp.MustCompile(`\A([^.\[\]]+\.[^.\[\]]+)(\[[0-9]+\])?\z`)
+
 // Config is the configuration that comes from loading a collection
 // of Terraform templates.
 type Config struct {
@@ -36,6 +43,14 @@ type Config struct {
 	Variables       []*Variable
 	Locals          []*Local
 	Outputs         []*Output
+	Imports         []*Import
+	Moved           []*Moved
+	Removed         []*Removed
+	Checks          []*Check
+
+	// EphemeralResources are kept apart from Resources because they have
+	// no state: only a few kinds of configuration may refer to them.
+	EphemeralResources []*Resource
 
 	// The fields below can be filled in by loaders for validation
 	// purposes.
@@ -59,6 +74,9 @@ type Module struct {
 	Version   string
 	Providers map[string]string
 	RawConfig *RawConfig
+
+	// Ranges are where the module block and its arguments are.
+	Ranges SourceRanges
 }
 
 // ProviderConfig is the configuration for a resource provider.
@@ -69,7 +87,28 @@ type ProviderConfig struct {
 	Name      string
 	Alias     string
 	Version   string
+	Retry     *ProviderRetry
+	RateLimit float64
 	RawConfig *RawConfig
+
+	// ForEachKey and ForEachValue are the key and the value of the
+	// element of for_each that the configuration was expanded for, which
+	// are the values of each.key and each.value. ForEachKey is empty if
+	// the provider block has no for_each.
+	ForEachKey   string
+	ForEachValue interface{}
+
+	// Ranges are where the provider block and its arguments are. The
+	// configurations expanded from a block with for_each share its ranges.
+	Ranges SourceRanges
+}
+
+// ProviderRetry is the retry configuration of a provider, set with its
+// retry block. MinBackoff and MaxBackoff are durations, such as "1s".
+type ProviderRetry struct {
+	MaxAttempts int    `mapstructure:"max_attempts"`
+	MinBackoff  string `mapstructure:"min_backoff"`
+	MaxBackoff  string `mapstructure:"max_backoff"`
 }
 
 // A resource represents a single Terraform resource in the configuration.
@@ -86,6 +125,13 @@ type Resource struct {
 	Provider     string
 	DependsOn    []string
 	Lifecycle    ResourceLifecycle
+
+	// Check is the name of the check block that declares this data
+	// resource, if any.
+	Check string
+
+	// Ranges are where the resource block and its arguments are.
+	Ranges SourceRanges
 }
 
 // Copy returns a copy of this Resource. Helpful for avoiding shared
@@ -102,6 +148,8 @@ func (r *Resource) Copy() *Resource {
 		Provider:     r.Provider,
 		DependsOn:    make([]string, len(r.DependsOn)),
 		Lifecycle:    *r.Lifecycle.Copy(),
+		Check:        r.Check,
+		Ranges:       r.Ranges,
 	}
 	for _, p := range r.Provisioners {
 		n.Provisioners = append(n.Provisioners, p.Copy())
@@ -116,6 +164,31 @@ type ResourceLifecycle struct {
 	CreateBeforeDestroy bool     `mapstructure:"create_before_destroy"`
 	PreventDestroy      bool     `mapstructure:"prevent_destroy"`
 	IgnoreChanges       []string `mapstructure:"ignore_changes"`
+
+	// Group names the ordering group of the resource within its module.
+	// CreateAfterDestroy lists groups whose resources must all be
+	// destroyed before this resource is created or updated.
+	Group              string   `mapstructure:"group"`
+	CreateAfterDestroy []string `mapstructure:"create_after_destroy"`
+
+	// Preconditions are checked before a resource is planned or applied,
+	// and Postconditions after. Postconditions may use self variables to
+	// refer to the resource itself.
+	Preconditions  []*ConditionRule `mapstructure:"-"`
+	Postconditions []*ConditionRule `mapstructure:"-"`
+
+	// Timeouts limit how long applying each kind of change to the resource
+	// may take, regardless of the provider.
+	Timeouts ResourceTimeouts `mapstructure:"-"`
+}
+
+// ResourceTimeouts are the longest that Terraform waits for each kind of
+// change to an instance of a resource to be applied, from the timeouts
+// block of its lifecycle. A zero duration means there is no limit.
+type ResourceTimeouts struct {
+	Create time.Duration
+	Update time.Duration
+	Delete time.Duration
 }
 
 // Copy returns a copy of this ResourceLifecycle
@@ -124,8 +197,42 @@ func (r *ResourceLifecycle) Copy() *ResourceLifecycle {
 		CreateBeforeDestroy: r.CreateBeforeDestroy,
 		PreventDestroy:      r.PreventDestroy,
 		IgnoreChanges:       make([]string, len(r.IgnoreChanges)),
+		Group:               r.Group,
+		Preconditions:       copyConditionRules(r.Preconditions),
+		Postconditions:      copyConditionRules(r.Postconditions),
+		Timeouts:            r.Timeouts,
 	}
 	copy(n.IgnoreChanges, r.IgnoreChanges)
+	if r.CreateAfterDestroy != nil {
+		n.CreateAfterDestroy = make([]string, len(r.CreateAfterDestroy))
+		copy(n.CreateAfterDestroy, r.CreateAfterDestroy)
+	}
+	return n
+}
+
+// ConditionRule is a precondition or postcondition block. Its RawConfig
+// has a "condition" key giving a value that must be true, and an
+// "error_message" key giving the message to report if it isn't.
+type ConditionRule struct {
+	RawConfig *RawConfig
+}
+
+// Copy returns a copy of this ConditionRule
+func (r *ConditionRule) Copy() *ConditionRule {
+	return &ConditionRule{
+		RawConfig: r.RawConfig.Copy(),
+	}
+}
+
+func copyConditionRules(rs []*ConditionRule) []*ConditionRule {
+	if rs == nil {
+		return nil
+	}
+
+	n := make([]*ConditionRule, len(rs))
+	for i, r := range rs {
+		n[i] = r.Copy()
+	}
 	return n
 }
 
@@ -137,6 +244,10 @@ type Provisioner struct {
 
 	When      ProvisionerWhen
 	OnFailure ProvisionerOnFailure
+
+	// DependsOn are the resources and modules that a destroy-time
+	// provisioner needs, which are destroyed only after it has run.
+	DependsOn []string
 }
 
 // Copy returns a copy of this Provisioner
@@ -147,6 +258,7 @@ func (p *Provisioner) Copy() *Provisioner {
 		ConnInfo:  p.ConnInfo.Copy(),
 		When:      p.When,
 		OnFailure: p.OnFailure,
+		DependsOn: append([]string(nil), p.DependsOn...),
 	}
 }
 
@@ -156,12 +268,34 @@ type Variable struct {
 	DeclaredType string `mapstructure:"type"`
 	Default      interface{}
 	Description  string
+
+	// Secret, if set, is where the value of the variable is read from at
+	// run time, instead of being set with -var or a variable file.
+	Secret *VariableSecret
+
+	// Validations are checked against the value of the variable before it
+	// is used. Their conditions may refer to the variable itself, to the
+	// other variables of the module and to its local values.
+	Validations []*ConditionRule `mapstructure:"-"`
+
+	// Ranges are where the variable block and its arguments are.
+	Ranges SourceRanges `mapstructure:"-"`
+}
+
+// VariableSecret is the "secret" block of a variable, naming the secret in
+// one of the secrets backends of the CLI configuration.
+type VariableSecret struct {
+	Backend string
+	Key     string
 }
 
 // Local is a local value defined within the configuration.
 type Local struct {
 	Name      string
 	RawConfig *RawConfig
+
+	// Ranges are where the local value's argument is.
+	Ranges SourceRanges
 }
 
 // Output is an output defined within the configuration. An output is
@@ -174,6 +308,84 @@ type Output struct {
 	Description string
 	Sensitive   bool
 	RawConfig   *RawConfig
+
+	// Preconditions are checked before the output value is set.
+	Preconditions []*ConditionRule
+
+	// Ranges are where the output block and its arguments are.
+	Ranges SourceRanges
+}
+
+// Import is a request to import an existing object as an instance of a
+// managed resource in the configuration. The import is planned like any
+// other change, and takes effect only when the plan is applied.
+type Import struct {
+	// To is the address of the resource instance to import to, such as
+	// "aws_instance.foo" or "aws_instance.foo[1]".
+	To string
+
+	// ID is the provider-specific ID of the object to import.
+	ID string
+
+	// Provider optionally selects a provider configuration, such as
+	// "aws.west", that overrides the resource's own.
+	Provider string
+}
+
+// UndeclaredImportError is the error for an import block whose resource
+// isn't declared. Its code lets "terraform plan -generate-config-out"
+// tell these apart from other errors, since it writes the missing
+// configuration.
+type UndeclaredImportError struct {
+	// To is the address that the import is to, and Resource is the
+	// address of its resource, without an index.
+	To       string
+	Resource string
+}
+
+func (e *UndeclaredImportError) Error() string {
+	return fmt.Sprintf("import %s: unknown resource '%s'", e.To, e.Resource)
+}
+
+// DiagnosticCode implements tfdiags.Coder.
+func (e *UndeclaredImportError) DiagnosticCode() tfdiags.Code {
+	return tfdiags.CodeConfigImportUndeclared
+}
+
+// Moved records that the objects of a resource, or of one of its instances,
+// are now tracked at a new address. Planning moves the objects in the
+// state, rather than destroying them and creating new ones.
+type Moved struct {
+	// From and To are the old and new addresses, such as "aws_instance.foo"
+	// or "aws_instance.foo[1]". If they're of different resource types, the
+	// provider of the new type converts the state of the objects.
+	From string
+	To   string
+}
+
+// Removed records that the objects of a resource, or of one of its
+// instances, are no longer managed by Terraform. Planning removes the
+// objects from the state without destroying them.
+type Removed struct {
+	// From is the address of the objects to remove, such as
+	// "aws_instance.foo" or "aws_instance.foo[1]".
+	From string
+}
+
+// Check is a check block: assertions about the infrastructure whose status
+// is reported and recorded in the state, but which don't stop a plan or
+// apply when they fail.
+type Check struct {
+	Name string
+
+	// DataSources are the data resources declared in the check block.
+	// These are also in the Resources of the Config, with Check set to
+	// the name of this check, and can only be referred to from the check.
+	DataSources []*Resource
+
+	// Asserts have the same "condition" and "error_message" keys as
+	// preconditions and postconditions.
+	Asserts []*ConditionRule
 }
 
 // VariableType is the type of value a variable is holding, and returned
@@ -244,6 +456,8 @@ func (r *Resource) Id() string {
 		return fmt.Sprintf("%s.%s", r.Type, r.Name)
 	case DataResourceMode:
 		return fmt.Sprintf("data.%s.%s", r.Type, r.Name)
+	case EphemeralResourceMode:
+		return fmt.Sprintf("ephemeral.%s.%s", r.Type, r.Name)
 	default:
 		panic(fmt.Errorf("unknown resource mode %s", r.Mode))
 	}
@@ -331,6 +545,43 @@ func (c *Config) Validate() tfdiags.Diagnostics {
 			continue
 		}
 
+		if v.Secret != nil {
+			if v.Secret.Backend == "" || v.Secret.Key == "" {
+				diags = diags.Append(fmt.Errorf(
+					"variable %q: secret must set backend and key", v.Name,
+				))
+			}
+			if v.Default != nil {
+				diags = diags.Append(fmt.Errorf(
+					"variable %q: a secret variable may not have a default", v.Name,
+				))
+			}
+			if v.Type() != VariableTypeString {
+				diags = diags.Append(fmt.Errorf(
+					"variable %q: a secret variable must be a string", v.Name,
+				))
+			}
+		}
+
+		source := fmt.Sprintf("variable %q", v.Name)
+		for _, err := range validateConditionRules(source, "validation", v.Validations) {
+			diags = diags.Append(err)
+		}
+		for i, cr := range v.Validations {
+			for _, rv := range cr.RawConfig.Variables {
+				switch rv.(type) {
+				case *UserVariable, *LocalVariable:
+				case *SelfVariable:
+					// Reported along with other self references below
+				default:
+					diags = diags.Append(fmt.Errorf(
+						"%s: validation #%d: can only refer to input variables and local values, not %s",
+						source, i+1, rv.FullKey(),
+					))
+				}
+			}
+		}
+
 		interp := false
 		fn := func(n ast.Node) (interface{}, error) {
 			// LiteralNode is a literal string (outside of a ${ ... } sequence).
@@ -376,6 +627,15 @@ func (c *Config) Validate() tfdiags.Diagnostics {
 		}
 	}
 
+	// The each variables are only valid in the configurations of provider
+	// blocks with for_each.
+	eachSources := make(map[string]bool)
+	for _, pc := range c.ProviderConfigs {
+		if pc.ForEachKey != "" {
+			eachSources[fmt.Sprintf("provider config '%s'", pc.FullName())] = true
+		}
+	}
+
 	// Check that all count variables are valid.
 	for source, vs := range vars {
 		for _, rawV := range vs {
@@ -388,6 +648,20 @@ func (c *Config) Validate() tfdiags.Diagnostics {
 						v.FullKey(),
 					))
 				}
+			case *EachVariable:
+				if v.Type == EachValueInvalid {
+					diags = diags.Append(fmt.Errorf(
+						"%s: invalid each variable: %s; only each.key and each.value are valid",
+						source,
+						v.FullKey(),
+					))
+				} else if !eachSources[source] {
+					diags = diags.Append(fmt.Errorf(
+						"%s: %s is only valid in provider blocks with for_each",
+						source,
+						v.FullKey(),
+					))
+				}
 			case *PathVariable:
 				if v.Type == PathValueInvalid {
 					diags = diags.Append(fmt.Errorf(
@@ -413,6 +687,42 @@ func (c *Config) Validate() tfdiags.Diagnostics {
 			continue
 		}
 
+		if r := p.Retry; r != nil {
+			if r.MaxAttempts < 1 {
+				diags = diags.Append(fmt.Errorf(
+					"provider.%s: retry max_attempts must be at least 1", name,
+				))
+			}
+			var min, max time.Duration
+			for _, d := range []struct {
+				Name  string
+				Value string
+				Out   *time.Duration
+			}{
+				{"min_backoff", r.MinBackoff, &min},
+				{"max_backoff", r.MaxBackoff, &max},
+			} {
+				var err error
+				*d.Out, err = time.ParseDuration(d.Value)
+				if err != nil || *d.Out < 0 {
+					diags = diags.Append(fmt.Errorf(
+						"provider.%s: retry %s must be a duration, such as \"1s\"", name, d.Name,
+					))
+				}
+			}
+			if max < min {
+				diags = diags.Append(fmt.Errorf(
+					"provider.%s: retry max_backoff must not be less than min_backoff", name,
+				))
+			}
+		}
+
+		if p.RateLimit < 0 {
+			diags = diags.Append(fmt.Errorf(
+				"provider.%s: rate_limit must not be negative", name,
+			))
+		}
+
 		if p.Version != "" {
 			_, err := discovery.ConstraintStr(p.Version).Parse()
 			if err != nil {
@@ -578,6 +888,17 @@ func (c *Config) Validate() tfdiags.Diagnostics {
 
 		resources[r.Id()] = r
 	}
+	for _, r := range c.EphemeralResources {
+		if _, ok := resources[r.Id()]; ok {
+			diags = diags.Append(fmt.Errorf(
+				"%s: ephemeral resource repeated multiple times",
+				r.Id(),
+			))
+			continue
+		}
+
+		resources[r.Id()] = r
+	}
 	dupped = nil
 
 	// Validate resources
@@ -670,10 +991,32 @@ func (c *Config) Validate() tfdiags.Diagnostics {
 					"%s: provisioner 'on_failure' value is invalid", n,
 				))
 			}
+
+			// Only destroy-time provisioners have their own dependencies,
+			// since the others run along with the resource's creation.
+			if len(p.DependsOn) > 0 && p.When != ProvisionerWhenDestroy {
+				diags = diags.Append(fmt.Errorf(
+					"%s: provisioner %s: depends_on is only allowed for destroy-time provisioners",
+					n, p.Type,
+				))
+				continue
+			}
+			pn := fmt.Sprintf("%s: provisioner %s", n, p.Type)
+			for _, err := range c.validateDependsOn(pn, p.DependsOn, resources, modules) {
+				diags = diags.Append(err)
+			}
 		}
 
 		// Verify ignore_changes contains valid entries
 		for _, v := range r.Lifecycle.IgnoreChanges {
+			if IsIgnorePath(v) {
+				if _, err := ParseIgnorePath(v); err != nil {
+					diags = diags.Append(fmt.Errorf(
+						"%s: ignore_changes: %s", n, err,
+					))
+				}
+				continue
+			}
 			if strings.Contains(v, "*") && v != "*" {
 				diags = diags.Append(fmt.Errorf(
 					"%s: ignore_changes does not support using a partial string together with a wildcard: %s",
@@ -698,6 +1041,38 @@ func (c *Config) Validate() tfdiags.Diagnostics {
 			))
 		}
 
+		// Verify the ordering groups. The groups in create_after_destroy
+		// needn't be declared by any resource in the configuration, since
+		// their resources may have been removed from it to destroy them.
+		if g := r.Lifecycle.Group; g != "" && !NameRegexp.MatchString(g) {
+			diags = diags.Append(fmt.Errorf(
+				"%s: lifecycle group name %q is invalid. Group names must match %s",
+				n, g, NameRegexp,
+			))
+		}
+		for _, g := range r.Lifecycle.CreateAfterDestroy {
+			if !NameRegexp.MatchString(g) {
+				diags = diags.Append(fmt.Errorf(
+					"%s: lifecycle create_after_destroy group name %q is invalid. Group names must match %s",
+					n, g, NameRegexp,
+				))
+			}
+			if g == r.Lifecycle.Group && r.Lifecycle.CreateBeforeDestroy {
+				diags = diags.Append(fmt.Errorf(
+					"%s: lifecycle create_before_destroy can't be set when create_after_destroy includes the resource's own group %q",
+					n, g,
+				))
+			}
+		}
+
+		// Verify preconditions and postconditions
+		for _, err := range validateConditionRules(n, "precondition", r.Lifecycle.Preconditions) {
+			diags = diags.Append(err)
+		}
+		for _, err := range validateConditionRules(n, "postcondition", r.Lifecycle.Postconditions) {
+			diags = diags.Append(err)
+		}
+
 		// If it is a data source then it can't have provisioners
 		if r.Mode == DataResourceMode {
 			if _, ok := r.RawConfig.Raw["provisioner"]; ok {
@@ -729,6 +1104,181 @@ func (c *Config) Validate() tfdiags.Diagnostics {
 		}
 	}
 
+	// Check that ephemeral values can't end up in the state or the plan.
+	// They may only be used to configure providers and provisioners, and
+	// other ephemeral resources.
+	{
+		ephemeralRefs := func(source string, vs []InterpolatedVariable) {
+			for _, v := range vs {
+				rv, ok := v.(*ResourceVariable)
+				if !ok || rv.Mode != EphemeralResourceMode {
+					continue
+				}
+				diags = diags.Append(fmt.Errorf(
+					"%s: ephemeral resource '%s' can only be referenced by providers, provisioners and other ephemeral resources",
+					source, rv.ResourceId(),
+				))
+			}
+		}
+		for source, vs := range vars {
+			if strings.HasPrefix(source, "provider config ") ||
+				strings.HasPrefix(source, "ephemeral resource ") ||
+				strings.Contains(source, " provisioner ") {
+				continue
+			}
+			ephemeralRefs(source, vs)
+		}
+		for _, l := range c.Locals {
+			var vs []InterpolatedVariable
+			for _, v := range l.RawConfig.Variables {
+				vs = append(vs, v)
+			}
+			ephemeralRefs(fmt.Sprintf("local '%s'", l.Name), vs)
+		}
+	}
+
+	// Check that all the provider function calls are well-formed. Whether
+	// the providers have the functions is checked when they're called.
+	for source, names := range c.providerFunctionCalls() {
+		for _, name := range names {
+			if _, _, err := ParseProviderFunctionName(name); err != nil {
+				diags = diags.Append(fmt.Errorf("%s: %s", source, err))
+			}
+		}
+	}
+
+	// Check that all imports are valid
+	{
+		found := make(map[string]struct{})
+		for _, i := range c.Imports {
+			if i.To == "" || i.ID == "" {
+				diags = diags.Append(fmt.Errorf(
+					"import: both 'to' and 'id' must be set",
+				))
+				continue
+			}
+			if _, ok := found[i.To]; ok {
+				diags = diags.Append(fmt.Errorf(
+					"import %s: duplicate import. each resource instance may be imported only once",
+					i.To,
+				))
+				continue
+			}
+			found[i.To] = struct{}{}
+
+			m := resourceInstanceRegexp.FindStringSubmatch(i.To)
+			if m == nil {
+				diags = diags.Append(fmt.Errorf(
+					"import %s: 'to' must be a managed resource address such as aws_instance.foo or aws_instance.foo[1]",
+					i.To,
+				))
+				continue
+			}
+			r, ok := resources[m[1]]
+			if !ok || r.Mode != ManagedResourceMode {
+				diags = diags.Append(&UndeclaredImportError{
+					To:       i.To,
+					Resource: m[1],
+				})
+			}
+		}
+	}
+
+	// Check that all moves are valid
+	{
+		found := make(map[string]struct{})
+		for _, m := range c.Moved {
+			if m.From == "" || m.To == "" {
+				diags = diags.Append(fmt.Errorf(
+					"moved: both 'from' and 'to' must be set",
+				))
+				continue
+			}
+			if _, ok := found[m.From]; ok {
+				diags = diags.Append(fmt.Errorf(
+					"moved %s: duplicate move. each address may be moved only once",
+					m.From,
+				))
+				continue
+			}
+			found[m.From] = struct{}{}
+
+			from := resourceInstanceRegexp.FindStringSubmatch(m.From)
+			to := resourceInstanceRegexp.FindStringSubmatch(m.To)
+			if from == nil || to == nil {
+				diags = diags.Append(fmt.Errorf(
+					"moved %s: 'from' and 'to' must be managed resource addresses such as aws_instance.foo or aws_instance.foo[1]",
+					m.From,
+				))
+				continue
+			}
+			if m.From == m.To {
+				diags = diags.Append(fmt.Errorf(
+					"moved %s: 'from' and 'to' must be different",
+					m.From,
+				))
+				continue
+			}
+			r, ok := resources[to[1]]
+			if !ok || r.Mode != ManagedResourceMode {
+				diags = diags.Append(fmt.Errorf(
+					"moved %s: unknown resource '%s'",
+					m.From, to[1],
+				))
+			}
+			if from[1] != to[1] {
+				if _, ok := resources[from[1]]; ok {
+					diags = diags.Append(fmt.Errorf(
+						"moved %s: resource '%s' is still declared, so it cannot be moved",
+						m.From, from[1],
+					))
+				}
+			}
+		}
+	}
+
+	// Check that all removals are valid
+	{
+		found := make(map[string]struct{})
+		for _, r := range c.Removed {
+			if r.From == "" {
+				diags = diags.Append(fmt.Errorf(
+					"removed: 'from' must be set",
+				))
+				continue
+			}
+			if _, ok := found[r.From]; ok {
+				diags = diags.Append(fmt.Errorf(
+					"removed %s: duplicate removal. each address may be removed only once",
+					r.From,
+				))
+				continue
+			}
+			found[r.From] = struct{}{}
+
+			m := resourceInstanceRegexp.FindStringSubmatch(r.From)
+			if m == nil {
+				diags = diags.Append(fmt.Errorf(
+					"removed %s: 'from' must be a managed resource address such as aws_instance.foo or aws_instance.foo[1]",
+					r.From,
+				))
+				continue
+			}
+
+			// A single instance can be removed from a resource that is still
+			// declared, such as when its count is reduced, but a whole
+			// resource would only be created again.
+			if m[2] == "" {
+				if _, ok := resources[m[1]]; ok {
+					diags = diags.Append(fmt.Errorf(
+						"removed %s: resource '%s' is still declared, so it cannot be removed",
+						r.From, m[1],
+					))
+				}
+			}
+		}
+	}
+
 	// Check that all locals are valid
 	{
 		found := make(map[string]struct{})
@@ -813,6 +1363,11 @@ func (c *Config) Validate() tfdiags.Diagnostics {
 				))
 			}
 
+			source := fmt.Sprintf("output %q", o.Name)
+			for _, err := range validateConditionRules(source, "precondition", o.Preconditions) {
+				diags = diags.Append(err)
+			}
+
 			for _, v := range o.RawConfig.Variables {
 				if _, ok := v.(*CountVariable); ok {
 					diags = diags.Append(fmt.Errorf(
@@ -870,11 +1425,79 @@ func (c *Config) Validate() tfdiags.Diagnostics {
 		}
 	}
 
+	// Check that all checks are valid
+	{
+		found := make(map[string]struct{})
+		for _, chk := range c.Checks {
+			if _, ok := found[chk.Name]; ok {
+				diags = diags.Append(fmt.Errorf(
+					"check %q: a check of this name was already defined",
+					chk.Name,
+				))
+				continue
+			}
+			found[chk.Name] = struct{}{}
+
+			source := fmt.Sprintf("check %q", chk.Name)
+			if len(chk.Asserts) == 0 {
+				diags = diags.Append(fmt.Errorf(
+					"%s: at least one assert block is required", source,
+				))
+			}
+			for _, err := range validateConditionRules(source, "assert", chk.Asserts) {
+				diags = diags.Append(err)
+			}
+		}
+	}
+
+	// Data sources declared in a check can only be referred to from within
+	// the same check. The owner of each raw config is found from the
+	// prefix of its source, as given by rawConfigs.
+	{
+		scoped := make(map[string]string)
+		for _, chk := range c.Checks {
+			for _, r := range chk.DataSources {
+				scoped[r.Id()] = chk.Name
+			}
+		}
+
+		if len(scoped) > 0 {
+			for source, rc := range c.rawConfigs() {
+				owner := ""
+				for _, chk := range c.Checks {
+					if strings.HasPrefix(source, fmt.Sprintf("check '%s' ", chk.Name)) {
+						owner = chk.Name
+					}
+				}
+				for id, name := range scoped {
+					if strings.HasPrefix(source, fmt.Sprintf("resource '%s' ", id)) {
+						owner = name
+					}
+				}
+
+				for _, v := range rc.Variables {
+					rv, ok := v.(*ResourceVariable)
+					if !ok {
+						continue
+					}
+
+					name, ok := scoped[rv.ResourceId()]
+					if ok && name != owner {
+						diags = diags.Append(fmt.Errorf(
+							"%s: %s is declared in check %q and can only be referred to from within it",
+							source, rv.ResourceId(), name,
+						))
+					}
+				}
+			}
+		}
+	}
+
 	// Validate the self variable
 	for source, rc := range c.rawConfigs() {
-		// Ignore provisioners. This is a pretty brittle way to do this,
-		// but better than also repeating all the resources.
-		if strings.Contains(source, "provision") {
+		// Ignore provisioners and postconditions. This is a pretty brittle
+		// way to do this, but better than also repeating all the resources.
+		if strings.Contains(source, "provision") || strings.Contains(source, "postcondition") {
 			continue
 		}
 
@@ -904,6 +1527,45 @@ func (c *Config) InterpolatedVariables() map[string][]InterpolatedVariable {
 	return result
 }
 
+// FunctionProviders returns the sorted names of the providers whose
+// functions are called by this configuration.
+func (c *Config) FunctionProviders() []string {
+	seen := make(map[string]struct{})
+	for _, names := range c.providerFunctionCalls() {
+		for _, name := range names {
+			if p, _, err := ParseProviderFunctionName(name); err == nil {
+				seen[p] = struct{}{}
+			}
+		}
+	}
+
+	result := make([]string, 0, len(seen))
+	for p := range seen {
+		result = append(result, p)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// providerFunctionCalls returns the names of the provider functions called
+// by each of the RawConfigs, including those of local values, keyed by a
+// human-friendly source.
+func (c *Config) providerFunctionCalls() map[string][]string {
+	result := make(map[string][]string)
+	for source, rc := range c.rawConfigs() {
+		if len(rc.ProviderFunctions) > 0 {
+			result[source] = rc.ProviderFunctions
+		}
+	}
+	for _, l := range c.Locals {
+		if len(l.RawConfig.ProviderFunctions) > 0 {
+			result[fmt.Sprintf("local '%s'", l.Name)] = l.RawConfig.ProviderFunctions
+		}
+	}
+
+	return result
+}
+
 // rawConfigs returns all of the RawConfigs that are available keyed by
 // a human-friendly source.
 func (c *Config) rawConfigs() map[string]*RawConfig {
@@ -914,7 +1576,7 @@ func (c *Config) rawConfigs() map[string]*RawConfig {
 	}
 
 	for _, pc := range c.ProviderConfigs {
-		source := fmt.Sprintf("provider config '%s'", pc.Name)
+		source := fmt.Sprintf("provider config '%s'", pc.FullName())
 		result[source] = pc.RawConfig
 	}
 
@@ -929,16 +1591,71 @@ func (c *Config) rawConfigs() map[string]*RawConfig {
 				source, p.Type, i+1)
 			result[subsource] = p.RawConfig
 		}
+
+		for i, cr := range rc.Lifecycle.Preconditions {
+			result[fmt.Sprintf("%s precondition #%d", source, i+1)] = cr.RawConfig
+		}
+		for i, cr := range rc.Lifecycle.Postconditions {
+			result[fmt.Sprintf("%s postcondition #%d", source, i+1)] = cr.RawConfig
+		}
+	}
+
+	for _, rc := range c.EphemeralResources {
+		result[fmt.Sprintf("ephemeral resource '%s' config", rc.Id())] = rc.RawConfig
+	}
+
+	for _, chk := range c.Checks {
+		for i, cr := range chk.Asserts {
+			result[fmt.Sprintf("check '%s' assert #%d", chk.Name, i+1)] = cr.RawConfig
+		}
+	}
+
+	for _, v := range c.Variables {
+		for i, cr := range v.Validations {
+			result[fmt.Sprintf("variable '%s' validation #%d", v.Name, i+1)] = cr.RawConfig
+		}
 	}
 
 	for _, o := range c.Outputs {
 		source := fmt.Sprintf("output '%s'", o.Name)
 		result[source] = o.RawConfig
+
+		for i, cr := range o.Preconditions {
+			result[fmt.Sprintf("%s precondition #%d", source, i+1)] = cr.RawConfig
+		}
 	}
 
 	return result
 }
 
+// validateConditionRules checks that each of the given precondition,
+// postcondition or validation blocks has a condition and an error message.
+func validateConditionRules(n, typ string, rules []*ConditionRule) []error {
+	var errs []error
+	for i, r := range rules {
+		if _, ok := r.RawConfig.Raw["condition"]; !ok {
+			errs = append(errs, fmt.Errorf(
+				"%s: %s #%d: missing required 'condition' argument",
+				n, typ, i+1))
+		}
+
+		msg, ok := r.RawConfig.Raw["error_message"]
+		if !ok {
+			errs = append(errs, fmt.Errorf(
+				"%s: %s #%d: missing required 'error_message' argument",
+				n, typ, i+1))
+			continue
+		}
+		if s, ok := msg.(string); !ok || s == "" {
+			errs = append(errs, fmt.Errorf(
+				"%s: %s #%d: 'error_message' must be a non-empty string",
+				n, typ, i+1))
+		}
+	}
+
+	return errs
+}
+
 func (c *Config) validateDependsOn(
 	n string,
 	v []string,
@@ -991,6 +1708,7 @@ func (m *Module) mergerMerge(other merger) merger {
 	result := *m
 	result.Name = m2.Name
 	result.RawConfig = result.RawConfig.merge(m2.RawConfig)
+	result.Ranges = result.Ranges.merge(m2.Ranges)
 
 	if m2.Source != "" {
 		result.Source = m2.Source
@@ -1010,6 +1728,7 @@ func (o *Output) mergerMerge(m merger) merger {
 	result.Name = o2.Name
 	result.Description = o2.Description
 	result.RawConfig = result.RawConfig.merge(o2.RawConfig)
+	result.Ranges = result.Ranges.merge(o2.Ranges)
 	result.Sensitive = o2.Sensitive
 	result.DependsOn = o2.DependsOn
 
@@ -1038,10 +1757,17 @@ func (c *ProviderConfig) mergerMerge(m merger) merger {
 	result := *c
 	result.Name = c2.Name
 	result.RawConfig = result.RawConfig.merge(c2.RawConfig)
+	result.Ranges = result.Ranges.merge(c2.Ranges)
 
 	if c2.Alias != "" {
 		result.Alias = c2.Alias
 	}
+	if c2.Retry != nil {
+		result.Retry = c2.Retry
+	}
+	if c2.RateLimit != 0 {
+		result.RateLimit = c2.RateLimit
+	}
 
 	return &result
 }
@@ -1058,6 +1784,7 @@ func (r *Resource) mergerMerge(m merger) merger {
 	result.Name = r2.Name
 	result.Type = r2.Type
 	result.RawConfig = result.RawConfig.merge(r2.RawConfig)
+	result.Ranges = result.Ranges.merge(r2.Ranges)
 
 	if r2.RawCount.Value() != "1" {
 		result.RawCount = r2.RawCount
@@ -1087,6 +1814,13 @@ func (v *Variable) Merge(v2 *Variable) *Variable {
 	if v2.Description != "" {
 		result.Description = v2.Description
 	}
+	if v2.Secret != nil {
+		result.Secret = v2.Secret
+	}
+	if len(v2.Validations) > 0 {
+		result.Validations = v2.Validations
+	}
+	result.Ranges = result.Ranges.merge(v2.Ranges)
 
 	return &result
 }
@@ -1189,7 +1923,7 @@ func (m ResourceMode) Taintable() bool {
 	switch m {
 	case ManagedResourceMode:
 		return true
-	case DataResourceMode:
+	case DataResourceMode, EphemeralResourceMode:
 		return false
 	default:
 		panic(fmt.Errorf("unsupported ResourceMode value %s", m))
@@ -284,6 +284,9 @@ func resourcesStr(rs []*Resource) string {
 				if p.OnFailure != ProvisionerOnFailureFail {
 					result += fmt.Sprintf("      on_failure = %s\n", p.OnFailure.String())
 				}
+				if len(p.DependsOn) > 0 {
+					result += fmt.Sprintf("      depends_on = %s\n", strings.Join(p.DependsOn, ", "))
+				}
 
 				ks := make([]string, 0, len(p.RawConfig.Raw))
 				for k, _ := range p.RawConfig.Raw {
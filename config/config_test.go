@@ -13,6 +13,7 @@ import (
 
 	"github.com/hashicorp/hil/ast"
 	"github.com/hashicorp/terraform/helper/logging"
+	"github.com/hashicorp/terraform/tfdiags"
 )
 
 // This is the directory where our test fixtures are.
@@ -195,6 +196,27 @@ func TestConfigValidate_table(t *testing.T) {
 			"",
 		},
 
+		{
+			"destroy provisioner depends on",
+			"validate-provisioner-depends-on",
+			false,
+			"",
+		},
+
+		{
+			"create provisioner depends on",
+			"validate-provisioner-depends-on-create",
+			true,
+			"only allowed for destroy-time provisioners",
+		},
+
+		{
+			"destroy provisioner depends on non-existent resource",
+			"validate-provisioner-depends-on-bad",
+			true,
+			"non-existent resource 'aws_instance.db'",
+		},
+
 		{
 			"backend config with interpolations",
 			"validate-backend-interpolate",
@@ -219,6 +241,18 @@ func TestConfigValidate_table(t *testing.T) {
 			true,
 			"not a valid version constraint",
 		},
+		{
+			"provider with retry and rate limit",
+			"provider-retry",
+			false,
+			"",
+		},
+		{
+			"provider with invalid retry backoff",
+			"provider-retry-invalid",
+			true,
+			"max_backoff must not be less than min_backoff",
+		},
 		{
 			"invalid provider name in module block",
 			"validate-missing-provider",
@@ -395,6 +429,70 @@ func TestConfigValidate_dupModule(t *testing.T) {
 	}
 }
 
+func TestConfigValidate_check(t *testing.T) {
+	c := testConfig(t, "validate-check")
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestConfigValidate_checkNoAssert(t *testing.T) {
+	c := testConfig(t, "validate-check-no-assert")
+	if err := c.Validate(); err == nil {
+		t.Fatal("should not be valid")
+	}
+}
+
+func TestConfigValidate_checkScopedRef(t *testing.T) {
+	c := testConfig(t, "validate-check-scoped-ref")
+	diags := c.Validate()
+	if !diags.HasErrors() {
+		t.Fatal("should not be valid")
+	}
+	if errStr := diags.Err().Error(); !strings.Contains(errStr, "declared in check") {
+		t.Fatalf("wrong error: %s", errStr)
+	}
+}
+
+func TestConfigValidate_conditions(t *testing.T) {
+	c := testConfig(t, "validate-conditions")
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestConfigValidate_conditionsNoMessage(t *testing.T) {
+	c := testConfig(t, "validate-conditions-no-message")
+	if err := c.Validate(); err == nil {
+		t.Fatal("should not be valid")
+	}
+}
+
+func TestConfigValidate_conditionsSelf(t *testing.T) {
+	c := testConfig(t, "validate-conditions-self")
+	if err := c.Validate(); err == nil {
+		t.Fatal("should not be valid")
+	}
+}
+
+func TestConfigValidate_varValidation(t *testing.T) {
+	c := testConfig(t, "validate-var-validation")
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestConfigValidate_varValidationResource(t *testing.T) {
+	c := testConfig(t, "validate-var-validation-resource")
+	diags := c.Validate()
+	if !diags.HasErrors() {
+		t.Fatal("should not be valid")
+	}
+	if errStr := diags.Err().Error(); !strings.Contains(errStr, "can only refer to input variables and local values") {
+		t.Fatalf("wrong error: %s", errStr)
+	}
+}
+
 func TestConfigValidate_dupResource(t *testing.T) {
 	c := testConfig(t, "validate-dup-resource")
 	if err := c.Validate(); err == nil {
@@ -402,6 +500,27 @@ func TestConfigValidate_dupResource(t *testing.T) {
 	}
 }
 
+func TestConfigValidate_lifecycleGroup(t *testing.T) {
+	c := testConfig(t, "validate-lifecycle-group")
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestConfigValidate_lifecycleGroupBad(t *testing.T) {
+	c := testConfig(t, "validate-lifecycle-group-bad")
+	if err := c.Validate(); err == nil {
+		t.Fatal("should not be valid")
+	}
+}
+
+func TestConfigValidate_lifecycleGroupCBD(t *testing.T) {
+	c := testConfig(t, "validate-lifecycle-group-cbd")
+	if err := c.Validate(); err == nil {
+		t.Fatal("should not be valid")
+	}
+}
+
 func TestConfigValidate_ignoreChanges(t *testing.T) {
 	c := testConfig(t, "validate-ignore-changes")
 	if err := c.Validate(); err != nil {
@@ -416,6 +535,20 @@ func TestConfigValidate_ignoreChangesBad(t *testing.T) {
 	}
 }
 
+func TestConfigValidate_ignoreChangesPaths(t *testing.T) {
+	c := testConfig(t, "validate-ignore-changes-paths")
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestConfigValidate_ignoreChangesPathsBad(t *testing.T) {
+	c := testConfig(t, "validate-ignore-changes-paths-bad")
+	if err := c.Validate(); err == nil {
+		t.Fatal("should not be valid")
+	}
+}
+
 func TestConfigValidate_ignoreChangesInterpolate(t *testing.T) {
 	c := testConfig(t, "validate-ignore-changes-interpolate")
 	if err := c.Validate(); err == nil {
@@ -499,6 +632,115 @@ func TestConfigValidate_outputDuplicate(t *testing.T) {
 	}
 }
 
+func TestConfigValidate_import(t *testing.T) {
+	c := testConfig(t, "validate-import-good")
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestConfigValidate_importDuplicate(t *testing.T) {
+	c := testConfig(t, "validate-import-dup")
+	if err := c.Validate(); err == nil {
+		t.Fatal("should not be valid")
+	}
+}
+
+func TestConfigValidate_importUnknownResource(t *testing.T) {
+	c := testConfig(t, "validate-import-unknown")
+	diags := c.Validate()
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics; want 1", len(diags))
+	}
+	if code := tfdiags.GetCode(diags[0]); code != tfdiags.CodeConfigImportUndeclared {
+		t.Fatalf("wrong code %q", code)
+	}
+}
+
+func TestConfigValidate_moved(t *testing.T) {
+	c := testConfig(t, "validate-moved-good")
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestConfigValidate_movedDifferentType(t *testing.T) {
+	c := testConfig(t, "validate-moved-type")
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestConfigValidate_movedStillDeclared(t *testing.T) {
+	c := testConfig(t, "validate-moved-declared")
+	if err := c.Validate(); err == nil {
+		t.Fatal("should not be valid")
+	}
+}
+
+func TestConfigValidate_removed(t *testing.T) {
+	c := testConfig(t, "validate-removed-good")
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestConfigValidate_removedStillDeclared(t *testing.T) {
+	c := testConfig(t, "validate-removed-declared")
+	if err := c.Validate(); err == nil {
+		t.Fatal("should not be valid")
+	}
+}
+
+func TestConfigValidate_ephemeral(t *testing.T) {
+	c := testConfig(t, "validate-ephemeral-good")
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestConfigValidate_ephemeralLeak(t *testing.T) {
+	c := testConfig(t, "validate-ephemeral-leak")
+	if err := c.Validate(); err == nil {
+		t.Fatal("should not be valid")
+	}
+}
+
+func TestConfigValidate_providerFunction(t *testing.T) {
+	c := testConfig(t, "validate-provider-function-good")
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := c.FunctionProviders()
+	expected := []string{"aws"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestConfigValidate_providerFunctionBad(t *testing.T) {
+	c := testConfig(t, "validate-provider-function-bad")
+	if err := c.Validate(); err == nil {
+		t.Fatal("should not be valid")
+	}
+}
+
+func TestConfigValidate_providerForEach(t *testing.T) {
+	c := testConfig(t, "validate-provider-for-each")
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestConfigValidate_eachOutsideProvider(t *testing.T) {
+	c := testConfig(t, "validate-each-outside-provider")
+	diags := c.Validate()
+	if !diags.HasErrors() || !strings.Contains(diags.Err().Error(), "each.key is only valid in provider blocks with for_each") {
+		t.Fatalf("bad error: %v", diags.Err())
+	}
+}
+
 func TestConfigValidate_pathVar(t *testing.T) {
 	c := testConfig(t, "validate-path-var")
 	if err := c.Validate(); err != nil {
@@ -639,6 +881,13 @@ func TestConfigValidate_varDefaultInterpolateEscaped(t *testing.T) {
 	}
 }
 
+func TestConfigValidate_varSecretDefault(t *testing.T) {
+	c := testConfig(t, "validate-var-secret-default")
+	if err := c.Validate(); err == nil {
+		t.Fatal("should not be valid")
+	}
+}
+
 func TestConfigValidate_varDup(t *testing.T) {
 	c := testConfig(t, "validate-var-dup")
 	if err := c.Validate(); err == nil {
@@ -795,6 +1044,33 @@ func TestConfigProviderVersion(t *testing.T) {
 	}
 }
 
+func TestConfigProviderRetry(t *testing.T) {
+	c := testConfig(t, "provider-retry")
+
+	if len(c.ProviderConfigs) != 1 {
+		t.Fatal("expected 1 provider")
+	}
+
+	p := c.ProviderConfigs[0]
+	want := &ProviderRetry{
+		MaxAttempts: 5,
+		MinBackoff:  "1s",
+		MaxBackoff:  "1m",
+	}
+	if !reflect.DeepEqual(p.Retry, want) {
+		t.Fatalf("wrong retry\ngot:  %#v\nwant: %#v", p.Retry, want)
+	}
+	if p.RateLimit != 2.5 {
+		t.Fatalf("expected rate limit 2.5, got %v", p.RateLimit)
+	}
+
+	for _, k := range []string{"retry", "rate_limit"} {
+		if _, ok := p.RawConfig.Raw[k]; ok {
+			t.Fatalf("%q should not exist in raw config", k)
+		}
+	}
+}
+
 func TestResourceProviderFullName(t *testing.T) {
 	type testCase struct {
 		ResourceName string
@@ -49,6 +49,12 @@ type Attribute struct {
 	// future to help Terraform mask sensitive information. (Terraform
 	// currently achieves this in a limited sense via other mechanisms.)
 	Sensitive bool
+
+	// Normalize lists the kinds of differences in the attribute's value
+	// that aren't significant to the provider, such as "case" for a value
+	// that the remote system compares case-insensitively. The kinds are
+	// those that diffs.ParseNormalizeKind accepts.
+	Normalize []string
 }
 
 // NestedBlock represents the embedding of one block within another.
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/hil/ast"
+)
+
+var (
+	extensionFuncs     map[string]ast.Function
+	extensionFuncsLock sync.RWMutex
+)
+
+// RegisterFuncs makes the given functions available to interpolations,
+// along with the built-in functions, such as the WASM functions declared in
+// the CLI configuration. It's an error to register a function with the name
+// of a built-in function or of one that's already registered, in which case
+// none of the given functions are registered.
+func RegisterFuncs(funcs map[string]ast.Function) error {
+	extensionFuncsLock.Lock()
+	defer extensionFuncsLock.Unlock()
+
+	builtins := builtinFuncs()
+	for name := range funcs {
+		if !NameRegexp.MatchString(name) {
+			return fmt.Errorf("invalid function name %q, it must match %s", name, NameRegexp)
+		}
+		if _, ok := builtins[name]; ok {
+			return fmt.Errorf("function %q is already a built-in function", name)
+		}
+		if _, ok := extensionFuncs[name]; ok {
+			return fmt.Errorf("function %q is already registered", name)
+		}
+	}
+
+	if extensionFuncs == nil {
+		extensionFuncs = make(map[string]ast.Function)
+	}
+	for name, f := range funcs {
+		extensionFuncs[name] = f
+	}
+	return nil
+}
+
+// registeredFuncs returns the functions registered with RegisterFuncs.
+func registeredFuncs() map[string]ast.Function {
+	extensionFuncsLock.RLock()
+	defer extensionFuncsLock.RUnlock()
+
+	result := make(map[string]ast.Function, len(extensionFuncs))
+	for name, f := range extensionFuncs {
+		result[name] = f
+	}
+	return result
+}
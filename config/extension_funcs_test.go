@@ -0,0 +1,59 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hil/ast"
+)
+
+func TestRegisterFuncs(t *testing.T) {
+	defer func() { extensionFuncs = nil }()
+
+	f := ast.Function{
+		ReturnType: ast.TypeString,
+		Callback: func([]interface{}) (interface{}, error) {
+			return "hello", nil
+		},
+	}
+
+	if err := RegisterFuncs(map[string]ast.Function{"greeting": f}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, ok := Funcs()["greeting"]; !ok {
+		t.Fatal("greeting should be registered")
+	}
+	if _, ok := Funcs()["upper"]; !ok {
+		t.Fatal("built-in functions should still be available")
+	}
+
+	cases := map[string]struct {
+		Funcs map[string]ast.Function
+		Error string
+	}{
+		"built-in": {
+			map[string]ast.Function{"farewell": f, "upper": f},
+			"already a built-in function",
+		},
+		"registered": {
+			map[string]ast.Function{"greeting": f},
+			"already registered",
+		},
+		"invalid name": {
+			map[string]ast.Function{"farewell": f, "good bye": f},
+			"invalid function name",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := RegisterFuncs(tc.Funcs)
+			if err == nil || !strings.Contains(err.Error(), tc.Error) {
+				t.Fatalf("bad: %v", err)
+			}
+			if _, ok := Funcs()["farewell"]; ok {
+				t.Fatal("no functions should be registered on error")
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// IgnorePath is an entry of lifecycle ignore_changes written as a path
+// expression, such as `tags["kubernetes.io/*"]` or `rule[*].description`,
+// rather than as a plain attribute name.
+//
+// Each step is either an attribute name or an index in brackets. An index
+// is "*" to match any element, a number to match a list element, or a key
+// of a map element, which can be quoted and can contain "*" to match any
+// sequence of characters.
+type IgnorePath []IgnoreStep
+
+// IgnoreStep is a step of an IgnorePath. Exactly one of Name and Index is
+// set.
+type IgnoreStep struct {
+	// Name is the name of the attribute that the step matches.
+	Name string
+
+	// Index is the index that the step matches. Quoted is set if it was
+	// written in quotes, in which case it only matches keys of map
+	// elements, even if it's "*" or a number.
+	Index  string
+	Quoted bool
+}
+
+// IsIgnorePath returns true if the given ignore_changes entry is a path
+// expression with indexes, to be parsed with ParseIgnorePath, rather than
+// an attribute name.
+func IsIgnorePath(s string) bool {
+	return strings.ContainsRune(s, '[')
+}
+
+// ParseIgnorePath parses an ignore_changes entry written as a path
+// expression.
+func ParseIgnorePath(s string) (IgnorePath, error) {
+	var ret IgnorePath
+	rest := s
+	for rest != "" {
+		switch {
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if strings.HasPrefix(rest, `["`) {
+				// A quoted key can contain brackets.
+				q := strings.IndexByte(rest[2:], '"')
+				if q < 0 {
+					return nil, fmt.Errorf("invalid path %q: unterminated quoted key", s)
+				}
+				end = q + 3
+				if end >= len(rest) || rest[end] != ']' {
+					return nil, fmt.Errorf("invalid path %q: expected ] after quoted key", s)
+				}
+				ret = append(ret, IgnoreStep{Index: rest[2 : end-1], Quoted: true})
+			} else {
+				if end < 0 {
+					return nil, fmt.Errorf("invalid path %q: unterminated index", s)
+				}
+				idx := strings.TrimSpace(rest[1:end])
+				if idx == "" {
+					return nil, fmt.Errorf("invalid path %q: empty index", s)
+				}
+				ret = append(ret, IgnoreStep{Index: idx})
+			}
+			rest = rest[end+1:]
+			if rest != "" && rest[0] != '.' && rest[0] != '[' {
+				return nil, fmt.Errorf("invalid path %q: expected . or [ after index", s)
+			}
+			if strings.HasPrefix(rest, ".") {
+				rest = rest[1:]
+				if rest == "" || rest[0] == '[' {
+					return nil, fmt.Errorf("invalid path %q: expected attribute name after .", s)
+				}
+			}
+
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			if name == "" || strings.ContainsAny(name, `*]"`) {
+				return nil, fmt.Errorf("invalid path %q: invalid attribute name %q", s, name)
+			}
+			ret = append(ret, IgnoreStep{Name: name})
+			rest = rest[end:]
+			if strings.HasPrefix(rest, ".") {
+				rest = rest[1:]
+				if rest == "" || rest[0] == '[' {
+					return nil, fmt.Errorf("invalid path %q: expected attribute name after .", s)
+				}
+			}
+		}
+	}
+
+	if len(ret) == 0 || ret[0].Name == "" {
+		return nil, fmt.Errorf("invalid path %q: must start with an attribute name", s)
+	}
+	return ret, nil
+}
+
+// MatchStep returns true if the given step of a value's path matches the
+// step of the ignore path at the given position.
+func (p IgnorePath) MatchStep(i int, step cty.PathStep) bool {
+	s := p[i]
+	switch ts := step.(type) {
+	case cty.GetAttrStep:
+		return s.Name != "" && s.Name == ts.Name
+	case cty.IndexStep:
+		if s.Name != "" || !ts.Key.IsKnown() || ts.Key.IsNull() {
+			return false
+		}
+		switch ts.Key.Type() {
+		case cty.Number:
+			return !s.Quoted && (s.Index == "*" || s.Index == ts.Key.AsBigFloat().Text('f', -1))
+		case cty.String:
+			if s.Index == "*" && !s.Quoted {
+				return true
+			}
+			return globMatch(s.Index, ts.Key.AsString())
+		}
+	}
+	return false
+}
+
+// Match returns true if the given path is matched by the ignore path or
+// is within a value that it matches.
+func (p IgnorePath) Match(path cty.Path) bool {
+	if len(path) < len(p) {
+		return false
+	}
+	for i := range p {
+		if !p.MatchStep(i, path[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p IgnorePath) String() string {
+	var buf strings.Builder
+	for i, s := range p {
+		switch {
+		case s.Name != "":
+			if i > 0 {
+				buf.WriteByte('.')
+			}
+			buf.WriteString(s.Name)
+		case s.Quoted:
+			fmt.Fprintf(&buf, "[%s]", strconv.Quote(s.Index))
+		default:
+			fmt.Fprintf(&buf, "[%s]", s.Index)
+		}
+	}
+	return buf.String()
+}
+
+// globMatch returns true if the given string matches the given pattern, in
+// which "*" matches any sequence of characters.
+func globMatch(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return len(s) >= len(last) && strings.HasSuffix(s, last)
+}
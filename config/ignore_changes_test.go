@@ -0,0 +1,107 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestParseIgnorePath(t *testing.T) {
+	cases := []struct {
+		Input string
+		Want  string
+		Err   bool
+	}{
+		{`tags["kubernetes.io/*"]`, `tags["kubernetes.io/*"]`, false},
+		{`rule[*].description`, `rule[*].description`, false},
+		{`rule[0]`, `rule[0]`, false},
+		{`a.b[*][1].c`, `a.b[*][1].c`, false},
+		{`tags["a]b"]`, `tags["a]b"]`, false},
+		{`[0]`, "", true},
+		{`tags[`, "", true},
+		{`tags[]`, "", true},
+		{`tags["a"`, "", true},
+		{`rule[0]x`, "", true},
+		{`rule.[0]`, "", true},
+		{`rule[0].`, "", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Input, func(t *testing.T) {
+			p, err := ParseIgnorePath(tc.Input)
+			if (err != nil) != tc.Err {
+				t.Fatalf("wrong error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if got := p.String(); got != tc.Want {
+				t.Fatalf("wrong result %s; want %s", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestIgnorePathMatch(t *testing.T) {
+	cases := []struct {
+		Path  string
+		Value cty.Path
+		Want  bool
+	}{
+		{
+			`tags["kubernetes.io/*"]`,
+			cty.Path(nil).GetAttr("tags").Index(cty.StringVal("kubernetes.io/role")),
+			true,
+		},
+		{
+			`tags["kubernetes.io/*"]`,
+			cty.Path(nil).GetAttr("tags").Index(cty.StringVal("Name")),
+			false,
+		},
+		{
+			`tags["kubernetes.io/*"]`,
+			cty.Path(nil).GetAttr("tags"),
+			false,
+		},
+		{
+			`rule[*].description`,
+			cty.Path(nil).GetAttr("rule").Index(cty.NumberIntVal(2)).GetAttr("description"),
+			true,
+		},
+		{
+			`rule[*].description`,
+			cty.Path(nil).GetAttr("rule").Index(cty.NumberIntVal(2)).GetAttr("port"),
+			false,
+		},
+		{
+			`rule[1]`,
+			cty.Path(nil).GetAttr("rule").Index(cty.NumberIntVal(1)).GetAttr("port"),
+			true,
+		},
+		{
+			`rule[1]`,
+			cty.Path(nil).GetAttr("rule").Index(cty.NumberIntVal(10)),
+			false,
+		},
+		{
+			`rule["*"]`,
+			cty.Path(nil).GetAttr("rule").Index(cty.NumberIntVal(1)),
+			false,
+		},
+		{
+			`tags["*-id"]`,
+			cty.Path(nil).GetAttr("tags").Index(cty.StringVal("vpc-id")),
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		p, err := ParseIgnorePath(tc.Path)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.Path, err)
+		}
+		if got := p.Match(tc.Value); got != tc.Want {
+			t.Errorf("%s matching %#v: got %t, want %t", tc.Path, tc.Value, got, tc.Want)
+		}
+	}
+}
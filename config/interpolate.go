@@ -49,6 +49,23 @@ const (
 	CountValueIndex
 )
 
+// EachVariable is a variable for referencing the key or the value of the
+// element of for_each that a provider configuration was expanded for.
+type EachVariable struct {
+	Type EachValueType
+	key  string
+	varRange
+}
+
+// EachValueType is the type of the each variable that is referenced.
+type EachValueType byte
+
+const (
+	EachValueInvalid EachValueType = iota
+	EachValueKey
+	EachValueValue
+)
+
 // A ModuleVariable is a variable that is referencing the output
 // of a module, such as "${module.foo.bar}"
 type ModuleVariable struct {
@@ -136,6 +153,8 @@ type LocalVariable struct {
 func NewInterpolatedVariable(v string) (InterpolatedVariable, error) {
 	if strings.HasPrefix(v, "count.") {
 		return NewCountVariable(v)
+	} else if strings.HasPrefix(v, "each.") {
+		return NewEachVariable(v)
 	} else if strings.HasPrefix(v, "path.") {
 		return NewPathVariable(v)
 	} else if strings.HasPrefix(v, "self.") {
@@ -173,6 +192,26 @@ func (c *CountVariable) FullKey() string {
 	return c.key
 }
 
+func NewEachVariable(key string) (*EachVariable, error) {
+	var fieldType EachValueType
+	parts := strings.SplitN(key, ".", 2)
+	switch parts[1] {
+	case "key":
+		fieldType = EachValueKey
+	case "value":
+		fieldType = EachValueValue
+	}
+
+	return &EachVariable{
+		Type: fieldType,
+		key:  key,
+	}, nil
+}
+
+func (c *EachVariable) FullKey() string {
+	return c.key
+}
+
 func NewModuleVariable(key string) (*ModuleVariable, error) {
 	parts := strings.SplitN(key, ".", 3)
 	if len(parts) < 3 {
@@ -233,6 +272,15 @@ func NewResourceVariable(key string) (*ResourceVariable, error) {
 		// Don't actually need the "data." prefix for parsing, since it's
 		// always constant.
 		parts = parts[1:]
+	} else if strings.HasPrefix(key, "ephemeral.") {
+		mode = EphemeralResourceMode
+		parts = strings.SplitN(key, ".", 4)
+		if len(parts) < 4 {
+			return nil, fmt.Errorf(
+				"%s: ephemeral variables must be four parts: ephemeral.TYPE.NAME.ATTR",
+				key)
+		}
+		parts = parts[1:]
 	} else {
 		mode = ManagedResourceMode
 		parts = strings.SplitN(key, ".", 3)
@@ -282,6 +330,8 @@ func (v *ResourceVariable) ResourceId() string {
 		return fmt.Sprintf("%s.%s", v.Type, v.Name)
 	case DataResourceMode:
 		return fmt.Sprintf("data.%s.%s", v.Type, v.Name)
+	case EphemeralResourceMode:
+		return fmt.Sprintf("ephemeral.%s.%s", v.Type, v.Name)
 	default:
 		panic(fmt.Errorf("unknown resource mode %s", v.Mode))
 	}
@@ -58,8 +58,18 @@ func listVariableValueToStringSlice(values []ast.Variable) ([]string, error) {
 	return output, nil
 }
 
-// Funcs is the mapping of built-in functions for configuration.
+// Funcs is the mapping of functions for configuration: the built-in
+// functions, and those registered with RegisterFuncs.
 func Funcs() map[string]ast.Function {
+	funcs := builtinFuncs()
+	for name, f := range registeredFuncs() {
+		funcs[name] = f
+	}
+	return funcs
+}
+
+// builtinFuncs is the mapping of built-in functions for configuration.
+func builtinFuncs() map[string]ast.Function {
 	return map[string]ast.Function{
 		"abs":          interpolationFuncAbs(),
 		"basename":     interpolationFuncBasename(),
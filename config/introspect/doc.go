@@ -0,0 +1,9 @@
+// Package introspect describes a fully decoded configuration, including
+// all of its child modules, for tools such as linters, language servers and
+// documentation generators that would otherwise have to load it themselves.
+//
+// The description includes where each object is declared and the
+// expressions of its arguments, with the references between objects that
+// they make. Its types can be serialized as JSON, which is the format of
+// the "terraform metadata dump" command.
+package introspect
@@ -0,0 +1,391 @@
+package introspect
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/tfdiags"
+)
+
+// Module describes a module of a configuration and its child modules.
+type Module struct {
+	// Path is the address of the module, such as "module.network", which
+	// is empty for the root module.
+	Path string `json:"path"`
+
+	// Dir is the directory that the module was loaded from.
+	Dir string `json:"dir"`
+
+	Variables   []*Variable   `json:"variables"`
+	Locals      []*Local      `json:"locals"`
+	Outputs     []*Output     `json:"outputs"`
+	Providers   []*Provider   `json:"providers"`
+	Resources   []*Resource   `json:"resources"`
+	ModuleCalls []*ModuleCall `json:"module_calls"`
+
+	// Children are the modules called by the module, sorted by name.
+	Children []*Module `json:"children"`
+}
+
+// Variable is an input variable of a module.
+type Variable struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Description string      `json:"description,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	Required    bool        `json:"required"`
+
+	// Secret is set if the value of the variable is read from a secrets
+	// backend.
+	Secret bool `json:"secret,omitempty"`
+
+	Range *Range `json:"range,omitempty"`
+}
+
+// Local is a local value of a module.
+type Local struct {
+	Name       string      `json:"name"`
+	Expression *Expression `json:"expression"`
+	Range      *Range      `json:"range,omitempty"`
+}
+
+// Output is an output value of a module.
+type Output struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	Sensitive   bool          `json:"sensitive,omitempty"`
+	DependsOn   []string      `json:"depends_on,omitempty"`
+	Expressions []*Expression `json:"expressions"`
+	Range       *Range        `json:"range,omitempty"`
+}
+
+// Provider is a provider configuration of a module.
+type Provider struct {
+	Name        string        `json:"name"`
+	Alias       string        `json:"alias,omitempty"`
+	Version     string        `json:"version,omitempty"`
+	Expressions []*Expression `json:"expressions"`
+	Range       *Range        `json:"range,omitempty"`
+}
+
+// Resource is a resource of a module, of any mode.
+type Resource struct {
+	// Address is the address of the resource within its module, such as
+	// "aws_instance.web" or "data.aws_ami.ubuntu".
+	Address string `json:"address"`
+
+	// Mode is "managed", "data" or "ephemeral".
+	Mode string `json:"mode"`
+
+	Type string `json:"type"`
+	Name string `json:"name"`
+
+	// Provider is the name of the provider configuration that the
+	// resource uses, such as "aws" or "aws.west".
+	Provider string `json:"provider"`
+
+	DependsOn []string `json:"depends_on,omitempty"`
+
+	// Check is the name of the check block that declares the resource, if
+	// it's a data resource of a check.
+	Check string `json:"check,omitempty"`
+
+	Expressions []*Expression `json:"expressions"`
+	Range       *Range        `json:"range,omitempty"`
+}
+
+// ModuleCall is a module block of a module.
+type ModuleCall struct {
+	Name    string `json:"name"`
+	Source  string `json:"source"`
+	Version string `json:"version,omitempty"`
+
+	// Providers maps the names of the provider configurations of the child
+	// module to those of the calling module that are passed to it.
+	Providers map[string]string `json:"providers,omitempty"`
+
+	Expressions []*Expression `json:"expressions"`
+	Range       *Range        `json:"range,omitempty"`
+}
+
+// Expression is the value of an argument of an object.
+type Expression struct {
+	// Attribute is the name of the argument.
+	Attribute string `json:"attribute"`
+
+	// Value is the value as written, in which interpolations are still
+	// strings such as "${var.ami}".
+	Value interface{} `json:"value"`
+
+	// References are the values that the expression refers to, sorted by
+	// their text.
+	References []*Reference `json:"references"`
+
+	Range *Range `json:"range,omitempty"`
+}
+
+// Reference is a reference of an expression to another value.
+type Reference struct {
+	// Text is the reference as written, such as "aws_instance.web.0.id".
+	Text string `json:"text"`
+
+	// Target is the address of the object of the same module that is
+	// referred to, such as "var.ami", "local.name", "module.network",
+	// "aws_instance.web" or "data.aws_ami.ubuntu". It is empty for
+	// references such as "count.index" and "path.module" that don't refer
+	// to objects.
+	Target string `json:"target,omitempty"`
+}
+
+// Range is a range of configuration source. Lines and columns start at 1
+// and bytes at 0, and the end is exclusive.
+type Range struct {
+	Filename string `json:"filename"`
+	Start    Pos    `json:"start"`
+	End      Pos    `json:"end"`
+}
+
+// Pos is a position in configuration source.
+type Pos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Byte   int `json:"byte"`
+}
+
+// Describe returns the description of the root module of the given tree
+// and its descendents. The tree must be loaded.
+//
+// Where objects are declared is only known for the configuration loaded
+// from HCL files, so their ranges are nil otherwise.
+func Describe(t *module.Tree) *Module {
+	return describeModule(t, nil)
+}
+
+func describeModule(t *module.Tree, path []string) *Module {
+	c := t.Config()
+	m := &Module{
+		Path:        modulePath(path),
+		Dir:         c.Dir,
+		Variables:   make([]*Variable, 0, len(c.Variables)),
+		Locals:      make([]*Local, 0, len(c.Locals)),
+		Outputs:     make([]*Output, 0, len(c.Outputs)),
+		Providers:   make([]*Provider, 0, len(c.ProviderConfigs)),
+		Resources:   make([]*Resource, 0, len(c.Resources)+len(c.EphemeralResources)),
+		ModuleCalls: make([]*ModuleCall, 0, len(c.Modules)),
+		Children:    make([]*Module, 0, len(c.Modules)),
+	}
+
+	for _, v := range c.Variables {
+		m.Variables = append(m.Variables, &Variable{
+			Name:        v.Name,
+			Type:        v.Type().Printable(),
+			Description: v.Description,
+			Default:     v.Default,
+			Required:    v.Required(),
+			Secret:      v.Secret != nil,
+			Range:       newRange(v.Ranges.Decl),
+		})
+	}
+
+	for _, l := range c.Locals {
+		exprs := expressions(l.RawConfig, config.SourceRanges{
+			Attrs: map[string]tfdiags.SourceRange{"value": l.Ranges.Decl},
+		})
+		local := &Local{
+			Name:  l.Name,
+			Range: newRange(l.Ranges.Decl),
+		}
+		if len(exprs) > 0 {
+			local.Expression = exprs[0]
+		}
+		m.Locals = append(m.Locals, local)
+	}
+
+	for _, o := range c.Outputs {
+		m.Outputs = append(m.Outputs, &Output{
+			Name:        o.Name,
+			Description: o.Description,
+			Sensitive:   o.Sensitive,
+			DependsOn:   o.DependsOn,
+			Expressions: expressions(o.RawConfig, o.Ranges),
+			Range:       newRange(o.Ranges.Decl),
+		})
+	}
+
+	for _, p := range c.ProviderConfigs {
+		m.Providers = append(m.Providers, &Provider{
+			Name:        p.Name,
+			Alias:       p.Alias,
+			Version:     p.Version,
+			Expressions: expressions(p.RawConfig, p.Ranges),
+			Range:       newRange(p.Ranges.Decl),
+		})
+	}
+
+	resources := make([]*config.Resource, 0, len(c.Resources)+len(c.EphemeralResources))
+	resources = append(resources, c.Resources...)
+	resources = append(resources, c.EphemeralResources...)
+	for _, r := range resources {
+		exprs := expressions(r.RawConfig, r.Ranges)
+		if count := countExpression(r); count != nil {
+			exprs = append(exprs, count)
+			sort.Slice(exprs, func(i, j int) bool {
+				return exprs[i].Attribute < exprs[j].Attribute
+			})
+		}
+
+		m.Resources = append(m.Resources, &Resource{
+			Address:     r.Id(),
+			Mode:        resourceMode(r.Mode),
+			Type:        r.Type,
+			Name:        r.Name,
+			Provider:    r.ProviderFullName(),
+			DependsOn:   r.DependsOn,
+			Check:       r.Check,
+			Expressions: exprs,
+			Range:       newRange(r.Ranges.Decl),
+		})
+	}
+
+	for _, mc := range c.Modules {
+		m.ModuleCalls = append(m.ModuleCalls, &ModuleCall{
+			Name:        mc.Name,
+			Source:      mc.Source,
+			Version:     mc.Version,
+			Providers:   mc.Providers,
+			Expressions: expressions(mc.RawConfig, mc.Ranges),
+			Range:       newRange(mc.Ranges.Decl),
+		})
+	}
+
+	children := t.Children()
+	names := make([]string, 0, len(children))
+	for name := range children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		childPath := make([]string, len(path), len(path)+1)
+		copy(childPath, path)
+		childPath = append(childPath, name)
+		m.Children = append(m.Children, describeModule(children[name], childPath))
+	}
+
+	return m
+}
+
+// expressions returns the expressions of the top-level arguments of the
+// given configuration, sorted by name, with their ranges from the given
+// ranges.
+func expressions(raw *config.RawConfig, ranges config.SourceRanges) []*Expression {
+	if raw == nil {
+		return []*Expression{}
+	}
+
+	keys := make([]string, 0, len(raw.Raw))
+	for k := range raw.Raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]*Expression, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, expression(k, raw.Raw[k], ranges.Attrs[k]))
+	}
+	return result
+}
+
+// countExpression returns the expression of the count argument of the given
+// resource, or nil if it hasn't got one.
+func countExpression(r *config.Resource) *Expression {
+	if r.RawCount == nil {
+		return nil
+	}
+	v, ok := r.RawCount.Raw["count"]
+	if !ok {
+		return nil
+	}
+
+	// The loader sets a count of "1" for resources that haven't got one.
+	rng, declared := r.Ranges.Attrs["count"]
+	if !declared && v == "1" {
+		return nil
+	}
+	return expression("count", v, rng)
+}
+
+func expression(name string, value interface{}, rng tfdiags.SourceRange) *Expression {
+	expr := &Expression{
+		Attribute:  name,
+		Value:      value,
+		References: []*Reference{},
+		Range:      newRange(rng),
+	}
+
+	// The references are found by interpolating the single argument.
+	rc, err := config.NewRawConfig(map[string]interface{}{name: value})
+	if err != nil {
+		// The configuration was already loaded from the same value, so this
+		// can't fail.
+		return expr
+	}
+	for text, v := range rc.Variables {
+		expr.References = append(expr.References, &Reference{
+			Text:   text,
+			Target: referenceTarget(v),
+		})
+	}
+	sort.Slice(expr.References, func(i, j int) bool {
+		return expr.References[i].Text < expr.References[j].Text
+	})
+	return expr
+}
+
+// referenceTarget returns the address of the object that the given variable
+// refers to, or an empty string if it doesn't refer to an object.
+func referenceTarget(v config.InterpolatedVariable) string {
+	switch v := v.(type) {
+	case *config.UserVariable:
+		return "var." + v.Name
+	case *config.LocalVariable:
+		return "local." + v.Name
+	case *config.ModuleVariable:
+		return "module." + v.Name
+	case *config.ResourceVariable:
+		return v.ResourceId()
+	default:
+		return ""
+	}
+}
+
+func resourceMode(mode config.ResourceMode) string {
+	switch mode {
+	case config.DataResourceMode:
+		return "data"
+	case config.EphemeralResourceMode:
+		return "ephemeral"
+	default:
+		return "managed"
+	}
+}
+
+func modulePath(path []string) string {
+	if len(path) == 0 {
+		return ""
+	}
+	return "module." + strings.Join(path, ".module.")
+}
+
+// newRange returns the given range, or nil if it's unknown.
+func newRange(rng tfdiags.SourceRange) *Range {
+	if rng.Start.Line == 0 {
+		return nil
+	}
+	return &Range{
+		Filename: rng.Filename,
+		Start:    Pos{Line: rng.Start.Line, Column: rng.Start.Column, Byte: rng.Start.Byte},
+		End:      Pos{Line: rng.End.Line, Column: rng.End.Column, Byte: rng.End.Byte},
+	}
+}
@@ -0,0 +1,97 @@
+package introspect
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/config/module"
+)
+
+func TestDescribe(t *testing.T) {
+	tree, cleanup := module.TestTree(t, filepath.Join("test-fixtures", "basic"))
+	defer cleanup()
+
+	m := Describe(tree)
+	if m.Path != "" {
+		t.Fatalf("wrong root path %q", m.Path)
+	}
+
+	if len(m.Variables) != 1 {
+		t.Fatalf("wrong variables %#v", m.Variables)
+	}
+	v := m.Variables[0]
+	if v.Name != "ami" || v.Type != "string" || !v.Required || v.Description != "The AMI to launch" {
+		t.Fatalf("wrong variable %#v", v)
+	}
+	wantRange := &Range{
+		Filename: filepath.Join("test-fixtures", "basic", "main.tf"),
+		Start:    Pos{Line: 1, Column: 10, Byte: 9},
+		End:      Pos{Line: 3, Column: 2, Byte: 54},
+	}
+	if !reflect.DeepEqual(v.Range, wantRange) {
+		t.Fatalf("wrong variable range\ngot:  %#v\nwant: %#v", v.Range, wantRange)
+	}
+
+	if len(m.Locals) != 1 || m.Locals[0].Expression == nil {
+		t.Fatalf("wrong locals %#v", m.Locals)
+	}
+	assertReferences(t, m.Locals[0].Expression, map[string]string{"var.ami": "var.ami"})
+
+	if len(m.Resources) != 1 {
+		t.Fatalf("wrong resources %#v", m.Resources)
+	}
+	r := m.Resources[0]
+	if r.Address != "aws_instance.web" || r.Mode != "managed" || r.Provider != "aws" {
+		t.Fatalf("wrong resource %#v", r)
+	}
+	var attrs []string
+	for _, expr := range r.Expressions {
+		attrs = append(attrs, expr.Attribute)
+		if expr.Range == nil {
+			t.Fatalf("no range for %q", expr.Attribute)
+		}
+	}
+	if want := []string{"ami", "count", "tags"}; !reflect.DeepEqual(attrs, want) {
+		t.Fatalf("wrong resource attributes %#v; want %#v", attrs, want)
+	}
+	assertReferences(t, r.Expressions[2], map[string]string{"local.name": "local.name"})
+
+	if len(m.ModuleCalls) != 1 || m.ModuleCalls[0].Source != "./child" {
+		t.Fatalf("wrong module calls %#v", m.ModuleCalls)
+	}
+	for _, expr := range m.ModuleCalls[0].Expressions {
+		if expr.Attribute == "id" {
+			assertReferences(t, expr, map[string]string{"aws_instance.web.0.id": "aws_instance.web"})
+		}
+	}
+
+	if len(m.Outputs) != 1 {
+		t.Fatalf("wrong outputs %#v", m.Outputs)
+	}
+	assertReferences(t, m.Outputs[0].Expressions[0], map[string]string{"module.child.ip": "module.child"})
+
+	if len(m.Children) != 1 {
+		t.Fatalf("wrong children %#v", m.Children)
+	}
+	child := m.Children[0]
+	if child.Path != "module.child" {
+		t.Fatalf("wrong child path %q", child.Path)
+	}
+	if len(child.Variables) != 1 || len(child.Outputs) != 1 {
+		t.Fatalf("wrong child %#v", child)
+	}
+	assertReferences(t, child.Outputs[0].Expressions[0], map[string]string{"var.id": "var.id"})
+}
+
+func assertReferences(t *testing.T, expr *Expression, want map[string]string) {
+	t.Helper()
+
+	got := make(map[string]string)
+	for _, ref := range expr.References {
+		got[ref.Text] = ref.Target
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong references for %q\ngot:  %#v\nwant: %#v", expr.Attribute, got, want)
+	}
+}
@@ -0,0 +1,5 @@
+variable "id" {}
+
+output "ip" {
+  value = "${var.id}"
+}
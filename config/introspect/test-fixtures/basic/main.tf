@@ -0,0 +1,29 @@
+variable "ami" {
+  description = "The AMI to launch"
+}
+
+locals {
+  name = "web-${var.ami}"
+}
+
+provider "aws" {
+  region = "us-west-2"
+}
+
+resource "aws_instance" "web" {
+  ami   = "${var.ami}"
+  count = 2
+
+  tags {
+    Name = "${local.name}"
+  }
+}
+
+module "child" {
+  source = "./child"
+  id     = "${aws_instance.web.0.id}"
+}
+
+output "ip" {
+  value = "${module.child.ip}"
+}
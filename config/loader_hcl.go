@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"io/ioutil"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/hcl"
@@ -38,17 +39,25 @@ var ReservedResourceFields = []string{
 
 var ReservedProviderFields = []string{
 	"alias",
+	"for_each",
+	"rate_limit",
+	"retry",
 	"version",
 }
 
 func (t *hclConfigurable) Config() (*Config, error) {
 	validKeys := map[string]struct{}{
 		"atlas":     struct{}{},
+		"check":     struct{}{},
 		"data":      struct{}{},
+		"ephemeral": struct{}{},
+		"import":    struct{}{},
 		"locals":    struct{}{},
 		"module":    struct{}{},
+		"moved":     struct{}{},
 		"output":    struct{}{},
 		"provider":  struct{}{},
+		"removed":   struct{}{},
 		"resource":  struct{}{},
 		"terraform": struct{}{},
 		"variable":  struct{}{},
@@ -141,6 +150,28 @@ func (t *hclConfigurable) Config() (*Config, error) {
 		config.Resources = append(config.Resources, managedResources...)
 	}
 
+	// Build the ephemeral resources
+	if ephemerals := list.Filter("ephemeral"); len(ephemerals.Items) > 0 {
+		var err error
+		config.EphemeralResources, err = loadEphemeralResourcesHcl(ephemerals)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Build the checks, whose data sources are resources too
+	if checks := list.Filter("check"); len(checks.Items) > 0 {
+		var err error
+		config.Checks, err = loadChecksHcl(checks)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range config.Checks {
+			config.Resources = append(config.Resources, c.DataSources...)
+		}
+	}
+
 	// Build the outputs
 	if outputs := list.Filter("output"); len(outputs.Items) > 0 {
 		var err error
@@ -150,6 +181,33 @@ func (t *hclConfigurable) Config() (*Config, error) {
 		}
 	}
 
+	// Build the imports
+	if imports := list.Filter("import"); len(imports.Items) > 0 {
+		var err error
+		config.Imports, err = loadImportsHcl(imports)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Build the moves
+	if moved := list.Filter("moved"); len(moved.Items) > 0 {
+		var err error
+		config.Moved, err = loadMovedHcl(moved)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Build the removals
+	if removed := list.Filter("removed"); len(removed.Items) > 0 {
+		var err error
+		config.Removed, err = loadRemovedHcl(removed)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Check for invalid keys
 	for _, item := range list.Items {
 		if len(item.Keys) == 0 {
@@ -165,6 +223,8 @@ func (t *hclConfigurable) Config() (*Config, error) {
 		config.unknownKeys = append(config.unknownKeys, k)
 	}
 
+	config.setSourceFilename(t.File)
+
 	return config, nil
 }
 
@@ -289,9 +349,66 @@ func loadTerraformHcl(list *ast.ObjectList) (*Terraform, error) {
 		}
 	}
 
+	// A cloud block is the configuration of the remote backend.
+	if os := listVal.Filter("cloud"); len(os.Items) > 0 {
+		if config.Backend != nil {
+			return nil, fmt.Errorf(
+				"only one of 'backend' and 'cloud' blocks allowed in the terraform block")
+		}
+
+		var err error
+		config.Backend, err = loadTerraformCloudHcl(os)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"Error reading cloud config for terraform block: %s",
+				err)
+		}
+	}
+
 	return &config, nil
 }
 
+// Loads the cloud configuration from an object list, as the configuration
+// of the remote backend.
+func loadTerraformCloudHcl(list *ast.ObjectList) (*Backend, error) {
+	if len(list.Items) > 1 {
+		return nil, fmt.Errorf("only one 'cloud' block allowed")
+	}
+
+	// Get our one item
+	item := list.Items[0]
+
+	// Verify the keys
+	if len(item.Keys) != 0 {
+		return nil, fmt.Errorf(
+			"position %s: 'cloud' must not be followed by a string",
+			item.Pos())
+	}
+
+	// Decode the raw config
+	var config map[string]interface{}
+	if err := hcl.DecodeObject(&config, item.Val); err != nil {
+		return nil, fmt.Errorf(
+			"Error reading cloud config: %s",
+			err)
+	}
+
+	rawConfig, err := NewRawConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Error reading cloud config: %s",
+			err)
+	}
+
+	b := &Backend{
+		Type:      "remote",
+		RawConfig: rawConfig,
+	}
+	b.Hash = b.Rehash()
+
+	return b, nil
+}
+
 // Loads the Backend configuration from an object list.
 func loadTerraformBackendHcl(list *ast.ObjectList) (*Backend, error) {
 	if len(list.Items) > 1 {
@@ -445,6 +562,7 @@ func loadModulesHcl(list *ast.ObjectList) ([]*Module, error) {
 			Version:   version,
 			Providers: providers,
 			RawConfig: rawConfig,
+			Ranges:    hclBlockRanges(item),
 		})
 	}
 
@@ -496,6 +614,7 @@ func loadLocalsHcl(list *ast.ObjectList) ([]*Local, error) {
 				result = append(result, &Local{
 					Name:      k,
 					RawConfig: rawConfig,
+					Ranges:    SourceRanges{Decl: hclItemRange(item)},
 				})
 			}
 		}
@@ -504,6 +623,126 @@ func loadLocalsHcl(list *ast.ObjectList) ([]*Local, error) {
 	return result, nil
 }
 
+// loadImportsHcl turns the given HCL object into a list of imports.
+func loadImportsHcl(list *ast.ObjectList) ([]*Import, error) {
+	result := make([]*Import, 0, len(list.Items))
+
+	for _, block := range list.Items {
+		if len(block.Keys) > 0 {
+			return nil, fmt.Errorf(
+				"import block at %s should not have label %q",
+				block.Pos(), block.Keys[0].Token.Value(),
+			)
+		}
+
+		if _, ok := block.Val.(*ast.ObjectType); !ok {
+			return nil, fmt.Errorf("import value at %s should be a block", block.Val.Pos())
+		}
+
+		valid := []string{"to", "id", "provider"}
+		if err := checkHCLKeys(block.Val, valid); err != nil {
+			return nil, multierror.Prefix(err, "import:")
+		}
+
+		var raw struct {
+			To       string `mapstructure:"to"`
+			ID       string `mapstructure:"id"`
+			Provider string `mapstructure:"provider"`
+		}
+		if err := hcl.DecodeObject(&raw, block.Val); err != nil {
+			return nil, fmt.Errorf(
+				"Error reading import block at %s: %s",
+				block.Pos(), err)
+		}
+
+		result = append(result, &Import{
+			To:       raw.To,
+			ID:       raw.ID,
+			Provider: raw.Provider,
+		})
+	}
+
+	return result, nil
+}
+
+// loadMovedHcl turns the given HCL object into a list of moves.
+func loadMovedHcl(list *ast.ObjectList) ([]*Moved, error) {
+	result := make([]*Moved, 0, len(list.Items))
+
+	for _, block := range list.Items {
+		if len(block.Keys) > 0 {
+			return nil, fmt.Errorf(
+				"moved block at %s should not have label %q",
+				block.Pos(), block.Keys[0].Token.Value(),
+			)
+		}
+
+		if _, ok := block.Val.(*ast.ObjectType); !ok {
+			return nil, fmt.Errorf("moved value at %s should be a block", block.Val.Pos())
+		}
+
+		valid := []string{"from", "to"}
+		if err := checkHCLKeys(block.Val, valid); err != nil {
+			return nil, multierror.Prefix(err, "moved:")
+		}
+
+		var raw struct {
+			From string `mapstructure:"from"`
+			To   string `mapstructure:"to"`
+		}
+		if err := hcl.DecodeObject(&raw, block.Val); err != nil {
+			return nil, fmt.Errorf(
+				"Error reading moved block at %s: %s",
+				block.Pos(), err)
+		}
+
+		result = append(result, &Moved{
+			From: raw.From,
+			To:   raw.To,
+		})
+	}
+
+	return result, nil
+}
+
+// loadRemovedHcl turns the given HCL object into a list of removals.
+func loadRemovedHcl(list *ast.ObjectList) ([]*Removed, error) {
+	result := make([]*Removed, 0, len(list.Items))
+
+	for _, block := range list.Items {
+		if len(block.Keys) > 0 {
+			return nil, fmt.Errorf(
+				"removed block at %s should not have label %q",
+				block.Pos(), block.Keys[0].Token.Value(),
+			)
+		}
+
+		if _, ok := block.Val.(*ast.ObjectType); !ok {
+			return nil, fmt.Errorf("removed value at %s should be a block", block.Val.Pos())
+		}
+
+		valid := []string{"from"}
+		if err := checkHCLKeys(block.Val, valid); err != nil {
+			return nil, multierror.Prefix(err, "removed:")
+		}
+
+		var raw struct {
+			From string `mapstructure:"from"`
+		}
+		if err := hcl.DecodeObject(&raw, block.Val); err != nil {
+			return nil, fmt.Errorf(
+				"Error reading removed block at %s: %s",
+				block.Pos(), err)
+		}
+
+		result = append(result, &Removed{
+			From: raw.From,
+		})
+	}
+
+	return result, nil
+}
+
 // LoadOutputsHcl recurses into the given HCL object and turns
 // it into a mapping of outputs.
 func loadOutputsHcl(list *ast.ObjectList) ([]*Output, error) {
@@ -533,6 +772,7 @@ func loadOutputsHcl(list *ast.ObjectList) ([]*Output, error) {
 		// Delete special keys
 		delete(config, "depends_on")
 		delete(config, "description")
+		delete(config, "precondition")
 
 		rawConfig, err := NewRawConfig(config)
 		if err != nil {
@@ -566,11 +806,21 @@ func loadOutputsHcl(list *ast.ObjectList) ([]*Output, error) {
 			}
 		}
 
+		preconditions, err := loadConditionsHcl(listVal.Filter("precondition"))
+		if err != nil {
+			return nil, fmt.Errorf(
+				"Error reading precondition for output %q: %s",
+				n,
+				err)
+		}
+
 		result = append(result, &Output{
-			Name:        n,
-			RawConfig:   rawConfig,
-			DependsOn:   dependsOn,
-			Description: description,
+			Name:          n,
+			RawConfig:     rawConfig,
+			DependsOn:     dependsOn,
+			Description:   description,
+			Preconditions: preconditions,
+			Ranges:        hclBlockRanges(item),
 		})
 	}
 
@@ -587,11 +837,16 @@ func loadVariablesHcl(list *ast.ObjectList) ([]*Variable, error) {
 	list = list.Children()
 
 	// hclVariable is the structure each variable is decoded into
+	type hclVariableSecret struct {
+		Backend string `hcl:"backend"`
+		Key     string `hcl:"key"`
+	}
 	type hclVariable struct {
 		DeclaredType string `hcl:"type"`
 		Default      interface{}
 		Description  string
-		Fields       []string `hcl:",decodedFields"`
+		Secret       *hclVariableSecret `hcl:"secret"`
+		Fields       []string           `hcl:",decodedFields"`
 	}
 
 	// Go through each object and turn it into an actual result.
@@ -615,7 +870,7 @@ func loadVariablesHcl(list *ast.ObjectList) ([]*Variable, error) {
 		}
 
 		// Check for invalid keys
-		valid := []string{"type", "default", "description"}
+		valid := []string{"type", "default", "description", "secret", "validation"}
 		if err := checkHCLKeys(item.Val, valid); err != nil {
 			return nil, multierror.Prefix(err, fmt.Sprintf(
 				"variable[%s]:", n))
@@ -647,6 +902,21 @@ func loadVariablesHcl(list *ast.ObjectList) ([]*Variable, error) {
 			DeclaredType: hclVar.DeclaredType,
 			Default:      hclVar.Default,
 			Description:  hclVar.Description,
+			Ranges:       hclBlockRanges(item),
+		}
+		if hclVar.Secret != nil {
+			newVar.Secret = &VariableSecret{
+				Backend: hclVar.Secret.Backend,
+				Key:     hclVar.Secret.Key,
+			}
+		}
+		if ot, ok := item.Val.(*ast.ObjectType); ok {
+			validations, err := loadConditionsHcl(ot.List.Filter("validation"))
+			if err != nil {
+				return nil, fmt.Errorf(
+					"Error reading validation for variable %q: %s", n, err)
+			}
+			newVar.Validations = validations
 		}
 		if err := newVar.ValidateTypeAndDefault(); err != nil {
 			return nil, err
@@ -688,6 +958,9 @@ func loadProvidersHcl(list *ast.ObjectList) ([]*ProviderConfig, error) {
 		}
 
 		delete(config, "alias")
+		delete(config, "for_each")
+		delete(config, "rate_limit")
+		delete(config, "retry")
 		delete(config, "version")
 
 		rawConfig, err := NewRawConfig(config)
@@ -722,12 +995,84 @@ func loadProvidersHcl(list *ast.ObjectList) ([]*ProviderConfig, error) {
 			}
 		}
 
-		result = append(result, &ProviderConfig{
+		// If we have a retry block then extract it, filling in defaults for
+		// any settings not given.
+		var retry *ProviderRetry
+		if o := listVal.Filter("retry"); len(o.Items) > 0 {
+			if len(o.Items) > 1 {
+				return nil, fmt.Errorf(
+					"provider[%s]: Multiple retry blocks found, expected one", n)
+			}
+
+			valid := []string{"max_attempts", "max_backoff", "min_backoff"}
+			if err := checkHCLKeys(o.Items[0].Val, valid); err != nil {
+				return nil, multierror.Prefix(err, fmt.Sprintf(
+					"provider[%s] retry:", n))
+			}
+
+			var raw map[string]interface{}
+			if err := hcl.DecodeObject(&raw, o.Items[0].Val); err != nil {
+				return nil, fmt.Errorf(
+					"Error reading retry for provider[%s]: %s",
+					n,
+					err)
+			}
+
+			retry = &ProviderRetry{
+				MaxAttempts: 3,
+				MinBackoff:  "1s",
+				MaxBackoff:  "30s",
+			}
+			if err := mapstructure.WeakDecode(raw, retry); err != nil {
+				return nil, fmt.Errorf(
+					"Error reading retry for provider[%s]: %s",
+					n,
+					err)
+			}
+		}
+
+		// If we have a rate_limit field then extract it
+		var rateLimit float64
+		if a := listVal.Filter("rate_limit"); len(a.Items) > 0 {
+			err := hcl.DecodeObject(&rateLimit, a.Items[0].Val)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"Error reading rate_limit for provider[%s]: %s",
+					n,
+					err)
+			}
+		}
+
+		pc := &ProviderConfig{
 			Name:      n,
 			Alias:     alias,
 			Version:   version,
+			Retry:     retry,
+			RateLimit: rateLimit,
 			RawConfig: rawConfig,
-		})
+			Ranges:    hclBlockRanges(item),
+		}
+
+		// If we have a for_each field then the block is expanded into a
+		// configuration for each of its elements.
+		if a := listVal.Filter("for_each"); len(a.Items) > 0 {
+			var forEach map[string]interface{}
+			if err := hcl.DecodeObject(&forEach, a.Items[0].Val); err != nil {
+				return nil, fmt.Errorf(
+					"Error reading for_each for provider[%s]: %s; for_each must be a literal map",
+					n,
+					err)
+			}
+
+			expanded, err := expandProviderConfig(pc, forEach)
+			if err != nil {
+				return nil, fmt.Errorf("provider[%s]: %s", n, err)
+			}
+			result = append(result, expanded...)
+			continue
+		}
+
+		result = append(result, pc)
 	}
 
 	return result, nil
@@ -784,6 +1129,7 @@ func loadDataResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
 		delete(config, "depends_on")
 		delete(config, "provider")
 		delete(config, "count")
+		delete(config, "lifecycle")
 
 		rawConfig, err := NewRawConfig(config)
 		if err != nil {
@@ -840,6 +1186,30 @@ func loadDataResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
 			}
 		}
 
+		// Data resources only support conditions in their lifecycle
+		var lifecycle ResourceLifecycle
+		if o := listVal.Filter("lifecycle"); len(o.Items) > 0 {
+			if len(o.Items) > 1 {
+				return nil, fmt.Errorf(
+					"data.%s[%s]: Multiple lifecycle blocks found, expected one",
+					t, k)
+			}
+
+			valid := []string{"precondition", "postcondition"}
+			if err := checkHCLKeys(o.Items[0].Val, valid); err != nil {
+				return nil, multierror.Prefix(err, fmt.Sprintf(
+					"data.%s[%s]:", t, k))
+			}
+
+			if err := loadLifecycleConditionsHcl(o.Items[0].Val, &lifecycle); err != nil {
+				return nil, fmt.Errorf(
+					"Error parsing lifecycle for data.%s[%s]: %s",
+					t,
+					k,
+					err)
+			}
+		}
+
 		result = append(result, &Resource{
 			Mode:         DataResourceMode,
 			Name:         k,
@@ -849,7 +1219,122 @@ func loadDataResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
 			Provider:     provider,
 			Provisioners: []*Provisioner{},
 			DependsOn:    dependsOn,
-			Lifecycle:    ResourceLifecycle{},
+			Lifecycle:    lifecycle,
+			Ranges:       hclBlockRanges(item),
+		})
+	}
+
+	return result, nil
+}
+
+// Given a handle to a HCL object, this recurses into the structure
+// and pulls out a list of ephemeral resources.
+//
+// Ephemeral resources don't support count, lifecycle or provisioners,
+// since they have no state to count, replace or provision.
+func loadEphemeralResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
+	if err := assertAllBlocksHaveNames("ephemeral", list); err != nil {
+		return nil, err
+	}
+
+	list = list.Children()
+	if len(list.Items) == 0 {
+		return nil, nil
+	}
+
+	var result []*Resource
+	for _, item := range list.Items {
+		if len(item.Keys) != 2 {
+			return nil, fmt.Errorf(
+				"position %s: 'ephemeral' must be followed by exactly two strings: a type and a name",
+				item.Pos())
+		}
+
+		t := item.Keys[0].Token.Value().(string)
+		k := item.Keys[1].Token.Value().(string)
+
+		var listVal *ast.ObjectList
+		if ot, ok := item.Val.(*ast.ObjectType); ok {
+			listVal = ot.List
+		} else {
+			return nil, fmt.Errorf("ephemeral resources %s[%s]: should be an object", t, k)
+		}
+
+		for _, key := range []string{"count", "lifecycle", "provisioner"} {
+			if o := listVal.Filter(key); len(o.Items) > 0 {
+				return nil, fmt.Errorf(
+					"ephemeral.%s.%s: ephemeral resources don't support %q",
+					t, k, key)
+			}
+		}
+
+		var config map[string]interface{}
+		if err := hcl.DecodeObject(&config, item.Val); err != nil {
+			return nil, fmt.Errorf(
+				"Error reading config for %s[%s]: %s",
+				t,
+				k,
+				err)
+		}
+
+		// Remove the fields we handle specially
+		delete(config, "depends_on")
+		delete(config, "provider")
+
+		rawConfig, err := NewRawConfig(config)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"Error reading config for %s[%s]: %s",
+				t,
+				k,
+				err)
+		}
+
+		// An ephemeral resource always has exactly one instance, but it
+		// still has a count so that it can be treated like any other
+		// resource when validating references to it.
+		countConfig, err := NewRawConfig(map[string]interface{}{
+			"count": "1",
+		})
+		if err != nil {
+			return nil, err
+		}
+		countConfig.Key = "count"
+
+		var dependsOn []string
+		if o := listVal.Filter("depends_on"); len(o.Items) > 0 {
+			err := hcl.DecodeObject(&dependsOn, o.Items[0].Val)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"Error reading depends_on for %s[%s]: %s",
+					t,
+					k,
+					err)
+			}
+		}
+
+		var provider string
+		if o := listVal.Filter("provider"); len(o.Items) > 0 {
+			err := hcl.DecodeObject(&provider, o.Items[0].Val)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"Error reading provider for %s[%s]: %s",
+					t,
+					k,
+					err)
+			}
+		}
+
+		result = append(result, &Resource{
+			Mode:         EphemeralResourceMode,
+			Name:         k,
+			Type:         t,
+			RawCount:     countConfig,
+			RawConfig:    rawConfig,
+			Provider:     provider,
+			Provisioners: []*Provisioner{},
+			DependsOn:    dependsOn,
+			Ranges:       hclBlockRanges(item),
 		})
 	}
 
@@ -1012,7 +1497,11 @@ func loadManagedResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
 			}
 
 			// Check for invalid keys
-			valid := []string{"create_before_destroy", "ignore_changes", "prevent_destroy"}
+			valid := []string{
+				"create_before_destroy", "ignore_changes", "prevent_destroy",
+				"group", "create_after_destroy", "precondition", "postcondition",
+				"timeouts",
+			}
 			if err := checkHCLKeys(o.Items[0].Val, valid); err != nil {
 				return nil, multierror.Prefix(err, fmt.Sprintf(
 					"%s[%s]:", t, k))
@@ -1026,6 +1515,9 @@ func loadManagedResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
 					k,
 					err)
 			}
+			delete(raw, "precondition")
+			delete(raw, "postcondition")
+			delete(raw, "timeouts")
 
 			if err := mapstructure.WeakDecode(raw, &lifecycle); err != nil {
 				return nil, fmt.Errorf(
@@ -1034,6 +1526,22 @@ func loadManagedResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
 					k,
 					err)
 			}
+
+			if err := loadLifecycleConditionsHcl(o.Items[0].Val, &lifecycle); err != nil {
+				return nil, fmt.Errorf(
+					"Error parsing lifecycle for %s[%s]: %s",
+					t,
+					k,
+					err)
+			}
+
+			if err := loadLifecycleTimeoutsHcl(o.Items[0].Val, &lifecycle); err != nil {
+				return nil, fmt.Errorf(
+					"Error parsing lifecycle for %s[%s]: %s",
+					t,
+					k,
+					err)
+			}
 		}
 
 		result = append(result, &Resource{
@@ -1046,12 +1554,159 @@ func loadManagedResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
 			Provider:     provider,
 			DependsOn:    dependsOn,
 			Lifecycle:    lifecycle,
+			Ranges:       hclBlockRanges(item),
+		})
+	}
+
+	return result, nil
+}
+
+// loadChecksHcl recurses into the given HCL object and turns it into a
+// list of checks.
+func loadChecksHcl(list *ast.ObjectList) ([]*Check, error) {
+	if err := assertAllBlocksHaveNames("check", list); err != nil {
+		return nil, err
+	}
+
+	list = list.Children()
+
+	result := make([]*Check, 0, len(list.Items))
+	for _, item := range list.Items {
+		n := item.Keys[0].Token.Value().(string)
+
+		var listVal *ast.ObjectList
+		if ot, ok := item.Val.(*ast.ObjectType); ok {
+			listVal = ot.List
+		} else {
+			return nil, fmt.Errorf("check %q: should be an object", n)
+		}
+
+		valid := []string{"data", "assert"}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return nil, multierror.Prefix(err, fmt.Sprintf("check %q:", n))
+		}
+
+		dataSources, err := loadDataResourcesHcl(listVal.Filter("data"))
+		if err != nil {
+			return nil, fmt.Errorf("check %q: %s", n, err)
+		}
+		for _, r := range dataSources {
+			r.Check = n
+		}
+
+		asserts, err := loadConditionsHcl(listVal.Filter("assert"))
+		if err != nil {
+			return nil, fmt.Errorf("Error reading assert for check %q: %s", n, err)
+		}
+
+		result = append(result, &Check{
+			Name:        n,
+			DataSources: dataSources,
+			Asserts:     asserts,
 		})
 	}
 
 	return result, nil
 }
 
+// loadLifecycleConditionsHcl loads the precondition and postcondition
+// blocks of the given lifecycle block into lifecycle.
+func loadLifecycleConditionsHcl(val ast.Node, lifecycle *ResourceLifecycle) error {
+	ot, ok := val.(*ast.ObjectType)
+	if !ok {
+		return fmt.Errorf("lifecycle should be an object")
+	}
+
+	var err error
+	lifecycle.Preconditions, err = loadConditionsHcl(ot.List.Filter("precondition"))
+	if err != nil {
+		return fmt.Errorf("precondition: %s", err)
+	}
+	lifecycle.Postconditions, err = loadConditionsHcl(ot.List.Filter("postcondition"))
+	if err != nil {
+		return fmt.Errorf("postcondition: %s", err)
+	}
+	return nil
+}
+
+// loadLifecycleTimeoutsHcl loads the timeouts block of the given lifecycle
+// block into lifecycle. Each timeout is a duration string such as "30m".
+func loadLifecycleTimeoutsHcl(val ast.Node, lifecycle *ResourceLifecycle) error {
+	ot, ok := val.(*ast.ObjectType)
+	if !ok {
+		return fmt.Errorf("lifecycle should be an object")
+	}
+
+	list := ot.List.Filter("timeouts")
+	switch {
+	case len(list.Items) == 0:
+		return nil
+	case len(list.Items) > 1:
+		return fmt.Errorf("timeouts: only one block is allowed")
+	case len(list.Items[0].Keys) > 0:
+		return fmt.Errorf("timeouts: the block has no name")
+	}
+
+	if err := checkHCLKeys(list.Items[0].Val, []string{"create", "update", "delete"}); err != nil {
+		return multierror.Prefix(err, "timeouts:")
+	}
+
+	var raw map[string]string
+	if err := hcl.DecodeObject(&raw, list.Items[0].Val); err != nil {
+		return fmt.Errorf("timeouts: %s", err)
+	}
+
+	for _, t := range []struct {
+		key string
+		dst *time.Duration
+	}{
+		{"create", &lifecycle.Timeouts.Create},
+		{"update", &lifecycle.Timeouts.Update},
+		{"delete", &lifecycle.Timeouts.Delete},
+	} {
+		v, ok := raw[t.key]
+		if !ok {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("timeouts: %s must be a positive duration such as \"30m\", got %q", t.key, v)
+		}
+		*t.dst = d
+	}
+	return nil
+}
+
+// loadConditionsHcl turns the given list of precondition or postcondition
+// blocks into ConditionRules.
+func loadConditionsHcl(list *ast.ObjectList) ([]*ConditionRule, error) {
+	var result []*ConditionRule
+	for _, item := range list.Items {
+		if len(item.Keys) > 0 {
+			return nil, fmt.Errorf("position %s: condition blocks have no names", item.Pos())
+		}
+
+		valid := []string{"condition", "error_message"}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return nil, err
+		}
+
+		var config map[string]interface{}
+		if err := hcl.DecodeObject(&config, item.Val); err != nil {
+			return nil, err
+		}
+
+		rawConfig, err := NewRawConfig(config)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &ConditionRule{RawConfig: rawConfig})
+	}
+
+	return result, nil
+}
+
 func loadProvisionersHcl(list *ast.ObjectList, connInfo map[string]interface{}) ([]*Provisioner, error) {
 	if err := assertAllBlocksHaveNames("provisioner", list); err != nil {
 		return nil, err
@@ -1109,10 +1764,20 @@ func loadProvisionersHcl(list *ast.ObjectList, connInfo map[string]interface{})
 			}
 		}
 
+		// Parse the "depends_on" value
+		var dependsOn []string
+		if o := listVal.Filter("depends_on"); len(o.Items) > 0 {
+			if err := hcl.DecodeObject(&dependsOn, o.Items[0].Val); err != nil {
+				return nil, fmt.Errorf(
+					"Error reading depends_on for provisioner '%s': %s", n, err)
+			}
+		}
+
 		// Delete fields we special case
 		delete(config, "connection")
 		delete(config, "when")
 		delete(config, "on_failure")
+		delete(config, "depends_on")
 
 		rawConfig, err := NewRawConfig(config)
 		if err != nil {
@@ -1153,6 +1818,7 @@ func loadProvisionersHcl(list *ast.ObjectList, connInfo map[string]interface{})
 			ConnInfo:  connRaw,
 			When:      when,
 			OnFailure: onFailure,
+			DependsOn: dependsOn,
 		})
 	}
 
@@ -99,8 +99,9 @@ func (t *hcl2Configurable) Config() (*Config, error) {
 	type provisioner struct {
 		Type string `hcl:"type,label"`
 
-		When      *string `hcl:"when,attr"`
-		OnFailure *string `hcl:"on_failure,attr"`
+		When      *string   `hcl:"when,attr"`
+		OnFailure *string   `hcl:"on_failure,attr"`
+		DependsOn *[]string `hcl:"depends_on,attr"`
 
 		Connection *connection `hcl:"connection,block"`
 		Config     hcl2.Body   `hcl:",remain"`
@@ -129,13 +130,18 @@ func (t *hcl2Configurable) Config() (*Config, error) {
 
 		Config hcl2.Body `hcl:",remain"`
 	}
+	type variableSecret struct {
+		Backend string `hcl:"backend,attr"`
+		Key     string `hcl:"key,attr"`
+	}
 	type variable struct {
 		Name string `hcl:"name,label"`
 
-		DeclaredType *string    `hcl:"type,attr"`
-		Default      *cty.Value `hcl:"default,attr"`
-		Description  *string    `hcl:"description,attr"`
-		Sensitive    *bool      `hcl:"sensitive,attr"`
+		DeclaredType *string         `hcl:"type,attr"`
+		Default      *cty.Value      `hcl:"default,attr"`
+		Description  *string         `hcl:"description,attr"`
+		Sensitive    *bool           `hcl:"sensitive,attr"`
+		Secret       *variableSecret `hcl:"secret,block"`
 	}
 	type output struct {
 		Name string `hcl:"name,label"`
@@ -275,6 +281,12 @@ func (t *hcl2Configurable) Config() (*Config, error) {
 		if rawV.Description != nil {
 			v.Description = *rawV.Description
 		}
+		if rawV.Secret != nil {
+			v.Secret = &VariableSecret{
+				Backend: rawV.Secret.Backend,
+				Key:     rawV.Secret.Key,
+			}
+		}
 
 		config.Variables = append(config.Variables, v)
 	}
@@ -368,6 +380,10 @@ func (t *hcl2Configurable) Config() (*Config, error) {
 				p.ConnInfo = defaultConnInfo
 			}
 
+			if rawP.DependsOn != nil {
+				p.DependsOn = *rawP.DependsOn
+			}
+
 			p.RawConfig = NewRawConfigHCL2(rawP.Config)
 
 			r.Provisioners = append(r.Provisioners, p)
@@ -6,6 +6,9 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform/tfdiags"
 )
 
 func TestErrNoConfigsFound_impl(t *testing.T) {
@@ -85,6 +88,189 @@ func TestLoadFile_resourceArityMistake(t *testing.T) {
 	}
 }
 
+func TestLoadFile_check(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "check.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(c.Checks) != 1 {
+		t.Fatalf("bad: %#v", c.Checks)
+	}
+	chk := c.Checks[0]
+	if chk.Name != "health" || len(chk.Asserts) != 1 || len(chk.DataSources) != 1 {
+		t.Fatalf("bad: %#v", chk)
+	}
+
+	// The check's data source is also a resource of the config
+	var found bool
+	for _, r := range c.Resources {
+		if r.Id() == "data.http.web" {
+			found = true
+			if r.Check != "health" {
+				t.Fatalf("bad: %#v", r)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("data source not in resources: %#v", c.Resources)
+	}
+}
+
+func TestLoadFile_lifecycleGroup(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "lifecycle-group.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, r := range c.Resources {
+		switch r.Name {
+		case "old":
+			if r.Lifecycle.Group != "legacy" || len(r.Lifecycle.CreateAfterDestroy) != 0 {
+				t.Fatalf("bad: %#v", r.Lifecycle)
+			}
+		case "new":
+			if r.Lifecycle.Group != "replacement" {
+				t.Fatalf("bad: %#v", r.Lifecycle)
+			}
+			if !reflect.DeepEqual(r.Lifecycle.CreateAfterDestroy, []string{"legacy"}) {
+				t.Fatalf("bad: %#v", r.Lifecycle.CreateAfterDestroy)
+			}
+		}
+	}
+}
+
+func TestLoadFile_lifecycleTimeouts(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "lifecycle-timeouts.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	want := ResourceTimeouts{Create: 30 * time.Minute, Delete: time.Hour}
+	if got := c.Resources[0].Lifecycle.Timeouts; got != want {
+		t.Fatalf("wrong timeouts\ngot:  %#v\nwant: %#v", got, want)
+	}
+	if got := c.Resources[0].Lifecycle.Copy().Timeouts; got != want {
+		t.Fatalf("timeouts not copied: %#v", got)
+	}
+
+	_, err = LoadFile(filepath.Join(fixtureDir, "lifecycle-timeouts-bad.tf"))
+	if err == nil || !strings.Contains(err.Error(), `update must be a positive duration such as "30m", got "soon"`) {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
+
+func TestLoadFile_conditions(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "conditions.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var managed, data *Resource
+	for _, r := range c.Resources {
+		switch r.Mode {
+		case ManagedResourceMode:
+			managed = r
+		case DataResourceMode:
+			data = r
+		}
+	}
+
+	if !managed.Lifecycle.CreateBeforeDestroy {
+		t.Fatalf("bad: %#v", managed.Lifecycle)
+	}
+	if len(managed.Lifecycle.Preconditions) != 1 || len(managed.Lifecycle.Postconditions) != 1 {
+		t.Fatalf("bad: %#v", managed.Lifecycle)
+	}
+	msg := managed.Lifecycle.Preconditions[0].RawConfig.Raw["error_message"]
+	if msg != "An AMI must be given." {
+		t.Fatalf("bad: %#v", msg)
+	}
+
+	if len(data.Lifecycle.Preconditions) != 0 || len(data.Lifecycle.Postconditions) != 1 {
+		t.Fatalf("bad: %#v", data.Lifecycle)
+	}
+	if _, ok := data.RawConfig.Raw["lifecycle"]; ok {
+		t.Fatalf("lifecycle should not be in the data source config: %#v", data.RawConfig.Raw)
+	}
+
+	o := c.Outputs[0]
+	if len(o.Preconditions) != 1 {
+		t.Fatalf("bad: %#v", o)
+	}
+	if _, ok := o.RawConfig.Raw["precondition"]; ok {
+		t.Fatalf("precondition should not be in the output config: %#v", o.RawConfig.Raw)
+	}
+}
+
+func TestLoadFile_variableValidation(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "validate-var-validation", "main.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var v *Variable
+	for _, cv := range c.Variables {
+		if cv.Name == "certificate" {
+			v = cv
+		}
+	}
+	if v == nil || len(v.Validations) != 1 {
+		t.Fatalf("bad: %#v", v)
+	}
+	msg := v.Validations[0].RawConfig.Raw["error_message"]
+	if msg != "A certificate is required when enable_tls is true." {
+		t.Fatalf("bad: %#v", msg)
+	}
+	if v.Default != "" {
+		t.Fatalf("validation should not change the default: %#v", v.Default)
+	}
+}
+
+func TestLoadFile_sourceRanges(t *testing.T) {
+	path := filepath.Join(fixtureDir, "source-ranges.tf")
+	c, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	rng := func(startLine, startCol, startByte, endLine, endCol, endByte int) tfdiags.SourceRange {
+		return tfdiags.SourceRange{
+			Filename: path,
+			Start:    tfdiags.SourcePos{Line: startLine, Column: startCol, Byte: startByte},
+			End:      tfdiags.SourcePos{Line: endLine, Column: endCol, Byte: endByte},
+		}
+	}
+
+	want := SourceRanges{
+		Decl: rng(1, 10, 9, 3, 2, 33),
+		Attrs: map[string]tfdiags.SourceRange{
+			"default": rng(2, 3, 20, 2, 14, 31),
+		},
+	}
+	if got := c.Variables[0].Ranges; !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong variable ranges\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	want = SourceRanges{
+		Decl: rng(5, 10, 44, 8, 2, 115),
+		Attrs: map[string]tfdiags.SourceRange{
+			"ami":   rng(6, 3, 69, 6, 23, 89),
+			"count": rng(7, 3, 92, 7, 24, 113),
+		},
+	}
+	if got := c.Resources[0].Ranges; !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong resource ranges\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	want = SourceRanges{
+		Decl: rng(11, 3, 128, 11, 15, 140),
+	}
+	if got := c.Locals[0].Ranges; !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong local ranges\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
 func TestLoadFile_resourceMultiLifecycle(t *testing.T) {
 	_, err := LoadFile(filepath.Join(fixtureDir, "resource-multi-lifecycle.tf"))
 	if err == nil {
@@ -442,6 +628,33 @@ func TestLoadFile_terraformBackendMulti(t *testing.T) {
 	}
 }
 
+func TestLoadFile_terraformCloud(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "terraform-cloud.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	b := c.Terraform.Backend
+	if b == nil || b.Type != "remote" {
+		t.Fatalf("bad: %#v", b)
+	}
+	if b.RawConfig.Raw["hostname"] != "runs.example.com" {
+		t.Fatalf("bad: %#v", b.RawConfig.Raw)
+	}
+}
+
+func TestLoadFile_terraformCloudBackend(t *testing.T) {
+	_, err := LoadFile(filepath.Join(fixtureDir, "terraform-cloud-backend.tf"))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	errorStr := err.Error()
+	if !strings.Contains(errorStr, "only one of 'backend' and 'cloud'") {
+		t.Fatalf("bad: expected error has wrong text: %s", errorStr)
+	}
+}
+
 func TestLoadJSONBasic(t *testing.T) {
 	raw, err := ioutil.ReadFile(filepath.Join(fixtureDir, "basic.tf.json"))
 	if err != nil {
@@ -765,6 +978,145 @@ func TestLoadFile_unnamedOutput(t *testing.T) {
 	}
 }
 
+func TestLoadFile_importBlocks(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "import-block.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	want := []*Import{
+		&Import{
+			To:       "aws_instance.web[1]",
+			ID:       "i-abc123",
+			Provider: "aws.west",
+		},
+	}
+	if !reflect.DeepEqual(c.Imports, want) {
+		t.Fatalf("wrong result\ngot:  %#v\nwant: %#v", c.Imports, want)
+	}
+}
+
+func TestLoadFile_movedBlocks(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "moved-block.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	want := []*Moved{
+		&Moved{
+			From: "aws_instance.web",
+			To:   "aws_instance.app",
+		},
+	}
+	if !reflect.DeepEqual(c.Moved, want) {
+		t.Fatalf("wrong result\ngot:  %#v\nwant: %#v", c.Moved, want)
+	}
+}
+
+func TestLoadFile_removedBlocks(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "removed-block.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	want := []*Removed{
+		&Removed{
+			From: "aws_instance.web",
+		},
+	}
+	if !reflect.DeepEqual(c.Removed, want) {
+		t.Fatalf("wrong result\ngot:  %#v\nwant: %#v", c.Removed, want)
+	}
+}
+
+func TestLoadFile_ephemeralResources(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "ephemeral-block.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(c.Resources) != 0 {
+		t.Fatalf("ephemeral resources loaded as resources: %#v", c.Resources)
+	}
+	if len(c.EphemeralResources) != 1 {
+		t.Fatalf("bad: %#v", c.EphemeralResources)
+	}
+
+	r := c.EphemeralResources[0]
+	if r.Id() != "ephemeral.vault_token.deploy" {
+		t.Fatalf("bad id: %s", r.Id())
+	}
+	if r.Provider != "vault.west" {
+		t.Fatalf("bad provider: %s", r.Provider)
+	}
+	if !reflect.DeepEqual(r.DependsOn, []string{"aws_instance.web"}) {
+		t.Fatalf("bad depends_on: %#v", r.DependsOn)
+	}
+	if !reflect.DeepEqual(r.RawConfig.Raw, map[string]interface{}{"role": "deploy"}) {
+		t.Fatalf("bad config: %#v", r.RawConfig.Raw)
+	}
+}
+
+func TestLoadFile_ephemeralCount(t *testing.T) {
+	_, err := LoadFile(filepath.Join(fixtureDir, "ephemeral-count.tf"))
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestLoadFile_variableSecret(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "variable-secret.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(c.Variables) != 1 {
+		t.Fatalf("bad: %#v", c.Variables)
+	}
+	expected := &VariableSecret{Backend: "vault", Key: "secret/data/db#password"}
+	if !reflect.DeepEqual(c.Variables[0].Secret, expected) {
+		t.Fatalf("bad secret: %#v", c.Variables[0].Secret)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("should be valid: %s", err)
+	}
+}
+
+func TestLoadFile_providerForEach(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "provider-for-each.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(c.ProviderConfigs) != 2 {
+		t.Fatalf("bad: %#v", c.ProviderConfigs)
+	}
+	for i, expected := range []struct {
+		FullName, Key, Value string
+	}{
+		{"aws.region[east]", "east", "us-east-1"},
+		{"aws.region[west]", "west", "us-west-2"},
+	} {
+		pc := c.ProviderConfigs[i]
+		if pc.FullName() != expected.FullName {
+			t.Fatalf("bad name %d: %s", i, pc.FullName())
+		}
+		if pc.ForEachKey != expected.Key || pc.ForEachValue != expected.Value {
+			t.Fatalf("bad each %d: %q, %#v", i, pc.ForEachKey, pc.ForEachValue)
+		}
+		if _, ok := pc.RawConfig.Raw["for_each"]; ok {
+			t.Fatalf("for_each in config %d: %#v", i, pc.RawConfig.Raw)
+		}
+	}
+}
+
+func TestLoadFile_providerForEachNoAlias(t *testing.T) {
+	_, err := LoadFile(filepath.Join(fixtureDir, "provider-for-each-no-alias.tf"))
+	if err == nil || !strings.Contains(err.Error(), "alias is required") {
+		t.Fatalf("bad error: %v", err)
+	}
+}
+
 func TestLoadFile_connections(t *testing.T) {
 	c, err := LoadFile(filepath.Join(fixtureDir, "connection.tf"))
 	if err != nil {
@@ -1295,6 +1647,9 @@ aws_instance.web (x1)
     shell (destroy)
       on_failure = continue
       path
+    shell (destroy)
+      depends_on = aws_instance.db, module.network
+      path
 `
 
 const connectionResourcesStr = `
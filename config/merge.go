@@ -120,6 +120,23 @@ func Merge(c1, c2 *Config) (*Config, error) {
 		}
 	}
 
+	// Ephemeral resources
+	m1 = make([]merger, 0, len(c1.EphemeralResources))
+	m2 = make([]merger, 0, len(c2.EphemeralResources))
+	for _, v := range c1.EphemeralResources {
+		m1 = append(m1, v)
+	}
+	for _, v := range c2.EphemeralResources {
+		m2 = append(m2, v)
+	}
+	mresult = mergeSlice(m1, m2)
+	if len(mresult) > 0 {
+		c.EphemeralResources = make([]*Resource, len(mresult))
+		for i, v := range mresult {
+			c.EphemeralResources[i] = v.(*Resource)
+		}
+	}
+
 	// Variables
 	m1 = make([]merger, 0, len(c1.Variables))
 	m2 = make([]merger, 0, len(c2.Variables))
@@ -148,6 +165,34 @@ func Merge(c1, c2 *Config) (*Config, error) {
 		c.Locals = append(c.Locals, c2.Locals...)
 	}
 
+	// Imports are also flat, so they are simply combined.
+	if len(c1.Imports)+len(c2.Imports) != 0 {
+		c.Imports = make([]*Import, 0, len(c1.Imports)+len(c2.Imports))
+		c.Imports = append(c.Imports, c1.Imports...)
+		c.Imports = append(c.Imports, c2.Imports...)
+	}
+
+	// Moves are flat in the same way.
+	if len(c1.Moved)+len(c2.Moved) != 0 {
+		c.Moved = make([]*Moved, 0, len(c1.Moved)+len(c2.Moved))
+		c.Moved = append(c.Moved, c1.Moved...)
+		c.Moved = append(c.Moved, c2.Moved...)
+	}
+
+	// As are removals.
+	if len(c1.Removed)+len(c2.Removed) != 0 {
+		c.Removed = make([]*Removed, 0, len(c1.Removed)+len(c2.Removed))
+		c.Removed = append(c.Removed, c1.Removed...)
+		c.Removed = append(c.Removed, c2.Removed...)
+	}
+
+	// And checks.
+	if len(c1.Checks)+len(c2.Checks) != 0 {
+		c.Checks = make([]*Check, 0, len(c1.Checks)+len(c2.Checks))
+		c.Checks = append(c.Checks, c1.Checks...)
+		c.Checks = append(c.Checks, c2.Checks...)
+	}
+
 	return c, nil
 }
 
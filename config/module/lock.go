@@ -0,0 +1,166 @@
+package module
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+)
+
+// ModuleLock is the version and the content of a module as of when it was
+// installed, so that the same module is installed again until the lock is
+// upgraded.
+type ModuleLock struct {
+	// Source is the source of the module in the configuration. A lock only
+	// applies while the configuration has the same source for the module.
+	Source string
+
+	// Version is the version of the module, or empty if its source has no
+	// versions.
+	Version string
+
+	// Hash is the hash of the contents of the module, as returned by
+	// HashDir.
+	Hash string
+}
+
+// Locks returns the locks of the modules installed for the tree, keyed by
+// the module path joined with ".", such as "network.subnets". Modules with
+// local sources are part of the configuration that refers to them, so they
+// have no locks.
+//
+// The tree must be loaded.
+func (t *Tree) Locks() (map[string]*ModuleLock, error) {
+	if !t.Loaded() {
+		return nil, fmt.Errorf("tree must be loaded before locking its modules")
+	}
+
+	locks := make(map[string]*ModuleLock)
+	var err error
+	t.DeepEach(func(c *Tree) {
+		if err != nil || len(c.path) == 0 || isLocalSource(c.source) {
+			return
+		}
+
+		var hash string
+		hash, err = HashDir(c.config.Dir)
+		if err != nil {
+			err = fmt.Errorf("module %s: %s", strings.Join(c.path, "."), err)
+			return
+		}
+		locks[strings.Join(c.path, ".")] = &ModuleLock{
+			Source:  c.source,
+			Version: c.version,
+			Hash:    hash,
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return locks, nil
+}
+
+// lockedConstraint returns the version constraint to use for the module
+// with the given path and source, which is the locked version if the
+// storage has a lock for the module with the same source.
+func (s Storage) lockedConstraint(path []string, m *Module) (string, error) {
+	l, ok := s.Locks[strings.Join(path, ".")]
+	if !ok || l.Source != m.Source || l.Version == "" {
+		return m.Version, nil
+	}
+
+	if m.Version != "" {
+		v, err := version.NewVersion(l.Version)
+		if err != nil {
+			return "", fmt.Errorf("invalid locked version %q: %s", l.Version, err)
+		}
+		cs, err := version.NewConstraint(m.Version)
+		if err != nil {
+			return "", err
+		}
+		if !cs.Check(v) {
+			return "", fmt.Errorf(
+				"locked version %s doesn't match the version constraint %q; run \"terraform init -upgrade\" to select a new version",
+				l.Version, m.Version)
+		}
+	}
+	return l.Version, nil
+}
+
+// HashDir returns a hash of the contents of the given module directory,
+// ignoring files and directories whose names start with "." as GetCopy
+// does, such as those of version control systems.
+//
+// The hash has the same "h1:" format as the hashes of Go modules, which is
+// the base64 encoded SHA-256 hash of a list of the SHA-256 hashes and paths
+// of the files, sorted by path.
+func HashDir(dir string) (string, error) {
+	// Modules from the local filesystem are stored as symlinks, which Walk
+	// wouldn't follow.
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var files []string
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	summary := sha256.New()
+	for _, f := range files {
+		sum, err := hashFile(filepath.Join(dir, filepath.FromSlash(f)))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(summary, "%x  %s\n", sum, f)
+	}
+	return "h1:" + base64.StdEncoding.EncodeToString(summary.Sum(nil)), nil
+}
+
+func hashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// isLocalSource returns true if the given module source is a path relative
+// to the configuration that refers to it.
+func isLocalSource(source string) bool {
+	return strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") ||
+		strings.HasPrefix(source, ".\\") || strings.HasPrefix(source, "..\\")
+}
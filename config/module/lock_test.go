@@ -0,0 +1,134 @@
+package module
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHashDir(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	hash := func() string {
+		h, err := HashDir(dir)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		return h
+	}
+
+	write("main.tf", "a")
+	write("child/main.tf", "b")
+	first := hash()
+	if !strings.HasPrefix(first, "h1:") {
+		t.Fatalf("wrong hash format: %s", first)
+	}
+
+	// Files and directories starting with "." are ignored
+	write(".git/HEAD", "ref: refs/heads/master")
+	write(".terraform-version", "0.11.0")
+	if h := hash(); h != first {
+		t.Fatalf("hash changed for ignored files: %s != %s", h, first)
+	}
+
+	write("child/main.tf", "c")
+	if h := hash(); h == first {
+		t.Fatal("hash should change with the contents")
+	}
+}
+
+func TestTreeLocks(t *testing.T) {
+	resolver := &testSourceResolver{
+		versions: []string{"1.0.0", "1.2.0"},
+	}
+	storage := testStorage(t, nil)
+	storage.Resolvers = map[string]SourceResolverFactory{
+		"test": func() (SourceResolver, error) { return resolver, nil },
+	}
+	storage.Mode = GetModeGet
+	tree := NewTree("", testConfig(t, "resolver-source"))
+	if err := tree.Load(storage); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	locks, err := tree.Locks()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(locks) != 1 {
+		t.Fatalf("wrong locks: %#v", locks)
+	}
+	l := locks["foo"]
+	if l == nil || l.Source != "test::team/foo//child" || l.Version != "1.2.0" || !strings.HasPrefix(l.Hash, "h1:") {
+		t.Fatalf("wrong lock: %#v", l)
+	}
+
+	// Local modules aren't locked
+	local := NewTree("", testConfig(t, "basic"))
+	localStorage := testStorage(t, nil)
+	localStorage.Mode = GetModeGet
+	if err := local.Load(localStorage); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	locks, err = local.Locks()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(locks) != 0 {
+		t.Fatalf("local modules should not be locked: %#v", locks)
+	}
+}
+
+func TestTreeLoad_locked(t *testing.T) {
+	resolver := &testSourceResolver{
+		versions: []string{"1.0.0", "1.2.0"},
+	}
+	storage := testStorage(t, nil)
+	storage.Resolvers = map[string]SourceResolverFactory{
+		"test": func() (SourceResolver, error) { return resolver, nil },
+	}
+	storage.Locks = map[string]*ModuleLock{
+		"foo": {Source: "test::team/foo//child", Version: "1.0.0"},
+	}
+
+	// A locked module keeps its version, even when updating
+	storage.Mode = GetModeUpdate
+	tree := NewTree("", testConfig(t, "resolver-source"))
+	if err := tree.Load(storage); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v := tree.Children()["foo"].version; v != "1.0.0" {
+		t.Fatalf("expected the locked version 1.0.0, got %q", v)
+	}
+
+	// A lock that doesn't match the constraint must be upgraded
+	storage.Locks["foo"].Version = "0.9.0"
+	err := tree.Load(storage)
+	if err == nil {
+		t.Fatal("should error")
+	}
+	if !strings.Contains(err.Error(), "terraform init -upgrade") {
+		t.Fatalf("bad error: %s", err)
+	}
+
+	// A lock for a different source doesn't apply
+	storage.Locks["foo"].Source = "test::team/bar"
+	if err := tree.Load(storage); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v := tree.Children()["foo"].version; v != "1.2.0" {
+		t.Fatalf("expected version 1.2.0, got %q", v)
+	}
+}
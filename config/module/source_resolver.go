@@ -0,0 +1,206 @@
+package module
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	getter "github.com/hashicorp/go-getter"
+)
+
+// SourceResolver resolves module sources that use a particular scheme, such
+// as "artifactory::team/network", for a module store that go-getter can't
+// fetch from on its own.
+//
+// The resolver is responsible for authenticating with its store. Terraform
+// chooses the version to use and caches the modules that the resolver gets
+// as it does for modules from a registry, so a resolver needs only to list
+// and download modules.
+type SourceResolver interface {
+	// Versions returns the versions of the module at the given address, which
+	// is the source without the scheme and any subdirectory. A resolver for
+	// a store without versions returns no versions.
+	Versions(addr string) ([]string, error)
+
+	// Get downloads the given version of the module at the given address
+	// into the given empty directory. The version is empty if the resolver
+	// returned no versions.
+	Get(dst, addr, version string) error
+}
+
+// SourceResolverFactory is a function that starts a SourceResolver. It's
+// only called for the schemes of the sources that are used, so that
+// resolvers served by plugins don't need to be started otherwise.
+type SourceResolverFactory func() (SourceResolver, error)
+
+// resolverSourceRegexp matches a source with a scheme, as go-getter
+// matches sources with a forced getter.
+var resolverSourceRegexp = regexp.MustCompile(`^([A-Za-z0-9]+)::(.+)$`)
+
+// resolver returns the resolver for the scheme of the given source along
+// with the source without the scheme, or a nil resolver if the source has no
+// scheme or there is no resolver for its scheme.
+func (s Storage) resolver(source string) (SourceResolver, string, error) {
+	ms := resolverSourceRegexp.FindStringSubmatch(source)
+	if ms == nil {
+		return nil, "", nil
+	}
+
+	f, ok := s.Resolvers[ms[1]]
+	if !ok {
+		return nil, "", nil
+	}
+
+	r, err := f()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to start the resolver for %q sources: %s", ms[1], err)
+	}
+	return r, ms[2], nil
+}
+
+// find a module with a source resolver
+func (s Storage) findResolverModule(mSource, constraint string) (moduleRecord, SourceResolver, error) {
+	rawSource, _ := getter.SourceDirSubdir(mSource)
+	rec := moduleRecord{
+		Source: rawSource,
+	}
+
+	r, addr, err := s.resolver(rawSource)
+	if err != nil || r == nil {
+		return rec, nil, err
+	}
+	rec.url = addr
+	rec.resolved = true
+
+	log.Printf("[TRACE] %q is resolved by a source resolver", rawSource)
+
+	versions, err := s.moduleVersions(rawSource)
+	if err != nil {
+		log.Printf("[ERROR] error looking up versions for %q: %s", rawSource, err)
+		return rec, nil, err
+	}
+
+	if len(versions) > 0 {
+		match, err := newestRecord(versions, constraint)
+		if err != nil {
+			log.Printf("[INFO] no matching version for %q<%s>, %s", rawSource, constraint, err)
+		}
+		rec.Dir = match.Dir
+		rec.Version = match.Version
+	}
+	found := rec.Dir != ""
+
+	// As with registry modules, we only list the versions on Get if we
+	// don't have a matching version, and unconditionally on Update.
+	if (s.Mode == GetModeGet && !found) || (s.Mode == GetModeUpdate) {
+		available, err := r.Versions(addr)
+		if err != nil {
+			return rec, nil, fmt.Errorf("failed to list the versions of module %q: %s", rawSource, err)
+		}
+
+		if len(available) == 0 {
+			if constraint != "" {
+				return rec, nil, fmt.Errorf("module %q has no versions to match %q", rawSource, constraint)
+			}
+			return rec, r, nil
+		}
+
+		match, err := newest(available, constraint)
+		if err != nil {
+			return rec, nil, err
+		}
+		if match == "" {
+			return rec, nil, fmt.Errorf("no versions for %q found matching %q", rawSource, constraint)
+		}
+
+		rec.Version = match
+		s.output(fmt.Sprintf("  Found version %s of %s", rec.Version, rawSource))
+	}
+
+	return rec, r, nil
+}
+
+// getResolverStorage gets the module of the given record with the given
+// resolver into the storage with the given key, as getStorage does for the
+// sources that go-getter fetches.
+func (s Storage) getResolverStorage(key string, rec moduleRecord, r SourceResolver) (string, bool, error) {
+	storage := &getter.FolderStorage{
+		StorageDir: s.StorageDir,
+	}
+
+	if s.Mode > GetModeNone {
+		dir, found, err := storage.Dir(key)
+		if err != nil {
+			return "", false, err
+		}
+
+		if !found || s.Mode == GetModeUpdate {
+			// We get the module into a temporary directory and move it into
+			// place, so that a failed get leaves nothing behind.
+			if err := os.MkdirAll(s.StorageDir, 0755); err != nil {
+				return "", false, err
+			}
+			tmpDir, err := ioutil.TempDir(s.StorageDir, "resolve")
+			if err != nil {
+				return "", false, err
+			}
+			defer os.RemoveAll(tmpDir)
+
+			log.Printf("[DEBUG] resolving %q version %q with key %q", rec.Source, rec.Version, key)
+			if err := r.Get(tmpDir, rec.url, rec.Version); err != nil {
+				return "", false, fmt.Errorf("failed to get module %q: %s", rec.Source, err)
+			}
+
+			// FolderStorage only returns the directory if it exists, so we
+			// name it as FolderStorage does.
+			if dir == "" {
+				sum := md5.Sum([]byte(key))
+				dir = filepath.Join(s.StorageDir, hex.EncodeToString(sum[:]))
+			}
+			if err := os.RemoveAll(dir); err != nil {
+				return "", false, err
+			}
+			if err := os.Rename(tmpDir, dir); err != nil {
+				return "", false, err
+			}
+		}
+	}
+
+	dir, found, err := storage.Dir(key)
+	log.Printf("[DEBUG] found %q in %q: %t", rec.Source, dir, found)
+	return dir, found, err
+}
+
+// getResolverCopy is the same as GetCopy for a module with a source
+// resolver, copying the given subdirectory of the module if there is one.
+func getResolverCopy(dst string, rec moduleRecord, r SourceResolver, subDir string) error {
+	tmpDir, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := r.Get(tmpDir, rec.url, rec.Version); err != nil {
+		return fmt.Errorf("failed to get module %q: %s", rec.Source, err)
+	}
+
+	srcDir := tmpDir
+	if subDir != "" {
+		srcDir, err = getter.SubdirGlob(tmpDir, subDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Make sure the destination exists
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+
+	return copyDir(dst, srcDir)
+}
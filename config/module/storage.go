@@ -55,6 +55,10 @@ type moduleRecord struct {
 
 	// Registry is true if this module is sourced from a registry
 	registry bool
+
+	// resolved is true if this module is sourced with a SourceResolver, in
+	// which case url is the address of the module for the resolver.
+	resolved bool
 }
 
 // Storage implements methods to manage the storage of modules.
@@ -74,6 +78,14 @@ type Storage struct {
 	Ui cli.Ui
 	// Mode is the GetMode that will be used for various operations.
 	Mode GetMode
+	// Resolvers optionally provides the resolvers for module sources with a
+	// scheme that go-getter doesn't handle, keyed by the scheme, such as
+	// "artifactory" for sources like "artifactory::team/network".
+	Resolvers map[string]SourceResolverFactory
+	// Locks optionally provides the locks of the modules, keyed by module
+	// path as returned by Tree.Locks, so that a locked module is installed
+	// at its locked version.
+	Locks map[string]*ModuleLock
 
 	registry *registry.Client
 }
@@ -253,7 +265,16 @@ func (s Storage) GetModule(dst, src string) error {
 		s.Mode = mode
 	}()
 
-	rec, err := s.findRegistryModule(src, anyVersion)
+	rec, r, err := s.findResolverModule(src, "")
+	if err != nil {
+		return fmt.Errorf("module %s: %s", src, err)
+	}
+	if r != nil {
+		_, subDir := getter.SourceDirSubdir(src)
+		return getResolverCopy(dst, rec, r, subDir)
+	}
+
+	rec, err = s.findRegistryModule(src, anyVersion)
 	if err != nil {
 		return err
 	}
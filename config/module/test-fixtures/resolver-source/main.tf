@@ -0,0 +1,4 @@
+module "foo" {
+  source  = "test::team/foo//child"
+  version = "~> 1.0"
+}
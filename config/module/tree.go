@@ -214,11 +214,23 @@ func (t *Tree) getChildren(s *Storage) (map[string]*Tree, error) {
 		// paths are being loaded from
 		s.output(fmt.Sprintf("- module.%s", strings.Join(modPath, ".")))
 
+		// A locked module keeps its locked version.
+		constraint, err := s.lockedConstraint(modPath, m)
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %s", m.Name, err)
+		}
+
 		// Lookup the local location of the module.
 		// dir is the local directory where the module is stored
-		mod, err := s.findRegistryModule(m.Source, m.Version)
+		mod, resolver, err := s.findResolverModule(m.Source, constraint)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("module %s: %s", m.Name, err)
+		}
+		if !mod.resolved {
+			mod, err = s.findRegistryModule(m.Source, constraint)
+			if err != nil {
+				return nil, err
+			}
 		}
 
 		// The key is the string that will be used to uniquely id the Source in
@@ -229,8 +241,8 @@ func (t *Tree) getChildren(s *Storage) (map[string]*Tree, error) {
 			key += "." + mod.Version
 		}
 
-		// Check for the exact key if it's not a registry module
-		if !mod.registry {
+		// Check for the exact key if it's not a versioned module
+		if !mod.registry && (!mod.resolved || mod.Version == "") {
 			mod.Dir, err = s.findModule(key)
 			if err != nil {
 				return nil, err
@@ -282,9 +294,14 @@ func (t *Tree) getChildren(s *Storage) (map[string]*Tree, error) {
 		// For example, the registry always adds a subdir of `//*`,
 		// indicating that we need to strip off the first component from the
 		// tar archive, though we may not yet know what it is called.
-		source, detectedSubDir := getter.SourceDirSubdir(source)
-		if detectedSubDir != "" {
-			subDir = filepath.Join(detectedSubDir, subDir)
+		// Resolvers get the modules themselves, so there is nothing to
+		// detect for their sources.
+		if !mod.resolved {
+			var detectedSubDir string
+			source, detectedSubDir = getter.SourceDirSubdir(source)
+			if detectedSubDir != "" {
+				subDir = filepath.Join(detectedSubDir, subDir)
+			}
 		}
 
 		output := ""
@@ -296,7 +313,13 @@ func (t *Tree) getChildren(s *Storage) (map[string]*Tree, error) {
 		}
 		s.output(output)
 
-		dir, ok, err := s.getStorage(key, source)
+		var dir string
+		var ok bool
+		if mod.resolved {
+			dir, ok, err = s.getResolverStorage(key, mod, resolver)
+		} else {
+			dir, ok, err = s.getStorage(key, source)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -444,6 +467,15 @@ func (t *Tree) Validate() tfdiags.Diagnostics {
 		for _, v := range tree.config.Variables {
 			varMap[v.Name] = struct{}{}
 
+			// Secrets are only read for the root module, and a child module
+			// gets its values from the module block.
+			if v.Secret != nil {
+				diags = diags.Append(fmt.Errorf(
+					"module %q: variable %q can't be a secret; only variables of the root module can be",
+					m.Name, v.Name,
+				))
+			}
+
 			if v.Required() {
 				requiredMap[v.Name] = struct{}{}
 			}
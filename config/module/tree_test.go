@@ -274,6 +274,108 @@ func TestTreeLoad_subdir(t *testing.T) {
 	}
 }
 
+func TestTreeLoad_sourceResolver(t *testing.T) {
+	resolver := &testSourceResolver{
+		versions: []string{"0.9.0", "1.0.0", "1.2.0", "2.0.0"},
+	}
+	storage := testStorage(t, nil)
+	storage.Resolvers = map[string]SourceResolverFactory{
+		"test": func() (SourceResolver, error) { return resolver, nil },
+	}
+	tree := NewTree("", testConfig(t, "resolver-source"))
+
+	storage.Mode = GetModeGet
+	if err := tree.Load(storage); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !reflect.DeepEqual(resolver.gets, []string{"team/foo@1.2.0"}) {
+		t.Fatalf("bad gets: %#v", resolver.gets)
+	}
+
+	child := tree.Children()["foo"]
+	if child == nil {
+		t.Fatal("module foo not loaded")
+	}
+	if child.version != "1.2.0" {
+		t.Fatalf("expected version 1.2.0, got %q", child.version)
+	}
+	if len(child.Config().Outputs) != 1 || child.Config().Outputs[0].Name != "child" {
+		t.Fatalf("expected the child subdirectory to be loaded, got %#v", child.Config().Outputs)
+	}
+
+	// The module is cached, so loading it again shouldn't use the resolver
+	resolver.versionsCalled = false
+	if err := tree.Load(storage); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if resolver.versionsCalled {
+		t.Fatal("versions should not be listed for a cached module")
+	}
+	if len(resolver.gets) != 1 {
+		t.Fatalf("bad gets: %#v", resolver.gets)
+	}
+
+	// Updating gets the newest matching version
+	resolver.versions = append(resolver.versions, "1.3.0")
+	storage.Mode = GetModeUpdate
+	if err := tree.Load(storage); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(resolver.gets, []string{"team/foo@1.2.0", "team/foo@1.3.0"}) {
+		t.Fatalf("bad gets: %#v", resolver.gets)
+	}
+	if v := tree.Children()["foo"].version; v != "1.3.0" {
+		t.Fatalf("expected version 1.3.0, got %q", v)
+	}
+}
+
+func TestTreeLoad_sourceResolverNoMatch(t *testing.T) {
+	resolver := &testSourceResolver{
+		versions: []string{"0.9.0", "2.0.0"},
+	}
+	storage := testStorage(t, nil)
+	storage.Resolvers = map[string]SourceResolverFactory{
+		"test": func() (SourceResolver, error) { return resolver, nil },
+	}
+	tree := NewTree("", testConfig(t, "resolver-source"))
+
+	storage.Mode = GetModeGet
+	err := tree.Load(storage)
+	if err == nil {
+		t.Fatal("should error")
+	}
+	if !strings.Contains(err.Error(), "no versions") {
+		t.Fatalf("bad error: %s", err)
+	}
+	if len(resolver.gets) != 0 {
+		t.Fatalf("bad gets: %#v", resolver.gets)
+	}
+}
+
+// testSourceResolver is a SourceResolver that gets a module with a
+// configuration in the "child" subdirectory.
+type testSourceResolver struct {
+	versions       []string
+	versionsCalled bool
+	gets           []string
+}
+
+func (r *testSourceResolver) Versions(addr string) ([]string, error) {
+	r.versionsCalled = true
+	return r.versions, nil
+}
+
+func (r *testSourceResolver) Get(dst, addr, version string) error {
+	r.gets = append(r.gets, addr+"@"+version)
+
+	dir := filepath.Join(dst, "child")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "main.tf"), []byte(`output "child" { value = "`+version+`" }`), 0644)
+}
+
 func TestTree_recordManifest(t *testing.T) {
 	td, err := ioutil.TempDir("", "tf-module")
 	if err != nil {
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ProviderForEachAlias returns the alias of the configuration that a
+// provider block with the given alias and for_each expands to for the
+// element with the given key, such as "region[east]".
+func ProviderForEachAlias(alias, key string) string {
+	return fmt.Sprintf("%s[%s]", alias, key)
+}
+
+// expandProviderConfig expands the given configuration of a provider block
+// with for_each into a configuration for each element of the given map,
+// sorted by key. Each configuration is aliased as ProviderForEachAlias
+// returns, and has its key and value for the each.key and each.value
+// variables.
+func expandProviderConfig(pc *ProviderConfig, forEach map[string]interface{}) ([]*ProviderConfig, error) {
+	if pc.Alias == "" {
+		return nil, fmt.Errorf(
+			"an alias is required with for_each, so that the configurations can be addressed as %s.ALIAS[KEY]",
+			pc.Name)
+	}
+
+	keys := make([]string, 0, len(forEach))
+	for k := range forEach {
+		if !NameRegexp.MatchString(k) {
+			return nil, fmt.Errorf(
+				"for_each key %q is invalid; keys may contain only letters, digits, underscores and dashes", k)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]*ProviderConfig, len(keys))
+	for i, k := range keys {
+		expanded := *pc
+		expanded.Alias = ProviderForEachAlias(pc.Alias, k)
+		expanded.ForEachKey = k
+		expanded.ForEachValue = forEach[k]
+
+		// An HCL map is multi-valued, so a map value may still be in a
+		// slice, as it is for maps in outputs.
+		if ms, ok := forEach[k].([]map[string]interface{}); ok && len(ms) == 1 {
+			expanded.ForEachValue = ms[0]
+		}
+		expanded.RawConfig = pc.RawConfig.Copy()
+		result[i] = &expanded
+	}
+
+	return result, nil
+}
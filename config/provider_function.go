@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hil/ast"
+)
+
+// ProviderFunctionPrefix is the prefix of the names of the functions that
+// providers export, which are called as provider::NAME::FUNCTION(...).
+const ProviderFunctionPrefix = "provider::"
+
+// IsProviderFunction returns true if the given function name refers to a
+// function exported by a provider, rather than a built-in function.
+func IsProviderFunction(name string) bool {
+	return strings.HasPrefix(name, ProviderFunctionPrefix)
+}
+
+// ParseProviderFunctionName splits the name of a provider function, such
+// as "provider::aws::arn_parse", into the name of the provider and the
+// name of the function within it.
+func ParseProviderFunctionName(name string) (provider, function string, err error) {
+	parts := strings.Split(name, "::")
+	if len(parts) != 3 || parts[0] != "provider" {
+		return "", "", fmt.Errorf(
+			"%s: provider functions must be called as provider::NAME::FUNCTION", name)
+	}
+	if !NameRegexp.MatchString(parts[1]) || !NameRegexp.MatchString(parts[2]) {
+		return "", "", fmt.Errorf(
+			"%s: invalid provider function name, the parts must match %s", name, NameRegexp)
+	}
+
+	return parts[1], parts[2], nil
+}
+
+// DetectProviderFunctions takes an AST root and returns the names of the
+// provider functions that it calls, in the order they are first called.
+func DetectProviderFunctions(root ast.Node) []string {
+	var result []string
+	seen := make(map[string]struct{})
+	root.Accept(func(n ast.Node) ast.Node {
+		if c, ok := n.(*ast.Call); ok && IsProviderFunction(c.Func) {
+			if _, ok := seen[c.Func]; !ok {
+				seen[c.Func] = struct{}{}
+				result = append(result, c.Func)
+			}
+		}
+		return n
+	})
+
+	return result
+}
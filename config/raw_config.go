@@ -54,6 +54,10 @@ type RawConfig struct {
 	Interpolations []ast.Node
 	Variables      map[string]InterpolatedVariable
 
+	// ProviderFunctions are the names of the provider functions called by
+	// the interpolations, such as "provider::aws::arn_parse".
+	ProviderFunctions []string
+
 	lock        sync.Mutex
 	config      map[string]interface{}
 	unknownKeys []string
@@ -168,10 +172,22 @@ func (r *RawConfig) Config() map[string]interface{} {
 //
 // If a variable key is missing, this will panic.
 func (r *RawConfig) Interpolate(vs map[string]ast.Variable) error {
+	return r.InterpolateWithFunctions(vs, nil)
+}
+
+// InterpolateWithFunctions is like Interpolate, but also makes the given
+// functions available to the interpolations, in addition to the built-in
+// functions. These are how the provider functions named by
+// ProviderFunctions are supplied.
+func (r *RawConfig) InterpolateWithFunctions(
+	vs map[string]ast.Variable, funcs map[string]ast.Function) error {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
 	config := langEvalConfig(vs)
+	for k, f := range funcs {
+		config.GlobalScope.FuncMap[k] = f
+	}
 	return r.interpolate(func(root ast.Node) (interface{}, error) {
 		// None of the variables we need are computed, meaning we should
 		// be able to properly evaluate.
@@ -241,7 +257,9 @@ func (r *RawConfig) init() error {
 	r.config = r.Raw
 	r.Interpolations = nil
 	r.Variables = nil
+	r.ProviderFunctions = nil
 
+	funcs := make(map[string]struct{})
 	fn := func(node ast.Node) (interface{}, error) {
 		r.Interpolations = append(r.Interpolations, node)
 		vars, err := DetectVariables(node)
@@ -257,6 +275,13 @@ func (r *RawConfig) init() error {
 			r.Variables[v.FullKey()] = v
 		}
 
+		for _, f := range DetectProviderFunctions(node) {
+			if _, ok := funcs[f]; !ok {
+				funcs[f] = struct{}{}
+				r.ProviderFunctions = append(r.ProviderFunctions, f)
+			}
+		}
+
 		return "", nil
 	}
 
@@ -3,6 +3,7 @@ package config
 import (
 	"encoding/gob"
 	"reflect"
+	"sort"
 	"testing"
 
 	hcl2 "github.com/hashicorp/hcl2/hcl"
@@ -66,6 +67,25 @@ func TestRawConfig_basic(t *testing.T) {
 	}
 }
 
+func TestRawConfig_providerFunctions(t *testing.T) {
+	raw := map[string]interface{}{
+		"foo": "${provider::aws::arn_parse(var.bar)}",
+		"baz": "${upper(provider::aws::arn_parse(var.bar))}-${provider::dns::reverse(var.bar)}",
+	}
+
+	rc, err := NewRawConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := rc.ProviderFunctions
+	sort.Strings(actual)
+	expected := []string{"provider::aws::arn_parse", "provider::dns::reverse"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
 func TestRawConfig_double(t *testing.T) {
 	raw := map[string]interface{}{
 		"foo": "${var.bar}",
@@ -6,4 +6,5 @@ type ResourceMode int
 const (
 	ManagedResourceMode ResourceMode = iota
 	DataResourceMode
+	EphemeralResourceMode
 )
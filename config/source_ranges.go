@@ -0,0 +1,178 @@
+package config
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/hcl/hcl/token"
+	"github.com/hashicorp/terraform/tfdiags"
+)
+
+// SourceRanges are the ranges of configuration source that declare an
+// object and each of its arguments, for tools that refer back to the
+// configuration such as editors.
+//
+// They're only recorded by the HCL loader, so they're empty for objects
+// loaded with the experimental HCL2 loader or built in other ways.
+type SourceRanges struct {
+	// Decl is the range of the block that declares the object, from its
+	// first label to its closing brace. For a local value it is the range
+	// of its argument in the locals block.
+	Decl tfdiags.SourceRange
+
+	// Attrs are the ranges of the top-level arguments and nested blocks of
+	// the object's block, keyed by name. Of nested blocks of the same type,
+	// only the first is included.
+	Attrs map[string]tfdiags.SourceRange
+}
+
+// merge returns the ranges of an object that is merged with an override
+// of it. The override declares the arguments that it sets, but the object
+// is still declared by the original block.
+func (r SourceRanges) merge(r2 SourceRanges) SourceRanges {
+	if len(r2.Attrs) == 0 {
+		return r
+	}
+
+	result := SourceRanges{
+		Decl:  r.Decl,
+		Attrs: make(map[string]tfdiags.SourceRange, len(r.Attrs)+len(r2.Attrs)),
+	}
+	for k, v := range r.Attrs {
+		result.Attrs[k] = v
+	}
+	for k, v := range r2.Attrs {
+		result.Attrs[k] = v
+	}
+	return result
+}
+
+// withFilename returns the ranges with the given filename, since the HCL
+// parser doesn't record it.
+func (r SourceRanges) withFilename(filename string) SourceRanges {
+	if r.Decl.Start.Line == 0 {
+		return r
+	}
+
+	result := SourceRanges{Decl: r.Decl}
+	result.Decl.Filename = filename
+	if r.Attrs != nil {
+		result.Attrs = make(map[string]tfdiags.SourceRange, len(r.Attrs))
+		for k, v := range r.Attrs {
+			v.Filename = filename
+			result.Attrs[k] = v
+		}
+	}
+	return result
+}
+
+// setSourceFilename sets the filename of the source ranges of all of the
+// objects of the configuration, which was loaded from the given file.
+func (c *Config) setSourceFilename(filename string) {
+	for _, v := range c.Variables {
+		v.Ranges = v.Ranges.withFilename(filename)
+	}
+	for _, l := range c.Locals {
+		l.Ranges = l.Ranges.withFilename(filename)
+	}
+	for _, m := range c.Modules {
+		m.Ranges = m.Ranges.withFilename(filename)
+	}
+	for _, p := range c.ProviderConfigs {
+		p.Ranges = p.Ranges.withFilename(filename)
+	}
+	for _, r := range c.Resources {
+		r.Ranges = r.Ranges.withFilename(filename)
+	}
+	for _, r := range c.EphemeralResources {
+		r.Ranges = r.Ranges.withFilename(filename)
+	}
+	for _, o := range c.Outputs {
+		o.Ranges = o.Ranges.withFilename(filename)
+	}
+}
+
+// hclBlockRanges returns the ranges of the block of the given item, whose
+// keys are its labels, and of its arguments. Configuration that wasn't
+// parsed from source, such as with LoadJSON, has no ranges.
+func hclBlockRanges(item *ast.ObjectItem) SourceRanges {
+	if pos := item.Pos(); !pos.IsValid() {
+		return SourceRanges{}
+	}
+	result := SourceRanges{Decl: hclItemRange(item)}
+
+	ot, ok := item.Val.(*ast.ObjectType)
+	if !ok {
+		return result
+	}
+	result.Attrs = make(map[string]tfdiags.SourceRange)
+	for _, attr := range ot.List.Items {
+		if len(attr.Keys) == 0 {
+			continue
+		}
+		name, ok := attr.Keys[0].Token.Value().(string)
+		if pos := attr.Pos(); !ok || !pos.IsValid() {
+			continue
+		}
+		if _, exists := result.Attrs[name]; !exists {
+			result.Attrs[name] = hclItemRange(attr)
+		}
+	}
+	return result
+}
+
+// hclItemRange returns the range of the given item, from its first key to
+// the end of its value.
+func hclItemRange(item *ast.ObjectItem) tfdiags.SourceRange {
+	start := hclSourcePos(item.Pos())
+	end := hclNodeEnd(item.Val)
+
+	// The values that the loader builds itself, such as when unwrapping
+	// JSON objects, have no position.
+	if end.Line == 0 || end.Byte < start.Byte {
+		end = start
+	}
+
+	return tfdiags.SourceRange{Start: start, End: end}
+}
+
+// hclNodeEnd returns the position just after the end of the given node.
+func hclNodeEnd(node ast.Node) tfdiags.SourcePos {
+	switch n := node.(type) {
+	case *ast.ObjectType:
+		return hclPosAfter(n.Rbrace, "}")
+	case *ast.ListType:
+		return hclPosAfter(n.Rbrack, "]")
+	case *ast.LiteralType:
+		return hclPosAfter(n.Token.Pos, n.Token.Text)
+	default:
+		return tfdiags.SourcePos{}
+	}
+}
+
+// hclPosAfter returns the position just after the given text, which starts
+// at the given position.
+func hclPosAfter(pos token.Pos, text string) tfdiags.SourcePos {
+	if !pos.IsValid() {
+		return tfdiags.SourcePos{}
+	}
+
+	result := hclSourcePos(pos)
+	result.Byte += len(text)
+	if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+		result.Line += strings.Count(text, "\n")
+		result.Column = utf8.RuneCountInString(text[i+1:]) + 1
+	} else {
+		result.Column += utf8.RuneCountInString(text)
+	}
+	return result
+}
+
+func hclSourcePos(pos token.Pos) tfdiags.SourcePos {
+	return tfdiags.SourcePos{
+		Line:   pos.Line,
+		Column: pos.Column,
+		Byte:   pos.Offset,
+	}
+}
@@ -0,0 +1,12 @@
+resource "aws_instance" "web" {}
+
+check "health" {
+    data "http" "web" {
+        url = "http://${aws_instance.web.public_ip}/health"
+    }
+
+    assert {
+        condition     = "${data.http.web.status_code == 200}"
+        error_message = "The web server must be healthy."
+    }
+}
@@ -0,0 +1,37 @@
+variable "ami" {}
+
+resource "aws_instance" "web" {
+    ami = "${var.ami}"
+
+    lifecycle {
+        create_before_destroy = true
+
+        precondition {
+            condition     = "${var.ami != ""}"
+            error_message = "An AMI must be given."
+        }
+
+        postcondition {
+            condition     = "${self.private_ip != ""}"
+            error_message = "The instance must have a private IP."
+        }
+    }
+}
+
+data "aws_ami" "web" {
+    lifecycle {
+        postcondition {
+            condition     = "${self.architecture == "x86_64"}"
+            error_message = "The AMI must be for x86_64."
+        }
+    }
+}
+
+output "ip" {
+    value = "${aws_instance.web.private_ip}"
+
+    precondition {
+        condition     = "${aws_instance.web.public_ip == ""}"
+        error_message = "The instance must not be public."
+    }
+}
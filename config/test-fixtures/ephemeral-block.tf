@@ -0,0 +1,5 @@
+ephemeral "vault_token" "deploy" {
+  provider   = "vault.west"
+  depends_on = ["aws_instance.web"]
+  role       = "deploy"
+}
@@ -0,0 +1,3 @@
+ephemeral "vault_token" "deploy" {
+  count = 2
+}
@@ -0,0 +1,9 @@
+resource "aws_instance" "web" {
+    count = 2
+}
+
+import {
+    to = "aws_instance.web[1]"
+    id = "i-abc123"
+    provider = "aws.west"
+}
@@ -0,0 +1,12 @@
+resource "aws_instance" "old" {
+  lifecycle {
+    group = "legacy"
+  }
+}
+
+resource "aws_instance" "new" {
+  lifecycle {
+    group                = "replacement"
+    create_after_destroy = ["legacy"]
+  }
+}
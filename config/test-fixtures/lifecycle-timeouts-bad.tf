@@ -0,0 +1,7 @@
+resource "aws_instance" "web" {
+  lifecycle {
+    timeouts {
+      update = "soon"
+    }
+  }
+}
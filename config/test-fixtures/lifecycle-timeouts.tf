@@ -0,0 +1,8 @@
+resource "aws_instance" "web" {
+  lifecycle {
+    timeouts {
+      create = "30m"
+      delete = "1h"
+    }
+  }
+}
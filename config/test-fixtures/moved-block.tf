@@ -0,0 +1,6 @@
+resource "aws_instance" "app" {}
+
+moved {
+    from = "aws_instance.web"
+    to = "aws_instance.app"
+}
@@ -0,0 +1,7 @@
+provider "aws" {
+  region = "${each.value}"
+
+  for_each = {
+    east = "us-east-1"
+  }
+}
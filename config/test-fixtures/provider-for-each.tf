@@ -0,0 +1,9 @@
+provider "aws" {
+  alias  = "region"
+  region = "${each.value}"
+
+  for_each = {
+    east = "us-east-1"
+    west = "us-west-2"
+  }
+}
@@ -0,0 +1,6 @@
+provider "aws" {
+  retry {
+    min_backoff = "10s"
+    max_backoff = "1s"
+  }
+}
@@ -0,0 +1,9 @@
+provider "aws" {
+  rate_limit = 2.5
+  a          = "a"
+
+  retry {
+    max_attempts = 5
+    max_backoff  = "1m"
+  }
+}
@@ -11,4 +11,10 @@ resource "aws_instance" "web" {
         when = "destroy"
         on_failure = "continue"
     }
+
+    provisioner "shell" {
+        path = "foo"
+        when = "destroy"
+        depends_on = ["aws_instance.db", "module.network"]
+    }
 }
@@ -0,0 +1,5 @@
+resource "aws_instance" "app" {}
+
+removed {
+    from = "aws_instance.web"
+}
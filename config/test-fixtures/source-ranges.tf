@@ -0,0 +1,12 @@
+variable "size" {
+  default = 1
+}
+
+resource "aws_instance" "web" {
+  ami   = "${var.ami}"
+  count = "${var.size}"
+}
+
+locals {
+  name = "web"
+}
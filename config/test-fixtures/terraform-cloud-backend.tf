@@ -0,0 +1,10 @@
+terraform {
+  backend "s3" {
+    foo = "bar"
+  }
+
+  cloud {
+    hostname     = "runs.example.com"
+    organization = "example"
+  }
+}
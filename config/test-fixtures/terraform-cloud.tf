@@ -0,0 +1,10 @@
+terraform {
+  cloud {
+    hostname     = "runs.example.com"
+    organization = "example"
+
+    workspaces {
+      name = "app"
+    }
+  }
+}
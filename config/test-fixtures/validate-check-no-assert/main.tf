@@ -0,0 +1,5 @@
+check "health" {
+    data "http" "web" {
+        url = "http://example.com/health"
+    }
+}
@@ -0,0 +1,14 @@
+check "health" {
+    data "http" "web" {
+        url = "http://example.com/health"
+    }
+
+    assert {
+        condition     = "${data.http.web.status_code == 200}"
+        error_message = "The web server must be healthy."
+    }
+}
+
+output "status" {
+    value = "${data.http.web.status_code}"
+}
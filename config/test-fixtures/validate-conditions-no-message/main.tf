@@ -0,0 +1,7 @@
+resource "aws_instance" "web" {
+    lifecycle {
+        precondition {
+            condition = "${1 == 1}"
+        }
+    }
+}
@@ -0,0 +1,8 @@
+resource "aws_instance" "web" {
+    lifecycle {
+        precondition {
+            condition     = "${self.ami != ""}"
+            error_message = "Preconditions can't refer to self."
+        }
+    }
+}
@@ -0,0 +1,13 @@
+provider "aws" {
+  alias  = "region"
+  region = "${each.value}"
+
+  for_each = {
+    east = "us-east-1"
+  }
+}
+
+resource "aws_instance" "web" {
+  provider = "aws.region[east]"
+  name     = "${each.key}"
+}
@@ -0,0 +1,17 @@
+ephemeral "vault_token" "deploy" {
+  role = "deploy"
+}
+
+ephemeral "vault_secret" "db" {
+  token = "${ephemeral.vault_token.deploy.value}"
+}
+
+provider "aws" {
+  token = "${ephemeral.vault_token.deploy.value}"
+}
+
+resource "aws_instance" "web" {
+  provisioner "local-exec" {
+    command = "echo ${ephemeral.vault_secret.db.password}"
+  }
+}
@@ -0,0 +1,7 @@
+ephemeral "vault_token" "deploy" {
+  role = "deploy"
+}
+
+output "token" {
+  value = "${ephemeral.vault_token.deploy.value}"
+}
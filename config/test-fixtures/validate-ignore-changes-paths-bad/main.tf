@@ -0,0 +1,5 @@
+resource "aws_security_group" "web" {
+  lifecycle {
+    ignore_changes = ["tags[\"kubernetes.io/*\""]
+  }
+}
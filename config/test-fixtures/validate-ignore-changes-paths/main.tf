@@ -0,0 +1,9 @@
+resource "aws_security_group" "web" {
+  lifecycle {
+    ignore_changes = [
+      "tags[\"kubernetes.io/*\"]",
+      "ingress[*].description",
+      "egress[0]",
+    ]
+  }
+}
@@ -0,0 +1,11 @@
+resource "aws_instance" "web" {}
+
+import {
+    to = "aws_instance.web"
+    id = "i-abc123"
+}
+
+import {
+    to = "aws_instance.web"
+    id = "i-def456"
+}
@@ -0,0 +1,5 @@
+resource "aws_instance" "new" {
+  lifecycle {
+    create_after_destroy = ["not a group"]
+  }
+}
@@ -0,0 +1,7 @@
+resource "aws_instance" "new" {
+  lifecycle {
+    create_before_destroy = true
+    group                 = "replacement"
+    create_after_destroy  = ["replacement"]
+  }
+}
@@ -0,0 +1,6 @@
+resource "aws_instance" "new" {
+  lifecycle {
+    group                = "replacement"
+    create_after_destroy = ["legacy", "replacement"]
+  }
+}
@@ -0,0 +1,7 @@
+resource "aws_instance" "web" {}
+resource "aws_instance" "app" {}
+
+moved {
+    from = "aws_instance.web"
+    to = "aws_instance.app"
+}
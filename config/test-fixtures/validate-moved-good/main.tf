@@ -0,0 +1,13 @@
+resource "aws_instance" "app" {
+    count = 2
+}
+
+moved {
+    from = "aws_instance.web"
+    to = "aws_instance.app[0]"
+}
+
+moved {
+    from = "aws_instance.app"
+    to = "aws_instance.app[1]"
+}
@@ -0,0 +1,6 @@
+resource "vendorb_instance" "app" {}
+
+moved {
+    from = "vendora_instance.app"
+    to = "vendorb_instance.app"
+}
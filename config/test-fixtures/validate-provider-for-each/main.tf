@@ -0,0 +1,14 @@
+provider "aws" {
+  alias      = "account"
+  role_arn   = "${each.value["role_arn"]}"
+  account_id = "${each.key}"
+
+  for_each = {
+    prod    = { role_arn = "arn:aws:iam::1:role/deploy" }
+    staging = { role_arn = "arn:aws:iam::2:role/deploy" }
+  }
+}
+
+resource "aws_instance" "web" {
+  provider = "aws.account[prod]"
+}
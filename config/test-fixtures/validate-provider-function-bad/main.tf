@@ -0,0 +1,3 @@
+resource "aws_instance" "web" {
+  arn = "${provider::arn_build("ec2", "instance")}"
+}
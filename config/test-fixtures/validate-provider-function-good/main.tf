@@ -0,0 +1,3 @@
+resource "aws_instance" "web" {
+  arn = "${provider::aws::arn_build("ec2", "instance")}"
+}
@@ -0,0 +1,7 @@
+resource "aws_instance" "app" {
+    provisioner "local-exec" {
+        command = "deregister"
+        when = "destroy"
+        depends_on = ["aws_instance.db"]
+    }
+}
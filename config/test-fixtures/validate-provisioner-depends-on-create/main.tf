@@ -0,0 +1,8 @@
+resource "aws_instance" "db" {}
+
+resource "aws_instance" "app" {
+    provisioner "local-exec" {
+        command = "register"
+        depends_on = ["aws_instance.db"]
+    }
+}
@@ -0,0 +1,9 @@
+resource "aws_instance" "db" {}
+
+resource "aws_instance" "app" {
+    provisioner "local-exec" {
+        command = "deregister"
+        when = "destroy"
+        depends_on = ["aws_instance.db"]
+    }
+}
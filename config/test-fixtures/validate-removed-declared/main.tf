@@ -0,0 +1,5 @@
+resource "aws_instance" "web" {}
+
+removed {
+    from = "aws_instance.web"
+}
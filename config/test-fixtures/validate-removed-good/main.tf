@@ -0,0 +1,11 @@
+resource "aws_instance" "app" {
+    count = 2
+}
+
+removed {
+    from = "aws_instance.web"
+}
+
+removed {
+    from = "aws_instance.app[2]"
+}
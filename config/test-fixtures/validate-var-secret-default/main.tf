@@ -0,0 +1,8 @@
+variable "db_password" {
+  default = "hunter2"
+
+  secret {
+    backend = "vault"
+    key     = "secret/data/db#password"
+  }
+}
@@ -0,0 +1,10 @@
+resource "aws_instance" "web" {}
+
+variable "ami" {
+  default = ""
+
+  validation {
+    condition     = "${var.ami != aws_instance.web.ami}"
+    error_message = "The AMI must differ from the running one."
+  }
+}
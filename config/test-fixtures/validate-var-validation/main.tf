@@ -0,0 +1,25 @@
+variable "enable_tls" {
+  default = "false"
+}
+
+variable "certificate" {
+  default = ""
+
+  validation {
+    condition     = "${var.enable_tls == "false" || var.certificate != ""}"
+    error_message = "A certificate is required when enable_tls is true."
+  }
+}
+
+locals {
+  max_size = 10
+}
+
+variable "size" {
+  default = "2"
+
+  validation {
+    condition     = "${var.size <= local.max_size}"
+    error_message = "The size must be at most ${local.max_size}."
+  }
+}
@@ -0,0 +1,8 @@
+variable "db_password" {
+  description = "Password of the database"
+
+  secret {
+    backend = "vault"
+    key     = "secret/data/db#password"
+  }
+}
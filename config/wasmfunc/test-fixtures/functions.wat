@@ -0,0 +1,43 @@
+;; The source of functions.wasm, a module of test functions. Each result is
+;; a pointer to its JSON in the high 32 bits and its length in the low 32.
+(module
+  (memory (export "memory") 1)
+  (global $heap (mut i32) (i32.const 4096))
+
+  (data (i32.const 0) "{\"functions\":[{\"name\":\"greeting\",\"description\":\"Returns a greeting.\",\"return\":\"string\"},{\"name\":\"fail\",\"return\":\"string\"},{\"name\":\"wrap\",\"variadic\":{\"name\":\"values\",\"type\":\"string\"},\"return\":\"list\"},{\"name\":\"spin\",\"return\":\"string\"},{\"name\":\"trap\",\"params\":[{\"name\":\"n\",\"type\":\"int\"}],\"return\":\"int\"}]}")
+  (data (i32.const 2048) "{\"result\":")
+  (data (i32.const 2064) "{\"result\":\"hello\"}")
+  (data (i32.const 2096) "{\"error\":\"no luck\"}")
+
+  (func $alloc (export "terraform_alloc") (param $size i32) (result i32)
+    (local $p i32)
+    (local.set $p (global.get $heap))
+    (global.set $heap (i32.add (global.get $heap) (local.get $size)))
+    (local.get $p))
+
+  (func (export "terraform_functions") (result i64)
+    (i64.const 302))
+
+  (func (export "greeting") (param i32 i32) (result i64)
+    (i64.const 8864812498962))
+
+  (func (export "fail") (param i32 i32) (result i64)
+    (i64.const 9002251452435))
+
+  ;; wrap returns its arguments as a list.
+  (func (export "wrap") (param $ptr i32) (param $len i32) (result i64)
+    (local $out i32)
+    (local.set $out (call $alloc (i32.add (local.get $len) (i32.const 11))))
+    (memory.copy (local.get $out) (i32.const 2048) (i32.const 10))
+    (memory.copy (i32.add (local.get $out) (i32.const 10)) (local.get $ptr) (local.get $len))
+    (i32.store8 (i32.add (i32.add (local.get $out) (i32.const 10)) (local.get $len)) (i32.const 125))
+    (i64.or
+      (i64.shl (i64.extend_i32_u (local.get $out)) (i64.const 32))
+      (i64.extend_i32_u (i32.add (local.get $len) (i32.const 11)))))
+
+  (func (export "spin") (param i32 i32) (result i64)
+    (loop $forever (br $forever))
+    (unreachable))
+
+  (func (export "trap") (param i32 i32) (result i64)
+    (unreachable)))
@@ -0,0 +1,5 @@
+;; The source of imports.wasm, a module that imports a function, which WASM
+;; functions can't do.
+(module
+  (import "env" "now" (func (result i64)))
+  (memory (export "memory") 1))
@@ -0,0 +1,434 @@
+// Package wasmfunc loads functions for configuration that are implemented by
+// WebAssembly (WASM) modules, so that they can be used in interpolations
+// like the built-in functions.
+//
+// The functions are sandboxed and pure: a module can't import anything, so
+// it has no access to the filesystem, the network, clocks or randomness,
+// and each call is made of a new instance of the module, so that no state
+// is kept between calls. Each call is limited in memory and time.
+//
+// A module must export:
+//
+//   - "memory", its memory.
+//   - "terraform_alloc", a function (i32) -> i32 that allocates the given
+//     number of bytes in the memory and returns their address.
+//   - "terraform_functions", a function () -> i64 that returns the JSON
+//     description of its functions, as described by Signatures.
+//   - Each of its functions, as (i32, i32) -> i64. They're given the address
+//     and length of the JSON array of their arguments, allocated with
+//     terraform_alloc, and return the JSON result, which is either
+//     {"result": VALUE} or {"error": "MESSAGE"}.
+//
+// Results of type i64 are the address of some JSON in the high 32 bits and
+// its length in the low 32 bits.
+package wasmfunc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hil"
+	"github.com/hashicorp/hil/ast"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+const (
+	// DefaultMemoryLimitPages is the number of 64KiB pages of memory that a
+	// call may use, so 64MiB.
+	DefaultMemoryLimitPages = 1024
+
+	// DefaultCallTimeout is how long a call may run.
+	DefaultCallTimeout = 10 * time.Second
+)
+
+// Signatures are the functions of a module, as returned by its
+// terraform_functions export.
+type Signatures struct {
+	Functions []*Signature `json:"functions"`
+}
+
+// Signature describes a function of a module. The types are "string",
+// "int", "float", "bool", "list" or "map".
+type Signature struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Params      []*Param `json:"params"`
+	Variadic    *Param   `json:"variadic"`
+	Return      string   `json:"return"`
+}
+
+// Param is a parameter of a function.
+type Param struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// LoadOpts are the options for loading a module.
+type LoadOpts struct {
+	// Name names the module in errors, such as the label of its block in
+	// the CLI configuration.
+	Name string
+
+	// Path is the path of the module's .wasm file.
+	Path string
+
+	// SHA256, if set, is the hex-encoded SHA256 checksum that the module's
+	// file must have.
+	SHA256 string
+
+	// MemoryLimitPages and CallTimeout limit each call, and default to
+	// DefaultMemoryLimitPages and DefaultCallTimeout.
+	MemoryLimitPages uint32
+	CallTimeout      time.Duration
+}
+
+// Module is a loaded WASM module.
+type Module struct {
+	Name      string
+	Functions []*Signature
+
+	runtime     wazero.Runtime
+	compiled    wazero.CompiledModule
+	callTimeout time.Duration
+}
+
+// Load loads the module described by the given options, checking that it's
+// pure and reading the descriptions of its functions.
+func Load(opts *LoadOpts) (*Module, error) {
+	code, err := ioutil.ReadFile(opts.Path)
+	if err != nil {
+		return nil, fmt.Errorf("WASM module %q: %s", opts.Name, err)
+	}
+	if opts.SHA256 != "" {
+		sum := sha256.Sum256(code)
+		if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, opts.SHA256) {
+			return nil, fmt.Errorf(
+				"WASM module %q: %s has checksum %s, but %s was expected",
+				opts.Name, opts.Path, got, opts.SHA256)
+		}
+	}
+
+	pages := opts.MemoryLimitPages
+	if pages == 0 {
+		pages = DefaultMemoryLimitPages
+	}
+	timeout := opts.CallTimeout
+	if timeout == 0 {
+		timeout = DefaultCallTimeout
+	}
+
+	// The interpreter is used, rather than the compiler, so that loading
+	// modules, which is done each time Terraform runs, is quick.
+	ctx := context.Background()
+	rc := wazero.NewRuntimeConfigInterpreter().
+		WithMemoryLimitPages(pages).
+		WithCloseOnContextDone(true)
+	m := &Module{
+		Name:        opts.Name,
+		runtime:     wazero.NewRuntimeWithConfig(ctx, rc),
+		callTimeout: timeout,
+	}
+
+	if err := m.load(ctx, code); err != nil {
+		m.Close()
+		return nil, fmt.Errorf("WASM module %q: %s", opts.Name, err)
+	}
+	return m, nil
+}
+
+func (m *Module) load(ctx context.Context, code []byte) error {
+	compiled, err := m.runtime.CompileModule(ctx, code)
+	if err != nil {
+		return err
+	}
+	m.compiled = compiled
+
+	// Without imports, the functions can't have any side effects or read
+	// anything but their arguments.
+	for _, f := range compiled.ImportedFunctions() {
+		module, name, _ := f.Import()
+		return fmt.Errorf(
+			"the module imports the function %s.%s, but WASM functions can't import anything", module, name)
+	}
+	for _, mem := range compiled.ImportedMemories() {
+		module, name, _ := mem.Import()
+		return fmt.Errorf(
+			"the module imports the memory %s.%s, but WASM functions can't import anything", module, name)
+	}
+
+	var sigs Signatures
+	if err := m.call(ctx, "terraform_functions", nil, &sigs); err != nil {
+		return fmt.Errorf("error reading its functions: %s", err)
+	}
+
+	exports := compiled.ExportedFunctions()
+	seen := make(map[string]bool)
+	for _, sig := range sigs.Functions {
+		if seen[sig.Name] {
+			return fmt.Errorf("function %q is described more than once", sig.Name)
+		}
+		seen[sig.Name] = true
+
+		def, ok := exports[sig.Name]
+		if !ok || !typesEqual(def.ParamTypes(), api.ValueTypeI32, api.ValueTypeI32) || !typesEqual(def.ResultTypes(), api.ValueTypeI64) {
+			return fmt.Errorf("function %q must be exported as (i32, i32) -> i64", sig.Name)
+		}
+		if _, err := sig.function(nil); err != nil {
+			return err
+		}
+	}
+	m.Functions = sigs.Functions
+	return nil
+}
+
+// ASTFunctions returns the module's functions as interpolation functions,
+// by name.
+func (m *Module) ASTFunctions() map[string]ast.Function {
+	result := make(map[string]ast.Function, len(m.Functions))
+	for _, sig := range m.Functions {
+		// The signatures were checked when the module was loaded.
+		f, _ := sig.function(m)
+		result[sig.Name] = f
+	}
+	return result
+}
+
+// Call calls the named function of the module with the given JSON-encodable
+// arguments, decoding its result into the given value.
+func (m *Module) Call(name string, args []interface{}, result interface{}) error {
+	if args == nil {
+		args = []interface{}{}
+	}
+	input, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.callTimeout)
+	defer cancel()
+
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *string         `json:"error"`
+	}
+	if err := m.call(ctx, name, input, &resp); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("the call timed out after %s", m.callTimeout)
+		}
+		return err
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("%s", *resp.Error)
+	}
+	if resp.Result == nil {
+		return fmt.Errorf("the function returned neither a result nor an error")
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+// call calls the named export of a new instance of the module, with the
+// given input, if any, decoding the JSON that it returns into the given
+// value.
+func (m *Module) call(ctx context.Context, name string, input []byte, result interface{}) error {
+	mod, err := m.runtime.InstantiateModule(ctx, m.compiled, wazero.NewModuleConfig().WithName(""))
+	if err != nil {
+		return err
+	}
+	defer mod.Close(ctx)
+
+	mem := mod.Memory()
+	if mem == nil {
+		return fmt.Errorf("the module doesn't export its memory")
+	}
+	fn := mod.ExportedFunction(name)
+	if fn == nil {
+		return fmt.Errorf("the module doesn't export %s", name)
+	}
+
+	var params []uint64
+	if input != nil {
+		alloc := mod.ExportedFunction("terraform_alloc")
+		if alloc == nil {
+			return fmt.Errorf("the module doesn't export terraform_alloc")
+		}
+		res, err := alloc.Call(ctx, uint64(len(input)))
+		if err != nil {
+			return err
+		}
+		ptr := uint32(res[0])
+		if !mem.Write(ptr, input) {
+			return fmt.Errorf("terraform_alloc returned an address outside of the memory")
+		}
+		params = []uint64{uint64(ptr), uint64(len(input))}
+	}
+
+	res, err := fn.Call(ctx, params...)
+	if err != nil {
+		return err
+	}
+	ptr, size := uint32(res[0]>>32), uint32(res[0])
+	output, ok := mem.Read(ptr, size)
+	if !ok {
+		return fmt.Errorf("%s returned an address outside of the memory", name)
+	}
+	if err := json.Unmarshal(output, result); err != nil {
+		return fmt.Errorf("%s returned invalid JSON: %s", name, err)
+	}
+	return nil
+}
+
+// Close releases the resources of the module.
+func (m *Module) Close() error {
+	return m.runtime.Close(context.Background())
+}
+
+// function returns the interpolation function with the signature, which
+// calls the function of the given module.
+func (sig *Signature) function(m *Module) (ast.Function, error) {
+	var f ast.Function
+	for _, p := range sig.Params {
+		t, err := parseType(p.Type)
+		if err != nil {
+			return f, fmt.Errorf("function %q, parameter %q: %s", sig.Name, p.Name, err)
+		}
+		f.ArgTypes = append(f.ArgTypes, t)
+	}
+	if sig.Variadic != nil {
+		t, err := parseType(sig.Variadic.Type)
+		if err != nil {
+			return f, fmt.Errorf("function %q, parameter %q: %s", sig.Name, sig.Variadic.Name, err)
+		}
+		f.Variadic = true
+		f.VariadicType = t
+	}
+	ret, err := parseType(sig.Return)
+	if err != nil {
+		return f, fmt.Errorf("function %q, return type: %s", sig.Name, err)
+	}
+	f.ReturnType = ret
+
+	f.Callback = func(args []interface{}) (interface{}, error) {
+		values := make([]interface{}, len(args))
+		for i, arg := range args {
+			t := f.VariadicType
+			if i < len(f.ArgTypes) {
+				t = f.ArgTypes[i]
+			}
+			v, err := argument(t, arg)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", sig.Name, err)
+			}
+			values[i] = v
+		}
+
+		var result interface{}
+		if err := m.Call(sig.Name, values, &result); err != nil {
+			return nil, fmt.Errorf("%s: %s", sig.Name, err)
+		}
+		v, err := returnValue(ret, result)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", sig.Name, err)
+		}
+		return v, nil
+	}
+	return f, nil
+}
+
+func parseType(s string) (ast.Type, error) {
+	switch s {
+	case "string":
+		return ast.TypeString, nil
+	case "int":
+		return ast.TypeInt, nil
+	case "float":
+		return ast.TypeFloat, nil
+	case "bool":
+		return ast.TypeBool, nil
+	case "list":
+		return ast.TypeList, nil
+	case "map":
+		return ast.TypeMap, nil
+	default:
+		return ast.TypeInvalid, fmt.Errorf(
+			"unsupported type %q, it must be string, int, float, bool, list or map", s)
+	}
+}
+
+// argument converts the value of an argument of an interpolation function
+// call to the value that's encoded as JSON.
+func argument(t ast.Type, v interface{}) (interface{}, error) {
+	switch t {
+	case ast.TypeList, ast.TypeMap:
+		return hil.VariableToInterface(ast.Variable{Type: t, Value: v})
+	default:
+		return v, nil
+	}
+}
+
+// returnValue converts the value decoded from the JSON result of a call to
+// the result of an interpolation function call of the given type.
+func returnValue(t ast.Type, v interface{}) (interface{}, error) {
+	switch t {
+	case ast.TypeString:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case ast.TypeInt:
+		if n, ok := v.(float64); ok && n == math.Trunc(n) {
+			return int(n), nil
+		}
+	case ast.TypeFloat:
+		if n, ok := v.(float64); ok {
+			return n, nil
+		}
+	case ast.TypeBool:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case ast.TypeList, ast.TypeMap:
+		result, err := hil.InterfaceToVariable(v)
+		if err == nil && result.Type == t {
+			return result.Value, nil
+		}
+	}
+	return nil, fmt.Errorf("the function returned %#v, which isn't a valid %s", v, typeName(t))
+}
+
+func typeName(t ast.Type) string {
+	switch t {
+	case ast.TypeString:
+		return "string"
+	case ast.TypeInt:
+		return "int"
+	case ast.TypeFloat:
+		return "float"
+	case ast.TypeBool:
+		return "bool"
+	case ast.TypeList:
+		return "list"
+	case ast.TypeMap:
+		return "map"
+	default:
+		return t.String()
+	}
+}
+
+func typesEqual(got []api.ValueType, want ...api.ValueType) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
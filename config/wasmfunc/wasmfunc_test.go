@@ -0,0 +1,178 @@
+package wasmfunc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/hil"
+	"github.com/hashicorp/hil/ast"
+)
+
+const fixturePath = "test-fixtures/functions.wasm"
+
+func testModule(t *testing.T, opts *LoadOpts) *Module {
+	t.Helper()
+	if opts == nil {
+		opts = &LoadOpts{}
+	}
+	opts.Name = "test"
+	opts.Path = fixturePath
+	m, err := Load(opts)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestLoad(t *testing.T) {
+	m := testModule(t, nil)
+
+	var names []string
+	for _, f := range m.Functions {
+		names = append(names, f.Name)
+	}
+	expected := []string{"greeting", "fail", "wrap", "spin", "trap"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("bad: %#v", names)
+	}
+	if got := m.Functions[0].Description; got != "Returns a greeting." {
+		t.Fatalf("bad: %q", got)
+	}
+}
+
+func TestLoad_checksum(t *testing.T) {
+	code, err := ioutil.ReadFile(fixturePath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	sum := sha256.Sum256(code)
+
+	m, err := Load(&LoadOpts{Name: "test", Path: fixturePath, SHA256: hex.EncodeToString(sum[:])})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	m.Close()
+
+	_, err = Load(&LoadOpts{Name: "test", Path: fixturePath, SHA256: strings.Repeat("0", 64)})
+	if err == nil || !strings.Contains(err.Error(), "was expected") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestLoad_imports(t *testing.T) {
+	_, err := Load(&LoadOpts{Name: "test", Path: "test-fixtures/imports.wasm"})
+	if err == nil || !strings.Contains(err.Error(), "imports the function env.now") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestModuleCall(t *testing.T) {
+	m := testModule(t, nil)
+
+	var result interface{}
+	if err := m.Call("wrap", []interface{}{"a", "b"}, &result); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(result, []interface{}{"a", "b"}) {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	err := m.Call("fail", nil, &result)
+	if err == nil || err.Error() != "no luck" {
+		t.Fatalf("bad: %v", err)
+	}
+
+	err = m.Call("trap", []interface{}{1}, &result)
+	if err == nil || !strings.Contains(err.Error(), "unreachable") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestModuleCall_timeout(t *testing.T) {
+	m := testModule(t, &LoadOpts{CallTimeout: 50 * time.Millisecond})
+
+	var result interface{}
+	err := m.Call("spin", nil, &result)
+	if err == nil || !strings.Contains(err.Error(), "timed out after 50ms") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// The module can still be called after a call times out.
+	if err := m.Call("greeting", nil, &result); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestModuleASTFunctions(t *testing.T) {
+	m := testModule(t, nil)
+
+	cases := map[string]struct {
+		Input  string
+		Result interface{}
+		Error  string
+	}{
+		"string": {
+			`${greeting()}`,
+			"hello",
+			"",
+		},
+		"variadic list": {
+			`${join(",", wrap("a", "b"))}`,
+			"a,b",
+			"",
+		},
+		"error": {
+			`${fail()}`,
+			nil,
+			"fail: no luck",
+		},
+		"trap": {
+			`${trap(1)}`,
+			nil,
+			"unreachable",
+		},
+	}
+
+	funcs := m.ASTFunctions()
+	funcs["join"] = ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString, ast.TypeList},
+		ReturnType: ast.TypeString,
+		Callback: func(args []interface{}) (interface{}, error) {
+			var parts []string
+			for _, v := range args[1].([]ast.Variable) {
+				parts = append(parts, v.Value.(string))
+			}
+			return strings.Join(parts, args[0].(string)), nil
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			root, err := hil.Parse(tc.Input)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			result, err := hil.Eval(root, &hil.EvalConfig{
+				GlobalScope: &ast.BasicScope{FuncMap: funcs},
+			})
+			if tc.Error != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.Error) {
+					t.Fatalf("bad: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if result.Value != tc.Result {
+				t.Fatalf("bad: %#v", result.Value)
+			}
+		})
+	}
+}
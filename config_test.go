@@ -54,6 +54,49 @@ func TestLoadConfig_env(t *testing.T) {
 	}
 }
 
+func TestLoadConfig_tracing(t *testing.T) {
+	got, diags := loadConfigFile(filepath.Join(fixtureDir, "tracing"))
+	if len(diags) != 0 {
+		t.Fatalf("%s", diags.Err())
+	}
+
+	want := &Config{
+		Tracing: map[string]*ConfigTracing{
+			"otlp": {
+				Endpoint:    "http://localhost:4318",
+				ServiceName: "ci-terraform",
+				Headers: map[string]string{
+					"x-honeycomb-team": "abc123",
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result\ngot:  %swant: %s", spew.Sdump(got), spew.Sdump(want))
+	}
+}
+
+func TestLoadConfig_wasmFunctions(t *testing.T) {
+	got, diags := loadConfigFile(filepath.Join(fixtureDir, "wasm-functions"))
+	if len(diags) != 0 {
+		t.Fatalf("%s", diags.Err())
+	}
+
+	want := &Config{
+		WASMFunctions: map[string]*ConfigWASMFunctions{
+			"strings": {
+				Path:   "/opt/terraform/strings.wasm",
+				SHA256: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result\ngot:  %swant: %s", spew.Sdump(got), spew.Sdump(want))
+	}
+}
+
 func TestLoadConfig_hosts(t *testing.T) {
 	got, diags := loadConfigFile(filepath.Join(fixtureDir, "hosts"))
 	if len(diags) != 0 {
@@ -169,6 +212,178 @@ func TestConfigValidate(t *testing.T) {
 			},
 			1, // no more than one credentials_helper block allowed
 		},
+		"policy hook good": {
+			&Config{
+				PolicyHooks: map[string]*ConfigPolicyHook{
+					"foo": {Command: "/usr/local/bin/check-plan"},
+				},
+			},
+			0,
+		},
+		"policy hook without command": {
+			&Config{
+				PolicyHooks: map[string]*ConfigPolicyHook{
+					"foo": {Args: []string{"bar"}},
+				},
+			},
+			1, // policy_hook block must set command
+		},
+		"plan analyzer good": {
+			&Config{
+				PlanAnalyzers: map[string]*ConfigPlanAnalyzer{
+					"costs": {Command: "/usr/local/bin/estimate-costs"},
+				},
+			},
+			0,
+		},
+		"plan analyzer without command": {
+			&Config{
+				PlanAnalyzers: map[string]*ConfigPlanAnalyzer{
+					"costs": {Args: []string{"--monthly"}},
+				},
+			},
+			1, // plan_analyzer block must set command
+		},
+		"provider OCI mirror good": {
+			&Config{
+				ProviderOCIMirrors: map[string]*ConfigProviderOCIMirror{
+					"ghcr.io/example/providers": {PublicKey: "cosign.pub"},
+				},
+			},
+			0,
+		},
+		"provider OCI mirror without repository": {
+			&Config{
+				ProviderOCIMirrors: map[string]*ConfigProviderOCIMirror{
+					"ghcr.io": {PublicKey: "cosign.pub"},
+				},
+			},
+			1, // provider_oci_mirror block must name a host and repository
+		},
+		"provider sigstore good": {
+			&Config{
+				ProviderSigstore: map[string]*ConfigProviderSigstore{
+					"ghcr.io": {
+						Identity:       "releases@example.com",
+						Issuer:         "https://accounts.google.com",
+						Roots:          "fulcio.pem",
+						RekorPublicKey: "rekor.pub",
+					},
+				},
+			},
+			0,
+		},
+		"provider sigstore incomplete": {
+			&Config{
+				ProviderSigstore: map[string]*ConfigProviderSigstore{
+					"ghcr.io": {Identity: "releases@example.com"},
+				},
+			},
+			1, // provider_sigstore block must set all of its arguments
+		},
+		"provider sigstore bad hostname": {
+			&Config{
+				ProviderSigstore: map[string]*ConfigProviderSigstore{
+					"not valid!": {
+						Identity:       "releases@example.com",
+						Issuer:         "https://accounts.google.com",
+						Roots:          "fulcio.pem",
+						RekorPublicKey: "rekor.pub",
+					},
+				},
+			},
+			1, // provider_sigstore block has an invalid hostname
+		},
+		"secrets backend good": {
+			&Config{
+				SecretsBackends: map[string]*ConfigSecretsBackend{
+					"vault": {Type: "vault", Address: "https://vault.example.com"},
+				},
+			},
+			0,
+		},
+		"secrets backend without type": {
+			&Config{
+				SecretsBackends: map[string]*ConfigSecretsBackend{
+					"vault": {Address: "https://vault.example.com"},
+				},
+			},
+			1, // secrets_backend block must set type
+		},
+		"tracing good": {
+			&Config{
+				Tracing: map[string]*ConfigTracing{
+					"otlp": {Endpoint: "http://localhost:4318"},
+				},
+			},
+			0,
+		},
+		"tracing without endpoint": {
+			&Config{
+				Tracing: map[string]*ConfigTracing{
+					"otlp": {},
+				},
+			},
+			1, // tracing block must set endpoint
+		},
+		"tracing unsupported protocol": {
+			&Config{
+				Tracing: map[string]*ConfigTracing{
+					"zipkin": {Endpoint: "http://localhost:9411"},
+				},
+			},
+			1, // tracing block has an unsupported protocol
+		},
+		"wasm functions good": {
+			&Config{
+				WASMFunctions: map[string]*ConfigWASMFunctions{
+					"strings": {Path: "strings.wasm"},
+				},
+			},
+			0,
+		},
+		"wasm functions without path": {
+			&Config{
+				WASMFunctions: map[string]*ConfigWASMFunctions{
+					"strings": {},
+				},
+			},
+			1, // wasm_functions block must set path
+		},
+		"wasm functions bad checksum": {
+			&Config{
+				WASMFunctions: map[string]*ConfigWASMFunctions{
+					"strings": {Path: "strings.wasm", SHA256: "abc"},
+				},
+			},
+			1, // sha256 must be 64 hexadecimal digits
+		},
+		"provider host idle timeout": {
+			&Config{
+				ProviderHost:            true,
+				ProviderHostIdleTimeout: "30m",
+			},
+			0,
+		},
+		"provider host bad idle timeout": {
+			&Config{
+				ProviderHost:            true,
+				ProviderHostIdleTimeout: "soon",
+			},
+			1, // idle timeout must be a duration
+		},
+		"sensitive redaction": {
+			&Config{
+				SensitiveRedaction: "length",
+			},
+			0,
+		},
+		"bad sensitive redaction": {
+			&Config{
+				SensitiveRedaction: "scrambled",
+			},
+			1, // redaction must be hidden, hash or length
+		},
 	}
 
 	for name, test := range tests {
@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/go-multierror"
 )
 
@@ -113,12 +114,13 @@ func (w *Walker) Wait() error {
 	w.errLock.Lock()
 	defer w.errLock.Unlock()
 
-	// Build the error
+	// Build the error. The errors of the vertices are wrapped rather than
+	// formatted, so callers can still find errors of particular types.
 	var result error
 	for v, err := range w.errMap {
 		if err != nil && err != errWalkUpstream {
-			result = multierror.Append(result, fmt.Errorf(
-				"%s: %s", VertexName(v), err))
+			result = multierror.Append(result, errwrap.Wrapf(
+				fmt.Sprintf("%s: {{err}}", VertexName(v)), err))
 		}
 	}
 
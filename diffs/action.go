@@ -0,0 +1,38 @@
+package diffs
+
+// Action describes the kind of operation that a Change represents.
+type Action rune
+
+//go:generate stringer -type=Action
+
+const (
+	// NoOp represents a change where the old and new values are identical,
+	// and so nothing needs to be done.
+	NoOp Action = 0
+
+	// Create represents a change where there is no old value and a new
+	// value is to be created.
+	Create Action = '+'
+
+	// Read represents a change where the new value is obtained by reading
+	// the current value from its source, rather than from configuration.
+	Read Action = '←'
+
+	// Update represents a change where an existing value is modified
+	// in-place to become the new value.
+	Update Action = '~'
+
+	// Replace represents a change where the existing value must be
+	// destroyed and a new value created in its place, because one or more
+	// of the changed paths cannot be updated in-place.
+	Replace Action = '±'
+
+	// Delete represents a change where the existing value is destroyed and
+	// there is no new value.
+	Delete Action = '-'
+
+	// Forget represents a change where the existing value is no longer
+	// tracked, but is not destroyed. Like Delete there is no new value,
+	// but the object that the old value describes continues to exist.
+	Forget Action = '.'
+)
@@ -0,0 +1,305 @@
+package diffs
+
+import (
+	"fmt"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Change describes a single change to a value of a particular type.
+//
+// Change values should usually be constructed using one of the New* functions
+// in this package, which ensure that the fields are populated consistently
+// for the selected action.
+type Change struct {
+	// Action is the kind of operation that will be performed to achieve the
+	// change.
+	Action Action
+
+	// Type is the type that both Old and New conform to.
+	Type cty.Type
+
+	// Old is the value prior to the change. This is a null value for a
+	// Create change.
+	Old cty.Value
+
+	// New is the value after the change. This is a null value for a Delete
+	// change. New may contain unknown values, representing parts of the
+	// result that cannot be determined until the change is applied.
+	New cty.Value
+
+	// ForcedReplace is the set of paths within the value whose changes
+	// cannot be applied in-place, and thus caused the change to be a
+	// Replace. It is empty for all other actions.
+	ForcedReplace PathSet
+
+	// ReplaceReason is the reason that the change is a Replace, and is
+	// ReplaceReasonNone for all other actions. A change that is replaced
+	// because it was requested or because the object is tainted may have
+	// no paths in ForcedReplace at all, and may even have identical old and
+	// new values.
+	ReplaceReason ReplaceReason
+
+	// ForcedReplaceReasons records the reasons for any of the paths in
+	// ForcedReplace that force replacement for a reason other than
+	// ReplaceBecauseCannotUpdate. Use ReplaceReasonFor to find the reason
+	// for a particular path.
+	ForcedReplaceReasons PathReasons
+
+	// Sensitive is the set of paths within the old and new values whose
+	// values are sensitive and so should not be displayed or otherwise
+	// revealed.
+	Sensitive PathSet
+
+	// WriteOnly is the set of paths within the value that are write-only,
+	// meaning that they are never persisted and so are always null in the
+	// old value. Differences at these paths are not considered to be
+	// changes.
+	WriteOnly PathSet
+
+	// Computed is the set of paths within the new value whose values will
+	// be decided by the provider during apply. A null value at one of these
+	// paths is rendered as unknown, since it is only null because it has
+	// not been computed yet.
+	Computed PathSet
+
+	// UnknownSources records the references that the unknown values at
+	// paths within the new value were derived from, where those are known.
+	UnknownSources PathSources
+}
+
+// AnnotateComputed returns a copy of the receiver that records the given
+// paths as being computed by the provider, so that null values at those
+// paths in the new value are rendered as "(known after apply)" rather than
+// as null.
+func (c *Change) AnnotateComputed(computed PathSet) *Change {
+	ret := *c
+	ret.Computed = computed
+	return &ret
+}
+
+// NewNoOp constructs a Change representing that the given value will remain
+// unchanged.
+func NewNoOp(ty cty.Type, val cty.Value) *Change {
+	mustConform(ty, val)
+	return &Change{
+		Action: NoOp,
+		Type:   ty,
+		Old:    val,
+		New:    val,
+	}
+}
+
+// NewCreate constructs a Change representing the creation of the given
+// new value.
+func NewCreate(ty cty.Type, new cty.Value) *Change {
+	mustConform(ty, new)
+	return &Change{
+		Action: Create,
+		Type:   ty,
+		Old:    cty.NullVal(ty),
+		New:    new,
+	}
+}
+
+// NewRead constructs a Change representing that the given new value will be
+// read from its source, replacing the given old value.
+func NewRead(ty cty.Type, old, new cty.Value) *Change {
+	mustConform(ty, old)
+	mustConform(ty, new)
+	return &Change{
+		Action: Read,
+		Type:   ty,
+		Old:    old,
+		New:    new,
+	}
+}
+
+// NewImport constructs a Change representing that the given existing object
+// will be imported, which is a read of its value from nothing. Importing an
+// object only adds it to the state, so no infrastructure is changed.
+func NewImport(ty cty.Type, imported cty.Value) *Change {
+	return NewRead(ty, cty.NullVal(ty), imported)
+}
+
+// NewUpdate constructs a Change representing an in-place update from the
+// given old value to the given new value.
+func NewUpdate(ty cty.Type, old, new cty.Value) *Change {
+	mustConform(ty, old)
+	mustConform(ty, new)
+	return &Change{
+		Action: Update,
+		Type:   ty,
+		Old:    old,
+		New:    new,
+	}
+}
+
+// NewDelete constructs a Change representing the deletion of the given old
+// value.
+func NewDelete(ty cty.Type, old cty.Value) *Change {
+	mustConform(ty, old)
+	return &Change{
+		Action: Delete,
+		Type:   ty,
+		Old:    old,
+		New:    cty.NullVal(ty),
+	}
+}
+
+// NewForget constructs a Change representing that the given old value will
+// no longer be tracked, without being destroyed.
+func NewForget(ty cty.Type, old cty.Value) *Change {
+	mustConform(ty, old)
+	return &Change{
+		Action: Forget,
+		Type:   ty,
+		Old:    old,
+		New:    cty.NullVal(ty),
+	}
+}
+
+// NewReplace constructs a Change representing that the given old value will
+// be destroyed and the given new value created in its place.
+//
+// forcedReplace is the set of paths whose changes required the replacement.
+func NewReplace(ty cty.Type, old, new cty.Value, forcedReplace PathSet) *Change {
+	mustConform(ty, old)
+	mustConform(ty, new)
+	return &Change{
+		Action:        Replace,
+		Type:          ty,
+		Old:           old,
+		New:           new,
+		ForcedReplace: forcedReplace,
+		ReplaceReason: ReplaceBecauseCannotUpdate,
+	}
+}
+
+// NewRequestedReplace constructs a Change representing that the given old
+// value will be destroyed and the given new value created in its place
+// because the user requested it, regardless of whether the values differ.
+func NewRequestedReplace(ty cty.Type, old, new cty.Value) *Change {
+	return NewReplace(ty, old, new, NewPathSet()).WithReplaceReason(ReplaceByRequest)
+}
+
+// WithReplaceReason returns a copy of the receiver, which must be a Replace
+// change, that records the given reason for the replacement.
+func (c *Change) WithReplaceReason(reason ReplaceReason) *Change {
+	if c.Action != Replace {
+		panic(fmt.Sprintf("cannot set replace reason on %s change", c.Action))
+	}
+	ret := *c
+	ret.ReplaceReason = reason
+	return &ret
+}
+
+// Merge combines the receiver with a second change that follows it, producing
+// a single change that has the net effect of both.
+//
+// The two changes must have the same type, and the old value of the second
+// change must be identical to the new value of the receiver. An error is
+// returned if either of these is not true.
+//
+// A Forget change can be combined only with a NoOp change, since the
+// effect of forgetting a value after otherwise changing it, or of changing
+// a value that was forgotten, cannot be described by a single change.
+func (c *Change) Merge(next *Change) (*Change, error) {
+	if !c.Type.Equals(next.Type) {
+		return nil, fmt.Errorf(
+			"cannot merge change of type %s with change of type %s",
+			c.Type.FriendlyName(), next.Type.FriendlyName(),
+		)
+	}
+	if !c.New.RawEquals(next.Old) {
+		return nil, fmt.Errorf("old value of subsequent change does not match new value of prior change")
+	}
+
+	if c.Action == Forget || next.Action == Forget {
+		switch {
+		case c.Action == NoOp:
+			return next, nil
+		case next.Action == NoOp:
+			return c, nil
+		default:
+			return nil, fmt.Errorf("cannot merge %s change with %s change", c.Action, next.Action)
+		}
+	}
+
+	old, new := c.Old, next.New
+	ret := &Change{
+		Type:      c.Type,
+		Old:       old,
+		New:       new,
+		Sensitive: c.Sensitive.Union(next.Sensitive),
+
+		// Only the new value of the second change remains, and with it
+		// only its unknown values.
+		UnknownSources: next.UnknownSources,
+	}
+
+	reason, nextReason := c.replaceReason(), next.replaceReason()
+	switch {
+	case (reason.unconditional() || nextReason.unconditional()) && !old.IsNull() && !new.IsNull():
+		// A requested or tainted replacement stands even if the values are
+		// equal.
+		ret.Action = Replace
+		ret.ForcedReplace = c.ForcedReplace.Union(next.ForcedReplace)
+		ret.ForcedReplaceReasons = c.ForcedReplaceReasons.Union(next.ForcedReplaceReasons)
+		ret.ReplaceReason = reason
+		if !reason.unconditional() {
+			ret.ReplaceReason = nextReason
+		}
+	case old.RawEquals(new):
+		ret.Action = NoOp
+	case old.IsNull():
+		ret.Action = Create
+	case new.IsNull():
+		ret.Action = Delete
+	case c.Action == Replace || next.Action == Replace:
+		ret.Action = Replace
+		ret.ForcedReplace = c.ForcedReplace.Union(next.ForcedReplace)
+		ret.ForcedReplaceReasons = c.ForcedReplaceReasons.Union(next.ForcedReplaceReasons)
+		ret.ReplaceReason = ret.forcedReplaceReason()
+	case c.Action == Read && next.Action == Read:
+		ret.Action = Read
+	default:
+		ret.Action = Update
+	}
+
+	return ret, nil
+}
+
+// Coalesce combines a sequence of changes to the same value into a single
+// change that has the net effect of all of them, as if by calling Merge
+// repeatedly.
+//
+// All of the given changes must have the same type, and the old value of
+// each change must be identical to the new value of the change before it.
+// An error is returned if the chain is broken at any point. At least one
+// change must be given.
+func Coalesce(changes ...*Change) (*Change, error) {
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("no changes to coalesce")
+	}
+
+	ret := changes[0]
+	for i, next := range changes[1:] {
+		merged, err := ret.Merge(next)
+		if err != nil {
+			return nil, fmt.Errorf("change %d does not follow from change %d: %s", i+1, i, err)
+		}
+		ret = merged
+	}
+	return ret, nil
+}
+
+// mustConform panics if the given value does not conform to the given type.
+//
+// The constructor functions use this to catch incorrect usage early, since
+// a non-conforming change is a bug in the caller.
+func mustConform(ty cty.Type, val cty.Value) {
+	if errs := val.Type().TestConformance(ty); len(errs) > 0 {
+		panic(fmt.Sprintf("value of type %s does not conform to %s", val.Type().FriendlyName(), ty.FriendlyName()))
+	}
+}
@@ -0,0 +1,92 @@
+package diffs
+
+import (
+	"fmt"
+	"iter"
+	"sort"
+)
+
+// ChangeSet is a collection of changes, each keyed by a string that
+// identifies the value it applies to, such as a resource address.
+type ChangeSet map[string]*Change
+
+// OmitNoOp returns a new ChangeSet containing only the changes from the
+// receiver whose action is not NoOp.
+func (cs ChangeSet) OmitNoOp() ChangeSet {
+	ret := make(ChangeSet, len(cs))
+	for k, c := range cs {
+		if c.Action != NoOp {
+			ret[k] = c
+		}
+	}
+	return ret
+}
+
+// Keys returns the keys of the changes in the set, in lexical order.
+func (cs ChangeSet) Keys() []string {
+	ret := make([]string, 0, len(cs))
+	for k := range cs {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// All returns a sequence of the changes in the set and their keys, in
+// lexical order by key.
+func (cs ChangeSet) All() iter.Seq2[string, *Change] {
+	return func(yield func(string, *Change) bool) {
+		for _, k := range cs.Keys() {
+			if !yield(k, cs[k]) {
+				return
+			}
+		}
+	}
+}
+
+// Stats returns the number of changes in the set of each kind.
+func (cs ChangeSet) Stats() Stats {
+	var ret Stats
+	for _, c := range cs {
+		ret.count(c)
+	}
+	return ret
+}
+
+// Stats gives summary counts for the changes in a ChangeSet, in the terms
+// used by Terraform's plan summary. A Replace change counts as both an
+// addition and a destruction, and NoOp and Read changes are not counted.
+type Stats struct {
+	ToAdd, ToChange, ToDestroy, ToForget int
+}
+
+func (s *Stats) count(c *Change) {
+	switch c.Action {
+	case Create:
+		s.ToAdd++
+	case Update:
+		s.ToChange++
+	case Replace:
+		s.ToAdd++
+		s.ToDestroy++
+	case Delete:
+		s.ToDestroy++
+	case Forget:
+		s.ToForget++
+	}
+}
+
+// String returns a one-line summary of the counts, such as
+// "Plan: 1 to add, 0 to change, 2 to destroy.", or "No changes." if all of
+// the counts are zero. The count of forgotten values is included only if
+// it is not zero.
+func (s Stats) String() string {
+	switch {
+	case s == Stats{}:
+		return "No changes."
+	case s.ToForget > 0:
+		return fmt.Sprintf("Plan: %d to add, %d to change, %d to destroy, %d to forget.", s.ToAdd, s.ToChange, s.ToDestroy, s.ToForget)
+	default:
+		return fmt.Sprintf("Plan: %d to add, %d to change, %d to destroy.", s.ToAdd, s.ToChange, s.ToDestroy)
+	}
+}
@@ -0,0 +1,50 @@
+package diffs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestChangeSet(t *testing.T) {
+	ty := cty.String
+	cs := ChangeSet{
+		"c.forgotten": NewForget(ty, cty.StringVal("a")),
+		"a.created":   NewCreate(ty, cty.StringVal("a")),
+		"b.replaced":  NewReplace(ty, cty.StringVal("a"), cty.StringVal("b"), NewPathSet(cty.Path{})),
+		"d.unchanged": NewNoOp(ty, cty.StringVal("a")),
+		"e.updated":   NewUpdate(ty, cty.StringVal("a"), cty.StringVal("b")),
+	}
+
+	wantKeys := []string{"a.created", "b.replaced", "c.forgotten", "d.unchanged", "e.updated"}
+	if got := cs.Keys(); !reflect.DeepEqual(got, wantKeys) {
+		t.Errorf("wrong keys\ngot:  %#v\nwant: %#v", got, wantKeys)
+	}
+
+	var gotKeys []string
+	for k, c := range cs.All() {
+		if c != cs[k] {
+			t.Errorf("wrong change for %s", k)
+		}
+		gotKeys = append(gotKeys, k)
+	}
+	if !reflect.DeepEqual(gotKeys, wantKeys) {
+		t.Errorf("wrong iteration order\ngot:  %#v\nwant: %#v", gotKeys, wantKeys)
+	}
+
+	gotStats := cs.Stats()
+	wantStats := Stats{ToAdd: 2, ToChange: 1, ToDestroy: 1, ToForget: 1}
+	if gotStats != wantStats {
+		t.Errorf("wrong stats\ngot:  %#v\nwant: %#v", gotStats, wantStats)
+	}
+	if got, want := gotStats.String(), "Plan: 2 to add, 1 to change, 1 to destroy, 1 to forget."; got != want {
+		t.Errorf("wrong summary\ngot:  %s\nwant: %s", got, want)
+	}
+	if got, want := cs.OmitNoOp().Stats(), wantStats; got != want {
+		t.Errorf("wrong stats after OmitNoOp\ngot:  %#v\nwant: %#v", got, want)
+	}
+	if got, want := (ChangeSet{}).Stats().String(), "No changes."; got != want {
+		t.Errorf("wrong summary for empty set\ngot:  %s\nwant: %s", got, want)
+	}
+}
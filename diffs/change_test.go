@@ -0,0 +1,180 @@
+package diffs
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestCoalesce(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"name": cty.String,
+		"size": cty.Number,
+	})
+	v1 := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("a"),
+		"size": cty.NumberIntVal(1),
+	})
+	v2 := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("b"),
+		"size": cty.NumberIntVal(1),
+	})
+	v3 := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("b"),
+		"size": cty.NumberIntVal(2),
+	})
+
+	tests := map[string]struct {
+		Changes    []*Change
+		WantAction Action
+		WantOld    cty.Value
+		WantNew    cty.Value
+		WantErr    bool
+	}{
+		"three chained updates": {
+			[]*Change{
+				NewUpdate(ty, v1, v2),
+				NewUpdate(ty, v2, v3),
+				NewUpdate(ty, v3, v2),
+			},
+			Update,
+			v1,
+			v2,
+			false,
+		},
+		"create then updates": {
+			[]*Change{
+				NewCreate(ty, v1),
+				NewUpdate(ty, v1, v2),
+				NewUpdate(ty, v2, v3),
+			},
+			Create,
+			cty.NullVal(ty),
+			v3,
+			false,
+		},
+		"tainted replace then update back": {
+			[]*Change{
+				NewReplace(ty, v1, v2, NewPathSet()).WithReplaceReason(ReplaceBecauseTainted),
+				NewUpdate(ty, v2, v1),
+			},
+			Replace,
+			v1,
+			v1,
+			false,
+		},
+		"no-op then forget": {
+			[]*Change{
+				NewNoOp(ty, v1),
+				NewForget(ty, v1),
+			},
+			Forget,
+			v1,
+			cty.NullVal(ty),
+			false,
+		},
+		"update then forget": {
+			[]*Change{
+				NewUpdate(ty, v1, v2),
+				NewForget(ty, v2),
+			},
+			NoOp,
+			cty.NilVal,
+			cty.NilVal,
+			true,
+		},
+		"updates then delete": {
+			[]*Change{
+				NewUpdate(ty, v1, v2),
+				NewUpdate(ty, v2, v3),
+				NewDelete(ty, v3),
+			},
+			Delete,
+			v1,
+			cty.NullVal(ty),
+			false,
+		},
+		"back to where we started": {
+			[]*Change{
+				NewUpdate(ty, v1, v2),
+				NewUpdate(ty, v2, v3),
+				NewUpdate(ty, v3, v1),
+			},
+			NoOp,
+			v1,
+			v1,
+			false,
+		},
+		"replace in the chain": {
+			[]*Change{
+				NewUpdate(ty, v1, v2),
+				NewReplace(ty, v2, v3, NewPathSet(cty.Path{cty.GetAttrStep{Name: "size"}})),
+			},
+			Replace,
+			v1,
+			v3,
+			false,
+		},
+		"single change": {
+			[]*Change{
+				NewUpdate(ty, v1, v2),
+			},
+			Update,
+			v1,
+			v2,
+			false,
+		},
+		"broken chain": {
+			[]*Change{
+				NewUpdate(ty, v1, v2),
+				NewUpdate(ty, v1, v3),
+			},
+			NoOp,
+			cty.NilVal,
+			cty.NilVal,
+			true,
+		},
+		"type mismatch": {
+			[]*Change{
+				NewUpdate(ty, v1, v2),
+				NewCreate(cty.String, cty.StringVal("b")),
+			},
+			NoOp,
+			cty.NilVal,
+			cty.NilVal,
+			true,
+		},
+		"no changes": {
+			nil,
+			NoOp,
+			cty.NilVal,
+			cty.NilVal,
+			true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := Coalesce(test.Changes...)
+			if test.WantErr {
+				if err == nil {
+					t.Fatalf("succeeded; want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if got.Action != test.WantAction {
+				t.Errorf("wrong action %s; want %s", got.Action, test.WantAction)
+			}
+			if !got.Old.RawEquals(test.WantOld) {
+				t.Errorf("wrong old value\ngot:  %#v\nwant: %#v", got.Old, test.WantOld)
+			}
+			if !got.New.RawEquals(test.WantNew) {
+				t.Errorf("wrong new value\ngot:  %#v\nwant: %#v", got.New, test.WantNew)
+			}
+		})
+	}
+}
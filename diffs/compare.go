@@ -0,0 +1,163 @@
+package diffs
+
+import (
+	"sort"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// DeltaKind describes how a change in one ChangeSet, or a leaf change within
+// it, differs from the corresponding one in another ChangeSet.
+type DeltaKind rune
+
+const (
+	// DeltaAdded is for a change that is only in the second set.
+	DeltaAdded DeltaKind = '+'
+
+	// DeltaRemoved is for a change that is only in the first set.
+	DeltaRemoved DeltaKind = '-'
+
+	// DeltaAltered is for a change that is in both sets, but differs.
+	DeltaAltered DeltaKind = '~'
+)
+
+// ChangeDelta describes how the changes with the same key in two change
+// sets differ, as returned by CompareChangeSets.
+type ChangeDelta struct {
+	Key  string
+	Kind DeltaKind
+
+	// OldAction and NewAction are the actions of the change in the first
+	// and second set. An action is NoOp if the set has no change with the
+	// key.
+	OldAction, NewAction Action
+
+	// Paths are the leaf changes that differ, for an altered change. A leaf
+	// change that is only made by the second change is added, one that is
+	// only made by the first is removed, and one that both make, but with
+	// different values or actions, is altered. They're in a stable order.
+	Paths []PathDelta
+}
+
+// PathDelta describes how a leaf change within a change differs from the
+// one at the same path within another change.
+type PathDelta struct {
+	Path cty.Path
+	Kind DeltaKind
+}
+
+// String returns the path in a syntax similar to Terraform's traversal
+// syntax, such as tags["Name"].
+func (d PathDelta) String() string {
+	return formatPath(d.Path)
+}
+
+// CompareChangeSets compares the changes in the given sets, such as the
+// changes of two plans of the same configuration, and returns the ones that
+// differ in order of their keys. NoOp changes are treated as if they
+// weren't in their set.
+//
+// Changes are compared leaf by leaf in the same way as WalkChanges, so two
+// changes that make the same alterations to the same values are the same,
+// even if they differ in parts that they don't change. Values are compared
+// without redaction, but aren't included in the result.
+func CompareChangeSets(a, b ChangeSet) []*ChangeDelta {
+	keys := make(map[string]bool, len(a)+len(b))
+	for k := range a.OmitNoOp() {
+		keys[k] = true
+	}
+	for k := range b.OmitNoOp() {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var ret []*ChangeDelta
+	for _, k := range sorted {
+		oldC, newC := a[k], b[k]
+		d := &ChangeDelta{
+			Key:       k,
+			OldAction: changeAction(oldC),
+			NewAction: changeAction(newC),
+		}
+		switch {
+		case d.OldAction == NoOp:
+			d.Kind = DeltaAdded
+		case d.NewAction == NoOp:
+			d.Kind = DeltaRemoved
+		default:
+			d.Paths = compareLeaves(oldC, newC)
+			if d.OldAction == d.NewAction && len(d.Paths) == 0 {
+				continue
+			}
+			d.Kind = DeltaAltered
+		}
+		ret = append(ret, d)
+	}
+	return ret
+}
+
+func changeAction(c *Change) Action {
+	if c == nil {
+		return NoOp
+	}
+	return c.Action
+}
+
+// leafChange is a leaf change visited by walkLeaves.
+type leafChange struct {
+	path     cty.Path
+	old, new string
+	action   Action
+}
+
+// compareLeaves returns the leaf changes that differ between the two given
+// changes, in order of their path keys.
+func compareLeaves(a, b *Change) []PathDelta {
+	oldLeaves := changeLeaves(a)
+	newLeaves := changeLeaves(b)
+
+	keys := make([]string, 0, len(oldLeaves)+len(newLeaves))
+	for k := range oldLeaves {
+		keys = append(keys, k)
+	}
+	for k := range newLeaves {
+		if _, exists := oldLeaves[k]; !exists {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var ret []PathDelta
+	for _, k := range keys {
+		oldL, inOld := oldLeaves[k]
+		newL, inNew := newLeaves[k]
+		switch {
+		case !inOld:
+			ret = append(ret, PathDelta{Path: newL.path, Kind: DeltaAdded})
+		case !inNew:
+			ret = append(ret, PathDelta{Path: oldL.path, Kind: DeltaRemoved})
+		case oldL.old != newL.old || oldL.new != newL.new || oldL.action != newL.action:
+			ret = append(ret, PathDelta{Path: newL.path, Kind: DeltaAltered})
+		}
+	}
+	return ret
+}
+
+// changeLeaves returns the leaf changes of the given change, keyed by
+// pathKey of their paths.
+func changeLeaves(c *Change) map[string]leafChange {
+	ret := make(map[string]leafChange)
+	c.walkLeaves(func(path cty.Path, old, new cty.Value, action Action) {
+		ret[pathKey(path)] = leafChange{
+			path:   path,
+			old:    valueKey(old),
+			new:    valueKey(new),
+			action: action,
+		}
+	})
+	return ret
+}
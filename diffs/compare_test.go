@@ -0,0 +1,82 @@
+package diffs
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestCompareChangeSets(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"ami":  cty.String,
+		"name": cty.String,
+		"tags": cty.Map(cty.String),
+	})
+	obj := func(ami, name string, tags map[string]string) cty.Value {
+		tagVals := cty.MapValEmpty(cty.String)
+		if len(tags) > 0 {
+			m := make(map[string]cty.Value, len(tags))
+			for k, v := range tags {
+				m[k] = cty.StringVal(v)
+			}
+			tagVals = cty.MapVal(m)
+		}
+		return cty.ObjectVal(map[string]cty.Value{
+			"ami":  cty.StringVal(ami),
+			"name": cty.StringVal(name),
+			"tags": tagVals,
+		})
+	}
+
+	a := ChangeSet{
+		"aws_instance.same": NewUpdate(ty, obj("a", "x", nil), obj("b", "x", nil)),
+		"aws_instance.unrelated": NewUpdate(ty,
+			obj("a", "x", nil),
+			obj("b", "x", nil)),
+		"aws_instance.altered": NewUpdate(ty,
+			obj("a", "x", map[string]string{"env": "prod"}),
+			obj("b", "x", map[string]string{"env": "staging"})),
+		"aws_instance.removed": NewDelete(ty, obj("a", "x", nil)),
+		"aws_instance.noop":    NewNoOp(ty, obj("a", "x", nil)),
+		"aws_instance.replace": NewUpdate(ty, obj("a", "x", nil), obj("a", "y", nil)),
+	}
+	b := ChangeSet{
+		// The same alterations, with another value that isn't changed.
+		"aws_instance.same": NewUpdate(ty, obj("a", "y", nil), obj("b", "y", nil)),
+		"aws_instance.unrelated": NewUpdate(ty,
+			obj("a", "x", nil),
+			obj("b", "x", nil)),
+		"aws_instance.altered": NewUpdate(ty,
+			obj("a", "x", map[string]string{"env": "prod"}),
+			obj("c", "y", map[string]string{"owner": "me"})),
+		"aws_instance.added": NewCreate(ty, obj("a", "x", nil)),
+		"aws_instance.replace": NewReplace(ty, obj("a", "x", nil), obj("a", "y", nil),
+			NewPathSet(cty.Path{cty.GetAttrStep{Name: "name"}})),
+	}
+
+	got := CompareChangeSets(a, b)
+
+	var gotDesc []string
+	for _, d := range got {
+		desc := fmt.Sprintf("%c %s %s->%s", d.Kind, d.Key, d.OldAction, d.NewAction)
+		for _, p := range d.Paths {
+			desc += fmt.Sprintf(" %c%s", p.Kind, p)
+		}
+		gotDesc = append(gotDesc, desc)
+	}
+	want := []string{
+		`+ aws_instance.added NoOp->Create`,
+		`~ aws_instance.altered Update->Update ~ami +name ~tags["env"] +tags["owner"]`,
+		`- aws_instance.removed Delete->NoOp`,
+		`~ aws_instance.replace Update->Replace`,
+	}
+	if !reflect.DeepEqual(gotDesc, want) {
+		t.Errorf("wrong result\ngot:  %#v\nwant: %#v", gotDesc, want)
+	}
+
+	if got := CompareChangeSets(a, a); len(got) != 0 {
+		t.Errorf("set differs from itself: %#v", got)
+	}
+}
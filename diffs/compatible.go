@@ -0,0 +1,158 @@
+package diffs
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// AssertObjectCompatible checks whether the given actual value, produced
+// when the change was applied, is consistent with the new value that was
+// planned. It returns error diagnostics describing each path at which the
+// actual value diverges from the plan.
+//
+// The actual value may replace unknown values in the plan with any known
+// value of the appropriate type, but must otherwise match the plan
+// exactly. Because set elements have no identity other than their values,
+// a planned set containing unknown values may be satisfied by any set.
+// Differences within the change's WriteOnly paths are ignored, since those
+// values are never stored.
+func (c *Change) AssertObjectCompatible(actual cty.Value) tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+	if errs := actual.Type().TestConformance(c.Type); len(errs) > 0 {
+		return diags.Append(inconsistentResult(
+			"The provider produced a value that does not conform to %s: %s.",
+			c.Type.FriendlyName(), errs[0],
+		))
+	}
+	c.assertCompatible(nil, c.New, actual, &diags)
+	return diags
+}
+
+func (c *Change) assertCompatible(path cty.Path, planned, actual cty.Value, diags *tfdiags.Diagnostics) {
+	if pathSetCovers(c.WriteOnly, path) {
+		return
+	}
+	if !actual.IsKnown() || (!planned.IsKnown() || planned.Type().IsSetType()) && !whollyKnown(actual) {
+		// Nested unknowns are otherwise found as we recurse, so we only
+		// need to check the whole value where we won't recurse into it.
+		*diags = diags.Append(inconsistentResult(
+			"The provider left %s unknown after apply, but all values must be known once a change is applied.",
+			describePath(path),
+		))
+		return
+	}
+	if !planned.IsKnown() {
+		return
+	}
+	if planned.IsNull() || actual.IsNull() {
+		if planned.IsNull() != actual.IsNull() {
+			*diags = diags.Append(c.valueChanged(path, planned, actual))
+		}
+		return
+	}
+
+	ty := planned.Type()
+	switch {
+	case ty.IsObjectType():
+		atys := ty.AttributeTypes()
+		names := make([]string, 0, len(atys))
+		for name := range atys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			c.assertCompatible(path.GetAttr(name), planned.GetAttr(name), actual.GetAttr(name), diags)
+		}
+	case ty.IsMapType():
+		plannedElems := mapElements(planned)
+		actualElems := mapElements(actual)
+		keys := make([]string, 0, len(plannedElems))
+		for k := range plannedElems {
+			keys = append(keys, k)
+		}
+		for k := range actualElems {
+			if _, exists := plannedElems[k]; !exists {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ep := path.Index(cty.StringVal(k))
+			pv, inPlanned := plannedElems[k]
+			av, inActual := actualElems[k]
+			switch {
+			case !inPlanned:
+				*diags = diags.Append(inconsistentResult("The provider added %s, which was not planned.", describePath(ep)))
+			case !inActual:
+				*diags = diags.Append(inconsistentResult("The provider removed %s, which was planned.", describePath(ep)))
+			default:
+				c.assertCompatible(ep, pv, av, diags)
+			}
+		}
+	case ty.IsListType() || ty.IsTupleType():
+		plannedElems := sequenceElements(planned)
+		actualElems := sequenceElements(actual)
+		if len(plannedElems) != len(actualElems) {
+			*diags = diags.Append(inconsistentResult(
+				"The provider produced %d elements for %s, but %d were planned.",
+				len(actualElems), describePath(path), len(plannedElems),
+			))
+			return
+		}
+		for i := range plannedElems {
+			c.assertCompatible(path.Index(cty.NumberIntVal(int64(i))), plannedElems[i], actualElems[i], diags)
+		}
+	case ty.IsSetType():
+		if whollyKnown(planned) && !planned.RawEquals(actual) {
+			*diags = diags.Append(c.valueChanged(path, planned, actual))
+		}
+	default:
+		if !planned.RawEquals(actual) {
+			*diags = diags.Append(c.valueChanged(path, planned, actual))
+		}
+	}
+}
+
+func (c *Change) valueChanged(path cty.Path, planned, actual cty.Value) *hcl.Diagnostic {
+	if pathSetCovers(c.Sensitive, path) {
+		return inconsistentResult(
+			"The provider produced an unexpected new value for %s, which is sensitive.",
+			describePath(path),
+		)
+	}
+	return inconsistentResult(
+		"The provider produced an unexpected new value for %s: it was planned to be %s, but is now %s.",
+		describePath(path), describeValue(planned), describeValue(actual),
+	)
+}
+
+func inconsistentResult(detail string, args ...interface{}) *hcl.Diagnostic {
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Provider produced inconsistent result after apply",
+		Detail: fmt.Sprintf(detail, args...) +
+			"\n\nThis is a bug in the provider, which should be reported in the provider's own issue tracker.",
+	}
+}
+
+func describePath(path cty.Path) string {
+	if len(path) == 0 {
+		return "the object"
+	}
+	return formatPath(path)
+}
+
+// describeValue returns a short description of the given value for use in
+// messages, showing primitive values in full.
+func describeValue(v cty.Value) string {
+	ty := v.Type()
+	if v.IsKnown() && !v.IsNull() && !ty.IsPrimitiveType() {
+		return "a value of type " + ty.FriendlyName()
+	}
+	r := &renderer{}
+	return r.leafText(DefaultClassify(nil, v), v)
+}
@@ -0,0 +1,106 @@
+package diffs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestChangeAssertObjectCompatible(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"id":       cty.String,
+		"password": cty.String,
+		"ports":    cty.List(cty.Number),
+		"tags":     cty.Map(cty.String),
+		"zones":    cty.Set(cty.String),
+	})
+	planned := cty.ObjectVal(map[string]cty.Value{
+		"id":       cty.UnknownVal(cty.String),
+		"password": cty.StringVal("hunter2"),
+		"ports":    cty.ListVal([]cty.Value{cty.NumberIntVal(80)}),
+		"tags":     cty.MapVal(map[string]cty.Value{"env": cty.StringVal("prod")}),
+		"zones":    cty.SetVal([]cty.Value{cty.StringVal("a"), cty.UnknownVal(cty.String)}),
+	})
+	change := NewCreate(ty, planned)
+	change.Sensitive = NewPathSet(cty.Path{cty.GetAttrStep{Name: "password"}})
+
+	actual := func(attrs map[string]cty.Value) cty.Value {
+		ret := map[string]cty.Value{
+			"id":       cty.StringVal("i-abc123"),
+			"password": cty.StringVal("hunter2"),
+			"ports":    cty.ListVal([]cty.Value{cty.NumberIntVal(80)}),
+			"tags":     cty.MapVal(map[string]cty.Value{"env": cty.StringVal("prod")}),
+			"zones":    cty.SetVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+		}
+		for k, v := range attrs {
+			ret[k] = v
+		}
+		return cty.ObjectVal(ret)
+	}
+
+	tests := map[string]struct {
+		Actual cty.Value
+		Want   []string
+	}{
+		"refines unknowns": {
+			actual(nil),
+			nil,
+		},
+		"known value changed": {
+			actual(map[string]cty.Value{
+				"ports": cty.ListVal([]cty.Value{cty.NumberIntVal(8080)}),
+			}),
+			[]string{`ports[0]: it was planned to be 80, but is now 8080`},
+		},
+		"map element added": {
+			actual(map[string]cty.Value{
+				"tags": cty.MapVal(map[string]cty.Value{
+					"env":  cty.StringVal("prod"),
+					"team": cty.StringVal("a"),
+				}),
+			}),
+			[]string{`added tags["team"]`},
+		},
+		"list length changed": {
+			actual(map[string]cty.Value{
+				"ports": cty.ListValEmpty(cty.Number),
+			}),
+			[]string{`0 elements for ports, but 1 were planned`},
+		},
+		"sensitive value changed": {
+			actual(map[string]cty.Value{
+				"password": cty.StringVal("hunter3"),
+			}),
+			[]string{`password, which is sensitive`},
+		},
+		"left unknown": {
+			actual(map[string]cty.Value{
+				"id": cty.UnknownVal(cty.String),
+			}),
+			[]string{`left id unknown`},
+		},
+		"wrong type": {
+			cty.StringVal("nope"),
+			[]string{`does not conform`},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			diags := change.AssertObjectCompatible(test.Actual)
+			if len(diags) != len(test.Want) {
+				t.Fatalf("wrong number of diagnostics %d; want %d\n%s", len(diags), len(test.Want), diags.Err())
+			}
+			for i, want := range test.Want {
+				detail := diags[i].Description().Detail
+				if !strings.Contains(detail, want) {
+					t.Errorf("diagnostic %d does not contain %q\n%s", i, want, detail)
+				}
+				if strings.Contains(detail, "hunter") {
+					t.Errorf("diagnostic %d reveals a sensitive value\n%s", i, detail)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package diffs
+
+import (
+	"fmt"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// DiffConvert is like Diff except that the given old and new values are
+// first converted to the given type, returning an error if either value
+// cannot be converted.
+//
+// This is useful when the type of a value has changed between the old and
+// new values, such as when a schema is migrated. In addition to the
+// conversions supported by the cty convert package, DiffConvert can convert
+// between lists and tuples in either direction, including when they are
+// nested inside other structures, as long as the lengths and element types
+// are compatible.
+func DiffConvert(ty cty.Type, old, new cty.Value, opts DiffOpts) (*Change, error) {
+	old, err := convertValue(nil, old, ty)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert old value: %s", err)
+	}
+	new, err = convertValue(nil, new, ty)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert new value: %s", err)
+	}
+	return Diff(ty, old, new, opts), nil
+}
+
+func convertValue(path cty.Path, v cty.Value, ty cty.Type) (cty.Value, error) {
+	if v.Type().Equals(ty) {
+		return v, nil
+	}
+	if conv := convert.GetConversion(v.Type(), ty); conv != nil {
+		ret, err := conv(v)
+		if err != nil {
+			return cty.NilVal, pathError(path, err)
+		}
+		return ret, nil
+	}
+
+	// The remaining cases are structural conversions that the convert
+	// package doesn't support, which we handle element-by-element.
+	vty := v.Type()
+	switch {
+	case ty.IsTupleType() && (vty.IsListType() || vty.IsTupleType()):
+		if v.IsNull() {
+			return cty.NullVal(ty), nil
+		}
+		if !v.IsKnown() {
+			return cty.UnknownVal(ty), nil
+		}
+		elems := sequenceElements(v)
+		if len(elems) != ty.Length() {
+			return cty.NilVal, pathError(path, fmt.Errorf("tuple requires %d elements, but value has %d", ty.Length(), len(elems)))
+		}
+		if len(elems) == 0 {
+			return cty.EmptyTupleVal, nil
+		}
+		for i, ev := range elems {
+			var err error
+			elems[i], err = convertValue(path.Index(cty.NumberIntVal(int64(i))), ev, ty.TupleElementType(i))
+			if err != nil {
+				return cty.NilVal, err
+			}
+		}
+		return cty.TupleVal(elems), nil
+	case ty.IsListType() && (vty.IsListType() || vty.IsTupleType()):
+		if v.IsNull() {
+			return cty.NullVal(ty), nil
+		}
+		if !v.IsKnown() {
+			return cty.UnknownVal(ty), nil
+		}
+		elems := sequenceElements(v)
+		if len(elems) == 0 {
+			return cty.ListValEmpty(ty.ElementType()), nil
+		}
+		for i, ev := range elems {
+			var err error
+			elems[i], err = convertValue(path.Index(cty.NumberIntVal(int64(i))), ev, ty.ElementType())
+			if err != nil {
+				return cty.NilVal, err
+			}
+		}
+		return cty.ListVal(elems), nil
+	case ty.IsObjectType() && vty.IsObjectType():
+		if v.IsNull() {
+			return cty.NullVal(ty), nil
+		}
+		if !v.IsKnown() {
+			return cty.UnknownVal(ty), nil
+		}
+		atys := ty.AttributeTypes()
+		for name := range vty.AttributeTypes() {
+			if _, exists := atys[name]; !exists {
+				return cty.NilVal, pathError(path, fmt.Errorf("unexpected attribute %q", name))
+			}
+		}
+		attrs := make(map[string]cty.Value, len(atys))
+		for name, aty := range atys {
+			if !vty.HasAttribute(name) {
+				return cty.NilVal, pathError(path, fmt.Errorf("missing attribute %q", name))
+			}
+			var err error
+			attrs[name], err = convertValue(path.GetAttr(name), v.GetAttr(name), aty)
+			if err != nil {
+				return cty.NilVal, err
+			}
+		}
+		return cty.ObjectVal(attrs), nil
+	}
+
+	return cty.NilVal, pathError(path, fmt.Errorf("cannot convert %s to %s", vty.FriendlyName(), ty.FriendlyName()))
+}
+
+func pathError(path cty.Path, err error) error {
+	if len(path) == 0 {
+		return err
+	}
+	return fmt.Errorf("at %s: %s", formatPath(path), err)
+}
@@ -0,0 +1,116 @@
+package diffs
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDiffConvert(t *testing.T) {
+	tupleTy := cty.Tuple([]cty.Type{cty.String, cty.String})
+
+	tests := map[string]struct {
+		Type       cty.Type
+		Old, New   cty.Value
+		WantAction Action
+		WantOld    cty.Value
+		WantNew    cty.Value
+		WantErr    bool
+	}{
+		"list to matching tuple": {
+			tupleTy,
+			cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("c")}),
+			Update,
+			cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("c")}),
+			false,
+		},
+		"list to identical tuple": {
+			tupleTy,
+			cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			NoOp,
+			cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			false,
+		},
+		"tuple to list": {
+			cty.List(cty.String),
+			cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			cty.ListVal([]cty.Value{cty.StringVal("a")}),
+			Update,
+			cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			cty.ListVal([]cty.Value{cty.StringVal("a")}),
+			false,
+		},
+		"nested list to tuple": {
+			cty.Object(map[string]cty.Type{"pair": tupleTy}),
+			cty.ObjectVal(map[string]cty.Value{
+				"pair": cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			}),
+			cty.ObjectVal(map[string]cty.Value{
+				"pair": cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			}),
+			NoOp,
+			cty.ObjectVal(map[string]cty.Value{
+				"pair": cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			}),
+			cty.ObjectVal(map[string]cty.Value{
+				"pair": cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			}),
+			false,
+		},
+		"element conversion": {
+			tupleTy,
+			cty.ListVal([]cty.Value{cty.NumberIntVal(1), cty.NumberIntVal(2)}),
+			cty.NullVal(tupleTy),
+			Delete,
+			cty.TupleVal([]cty.Value{cty.StringVal("1"), cty.StringVal("2")}),
+			cty.NullVal(tupleTy),
+			false,
+		},
+		"wrong length": {
+			tupleTy,
+			cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b"), cty.StringVal("c")}),
+			cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			NoOp,
+			cty.NilVal,
+			cty.NilVal,
+			true,
+		},
+		"incompatible element types": {
+			cty.Tuple([]cty.Type{cty.String, cty.Number}),
+			cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.NumberIntVal(1)}),
+			NoOp,
+			cty.NilVal,
+			cty.NilVal,
+			true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := DiffConvert(test.Type, test.Old, test.New, DiffOpts{})
+			if test.WantErr {
+				if err == nil {
+					t.Fatalf("succeeded; want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got.Action != test.WantAction {
+				t.Errorf("wrong action %s; want %s", got.Action, test.WantAction)
+			}
+			if !got.Old.RawEquals(test.WantOld) {
+				t.Errorf("wrong old value\ngot:  %#v\nwant: %#v", got.Old, test.WantOld)
+			}
+			if !got.New.RawEquals(test.WantNew) {
+				t.Errorf("wrong new value\ngot:  %#v\nwant: %#v", got.New, test.WantNew)
+			}
+		})
+	}
+}
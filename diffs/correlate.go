@@ -0,0 +1,187 @@
+package diffs
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ElementPair is an element of the old value of a set paired with the
+// element of the new value that it corresponds to. Old is null for an
+// added element and New is null for a removed element.
+type ElementPair struct {
+	Old, New cty.Value
+}
+
+// CorrelateSetElements pairs the elements of the given old and new set
+// values, either of which may be null, so that an element whose value has
+// changed can be described as an update of its prior value rather than as
+// a removal and an addition.
+//
+// Elements that are in both sets are paired with themselves. Of the rest,
+// object elements are paired by the given identifying attributes if any
+// are given, and an old and new element that have the same non-null values
+// for all of them are paired, as long as no other element has the same
+// identity. Without identifying attributes, elements are paired by their
+// similarity instead, with each removed element paired with the added
+// element that has the most attributes equal to its own, as long as more
+// than half of them are. Elements that aren't paired are returned with a
+// null counterpart.
+//
+// The pairs are in order of the values of their new elements, or of their
+// old elements for those that were removed.
+func CorrelateSetElements(old, new cty.Value, keys []string) []ElementPair {
+	var ety cty.Type
+	switch {
+	case !old.IsNull():
+		ety = old.Type().ElementType()
+	case !new.IsNull():
+		ety = new.Type().ElementType()
+	default:
+		return nil
+	}
+
+	inNew := make(map[string]bool)
+	for _, v := range sequenceElements(new) {
+		inNew[indexKey(v)] = true
+	}
+
+	var ret, removed, added []ElementPair
+	inOld := make(map[string]bool)
+	for _, v := range sortedSetElements(old) {
+		k := indexKey(v)
+		inOld[k] = true
+		if inNew[k] {
+			ret = append(ret, ElementPair{Old: v, New: v})
+		} else {
+			removed = append(removed, ElementPair{Old: v, New: cty.NullVal(ety)})
+		}
+	}
+	for _, v := range sortedSetElements(new) {
+		if !inOld[indexKey(v)] {
+			added = append(added, ElementPair{Old: cty.NullVal(ety), New: v})
+		}
+	}
+
+	if ety.IsObjectType() && len(removed) > 0 && len(added) > 0 {
+		if len(keys) > 0 {
+			correlateByKeys(removed, added, keys)
+		} else {
+			correlateBySimilarity(removed, added, ety)
+		}
+	}
+
+	// Each correlated pair is recorded in both removed and added, and is
+	// kept only once, from added.
+	for _, p := range removed {
+		if p.New.IsNull() {
+			ret = append(ret, p)
+		}
+	}
+	ret = append(ret, added...)
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return pairSortKey(ret[i]) < pairSortKey(ret[j])
+	})
+	return ret
+}
+
+// correlateByKeys pairs the given removed and added object elements by the
+// values of the given attributes, setting the counterpart of each paired
+// element in both slices.
+func correlateByKeys(removed, added []ElementPair, keys []string) {
+	identity := func(v cty.Value) (string, bool) {
+		atys := v.Type().AttributeTypes()
+		parts := make([]string, len(keys))
+		for i, name := range keys {
+			if _, ok := atys[name]; !ok {
+				return "", false
+			}
+			av := v.GetAttr(name)
+			if av.IsNull() || !whollyKnown(av) {
+				return "", false
+			}
+			parts[i] = valueKey(av)
+		}
+		return strings.Join(parts, "\x00"), true
+	}
+
+	// Elements whose identity is shared with another element on the same
+	// side are ambiguous, so they are never paired.
+	index := func(pairs []ElementPair, elem func(ElementPair) cty.Value) map[string]int {
+		ret := make(map[string]int)
+		for i, p := range pairs {
+			id, ok := identity(elem(p))
+			if !ok {
+				continue
+			}
+			if _, exists := ret[id]; exists {
+				ret[id] = -1
+				continue
+			}
+			ret[id] = i
+		}
+		return ret
+	}
+	oldIDs := index(removed, func(p ElementPair) cty.Value { return p.Old })
+	newIDs := index(added, func(p ElementPair) cty.Value { return p.New })
+
+	for id, i := range oldIDs {
+		j, ok := newIDs[id]
+		if i < 0 || !ok || j < 0 {
+			continue
+		}
+		removed[i].New = added[j].New
+		added[j].Old = removed[i].Old
+	}
+}
+
+// correlateBySimilarity pairs the given removed and added object elements
+// by how many of their attributes are equal, setting the counterpart of
+// each paired element in both slices. The most similar pairs are chosen
+// first, and only elements with more than half of their attributes equal
+// are paired.
+func correlateBySimilarity(removed, added []ElementPair, ety cty.Type) {
+	n := len(ety.AttributeTypes())
+
+	type candidate struct {
+		i, j  int
+		score int
+	}
+	var candidates []candidate
+	for i, r := range removed {
+		for j, a := range added {
+			score := 0
+			for name := range ety.AttributeTypes() {
+				if r.Old.GetAttr(name).RawEquals(a.New.GetAttr(name)) {
+					score++
+				}
+			}
+			if score*2 > n {
+				candidates = append(candidates, candidate{i, j, score})
+			}
+		}
+	}
+
+	// Removed and added elements are each in order of their values, so
+	// ties are broken deterministically.
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].score > candidates[b].score
+	})
+
+	for _, c := range candidates {
+		if !removed[c.i].New.IsNull() || !added[c.j].Old.IsNull() {
+			continue
+		}
+		removed[c.i].New = added[c.j].New
+		added[c.j].Old = removed[c.i].Old
+	}
+}
+
+func pairSortKey(p ElementPair) string {
+	if !p.New.IsNull() {
+		return indexKey(p.New)
+	}
+	return indexKey(p.Old)
+}
@@ -0,0 +1,95 @@
+package diffs
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestCorrelateSetElements(t *testing.T) {
+	null := cty.NullVal(ruleTy)
+
+	tests := map[string]struct {
+		Old, New cty.Value
+		Keys     []string
+		Want     []ElementPair
+	}{
+		"unchanged": {
+			cty.SetVal([]cty.Value{testRule("ssh", 22, "10.0.0.0/8")}),
+			cty.SetVal([]cty.Value{testRule("ssh", 22, "10.0.0.0/8")}),
+			nil,
+			[]ElementPair{
+				{testRule("ssh", 22, "10.0.0.0/8"), testRule("ssh", 22, "10.0.0.0/8")},
+			},
+		},
+		"by key": {
+			cty.SetVal([]cty.Value{
+				testRule("http", 80, "0.0.0.0/0"),
+				testRule("ssh", 22, "10.0.0.0/8"),
+			}),
+			cty.SetVal([]cty.Value{
+				testRule("https", 443, "0.0.0.0/0"),
+				testRule("ssh", 2222, "10.1.0.0/16"),
+			}),
+			[]string{"name"},
+			[]ElementPair{
+				{testRule("http", 80, "0.0.0.0/0"), null},
+				{null, testRule("https", 443, "0.0.0.0/0")},
+				{testRule("ssh", 22, "10.0.0.0/8"), testRule("ssh", 2222, "10.1.0.0/16")},
+			},
+		},
+		"ambiguous key": {
+			cty.SetVal([]cty.Value{
+				testRule("ssh", 22, "10.0.0.0/8"),
+			}),
+			cty.SetVal([]cty.Value{
+				testRule("ssh", 22, "10.1.0.0/16"),
+				testRule("ssh", 22, "10.2.0.0/16"),
+			}),
+			[]string{"name"},
+			[]ElementPair{
+				{testRule("ssh", 22, "10.0.0.0/8"), null},
+				{null, testRule("ssh", 22, "10.1.0.0/16")},
+				{null, testRule("ssh", 22, "10.2.0.0/16")},
+			},
+		},
+		"by similarity": {
+			cty.SetVal([]cty.Value{
+				testRule("http", 80, "0.0.0.0/0"),
+				testRule("ssh", 22, "10.0.0.0/8"),
+			}),
+			cty.SetVal([]cty.Value{
+				testRule("https", 443, "0.0.0.0/0"),
+				testRule("ssh", 22, "10.1.0.0/16"),
+			}),
+			nil,
+			[]ElementPair{
+				{testRule("http", 80, "0.0.0.0/0"), null},
+				{null, testRule("https", 443, "0.0.0.0/0")},
+				{testRule("ssh", 22, "10.0.0.0/8"), testRule("ssh", 22, "10.1.0.0/16")},
+			},
+		},
+		"created": {
+			cty.NullVal(cty.Set(ruleTy)),
+			cty.SetVal([]cty.Value{testRule("ssh", 22, "10.0.0.0/8")}),
+			nil,
+			[]ElementPair{
+				{null, testRule("ssh", 22, "10.0.0.0/8")},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := CorrelateSetElements(test.Old, test.New, test.Keys)
+			if len(got) != len(test.Want) {
+				t.Fatalf("wrong number of pairs %d; want %d\n%#v", len(got), len(test.Want), got)
+			}
+			for i := range got {
+				if !got[i].Old.RawEquals(test.Want[i].Old) || !got[i].New.RawEquals(test.Want[i].New) {
+					t.Errorf("wrong pair %d\ngot:  %#v\nwant: %#v", i, got[i], test.Want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package diffs
+
+import (
+	"fmt"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// DiffOpts customizes the behavior of Diff.
+//
+// The zero value of DiffOpts selects the default behavior.
+type DiffOpts struct {
+	// RequiresReplace is the set of paths that cannot be updated in-place.
+	// If any of these paths, or any path nested within them, has changed
+	// then the result is a Replace change.
+	RequiresReplace PathSet
+
+	// WriteOnly is the set of paths that are write-only, and so are null
+	// in the old value by design. Differences within these paths are
+	// ignored when selecting the action, and the paths are recorded in
+	// the WriteOnly field of the result.
+	WriteOnly PathSet
+
+	// Normalize suppresses differences that aren't significant, such as in
+	// the letter case of a case-insensitive identifier, before the values
+	// are compared. Parts of the new value that differ from the old value
+	// only in such ways are replaced by the old value in the result, as by
+	// Normalize.
+	Normalize []Normalization
+}
+
+// Diff compares the given old and new values, both of which must conform
+// to the given type, and returns a change with an action selected based
+// on their nullness and equality.
+//
+// Diff panics if either value does not conform to the given type.
+func Diff(ty cty.Type, old, new cty.Value, opts DiffOpts) *Change {
+	new = Normalize(old, new, opts.Normalize)
+	ret := diff(ty, old, new, opts)
+	ret.WriteOnly = opts.WriteOnly
+	return ret
+}
+
+// Compute is a shorthand for Diff for the common case where the only
+// option needed is the list of paths that cannot be updated in-place.
+func Compute(ty cty.Type, old, new cty.Value, requiresReplace []cty.Path) *Change {
+	return Diff(ty, old, new, DiffOpts{
+		RequiresReplace: NewPathSet(requiresReplace...),
+	})
+}
+
+func diff(ty cty.Type, old, new cty.Value, opts DiffOpts) *Change {
+	switch {
+	case old.RawEquals(new):
+		return NewNoOp(ty, new)
+	case old.IsNull():
+		return NewCreate(ty, new)
+	case new.IsNull():
+		return NewDelete(ty, old)
+	}
+
+	changed := false
+	forced := NewPathSet()
+	walkChangesExcept(nil, old, new, opts.WriteOnly, func(path cty.Path, old, new cty.Value, action Action) {
+		changed = true
+		for _, rp := range opts.RequiresReplace.List() {
+			// An unknown or wholly-replaced collection may also imply
+			// changes to the paths nested within it, so we must check
+			// in both directions here.
+			if pathHasPrefix(path, rp) || pathHasPrefix(rp, path) {
+				forced.Add(rp)
+			}
+		}
+	})
+
+	switch {
+	case !changed:
+		// The values differ only in write-only paths, which don't count.
+		mustConform(ty, old)
+		mustConform(ty, new)
+		return &Change{
+			Action: NoOp,
+			Type:   ty,
+			Old:    old,
+			New:    new,
+		}
+	case !forced.Empty():
+		return NewReplace(ty, old, new, forced)
+	default:
+		return NewUpdate(ty, old, new)
+	}
+}
+
+// Rebase returns a new change from the given old value to the new value of
+// the receiver, re-deriving the action. This is useful when the prior value
+// has been refreshed after the change was planned: if the refreshed value
+// now matches the desired new value then the result is a NoOp.
+//
+// The receiver's ForcedReplace paths are treated as requiring replacement,
+// and its Sensitive, WriteOnly and Computed paths are retained, as are the
+// reasons of its ForcedReplace paths and the sources of its unknown values. If the receiver was replaced because
+// it was requested or tainted then so is the result, unless the result is a
+// Create or Delete. An error is returned if the given value
+// does not conform to the change's type.
+func (c *Change) Rebase(newOld cty.Value) (*Change, error) {
+	if errs := newOld.Type().TestConformance(c.Type); len(errs) > 0 {
+		return nil, fmt.Errorf("new prior value does not conform to %s: %s", c.Type.FriendlyName(), errs[0])
+	}
+	return c.rederive(newOld, c.New), nil
+}
+
+// rederive returns a new change between the given values, carrying over
+// the path sets of the receiver.
+func (c *Change) rederive(old, new cty.Value) *Change {
+	ret := Diff(c.Type, old, new, DiffOpts{
+		RequiresReplace: c.ForcedReplace,
+		WriteOnly:       c.WriteOnly,
+	})
+	if ret.Action == Replace {
+		ret.ForcedReplaceReasons = c.ForcedReplaceReasons.Only(ret.ForcedReplace)
+		ret.ReplaceReason = ret.forcedReplaceReason()
+	}
+	if reason := c.replaceReason(); reason.unconditional() && ret.Action != Create && ret.Action != Delete {
+		ret.Action = Replace
+		ret.ReplaceReason = reason
+	}
+	ret.Sensitive = c.Sensitive
+	ret.Computed = c.Computed
+	ret.UnknownSources = c.UnknownSources
+	return ret
+}
+
+// ChangedPaths returns the set of paths to the leaf values that differ
+// between the old and new values of the change.
+//
+// Nested values are compared element-by-element, so a change to a single
+// attribute of a nested object produces only the path to that attribute.
+// Added and removed set elements are identified by their values. Paths
+// within the change's WriteOnly set are not included.
+func (c *Change) ChangedPaths() PathSet {
+	ret := NewPathSet()
+	c.walkLeaves(func(path cty.Path, old, new cty.Value, action Action) {
+		ret.Add(path)
+	})
+	return ret
+}
+
+// SplitByAttr splits a change to an object value into separate changes for
+// each of the object's top-level attributes, keyed by attribute name. Each
+// of the resulting changes describes only the subtree of its attribute.
+//
+// Attributes that are unchanged produce NoOp changes, which can be removed
+// from the result using ChangeSet.OmitNoOp if they are not needed. If the
+// receiver is a Replace, only the attributes that contain paths from
+// ForcedReplace are themselves Replace changes.
+//
+// The result is nil if the change is not for an object type.
+func (c *Change) SplitByAttr() ChangeSet {
+	if !c.Type.IsObjectType() {
+		return nil
+	}
+
+	ret := make(ChangeSet)
+	for name, aty := range c.Type.AttributeTypes() {
+		attrPath := cty.Path{cty.GetAttrStep{Name: name}}
+		sub := Diff(aty, attrOrNull(c.Old, name, aty), attrOrNull(c.New, name, aty), DiffOpts{
+			RequiresReplace: NewPathSet(pathsUnder(c.ForcedReplace, attrPath)...),
+			WriteOnly:       NewPathSet(pathsUnder(c.WriteOnly, attrPath)...),
+		})
+		sub.Sensitive = NewPathSet(pathsUnder(c.Sensitive, attrPath)...)
+		sub.Computed = NewPathSet(pathsUnder(c.Computed, attrPath)...)
+		sub.UnknownSources = c.UnknownSources.Under(attrPath)
+		ret[name] = sub
+	}
+	return ret
+}
+
+// pathsUnder returns the paths from the given set that begin with the given
+// prefix, with the prefix removed.
+func pathsUnder(s PathSet, prefix cty.Path) []cty.Path {
+	var ret []cty.Path
+	for _, p := range s.List() {
+		if pathHasPrefix(p, prefix) {
+			ret = append(ret, p[len(prefix):])
+		}
+	}
+	return ret
+}
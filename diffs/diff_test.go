@@ -0,0 +1,356 @@
+package diffs
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDiff(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"name": cty.String,
+		"tags": cty.Map(cty.String),
+	})
+	v1 := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("a"),
+		"tags": cty.MapVal(map[string]cty.Value{
+			"env": cty.StringVal("prod"),
+		}),
+	})
+	v2 := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("a"),
+		"tags": cty.MapVal(map[string]cty.Value{
+			"env": cty.StringVal("dev"),
+		}),
+	})
+	v3 := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("b"),
+		"tags": cty.MapVal(map[string]cty.Value{
+			"env": cty.StringVal("prod"),
+		}),
+	})
+	namePath := cty.Path{cty.GetAttrStep{Name: "name"}}
+
+	tests := map[string]struct {
+		Old, New   cty.Value
+		Opts       DiffOpts
+		WantAction Action
+		WantForced []cty.Path
+	}{
+		"no change": {
+			v1,
+			v1,
+			DiffOpts{},
+			NoOp,
+			nil,
+		},
+		"create": {
+			cty.NullVal(ty),
+			v1,
+			DiffOpts{},
+			Create,
+			nil,
+		},
+		"delete": {
+			v1,
+			cty.NullVal(ty),
+			DiffOpts{},
+			Delete,
+			nil,
+		},
+		"update": {
+			v1,
+			v2,
+			DiffOpts{},
+			Update,
+			nil,
+		},
+		"update not affecting forced replace path": {
+			v1,
+			v2,
+			DiffOpts{RequiresReplace: NewPathSet(namePath)},
+			Update,
+			nil,
+		},
+		"replace": {
+			v1,
+			v3,
+			DiffOpts{RequiresReplace: NewPathSet(namePath)},
+			Replace,
+			[]cty.Path{namePath},
+		},
+		"replace due to unknown": {
+			v1,
+			cty.UnknownVal(ty),
+			DiffOpts{RequiresReplace: NewPathSet(namePath)},
+			Replace,
+			[]cty.Path{namePath},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := Diff(ty, test.Old, test.New, test.Opts)
+			if got.Action != test.WantAction {
+				t.Errorf("wrong action %s; want %s", got.Action, test.WantAction)
+			}
+			if got, want := got.ForcedReplace.Len(), len(test.WantForced); got != want {
+				t.Errorf("wrong number of forced replace paths %d; want %d", got, want)
+			}
+			for _, p := range test.WantForced {
+				if !got.ForcedReplace.Has(p) {
+					t.Errorf("missing forced replace path %#v", p)
+				}
+			}
+		})
+	}
+}
+
+func TestChangeSplitByAttr(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"ami":  cty.String,
+		"name": cty.String,
+		"size": cty.Number,
+	})
+	c := NewReplace(
+		ty,
+		cty.ObjectVal(map[string]cty.Value{
+			"ami":  cty.StringVal("ami-1"),
+			"name": cty.StringVal("foo"),
+			"size": cty.NumberIntVal(1),
+		}),
+		cty.ObjectVal(map[string]cty.Value{
+			"ami":  cty.StringVal("ami-2"),
+			"name": cty.StringVal("foo"),
+			"size": cty.NumberIntVal(2),
+		}),
+		NewPathSet(cty.Path{cty.GetAttrStep{Name: "ami"}}),
+	)
+
+	got := c.SplitByAttr()
+	if len(got) != 3 {
+		t.Fatalf("wrong number of changes %d; want 3", len(got))
+	}
+
+	want := map[string]struct {
+		Action Action
+		Old    cty.Value
+		New    cty.Value
+	}{
+		"ami":  {Replace, cty.StringVal("ami-1"), cty.StringVal("ami-2")},
+		"name": {NoOp, cty.StringVal("foo"), cty.StringVal("foo")},
+		"size": {Update, cty.NumberIntVal(1), cty.NumberIntVal(2)},
+	}
+	for name, w := range want {
+		sub, ok := got[name]
+		if !ok {
+			t.Errorf("no change for %q", name)
+			continue
+		}
+		if sub.Action != w.Action {
+			t.Errorf("wrong action for %q %s; want %s", name, sub.Action, w.Action)
+		}
+		if !sub.Old.RawEquals(w.Old) {
+			t.Errorf("wrong old value for %q\ngot:  %#v\nwant: %#v", name, sub.Old, w.Old)
+		}
+		if !sub.New.RawEquals(w.New) {
+			t.Errorf("wrong new value for %q\ngot:  %#v\nwant: %#v", name, sub.New, w.New)
+		}
+	}
+	if !got["ami"].ForcedReplace.Has(cty.Path{}) {
+		t.Errorf("ami change does not record its own forced replacement")
+	}
+
+	if got, want := len(got.OmitNoOp()), 2; got != want {
+		t.Errorf("wrong number of changes after OmitNoOp %d; want %d", got, want)
+	}
+}
+
+func TestDiffWriteOnly(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"name":     cty.String,
+		"password": cty.String,
+	})
+	old := cty.ObjectVal(map[string]cty.Value{
+		"name":     cty.StringVal("foo"),
+		"password": cty.NullVal(cty.String),
+	})
+	passwordPath := cty.Path{cty.GetAttrStep{Name: "password"}}
+	opts := DiffOpts{
+		WriteOnly: NewPathSet(passwordPath),
+	}
+
+	t.Run("only write-only set", func(t *testing.T) {
+		new := cty.ObjectVal(map[string]cty.Value{
+			"name":     cty.StringVal("foo"),
+			"password": cty.StringVal("hunter2"),
+		})
+		got := Diff(ty, old, new, opts)
+		if got.Action != NoOp {
+			t.Errorf("wrong action %s; want %s", got.Action, NoOp)
+		}
+		if !got.ChangedPaths().Empty() {
+			t.Errorf("unexpected changed paths %#v", got.ChangedPaths().List())
+		}
+
+		wantRender := `  {
+      name     = "foo"
+      password = (write-only, not stored)
+  }
+`
+		if got := got.Render(RenderOpts{}); got != wantRender {
+			t.Errorf("wrong rendering\ngot:\n%s\nwant:\n%s", got, wantRender)
+		}
+	})
+
+	t.Run("other attribute also changed", func(t *testing.T) {
+		new := cty.ObjectVal(map[string]cty.Value{
+			"name":     cty.StringVal("bar"),
+			"password": cty.StringVal("hunter2"),
+		})
+		got := Diff(ty, old, new, opts)
+		if got.Action != Update {
+			t.Errorf("wrong action %s; want %s", got.Action, Update)
+		}
+		paths := got.ChangedPaths()
+		if paths.Len() != 1 || !paths.Has(cty.Path{cty.GetAttrStep{Name: "name"}}) {
+			t.Errorf("wrong changed paths %#v", paths.List())
+		}
+	})
+}
+
+func TestChangeRebase(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"name": cty.String,
+		"size": cty.Number,
+	})
+	old := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("foo"),
+		"size": cty.NumberIntVal(1),
+	})
+	new := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("foo"),
+		"size": cty.NumberIntVal(2),
+	})
+	c := NewUpdate(ty, old, new)
+
+	t.Run("prior now matches", func(t *testing.T) {
+		got, err := c.Rebase(new)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got.Action != NoOp {
+			t.Errorf("wrong action %s; want %s", got.Action, NoOp)
+		}
+	})
+
+	t.Run("different prior", func(t *testing.T) {
+		refreshed := cty.ObjectVal(map[string]cty.Value{
+			"name": cty.StringVal("bar"),
+			"size": cty.NumberIntVal(1),
+		})
+		got, err := c.Rebase(refreshed)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got.Action != Update {
+			t.Errorf("wrong action %s; want %s", got.Action, Update)
+		}
+		if !got.Old.RawEquals(refreshed) {
+			t.Errorf("wrong old value\ngot:  %#v\nwant: %#v", got.Old, refreshed)
+		}
+		if !got.New.RawEquals(new) {
+			t.Errorf("wrong new value\ngot:  %#v\nwant: %#v", got.New, new)
+		}
+		paths := got.ChangedPaths()
+		if paths.Len() != 2 {
+			t.Errorf("wrong number of changed paths %d; want 2", paths.Len())
+		}
+	})
+
+	t.Run("prior was deleted", func(t *testing.T) {
+		got, err := c.Rebase(cty.NullVal(ty))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got.Action != Create {
+			t.Errorf("wrong action %s; want %s", got.Action, Create)
+		}
+	})
+
+	t.Run("requested replacement", func(t *testing.T) {
+		got, err := NewRequestedReplace(ty, old, new).Rebase(new)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got.Action != Replace || got.ReplaceReason != ReplaceByRequest {
+			t.Errorf("wrong result %s (%s); want %s (%s)", got.Action, got.ReplaceReason, Replace, ReplaceByRequest)
+		}
+	})
+
+	t.Run("dependency replacement", func(t *testing.T) {
+		size := cty.Path{cty.GetAttrStep{Name: "size"}}
+		reasons := NewPathReasons()
+		reasons.Set(size, ReplaceBecauseDependency)
+		dep := &Change{
+			Action:               Replace,
+			Type:                 ty,
+			Old:                  old,
+			New:                  new,
+			ForcedReplace:        NewPathSet(size),
+			ForcedReplaceReasons: reasons,
+			ReplaceReason:        ReplaceBecauseDependency,
+		}
+		got, err := dep.Rebase(old)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got.Action != Replace || got.ReplaceReason != ReplaceBecauseDependency {
+			t.Errorf("wrong result %s (%s); want %s (%s)", got.Action, got.ReplaceReason, Replace, ReplaceBecauseDependency)
+		}
+		if reason := got.ReplaceReasonFor(size); reason != ReplaceBecauseDependency {
+			t.Errorf("wrong reason for size %s; want %s", reason, ReplaceBecauseDependency)
+		}
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		_, err := c.Rebase(cty.StringVal("nope"))
+		if err == nil {
+			t.Fatalf("succeeded; want error")
+		}
+	})
+}
+
+func TestCompute(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"ami":  cty.String,
+		"name": cty.String,
+	})
+	obj := func(ami, name string) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{
+			"ami":  cty.StringVal(ami),
+			"name": cty.StringVal(name),
+		})
+	}
+	requiresReplace := []cty.Path{{cty.GetAttrStep{Name: "ami"}}}
+
+	tests := map[string]struct {
+		Old, New cty.Value
+		Want     Action
+	}{
+		"no-op":   {obj("ami-1", "foo"), obj("ami-1", "foo"), NoOp},
+		"create":  {cty.NullVal(ty), obj("ami-1", "foo"), Create},
+		"update":  {obj("ami-1", "foo"), obj("ami-1", "bar"), Update},
+		"replace": {obj("ami-1", "foo"), obj("ami-2", "foo"), Replace},
+		"delete":  {obj("ami-1", "foo"), cty.NullVal(ty), Delete},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := Compute(ty, test.Old, test.New, requiresReplace)
+			if got.Action != test.Want {
+				t.Errorf("wrong action %s; want %s", got.Action, test.Want)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+// Package diffs contains types for describing changes to values, such as
+// the planned changes to a resource instance object.
+//
+// A Change describes the transition of a single value from one state to
+// another, along with the action that will be taken to achieve it. The
+// values are represented using cty, with the type of both the old and new
+// value given explicitly so that changes can be inspected and decomposed
+// without reference to any external schema.
+//
+// This package intentionally does not depend on Terraform core, so that it
+// can be used both by core and by other components that need to work with
+// changes, such as the provider SDK.
+package diffs
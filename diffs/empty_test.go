@@ -0,0 +1,99 @@
+package diffs
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestEmptyTypes(t *testing.T) {
+	tests := map[string]struct {
+		Type       cty.Type
+		Old, New   cty.Value
+		WantAction Action
+		WantRender string
+	}{
+		"empty object unchanged": {
+			cty.EmptyObject,
+			cty.EmptyObjectVal,
+			cty.EmptyObjectVal,
+			NoOp,
+			"  {}\n",
+		},
+		"empty object created": {
+			cty.EmptyObject,
+			cty.NullVal(cty.EmptyObject),
+			cty.EmptyObjectVal,
+			Create,
+			"+ {}\n",
+		},
+		"empty object deleted": {
+			cty.EmptyObject,
+			cty.EmptyObjectVal,
+			cty.NullVal(cty.EmptyObject),
+			Delete,
+			"- {} -> null\n",
+		},
+		"empty tuple unchanged": {
+			cty.EmptyTuple,
+			cty.EmptyTupleVal,
+			cty.EmptyTupleVal,
+			NoOp,
+			"  []\n",
+		},
+		"empty tuple created": {
+			cty.EmptyTuple,
+			cty.NullVal(cty.EmptyTuple),
+			cty.EmptyTupleVal,
+			Create,
+			"+ []\n",
+		},
+		"nested empty types": {
+			cty.Object(map[string]cty.Type{
+				"obj":   cty.EmptyObject,
+				"tuple": cty.EmptyTuple,
+			}),
+			cty.ObjectVal(map[string]cty.Value{
+				"obj":   cty.NullVal(cty.EmptyObject),
+				"tuple": cty.EmptyTupleVal,
+			}),
+			cty.ObjectVal(map[string]cty.Value{
+				"obj":   cty.EmptyObjectVal,
+				"tuple": cty.NullVal(cty.EmptyTuple),
+			}),
+			Update,
+			`~ {
+    + obj   = {}
+    - tuple = [] -> null
+  }
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := Diff(test.Type, test.Old, test.New, DiffOpts{})
+			if c.Action != test.WantAction {
+				t.Errorf("wrong action %s; want %s", c.Action, test.WantAction)
+			}
+			if got := c.Render(RenderOpts{}); got != test.WantRender {
+				t.Errorf("wrong rendering\ngot:\n%s\nwant:\n%s", got, test.WantRender)
+			}
+			if c.Action == NoOp && !c.ChangedPaths().Empty() {
+				t.Errorf("unexpected changed paths for no-op: %#v", c.ChangedPaths().List())
+			}
+			if _, err := c.MarshalJSON(); err != nil {
+				t.Errorf("failed to marshal: %s", err)
+			}
+			if test.Type.IsObjectType() {
+				split := c.SplitByAttr()
+				if split == nil {
+					t.Fatalf("SplitByAttr returned nil for object type")
+				}
+				if got, want := len(split), len(test.Type.AttributeTypes()); got != want {
+					t.Errorf("wrong number of split changes %d; want %d", got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package diffs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ChangedFingerprint returns a hash of only the changed portion of the
+// change: its action, and the path, old value and new value of each of
+// the changed leaf values as returned by ChangedPaths.
+//
+// Two changes that make the same alterations to the same attributes have
+// the same fingerprint even if their unchanged attributes differ, making
+// this suitable for deduplicating notifications about changes to
+// different objects. Values are not redacted before hashing, but whether
+// each changed leaf is sensitive is included in the hash.
+func (c *Change) ChangedFingerprint() string {
+	var tuples []string
+	c.walkLeaves(func(path cty.Path, old, new cty.Value, action Action) {
+		tuples = append(tuples, fmt.Sprintf(
+			"%s\x00%s\x00%s\x00%t",
+			pathKey(path), valueKey(old), valueKey(new), pathSetCovers(c.Sensitive, path),
+		))
+	})
+	sort.Strings(tuples)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", c.Action)
+	for _, t := range tuples {
+		fmt.Fprintf(h, "%s\n", t)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
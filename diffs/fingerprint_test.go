@@ -0,0 +1,54 @@
+package diffs
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestChangeChangedFingerprint(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"id":   cty.String,
+		"tags": cty.Map(cty.String),
+	})
+	change := func(id, oldEnv, newEnv string) *Change {
+		return NewUpdate(
+			ty,
+			cty.ObjectVal(map[string]cty.Value{
+				"id": cty.StringVal(id),
+				"tags": cty.MapVal(map[string]cty.Value{
+					"env": cty.StringVal(oldEnv),
+				}),
+			}),
+			cty.ObjectVal(map[string]cty.Value{
+				"id": cty.StringVal(id),
+				"tags": cty.MapVal(map[string]cty.Value{
+					"env": cty.StringVal(newEnv),
+				}),
+			}),
+		)
+	}
+
+	a := change("i-a", "dev", "prod")
+	b := change("i-b", "dev", "prod")
+	if a.ChangedFingerprint() != b.ChangedFingerprint() {
+		t.Errorf("changes differing only in unchanged attributes have different fingerprints")
+	}
+
+	c := change("i-a", "dev", "staging")
+	if a.ChangedFingerprint() == c.ChangedFingerprint() {
+		t.Errorf("changes with different new values have the same fingerprint")
+	}
+
+	d := change("i-a", "dev", "prod")
+	d.Sensitive = NewPathSet(cty.Path{cty.GetAttrStep{Name: "tags"}})
+	if a.ChangedFingerprint() == d.ChangedFingerprint() {
+		t.Errorf("changes with different sensitivity have the same fingerprint")
+	}
+
+	e := change("i-a", "dev", "prod")
+	e.Action = Replace
+	if a.ChangedFingerprint() == e.ChangedFingerprint() {
+		t.Errorf("changes with different actions have the same fingerprint")
+	}
+}
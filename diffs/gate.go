@@ -0,0 +1,83 @@
+package diffs
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GateOpts is the policy used by ChangeSet.RenderGate to decide whether a
+// set of changes is blocked.
+//
+// The zero value of GateOpts blocks any destructive change.
+type GateOpts struct {
+	// AllowDelete, if set, permits Delete changes.
+	AllowDelete bool
+
+	// AllowReplace, if set, permits Replace changes.
+	AllowReplace bool
+}
+
+// RenderGate produces a terse report of only the destructive changes in
+// the set, for use by a CI gate that must decide whether to permit an
+// apply. Each Delete and Replace change is listed by its key, with the
+// paths that forced each replacement.
+//
+// The boolean result is true if any of the destructive changes are not
+// permitted by the given policy, in which case the apply should be
+// blocked.
+func (cs ChangeSet) RenderGate(opts GateOpts) (string, bool) {
+	var keys []string
+	for k, c := range cs {
+		if c.Action == Delete || c.Action == Replace {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return "No destructive changes.\n", false
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	blocked := false
+	if len(keys) == 1 {
+		buf.WriteString("1 destructive change:\n")
+	} else {
+		fmt.Fprintf(&buf, "%d destructive changes:\n", len(keys))
+	}
+	for _, k := range keys {
+		c := cs[k]
+		switch c.Action {
+		case Delete:
+			fmt.Fprintf(&buf, "  %s %s will be destroyed\n", actionMarkers[Delete], k)
+			if !opts.AllowDelete {
+				blocked = true
+			}
+		case Replace:
+			fmt.Fprintf(&buf, "  %s %s will be replaced", actionMarkers[Replace], k)
+			if paths := sortedPaths(c.ForcedReplace); len(paths) > 0 {
+				fmt.Fprintf(&buf, ", forced by %s", strings.Join(paths, ", "))
+			}
+			buf.WriteByte('\n')
+			if !opts.AllowReplace {
+				blocked = true
+			}
+		}
+	}
+	if blocked {
+		buf.WriteString("Blocked: policy does not permit these changes.\n")
+	}
+	return buf.String(), blocked
+}
+
+// sortedPaths returns the paths in the given set formatted as strings, in
+// lexical order.
+func sortedPaths(s PathSet) []string {
+	var ret []string
+	for _, p := range s.List() {
+		ret = append(ret, formatPath(p))
+	}
+	sort.Strings(ret)
+	return ret
+}
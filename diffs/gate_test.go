@@ -0,0 +1,84 @@
+package diffs
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestChangeSetRenderGate(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"ami": cty.String,
+	})
+	v1 := cty.ObjectVal(map[string]cty.Value{
+		"ami": cty.StringVal("ami-1"),
+	})
+	v2 := cty.ObjectVal(map[string]cty.Value{
+		"ami": cty.StringVal("ami-2"),
+	})
+	amiPath := cty.Path{cty.GetAttrStep{Name: "ami"}}
+
+	tests := map[string]struct {
+		Changes     ChangeSet
+		Opts        GateOpts
+		Want        string
+		WantBlocked bool
+	}{
+		"no destructive changes": {
+			ChangeSet{
+				"aws_instance.a": NewCreate(ty, v1),
+				"aws_instance.b": NewUpdate(ty, v1, v2),
+			},
+			GateOpts{},
+			"No destructive changes.\n",
+			false,
+		},
+		"replace under no-destroy policy": {
+			ChangeSet{
+				"aws_instance.a": NewCreate(ty, v1),
+				"aws_instance.b": NewReplace(ty, v1, v2, NewPathSet(amiPath)),
+			},
+			GateOpts{},
+			`1 destructive change:
+  -/+ aws_instance.b will be replaced, forced by ami
+Blocked: policy does not permit these changes.
+`,
+			true,
+		},
+		"replace permitted": {
+			ChangeSet{
+				"aws_instance.b": NewReplace(ty, v1, v2, NewPathSet(amiPath)),
+			},
+			GateOpts{AllowReplace: true},
+			`1 destructive change:
+  -/+ aws_instance.b will be replaced, forced by ami
+`,
+			false,
+		},
+		"delete not permitted even though replace is": {
+			ChangeSet{
+				"aws_instance.a": NewDelete(ty, v1),
+				"aws_instance.b": NewReplace(ty, v1, v2, NewPathSet(amiPath)),
+			},
+			GateOpts{AllowReplace: true},
+			`2 destructive changes:
+  - aws_instance.a will be destroyed
+  -/+ aws_instance.b will be replaced, forced by ami
+Blocked: policy does not permit these changes.
+`,
+			true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, blocked := test.Changes.RenderGate(test.Opts)
+			if got != test.Want {
+				t.Errorf("wrong report\ngot:\n%s\nwant:\n%s", got, test.Want)
+			}
+			if blocked != test.WantBlocked {
+				t.Errorf("wrong blocked %t; want %t", blocked, test.WantBlocked)
+			}
+		})
+	}
+}
@@ -0,0 +1,445 @@
+package diffs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// MarshalOpts customizes the behavior of Change.MarshalJSONWith.
+//
+// The zero value of MarshalOpts selects the same behavior as MarshalJSON.
+type MarshalOpts struct {
+	// StrictSensitive, if set, causes serialization to fail if any of the
+	// paths in the change's Sensitive set has a non-null, known value in
+	// either the old or new value. A path through a set applies to every
+	// element of the set. Callers in security-sensitive contexts
+	// can use this to ensure that sensitive values are always redacted
+	// before a change is serialized.
+	StrictSensitive bool
+
+	// RedactSensitive, if set, causes the values at the change's Sensitive
+	// paths to be replaced with nulls before serialization, as if by
+	// calling Change.Redacted. The paths themselves are still included.
+	RedactSensitive bool
+}
+
+// MarshalJSON returns a JSON representation of the change.
+//
+// The JSON object includes the action, the type, and the old and new
+// values. Unknown values are written as null, with their paths listed
+// separately, and the paths in ForcedReplace and Sensitive are also
+// included, along with the WriteOnly and Computed paths, the reasons for a
+// replacement and the sources of unknown values. A Sensitive path through a
+// set is written as the path of the whole set, since the keys of set
+// elements are their values. Sensitive values are written verbatim; use
+// MarshalJSONWith with RedactSensitive or StrictSensitive set to prevent
+// that.
+//
+// The result can be decoded with UnmarshalJSON to produce an equivalent
+// change.
+func (c *Change) MarshalJSON() ([]byte, error) {
+	return c.MarshalJSONWith(MarshalOpts{})
+}
+
+// MarshalJSONWith is like MarshalJSON but allows its behavior to be
+// customized with the given options.
+func (c *Change) MarshalJSONWith(opts MarshalOpts) ([]byte, error) {
+	if opts.RedactSensitive {
+		c = c.Redacted()
+	}
+	if opts.StrictSensitive {
+		for _, p := range c.Sensitive.List() {
+			for _, v := range []cty.Value{c.Old, c.New} {
+				for _, sv := range valuesAt(v, p) {
+					if sv.IsKnown() && !sv.IsNull() {
+						return nil, fmt.Errorf("sensitive value at %s would be serialized in cleartext", formatPath(p))
+					}
+				}
+			}
+		}
+	}
+
+	var err error
+	raw := changeJSON{
+		Action:        c.Action,
+		ReplaceReason: c.ReplaceReason,
+	}
+	if raw.Type, err = ctyjson.MarshalType(c.Type); err != nil {
+		return nil, fmt.Errorf("invalid type: %s", err)
+	}
+	if raw.Old, raw.OldUnknown, err = marshalValue(c.Old, c.Type); err != nil {
+		return nil, fmt.Errorf("invalid old value: %s", err)
+	}
+	if raw.New, raw.NewUnknown, err = marshalValue(c.New, c.Type); err != nil {
+		return nil, fmt.Errorf("invalid new value: %s", err)
+	}
+	if raw.ForcedReplace, err = marshalPaths(c.ForcedReplace.List()); err != nil {
+		return nil, fmt.Errorf("invalid forced replace path: %s", err)
+	}
+	if raw.Sensitive, err = marshalPaths(sensitiveMarkers(c.Type, c.Sensitive.List())); err != nil {
+		return nil, fmt.Errorf("invalid sensitive path: %s", err)
+	}
+	if raw.WriteOnly, err = marshalPaths(c.WriteOnly.List()); err != nil {
+		return nil, fmt.Errorf("invalid write-only path: %s", err)
+	}
+	if raw.Computed, err = marshalPaths(c.Computed.List()); err != nil {
+		return nil, fmt.Errorf("invalid computed path: %s", err)
+	}
+	for _, p := range c.ForcedReplaceReasons.Paths().List() {
+		path, err := marshalPath(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid forced replace reason path: %s", err)
+		}
+		raw.ForcedReplaceReasons = append(raw.ForcedReplaceReasons, pathReasonJSON{
+			Path:   path,
+			Reason: c.ForcedReplaceReasons.Get(p),
+		})
+	}
+	for _, p := range c.UnknownSources.Paths().List() {
+		path, err := marshalPath(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unknown source path: %s", err)
+		}
+		raw.UnknownSources = append(raw.UnknownSources, pathSourcesJSON{
+			Path: path,
+			Refs: c.UnknownSources.Get(p),
+		})
+	}
+
+	return json.Marshal(raw)
+}
+
+// UnmarshalJSON decodes a change from the JSON representation produced by
+// MarshalJSON, restoring any unknown values within the old and new values.
+//
+// Set elements have no paths of their own, so a set that contained unknown
+// values when marshaled is decoded as a wholly unknown set. A change
+// serialized with the legacy "replace_requested" property is decoded as
+// having the reason ReplaceByRequest.
+func (c *Change) UnmarshalJSON(buf []byte) error {
+	var raw changeJSON
+	if err := json.Unmarshal(buf, &raw); err != nil {
+		return err
+	}
+
+	ty, err := ctyjson.UnmarshalType(raw.Type)
+	if err != nil {
+		return fmt.Errorf("invalid type: %s", err)
+	}
+	old, err := unmarshalValue(raw.Old, raw.OldUnknown, ty)
+	if err != nil {
+		return fmt.Errorf("invalid old value: %s", err)
+	}
+	new, err := unmarshalValue(raw.New, raw.NewUnknown, ty)
+	if err != nil {
+		return fmt.Errorf("invalid new value: %s", err)
+	}
+
+	ret := Change{
+		Action:        raw.Action,
+		Type:          ty,
+		Old:           old,
+		New:           new,
+		ReplaceReason: raw.ReplaceReason,
+	}
+	if raw.ReplaceRequested && ret.ReplaceReason == ReplaceReasonNone {
+		ret.ReplaceReason = ReplaceByRequest
+	}
+	if ret.ForcedReplace, err = unmarshalPathSet(raw.ForcedReplace); err != nil {
+		return fmt.Errorf("invalid forced replace path: %s", err)
+	}
+	if ret.Sensitive, err = unmarshalPathSet(raw.Sensitive); err != nil {
+		return fmt.Errorf("invalid sensitive path: %s", err)
+	}
+	if ret.WriteOnly, err = unmarshalPathSet(raw.WriteOnly); err != nil {
+		return fmt.Errorf("invalid write-only path: %s", err)
+	}
+	if ret.Computed, err = unmarshalPathSet(raw.Computed); err != nil {
+		return fmt.Errorf("invalid computed path: %s", err)
+	}
+	ret.ForcedReplaceReasons = NewPathReasons()
+	for _, rr := range raw.ForcedReplaceReasons {
+		p, err := unmarshalPath(rr.Path)
+		if err != nil {
+			return fmt.Errorf("invalid forced replace reason path: %s", err)
+		}
+		ret.ForcedReplaceReasons.Set(p, rr.Reason)
+	}
+	ret.UnknownSources = NewPathSources()
+	for _, us := range raw.UnknownSources {
+		p, err := unmarshalPath(us.Path)
+		if err != nil {
+			return fmt.Errorf("invalid unknown source path: %s", err)
+		}
+		ret.UnknownSources.Set(p, us.Refs)
+	}
+
+	*c = ret
+	return nil
+}
+
+// MarshalJSON returns the JSON representation of the action, which is a
+// string such as "create" or "no-op".
+func (a Action) MarshalJSON() ([]byte, error) {
+	name, ok := actionJSONNames[a]
+	if !ok {
+		return nil, fmt.Errorf("invalid action %s", a)
+	}
+	return json.Marshal(name)
+}
+
+// UnmarshalJSON decodes an action from the representation produced by
+// MarshalJSON.
+func (a *Action) UnmarshalJSON(buf []byte) error {
+	var name string
+	if err := json.Unmarshal(buf, &name); err != nil {
+		return err
+	}
+	for action, n := range actionJSONNames {
+		if n == name {
+			*a = action
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid action %q", name)
+}
+
+// MarshalJSON returns the JSON representation of the reason, which is a
+// string such as "cannot_update" or "requested".
+func (r ReplaceReason) MarshalJSON() ([]byte, error) {
+	name, ok := replaceReasonJSONNames[r]
+	if !ok {
+		return nil, fmt.Errorf("invalid replace reason %s", r)
+	}
+	return json.Marshal(name)
+}
+
+// UnmarshalJSON decodes a reason from the representation produced by
+// MarshalJSON.
+func (r *ReplaceReason) UnmarshalJSON(buf []byte) error {
+	var name string
+	if err := json.Unmarshal(buf, &name); err != nil {
+		return err
+	}
+	for reason, n := range replaceReasonJSONNames {
+		if n == name {
+			*r = reason
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid replace reason %q", name)
+}
+
+// MarshalJSON returns the JSON representation of the set, which is an array
+// of paths in a stable order. Each path is an array of steps, where an
+// attribute step is given as a string and an index step is given as an
+// array containing only the JSON value of its key.
+func (s PathSet) MarshalJSON() ([]byte, error) {
+	paths, err := marshalPaths(s.List())
+	if err != nil {
+		return nil, err
+	}
+	if paths == nil {
+		paths = []pathJSON{}
+	}
+	return json.Marshal(paths)
+}
+
+// UnmarshalJSON decodes a set from the representation produced by
+// MarshalJSON.
+func (s *PathSet) UnmarshalJSON(buf []byte) error {
+	var raw []pathJSON
+	if err := json.Unmarshal(buf, &raw); err != nil {
+		return err
+	}
+	ret, err := unmarshalPathSet(raw)
+	if err != nil {
+		return err
+	}
+	*s = ret
+	return nil
+}
+
+// changeJSON is the JSON representation of a Change.
+type changeJSON struct {
+	Action        Action          `json:"action"`
+	Type          json.RawMessage `json:"type"`
+	Old           json.RawMessage `json:"old"`
+	New           json.RawMessage `json:"new"`
+	OldUnknown    []pathJSON      `json:"old_unknown,omitempty"`
+	NewUnknown    []pathJSON      `json:"new_unknown,omitempty"`
+	ForcedReplace []pathJSON      `json:"forced_replace,omitempty"`
+	Sensitive     []pathJSON      `json:"sensitive,omitempty"`
+	WriteOnly     []pathJSON      `json:"write_only,omitempty"`
+	Computed      []pathJSON      `json:"computed,omitempty"`
+
+	ReplaceReason        ReplaceReason    `json:"replace_reason,omitempty"`
+	ForcedReplaceReasons []pathReasonJSON `json:"forced_replace_reasons,omitempty"`
+
+	UnknownSources []pathSourcesJSON `json:"unknown_sources,omitempty"`
+
+	// ReplaceRequested is set only by older versions, which recorded a
+	// requested replacement before replace reasons were introduced. It is
+	// read but never written.
+	ReplaceRequested bool `json:"replace_requested,omitempty"`
+}
+
+// pathReasonJSON is the JSON representation of the reason that a single
+// path forces replacement.
+type pathReasonJSON struct {
+	Path   pathJSON      `json:"path"`
+	Reason ReplaceReason `json:"reason"`
+}
+
+// pathSourcesJSON is the JSON representation of the references that the
+// unknown value at a single path was derived from.
+type pathSourcesJSON struct {
+	Path pathJSON `json:"path"`
+	Refs []string `json:"refs"`
+}
+
+// pathJSON is the JSON representation of a cty.Path, where each step is
+// either an attribute name given as a string or an index key given as an
+// array containing only the key's JSON value.
+type pathJSON []json.RawMessage
+
+var actionJSONNames = map[Action]string{
+	NoOp:    "no-op",
+	Create:  "create",
+	Read:    "read",
+	Update:  "update",
+	Replace: "replace",
+	Delete:  "delete",
+	Forget:  "forget",
+}
+
+var replaceReasonJSONNames = map[ReplaceReason]string{
+	ReplaceReasonNone:          "none",
+	ReplaceBecauseCannotUpdate: "cannot_update",
+	ReplaceByRequest:           "requested",
+	ReplaceBecauseTainted:      "tainted",
+	ReplaceBecauseDependency:   "dependency_replaced",
+}
+
+func marshalValue(v cty.Value, ty cty.Type) (json.RawMessage, []pathJSON, error) {
+	var unknowns []cty.Path
+	v = stripUnknowns(nil, v, &unknowns)
+	buf, err := ctyjson.Marshal(v, ty)
+	if err != nil {
+		return nil, nil, err
+	}
+	paths, err := marshalPaths(unknowns)
+	if err != nil {
+		return nil, nil, err
+	}
+	return buf, paths, nil
+}
+
+func unmarshalValue(buf json.RawMessage, unknowns []pathJSON, ty cty.Type) (cty.Value, error) {
+	v, err := ctyjson.Unmarshal(buf, ty)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	for _, raw := range unknowns {
+		p, err := unmarshalPath(raw)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		v, err = unknownAt(v, p)
+		if err != nil {
+			return cty.NilVal, fmt.Errorf("invalid unknown path %s: %s", formatPath(p), err)
+		}
+	}
+	return v, nil
+}
+
+// unknownAt returns a copy of the given value with the value at the given
+// path replaced by an unknown value of the same type.
+func unknownAt(v cty.Value, p cty.Path) (cty.Value, error) {
+	return replaceAt(v, p, func(v cty.Value) cty.Value {
+		return cty.UnknownVal(v.Type())
+	})
+}
+
+func marshalPaths(paths []cty.Path) ([]pathJSON, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	paths = append([]cty.Path(nil), paths...)
+	sort.Slice(paths, func(i, j int) bool {
+		return pathKey(paths[i]) < pathKey(paths[j])
+	})
+	ret := make([]pathJSON, len(paths))
+	for i, p := range paths {
+		raw, err := marshalPath(p)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = raw
+	}
+	return ret, nil
+}
+
+func marshalPath(p cty.Path) (pathJSON, error) {
+	ret := make(pathJSON, len(p))
+	for i, step := range p {
+		var err error
+		switch ts := step.(type) {
+		case cty.GetAttrStep:
+			ret[i], err = json.Marshal(ts.Name)
+		case cty.IndexStep:
+			var key json.RawMessage
+			key, err = ctyjson.Marshal(ts.Key, ts.Key.Type())
+			if err == nil {
+				ret[i], err = json.Marshal([]json.RawMessage{key})
+			}
+		default:
+			err = fmt.Errorf("unsupported step type %T", step)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %s", i, err)
+		}
+	}
+	return ret, nil
+}
+
+func unmarshalPathSet(raw []pathJSON) (PathSet, error) {
+	ret := NewPathSet()
+	for _, rp := range raw {
+		p, err := unmarshalPath(rp)
+		if err != nil {
+			return PathSet{}, err
+		}
+		ret.Add(p)
+	}
+	return ret, nil
+}
+
+func unmarshalPath(raw pathJSON) (cty.Path, error) {
+	ret := make(cty.Path, len(raw))
+	for i, rs := range raw {
+		var name string
+		if err := json.Unmarshal(rs, &name); err == nil {
+			ret[i] = cty.GetAttrStep{Name: name}
+			continue
+		}
+		var wrapped []json.RawMessage
+		if err := json.Unmarshal(rs, &wrapped); err != nil || len(wrapped) != 1 {
+			return nil, fmt.Errorf("step %d: must be a string or a single-element array", i)
+		}
+		ty, err := ctyjson.ImpliedType(wrapped[0])
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %s", i, err)
+		}
+		key, err := ctyjson.Unmarshal(wrapped[0], ty)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %s", i, err)
+		}
+		ret[i] = cty.IndexStep{Key: key}
+	}
+	return ret, nil
+}
@@ -0,0 +1,318 @@
+package diffs
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestChangeMarshalJSON(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"id":   cty.String,
+		"name": cty.String,
+	})
+	c := NewCreate(ty, cty.ObjectVal(map[string]cty.Value{
+		"id":   cty.UnknownVal(cty.String),
+		"name": cty.StringVal("foo"),
+	}))
+
+	buf, err := c.MarshalJSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	got := map[string]interface{}{}
+	if err := json.Unmarshal(buf, &got); err != nil {
+		t.Fatalf("result is not valid JSON: %s", err)
+	}
+	if got, want := got["action"], "create"; got != want {
+		t.Errorf("wrong action %q; want %q", got, want)
+	}
+	if got, want := string(mustMarshal(t, got["new"])), `{"id":null,"name":"foo"}`; got != want {
+		t.Errorf("wrong new value %s; want %s", got, want)
+	}
+	if got, want := string(mustMarshal(t, got["new_unknown"])), `[["id"]]`; got != want {
+		t.Errorf("wrong new unknown paths %s; want %s", got, want)
+	}
+}
+
+func TestChangeMarshalJSONWith(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"name":     cty.String,
+		"password": cty.String,
+	})
+	old := cty.ObjectVal(map[string]cty.Value{
+		"name":     cty.StringVal("foo"),
+		"password": cty.NullVal(cty.String),
+	})
+	new := cty.ObjectVal(map[string]cty.Value{
+		"name":     cty.StringVal("foo"),
+		"password": cty.StringVal("hunter2"),
+	})
+	passwordPath := cty.Path{cty.GetAttrStep{Name: "password"}}
+
+	sensitive := NewUpdate(ty, old, new)
+	sensitive.Sensitive = NewPathSet(passwordPath)
+
+	notSensitive := NewUpdate(ty, old, new)
+
+	redacted := NewUpdate(ty, old, cty.ObjectVal(map[string]cty.Value{
+		"name":     cty.StringVal("foo"),
+		"password": cty.UnknownVal(cty.String),
+	}))
+	redacted.Sensitive = NewPathSet(passwordPath)
+
+	create := NewCreate(ty, new)
+	create.Sensitive = NewPathSet(passwordPath)
+
+	delete := NewDelete(ty, new)
+	delete.Sensitive = NewPathSet(passwordPath)
+
+	tupleTy := cty.Object(map[string]cty.Type{
+		"keys": cty.Tuple([]cty.Type{cty.String, cty.String}),
+	})
+	tuple := NewCreate(tupleTy, cty.ObjectVal(map[string]cty.Value{
+		"keys": cty.TupleVal([]cty.Value{cty.StringVal("public"), cty.StringVal("private")}),
+	}))
+	tuple.Sensitive = NewPathSet(cty.Path{cty.GetAttrStep{Name: "keys"}, cty.IndexStep{Key: cty.NumberIntVal(1)}})
+
+	userTy := cty.Object(map[string]cty.Type{
+		"name":     cty.String,
+		"password": cty.String,
+	})
+	user := cty.ObjectVal(map[string]cty.Value{
+		"name":     cty.StringVal("admin"),
+		"password": cty.StringVal("hunter2"),
+	})
+	setTy := cty.Object(map[string]cty.Type{
+		"users": cty.Set(userTy),
+	})
+	set := NewCreate(setTy, cty.ObjectVal(map[string]cty.Value{
+		"users": cty.SetVal([]cty.Value{user}),
+	}))
+	set.Sensitive = NewPathSet(cty.Path{cty.GetAttrStep{Name: "users"}, cty.IndexStep{Key: user}, cty.GetAttrStep{Name: "password"}})
+
+	tests := map[string]struct {
+		Change  *Change
+		Opts    MarshalOpts
+		WantErr bool
+	}{
+		"sensitive, strict": {
+			sensitive,
+			MarshalOpts{StrictSensitive: true},
+			true,
+		},
+		"sensitive, not strict": {
+			sensitive,
+			MarshalOpts{},
+			false,
+		},
+		"not sensitive, strict": {
+			notSensitive,
+			MarshalOpts{StrictSensitive: true},
+			false,
+		},
+		"sensitive but redacted, strict": {
+			redacted,
+			MarshalOpts{StrictSensitive: true},
+			false,
+		},
+		"create, strict": {
+			create,
+			MarshalOpts{StrictSensitive: true},
+			true,
+		},
+		"create, redacted and strict": {
+			create,
+			MarshalOpts{RedactSensitive: true, StrictSensitive: true},
+			false,
+		},
+		"delete, strict": {
+			delete,
+			MarshalOpts{StrictSensitive: true},
+			true,
+		},
+		"tuple element, strict": {
+			tuple,
+			MarshalOpts{StrictSensitive: true},
+			true,
+		},
+		"set element, strict": {
+			set,
+			MarshalOpts{StrictSensitive: true},
+			true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := test.Change.MarshalJSONWith(test.Opts)
+			if test.WantErr && err == nil {
+				t.Fatalf("succeeded; want error")
+			}
+			if !test.WantErr && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestChangeUnmarshalJSON(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"id":    cty.String,
+		"ports": cty.List(cty.Number),
+		"tags":  cty.Map(cty.String),
+		"zones": cty.Set(cty.String),
+	})
+	old := cty.ObjectVal(map[string]cty.Value{
+		"id":    cty.StringVal("i-abc123"),
+		"ports": cty.ListVal([]cty.Value{cty.NumberIntVal(80)}),
+		"tags":  cty.MapVal(map[string]cty.Value{"env": cty.StringVal("prod")}),
+		"zones": cty.SetVal([]cty.Value{cty.StringVal("a")}),
+	})
+	new := cty.ObjectVal(map[string]cty.Value{
+		"id":    cty.UnknownVal(cty.String),
+		"ports": cty.ListVal([]cty.Value{cty.NumberIntVal(80), cty.UnknownVal(cty.Number)}),
+		"tags":  cty.MapVal(map[string]cty.Value{"env": cty.UnknownVal(cty.String)}),
+		"zones": cty.SetVal([]cty.Value{cty.UnknownVal(cty.String)}),
+	})
+	want := NewReplace(ty, old, new, NewPathSet(
+		cty.Path{cty.GetAttrStep{Name: "zones"}},
+	))
+	want.Sensitive = NewPathSet(
+		cty.Path{cty.GetAttrStep{Name: "tags"}, cty.IndexStep{Key: cty.StringVal("env")}},
+		cty.Path{cty.GetAttrStep{Name: "ports"}, cty.IndexStep{Key: cty.NumberIntVal(1)}},
+	)
+	want.ReplaceReason = ReplaceBecauseDependency
+	want.ForcedReplaceReasons = NewPathReasons()
+	want.ForcedReplaceReasons.Set(cty.Path{cty.GetAttrStep{Name: "zones"}}, ReplaceBecauseDependency)
+	want.UnknownSources = NewPathSources()
+	want.UnknownSources.Set(cty.Path{cty.GetAttrStep{Name: "id"}}, []string{"aws_subnet.main.id"})
+
+	buf, err := want.MarshalJSON()
+	if err != nil {
+		t.Fatalf("unexpected error marshaling: %s", err)
+	}
+	var got Change
+	if err := json.Unmarshal(buf, &got); err != nil {
+		t.Fatalf("unexpected error unmarshaling: %s\n%s", err, buf)
+	}
+
+	// A set containing unknown values can only be recorded as wholly
+	// unknown, since its elements have no paths.
+	wantNew := cty.ObjectVal(map[string]cty.Value{
+		"id":    cty.UnknownVal(cty.String),
+		"ports": cty.ListVal([]cty.Value{cty.NumberIntVal(80), cty.UnknownVal(cty.Number)}),
+		"tags":  cty.MapVal(map[string]cty.Value{"env": cty.UnknownVal(cty.String)}),
+		"zones": cty.UnknownVal(cty.Set(cty.String)),
+	})
+
+	if got.Action != want.Action {
+		t.Errorf("wrong action %s; want %s", got.Action, want.Action)
+	}
+	if got.ReplaceReason != want.ReplaceReason {
+		t.Errorf("wrong replace reason %s; want %s", got.ReplaceReason, want.ReplaceReason)
+	}
+	zones := cty.Path{cty.GetAttrStep{Name: "zones"}}
+	if got, want := got.ReplaceReasonFor(zones), want.ReplaceReasonFor(zones); got != want {
+		t.Errorf("wrong replace reason for zones %s; want %s", got, want)
+	}
+	if !got.Type.Equals(want.Type) {
+		t.Errorf("wrong type\ngot:  %#v\nwant: %#v", got.Type, want.Type)
+	}
+	if !got.Old.RawEquals(want.Old) {
+		t.Errorf("wrong old value\ngot:  %#v\nwant: %#v", got.Old, want.Old)
+	}
+	if !got.New.RawEquals(wantNew) {
+		t.Errorf("wrong new value\ngot:  %#v\nwant: %#v", got.New, wantNew)
+	}
+	if got, want := sortedPaths(got.ForcedReplace), sortedPaths(want.ForcedReplace); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong forced replace paths\ngot:  %#v\nwant: %#v", got, want)
+	}
+	if got, want := sortedPaths(got.Sensitive), sortedPaths(want.Sensitive); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong sensitive paths\ngot:  %#v\nwant: %#v", got, want)
+	}
+	id := cty.Path{cty.GetAttrStep{Name: "id"}}
+	if got, want := got.UnknownSources.Get(id), want.UnknownSources.Get(id); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong unknown sources for id\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestPathSetMarshalJSON(t *testing.T) {
+	want := NewPathSet(
+		cty.Path{cty.GetAttrStep{Name: "tags"}, cty.IndexStep{Key: cty.StringVal("env")}},
+		cty.Path{cty.GetAttrStep{Name: "ports"}, cty.IndexStep{Key: cty.NumberIntVal(0)}},
+	)
+
+	buf, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("unexpected error marshaling: %s", err)
+	}
+	if got, want := string(buf), `[["ports",[0]],["tags",["env"]]]`; got != want {
+		t.Errorf("wrong JSON\ngot:  %s\nwant: %s", got, want)
+	}
+
+	var got PathSet
+	if err := json.Unmarshal(buf, &got); err != nil {
+		t.Fatalf("unexpected error unmarshaling: %s", err)
+	}
+	if got, want := sortedPaths(got), sortedPaths(want); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestActionMarshalJSON(t *testing.T) {
+	for action := range actionJSONNames {
+		buf, err := json.Marshal(action)
+		if err != nil {
+			t.Fatalf("unexpected error marshaling %s: %s", action, err)
+		}
+		var got Action
+		if err := json.Unmarshal(buf, &got); err != nil {
+			t.Fatalf("unexpected error unmarshaling %s: %s", buf, err)
+		}
+		if got != action {
+			t.Errorf("wrong result for %s: got %s", buf, got)
+		}
+	}
+}
+
+func TestReplaceReasonMarshalJSON(t *testing.T) {
+	for reason := range replaceReasonJSONNames {
+		buf, err := json.Marshal(reason)
+		if err != nil {
+			t.Fatalf("unexpected error marshaling %s: %s", reason, err)
+		}
+		var got ReplaceReason
+		if err := json.Unmarshal(buf, &got); err != nil {
+			t.Fatalf("unexpected error unmarshaling %s: %s", buf, err)
+		}
+		if got != reason {
+			t.Errorf("wrong result for %s: got %s", buf, got)
+		}
+	}
+}
+
+func TestChangeUnmarshalJSON_replaceRequested(t *testing.T) {
+	buf := []byte(`{"action":"replace","type":"string","old":"a","new":"a","replace_requested":true}`)
+	var got Change
+	if err := json.Unmarshal(buf, &got); err != nil {
+		t.Fatalf("unexpected error unmarshaling: %s", err)
+	}
+	if got.ReplaceReason != ReplaceByRequest {
+		t.Errorf("wrong replace reason %s; want %s", got.ReplaceReason, ReplaceByRequest)
+	}
+	if diags := got.Validate(); diags.HasErrors() {
+		t.Errorf("unexpected validation errors: %s", diags.Err())
+	}
+}
@@ -0,0 +1,47 @@
+package diffs
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/terraform/tfdiags"
+)
+
+// Move records that the value identified by one address, such as a
+// resource address, is now identified by another. Unlike a Change, a Move
+// never alters the value itself, so applying it is only a matter of
+// updating the record of where the value is tracked.
+//
+// A value may be both moved and changed, in which case the change is
+// keyed by the new address.
+type Move struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Validate checks that the move is internally consistent, returning error
+// diagnostics describing any problems.
+func (m *Move) Validate() tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+	switch {
+	case m.From == "" || m.To == "":
+		diags = diags.Append(invalidMove("The move must have both an old and a new address."))
+	case m.From == m.To:
+		diags = diags.Append(invalidMove("The move from %s has the same old and new address.", m.From))
+	}
+	return diags
+}
+
+// String returns a one-line description of the move, such as
+// "aws_instance.a has moved to aws_instance.b".
+func (m *Move) String() string {
+	return fmt.Sprintf("%s has moved to %s", m.From, m.To)
+}
+
+func invalidMove(detail string, args ...interface{}) *hcl.Diagnostic {
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Invalid move",
+		Detail:   fmt.Sprintf(detail, args...),
+	}
+}
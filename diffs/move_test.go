@@ -0,0 +1,38 @@
+package diffs
+
+import (
+	"testing"
+)
+
+func TestMoveValidate(t *testing.T) {
+	tests := map[string]struct {
+		Move    *Move
+		WantErr bool
+	}{
+		"valid": {
+			&Move{From: "aws_instance.a", To: "aws_instance.b"},
+			false,
+		},
+		"no old address": {
+			&Move{To: "aws_instance.b"},
+			true,
+		},
+		"no new address": {
+			&Move{From: "aws_instance.a"},
+			true,
+		},
+		"same address": {
+			&Move{From: "aws_instance.a", To: "aws_instance.a"},
+			true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			diags := test.Move.Validate()
+			if got := diags.HasErrors(); got != test.WantErr {
+				t.Errorf("wrong result %t; want %t\n%s", got, test.WantErr, diags.Err())
+			}
+		})
+	}
+}
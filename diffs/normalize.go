@@ -0,0 +1,315 @@
+package diffs
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform/config/configschema"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// NormalizeKind is a kind of difference between two values that isn't
+// significant, and so is suppressed by normalization.
+type NormalizeKind int
+
+const (
+	// NormalizeCase treats strings that differ only in letter case as
+	// equal.
+	NormalizeCase NormalizeKind = iota + 1
+
+	// NormalizeJSON treats strings that contain JSON documents with the
+	// same meaning as equal, regardless of formatting and the order of
+	// object properties.
+	NormalizeJSON
+
+	// NormalizeWhitespace treats strings that differ only in leading and
+	// trailing whitespace and the length of runs of whitespace as equal.
+	NormalizeWhitespace
+
+	// NormalizeUnordered treats lists with the same elements in a different
+	// order as equal, for lists that a remote system stores as sets.
+	NormalizeUnordered
+)
+
+var normalizeKindNames = map[NormalizeKind]string{
+	NormalizeCase:       "case",
+	NormalizeJSON:       "json",
+	NormalizeWhitespace: "whitespace",
+	NormalizeUnordered:  "unordered",
+}
+
+func (k NormalizeKind) String() string {
+	if name, ok := normalizeKindNames[k]; ok {
+		return name
+	}
+	return "NormalizeKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// ParseNormalizeKind returns the kind of normalization with the given name,
+// which is one of "case", "json", "whitespace" and "unordered".
+func ParseNormalizeKind(s string) (NormalizeKind, error) {
+	for k, name := range normalizeKindNames {
+		if name == s {
+			return k, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown normalization %q: must be case, json, whitespace or unordered", s)
+}
+
+// PathPattern matches paths within a value. It is written in the same way
+// as the paths of ignore_changes, as attribute names and element keys
+// separated by dots, such as "tags.Name", with "*" matching any attribute
+// or element, as in "rule.*.cidr".
+type PathPattern []string
+
+// ParsePathPattern parses the given string representation of a path
+// pattern.
+func ParsePathPattern(s string) (PathPattern, error) {
+	if s == "" {
+		return nil, fmt.Errorf("path pattern must not be empty")
+	}
+	steps := strings.Split(s, ".")
+	for _, step := range steps {
+		if step == "" {
+			return nil, fmt.Errorf("invalid path pattern %q: empty step", s)
+		}
+	}
+	return PathPattern(steps), nil
+}
+
+// Match returns true if the given path matches the pattern, having the
+// same number of steps, with each step matching the corresponding step of
+// the pattern. Set elements can't be matched except with "*".
+func (p PathPattern) Match(path cty.Path) bool {
+	if len(path) != len(p) {
+		return false
+	}
+	for i, step := range path {
+		if p[i] == "*" {
+			continue
+		}
+		switch ts := step.(type) {
+		case cty.GetAttrStep:
+			if ts.Name != p[i] {
+				return false
+			}
+		case cty.IndexStep:
+			switch {
+			case !ts.Key.IsKnown() || ts.Key.IsNull():
+				return false
+			case ts.Key.Type() == cty.String:
+				if ts.Key.AsString() != p[i] {
+					return false
+				}
+			case ts.Key.Type() == cty.Number:
+				if ts.Key.AsBigFloat().Text('f', -1) != p[i] {
+					return false
+				}
+			default:
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (p PathPattern) String() string {
+	return strings.Join(p, ".")
+}
+
+// Normalization suppresses differences of the given kind in the values at
+// the paths matching the given pattern. NormalizeCase, NormalizeJSON and
+// NormalizeWhitespace also apply to the strings nested within the matched
+// values.
+type Normalization struct {
+	Path PathPattern
+	Kind NormalizeKind
+}
+
+// SchemaNormalizations returns the normalizations that the attributes of
+// the given schema declare with their Normalize field, with the paths of
+// the attributes within the blocks nested in lists, sets and maps matching
+// any of their elements.
+//
+// An error is returned if an attribute declares an unknown kind of
+// normalization.
+func SchemaNormalizations(schema *configschema.Block) ([]Normalization, error) {
+	var ret []Normalization
+	if err := schemaNormalizations(schema, nil, &ret); err != nil {
+		return nil, err
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if a, b := ret[i].Path.String(), ret[j].Path.String(); a != b {
+			return a < b
+		}
+		return ret[i].Kind < ret[j].Kind
+	})
+	return ret, nil
+}
+
+func schemaNormalizations(schema *configschema.Block, prefix PathPattern, ret *[]Normalization) error {
+	for name, attr := range schema.Attributes {
+		path := append(append(PathPattern(nil), prefix...), name)
+		for _, s := range attr.Normalize {
+			kind, err := ParseNormalizeKind(s)
+			if err != nil {
+				return fmt.Errorf("attribute %s: %s", path, err)
+			}
+			*ret = append(*ret, Normalization{Path: path, Kind: kind})
+		}
+	}
+	for name, nested := range schema.BlockTypes {
+		path := append(append(PathPattern(nil), prefix...), name)
+		if nested.Nesting != configschema.NestingSingle {
+			path = append(path, "*")
+		}
+		if err := schemaNormalizations(&nested.Block, path, ret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Normalize returns the given new value with each part of it whose
+// difference from the corresponding part of the given old value is
+// suppressed by one of the given normalizations replaced by that part of
+// the old value, so that comparing the result with the old value finds
+// only the significant differences. Both values must be of the same type.
+//
+// Unknown and null values are never replaced, and sets are compared only
+// as a whole, since their elements can't be correlated by path.
+func Normalize(old, new cty.Value, rules []Normalization) cty.Value {
+	if len(rules) == 0 {
+		return new
+	}
+	return normalize(nil, old, new, rules, nil)
+}
+
+func normalize(path cty.Path, old, new cty.Value, rules []Normalization, inherited []NormalizeKind) cty.Value {
+	if old.IsNull() || new.IsNull() || !old.IsKnown() || !new.IsKnown() || old.RawEquals(new) {
+		return new
+	}
+	if !old.Type().Equals(new.Type()) {
+		return new
+	}
+
+	kinds := inherited
+	unordered := false
+	for _, r := range rules {
+		if !r.Path.Match(path) {
+			continue
+		}
+		if r.Kind == NormalizeUnordered {
+			unordered = true
+			continue
+		}
+		kinds = append(append([]NormalizeKind(nil), kinds...), r.Kind)
+	}
+
+	ty := new.Type()
+	switch {
+	case ty == cty.String:
+		if equivalentStrings(old.AsString(), new.AsString(), kinds) {
+			return old
+		}
+		return new
+
+	case ty.IsObjectType():
+		attrs := make(map[string]cty.Value)
+		for name := range ty.AttributeTypes() {
+			attrs[name] = normalize(path.GetAttr(name), old.GetAttr(name), new.GetAttr(name), rules, kinds)
+		}
+		return cty.ObjectVal(attrs)
+
+	case ty.IsMapType():
+		if new.LengthInt() == 0 {
+			return new
+		}
+		oldElems := mapElements(old)
+		elems := make(map[string]cty.Value)
+		for k, nv := range mapElements(new) {
+			if ov, ok := oldElems[k]; ok {
+				nv = normalize(path.Index(cty.StringVal(k)), ov, nv, rules, kinds)
+			}
+			elems[k] = nv
+		}
+		normalized := cty.MapVal(elems)
+		if normalized.RawEquals(old) {
+			return old
+		}
+		return normalized
+
+	case ty.IsListType() || ty.IsTupleType():
+		oldElems := sequenceElements(old)
+		newElems := sequenceElements(new)
+		if unordered && ty.IsListType() && samePermutation(oldElems, newElems) {
+			return old
+		}
+		if len(newElems) == 0 {
+			return new
+		}
+		elems := make([]cty.Value, len(newElems))
+		for i, nv := range newElems {
+			if i < len(oldElems) {
+				nv = normalize(path.Index(cty.NumberIntVal(int64(i))), oldElems[i], nv, rules, kinds)
+			}
+			elems[i] = nv
+		}
+		if ty.IsTupleType() {
+			return cty.TupleVal(elems)
+		}
+		return cty.ListVal(elems)
+
+	default:
+		return new
+	}
+}
+
+// equivalentStrings returns true if the given strings are equal once
+// normalized by any one of the given kinds.
+func equivalentStrings(a, b string, kinds []NormalizeKind) bool {
+	for _, k := range kinds {
+		switch k {
+		case NormalizeCase:
+			if strings.EqualFold(a, b) {
+				return true
+			}
+		case NormalizeWhitespace:
+			if strings.Join(strings.Fields(a), " ") == strings.Join(strings.Fields(b), " ") {
+				return true
+			}
+		case NormalizeJSON:
+			var av, bv interface{}
+			if json.Unmarshal([]byte(a), &av) == nil && json.Unmarshal([]byte(b), &bv) == nil && reflect.DeepEqual(av, bv) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// samePermutation returns true if the two given slices have the same
+// elements, in any order.
+func samePermutation(a, b []cty.Value) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[string]int)
+	for _, v := range a {
+		counts[valueKey(v)]++
+	}
+	for _, v := range b {
+		k := valueKey(v)
+		if counts[k] == 0 {
+			return false
+		}
+		counts[k]--
+	}
+	return true
+}
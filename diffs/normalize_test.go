@@ -0,0 +1,192 @@
+package diffs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/config/configschema"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestNormalize(t *testing.T) {
+	obj := func(name, policy, script string, zones []string, tags map[string]string) cty.Value {
+		zv := make([]cty.Value, len(zones))
+		for i, z := range zones {
+			zv[i] = cty.StringVal(z)
+		}
+		tv := make(map[string]cty.Value)
+		for k, v := range tags {
+			tv[k] = cty.StringVal(v)
+		}
+		return cty.ObjectVal(map[string]cty.Value{
+			"name":   cty.StringVal(name),
+			"policy": cty.StringVal(policy),
+			"script": cty.StringVal(script),
+			"zones":  cty.ListVal(zv),
+			"tags":   cty.MapVal(tv),
+		})
+	}
+	old := obj("Web", `{"a": 1, "b": [true]}`, "echo  hi\n", []string{"a", "b"}, map[string]string{"Env": "Prod"})
+
+	tests := map[string]struct {
+		New   cty.Value
+		Rules []Normalization
+		Want  cty.Value
+	}{
+		"no rules": {
+			obj("web", `{"a": 1, "b": [true]}`, "echo  hi\n", []string{"a", "b"}, map[string]string{"Env": "Prod"}),
+			nil,
+			obj("web", `{"a": 1, "b": [true]}`, "echo  hi\n", []string{"a", "b"}, map[string]string{"Env": "Prod"}),
+		},
+		"case": {
+			obj("web", `{"a": 1, "b": [true]}`, "echo  hi\n", []string{"a", "b"}, map[string]string{"Env": "Prod"}),
+			[]Normalization{{Path: PathPattern{"name"}, Kind: NormalizeCase}},
+			old,
+		},
+		"case elsewhere": {
+			obj("web", `{"a": 1, "b": [true]}`, "echo  hi\n", []string{"a", "b"}, map[string]string{"Env": "Prod"}),
+			[]Normalization{{Path: PathPattern{"script"}, Kind: NormalizeCase}},
+			obj("web", `{"a": 1, "b": [true]}`, "echo  hi\n", []string{"a", "b"}, map[string]string{"Env": "Prod"}),
+		},
+		"json": {
+			obj("Web", `{"b":[true],"a":1.0}`, "echo  hi\n", []string{"a", "b"}, map[string]string{"Env": "Prod"}),
+			[]Normalization{{Path: PathPattern{"policy"}, Kind: NormalizeJSON}},
+			old,
+		},
+		"json changed": {
+			obj("Web", `{"b":[false],"a":1}`, "echo  hi\n", []string{"a", "b"}, map[string]string{"Env": "Prod"}),
+			[]Normalization{{Path: PathPattern{"policy"}, Kind: NormalizeJSON}},
+			obj("Web", `{"b":[false],"a":1}`, "echo  hi\n", []string{"a", "b"}, map[string]string{"Env": "Prod"}),
+		},
+		"whitespace": {
+			obj("Web", `{"a": 1, "b": [true]}`, "  echo hi", []string{"a", "b"}, map[string]string{"Env": "Prod"}),
+			[]Normalization{{Path: PathPattern{"script"}, Kind: NormalizeWhitespace}},
+			old,
+		},
+		"unordered": {
+			obj("Web", `{"a": 1, "b": [true]}`, "echo  hi\n", []string{"b", "a"}, map[string]string{"Env": "Prod"}),
+			[]Normalization{{Path: PathPattern{"zones"}, Kind: NormalizeUnordered}},
+			old,
+		},
+		"unordered with other changes": {
+			obj("Web", `{"a": 1, "b": [true]}`, "echo  hi\n", []string{"b", "c"}, map[string]string{"Env": "Prod"}),
+			[]Normalization{{Path: PathPattern{"zones"}, Kind: NormalizeUnordered}},
+			obj("Web", `{"a": 1, "b": [true]}`, "echo  hi\n", []string{"b", "c"}, map[string]string{"Env": "Prod"}),
+		},
+		"map elements by wildcard": {
+			obj("Web", `{"a": 1, "b": [true]}`, "echo  hi\n", []string{"a", "b"}, map[string]string{"Env": "prod"}),
+			[]Normalization{{Path: PathPattern{"tags", "*"}, Kind: NormalizeCase}},
+			old,
+		},
+		"nested within matched value": {
+			obj("Web", `{"a": 1, "b": [true]}`, "echo  hi\n", []string{"A", "B"}, map[string]string{"Env": "Prod"}),
+			[]Normalization{{Path: PathPattern{"zones"}, Kind: NormalizeCase}},
+			old,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := Normalize(old, test.New, test.Rules)
+			if !got.RawEquals(test.Want) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, test.Want)
+			}
+		})
+	}
+}
+
+func TestDiff_normalize(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"name": cty.String,
+		"size": cty.Number,
+	})
+	old := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("Web"),
+		"size": cty.NumberIntVal(1),
+	})
+	opts := DiffOpts{
+		Normalize: []Normalization{{Path: PathPattern{"name"}, Kind: NormalizeCase}},
+	}
+
+	c := Diff(ty, old, cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("web"),
+		"size": cty.NumberIntVal(1),
+	}), opts)
+	if c.Action != NoOp {
+		t.Fatalf("wrong action %s; want NoOp", c.Action)
+	}
+
+	c = Diff(ty, old, cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("web"),
+		"size": cty.NumberIntVal(2),
+	}), opts)
+	if c.Action != Update {
+		t.Fatalf("wrong action %s; want Update", c.Action)
+	}
+	if got, want := c.ChangedPaths().List(), []cty.Path{{cty.GetAttrStep{Name: "size"}}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong changed paths %#v; want %#v", got, want)
+	}
+}
+
+func TestParsePathPattern(t *testing.T) {
+	p, err := ParsePathPattern("rule.*.cidr")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	path := cty.Path{
+		cty.GetAttrStep{Name: "rule"},
+		cty.IndexStep{Key: cty.NumberIntVal(2)},
+		cty.GetAttrStep{Name: "cidr"},
+	}
+	if !p.Match(path) {
+		t.Errorf("%s doesn't match %#v", p, path)
+	}
+	if p.Match(path[:2]) {
+		t.Errorf("%s matches %#v", p, path[:2])
+	}
+
+	for _, s := range []string{"", "tags..Name", "tags."} {
+		if _, err := ParsePathPattern(s); err == nil {
+			t.Errorf("no error for %q", s)
+		}
+	}
+}
+
+func TestSchemaNormalizations(t *testing.T) {
+	schema := &configschema.Block{
+		Attributes: map[string]*configschema.Attribute{
+			"name":   {Type: cty.String, Optional: true, Normalize: []string{"case"}},
+			"policy": {Type: cty.String, Optional: true, Normalize: []string{"json", "whitespace"}},
+			"size":   {Type: cty.Number, Optional: true},
+		},
+		BlockTypes: map[string]*configschema.NestedBlock{
+			"rule": {
+				Nesting: configschema.NestingList,
+				Block: configschema.Block{
+					Attributes: map[string]*configschema.Attribute{
+						"zones": {Type: cty.List(cty.String), Optional: true, Normalize: []string{"unordered"}},
+					},
+				},
+			},
+		},
+	}
+
+	got, err := SchemaNormalizations(schema)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []Normalization{
+		{Path: PathPattern{"name"}, Kind: NormalizeCase},
+		{Path: PathPattern{"policy"}, Kind: NormalizeJSON},
+		{Path: PathPattern{"policy"}, Kind: NormalizeWhitespace},
+		{Path: PathPattern{"rule", "*", "zones"}, Kind: NormalizeUnordered},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	schema.Attributes["size"].Normalize = []string{"fuzzy"}
+	if _, err := SchemaNormalizations(schema); err == nil {
+		t.Fatal("no error for unknown normalization")
+	}
+}
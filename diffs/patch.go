@@ -0,0 +1,351 @@
+package diffs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// ApplyPatch applies the given JSON Patch document, as defined in RFC 6902,
+// to the new value of the change and returns a new change from the same
+// old value to the patched new value.
+//
+// JSON Pointers in the patch are resolved against the structure of the
+// new value, with each token selecting an object attribute, a map key, or
+// a list or tuple index. Set elements cannot be addressed. Because object
+// types have a fixed set of attributes, "remove" on an attribute sets it
+// to null and "add" on an attribute behaves as "replace". Operations that
+// would change the type of the value, such as adding elements to a tuple,
+// are not permitted.
+//
+// The action of the result is re-derived in the same way as for Rebase.
+func (c *Change) ApplyPatch(patch []byte) (*Change, error) {
+	var ops []patchOp
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("invalid JSON patch: %s", err)
+	}
+
+	new := c.New
+	for i, op := range ops {
+		var err error
+		new, err = op.apply(new)
+		if err != nil {
+			return nil, fmt.Errorf("patch operation %d (%s %q): %s", i, op.Op, op.Path, err)
+		}
+	}
+
+	return c.rederive(c.Old, new), nil
+}
+
+type patchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (op patchOp) apply(v cty.Value) (cty.Value, error) {
+	tokens, err := parsePointer(op.Path)
+	if err != nil {
+		return cty.NilVal, err
+	}
+
+	switch op.Op {
+	case "add", "replace":
+		if op.Value == nil {
+			return cty.NilVal, fmt.Errorf("missing value")
+		}
+		insert := op.Op == "add"
+		return updateAt(v, tokens, func(container cty.Value, key string) (cty.Value, error) {
+			newVal, err := decodeAt(container, key, op.Value)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			return setChild(container, key, newVal, insert)
+		}, func(old cty.Value) (cty.Value, error) {
+			return ctyjson.Unmarshal(op.Value, old.Type())
+		})
+	case "remove":
+		return updateAt(v, tokens, removeChild, func(old cty.Value) (cty.Value, error) {
+			return cty.NullVal(old.Type()), nil
+		})
+	case "test":
+		got, err := getAt(v, tokens)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		want, err := ctyjson.Unmarshal(op.Value, got.Type())
+		if err != nil {
+			return cty.NilVal, err
+		}
+		if !got.RawEquals(want) {
+			return cty.NilVal, fmt.Errorf("test failed")
+		}
+		return v, nil
+	case "move", "copy":
+		fromTokens, err := parsePointer(op.From)
+		if err != nil {
+			return cty.NilVal, fmt.Errorf("invalid from pointer: %s", err)
+		}
+		moving, err := getAt(v, fromTokens)
+		if err != nil {
+			return cty.NilVal, fmt.Errorf("invalid from pointer: %s", err)
+		}
+		if op.Op == "move" {
+			v, err = updateAt(v, fromTokens, removeChild, func(old cty.Value) (cty.Value, error) {
+				return cty.NullVal(old.Type()), nil
+			})
+			if err != nil {
+				return cty.NilVal, err
+			}
+		}
+		return updateAt(v, tokens, func(container cty.Value, key string) (cty.Value, error) {
+			ty, err := childType(container, key)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			newVal, err := convert.Convert(moving, ty)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			return setChild(container, key, newVal, true)
+		}, func(old cty.Value) (cty.Value, error) {
+			return convert.Convert(moving, old.Type())
+		})
+	default:
+		return cty.NilVal, fmt.Errorf("unsupported operation")
+	}
+}
+
+// parsePointer splits the given JSON Pointer into its unescaped tokens.
+func parsePointer(ptr string) ([]string, error) {
+	if ptr == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(ptr, "/") {
+		return nil, fmt.Errorf("JSON pointer must start with a slash")
+	}
+	tokens := strings.Split(ptr[1:], "/")
+	for i, tok := range tokens {
+		tok = strings.Replace(tok, "~1", "/", -1)
+		tokens[i] = strings.Replace(tok, "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+// updateAt returns a copy of the given value where the container selected
+// by all but the last of the given tokens has been replaced with the result
+// of calling fn with that container and the last token. If there are no
+// tokens at all, root is called with the whole value instead.
+func updateAt(v cty.Value, tokens []string, fn func(container cty.Value, key string) (cty.Value, error), root func(cty.Value) (cty.Value, error)) (cty.Value, error) {
+	switch len(tokens) {
+	case 0:
+		return root(v)
+	case 1:
+		if err := requireContainer(v); err != nil {
+			return cty.NilVal, err
+		}
+		return fn(v, tokens[0])
+	}
+
+	child, err := getChild(v, tokens[0])
+	if err != nil {
+		return cty.NilVal, err
+	}
+	newChild, err := updateAt(child, tokens[1:], fn, root)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return setChild(v, tokens[0], newChild, false)
+}
+
+func getAt(v cty.Value, tokens []string) (cty.Value, error) {
+	for _, tok := range tokens {
+		var err error
+		v, err = getChild(v, tok)
+		if err != nil {
+			return cty.NilVal, err
+		}
+	}
+	return v, nil
+}
+
+func requireContainer(v cty.Value) error {
+	switch {
+	case !v.IsKnown():
+		return fmt.Errorf("cannot traverse into an unknown value")
+	case v.IsNull():
+		return fmt.Errorf("cannot traverse into a null value")
+	}
+	ty := v.Type()
+	if !(ty.IsObjectType() || ty.IsMapType() || ty.IsListType() || ty.IsTupleType()) {
+		return fmt.Errorf("cannot traverse into a value of type %s", ty.FriendlyName())
+	}
+	return nil
+}
+
+func getChild(v cty.Value, key string) (cty.Value, error) {
+	if err := requireContainer(v); err != nil {
+		return cty.NilVal, err
+	}
+	ty := v.Type()
+	switch {
+	case ty.IsObjectType():
+		if !ty.HasAttribute(key) {
+			return cty.NilVal, fmt.Errorf("no attribute named %q", key)
+		}
+		return v.GetAttr(key), nil
+	case ty.IsMapType():
+		elems := mapElements(v)
+		ev, exists := elems[key]
+		if !exists {
+			return cty.NilVal, fmt.Errorf("no map element with key %q", key)
+		}
+		return ev, nil
+	default:
+		elems := sequenceElements(v)
+		idx, err := sequenceIndex(key, len(elems), false)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		return elems[idx], nil
+	}
+}
+
+func childType(container cty.Value, key string) (cty.Type, error) {
+	ty := container.Type()
+	switch {
+	case ty.IsObjectType():
+		if !ty.HasAttribute(key) {
+			return cty.NilType, fmt.Errorf("no attribute named %q", key)
+		}
+		return ty.AttributeType(key), nil
+	case ty.IsTupleType():
+		idx, err := sequenceIndex(key, ty.Length(), false)
+		if err != nil {
+			return cty.NilType, err
+		}
+		return ty.TupleElementType(idx), nil
+	default:
+		return ty.ElementType(), nil
+	}
+}
+
+func decodeAt(container cty.Value, key string, raw json.RawMessage) (cty.Value, error) {
+	ty, err := childType(container, key)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return ctyjson.Unmarshal(raw, ty)
+}
+
+// setChild returns a copy of the given container with the given child
+// value at the given key. If insert is set, a new list element is inserted
+// at the given index rather than replacing the existing one, and a new map
+// element may be created.
+func setChild(container cty.Value, key string, child cty.Value, insert bool) (cty.Value, error) {
+	ty := container.Type()
+	switch {
+	case ty.IsObjectType():
+		if !ty.HasAttribute(key) {
+			return cty.NilVal, fmt.Errorf("no attribute named %q", key)
+		}
+		attrs := make(map[string]cty.Value)
+		for name := range ty.AttributeTypes() {
+			attrs[name] = container.GetAttr(name)
+		}
+		attrs[key] = child
+		return cty.ObjectVal(attrs), nil
+	case ty.IsMapType():
+		elems := mapElements(container)
+		if _, exists := elems[key]; !exists && !insert {
+			return cty.NilVal, fmt.Errorf("no map element with key %q", key)
+		}
+		elems[key] = child
+		return cty.MapVal(elems), nil
+	case ty.IsTupleType():
+		if insert {
+			return cty.NilVal, fmt.Errorf("cannot add elements to a tuple")
+		}
+		elems := sequenceElements(container)
+		idx, err := sequenceIndex(key, len(elems), false)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		elems[idx] = child
+		return cty.TupleVal(elems), nil
+	default:
+		elems := sequenceElements(container)
+		idx, err := sequenceIndex(key, len(elems), insert)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		if insert {
+			elems = append(elems, cty.NilVal)
+			copy(elems[idx+1:], elems[idx:])
+		}
+		elems[idx] = child
+		return cty.ListVal(elems), nil
+	}
+}
+
+func removeChild(container cty.Value, key string) (cty.Value, error) {
+	ty := container.Type()
+	switch {
+	case ty.IsObjectType():
+		if !ty.HasAttribute(key) {
+			return cty.NilVal, fmt.Errorf("no attribute named %q", key)
+		}
+		return setChild(container, key, cty.NullVal(ty.AttributeType(key)), false)
+	case ty.IsMapType():
+		elems := mapElements(container)
+		if _, exists := elems[key]; !exists {
+			return cty.NilVal, fmt.Errorf("no map element with key %q", key)
+		}
+		delete(elems, key)
+		if len(elems) == 0 {
+			return cty.MapValEmpty(ty.ElementType()), nil
+		}
+		return cty.MapVal(elems), nil
+	case ty.IsTupleType():
+		return cty.NilVal, fmt.Errorf("cannot remove elements from a tuple")
+	default:
+		elems := sequenceElements(container)
+		idx, err := sequenceIndex(key, len(elems), false)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		elems = append(elems[:idx], elems[idx+1:]...)
+		if len(elems) == 0 {
+			return cty.ListValEmpty(ty.ElementType()), nil
+		}
+		return cty.ListVal(elems), nil
+	}
+}
+
+// sequenceIndex parses the given JSON Pointer token as an index into a
+// sequence of the given length. If insert is set then the index may also
+// refer to the position just after the last element, either by number or
+// by the special token "-".
+func sequenceIndex(tok string, length int, insert bool) (int, error) {
+	max := length - 1
+	if insert {
+		max = length
+		if tok == "-" {
+			return length, nil
+		}
+	}
+	idx, err := strconv.Atoi(tok)
+	if err != nil || idx < 0 || (tok != "0" && strings.HasPrefix(tok, "0")) {
+		return 0, fmt.Errorf("invalid index %q", tok)
+	}
+	if idx > max {
+		return 0, fmt.Errorf("index %d is out of range", idx)
+	}
+	return idx, nil
+}
@@ -0,0 +1,151 @@
+package diffs
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestChangeApplyPatch(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"name":  cty.String,
+		"ports": cty.List(cty.Number),
+		"tags":  cty.Map(cty.String),
+	})
+	old := cty.ObjectVal(map[string]cty.Value{
+		"name":  cty.StringVal("foo"),
+		"ports": cty.ListVal([]cty.Value{cty.NumberIntVal(80)}),
+		"tags": cty.MapVal(map[string]cty.Value{
+			"env": cty.StringVal("prod"),
+		}),
+	})
+	new := cty.ObjectVal(map[string]cty.Value{
+		"name":  cty.StringVal("bar"),
+		"ports": cty.ListVal([]cty.Value{cty.NumberIntVal(80)}),
+		"tags": cty.MapVal(map[string]cty.Value{
+			"env": cty.StringVal("prod"),
+		}),
+	})
+
+	tests := map[string]struct {
+		Change     *Change
+		Patch      string
+		WantAction Action
+		WantNew    cty.Value
+		WantErr    bool
+	}{
+		"replace re-derives update": {
+			NewNoOp(ty, old),
+			`[{"op": "replace", "path": "/name", "value": "baz"}]`,
+			Update,
+			cty.ObjectVal(map[string]cty.Value{
+				"name":  cty.StringVal("baz"),
+				"ports": cty.ListVal([]cty.Value{cty.NumberIntVal(80)}),
+				"tags": cty.MapVal(map[string]cty.Value{
+					"env": cty.StringVal("prod"),
+				}),
+			}),
+			false,
+		},
+		"replace re-derives no-op": {
+			NewUpdate(ty, old, new),
+			`[{"op": "replace", "path": "/name", "value": "foo"}]`,
+			NoOp,
+			old,
+			false,
+		},
+		"replace re-derives replace": {
+			NewReplace(ty, old, new, NewPathSet(cty.Path{cty.GetAttrStep{Name: "name"}})),
+			`[{"op": "replace", "path": "/name", "value": "baz"}]`,
+			Replace,
+			cty.ObjectVal(map[string]cty.Value{
+				"name":  cty.StringVal("baz"),
+				"ports": cty.ListVal([]cty.Value{cty.NumberIntVal(80)}),
+				"tags": cty.MapVal(map[string]cty.Value{
+					"env": cty.StringVal("prod"),
+				}),
+			}),
+			false,
+		},
+		"add and remove": {
+			NewNoOp(ty, old),
+			`[
+				{"op": "add", "path": "/ports/0", "value": 443},
+				{"op": "add", "path": "/ports/-", "value": 8080},
+				{"op": "add", "path": "/tags/team", "value": "a"},
+				{"op": "remove", "path": "/tags/env"}
+			]`,
+			Update,
+			cty.ObjectVal(map[string]cty.Value{
+				"name": cty.StringVal("foo"),
+				"ports": cty.ListVal([]cty.Value{
+					cty.NumberIntVal(443),
+					cty.NumberIntVal(80),
+					cty.NumberIntVal(8080),
+				}),
+				"tags": cty.MapVal(map[string]cty.Value{
+					"team": cty.StringVal("a"),
+				}),
+			}),
+			false,
+		},
+		"move": {
+			NewNoOp(ty, old),
+			`[{"op": "move", "from": "/tags/env", "path": "/tags/environment"}]`,
+			Update,
+			cty.ObjectVal(map[string]cty.Value{
+				"name":  cty.StringVal("foo"),
+				"ports": cty.ListVal([]cty.Value{cty.NumberIntVal(80)}),
+				"tags": cty.MapVal(map[string]cty.Value{
+					"environment": cty.StringVal("prod"),
+				}),
+			}),
+			false,
+		},
+		"failed test": {
+			NewNoOp(ty, old),
+			`[{"op": "test", "path": "/name", "value": "bar"}]`,
+			NoOp,
+			cty.NilVal,
+			true,
+		},
+		"wrong type": {
+			NewNoOp(ty, old),
+			`[{"op": "replace", "path": "/ports", "value": "nope"}]`,
+			NoOp,
+			cty.NilVal,
+			true,
+		},
+		"no such attribute": {
+			NewNoOp(ty, old),
+			`[{"op": "replace", "path": "/nope", "value": "x"}]`,
+			NoOp,
+			cty.NilVal,
+			true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := test.Change.ApplyPatch([]byte(test.Patch))
+			if test.WantErr {
+				if err == nil {
+					t.Fatalf("succeeded; want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got.Action != test.WantAction {
+				t.Errorf("wrong action %s; want %s", got.Action, test.WantAction)
+			}
+			if !got.New.RawEquals(test.WantNew) {
+				t.Errorf("wrong new value\ngot:  %#v\nwant: %#v", got.New, test.WantNew)
+			}
+			if !got.Old.RawEquals(test.Change.Old) {
+				t.Errorf("old value changed\ngot:  %#v\nwant: %#v", got.Old, test.Change.Old)
+			}
+		})
+	}
+}
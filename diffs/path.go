@@ -0,0 +1,119 @@
+package diffs
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// pathKey produces a string that uniquely identifies the given path, for
+// use as a map key. Two paths produce the same key if and only if they
+// traverse the same steps.
+func pathKey(p cty.Path) string {
+	var buf bytes.Buffer
+	for _, step := range p {
+		switch ts := step.(type) {
+		case cty.GetAttrStep:
+			fmt.Fprintf(&buf, ".%s", ts.Name)
+		case cty.IndexStep:
+			buf.WriteByte('[')
+			buf.WriteString(indexKey(ts.Key))
+			buf.WriteByte(']')
+		default:
+			// There are no other step types in cty, but we'll be defensive
+			// in case new ones are added in future.
+			fmt.Fprintf(&buf, "<%#v>", step)
+		}
+	}
+	return buf.String()
+}
+
+func indexKey(key cty.Value) string {
+	if !key.IsKnown() || key.IsNull() {
+		// Unknown and null keys are not valid in practice, so we don't
+		// need to distinguish them by type.
+		return fmt.Sprintf("%#v", key)
+	}
+	ty := key.Type()
+	switch ty {
+	case cty.String:
+		return fmt.Sprintf("%q", key.AsString())
+	case cty.Number:
+		return key.AsBigFloat().Text('f', -1)
+	}
+	// For other key types, which arise when indexing into sets, we use the
+	// JSON serialization of the type and value, which is deterministic.
+	tyJSON, err := ctyjson.MarshalType(ty)
+	if err != nil {
+		return fmt.Sprintf("%#v", key)
+	}
+	valJSON, err := ctyjson.Marshal(key, ty)
+	if err != nil {
+		return fmt.Sprintf("%#v", key)
+	}
+	return fmt.Sprintf("%s:%s", tyJSON, valJSON)
+}
+
+// pathHasPrefix returns true if the given path begins with all of the steps
+// in the given prefix. Every path has the empty path as a prefix.
+func pathHasPrefix(p, prefix cty.Path) bool {
+	if len(prefix) > len(p) {
+		return false
+	}
+	return pathKey(p[:len(prefix)]) == pathKey(prefix)
+}
+
+// formatPath returns a string representation of the given path in a syntax
+// similar to Terraform's traversal syntax, for use in messages.
+func formatPath(p cty.Path) string {
+	if len(p) == 0 {
+		return "(root)"
+	}
+	var buf bytes.Buffer
+	for i, step := range p {
+		switch ts := step.(type) {
+		case cty.GetAttrStep:
+			if i > 0 {
+				buf.WriteByte('.')
+			}
+			buf.WriteString(ts.Name)
+		case cty.IndexStep:
+			buf.WriteByte('[')
+			switch {
+			case !ts.Key.IsKnown() || ts.Key.IsNull():
+				buf.WriteString("?")
+			case ts.Key.Type() == cty.String:
+				buf.WriteString(strconv.Quote(ts.Key.AsString()))
+			case ts.Key.Type() == cty.Number:
+				buf.WriteString(ts.Key.AsBigFloat().Text('f', -1))
+			default:
+				buf.WriteString(indexKey(ts.Key))
+			}
+			buf.WriteByte(']')
+		}
+	}
+	return buf.String()
+}
+
+// pathSetCovers returns true if the given path or any of its prefixes is
+// in the given set.
+func pathSetCovers(s PathSet, path cty.Path) bool {
+	for i := len(path); i >= 0; i-- {
+		if s.Has(path[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// valueKey produces a string that uniquely identifies the given value,
+// including its type.
+func valueKey(v cty.Value) string {
+	if v.IsNull() || !whollyKnown(v) {
+		return fmt.Sprintf("%#v", v)
+	}
+	return indexKey(v)
+}
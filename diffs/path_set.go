@@ -0,0 +1,110 @@
+package diffs
+
+import (
+	"sort"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// PathSet is a set of cty.Path values.
+//
+// The zero value of PathSet is an empty set that can be read from but not
+// added to. Use NewPathSet to create a set that can be modified.
+type PathSet struct {
+	set map[string]cty.Path
+}
+
+// NewPathSet creates and returns a new PathSet containing the given paths.
+func NewPathSet(paths ...cty.Path) PathSet {
+	ret := PathSet{
+		set: make(map[string]cty.Path, len(paths)),
+	}
+	for _, p := range paths {
+		ret.Add(p)
+	}
+	return ret
+}
+
+// Add inserts the given path into the set, if it is not already present.
+//
+// Add will panic if called on a zero-value PathSet.
+func (s PathSet) Add(p cty.Path) {
+	s.set[pathKey(p)] = p.Copy()
+}
+
+// Remove deletes the given path from the set, if it is present.
+func (s PathSet) Remove(p cty.Path) {
+	delete(s.set, pathKey(p))
+}
+
+// Has returns true if the given path is in the set.
+func (s PathSet) Has(p cty.Path) bool {
+	_, exists := s.set[pathKey(p)]
+	return exists
+}
+
+// Empty returns true if the set contains no paths.
+func (s PathSet) Empty() bool {
+	return len(s.set) == 0
+}
+
+// Len returns the number of paths in the set.
+func (s PathSet) Len() int {
+	return len(s.set)
+}
+
+// List returns the paths in the set as a slice. The result is in a stable
+// order, in which each path appears after any of its prefixes that are
+// also in the set.
+func (s PathSet) List() []cty.Path {
+	if len(s.set) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(s.set))
+	for k := range s.set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ret := make([]cty.Path, len(keys))
+	for i, k := range keys {
+		ret[i] = s.set[k]
+	}
+	return ret
+}
+
+// Union returns a new set containing the paths that are in either the
+// receiver or the other given set.
+func (s PathSet) Union(other PathSet) PathSet {
+	ret := NewPathSet()
+	for k, p := range s.set {
+		ret.set[k] = p
+	}
+	for k, p := range other.set {
+		ret.set[k] = p
+	}
+	return ret
+}
+
+// Intersect returns a new set containing only the paths that are in both
+// the receiver and the other given set.
+func (s PathSet) Intersect(other PathSet) PathSet {
+	ret := NewPathSet()
+	for k, p := range s.set {
+		if _, exists := other.set[k]; exists {
+			ret.set[k] = p
+		}
+	}
+	return ret
+}
+
+// ContainsPrefix returns true if any path in the set has the given path as
+// a prefix, including the given path itself. This is true of the empty
+// path for any non-empty set.
+func (s PathSet) ContainsPrefix(prefix cty.Path) bool {
+	for _, p := range s.set {
+		if pathHasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package diffs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestPathSetOperations(t *testing.T) {
+	a := cty.Path{cty.GetAttrStep{Name: "a"}}
+	ab := cty.Path{cty.GetAttrStep{Name: "a"}, cty.GetAttrStep{Name: "b"}}
+	c := cty.Path{cty.GetAttrStep{Name: "c"}}
+	d0 := cty.Path{cty.GetAttrStep{Name: "d"}, cty.IndexStep{Key: cty.NumberIntVal(0)}}
+
+	s1 := NewPathSet(c, ab)
+	s2 := NewPathSet(d0, a, c)
+
+	tests := map[string]struct {
+		Got  PathSet
+		Want []string
+	}{
+		"union": {
+			s1.Union(s2),
+			[]string{"a", "a.b", "c", "d[0]"},
+		},
+		"intersect": {
+			s1.Intersect(s2),
+			[]string{"c"},
+		},
+		"intersect disjoint": {
+			NewPathSet(ab).Intersect(NewPathSet(a)),
+			nil,
+		},
+		"union with zero value": {
+			s1.Union(PathSet{}),
+			[]string{"a.b", "c"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			for _, p := range test.Got.List() {
+				got = append(got, formatPath(p))
+			}
+			if !reflect.DeepEqual(got, test.Want) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, test.Want)
+			}
+		})
+	}
+}
+
+func TestPathSetContainsPrefix(t *testing.T) {
+	s := NewPathSet(
+		cty.Path{cty.GetAttrStep{Name: "network"}, cty.IndexStep{Key: cty.NumberIntVal(0)}, cty.GetAttrStep{Name: "subnet"}},
+	)
+
+	tests := map[string]struct {
+		Prefix cty.Path
+		Want   bool
+	}{
+		"root": {
+			nil,
+			true,
+		},
+		"block": {
+			cty.Path{cty.GetAttrStep{Name: "network"}},
+			true,
+		},
+		"element": {
+			cty.Path{cty.GetAttrStep{Name: "network"}, cty.IndexStep{Key: cty.NumberIntVal(0)}},
+			true,
+		},
+		"exact": {
+			cty.Path{cty.GetAttrStep{Name: "network"}, cty.IndexStep{Key: cty.NumberIntVal(0)}, cty.GetAttrStep{Name: "subnet"}},
+			true,
+		},
+		"other element": {
+			cty.Path{cty.GetAttrStep{Name: "network"}, cty.IndexStep{Key: cty.NumberIntVal(1)}},
+			false,
+		},
+		"longer": {
+			cty.Path{cty.GetAttrStep{Name: "network"}, cty.IndexStep{Key: cty.NumberIntVal(0)}, cty.GetAttrStep{Name: "subnet"}, cty.GetAttrStep{Name: "id"}},
+			false,
+		},
+		"other block": {
+			cty.Path{cty.GetAttrStep{Name: "tags"}},
+			false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := s.ContainsPrefix(test.Prefix); got != test.Want {
+				t.Errorf("wrong result %t; want %t", got, test.Want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package diffs
+
+import (
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Redacted returns a copy of the receiver in which the known values at
+// each of the Sensitive paths have been replaced with nulls, in both the
+// old and new values. Unknown values are retained, since they reveal
+// nothing. The action and path sets are unchanged, so the result still
+// describes the same change.
+//
+// Set elements have no paths of their own, so a sensitive path through a
+// set is redacted within every element of the set, rebuilding the set from
+// the redacted elements. A sensitive path to a set element itself redacts
+// the whole set. Sensitive paths that don't exist within a value, such as
+// those within a null object, are ignored.
+func (c *Change) Redacted() *Change {
+	ret := *c
+	ret.Old = redactValue(c.Old, c.Sensitive)
+	ret.New = redactValue(c.New, c.Sensitive)
+	return &ret
+}
+
+func redactValue(v cty.Value, sensitive PathSet) cty.Value {
+	for _, p := range sensitive.List() {
+		v = redactAt(v, p)
+	}
+	return v
+}
+
+// redactAt returns a copy of the given value with the known value at the
+// given path replaced with a null, as described for Change.Redacted.
+func redactAt(v cty.Value, p cty.Path) cty.Value {
+	if !v.IsKnown() {
+		return v
+	}
+	if len(p) == 0 {
+		return cty.NullVal(v.Type())
+	}
+	if v.IsNull() {
+		return v
+	}
+	if v.Type().IsSetType() {
+		switch {
+		case len(p) == 1:
+			return cty.NullVal(v.Type())
+		case v.LengthInt() == 0:
+			return v
+		}
+		var elems []cty.Value
+		for it := v.ElementIterator(); it.Next(); {
+			_, ev := it.Element()
+			elems = append(elems, redactAt(ev, p[1:]))
+		}
+		return cty.SetVal(elems)
+	}
+
+	tok, err := stepToken(p[0])
+	if err != nil {
+		return v
+	}
+	child, err := getChild(v, tok)
+	if err != nil {
+		// The path doesn't exist in this value, so there's nothing to
+		// redact.
+		return v
+	}
+	ret, err := setChild(v, tok, redactAt(child, p[1:]), false)
+	if err != nil {
+		return v
+	}
+	return ret
+}
+
+// sensitiveMarkers returns the given sensitive paths within a value of the
+// given type as they are to be serialized. The key of a step into a set is
+// the set element itself, which may be the very value that's sensitive, so
+// each path through a set is cut short to mark the whole set instead.
+func sensitiveMarkers(ty cty.Type, paths []cty.Path) []cty.Path {
+	ret := NewPathSet()
+	for _, p := range paths {
+		ret.Add(truncateAtSet(ty, p))
+	}
+	return ret.List()
+}
+
+// truncateAtSet returns the prefix of the given path, within a value of the
+// given type, that leads to the first set it steps into, or the whole path
+// if it steps into none. Index steps with keys that can't have come from a
+// list, tuple or map are treated as set steps too, in case the type is too
+// dynamic to tell.
+func truncateAtSet(ty cty.Type, p cty.Path) cty.Path {
+	for i, step := range p {
+		if is, ok := step.(cty.IndexStep); ok && is.Key.Type() != cty.String && is.Key.Type() != cty.Number {
+			return p[:i]
+		}
+		switch {
+		case ty.IsSetType():
+			return p[:i]
+		case ty.IsObjectType():
+			as, ok := step.(cty.GetAttrStep)
+			if !ok || !ty.HasAttribute(as.Name) {
+				return p
+			}
+			ty = ty.AttributeType(as.Name)
+		case ty.IsTupleType():
+			tok, err := stepToken(step)
+			if err != nil {
+				return p
+			}
+			idx, err := sequenceIndex(tok, ty.Length(), false)
+			if err != nil {
+				return p
+			}
+			ty = ty.TupleElementType(idx)
+		case ty.IsMapType() || ty.IsListType():
+			ty = ty.ElementType()
+		default:
+			return p
+		}
+	}
+	return p
+}
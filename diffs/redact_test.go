@@ -0,0 +1,153 @@
+package diffs
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestChangeRedacted(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"name":     cty.String,
+		"password": cty.String,
+		"token":    cty.String,
+	})
+	c := NewUpdate(
+		ty,
+		cty.ObjectVal(map[string]cty.Value{
+			"name":     cty.StringVal("foo"),
+			"password": cty.StringVal("hunter2"),
+			"token":    cty.StringVal("abc"),
+		}),
+		cty.ObjectVal(map[string]cty.Value{
+			"name":     cty.StringVal("bar"),
+			"password": cty.StringVal("hunter3"),
+			"token":    cty.UnknownVal(cty.String),
+		}),
+	)
+	c.Sensitive = NewPathSet(
+		cty.Path{cty.GetAttrStep{Name: "password"}},
+		cty.Path{cty.GetAttrStep{Name: "token"}},
+		cty.Path{cty.GetAttrStep{Name: "missing"}},
+	)
+
+	got := c.Redacted()
+
+	wantOld := cty.ObjectVal(map[string]cty.Value{
+		"name":     cty.StringVal("foo"),
+		"password": cty.NullVal(cty.String),
+		"token":    cty.NullVal(cty.String),
+	})
+	wantNew := cty.ObjectVal(map[string]cty.Value{
+		"name":     cty.StringVal("bar"),
+		"password": cty.NullVal(cty.String),
+		"token":    cty.UnknownVal(cty.String),
+	})
+	if !got.Old.RawEquals(wantOld) {
+		t.Errorf("wrong old value\ngot:  %#v\nwant: %#v", got.Old, wantOld)
+	}
+	if !got.New.RawEquals(wantNew) {
+		t.Errorf("wrong new value\ngot:  %#v\nwant: %#v", got.New, wantNew)
+	}
+	if got.Action != Update {
+		t.Errorf("wrong action %s; want %s", got.Action, Update)
+	}
+	if c.Old.GetAttr("password").IsNull() {
+		t.Errorf("receiver was modified")
+	}
+
+	if _, err := c.MarshalJSONWith(MarshalOpts{RedactSensitive: true, StrictSensitive: true}); err != nil {
+		t.Errorf("unexpected error marshaling redacted change: %s", err)
+	}
+}
+
+func TestChangeRedacted_setOfObjects(t *testing.T) {
+	userTy := cty.Object(map[string]cty.Type{
+		"name":     cty.String,
+		"password": cty.String,
+	})
+	ty := cty.Object(map[string]cty.Type{
+		"users": cty.Set(userTy),
+		"keys":  cty.Set(cty.String),
+	})
+	admin := cty.ObjectVal(map[string]cty.Value{
+		"name":     cty.StringVal("admin"),
+		"password": cty.StringVal("hunter2"),
+	})
+	c := NewUpdate(
+		ty,
+		cty.ObjectVal(map[string]cty.Value{
+			"users": cty.SetVal([]cty.Value{admin}),
+			"keys":  cty.SetVal([]cty.Value{cty.StringVal("abc")}),
+		}),
+		cty.ObjectVal(map[string]cty.Value{
+			"users": cty.SetVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{
+					"name":     cty.StringVal("admin"),
+					"password": cty.StringVal("hunter3"),
+				}),
+				cty.ObjectVal(map[string]cty.Value{
+					"name":     cty.StringVal("guest"),
+					"password": cty.UnknownVal(cty.String),
+				}),
+			}),
+			"keys": cty.SetVal([]cty.Value{cty.StringVal("def")}),
+		}),
+	)
+	c.Sensitive = NewPathSet(
+		cty.Path{cty.GetAttrStep{Name: "users"}, cty.IndexStep{Key: admin}, cty.GetAttrStep{Name: "password"}},
+		cty.Path{cty.GetAttrStep{Name: "keys"}, cty.IndexStep{Key: cty.StringVal("abc")}},
+	)
+
+	got := c.Redacted()
+
+	wantOld := cty.ObjectVal(map[string]cty.Value{
+		"users": cty.SetVal([]cty.Value{
+			cty.ObjectVal(map[string]cty.Value{
+				"name":     cty.StringVal("admin"),
+				"password": cty.NullVal(cty.String),
+			}),
+		}),
+		"keys": cty.NullVal(cty.Set(cty.String)),
+	})
+	wantNew := cty.ObjectVal(map[string]cty.Value{
+		"users": cty.SetVal([]cty.Value{
+			cty.ObjectVal(map[string]cty.Value{
+				"name":     cty.StringVal("admin"),
+				"password": cty.NullVal(cty.String),
+			}),
+			cty.ObjectVal(map[string]cty.Value{
+				"name":     cty.StringVal("guest"),
+				"password": cty.UnknownVal(cty.String),
+			}),
+		}),
+		"keys": cty.NullVal(cty.Set(cty.String)),
+	})
+	if !got.Old.RawEquals(wantOld) {
+		t.Errorf("wrong old value\ngot:  %#v\nwant: %#v", got.Old, wantOld)
+	}
+	if !got.New.RawEquals(wantNew) {
+		t.Errorf("wrong new value\ngot:  %#v\nwant: %#v", got.New, wantNew)
+	}
+
+	buf, err := c.MarshalJSONWith(MarshalOpts{RedactSensitive: true, StrictSensitive: true})
+	if err != nil {
+		t.Fatalf("unexpected error marshaling redacted change: %s", err)
+	}
+	for _, secret := range []string{"hunter2", "hunter3", "abc", "def"} {
+		if strings.Contains(string(buf), secret) {
+			t.Errorf("sensitive value %q serialized in cleartext:\n%s", secret, buf)
+		}
+	}
+	var raw struct {
+		Sensitive []pathJSON `json:"sensitive"`
+	}
+	if err := json.Unmarshal(buf, &raw); err != nil {
+		t.Fatalf("result is not valid JSON: %s", err)
+	}
+	if got, want := string(mustMarshal(t, raw.Sensitive)), `[["keys"],["users"]]`; got != want {
+		t.Errorf("wrong sensitive paths %s; want %s", got, want)
+	}
+}
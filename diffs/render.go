@@ -0,0 +1,723 @@
+package diffs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+	"golang.org/x/text/language"
+)
+
+// RenderOpts customizes the output produced by Change.Render and
+// Change.RenderTo.
+//
+// The zero value of RenderOpts selects the default rendering.
+type RenderOpts struct {
+	// AnnotateInPlace, if set, causes the renderer to note "(updated in
+	// place)" beside any changed path in ForceNewCapable when rendering
+	// an Update change, to reassure the reader that the change will not
+	// cause the object to be recreated.
+	AnnotateInPlace bool
+
+	// ForceNewCapable is the set of paths that can force replacement under
+	// some circumstances, even though they did not do so for the change
+	// being rendered. It is used only when AnnotateInPlace is set.
+	ForceNewCapable PathSet
+
+	// GroupByLeafAction, if set, groups the attributes within each object
+	// or map so that all of the removed attributes appear first, followed
+	// by those that are updated, then those that are added, and finally
+	// those that are unchanged. Otherwise, attributes are in lexical order.
+	GroupByLeafAction bool
+
+	// Locale, if set, selects the locale whose digits, digit grouping and
+	// decimal separator are used for numbers, as formatted by the
+	// golang.org/x/text/message package. The zero value renders numbers in
+	// the same way as cty's own formatting, with no grouping.
+	Locale language.Tag
+
+	// BoolWords, if set, gives the words to use when rendering boolean
+	// values, indexed by the numeric value of the boolean: the word for
+	// false first, then the word for true. The zero value renders
+	// booleans as "false" and "true".
+	BoolWords [2]string
+
+	// Classify, if set, is called for each primitive, null or unknown value
+	// to be rendered, and returns the kind of value it should be displayed
+	// as. If it is not set, DefaultClassify is used.
+	//
+	// Strings classified as KindReference are rendered without quotes.
+	// Other kinds do not change the text of the value, but are passed to
+	// Decorate.
+	Classify func(path cty.Path, v cty.Value) ValueKind
+
+	// Decorate, if set, is called with the kind and rendered text of each
+	// primitive, null or unknown value and returns the text to include in
+	// the output. Frontends can use this to add syntax highlighting.
+	Decorate func(kind ValueKind, text string) string
+
+	// SetElementKeys, if set, is called for each set of objects to be
+	// rendered and returns the names of the attributes that identify its
+	// elements, or nil if they have none. An element whose identifying
+	// attributes are unchanged is rendered as updated in place rather than
+	// as removed and added. See CorrelateSetElements.
+	SetElementKeys func(path cty.Path) []string
+
+	// CorrelateSets, if set, causes the removed and added elements of sets
+	// of objects without identifying attributes to be paired by their
+	// similarity, so that an element in which only some attributes have
+	// changed is rendered as updated in place.
+	CorrelateSets bool
+
+	// DecorateMarker, if set, is called with each action marker and the
+	// action it indicates, and returns the text to include in the output
+	// in its place. Package diffs/render uses this to colorize markers.
+	DecorateMarker func(action Action, marker string) string
+
+	// DecorateReplaceNote, if set, is called with the text of each
+	// annotation that explains why the object must be replaced, such as
+	// "forces replacement", and returns the text to include in the output
+	// in its place.
+	DecorateReplaceNote func(text string) string
+}
+
+// Render returns a human-oriented representation of the change, in a
+// syntax similar to Terraform's native configuration language, with
+// markers indicating the action for each changed attribute or element.
+// Values at the change's Sensitive paths are always shown as
+// "(sensitive value)".
+func (c *Change) Render(opts RenderOpts) string {
+	var buf bytes.Buffer
+	c.RenderTo(&buf, opts) // writing to a bytes.Buffer cannot fail
+	return buf.String()
+}
+
+// RenderTo is like Render except that it writes the result to the given
+// writer, returning any error the writer produces.
+func (c *Change) RenderTo(w io.Writer, opts RenderOpts) error {
+	r := &renderer{
+		opts:   opts,
+		change: c,
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(r.marker(c.Action))
+	buf.WriteByte(' ')
+	if c.Action == Forget {
+		// The old value remains as-is, so we show it unchanged rather
+		// than as being removed.
+		plain := *r
+		plain.plain = true
+		buf.WriteString(plain.lineValue(nil, c.Old, c.Old, 0))
+	} else {
+		buf.WriteString(r.lineValue(nil, c.Old, r.computedNew(nil, c.New), 0))
+	}
+	buf.WriteByte('\n')
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// actionMarkers are the prefixes used to indicate the action for the
+// top-level change and for each nested attribute or element.
+var actionMarkers = map[Action]string{
+	NoOp:    " ",
+	Create:  "+",
+	Read:    "<=",
+	Update:  "~",
+	Replace: "-/+",
+	Delete:  "-",
+	Forget:  ".",
+}
+
+type renderer struct {
+	opts   RenderOpts
+	change *Change
+
+	// plain disables annotations, for rendering values that are not
+	// themselves part of the change, such as the old value of a
+	// collection whose new value is unknown.
+	plain bool
+}
+
+// marker returns the marker for the given action, decorated if requested.
+func (r *renderer) marker(action Action) string {
+	if r.opts.DecorateMarker != nil {
+		return r.opts.DecorateMarker(action, actionMarkers[action])
+	}
+	return actionMarkers[action]
+}
+
+// replaceNote returns the given annotation explaining a replacement,
+// decorated if requested.
+func (r *renderer) replaceNote(text string) string {
+	if r.opts.DecorateReplaceNote != nil {
+		return r.opts.DecorateReplaceNote(text)
+	}
+	return text
+}
+
+// annotatedValue renders the given value pair and then appends any
+// annotations for the given path. For collections that are rendered
+// element-by-element the annotations are placed at the end of the first
+// line, beside the opening bracket, and otherwise at the very end.
+func (r *renderer) annotatedValue(path cty.Path, old, new cty.Value, depth int) string {
+	return r.annotate(path, old, new, r.value(path, old, new, depth))
+}
+
+// annotate appends any annotations for the given path to the given
+// rendered value.
+func (r *renderer) annotate(path cty.Path, old, new cty.Value, val string) string {
+	if r.plain {
+		return val
+	}
+	notes := r.annotations(path, old, new)
+	if len(notes) == 0 {
+		return val
+	}
+	comment := " # " + strings.Join(notes, ", ")
+
+	if _, ok := collectionType(old, new); ok {
+		if nl := strings.IndexByte(val, '\n'); nl >= 0 {
+			return val[:nl] + comment + val[nl:]
+		}
+	}
+	return val + comment
+}
+
+func (r *renderer) annotations(path cty.Path, old, new cty.Value) []string {
+	var notes []string
+	if r.change.Action == Replace && len(path) == 0 {
+		switch r.change.ReplaceReason {
+		case ReplaceByRequest:
+			notes = append(notes, r.replaceNote("replace requested by user"))
+		case ReplaceBecauseTainted:
+			notes = append(notes, r.replaceNote("tainted, so must be replaced"))
+		}
+	}
+	if r.change.Action == Replace {
+		switch r.forcesReplacement(path, new) {
+		case ReplaceBecauseCannotUpdate:
+			notes = append(notes, r.replaceNote("forces replacement"))
+		case ReplaceBecauseDependency:
+			notes = append(notes, r.replaceNote("forces replacement")+" due to a replaced dependency")
+		}
+	}
+	if r.opts.AnnotateInPlace && r.change.Action == Update && leafAction(old, new) == Update && r.opts.ForceNewCapable.Has(path) {
+		notes = append(notes, "(updated in place)")
+	}
+	if refs := r.change.UnknownSources.Get(path); len(refs) > 0 && !new.IsKnown() {
+		notes = append(notes, "from "+strings.Join(refs, ", "))
+	}
+	return notes
+}
+
+// forcesReplacement returns the reason that the given path forces
+// replacement, or ReplaceReasonNone if it does not. If the new value is
+// unknown then the paths nested within it can't be rendered separately, so
+// the annotation is shown here if any of those paths forced replacement,
+// with the reason ReplaceBecauseDependency only if all of them have it.
+func (r *renderer) forcesReplacement(path cty.Path, new cty.Value) ReplaceReason {
+	if r.change.ForcedReplace.Has(path) {
+		return r.change.ReplaceReasonFor(path)
+	}
+	if new.IsKnown() || !r.change.ForcedReplace.ContainsPrefix(path) {
+		return ReplaceReasonNone
+	}
+	for _, p := range r.change.ForcedReplace.List() {
+		if pathHasPrefix(p, path) && r.change.ReplaceReasonFor(p) != ReplaceBecauseDependency {
+			return ReplaceBecauseCannotUpdate
+		}
+	}
+	return ReplaceBecauseDependency
+}
+
+// value renders the given pair of values. The result may span multiple
+// lines, in which case depth is used to indent the subsequent lines.
+func (r *renderer) value(path cty.Path, old, new cty.Value, depth int) string {
+	if ty, ok := collectionType(old, new); ok {
+		switch {
+		case ty.IsObjectType():
+			return r.object(path, ty, old, new, depth)
+		case ty.IsMapType():
+			return r.mapping(path, ty, old, new, depth)
+		case ty.IsListType() || ty.IsTupleType():
+			return r.sequence(path, ty, old, new, depth)
+		case ty.IsSetType():
+			return r.set(path, ty, old, new, depth)
+		}
+	}
+
+	switch leafAction(old, new) {
+	case NoOp, Create:
+		return r.leaf(path, new, depth)
+	case Delete:
+		return r.leaf(path, old, depth) + " -> null"
+	default:
+		return r.leaf(path, old, depth) + " -> " + r.leaf(path, new, depth)
+	}
+}
+
+func (r *renderer) leaf(path cty.Path, v cty.Value, depth int) string {
+	if v.IsKnown() && !v.IsNull() {
+		if ty := v.Type(); ty.IsObjectType() || ty.IsMapType() || ty.IsListType() || ty.IsTupleType() || ty.IsSetType() {
+			// Render the whole value as unchanged, since we only get here
+			// for collections that can't be compared element-by-element,
+			// such as when the other value is unknown or of a different
+			// type.
+			plain := *r
+			plain.plain = true
+			return plain.value(path, v, v, depth)
+		}
+	}
+
+	classify := r.opts.Classify
+	if classify == nil {
+		classify = DefaultClassify
+	}
+	kind := classify(path, v)
+	text := r.leafText(kind, v)
+	if r.opts.Decorate != nil {
+		text = r.opts.Decorate(kind, text)
+	}
+	return text
+}
+
+// leafText formats the given primitive, null or unknown value. The kind
+// selects between alternative presentations where they are compatible with
+// the value's type; it cannot, for example, cause a string to be rendered
+// as a number.
+func (r *renderer) leafText(kind ValueKind, v cty.Value) string {
+	switch {
+	case !v.IsKnown():
+		return "(known after apply)"
+	case v.IsNull():
+		return "null"
+	}
+
+	switch ty := v.Type(); {
+	case ty == cty.String:
+		if kind == KindReference {
+			return v.AsString()
+		}
+		return strconv.Quote(v.AsString())
+	case ty == cty.Number:
+		if r.opts.Locale != language.Und {
+			return formatNumber(v.AsBigFloat(), r.opts.Locale)
+		}
+		return v.AsBigFloat().Text('f', -1)
+	case ty == cty.Bool:
+		if r.opts.BoolWords != [2]string{} {
+			if v.True() {
+				return r.opts.BoolWords[1]
+			}
+			return r.opts.BoolWords[0]
+		}
+		if v.True() {
+			return "true"
+		}
+		return "false"
+	default:
+		return fmt.Sprintf("%#v", v)
+	}
+}
+
+func (r *renderer) object(path cty.Path, ty cty.Type, old, new cty.Value, depth int) string {
+	atys := ty.AttributeTypes()
+	if len(atys) == 0 {
+		return "{}"
+	}
+	names := make([]string, 0, len(atys))
+	width := 0
+	for name := range atys {
+		names = append(names, name)
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+	sort.Strings(names)
+
+	var lines attrLines
+	for _, name := range names {
+		aty := atys[name]
+		r.attrLine(&lines, path.GetAttr(name), name, width, attrOrNull(old, name, aty), attrOrNull(new, name, aty), depth+1)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	lines.writeTo(&buf, r.opts.GroupByLeafAction)
+	buf.WriteString(closing(depth, "}"))
+	return buf.String()
+}
+
+func (r *renderer) mapping(path cty.Path, ty cty.Type, old, new cty.Value, depth int) string {
+	ety := ty.ElementType()
+	oldElems := mapElements(old)
+	newElems := mapElements(new)
+
+	keys := make([]string, 0, len(oldElems)+len(newElems))
+	width := 0
+	for k := range oldElems {
+		keys = append(keys, k)
+	}
+	for k := range newElems {
+		if _, exists := oldElems[k]; !exists {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return "{}"
+	}
+	for _, k := range keys {
+		if l := len(strconv.Quote(k)); l > width {
+			width = l
+		}
+	}
+	sort.Strings(keys)
+
+	var lines attrLines
+	for _, k := range keys {
+		oldV, exists := oldElems[k]
+		if !exists {
+			oldV = cty.NullVal(ety)
+		}
+		newV, exists := newElems[k]
+		if !exists {
+			newV = cty.NullVal(ety)
+		}
+		r.attrLine(&lines, path.Index(cty.StringVal(k)), strconv.Quote(k), width, oldV, newV, depth+1)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	lines.writeTo(&buf, r.opts.GroupByLeafAction)
+	buf.WriteString(closing(depth, "}"))
+	return buf.String()
+}
+
+func (r *renderer) sequence(path cty.Path, ty cty.Type, old, new cty.Value, depth int) string {
+	oldElems := sequenceElements(old)
+	newElems := sequenceElements(new)
+	n := len(oldElems)
+	if len(newElems) > n {
+		n = len(newElems)
+	}
+	if n == 0 {
+		return "[]"
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("[\n")
+	for i := 0; i < n; i++ {
+		ety := sequenceElementType(ty, i)
+		oldV, newV := cty.NullVal(ety), cty.NullVal(ety)
+		if i < len(oldElems) {
+			oldV = oldElems[i]
+		}
+		if i < len(newElems) {
+			newV = newElems[i]
+		}
+		r.elemLine(&buf, path.Index(cty.NumberIntVal(int64(i))), oldV, newV, depth+1)
+	}
+	buf.WriteString(closing(depth, "]"))
+	return buf.String()
+}
+
+func (r *renderer) set(path cty.Path, ty cty.Type, old, new cty.Value, depth int) string {
+	ety := ty.ElementType()
+	oldElems := sequenceElements(old)
+	newElems := sequenceElements(new)
+	if len(oldElems) == 0 && len(newElems) == 0 {
+		return "[]"
+	}
+
+	if keys, ok := r.setElementKeys(path, ety); ok {
+		var buf bytes.Buffer
+		buf.WriteString("[\n")
+		for _, p := range CorrelateSetElements(old, new, keys) {
+			elemPath := path.Index(p.New)
+			if p.New.IsNull() {
+				elemPath = path.Index(p.Old)
+			}
+			r.elemLine(&buf, elemPath, p.Old, p.New, depth+1)
+		}
+		buf.WriteString(closing(depth, "]"))
+		return buf.String()
+	}
+
+	// We render the union of the old and new elements in a consistent
+	// order, marking each one as either retained, added, or removed.
+	elems := make(map[string]cty.Value, len(oldElems)+len(newElems))
+	inOld := make(map[string]bool, len(oldElems))
+	for _, v := range oldElems {
+		k := indexKey(v)
+		elems[k] = v
+		inOld[k] = true
+	}
+	inNew := make(map[string]bool, len(newElems))
+	for _, v := range newElems {
+		k := indexKey(v)
+		elems[k] = v
+		inNew[k] = true
+	}
+	keys := make([]string, 0, len(elems))
+	for k := range elems {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteString("[\n")
+	for _, k := range keys {
+		v := elems[k]
+		oldV, newV := v, v
+		if !inOld[k] {
+			oldV = cty.NullVal(ety)
+		}
+		if !inNew[k] {
+			newV = cty.NullVal(ety)
+		}
+		r.elemLine(&buf, path.Index(v), oldV, newV, depth+1)
+	}
+	buf.WriteString(closing(depth, "]"))
+	return buf.String()
+}
+
+// setElementKeys returns the identifying attributes of the elements of the
+// set at the given path, and whether its elements are to be correlated at
+// all. Only sets of objects are correlated.
+func (r *renderer) setElementKeys(path cty.Path, ety cty.Type) ([]string, bool) {
+	if r.plain || !ety.IsObjectType() {
+		return nil, false
+	}
+	if r.opts.SetElementKeys != nil {
+		if keys := r.opts.SetElementKeys(path); len(keys) > 0 {
+			return keys, true
+		}
+	}
+	return nil, r.opts.CorrelateSets
+}
+
+// attrLine adds a line for a single object attribute or map element,
+// padding the key to the given width so that the equals signs align.
+func (r *renderer) attrLine(lines *attrLines, path cty.Path, key string, width int, old, new cty.Value, depth int) {
+	new = r.computedNew(path, new)
+	if old.IsNull() && new.IsNull() {
+		// Attributes that are null on both sides are just noise.
+		return
+	}
+	action := r.lineAction(path, old, new)
+	*lines = append(*lines, attrLine{
+		action: action,
+		text: fmt.Sprintf(
+			"%s%s %-*s = %s\n",
+			strings.Repeat(" ", 4*depth), r.marker(action), width, key,
+			r.lineValue(path, old, new, depth),
+		),
+	})
+}
+
+// attrLines is a sequence of rendered lines for the attributes of an
+// object or the elements of a map.
+type attrLines []attrLine
+
+type attrLine struct {
+	action Action
+	text   string
+}
+
+// attrGroupOrder is the order in which lines are grouped by action when
+// RenderOpts.GroupByLeafAction is set.
+var attrGroupOrder = map[Action]int{
+	Delete: 0,
+	Update: 1,
+	Create: 2,
+	NoOp:   3,
+}
+
+// writeTo writes the lines to the given buffer, optionally grouping them
+// by action while otherwise preserving their order.
+func (ls attrLines) writeTo(buf *bytes.Buffer, group bool) {
+	if group {
+		sort.SliceStable(ls, func(i, j int) bool {
+			return attrGroupOrder[ls[i].action] < attrGroupOrder[ls[j].action]
+		})
+	}
+	for _, l := range ls {
+		buf.WriteString(l.text)
+	}
+}
+
+// elemLine writes a line for a single element of a list, set or tuple.
+func (r *renderer) elemLine(buf *bytes.Buffer, path cty.Path, old, new cty.Value, depth int) {
+	new = r.computedNew(path, new)
+	fmt.Fprintf(
+		buf, "%s%s %s,\n",
+		strings.Repeat(" ", 4*depth), r.marker(r.lineAction(path, old, new)),
+		r.lineValue(path, old, new, depth),
+	)
+}
+
+// lineAction returns the action to display for the line representing the
+// given path, which disregards any differences in write-only paths.
+func (r *renderer) lineAction(path cty.Path, old, new cty.Value) Action {
+	action := leafAction(old, new)
+	if action == NoOp || r.change.WriteOnly.Empty() {
+		return action
+	}
+	if pathSetCovers(r.change.WriteOnly, path) {
+		return NoOp
+	}
+	changed := false
+	walkChangesExcept(path, old, new, r.change.WriteOnly, func(cty.Path, cty.Value, cty.Value, Action) {
+		changed = true
+	})
+	if !changed {
+		return NoOp
+	}
+	return action
+}
+
+// computedNew returns an unknown value in place of the given new value if
+// it is null only because it is yet to be computed by the provider.
+// Deleted and unchanged values are never computed.
+func (r *renderer) computedNew(path cty.Path, new cty.Value) cty.Value {
+	if r.plain || !new.IsNull() || !r.change.Computed.Has(path) {
+		return new
+	}
+	if r.change.Action == Delete || r.change.Action == NoOp {
+		return new
+	}
+	return cty.UnknownVal(new.Type())
+}
+
+func (r *renderer) lineValue(path cty.Path, old, new cty.Value, depth int) string {
+	if pathSetCovers(r.change.WriteOnly, path) {
+		return "(write-only, not stored)"
+	}
+	if pathSetCovers(r.change.Sensitive, path) && !(old.IsNull() && new.IsNull()) {
+		return r.annotate(path, old, new, "(sensitive value)")
+	}
+	return r.annotatedValue(path, old, new, depth)
+}
+
+func closing(depth int, bracket string) string {
+	return strings.Repeat(" ", 4*depth+2) + bracket
+}
+
+// leafAction returns the action that describes the transition between the
+// two given values, without considering any nested values.
+func leafAction(old, new cty.Value) Action {
+	switch {
+	case old.RawEquals(new):
+		return NoOp
+	case old.IsNull():
+		return Create
+	case new.IsNull():
+		return Delete
+	default:
+		return Update
+	}
+}
+
+// collectionType returns the type of the given values if they are both of
+// the same collection or structural type and are known, and so can be
+// compared element-by-element. Either value may be null, but not both.
+func collectionType(old, new cty.Value) (cty.Type, bool) {
+	if old.IsNull() && new.IsNull() {
+		return cty.NilType, false
+	}
+	if !old.IsKnown() || !new.IsKnown() {
+		return cty.NilType, false
+	}
+
+	var ty cty.Type
+	switch {
+	case old.IsNull():
+		// An empty collection has no elements to compare, so transitions
+		// to or from null must be treated as a change to a leaf value.
+		if isEmptyCollection(new) {
+			return cty.NilType, false
+		}
+		ty = new.Type()
+	case new.IsNull():
+		if isEmptyCollection(old) {
+			return cty.NilType, false
+		}
+		ty = old.Type()
+	default:
+		ty = old.Type()
+		if !ty.Equals(new.Type()) {
+			return cty.NilType, false
+		}
+	}
+
+	if ty.IsObjectType() || ty.IsMapType() || ty.IsListType() || ty.IsTupleType() || ty.IsSetType() {
+		return ty, true
+	}
+	return cty.NilType, false
+}
+
+// isEmptyCollection returns true if the given known, non-null value is of a
+// collection or structural type and has no elements or attributes.
+func isEmptyCollection(v cty.Value) bool {
+	ty := v.Type()
+	switch {
+	case ty.IsObjectType():
+		return len(ty.AttributeTypes()) == 0
+	case ty.IsTupleType():
+		return ty.Length() == 0
+	case ty.IsMapType() || ty.IsListType() || ty.IsSetType():
+		return v.LengthInt() == 0
+	default:
+		return false
+	}
+}
+
+// attrOrNull returns the named attribute of the given object value, or a
+// null value of the given type if the object itself is null.
+func attrOrNull(obj cty.Value, name string, ty cty.Type) cty.Value {
+	if obj.IsNull() {
+		return cty.NullVal(ty)
+	}
+	return obj.GetAttr(name)
+}
+
+// sequenceElements returns the elements of the given list, set or tuple
+// value as a slice, or nil if the value is null.
+func sequenceElements(v cty.Value) []cty.Value {
+	if v.IsNull() {
+		return nil
+	}
+	var ret []cty.Value
+	for it := v.ElementIterator(); it.Next(); {
+		_, ev := it.Element()
+		ret = append(ret, ev)
+	}
+	return ret
+}
+
+// mapElements returns the elements of the given map value, or nil if the
+// value is null.
+func mapElements(v cty.Value) map[string]cty.Value {
+	if v.IsNull() {
+		return nil
+	}
+	ret := make(map[string]cty.Value)
+	for it := v.ElementIterator(); it.Next(); {
+		k, ev := it.Element()
+		ret[k.AsString()] = ev
+	}
+	return ret
+}
+
+func sequenceElementType(ty cty.Type, i int) cty.Type {
+	if ty.IsTupleType() {
+		return ty.TupleElementType(i)
+	}
+	return ty.ElementType()
+}
@@ -0,0 +1,75 @@
+// Package render renders the changes described by package diffs as
+// terminal output, with the action markers and the annotations explaining
+// replacements in the same colors as Terraform's other plan output.
+//
+// The layout is that of diffs.Change.Render: attributes and elements nested
+// by indentation, each marked with its action, and "forces replacement"
+// beside the paths in the change's ForcedReplace set.
+package render
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/hashicorp/terraform/diffs"
+	"github.com/mitchellh/colorstring"
+)
+
+// Opts customizes the output produced by Change and ChangeTo.
+//
+// The zero value of Opts renders without color.
+type Opts struct {
+	diffs.RenderOpts
+
+	// Color is used to colorize the output. If it is nil or disabled, the
+	// output is the same as that of diffs.Change.Render.
+	Color *colorstring.Colorize
+}
+
+// actionColors are the color codes of the marker of each action.
+var actionColors = map[diffs.Action]string{
+	diffs.Create:  "[green]+[reset]",
+	diffs.Read:    "[cyan]<=[reset]",
+	diffs.Update:  "[yellow]~[reset]",
+	diffs.Replace: "[red]-[reset]/[green]+[reset]",
+	diffs.Delete:  "[red]-[reset]",
+	diffs.Forget:  "[cyan].[reset]",
+}
+
+// Options returns the options to pass to the rendering functions of
+// package diffs, such as diffs.ChangeSet.RenderTo and
+// diffs.StreamDiffRender, to render with color. The DecorateMarker and
+// DecorateReplaceNote fields of the embedded options are replaced if
+// color is enabled.
+func (o Opts) Options() diffs.RenderOpts {
+	ret := o.RenderOpts
+	color := o.Color
+	if color == nil || color.Disable {
+		return ret
+	}
+
+	ret.DecorateMarker = func(action diffs.Action, marker string) string {
+		code, ok := actionColors[action]
+		if !ok {
+			return marker
+		}
+		return color.Color(code)
+	}
+	ret.DecorateReplaceNote = func(text string) string {
+		return color.Color("[red]" + text + "[reset]")
+	}
+	return ret
+}
+
+// Change returns the terminal rendering of the given change.
+func Change(c *diffs.Change, opts Opts) string {
+	var buf bytes.Buffer
+	ChangeTo(&buf, c, opts) // writing to a bytes.Buffer cannot fail
+	return buf.String()
+}
+
+// ChangeTo is like Change except that it writes the result to the given
+// writer, returning any error the writer produces.
+func ChangeTo(w io.Writer, c *diffs.Change, opts Opts) error {
+	return c.RenderTo(w, opts.Options())
+}
@@ -0,0 +1,69 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform/diffs"
+	"github.com/mitchellh/colorstring"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestChange(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"id":   cty.String,
+		"name": cty.String,
+		"size": cty.Number,
+		"tags": cty.Map(cty.String),
+	})
+	change := diffs.NewReplace(
+		ty,
+		cty.ObjectVal(map[string]cty.Value{
+			"id":   cty.StringVal("i-abc123"),
+			"name": cty.StringVal("foo"),
+			"size": cty.NumberIntVal(2),
+			"tags": cty.NullVal(cty.Map(cty.String)),
+		}),
+		cty.ObjectVal(map[string]cty.Value{
+			"id":   cty.UnknownVal(cty.String),
+			"name": cty.StringVal("foo"),
+			"size": cty.NumberIntVal(4),
+			"tags": cty.NullVal(cty.Map(cty.String)),
+		}),
+		diffs.NewPathSet(cty.Path{cty.GetAttrStep{Name: "size"}}),
+	)
+
+	tests := map[string]struct {
+		Opts Opts
+		Want string
+	}{
+		"color": {
+			Opts{
+				Color: &colorstring.Colorize{Colors: colorstring.DefaultColors},
+			},
+			"\x1b[31m-\x1b[0m/\x1b[32m+\x1b[0m {\n" +
+				"    \x1b[33m~\x1b[0m id   = \"i-abc123\" -> (known after apply)\n" +
+				"      name = \"foo\"\n" +
+				"    \x1b[33m~\x1b[0m size = 2 -> 4 # \x1b[31mforces replacement\x1b[0m\n" +
+				"  }\n",
+		},
+		"color disabled": {
+			Opts{
+				Color: &colorstring.Colorize{Colors: colorstring.DefaultColors, Disable: true},
+			},
+			change.Render(diffs.RenderOpts{}),
+		},
+		"no color": {
+			Opts{},
+			change.Render(diffs.RenderOpts{}),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := Change(change, test.Opts)
+			if got != test.Want {
+				t.Errorf("wrong result\ngot:\n%q\nwant:\n%q", got, test.Want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package diffs
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// formatNumber formats the given number in decimal notation for the given
+// locale, using its digits, grouping and separators.
+//
+// The locale's formatting can only be applied to numbers that are exactly
+// an int64 or that a float64 represents without losing any of their
+// decimal digits. Other numbers are formatted as cty formats them, with no
+// grouping, so that no precision is lost.
+func formatNumber(f *big.Float, tag language.Tag) string {
+	text := f.Text('f', -1)
+	p := message.NewPrinter(tag)
+
+	if f.IsInt() {
+		if i, acc := f.Int64(); acc == big.Exact {
+			return p.Sprint(number.Decimal(i))
+		}
+		return text
+	}
+
+	x, _ := f.Float64()
+	if strconv.FormatFloat(x, 'f', -1, 64) != text {
+		return text
+	}
+	digits := 0
+	if dot := strings.IndexByte(text, '.'); dot >= 0 {
+		digits = len(text) - dot - 1
+	}
+	return p.Sprint(number.Decimal(x, number.MinFractionDigits(digits), number.MaxFractionDigits(digits)))
+}
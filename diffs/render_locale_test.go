@@ -0,0 +1,99 @@
+package diffs
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+	"golang.org/x/text/language"
+)
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		Num    string
+		Locale language.Tag
+		Want   string
+	}{
+		{"0", language.English, "0"},
+		{"999", language.English, "999"},
+		{"1000", language.English, "1,000"},
+		{"1234567.25", language.English, "1,234,567.25"},
+		{"-1234567", language.English, "-1,234,567"},
+		{"1234567.25", language.German, "1.234.567,25"},
+		{"1234567.25", language.MustParse("de-CH"), "1\u2019234\u2019567.25"},
+		{"1234567.25", language.French, "1\u00a0234\u00a0567,25"},
+		{"1234567", language.MustParse("en-IN"), "12,34,567"},
+		{"0.1", language.German, "0,1"},
+		{"1234567.25", language.MustParse("tlh"), "1,234,567.25"},
+
+		// Numbers beyond int64 and float64 are not grouped, so that no
+		// precision is lost.
+		{"123456789012345678901234567890", language.English, "123456789012345678901234567890"},
+		{"0.12345678901234567890123", language.German, "0.12345678901234567890123"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Locale.String()+" "+test.Num, func(t *testing.T) {
+			f, _, err := big.ParseFloat(test.Num, 10, 512, big.ToNearestEven)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := formatNumber(f, test.Locale)
+			if got != test.Want {
+				t.Errorf("wrong result\ngot:  %q\nwant: %q", got, test.Want)
+			}
+		})
+	}
+}
+
+func TestChangeRenderLocale(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"enabled": cty.Bool,
+		"size":    cty.Number,
+	})
+	c := NewUpdate(
+		ty,
+		cty.ObjectVal(map[string]cty.Value{
+			"enabled": cty.False,
+			"size":    cty.NumberIntVal(1048576),
+		}),
+		cty.ObjectVal(map[string]cty.Value{
+			"enabled": cty.True,
+			"size":    cty.NumberIntVal(2097152),
+		}),
+	)
+
+	tests := map[string]struct {
+		Opts RenderOpts
+		Want string
+	}{
+		"default": {
+			RenderOpts{},
+			`~ {
+    ~ enabled = false -> true
+    ~ size    = 1048576 -> 2097152
+  }
+`,
+		},
+		"german": {
+			RenderOpts{
+				Locale:    language.German,
+				BoolWords: [2]string{"falsch", "wahr"},
+			},
+			`~ {
+    ~ enabled = falsch -> wahr
+    ~ size    = 1.048.576 -> 2.097.152
+  }
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := c.Render(test.Opts)
+			if got != test.Want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.Want)
+			}
+		})
+	}
+}
@@ -0,0 +1,531 @@
+package diffs
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestChangeRender(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"id":   cty.String,
+		"name": cty.String,
+		"size": cty.Number,
+		"tags": cty.Map(cty.String),
+	})
+
+	tests := map[string]struct {
+		Change *Change
+		Opts   RenderOpts
+		Want   string
+	}{
+		"create": {
+			NewCreate(ty, cty.ObjectVal(map[string]cty.Value{
+				"id":   cty.UnknownVal(cty.String),
+				"name": cty.StringVal("foo"),
+				"size": cty.NumberIntVal(2),
+				"tags": cty.NullVal(cty.Map(cty.String)),
+			})),
+			RenderOpts{},
+			`+ {
+    + id   = (known after apply)
+    + name = "foo"
+    + size = 2
+  }
+`,
+		},
+		"create with computed null": {
+			NewCreate(ty, cty.ObjectVal(map[string]cty.Value{
+				"id":   cty.NullVal(cty.String),
+				"name": cty.StringVal("foo"),
+				"size": cty.NumberIntVal(2),
+				"tags": cty.NullVal(cty.Map(cty.String)),
+			})).AnnotateComputed(NewPathSet(
+				cty.Path{cty.GetAttrStep{Name: "id"}},
+			)),
+			RenderOpts{},
+			`+ {
+    + id   = (known after apply)
+    + name = "foo"
+    + size = 2
+  }
+`,
+		},
+		"create with unknown sources": {
+			func() *Change {
+				c := NewCreate(ty, cty.ObjectVal(map[string]cty.Value{
+					"id":   cty.UnknownVal(cty.String),
+					"name": cty.UnknownVal(cty.String),
+					"size": cty.NumberIntVal(2),
+					"tags": cty.NullVal(cty.Map(cty.String)),
+				}))
+				c.UnknownSources = NewPathSources()
+				c.UnknownSources.Set(cty.Path{cty.GetAttrStep{Name: "name"}}, []string{"random_id.suffix.hex", "aws_vpc.main.id"})
+				return c
+			}(),
+			RenderOpts{},
+			`+ {
+    + id   = (known after apply)
+    + name = (known after apply) # from aws_vpc.main.id, random_id.suffix.hex
+    + size = 2
+  }
+`,
+		},
+		"update": {
+			NewUpdate(
+				ty,
+				cty.ObjectVal(map[string]cty.Value{
+					"id":   cty.StringVal("i-abc123"),
+					"name": cty.StringVal("foo"),
+					"size": cty.NumberIntVal(2),
+					"tags": cty.MapVal(map[string]cty.Value{
+						"env":  cty.StringVal("prod"),
+						"team": cty.StringVal("a"),
+					}),
+				}),
+				cty.ObjectVal(map[string]cty.Value{
+					"id":   cty.StringVal("i-abc123"),
+					"name": cty.StringVal("bar"),
+					"size": cty.NumberIntVal(2),
+					"tags": cty.MapVal(map[string]cty.Value{
+						"env":   cty.StringVal("prod"),
+						"owner": cty.StringVal("b"),
+					}),
+				}),
+			),
+			RenderOpts{},
+			`~ {
+      id   = "i-abc123"
+    ~ name = "foo" -> "bar"
+      size = 2
+    ~ tags = {
+          "env"   = "prod"
+        + "owner" = "b"
+        - "team"  = "a" -> null
+      }
+  }
+`,
+		},
+		"forget": {
+			NewForget(ty, cty.ObjectVal(map[string]cty.Value{
+				"id":   cty.StringVal("i-abc123"),
+				"name": cty.StringVal("foo"),
+				"size": cty.NumberIntVal(2),
+				"tags": cty.NullVal(cty.Map(cty.String)),
+			})),
+			RenderOpts{},
+			`. {
+      id   = "i-abc123"
+      name = "foo"
+      size = 2
+  }
+`,
+		},
+		"replace": {
+			NewReplace(
+				ty,
+				cty.ObjectVal(map[string]cty.Value{
+					"id":   cty.StringVal("i-abc123"),
+					"name": cty.StringVal("foo"),
+					"size": cty.NumberIntVal(2),
+					"tags": cty.NullVal(cty.Map(cty.String)),
+				}),
+				cty.ObjectVal(map[string]cty.Value{
+					"id":   cty.UnknownVal(cty.String),
+					"name": cty.StringVal("foo"),
+					"size": cty.NumberIntVal(4),
+					"tags": cty.NullVal(cty.Map(cty.String)),
+				}),
+				NewPathSet(cty.Path{cty.GetAttrStep{Name: "size"}}),
+			),
+			RenderOpts{},
+			`-/+ {
+    ~ id   = "i-abc123" -> (known after apply)
+      name = "foo"
+    ~ size = 2 -> 4 # forces replacement
+  }
+`,
+		},
+		"requested replace": {
+			NewRequestedReplace(
+				ty,
+				cty.ObjectVal(map[string]cty.Value{
+					"id":   cty.StringVal("i-abc123"),
+					"name": cty.StringVal("foo"),
+					"size": cty.NumberIntVal(2),
+					"tags": cty.NullVal(cty.Map(cty.String)),
+				}),
+				cty.ObjectVal(map[string]cty.Value{
+					"id":   cty.UnknownVal(cty.String),
+					"name": cty.StringVal("foo"),
+					"size": cty.NumberIntVal(2),
+					"tags": cty.NullVal(cty.Map(cty.String)),
+				}),
+			),
+			RenderOpts{},
+			`-/+ { # replace requested by user
+    ~ id   = "i-abc123" -> (known after apply)
+      name = "foo"
+      size = 2
+  }
+`,
+		},
+		"tainted replace": {
+			NewReplace(
+				ty,
+				cty.ObjectVal(map[string]cty.Value{
+					"id":   cty.StringVal("i-abc123"),
+					"name": cty.StringVal("foo"),
+					"size": cty.NumberIntVal(2),
+					"tags": cty.NullVal(cty.Map(cty.String)),
+				}),
+				cty.ObjectVal(map[string]cty.Value{
+					"id":   cty.StringVal("i-abc123"),
+					"name": cty.StringVal("foo"),
+					"size": cty.NumberIntVal(2),
+					"tags": cty.NullVal(cty.Map(cty.String)),
+				}),
+				NewPathSet(),
+			).WithReplaceReason(ReplaceBecauseTainted),
+			RenderOpts{},
+			`-/+ { # tainted, so must be replaced
+      id   = "i-abc123"
+      name = "foo"
+      size = 2
+  }
+`,
+		},
+		"dependency replace": {
+			&Change{
+				Action: Replace,
+				Type:   ty,
+				Old: cty.ObjectVal(map[string]cty.Value{
+					"id":   cty.StringVal("i-abc123"),
+					"name": cty.StringVal("foo"),
+					"size": cty.NumberIntVal(2),
+					"tags": cty.NullVal(cty.Map(cty.String)),
+				}),
+				New: cty.ObjectVal(map[string]cty.Value{
+					"id":   cty.StringVal("i-abc123"),
+					"name": cty.UnknownVal(cty.String),
+					"size": cty.NumberIntVal(4),
+					"tags": cty.NullVal(cty.Map(cty.String)),
+				}),
+				ForcedReplace: NewPathSet(
+					cty.Path{cty.GetAttrStep{Name: "name"}},
+					cty.Path{cty.GetAttrStep{Name: "size"}},
+				),
+				ForcedReplaceReasons: func() PathReasons {
+					ret := NewPathReasons()
+					ret.Set(cty.Path{cty.GetAttrStep{Name: "name"}}, ReplaceBecauseDependency)
+					return ret
+				}(),
+				ReplaceReason: ReplaceBecauseCannotUpdate,
+			},
+			RenderOpts{},
+			`-/+ {
+      id   = "i-abc123"
+    ~ name = "foo" -> (known after apply) # forces replacement due to a replaced dependency
+    ~ size = 2 -> 4 # forces replacement
+  }
+`,
+		},
+		"sensitive": {
+			&Change{
+				Action: Update,
+				Type:   ty,
+				Old: cty.ObjectVal(map[string]cty.Value{
+					"id":   cty.StringVal("i-abc123"),
+					"name": cty.StringVal("foo"),
+					"size": cty.NumberIntVal(2),
+					"tags": cty.MapVal(map[string]cty.Value{
+						"secret": cty.StringVal("hunter2"),
+					}),
+				}),
+				New: cty.ObjectVal(map[string]cty.Value{
+					"id":   cty.StringVal("i-abc123"),
+					"name": cty.StringVal("bar"),
+					"size": cty.NumberIntVal(2),
+					"tags": cty.MapVal(map[string]cty.Value{
+						"secret": cty.StringVal("hunter3"),
+					}),
+				}),
+				Sensitive: NewPathSet(
+					cty.Path{cty.GetAttrStep{Name: "name"}},
+					cty.Path{cty.GetAttrStep{Name: "size"}},
+					cty.Path{cty.GetAttrStep{Name: "tags"}, cty.IndexStep{Key: cty.StringVal("secret")}},
+				),
+			},
+			RenderOpts{},
+			`~ {
+      id   = "i-abc123"
+    ~ name = (sensitive value)
+      size = (sensitive value)
+    ~ tags = {
+        ~ "secret" = (sensitive value)
+      }
+  }
+`,
+		},
+		"unknown forces replacement": {
+			NewReplace(
+				cty.Object(map[string]cty.Type{
+					"ami":    cty.String,
+					"name":   cty.String,
+					"subnet": cty.Object(map[string]cty.Type{"id": cty.String}),
+				}),
+				cty.ObjectVal(map[string]cty.Value{
+					"ami":  cty.StringVal("ami-1"),
+					"name": cty.StringVal("foo"),
+					"subnet": cty.ObjectVal(map[string]cty.Value{
+						"id": cty.StringVal("subnet-1"),
+					}),
+				}),
+				cty.ObjectVal(map[string]cty.Value{
+					"ami":    cty.UnknownVal(cty.String),
+					"name":   cty.StringVal("foo"),
+					"subnet": cty.UnknownVal(cty.Object(map[string]cty.Type{"id": cty.String})),
+				}),
+				NewPathSet(
+					cty.Path{cty.GetAttrStep{Name: "ami"}},
+					cty.Path{cty.GetAttrStep{Name: "subnet"}, cty.GetAttrStep{Name: "id"}},
+				),
+			),
+			RenderOpts{},
+			`-/+ {
+    ~ ami    = "ami-1" -> (known after apply) # forces replacement
+      name   = "foo"
+    ~ subnet = {
+          id = "subnet-1"
+      } -> (known after apply) # forces replacement
+  }
+`,
+		},
+		"annotate in-place update": {
+			NewUpdate(
+				ty,
+				cty.ObjectVal(map[string]cty.Value{
+					"id":   cty.StringVal("i-abc123"),
+					"name": cty.StringVal("foo"),
+					"size": cty.NumberIntVal(2),
+					"tags": cty.NullVal(cty.Map(cty.String)),
+				}),
+				cty.ObjectVal(map[string]cty.Value{
+					"id":   cty.StringVal("i-abc123"),
+					"name": cty.StringVal("bar"),
+					"size": cty.NumberIntVal(4),
+					"tags": cty.NullVal(cty.Map(cty.String)),
+				}),
+			),
+			RenderOpts{
+				AnnotateInPlace: true,
+				ForceNewCapable: NewPathSet(
+					cty.Path{cty.GetAttrStep{Name: "size"}},
+					cty.Path{cty.GetAttrStep{Name: "id"}},
+				),
+			},
+			`~ {
+      id   = "i-abc123"
+    ~ name = "foo" -> "bar"
+    ~ size = 2 -> 4 # (updated in place)
+  }
+`,
+		},
+		"grouped by leaf action": {
+			NewReplace(
+				cty.Object(map[string]cty.Type{
+					"ami":   cty.String,
+					"arn":   cty.String,
+					"name":  cty.String,
+					"size":  cty.Number,
+					"type":  cty.String,
+					"zone":  cty.String,
+					"owner": cty.String,
+				}),
+				cty.ObjectVal(map[string]cty.Value{
+					"ami":   cty.StringVal("ami-1"),
+					"arn":   cty.StringVal("arn:1"),
+					"name":  cty.StringVal("foo"),
+					"size":  cty.NumberIntVal(1),
+					"type":  cty.StringVal("small"),
+					"zone":  cty.NullVal(cty.String),
+					"owner": cty.NullVal(cty.String),
+				}),
+				cty.ObjectVal(map[string]cty.Value{
+					"ami":   cty.StringVal("ami-2"),
+					"arn":   cty.NullVal(cty.String),
+					"name":  cty.StringVal("foo"),
+					"size":  cty.NumberIntVal(2),
+					"type":  cty.NullVal(cty.String),
+					"zone":  cty.StringVal("a"),
+					"owner": cty.StringVal("b"),
+				}),
+				NewPathSet(cty.Path{cty.GetAttrStep{Name: "ami"}}),
+			),
+			RenderOpts{
+				GroupByLeafAction: true,
+			},
+			`-/+ {
+    - arn   = "arn:1" -> null
+    - type  = "small" -> null
+    ~ ami   = "ami-1" -> "ami-2" # forces replacement
+    ~ size  = 1 -> 2
+    + owner = "b"
+    + zone  = "a"
+      name  = "foo"
+  }
+`,
+		},
+		"list and set elements": {
+			NewUpdate(
+				cty.Object(map[string]cty.Type{
+					"list": cty.List(cty.String),
+					"set":  cty.Set(cty.String),
+				}),
+				cty.ObjectVal(map[string]cty.Value{
+					"list": cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+					"set":  cty.SetVal([]cty.Value{cty.StringVal("x"), cty.StringVal("y")}),
+				}),
+				cty.ObjectVal(map[string]cty.Value{
+					"list": cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("c"), cty.StringVal("d")}),
+					"set":  cty.SetVal([]cty.Value{cty.StringVal("x"), cty.StringVal("z")}),
+				}),
+			),
+			RenderOpts{},
+			`~ {
+    ~ list = [
+          "a",
+        ~ "b" -> "c",
+        + "d",
+      ]
+    ~ set  = [
+          "x",
+        - "y" -> null,
+        + "z",
+      ]
+  }
+`,
+		},
+		"set element updated by key": {
+			NewUpdate(
+				rulesTy,
+				cty.ObjectVal(map[string]cty.Value{
+					"rules": cty.SetVal([]cty.Value{
+						testRule("http", 80, "0.0.0.0/0"),
+						testRule("ssh", 22, "10.0.0.0/8"),
+					}),
+				}),
+				cty.ObjectVal(map[string]cty.Value{
+					"rules": cty.SetVal([]cty.Value{
+						testRule("http", 80, "0.0.0.0/0"),
+						testRule("ssh", 2222, "10.0.0.0/8"),
+					}),
+				}),
+			),
+			RenderOpts{
+				SetElementKeys: func(path cty.Path) []string {
+					return []string{"name"}
+				},
+			},
+			`~ {
+    ~ rules = [
+          {
+              cidr = "0.0.0.0/0"
+              name = "http"
+              port = 80
+          },
+        ~ {
+              cidr = "10.0.0.0/8"
+              name = "ssh"
+            ~ port = 22 -> 2222
+          },
+      ]
+  }
+`,
+		},
+		"set element updated by similarity": {
+			NewUpdate(
+				rulesTy,
+				cty.ObjectVal(map[string]cty.Value{
+					"rules": cty.SetVal([]cty.Value{
+						testRule("ssh", 22, "10.0.0.0/8"),
+					}),
+				}),
+				cty.ObjectVal(map[string]cty.Value{
+					"rules": cty.SetVal([]cty.Value{
+						testRule("ssh", 22, "10.1.0.0/16"),
+					}),
+				}),
+			),
+			RenderOpts{CorrelateSets: true},
+			`~ {
+    ~ rules = [
+        ~ {
+            ~ cidr = "10.0.0.0/8" -> "10.1.0.0/16"
+              name = "ssh"
+              port = 22
+          },
+      ]
+  }
+`,
+		},
+		"set element replaced without correlation": {
+			NewUpdate(
+				rulesTy,
+				cty.ObjectVal(map[string]cty.Value{
+					"rules": cty.SetVal([]cty.Value{
+						testRule("ssh", 22, "10.0.0.0/8"),
+					}),
+				}),
+				cty.ObjectVal(map[string]cty.Value{
+					"rules": cty.SetVal([]cty.Value{
+						testRule("ssh", 22, "10.1.0.0/16"),
+					}),
+				}),
+			),
+			RenderOpts{},
+			`~ {
+    ~ rules = [
+        - {
+            - cidr = "10.0.0.0/8" -> null
+            - name = "ssh" -> null
+            - port = 22 -> null
+          },
+        + {
+            + cidr = "10.1.0.0/16"
+            + name = "ssh"
+            + port = 22
+          },
+      ]
+  }
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := test.Change.Render(test.Opts)
+			if got != test.Want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.Want)
+			}
+		})
+	}
+}
+
+var rulesTy = cty.Object(map[string]cty.Type{
+	"rules": cty.Set(ruleTy),
+})
+
+var ruleTy = cty.Object(map[string]cty.Type{
+	"name": cty.String,
+	"port": cty.Number,
+	"cidr": cty.String,
+})
+
+func testRule(name string, port int64, cidr string) cty.Value {
+	return cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal(name),
+		"port": cty.NumberIntVal(port),
+		"cidr": cty.StringVal(cidr),
+	})
+}
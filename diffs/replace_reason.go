@@ -0,0 +1,158 @@
+package diffs
+
+import (
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ReplaceReason describes why a change is a Replace.
+type ReplaceReason int
+
+//go:generate stringer -type=ReplaceReason
+
+const (
+	// ReplaceReasonNone is the reason of any change that is not a Replace.
+	// A Replace change with this reason is treated as having the reason
+	// ReplaceBecauseCannotUpdate.
+	ReplaceReasonNone ReplaceReason = iota
+
+	// ReplaceBecauseCannotUpdate is the reason of a Replace change whose
+	// ForcedReplace paths have changes that the provider cannot apply
+	// in-place.
+	ReplaceBecauseCannotUpdate
+
+	// ReplaceByRequest is the reason of a Replace change that the user
+	// requested, such as with the -replace planning option.
+	ReplaceByRequest
+
+	// ReplaceBecauseTainted is the reason of a Replace change for an object
+	// that is tainted, and so must be replaced regardless of its value.
+	ReplaceBecauseTainted
+
+	// ReplaceBecauseDependency is the reason of a path that forces
+	// replacement only because its new value depends on another object that
+	// is being replaced, as with a create_before_destroy dependency. It is
+	// the reason of a Replace change whose ForcedReplace paths all have this
+	// reason.
+	ReplaceBecauseDependency
+)
+
+// unconditional returns true if a Replace change with the receiving reason
+// is a replacement regardless of whether the old and new values differ.
+func (r ReplaceReason) unconditional() bool {
+	return r == ReplaceByRequest || r == ReplaceBecauseTainted
+}
+
+// PathReasons records the reason that each of a set of paths forces
+// replacement.
+//
+// The zero value of PathReasons is empty and can be read from but not
+// added to. Use NewPathReasons to create a value that can be modified.
+type PathReasons struct {
+	reasons map[string]pathReason
+}
+
+type pathReason struct {
+	path   cty.Path
+	reason ReplaceReason
+}
+
+// NewPathReasons creates and returns a new, empty PathReasons.
+func NewPathReasons() PathReasons {
+	return PathReasons{
+		reasons: make(map[string]pathReason),
+	}
+}
+
+// Set records the given reason for the given path, replacing any reason
+// previously recorded for it.
+//
+// Set will panic if called on a zero-value PathReasons.
+func (r PathReasons) Set(p cty.Path, reason ReplaceReason) {
+	r.reasons[pathKey(p)] = pathReason{p.Copy(), reason}
+}
+
+// Get returns the reason recorded for the given path, or ReplaceReasonNone
+// if there is none.
+func (r PathReasons) Get(p cty.Path) ReplaceReason {
+	return r.reasons[pathKey(p)].reason
+}
+
+// Paths returns the set of paths that have a recorded reason.
+func (r PathReasons) Paths() PathSet {
+	ret := NewPathSet()
+	for _, pr := range r.reasons {
+		ret.Add(pr.path)
+	}
+	return ret
+}
+
+// Union returns a new value containing the reasons from both the receiver
+// and the other given value. Where both record a reason for the same path,
+// the reason from the receiver is used.
+func (r PathReasons) Union(other PathReasons) PathReasons {
+	ret := NewPathReasons()
+	for k, pr := range other.reasons {
+		ret.reasons[k] = pr
+	}
+	for k, pr := range r.reasons {
+		ret.reasons[k] = pr
+	}
+	return ret
+}
+
+// Only returns a new value containing only the reasons for the paths in the
+// given set.
+func (r PathReasons) Only(paths PathSet) PathReasons {
+	ret := NewPathReasons()
+	for k, pr := range r.reasons {
+		if paths.Has(pr.path) {
+			ret.reasons[k] = pr
+		}
+	}
+	return ret
+}
+
+// ReplaceReasonFor returns the reason that the given path forces
+// replacement in the receiving change, or ReplaceReasonNone if the path is
+// not one of its ForcedReplace paths.
+//
+// Paths with no reason recorded in ForcedReplaceReasons have the reason
+// ReplaceBecauseCannotUpdate.
+func (c *Change) ReplaceReasonFor(p cty.Path) ReplaceReason {
+	if !c.ForcedReplace.Has(p) {
+		return ReplaceReasonNone
+	}
+	if reason := c.ForcedReplaceReasons.Get(p); reason != ReplaceReasonNone {
+		return reason
+	}
+	return ReplaceBecauseCannotUpdate
+}
+
+// replaceReason returns the reason that the receiver is a Replace change,
+// or ReplaceReasonNone if it is not a Replace change.
+func (c *Change) replaceReason() ReplaceReason {
+	switch {
+	case c.Action != Replace:
+		return ReplaceReasonNone
+	case c.ReplaceReason == ReplaceReasonNone:
+		return ReplaceBecauseCannotUpdate
+	default:
+		return c.ReplaceReason
+	}
+}
+
+// forcedReplaceReason returns the reason for a Replace change that is due
+// only to its ForcedReplace paths, which is ReplaceBecauseDependency if all
+// of them have that reason.
+func (c *Change) forcedReplaceReason() ReplaceReason {
+	paths := c.ForcedReplace.List()
+	if len(paths) == 0 {
+		return ReplaceBecauseCannotUpdate
+	}
+	for _, p := range paths {
+		if c.ReplaceReasonFor(p) != ReplaceBecauseDependency {
+			return ReplaceBecauseCannotUpdate
+		}
+	}
+	return ReplaceBecauseDependency
+}
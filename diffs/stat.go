@@ -0,0 +1,115 @@
+package diffs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// DiffStat returns a terse one-line summary of the change, similar to the
+// output of "git diff --shortstat", suitable for use in logs.
+//
+// For example, an update might be summarized as
+// "updated: 3 changed, 1 added, 1 sensitive".
+func (c *Change) DiffStat() string {
+	counts := c.Summary()
+
+	switch c.Action {
+	case NoOp:
+		return "no changes"
+	case Create:
+		return fmt.Sprintf("created: %s set", pluralAttrs(counts.Added))
+	case Delete:
+		return fmt.Sprintf("destroyed: %s removed", pluralAttrs(counts.Removed))
+	case Forget:
+		return fmt.Sprintf("forgotten: %s no longer tracked", pluralAttrs(counts.Removed))
+	}
+
+	var parts []string
+	if counts.Changed > 0 {
+		parts = append(parts, fmt.Sprintf("%d changed", counts.Changed))
+	}
+	if counts.Added > 0 {
+		parts = append(parts, fmt.Sprintf("%d added", counts.Added))
+	}
+	if counts.Removed > 0 {
+		parts = append(parts, fmt.Sprintf("%d removed", counts.Removed))
+	}
+	if counts.ForcesReplacement > 0 {
+		parts = append(parts, fmt.Sprintf("%d forces replacement", counts.ForcesReplacement))
+	}
+	if counts.Sensitive > 0 {
+		parts = append(parts, fmt.Sprintf("%d sensitive", counts.Sensitive))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "no attributes changed")
+	}
+
+	var verb string
+	switch c.Action {
+	case Read:
+		verb = "read"
+	case Replace:
+		verb = "replaced"
+	default:
+		verb = "updated"
+	}
+	return verb + ": " + strings.Join(parts, ", ")
+}
+
+// Summary is a condensed description of the leaf-level changes within a
+// Change, as returned by Change.Summary.
+type Summary struct {
+	// Added, Changed and Removed count the leaf values that are added,
+	// changed and removed, respectively. Added and removed set elements
+	// are counted as added and removed values.
+	Added, Changed, Removed int
+
+	// ForcesReplacement and Sensitive count the leaf changes that are
+	// within one of the change's ForcedReplace or Sensitive paths,
+	// respectively. These overlap with the counts above.
+	ForcesReplacement, Sensitive int
+
+	// TopLevel contains the single-step paths to the top-level attributes
+	// or elements that contain at least one leaf change, in a stable
+	// order. It is empty if the value isn't of a collection or structural
+	// type, in which case a change is to the value as a whole.
+	TopLevel []cty.Path
+}
+
+// Summary walks the change and returns a summary of the leaf-level changes
+// within it. Differences within the change's WriteOnly paths are not
+// counted.
+func (c *Change) Summary() Summary {
+	var ret Summary
+	top := NewPathSet()
+	c.walkLeaves(func(path cty.Path, old, new cty.Value, action Action) {
+		switch action {
+		case Create:
+			ret.Added++
+		case Delete:
+			ret.Removed++
+		default:
+			ret.Changed++
+		}
+		if pathSetCovers(c.ForcedReplace, path) {
+			ret.ForcesReplacement++
+		}
+		if pathSetCovers(c.Sensitive, path) {
+			ret.Sensitive++
+		}
+		if len(path) > 0 {
+			top.Add(path[:1])
+		}
+	})
+	ret.TopLevel = top.List()
+	return ret
+}
+
+func pluralAttrs(n int) string {
+	if n == 1 {
+		return "1 attribute"
+	}
+	return fmt.Sprintf("%d attributes", n)
+}
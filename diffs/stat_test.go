@@ -0,0 +1,119 @@
+package diffs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestChangeDiffStat(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"ami":      cty.String,
+		"name":     cty.String,
+		"password": cty.String,
+		"size":     cty.Number,
+		"zone":     cty.String,
+	})
+	old := cty.ObjectVal(map[string]cty.Value{
+		"ami":      cty.StringVal("ami-1"),
+		"name":     cty.StringVal("foo"),
+		"password": cty.StringVal("hunter2"),
+		"size":     cty.NumberIntVal(1),
+		"zone":     cty.NullVal(cty.String),
+	})
+	new := cty.ObjectVal(map[string]cty.Value{
+		"ami":      cty.StringVal("ami-2"),
+		"name":     cty.StringVal("bar"),
+		"password": cty.StringVal("hunter3"),
+		"size":     cty.NumberIntVal(1),
+		"zone":     cty.StringVal("a"),
+	})
+
+	update := NewUpdate(ty, old, new)
+	update.Sensitive = NewPathSet(cty.Path{cty.GetAttrStep{Name: "password"}})
+
+	replace := NewReplace(ty, old, new, NewPathSet(
+		cty.Path{cty.GetAttrStep{Name: "ami"}},
+		cty.Path{cty.GetAttrStep{Name: "zone"}},
+	))
+
+	tests := map[string]struct {
+		Change *Change
+		Want   string
+	}{
+		"create": {
+			NewCreate(ty, old),
+			"created: 4 attributes set",
+		},
+		"delete": {
+			NewDelete(ty, new),
+			"destroyed: 5 attributes removed",
+		},
+		"forget": {
+			NewForget(ty, new),
+			"forgotten: 5 attributes no longer tracked",
+		},
+		"update": {
+			update,
+			"updated: 3 changed, 1 added, 1 sensitive",
+		},
+		"replace": {
+			replace,
+			"replaced: 3 changed, 1 added, 2 forces replacement",
+		},
+		"no-op": {
+			NewNoOp(ty, old),
+			"no changes",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := test.Change.DiffStat()
+			if got != test.Want {
+				t.Errorf("wrong result\ngot:  %s\nwant: %s", got, test.Want)
+			}
+		})
+	}
+}
+
+func TestChangeSummary(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"name": cty.String,
+		"tags": cty.Map(cty.String),
+		"zone": cty.String,
+	})
+	old := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("foo"),
+		"tags": cty.MapVal(map[string]cty.Value{
+			"env":  cty.StringVal("prod"),
+			"team": cty.StringVal("a"),
+		}),
+		"zone": cty.StringVal("a"),
+	})
+	new := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("foo"),
+		"tags": cty.MapVal(map[string]cty.Value{
+			"env":   cty.StringVal("staging"),
+			"owner": cty.StringVal("b"),
+		}),
+		"zone": cty.StringVal("b"),
+	})
+	change := NewUpdate(ty, old, new)
+	change.Sensitive = NewPathSet(cty.Path{cty.GetAttrStep{Name: "zone"}})
+
+	got := change.Summary()
+	if got.Added != 1 || got.Changed != 2 || got.Removed != 1 || got.ForcesReplacement != 0 || got.Sensitive != 1 {
+		t.Errorf("wrong counts\ngot:  %#v", got)
+	}
+
+	var gotTop []string
+	for _, p := range got.TopLevel {
+		gotTop = append(gotTop, formatPath(p))
+	}
+	wantTop := []string{"tags", "zone"}
+	if !reflect.DeepEqual(gotTop, wantTop) {
+		t.Errorf("wrong top-level paths\ngot:  %#v\nwant: %#v", gotTop, wantTop)
+	}
+}
@@ -0,0 +1,100 @@
+package diffs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// DiffAll computes the changes between each of the given pairs of old and
+// new values, all of which must conform to the given type. Each pair is
+// given as an array whose first element is the old value and whose second
+// is the new value.
+func DiffAll(ty cty.Type, pairs map[string][2]cty.Value, opts DiffOpts) ChangeSet {
+	ret := make(ChangeSet, len(pairs))
+	for k, pair := range pairs {
+		ret[k] = Diff(ty, pair[0], pair[1], opts)
+	}
+	return ret
+}
+
+// RenderTo writes a rendering of each of the changes in the set to the
+// given writer, in order by key, followed by a summary of the number of
+// changes of each kind. NoOp changes are not rendered.
+func (cs ChangeSet) RenderTo(w io.Writer, opts RenderOpts) error {
+	keys := cs.Keys()
+	changes := func(yield func(string, *Change) bool) {
+		for _, k := range keys {
+			if !yield(k, cs[k]) {
+				return
+			}
+		}
+	}
+	return renderChanges(w, changes, opts)
+}
+
+// StreamDiffRender is equivalent to calling DiffAll and then rendering the
+// result with ChangeSet.RenderTo, except that each change is rendered as
+// soon as its pair of values arrives from the given sequence and is then
+// discarded, so that the whole set of changes is never held in memory.
+//
+// Changes are rendered in the order the sequence produces them, so the
+// output matches ChangeSet.RenderTo only if the sequence is in order by
+// key.
+func StreamDiffRender(w io.Writer, ty cty.Type, pairs iter.Seq2[string, [2]cty.Value], opts RenderOpts) error {
+	changes := func(yield func(string, *Change) bool) {
+		for k, pair := range pairs {
+			if !yield(k, Diff(ty, pair[0], pair[1], DiffOpts{})) {
+				return
+			}
+		}
+	}
+	return renderChanges(w, changes, opts)
+}
+
+// renderChanges writes the given changes and their summary to the given
+// writer through a buffer, which is flushed even if writing fails so that
+// whatever was rendered before the failure isn't lost.
+func renderChanges(w io.Writer, changes iter.Seq2[string, *Change], opts RenderOpts) error {
+	bw := bufio.NewWriter(w)
+	err := writeChanges(bw, changes, opts)
+	if flushErr := bw.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+func writeChanges(w io.Writer, changes iter.Seq2[string, *Change], opts RenderOpts) error {
+	var stats Stats
+	for k, c := range changes {
+		if err := writeChange(w, &stats, k, c, opts); err != nil {
+			return err
+		}
+	}
+	return writeSummary(w, stats)
+}
+
+// writeChange renders the given change under a heading of its key, unless
+// it is a NoOp, and adds it to the given stats.
+func writeChange(w io.Writer, stats *Stats, key string, c *Change, opts RenderOpts) error {
+	stats.count(c)
+	if c.Action == NoOp {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "# %s\n", key); err != nil {
+		return err
+	}
+	if err := c.RenderTo(w, opts); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func writeSummary(w io.Writer, stats Stats) error {
+	_, err := io.WriteString(w, stats.String()+"\n")
+	return err
+}
@@ -0,0 +1,120 @@
+package diffs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"sort"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestStreamDiffRender(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"name": cty.String,
+	})
+	obj := func(name string) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{
+			"name": cty.StringVal(name),
+		})
+	}
+	pairs := map[string][2]cty.Value{
+		"a.created":   {cty.NullVal(ty), obj("new")},
+		"a.deleted":   {obj("old"), cty.NullVal(ty)},
+		"a.unchanged": {obj("same"), obj("same")},
+		"a.updated":   {obj("before"), obj("after")},
+	}
+
+	var want bytes.Buffer
+	if err := DiffAll(ty, pairs, DiffOpts{}).RenderTo(&want, RenderOpts{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got bytes.Buffer
+	if err := StreamDiffRender(&got, ty, sortedPairs(pairs), RenderOpts{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.String() != want.String() {
+		t.Errorf("streamed output differs from batch output\ngot:\n%s\nwant:\n%s", got.String(), want.String())
+	}
+
+	wantSummary := "Plan: 1 to add, 1 to change, 1 to destroy.\n"
+	if !bytes.HasSuffix(got.Bytes(), []byte(wantSummary)) {
+		t.Errorf("wrong summary\ngot:\n%s\nwant suffix: %s", got.String(), wantSummary)
+	}
+}
+
+func TestStreamDiffRender_writeError(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"name": cty.String,
+	})
+	pairs := map[string][2]cty.Value{
+		"a.created": {cty.NullVal(ty), cty.ObjectVal(map[string]cty.Value{
+			"name": cty.StringVal("new"),
+		})},
+	}
+
+	want := errors.New("disk full")
+	if err := StreamDiffRender(errWriter{want}, ty, sortedPairs(pairs), RenderOpts{}); err != want {
+		t.Fatalf("wrong error %v; want %v", err, want)
+	}
+}
+
+type errWriter struct {
+	err error
+}
+
+func (w errWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+func sortedPairs(pairs map[string][2]cty.Value) iter.Seq2[string, [2]cty.Value] {
+	return func(yield func(string, [2]cty.Value) bool) {
+		keys := make([]string, 0, len(pairs))
+		for k := range pairs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if !yield(k, pairs[k]) {
+				return
+			}
+		}
+	}
+}
+
+// BenchmarkStreamDiffRender generates each pair only when it is requested,
+// so that only one resource's values and change are live at a time.
+func BenchmarkStreamDiffRender(b *testing.B) {
+	ty := cty.Object(map[string]cty.Type{
+		"id":   cty.String,
+		"size": cty.Number,
+	})
+	const resources = 10000
+	pairs := func(yield func(string, [2]cty.Value) bool) {
+		for i := 0; i < resources; i++ {
+			old := cty.ObjectVal(map[string]cty.Value{
+				"id":   cty.StringVal(fmt.Sprintf("i-%d", i)),
+				"size": cty.NumberIntVal(int64(i)),
+			})
+			new := cty.ObjectVal(map[string]cty.Value{
+				"id":   cty.StringVal(fmt.Sprintf("i-%d", i)),
+				"size": cty.NumberIntVal(int64(i + 1)),
+			})
+			if !yield(fmt.Sprintf("resource.r%d", i), [2]cty.Value{old, new}) {
+				return
+			}
+		}
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := StreamDiffRender(io.Discard, ty, pairs, RenderOpts{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package diffs
+
+import (
+	"sort"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// PathSources records, for each of a set of paths whose new values are
+// unknown, the references that the unknown values were derived from, such
+// as "aws_subnet.main.id". These are what must be known before the values
+// can be, and so explain why the values are unknown.
+//
+// The zero value of PathSources is empty and can be read from but not
+// added to. Use NewPathSources to create a value that can be modified.
+type PathSources struct {
+	sources map[string]pathSources
+}
+
+type pathSources struct {
+	path cty.Path
+	refs []string
+}
+
+// NewPathSources creates and returns a new, empty PathSources.
+func NewPathSources() PathSources {
+	return PathSources{
+		sources: make(map[string]pathSources),
+	}
+}
+
+// Set records the given references as the sources of the given path,
+// replacing any previously recorded for it. The references are sorted.
+//
+// Set will panic if called on a zero-value PathSources.
+func (s PathSources) Set(p cty.Path, refs []string) {
+	sorted := append([]string(nil), refs...)
+	sort.Strings(sorted)
+	s.sources[pathKey(p)] = pathSources{p.Copy(), sorted}
+}
+
+// Get returns the references recorded as the sources of the given path, or
+// nil if there are none.
+func (s PathSources) Get(p cty.Path) []string {
+	return s.sources[pathKey(p)].refs
+}
+
+// Paths returns the set of paths that have recorded sources.
+func (s PathSources) Paths() PathSet {
+	ret := NewPathSet()
+	for _, ps := range s.sources {
+		ret.Add(ps.path)
+	}
+	return ret
+}
+
+// Under returns a new value containing only the sources of the paths that
+// begin with the given prefix, with the prefix removed.
+func (s PathSources) Under(prefix cty.Path) PathSources {
+	ret := NewPathSources()
+	for _, ps := range s.sources {
+		if pathHasPrefix(ps.path, prefix) {
+			ret.Set(ps.path[len(prefix):], ps.refs)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,112 @@
+package diffs
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Validate checks that the change is internally consistent, returning
+// error diagnostics describing any problems.
+//
+// The New* functions always produce valid changes, but a change may become
+// invalid if its fields are modified directly, or if it was decoded from
+// an untrusted source. Callers can use Validate to detect such changes
+// before acting on them.
+func (c *Change) Validate() tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+
+	if _, known := actionMarkers[c.Action]; !known {
+		return diags.Append(invalidChange("The change has an unsupported action %s.", c.Action))
+	}
+	if c.Type == cty.NilType {
+		return diags.Append(invalidChange("The change has no type."))
+	}
+	for _, v := range []struct {
+		name string
+		val  cty.Value
+	}{{"old", c.Old}, {"new", c.New}} {
+		if v.val == cty.NilVal {
+			diags = diags.Append(invalidChange("The %s value is not set.", v.name))
+			continue
+		}
+		if errs := v.val.Type().TestConformance(c.Type); len(errs) > 0 {
+			diags = diags.Append(invalidChange("The %s value does not conform to %s: %s.", v.name, c.Type.FriendlyName(), errs[0]))
+		}
+	}
+	if diags.HasErrors() {
+		return diags
+	}
+
+	switch c.Action {
+	case NoOp:
+		if !c.ChangedPaths().Empty() {
+			diags = diags.Append(invalidChange("A no-op change must have identical old and new values."))
+		}
+	case Create:
+		if !c.Old.IsNull() {
+			diags = diags.Append(invalidChange("A create change must have a null old value."))
+		}
+		if c.New.IsNull() {
+			diags = diags.Append(invalidChange("A create change must not have a null new value."))
+		}
+	case Read:
+		if c.New.IsNull() {
+			diags = diags.Append(invalidChange("A read change must not have a null new value."))
+		}
+	case Update:
+		if c.Old.IsNull() || c.New.IsNull() {
+			diags = diags.Append(invalidChange("An update change must have non-null old and new values."))
+		}
+	case Replace:
+		if c.Old.IsNull() || c.New.IsNull() {
+			diags = diags.Append(invalidChange("A replace change must have non-null old and new values."))
+		}
+	case Delete:
+		if c.Old.IsNull() {
+			diags = diags.Append(invalidChange("A delete change must not have a null old value."))
+		}
+		if !c.New.IsNull() {
+			diags = diags.Append(invalidChange("A delete change must have a null new value."))
+		}
+	case Forget:
+		if c.Old.IsNull() {
+			diags = diags.Append(invalidChange("A forget change must not have a null old value."))
+		}
+		if !c.New.IsNull() {
+			diags = diags.Append(invalidChange("A forget change must have a null new value."))
+		}
+	}
+
+	switch {
+	case c.ReplaceReason < ReplaceReasonNone || c.ReplaceReason > ReplaceBecauseDependency:
+		diags = diags.Append(invalidChange("The change has an unsupported replace reason %s.", c.ReplaceReason))
+	case c.Action != Replace && c.ReplaceReason != ReplaceReasonNone:
+		diags = diags.Append(invalidChange("Only a replace change may have a replace reason."))
+	case c.Action == Replace && c.ForcedReplace.Empty() && !c.ReplaceReason.unconditional():
+		diags = diags.Append(invalidChange("A replace change must have at least one path that forces replacement, unless the replacement was requested or the object is tainted."))
+	}
+	if c.Action != Replace && !c.ForcedReplace.Empty() {
+		diags = diags.Append(invalidChange("Only a replace change may have paths that force replacement."))
+	}
+	for _, p := range c.ForcedReplaceReasons.Paths().List() {
+		switch reason := c.ForcedReplaceReasons.Get(p); {
+		case !c.ForcedReplace.Has(p):
+			diags = diags.Append(invalidChange("A replace reason is recorded for %s, which does not force replacement.", formatPath(p)))
+		case reason != ReplaceBecauseCannotUpdate && reason != ReplaceBecauseDependency:
+			diags = diags.Append(invalidChange("The path %s has replace reason %s, which does not apply to a single path.", formatPath(p), reason))
+		}
+	}
+
+	return diags
+}
+
+func invalidChange(detail string, args ...interface{}) *hcl.Diagnostic {
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Invalid change",
+		Detail:   fmt.Sprintf(detail, args...),
+	}
+}
@@ -0,0 +1,137 @@
+package diffs
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestChangeValidate(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"ami": cty.String,
+	})
+	v1 := cty.ObjectVal(map[string]cty.Value{"ami": cty.StringVal("ami-1")})
+	v2 := cty.ObjectVal(map[string]cty.Value{"ami": cty.StringVal("ami-2")})
+	null := cty.NullVal(ty)
+	forced := NewPathSet(cty.Path{cty.GetAttrStep{Name: "ami"}})
+	dependency := NewPathReasons()
+	dependency.Set(cty.Path{cty.GetAttrStep{Name: "ami"}}, ReplaceBecauseDependency)
+	requested := NewPathReasons()
+	requested.Set(cty.Path{cty.GetAttrStep{Name: "ami"}}, ReplaceByRequest)
+
+	tests := map[string]struct {
+		Change  *Change
+		WantErr bool
+	}{
+		"valid no-op": {
+			NewNoOp(ty, v1),
+			false,
+		},
+		"valid create": {
+			NewCreate(ty, v1),
+			false,
+		},
+		"valid update": {
+			NewUpdate(ty, v1, v2),
+			false,
+		},
+		"valid replace": {
+			NewReplace(ty, v1, v2, forced),
+			false,
+		},
+		"valid requested replace": {
+			NewRequestedReplace(ty, v1, v1),
+			false,
+		},
+		"valid tainted replace": {
+			NewReplace(ty, v1, v1, NewPathSet()).WithReplaceReason(ReplaceBecauseTainted),
+			false,
+		},
+		"valid dependency replace": {
+			&Change{
+				Action: Replace, Type: ty, Old: v1, New: v2,
+				ForcedReplace:        forced,
+				ForcedReplaceReasons: dependency,
+				ReplaceReason:        ReplaceBecauseDependency,
+			},
+			false,
+		},
+		"update with replace reason": {
+			&Change{Action: Update, Type: ty, Old: v1, New: v2, ReplaceReason: ReplaceByRequest},
+			true,
+		},
+		"unsupported replace reason": {
+			&Change{Action: Replace, Type: ty, Old: v1, New: v2, ForcedReplace: forced, ReplaceReason: ReplaceReason(99)},
+			true,
+		},
+		"reason for path not forcing replacement": {
+			&Change{Action: Replace, Type: ty, Old: v1, New: v2, ForcedReplaceReasons: dependency, ReplaceReason: ReplaceByRequest},
+			true,
+		},
+		"requested reason for path": {
+			&Change{Action: Replace, Type: ty, Old: v1, New: v2, ForcedReplace: forced, ForcedReplaceReasons: requested},
+			true,
+		},
+		"valid import": {
+			NewImport(ty, v1),
+			false,
+		},
+		"valid delete": {
+			NewDelete(ty, v1),
+			false,
+		},
+		"valid forget": {
+			NewForget(ty, v1),
+			false,
+		},
+		"forget with new value": {
+			&Change{Action: Forget, Type: ty, Old: v1, New: v1},
+			true,
+		},
+		"no-op with differing values": {
+			&Change{Action: NoOp, Type: ty, Old: v1, New: v2},
+			true,
+		},
+		"create with old value": {
+			&Change{Action: Create, Type: ty, Old: v1, New: v2},
+			true,
+		},
+		"delete with new value": {
+			&Change{Action: Delete, Type: ty, Old: v1, New: v2},
+			true,
+		},
+		"update from null": {
+			&Change{Action: Update, Type: ty, Old: null, New: v2},
+			true,
+		},
+		"replace without forced paths": {
+			&Change{Action: Replace, Type: ty, Old: v1, New: v2},
+			true,
+		},
+		"update with forced paths": {
+			&Change{Action: Update, Type: ty, Old: v1, New: v2, ForcedReplace: forced},
+			true,
+		},
+		"nonconforming value": {
+			&Change{Action: Update, Type: ty, Old: v1, New: cty.StringVal("ami-2")},
+			true,
+		},
+		"missing value": {
+			&Change{Action: Create, Type: ty, Old: null},
+			true,
+		},
+		"unsupported action": {
+			&Change{Action: Action('?'), Type: ty, Old: v1, New: v2},
+			true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			diags := test.Change.Validate()
+			if got := diags.HasErrors(); got != test.WantErr {
+				t.Errorf("wrong result %t; want %t\n%s", got, test.WantErr, diags.Err())
+			}
+		})
+	}
+}
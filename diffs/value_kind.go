@@ -0,0 +1,57 @@
+package diffs
+
+import (
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ValueKind describes how a value is displayed by the renderer.
+type ValueKind int
+
+//go:generate stringer -type=ValueKind
+
+const (
+	// KindOther is the kind of any value not covered by the other kinds.
+	KindOther ValueKind = iota
+
+	// KindNull is the kind of null values.
+	KindNull
+
+	// KindUnknown is the kind of unknown values.
+	KindUnknown
+
+	// KindString is the kind of string values.
+	KindString
+
+	// KindNumber is the kind of number values.
+	KindNumber
+
+	// KindBool is the kind of boolean values.
+	KindBool
+
+	// KindReference is the kind of string values that represent a
+	// reference to some other object, such as an expression in the
+	// configuration language. DefaultClassify never returns this kind,
+	// since only the caller can recognize references.
+	KindReference
+)
+
+// DefaultClassify is the default value of RenderOpts.Classify, which infers
+// the kind of a value from its type.
+func DefaultClassify(path cty.Path, v cty.Value) ValueKind {
+	switch {
+	case !v.IsKnown():
+		return KindUnknown
+	case v.IsNull():
+		return KindNull
+	}
+	switch v.Type() {
+	case cty.String:
+		return KindString
+	case cty.Number:
+		return KindNumber
+	case cty.Bool:
+		return KindBool
+	default:
+		return KindOther
+	}
+}
@@ -0,0 +1,68 @@
+package diffs
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestRenderOptsClassify(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"count":  cty.Number,
+		"name":   cty.String,
+		"subnet": cty.String,
+	})
+	c := NewCreate(ty, cty.ObjectVal(map[string]cty.Value{
+		"count":  cty.NumberIntVal(2),
+		"name":   cty.StringVal("web"),
+		"subnet": cty.StringVal("aws_subnet.main.id"),
+	}))
+
+	refPattern := regexp.MustCompile(`^[a-z_]+\.[a-z_]+\.[a-z_]+$`)
+	opts := RenderOpts{
+		Classify: func(path cty.Path, v cty.Value) ValueKind {
+			if v.IsKnown() && !v.IsNull() && v.Type() == cty.String && refPattern.MatchString(v.AsString()) {
+				return KindReference
+			}
+			return DefaultClassify(path, v)
+		},
+		Decorate: func(kind ValueKind, text string) string {
+			return fmt.Sprintf("<%s>%s", kind, text)
+		},
+	}
+
+	got := c.Render(opts)
+	want := `+ {
+    + count  = <KindNumber>2
+    + name   = <KindString>"web"
+    + subnet = <KindReference>aws_subnet.main.id
+  }
+`
+	if got != want {
+		t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDefaultClassify(t *testing.T) {
+	tests := []struct {
+		Value cty.Value
+		Want  ValueKind
+	}{
+		{cty.StringVal("a"), KindString},
+		{cty.NumberIntVal(1), KindNumber},
+		{cty.True, KindBool},
+		{cty.NullVal(cty.String), KindNull},
+		{cty.UnknownVal(cty.Number), KindUnknown},
+		{cty.ListValEmpty(cty.String), KindOther},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Value.GoString(), func(t *testing.T) {
+			if got := DefaultClassify(nil, test.Value); got != test.Want {
+				t.Errorf("wrong result %s; want %s", got, test.Want)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package diffs
+
+import (
+	"fmt"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// whollyKnown returns true if the given value is known and contains no
+// unknown values nested within it.
+func whollyKnown(v cty.Value) bool {
+	if !v.IsKnown() {
+		return false
+	}
+	if v.IsNull() || !v.CanIterateElements() {
+		return true
+	}
+	for it := v.ElementIterator(); it.Next(); {
+		_, ev := it.Element()
+		if !whollyKnown(ev) {
+			return false
+		}
+	}
+	return true
+}
+
+// stripUnknowns returns a copy of the given value with any unknown values
+// within it replaced by nulls of the same type, appending the path of each
+// replaced value to the given slice.
+//
+// Sets cannot be partially unknown without changing the identity of their
+// elements, so a set containing any unknowns is replaced as a whole.
+func stripUnknowns(path cty.Path, v cty.Value, unknowns *[]cty.Path) cty.Value {
+	if !v.IsKnown() {
+		*unknowns = append(*unknowns, path.Copy())
+		return cty.NullVal(v.Type())
+	}
+	if v.IsNull() || !v.CanIterateElements() {
+		return v
+	}
+
+	ty := v.Type()
+	switch {
+	case (ty.IsMapType() || ty.IsListType()) && v.LengthInt() == 0:
+		// Empty collections can't be reconstructed without their element
+		// type, but there's nothing to strip from them anyway.
+		return v
+	case ty.IsObjectType():
+		attrs := make(map[string]cty.Value)
+		for name := range ty.AttributeTypes() {
+			attrs[name] = stripUnknowns(path.GetAttr(name), v.GetAttr(name), unknowns)
+		}
+		return cty.ObjectVal(attrs)
+	case ty.IsMapType():
+		elems := make(map[string]cty.Value)
+		for it := v.ElementIterator(); it.Next(); {
+			k, ev := it.Element()
+			elems[k.AsString()] = stripUnknowns(path.Index(k), ev, unknowns)
+		}
+		return cty.MapVal(elems)
+	case ty.IsListType() || ty.IsTupleType():
+		var elems []cty.Value
+		for it := v.ElementIterator(); it.Next(); {
+			k, ev := it.Element()
+			elems = append(elems, stripUnknowns(path.Index(k), ev, unknowns))
+		}
+		if ty.IsTupleType() {
+			return cty.TupleVal(elems)
+		}
+		return cty.ListVal(elems)
+	case ty.IsSetType():
+		if !whollyKnown(v) {
+			*unknowns = append(*unknowns, path.Copy())
+			return cty.NullVal(ty)
+		}
+	}
+	return v
+}
+
+// replaceAt returns a copy of the given value with the value at the given
+// path replaced by the result of the given function. An error is returned
+// if the path does not exist within the value, or if it traverses through
+// a set, whose elements have no paths of their own.
+func replaceAt(v cty.Value, p cty.Path, fn func(cty.Value) cty.Value) (cty.Value, error) {
+	tokens := make([]string, len(p))
+	for i, step := range p {
+		tok, err := stepToken(step)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		tokens[i] = tok
+	}
+	return updateAt(v, tokens, func(container cty.Value, key string) (cty.Value, error) {
+		child, err := getChild(container, key)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		return setChild(container, key, fn(child), false)
+	}, func(v cty.Value) (cty.Value, error) {
+		return fn(v), nil
+	})
+}
+
+// stepToken returns the token that selects the child at the given step of
+// a path, as used by getChild and setChild.
+func stepToken(step cty.PathStep) (string, error) {
+	switch ts := step.(type) {
+	case cty.GetAttrStep:
+		return ts.Name, nil
+	case cty.IndexStep:
+		switch ts.Key.Type() {
+		case cty.String:
+			return ts.Key.AsString(), nil
+		case cty.Number:
+			return ts.Key.AsBigFloat().Text('f', -1), nil
+		default:
+			return "", fmt.Errorf("unsupported index key type %s", ts.Key.Type().FriendlyName())
+		}
+	}
+	return "", fmt.Errorf("unsupported path step %T", step)
+}
+
+// valuesAt returns the values at the given path within the given value.
+//
+// Unlike cty.Path.Apply, a path that doesn't exist within the value,
+// including one through a null or unknown value, yields no values rather
+// than an error, and tuples are supported. Set elements have no paths of
+// their own, so a step into a set yields the values at the rest of the
+// path within every element of the set, or the set itself if it's the last
+// step.
+func valuesAt(v cty.Value, p cty.Path) []cty.Value {
+	if len(p) == 0 {
+		return []cty.Value{v}
+	}
+	if !v.IsKnown() || v.IsNull() {
+		return nil
+	}
+	if v.Type().IsSetType() {
+		if len(p) == 1 {
+			return []cty.Value{v}
+		}
+		var ret []cty.Value
+		for it := v.ElementIterator(); it.Next(); {
+			_, ev := it.Element()
+			ret = append(ret, valuesAt(ev, p[1:])...)
+		}
+		return ret
+	}
+	tok, err := stepToken(p[0])
+	if err != nil {
+		return nil
+	}
+	child, err := getChild(v, tok)
+	if err != nil {
+		return nil
+	}
+	return valuesAt(child, p[1:])
+}
@@ -0,0 +1,158 @@
+package diffs
+
+import (
+	"sort"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// walkChanges decomposes the transition between the two given values into
+// changes to individual leaf values, calling the given callback for each
+// one.
+//
+// Objects, maps, lists and tuples are decomposed into their attributes or
+// elements. Sets are decomposed into the elements that were added or
+// removed. Unknown values and primitive values are leaves. Positions where
+// both values are identical are skipped. Attributes and map elements are
+// visited in lexical order, and added or removed set elements in order of
+// their values, so that the sequence of callbacks is deterministic.
+func walkChanges(path cty.Path, old, new cty.Value, cb func(path cty.Path, old, new cty.Value, action Action)) {
+	if old.RawEquals(new) {
+		return
+	}
+
+	ty, ok := collectionType(old, new)
+	if !ok {
+		cb(path, old, new, leafAction(old, new))
+		return
+	}
+
+	switch {
+	case ty.IsObjectType():
+		atys := ty.AttributeTypes()
+		names := make([]string, 0, len(atys))
+		for name := range atys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			aty := atys[name]
+			walkChanges(path.GetAttr(name), attrOrNull(old, name, aty), attrOrNull(new, name, aty), cb)
+		}
+	case ty.IsMapType():
+		ety := ty.ElementType()
+		oldElems := mapElements(old)
+		newElems := mapElements(new)
+		keys := make([]string, 0, len(oldElems))
+		for k := range oldElems {
+			keys = append(keys, k)
+		}
+		for k := range newElems {
+			if _, exists := oldElems[k]; !exists {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			oldV, exists := oldElems[k]
+			if !exists {
+				oldV = cty.NullVal(ety)
+			}
+			newV, exists := newElems[k]
+			if !exists {
+				newV = cty.NullVal(ety)
+			}
+			walkChanges(path.Index(cty.StringVal(k)), oldV, newV, cb)
+		}
+	case ty.IsListType() || ty.IsTupleType():
+		oldElems := sequenceElements(old)
+		newElems := sequenceElements(new)
+		n := len(oldElems)
+		if len(newElems) > n {
+			n = len(newElems)
+		}
+		for i := 0; i < n; i++ {
+			ety := sequenceElementType(ty, i)
+			oldV, newV := cty.NullVal(ety), cty.NullVal(ety)
+			if i < len(oldElems) {
+				oldV = oldElems[i]
+			}
+			if i < len(newElems) {
+				newV = newElems[i]
+			}
+			walkChanges(path.Index(cty.NumberIntVal(int64(i))), oldV, newV, cb)
+		}
+	case ty.IsSetType():
+		ety := ty.ElementType()
+		oldElems := sortedSetElements(old)
+		newElems := sortedSetElements(new)
+		inOld := make(map[string]bool, len(oldElems))
+		for _, v := range oldElems {
+			inOld[indexKey(v)] = true
+		}
+		inNew := make(map[string]bool, len(newElems))
+		for _, v := range newElems {
+			inNew[indexKey(v)] = true
+		}
+		for _, v := range oldElems {
+			if !inNew[indexKey(v)] {
+				cb(path.Index(v), v, cty.NullVal(ety), Delete)
+			}
+		}
+		for _, v := range newElems {
+			if !inOld[indexKey(v)] {
+				cb(path.Index(v), cty.NullVal(ety), v, Create)
+			}
+		}
+	}
+}
+
+// walkLeaves calls walkChanges for the old and new values of the change,
+// skipping any leaf changes that are within the change's WriteOnly paths.
+func (c *Change) walkLeaves(cb func(path cty.Path, old, new cty.Value, action Action)) {
+	walkChangesExcept(nil, c.Old, c.New, c.WriteOnly, cb)
+}
+
+// walkChangesExcept is like walkChanges but skips any leaf changes that are
+// within one of the paths in the given set.
+func walkChangesExcept(path cty.Path, old, new cty.Value, except PathSet, cb func(path cty.Path, old, new cty.Value, action Action)) {
+	if except.Empty() {
+		walkChanges(path, old, new, cb)
+		return
+	}
+	walkChanges(path, old, new, func(path cty.Path, old, new cty.Value, action Action) {
+		if !pathSetCovers(except, path) {
+			cb(path, old, new, action)
+		}
+	})
+}
+
+// WalkChanges decomposes the given change into changes to individual leaf
+// values, calling the given function for each one with its path, its old
+// and new values, and the action that describes it. Walking stops at the
+// first error returned by the function, which is then returned.
+//
+// Objects, maps, lists and tuples are decomposed into their attributes or
+// elements, with list and tuple elements compared by index. Sets are
+// decomposed into the elements that were added or removed, whose paths use
+// the element value as the index key. A value that is unknown on either
+// side is a leaf, as are primitive values. Differences within the change's
+// WriteOnly paths are not reported.
+func WalkChanges(c *Change, fn func(path cty.Path, old, new cty.Value, action Action) error) error {
+	var err error
+	c.walkLeaves(func(path cty.Path, old, new cty.Value, action Action) {
+		if err != nil {
+			return
+		}
+		err = fn(path, old, new, action)
+	})
+	return err
+}
+
+func sortedSetElements(v cty.Value) []cty.Value {
+	ret := sequenceElements(v)
+	sort.Slice(ret, func(i, j int) bool {
+		return indexKey(ret[i]) < indexKey(ret[j])
+	})
+	return ret
+}
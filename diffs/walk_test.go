@@ -0,0 +1,80 @@
+package diffs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestWalkChanges(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"id":    cty.String,
+		"name":  cty.String,
+		"ports": cty.List(cty.Number),
+		"tags":  cty.Map(cty.String),
+		"zones": cty.Set(cty.String),
+	})
+	old := cty.ObjectVal(map[string]cty.Value{
+		"id":    cty.StringVal("i-abc123"),
+		"name":  cty.StringVal("foo"),
+		"ports": cty.ListVal([]cty.Value{cty.NumberIntVal(80)}),
+		"tags": cty.MapVal(map[string]cty.Value{
+			"env":  cty.StringVal("prod"),
+			"team": cty.StringVal("a"),
+		}),
+		"zones": cty.SetVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+	})
+	new := cty.ObjectVal(map[string]cty.Value{
+		"id":    cty.UnknownVal(cty.String),
+		"name":  cty.StringVal("foo"),
+		"ports": cty.ListVal([]cty.Value{cty.NumberIntVal(80), cty.NumberIntVal(443)}),
+		"tags": cty.MapVal(map[string]cty.Value{
+			"env":   cty.StringVal("staging"),
+			"owner": cty.StringVal("b"),
+		}),
+		"zones": cty.SetVal([]cty.Value{cty.StringVal("b"), cty.StringVal("c")}),
+	})
+	change := NewUpdate(ty, old, new)
+
+	var got []string
+	err := WalkChanges(change, func(path cty.Path, old, new cty.Value, action Action) error {
+		got = append(got, fmt.Sprintf("%s %s", action, formatPath(path)))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []string{
+		`Update id`,
+		`Create ports[1]`,
+		`Update tags["env"]`,
+		`Create tags["owner"]`,
+		`Delete tags["team"]`,
+		`Delete zones["a"]`,
+		`Create zones["c"]`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	t.Run("stops at first error", func(t *testing.T) {
+		wantErr := errors.New("tags may not change")
+		calls := 0
+		err := WalkChanges(change, func(path cty.Path, old, new cty.Value, action Action) error {
+			calls++
+			if pathHasPrefix(path, cty.Path{cty.GetAttrStep{Name: "tags"}}) {
+				return wantErr
+			}
+			return nil
+		})
+		if err != wantErr {
+			t.Errorf("wrong error %v; want %v", err, wantErr)
+		}
+		if calls != 3 {
+			t.Errorf("wrong number of calls %d; want 3", calls)
+		}
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"os/exec"
 	"path/filepath"
 
+	"github.com/hashicorp/terraform/planfile"
 	tfcore "github.com/hashicorp/terraform/terraform"
 )
 
@@ -181,12 +182,7 @@ func (b *binary) LocalState() (*tfcore.State, error) {
 
 // Plan is a helper for easily reading a plan file from the working directory.
 func (b *binary) Plan(path ...string) (*tfcore.Plan, error) {
-	f, err := b.OpenFile(path...)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-	return tfcore.ReadPlan(f)
+	return planfile.ReadPlan(b.Path(path...))
 }
 
 // SetLocalState is a helper for easily writing to the file the local backend
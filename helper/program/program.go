@@ -0,0 +1,81 @@
+// Package program runs the external programs that Terraform is extended
+// with, such as policy hooks, plan analyzers and secrets backends, which
+// read a document from their standard input and write JSON to their
+// standard output.
+package program
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+)
+
+// Program is an external program, which is run with its arguments followed
+// by any given to Run.
+type Program struct {
+	// Path is the path of the executable. If it has no path separators,
+	// it's looked up in the PATH directories.
+	Path string
+	Args []string
+}
+
+// New returns the Program at the given path, with the given arguments. The
+// caller is the name of the function that it's called by, for the message
+// of its panic.
+//
+// The given executable path must be an absolute path; it is the caller's
+// responsibility to validate and process a relative path or other input
+// provided by an end-user. If the given path is not absolute, this
+// function will panic.
+func New(caller, executable string, args ...string) *Program {
+	if !filepath.IsAbs(executable) {
+		panic(caller + " requires absolute path to executable")
+	}
+	return &Program{Path: executable, Args: args}
+}
+
+// Run runs the program with the given additional arguments, with the given
+// input, if any, on its standard input, and returns what it wrote to its
+// standard output.
+//
+// If the program exits with a non-zero status, the error has what it wrote
+// to its standard error.
+func (p *Program) Run(input []byte, args ...string) ([]byte, error) {
+	outBuf := bytes.Buffer{}
+	errBuf := bytes.Buffer{}
+
+	cmd := exec.Command(p.Path, append(append([]string(nil), p.Args...), args...)...)
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+	cmd.Stdout = &outBuf
+	cmd.Stderr = &errBuf
+	err := cmd.Run()
+	if _, isExitErr := err.(*exec.ExitError); isExitErr {
+		errText := errBuf.String()
+		if errText == "" {
+			// Shouldn't happen for a well-behaved program
+			return nil, fmt.Errorf("error in %s, but it produced no error message", p.Path)
+		}
+		return nil, fmt.Errorf("error in %s: %s", p.Path, errText)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to run %s: %s", p.Path, err)
+	}
+
+	return outBuf.Bytes(), nil
+}
+
+// RunJSON is like Run, except that the JSON object that the program writes
+// to its standard output is decoded into the value pointed to by out.
+func (p *Program) RunJSON(input []byte, out interface{}, args ...string) error {
+	output, err := p.Run(input, args...)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(output, out); err != nil {
+		return fmt.Errorf("malformed output from %s: %s", p.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package program
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNew_relative(t *testing.T) {
+	defer func() {
+		if r := recover(); r != "Test requires absolute path to executable" {
+			t.Fatalf("wrong panic %#v", r)
+		}
+	}()
+	New("Test", "sh")
+}
+
+func TestProgram_Run(t *testing.T) {
+	p := New("Test", "/bin/sh", "-c", `cat; echo " $0 $1"`)
+	out, err := p.Run([]byte("input"), "a", "b")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if got, want := string(out), "input a b\n"; got != want {
+		t.Fatalf("wrong output %q; want %q", got, want)
+	}
+}
+
+func TestProgram_RunError(t *testing.T) {
+	cases := map[string]string{
+		`echo failed >&2; exit 1`: "error in /bin/sh: failed",
+		`exit 1`:                  "error in /bin/sh, but it produced no error message",
+	}
+	for script, want := range cases {
+		_, err := New("Test", "/bin/sh", "-c", script).Run(nil)
+		if err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("%s: wrong error %v; want %q", script, err, want)
+		}
+	}
+}
+
+func TestProgram_RunJSON(t *testing.T) {
+	var out struct {
+		Value string `json:"value"`
+	}
+	p := New("Test", "/bin/sh", "-c", `echo "{\"value\": \"$0\"}"`)
+	if err := p.RunJSON(nil, &out, "hello"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if out.Value != "hello" {
+		t.Fatalf("wrong value %q", out.Value)
+	}
+
+	p = New("Test", "/bin/sh", "-c", `echo not json`)
+	err := p.RunJSON(nil, &out)
+	if err == nil || !strings.HasPrefix(err.Error(), "malformed output from /bin/sh") {
+		t.Fatalf("wrong error %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/hil/ast"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// functionNameRegexp is the pattern that the names of functions must match.
+var functionNameRegexp = regexp.MustCompile(`\A[a-z][a-z0-9_]*\z`)
+
+// Function is a function that the provider exports for use in
+// configuration expressions, which call it as provider::NAME::FUNCTION(...).
+type Function struct {
+	// Description is a human-readable description of the function.
+	Description string
+
+	// Parameters are the parameters of the function in order, and
+	// VariadicParameter, if set, describes any arguments after those.
+	Parameters        []*FunctionParameter
+	VariadicParameter *FunctionParameter
+
+	// ReturnType is the type of the value returned by Call.
+	ReturnType ValueType
+
+	// Call calls the function with arguments that have already been
+	// checked against its parameters. Lists and sets are given as
+	// []interface{} and maps as map[string]interface{}.
+	//
+	// Functions are called during plan as well as apply, so Call must not
+	// have side effects, and should return the same result for the same
+	// arguments. It is not given the provider's meta, since functions may
+	// be called before the provider is configured.
+	Call func(args []interface{}) (interface{}, error)
+}
+
+// FunctionParameter is a parameter of a Function.
+type FunctionParameter struct {
+	Name        string
+	Description string
+	Type        ValueType
+}
+
+// InternalValidate should be called to validate the structure
+// of the function.
+//
+// This should be called in a unit test for any provider to verify
+// before release that its functions are properly configured for use with
+// this library.
+func (f *Function) InternalValidate(name string) error {
+	if !functionNameRegexp.MatchString(name) {
+		return fmt.Errorf("name must match %s", functionNameRegexp)
+	}
+	if f.Call == nil {
+		return fmt.Errorf("Call must be set")
+	}
+	if _, err := functionType(f.ReturnType); err != nil {
+		return fmt.Errorf("return type: %s", err)
+	}
+
+	params := append([]*FunctionParameter{}, f.Parameters...)
+	if f.VariadicParameter != nil {
+		params = append(params, f.VariadicParameter)
+	}
+	for i, p := range params {
+		if p.Name == "" {
+			return fmt.Errorf("parameter %d: Name must be set", i+1)
+		}
+		if _, err := functionType(p.Type); err != nil {
+			return fmt.Errorf("parameter %s: %s", p.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (f *Function) providerFunction(name string) terraform.ProviderFunction {
+	result := terraform.ProviderFunction{
+		Name:        name,
+		Description: f.Description,
+	}
+	result.ReturnType, _ = functionType(f.ReturnType)
+
+	for _, p := range f.Parameters {
+		result.Parameters = append(result.Parameters, p.functionParameter())
+	}
+	if f.VariadicParameter != nil {
+		p := f.VariadicParameter.functionParameter()
+		result.VariadicParameter = &p
+	}
+
+	return result
+}
+
+func (p *FunctionParameter) functionParameter() terraform.FunctionParameter {
+	t, _ := functionType(p.Type)
+	return terraform.FunctionParameter{
+		Name:        p.Name,
+		Description: p.Description,
+		Type:        t,
+	}
+}
+
+// functionType returns the interpolation type of the given value type of
+// a function parameter or return value. Sets are lists in interpolations.
+func functionType(t ValueType) (ast.Type, error) {
+	switch t {
+	case TypeBool:
+		return ast.TypeBool, nil
+	case TypeInt:
+		return ast.TypeInt, nil
+	case TypeFloat:
+		return ast.TypeFloat, nil
+	case TypeString:
+		return ast.TypeString, nil
+	case TypeList, TypeSet:
+		return ast.TypeList, nil
+	case TypeMap:
+		return ast.TypeMap, nil
+	default:
+		return ast.TypeInvalid, fmt.Errorf("unsupported type %s", t)
+	}
+}
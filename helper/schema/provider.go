@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"sync"
 
 	"github.com/hashicorp/go-multierror"
@@ -46,6 +47,21 @@ type Provider struct {
 	// and must *not* implement Create, Update or Delete.
 	DataSourcesMap map[string]*Resource
 
+	// EphemeralResourcesMap is the collection of available ephemeral
+	// resources that this provider implements, with a Resource instance
+	// defining the schema and operations of each.
+	//
+	// The Read function of an ephemeral resource opens it, setting its
+	// attributes from the configuration, and the optional Delete function
+	// closes it at the end of the run. They must *not* implement Create
+	// or Update.
+	EphemeralResourcesMap map[string]*Resource
+
+	// FunctionsMap is the collection of functions that this provider
+	// exports for use in configuration expressions, keyed by their
+	// names without the provider::NAME:: prefix.
+	FunctionsMap map[string]*Function
+
 	// ConfigureFunc is a function for configuring the provider. If the
 	// provider doesn't need to be configured, this can be omitted.
 	//
@@ -110,6 +126,22 @@ func (p *Provider) InternalValidate() error {
 		}
 	}
 
+	for k, r := range p.EphemeralResourcesMap {
+		// Delete closes an ephemeral resource, rather than destroying it,
+		// so it's allowed unlike for data sources.
+		open := *r
+		open.Delete = nil
+		if err := open.InternalValidate(nil, false); err != nil {
+			validationErrors = multierror.Append(validationErrors, fmt.Errorf("ephemeral resource %s: %s", k, err))
+		}
+	}
+
+	for k, f := range p.FunctionsMap {
+		if err := f.InternalValidate(k); err != nil {
+			validationErrors = multierror.Append(validationErrors, fmt.Errorf("function %s: %s", k, err))
+		}
+	}
+
 	return validationErrors
 }
 
@@ -190,10 +222,12 @@ func (p *Provider) TestReset() error {
 func (p *Provider) GetSchema(req *terraform.ProviderSchemaRequest) (*terraform.ProviderSchema, error) {
 	resourceTypes := map[string]*configschema.Block{}
 	dataSources := map[string]*configschema.Block{}
+	versions := map[string]uint64{}
 
 	for _, name := range req.ResourceTypes {
 		if r, exists := p.ResourcesMap[name]; exists {
 			resourceTypes[name] = r.CoreConfigSchema()
+			versions[name] = uint64(r.SchemaVersion)
 		}
 	}
 	for _, name := range req.DataSources {
@@ -206,6 +240,8 @@ func (p *Provider) GetSchema(req *terraform.ProviderSchemaRequest) (*terraform.P
 		Provider:      schemaMap(p.Schema).CoreConfigSchema(),
 		ResourceTypes: resourceTypes,
 		DataSources:   dataSources,
+
+		ResourceTypeSchemaVersions: versions,
 	}, nil
 }
 
@@ -283,6 +319,49 @@ func (p *Provider) Apply(
 	return r.Apply(s, d, p.meta)
 }
 
+// ApplyContext implementation of terraform.ResourceProviderContextApplier
+// interface. The context of the resource's CRUD functions is cancelled
+// when either the given context is cancelled or the provider is stopped.
+func (p *Provider) ApplyContext(
+	ctx context.Context,
+	info *terraform.InstanceInfo,
+	s *terraform.InstanceState,
+	d *terraform.InstanceDiff) (*terraform.InstanceState, error) {
+	r, ok := p.ResourcesMap[info.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown resource type: %s", info.Type)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stopCtx := p.StopContext()
+	go func() {
+		select {
+		case <-stopCtx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return r.ApplyContext(ctx, s, d, p.meta)
+}
+
+// ValidateApply implementation of terraform.ResourceProvider interface.
+func (p *Provider) ValidateApply(
+	info *terraform.InstanceInfo,
+	s *terraform.InstanceState,
+	d *terraform.InstanceDiff) ([]string, []error) {
+	r, ok := p.ResourcesMap[info.Type]
+	if !ok {
+		return nil, []error{fmt.Errorf("unknown resource type: %s", info.Type)}
+	}
+
+	if err := r.DryRunApply(s, d, p.meta); err != nil {
+		return nil, []error{err}
+	}
+	return nil, nil
+}
+
 // Diff implementation of terraform.ResourceProvider interface.
 func (p *Provider) Diff(
 	info *terraform.InstanceInfo,
@@ -426,6 +505,101 @@ func (p *Provider) ReadDataApply(
 	return r.ReadDataApply(d, p.meta)
 }
 
+// MoveResourceState implementation of terraform.ResourceProviderStateMover
+// interface.
+func (p *Provider) MoveResourceState(
+	req *terraform.MoveResourceStateRequest) (*terraform.InstanceState, error) {
+
+	r, ok := p.ResourcesMap[req.TargetType]
+	if !ok {
+		return nil, fmt.Errorf("unknown resource type: %s", req.TargetType)
+	}
+	if r.MoveState == nil {
+		return nil, fmt.Errorf(
+			"resource %s doesn't support moving state from other resource types", req.TargetType)
+	}
+
+	state, err := r.MoveState(req)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil || state.ID == "" {
+		return nil, fmt.Errorf(
+			"resource %s returned no state when moving %s", req.TargetType, req.SourceType)
+	}
+
+	if state.Meta == nil {
+		state.Meta = make(map[string]interface{})
+	}
+	state.Meta["schema_version"] = strconv.Itoa(r.SchemaVersion)
+	return state, nil
+}
+
+// OpenEphemeral implementation of terraform.ResourceProvider interface.
+func (p *Provider) OpenEphemeral(
+	info *terraform.InstanceInfo,
+	c *terraform.ResourceConfig) (*terraform.InstanceState, error) {
+
+	r, ok := p.EphemeralResourcesMap[info.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown ephemeral resource: %s", info.Type)
+	}
+
+	// An ephemeral resource is opened in the same way as a data source is
+	// read, from a diff of its configuration.
+	d, err := r.Diff(nil, c, p.meta)
+	if err != nil {
+		return nil, err
+	}
+	return r.ReadDataApply(d, p.meta)
+}
+
+// CloseEphemeral implementation of terraform.ResourceProvider interface.
+func (p *Provider) CloseEphemeral(
+	info *terraform.InstanceInfo,
+	s *terraform.InstanceState) error {
+
+	r, ok := p.EphemeralResourcesMap[info.Type]
+	if !ok {
+		return fmt.Errorf("unknown ephemeral resource: %s", info.Type)
+	}
+	if r.Delete == nil {
+		return nil
+	}
+
+	data, err := schemaMap(r.Schema).Data(s, nil)
+	if err != nil {
+		return err
+	}
+	return r.Delete(data, p.meta)
+}
+
+// Functions implementation of terraform.ResourceProvider interface.
+func (p *Provider) Functions() []terraform.ProviderFunction {
+	keys := make([]string, 0, len(p.FunctionsMap))
+	for k := range p.FunctionsMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]terraform.ProviderFunction, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, p.FunctionsMap[k].providerFunction(k))
+	}
+
+	return result
+}
+
+// CallFunction implementation of terraform.ResourceProvider interface.
+func (p *Provider) CallFunction(name string, args []interface{}) (interface{}, error) {
+	f, ok := p.FunctionsMap[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function: %s", name)
+	}
+
+	return f.Call(args)
+}
+
 // DataSources implementation of terraform.ResourceProvider interface.
 func (p *Provider) DataSources() []terraform.DataSource {
 	keys := make([]string, 0, len(p.DataSourcesMap))
@@ -3,10 +3,12 @@ package schema
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/hashicorp/hil/ast"
 	"github.com/zclconf/go-cty/cty"
 
 	"github.com/hashicorp/terraform/config"
@@ -36,6 +38,7 @@ func TestProviderGetSchema(t *testing.T) {
 						Required: true,
 					},
 				},
+				SchemaVersion: 2,
 			},
 		},
 		DataSourcesMap: map[string]*Resource{
@@ -82,6 +85,9 @@ func TestProviderGetSchema(t *testing.T) {
 				BlockTypes: map[string]*configschema.NestedBlock{},
 			},
 		},
+		ResourceTypeSchemaVersions: map[string]uint64{
+			"foo": 2,
+		},
 	}
 	got, err := p.GetSchema(&terraform.ProviderSchemaRequest{
 		ResourceTypes: []string{"foo", "bar"},
@@ -399,6 +405,179 @@ func TestProviderImportState_setsType(t *testing.T) {
 	}
 }
 
+func TestProviderMoveResourceState(t *testing.T) {
+	p := &Provider{
+		ResourcesMap: map[string]*Resource{
+			"foo": &Resource{
+				SchemaVersion: 2,
+				MoveState: func(req *terraform.MoveResourceStateRequest) (*terraform.InstanceState, error) {
+					if req.SourceType != "bar_thing" {
+						return nil, fmt.Errorf("can't move from %s", req.SourceType)
+					}
+					return &terraform.InstanceState{
+						ID:         req.SourceState.ID,
+						Attributes: map[string]string{"name": req.SourceState.Attributes["label"]},
+					}, nil
+				},
+			},
+			"baz": &Resource{},
+		},
+	}
+
+	state, err := p.MoveResourceState(&terraform.MoveResourceStateRequest{
+		SourceProvider: "bar",
+		SourceType:     "bar_thing",
+		SourceState: &terraform.InstanceState{
+			ID:         "abc",
+			Attributes: map[string]string{"label": "web"},
+		},
+		TargetType: "foo",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := &terraform.InstanceState{
+		ID:         "abc",
+		Attributes: map[string]string{"name": "web"},
+		Meta:       map[string]interface{}{"schema_version": "2"},
+	}
+	if !reflect.DeepEqual(state, expected) {
+		t.Fatalf("bad: %#v", state)
+	}
+
+	_, err = p.MoveResourceState(&terraform.MoveResourceStateRequest{
+		SourceType:  "bar_thing",
+		SourceState: &terraform.InstanceState{ID: "abc"},
+		TargetType:  "baz",
+	})
+	if err == nil || !strings.Contains(err.Error(), "doesn't support moving state") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestProviderOpenEphemeral(t *testing.T) {
+	var closed string
+	p := &Provider{
+		EphemeralResourcesMap: map[string]*Resource{
+			"foo": &Resource{
+				Schema: map[string]*Schema{
+					"name": &Schema{
+						Type:     TypeString,
+						Required: true,
+					},
+					"token": &Schema{
+						Type:     TypeString,
+						Computed: true,
+					},
+				},
+				Read: func(d *ResourceData, meta interface{}) error {
+					d.SetId("1")
+					return d.Set("token", "token-for-"+d.Get("name").(string))
+				},
+				Delete: func(d *ResourceData, meta interface{}) error {
+					closed = d.Get("token").(string)
+					return nil
+				},
+			},
+		},
+	}
+	if err := p.InternalValidate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	info := &terraform.InstanceInfo{Type: "foo"}
+	c := terraform.NewResourceConfig(config.TestRawConfig(t, map[string]interface{}{
+		"name": "bar",
+	}))
+	state, err := p.OpenEphemeral(info, c)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if got := state.Attributes["token"]; got != "token-for-bar" {
+		t.Fatalf("wrong token %q", got)
+	}
+
+	if err := p.CloseEphemeral(info, state); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if closed != "token-for-bar" {
+		t.Fatalf("wrong token closed %q", closed)
+	}
+}
+
+func TestProviderFunctions(t *testing.T) {
+	p := &Provider{
+		FunctionsMap: map[string]*Function{
+			"join_path": &Function{
+				Parameters: []*FunctionParameter{
+					&FunctionParameter{Name: "parts", Type: TypeList},
+				},
+				VariadicParameter: &FunctionParameter{Name: "more", Type: TypeString},
+				ReturnType:        TypeString,
+				Call: func(args []interface{}) (interface{}, error) {
+					var parts []string
+					for _, v := range args[0].([]interface{}) {
+						parts = append(parts, v.(string))
+					}
+					for _, v := range args[1:] {
+						parts = append(parts, v.(string))
+					}
+					return strings.Join(parts, "/"), nil
+				},
+			},
+		},
+	}
+	if err := p.InternalValidate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []terraform.ProviderFunction{
+		{
+			Name: "join_path",
+			Parameters: []terraform.FunctionParameter{
+				{Name: "parts", Type: ast.TypeList},
+			},
+			VariadicParameter: &terraform.FunctionParameter{Name: "more", Type: ast.TypeString},
+			ReturnType:        ast.TypeString,
+		},
+	}
+	if actual := p.Functions(); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	v, err := p.CallFunction("join_path", []interface{}{[]interface{}{"a", "b"}, "c"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v != "a/b/c" {
+		t.Fatalf("bad: %#v", v)
+	}
+
+	if _, err := p.CallFunction("nope", nil); err == nil {
+		t.Fatal("should error for an unknown function")
+	}
+}
+
+func TestProviderFunctions_invalid(t *testing.T) {
+	call := func(args []interface{}) (interface{}, error) { return nil, nil }
+	cases := map[string]*Function{
+		"Bad-Name": &Function{ReturnType: TypeString, Call: call},
+		"no_call":  &Function{ReturnType: TypeString},
+		"no_type":  &Function{Call: call},
+		"unnamed_param": &Function{
+			Parameters: []*FunctionParameter{&FunctionParameter{Type: TypeString}},
+			ReturnType: TypeString,
+			Call:       call,
+		},
+	}
+	for name, f := range cases {
+		p := &Provider{FunctionsMap: map[string]*Function{name: f}}
+		if err := p.InternalValidate(); err == nil {
+			t.Fatalf("%s: should not be valid", name)
+		}
+	}
+}
+
 func TestProviderMeta(t *testing.T) {
 	p := new(Provider)
 	if v := p.Meta(); v != nil {
@@ -1,6 +1,7 @@
 package schema
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -56,6 +57,19 @@ type Resource struct {
 	// needs to make any remote API calls.
 	MigrateState StateMigrateFunc
 
+	// MoveState converts the state of an object of another resource type,
+	// which may belong to another provider, into the state of an object of
+	// this resource type, so that moved blocks can move it to a resource of
+	// this type without it being destroyed and created again. If it's nil,
+	// objects can't be moved to this resource type from others.
+	//
+	// The function is yielded the request, which includes the source
+	// resource type and the InstanceState of the object, whose Meta has the
+	// schema version of the source resource type. The provider isn't
+	// configured, so no remote API calls can be made. The state it returns
+	// must be in the format of the current SchemaVersion.
+	MoveState StateMoveFunc
+
 	// The functions below are the CRUD operations for this resource.
 	//
 	// The only optional operation is Update. If Update is not implemented,
@@ -116,6 +130,14 @@ type Resource struct {
 	// This function is only allowed on regular resources (not data sources).
 	CustomizeDiff CustomizeDiffFunc
 
+	// ValidateApply is called for a dry-run apply to check that a create or
+	// update could be made, without making it, for example to check quotas
+	// and permissions that the diff can't. It is passed the *ResourceData
+	// that Create or Update would be, which should _not_ be modified: for a
+	// new resource, Id is empty. It isn't called for destroys, and if it
+	// isn't set, changes to the resource aren't checked.
+	ValidateApply ValidateApplyFunc
+
 	// Importer is the ResourceImporter implementation for this resource.
 	// If this is nil, then this resource does not support importing. If
 	// this is non-nil, then it supports importing and ResourceImporter
@@ -157,11 +179,28 @@ type ExistsFunc func(*ResourceData, interface{}) (bool, error)
 type StateMigrateFunc func(
 	int, *terraform.InstanceState, interface{}) (*terraform.InstanceState, error)
 
+// See Resource documentation.
+type StateMoveFunc func(*terraform.MoveResourceStateRequest) (*terraform.InstanceState, error)
+
 // See Resource documentation.
 type CustomizeDiffFunc func(*ResourceDiff, interface{}) error
 
+// See Resource documentation.
+type ValidateApplyFunc func(*ResourceData, interface{}) error
+
 // Apply creates, updates, and/or deletes a resource.
 func (r *Resource) Apply(
+	s *terraform.InstanceState,
+	d *terraform.InstanceDiff,
+	meta interface{}) (*terraform.InstanceState, error) {
+	return r.ApplyContext(context.Background(), s, d, meta)
+}
+
+// ApplyContext is like Apply, but the given context is available to the
+// CRUD functions as the Context of their ResourceData, so that they can
+// stop waiting for remote operations when it is cancelled.
+func (r *Resource) ApplyContext(
+	ctx context.Context,
 	s *terraform.InstanceState,
 	d *terraform.InstanceDiff,
 	meta interface{}) (*terraform.InstanceState, error) {
@@ -169,6 +208,7 @@ func (r *Resource) Apply(
 	if err != nil {
 		return s, err
 	}
+	data.ctx = ctx
 
 	// Instance Diff shoould have the timeout info, need to copy it over to the
 	// ResourceData meta
@@ -218,6 +258,7 @@ func (r *Resource) Apply(
 		if err != nil {
 			return nil, err
 		}
+		data.ctx = ctx
 	}
 
 	err = nil
@@ -236,6 +277,31 @@ func (r *Resource) Apply(
 	return r.recordCurrentSchemaVersion(data.State()), err
 }
 
+// DryRunApply checks that the diff could be applied, using ValidateApply.
+func (r *Resource) DryRunApply(
+	s *terraform.InstanceState,
+	d *terraform.InstanceDiff,
+	meta interface{}) error {
+	if r.ValidateApply == nil || d.Destroy && !d.RequiresNew() {
+		return nil
+	}
+
+	// A replacement is checked as the new resource that it creates.
+	if d.RequiresNew() {
+		s = nil
+	}
+
+	data, err := schemaMap(r.Schema).Data(s, d)
+	if err != nil {
+		return err
+	}
+	if data.Id() == "" {
+		data.MarkNewResource()
+	}
+
+	return r.ValidateApply(data, meta)
+}
+
 // Diff returns a diff of this resource.
 func (r *Resource) Diff(
 	s *terraform.InstanceState,
@@ -1,6 +1,7 @@
 package schema
 
 import (
+	"context"
 	"log"
 	"reflect"
 	"strings"
@@ -26,6 +27,7 @@ type ResourceData struct {
 	diff     *terraform.InstanceDiff
 	meta     map[string]interface{}
 	timeouts *ResourceTimeout
+	ctx      context.Context
 
 	// Don't set
 	multiReader *MultiLevelFieldReader
@@ -361,6 +363,18 @@ func (d *ResourceData) State() *terraform.InstanceState {
 	return &result
 }
 
+// Context returns a context that is cancelled when the operation in
+// progress should stop, such as when the provider is stopped or the
+// lifecycle timeout of the resource passes while it is being applied. CRUD
+// functions that wait for remote operations to finish should return, with
+// the partial state set, once it is done.
+func (d *ResourceData) Context() context.Context {
+	if d.ctx == nil {
+		return context.Background()
+	}
+	return d.ctx
+}
+
 // Timeout returns the data for the given timeout key
 // Returns a duration of 20 minutes for any key not found, or not found and no default.
 func (d *ResourceData) Timeout(key string) time.Duration {
@@ -64,6 +64,70 @@ func TestResourceApply_create(t *testing.T) {
 	}
 }
 
+func TestResourceDryRunApply(t *testing.T) {
+	r := &Resource{
+		Schema: map[string]*Schema{
+			"foo": &Schema{
+				Type:     TypeInt,
+				Optional: true,
+			},
+		},
+	}
+
+	var gotID string
+	var gotFoo int
+	r.ValidateApply = func(d *ResourceData, m interface{}) error {
+		gotID = d.Id()
+		gotFoo = d.Get("foo").(int)
+		if gotFoo > 10 {
+			return fmt.Errorf("quota exceeded")
+		}
+		return nil
+	}
+	r.Create = func(d *ResourceData, m interface{}) error {
+		t.Fatal("create should not be called")
+		return nil
+	}
+
+	s := &terraform.InstanceState{
+		ID:         "bar",
+		Attributes: map[string]string{"foo": "1"},
+	}
+	d := &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"foo": &terraform.ResourceAttrDiff{
+				Old: "1",
+				New: "2",
+			},
+		},
+	}
+	if err := r.DryRunApply(s, d, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if gotID != "bar" || gotFoo != 2 {
+		t.Fatalf("bad: %q %d", gotID, gotFoo)
+	}
+
+	// A replacement is checked as a new resource.
+	d.Attributes["foo"].New = "42"
+	d.Attributes["foo"].RequiresNew = true
+	if err := r.DryRunApply(s, d, nil); err == nil {
+		t.Fatal("should error")
+	}
+	if gotID != "" || gotFoo != 42 {
+		t.Fatalf("bad: %q %d", gotID, gotFoo)
+	}
+
+	// Destroys aren't checked.
+	gotFoo = 0
+	if err := r.DryRunApply(s, &terraform.InstanceDiff{Destroy: true}, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if gotFoo != 0 {
+		t.Fatal("validate apply should not be called")
+	}
+}
+
 func TestResourceApply_Timeout_state(t *testing.T) {
 	r := &Resource{
 		SchemaVersion: 2,
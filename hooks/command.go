@@ -0,0 +1,99 @@
+package hooks
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/hashicorp/terraform/diffs"
+	"github.com/hashicorp/terraform/helper/program"
+)
+
+// The names of the hooks, as given to commands.
+const (
+	NamePreApply    = "pre_apply"
+	NamePostApply   = "post_apply"
+	NamePreRefresh  = "pre_refresh"
+	NamePostRefresh = "post_refresh"
+)
+
+// CommandEvent is the JSON document that a command run by Command receives
+// on its standard input.
+type CommandEvent struct {
+	Hook    string `json:"hook"`
+	Address string `json:"address"`
+	Deposed bool   `json:"deposed,omitempty"`
+
+	// Action and Change are set when the event has a change. Sensitive
+	// values in the change are redacted.
+	Action *diffs.Action   `json:"action,omitempty"`
+	Change json.RawMessage `json:"change,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// Command returns Hooks that run the command at the given path with the
+// given arguments once for each event, with the event encoded as a
+// CommandEvent on its standard input.
+//
+// The commands are run one at a time, and the operation waits for each to
+// exit. A command that fails doesn't stop the operation; its failure and
+// output are only logged.
+func Command(path string, args ...string) Hooks {
+	return &commandHooks{program: &program.Program{Path: path, Args: args}}
+}
+
+type commandHooks struct {
+	program *program.Program
+
+	// l serializes the commands, so that a command sees the events in
+	// the order they happened.
+	l sync.Mutex
+}
+
+func (h *commandHooks) PreApply(e *Event)    { h.run(NamePreApply, e) }
+func (h *commandHooks) PostApply(e *Event)   { h.run(NamePostApply, e) }
+func (h *commandHooks) PreRefresh(e *Event)  { h.run(NamePreRefresh, e) }
+func (h *commandHooks) PostRefresh(e *Event) { h.run(NamePostRefresh, e) }
+
+func (h *commandHooks) run(name string, e *Event) {
+	input, err := json.Marshal(newCommandEvent(name, e))
+	if err != nil {
+		log.Printf("[ERROR] hooks: failed to encode %s event for %s: %s", name, e.Address, err)
+		return
+	}
+
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	output, err := h.program.Run(input)
+	if err != nil {
+		log.Printf("[WARN] hooks: %s hook command for %s failed: %s", name, e.Address, err)
+		return
+	}
+	log.Printf("[TRACE] hooks: %s hook command for %s output: %s", name, e.Address, output)
+}
+
+func newCommandEvent(name string, e *Event) *CommandEvent {
+	ce := &CommandEvent{
+		Hook:    name,
+		Address: e.Address,
+		Deposed: e.Deposed,
+	}
+	if e.Change != nil {
+		change, err := e.Change.MarshalJSONWith(diffs.MarshalOpts{RedactSensitive: true})
+		if err != nil {
+			// should never happen; the changes are of flatmapped states
+			log.Printf("[ERROR] hooks: failed to encode change for %s: %s", e.Address, err)
+		} else {
+			ce.Change = change
+		}
+
+		action := e.Change.Action
+		ce.Action = &action
+	}
+	if e.Err != nil {
+		ce.Error = e.Err.Error()
+	}
+	return ce
+}
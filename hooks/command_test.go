@@ -0,0 +1,86 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hashicorp/terraform/diffs"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test command is a shell script")
+	}
+
+	td, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+	out := filepath.Join(td, "events")
+
+	ty := cty.Map(cty.String)
+	change := diffs.NewCreate(ty, cty.MapVal(map[string]cty.Value{
+		"ami":      cty.StringVal("bar"),
+		"password": cty.StringVal("secret"),
+	}))
+	change.Sensitive = diffs.NewPathSet(cty.Path{cty.IndexStep{Key: cty.StringVal("password")}})
+
+	h := Command("sh", "-c", `cat >> "$0"; echo >> "$0"`, out)
+	h.PreApply(&Event{Address: "test_instance.foo", Change: change})
+	h.PostApply(&Event{Address: "test_instance.foo", Change: change, Err: errors.New("boom")})
+	h.PreRefresh(&Event{Address: "test_instance.bar"})
+
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var events []map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var e map[string]interface{}
+		if err := dec.Decode(&e); err != nil {
+			t.Fatalf("err: %s\n\n%s", err, data)
+		}
+		events = append(events, e)
+	}
+	if len(events) != 3 {
+		t.Fatalf("wrong number of events %d\n\n%s", len(events), data)
+	}
+
+	for i, expected := range []struct {
+		Hook, Action, Error string
+	}{
+		{NamePreApply, "create", ""},
+		{NamePostApply, "create", "boom"},
+		{NamePreRefresh, "", ""},
+	} {
+		e := events[i]
+		if e["hook"] != expected.Hook {
+			t.Errorf("event %d: wrong hook %v; want %s", i, e["hook"], expected.Hook)
+		}
+		action, _ := e["action"].(string)
+		if action != expected.Action {
+			t.Errorf("event %d: wrong action %q; want %q", i, action, expected.Action)
+		}
+		errMsg, _ := e["error"].(string)
+		if errMsg != expected.Error {
+			t.Errorf("event %d: wrong error %q; want %q", i, errMsg, expected.Error)
+		}
+	}
+
+	if _, ok := events[2]["change"]; ok {
+		t.Errorf("unexpected change in %s event", NamePreRefresh)
+	}
+	if bytes.Contains(data, []byte("secret")) {
+		t.Fatalf("events contain the sensitive value:\n\n%s", data)
+	}
+}
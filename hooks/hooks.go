@@ -0,0 +1,157 @@
+// Package hooks provides a stable interface for programs that embed
+// Terraform to be notified of the lifecycle of each resource instance as it
+// is refreshed and applied, without depending on the internals of the
+// terraform package.
+package hooks
+
+import (
+	"sync"
+
+	"github.com/hashicorp/terraform/command/format"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/diffs"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// Hooks is notified before and after each resource instance is refreshed
+// and applied.
+//
+// The methods may be called concurrently for different instances, and are
+// called synchronously with the operation, so implementations that do slow
+// work, such as network requests, should keep it brief.
+type Hooks interface {
+	PreApply(*Event)
+	PostApply(*Event)
+	PreRefresh(*Event)
+	PostRefresh(*Event)
+}
+
+// Event describes a resource instance at a point in its lifecycle.
+type Event struct {
+	// Address is the address of the resource instance, in the same form as
+	// the addresses of the changes of a plan, and Deposed is true if the
+	// event is for a deposed object of the instance.
+	Address string
+	Deposed bool
+
+	// Change is the change being made to the instance.
+	//
+	// For PreApply and PostApply it's the planned change. For PostRefresh
+	// it's a read from the state before the refresh to the refreshed
+	// state. It's nil for PreRefresh, before the instance is read.
+	Change *diffs.Change
+
+	// Err is the error applying the change, for PostApply.
+	Err error
+}
+
+// New returns a terraform.Hook that notifies the given hooks.
+func New(h Hooks) terraform.Hook {
+	return &hook{
+		hooks:     h,
+		applying:  make(map[string]*Event),
+		refreshes: make(map[string]*terraform.InstanceState),
+	}
+}
+
+// hook adapts Hooks to terraform.Hook.
+type hook struct {
+	terraform.NilHook
+
+	hooks Hooks
+
+	l sync.Mutex
+
+	// applying and refreshes hold, keyed by the human ID of the instance,
+	// the events of the instances being applied and the states of the
+	// instances being refreshed.
+	applying  map[string]*Event
+	refreshes map[string]*terraform.InstanceState
+}
+
+func (h *hook) PreApply(
+	n *terraform.InstanceInfo,
+	s *terraform.InstanceState,
+	d *terraform.InstanceDiff) (terraform.HookAction, error) {
+	if d.Empty() {
+		return terraform.HookActionContinue, nil
+	}
+
+	// Destroying a data resource only removes it from the state, which
+	// isn't shown as a change.
+	addr := n.ResourceAddress()
+	if addr.Mode == config.DataResourceMode && d.ChangeType() == terraform.DiffDestroy {
+		return terraform.HookActionContinue, nil
+	}
+
+	e := newEvent(n)
+	e.Change = format.InstanceChange(addr, d)
+
+	h.l.Lock()
+	h.applying[n.HumanId()] = e
+	h.l.Unlock()
+
+	h.hooks.PreApply(e)
+	return terraform.HookActionContinue, nil
+}
+
+func (h *hook) PostApply(
+	n *terraform.InstanceInfo,
+	s *terraform.InstanceState,
+	applyerr error) (terraform.HookAction, error) {
+	id := n.HumanId()
+
+	h.l.Lock()
+	pre, ok := h.applying[id]
+	delete(h.applying, id)
+	h.l.Unlock()
+
+	// Only the instances that PreApply was notified of are notified.
+	if !ok {
+		return terraform.HookActionContinue, nil
+	}
+
+	e := *pre
+	e.Err = applyerr
+	h.hooks.PostApply(&e)
+	return terraform.HookActionContinue, nil
+}
+
+func (h *hook) PreRefresh(
+	n *terraform.InstanceInfo,
+	s *terraform.InstanceState) (terraform.HookAction, error) {
+	h.l.Lock()
+	h.refreshes[n.HumanId()] = s.DeepCopy()
+	h.l.Unlock()
+
+	h.hooks.PreRefresh(newEvent(n))
+	return terraform.HookActionContinue, nil
+}
+
+func (h *hook) PostRefresh(
+	n *terraform.InstanceInfo,
+	s *terraform.InstanceState) (terraform.HookAction, error) {
+	id := n.HumanId()
+
+	h.l.Lock()
+	prior := h.refreshes[id]
+	delete(h.refreshes, id)
+	h.l.Unlock()
+
+	e := newEvent(n)
+	e.Change = format.RefreshChange(prior, s)
+	h.hooks.PostRefresh(e)
+	return terraform.HookActionContinue, nil
+}
+
+// newEvent returns an event for the given instance with its address set.
+func newEvent(n *terraform.InstanceInfo) *Event {
+	addr := n.ResourceAddress().Copy()
+	deposed := addr.InstanceType == terraform.TypeDeposed
+	addr.InstanceType = terraform.TypeInvalid
+
+	return &Event{
+		Address: addr.String(),
+		Deposed: deposed,
+	}
+}
@@ -0,0 +1,159 @@
+package hooks
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform/diffs"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type recordingHooks struct {
+	sync.Mutex
+	Names  []string
+	Events []*Event
+}
+
+func (h *recordingHooks) PreApply(e *Event)    { h.record(NamePreApply, e) }
+func (h *recordingHooks) PostApply(e *Event)   { h.record(NamePostApply, e) }
+func (h *recordingHooks) PreRefresh(e *Event)  { h.record(NamePreRefresh, e) }
+func (h *recordingHooks) PostRefresh(e *Event) { h.record(NamePostRefresh, e) }
+
+func (h *recordingHooks) record(name string, e *Event) {
+	h.Lock()
+	defer h.Unlock()
+	h.Names = append(h.Names, name)
+	h.Events = append(h.Events, e)
+}
+
+func TestNew_impl(t *testing.T) {
+	var _ terraform.Hook = New(&recordingHooks{})
+}
+
+func TestNew_apply(t *testing.T) {
+	r := &recordingHooks{}
+	h := New(r)
+
+	n := &terraform.InstanceInfo{
+		Id:         "test_instance.foo",
+		ModulePath: []string{"root", "child"},
+		Type:       "test_instance",
+	}
+	d := &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"ami": &terraform.ResourceAttrDiff{Old: "", New: "bar", RequiresNew: true},
+		},
+	}
+	applyErr := errors.New("boom")
+
+	h.PreApply(n, nil, d)
+	h.PostApply(n, nil, applyErr)
+
+	if expected := []string{NamePreApply, NamePostApply}; !reflect.DeepEqual(r.Names, expected) {
+		t.Fatalf("wrong hooks %#v; want %#v", r.Names, expected)
+	}
+
+	pre, post := r.Events[0], r.Events[1]
+	if pre.Address != "module.child.test_instance.foo" || pre.Deposed {
+		t.Fatalf("wrong address %q (deposed %t)", pre.Address, pre.Deposed)
+	}
+	if pre.Change == nil || pre.Change.Action != diffs.Create {
+		t.Fatalf("wrong change %#v", pre.Change)
+	}
+	if pre.Err != nil {
+		t.Fatalf("unexpected error in PreApply event: %s", pre.Err)
+	}
+	if post.Change != pre.Change {
+		t.Fatalf("PostApply change %#v isn't the planned change %#v", post.Change, pre.Change)
+	}
+	if post.Err != applyErr {
+		t.Fatalf("wrong PostApply error %v", post.Err)
+	}
+}
+
+func TestNew_applyDeposed(t *testing.T) {
+	r := &recordingHooks{}
+	h := New(r)
+
+	n := &terraform.InstanceInfo{
+		Id:         "test_instance.foo (deposed #0)",
+		ModulePath: []string{"root"},
+		Type:       "test_instance",
+	}
+	d := &terraform.InstanceDiff{Destroy: true, DestroyDeposed: true}
+
+	h.PreApply(n, nil, d)
+	h.PostApply(n, nil, nil)
+
+	if len(r.Events) != 2 {
+		t.Fatalf("wrong hooks %#v", r.Names)
+	}
+	e := r.Events[0]
+	if e.Address != "test_instance.foo" || !e.Deposed {
+		t.Fatalf("wrong address %q (deposed %t)", e.Address, e.Deposed)
+	}
+	if e.Change.Action != diffs.Delete {
+		t.Fatalf("wrong action %s", e.Change.Action)
+	}
+}
+
+func TestNew_applyEmpty(t *testing.T) {
+	r := &recordingHooks{}
+	h := New(r)
+
+	n := &terraform.InstanceInfo{
+		Id:         "test_instance.foo",
+		ModulePath: []string{"root"},
+		Type:       "test_instance",
+	}
+	h.PreApply(n, nil, &terraform.InstanceDiff{})
+	h.PostApply(n, nil, nil)
+
+	if len(r.Names) != 0 {
+		t.Fatalf("unexpected hooks %#v", r.Names)
+	}
+}
+
+func TestNew_refresh(t *testing.T) {
+	r := &recordingHooks{}
+	h := New(r)
+
+	n := &terraform.InstanceInfo{
+		Id:         "test_instance.foo",
+		ModulePath: []string{"root"},
+		Type:       "test_instance",
+	}
+	prior := &terraform.InstanceState{
+		ID:         "foo",
+		Attributes: map[string]string{"id": "foo", "ami": "bar"},
+	}
+	refreshed := &terraform.InstanceState{
+		ID:         "foo",
+		Attributes: map[string]string{"id": "foo", "ami": "baz"},
+	}
+
+	h.PreRefresh(n, prior)
+	h.PostRefresh(n, refreshed)
+
+	if expected := []string{NamePreRefresh, NamePostRefresh}; !reflect.DeepEqual(r.Names, expected) {
+		t.Fatalf("wrong hooks %#v; want %#v", r.Names, expected)
+	}
+
+	if c := r.Events[0].Change; c != nil {
+		t.Fatalf("unexpected PreRefresh change %#v", c)
+	}
+
+	c := r.Events[1].Change
+	if c == nil || c.Action != diffs.Read {
+		t.Fatalf("wrong PostRefresh change %#v", c)
+	}
+	if got := c.Old.Index(cty.StringVal("ami")).AsString(); got != "bar" {
+		t.Fatalf("wrong old ami %q", got)
+	}
+	if got := c.New.Index(cty.StringVal("ami")).AsString(); got != "baz" {
+		t.Fatalf("wrong new ami %q", got)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/hashicorp/terraform/command/format"
 	"github.com/hashicorp/terraform/helper/logging"
 	"github.com/hashicorp/terraform/terraform"
+	"github.com/hashicorp/terraform/tracing"
 	"github.com/mattn/go-colorable"
 	"github.com/mattn/go-shellwords"
 	"github.com/mitchellh/cli"
@@ -123,6 +124,9 @@ func wrappedMain() int {
 	log.Printf("[INFO] CLI args: %#v", os.Args)
 
 	config, diags := LoadConfig()
+	if !diags.HasErrors() {
+		diags = diags.Append(registerWASMFunctions(config))
+	}
 	if len(diags) > 0 {
 		// Since we haven't instantiated a command.Meta yet, we need to do
 		// some things manually here and use some "safe" defaults for things
@@ -211,7 +215,17 @@ func wrappedMain() int {
 	PluginOverrides.Providers = config.Providers
 	PluginOverrides.Provisioners = config.Provisioners
 
+	root := Tracer.StartRoot("terraform "+cliRunner.Subcommand(),
+		tracing.String("terraform.version", Version))
+	defer func() {
+		if err := Tracer.Shutdown(); err != nil {
+			log.Printf("[WARN] Failed to export the trace of the run: %s", err)
+		}
+	}()
+
 	exitCode, err := cliRunner.Run()
+	root.SetAttributes(tracing.Int("terraform.exit_code", exitCode))
+	root.Finish(err)
 	if err != nil {
 		Ui.Error(fmt.Sprintf("Error executing CLI: %s", err.Error()))
 		return 1
@@ -0,0 +1,9 @@
+// Package moduletest implements the test files run by "terraform test".
+//
+// A test file has the extension ".tftest.hcl" and contains a sequence of
+// run blocks, each of which plans, and optionally applies, the module
+// under test with a set of variables and then checks assertions about the
+// output values and the planned changes. The runs of a file share a state,
+// so later runs can test changes to what earlier runs created. Once all of
+// the runs of a file are done, everything they created is destroyed.
+package moduletest
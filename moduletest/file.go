@@ -0,0 +1,337 @@
+package moduletest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/terraform/diffs"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// FileExtension is the extension of test files.
+const FileExtension = ".tftest.hcl"
+
+// File is a test file.
+type File struct {
+	// Path is the path the file was loaded from.
+	Path string
+
+	// Variables are the variables set for all of the runs in the file.
+	Variables map[string]interface{}
+
+	Runs []*Run
+}
+
+// Command is the operation that a run performs.
+type Command string
+
+const (
+	CommandApply Command = "apply"
+	CommandPlan  Command = "plan"
+)
+
+// Run is a run block of a test file.
+type Run struct {
+	Name    string
+	Command Command
+
+	// Variables are the variables set for the run, which override those
+	// set for the file.
+	Variables map[string]interface{}
+
+	Asserts []*Assert
+}
+
+// Assert is an assertion of a run, about either the value of an output of
+// the root module or the planned change for a resource instance.
+type Assert struct {
+	// Output is the name of the output, which must have the value Equals
+	// once the run is applied.
+	Output string
+	Equals interface{}
+
+	// Resource is the address of the resource instance, which must have a
+	// planned change with the given Action.
+	Resource string
+	Action   diffs.Action
+
+	// ErrorMessage is shown if the assertion fails, instead of a
+	// description of the mismatch.
+	ErrorMessage string
+}
+
+// LoadDir loads the test files in the given directory and in its "tests"
+// subdirectory, sorted by path.
+func LoadDir(dir string) ([]*File, error) {
+	var paths []string
+	for _, d := range []string{dir, filepath.Join(dir, "tests")} {
+		infos, err := ioutil.ReadDir(d)
+		if err != nil {
+			if os.IsNotExist(err) && d != dir {
+				continue
+			}
+			return nil, fmt.Errorf("Error reading %s: %s", d, err)
+		}
+
+		for _, info := range infos {
+			if !info.IsDir() && strings.HasSuffix(info.Name(), FileExtension) {
+				paths = append(paths, filepath.Join(d, info.Name()))
+			}
+		}
+	}
+	sort.Strings(paths)
+
+	result := make([]*File, 0, len(paths))
+	for _, path := range paths {
+		f, err := LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+
+	return result, nil
+}
+
+// LoadFile loads the test file at the given path.
+func LoadFile(path string) (*File, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %s: %s", path, err)
+	}
+
+	root, err := hcl.Parse(string(d))
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing %s: %s", path, err)
+	}
+
+	f, err := loadFileHcl(root)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading %s: %s", path, err)
+	}
+	f.Path = path
+
+	return f, nil
+}
+
+func loadFileHcl(root *ast.File) (*File, error) {
+	list, ok := root.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("test file doesn't contain a root object")
+	}
+
+	if err := checkHCLKeys(list, []string{"variables", "run"}); err != nil {
+		return nil, err
+	}
+
+	f := &File{}
+
+	var err error
+	if f.Variables, err = loadVariablesHcl(list.Filter("variables")); err != nil {
+		return nil, err
+	}
+
+	runs := list.Filter("run")
+	if len(runs.Elem().Items) > 0 {
+		return nil, fmt.Errorf("%s: \"run\" must be followed by a name", runs.Elem().Items[0].Pos())
+	}
+
+	names := make(map[string]struct{})
+	for _, item := range runs.Children().Items {
+		n := item.Keys[0].Token.Value().(string)
+		if _, ok := names[n]; ok {
+			return nil, fmt.Errorf("run %q: declared more than once", n)
+		}
+		names[n] = struct{}{}
+
+		r, err := loadRunHcl(n, item.Val)
+		if err != nil {
+			return nil, fmt.Errorf("run %q: %s", n, err)
+		}
+		f.Runs = append(f.Runs, r)
+	}
+
+	return f, nil
+}
+
+func loadRunHcl(name string, val ast.Node) (*Run, error) {
+	ot, ok := val.(*ast.ObjectType)
+	if !ok {
+		return nil, fmt.Errorf("should be an object")
+	}
+
+	valid := []string{"command", "variables", "assert"}
+	if err := checkHCLKeys(ot, valid); err != nil {
+		return nil, err
+	}
+
+	r := &Run{Name: name, Command: CommandApply}
+
+	if items := ot.List.Filter("command").Items; len(items) > 0 {
+		var command string
+		if err := hcl.DecodeObject(&command, items[0].Val); err != nil {
+			return nil, fmt.Errorf("command: %s", err)
+		}
+
+		r.Command = Command(command)
+		if r.Command != CommandApply && r.Command != CommandPlan {
+			return nil, fmt.Errorf("command must be %q or %q, got %q",
+				CommandApply, CommandPlan, command)
+		}
+	}
+
+	var err error
+	if r.Variables, err = loadVariablesHcl(ot.List.Filter("variables")); err != nil {
+		return nil, err
+	}
+
+	for i, item := range ot.List.Filter("assert").Items {
+		a, err := loadAssertHcl(item)
+		if err != nil {
+			return nil, fmt.Errorf("assert #%d: %s", i+1, err)
+		}
+
+		if a.Output != "" && r.Command != CommandApply {
+			return nil, fmt.Errorf(
+				"assert #%d: outputs can only be checked by runs that apply", i+1)
+		}
+
+		r.Asserts = append(r.Asserts, a)
+	}
+
+	return r, nil
+}
+
+func loadAssertHcl(item *ast.ObjectItem) (*Assert, error) {
+	if len(item.Keys) > 0 {
+		return nil, fmt.Errorf("assert blocks have no names")
+	}
+
+	valid := []string{"output", "equals", "resource", "action", "error_message"}
+	if err := checkHCLKeys(item.Val, valid); err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	if err := hcl.DecodeObject(&raw, item.Val); err != nil {
+		return nil, err
+	}
+
+	a := &Assert{}
+	a.Output, _ = raw["output"].(string)
+	a.Resource, _ = raw["resource"].(string)
+	a.ErrorMessage, _ = raw["error_message"].(string)
+
+	switch {
+	case a.Output != "" && a.Resource != "":
+		return nil, fmt.Errorf("only one of output or resource may be set")
+	case a.Output != "":
+		equals, ok := raw["equals"]
+		if !ok {
+			return nil, fmt.Errorf("equals must be set for an output")
+		}
+		if _, ok := raw["action"]; ok {
+			return nil, fmt.Errorf("action can only be set for a resource")
+		}
+
+		a.Equals = flattenValue(equals)
+	case a.Resource != "":
+		if _, err := terraform.ParseResourceAddress(a.Resource); err != nil {
+			return nil, fmt.Errorf("invalid resource address %q: %s", a.Resource, err)
+		}
+		if _, ok := raw["equals"]; ok {
+			return nil, fmt.Errorf("equals can only be set for an output")
+		}
+
+		action, _ := raw["action"].(string)
+		if action == "" {
+			return nil, fmt.Errorf("action must be set for a resource")
+		}
+
+		// Actions are named as in the JSON plan.
+		js, _ := json.Marshal(action)
+		if err := a.Action.UnmarshalJSON(js); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("one of output or resource must be set")
+	}
+
+	return a, nil
+}
+
+func loadVariablesHcl(list *ast.ObjectList) (map[string]interface{}, error) {
+	if len(list.Items) == 0 {
+		return nil, nil
+	}
+	if len(list.Items) > 1 {
+		return nil, fmt.Errorf("only one \"variables\" block is allowed")
+	}
+
+	var result map[string]interface{}
+	if err := hcl.DecodeObject(&result, list.Items[0].Val); err != nil {
+		return nil, fmt.Errorf("variables: %s", err)
+	}
+	for k, v := range result {
+		result[k] = flattenValue(v)
+	}
+
+	return result, nil
+}
+
+// flattenValue replaces the lists of maps that HCL decodes maps to with the
+// maps themselves, as for the values of variables and outputs.
+func flattenValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []map[string]interface{}:
+		m := make(map[string]interface{})
+		for _, vm := range v {
+			for k, vv := range vm {
+				m[k] = flattenValue(vv)
+			}
+		}
+		return m
+	case []interface{}:
+		for i, vv := range v {
+			v[i] = flattenValue(vv)
+		}
+		return v
+	}
+	return v
+}
+
+func checkHCLKeys(node ast.Node, valid []string) error {
+	var list *ast.ObjectList
+	switch n := node.(type) {
+	case *ast.ObjectList:
+		list = n
+	case *ast.ObjectType:
+		list = n.List
+	default:
+		return fmt.Errorf("cannot check HCL keys of type %T", n)
+	}
+
+	validMap := make(map[string]struct{}, len(valid))
+	for _, v := range valid {
+		validMap[v] = struct{}{}
+	}
+
+	var result error
+	for _, item := range list.Items {
+		key := item.Keys[0].Token.Value().(string)
+		if _, ok := validMap[key]; !ok {
+			result = multierror.Append(result, fmt.Errorf(
+				"invalid key: %s", key))
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,92 @@
+package moduletest
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/diffs"
+)
+
+func TestLoadDir(t *testing.T) {
+	files, err := LoadDir(filepath.Join(fixtureDir, "basic"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	expected := []string{
+		filepath.Join(fixtureDir, "basic", "main.tftest.hcl"),
+		filepath.Join(fixtureDir, "basic", "tests", "fail.tftest.hcl"),
+	}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("bad: %#v", paths)
+	}
+
+	f := files[0]
+	if !reflect.DeepEqual(f.Variables, map[string]interface{}{"name": "foo"}) {
+		t.Fatalf("bad variables: %#v", f.Variables)
+	}
+
+	expectedRuns := []*Run{
+		{
+			Name:    "create",
+			Command: CommandApply,
+			Asserts: []*Assert{
+				{Resource: "test_instance.foo", Action: diffs.Create},
+				{Output: "name", Equals: "foo"},
+				{Output: "tags", Equals: map[string]interface{}{"name": "foo", "num": 1}},
+			},
+		},
+		{
+			Name:      "update",
+			Command:   CommandPlan,
+			Variables: map[string]interface{}{"num": 2},
+			Asserts: []*Assert{
+				{Resource: "test_instance.foo", Action: diffs.Update},
+			},
+		},
+		{
+			Name:    "unchanged",
+			Command: CommandPlan,
+			Asserts: []*Assert{
+				{Resource: "test_instance.foo", Action: diffs.NoOp},
+			},
+		},
+	}
+	if !reflect.DeepEqual(f.Runs, expectedRuns) {
+		for i, r := range f.Runs {
+			t.Logf("run %d: %#v", i, r)
+			for _, a := range r.Asserts {
+				t.Logf("  %#v", a)
+			}
+		}
+		t.Fatal("bad runs")
+	}
+}
+
+func TestLoadFile_invalid(t *testing.T) {
+	cases := map[string]string{
+		"output-plan": "outputs can only be checked by runs that apply",
+		"action":      `invalid action "explode"`,
+		"command":     `command must be "apply" or "plan", got "destroy"`,
+		"duplicate":   "declared more than once",
+		"both":        "only one of output or resource may be set",
+	}
+
+	for name, expected := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadFile(filepath.Join(fixtureDir, "invalid", name+FileExtension))
+			if err == nil {
+				t.Fatal("should error")
+			}
+			if !strings.Contains(err.Error(), expected) {
+				t.Fatalf("expected error containing %q, got: %s", expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package moduletest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/hashicorp/terraform/command/format"
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/diffs"
+	"github.com/hashicorp/terraform/helper/variables"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// Status is the outcome of a run or of a test file.
+type Status string
+
+const (
+	StatusPass Status = "pass"
+
+	// StatusFail means that an assertion failed.
+	StatusFail Status = "fail"
+
+	// StatusError means that the plan or apply of a run failed, or that
+	// what the runs created couldn't be destroyed.
+	StatusError Status = "error"
+
+	// StatusSkip is the status of the runs after a run that had an error,
+	// which aren't run.
+	StatusSkip Status = "skip"
+)
+
+// RunResult is the result of a run.
+type RunResult struct {
+	Name   string
+	Status Status
+
+	// Failures are the messages of the assertions that failed.
+	Failures []string
+
+	// Err is the error planning or applying the run, if any.
+	Err error
+}
+
+// FileResult is the result of the runs of a test file.
+type FileResult struct {
+	Path string
+	Runs []*RunResult
+
+	// DestroyErr is the error destroying what the runs created, if any. The
+	// state is then left in State, so that it can be cleaned up by hand.
+	DestroyErr error
+	State      *terraform.State
+}
+
+// Status returns the overall status of the file.
+func (r *FileResult) Status() Status {
+	status := StatusPass
+	if r.DestroyErr != nil {
+		status = StatusError
+	}
+	for _, run := range r.Runs {
+		switch run.Status {
+		case StatusError:
+			status = StatusError
+		case StatusFail:
+			if status == StatusPass {
+				status = StatusFail
+			}
+		}
+	}
+	return status
+}
+
+// Runner runs test files against a module.
+type Runner struct {
+	Module *module.Tree
+
+	// ContextOpts returns the options for each context that the runner
+	// creates. The variables of the runs are merged into the returned
+	// Variables, and Module, State and Destroy are set by the runner.
+	ContextOpts func() *terraform.ContextOpts
+}
+
+// RunFile runs the runs of the given file in order and then destroys what
+// they created.
+func (r *Runner) RunFile(f *File) *FileResult {
+	result := &FileResult{Path: f.Path}
+
+	var state *terraform.State
+	var skip bool
+	vars := variables.Merge(nil, f.Variables)
+	for _, run := range f.Runs {
+		if skip {
+			result.Runs = append(result.Runs, &RunResult{Name: run.Name, Status: StatusSkip})
+			continue
+		}
+
+		vars = variables.Merge(variables.Merge(nil, f.Variables), run.Variables)
+		rr, newState := r.run(run, state, vars)
+		if newState != nil {
+			state = newState
+		}
+		result.Runs = append(result.Runs, rr)
+		skip = rr.Status == StatusError
+	}
+
+	if err := r.destroy(state, vars); err != nil {
+		result.DestroyErr = err
+		result.State = state
+	}
+
+	return result
+}
+
+// run plans, and for apply runs applies, the given run with the given
+// prior state, returning the result and the new state, if any.
+func (r *Runner) run(run *Run, state *terraform.State, vars map[string]interface{}) (*RunResult, *terraform.State) {
+	result := &RunResult{Name: run.Name, Status: StatusPass}
+
+	ctx, err := r.context(state, vars, false)
+	if err != nil {
+		result.Status = StatusError
+		result.Err = err
+		return result, nil
+	}
+
+	if _, err := ctx.Refresh(); err != nil {
+		result.Status = StatusError
+		result.Err = fmt.Errorf("Error refreshing state: %s", err)
+		return result, nil
+	}
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		result.Status = StatusError
+		result.Err = fmt.Errorf("Error running plan: %s", err)
+		return result, nil
+	}
+	changes := format.NewPlan(plan).Changes()
+
+	var newState *terraform.State
+	if run.Command == CommandApply {
+		if _, err := ctx.Apply(); err != nil {
+			// What was created before the error must still be destroyed.
+			result.Status = StatusError
+			result.Err = fmt.Errorf("Error applying plan: %s", err)
+			return result, ctx.State()
+		}
+		newState = ctx.State()
+	}
+
+	for _, a := range run.Asserts {
+		if msg := a.check(changes, newState); msg != "" {
+			result.Status = StatusFail
+			result.Failures = append(result.Failures, msg)
+		}
+	}
+
+	return result, newState
+}
+
+// destroy destroys everything in the given state.
+func (r *Runner) destroy(state *terraform.State, vars map[string]interface{}) error {
+	if !state.HasResources() {
+		return nil
+	}
+
+	ctx, err := r.context(state, vars, true)
+	if err != nil {
+		return err
+	}
+	if _, err := ctx.Plan(); err != nil {
+		return fmt.Errorf("Error planning destroy: %s", err)
+	}
+	if _, err := ctx.Apply(); err != nil {
+		return fmt.Errorf("Error destroying: %s", err)
+	}
+
+	return nil
+}
+
+// context returns a context for the module with the given state and
+// variables. Like the context of the plan and refresh commands, it's
+// validated before it's returned, which also interpolates the parts of the
+// configuration, such as resource counts, that the other walks expect to
+// have been interpolated already.
+func (r *Runner) context(
+	state *terraform.State, vars map[string]interface{}, destroy bool) (*terraform.Context, error) {
+	opts := r.ContextOpts()
+	opts.Module = r.Module
+	opts.State = state
+	opts.Variables = variables.Merge(variables.Merge(nil, opts.Variables), vars)
+	opts.Destroy = destroy
+
+	ctx, err := terraform.NewContext(opts)
+	if err != nil {
+		return nil, err
+	}
+	if diags := ctx.Validate(); diags.HasErrors() {
+		return nil, fmt.Errorf("Error validating: %s", diags.Err())
+	}
+	return ctx, nil
+}
+
+// check checks the assertion against the changes planned by a run and the
+// state it left, returning a message if it fails.
+func (a *Assert) check(changes diffs.ChangeSet, state *terraform.State) string {
+	var msg string
+	switch {
+	case a.Output != "":
+		var actual interface{}
+		if state != nil {
+			if o, ok := state.RootModule().Outputs[a.Output]; ok {
+				actual = o.Value
+			}
+		}
+
+		expected := outputValue(a.Equals)
+		if actual == nil {
+			msg = fmt.Sprintf("output %q: expected %s, but it isn't set",
+				a.Output, jsonString(expected))
+		} else if !reflect.DeepEqual(actual, expected) {
+			msg = fmt.Sprintf("output %q: expected %s, got %s",
+				a.Output, jsonString(expected), jsonString(actual))
+		}
+	case a.Resource != "":
+		// The addresses of the changes are in the canonical form.
+		addr, _ := terraform.ParseResourceAddress(a.Resource)
+
+		actual := diffs.NoOp
+		if c, ok := changes[addr.String()]; ok {
+			actual = c.Action
+		}
+
+		if actual != a.Action {
+			msg = fmt.Sprintf("resource %s: expected a %s change, got %s",
+				a.Resource, jsonString(a.Action), jsonString(actual))
+		}
+	}
+
+	if msg != "" && a.ErrorMessage != "" {
+		msg = a.ErrorMessage
+	}
+	return msg
+}
+
+// outputValue converts an expected value to the form that output values
+// have in the state, where primitive values are strings.
+func outputValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, vv := range v {
+			result[i] = outputValue(vv)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, vv := range v {
+			result[k] = outputValue(vv)
+		}
+		return result
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func jsonString(v interface{}) string {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%#v", v)
+	}
+	return string(js)
+}
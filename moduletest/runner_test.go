@@ -0,0 +1,188 @@
+package moduletest
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// This is the directory where our test fixtures are.
+const fixtureDir = "./test-fixtures"
+
+func TestRunner_RunFile(t *testing.T) {
+	p := testProvider()
+	r := testRunner(t, "basic", p)
+
+	f, err := LoadFile(filepath.Join(fixtureDir, "basic", "main.tftest.hcl"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	result := r.RunFile(f)
+	for _, run := range result.Runs {
+		if run.Status != StatusPass {
+			t.Errorf("run %q: %s\nerr: %v\nfailures: %v", run.Name, run.Status, run.Err, run.Failures)
+		}
+	}
+	if result.DestroyErr != nil {
+		t.Fatalf("destroy err: %s", result.DestroyErr)
+	}
+	if result.Status() != StatusPass {
+		t.Fatalf("bad status: %s", result.Status())
+	}
+
+	// The instance was created once and destroyed once.
+	if len(p.destroyed) != 1 || p.destroyed[0] != "test_instance.foo" {
+		t.Fatalf("bad destroyed: %#v", p.destroyed)
+	}
+}
+
+func TestRunner_RunFile_fail(t *testing.T) {
+	p := testProvider()
+	r := testRunner(t, "basic", p)
+
+	f, err := LoadFile(filepath.Join(fixtureDir, "basic", "tests", "fail.tftest.hcl"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	result := r.RunFile(f)
+	expected := []*RunResult{
+		{
+			Name:   "create",
+			Status: StatusFail,
+			Failures: []string{
+				"The name must be foo.",
+				`resource test_instance.foo: expected a "delete" change, got "create"`,
+			},
+		},
+		{
+			Name:   "unchanged",
+			Status: StatusPass,
+		},
+	}
+	if !reflect.DeepEqual(result.Runs, expected) {
+		for _, run := range result.Runs {
+			t.Logf("%#v", run)
+		}
+		t.Fatal("bad runs")
+	}
+	if result.Status() != StatusFail {
+		t.Fatalf("bad status: %s", result.Status())
+	}
+	if len(p.destroyed) != 1 {
+		t.Fatalf("bad destroyed: %#v", p.destroyed)
+	}
+}
+
+func TestRunner_RunFile_error(t *testing.T) {
+	p := testProvider()
+	p.ApplyReturnError = true
+	r := testRunner(t, "basic", p)
+
+	f, err := LoadFile(filepath.Join(fixtureDir, "basic", "main.tftest.hcl"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	result := r.RunFile(f)
+	if result.Runs[0].Status != StatusError || result.Runs[0].Err == nil {
+		t.Fatalf("bad: %#v", result.Runs[0])
+	}
+	for _, run := range result.Runs[1:] {
+		if run.Status != StatusSkip {
+			t.Fatalf("run %q should be skipped, got %s", run.Name, run.Status)
+		}
+	}
+	if result.Status() != StatusError {
+		t.Fatalf("bad status: %s", result.Status())
+	}
+}
+
+func testRunner(t *testing.T, name string, p *testResourceProvider) *Runner {
+	t.Helper()
+
+	mod, err := module.NewTreeModule("", filepath.Join(fixtureDir, name))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	s := &module.Storage{
+		StorageDir: t.TempDir(),
+		Mode:       module.GetModeGet,
+	}
+	if err := mod.Load(s); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return &Runner{
+		Module: mod,
+		ContextOpts: func() *terraform.ContextOpts {
+			return &terraform.ContextOpts{
+				ProviderResolver: terraform.ResourceProviderResolverFixed(
+					map[string]terraform.ResourceProviderFactory{
+						"test": func() (terraform.ResourceProvider, error) {
+							return p, nil
+						},
+					},
+				),
+			}
+		},
+	}
+}
+
+// testResourceProvider is a mock provider whose objects have the
+// attributes of their configuration.
+type testResourceProvider struct {
+	*terraform.MockResourceProvider
+
+	// ApplyReturnError makes creating objects fail.
+	ApplyReturnError bool
+
+	destroyed []string
+}
+
+func testProvider() *testResourceProvider {
+	p := &testResourceProvider{
+		MockResourceProvider: new(terraform.MockResourceProvider),
+	}
+	p.RefreshFn = func(info *terraform.InstanceInfo, s *terraform.InstanceState) (*terraform.InstanceState, error) {
+		return s, nil
+	}
+	p.DiffFn = func(info *terraform.InstanceInfo, s *terraform.InstanceState, c *terraform.ResourceConfig) (*terraform.InstanceDiff, error) {
+		diff := &terraform.InstanceDiff{Attributes: make(map[string]*terraform.ResourceAttrDiff)}
+		for k, v := range c.Config {
+			var old string
+			if s != nil {
+				old = s.Attributes[k]
+			}
+			if new, _ := v.(string); new != old || s == nil {
+				diff.Attributes[k] = &terraform.ResourceAttrDiff{Old: old, New: new}
+			}
+		}
+		return diff, nil
+	}
+	p.ApplyFn = func(info *terraform.InstanceInfo, s *terraform.InstanceState, d *terraform.InstanceDiff) (*terraform.InstanceState, error) {
+		if d.Destroy {
+			p.destroyed = append(p.destroyed, info.Id)
+			return nil, nil
+		}
+		if p.ApplyReturnError {
+			return nil, errTest
+		}
+
+		result := &terraform.InstanceState{ID: "foo", Attributes: map[string]string{}}
+		if s != nil {
+			for k, v := range s.Attributes {
+				result.Attributes[k] = v
+			}
+		}
+		return result.MergeDiff(d), nil
+	}
+	return p
+}
+
+var errTest = errors.New("failed")
@@ -0,0 +1,21 @@
+variable "name" {}
+
+variable "num" {
+    default = 1
+}
+
+resource "test_instance" "foo" {
+    name = "${var.name}"
+    num  = "${var.num}"
+}
+
+output "name" {
+    value = "${test_instance.foo.name}"
+}
+
+output "tags" {
+    value = {
+        name = "${var.name}"
+        num  = "${var.num}"
+    }
+}
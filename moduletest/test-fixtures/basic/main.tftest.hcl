@@ -0,0 +1,45 @@
+variables {
+    name = "foo"
+}
+
+run "create" {
+    assert {
+        resource = "test_instance.foo"
+        action   = "create"
+    }
+
+    assert {
+        output = "name"
+        equals = "foo"
+    }
+
+    assert {
+        output = "tags"
+        equals = {
+            name = "foo"
+            num  = 1
+        }
+    }
+}
+
+run "update" {
+    command = "plan"
+
+    variables {
+        num = 2
+    }
+
+    assert {
+        resource = "test_instance.foo"
+        action   = "update"
+    }
+}
+
+run "unchanged" {
+    command = "plan"
+
+    assert {
+        resource = "test_instance.foo"
+        action   = "no-op"
+    }
+}
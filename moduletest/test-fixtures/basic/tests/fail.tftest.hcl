@@ -0,0 +1,29 @@
+run "create" {
+    variables {
+        name = "bar"
+    }
+
+    assert {
+        output        = "name"
+        equals        = "foo"
+        error_message = "The name must be foo."
+    }
+
+    assert {
+        resource = "test_instance.foo"
+        action   = "delete"
+    }
+}
+
+run "unchanged" {
+    command = "plan"
+
+    variables {
+        name = "bar"
+    }
+
+    assert {
+        resource = "test_instance.foo"
+        action   = "no-op"
+    }
+}
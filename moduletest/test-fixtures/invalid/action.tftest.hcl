@@ -0,0 +1,6 @@
+run "create" {
+    assert {
+        resource = "test_instance.foo"
+        action   = "explode"
+    }
+}
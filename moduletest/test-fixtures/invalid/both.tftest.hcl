@@ -0,0 +1,8 @@
+run "create" {
+    assert {
+        output   = "name"
+        equals   = "foo"
+        resource = "test_instance.foo"
+        action   = "create"
+    }
+}
@@ -0,0 +1,3 @@
+run "create" {}
+
+run "create" {}
@@ -0,0 +1,8 @@
+run "plan" {
+    command = "plan"
+
+    assert {
+        output = "name"
+        equals = "foo"
+    }
+}
@@ -0,0 +1,45 @@
+package planfile
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// ConfigSnapshot is a snapshot of the configuration files that a plan was
+// created from, keyed by their paths relative to the root module
+// directory, using forward slashes as the separator.
+type ConfigSnapshot map[string][]byte
+
+// SnapshotDir returns a snapshot of the Terraform configuration files in
+// the given directory, which are those with the ".tf" or ".tf.json"
+// extensions. Subdirectories are not included.
+func SnapshotDir(dir string) (ConfigSnapshot, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(ConfigSnapshot)
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !isConfigFile(name) {
+			continue
+		}
+		src, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		ret[name] = src
+	}
+	return ret, nil
+}
+
+func isConfigFile(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		// Hidden files, including editor swap files, are ignored when
+		// loading configuration, so they are not part of the snapshot.
+		return false
+	}
+	return strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json")
+}
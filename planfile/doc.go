@@ -0,0 +1,11 @@
+// Package planfile deals with the file format used to serialize plans to
+// disk and then deserialize them back into memory later.
+//
+// A plan file is a ZIP archive containing the plan as understood by
+// Terraform core, the same changes serialized using the "diffs" package,
+// the prior state, the backend settings, and a snapshot of the
+// configuration files that the plan was created from. Reading a plan file
+// back in produces an equivalent plan, so a plan can be created with
+// "terraform plan -out=..." and then applied later with
+// "terraform apply".
+package planfile
@@ -0,0 +1,216 @@
+package planfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/diffs"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestRoundtrip(t *testing.T) {
+	plan := &terraform.Plan{
+		Diff: &terraform.Diff{
+			Modules: []*terraform.ModuleDiff{
+				&terraform.ModuleDiff{
+					Path: []string{"root"},
+					Resources: map[string]*terraform.InstanceDiff{
+						"test_thing.foo": &terraform.InstanceDiff{
+							Attributes: map[string]*terraform.ResourceAttrDiff{
+								"name": &terraform.ResourceAttrDiff{
+									Old: "foo",
+									New: "bar",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		State: &terraform.State{
+			Modules: []*terraform.ModuleState{
+				&terraform.ModuleState{
+					Path: []string{"root"},
+					Resources: map[string]*terraform.ResourceState{
+						"test_thing.foo": &terraform.ResourceState{
+							Type: "test_thing",
+							Primary: &terraform.InstanceState{
+								ID: "foo",
+								Attributes: map[string]string{
+									"id":   "foo",
+									"name": "foo",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Backend: &terraform.BackendState{
+			Type:   "local",
+			Config: map[string]interface{}{"path": "foo.tfstate"},
+			Hash:   12345,
+		},
+		Vars: map[string]interface{}{
+			"foo": "bar",
+		},
+	}
+	ty := cty.Map(cty.String)
+	changes := diffs.ChangeSet{
+		"test_thing.foo": diffs.NewUpdate(
+			ty,
+			cty.MapVal(map[string]cty.Value{"name": cty.StringVal("foo")}),
+			cty.MapVal(map[string]cty.Value{"name": cty.StringVal("bar")}),
+		),
+	}
+	snap := ConfigSnapshot{
+		"main.tf": []byte(`resource "test_thing" "foo" {}`),
+	}
+
+	dir, err := ioutil.TempDir("", "tf-planfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "tfplan")
+
+	if err := Create(filename, snap, plan, changes); err != nil {
+		t.Fatalf("failed to create plan file: %s", err)
+	}
+
+	r, err := Open(filename)
+	if err != nil {
+		t.Fatalf("failed to open plan file: %s", err)
+	}
+	defer r.Close()
+
+	t.Run("ReadPlan", func(t *testing.T) {
+		got, err := r.ReadPlan()
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotStr := strings.TrimSpace(got.String())
+		wantStr := strings.TrimSpace(plan.String())
+		if gotStr != wantStr {
+			t.Errorf("wrong result\ngot:  %s\nwant: %s", gotStr, wantStr)
+		}
+	})
+	t.Run("ReadChanges", func(t *testing.T) {
+		got, err := r.ReadChanges()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(changes) {
+			t.Fatalf("wrong number of changes %d; want %d", len(got), len(changes))
+		}
+		for key, want := range changes {
+			c, ok := got[key]
+			switch {
+			case !ok:
+				t.Errorf("missing change for %s", key)
+			case c.Action != want.Action || !c.Old.RawEquals(want.Old) || !c.New.RawEquals(want.New):
+				t.Errorf("wrong change for %s\ngot:  %#v\nwant: %#v", key, c, want)
+			}
+		}
+	})
+	t.Run("ReadPriorState", func(t *testing.T) {
+		got, err := r.ReadPriorState()
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotStr := strings.TrimSpace(got.String())
+		wantStr := strings.TrimSpace(plan.State.String())
+		if gotStr != wantStr {
+			t.Errorf("wrong result\ngot:  %s\nwant: %s", gotStr, wantStr)
+		}
+	})
+	t.Run("ReadBackend", func(t *testing.T) {
+		got, err := r.ReadBackend()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, plan.Backend) {
+			t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, plan.Backend)
+		}
+	})
+	t.Run("ReadConfigSnapshot", func(t *testing.T) {
+		got, err := r.ReadConfigSnapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, snap) {
+			t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, snap)
+		}
+	})
+}
+
+func TestReadPlan_legacy(t *testing.T) {
+	plan := &terraform.Plan{
+		Vars: map[string]interface{}{
+			"foo": "bar",
+		},
+	}
+
+	f, err := ioutil.TempFile("", "tf-planfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	err = terraform.WritePlan(plan, f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(f.Name()); err != ErrNotPlanFile {
+		t.Fatalf("wrong error from Open\ngot:  %v\nwant: %v", err, ErrNotPlanFile)
+	}
+
+	got, err := ReadPlan(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Vars, plan.Vars) {
+		t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got.Vars, plan.Vars)
+	}
+}
+
+func TestSnapshotDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tf-planfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"main.tf":       "# main",
+		"extra.tf.json": "{}",
+		".hidden.tf":    "# hidden",
+		"README.md":     "# readme",
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub.tf"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := SnapshotDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ConfigSnapshot{
+		"main.tf":       []byte("# main"),
+		"extra.tf.json": []byte("{}"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
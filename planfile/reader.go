@@ -0,0 +1,194 @@
+package planfile
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/terraform/diffs"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// ErrNotPlanFile is returned by Open if the given file is not a plan file
+// in the format produced by Create. Callers can use this to fall back to
+// reading plans saved by older versions of Terraform, using
+// terraform.ReadPlan.
+var ErrNotPlanFile = errors.New("not a plan file")
+
+// Reader is the main type used to read plan files. Create a Reader by
+// calling Open.
+//
+// A plan file is a random-access file format, so data can be retrieved
+// from it in any order. A Reader must be closed with Close once it is no
+// longer needed.
+type Reader struct {
+	zip *zip.ReadCloser
+}
+
+// Open creates a Reader for the file at the given filename, or returns an
+// error if the file doesn't seem to be a plan file. ErrNotPlanFile is
+// returned if the file exists but is not a plan file.
+func Open(filename string) (*Reader, error) {
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		if err == zip.ErrFormat {
+			return nil, ErrNotPlanFile
+		}
+		return nil, err
+	}
+
+	// A valid plan file always has a plan entry, so we'll check for it
+	// here to catch other ZIP archives early.
+	if findEntry(&r.Reader, tfplanFilename) == nil {
+		r.Close()
+		return nil, ErrNotPlanFile
+	}
+
+	return &Reader{zip: r}, nil
+}
+
+// ReadPlan reads the plan embedded in the plan file, which includes the
+// prior state and the backend settings.
+func (r *Reader) ReadPlan() (*terraform.Plan, error) {
+	rc, err := r.openEntry(tfplanFilename)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	plan, err := terraform.ReadPlan(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %s", err)
+	}
+	return plan, nil
+}
+
+// ReadChanges reads the changes embedded in the plan file, keyed by
+// resource address.
+func (r *Reader) ReadChanges() (diffs.ChangeSet, error) {
+	rc, err := r.openEntry(tfchangesFilename)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var doc changesJSON
+	if err := json.NewDecoder(rc).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to read changes: %s", err)
+	}
+	if doc.FormatVersion != changesFormatVersion {
+		return nil, fmt.Errorf("unsupported changes format version %d", doc.FormatVersion)
+	}
+	return diffs.ChangeSet(doc.Changes), nil
+}
+
+// ReadPriorState reads the state that the plan was created against. The
+// result is nil if the plan file has no prior state.
+func (r *Reader) ReadPriorState() (*terraform.State, error) {
+	if findEntry(&r.zip.Reader, tfstateFilename) == nil {
+		return nil, nil
+	}
+	rc, err := r.openEntry(tfstateFilename)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	state, err := terraform.ReadState(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prior state: %s", err)
+	}
+	return state, nil
+}
+
+// ReadBackend reads the settings of the backend that the plan was created
+// with. The result is nil if the plan file has no backend settings.
+func (r *Reader) ReadBackend() (*terraform.BackendState, error) {
+	if findEntry(&r.zip.Reader, tfbackendFilename) == nil {
+		return nil, nil
+	}
+	rc, err := r.openEntry(tfbackendFilename)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var backend terraform.BackendState
+	if err := json.NewDecoder(rc).Decode(&backend); err != nil {
+		return nil, fmt.Errorf("failed to read backend settings: %s", err)
+	}
+	return &backend, nil
+}
+
+// ReadConfigSnapshot reads the snapshot of the configuration files that
+// the plan was created from.
+func (r *Reader) ReadConfigSnapshot() (ConfigSnapshot, error) {
+	ret := make(ConfigSnapshot)
+	for _, f := range r.zip.File {
+		if !strings.HasPrefix(f.Name, tfconfigPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(f.Name, tfconfigPrefix)
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open config file %s: %s", name, err)
+		}
+		src, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %s", name, err)
+		}
+		ret[name] = src
+	}
+	return ret, nil
+}
+
+// Close closes the file, after which no other operations may be performed.
+func (r *Reader) Close() error {
+	return r.zip.Close()
+}
+
+func (r *Reader) openEntry(name string) (io.ReadCloser, error) {
+	f := findEntry(&r.zip.Reader, name)
+	if f == nil {
+		return nil, fmt.Errorf("plan file does not contain %s", name)
+	}
+	return f.Open()
+}
+
+func findEntry(r *zip.Reader, name string) *zip.File {
+	for _, f := range r.File {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// ReadPlan is a helper that reads just the plan from the file at the given
+// filename.
+//
+// For compatibility with older versions of Terraform, the file may also be
+// a plan that was saved directly with terraform.WritePlan.
+func ReadPlan(filename string) (*terraform.Plan, error) {
+	r, err := Open(filename)
+	switch {
+	case err == ErrNotPlanFile:
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return terraform.ReadPlan(f)
+	case err != nil:
+		return nil, err
+	}
+	defer r.Close()
+	return r.ReadPlan()
+}
@@ -0,0 +1,131 @@
+package planfile
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/terraform/diffs"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+const (
+	tfplanFilename    = "tfplan"
+	tfchangesFilename = "tfchanges.json"
+	tfstateFilename   = "tfstate"
+	tfbackendFilename = "tfbackend.json"
+	tfconfigPrefix    = "tfconfig/"
+)
+
+// changesFormatVersion is the version of the document in the changes
+// entry of a plan file.
+const changesFormatVersion = 1
+
+// changesJSON is the JSON document stored in the changes entry of a plan
+// file, which records each change keyed by its resource address.
+type changesJSON struct {
+	FormatVersion int                      `json:"format_version"`
+	Changes       map[string]*diffs.Change `json:"changes"`
+}
+
+// Create creates a new plan file with the given filename, overwriting any
+// file that might already exist there.
+//
+// The plan's prior state and backend settings are written alongside the
+// plan itself, along with the given changes, which should describe the same
+// actions as the plan's diff, and the given configuration snapshot.
+func Create(filename string, snap ConfigSnapshot, plan *terraform.Plan, changes diffs.ChangeSet) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	defer zw.Close()
+
+	// tfplan
+	{
+		w, err := createEntry(zw, tfplanFilename)
+		if err != nil {
+			return fmt.Errorf("failed to create plan entry: %s", err)
+		}
+		if err := terraform.WritePlan(plan, w); err != nil {
+			return fmt.Errorf("failed to write plan: %s", err)
+		}
+	}
+
+	// tfchanges.json
+	{
+		w, err := createEntry(zw, tfchangesFilename)
+		if err != nil {
+			return fmt.Errorf("failed to create changes entry: %s", err)
+		}
+		doc := changesJSON{
+			FormatVersion: changesFormatVersion,
+			Changes:       changes,
+		}
+		if doc.Changes == nil {
+			doc.Changes = diffs.ChangeSet{}
+		}
+		if err := json.NewEncoder(w).Encode(doc); err != nil {
+			return fmt.Errorf("failed to write changes: %s", err)
+		}
+	}
+
+	// tfstate
+	if plan.State != nil {
+		w, err := createEntry(zw, tfstateFilename)
+		if err != nil {
+			return fmt.Errorf("failed to create prior state entry: %s", err)
+		}
+		if err := terraform.WriteState(plan.State, w); err != nil {
+			return fmt.Errorf("failed to write prior state: %s", err)
+		}
+	}
+
+	// tfbackend.json
+	if plan.Backend != nil {
+		w, err := createEntry(zw, tfbackendFilename)
+		if err != nil {
+			return fmt.Errorf("failed to create backend entry: %s", err)
+		}
+		if err := json.NewEncoder(w).Encode(plan.Backend); err != nil {
+			return fmt.Errorf("failed to write backend settings: %s", err)
+		}
+	}
+
+	// tfconfig/...
+	names := make([]string, 0, len(snap))
+	for name := range snap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		w, err := createEntry(zw, path.Join(tfconfigPrefix, name))
+		if err != nil {
+			return fmt.Errorf("failed to create config entry for %s: %s", name, err)
+		}
+		if _, err := w.Write(snap[name]); err != nil {
+			return fmt.Errorf("failed to write config file %s: %s", name, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize plan file: %s", err)
+	}
+	return f.Close()
+}
+
+func createEntry(zw *zip.Writer, name string) (io.Writer, error) {
+	return zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+}
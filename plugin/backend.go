@@ -0,0 +1,628 @@
+package plugin
+
+import (
+	"bytes"
+	"log"
+	"net/rpc"
+	"sync"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/encryption"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// BackendPlugin is the plugin.Plugin implementation.
+type BackendPlugin struct {
+	F func() backend.Backend
+}
+
+func (p *BackendPlugin) Server(b *plugin.MuxBroker) (interface{}, error) {
+	return &BackendServer{Broker: b, Backend: p.F()}, nil
+}
+
+func (p *BackendPlugin) Client(
+	b *plugin.MuxBroker, c *rpc.Client) (interface{}, error) {
+	return &Backend{Broker: b, Client: c}, nil
+}
+
+// Backend is an implementation of backend.Backend that communicates
+// over RPC.
+//
+// Only state storage is available over RPC, so a Backend is never an
+// enhanced backend. The states it returns are read and written as a whole
+// by the plugin, and are locked by the plugin if it supports locking.
+type Backend struct {
+	Broker *plugin.MuxBroker
+	Client *rpc.Client
+}
+
+func (b *Backend) Input(
+	input terraform.UIInput,
+	c *terraform.ResourceConfig) (*terraform.ResourceConfig, error) {
+	id := b.Broker.NextId()
+	go b.Broker.AcceptAndServe(id, &UIInputServer{
+		UIInput: input,
+	})
+
+	var resp BackendInputResponse
+	args := BackendInputArgs{
+		InputId: id,
+		Config:  c,
+	}
+
+	err := b.Client.Call("Plugin.Input", &args, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		err = resp.Error
+		return nil, err
+	}
+
+	return resp.Config, nil
+}
+
+func (b *Backend) Validate(c *terraform.ResourceConfig) ([]string, []error) {
+	var resp BackendValidateResponse
+	args := BackendValidateArgs{
+		Config: c,
+	}
+
+	err := b.Client.Call("Plugin.Validate", &args, &resp)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var errs []error
+	if len(resp.Errors) > 0 {
+		errs = make([]error, len(resp.Errors))
+		for i, err := range resp.Errors {
+			errs[i] = err
+		}
+	}
+
+	return resp.Warnings, errs
+}
+
+func (b *Backend) Configure(c *terraform.ResourceConfig) error {
+	var resp BackendConfigureResponse
+	err := b.Client.Call("Plugin.Configure", c, &resp)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		err = resp.Error
+	}
+
+	return err
+}
+
+func (b *Backend) State(name string) (state.State, error) {
+	// Make sure the plugin can create the state before we hand it out,
+	// since the returned state makes its own calls for each operation.
+	var resp BackendStateResponse
+	err := b.Client.Call("Plugin.State", name, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, backendError(resp.Error)
+	}
+
+	return &BackendState{Client: b.Client, Name: name}, nil
+}
+
+func (b *Backend) DeleteState(name string) error {
+	var resp BackendDeleteStateResponse
+	err := b.Client.Call("Plugin.DeleteState", name, &resp)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return backendError(resp.Error)
+	}
+
+	return nil
+}
+
+func (b *Backend) States() ([]string, error) {
+	var resp BackendStatesResponse
+	err := b.Client.Call("Plugin.States", new(interface{}), &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, backendError(resp.Error)
+	}
+
+	return resp.States, nil
+}
+
+// backendError returns the error the backend returned for the given error
+// from the plugin, so that callers can compare it with the errors declared
+// in the backend package.
+func backendError(err *plugin.BasicError) error {
+	if err.Message == backend.ErrNamedStatesNotSupported.Error() {
+		return backend.ErrNamedStatesNotSupported
+	}
+	return err
+}
+
+// BackendState is an implementation of state.State for a named state in
+// a Backend, which reads and writes the state over RPC.
+type BackendState struct {
+	mu sync.Mutex
+
+	Client *rpc.Client
+	Name   string
+
+	state, readState *terraform.State
+	encryption       *encryption.Encryption
+}
+
+// SetEncryption encrypts the state before it is sent to the plugin.
+func (s *BackendState) SetEncryption(e *encryption.Encryption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.encryption = e
+}
+
+// StateReader impl.
+func (s *BackendState) State() *terraform.State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state.DeepCopy()
+}
+
+// StateWriter impl.
+func (s *BackendState) WriteState(state *terraform.State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.readState != nil && !state.SameLineage(s.readState) {
+		log.Printf("[WARN] incompatible state lineage; given %s but want %s", state.Lineage, s.readState.Lineage)
+	}
+
+	// As with remote.State, we keep our own copy of the state and keep the
+	// serial of our read state until the state is persisted.
+	s.state = state.DeepCopy()
+	if s.readState != nil {
+		s.state.Serial = s.readState.Serial
+	}
+
+	return nil
+}
+
+// StateRefresher impl.
+func (s *BackendState) RefreshState() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var resp BackendStateGetResponse
+	err := s.Client.Call("Plugin.StateGet", s.Name, &resp)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	// no stored state is OK
+	if resp.Data == nil {
+		return nil
+	}
+
+	data := resp.Data
+	if s.encryption != nil {
+		data, err = s.encryption.Decrypt(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	state, err := terraform.ReadState(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	s.state = state
+	s.readState = s.state.DeepCopy()
+	return nil
+}
+
+// StatePersister impl.
+func (s *BackendState) PersistState() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.state.MarshalEqual(s.readState) {
+		s.state.Serial++
+	}
+
+	var buf bytes.Buffer
+	if err := terraform.WriteState(s.state, &buf); err != nil {
+		return err
+	}
+
+	data := buf.Bytes()
+	if s.encryption != nil {
+		var err error
+		data, err = s.encryption.Encrypt(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	var resp BackendStatePutResponse
+	args := BackendStatePutArgs{
+		Name: s.Name,
+		Data: data,
+	}
+	err := s.Client.Call("Plugin.StatePut", &args, &resp)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	s.readState = s.state.DeepCopy()
+	return nil
+}
+
+// Lock locks the state in the plugin. If the plugin does not support
+// locking this does nothing.
+func (s *BackendState) Lock(info *state.LockInfo) (string, error) {
+	var resp BackendStateLockResponse
+	args := BackendStateLockArgs{
+		Name: s.Name,
+		Info: info,
+	}
+	err := s.Client.Call("Plugin.StateLock", &args, &resp)
+	if err != nil {
+		return "", err
+	}
+	if resp.Error != nil {
+		// The holder of an existing lock is returned as a LockError so
+		// that it can be reported to the user.
+		if resp.LockInfo != nil {
+			return "", &state.LockError{Info: resp.LockInfo, Err: resp.Error}
+		}
+		return "", resp.Error
+	}
+
+	return resp.ID, nil
+}
+
+// Unlock unlocks the state in the plugin.
+func (s *BackendState) Unlock(id string) error {
+	var resp BackendStateUnlockResponse
+	args := BackendStateUnlockArgs{
+		Name: s.Name,
+		ID:   id,
+	}
+	err := s.Client.Call("Plugin.StateUnlock", &args, &resp)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		if resp.LockInfo != nil {
+			return &state.LockError{Info: resp.LockInfo, Err: resp.Error}
+		}
+		return resp.Error
+	}
+
+	return nil
+}
+
+// BackendServer is a net/rpc compatible structure for serving
+// a Backend. This should not be used directly.
+type BackendServer struct {
+	Broker  *plugin.MuxBroker
+	Backend backend.Backend
+
+	// states holds the states returned by Backend, since a state may need
+	// to be unlocked by the same instance that locked it.
+	statesLock sync.Mutex
+	states     map[string]state.State
+}
+
+type BackendInputArgs struct {
+	InputId uint32
+	Config  *terraform.ResourceConfig
+}
+
+type BackendInputResponse struct {
+	Config *terraform.ResourceConfig
+	Error  *plugin.BasicError
+}
+
+type BackendValidateArgs struct {
+	Config *terraform.ResourceConfig
+}
+
+type BackendValidateResponse struct {
+	Warnings []string
+	Errors   []*plugin.BasicError
+}
+
+type BackendConfigureResponse struct {
+	Error *plugin.BasicError
+}
+
+type BackendStateResponse struct {
+	Error *plugin.BasicError
+}
+
+type BackendDeleteStateResponse struct {
+	Error *plugin.BasicError
+}
+
+type BackendStatesResponse struct {
+	States []string
+	Error  *plugin.BasicError
+}
+
+type BackendStateGetResponse struct {
+	Data  []byte
+	Error *plugin.BasicError
+}
+
+type BackendStatePutArgs struct {
+	Name string
+	Data []byte
+}
+
+type BackendStatePutResponse struct {
+	Error *plugin.BasicError
+}
+
+type BackendStateLockArgs struct {
+	Name string
+	Info *state.LockInfo
+}
+
+type BackendStateLockResponse struct {
+	ID       string
+	LockInfo *state.LockInfo
+	Error    *plugin.BasicError
+}
+
+type BackendStateUnlockArgs struct {
+	Name string
+	ID   string
+}
+
+type BackendStateUnlockResponse struct {
+	LockInfo *state.LockInfo
+	Error    *plugin.BasicError
+}
+
+func (s *BackendServer) Input(
+	args *BackendInputArgs,
+	reply *BackendInputResponse) error {
+	conn, err := s.Broker.Dial(args.InputId)
+	if err != nil {
+		*reply = BackendInputResponse{
+			Error: plugin.NewBasicError(err),
+		}
+		return nil
+	}
+	client := rpc.NewClient(conn)
+	defer client.Close()
+
+	input := &UIInput{Client: client}
+
+	config, err := s.Backend.Input(input, args.Config)
+	*reply = BackendInputResponse{
+		Config: config,
+		Error:  plugin.NewBasicError(err),
+	}
+
+	return nil
+}
+
+func (s *BackendServer) Validate(
+	args *BackendValidateArgs,
+	reply *BackendValidateResponse) error {
+	warns, errs := s.Backend.Validate(args.Config)
+	berrs := make([]*plugin.BasicError, len(errs))
+	for i, err := range errs {
+		berrs[i] = plugin.NewBasicError(err)
+	}
+	*reply = BackendValidateResponse{
+		Warnings: warns,
+		Errors:   berrs,
+	}
+	return nil
+}
+
+func (s *BackendServer) Configure(
+	config *terraform.ResourceConfig,
+	reply *BackendConfigureResponse) error {
+	err := s.Backend.Configure(config)
+	*reply = BackendConfigureResponse{
+		Error: plugin.NewBasicError(err),
+	}
+	return nil
+}
+
+func (s *BackendServer) State(
+	name string,
+	reply *BackendStateResponse) error {
+	_, err := s.state(name)
+	*reply = BackendStateResponse{
+		Error: plugin.NewBasicError(err),
+	}
+	return nil
+}
+
+func (s *BackendServer) DeleteState(
+	name string,
+	reply *BackendDeleteStateResponse) error {
+	err := s.Backend.DeleteState(name)
+	if err == nil {
+		s.statesLock.Lock()
+		delete(s.states, name)
+		s.statesLock.Unlock()
+	}
+	*reply = BackendDeleteStateResponse{
+		Error: plugin.NewBasicError(err),
+	}
+	return nil
+}
+
+func (s *BackendServer) States(
+	nothing interface{},
+	reply *BackendStatesResponse) error {
+	states, err := s.Backend.States()
+	*reply = BackendStatesResponse{
+		States: states,
+		Error:  plugin.NewBasicError(err),
+	}
+	return nil
+}
+
+func (s *BackendServer) StateGet(
+	name string,
+	reply *BackendStateGetResponse) error {
+	data, err := s.stateGet(name)
+	*reply = BackendStateGetResponse{
+		Data:  data,
+		Error: plugin.NewBasicError(err),
+	}
+	return nil
+}
+
+func (s *BackendServer) stateGet(name string) ([]byte, error) {
+	sMgr, err := s.state(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := sMgr.RefreshState(); err != nil {
+		return nil, err
+	}
+
+	current := sMgr.State()
+	if current == nil {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	if err := terraform.WriteState(current, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *BackendServer) StatePut(
+	args *BackendStatePutArgs,
+	reply *BackendStatePutResponse) error {
+	err := s.statePut(args.Name, args.Data)
+	*reply = BackendStatePutResponse{
+		Error: plugin.NewBasicError(err),
+	}
+	return nil
+}
+
+func (s *BackendServer) statePut(name string, data []byte) error {
+	sMgr, err := s.state(name)
+	if err != nil {
+		return err
+	}
+
+	// The state manager maintains the serial of the state itself, as it
+	// would for any other caller.
+	current, err := terraform.ReadState(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if err := sMgr.WriteState(current); err != nil {
+		return err
+	}
+	return sMgr.PersistState()
+}
+
+func (s *BackendServer) StateLock(
+	args *BackendStateLockArgs,
+	reply *BackendStateLockResponse) error {
+	sMgr, err := s.state(args.Name)
+	if err != nil {
+		*reply = BackendStateLockResponse{
+			Error: plugin.NewBasicError(err),
+		}
+		return nil
+	}
+
+	locker, ok := sMgr.(state.Locker)
+	if !ok {
+		return nil
+	}
+
+	id, err := locker.Lock(args.Info)
+	*reply = BackendStateLockResponse{
+		ID:       id,
+		LockInfo: lockErrorInfo(err),
+		Error:    plugin.NewBasicError(err),
+	}
+	return nil
+}
+
+func (s *BackendServer) StateUnlock(
+	args *BackendStateUnlockArgs,
+	reply *BackendStateUnlockResponse) error {
+	sMgr, err := s.state(args.Name)
+	if err != nil {
+		*reply = BackendStateUnlockResponse{
+			Error: plugin.NewBasicError(err),
+		}
+		return nil
+	}
+
+	locker, ok := sMgr.(state.Locker)
+	if !ok {
+		return nil
+	}
+
+	err = locker.Unlock(args.ID)
+	*reply = BackendStateUnlockResponse{
+		LockInfo: lockErrorInfo(err),
+		Error:    plugin.NewBasicError(err),
+	}
+	return nil
+}
+
+// state returns the state of the given name from the backend, reusing the
+// state from an earlier call if there is one.
+func (s *BackendServer) state(name string) (state.State, error) {
+	s.statesLock.Lock()
+	defer s.statesLock.Unlock()
+
+	if sMgr, ok := s.states[name]; ok {
+		return sMgr, nil
+	}
+
+	sMgr, err := s.Backend.State(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.states == nil {
+		s.states = make(map[string]state.State)
+	}
+	s.states[name] = sMgr
+	return sMgr, nil
+}
+
+// lockErrorInfo returns the lock info from the given error if it is a
+// state.LockError, or nil otherwise.
+func lockErrorInfo(err error) *state.LockInfo {
+	if lockErr, ok := err.(*state.LockError); ok {
+		return lockErr.Info
+	}
+	return nil
+}
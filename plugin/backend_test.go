@@ -0,0 +1,75 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/terraform/backend"
+	backendlocal "github.com/hashicorp/terraform/backend/local"
+	"github.com/hashicorp/terraform/state"
+)
+
+func TestBackend_impl(t *testing.T) {
+	var _ plugin.Plugin = new(BackendPlugin)
+	var _ backend.Backend = new(Backend)
+	var _ state.State = new(BackendState)
+	var _ state.Locker = new(BackendState)
+	var _ state.Encrypter = new(BackendState)
+}
+
+func TestBackend_state(t *testing.T) {
+	b := backendlocal.TestLocal(t)
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		BackendFunc: func() backend.Backend { return b },
+	}))
+	defer client.Close()
+
+	raw, err := client.Dispense(BackendPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	remote := raw.(backend.Backend)
+
+	s, err := remote.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	lockID, err := s.(state.Locker).Lock(state.NewLockInfo())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Uses the RPC state as any other state manager
+	if err := s.WriteState(state.TestStateInitial()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	state.TestState(t, s)
+
+	if err := s.(state.Locker).Unlock(lockID); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The state is stored by the backend in the plugin
+	local, err := b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := local.RefreshState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !local.State().Equal(s.State()) {
+		t.Fatalf("wrong state in backend\ngot:  %s\nwant: %s", local.State(), s.State())
+	}
+
+	states, err := remote.States()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(states) != 1 || states[0] != backend.DefaultStateName {
+		t.Fatalf("wrong states: %#v", states)
+	}
+}
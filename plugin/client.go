@@ -31,3 +31,22 @@ func ClientConfig(m discovery.PluginMeta) *plugin.ClientConfig {
 func Client(m discovery.PluginMeta) *plugin.Client {
 	return plugin.NewClient(ClientConfig(m))
 }
+
+// ReattachClient returns a plugin client for a plugin process that is
+// already running, such as one kept running by a provider host. The process
+// isn't killed when the client is cleaned up, so that it can be reattached
+// to again.
+func ReattachClient(c *plugin.ReattachConfig) *plugin.Client {
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:   "plugin",
+		Level:  hclog.Trace,
+		Output: os.Stderr,
+	})
+
+	return plugin.NewClient(&plugin.ClientConfig{
+		Reattach:        c,
+		HandshakeConfig: Handshake,
+		Plugins:         PluginMap,
+		Logger:          logger,
+	})
+}
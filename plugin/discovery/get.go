@@ -18,6 +18,7 @@ import (
 	cleanhttp "github.com/hashicorp/go-cleanhttp"
 	getter "github.com/hashicorp/go-getter"
 	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/terraform/svchost"
 	"github.com/mitchellh/cli"
 )
 
@@ -66,6 +67,17 @@ type ProviderInstaller struct {
 	// Skip checksum and signature verification
 	SkipVerify bool
 
+	// OCIMirrors are the OCI registry repositories to install providers
+	// from, in order of preference. If there are any then providers are
+	// installed only from these rather than from the release host.
+	OCIMirrors []*OCIMirror
+
+	// SigstorePolicies are the provenance policies for releases from the
+	// release host and OCI mirror hosts, keyed by hostname. Releases from a
+	// host with a policy must also have a keyless signature that satisfies
+	// it.
+	SigstorePolicies map[svchost.Hostname]*SigstorePolicy
+
 	Ui cli.Ui // Ui for output
 }
 
@@ -89,6 +101,10 @@ type ProviderInstaller struct {
 // be presented alongside context about what is being installed, and thus the
 // error messages do not redundantly include such information.
 func (i *ProviderInstaller) Get(provider string, req Constraints) (PluginMeta, error) {
+	if len(i.OCIMirrors) > 0 {
+		return i.getFromOCIMirrors(provider, req)
+	}
+
 	versions, err := i.listProviderVersions(provider)
 	// TODO: return multiple errors
 	if err != nil {
@@ -138,40 +154,7 @@ func (i *ProviderInstaller) Get(provider string, req Constraints) (PluginMeta, e
 				return PluginMeta{}, err
 			}
 
-			// Find what we just installed
-			// (This is weird, because go-getter doesn't directly return
-			//  information about what was extracted, and we just extracted
-			//  the archive directly into a shared dir here.)
-			log.Printf("[DEBUG] looking for the %s %s plugin we just installed", provider, v)
-			metas := FindPlugins("provider", []string{i.Dir})
-			log.Printf("[DEBUG] all plugins found %#v", metas)
-			metas, _ = metas.ValidateVersions()
-			metas = metas.WithName(provider).WithVersion(v)
-			log.Printf("[DEBUG] filtered plugins %#v", metas)
-			if metas.Count() == 0 {
-				// This should never happen. Suggests that the release archive
-				// contains an executable file whose name doesn't match the
-				// expected convention.
-				return PluginMeta{}, fmt.Errorf(
-					"failed to find installed plugin version %s; this is a bug in Terraform and should be reported",
-					v,
-				)
-			}
-
-			if metas.Count() > 1 {
-				// This should also never happen, and suggests that a
-				// particular version was re-released with a different
-				// executable filename. We consider releases as immutable, so
-				// this is an error.
-				return PluginMeta{}, fmt.Errorf(
-					"multiple plugins installed for version %s; this is a bug in Terraform and should be reported",
-					v,
-				)
-			}
-
-			// By now we know we have exactly one meta, and so "Newest" will
-			// return that one.
-			return metas.Newest(), nil
+			return i.installedMeta(provider, v)
 		}
 
 		log.Printf("[INFO] incompatible ProtocolVersion for %s version %s", provider, v)
@@ -180,6 +163,45 @@ func (i *ProviderInstaller) Get(provider string, req Constraints) (PluginMeta, e
 	return PluginMeta{}, ErrorNoVersionCompatible
 }
 
+// installedMeta returns the meta of the given version of the given provider
+// that was just installed into the installer's directory.
+func (i *ProviderInstaller) installedMeta(provider string, v Version) (PluginMeta, error) {
+	// Find what we just installed
+	// (This is weird, because go-getter doesn't directly return
+	//  information about what was extracted, and we just extracted
+	//  the archive directly into a shared dir here.)
+	log.Printf("[DEBUG] looking for the %s %s plugin we just installed", provider, v)
+	metas := FindPlugins("provider", []string{i.Dir})
+	log.Printf("[DEBUG] all plugins found %#v", metas)
+	metas, _ = metas.ValidateVersions()
+	metas = metas.WithName(provider).WithVersion(v)
+	log.Printf("[DEBUG] filtered plugins %#v", metas)
+	if metas.Count() == 0 {
+		// This should never happen. Suggests that the release archive
+		// contains an executable file whose name doesn't match the
+		// expected convention.
+		return PluginMeta{}, fmt.Errorf(
+			"failed to find installed plugin version %s; this is a bug in Terraform and should be reported",
+			v,
+		)
+	}
+
+	if metas.Count() > 1 {
+		// This should also never happen, and suggests that a
+		// particular version was re-released with a different
+		// executable filename. We consider releases as immutable, so
+		// this is an error.
+		return PluginMeta{}, fmt.Errorf(
+			"multiple plugins installed for version %s; this is a bug in Terraform and should be reported",
+			v,
+		)
+	}
+
+	// By now we know we have exactly one meta, and so "Newest" will
+	// return that one.
+	return metas.Newest(), nil
+}
+
 func (i *ProviderInstaller) install(provider string, version Version, url string) error {
 	if i.Cache != nil {
 		log.Printf("[DEBUG] looking for provider %s %s in plugin cache", provider, version)
@@ -342,10 +364,14 @@ func (i *ProviderInstaller) providerChecksumURL(name, version string) string {
 }
 
 func (i *ProviderInstaller) getProviderChecksum(name, version string) (string, error) {
-	checksums, err := getPluginSHA256SUMs(i.providerChecksumURL(name, version))
+	sumsURL := i.providerChecksumURL(name, version)
+	checksums, err := getPluginSHA256SUMs(sumsURL)
 	if err != nil {
 		return "", err
 	}
+	if err := i.verifyReleaseSHA256SUMs(sumsURL, checksums); err != nil {
+		return "", err
+	}
 
 	return checksumForFile(checksums, i.providerFileName(name, version)), nil
 }
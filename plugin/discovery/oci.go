@@ -0,0 +1,641 @@
+package discovery
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/hashicorp/terraform/svchost"
+	"github.com/hashicorp/terraform/svchost/auth"
+)
+
+// OCI provider mirrors are repositories of an OCI registry, such as ghcr.io
+// or ECR, that provider releases are installed from instead of the release
+// host.
+//
+// Each provider has its own repository, named for the plugin:
+//    <mirror repository>/terraform-provider-<name>
+//
+// Each version of the provider is tagged with the version number, such as
+// "1.2.0". The tag refers either to an image index with a manifest for each
+// OS and architecture, or to a single manifest for one platform. The first
+// layer of the manifest is the release archive, exactly as it is published
+// on the release host.
+//
+// Signatures follow the conventions of cosign: the signature of the tagged
+// index or manifest with digest "sha256:<hex>" is tagged "sha256-<hex>.sig",
+// and each of its layers is a simple signing payload, with the base64
+// encoded signature of the payload in the layer's
+// "dev.cosignproject.cosign/signature" annotation. Keyless signatures also
+// have the signing certificate and the transparency log bundle in the
+// "dev.sigstore.cosign/certificate" and "dev.sigstore.cosign/bundle"
+// annotations.
+
+// OCIMirror is an OCI registry repository to install providers from.
+type OCIMirror struct {
+	// Repository is the registry host and the repository path that the
+	// repositories of the providers are in, such as
+	// "ghcr.io/example/terraform-providers".
+	Repository string
+
+	// PublicKeyFile is the path of a PEM encoded ECDSA, Ed25519 or RSA public
+	// key that the provider releases must be signed with. It's required
+	// unless signatures aren't verified.
+	PublicKeyFile string
+
+	// Username is the user name to authenticate to the registry with, if
+	// Credentials has a token for its host. Many registries accept any
+	// user name along with a token.
+	Username string
+
+	// Credentials optionally provides a token for the registry host, as for
+	// the other hosts that Terraform uses.
+	Credentials auth.CredentialsSource
+}
+
+const (
+	ociMediaTypeIndex           = "application/vnd.oci.image.index.v1+json"
+	ociMediaTypeManifest        = "application/vnd.oci.image.manifest.v1+json"
+	dockerMediaTypeManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	dockerMediaTypeManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+
+	ociSignatureAnnotation   = "dev.cosignproject.cosign/signature"
+	ociCertificateAnnotation = "dev.sigstore.cosign/certificate"
+	ociBundleAnnotation      = "dev.sigstore.cosign/bundle"
+)
+
+// ociDescriptor is the descriptor of content in an OCI registry.
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations"`
+	Platform    *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+	} `json:"platform"`
+}
+
+// ociManifest is an image manifest or index, of which only the fields that
+// are used are decoded.
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Manifests []ociDescriptor `json:"manifests"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+// ociClient makes the requests for the repository of a provider.
+type ociClient struct {
+	mirror *OCIMirror
+	host   string
+	repo   string
+	token  string
+}
+
+func (m *OCIMirror) client(provider string) (*ociClient, error) {
+	parts := strings.SplitN(m.Repository, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid OCI mirror repository %q; must be a registry host and a repository path", m.Repository)
+	}
+	return &ociClient{
+		mirror: m,
+		host:   parts[0],
+		repo:   strings.TrimSuffix(parts[1], "/") + "/terraform-provider-" + provider,
+	}, nil
+}
+
+// Versions returns the versions of the given provider in the mirror, or
+// ErrorNoSuchProvider if the mirror has no repository for it.
+func (m *OCIMirror) Versions(provider string) ([]Version, error) {
+	c, err := m.client(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.get("/tags/list", "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var tags struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("invalid tag list for %s: %s", c.repo, err)
+	}
+
+	// Tags that aren't versions, such as those of signatures, are ignored.
+	var versions []Version
+	for _, tag := range tags.Tags {
+		v, err := VersionStr(tag).Parse()
+		if err != nil {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// Download downloads the release archive of the given version of the given
+// provider for the given platform into the given file, verifying the
+// signature of the release with the mirror's public key and the given
+// Sigstore policy, if any, unless skipVerify is set.
+func (m *OCIMirror) Download(provider string, v Version, goos, goarch, dst string, policy *SigstorePolicy, skipVerify bool) error {
+	c, err := m.client(provider)
+	if err != nil {
+		return err
+	}
+
+	body, digest, err := c.manifest(v.String())
+	if err != nil {
+		return err
+	}
+	if skipVerify {
+		log.Printf("[WARN] not verifying the signature of %s:%s", c.repo, v)
+	} else if err := c.verifySignature(digest, policy); err != nil {
+		return fmt.Errorf("failed to verify the signature of %s:%s: %s", c.repo, v, err)
+	}
+
+	var manifest ociManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return fmt.Errorf("invalid manifest for %s:%s: %s", c.repo, v, err)
+	}
+
+	// An index refers to the manifests of each platform by digest, so the
+	// signature of the index covers them too.
+	if len(manifest.Manifests) > 0 {
+		var found string
+		for _, d := range manifest.Manifests {
+			if d.Platform != nil && d.Platform.OS == goos && d.Platform.Architecture == goarch {
+				found = d.Digest
+				break
+			}
+		}
+		if found == "" {
+			return fmt.Errorf("%s:%s has no release for %s_%s", c.repo, v, goos, goarch)
+		}
+
+		body, _, err = c.manifest(found)
+		if err != nil {
+			return err
+		}
+		manifest = ociManifest{}
+		if err := json.Unmarshal(body, &manifest); err != nil {
+			return fmt.Errorf("invalid manifest for %s:%s: %s", c.repo, v, err)
+		}
+	}
+
+	if len(manifest.Layers) == 0 {
+		return fmt.Errorf("manifest for %s:%s has no layers", c.repo, v)
+	}
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if err := c.blob(manifest.Layers[0].Digest, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// manifest returns the manifest or index with the given tag or digest,
+// along with its digest. If ref is a digest then the manifest is checked
+// against it.
+func (c *ociClient) manifest(ref string) ([]byte, string, error) {
+	resp, err := c.get("/manifests/"+ref, strings.Join([]string{
+		ociMediaTypeIndex, ociMediaTypeManifest,
+		dockerMediaTypeManifestList, dockerMediaTypeManifest,
+	}, ", "))
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+	if strings.HasPrefix(ref, "sha256:") && ref != digest {
+		return nil, "", fmt.Errorf("manifest %s of %s has the wrong digest %s", ref, c.repo, digest)
+	}
+	return body, digest, nil
+}
+
+// blob writes the blob with the given digest to the given writer, failing
+// if the content doesn't match the digest.
+func (c *ociClient) blob(digest string, w io.Writer) error {
+	if !strings.HasPrefix(digest, "sha256:") {
+		return fmt.Errorf("unsupported digest %q", digest)
+	}
+
+	resp, err := c.get("/blobs/"+digest, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, h), resp.Body); err != nil {
+		return err
+	}
+	if got := fmt.Sprintf("sha256:%x", h.Sum(nil)); got != digest {
+		return fmt.Errorf("blob %s of %s has the wrong digest %s", digest, c.repo, got)
+	}
+	return nil
+}
+
+// verifySignature checks that the manifest or index with the given digest
+// has a cosign signature made with the mirror's public key, and a keyless
+// signature that satisfies the given policy if there is one.
+func (c *ociClient) verifySignature(digest string, policy *SigstorePolicy) error {
+	if c.mirror.PublicKeyFile == "" && policy == nil {
+		return fmt.Errorf("no public_key or Sigstore policy is configured for the OCI mirror %s", c.mirror.Repository)
+	}
+	var key crypto.PublicKey
+	if c.mirror.PublicKeyFile != "" {
+		var err error
+		key, err = readPublicKey(c.mirror.PublicKeyFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	body, _, err := c.manifest(strings.Replace(digest, ":", "-", 1) + ".sig")
+	if err != nil {
+		return fmt.Errorf("no signature found: %s", err)
+	}
+	var sigs ociManifest
+	if err := json.Unmarshal(body, &sigs); err != nil {
+		return fmt.Errorf("invalid signature manifest: %s", err)
+	}
+
+	keyOK, keylessOK := key == nil, policy == nil
+	for _, layer := range sigs.Layers {
+		if keyOK && keylessOK {
+			break
+		}
+		sig, ok := layer.Annotations[ociSignatureAnnotation]
+		if !ok {
+			continue
+		}
+
+		var payload strings.Builder
+		if err := c.blob(layer.Digest, &payload); err != nil {
+			return err
+		}
+
+		if !keyOK {
+			if err := verifyOCISignature(key, []byte(payload.String()), sig, digest); err != nil {
+				log.Printf("[DEBUG] signature %s of %s isn't valid: %s", layer.Digest, c.repo, err)
+			} else {
+				keyOK = true
+			}
+		}
+
+		// Keyless signatures carry the certificate they were made with and
+		// the proof that they were recorded in the transparency log.
+		if cert, ok := layer.Annotations[ociCertificateAnnotation]; ok && !keylessOK {
+			err := verifyOCIKeylessSignature(policy, []byte(payload.String()), sig,
+				[]byte(cert), []byte(layer.Annotations[ociBundleAnnotation]), digest)
+			if err != nil {
+				log.Printf("[DEBUG] keyless signature %s of %s isn't valid: %s", layer.Digest, c.repo, err)
+			} else {
+				keylessOK = true
+			}
+		}
+	}
+
+	if !keyOK {
+		return fmt.Errorf("no valid signature made with the key in %s", c.mirror.PublicKeyFile)
+	}
+	if !keylessOK {
+		return fmt.Errorf("no valid keyless signature by %s from %s", policy.Identity, policy.Issuer)
+	}
+	return nil
+}
+
+// verifyOCISignature checks that the given base64 encoded signature of the
+// given simple signing payload was made with the given key, and that the
+// payload is for the given digest.
+func verifyOCISignature(key crypto.PublicKey, payload []byte, sig, digest string) error {
+	raw, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %s", err)
+	}
+	if err := verifyWithKey(key, payload, raw); err != nil {
+		return err
+	}
+	return checkOCIPayload(payload, digest)
+}
+
+// verifyOCIKeylessSignature is the same as verifyOCISignature for a keyless
+// signature made with the given certificate, which must satisfy the given
+// policy.
+func verifyOCIKeylessSignature(policy *SigstorePolicy, payload []byte, sig string, cert, bundle []byte, digest string) error {
+	var b rekorBundle
+	if err := json.Unmarshal(bundle, &b); err != nil {
+		return fmt.Errorf("invalid transparency log bundle: %s", err)
+	}
+	if err := policy.verify(payload, sig, cert, &b); err != nil {
+		return err
+	}
+	return checkOCIPayload(payload, digest)
+}
+
+// verifyWithKey checks that the given raw signature of the given data was
+// made with the given key.
+func verifyWithKey(key crypto.PublicKey, data, sig []byte) error {
+	sum := sha256.Sum256(data)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, sum[:], sig) {
+			return fmt.Errorf("signature doesn't match")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, data, sig) {
+			return fmt.Errorf("signature doesn't match")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig); err != nil {
+			return fmt.Errorf("signature doesn't match")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	return nil
+}
+
+// checkOCIPayload checks that the given simple signing payload is for the
+// given digest. The payload says what was signed, which must be what we're
+// installing rather than another release signed by the same signer.
+func checkOCIPayload(payload []byte, digest string) error {
+	var p struct {
+		Critical struct {
+			Image struct {
+				Digest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+		} `json:"critical"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("invalid signature payload: %s", err)
+	}
+	if p.Critical.Image.Digest != digest {
+		return fmt.Errorf("signature is for %s", p.Critical.Image.Digest)
+	}
+	return nil
+}
+
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %s", err)
+	}
+	block, _ := pem.Decode(buf)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded public key in %s", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key in %s: %s", path, err)
+	}
+	return key, nil
+}
+
+// get makes a request to the given path of the repository API, handling
+// the token authentication challenges of the registry.
+func (c *ociClient) get(path, accept string) (*http.Response, error) {
+	u := "https://" + c.host + "/v2/" + c.repo + path
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return resp, nil
+		case resp.StatusCode == http.StatusUnauthorized && attempt == 0:
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+			if err := c.authenticate(challenge); err != nil {
+				return nil, err
+			}
+		case resp.StatusCode == http.StatusNotFound && path == "/tags/list":
+			resp.Body.Close()
+			return nil, ErrorNoSuchProvider
+		default:
+			resp.Body.Close()
+			return nil, fmt.Errorf("error fetching %s: %s", u, resp.Status)
+		}
+	}
+}
+
+// authenticate gets a token for the repository with the given challenge
+// from the registry, using the credentials for the registry host if there
+// are any.
+func (c *ociClient) authenticate(challenge string) error {
+	scheme, params := parseAuthChallenge(challenge)
+	basicUser, basicPass, haveBasic := c.basicAuth()
+
+	if !strings.EqualFold(scheme, "bearer") {
+		return fmt.Errorf("unsupported authentication challenge from %s: %q", c.host, challenge)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("invalid authentication realm from %s: %q", c.host, params["realm"])
+	}
+	q := realm.Query()
+	if s := params["service"]; s != "" {
+		q.Set("service", s)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + c.repo + ":pull"
+	}
+	q.Set("scope", scope)
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequest("GET", realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if haveBasic {
+		req.SetBasicAuth(basicUser, basicPass)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to authenticate to %s: %s", c.host, resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("invalid token from %s: %s", c.host, err)
+	}
+	c.token = token.Token
+	if c.token == "" {
+		c.token = token.AccessToken
+	}
+	return nil
+}
+
+// basicAuth returns the user name and the token to get registry tokens
+// with, if there are credentials for the registry host.
+func (c *ociClient) basicAuth() (string, string, bool) {
+	if c.mirror.Credentials == nil {
+		return "", "", false
+	}
+	host, err := svchost.ForComparison(c.host)
+	if err != nil {
+		return "", "", false
+	}
+	creds, err := c.mirror.Credentials.ForHost(host)
+	if err != nil {
+		log.Printf("[WARN] failed to get credentials for %s: %s", c.host, err)
+		return "", "", false
+	}
+	token, ok := creds.(auth.HostCredentialsToken)
+	if !ok {
+		return "", "", false
+	}
+
+	username := c.mirror.Username
+	if username == "" {
+		username = "terraform"
+	}
+	return username, string(token), true
+}
+
+// parseAuthChallenge parses a WWW-Authenticate header, such as
+// `Bearer realm="https://ghcr.io/token",service="ghcr.io"`, into its scheme
+// and parameters.
+func parseAuthChallenge(challenge string) (string, map[string]string) {
+	params := make(map[string]string)
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	if len(parts) < 2 {
+		return parts[0], params
+	}
+
+	rest := parts[1]
+	for rest != "" {
+		eq := strings.Index(rest, "=")
+		if eq == -1 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = strings.TrimSpace(rest[eq+1:])
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end == -1 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if comma := strings.Index(rest, ","); comma != -1 {
+			value, rest = rest[:comma], rest[comma:]
+		} else {
+			value, rest = rest, ""
+		}
+		params[key] = value
+		rest = strings.TrimPrefix(strings.TrimSpace(rest), ",")
+	}
+	return parts[0], params
+}
+
+// getFromOCIMirrors installs a version of the given provider that meets the
+// given constraints from the first of the installer's OCI mirrors that has
+// the provider, as Get does from the release host.
+func (i *ProviderInstaller) getFromOCIMirrors(provider string, req Constraints) (PluginMeta, error) {
+	goos, goarch := i.OS, i.Arch
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if goarch == "" {
+		goarch = runtime.GOARCH
+	}
+
+	for _, m := range i.OCIMirrors {
+		versions, err := m.Versions(provider)
+		if err == ErrorNoSuchProvider {
+			log.Printf("[DEBUG] provider %q isn't in the OCI mirror %s", provider, m.Repository)
+			continue
+		}
+		if err != nil {
+			return PluginMeta{}, err
+		}
+
+		versions = allowedVersions(versions, req)
+		if len(versions) == 0 {
+			return PluginMeta{}, ErrorNoSuitableVersion
+		}
+		Versions(versions).Sort()
+		v := versions[0]
+
+		if err := os.MkdirAll(i.Dir, os.ModePerm); err != nil {
+			return PluginMeta{}, fmt.Errorf("failed to create plugin dir %s: %s", i.Dir, err)
+		}
+
+		// go-getter unpacks the archive as it does archives from the release
+		// host, as long as the file has the expected extension.
+		tmpDir, err := ioutil.TempDir("", "tf-oci")
+		if err != nil {
+			return PluginMeta{}, err
+		}
+		defer os.RemoveAll(tmpDir)
+		archive := filepath.Join(tmpDir, i.providerFileName(provider, v.String()))
+
+		i.Ui.Info(fmt.Sprintf("- Downloading plugin for provider %q (%s) from %s...", provider, v.String(), m.Repository))
+		policy := i.sigstorePolicy(strings.SplitN(m.Repository, "/", 2)[0])
+		if err := m.Download(provider, v, goos, goarch, archive, policy, i.SkipVerify); err != nil {
+			return PluginMeta{}, err
+		}
+		if err := i.install(provider, v, archive); err != nil {
+			return PluginMeta{}, err
+		}
+		return i.installedMeta(provider, v)
+	}
+
+	return PluginMeta{}, ErrorNoSuchProvider
+}
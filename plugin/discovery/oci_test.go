@@ -0,0 +1,310 @@
+package discovery
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+// testOCIRegistry is a registry with a repository for version 1.0.0 of the
+// "test" provider, which requires a token for every request.
+type testOCIRegistry struct {
+	server    *httptest.Server
+	key       *ecdsa.PrivateKey
+	manifests map[string][]byte
+	blobs     map[string][]byte
+}
+
+func testOCIDigest(b []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(b))
+}
+
+func newTestOCIRegistry(t *testing.T) *testOCIRegistry {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &testOCIRegistry{
+		key:       key,
+		manifests: make(map[string][]byte),
+		blobs:     make(map[string][]byte),
+	}
+
+	var archive bytes.Buffer
+	z := zip.NewWriter(&archive)
+	f, err := z.Create("terraform-provider-test_v1.0.0_x4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte(testProviderFile))
+	z.Close()
+	r.blobs[testOCIDigest(archive.Bytes())] = archive.Bytes()
+
+	manifest, _ := json.Marshal(map[string]interface{}{
+		"mediaType": ociMediaTypeManifest,
+		"layers": []map[string]interface{}{
+			{"mediaType": "application/zip", "digest": testOCIDigest(archive.Bytes())},
+		},
+	})
+	r.manifests[testOCIDigest(manifest)] = manifest
+
+	index, _ := json.Marshal(map[string]interface{}{
+		"mediaType": ociMediaTypeIndex,
+		"manifests": []map[string]interface{}{
+			{
+				"mediaType": ociMediaTypeManifest,
+				"digest":    testOCIDigest(manifest),
+				"platform":  map[string]string{"os": "linux", "architecture": "amd64"},
+			},
+		},
+	})
+	r.manifests["1.0.0"] = index
+	r.sign(t, testOCIDigest(index), key)
+
+	r.server = httptest.NewTLSServer(http.HandlerFunc(r.handler))
+	return r
+}
+
+// sign adds a signature for the given digest made with the given key.
+func (r *testOCIRegistry) sign(t *testing.T, digest string, key *ecdsa.PrivateKey) {
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"test"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"}}`, digest))
+	sum := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.blobs[testOCIDigest(payload)] = payload
+
+	manifest, _ := json.Marshal(map[string]interface{}{
+		"mediaType": ociMediaTypeManifest,
+		"layers": []map[string]interface{}{
+			{
+				"mediaType":   "application/vnd.dev.cosign.simplesigning.v1+json",
+				"digest":      testOCIDigest(payload),
+				"annotations": map[string]string{ociSignatureAnnotation: base64.StdEncoding.EncodeToString(sig)},
+			},
+		},
+	})
+	r.manifests[strings.Replace(digest, ":", "-", 1)+".sig"] = manifest
+}
+
+func (r *testOCIRegistry) handler(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/token" {
+		if !strings.HasPrefix(req.URL.Query().Get("scope"), "repository:providers/") {
+			http.Error(w, "wrong scope", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"token":"abc123"}`))
+		return
+	}
+
+	if req.Header.Get("Authorization") != "Bearer abc123" {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, r.server.URL))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	const prefix = "/v2/providers/terraform-provider-test/"
+	if !strings.HasPrefix(req.URL.Path, prefix) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	path := strings.TrimPrefix(req.URL.Path, prefix)
+
+	switch {
+	case path == "tags/list":
+		tags := []string{"latest"}
+		for tag := range r.manifests {
+			tags = append(tags, tag)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"tags": tags})
+	case strings.HasPrefix(path, "manifests/"):
+		b, ok := r.manifests[strings.TrimPrefix(path, "manifests/")]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write(b)
+	case strings.HasPrefix(path, "blobs/"):
+		b, ok := r.blobs[strings.TrimPrefix(path, "blobs/")]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write(b)
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+// mirror returns a mirror of the registry with the given public key.
+func (r *testOCIRegistry) mirror(t *testing.T, dir string, key *ecdsa.PrivateKey) *OCIMirror {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "key.pem")
+	err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &OCIMirror{
+		Repository:    strings.TrimPrefix(r.server.URL, "https://") + "/providers",
+		PublicKeyFile: keyFile,
+	}
+}
+
+func TestProviderInstallerGet_ociMirror(t *testing.T) {
+	r := newTestOCIRegistry(t)
+	defer r.server.Close()
+
+	oldClient := httpClient
+	httpClient = r.server.Client()
+	defer func() { httpClient = oldClient }()
+
+	tmpDir, err := ioutil.TempDir("", "tf-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	pluginDir := filepath.Join(tmpDir, "plugins")
+
+	i := &ProviderInstaller{
+		Dir:                   pluginDir,
+		PluginProtocolVersion: 4,
+		OS:                    "linux",
+		Arch:                  "amd64",
+		OCIMirrors:            []*OCIMirror{r.mirror(t, tmpDir, r.key)},
+		Ui:                    cli.NewMockUi(),
+	}
+
+	{
+		_, err := i.Get("test", ConstraintStr(">9.0.0").MustParse())
+		if err != ErrorNoSuitableVersion {
+			t.Fatalf("want error for mismatching constraints, got %v", err)
+		}
+	}
+
+	{
+		_, err := i.Get("nonexist", AllVersions)
+		if err != ErrorNoSuchProvider {
+			t.Fatalf("want error for no such provider, got %v", err)
+		}
+	}
+
+	gotMeta, err := i.Get("test", AllVersions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(pluginDir, "terraform-provider-test_v1.0.0_x4")
+	wantMeta := PluginMeta{
+		Name:    "test",
+		Version: VersionStr("1.0.0"),
+		Path:    dest,
+	}
+	if !reflect.DeepEqual(gotMeta, wantMeta) {
+		t.Errorf("wrong result meta\ngot:  %#v\nwant: %#v", gotMeta, wantMeta)
+	}
+
+	f, err := ioutil.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(f) != testProviderFile {
+		t.Fatalf("test provider contains: %q", f)
+	}
+}
+
+func TestProviderInstallerGet_ociMirrorBadSignature(t *testing.T) {
+	r := newTestOCIRegistry(t)
+	defer r.server.Close()
+
+	oldClient := httpClient
+	httpClient = r.server.Client()
+	defer func() { httpClient = oldClient }()
+
+	tmpDir, err := ioutil.TempDir("", "tf-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := &ProviderInstaller{
+		Dir:        filepath.Join(tmpDir, "plugins"),
+		OS:         "linux",
+		Arch:       "amd64",
+		OCIMirrors: []*OCIMirror{r.mirror(t, tmpDir, otherKey)},
+		Ui:         cli.NewMockUi(),
+	}
+
+	_, err = i.Get("test", AllVersions)
+	if err == nil || !strings.Contains(err.Error(), "failed to verify the signature") {
+		t.Fatalf("want error for signature made with another key, got %v", err)
+	}
+
+	// The signature isn't checked if verification is skipped.
+	i.SkipVerify = true
+	if _, err := i.Get("test", AllVersions); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyOCISignature_wrongDigest(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A valid signature of another release must not be accepted.
+	payload := []byte(`{"critical":{"image":{"docker-manifest-digest":"sha256:other"}}}`)
+	sum := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = verifyOCISignature(&key.PublicKey, payload, base64.StdEncoding.EncodeToString(sig), "sha256:release")
+	if err == nil {
+		t.Fatal("want error for signature of another digest")
+	}
+}
+
+func TestParseAuthChallenge(t *testing.T) {
+	scheme, params := parseAuthChallenge(`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:foo:pull"`)
+	if scheme != "Bearer" {
+		t.Fatalf("wrong scheme %q", scheme)
+	}
+	want := map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry.example.com",
+		"scope":   "repository:foo:pull",
+	}
+	if !reflect.DeepEqual(params, want) {
+		t.Fatalf("wrong params\ngot:  %#v\nwant: %#v", params, want)
+	}
+}
@@ -0,0 +1,297 @@
+package discovery
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/terraform/svchost"
+)
+
+// Keyless signatures are made with a short-lived certificate that a Sigstore
+// certificate authority (Fulcio) issues to an OIDC identity, such as the
+// workflow of a CI system that builds the releases, and are recorded in a
+// Sigstore transparency log (Rekor). The certificate has expired by the time
+// the signature is verified, so the log's signed entry timestamp is what
+// proves that the signature was made while the certificate was valid.
+//
+// Releases from the release host are signed by signing the SHA256SUMS file
+// with "cosign sign-blob --bundle", which is published alongside it as
+//    terraform-provider-name_<x.y.z>_SHA256SUMS.cosign.bundle
+
+// SigstorePolicy is the provenance that releases from a host must have,
+// verified with keyless signatures.
+type SigstorePolicy struct {
+	// Identity is the email address or the URI, such as that of a CI
+	// workflow, that the signing certificate must be issued to.
+	Identity string
+
+	// Issuer is the URL of the OIDC issuer that must have authenticated the
+	// identity, such as "https://token.actions.githubusercontent.com".
+	Issuer string
+
+	// RootsFile is the path of the PEM encoded root and intermediate
+	// certificates of the certificate authority.
+	RootsFile string
+
+	// RekorPublicKeyFile is the path of the PEM encoded public key of the
+	// transparency log.
+	RekorPublicKeyFile string
+}
+
+var (
+	// oidFulcioIssuer is the extension of Fulcio certificates with the OIDC
+	// issuer as a raw string, and oidFulcioIssuerV2 is its successor with
+	// the issuer as a DER encoded UTF8String.
+	oidFulcioIssuer   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidFulcioIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// rekorBundle is the proof that a signature was recorded in the
+// transparency log, as cosign includes it with keyless signatures.
+type rekorBundle struct {
+	SignedEntryTimestamp []byte       `json:"SignedEntryTimestamp"`
+	Payload              rekorPayload `json:"Payload"`
+}
+
+// rekorPayload is the log entry that the signed entry timestamp signs. Its
+// fields are in the order of their names, so that it encodes as the
+// canonical JSON that the log signs.
+type rekorPayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+// cosignBlobBundle is the bundle that "cosign sign-blob --bundle" writes.
+type cosignBlobBundle struct {
+	Base64Signature string      `json:"base64Signature"`
+	Cert            string      `json:"cert"`
+	RekorBundle     rekorBundle `json:"rekorBundle"`
+}
+
+// verify checks that the given base64 encoded signature of the given data
+// was made with the given PEM encoded certificate, which the policy's
+// certificate authority issued to the policy's identity, and that the
+// signature was recorded in the transparency log while the certificate was
+// valid.
+func (p *SigstorePolicy) verify(data []byte, sig string, certPEM []byte, bundle *rekorBundle) error {
+	rawSig, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %s", err)
+	}
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return fmt.Errorf("no PEM encoded signing certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid signing certificate: %s", err)
+	}
+
+	// The log entry comes first, since its time is when the certificate
+	// must have been valid.
+	signedAt, err := p.verifyLogEntry(data, rawSig, bundle)
+	if err != nil {
+		return err
+	}
+
+	roots, intermediates, err := readCertPools(p.RootsFile)
+	if err != nil {
+		return err
+	}
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   signedAt,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return fmt.Errorf("untrusted signing certificate: %s", err)
+	}
+
+	if !certHasIdentity(cert, p.Identity) {
+		return fmt.Errorf("signing certificate isn't issued to %s", p.Identity)
+	}
+	if issuer := certIssuer(cert); issuer != p.Issuer {
+		return fmt.Errorf("signing certificate identity is from %q, not %q", issuer, p.Issuer)
+	}
+
+	return verifyWithKey(cert.PublicKey, data, rawSig)
+}
+
+// verifyLogEntry checks that the given bundle is a log entry for the given
+// signature of the given data signed by the transparency log, returning the
+// time that the entry was made.
+func (p *SigstorePolicy) verifyLogEntry(data, sig []byte, bundle *rekorBundle) (time.Time, error) {
+	if bundle == nil || len(bundle.SignedEntryTimestamp) == 0 {
+		return time.Time{}, fmt.Errorf("signature has no transparency log bundle")
+	}
+
+	key, err := readPublicKey(p.RekorPublicKeyFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	canonical, err := json.Marshal(bundle.Payload)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := verifyWithKey(key, canonical, bundle.SignedEntryTimestamp); err != nil {
+		return time.Time{}, fmt.Errorf("transparency log bundle isn't signed by the log: %s", err)
+	}
+
+	// The signed entry is for a particular signature of particular data, so
+	// the entry of another signature can't be used in its place.
+	rawBody, err := base64.StdEncoding.DecodeString(bundle.Payload.Body)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid transparency log entry: %s", err)
+	}
+	var body struct {
+		Spec struct {
+			Data struct {
+				Hash struct {
+					Algorithm string `json:"algorithm"`
+					Value     string `json:"value"`
+				} `json:"hash"`
+			} `json:"data"`
+			Signature struct {
+				Content string `json:"content"`
+			} `json:"signature"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(rawBody, &body); err != nil {
+		return time.Time{}, fmt.Errorf("invalid transparency log entry: %s", err)
+	}
+	sum := sha256.Sum256(data)
+	if body.Spec.Data.Hash.Algorithm != "sha256" || body.Spec.Data.Hash.Value != hex.EncodeToString(sum[:]) {
+		return time.Time{}, fmt.Errorf("transparency log entry is for other data")
+	}
+	if body.Spec.Signature.Content != base64.StdEncoding.EncodeToString(sig) {
+		return time.Time{}, fmt.Errorf("transparency log entry is for another signature")
+	}
+
+	return time.Unix(bundle.Payload.IntegratedTime, 0), nil
+}
+
+// verifyBlobBundle checks that the given cosign blob bundle is a keyless
+// signature of the given data that satisfies the policy.
+func (p *SigstorePolicy) verifyBlobBundle(data, bundle []byte) error {
+	var b cosignBlobBundle
+	if err := json.Unmarshal(bundle, &b); err != nil {
+		return fmt.Errorf("invalid signature bundle: %s", err)
+	}
+	certPEM, err := base64.StdEncoding.DecodeString(b.Cert)
+	if err != nil {
+		return fmt.Errorf("invalid signing certificate encoding: %s", err)
+	}
+	return p.verify(data, b.Base64Signature, certPEM, &b.RekorBundle)
+}
+
+func certHasIdentity(cert *x509.Certificate, identity string) bool {
+	for _, email := range cert.EmailAddresses {
+		if email == identity {
+			return true
+		}
+	}
+	for _, uri := range cert.URIs {
+		if uri.String() == identity {
+			return true
+		}
+	}
+	return false
+}
+
+func certIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidFulcioIssuerV2):
+			var issuer string
+			if _, err := asn1.UnmarshalWithParams(ext.Value, &issuer, "utf8"); err == nil {
+				return issuer
+			}
+		case ext.Id.Equal(oidFulcioIssuer):
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
+
+// readCertPools reads the PEM encoded certificates in the given file, of
+// which the self-signed ones are roots and the others are intermediates.
+func readCertPools(path string) (*x509.CertPool, *x509.CertPool, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read root certificates: %s", err)
+	}
+
+	roots, intermediates := x509.NewCertPool(), x509.NewCertPool()
+	found := false
+	for {
+		var block *pem.Block
+		block, buf = pem.Decode(buf)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid certificate in %s: %s", path, err)
+		}
+		if cert.CheckSignatureFrom(cert) == nil {
+			roots.AddCert(cert)
+			found = true
+		} else {
+			intermediates.AddCert(cert)
+		}
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("no root certificates in %s", path)
+	}
+	return roots, intermediates, nil
+}
+
+// sigstorePolicy returns the policy for releases from the given host, or
+// nil if releases from the host needn't have keyless signatures.
+func (i *ProviderInstaller) sigstorePolicy(host string) *SigstorePolicy {
+	if len(i.SigstorePolicies) == 0 {
+		return nil
+	}
+	hostname, err := svchost.ForComparison(host)
+	if err != nil {
+		log.Printf("[WARN] invalid hostname %q: %s", host, err)
+		return nil
+	}
+	return i.SigstorePolicies[hostname]
+}
+
+// verifyReleaseSHA256SUMs checks the keyless signature of the given
+// SHA256SUMS file from the release host, if the release host has a policy.
+func (i *ProviderInstaller) verifyReleaseSHA256SUMs(sumsURL string, sums []byte) error {
+	u, err := url.Parse(releaseHost)
+	if err != nil {
+		return err
+	}
+	policy := i.sigstorePolicy(u.Host)
+	if policy == nil {
+		return nil
+	}
+
+	bundle, err := getFile(sumsURL + ".cosign.bundle")
+	if err != nil {
+		return fmt.Errorf("error fetching checksums signature bundle: %s", err)
+	}
+	if err := policy.verifyBlobBundle(sums, bundle); err != nil {
+		return fmt.Errorf("failed to verify the keyless signature of the checksums: %s", err)
+	}
+	return nil
+}
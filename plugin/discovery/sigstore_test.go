@@ -0,0 +1,357 @@
+package discovery
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform/svchost"
+	"github.com/mitchellh/cli"
+)
+
+const (
+	testSigstoreIdentity = "https://github.com/example/terraform-provider-test/.github/workflows/release.yml@refs/heads/main"
+	testSigstoreIssuer   = "https://token.actions.githubusercontent.com"
+)
+
+// testSigstore is a certificate authority and a transparency log for making
+// keyless signatures.
+type testSigstore struct {
+	caKey    *ecdsa.PrivateKey
+	caCert   *x509.Certificate
+	rekorKey *ecdsa.PrivateKey
+
+	policy *SigstorePolicy
+}
+
+func newTestSigstore(t *testing.T, dir string) *testSigstore {
+	s := &testSigstore{}
+
+	var err error
+	s.caKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.rekorKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-fulcio"},
+		NotBefore:             time.Now().Add(-24 * time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &s.caKey.PublicKey, s.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.caCert, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rootsFile := filepath.Join(dir, "fulcio.pem")
+	err = ioutil.WriteFile(rootsFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rekorDER, err := x509.MarshalPKIXPublicKey(&s.rekorKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rekorFile := filepath.Join(dir, "rekor.pub")
+	err = ioutil.WriteFile(rekorFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rekorDER}), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.policy = &SigstorePolicy{
+		Identity:           testSigstoreIdentity,
+		Issuer:             testSigstoreIssuer,
+		RootsFile:          rootsFile,
+		RekorPublicKeyFile: rekorFile,
+	}
+	return s
+}
+
+// sign makes a keyless signature of the given data for the given identity,
+// with a certificate that expired before now, as Fulcio's certificates
+// have by the time their signatures are verified. It returns the base64
+// encoded signature, the PEM encoded certificate and the log bundle.
+func (s *testSigstore) sign(t *testing.T, data []byte, identity string) (string, []byte, *rekorBundle) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer, err := asn1.MarshalWithParams(testSigstoreIssuer, "utf8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	identityURL, err := url.Parse(identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedAt := time.Now().Add(-time.Hour)
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       signedAt.Add(-time.Minute),
+		NotAfter:        signedAt.Add(10 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		URIs:            []*url.URL{identityURL},
+		ExtraExtensions: []pkix.Extension{{Id: oidFulcioIssuerV2, Value: issuer}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, s.caCert, &key.PublicKey, s.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	sum := sha256.Sum256(data)
+	rawSig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := base64.StdEncoding.EncodeToString(rawSig)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"apiVersion": "0.0.1",
+		"kind":       "hashedrekord",
+		"spec": map[string]interface{}{
+			"data": map[string]interface{}{
+				"hash": map[string]string{"algorithm": "sha256", "value": hex.EncodeToString(sum[:])},
+			},
+			"signature": map[string]interface{}{
+				"content":   sig,
+				"publicKey": map[string]string{"content": base64.StdEncoding.EncodeToString(certPEM)},
+			},
+		},
+	})
+	payload := rekorPayload{
+		Body:           base64.StdEncoding.EncodeToString(body),
+		IntegratedTime: signedAt.Unix(),
+		LogID:          "test",
+		LogIndex:       1,
+	}
+	canonical, _ := json.Marshal(payload)
+	setSum := sha256.Sum256(canonical)
+	set, err := ecdsa.SignASN1(rand.Reader, s.rekorKey, setSum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sig, certPEM, &rekorBundle{SignedEntryTimestamp: set, Payload: payload}
+}
+
+func TestSigstorePolicyVerify(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tf-sigstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	s := newTestSigstore(t, tmpDir)
+	data := []byte("release")
+
+	sig, cert, bundle := s.sign(t, data, testSigstoreIdentity)
+	if err := s.policy.verify(data, sig, cert, bundle); err != nil {
+		t.Fatalf("valid signature not accepted: %s", err)
+	}
+
+	if err := s.policy.verify([]byte("other release"), sig, cert, bundle); err == nil {
+		t.Fatal("want error for signature of other data")
+	}
+
+	{
+		sig, cert, bundle := s.sign(t, data, "https://github.com/attacker/repo/.github/workflows/release.yml@refs/heads/main")
+		if err := s.policy.verify(data, sig, cert, bundle); err == nil {
+			t.Fatal("want error for signature by another identity")
+		}
+	}
+
+	{
+		policy := *s.policy
+		policy.Issuer = "https://accounts.google.com"
+		if err := policy.verify(data, sig, cert, bundle); err == nil {
+			t.Fatal("want error for identity from another issuer")
+		}
+	}
+
+	{
+		// The log entry of one signature can't vouch for another.
+		otherSig, _, _ := s.sign(t, data, testSigstoreIdentity)
+		if err := s.policy.verify(data, otherSig, cert, bundle); err == nil {
+			t.Fatal("want error for log entry of another signature")
+		}
+	}
+
+	{
+		// The time of the entry is signed by the log, and the certificate
+		// had expired by now.
+		late := *bundle
+		late.Payload.IntegratedTime = time.Now().Unix()
+		if err := s.policy.verify(data, sig, cert, &late); err == nil {
+			t.Fatal("want error for log entry with a modified time")
+		}
+	}
+}
+
+func TestProviderInstallerGet_ociMirrorKeyless(t *testing.T) {
+	r := newTestOCIRegistry(t)
+	defer r.server.Close()
+
+	// Hostnames can't be IP addresses, so the mirror is on localhost, for
+	// which the server's certificate isn't valid.
+	oldClient := httpClient
+	httpClient = r.server.Client()
+	httpClient.Transport.(*http.Transport).TLSClientConfig.ServerName = "example.com"
+	defer func() { httpClient = oldClient }()
+
+	tmpDir, err := ioutil.TempDir("", "tf-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	s := newTestSigstore(t, tmpDir)
+	addr := strings.Replace(r.server.Listener.Addr().String(), "127.0.0.1", "localhost", 1)
+	host, err := svchost.ForComparison(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mirror := r.mirror(t, tmpDir, r.key)
+	mirror.Repository = addr + "/providers"
+	mirror.PublicKeyFile = ""
+	i := &ProviderInstaller{
+		Dir:              filepath.Join(tmpDir, "plugins"),
+		OS:               "linux",
+		Arch:             "amd64",
+		OCIMirrors:       []*OCIMirror{mirror},
+		SigstorePolicies: map[svchost.Hostname]*SigstorePolicy{host: s.policy},
+		Ui:               cli.NewMockUi(),
+	}
+
+	// The release only has a signature made with a key so far.
+	_, err = i.Get("test", AllVersions)
+	if err == nil || !strings.Contains(err.Error(), "no valid keyless signature") {
+		t.Fatalf("want error for release without a keyless signature, got %v", err)
+	}
+
+	r.signKeyless(t, s, testOCIDigest(r.manifests["1.0.0"]))
+	if _, err := i.Get("test", AllVersions); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// signKeyless adds a keyless signature for the given digest to the
+// signatures of the registry.
+func (r *testOCIRegistry) signKeyless(t *testing.T, s *testSigstore, digest string) {
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"test"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":{"keyless":true}}`, digest))
+	sig, cert, bundle := s.sign(t, payload, testSigstoreIdentity)
+	r.blobs[testOCIDigest(payload)] = payload
+	bundleJSON, _ := json.Marshal(bundle)
+
+	tag := strings.Replace(digest, ":", "-", 1) + ".sig"
+	var manifest map[string]interface{}
+	if err := json.Unmarshal(r.manifests[tag], &manifest); err != nil {
+		t.Fatal(err)
+	}
+	manifest["layers"] = append(manifest["layers"].([]interface{}), map[string]interface{}{
+		"mediaType": "application/vnd.dev.cosign.simplesigning.v1+json",
+		"digest":    testOCIDigest(payload),
+		"annotations": map[string]string{
+			ociSignatureAnnotation:   sig,
+			ociCertificateAnnotation: string(cert),
+			ociBundleAnnotation:      string(bundleJSON),
+		},
+	})
+	r.manifests[tag], _ = json.Marshal(manifest)
+}
+
+func TestProviderChecksum_keyless(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tf-sigstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	s := newTestSigstore(t, tmpDir)
+	sums, err := ioutil.ReadFile("testdata/terraform-provider-template_0.1.0_SHA256SUMS")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var bundle []byte
+	handler := http.NewServeMux()
+	handler.HandleFunc("/terraform-provider-template/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".cosign.bundle") {
+			if bundle == nil {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(bundle)
+			return
+		}
+		testChecksumHandler(w, r)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	oldHost := releaseHost
+	releaseHost = strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	defer func() { releaseHost = oldHost }()
+
+	host, err := svchost.ForComparison(strings.TrimPrefix(releaseHost, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := &ProviderInstaller{
+		SigstorePolicies: map[svchost.Hostname]*SigstorePolicy{host: s.policy},
+	}
+
+	// Without a keyless signature the GPG signature isn't enough.
+	if _, err := i.getProviderChecksum("template", "0.1.0"); err == nil {
+		t.Fatal("want error for checksums without a keyless signature")
+	}
+
+	sig, cert, rekor := s.sign(t, sums, testSigstoreIdentity)
+	bundle, _ = json.Marshal(cosignBlobBundle{
+		Base64Signature: sig,
+		Cert:            base64.StdEncoding.EncodeToString(cert),
+		RekorBundle:     *rekor,
+	})
+	sha256sum, err := i.getProviderChecksum("template", "0.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := checksumForFile(sums, i.providerFileName("template", "0.1.0")); sha256sum != expected {
+		t.Fatalf("expected: %s\ngot %s\n", expected, sha256sum)
+	}
+}
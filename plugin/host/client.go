@@ -0,0 +1,134 @@
+package host
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+
+	plugin "github.com/hashicorp/go-plugin"
+	tfplugin "github.com/hashicorp/terraform/plugin"
+	"github.com/hashicorp/terraform/plugin/discovery"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// Client is a connection to a provider host, which holds a lease on the
+// host until it's closed.
+type Client struct {
+	rpc   *rpc.Client
+	lease uint64
+
+	stopCh chan struct{}
+	once   sync.Once
+}
+
+// Dial connects to the provider host listening on the given socket, and
+// takes a lease on it.
+func Dial(socket string) (*Client, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		rpc:    rpc.NewClient(conn),
+		stopCh: make(chan struct{}),
+	}
+	if err := c.rpc.Call("Host.Lease", struct{}{}, &c.lease); err != nil {
+		c.rpc.Close()
+		return nil, err
+	}
+
+	go c.renew(renewInterval)
+	return c, nil
+}
+
+// renew renews the client's lease at the given interval until the client is
+// closed.
+func (c *Client) renew(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := c.rpc.Call("Host.Renew", c.lease, &struct{}{}); err != nil {
+				log.Printf("[WARN] provider host: failed to renew lease: %s", err)
+				return
+			}
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+// Acquire returns a client for the process of the given plugin that's kept
+// running by the host, starting it if it isn't already running.
+func (c *Client) Acquire(meta discovery.PluginMeta) (*plugin.Client, error) {
+	sum, err := meta.SHA256()
+	if err != nil {
+		return nil, err
+	}
+
+	var resp AcquireResponse
+	args := &AcquireArgs{
+		Path:   meta.Path,
+		SHA256: sum,
+	}
+	if err := c.rpc.Call("Host.Acquire", args, &resp); err != nil {
+		return nil, err
+	}
+
+	var addr net.Addr
+	switch resp.Network {
+	case "unix":
+		addr, err = net.ResolveUnixAddr(resp.Network, resp.Addr)
+	case "tcp":
+		addr, err = net.ResolveTCPAddr(resp.Network, resp.Addr)
+	default:
+		err = fmt.Errorf("unsupported network %q", resp.Network)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid address of plugin %s: %s", meta.Path, err)
+	}
+
+	return tfplugin.ReattachClient(&plugin.ReattachConfig{
+		Protocol: plugin.Protocol(resp.Protocol),
+		Addr:     addr,
+		Pid:      resp.Pid,
+	}), nil
+}
+
+// Schema returns the schema cached by the host with the given key, or nil
+// if there isn't one.
+func (c *Client) Schema(key string) (*terraform.ProviderSchema, error) {
+	var resp SchemaResponse
+	if err := c.rpc.Call("Host.Schema", key, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Schema, nil
+}
+
+// PutSchema caches the given schema in the host with the given key.
+func (c *Client) PutSchema(key string, schema *terraform.ProviderSchema) error {
+	args := &SchemaArgs{
+		Key:    key,
+		Schema: schema,
+	}
+	return c.rpc.Call("Host.PutSchema", args, &struct{}{})
+}
+
+// Close releases the client's lease and closes its connection. The host's
+// plugin processes keep running.
+func (c *Client) Close() error {
+	var err error
+	c.once.Do(func() {
+		close(c.stopCh)
+		err = c.rpc.Call("Host.Release", c.lease, &struct{}{})
+		if cerr := c.rpc.Close(); err == nil {
+			err = cerr
+		}
+	})
+	return err
+}
@@ -0,0 +1,361 @@
+// Package host implements a provider host, which keeps provider plugin
+// processes running between Terraform commands in the same working
+// directory, so that each command can reattach to them rather than starting
+// them again.
+//
+// The host is a daemon that serves net/rpc on a unix socket in the data
+// directory of the working directory. Each command that uses it holds a
+// lease, which it renews for as long as it runs, and the host shuts down,
+// stopping all of its plugins, once it has had no leases for its idle
+// timeout.
+package host
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	plugin "github.com/hashicorp/go-plugin"
+	tfplugin "github.com/hashicorp/terraform/plugin"
+	"github.com/hashicorp/terraform/plugin/discovery"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// SocketFile is the name of the socket that a provider host listens on,
+// within the data directory of its working directory.
+const SocketFile = "provider-host.sock"
+
+// DefaultIdleTimeout is how long a provider host keeps running without any
+// commands using it, unless configured otherwise.
+const DefaultIdleTimeout = 10 * time.Minute
+
+var (
+	// leaseTTL is how long a lease is held without being renewed, and
+	// renewInterval is how often clients renew their leases.
+	leaseTTL      = 30 * time.Second
+	renewInterval = 10 * time.Second
+
+	// checkInterval is how often a host checks for expired leases and
+	// whether it's idle.
+	checkInterval = time.Second
+)
+
+// SocketPath returns the path of the socket of the provider host for the
+// working directory with the given data directory.
+func SocketPath(dataDir string) string {
+	return filepath.Join(dataDir, SocketFile)
+}
+
+// ErrRunning is returned by Listen if a provider host is already listening
+// on the socket.
+var ErrRunning = errors.New("a provider host is already running")
+
+// Listen listens on the given socket for a provider host. Since a host
+// removes its socket when it stops, a socket that no host is listening on
+// was left by one that crashed, and is replaced.
+func Listen(socket string) (net.Listener, error) {
+	if conn, err := net.Dial("unix", socket); err == nil {
+		conn.Close()
+		return nil, ErrRunning
+	}
+	if err := os.Remove(socket); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return net.Listen("unix", socket)
+}
+
+// Server is a provider host.
+type Server struct {
+	// IdleTimeout is how long the host keeps running once no commands are
+	// using it.
+	IdleTimeout time.Duration
+
+	// launch starts a plugin process from the executable with the given
+	// path, which must have the given SHA256 checksum.
+	launch func(path string, sum []byte) (process, error)
+
+	mu        sync.Mutex
+	processes map[string]process
+	schemas   map[string]*terraform.ProviderSchema
+	leases    map[uint64]time.Time
+	nextLease uint64
+	idleSince time.Time
+	listener  net.Listener
+	done      chan struct{}
+	doneOnce  sync.Once
+}
+
+// process is a plugin process started by a host.
+type process interface {
+	ReattachConfig() *plugin.ReattachConfig
+	Exited() bool
+	Kill()
+}
+
+// NewServer returns a provider host with the given idle timeout.
+func NewServer(idleTimeout time.Duration) *Server {
+	return &Server{
+		IdleTimeout: idleTimeout,
+		launch:      launchPlugin,
+		processes:   make(map[string]process),
+		schemas:     make(map[string]*terraform.ProviderSchema),
+		leases:      make(map[uint64]time.Time),
+		done:        make(chan struct{}),
+	}
+}
+
+// Serve accepts connections from commands on the given listener until the
+// host is idle, and then stops all of its plugin processes and closes the
+// listener.
+func (s *Server) Serve(l net.Listener) error {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("Host", &hostRPC{s: s}); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.idleSince = time.Now()
+	s.listener = l
+	s.mu.Unlock()
+
+	go s.watch(l, checkInterval)
+	defer s.stop()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+				return err
+			}
+		}
+		go srv.ServeConn(conn)
+	}
+}
+
+// watch expires leases that haven't been renewed, and closes the given
+// listener once the host has been idle for its timeout, checking at the
+// given interval.
+func (s *Server) watch(l net.Listener, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		var now time.Time
+		select {
+		case now = <-t.C:
+		case <-s.done:
+			return
+		}
+
+		s.mu.Lock()
+		for id, expires := range s.leases {
+			if now.After(expires) {
+				log.Printf("[WARN] provider host: lease %d expired", id)
+				s.releaseLocked(id)
+			}
+		}
+		idle := len(s.leases) == 0 && now.Sub(s.idleSince) >= s.IdleTimeout
+		s.mu.Unlock()
+
+		if idle {
+			log.Printf("[INFO] provider host: idle for %s, shutting down", s.IdleTimeout)
+			s.shutdown(l)
+			return
+		}
+	}
+}
+
+// Stop stops the host, as if it were idle, even if commands are using it.
+func (s *Server) Stop() {
+	s.mu.Lock()
+	l := s.listener
+	s.mu.Unlock()
+	if l != nil {
+		s.shutdown(l)
+	}
+}
+
+func (s *Server) shutdown(l net.Listener) {
+	s.doneOnce.Do(func() {
+		close(s.done)
+		l.Close()
+	})
+}
+
+// stop stops all of the host's plugin processes.
+func (s *Server) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, p := range s.processes {
+		p.Kill()
+		delete(s.processes, key)
+	}
+}
+
+func (s *Server) releaseLocked(id uint64) {
+	if _, ok := s.leases[id]; !ok {
+		return
+	}
+	delete(s.leases, id)
+	if len(s.leases) == 0 {
+		s.idleSince = time.Now()
+	}
+}
+
+// acquire returns the reattach configuration of the process of the given
+// plugin, starting it if it isn't already running.
+func (s *Server) acquire(path string, sum []byte) (*plugin.ReattachConfig, error) {
+	key := path + "\x00" + hex.EncodeToString(sum)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, ok := s.processes[key]; ok {
+		if !p.Exited() {
+			return p.ReattachConfig(), nil
+		}
+		log.Printf("[WARN] provider host: plugin %s exited, starting it again", path)
+		delete(s.processes, key)
+	}
+
+	p, err := s.launch(path, sum)
+	if err != nil {
+		return nil, err
+	}
+	attach := p.ReattachConfig()
+	if attach == nil {
+		p.Kill()
+		return nil, fmt.Errorf("plugin %s didn't start", path)
+	}
+	s.processes[key] = p
+	log.Printf("[INFO] provider host: started plugin %s with pid %d", path, attach.Pid)
+	return attach, nil
+}
+
+// launchPlugin starts a plugin process that isn't stopped when the host's
+// command exits, so that it's only stopped by the host.
+func launchPlugin(path string, sum []byte) (process, error) {
+	config := tfplugin.ClientConfig(discovery.PluginMeta{Path: path})
+	config.Managed = false
+	config.SecureConfig = &plugin.SecureConfig{
+		Checksum: sum,
+		Hash:     sha256.New(),
+	}
+
+	client := plugin.NewClient(config)
+	if _, err := client.Start(); err != nil {
+		client.Kill()
+		return nil, err
+	}
+	return client, nil
+}
+
+// hostRPC is the net/rpc service of a host.
+type hostRPC struct {
+	s *Server
+}
+
+// AcquireArgs are the arguments of Host.Acquire.
+type AcquireArgs struct {
+	// Path is the path of the plugin's executable, and SHA256 is its
+	// checksum, which is verified before the plugin is started.
+	Path   string
+	SHA256 []byte
+}
+
+// AcquireResponse is the response of Host.Acquire, which describes how to
+// reattach to the plugin's process.
+type AcquireResponse struct {
+	Protocol string
+	Network  string
+	Addr     string
+	Pid      int
+}
+
+// SchemaArgs are the arguments of Host.PutSchema.
+type SchemaArgs struct {
+	Key    string
+	Schema *terraform.ProviderSchema
+}
+
+// SchemaResponse is the response of Host.Schema. Its schema is nil if none
+// is cached with the key.
+type SchemaResponse struct {
+	Schema *terraform.ProviderSchema
+}
+
+// Acquire returns the process of the given plugin.
+func (h *hostRPC) Acquire(args *AcquireArgs, resp *AcquireResponse) error {
+	attach, err := h.s.acquire(args.Path, args.SHA256)
+	if err != nil {
+		return err
+	}
+	*resp = AcquireResponse{
+		Protocol: string(attach.Protocol),
+		Network:  attach.Addr.Network(),
+		Addr:     attach.Addr.String(),
+		Pid:      attach.Pid,
+	}
+	return nil
+}
+
+// Lease returns a new lease, which keeps the host running until it's
+// released or expires.
+func (h *hostRPC) Lease(args struct{}, id *uint64) error {
+	s := h.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextLease++
+	*id = s.nextLease
+	s.leases[*id] = time.Now().Add(leaseTTL)
+	return nil
+}
+
+// Renew renews the given lease.
+func (h *hostRPC) Renew(id uint64, _ *struct{}) error {
+	s := h.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.leases[id]; !ok {
+		return fmt.Errorf("no lease %d", id)
+	}
+	s.leases[id] = time.Now().Add(leaseTTL)
+	return nil
+}
+
+// Release releases the given lease.
+func (h *hostRPC) Release(id uint64, _ *struct{}) error {
+	s := h.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseLocked(id)
+	return nil
+}
+
+// Schema returns the schema cached with the given key.
+func (h *hostRPC) Schema(key string, resp *SchemaResponse) error {
+	s := h.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp.Schema = s.schemas[key]
+	return nil
+}
+
+// PutSchema caches the given schema.
+func (h *hostRPC) PutSchema(args *SchemaArgs, _ *struct{}) error {
+	s := h.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schemas[args.Key] = args.Schema
+	return nil
+}
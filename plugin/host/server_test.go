@@ -0,0 +1,327 @@
+package host
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	plugin "github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/terraform/config/configschema"
+	"github.com/hashicorp/terraform/plugin/discovery"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestServer(t *testing.T) {
+	defer testIntervals(50 * time.Millisecond)()
+
+	_, socket, launches, done := testServer(t, 100*time.Millisecond)
+	defer func() { <-done }()
+
+	path := testPlugin(t, "one")
+	c, err := Dial(socket)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer c.Close()
+
+	// A plugin is started once, and its process is reused until it exits.
+	for i := 0; i < 2; i++ {
+		if _, err := c.Acquire(discovery.PluginMeta{Path: path}); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if launches.count(path) != 1 {
+		t.Fatalf("plugin started %d times; want 1", launches.count(path))
+	}
+
+	launches.exit(path)
+	if _, err := c.Acquire(discovery.PluginMeta{Path: path}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if launches.count(path) != 2 {
+		t.Fatalf("exited plugin wasn't started again")
+	}
+
+	// A changed plugin is started again.
+	if err := ioutil.WriteFile(path, []byte("two"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := c.Acquire(discovery.PluginMeta{Path: path}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if launches.count(path) != 3 {
+		t.Fatalf("changed plugin wasn't started again")
+	}
+}
+
+func TestServer_schema(t *testing.T) {
+	defer testIntervals(50 * time.Millisecond)()
+
+	_, socket, _, done := testServer(t, 100*time.Millisecond)
+	defer func() { <-done }()
+
+	c, err := Dial(socket)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer c.Close()
+
+	if schema, err := c.Schema("key"); err != nil || schema != nil {
+		t.Fatalf("wrong result %#v, %v; want no schema", schema, err)
+	}
+	want := &terraform.ProviderSchema{
+		Provider: &configschema.Block{},
+		ResourceTypes: map[string]*configschema.Block{
+			"test_instance": &configschema.Block{},
+		},
+	}
+	if err := c.PutSchema("key", want); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	schema, err := c.Schema("key")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if schema == nil || schema.ResourceTypes["test_instance"] == nil {
+		t.Fatalf("wrong schema %#v", schema)
+	}
+}
+
+func TestServer_idle(t *testing.T) {
+	defer testIntervals(50 * time.Millisecond)()
+
+	_, socket, launches, done := testServer(t, 100*time.Millisecond)
+
+	path := testPlugin(t, "one")
+	c, err := Dial(socket)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := c.Acquire(discovery.PluginMeta{Path: path}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The host keeps running while its lease is renewed.
+	select {
+	case <-done:
+		t.Fatal("host stopped while in use")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	c.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle host didn't stop")
+	}
+	if !launches.killed(path) {
+		t.Fatal("plugin wasn't stopped")
+	}
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Fatalf("socket wasn't removed: %v", err)
+	}
+}
+
+func TestServer_leaseExpired(t *testing.T) {
+	defer testIntervals(50 * time.Millisecond)()
+
+	s, socket, _, done := testServer(t, 100*time.Millisecond)
+
+	// A command that exits without releasing its lease doesn't keep the
+	// host running.
+	s.mu.Lock()
+	s.leases[100] = time.Now().Add(leaseTTL)
+	s.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("host with an expired lease didn't stop")
+	}
+	if _, err := Dial(socket); err == nil {
+		t.Fatal("stopped host is listening")
+	}
+}
+
+func TestListen(t *testing.T) {
+	dir := testTempDir(t)
+	socket := SocketPath(dir)
+
+	l, err := Listen(socket)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := Listen(socket); err != ErrRunning {
+		t.Fatalf("wrong error %v; want %v", err, ErrRunning)
+	}
+	l.Close()
+
+	// A socket left by a host that crashed is replaced.
+	if err := ioutil.WriteFile(socket, nil, 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	l, err = Listen(socket)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	l.Close()
+}
+
+// testServer starts a host that launches fake plugin processes, returning
+// a channel that's closed when it stops.
+func testServer(t *testing.T, idle time.Duration) (*Server, string, *fakeLaunches, chan struct{}) {
+	socket := SocketPath(testTempDir(t))
+	l, err := Listen(socket)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	launches := &fakeLaunches{}
+	s := NewServer(idle)
+	s.launch = launches.launch
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := s.Serve(l); err != nil {
+			t.Errorf("err: %s", err)
+		}
+	}()
+	return s, socket, launches, done
+}
+
+func testTempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "tf-host")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return dir
+}
+
+func testPlugin(t *testing.T, content string) string {
+	path := filepath.Join(testTempDir(t), "terraform-provider-test")
+	if err := ioutil.WriteFile(path, []byte(content), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return path
+}
+
+// testIntervals changes how often leases are renewed and checked, returning
+// a function that restores them.
+func testIntervals(d time.Duration) func() {
+	ttl, renew, check := leaseTTL, renewInterval, checkInterval
+	leaseTTL, renewInterval, checkInterval = 3*d, d, d/2
+	return func() {
+		leaseTTL, renewInterval, checkInterval = ttl, renew, check
+	}
+}
+
+// fakeLaunches records the fake plugin processes launched by a host.
+type fakeLaunches struct {
+	mu        sync.Mutex
+	processes []*fakeProcess
+}
+
+func (l *fakeLaunches) launch(path string, sum []byte) (process, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p := &fakeProcess{
+		mu:   &l.mu,
+		path: path,
+		attach: &plugin.ReattachConfig{
+			Protocol: plugin.ProtocolNetRPC,
+			Addr:     &net.UnixAddr{Name: path + ".sock", Net: "unix"},
+			Pid:      len(l.processes) + 1,
+		},
+	}
+	l.processes = append(l.processes, p)
+	return p, nil
+}
+
+func (l *fakeLaunches) count(path string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, p := range l.processes {
+		if p.path == path {
+			n++
+		}
+	}
+	return n
+}
+
+// exit makes the processes of the given plugin exit.
+func (l *fakeLaunches) exit(path string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, p := range l.processes {
+		if p.path == path {
+			p.exited = true
+		}
+	}
+}
+
+// killed returns true if all the processes of the given plugin that didn't
+// exit were killed.
+func (l *fakeLaunches) killed(path string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, p := range l.processes {
+		if p.path == path && !p.exited {
+			return false
+		}
+	}
+	return true
+}
+
+type fakeProcess struct {
+	mu     *sync.Mutex
+	path   string
+	attach *plugin.ReattachConfig
+	exited bool
+}
+
+func (p *fakeProcess) ReattachConfig() *plugin.ReattachConfig {
+	return p.attach
+}
+
+func (p *fakeProcess) Exited() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.exited
+}
+
+func (p *fakeProcess) Kill() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.exited = true
+}
+
+func TestServer_stop(t *testing.T) {
+	s, socket, launches, done := testServer(t, time.Hour)
+
+	path := testPlugin(t, "one")
+	c, err := Dial(socket)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer c.Close()
+	if _, err := c.Acquire(discovery.PluginMeta{Path: path}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// A stopped host stops its plugins even while commands are using it.
+	s.Stop()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("host didn't stop")
+	}
+	if !launches.killed(path) {
+		t.Fatal("plugin wasn't stopped")
+	}
+}
@@ -0,0 +1,108 @@
+package plugin
+
+import (
+	"net/rpc"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/terraform/config/module"
+)
+
+// ModuleSourcePlugin is the plugin.Plugin implementation.
+type ModuleSourcePlugin struct {
+	F func() module.SourceResolver
+}
+
+func (p *ModuleSourcePlugin) Server(b *plugin.MuxBroker) (interface{}, error) {
+	return &ModuleSourceServer{Broker: b, Resolver: p.F()}, nil
+}
+
+func (p *ModuleSourcePlugin) Client(
+	b *plugin.MuxBroker, c *rpc.Client) (interface{}, error) {
+	return &ModuleSource{Broker: b, Client: c}, nil
+}
+
+// ModuleSource is an implementation of module.SourceResolver that
+// communicates over RPC.
+//
+// The plugin runs on the same host as Terraform, so it gets modules
+// directly into the directories that Terraform gives it.
+type ModuleSource struct {
+	Broker *plugin.MuxBroker
+	Client *rpc.Client
+}
+
+func (s *ModuleSource) Versions(addr string) ([]string, error) {
+	var resp ModuleSourceVersionsResponse
+	err := s.Client.Call("Plugin.Versions", addr, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		err = resp.Error
+		return nil, err
+	}
+
+	return resp.Versions, nil
+}
+
+func (s *ModuleSource) Get(dst, addr, version string) error {
+	var resp ModuleSourceGetResponse
+	args := ModuleSourceGetArgs{
+		Dst:     dst,
+		Addr:    addr,
+		Version: version,
+	}
+
+	err := s.Client.Call("Plugin.Get", &args, &resp)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		err = resp.Error
+	}
+
+	return err
+}
+
+// ModuleSourceServer is a net/rpc compatible structure for serving
+// a module.SourceResolver. This should not be used directly.
+type ModuleSourceServer struct {
+	Broker   *plugin.MuxBroker
+	Resolver module.SourceResolver
+}
+
+type ModuleSourceVersionsResponse struct {
+	Versions []string
+	Error    *plugin.BasicError
+}
+
+type ModuleSourceGetArgs struct {
+	Dst     string
+	Addr    string
+	Version string
+}
+
+type ModuleSourceGetResponse struct {
+	Error *plugin.BasicError
+}
+
+func (s *ModuleSourceServer) Versions(
+	addr string,
+	reply *ModuleSourceVersionsResponse) error {
+	versions, err := s.Resolver.Versions(addr)
+	*reply = ModuleSourceVersionsResponse{
+		Versions: versions,
+		Error:    plugin.NewBasicError(err),
+	}
+	return nil
+}
+
+func (s *ModuleSourceServer) Get(
+	args *ModuleSourceGetArgs,
+	reply *ModuleSourceGetResponse) error {
+	err := s.Resolver.Get(args.Dst, args.Addr, args.Version)
+	*reply = ModuleSourceGetResponse{
+		Error: plugin.NewBasicError(err),
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package plugin
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/terraform/config/module"
+)
+
+func TestModuleSource_impl(t *testing.T) {
+	var _ plugin.Plugin = new(ModuleSourcePlugin)
+	var _ module.SourceResolver = new(ModuleSource)
+}
+
+func TestModuleSource(t *testing.T) {
+	r := &testModuleSource{
+		versions: []string{"1.0.0", "1.1.0"},
+	}
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ModuleSourceFunc: func() module.SourceResolver { return r },
+	}))
+	defer client.Close()
+
+	raw, err := client.Dispense(ModuleSourcePluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	remote := raw.(module.SourceResolver)
+
+	versions, err := remote.Versions("team/network")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(versions, r.versions) {
+		t.Fatalf("wrong versions: %#v", versions)
+	}
+	if r.versionsAddr != "team/network" {
+		t.Fatalf("wrong address: %q", r.versionsAddr)
+	}
+
+	if err := remote.Get("/tmp/dst", "team/network", "1.1.0"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	want := []string{"/tmp/dst", "team/network", "1.1.0"}
+	if !reflect.DeepEqual(r.getArgs, want) {
+		t.Fatalf("wrong get args: %#v", r.getArgs)
+	}
+
+	r.getErr = errors.New("unauthorized")
+	err = remote.Get("/tmp/dst", "team/network", "1.1.0")
+	if err == nil || err.Error() != "unauthorized" {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
+
+type testModuleSource struct {
+	versions     []string
+	versionsAddr string
+	getArgs      []string
+	getErr       error
+}
+
+func (r *testModuleSource) Versions(addr string) ([]string, error) {
+	r.versionsAddr = addr
+	return r.versions, nil
+}
+
+func (r *testModuleSource) Get(dst, addr, version string) error {
+	r.getArgs = []string{dst, addr, version}
+	return r.getErr
+}
@@ -8,6 +8,8 @@ import (
 
 // PluginMap should be used by clients for the map of plugins.
 var PluginMap = map[string]plugin.Plugin{
+	"backend":     &BackendPlugin{},
+	"modsource":   &ModuleSourcePlugin{},
 	"provider":    &ResourceProviderPlugin{},
 	"provisioner": &ResourceProvisionerPlugin{},
 }
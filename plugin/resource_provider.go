@@ -1,7 +1,13 @@
 package plugin
 
 import (
+	"context"
+	"fmt"
+	"log"
 	"net/rpc"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/hashicorp/go-plugin"
 	"github.com/hashicorp/terraform/terraform"
@@ -166,6 +172,83 @@ func (p *ResourceProvider) Apply(
 	return resp.State, err
 }
 
+// applyCallID is the last ID given to a call of ApplyContext, so that the
+// plugin can tell which apply to cancel.
+var applyCallID uint64
+
+// ApplyContext is like Apply, but cancels the apply in the plugin if the
+// given context is cancelled before it returns, and then waits for the
+// plugin to return the partial state.
+func (p *ResourceProvider) ApplyContext(
+	ctx context.Context,
+	info *terraform.InstanceInfo,
+	s *terraform.InstanceState,
+	d *terraform.InstanceDiff) (*terraform.InstanceState, error) {
+	var resp ResourceProviderApplyResponse
+	args := &ResourceProviderApplyArgs{
+		Info:   info,
+		State:  s,
+		Diff:   d,
+		CallID: atomic.AddUint64(&applyCallID, 1),
+	}
+
+	call := p.Client.Go("Plugin.Apply", args, &resp, nil)
+	select {
+	case <-call.Done:
+	case <-ctx.Done():
+		// Plugins built before CancelApply was added don't have it, in
+		// which case the apply just runs to completion.
+		cancelArgs := &ResourceProviderCancelApplyArgs{CallID: args.CallID}
+		var cancelResp ResourceProviderCancelApplyResponse
+		if err := p.Client.Call("Plugin.CancelApply", cancelArgs, &cancelResp); err != nil {
+			log.Printf("[WARN] plugin: failed to cancel apply of %s: %s", info.Id, err)
+		}
+		<-call.Done
+	}
+	if call.Error != nil {
+		return nil, call.Error
+	}
+
+	var err error
+	if resp.Error != nil {
+		err = resp.Error
+	}
+	return resp.State, err
+}
+
+func (p *ResourceProvider) ValidateApply(
+	info *terraform.InstanceInfo,
+	s *terraform.InstanceState,
+	d *terraform.InstanceDiff) ([]string, []error) {
+	var resp ResourceProviderValidateApplyResponse
+	args := &ResourceProviderValidateApplyArgs{
+		Info:  info,
+		State: s,
+		Diff:  d,
+	}
+
+	err := p.Client.Call("Plugin.ValidateApply", args, &resp)
+	if err != nil {
+		// Providers built before ValidateApply existed can't check
+		// changes, which isn't an error.
+		if _, ok := err.(rpc.ServerError); ok && strings.Contains(err.Error(), "can't find method") {
+			log.Printf("[DEBUG] plugin: provider doesn't support ValidateApply")
+			return nil, nil
+		}
+		return nil, []error{err}
+	}
+
+	var errs []error
+	if len(resp.Errors) > 0 {
+		errs = make([]error, len(resp.Errors))
+		for i, err := range resp.Errors {
+			errs[i] = err
+		}
+	}
+
+	return resp.Warnings, errs
+}
+
 func (p *ResourceProvider) Diff(
 	info *terraform.InstanceInfo,
 	s *terraform.InstanceState,
@@ -231,6 +314,26 @@ func (p *ResourceProvider) Refresh(
 	return resp.State, err
 }
 
+func (p *ResourceProvider) RefreshBatch(
+	infos []*terraform.InstanceInfo,
+	states []*terraform.InstanceState) ([]*terraform.InstanceState, error) {
+	var resp ResourceProviderRefreshBatchResponse
+	args := &ResourceProviderRefreshBatchArgs{
+		Infos:  infos,
+		States: states,
+	}
+
+	err := p.Client.Call("Plugin.RefreshBatch", args, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		err = resp.Error
+	}
+
+	return resp.States, err
+}
+
 func (p *ResourceProvider) ImportState(
 	info *terraform.InstanceInfo,
 	id string) ([]*terraform.InstanceState, error) {
@@ -303,6 +406,116 @@ func (p *ResourceProvider) ReadDataApply(
 	return resp.State, err
 }
 
+func (p *ResourceProvider) OpenEphemeral(
+	info *terraform.InstanceInfo,
+	c *terraform.ResourceConfig) (*terraform.InstanceState, error) {
+	var resp ResourceProviderOpenEphemeralResponse
+	args := &ResourceProviderOpenEphemeralArgs{
+		Info:   info,
+		Config: c,
+	}
+
+	err := p.Client.Call("Plugin.OpenEphemeral", args, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		err = resp.Error
+	}
+
+	return resp.State, err
+}
+
+func (p *ResourceProvider) CloseEphemeral(
+	info *terraform.InstanceInfo,
+	s *terraform.InstanceState) error {
+	var resp ResourceProviderCloseEphemeralResponse
+	args := &ResourceProviderCloseEphemeralArgs{
+		Info:  info,
+		State: s,
+	}
+
+	err := p.Client.Call("Plugin.CloseEphemeral", args, &resp)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		err = resp.Error
+	}
+
+	return err
+}
+
+func (p *ResourceProvider) Functions() []terraform.ProviderFunction {
+	var result []terraform.ProviderFunction
+
+	err := p.Client.Call("Plugin.Functions", new(interface{}), &result)
+	if err != nil {
+		// TODO: panic, log, what?
+		return nil
+	}
+
+	return result
+}
+
+func (p *ResourceProvider) CallFunction(
+	name string,
+	funcArgs []interface{}) (interface{}, error) {
+	var resp ResourceProviderCallFunctionResponse
+	args := &ResourceProviderCallFunctionArgs{
+		Name: name,
+		Args: funcArgs,
+	}
+
+	err := p.Client.Call("Plugin.CallFunction", args, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		err = resp.Error
+	}
+
+	return resp.Result, err
+}
+
+func (p *ResourceProvider) MoveResourceState(
+	req *terraform.MoveResourceStateRequest) (*terraform.InstanceState, error) {
+	var resp ResourceProviderMoveResourceStateResponse
+
+	err := p.Client.Call("Plugin.MoveResourceState", req, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		err = resp.Error
+	}
+
+	return resp.State, err
+}
+
+func (p *ResourceProvider) Capabilities() ([]terraform.ProviderCapability, error) {
+	var resp ResourceProviderCapabilitiesResponse
+	args := &ResourceProviderCapabilitiesArgs{
+		Capabilities: terraform.AllProviderCapabilities,
+	}
+
+	err := p.Client.Call("Plugin.Capabilities", args, &resp)
+	if err != nil {
+		// Providers built before capabilities could be negotiated support
+		// none of them.
+		if _, ok := err.(rpc.ServerError); ok && strings.Contains(err.Error(), "can't find method") {
+			log.Printf("[DEBUG] plugin: provider doesn't support Capabilities")
+			return nil, nil
+		}
+		return nil, err
+	}
+	if resp.Error != nil {
+		err = resp.Error
+	}
+
+	return resp.Capabilities, err
+}
+
 func (p *ResourceProvider) DataSources() []terraform.DataSource {
 	var result []terraform.DataSource
 
@@ -324,6 +537,11 @@ func (p *ResourceProvider) Close() error {
 type ResourceProviderServer struct {
 	Broker   *plugin.MuxBroker
 	Provider terraform.ResourceProvider
+
+	// applyCancels cancel the applies in progress that were started by
+	// ApplyContext, keyed by their call IDs.
+	applyCancelsLock sync.Mutex
+	applyCancels     map[uint64]context.CancelFunc
 }
 
 type ResourceProviderStopResponse struct {
@@ -357,6 +575,18 @@ type ResourceProviderApplyArgs struct {
 	Info  *terraform.InstanceInfo
 	State *terraform.InstanceState
 	Diff  *terraform.InstanceDiff
+
+	// CallID identifies an apply that can be cancelled with CancelApply,
+	// or is zero.
+	CallID uint64
+}
+
+type ResourceProviderCancelApplyArgs struct {
+	CallID uint64
+}
+
+type ResourceProviderCancelApplyResponse struct {
+	Error *plugin.BasicError
 }
 
 type ResourceProviderApplyResponse struct {
@@ -364,6 +594,17 @@ type ResourceProviderApplyResponse struct {
 	Error *plugin.BasicError
 }
 
+type ResourceProviderValidateApplyArgs struct {
+	Info  *terraform.InstanceInfo
+	State *terraform.InstanceState
+	Diff  *terraform.InstanceDiff
+}
+
+type ResourceProviderValidateApplyResponse struct {
+	Warnings []string
+	Errors   []*plugin.BasicError
+}
+
 type ResourceProviderDiffArgs struct {
 	Info   *terraform.InstanceInfo
 	State  *terraform.InstanceState
@@ -385,6 +626,16 @@ type ResourceProviderRefreshResponse struct {
 	Error *plugin.BasicError
 }
 
+type ResourceProviderRefreshBatchArgs struct {
+	Infos  []*terraform.InstanceInfo
+	States []*terraform.InstanceState
+}
+
+type ResourceProviderRefreshBatchResponse struct {
+	States []*terraform.InstanceState
+	Error  *plugin.BasicError
+}
+
 type ResourceProviderImportStateArgs struct {
 	Info *terraform.InstanceInfo
 	Id   string
@@ -415,6 +666,53 @@ type ResourceProviderReadDataDiffResponse struct {
 	Error *plugin.BasicError
 }
 
+type ResourceProviderOpenEphemeralArgs struct {
+	Info   *terraform.InstanceInfo
+	Config *terraform.ResourceConfig
+}
+
+type ResourceProviderOpenEphemeralResponse struct {
+	State *terraform.InstanceState
+	Error *plugin.BasicError
+}
+
+type ResourceProviderCloseEphemeralArgs struct {
+	Info  *terraform.InstanceInfo
+	State *terraform.InstanceState
+}
+
+type ResourceProviderCloseEphemeralResponse struct {
+	Error *plugin.BasicError
+}
+
+type ResourceProviderCallFunctionArgs struct {
+	Name string
+	Args []interface{}
+}
+
+type ResourceProviderCallFunctionResponse struct {
+	Result interface{}
+	Error  *plugin.BasicError
+}
+
+type ResourceProviderMoveResourceStateResponse struct {
+	State *terraform.InstanceState
+	Error *plugin.BasicError
+}
+
+// ResourceProviderCapabilitiesArgs are the capabilities that the client
+// supports. It's a struct, rather than nothing as for the other calls
+// without arguments, since otherwise a server that doesn't support the call
+// can't discard its arguments and respond.
+type ResourceProviderCapabilitiesArgs struct {
+	Capabilities []terraform.ProviderCapability
+}
+
+type ResourceProviderCapabilitiesResponse struct {
+	Capabilities []terraform.ProviderCapability
+	Error        *plugin.BasicError
+}
+
 type ResourceProviderValidateArgs struct {
 	Config *terraform.ResourceConfig
 }
@@ -524,7 +822,26 @@ func (s *ResourceProviderServer) Configure(
 func (s *ResourceProviderServer) Apply(
 	args *ResourceProviderApplyArgs,
 	result *ResourceProviderApplyResponse) error {
-	state, err := s.Provider.Apply(args.Info, args.State, args.Diff)
+	var state *terraform.InstanceState
+	var err error
+	if p, ok := s.Provider.(terraform.ResourceProviderContextApplier); ok && args.CallID != 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		s.applyCancelsLock.Lock()
+		if s.applyCancels == nil {
+			s.applyCancels = make(map[uint64]context.CancelFunc)
+		}
+		s.applyCancels[args.CallID] = cancel
+		s.applyCancelsLock.Unlock()
+
+		state, err = p.ApplyContext(ctx, args.Info, args.State, args.Diff)
+
+		s.applyCancelsLock.Lock()
+		delete(s.applyCancels, args.CallID)
+		s.applyCancelsLock.Unlock()
+		cancel()
+	} else {
+		state, err = s.Provider.Apply(args.Info, args.State, args.Diff)
+	}
 	*result = ResourceProviderApplyResponse{
 		State: state,
 		Error: plugin.NewBasicError(err),
@@ -532,6 +849,35 @@ func (s *ResourceProviderServer) Apply(
 	return nil
 }
 
+// CancelApply cancels the apply in progress with the given call ID, if
+// there is one.
+func (s *ResourceProviderServer) CancelApply(
+	args *ResourceProviderCancelApplyArgs,
+	reply *ResourceProviderCancelApplyResponse) error {
+	s.applyCancelsLock.Lock()
+	cancel, ok := s.applyCancels[args.CallID]
+	s.applyCancelsLock.Unlock()
+	if ok {
+		cancel()
+	}
+	return nil
+}
+
+func (s *ResourceProviderServer) ValidateApply(
+	args *ResourceProviderValidateApplyArgs,
+	reply *ResourceProviderValidateApplyResponse) error {
+	warns, errs := s.Provider.ValidateApply(args.Info, args.State, args.Diff)
+	berrs := make([]*plugin.BasicError, len(errs))
+	for i, err := range errs {
+		berrs[i] = plugin.NewBasicError(err)
+	}
+	*reply = ResourceProviderValidateApplyResponse{
+		Warnings: warns,
+		Errors:   berrs,
+	}
+	return nil
+}
+
 func (s *ResourceProviderServer) Diff(
 	args *ResourceProviderDiffArgs,
 	result *ResourceProviderDiffResponse) error {
@@ -554,6 +900,26 @@ func (s *ResourceProviderServer) Refresh(
 	return nil
 }
 
+func (s *ResourceProviderServer) RefreshBatch(
+	args *ResourceProviderRefreshBatchArgs,
+	result *ResourceProviderRefreshBatchResponse) error {
+	b, ok := s.Provider.(terraform.ResourceProviderBatchRefresher)
+	if !ok {
+		*result = ResourceProviderRefreshBatchResponse{
+			Error: plugin.NewBasicError(fmt.Errorf(
+				"provider doesn't support refreshing instances in batches")),
+		}
+		return nil
+	}
+
+	states, err := b.RefreshBatch(args.Infos, args.States)
+	*result = ResourceProviderRefreshBatchResponse{
+		States: states,
+		Error:  plugin.NewBasicError(err),
+	}
+	return nil
+}
+
 func (s *ResourceProviderServer) ImportState(
 	args *ResourceProviderImportStateArgs,
 	result *ResourceProviderImportStateResponse) error {
@@ -609,6 +975,90 @@ func (s *ResourceProviderServer) ReadDataApply(
 	return nil
 }
 
+func (s *ResourceProviderServer) OpenEphemeral(
+	args *ResourceProviderOpenEphemeralArgs,
+	result *ResourceProviderOpenEphemeralResponse) error {
+	state, err := s.Provider.OpenEphemeral(args.Info, args.Config)
+	*result = ResourceProviderOpenEphemeralResponse{
+		State: state,
+		Error: plugin.NewBasicError(err),
+	}
+	return nil
+}
+
+func (s *ResourceProviderServer) CloseEphemeral(
+	args *ResourceProviderCloseEphemeralArgs,
+	result *ResourceProviderCloseEphemeralResponse) error {
+	err := s.Provider.CloseEphemeral(args.Info, args.State)
+	*result = ResourceProviderCloseEphemeralResponse{
+		Error: plugin.NewBasicError(err),
+	}
+	return nil
+}
+
+func (s *ResourceProviderServer) Functions(
+	nothing interface{},
+	result *[]terraform.ProviderFunction) error {
+	*result = s.Provider.Functions()
+	return nil
+}
+
+func (s *ResourceProviderServer) CallFunction(
+	args *ResourceProviderCallFunctionArgs,
+	result *ResourceProviderCallFunctionResponse) error {
+	v, err := s.Provider.CallFunction(args.Name, args.Args)
+	*result = ResourceProviderCallFunctionResponse{
+		Result: v,
+		Error:  plugin.NewBasicError(err),
+	}
+	return nil
+}
+
+func (s *ResourceProviderServer) MoveResourceState(
+	req *terraform.MoveResourceStateRequest,
+	result *ResourceProviderMoveResourceStateResponse) error {
+	m, ok := s.Provider.(terraform.ResourceProviderStateMover)
+	if !ok {
+		*result = ResourceProviderMoveResourceStateResponse{
+			Error: plugin.NewBasicError(fmt.Errorf(
+				"provider doesn't support moving state from other resource types")),
+		}
+		return nil
+	}
+
+	state, err := m.MoveResourceState(req)
+	*result = ResourceProviderMoveResourceStateResponse{
+		State: state,
+		Error: plugin.NewBasicError(err),
+	}
+	return nil
+}
+
+// Capabilities returns the capabilities that both the provider and the
+// client support.
+func (s *ResourceProviderServer) Capabilities(
+	args *ResourceProviderCapabilitiesArgs,
+	result *ResourceProviderCapabilitiesResponse) error {
+	caps, err := terraform.ProviderCapabilitiesOf(s.Provider)
+
+	known := make(map[terraform.ProviderCapability]bool)
+	for _, c := range args.Capabilities {
+		known[c] = true
+	}
+	var supported []terraform.ProviderCapability
+	for _, c := range caps {
+		if known[c] {
+			supported = append(supported, c)
+		}
+	}
+
+	*result = ResourceProviderCapabilitiesResponse{
+		Capabilities: supported,
+		Error:        plugin.NewBasicError(err),
+	}
+	return nil
+}
+
 func (s *ResourceProviderServer) DataSources(
 	nothing interface{},
 	result *[]terraform.DataSource) error {
@@ -1,11 +1,16 @@
 package plugin
 
 import (
+	"context"
 	"errors"
+	"net"
+	"net/rpc"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/hil/ast"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -249,6 +254,268 @@ func TestResourceProvider_apply(t *testing.T) {
 	}
 }
 
+func TestResourceProvider_applyContextCancel(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+	startedCh := make(chan struct{})
+	p.ApplyContextFn = func(
+		ctx context.Context,
+		info *terraform.InstanceInfo,
+		s *terraform.InstanceState,
+		d *terraform.InstanceDiff) (*terraform.InstanceState, error) {
+		close(startedCh)
+		<-ctx.Done()
+		return &terraform.InstanceState{ID: "partial"}, ctx.Err()
+	}
+
+	// Create a mock provider
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(p),
+	}))
+	defer client.Close()
+
+	// Request the provider
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProviderContextApplier)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-startedCh
+		cancel()
+	}()
+
+	info := &terraform.InstanceInfo{}
+	state := &terraform.InstanceState{}
+	diff := &terraform.InstanceDiff{}
+	newState, err := provider.ApplyContext(ctx, info, state, diff)
+	if err == nil || err.Error() != context.Canceled.Error() {
+		t.Fatalf("bad: %#v", err)
+	}
+	if newState == nil || newState.ID != "partial" {
+		t.Fatalf("bad: %#v", newState)
+	}
+}
+
+func TestResourceProvider_validateApply(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+
+	// Create a mock provider
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(p),
+	}))
+	defer client.Close()
+
+	// Request the provider
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProvider)
+
+	p.ValidateApplyReturnWarns = []string{"warn"}
+	p.ValidateApplyReturnErrors = []error{errors.New("quota exceeded")}
+
+	info := &terraform.InstanceInfo{Type: "foo"}
+	state := &terraform.InstanceState{ID: "bob"}
+	diff := &terraform.InstanceDiff{}
+	w, e := provider.ValidateApply(info, state, diff)
+	if !p.ValidateApplyCalled {
+		t.Fatal("validate apply should be called")
+	}
+	if !reflect.DeepEqual(p.ValidateApplyState, state) {
+		t.Fatalf("bad: %#v", p.ValidateApplyState)
+	}
+	if !reflect.DeepEqual(w, []string{"warn"}) {
+		t.Fatalf("bad: %#v", w)
+	}
+	if len(e) != 1 || e[0].Error() != "quota exceeded" {
+		t.Fatalf("bad: %#v", e)
+	}
+}
+
+// mockBatchRefreshResourceProvider is a MockResourceProvider that can
+// refresh instances in batches.
+type mockBatchRefreshResourceProvider struct {
+	*terraform.MockResourceProvider
+
+	RefreshBatchInfos  []*terraform.InstanceInfo
+	RefreshBatchStates []*terraform.InstanceState
+	RefreshBatchReturn []*terraform.InstanceState
+}
+
+func (p *mockBatchRefreshResourceProvider) RefreshBatch(
+	infos []*terraform.InstanceInfo,
+	states []*terraform.InstanceState) ([]*terraform.InstanceState, error) {
+	p.RefreshBatchInfos = infos
+	p.RefreshBatchStates = states
+	return p.RefreshBatchReturn, nil
+}
+
+func TestResourceProvider_capabilities(t *testing.T) {
+	p := &mockBatchRefreshResourceProvider{
+		MockResourceProvider: new(terraform.MockResourceProvider),
+	}
+
+	// Create a mock provider
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(p),
+	}))
+	defer client.Close()
+
+	// Request the provider
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProviderCapabilities)
+
+	caps, err := provider.Capabilities()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(caps, terraform.AllProviderCapabilities) {
+		t.Fatalf("bad: %#v", caps)
+	}
+}
+
+func TestResourceProvider_refreshBatch(t *testing.T) {
+	p := &mockBatchRefreshResourceProvider{
+		MockResourceProvider: new(terraform.MockResourceProvider),
+	}
+
+	// Create a mock provider
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(p),
+	}))
+	defer client.Close()
+
+	// Request the provider
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProviderBatchRefresher)
+
+	p.RefreshBatchReturn = []*terraform.InstanceState{{ID: "bob"}, {ID: "alice"}}
+
+	infos := []*terraform.InstanceInfo{{Id: "foo.0"}, {Id: "foo.1"}}
+	states := []*terraform.InstanceState{{ID: "bob"}, {ID: "alice"}}
+	result, err := provider.RefreshBatch(infos, states)
+	if err != nil {
+		t.Fatalf("bad: %#v", err)
+	}
+	if !reflect.DeepEqual(p.RefreshBatchInfos, infos) {
+		t.Fatalf("bad: %#v", p.RefreshBatchInfos)
+	}
+	if !reflect.DeepEqual(p.RefreshBatchStates, states) {
+		t.Fatalf("bad: %#v", p.RefreshBatchStates)
+	}
+	if !reflect.DeepEqual(result, p.RefreshBatchReturn) {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestResourceProvider_refreshBatchUnsupported(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+
+	// Create a mock provider
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(p),
+	}))
+	defer client.Close()
+
+	// Request the provider
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProviderBatchRefresher)
+
+	_, err = provider.RefreshBatch(
+		[]*terraform.InstanceInfo{{Id: "foo.0"}},
+		[]*terraform.InstanceState{{ID: "bob"}})
+	if err == nil || !strings.Contains(err.Error(), "doesn't support refreshing instances in batches") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestResourceProvider_moveResourceState(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+
+	// Create a mock provider
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(p),
+	}))
+	defer client.Close()
+
+	// Request the provider
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProviderStateMover)
+
+	p.MoveResourceStateReturn = &terraform.InstanceState{ID: "bob"}
+
+	req := &terraform.MoveResourceStateRequest{
+		SourceProvider: "foo",
+		SourceType:     "foo_instance",
+		SourceState:    &terraform.InstanceState{ID: "bob"},
+		TargetType:     "bar_instance",
+	}
+	state, err := provider.MoveResourceState(req)
+	if !p.MoveResourceStateCalled {
+		t.Fatal("move resource state should be called")
+	}
+	if !reflect.DeepEqual(p.MoveResourceStateRequest, req) {
+		t.Fatalf("bad: %#v", p.MoveResourceStateRequest)
+	}
+	if err != nil {
+		t.Fatalf("bad: %#v", err)
+	}
+	if !reflect.DeepEqual(state, p.MoveResourceStateReturn) {
+		t.Fatalf("bad: %#v", state)
+	}
+}
+
+// legacyResourceProviderServer is a server of a provider built before
+// capabilities could be negotiated.
+type legacyResourceProviderServer struct{}
+
+func (s *legacyResourceProviderServer) Stop(_ interface{}, reply *error) error {
+	return nil
+}
+
+func TestResourceProvider_capabilitiesLegacy(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("Plugin", new(legacyResourceProviderServer)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer l.Close()
+	go server.Accept(l)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := &ResourceProvider{Client: rpc.NewClient(conn)}
+	defer provider.Close()
+
+	caps, err := provider.Capabilities()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(caps) != 0 {
+		t.Fatalf("bad: %#v", caps)
+	}
+}
+
 func TestResourceProvider_diff(t *testing.T) {
 	p := new(terraform.MockResourceProvider)
 
@@ -482,6 +749,124 @@ func TestResourceProvider_readdataapply(t *testing.T) {
 	}
 }
 
+func TestResourceProvider_openEphemeral(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+
+	// Create a mock provider
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(p),
+	}))
+	defer client.Close()
+
+	// Request the provider
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProvider)
+
+	p.OpenEphemeralReturn = &terraform.InstanceState{
+		ID: "bob",
+		Attributes: map[string]string{
+			"token": "secret",
+		},
+	}
+
+	// Open
+	info := &terraform.InstanceInfo{Type: "test_token"}
+	config := &terraform.ResourceConfig{
+		Raw: map[string]interface{}{"ttl": "1h"},
+	}
+	state, err := provider.OpenEphemeral(info, config)
+	if !p.OpenEphemeralCalled {
+		t.Fatal("OpenEphemeral should be called")
+	}
+	if !reflect.DeepEqual(p.OpenEphemeralConfig, config) {
+		t.Fatalf("bad: %#v", p.OpenEphemeralConfig)
+	}
+	if err != nil {
+		t.Fatalf("bad: %#v", err)
+	}
+	if !reflect.DeepEqual(p.OpenEphemeralReturn, state) {
+		t.Fatalf("bad: %#v", state)
+	}
+
+	// Close
+	if err := provider.CloseEphemeral(info, state); err != nil {
+		t.Fatalf("bad: %#v", err)
+	}
+	if !p.CloseEphemeralCalled {
+		t.Fatal("CloseEphemeral should be called")
+	}
+	if !reflect.DeepEqual(p.CloseEphemeralState, state) {
+		t.Fatalf("bad: %#v", p.CloseEphemeralState)
+	}
+}
+
+func TestResourceProvider_functions(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+
+	// Create a mock provider
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(p),
+	}))
+	defer client.Close()
+
+	// Request the provider
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProvider)
+
+	expected := []terraform.ProviderFunction{
+		{
+			Name: "join_path",
+			Parameters: []terraform.FunctionParameter{
+				{Name: "parts", Type: ast.TypeList},
+			},
+			VariadicParameter: &terraform.FunctionParameter{Name: "more", Type: ast.TypeString},
+			ReturnType:        ast.TypeString,
+		},
+	}
+	p.FunctionsReturn = expected
+
+	// Functions
+	result := provider.Functions()
+	if !p.FunctionsCalled {
+		t.Fatal("Functions should be called")
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	// CallFunction
+	p.CallFunctionReturn = "a/b/c"
+	args := []interface{}{[]interface{}{"a", "b"}, "c"}
+	v, err := provider.CallFunction("join_path", args)
+	if err != nil {
+		t.Fatalf("bad: %#v", err)
+	}
+	if !p.CallFunctionCalled {
+		t.Fatal("CallFunction should be called")
+	}
+	if p.CallFunctionName != "join_path" {
+		t.Fatalf("bad: %#v", p.CallFunctionName)
+	}
+	if !reflect.DeepEqual(p.CallFunctionArgs, args) {
+		t.Fatalf("bad: %#v", p.CallFunctionArgs)
+	}
+	if v != "a/b/c" {
+		t.Fatalf("bad: %#v", v)
+	}
+
+	// CallFunction with an error
+	p.CallFunctionReturnError = errors.New("bad path")
+	if _, err := provider.CallFunction("join_path", args); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
 func TestResourceProvider_datasources(t *testing.T) {
 	p := new(terraform.MockResourceProvider)
 
@@ -2,14 +2,18 @@ package plugin
 
 import (
 	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/config/module"
 	"github.com/hashicorp/terraform/terraform"
 )
 
 // The constants below are the names of the plugins that can be dispensed
 // from the plugin server.
 const (
-	ProviderPluginName    = "provider"
-	ProvisionerPluginName = "provisioner"
+	BackendPluginName      = "backend"
+	ModuleSourcePluginName = "modsource"
+	ProviderPluginName     = "provider"
+	ProvisionerPluginName  = "provisioner"
 )
 
 // Handshake is the HandshakeConfig used to configure clients and servers.
@@ -26,13 +30,17 @@ var Handshake = plugin.HandshakeConfig{
 	MagicCookieValue: "d602bf8f470bc67ca7faa0386276bbdd4330efaf76d1a219cb4d6991ca9872b2",
 }
 
+type BackendFunc func() backend.Backend
+type ModuleSourceFunc func() module.SourceResolver
 type ProviderFunc func() terraform.ResourceProvider
 type ProvisionerFunc func() terraform.ResourceProvisioner
 
 // ServeOpts are the configurations to serve a plugin.
 type ServeOpts struct {
-	ProviderFunc    ProviderFunc
-	ProvisionerFunc ProvisionerFunc
+	BackendFunc      BackendFunc
+	ModuleSourceFunc ModuleSourceFunc
+	ProviderFunc     ProviderFunc
+	ProvisionerFunc  ProvisionerFunc
 }
 
 // Serve serves a plugin. This function never returns and should be the final
@@ -48,6 +56,8 @@ func Serve(opts *ServeOpts) {
 // server or client.
 func pluginMap(opts *ServeOpts) map[string]plugin.Plugin {
 	return map[string]plugin.Plugin{
+		"backend":     &BackendPlugin{F: opts.BackendFunc},
+		"modsource":   &ModuleSourcePlugin{F: opts.ModuleSourceFunc},
 		"provider":    &ResourceProviderPlugin{F: opts.ProviderFunc},
 		"provisioner": &ResourceProvisionerPlugin{F: opts.ProvisionerFunc},
 	}
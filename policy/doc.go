@@ -0,0 +1,8 @@
+// Package policy checks plans against user-supplied policies before they
+// are applied.
+//
+// A policy is checked by a Hook, which is given the plan in the JSON form
+// of "terraform plan -json" and returns a verdict: the plan passes, it
+// soft-fails and may only be applied if the user overrides the policy, or
+// it hard-fails and must not be applied.
+package policy
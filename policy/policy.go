@@ -0,0 +1,91 @@
+package policy
+
+import (
+	"fmt"
+)
+
+// Verdict is the outcome of checking a plan against a policy.
+type Verdict string
+
+const (
+	Pass Verdict = "pass"
+
+	// SoftFail means that the plan violates the policy, but that it may
+	// still be applied if the user overrides the policy.
+	SoftFail Verdict = "soft-fail"
+
+	// HardFail means that the plan violates the policy and must not be
+	// applied.
+	HardFail Verdict = "hard-fail"
+)
+
+// Valid returns true if v is one of the known verdicts.
+func (v Verdict) Valid() bool {
+	switch v {
+	case Pass, SoftFail, HardFail:
+		return true
+	default:
+		return false
+	}
+}
+
+// Result is the result of checking a plan with a hook.
+type Result struct {
+	// Hook is the name of the hook that produced the result.
+	Hook string
+
+	Verdict Verdict
+
+	// Messages explain the verdict, typically by describing the changes
+	// that violate the policy.
+	Messages []string
+}
+
+// Hook is the interface implemented by policy checks.
+type Hook interface {
+	// Name returns the name of the hook, for use in messages.
+	Name() string
+
+	// Check checks the given plan, in the JSON form of "terraform plan
+	// -json". An error means that the plan couldn't be checked.
+	Check(plan []byte) (*Result, error)
+}
+
+// Results are the results of checking a plan with several hooks.
+type Results []*Result
+
+// Verdict returns the strictest verdict of the results, or Pass if there
+// are none.
+func (rs Results) Verdict() Verdict {
+	verdict := Pass
+	for _, r := range rs {
+		switch r.Verdict {
+		case HardFail:
+			return HardFail
+		case SoftFail:
+			verdict = SoftFail
+		}
+	}
+	return verdict
+}
+
+// Check checks the given plan with each of the given hooks, in order.
+//
+// A hook that fails to check the plan gives a HardFail result with the
+// error as its message, so that a plan is never applied without its
+// policies having been checked.
+func Check(hooks []Hook, plan []byte) Results {
+	results := make(Results, 0, len(hooks))
+	for _, h := range hooks {
+		r, err := h.Check(plan)
+		if err != nil {
+			r = &Result{
+				Verdict:  HardFail,
+				Messages: []string{fmt.Sprintf("Error checking policy: %s", err)},
+			}
+		}
+		r.Hook = h.Name()
+		results = append(results, r)
+	}
+	return results
+}
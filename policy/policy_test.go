@@ -0,0 +1,115 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	program := testHookProgram(t)
+
+	cases := map[string]struct {
+		Hooks    []Hook
+		Plan     string
+		Verdict  Verdict
+		Messages []string
+	}{
+		"no hooks": {
+			nil,
+			`{"resource_changes":[]}`,
+			Pass,
+			nil,
+		},
+		"pass": {
+			[]Hook{ProgramHook("a", "/bin/sh", program, "pass")},
+			`{"resource_changes":[]}`,
+			Pass,
+			nil,
+		},
+		"soft-fail": {
+			[]Hook{
+				ProgramHook("a", "/bin/sh", program, "pass"),
+				ProgramHook("b", "/bin/sh", program, "soft-fail"),
+			},
+			`{"resource_changes":[]}`,
+			SoftFail,
+			[]string{"b: this is a warning"},
+		},
+		"hard-fail from plan": {
+			[]Hook{ProgramHook("a", "/bin/sh", program, "soft-fail")},
+			`{"resource_changes":[{"address":"test_instance.deny"}]}`,
+			HardFail,
+			[]string{"a: test_instance.deny must not be created"},
+		},
+		"error": {
+			[]Hook{
+				ProgramHook("a", "/bin/sh", program, "fail"),
+				ProgramHook("b", "/bin/sh", program, "soft-fail"),
+			},
+			`{"resource_changes":[]}`,
+			HardFail,
+			[]string{
+				"a: Error checking policy: error in /bin/sh: failing because you told me to fail\n",
+				"b: this is a warning",
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			results := Check(tc.Hooks, []byte(tc.Plan))
+			if len(results) != len(tc.Hooks) {
+				t.Fatalf("got %d results, want %d", len(results), len(tc.Hooks))
+			}
+			if got := results.Verdict(); got != tc.Verdict {
+				t.Errorf("wrong verdict %q; want %q", got, tc.Verdict)
+			}
+
+			var messages []string
+			for _, r := range results {
+				for _, msg := range r.Messages {
+					messages = append(messages, r.Hook+": "+msg)
+				}
+			}
+			if !reflect.DeepEqual(messages, tc.Messages) {
+				t.Errorf("wrong messages\ngot:  %#v\nwant: %#v", messages, tc.Messages)
+			}
+		})
+	}
+}
+
+func TestProgramHook_badOutput(t *testing.T) {
+	program := testHookProgram(t)
+
+	cases := map[string]string{
+		"invalid":   `invalid verdict "maybe"`,
+		"malformed": "malformed output",
+	}
+
+	for arg, want := range cases {
+		t.Run(arg, func(t *testing.T) {
+			_, err := ProgramHook("a", "/bin/sh", program, arg).Check([]byte("{}"))
+			if err == nil {
+				t.Fatal("succeeded; want error")
+			}
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("wrong error %q; want it to contain %q", err, want)
+			}
+		})
+	}
+}
+
+func testHookProgram(t *testing.T) string {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("policy hook tests require /bin/sh")
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(wd, "test-fixtures/hook.sh")
+}
@@ -0,0 +1,50 @@
+package policy
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/helper/program"
+)
+
+type programHook struct {
+	name    string
+	program *program.Program
+}
+
+// ProgramHook returns a Hook that runs the given program with the given
+// arguments in order to check a plan. The executable path must be
+// absolute, as for program.New.
+//
+// The plan is written to the standard input of the program, which must
+// write a JSON object to its standard output with the properties
+// "verdict", one of "pass", "soft-fail" or "hard-fail", and optionally
+// "messages", a list of strings explaining the verdict. If the program
+// exits with a non-zero status, the plan couldn't be checked.
+func ProgramHook(name, executable string, args ...string) Hook {
+	return &programHook{
+		name:    name,
+		program: program.New("ProgramHook", executable, args...),
+	}
+}
+
+func (h *programHook) Name() string {
+	return h.name
+}
+
+func (h *programHook) Check(plan []byte) (*Result, error) {
+	var out struct {
+		Verdict  Verdict  `json:"verdict"`
+		Messages []string `json:"messages"`
+	}
+	if err := h.program.RunJSON(plan, &out); err != nil {
+		return nil, err
+	}
+	if !out.Verdict.Valid() {
+		return nil, fmt.Errorf("invalid verdict %q from %s", out.Verdict, h.program.Path)
+	}
+
+	return &Result{
+		Verdict:  out.Verdict,
+		Messages: out.Messages,
+	}, nil
+}
@@ -0,0 +1,27 @@
+#!/bin/sh
+# A policy program for testing, which gives the verdict named by its first
+# argument. A plan that creates a "deny" resource always hard-fails.
+
+if grep -q '"address":"test_instance.deny"' -; then
+  echo '{"verdict":"hard-fail","messages":["test_instance.deny must not be created"]}'
+  exit 0
+fi
+
+case "$1" in
+  pass)
+    echo '{"verdict":"pass"}'
+    ;;
+  soft-fail)
+    echo '{"verdict":"soft-fail","messages":["this is a warning"]}'
+    ;;
+  invalid)
+    echo '{"verdict":"maybe"}'
+    ;;
+  malformed)
+    echo 'not json'
+    ;;
+  *)
+    echo "failing because you told me to fail" >&2
+    exit 1
+    ;;
+esac
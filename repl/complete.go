@@ -0,0 +1,158 @@
+package repl
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// Complete returns the completions of the reference that ends at the end of
+// the given input, such as "aws_inst" or "var.", and the length of that
+// partial reference. Each completion is a whole reference that starts with
+// the partial one, and they're sorted.
+//
+// The references that are completed are the addresses of the resources in
+// the state of the root module and their attributes, the outputs of its
+// child modules, and the names of its variables, locals and modules.
+func (s *Session) Complete(input string) ([]string, int) {
+	partial := input[partialStart(input):]
+
+	var result []string
+	for _, c := range s.completions(partial) {
+		if strings.HasPrefix(c, partial) {
+			result = append(result, c)
+		}
+	}
+	sort.Strings(result)
+
+	return result, len(partial)
+}
+
+// completions returns the references that the given partial reference may
+// complete to. The attributes of a resource are only included once the
+// partial reference includes its address.
+func (s *Session) completions(partial string) []string {
+	result := []string{
+		"path.cwd",
+		"path.module",
+		"path.root",
+		"terraform.workspace",
+	}
+
+	if s.Interpolater.Module != nil {
+		if conf := s.Interpolater.Module.Config(); conf != nil {
+			for _, v := range conf.Variables {
+				result = append(result, "var."+v.Name)
+			}
+			for _, l := range conf.Locals {
+				result = append(result, "local."+l.Name)
+			}
+			for _, m := range conf.Modules {
+				result = append(result, "module."+m.Name)
+			}
+		}
+	}
+
+	if s.Interpolater.State == nil {
+		return result
+	}
+	if s.Interpolater.StateLock != nil {
+		s.Interpolater.StateLock.RLock()
+		defer s.Interpolater.StateLock.RUnlock()
+	}
+
+	for _, ms := range s.Interpolater.State.Modules {
+		// Only the outputs of the children of the root module can be
+		// referred to.
+		if len(ms.Path) != 2 {
+			continue
+		}
+		for name := range ms.Outputs {
+			result = append(result, "module."+ms.Path[1]+"."+name)
+		}
+	}
+
+	ms := s.Interpolater.State.RootModule()
+	if ms == nil {
+		return result
+	}
+	seen := make(map[string]bool)
+	for k, rs := range ms.Resources {
+		key, err := terraform.ParseResourceStateKey(k)
+		if err != nil {
+			continue
+		}
+		addr := key.Type + "." + key.Name
+		if key.Mode == config.DataResourceMode {
+			addr = "data." + addr
+		}
+		if !seen[addr] {
+			result = append(result, addr)
+			seen[addr] = true
+		}
+
+		if rs.Primary == nil || !strings.HasPrefix(partial, addr+".") {
+			continue
+		}
+
+		// The instances of a resource with count are referred to by index,
+		// or all together with a splat.
+		prefixes := []string{addr}
+		if key.Index >= 0 {
+			prefixes = []string{addr + "." + strconv.Itoa(key.Index), addr + ".*"}
+		}
+		for _, prefix := range prefixes {
+			for _, attr := range attributeNames(rs.Primary.Attributes) {
+				c := prefix + "." + attr
+				if !seen[c] {
+					result = append(result, c)
+					seen[c] = true
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// attributeNames returns the names of the top-level attributes of the given
+// flattened attributes.
+func attributeNames(attrs map[string]string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for k := range attrs {
+		name := k
+		if i := strings.IndexByte(k, '.'); i >= 0 {
+			name = k[:i]
+		}
+		if !seen[name] {
+			result = append(result, name)
+			seen[name] = true
+		}
+	}
+	return result
+}
+
+// partialStart returns the index of the start of the reference that ends
+// at the end of the given input.
+func partialStart(input string) int {
+	i := len(input)
+	for i > 0 && isReferenceByte(input[i-1]) {
+		i--
+	}
+	return i
+}
+
+func isReferenceByte(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	case c == '_', c == '-', c == '.', c == '*':
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,58 @@
+package repl
+
+// Incomplete returns true if the given input is the start of an expression
+// that continues on the next line, because it has brackets or quotes that
+// aren't closed yet.
+//
+// Brackets that are closed with the wrong kind of bracket make the input
+// complete, so that the error is reported when it's evaluated rather than
+// waiting for more input.
+func Incomplete(input string) bool {
+	// open is the stack of the brackets and quotes that are open, where a
+	// quote is the start of a string and the brace of an interpolation
+	// within a string is also pushed as "{".
+	var open []byte
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		top := byte(0)
+		if len(open) > 0 {
+			top = open[len(open)-1]
+		}
+
+		if top == '"' {
+			switch {
+			case c == '\\':
+				i++
+			case c == '"':
+				open = open[:len(open)-1]
+			case c == '$' && i+1 < len(input) && input[i+1] == '{':
+				open = append(open, '{')
+				i++
+			}
+			continue
+		}
+
+		switch c {
+		case '"', '(', '[', '{':
+			open = append(open, c)
+		case ')', ']', '}':
+			if top != matchingBracket(c) {
+				return false
+			}
+			open = open[:len(open)-1]
+		}
+	}
+
+	return len(open) > 0
+}
+
+func matchingBracket(c byte) byte {
+	switch c {
+	case ')':
+		return '('
+	case ']':
+		return '['
+	default:
+		return '{'
+	}
+}
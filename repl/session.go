@@ -37,15 +37,32 @@ func (s *Session) Handle(line string) (string, error) {
 }
 
 func (s *Session) handleEval(line string) (string, error) {
+	value, err := s.Eval(line)
+	if err != nil {
+		return "", err
+	}
+
+	// Read the value
+	result, err := FormatResult(value)
+	if err != nil {
+		return "", err
+	}
+
+	return result, nil
+}
+
+// Eval evaluates the given expression, which may span several lines, and
+// returns its value, which is a string, list or map.
+func (s *Session) Eval(expr string) (interface{}, error) {
 	// Wrap the line to make it an interpolation.
-	line = fmt.Sprintf("${%s}", line)
+	line := fmt.Sprintf("${%s}", expr)
 
 	// Parse the line
 	raw, err := config.NewRawConfig(map[string]interface{}{
 		"value": line,
 	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Set the value
@@ -56,26 +73,20 @@ func (s *Session) handleEval(line string) (string, error) {
 		Path: []string{"root"},
 	}, raw.Variables)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Interpolate
 	if err := raw.Interpolate(vars); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// If we have any unknown keys, let the user know.
 	if ks := raw.UnknownKeys(); len(ks) > 0 {
-		return "", fmt.Errorf("unknown values referenced, can't compute value")
-	}
-
-	// Read the value
-	result, err := FormatResult(raw.Value())
-	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("unknown values referenced, can't compute value")
 	}
 
-	return result, nil
+	return raw.Value(), nil
 }
 
 func (s *Session) handleHelp() (string, error) {
@@ -85,7 +96,10 @@ You may access resources in the state (if you have one) just as you would
 from a configuration. For example: "aws_instance.foo.id" would evaluate
 to the ID of "aws_instance.foo" if it exists in your state.
 
-Type in the interpolation to test and hit <enter> to see the result.
+Type in the interpolation to test and hit <enter> to see the result. An
+interpolation with unclosed brackets or quotes continues on the next line.
+Hit <tab> to complete the addresses of resources in the state and the names
+of variables, locals and modules.
 
 To exit the console, type "exit" and hit <enter>, or use Control-C or
 Control-D.
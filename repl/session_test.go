@@ -1,6 +1,7 @@
 package repl
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
@@ -164,6 +165,139 @@ func TestSession_stateless(t *testing.T) {
 			},
 		})
 	})
+
+	t.Run("multiple lines", func(t *testing.T) {
+		testSession(t, testSessionTest{
+			Inputs: []testSessionInput{
+				{
+					Input:  "max(\n  1,\n  5\n)",
+					Output: "5",
+				},
+			},
+		})
+	})
+}
+
+func TestSessionComplete(t *testing.T) {
+	state := &terraform.State{
+		Modules: []*terraform.ModuleState{
+			&terraform.ModuleState{
+				Path: []string{"root"},
+				Resources: map[string]*terraform.ResourceState{
+					"test_instance.foo": &terraform.ResourceState{
+						Type: "test_instance",
+						Primary: &terraform.InstanceState{
+							ID: "bar",
+							Attributes: map[string]string{
+								"id":       "bar",
+								"tags.%":   "1",
+								"tags.Foo": "baz",
+							},
+						},
+					},
+					"test_instance.many.0": &terraform.ResourceState{
+						Type: "test_instance",
+						Primary: &terraform.InstanceState{
+							ID:         "a",
+							Attributes: map[string]string{"id": "a"},
+						},
+					},
+					"data.test_data.one": &terraform.ResourceState{
+						Type: "test_data",
+						Primary: &terraform.InstanceState{
+							ID:         "c",
+							Attributes: map[string]string{"id": "c"},
+						},
+					},
+				},
+			},
+			&terraform.ModuleState{
+				Path: []string{"root", "child"},
+				Outputs: map[string]*terraform.OutputState{
+					"ip": &terraform.OutputState{Type: "string", Value: "10.0.0.1"},
+				},
+			},
+		},
+	}
+
+	ctx, err := terraform.NewContext(&terraform.ContextOpts{
+		State:  state,
+		Module: module.NewEmptyTree(),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	s := &Session{
+		Interpolater: ctx.Interpolater(),
+	}
+
+	cases := []struct {
+		Input  string
+		Want   []string
+		Length int
+	}{
+		{
+			"test_",
+			[]string{"test_instance.foo", "test_instance.many"},
+			5,
+		},
+		{
+			"upper(test_instance.foo.",
+			[]string{"test_instance.foo.id", "test_instance.foo.tags"},
+			18,
+		},
+		{
+			"test_instance.many.",
+			[]string{"test_instance.many.*.id", "test_instance.many.0.id"},
+			19,
+		},
+		{
+			"data.",
+			[]string{"data.test_data.one"},
+			5,
+		},
+		{
+			"module.child.",
+			[]string{"module.child.ip"},
+			13,
+		},
+		{
+			"1 + 5",
+			nil,
+			1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Input, func(t *testing.T) {
+			got, length := s.Complete(tc.Input)
+			if !reflect.DeepEqual(got, tc.Want) || length != tc.Length {
+				t.Fatalf("wrong completions\ngot:  %#v, %d\nwant: %#v, %d", got, length, tc.Want, tc.Length)
+			}
+		})
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	cases := map[string]bool{
+		"1 + 5":                 false,
+		"max(1,":                true,
+		"list(\"a\", \"b\")":    false,
+		"\"foo":                 true,
+		"\"foo ${var.bar":       true,
+		"\"foo ${var.bar}\"":    false,
+		"\"a \\\" (\"":          false,
+		"map(\"a\", list(1, 2)": true,
+		"max(1, 2]":             false,
+	}
+
+	for input, want := range cases {
+		t.Run(input, func(t *testing.T) {
+			if got := Incomplete(input); got != want {
+				t.Fatalf("got %t; want %t", got, want)
+			}
+		})
+	}
 }
 
 func testSession(t *testing.T, test testSessionTest) {
@@ -0,0 +1,8 @@
+// Package secrets reads the values of secret variables from the secrets
+// backends declared in the CLI configuration.
+//
+// A secret variable names the backend that its value is read from and the
+// key of the value in that backend. Values are read each time Terraform
+// runs, so they never need to be written to a variable file, and they
+// aren't saved in plans.
+package secrets
@@ -0,0 +1,22 @@
+package secrets
+
+import (
+	"fmt"
+	"os"
+)
+
+type envBackend struct{}
+
+// Env returns a Backend that reads secrets from the environment variables
+// named by their keys, such as those set by an agent that runs Terraform.
+func Env() Backend {
+	return envBackend{}
+}
+
+func (envBackend) Secret(key string) (string, error) {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", key)
+	}
+	return v, nil
+}
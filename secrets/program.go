@@ -0,0 +1,40 @@
+package secrets
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/helper/program"
+)
+
+type programBackend struct {
+	program *program.Program
+}
+
+// ProgramBackend returns a Backend that runs the given program with the
+// given arguments in order to read secrets, such as a plugin for a cloud
+// secret manager. The executable path must be absolute, as for
+// program.New.
+//
+// When a secret is requested, the program will be run in a child process
+// with the given arguments along with two additional arguments added to the
+// end of the list: the literal string "get", followed by the key of the
+// secret. The program must write a JSON object to its standard output with
+// the property "value", the value of the secret.
+func ProgramBackend(executable string, args ...string) Backend {
+	return &programBackend{
+		program: program.New("ProgramBackend", executable, args...),
+	}
+}
+
+func (b *programBackend) Secret(key string) (string, error) {
+	var out struct {
+		Value *string `json:"value"`
+	}
+	if err := b.program.RunJSON(nil, &out, "get", key); err != nil {
+		return "", err
+	}
+	if out.Value == nil {
+		return "", fmt.Errorf("no value in output from %s", b.program.Path)
+	}
+	return *out.Value, nil
+}
@@ -0,0 +1,57 @@
+package secrets
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Backend is the interface implemented by secrets backends.
+type Backend interface {
+	// Secret returns the value of the secret with the given key. The form
+	// of the key depends on the backend.
+	Secret(key string) (string, error)
+}
+
+// Source reads secrets from a set of named backends. It caches the values
+// that it reads, so that each secret is read from its backend only once.
+type Source struct {
+	backends map[string]Backend
+
+	mu    sync.Mutex
+	cache map[string]map[string]string
+}
+
+// NewSource returns a Source that reads secrets from the given backends,
+// keyed by their names.
+func NewSource(backends map[string]Backend) *Source {
+	return &Source{
+		backends: backends,
+		cache:    make(map[string]map[string]string),
+	}
+}
+
+// Secret returns the value of the secret with the given key in the backend
+// with the given name.
+func (s *Source) Secret(backend, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if v, ok := s.cache[backend][key]; ok {
+		return v, nil
+	}
+
+	b, ok := s.backends[backend]
+	if !ok {
+		return "", fmt.Errorf("no secrets_backend %q block in the CLI configuration", backend)
+	}
+	v, err := b.Secret(key)
+	if err != nil {
+		return "", err
+	}
+
+	if s.cache[backend] == nil {
+		s.cache[backend] = make(map[string]string)
+	}
+	s.cache[backend][key] = v
+	return v, nil
+}
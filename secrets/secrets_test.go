@@ -0,0 +1,140 @@
+package secrets
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type countingBackend struct {
+	calls int
+}
+
+func (b *countingBackend) Secret(key string) (string, error) {
+	b.calls++
+	return "value of " + key, nil
+}
+
+func TestSource(t *testing.T) {
+	b := &countingBackend{}
+	src := NewSource(map[string]Backend{"test": b})
+
+	for i := 0; i < 2; i++ {
+		v, err := src.Secret("test", "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != "value of foo" {
+			t.Fatalf("wrong value %q", v)
+		}
+	}
+	if b.calls != 1 {
+		t.Fatalf("backend read the secret %d times; want 1", b.calls)
+	}
+
+	if _, err := src.Secret("nonexist", "foo"); err == nil {
+		t.Fatal("succeeded for unknown backend; want error")
+	}
+}
+
+func TestEnv(t *testing.T) {
+	os.Setenv("TF_TEST_SECRET", "hunter2")
+	defer os.Unsetenv("TF_TEST_SECRET")
+
+	v, err := Env().Secret("TF_TEST_SECRET")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "hunter2" {
+		t.Fatalf("wrong value %q", v)
+	}
+
+	if _, err := Env().Secret("TF_TEST_SECRET_NONEXIST"); err == nil {
+		t.Fatal("succeeded for unset variable; want error")
+	}
+}
+
+func TestVault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/db":
+			fmt.Fprint(w, `{"data":{"data":{"password":"hunter2"},"metadata":{"version":3}}}`)
+		case "/v1/kv/db":
+			fmt.Fprint(w, `{"data":{"password":"hunter3","port":5432}}`)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	b := Vault(server.URL, "s.token")
+
+	cases := map[string]struct {
+		Key   string
+		Value string
+		Err   string
+	}{
+		"kv v2":        {"secret/data/db#password", "hunter2", ""},
+		"kv v1":        {"kv/db#password", "hunter3", ""},
+		"no field":     {"kv/db#username", "", `no field "username"`},
+		"not a string": {"kv/db#port", "", "not a string"},
+		"no secret":    {"kv/nonexist#password", "", "no secret kv/nonexist"},
+		"invalid key":  {"kv/db", "", "invalid Vault secret key"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			v, err := b.Secret(tc.Key)
+			if tc.Err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.Err) {
+					t.Fatalf("wrong error %v; want it to contain %q", err, tc.Err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != tc.Value {
+				t.Fatalf("wrong value %q; want %q", v, tc.Value)
+			}
+		})
+	}
+
+	if _, err := Vault(server.URL, "wrong").Secret("kv/db#password"); err == nil {
+		t.Fatal("succeeded with wrong token; want error")
+	}
+}
+
+func TestProgramBackend(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("secrets program tests require /bin/sh")
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := ProgramBackend("/bin/sh", filepath.Join(wd, "test-fixtures/backend.sh"))
+
+	v, err := b.Secret("db-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "hunter2" {
+		t.Fatalf("wrong value %q", v)
+	}
+
+	if _, err := b.Secret("malformed"); err == nil || !strings.Contains(err.Error(), "malformed output") {
+		t.Fatalf("wrong error %v for malformed output", err)
+	}
+	if _, err := b.Secret("nonexist"); err == nil || !strings.Contains(err.Error(), "no secret nonexist") {
+		t.Fatalf("wrong error %v for failing program", err)
+	}
+}
@@ -0,0 +1,15 @@
+#!/bin/sh
+# A secrets program for testing, which is run as "backend.sh get KEY".
+
+case "$2" in
+  db-password)
+    echo '{"value":"hunter2"}'
+    ;;
+  malformed)
+    echo 'not json'
+    ;;
+  *)
+    echo "no secret $2" >&2
+    exit 1
+    ;;
+esac
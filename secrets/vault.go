@@ -0,0 +1,100 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+)
+
+type vaultBackend struct {
+	address string
+	token   string
+	client  *http.Client
+}
+
+// Vault returns a Backend that reads secrets from the Vault server at the
+// given address with the given token. If the address or token is empty,
+// VAULT_ADDR or VAULT_TOKEN is used instead.
+//
+// The key of a secret is the path of a secret in Vault and the name of a
+// field of the secret, separated by "#", such as "secret/data/db#password".
+// Both version 1 and version 2 of the key/value secrets engine are
+// supported.
+func Vault(address, token string) Backend {
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+
+	return &vaultBackend{
+		address: strings.TrimSuffix(address, "/"),
+		token:   token,
+		client:  cleanhttp.DefaultClient(),
+	}
+}
+
+func (b *vaultBackend) Secret(key string) (string, error) {
+	idx := strings.LastIndex(key, "#")
+	if idx <= 0 || idx == len(key)-1 {
+		return "", fmt.Errorf("invalid Vault secret key %q; must be a path and a field, like \"secret/data/db#password\"", key)
+	}
+	path, field := strings.Trim(key[:idx], "/"), key[idx+1:]
+
+	if b.address == "" {
+		return "", fmt.Errorf("no Vault address; set address in the secrets_backend block or VAULT_ADDR")
+	}
+
+	req, err := http.NewRequest("GET", b.address+"/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	if b.token != "" {
+		req.Header.Set("X-Vault-Token", b.token)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from Vault: %s", path, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", fmt.Errorf("no secret %s in Vault", path)
+	default:
+		return "", fmt.Errorf("failed to read %s from Vault: %s", path, resp.Status)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("malformed response from Vault: %s", err)
+	}
+
+	// Version 2 of the key/value engine nests the fields of the secret in
+	// another data object, along with its metadata.
+	data := body.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+
+	v, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("secret %s in Vault has no field %q", path, field)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("field %q of secret %s in Vault is not a string", field, path)
+	}
+	return s, nil
+}
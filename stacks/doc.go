@@ -0,0 +1,8 @@
+// Package stacks implements the stacks run by "terraform stack".
+//
+// A stack is a manifest of components, each of which is a root module with
+// a workspace. The input variables of a component can be set from the
+// output values of other components, which makes it depend on them, and
+// the components are planned or applied in the order of their dependencies,
+// with the components that don't depend on each other run in parallel.
+package stacks
@@ -0,0 +1,324 @@
+package stacks
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/terraform/dag"
+)
+
+// ManifestFilename is the name of the manifest that "terraform stack" loads
+// by default.
+const ManifestFilename = "terraform.tfstack.hcl"
+
+// Manifest is a stack manifest.
+type Manifest struct {
+	// Path is the path the manifest was loaded from.
+	Path string
+
+	// Components are the components of the stack, sorted by name.
+	Components []*Component
+}
+
+// Component is a component block of a manifest.
+type Component struct {
+	Name string
+
+	// Source is the directory of the root module as written, relative to
+	// the directory of the manifest, and Dir is the directory it refers to.
+	Source string
+	Dir    string
+
+	// Workspace is the workspace of the root module, which is "default" if
+	// it isn't set.
+	Workspace string
+
+	// Variables are the values of variables set in the manifest, and Inputs
+	// are the variables set from the outputs of other components.
+	Variables map[string]interface{}
+	Inputs    map[string]*Input
+
+	// DependsOn are the names of the components that this component depends
+	// on without referring to their outputs.
+	DependsOn []string
+}
+
+func (c *Component) String() string {
+	return fmt.Sprintf("component %q", c.Name)
+}
+
+// Input is an input variable of a component that's set from an output of
+// another component, written as "COMPONENT.OUTPUT".
+type Input struct {
+	Component string
+	Output    string
+}
+
+func (i *Input) String() string {
+	return i.Component + "." + i.Output
+}
+
+// Dependencies returns the names of the components that the component
+// depends on, sorted.
+func (c *Component) Dependencies() []string {
+	set := make(map[string]struct{})
+	for _, n := range c.DependsOn {
+		set[n] = struct{}{}
+	}
+	for _, in := range c.Inputs {
+		set[in.Component] = struct{}{}
+	}
+
+	result := make([]string, 0, len(set))
+	for n := range set {
+		result = append(result, n)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Component returns the component with the given name, or nil.
+func (m *Manifest) Component(name string) *Component {
+	for _, c := range m.Components {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Graph returns the graph of the components of the manifest, with an edge
+// from each component to each component that it depends on. The vertices
+// are the components.
+func (m *Manifest) Graph() (*dag.AcyclicGraph, error) {
+	var g dag.AcyclicGraph
+	for _, c := range m.Components {
+		g.Add(c)
+	}
+	for _, c := range m.Components {
+		for _, n := range c.Dependencies() {
+			dep := m.Component(n)
+			if dep == nil {
+				return nil, fmt.Errorf("component %q: depends on undeclared component %q", c.Name, n)
+			}
+			if dep == c {
+				return nil, fmt.Errorf("component %q: depends on itself", c.Name)
+			}
+			g.Connect(dag.BasicEdge(c, dep))
+		}
+	}
+
+	var err error
+	for _, cycle := range g.Cycles() {
+		names := make([]string, len(cycle))
+		for i, v := range cycle {
+			names[i] = v.(*Component).Name
+		}
+		sort.Strings(names)
+		err = multierror.Append(err, fmt.Errorf(
+			"dependency cycle between components: %s", strings.Join(names, ", ")))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &g, nil
+}
+
+// LoadManifest loads the manifest at the given path and checks that the
+// dependencies between its components form no cycles.
+func LoadManifest(path string) (*Manifest, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %s: %s", path, err)
+	}
+
+	root, err := hcl.Parse(string(d))
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing %s: %s", path, err)
+	}
+
+	m, err := loadManifestHcl(root, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("Error loading %s: %s", path, err)
+	}
+	m.Path = path
+
+	if _, err := m.Graph(); err != nil {
+		return nil, fmt.Errorf("Error loading %s: %s", path, err)
+	}
+
+	return m, nil
+}
+
+func loadManifestHcl(root *ast.File, dir string) (*Manifest, error) {
+	list, ok := root.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("manifest doesn't contain a root object")
+	}
+
+	if err := checkHCLKeys(list, []string{"component"}); err != nil {
+		return nil, err
+	}
+
+	components := list.Filter("component")
+	if len(components.Elem().Items) > 0 {
+		return nil, fmt.Errorf("%s: \"component\" must be followed by a name", components.Elem().Items[0].Pos())
+	}
+
+	m := &Manifest{}
+	for _, item := range components.Children().Items {
+		n := item.Keys[0].Token.Value().(string)
+		if m.Component(n) != nil {
+			return nil, fmt.Errorf("component %q: declared more than once", n)
+		}
+
+		c, err := loadComponentHcl(n, item.Val, dir)
+		if err != nil {
+			return nil, fmt.Errorf("component %q: %s", n, err)
+		}
+		m.Components = append(m.Components, c)
+	}
+	if len(m.Components) == 0 {
+		return nil, fmt.Errorf("no components are declared")
+	}
+	sort.Slice(m.Components, func(i, j int) bool { return m.Components[i].Name < m.Components[j].Name })
+
+	return m, nil
+}
+
+func loadComponentHcl(name string, val ast.Node, dir string) (*Component, error) {
+	ot, ok := val.(*ast.ObjectType)
+	if !ok {
+		return nil, fmt.Errorf("should be an object")
+	}
+
+	valid := []string{"source", "workspace", "variables", "inputs", "depends_on"}
+	if err := checkHCLKeys(ot, valid); err != nil {
+		return nil, err
+	}
+
+	var raw struct {
+		Source    string   `hcl:"source"`
+		Workspace string   `hcl:"workspace"`
+		DependsOn []string `hcl:"depends_on"`
+	}
+	if err := hcl.DecodeObject(&raw, ot); err != nil {
+		return nil, err
+	}
+	if raw.Source == "" {
+		return nil, fmt.Errorf("source must be set")
+	}
+	if raw.Workspace == "" {
+		raw.Workspace = "default"
+	}
+
+	c := &Component{
+		Name:      name,
+		Source:    raw.Source,
+		Dir:       filepath.Join(dir, raw.Source),
+		Workspace: raw.Workspace,
+		DependsOn: raw.DependsOn,
+	}
+
+	var err error
+	if c.Variables, err = loadObjectHcl("variables", ot.List.Filter("variables")); err != nil {
+		return nil, err
+	}
+
+	inputs, err := loadObjectHcl("inputs", ot.List.Filter("inputs"))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range inputs {
+		s, ok := v.(string)
+		parts := strings.Split(s, ".")
+		if !ok || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("input %q: must be an output of a component, as \"COMPONENT.OUTPUT\"", k)
+		}
+		if _, ok := c.Variables[k]; ok {
+			return nil, fmt.Errorf("input %q: is also set in variables", k)
+		}
+
+		if c.Inputs == nil {
+			c.Inputs = make(map[string]*Input)
+		}
+		c.Inputs[k] = &Input{Component: parts[0], Output: parts[1]}
+	}
+
+	return c, nil
+}
+
+func loadObjectHcl(key string, list *ast.ObjectList) (map[string]interface{}, error) {
+	if len(list.Items) == 0 {
+		return nil, nil
+	}
+	if len(list.Items) > 1 {
+		return nil, fmt.Errorf("only one %q block is allowed", key)
+	}
+
+	var result map[string]interface{}
+	if err := hcl.DecodeObject(&result, list.Items[0].Val); err != nil {
+		return nil, fmt.Errorf("%s: %s", key, err)
+	}
+	for k, v := range result {
+		result[k] = flattenValue(v)
+	}
+
+	return result, nil
+}
+
+// flattenValue replaces the lists of maps that HCL decodes maps to with the
+// maps themselves.
+func flattenValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []map[string]interface{}:
+		m := make(map[string]interface{})
+		for _, vm := range v {
+			for k, vv := range vm {
+				m[k] = flattenValue(vv)
+			}
+		}
+		return m
+	case []interface{}:
+		for i, vv := range v {
+			v[i] = flattenValue(vv)
+		}
+		return v
+	}
+	return v
+}
+
+func checkHCLKeys(node ast.Node, valid []string) error {
+	var list *ast.ObjectList
+	switch n := node.(type) {
+	case *ast.ObjectList:
+		list = n
+	case *ast.ObjectType:
+		list = n.List
+	default:
+		return fmt.Errorf("cannot check HCL keys of type %T", n)
+	}
+
+	validMap := make(map[string]struct{}, len(valid))
+	for _, v := range valid {
+		validMap[v] = struct{}{}
+	}
+
+	var result error
+	for _, item := range list.Items {
+		key := item.Keys[0].Token.Value().(string)
+		if _, ok := validMap[key]; !ok {
+			result = multierror.Append(result, fmt.Errorf(
+				"invalid key: %s", key))
+		}
+	}
+
+	return result
+}
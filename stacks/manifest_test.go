@@ -0,0 +1,72 @@
+package stacks
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// This is the directory where our test fixtures are.
+const fixtureDir = "./test-fixtures"
+
+func TestLoadManifest(t *testing.T) {
+	dir := filepath.Join(fixtureDir, "basic")
+	m, err := LoadManifest(filepath.Join(dir, ManifestFilename))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []*Component{
+		{
+			Name:      "app",
+			Source:    "./app",
+			Dir:       filepath.Join(dir, "app"),
+			Workspace: "prod",
+			Variables: map[string]interface{}{"instances": 2},
+			Inputs: map[string]*Input{
+				"subnet_id": {Component: "network", Output: "subnet_id"},
+			},
+		},
+		{
+			Name:      "dns",
+			Source:    "./dns",
+			Dir:       filepath.Join(dir, "dns"),
+			Workspace: "default",
+			DependsOn: []string{"app"},
+		},
+		{
+			Name:      "network",
+			Source:    "./network",
+			Dir:       filepath.Join(dir, "network"),
+			Workspace: "prod",
+			Variables: map[string]interface{}{"cidr": "10.0.0.0/16"},
+		},
+	}
+	if !reflect.DeepEqual(m.Components, expected) {
+		t.Fatalf("bad: %#v", m.Components)
+	}
+
+	if deps := m.Component("app").Dependencies(); !reflect.DeepEqual(deps, []string{"network"}) {
+		t.Fatalf("bad dependencies: %#v", deps)
+	}
+}
+
+func TestLoadManifest_invalid(t *testing.T) {
+	cases := map[string]string{
+		"cycle":     "dependency cycle between components: a, b",
+		"bad-input": `input "foo": must be an output of a component`,
+	}
+
+	for name, expected := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadManifest(filepath.Join(fixtureDir, name, ManifestFilename))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), expected) {
+				t.Fatalf("bad error: %s", err)
+			}
+		})
+	}
+}
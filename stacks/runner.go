@@ -0,0 +1,305 @@
+package stacks
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/terraform/dag"
+	"github.com/hashicorp/terraform/helper/variables"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// Operation is what a runner does with each component.
+type Operation string
+
+const (
+	OperationPlan  Operation = "plan"
+	OperationApply Operation = "apply"
+)
+
+// Status is the outcome of the operation of a component.
+type Status string
+
+const (
+	StatusOK Status = "ok"
+
+	// StatusError means that the operation failed, or that the outputs of
+	// the component couldn't be read.
+	StatusError Status = "error"
+
+	// StatusSkip is the status of a component whose operation isn't run,
+	// because a component that it depends on failed or was skipped, or
+	// because an output that it takes an input from doesn't exist yet.
+	StatusSkip Status = "skip"
+)
+
+// Executor runs the operations of components, each in its own root module
+// and workspace. Its methods are called concurrently for different
+// components.
+type Executor interface {
+	// Run runs the given operation for the given component, with the
+	// given variables.
+	Run(op Operation, c *Component, vars map[string]interface{}) (*ExecResult, error)
+
+	// Outputs returns the values of the outputs of the given component in
+	// its current state, which has no outputs if it was never applied.
+	Outputs(c *Component) (map[string]interface{}, error)
+}
+
+// ExecResult is the result of the operation of a component.
+type ExecResult struct {
+	// Changes is set if a plan has changes.
+	Changes bool
+
+	// Log is the output of the operation.
+	Log string
+}
+
+// ComponentResult is the result of the operation of a component.
+type ComponentResult struct {
+	Name      string
+	Workspace string
+	Status    Status
+
+	// Changes is set if the component was planned and has changes.
+	Changes bool
+
+	// Reason is why the component was skipped.
+	Reason string
+
+	// Warnings are about the inputs of a plan that were set from the
+	// current outputs of components with changes, which may change them.
+	Warnings []string
+
+	Log string
+	Err error
+}
+
+// Result is the result of an operation of a stack.
+type Result struct {
+	Operation  Operation
+	Components []*ComponentResult
+}
+
+// Status returns the overall status of the operation, which is the status
+// of the components if they're all the same, and otherwise StatusError if
+// any failed and StatusSkip if any were skipped.
+func (r *Result) Status() Status {
+	status := StatusOK
+	for _, c := range r.Components {
+		switch c.Status {
+		case StatusError:
+			status = StatusError
+		case StatusSkip:
+			if status == StatusOK {
+				status = StatusSkip
+			}
+		}
+	}
+	return status
+}
+
+// Runner runs the operations of the components of a manifest, in the order
+// of their dependencies.
+type Runner struct {
+	Manifest *Manifest
+	Executor Executor
+
+	// Parallelism is the maximum number of components whose operations
+	// run at once. It defaults to 1.
+	Parallelism int
+
+	// Progress, if set, is called with the result of each component once
+	// its operation is done, never concurrently.
+	Progress func(*ComponentResult)
+}
+
+// Run runs the given operation for each component once the components that
+// it depends on are done, and returns the results in order of name.
+func (r *Runner) Run(op Operation) (*Result, error) {
+	g, err := r.Manifest.Graph()
+	if err != nil {
+		return nil, err
+	}
+
+	par := r.Parallelism
+	if par <= 0 {
+		par = 1
+	}
+	w := &runWalker{
+		runner:  r,
+		op:      op,
+		sem:     terraform.NewSemaphore(par),
+		results: make(map[string]*ComponentResult),
+		outputs: make(map[string]map[string]interface{}),
+	}
+
+	// The error of the walk is that of the components that failed, which
+	// is in their results.
+	g.Walk(w.walk)
+
+	result := &Result{Operation: op}
+	for _, c := range r.Manifest.Components {
+		cr, ok := w.results[c.Name]
+		if !ok {
+			// The walk doesn't call back for the components that depend
+			// on a component that failed or was skipped.
+			cr = &ComponentResult{
+				Name:      c.Name,
+				Workspace: c.Workspace,
+				Status:    StatusSkip,
+				Reason:    fmt.Sprintf("it depends on %s, which didn't succeed", w.blocking(c)),
+			}
+			w.report(cr)
+		}
+		result.Components = append(result.Components, cr)
+	}
+
+	return result, nil
+}
+
+// runWalker walks the graph of the components of a manifest.
+type runWalker struct {
+	runner *Runner
+	op     Operation
+	sem    terraform.Semaphore
+
+	lock    sync.Mutex
+	results map[string]*ComponentResult
+	outputs map[string]map[string]interface{}
+}
+
+func (w *runWalker) walk(v dag.Vertex) error {
+	c := v.(*Component)
+
+	w.sem.Acquire()
+	defer w.sem.Release()
+
+	cr := w.run(c)
+	w.report(cr)
+
+	if cr.Status != StatusOK {
+		return fmt.Errorf("%s: %s", c, cr.Status)
+	}
+	return nil
+}
+
+// run runs the operation of the given component, whose dependencies are
+// done.
+func (w *runWalker) run(c *Component) *ComponentResult {
+	cr := &ComponentResult{
+		Name:      c.Name,
+		Workspace: c.Workspace,
+		Status:    StatusOK,
+	}
+
+	vars := variables.Merge(nil, c.Variables)
+	names := make([]string, 0, len(c.Inputs))
+	for n := range c.Inputs {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		in := c.Inputs[n]
+
+		w.lock.Lock()
+		outputs := w.outputs[in.Component]
+		dep := w.results[in.Component]
+		w.lock.Unlock()
+
+		v, ok := outputs[in.Output]
+		if !ok {
+			cr.Status = StatusSkip
+			cr.Reason = fmt.Sprintf("input %q is set from the output %s, which doesn't exist yet", n, in)
+			if w.op == OperationPlan {
+				cr.Reason += fmt.Sprintf("; apply component %q first", in.Component)
+			}
+			return cr
+		}
+		if w.op == OperationPlan && dep != nil && dep.Changes {
+			cr.Warnings = append(cr.Warnings, fmt.Sprintf(
+				"input %q is set from the current value of the output %s, which the changes of component %q may change",
+				n, in, in.Component))
+		}
+		vars[n] = v
+	}
+
+	er, err := w.runner.Executor.Run(w.op, c, vars)
+	if er != nil {
+		cr.Changes = er.Changes
+		cr.Log = er.Log
+	}
+	if err != nil {
+		cr.Status = StatusError
+		cr.Err = err
+		return cr
+	}
+
+	if w.needsOutputs(c) {
+		outputs, err := w.runner.Executor.Outputs(c)
+		if err != nil {
+			cr.Status = StatusError
+			cr.Err = fmt.Errorf("Error reading outputs: %s", err)
+			return cr
+		}
+
+		w.lock.Lock()
+		w.outputs[c.Name] = outputs
+		w.lock.Unlock()
+	}
+
+	return cr
+}
+
+// needsOutputs returns true if an input of another component is set from
+// an output of the given component.
+func (w *runWalker) needsOutputs(c *Component) bool {
+	for _, other := range w.runner.Manifest.Components {
+		for _, in := range other.Inputs {
+			if in.Component == c.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// blocking returns the names of the dependencies of the given component
+// that didn't succeed, as a phrase such as `components "a" and "b"`.
+func (w *runWalker) blocking(c *Component) string {
+	var names []string
+	for _, n := range c.Dependencies() {
+		if cr, ok := w.results[n]; !ok || cr.Status != StatusOK {
+			names = append(names, fmt.Sprintf("%q", n))
+		}
+	}
+	if len(names) == 1 {
+		return "component " + names[0]
+	}
+	return fmt.Sprintf("components %s", joinNames(names))
+}
+
+func (w *runWalker) report(cr *ComponentResult) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.results[cr.Name] = cr
+	if w.runner.Progress != nil {
+		w.runner.Progress(cr)
+	}
+}
+
+func joinNames(names []string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	}
+	result := names[0]
+	for _, n := range names[1 : len(names)-1] {
+		result += ", " + n
+	}
+	return result + " and " + names[len(names)-1]
+}
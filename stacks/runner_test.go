@@ -0,0 +1,129 @@
+package stacks
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRunner_Run(t *testing.T) {
+	m := testManifest(t)
+	e := &testExecutor{
+		changes: map[string]bool{"network": true},
+		outputs: map[string]map[string]interface{}{
+			"network": {"subnet_id": "subnet-123"},
+		},
+	}
+	r := &Runner{Manifest: m, Executor: e, Parallelism: 2}
+
+	result, err := r.Run(OperationPlan)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if result.Status() != StatusOK {
+		t.Fatalf("bad status: %s", result.Status())
+	}
+
+	// The components ran after the components that they depend on.
+	if !reflect.DeepEqual(e.order, []string{"network", "app", "dns"}) {
+		t.Fatalf("bad order: %#v", e.order)
+	}
+
+	expected := map[string]interface{}{"instances": 2, "subnet_id": "subnet-123"}
+	if !reflect.DeepEqual(e.vars["app"], expected) {
+		t.Fatalf("bad vars: %#v", e.vars["app"])
+	}
+
+	app := result.Components[0]
+	if app.Name != "app" || len(app.Warnings) != 1 || !strings.Contains(app.Warnings[0], "network.subnet_id") {
+		t.Fatalf("bad result: %#v", app)
+	}
+}
+
+func TestRunner_Run_error(t *testing.T) {
+	m := testManifest(t)
+	e := &testExecutor{
+		errs: map[string]error{"network": errors.New("boom")},
+	}
+	r := &Runner{Manifest: m, Executor: e}
+
+	result, err := r.Run(OperationApply)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if result.Status() != StatusError {
+		t.Fatalf("bad status: %s", result.Status())
+	}
+	if !reflect.DeepEqual(e.order, []string{"network"}) {
+		t.Fatalf("bad order: %#v", e.order)
+	}
+
+	var statuses []Status
+	for _, c := range result.Components {
+		statuses = append(statuses, c.Status)
+	}
+	if !reflect.DeepEqual(statuses, []Status{StatusSkip, StatusSkip, StatusError}) {
+		t.Fatalf("bad statuses: %#v", statuses)
+	}
+	if reason := result.Components[1].Reason; reason != `it depends on component "app", which didn't succeed` {
+		t.Fatalf("bad reason: %s", reason)
+	}
+}
+
+func TestRunner_Run_missingOutput(t *testing.T) {
+	m := testManifest(t)
+	e := &testExecutor{}
+	r := &Runner{Manifest: m, Executor: e}
+
+	result, err := r.Run(OperationPlan)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	app := result.Components[0]
+	if app.Status != StatusSkip || !strings.Contains(app.Reason, "doesn't exist yet") {
+		t.Fatalf("bad result: %#v", app)
+	}
+	if !reflect.DeepEqual(e.order, []string{"network"}) {
+		t.Fatalf("bad order: %#v", e.order)
+	}
+}
+
+func testManifest(t *testing.T) *Manifest {
+	m, err := LoadManifest(filepath.Join(fixtureDir, "basic", ManifestFilename))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return m
+}
+
+// testExecutor is an Executor that records the components that it runs.
+type testExecutor struct {
+	changes map[string]bool
+	outputs map[string]map[string]interface{}
+	errs    map[string]error
+
+	lock  sync.Mutex
+	order []string
+	vars  map[string]map[string]interface{}
+}
+
+func (e *testExecutor) Run(op Operation, c *Component, vars map[string]interface{}) (*ExecResult, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.order = append(e.order, c.Name)
+	if e.vars == nil {
+		e.vars = make(map[string]map[string]interface{})
+	}
+	e.vars[c.Name] = vars
+
+	return &ExecResult{Changes: e.changes[c.Name]}, e.errs[c.Name]
+}
+
+func (e *testExecutor) Outputs(c *Component) (map[string]interface{}, error) {
+	return e.outputs[c.Name], nil
+}
@@ -0,0 +1,7 @@
+component "a" {
+  source = "./a"
+
+  inputs {
+    foo = "foo"
+  }
+}
@@ -0,0 +1,26 @@
+component "network" {
+  source    = "./network"
+  workspace = "prod"
+
+  variables {
+    cidr = "10.0.0.0/16"
+  }
+}
+
+component "app" {
+  source    = "./app"
+  workspace = "prod"
+
+  variables {
+    instances = 2
+  }
+
+  inputs {
+    subnet_id = "network.subnet_id"
+  }
+}
+
+component "dns" {
+  source     = "./dns"
+  depends_on = ["app"]
+}
@@ -0,0 +1,12 @@
+component "a" {
+  source = "./a"
+
+  inputs {
+    foo = "b.foo"
+  }
+}
+
+component "b" {
+  source     = "./b"
+  depends_on = ["a"]
+}
@@ -3,6 +3,7 @@ package state
 import (
 	"sync"
 
+	"github.com/hashicorp/terraform/state/encryption"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -15,7 +16,8 @@ type BackupState struct {
 	Real State
 	Path string
 
-	done bool
+	done       bool
+	encryption *encryption.Encryption
 }
 
 func (s *BackupState) State() *terraform.State {
@@ -52,6 +54,18 @@ func (s *BackupState) PersistState() error {
 	return s.Real.PersistState()
 }
 
+// SetEncryption encrypts the backup with the given Encryption, and calls
+// the Real state's SetEncryption method if it's implemented.
+func (s *BackupState) SetEncryption(e *encryption.Encryption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.encryption = e
+	if real, ok := s.Real.(Encrypter); ok {
+		real.SetEncryption(e)
+	}
+}
+
 func (s *BackupState) Lock(info *LockInfo) (string, error) {
 	return s.Real.Lock(info)
 }
@@ -74,7 +88,7 @@ func (s *BackupState) backup() error {
 	// purposes, but we don't need a backup or lock if the state is empty, so
 	// skip this with a nil state.
 	if state != nil {
-		ls := &LocalState{Path: s.Path}
+		ls := &LocalState{Path: s.Path, encryption: s.encryption}
 		if err := ls.WriteState(state); err != nil {
 			return err
 		}
@@ -83,3 +97,23 @@ func (s *BackupState) backup() error {
 	s.done = true
 	return nil
 }
+
+// StateVersions calls the Real state's StateVersions method if it
+// implements History, and otherwise returns ErrHistoryNotSupported.
+func (s *BackupState) StateVersions() ([]*StateVersion, error) {
+	h, ok := s.Real.(History)
+	if !ok {
+		return nil, ErrHistoryNotSupported
+	}
+	return h.StateVersions()
+}
+
+// ReadStateVersion calls the Real state's ReadStateVersion method if it
+// implements History, and otherwise returns ErrHistoryNotSupported.
+func (s *BackupState) ReadStateVersion(v *StateVersion) (*terraform.State, error) {
+	h, ok := s.Real.(History)
+	if !ok {
+		return nil, ErrHistoryNotSupported
+	}
+	return h.ReadStateVersion(v)
+}
@@ -0,0 +1,74 @@
+package encryption
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// awsKMSEncryptionContext is the encryption context given to AWS KMS with
+// each data key, which must be given again to decrypt it.
+var awsKMSEncryptionContext = map[string]*string{
+	"purpose": aws.String("terraform-state"),
+}
+
+// awsKMSAPI is the subset of the AWS KMS client used by awsKMSProvider.
+type awsKMSAPI interface {
+	GenerateDataKey(*kms.GenerateDataKeyInput) (*kms.GenerateDataKeyOutput, error)
+	Decrypt(*kms.DecryptInput) (*kms.DecryptOutput, error)
+}
+
+// awsKMSProvider obtains data keys from AWS KMS, storing each data key
+// encrypted under the configured KMS key as its metadata.
+type awsKMSProvider struct {
+	client awsKMSAPI
+	keyID  string
+}
+
+func awsKMSFactory(conf map[string]string) (KeyProvider, error) {
+	if err := checkKeys(conf, []string{"kms_key_id"}, []string{"region", "profile"}); err != nil {
+		return nil, err
+	}
+
+	opts := session.Options{
+		Profile:           conf["profile"],
+		SharedConfigState: session.SharedConfigEnable,
+	}
+	if region := conf["region"]; region != "" {
+		opts.Config.Region = aws.String(region)
+	}
+	sess, err := session.NewSessionWithOptions(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure AWS: %s", err)
+	}
+
+	return &awsKMSProvider{
+		client: kms.New(sess),
+		keyID:  conf["kms_key_id"],
+	}, nil
+}
+
+func (p *awsKMSProvider) NewKey() ([]byte, []byte, error) {
+	out, err := p.client.GenerateDataKey(&kms.GenerateDataKeyInput{
+		KeyId:             aws.String(p.keyID),
+		KeySpec:           aws.String(kms.DataKeySpecAes256),
+		EncryptionContext: awsKMSEncryptionContext,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return out.Plaintext, out.CiphertextBlob, nil
+}
+
+func (p *awsKMSProvider) DecryptKey(meta []byte) ([]byte, error) {
+	out, err := p.client.Decrypt(&kms.DecryptInput{
+		CiphertextBlob:    meta,
+		EncryptionContext: awsKMSEncryptionContext,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Plaintext, nil
+}
@@ -0,0 +1,66 @@
+package encryption
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// fakeAWSKMS is an awsKMSAPI that "encrypts" data keys by prefixing them
+// with the key ID.
+type fakeAWSKMS struct{}
+
+func (fakeAWSKMS) GenerateDataKey(in *kms.GenerateDataKeyInput) (*kms.GenerateDataKeyOutput, error) {
+	if !reflect.DeepEqual(in.EncryptionContext, awsKMSEncryptionContext) {
+		return nil, fmt.Errorf("wrong encryption context")
+	}
+	key := bytes.Repeat([]byte{1}, 32)
+	return &kms.GenerateDataKeyOutput{
+		Plaintext:      key,
+		CiphertextBlob: append([]byte(aws.StringValue(in.KeyId)+":"), key...),
+	}, nil
+}
+
+func (fakeAWSKMS) Decrypt(in *kms.DecryptInput) (*kms.DecryptOutput, error) {
+	if !reflect.DeepEqual(in.EncryptionContext, awsKMSEncryptionContext) {
+		return nil, fmt.Errorf("wrong encryption context")
+	}
+	parts := bytes.SplitN(in.CiphertextBlob, []byte(":"), 2)
+	if len(parts) != 2 || string(parts[0]) != "alias/terraform" {
+		return nil, fmt.Errorf("invalid ciphertext")
+	}
+	return &kms.DecryptOutput{Plaintext: parts[1]}, nil
+}
+
+func TestAWSKMSProvider(t *testing.T) {
+	e := NewWithProvider("aws_kms", &awsKMSProvider{
+		client: fakeAWSKMS{},
+		keyID:  "alias/terraform",
+	})
+	plaintext := []byte(`{"version": 3}`)
+
+	data, err := e.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %s", err)
+	}
+	got, err := e.Decrypt(data)
+	if err != nil {
+		t.Fatalf("unexpected error decrypting: %s", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("wrong result\ngot:  %s\nwant: %s", got, plaintext)
+	}
+}
+
+func TestAWSKMSFactory(t *testing.T) {
+	if _, err := awsKMSFactory(map[string]string{}); err == nil {
+		t.Fatalf("missing kms_key_id succeeded; want error")
+	}
+	if _, err := awsKMSFactory(map[string]string{"kms_key_id": "alias/terraform", "bucket": "foo"}); err == nil {
+		t.Fatalf("unsupported argument succeeded; want error")
+	}
+}
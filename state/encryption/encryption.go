@@ -0,0 +1,236 @@
+// Package encryption implements the optional encryption of stored states.
+//
+// Each state is encrypted with AES-256-GCM using a new data key, which is
+// obtained from a KeyProvider along with metadata that the same provider
+// can later use to recover it. The encrypted state is stored as a small
+// JSON document recording the provider, the key metadata and the
+// ciphertext, so that it can be recognized when it is read.
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// KeyProvider provides the data keys used to encrypt states.
+type KeyProvider interface {
+	// NewKey returns a new 32-byte data key, along with metadata from
+	// which DecryptKey can recover it. The metadata is stored unencrypted
+	// alongside the encrypted state.
+	NewKey() (key, meta []byte, err error)
+
+	// DecryptKey recovers the data key from the given metadata.
+	DecryptKey(meta []byte) ([]byte, error)
+}
+
+// KeyProviderFactory creates a KeyProvider from its configuration.
+type KeyProviderFactory func(conf map[string]string) (KeyProvider, error)
+
+// KeyProviders are the available key providers, by the name used to select
+// them in configuration.
+var KeyProviders = map[string]KeyProviderFactory{
+	"aws_kms":       awsKMSFactory,
+	"gcp_kms":       gcpKMSFactory,
+	"passphrase":    passphraseFactory,
+	"vault_transit": vaultTransitFactory,
+}
+
+// envelopeVersion is the version of the encrypted state document. It will
+// be incremented if the document changes in a way that is not
+// backward-compatible.
+const envelopeVersion = 1
+
+// Encryption encrypts and decrypts states using keys from a particular key
+// provider.
+type Encryption struct {
+	name     string
+	provider KeyProvider
+
+	allowUnencrypted bool
+}
+
+// New returns an Encryption using the key provider with the given name,
+// configured with the given configuration.
+func New(name string, conf map[string]string) (*Encryption, error) {
+	f, ok := KeyProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown key provider %q; must be one of %s", name, providerNames())
+	}
+	p, err := f(conf)
+	if err != nil {
+		return nil, fmt.Errorf("key provider %q: %s", name, err)
+	}
+	return NewWithProvider(name, p), nil
+}
+
+// NewWithProvider returns an Encryption that uses the given key provider,
+// recording the given name in each state it encrypts.
+func NewWithProvider(name string, p KeyProvider) *Encryption {
+	return &Encryption{
+		name:     name,
+		provider: p,
+	}
+}
+
+// Name returns the name of the key provider.
+func (e *Encryption) Name() string {
+	return e.name
+}
+
+// SetAllowUnencrypted sets whether Decrypt accepts data that isn't an
+// encrypted state document. It's meant to be enabled only while migrating
+// states that were stored before encryption was enabled.
+func (e *Encryption) SetAllowUnencrypted(allow bool) {
+	e.allowUnencrypted = allow
+}
+
+type document struct {
+	Encrypted *envelope `json:"encrypted_state"`
+}
+
+type envelope struct {
+	Version     int    `json:"version"`
+	KeyProvider string `json:"key_provider"`
+	KeyMetadata []byte `json:"key_metadata"`
+	Ciphertext  []byte `json:"ciphertext"`
+}
+
+// Encrypt encrypts the given serialized state, returning the document to
+// store in its place.
+func (e *Encryption) Encrypt(plaintext []byte) ([]byte, error) {
+	key, meta, err := e.provider.NewKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a key for encrypting the state: %s", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate a nonce: %s", err)
+	}
+	ciphertext := aead.Seal(nonce, nonce, plaintext, e.additionalData(envelopeVersion))
+
+	return json.MarshalIndent(document{
+		Encrypted: &envelope{
+			Version:     envelopeVersion,
+			KeyProvider: e.name,
+			KeyMetadata: meta,
+			Ciphertext:  ciphertext,
+		},
+	}, "", "    ")
+}
+
+// Decrypt decrypts the given stored state document, returning the
+// serialized state.
+//
+// Data that is not an encrypted state document is rejected, since anyone who
+// can write the stored state could otherwise replace it with an unencrypted
+// one, unless SetAllowUnencrypted is used to allow it. It is then returned
+// unchanged, and is encrypted when it is next written. Empty data, which is
+// no state at all, is always returned unchanged.
+func (e *Encryption) Decrypt(data []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return data, nil
+	}
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil || doc.Encrypted == nil {
+		if !e.allowUnencrypted {
+			return nil, fmt.Errorf("the state is not encrypted, but encryption is configured; set allow_unencrypted in the state encryption configuration to read it while migrating to encryption")
+		}
+		return data, nil
+	}
+	env := doc.Encrypted
+
+	if env.Version != envelopeVersion {
+		return nil, fmt.Errorf("the state was encrypted using unsupported version %d of the encrypted state format", env.Version)
+	}
+	if env.KeyProvider != e.name {
+		return nil, fmt.Errorf("the state was encrypted using the key provider %q, but %q is configured", env.KeyProvider, e.name)
+	}
+
+	key, err := e.provider.DecryptKey(env.KeyMetadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the key for decrypting the state: %s", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("the encrypted state is truncated")
+	}
+
+	nonce, ciphertext := env.Ciphertext[:aead.NonceSize()], env.Ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, e.additionalData(env.Version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the state: the key is wrong or the state has been modified")
+	}
+	return plaintext, nil
+}
+
+// additionalData returns the data that is authenticated along with each
+// encrypted state, so that its envelope cannot be altered undetected.
+func (e *Encryption) additionalData(version int) []byte {
+	return []byte(fmt.Sprintf("terraform-state-v%d:%s", version, e.name))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("the data key has %d bytes; want 32", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// newDataKey returns a new random 32-byte data key.
+func newDataKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate a data key: %s", err)
+	}
+	return key, nil
+}
+
+// checkKeys returns an error if any of the given required keys is not set
+// in the given configuration, or if it sets a key that is neither required
+// nor optional.
+func checkKeys(conf map[string]string, required, optional []string) error {
+	valid := make(map[string]bool, len(required)+len(optional))
+	for _, k := range required {
+		if conf[k] == "" {
+			return fmt.Errorf("%q must be set", k)
+		}
+		valid[k] = true
+	}
+	for _, k := range optional {
+		valid[k] = true
+	}
+	for k := range conf {
+		if !valid[k] {
+			return fmt.Errorf("unsupported argument %q", k)
+		}
+	}
+	return nil
+}
+
+func providerNames() string {
+	names := make([]string, 0, len(KeyProviders))
+	for name := range KeyProviders {
+		names = append(names, fmt.Sprintf("%q", name))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,162 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testPassphraseEncryption(passphrase string) *Encryption {
+	return NewWithProvider("passphrase", &passphraseProvider{
+		passphrase: passphrase,
+		iterations: 1000,
+	})
+}
+
+func TestEncryption_roundTrip(t *testing.T) {
+	e := testPassphraseEncryption("correct horse battery staple")
+	plaintext := []byte(`{"version": 3, "serial": 1}`)
+
+	data, err := e.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %s", err)
+	}
+	if bytes.Contains(data, []byte("serial")) {
+		t.Fatalf("encrypted state contains plaintext:\n%s", data)
+	}
+
+	got, err := e.Decrypt(data)
+	if err != nil {
+		t.Fatalf("unexpected error decrypting: %s", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("wrong result\ngot:  %s\nwant: %s", got, plaintext)
+	}
+
+	// Each state is encrypted with a new key and nonce.
+	again, err := e.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %s", err)
+	}
+	if bytes.Equal(again, data) {
+		t.Fatalf("encrypting twice produced the same result")
+	}
+}
+
+func TestEncryption_plaintext(t *testing.T) {
+	e := testPassphraseEncryption("correct horse battery staple")
+	plaintexts := [][]byte{
+		[]byte(`{"version": 3, "serial": 1}`),
+		[]byte(`not JSON`),
+	}
+
+	// Unencrypted states are rejected, so that a stored state can't be
+	// downgraded by replacing it with an unencrypted one.
+	for _, data := range plaintexts {
+		if _, err := e.Decrypt(data); err == nil || !strings.Contains(err.Error(), "not encrypted") {
+			t.Fatalf("wrong error decrypting %q: %v", data, err)
+		}
+	}
+
+	// Empty data is no state at all, so there's nothing to reject.
+	for _, data := range [][]byte{[]byte(``), []byte("\n")} {
+		got, err := e.Decrypt(data)
+		if err != nil {
+			t.Fatalf("unexpected error decrypting %q: %s", data, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("wrong result\ngot:  %s\nwant: %s", got, data)
+		}
+	}
+
+	e.SetAllowUnencrypted(true)
+	for _, data := range plaintexts {
+		got, err := e.Decrypt(data)
+		if err != nil {
+			t.Fatalf("unexpected error decrypting %q with unencrypted states allowed: %s", data, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("wrong result\ngot:  %s\nwant: %s", got, data)
+		}
+	}
+}
+
+func TestEncryption_decryptErrors(t *testing.T) {
+	e := testPassphraseEncryption("correct horse battery staple")
+	data, err := e.Encrypt([]byte(`{"version": 3}`))
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %s", err)
+	}
+
+	modify := func(f func(env *envelope)) []byte {
+		var doc document
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Fatal(err)
+		}
+		f(doc.Encrypted)
+		buf, err := json.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf
+	}
+
+	tests := map[string]struct {
+		Encryption *Encryption
+		Data       []byte
+	}{
+		"wrong passphrase": {
+			testPassphraseEncryption("incorrect horse battery staple"),
+			data,
+		},
+		"modified ciphertext": {
+			e,
+			modify(func(env *envelope) { env.Ciphertext[len(env.Ciphertext)-1] ^= 1 }),
+		},
+		"truncated ciphertext": {
+			e,
+			modify(func(env *envelope) { env.Ciphertext = env.Ciphertext[:4] }),
+		},
+		"different key provider": {
+			e,
+			modify(func(env *envelope) { env.KeyProvider = "aws_kms" }),
+		},
+		"unsupported version": {
+			e,
+			modify(func(env *envelope) { env.Version = 99 }),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := test.Encryption.Decrypt(test.Data); err == nil {
+				t.Fatalf("succeeded; want error")
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv(PassphraseEnvVar, "correct horse battery staple")
+
+	e, err := New("passphrase", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got, want := e.Name(), "passphrase"; got != want {
+		t.Fatalf("wrong name %q; want %q", got, want)
+	}
+
+	if _, err := New("nope", nil); err == nil {
+		t.Fatalf("unknown key provider succeeded; want error")
+	}
+	if _, err := New("passphrase", map[string]string{"passphrase": "hunter2"}); err == nil {
+		t.Fatalf("passphrase in configuration succeeded; want error")
+	}
+
+	t.Setenv(PassphraseEnvVar, "short")
+	if _, err := New("passphrase", nil); err == nil {
+		t.Fatalf("short passphrase succeeded; want error")
+	}
+}
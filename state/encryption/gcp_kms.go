@@ -0,0 +1,72 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2/google"
+)
+
+// gcpKMSEndpoint is the base URL of the Google Cloud KMS API.
+const gcpKMSEndpoint = "https://cloudkms.googleapis.com/v1/"
+
+// gcpKMSProvider generates data keys locally and encrypts them with a
+// Google Cloud KMS key, storing the encrypted data key as its metadata.
+//
+// Credentials are found in the same way as for other Google Cloud tools,
+// such as from the GOOGLE_APPLICATION_CREDENTIALS environment variable.
+type gcpKMSProvider struct {
+	client   *http.Client
+	endpoint string
+	keyName  string
+}
+
+func gcpKMSFactory(conf map[string]string) (KeyProvider, error) {
+	if err := checkKeys(conf, []string{"key_name"}, nil); err != nil {
+		return nil, err
+	}
+
+	client, err := google.DefaultClient(context.Background(), "https://www.googleapis.com/auth/cloudkms")
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure Google Cloud credentials: %s", err)
+	}
+
+	return &gcpKMSProvider{
+		client:   client,
+		endpoint: gcpKMSEndpoint,
+		keyName:  conf["key_name"],
+	}, nil
+}
+
+func (p *gcpKMSProvider) NewKey() ([]byte, []byte, error) {
+	key, err := newDataKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var resp struct {
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	err = postJSON(p.client, p.endpoint+p.keyName+":encrypt", nil, map[string]string{
+		"plaintext": base64.StdEncoding.EncodeToString(key),
+	}, &resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, resp.Ciphertext, nil
+}
+
+func (p *gcpKMSProvider) DecryptKey(meta []byte) ([]byte, error) {
+	var resp struct {
+		Plaintext []byte `json:"plaintext"`
+	}
+	err := postJSON(p.client, p.endpoint+p.keyName+":decrypt", nil, map[string]string{
+		"ciphertext": base64.StdEncoding.EncodeToString(meta),
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Plaintext, nil
+}
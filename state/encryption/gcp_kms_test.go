@@ -0,0 +1,59 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGCPKMSProvider(t *testing.T) {
+	const keyName = "projects/p/locations/global/keyRings/r/cryptoKeys/k"
+
+	// The fake KMS "encrypts" by reversing the bytes of the plaintext.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+
+		switch r.URL.Path {
+		case "/v1/" + keyName + ":encrypt":
+			key, _ := base64.StdEncoding.DecodeString(body["plaintext"])
+			json.NewEncoder(w).Encode(map[string][]byte{"ciphertext": reversed(key)})
+		case "/v1/" + keyName + ":decrypt":
+			ciphertext, _ := base64.StdEncoding.DecodeString(body["ciphertext"])
+			json.NewEncoder(w).Encode(map[string][]byte{"plaintext": reversed(ciphertext)})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	e := NewWithProvider("gcp_kms", &gcpKMSProvider{
+		client:   srv.Client(),
+		endpoint: srv.URL + "/v1/",
+		keyName:  keyName,
+	})
+	plaintext := []byte(`{"version": 3}`)
+
+	data, err := e.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %s", err)
+	}
+	got, err := e.Decrypt(data)
+	if err != nil {
+		t.Fatalf("unexpected error decrypting: %s", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("wrong result\ngot:  %s\nwant: %s", got, plaintext)
+	}
+}
+
+func reversed(b []byte) []byte {
+	ret := make([]byte, len(b))
+	for i := range b {
+		ret[len(b)-1-i] = b[i]
+	}
+	return ret
+}
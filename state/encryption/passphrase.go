@@ -0,0 +1,87 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// PassphraseEnvVar is the environment variable that sets the passphrase for
+// the "passphrase" key provider. The passphrase cannot be set in the
+// configuration, since the backend configuration is itself stored
+// unencrypted.
+const PassphraseEnvVar = "TF_STATE_ENCRYPTION_PASSPHRASE"
+
+const (
+	// passphraseMinLength is the minimum length of a passphrase.
+	passphraseMinLength = 16
+
+	// passphraseIterations is the number of PBKDF2 iterations used to
+	// derive each new data key. The count is recorded in the key metadata,
+	// so it can be increased without affecting existing states.
+	passphraseIterations = 600000
+
+	// passphraseMaxIterations limits the iteration count accepted from key
+	// metadata, so that a modified state cannot make decryption take an
+	// unreasonably long time.
+	passphraseMaxIterations = 10000000
+)
+
+// passphraseProvider derives data keys from a passphrase using PBKDF2 with
+// SHA-256 and a new random salt for each key.
+type passphraseProvider struct {
+	passphrase string
+	iterations int
+}
+
+type passphraseMeta struct {
+	Salt       []byte `json:"salt"`
+	Iterations int    `json:"iterations"`
+}
+
+func passphraseFactory(conf map[string]string) (KeyProvider, error) {
+	if len(conf) > 0 {
+		return nil, fmt.Errorf("no arguments are supported; set the passphrase with the %s environment variable", PassphraseEnvVar)
+	}
+	passphrase := os.Getenv(PassphraseEnvVar)
+	if len(passphrase) < passphraseMinLength {
+		return nil, fmt.Errorf("the %s environment variable must be set to a passphrase of at least %d characters", PassphraseEnvVar, passphraseMinLength)
+	}
+	return &passphraseProvider{
+		passphrase: passphrase,
+		iterations: passphraseIterations,
+	}, nil
+}
+
+func (p *passphraseProvider) NewKey() ([]byte, []byte, error) {
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate a salt: %s", err)
+	}
+
+	key := pbkdf2.Key([]byte(p.passphrase), salt, p.iterations, 32, sha256.New)
+	meta, err := json.Marshal(passphraseMeta{
+		Salt:       salt,
+		Iterations: p.iterations,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, meta, nil
+}
+
+func (p *passphraseProvider) DecryptKey(meta []byte) ([]byte, error) {
+	var m passphraseMeta
+	if err := json.Unmarshal(meta, &m); err != nil {
+		return nil, fmt.Errorf("invalid key metadata: %s", err)
+	}
+	if len(m.Salt) == 0 || m.Iterations < 1 || m.Iterations > passphraseMaxIterations {
+		return nil, fmt.Errorf("invalid key metadata")
+	}
+	return pbkdf2.Key([]byte(p.passphrase), m.Salt, m.Iterations, 32, sha256.New), nil
+}
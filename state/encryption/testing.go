@@ -0,0 +1,27 @@
+package encryption
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// TestEncryption returns an Encryption for use in tests of other packages.
+// Its key provider uses a fixed data key, so it offers no protection.
+func TestEncryption() *Encryption {
+	return NewWithProvider("test", testKeyProvider{})
+}
+
+type testKeyProvider struct{}
+
+var testKey = bytes.Repeat([]byte{0x42}, 32)
+
+func (testKeyProvider) NewKey() ([]byte, []byte, error) {
+	return testKey, []byte("test"), nil
+}
+
+func (testKeyProvider) DecryptKey(meta []byte) ([]byte, error) {
+	if string(meta) != "test" {
+		return nil, fmt.Errorf("invalid key metadata")
+	}
+	return testKey, nil
+}
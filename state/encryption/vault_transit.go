@@ -0,0 +1,96 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+)
+
+// vaultTransitProvider obtains data keys from the transit secrets engine
+// of a Vault server, storing each data key encrypted under the configured
+// transit key as its metadata.
+//
+// The Vault token is read from the VAULT_TOKEN environment variable, and
+// the address from VAULT_ADDR unless it is configured.
+type vaultTransitProvider struct {
+	client  *http.Client
+	address string
+	mount   string
+	keyName string
+	token   string
+}
+
+type vaultTransitResponse struct {
+	Data struct {
+		Plaintext  string `json:"plaintext"`
+		Ciphertext string `json:"ciphertext"`
+	} `json:"data"`
+}
+
+func vaultTransitFactory(conf map[string]string) (KeyProvider, error) {
+	if err := checkKeys(conf, []string{"key_name"}, []string{"address", "mount_path"}); err != nil {
+		return nil, err
+	}
+
+	address := conf["address"]
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return nil, fmt.Errorf("\"address\" or the VAULT_ADDR environment variable must be set")
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("the VAULT_TOKEN environment variable must be set")
+	}
+	mount := conf["mount_path"]
+	if mount == "" {
+		mount = "transit"
+	}
+
+	return &vaultTransitProvider{
+		client:  cleanhttp.DefaultClient(),
+		address: strings.TrimRight(address, "/"),
+		mount:   strings.Trim(mount, "/"),
+		keyName: conf["key_name"],
+		token:   token,
+	}, nil
+}
+
+func (p *vaultTransitProvider) NewKey() ([]byte, []byte, error) {
+	var resp vaultTransitResponse
+	err := p.post("datakey/plaintext", map[string]interface{}{"bits": 256}, &resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid data key from Vault: %s", err)
+	}
+	return key, []byte(resp.Data.Ciphertext), nil
+}
+
+func (p *vaultTransitProvider) DecryptKey(meta []byte) ([]byte, error) {
+	var resp vaultTransitResponse
+	err := p.post("decrypt", map[string]interface{}{"ciphertext": string(meta)}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key from Vault: %s", err)
+	}
+	return key, nil
+}
+
+func (p *vaultTransitProvider) post(op string, in, out interface{}) error {
+	u := fmt.Sprintf("%s/v1/%s/%s/%s", p.address, p.mount, op, url.PathEscape(p.keyName))
+	header := http.Header{}
+	header.Set("X-Vault-Token", p.token)
+	return postJSON(p.client, u, header, in, out)
+}
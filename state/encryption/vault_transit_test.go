@@ -0,0 +1,78 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVaultTransitProvider(t *testing.T) {
+	key := bytes.Repeat([]byte{2}, 32)
+	encoded := base64.StdEncoding.EncodeToString(key)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		var data map[string]string
+		switch r.URL.Path {
+		case "/v1/transit/datakey/plaintext/terraform":
+			data = map[string]string{"plaintext": encoded, "ciphertext": "vault:v1:abc"}
+		case "/v1/transit/decrypt/terraform":
+			if body["ciphertext"] != "vault:v1:abc" {
+				http.Error(w, "invalid ciphertext", http.StatusBadRequest)
+				return
+			}
+			data = map[string]string{"plaintext": encoded}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer srv.Close()
+
+	t.Setenv("VAULT_TOKEN", "s.token")
+	p, err := vaultTransitFactory(map[string]string{
+		"address":  srv.URL,
+		"key_name": "terraform",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	e := NewWithProvider("vault_transit", p)
+	plaintext := []byte(`{"version": 3}`)
+
+	data, err := e.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %s", err)
+	}
+	got, err := e.Decrypt(data)
+	if err != nil {
+		t.Fatalf("unexpected error decrypting: %s", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("wrong result\ngot:  %s\nwant: %s", got, plaintext)
+	}
+
+	t.Setenv("VAULT_TOKEN", "s.wrong")
+	p, err = vaultTransitFactory(map[string]string{
+		"address":  srv.URL,
+		"key_name": "terraform",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_, err = NewWithProvider("vault_transit", p).Decrypt(data)
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("wrong error %v; want permission denied", err)
+	}
+}
@@ -0,0 +1,33 @@
+package state
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// ErrHistoryNotSupported is returned by the History methods of state
+// managers that wrap another state manager, such as BackupState, when the
+// wrapped state manager doesn't keep history.
+var ErrHistoryNotSupported = errors.New("state history not supported")
+
+// StateVersion describes a stored version of a state.
+type StateVersion struct {
+	Serial  int64
+	Lineage string
+
+	// Time is when the version was stored.
+	Time time.Time
+}
+
+// History is implemented by state managers that keep the previous versions
+// of the state that they store, as well as the current one.
+type History interface {
+	// StateVersions returns the stored versions of the state, newest first.
+	StateVersions() ([]*StateVersion, error)
+
+	// ReadStateVersion returns the stored state of the given version, which
+	// must be one of those returned by StateVersions.
+	ReadStateVersion(*StateVersion) (*terraform.State, error)
+}
@@ -12,6 +12,8 @@ import (
 type InmemState struct {
 	mu    sync.Mutex
 	state *terraform.State
+
+	resourceLocks ResourceLocks
 }
 
 func (s *InmemState) State() *terraform.State {
@@ -59,6 +61,53 @@ func (s *InmemState) Unlock(string) error {
 	return nil
 }
 
+// ResourceLocker impl.
+func (s *InmemState) LockResources(info *LockInfo, addrs []string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.resourceLocks.Lock(info, addrs)
+}
+
+// ResourceLocker impl.
+func (s *InmemState) UnlockResources(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.resourceLocks.Unlock(id)
+}
+
+// ResourceLocker impl.
+func (s *InmemState) WriteResources(id string, base, state *terraform.State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	addrs, err := s.resourceLocks.Addrs(id)
+	if err != nil {
+		return err
+	}
+
+	merged, err := MergeResources(s.state, base, state, addrs)
+	if err != nil {
+		return err
+	}
+	if merged == nil {
+		return nil
+	}
+
+	if s.state != nil {
+		merged.Serial = s.state.Serial
+
+		if !s.state.MarshalEqual(merged) {
+			merged.Serial++
+		}
+	}
+
+	s.state = merged
+
+	return nil
+}
+
 // inmemLocker is an in-memory State implementation for testing locks.
 type inmemLocker struct {
 	*InmemState
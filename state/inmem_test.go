@@ -13,6 +13,7 @@ func TestInmemState_impl(t *testing.T) {
 	var _ StateWriter = new(InmemState)
 	var _ StatePersister = new(InmemState)
 	var _ StateRefresher = new(InmemState)
+	var _ ResourceLocker = new(InmemState)
 }
 
 func TestInmemLocker(t *testing.T) {
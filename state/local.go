@@ -12,6 +12,7 @@ import (
 	"time"
 
 	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/terraform/state/encryption"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -25,6 +26,12 @@ type LocalState struct {
 	Path    string
 	PathOut string
 
+	// HistoryDir, if set, is a directory that each written version of the
+	// state is also stored in, so that the state can be rolled back. See
+	// the History interface. The directory is never pruned, and is only
+	// readable by the current user since the state may contain secrets.
+	HistoryDir string
+
 	// the file handle corresponding to PathOut
 	stateFileOut *os.File
 
@@ -41,6 +48,22 @@ type LocalState struct {
 	state     *terraform.State
 	readState *terraform.State
 	written   bool
+
+	// encoder writes the state, so that only the resource instances that
+	// changed since the last write are encoded again.
+	encoder *terraform.StateEncoder
+
+	encryption *encryption.Encryption
+}
+
+// SetEncryption encrypts the state file with the given Encryption. An
+// existing unencrypted state file can only be read if the Encryption allows
+// it, and is then encrypted when the state is next written.
+func (s *LocalState) SetEncryption(e *encryption.Encryption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.encryption = e
 }
 
 // SetState will force a specific state in-memory for this local state.
@@ -99,11 +122,51 @@ func (s *LocalState) WriteState(state *terraform.State) error {
 		s.state.Serial++
 	}
 
-	if err := terraform.WriteState(s.state, s.stateFileOut); err != nil {
-		return err
+	if s.encoder == nil {
+		s.encoder = terraform.NewStateEncoder()
+	}
+
+	// The encoded state is written straight to the state file, and to the
+	// history file if there is one, rather than held in memory, unless it
+	// must be encrypted first.
+	out := io.Writer(s.stateFileOut)
+	var hist *os.File
+	if s.HistoryDir != "" {
+		var err error
+		hist, err = s.createHistoryFile()
+		if err != nil {
+			return fmt.Errorf("Error storing state in history: %s", err)
+		}
+		defer hist.Close()
+		out = io.MultiWriter(s.stateFileOut, hist)
+	}
+
+	if s.encryption == nil {
+		if err := s.encoder.Encode(s.state, out); err != nil {
+			return err
+		}
+	} else {
+		var buf bytes.Buffer
+		if err := s.encoder.Encode(s.state, &buf); err != nil {
+			return err
+		}
+		data, err := s.encryption.Encrypt(buf.Bytes())
+		if err != nil {
+			return err
+		}
+		if _, err := out.Write(data); err != nil {
+			return err
+		}
 	}
 
 	s.written = true
+
+	if hist != nil {
+		if err := hist.Close(); err != nil {
+			return fmt.Errorf("Error storing state in history: %s", err)
+		}
+	}
+
 	return nil
 }
 
@@ -148,6 +211,18 @@ func (s *LocalState) RefreshState() error {
 		reader = s.stateFileOut
 	}
 
+	if s.encryption != nil {
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		data, err = s.encryption.Decrypt(data)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
 	state, err := terraform.ReadState(reader)
 	// if there's no state we just assign the nil return value
 	if err != nil && err != terraform.ErrNoState {
@@ -0,0 +1,111 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// localHistoryExtension is the extension of the files in the history
+// directory of a LocalState, which are named by the serial and lineage of
+// the version of the state that they contain.
+const localHistoryExtension = ".tfstate"
+
+// createHistoryFile creates the file in the history directory that the
+// current version of the state is to be written to, as it's written to the
+// state file. A later write of the same version replaces it.
+func (s *LocalState) createHistoryFile() (*os.File, error) {
+	if err := os.MkdirAll(s.HistoryDir, 0700); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(s.historyPath(s.state.Serial, s.state.Lineage), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+}
+
+func (s *LocalState) historyPath(serial int64, lineage string) string {
+	return filepath.Join(s.HistoryDir, fmt.Sprintf("%d-%s%s", serial, lineage, localHistoryExtension))
+}
+
+// StateVersions returns the versions of the state in the history directory.
+//
+// History impl.
+func (s *LocalState) StateVersions() ([]*StateVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.HistoryDir == "" {
+		return nil, nil
+	}
+
+	infos, err := ioutil.ReadDir(s.HistoryDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*StateVersion
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, localHistoryExtension) {
+			continue
+		}
+
+		parts := strings.SplitN(strings.TrimSuffix(name, localHistoryExtension), "-", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		serial, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		result = append(result, &StateVersion{
+			Serial:  serial,
+			Lineage: parts[1],
+			Time:    info.ModTime(),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Serial != result[j].Serial {
+			return result[i].Serial > result[j].Serial
+		}
+		return result[i].Time.After(result[j].Time)
+	})
+
+	return result, nil
+}
+
+// ReadStateVersion reads the given version of the state from the history
+// directory.
+//
+// History impl.
+func (s *LocalState) ReadStateVersion(v *StateVersion) (*terraform.State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.HistoryDir == "" {
+		return nil, fmt.Errorf("no state history directory is set")
+	}
+
+	data, err := ioutil.ReadFile(s.historyPath(v.Serial, v.Lineage))
+	if err != nil {
+		return nil, err
+	}
+	if s.encryption != nil {
+		data, err = s.encryption.Decrypt(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return terraform.ReadState(bytes.NewReader(data))
+}
@@ -1,12 +1,17 @@
 package state
 
 import (
+	"bytes"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"runtime"
+	"strings"
 	"sync"
 	"testing"
 
+	"github.com/hashicorp/terraform/state/encryption"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -140,11 +145,111 @@ func TestLocalState_nonExist(t *testing.T) {
 	}
 }
 
+func TestLocalState_encryption(t *testing.T) {
+	ls := testLocalState(t)
+	defer os.Remove(ls.Path)
+	e := encryption.TestEncryption()
+	ls.SetEncryption(e)
+
+	// The unencrypted state written by testLocalState is rejected, unless
+	// unencrypted states are allowed in order to migrate it.
+	if err := ls.RefreshState(); err == nil || !strings.Contains(err.Error(), "not encrypted") {
+		t.Fatalf("wrong error reading unencrypted state: %v", err)
+	}
+	e.SetAllowUnencrypted(true)
+
+	// The unencrypted state is then read, and encrypted when written.
+	TestState(t, ls)
+
+	raw, err := ioutil.ReadFile(ls.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(raw, []byte("encrypted_state")) || bytes.Contains(raw, []byte("lineage")) {
+		t.Fatalf("state file is not encrypted:\n%s", raw)
+	}
+
+	encrypted := &LocalState{Path: ls.Path}
+	encrypted.SetEncryption(encryption.TestEncryption())
+	if err := encrypted.RefreshState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !encrypted.State().Equal(ls.State()) {
+		t.Fatalf("wrong state\ngot:  %s\nwant: %s", encrypted.State(), ls.State())
+	}
+
+	plain := &LocalState{Path: ls.Path}
+	if err := plain.RefreshState(); err == nil {
+		t.Fatalf("reading encrypted state without encryption succeeded; want error")
+	}
+}
+
+func TestLocalState_history(t *testing.T) {
+	ls := testLocalState(t)
+	defer os.Remove(ls.Path)
+
+	dir, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	ls.HistoryDir = dir
+
+	var want []*terraform.State
+	for i := 0; i < 2; i++ {
+		// Each version is written after reading the previous one, since
+		// the serial is only incremented once per read.
+		if err := ls.RefreshState(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		s := ls.State()
+		s.RootModule().Outputs = map[string]*terraform.OutputState{
+			"version": &terraform.OutputState{Type: "string", Value: fmt.Sprint(i)},
+		}
+		if err := ls.WriteState(s); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		want = append(want, ls.State())
+	}
+
+	versions, err := ls.StateVersions()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("wrong number of versions %d; want 2", len(versions))
+	}
+	for i, v := range versions {
+		expected := want[len(want)-1-i]
+		if v.Serial != expected.Serial || v.Lineage != expected.Lineage {
+			t.Fatalf("wrong version %d: %#v", i, v)
+		}
+
+		s, err := ls.ReadStateVersion(v)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !s.Equal(expected) {
+			t.Fatalf("wrong state for version %d\ngot:  %s\nwant: %s", v.Serial, s, expected)
+		}
+
+		// The stored versions may contain secrets.
+		info, err := os.Stat(ls.historyPath(v.Serial, v.Lineage))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+			t.Fatalf("wrong mode %s for version %d; want 0600", info.Mode(), v.Serial)
+		}
+	}
+}
+
 func TestLocalState_impl(t *testing.T) {
 	var _ StateReader = new(LocalState)
 	var _ StateWriter = new(LocalState)
 	var _ StatePersister = new(LocalState)
 	var _ StateRefresher = new(LocalState)
+	var _ History = new(LocalState)
 }
 
 func testLocalState(t *testing.T) *LocalState {
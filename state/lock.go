@@ -1,6 +1,7 @@
 package state
 
 import (
+	"github.com/hashicorp/terraform/state/encryption"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -29,6 +30,14 @@ func (s *LockDisabled) PersistState() error {
 	return s.Inner.PersistState()
 }
 
+// SetEncryption calls the Inner state's SetEncryption method if it's
+// implemented.
+func (s *LockDisabled) SetEncryption(e *encryption.Encryption) {
+	if inner, ok := s.Inner.(Encrypter); ok {
+		inner.SetEncryption(e)
+	}
+}
+
 func (s *LockDisabled) Lock(info *LockInfo) (string, error) {
 	return "", nil
 }
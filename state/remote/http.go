@@ -1,21 +1,37 @@
 package remote
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/md5"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/go-retryablehttp"
+	uuid "github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/terraform/state"
 )
 
+// The defaults of the http client's retry policy, which can be configured
+// with retry_max, retry_wait_min and retry_wait_max.
+const (
+	defaultHTTPRetryMax     = 2
+	defaultHTTPRetryWaitMin = 1 * time.Second
+	defaultHTTPRetryWaitMax = 30 * time.Second
+)
+
 func httpFactory(conf map[string]string) (Client, error) {
 	address, ok := conf["address"]
 	if !ok {
@@ -70,21 +86,62 @@ func httpFactory(conf map[string]string) (Client, error) {
 		unlockMethod = "UNLOCK"
 	}
 
-	client := &http.Client{}
+	tlsConfig := &tls.Config{}
 	if skipRaw, ok := conf["skip_cert_verification"]; ok {
 		skip, err := strconv.ParseBool(skipRaw)
 		if err != nil {
 			return nil, fmt.Errorf("skip_cert_verification must be boolean")
 		}
-		if skip {
-			// Replace the client with one that ignores TLS verification
-			client = &http.Client{
-				Transport: &http.Transport{
-					TLSClientConfig: &tls.Config{
-						InsecureSkipVerify: true,
-					},
-				},
-			}
+		tlsConfig.InsecureSkipVerify = skip
+	}
+	if caPEM, ok := conf["client_ca_certificate_pem"]; ok {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(caPEM)) {
+			return nil, fmt.Errorf("failed to parse client_ca_certificate_pem")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	certPEM, hasCert := conf["client_certificate_pem"]
+	keyPEM, hasKey := conf["client_private_key_pem"]
+	if hasCert != hasKey {
+		return nil, fmt.Errorf("client_certificate_pem and client_private_key_pem must be set together")
+	}
+	if hasCert {
+		cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := cleanhttp.DefaultPooledTransport()
+	transport.TLSClientConfig = tlsConfig
+	client := &http.Client{Transport: transport}
+
+	retryMax := defaultHTTPRetryMax
+	if raw, ok := conf["retry_max"]; ok {
+		retryMax, err = strconv.Atoi(raw)
+		if err != nil || retryMax < 0 {
+			return nil, fmt.Errorf("retry_max must be a non-negative number")
+		}
+	}
+	retryWaitMin, err := httpRetryWait(conf, "retry_wait_min", defaultHTTPRetryWaitMin)
+	if err != nil {
+		return nil, err
+	}
+	retryWaitMax, err := httpRetryWait(conf, "retry_wait_max", defaultHTTPRetryWaitMax)
+	if err != nil {
+		return nil, err
+	}
+	if retryWaitMax < retryWaitMin {
+		return nil, fmt.Errorf("retry_wait_max must not be less than retry_wait_min")
+	}
+
+	var headers http.Header
+	if raw, ok := conf["headers"]; ok {
+		headers, err = parseHTTPHeaders(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse headers: %s", err)
 		}
 	}
 
@@ -99,6 +156,11 @@ func httpFactory(conf map[string]string) (Client, error) {
 
 		Username: conf["username"],
 		Password: conf["password"],
+		Headers:  headers,
+
+		RetryMax:     retryMax,
+		RetryWaitMin: retryWaitMin,
+		RetryWaitMax: retryWaitMax,
 
 		// accessible only for testing use
 		Client: client,
@@ -107,6 +169,39 @@ func httpFactory(conf map[string]string) (Client, error) {
 	return ret, nil
 }
 
+// httpRetryWait returns the wait configured in seconds with the given key,
+// or the given default if it isn't set.
+func httpRetryWait(conf map[string]string, key string, def time.Duration) (time.Duration, error) {
+	raw, ok := conf[key]
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative number of seconds", key)
+	}
+	return time.Duration(n) * time.Second, nil
+}
+
+// parseHTTPHeaders parses headers written one per line as "Name: value",
+// since legacy remote state configuration can only be strings.
+func parseHTTPHeaders(raw string) (http.Header, error) {
+	headers := make(http.Header)
+	sc := bufio.NewScanner(strings.NewReader(raw))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		idx := strings.Index(line, ":")
+		if idx < 1 {
+			return nil, fmt.Errorf("invalid header %q, expected \"Name: value\"", line)
+		}
+		headers.Add(strings.TrimSpace(line[:idx]), strings.TrimSpace(line[idx+1:]))
+	}
+	return headers, sc.Err()
+}
+
 // HTTPClient is a remote client that stores data in Consul or HTTP REST.
 type HTTPClient struct {
 	// Update & Retrieve
@@ -124,27 +219,67 @@ type HTTPClient struct {
 	Username string
 	Password string
 
+	// Headers are added to every request.
+	Headers http.Header
+
+	// Retries of requests that fail with a connection error, a 429 or a
+	// server error, with an exponential backoff between RetryWaitMin and
+	// RetryWaitMax. The zero value doesn't retry.
+	RetryMax     int
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+
 	lockID       string
 	jsonLockInfo []byte
+
+	// etag is the ETag of the state that was last read or written, which
+	// is sent with updates so that the server can reject them if the state
+	// was changed by someone else in the meantime.
+	etag string
 }
 
 func (c *HTTPClient) httpRequest(method string, url *url.URL, data *[]byte, what string) (*http.Response, error) {
+	return c.httpRequestHeaders(method, url, data, nil, what)
+}
+
+// httpRequestHeaders is like httpRequest, but adds the given headers to the
+// request.
+func (c *HTTPClient) httpRequestHeaders(method string, url *url.URL, data *[]byte, headers http.Header, what string) (*http.Response, error) {
 	// If we have data we need a reader
-	var reader io.Reader = nil
+	var reader io.ReadSeeker = nil
 	if data != nil {
 		reader = bytes.NewReader(*data)
 	}
 
 	// Create the request
-	req, err := http.NewRequest(method, url.String(), reader)
+	req, err := retryablehttp.NewRequest(method, url.String(), reader)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to make %s HTTP request: %s", what, err)
 	}
+	for k, vs := range c.Headers {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	for k, vs := range headers {
+		req.Header[k] = vs
+	}
+
 	// Setup basic auth
 	if c.Username != "" {
 		req.SetBasicAuth(c.Username, c.Password)
 	}
 
+	// Requests that change anything carry a key that's the same across
+	// retries, so that the server can tell a retry from a new request.
+	if method != "GET" {
+		key, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to make %s HTTP request: %s", what, err)
+		}
+		req.Header.Set("Idempotency-Key", key)
+	}
+
 	// Work with data/body
 	if data != nil {
 		req.Header.Set("Content-Type", "application/json")
@@ -157,7 +292,15 @@ func (c *HTTPClient) httpRequest(method string, url *url.URL, data *[]byte, what
 	}
 
 	// Make the request
-	resp, err := c.Client.Do(req)
+	rc := &retryablehttp.Client{
+		HTTPClient:   c.Client,
+		Logger:       log.New(log.Writer(), "", log.Flags()),
+		RetryMax:     c.RetryMax,
+		RetryWaitMin: c.RetryWaitMin,
+		RetryWaitMax: c.RetryWaitMax,
+		CheckRetry:   httpRetryPolicy,
+	}
+	resp, err := rc.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to %s: %v", what, err)
 	}
@@ -165,6 +308,28 @@ func (c *HTTPClient) httpRequest(method string, url *url.URL, data *[]byte, what
 	return resp, nil
 }
 
+// httpRetryPolicy retries requests that fail with connection errors, other
+// than certificate errors, and responses that say the server is overloaded
+// or failed.
+func httpRetryPolicy(resp *http.Response, err error) (bool, error) {
+	if err != nil {
+		if err, ok := err.(*url.Error); ok {
+			switch err.Err.(type) {
+			case x509.UnknownAuthorityError, x509.CertificateInvalidError, x509.HostnameError:
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true, nil
+	}
+	if resp.StatusCode == http.StatusNotImplemented {
+		return false, nil
+	}
+	return retryablehttp.DefaultRetryPolicy(resp, err)
+}
+
 func (c *HTTPClient) Lock(info *state.LockInfo) (string, error) {
 	if c.LockURL == nil {
 		return "", nil
@@ -235,8 +400,10 @@ func (c *HTTPClient) Get() (*Payload, error) {
 	case http.StatusOK:
 		// Handled after
 	case http.StatusNoContent:
+		c.etag = ""
 		return nil, nil
 	case http.StatusNotFound:
+		c.etag = ""
 		return nil, nil
 	case http.StatusUnauthorized:
 		return nil, fmt.Errorf("HTTP remote state endpoint requires auth")
@@ -258,6 +425,7 @@ func (c *HTTPClient) Get() (*Payload, error) {
 	payload := &Payload{
 		Data: buf.Bytes(),
 	}
+	c.etag = resp.Header.Get("ETag")
 
 	// If there was no data, then return nil
 	if len(payload.Data) == 0 {
@@ -305,7 +473,14 @@ func (c *HTTPClient) Put(data []byte) error {
 	if c.UpdateMethod != "" {
 		method = c.UpdateMethod
 	}
-	resp, err := c.httpRequest(method, &base, &data, "upload state")
+	// If the server told us the version of the state we read, it's only
+	// replaced if it's still that version.
+	var headers http.Header
+	if c.etag != "" {
+		headers = http.Header{"If-Match": []string{c.etag}}
+	}
+
+	resp, err := c.httpRequestHeaders(method, &base, &data, headers, "upload state")
 	if err != nil {
 		return err
 	}
@@ -314,7 +489,11 @@ func (c *HTTPClient) Put(data []byte) error {
 	// Handle the error codes
 	switch resp.StatusCode {
 	case http.StatusOK:
+		c.etag = resp.Header.Get("ETag")
 		return nil
+	case http.StatusPreconditionFailed:
+		return fmt.Errorf("HTTP remote state was changed since it was read, " +
+			"refresh and try again")
 	default:
 		return fmt.Errorf("HTTP error: %d", resp.StatusCode)
 	}
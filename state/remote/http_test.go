@@ -7,7 +7,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/go-cleanhttp"
 )
@@ -125,6 +128,186 @@ func TestHTTPClientFactory(t *testing.T) {
 	}
 }
 
+func TestHTTPClient_etag(t *testing.T) {
+	handler := new(testHTTPETagHandler)
+	ts := httptest.NewServer(http.HandlerFunc(handler.Handle))
+	defer ts.Close()
+
+	url, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	a := &HTTPClient{URL: url, UpdateMethod: "PUT", Client: cleanhttp.DefaultClient()}
+	b := &HTTPClient{URL: url, UpdateMethod: "PUT", Client: cleanhttp.DefaultClient()}
+	testClient(t, a)
+	if err := a.Put([]byte("init")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Both clients read the same version of the state, so only the first
+	// update succeeds.
+	for _, c := range []*HTTPClient{a, b} {
+		if _, err := c.Get(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if err := a.Put([]byte("a")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := a.Put([]byte("aa")); err != nil {
+		t.Fatalf("update after update failed: %s", err)
+	}
+	if err := b.Put([]byte("b")); err == nil {
+		t.Fatal("expected conflicting update to fail")
+	}
+
+	// Once it reads the state again, the second client can update it.
+	if _, err := b.Get(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := b.Put([]byte("b")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestHTTPClient_retry(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	failures := 2
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if r.Header.Get("X-Tenant") != "test" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+	}))
+	defer ts.Close()
+
+	url, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	client := &HTTPClient{
+		URL:          url,
+		Client:       cleanhttp.DefaultClient(),
+		Headers:      http.Header{"X-Tenant": []string{"test"}},
+		RetryMax:     2,
+		RetryWaitMin: time.Millisecond,
+		RetryWaitMax: time.Millisecond,
+	}
+	if err := client.Put([]byte("data")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The key of a request is the same across its retries.
+	mu.Lock()
+	defer mu.Unlock()
+	if len(keys) != 3 {
+		t.Fatalf("got %d requests; want 3", len(keys))
+	}
+	for _, k := range keys {
+		if k == "" || k != keys[0] {
+			t.Fatalf("wrong idempotency keys %q", keys)
+		}
+	}
+
+	// Requests fail once their retries are exhausted.
+	failures = 3
+	mu.Unlock()
+	defer mu.Lock()
+	if err := client.Put([]byte("data")); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestHTTPClientFactory_options(t *testing.T) {
+	conf := map[string]string{
+		"address":        "http://127.0.0.1:8888/foo",
+		"headers":        "X-Tenant: test\n\nX-Other : a: b\n",
+		"retry_max":      "5",
+		"retry_wait_min": "2",
+		"retry_wait_max": "10",
+	}
+	c, err := httpFactory(conf)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	client := c.(*HTTPClient)
+	if got := client.Headers.Get("X-Tenant"); got != "test" {
+		t.Fatalf("wrong X-Tenant header %q", got)
+	}
+	if got := client.Headers.Get("X-Other"); got != "a: b" {
+		t.Fatalf("wrong X-Other header %q", got)
+	}
+	if client.RetryMax != 5 || client.RetryWaitMin != 2*time.Second || client.RetryWaitMax != 10*time.Second {
+		t.Fatalf("wrong retry policy %d, %s, %s", client.RetryMax, client.RetryWaitMin, client.RetryWaitMax)
+	}
+
+	invalid := map[string]string{
+		"headers":                   "X-Tenant",
+		"retry_max":                 "-1",
+		"retry_wait_min":            "1s",
+		"retry_wait_max":            "0",
+		"client_ca_certificate_pem": "not a certificate",
+		"client_certificate_pem":    "not a certificate",
+	}
+	for k, v := range invalid {
+		conf := map[string]string{
+			"address": "http://127.0.0.1:8888/foo",
+			k:         v,
+		}
+		if _, err := httpFactory(conf); err == nil {
+			t.Fatalf("expected error for %s = %q", k, v)
+		}
+	}
+}
+
+// testHTTPETagHandler is a state server that versions its state with ETags,
+// and rejects updates of versions other than the current one.
+type testHTTPETagHandler struct {
+	Data    []byte
+	Version int
+}
+
+func (h *testHTTPETagHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	etag := strconv.Quote(strconv.Itoa(h.Version))
+	switch r.Method {
+	case "GET":
+		if h.Data == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write(h.Data)
+	case "PUT":
+		if match := r.Header.Get("If-Match"); h.Data != nil && match != "" && match != etag {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		buf := new(bytes.Buffer)
+		if _, err := io.Copy(buf, r.Body); err != nil {
+			w.WriteHeader(500)
+		}
+		h.Data = buf.Bytes()
+		h.Version++
+		w.Header().Set("ETag", strconv.Quote(strconv.Itoa(h.Version)))
+	case "DELETE":
+		h.Data = nil
+		h.Version++
+	default:
+		w.WriteHeader(500)
+	}
+}
+
 type testHTTPHandler struct {
 	Data   []byte
 	Locked bool
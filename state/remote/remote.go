@@ -22,6 +22,36 @@ type ClientLocker interface {
 	state.Locker
 }
 
+// ClientResourceLocker is an optional interface that allows a remote state
+// backend to lock individual resources of the state. See
+// state.ResourceLocker. A client that supports it only in some
+// configurations can also implement a ResourceLockingSupported method to
+// report whether it's supported.
+type ClientResourceLocker interface {
+	ClientLocker
+
+	LockResources(info *state.LockInfo, addrs []string) (string, error)
+	UnlockResources(id string) error
+
+	// Update atomically replaces the stored data with the result of calling
+	// the given function with the stored payload, which is nil if nothing
+	// is stored. Nothing is stored if the function returns an error.
+	Update(func(*Payload) ([]byte, error)) error
+}
+
+// clientResourceLockingSupported returns true if the given client implements
+// ClientResourceLocker, and its ResourceLockingSupported method, if any,
+// returns true.
+func clientResourceLockingSupported(c Client) bool {
+	if _, ok := c.(ClientResourceLocker); !ok {
+		return false
+	}
+	if c, ok := c.(interface{ ResourceLockingSupported() bool }); ok {
+		return c.ResourceLockingSupported()
+	}
+	return true
+}
+
 // Payload is the return value from the remote state storage.
 type Payload struct {
 	MD5  []byte
@@ -0,0 +1,666 @@
+package remote
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/encryption"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// ShardBy is the way that a ShardedState divides the resources of the state
+// between shards.
+type ShardBy string
+
+const (
+	// ShardByModule stores the resources of each module in a shard.
+	ShardByModule ShardBy = "module"
+
+	// ShardByResourceType stores the resources of each type in each module
+	// in a shard.
+	ShardByResourceType ShardBy = "resource_type"
+)
+
+// ParseShardBy returns the ShardBy with the given name, which is "module"
+// or "resource_type".
+func ParseShardBy(s string) (ShardBy, error) {
+	switch v := ShardBy(s); v {
+	case ShardByModule, ShardByResourceType:
+		return v, nil
+	}
+	return "", fmt.Errorf("invalid shard layout %q: must be %q or %q", s, ShardByModule, ShardByResourceType)
+}
+
+// shardFormatVersion is the version of the format of the manifest of a
+// sharded state.
+const shardFormatVersion = 1
+
+// shardRetries is the number of times that reading a sharded state is
+// retried when a shard it refers to has been removed since its manifest was
+// read, by a concurrent write.
+const shardRetries = 3
+
+// ShardedState is like State, except that it stores the state as several
+// objects instead of one: the resources of the state are divided between
+// shards, and a manifest records the rest of the state and the shards that
+// it consists of.
+//
+// Shards are named for their content, so only the shards that change are
+// written when the state is persisted, and the state changes when the
+// manifest is written. The shards that the manifest no longer refers to are
+// removed after it's written. Their names also have a suffix that's chosen
+// for each write, so a shard being removed is never one that a concurrent
+// write has written again. ReadModules reads only the shards of some of the
+// modules.
+//
+// A state stored as a single object by State can be read, and is stored as
+// shards when it's next persisted.
+type ShardedState struct {
+	mu sync.Mutex
+
+	// Manifest is the client of the manifest. Locks of the whole state and
+	// of its resources are taken with it.
+	Manifest Client
+
+	// Shard returns the client of the shard with the given name. Names
+	// consist of letters, digits and the characters "-", "_", "." and "~".
+	Shard func(name string) (Client, error)
+
+	// ShardBy is how the resources are divided between shards, which is
+	// ShardByModule if it isn't set. It can be changed between writes.
+	ShardBy ShardBy
+
+	state, readState *terraform.State
+	encryption       *encryption.Encryption
+
+	// shards are the names of the shards of readState.
+	shards map[string]bool
+
+	// resourceLocks are the addresses of the resource locks taken through
+	// this state, by lock ID.
+	resourceLocks map[string][]string
+}
+
+// shardManifest is the encoding of the manifest of a sharded state.
+type shardManifest struct {
+	// Format is the version of the manifest format, which is never zero,
+	// so that a manifest can be told apart from a state.
+	Format int `json:"shard_format"`
+
+	// State is the encoding of the state without any resources.
+	State json.RawMessage `json:"state"`
+
+	Shards []*shardRef `json:"shards"`
+}
+
+// shardRef is a shard listed in a manifest.
+type shardRef struct {
+	Name string `json:"name"`
+
+	// Module is the path of the module that the resources of the shard are
+	// in, and Type is their type if the shard is for a resource type.
+	Module []string `json:"module"`
+	Type   string   `json:"type,omitempty"`
+}
+
+// SetEncryption encrypts the manifest and shards with the given
+// Encryption.
+func (s *ShardedState) SetEncryption(e *encryption.Encryption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.encryption = e
+}
+
+// StateReader impl.
+func (s *ShardedState) State() *terraform.State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state.DeepCopy()
+}
+
+// StateWriter impl.
+func (s *ShardedState) WriteState(state *terraform.State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.readState != nil && !state.SameLineage(s.readState) {
+		log.Printf("[WARN] incompatible state lineage; given %s but want %s", state.Lineage, s.readState.Lineage)
+	}
+
+	// As for State, we keep a snapshot, with the serial of the read state
+	// until it's persisted.
+	s.state = state.DeepCopy()
+	if s.readState != nil {
+		s.state.Serial = s.readState.Serial
+	}
+
+	return nil
+}
+
+// StateRefresher impl.
+func (s *ShardedState) RefreshState() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, shards, err := s.read(nil)
+	if err != nil {
+		return err
+	}
+
+	// no remote state is OK
+	if state == nil {
+		return nil
+	}
+
+	s.state = state
+	s.readState = s.state.DeepCopy()
+	s.shards = shards
+	return nil
+}
+
+// ReadModules reads only the modules with the given paths from the stored
+// state, fetching just their shards. The rest of the modules are left out
+// of the returned state, which is nil if nothing is stored. The state that
+// this ShardedState holds is unchanged.
+func (s *ShardedState) ReadModules(paths [][]string) (*terraform.State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	want := make(map[string]bool)
+	for _, p := range paths {
+		want[modulePathKey(p)] = true
+	}
+
+	state, _, err := s.read(want)
+	if err != nil || state == nil {
+		return nil, err
+	}
+
+	var modules []*terraform.ModuleState
+	for _, m := range state.Modules {
+		if want[modulePathKey(m.Path)] {
+			modules = append(modules, m)
+		}
+	}
+	state.Modules = modules
+	return state, nil
+}
+
+// StatePersister impl.
+func (s *ShardedState) PersistState() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.state.MarshalEqual(s.readState) {
+		// As for State, the serial is incremented only if the state has
+		// changed since it was read.
+		s.state.Serial++
+	}
+
+	data, shards, err := s.writeShards(s.state, s.shards)
+	if err != nil {
+		return err
+	}
+	if err := s.Manifest.Put(data); err != nil {
+		return err
+	}
+	s.removeShards(s.shards, shards)
+
+	s.readState = s.state.DeepCopy()
+	s.shards = shards
+	return nil
+}
+
+// Lock calls the Manifest client's Lock method if it's implemented.
+func (s *ShardedState) Lock(info *state.LockInfo) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.Manifest.(ClientLocker); ok {
+		return c.Lock(info)
+	}
+	return "", nil
+}
+
+// Unlock calls the Manifest client's Unlock method if it's implemented.
+func (s *ShardedState) Unlock(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.Manifest.(ClientLocker); ok {
+		return c.Unlock(id)
+	}
+	return nil
+}
+
+// ResourceLockingSupported returns true if the Manifest client implements
+// ClientResourceLocker, and its ResourceLockingSupported method, if any,
+// returns true.
+func (s *ShardedState) ResourceLockingSupported() bool {
+	return clientResourceLockingSupported(s.Manifest)
+}
+
+// LockResources calls the Manifest client's LockResources method.
+//
+// state.ResourceLocker impl.
+func (s *ShardedState) LockResources(info *state.LockInfo, addrs []string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.Manifest.(ClientResourceLocker)
+	if !ok {
+		return "", errResourceLockingNotSupported
+	}
+
+	id, err := c.LockResources(info, addrs)
+	if err != nil {
+		return "", err
+	}
+	if s.resourceLocks == nil {
+		s.resourceLocks = make(map[string][]string)
+	}
+	s.resourceLocks[id] = append([]string(nil), addrs...)
+	return id, nil
+}
+
+// UnlockResources calls the Manifest client's UnlockResources method.
+//
+// state.ResourceLocker impl.
+func (s *ShardedState) UnlockResources(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.Manifest.(ClientResourceLocker)
+	if !ok {
+		return errResourceLockingNotSupported
+	}
+
+	if err := c.UnlockResources(id); err != nil {
+		return err
+	}
+	delete(s.resourceLocks, id)
+	return nil
+}
+
+// WriteResources merges the resources covered by the given resource lock
+// into the stored state, updating the manifest with the Manifest client's
+// Update method so that the stored state can't change during the merge.
+// Only the shards whose resources change are written.
+//
+// state.ResourceLocker impl.
+func (s *ShardedState) WriteResources(id string, base, v *terraform.State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.Manifest.(ClientResourceLocker)
+	if !ok {
+		return errResourceLockingNotSupported
+	}
+	addrs, ok := s.resourceLocks[id]
+	if !ok {
+		return &state.LockError{Err: errors.New("invalid resource lock id")}
+	}
+
+	var merged *terraform.State
+	var oldShards, newShards map[string]bool
+	err := c.Update(func(payload *Payload) ([]byte, error) {
+		current, shards, err := s.readManifest(payload, nil)
+		if err != nil {
+			return nil, err
+		}
+		oldShards = shards
+
+		merged, err = state.MergeResources(current, base, v, addrs)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil && !merged.MarshalEqual(current) {
+			merged.Serial = current.Serial + 1
+		}
+
+		data, shards, err := s.writeShards(merged, oldShards)
+		if err != nil {
+			return nil, err
+		}
+		newShards = shards
+		return data, nil
+	})
+	if err != nil {
+		return err
+	}
+	s.removeShards(oldShards, newShards)
+
+	s.state = merged
+	s.readState = merged.DeepCopy()
+	s.shards = newShards
+	return nil
+}
+
+// read reads the stored state, or nil if nothing is stored, along with the
+// names of its shards. If modules is non-nil, only the shards of the
+// modules it has the keys of are read.
+//
+// A shard can be removed by a concurrent write after the manifest that
+// refers to it is read, in which case reading is retried with the new
+// manifest.
+func (s *ShardedState) read(modules map[string]bool) (*terraform.State, map[string]bool, error) {
+	var err error
+	for i := 0; i < shardRetries; i++ {
+		var payload *Payload
+		payload, err = s.Manifest.Get()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var state *terraform.State
+		var shards map[string]bool
+		state, shards, err = s.readManifest(payload, modules)
+		if _, ok := err.(errShardMissing); ok {
+			log.Printf("[DEBUG] %s, reading the state again", err)
+			continue
+		}
+		return state, shards, err
+	}
+	return nil, nil, err
+}
+
+// readManifest reads the state that the given payload of the manifest
+// refers to, or nil if the payload is nil. If modules is non-nil, only the
+// shards of the modules it has the keys of are read.
+func (s *ShardedState) readManifest(payload *Payload, modules map[string]bool) (*terraform.State, map[string]bool, error) {
+	if payload == nil {
+		return nil, nil, nil
+	}
+
+	data, err := s.decrypt(payload.Data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var manifest shardManifest
+	if err := json.Unmarshal(data, &manifest); err != nil || manifest.Format == 0 {
+		// This is a state stored as a single object, which has no shards.
+		state, err := terraform.ReadState(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		return state, nil, nil
+	}
+	if manifest.Format > shardFormatVersion {
+		return nil, nil, fmt.Errorf("unsupported sharded state format %d", manifest.Format)
+	}
+
+	// The state is indented along with the rest of the manifest, so it's
+	// put back in the form that it was written in, for ReadState to only
+	// detect changes in its normalization.
+	var compact, canonical bytes.Buffer
+	if err := json.Compact(&compact, manifest.State); err != nil {
+		return nil, nil, fmt.Errorf("Decoding sharded state manifest failed: %s", err)
+	}
+	if err := json.Indent(&canonical, compact.Bytes(), "", "    "); err != nil {
+		return nil, nil, fmt.Errorf("Decoding sharded state manifest failed: %s", err)
+	}
+	canonical.WriteByte('\n')
+
+	result, err := terraform.ReadState(&canonical)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Decoding sharded state manifest failed: %s", err)
+	}
+
+	shards := make(map[string]bool)
+	for _, ref := range manifest.Shards {
+		shards[ref.Name] = true
+		if modules != nil && !modules[modulePathKey(ref.Module)] {
+			continue
+		}
+
+		shard, err := s.readShard(ref.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		mod := result.ModuleByPath(ref.Module)
+		if mod == nil {
+			mod = result.AddModule(ref.Module)
+		}
+		for _, sm := range shard.Modules {
+			for k, r := range sm.Resources {
+				mod.Resources[k] = r
+			}
+		}
+	}
+
+	return result, shards, nil
+}
+
+// readShard reads the shard with the given name.
+func (s *ShardedState) readShard(name string) (*terraform.State, error) {
+	c, err := s.Shard(name)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := c.Get()
+	if err != nil {
+		return nil, fmt.Errorf("Reading state shard %s failed: %s", name, err)
+	}
+	if payload == nil {
+		return nil, errShardMissing(name)
+	}
+
+	data, err := s.decrypt(payload.Data)
+	if err != nil {
+		return nil, err
+	}
+	shard, err := terraform.ReadState(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("Decoding state shard %s failed: %s", name, err)
+	}
+	return shard, nil
+}
+
+// writeShards writes the shards of the given state that aren't among the
+// given existing shards, and returns the encoding of its manifest along
+// with the names of all of its shards.
+func (s *ShardedState) writeShards(v *terraform.State, existing map[string]bool) ([]byte, map[string]bool, error) {
+	// The manifest has the state without any resources, and each shard a
+	// state with only the resources of its module or type.
+	skeleton := v.DeepCopy()
+	manifest := shardManifest{Format: shardFormatVersion}
+	shards := make(map[string]bool)
+
+	// The existing shards are reused by the names they have without the
+	// suffix of the write that wrote them, and new shards get a new suffix.
+	reuse := make(map[string]string, len(existing))
+	for name := range existing {
+		reuse[shardContentName(name)] = name
+	}
+	var suffix [shardSuffixBytes]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		return nil, nil, err
+	}
+	for _, m := range skeleton.Modules {
+		for _, ref := range s.shardRefs(m) {
+			// Shards have the lineage of the state, but no serial, so
+			// that their content only changes with their resources.
+			shard := &terraform.State{
+				Lineage: v.Lineage,
+				Modules: []*terraform.ModuleState{
+					{
+						Path:      m.Path,
+						Resources: ref.resources,
+					},
+				},
+			}
+			var buf bytes.Buffer
+			if err := terraform.WriteState(shard, &buf); err != nil {
+				return nil, nil, err
+			}
+
+			sum := sha256.Sum256(buf.Bytes())
+			content := ref.bucket + "~" + hex.EncodeToString(sum[:8])
+			if name, ok := reuse[content]; ok {
+				ref.Name = name
+			} else {
+				ref.Name = content + "-" + hex.EncodeToString(suffix[:])
+				if err := s.putShard(ref.Name, buf.Bytes()); err != nil {
+					return nil, nil, err
+				}
+				reuse[content] = ref.Name
+			}
+			shards[ref.Name] = true
+			manifest.Shards = append(manifest.Shards, &ref.shardRef)
+		}
+		m.Resources = map[string]*terraform.ResourceState{}
+	}
+
+	var buf bytes.Buffer
+	if err := terraform.WriteState(skeleton, &buf); err != nil {
+		return nil, nil, err
+	}
+	manifest.State = buf.Bytes()
+
+	data, err := json.MarshalIndent(&manifest, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err = s.encrypt(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, shards, nil
+}
+
+// shardSuffixBytes is the number of random bytes of the suffix of the names
+// of the shards written by each write.
+const shardSuffixBytes = 4
+
+// shardContentName returns the given shard name without the suffix of the
+// write that wrote it. Names written before shards had suffixes are
+// returned as they are.
+func shardContentName(name string) string {
+	n := len(name) - 2*shardSuffixBytes - 1
+	if n < 0 || name[n] != '-' {
+		return name
+	}
+	if _, err := hex.DecodeString(name[n+1:]); err != nil {
+		return name
+	}
+	return name[:n]
+}
+
+// pendingShard is a shard of a state being written.
+type pendingShard struct {
+	shardRef
+
+	// bucket is the name of the shard without the sum of its content.
+	bucket    string
+	resources map[string]*terraform.ResourceState
+}
+
+// shardRefs divides the resources of the given module into shards, in
+// order of their buckets.
+func (s *ShardedState) shardRefs(m *terraform.ModuleState) []*pendingShard {
+	byBucket := make(map[string]*pendingShard)
+	for k, r := range m.Resources {
+		ref := shardRef{Module: m.Path}
+		if s.ShardBy == ShardByResourceType && r != nil {
+			ref.Type = r.Type
+		}
+
+		bucket := strings.Join(m.Path, ".")
+		if ref.Type != "" {
+			bucket += "~" + ref.Type
+		}
+		p := byBucket[bucket]
+		if p == nil {
+			p = &pendingShard{
+				shardRef:  ref,
+				bucket:    bucket,
+				resources: make(map[string]*terraform.ResourceState),
+			}
+			byBucket[bucket] = p
+		}
+		p.resources[k] = r
+	}
+
+	ret := make([]*pendingShard, 0, len(byBucket))
+	for _, p := range byBucket {
+		ret = append(ret, p)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].bucket < ret[j].bucket
+	})
+	return ret
+}
+
+func (s *ShardedState) putShard(name string, data []byte) error {
+	c, err := s.Shard(name)
+	if err != nil {
+		return err
+	}
+	data, err = s.encrypt(data)
+	if err != nil {
+		return err
+	}
+	if err := c.Put(data); err != nil {
+		return fmt.Errorf("Writing state shard %s failed: %s", name, err)
+	}
+	return nil
+}
+
+// removeShards removes the given old shards that aren't among the given
+// current ones. Failing to remove a shard only leaves it behind, so errors
+// are logged rather than returned.
+func (s *ShardedState) removeShards(old, current map[string]bool) {
+	for name := range old {
+		if current[name] {
+			continue
+		}
+		c, err := s.Shard(name)
+		if err == nil {
+			err = c.Delete()
+		}
+		if err != nil {
+			log.Printf("[WARN] failed to remove state shard %s: %s", name, err)
+		}
+	}
+}
+
+func (s *ShardedState) decrypt(data []byte) ([]byte, error) {
+	if s.encryption == nil {
+		return data, nil
+	}
+	return s.encryption.Decrypt(data)
+}
+
+func (s *ShardedState) encrypt(data []byte) ([]byte, error) {
+	if s.encryption == nil {
+		return data, nil
+	}
+	return s.encryption.Encrypt(data)
+}
+
+// modulePathKey returns a key for the module with the given path.
+func modulePathKey(path []string) string {
+	return strings.Join(path, "\x00")
+}
+
+// errShardMissing is returned when a shard that a manifest refers to isn't
+// stored.
+type errShardMissing string
+
+func (e errShardMissing) Error() string {
+	return fmt.Sprintf("state shard %s is missing", string(e))
+}
@@ -0,0 +1,343 @@
+package remote
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestShardedState_impl(t *testing.T) {
+	var _ state.State = new(ShardedState)
+	var _ state.ResourceLocker = new(ShardedState)
+	var _ state.Encrypter = new(ShardedState)
+}
+
+func TestShardedState(t *testing.T) {
+	store := newShardStore()
+	s := store.state(ShardByModule)
+	if err := s.WriteState(state.TestStateInitial()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state.TestState(t, store.state(ShardByModule))
+}
+
+func TestShardedState_shards(t *testing.T) {
+	store := newShardStore()
+	s := store.state(ShardByModule)
+
+	current := testShardedStateFixture()
+	if err := s.WriteState(current); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if got := store.shardNames(); len(got) != 2 {
+		t.Fatalf("wrong shards %#v; want one for each module with resources", got)
+	}
+	if bytes.Contains(store.manifest.data, []byte("i-web")) {
+		t.Fatalf("manifest has resources:\n%s", store.manifest.data)
+	}
+
+	// Only the shard of the changed module is written, and the shard it
+	// replaces is removed.
+	store.puts = 0
+	current.Modules[1].Resources["aws_subnet.a"].Primary.Attributes["cidr"] = "10.0.2.0/24"
+	if err := s.WriteState(current); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if store.puts != 1 {
+		t.Fatalf("wrote %d shards; want 1", store.puts)
+	}
+	names := store.shardNames()
+	if len(names) != 2 {
+		t.Fatalf("wrong shards %#v", names)
+	}
+
+	other := store.state(ShardByModule)
+	if err := other.RefreshState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if got := other.State(); !got.Equal(current) || got.Serial != s.State().Serial {
+		t.Fatalf("wrong state\ngot:  %s\nwant: %s", got, current)
+	}
+
+	// Reading a module only reads its shard.
+	store.gets = 0
+	partial, err := other.ReadModules([][]string{{"root", "network"}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if store.gets != 1 {
+		t.Fatalf("read %d shards; want 1", store.gets)
+	}
+	if len(partial.Modules) != 1 || partial.Modules[0].Resources["aws_subnet.a"] == nil {
+		t.Fatalf("wrong partial state:\n%s", partial)
+	}
+}
+
+func TestShardedState_resourceType(t *testing.T) {
+	store := newShardStore()
+	s := store.state(ShardByResourceType)
+
+	current := testShardedStateFixture()
+	if err := s.WriteState(current); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	names := store.shardNames()
+	if len(names) != 3 {
+		t.Fatalf("wrong shards %#v; want one for each resource type of each module", names)
+	}
+	for _, prefix := range []string{"root.network~aws_subnet~", "root~aws_instance~", "root~aws_vpc~"} {
+		found := false
+		for _, name := range names {
+			found = found || strings.HasPrefix(name, prefix)
+		}
+		if !found {
+			t.Fatalf("no shard for %s in %#v", prefix, names)
+		}
+	}
+
+	other := store.state(ShardByModule)
+	if err := other.RefreshState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !other.State().Equal(current) {
+		t.Fatalf("wrong state\ngot:  %s\nwant: %s", other.State(), current)
+	}
+}
+
+func TestShardedState_writeResourcesConcurrent(t *testing.T) {
+	store := newShardStore()
+	manifest := &updateClient{memClient: store.manifest}
+	p := store.state(ShardByModule)
+	p.Manifest = manifest
+	q := store.state(ShardByModule)
+	q.Manifest = manifest
+
+	base := testShardedStateFixture()
+	if err := p.WriteState(base); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := p.PersistState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	write := func(s *ShardedState, cidr string) {
+		id, err := s.LockResources(state.NewLockInfo(), []string{"module.network.aws_subnet.a"})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		v := base.DeepCopy()
+		v.Modules[1].Resources["aws_subnet.a"].Primary.Attributes["cidr"] = cidr
+		if err := s.WriteResources(id, base, v); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if err := s.UnlockResources(id); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// The second write stores the content of the shard that the first
+	// removes, after the first has written the manifest but before it has
+	// removed the shard.
+	manifest.afterUpdate = func() {
+		manifest.afterUpdate = nil
+		write(q, "10.0.1.0/24")
+	}
+	write(p, "10.0.2.0/24")
+
+	other := store.state(ShardByModule)
+	if err := other.RefreshState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if got := other.State(); !got.Equal(base) {
+		t.Fatalf("wrong state\ngot:  %s\nwant: %s", got, base)
+	}
+	if got := store.shardNames(); len(got) != 2 {
+		t.Fatalf("wrong shards %#v", got)
+	}
+}
+
+func TestShardContentName(t *testing.T) {
+	cases := map[string]string{
+		"root~0123456789abcdef":                "root~0123456789abcdef",
+		"root~0123456789abcdef-01234567":       "root~0123456789abcdef",
+		"root.web-servers~0123456789abcdef":    "root.web-servers~0123456789abcdef",
+		"root~aws_instance~0123456789abcdef-0": "root~aws_instance~0123456789abcdef-0",
+	}
+	for name, want := range cases {
+		if got := shardContentName(name); got != want {
+			t.Errorf("%s: got %q; want %q", name, got, want)
+		}
+	}
+}
+
+func TestShardedState_unsharded(t *testing.T) {
+	store := newShardStore()
+
+	// A state stored as a single object is read, and stored as shards when
+	// it's persisted.
+	current := testShardedStateFixture()
+	var buf bytes.Buffer
+	if err := terraform.WriteState(current, &buf); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	store.manifest.data = buf.Bytes()
+
+	s := store.state(ShardByModule)
+	if err := s.RefreshState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !s.State().Equal(current) {
+		t.Fatalf("wrong state\ngot:  %s\nwant: %s", s.State(), current)
+	}
+
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if got := store.shardNames(); len(got) != 2 {
+		t.Fatalf("wrong shards %#v", got)
+	}
+}
+
+func testShardedStateFixture() *terraform.State {
+	s := terraform.NewState()
+	s.Lineage = "sharded"
+	root := s.RootModule()
+	root.Resources["aws_instance.web"] = &terraform.ResourceState{
+		Type: "aws_instance",
+		Primary: &terraform.InstanceState{
+			ID:         "i-web",
+			Attributes: map[string]string{"id": "i-web"},
+		},
+	}
+	root.Resources["aws_vpc.main"] = &terraform.ResourceState{
+		Type: "aws_vpc",
+		Primary: &terraform.InstanceState{
+			ID:         "vpc-1",
+			Attributes: map[string]string{"id": "vpc-1"},
+		},
+	}
+	network := s.AddModule([]string{"root", "network"})
+	network.Resources["aws_subnet.a"] = &terraform.ResourceState{
+		Type: "aws_subnet",
+		Primary: &terraform.InstanceState{
+			ID:         "subnet-a",
+			Attributes: map[string]string{"id": "subnet-a", "cidr": "10.0.1.0/24"},
+		},
+	}
+	return s
+}
+
+// shardStore stores a sharded state in memory, counting the reads and
+// writes of its shards.
+type shardStore struct {
+	manifest   *memClient
+	shards     map[string]*memClient
+	gets, puts int
+}
+
+func newShardStore() *shardStore {
+	return &shardStore{
+		manifest: &memClient{},
+		shards:   make(map[string]*memClient),
+	}
+}
+
+func (s *shardStore) state(by ShardBy) *ShardedState {
+	return &ShardedState{
+		Manifest: s.manifest,
+		Shard: func(name string) (Client, error) {
+			c := s.shards[name]
+			if c == nil {
+				c = &memClient{}
+				s.shards[name] = c
+			}
+			return &countingClient{Client: c, store: s}, nil
+		},
+		ShardBy: by,
+	}
+}
+
+// shardNames returns the names of the shards that are stored.
+func (s *shardStore) shardNames() []string {
+	var ret []string
+	for name, c := range s.shards {
+		if c.data != nil {
+			ret = append(ret, name)
+		}
+	}
+	return ret
+}
+
+type countingClient struct {
+	Client
+	store *shardStore
+}
+
+func (c *countingClient) Get() (*Payload, error) {
+	c.store.gets++
+	return c.Client.Get()
+}
+
+func (c *countingClient) Put(data []byte) error {
+	c.store.puts++
+	return c.Client.Put(data)
+}
+
+// updateClient is a memClient that implements ClientResourceLocker without
+// checking for conflicting locks, calling afterUpdate, if it's set, once
+// the manifest is updated.
+type updateClient struct {
+	*memClient
+	afterUpdate func()
+}
+
+func (c *updateClient) Lock(info *state.LockInfo) (string, error) {
+	return info.ID, nil
+}
+
+func (c *updateClient) Unlock(id string) error {
+	return nil
+}
+
+func (c *updateClient) LockResources(info *state.LockInfo, addrs []string) (string, error) {
+	return info.ID, nil
+}
+
+func (c *updateClient) UnlockResources(id string) error {
+	return nil
+}
+
+func (c *updateClient) Update(f func(*Payload) ([]byte, error)) error {
+	payload, err := c.Get()
+	if err != nil {
+		return err
+	}
+	data, err := f(payload)
+	if err != nil {
+		return err
+	}
+	if err := c.Put(data); err != nil {
+		return err
+	}
+	if c.afterUpdate != nil {
+		c.afterUpdate()
+	}
+	return nil
+}
@@ -2,10 +2,12 @@ package remote
 
 import (
 	"bytes"
+	"errors"
 	"log"
 	"sync"
 
 	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/encryption"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -19,6 +21,21 @@ type State struct {
 	Client Client
 
 	state, readState *terraform.State
+	encryption       *encryption.Encryption
+
+	// resourceLocks are the addresses of the resource locks taken through
+	// this State, by lock ID.
+	resourceLocks map[string][]string
+}
+
+// SetEncryption encrypts the stored state with the given Encryption. An
+// existing unencrypted state can only be read if the Encryption allows it,
+// and is then encrypted when the state is next persisted.
+func (s *State) SetEncryption(e *encryption.Encryption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.encryption = e
 }
 
 // StateReader impl.
@@ -77,7 +94,7 @@ func (s *State) RefreshState() error {
 		return nil
 	}
 
-	state, err := terraform.ReadState(bytes.NewReader(payload.Data))
+	state, err := s.readPayload(payload)
 	if err != nil {
 		return err
 	}
@@ -100,13 +117,12 @@ func (s *State) PersistState() error {
 		s.state.Serial++
 	}
 
-	var buf bytes.Buffer
-	if err := terraform.WriteState(s.state, &buf); err != nil {
+	data, err := s.encodeState(s.state)
+	if err != nil {
 		return err
 	}
 
-	err := s.Client.Put(buf.Bytes())
-	if err != nil {
+	if err := s.Client.Put(data); err != nil {
 		return err
 	}
 
@@ -137,3 +153,133 @@ func (s *State) Unlock(id string) error {
 	}
 	return nil
 }
+
+// ResourceLockingSupported returns true if the Client implements
+// ClientResourceLocker, and its ResourceLockingSupported method, if any,
+// returns true.
+func (s *State) ResourceLockingSupported() bool {
+	return clientResourceLockingSupported(s.Client)
+}
+
+// LockResources calls the Client's LockResources method. It fails if the
+// Client doesn't implement ClientResourceLocker.
+//
+// state.ResourceLocker impl.
+func (s *State) LockResources(info *state.LockInfo, addrs []string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.Client.(ClientResourceLocker)
+	if !ok {
+		return "", errResourceLockingNotSupported
+	}
+
+	id, err := c.LockResources(info, addrs)
+	if err != nil {
+		return "", err
+	}
+	if s.resourceLocks == nil {
+		s.resourceLocks = make(map[string][]string)
+	}
+	s.resourceLocks[id] = append([]string(nil), addrs...)
+	return id, nil
+}
+
+// UnlockResources calls the Client's UnlockResources method.
+//
+// state.ResourceLocker impl.
+func (s *State) UnlockResources(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.Client.(ClientResourceLocker)
+	if !ok {
+		return errResourceLockingNotSupported
+	}
+
+	if err := c.UnlockResources(id); err != nil {
+		return err
+	}
+	delete(s.resourceLocks, id)
+	return nil
+}
+
+// WriteResources merges the resources covered by the given resource lock
+// into the stored state, using the Client's Update method so that the
+// stored state can't change during the merge.
+//
+// state.ResourceLocker impl.
+func (s *State) WriteResources(id string, base, v *terraform.State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.Client.(ClientResourceLocker)
+	if !ok {
+		return errResourceLockingNotSupported
+	}
+	addrs, ok := s.resourceLocks[id]
+	if !ok {
+		return &state.LockError{Err: errors.New("invalid resource lock id")}
+	}
+
+	var merged *terraform.State
+	err := c.Update(func(payload *Payload) ([]byte, error) {
+		var current *terraform.State
+		if payload != nil {
+			var err error
+			current, err = s.readPayload(payload)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var err error
+		merged, err = state.MergeResources(current, base, v, addrs)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil && !merged.MarshalEqual(current) {
+			merged.Serial = current.Serial + 1
+		}
+		return s.encodeState(merged)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.state = merged
+	s.readState = merged.DeepCopy()
+	return nil
+}
+
+// readPayload decodes the state from the given payload, decrypting it if
+// encryption is set.
+func (s *State) readPayload(payload *Payload) (*terraform.State, error) {
+	data := payload.Data
+	if s.encryption != nil {
+		var err error
+		data, err = s.encryption.Decrypt(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return terraform.ReadState(bytes.NewReader(data))
+}
+
+// encodeState encodes the given state for storing, encrypting it if
+// encryption is set.
+func (s *State) encodeState(v *terraform.State) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := terraform.WriteState(v, &buf); err != nil {
+		return nil, err
+	}
+
+	data := buf.Bytes()
+	if s.encryption != nil {
+		return s.encryption.Encrypt(data)
+	}
+	return data, nil
+}
+
+var errResourceLockingNotSupported = errors.New("the remote state client doesn't support resource locking")
@@ -1,10 +1,12 @@
 package remote
 
 import (
+	"bytes"
 	"sync"
 	"testing"
 
 	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/encryption"
 )
 
 func TestState_impl(t *testing.T) {
@@ -13,6 +15,55 @@ func TestState_impl(t *testing.T) {
 	var _ state.StatePersister = new(State)
 	var _ state.StateRefresher = new(State)
 	var _ state.Locker = new(State)
+	var _ state.Encrypter = new(State)
+}
+
+func TestState_encryption(t *testing.T) {
+	client := &memClient{}
+	s := &State{Client: client}
+	s.SetEncryption(encryption.TestEncryption())
+
+	current := state.TestStateInitial()
+	if err := s.WriteState(current); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Contains(client.data, []byte("encrypted_state")) || bytes.Contains(client.data, []byte("lineage")) {
+		t.Fatalf("stored state is not encrypted:\n%s", client.data)
+	}
+
+	other := &State{Client: client}
+	other.SetEncryption(encryption.TestEncryption())
+	if err := other.RefreshState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !other.State().Equal(current) {
+		t.Fatalf("wrong state\ngot:  %s\nwant: %s", other.State(), current)
+	}
+}
+
+// memClient stores the state in memory
+type memClient struct {
+	data []byte
+}
+
+func (c *memClient) Get() (*Payload, error) {
+	if c.data == nil {
+		return nil, nil
+	}
+	return &Payload{Data: c.data}, nil
+}
+
+func (c *memClient) Put(data []byte) error {
+	c.data = data
+	return nil
+}
+
+func (c *memClient) Delete() error {
+	c.data = nil
+	return nil
 }
 
 func TestStateRace(t *testing.T) {
@@ -0,0 +1,410 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// ResourceLocker is implemented by state managers that can lock individual
+// resources of the state instead of the whole state, so that operations
+// that change disjoint sets of resources can run concurrently.
+//
+// Only InmemState implements it unconditionally. The remote state managers
+// implement it when their client implements remote.ClientResourceLocker,
+// which the clients of the inmem backend and of the s3 backend with a
+// DynamoDB table do. LocalState doesn't implement it.
+//
+// A resource lock conflicts with a lock of the whole state and with any
+// other resource lock of an overlapping address. Conflicts are returned as
+// a *LockError, like those of Locker.
+type ResourceLocker interface {
+	// LockResources locks the resources with the given addresses, which
+	// are in the resource address format and may address resources or
+	// whole modules.
+	LockResources(info *LockInfo, addrs []string) (string, error)
+
+	// UnlockResources releases the resource lock with the given ID.
+	UnlockResources(id string) error
+
+	// WriteResources replaces the resources covered by the resource lock
+	// with the given ID with those of the state s, and persists the
+	// result. The other resources of the stored state are left as they
+	// are, so the write doesn't undo the writes of other operations. The
+	// same goes for outputs: only those that differ between s and base,
+	// the state that s was derived from, are written.
+	WriteResources(id string, base, s *terraform.State) error
+}
+
+// ResourceLockingSupported returns true if the resources of the given state
+// can be locked. A state manager that implements ResourceLocker only
+// conditionally, such as one that delegates to a client, can implement a
+// ResourceLockingSupported method to report whether it's supported.
+func ResourceLockingSupported(s State) bool {
+	if _, ok := s.(ResourceLocker); !ok {
+		return false
+	}
+	if c, ok := s.(resourceLockingChecker); ok {
+		return c.ResourceLockingSupported()
+	}
+	return true
+}
+
+type resourceLockingChecker interface {
+	ResourceLockingSupported() bool
+}
+
+// ResourceLocks is a table of resource locks, for use in implementing
+// ResourceLocker. It only detects conflicts between resource locks, so the
+// implementation must also check the lock of the whole state, if any,
+// and must serialize calls to its methods.
+//
+// The zero value is an empty table.
+type ResourceLocks struct {
+	locks map[string]*resourceLock
+}
+
+type resourceLock struct {
+	info  *LockInfo
+	addrs []string
+}
+
+// Lock adds a lock of the given addresses to the table, returning a
+// *LockError if any of them overlaps with an address that is already
+// locked.
+func (l *ResourceLocks) Lock(info *LockInfo, addrs []string) (string, error) {
+	parsed, err := parseResourceAddrs(addrs)
+	if err != nil {
+		return "", err
+	}
+
+	for _, lock := range l.locks {
+		locked, err := parseResourceAddrs(lock.addrs)
+		if err != nil {
+			return "", err
+		}
+		for _, a := range parsed {
+			for _, b := range locked {
+				if resourceAddrCovers(a, b) || resourceAddrCovers(b, a) {
+					lockErr := &LockError{
+						Info: &LockInfo{},
+						Err:  fmt.Errorf("%s is locked", b),
+					}
+					*lockErr.Info = *lock.info
+					return "", lockErr
+				}
+			}
+		}
+	}
+
+	if l.locks == nil {
+		l.locks = make(map[string]*resourceLock)
+	}
+	info.Created = time.Now().UTC()
+	l.locks[info.ID] = &resourceLock{
+		info:  info,
+		addrs: append([]string(nil), addrs...),
+	}
+	return info.ID, nil
+}
+
+// Len returns the number of locks in the table.
+func (l *ResourceLocks) Len() int {
+	return len(l.locks)
+}
+
+// resourceLocksJSON is the JSON encoding of ResourceLocks, for clients
+// that store the table with the state.
+type resourceLocksJSON struct {
+	Info  *LockInfo
+	Addrs []string
+}
+
+// MarshalJSON encodes the table as JSON, so that it can be stored by a
+// remote state client.
+func (l *ResourceLocks) MarshalJSON() ([]byte, error) {
+	locks := make(map[string]resourceLocksJSON, len(l.locks))
+	for id, lock := range l.locks {
+		locks[id] = resourceLocksJSON{Info: lock.info, Addrs: lock.addrs}
+	}
+	return json.Marshal(locks)
+}
+
+// UnmarshalJSON decodes a table encoded by MarshalJSON.
+func (l *ResourceLocks) UnmarshalJSON(data []byte) error {
+	var locks map[string]resourceLocksJSON
+	if err := json.Unmarshal(data, &locks); err != nil {
+		return err
+	}
+
+	l.locks = make(map[string]*resourceLock, len(locks))
+	for id, lock := range locks {
+		if lock.Info == nil {
+			return fmt.Errorf("resource lock %q has no info", id)
+		}
+		l.locks[id] = &resourceLock{info: lock.Info, addrs: lock.Addrs}
+	}
+	return nil
+}
+
+// Unlock removes the lock with the given ID from the table.
+func (l *ResourceLocks) Unlock(id string) error {
+	if _, ok := l.locks[id]; !ok {
+		return &LockError{Err: errors.New("invalid resource lock id")}
+	}
+	delete(l.locks, id)
+	return nil
+}
+
+// Addrs returns the addresses locked by the lock with the given ID, or an
+// error if there's no such lock.
+func (l *ResourceLocks) Addrs(id string) ([]string, error) {
+	lock, ok := l.locks[id]
+	if !ok {
+		return nil, &LockError{Err: errors.New("invalid resource lock id")}
+	}
+	return lock.addrs, nil
+}
+
+// Info returns the info of one of the locks in the table, or nil if the
+// table is empty. It's used to report a conflict with a lock of the whole
+// state.
+func (l *ResourceLocks) Info() *LockInfo {
+	for _, lock := range l.locks {
+		info := *lock.info
+		return &info
+	}
+	return nil
+}
+
+// MergeResources returns a copy of dst in which the resources covered by
+// the given addresses are replaced with those of src. The outputs of dst
+// are kept, except for those that src added, changed or removed relative to
+// base, the state that src was derived from, which are replaced with those
+// of src. If base is nil, all of the outputs of src are written, but none
+// are removed. It's used to implement WriteResources.
+//
+// dst may be nil if no state is stored yet. Otherwise, unless src is nil,
+// both states must have the same lineage.
+func MergeResources(dst, base, src *terraform.State, addrs []string) (*terraform.State, error) {
+	parsed, err := parseResourceAddrs(addrs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := dst.DeepCopy()
+	if src == nil {
+		return result, nil
+	}
+	if result == nil {
+		result = terraform.NewState()
+		result.Lineage = src.Lineage
+	}
+	src = src.DeepCopy()
+	if !result.SameLineage(src) {
+		return nil, fmt.Errorf(
+			"the state has lineage %q, but the resources to write are from lineage %q",
+			result.Lineage, src.Lineage)
+	}
+
+	for _, ms := range result.Modules {
+		for key := range ms.Resources {
+			covered, err := resourceCovered(parsed, ms.Path, key)
+			if err != nil {
+				return nil, err
+			}
+			if covered {
+				delete(ms.Resources, key)
+			}
+		}
+	}
+
+	if base != nil {
+		for _, baseMod := range base.Modules {
+			if src.ModuleByPath(baseMod.Path) != nil {
+				continue
+			}
+			if ms := result.ModuleByPath(baseMod.Path); ms != nil {
+				mergeOutputs(ms.Outputs, baseMod.Outputs, nil)
+			}
+		}
+	}
+
+	for _, srcMod := range src.Modules {
+		ms := result.ModuleByPath(srcMod.Path)
+		if ms == nil {
+			ms = result.AddModule(srcMod.Path)
+		}
+		var baseOutputs map[string]*terraform.OutputState
+		if base != nil {
+			if baseMod := base.ModuleByPath(srcMod.Path); baseMod != nil {
+				baseOutputs = baseMod.Outputs
+			}
+		}
+		if ms.Outputs == nil {
+			ms.Outputs = make(map[string]*terraform.OutputState)
+		}
+		mergeOutputs(ms.Outputs, baseOutputs, srcMod.Outputs)
+		for key, rs := range srcMod.Resources {
+			covered, err := resourceCovered(parsed, srcMod.Path, key)
+			if err != nil {
+				return nil, err
+			}
+			if covered {
+				ms.Resources[key] = rs
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// mergeOutputs sets the outputs in dst that differ between base and src to
+// those of src, removing those that src doesn't have.
+func mergeOutputs(dst, base, src map[string]*terraform.OutputState) {
+	for name, out := range src {
+		if b, ok := base[name]; !ok || !b.Equal(out) {
+			dst[name] = out
+		}
+	}
+	for name := range base {
+		if _, ok := src[name]; !ok {
+			delete(dst, name)
+		}
+	}
+}
+
+// ResourcesEqual returns true if the resources covered by the given
+// addresses are the same in both states. It's used to check that locked
+// resources didn't change between reading the state and locking them.
+func ResourcesEqual(a, b *terraform.State, addrs []string) (bool, error) {
+	parsed, err := parseResourceAddrs(addrs)
+	if err != nil {
+		return false, err
+	}
+
+	covered := func(s *terraform.State) (map[string]*terraform.ResourceState, error) {
+		result := make(map[string]*terraform.ResourceState)
+		if s == nil {
+			return result, nil
+		}
+		for _, ms := range s.Modules {
+			for key, rs := range ms.Resources {
+				ok, err := resourceCovered(parsed, ms.Path, key)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					result[fmt.Sprintf("%v.%s", ms.Path, key)] = rs
+				}
+			}
+		}
+		return result, nil
+	}
+
+	ra, err := covered(a)
+	if err != nil {
+		return false, err
+	}
+	rb, err := covered(b)
+	if err != nil {
+		return false, err
+	}
+	if len(ra) != len(rb) {
+		return false, nil
+	}
+	for k, rs := range ra {
+		other, ok := rb[k]
+		if !ok || !rs.Equal(other) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func parseResourceAddrs(addrs []string) ([]*terraform.ResourceAddress, error) {
+	result := make([]*terraform.ResourceAddress, len(addrs))
+	for i, addr := range addrs {
+		parsed, err := terraform.ParseResourceAddress(addr)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = parsed
+	}
+	return result, nil
+}
+
+// resourceCovered returns true if the resource with the given key in the
+// module with the given path is covered by any of the given addresses.
+func resourceCovered(addrs []*terraform.ResourceAddress, path []string, key string) (bool, error) {
+	addr, err := terraform.ParseResourceAddressForInstanceDiff(path[1:], key)
+	if err != nil {
+		return false, err
+	}
+	for _, a := range addrs {
+		if resourceAddrCovers(a, addr) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// resourceAddrCovers returns true if the lock address covers the given
+// address. Unlike ResourceAddress.Contains, a resource address only covers
+// addresses in its own module, not those in descendant modules.
+func resourceAddrCovers(lock, addr *terraform.ResourceAddress) bool {
+	if lock.HasResourceSpec() && len(lock.Path) != len(addr.Path) {
+		return false
+	}
+	return lock.Contains(addr)
+}
+
+// ResourceLockedState is a State that writes to the state of a
+// ResourceLocker only the resources covered by one of its resource locks.
+// Writes are persisted immediately, so PersistState does nothing.
+//
+// It's used for operations that lock resources rather than the whole
+// state. Since the resources are already locked, Lock and Unlock do
+// nothing.
+type ResourceLockedState struct {
+	// Inner must implement ResourceLocker.
+	Inner State
+
+	// LockID is the ID of the resource lock.
+	LockID string
+
+	// Base is the state that the written states are derived from, which
+	// determines which of their outputs are written. See WriteResources.
+	Base *terraform.State
+}
+
+func (s *ResourceLockedState) State() *terraform.State {
+	return s.Inner.State()
+}
+
+func (s *ResourceLockedState) WriteState(v *terraform.State) error {
+	locker, ok := s.Inner.(ResourceLocker)
+	if !ok {
+		return errors.New("the state doesn't support resource locking")
+	}
+	return locker.WriteResources(s.LockID, s.Base, v)
+}
+
+func (s *ResourceLockedState) RefreshState() error {
+	return s.Inner.RefreshState()
+}
+
+func (s *ResourceLockedState) PersistState() error {
+	return nil
+}
+
+func (s *ResourceLockedState) Lock(info *LockInfo) (string, error) {
+	return "", nil
+}
+
+func (s *ResourceLockedState) Unlock(id string) error {
+	return nil
+}
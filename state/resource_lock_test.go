@@ -0,0 +1,242 @@
+package state
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestResourceLocks(t *testing.T) {
+	var locks ResourceLocks
+
+	id, err := locks.Lock(NewLockInfo(), []string{"module.a", "aws_instance.foo"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := []struct {
+		Addr     string
+		Conflict bool
+	}{
+		{"aws_instance.foo", true},
+		{"aws_instance.foo[1]", true},
+		{"aws_instance.bar", false},
+		{"data.aws_instance.foo", false},
+		{"module.a.aws_instance.bar", true},
+		{"module.b.aws_instance.foo", false},
+	}
+	for _, tc := range cases {
+		otherID, err := locks.Lock(NewLockInfo(), []string{tc.Addr})
+		if tc.Conflict {
+			if _, ok := err.(*LockError); !ok {
+				t.Fatalf("%s: wrong error %#v; want *LockError", tc.Addr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc.Addr, err)
+		}
+		if err := locks.Unlock(otherID); err != nil {
+			t.Fatalf("%s: err: %s", tc.Addr, err)
+		}
+	}
+
+	if info := locks.Info(); info == nil || info.ID != id {
+		t.Fatalf("wrong info %#v", info)
+	}
+	if err := locks.Unlock(id); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if info := locks.Info(); info != nil {
+		t.Fatalf("lock remains after unlock: %#v", info)
+	}
+	if err := locks.Unlock(id); err == nil {
+		t.Fatal("second unlock succeeded; want error")
+	}
+}
+
+func TestResourceLocks_json(t *testing.T) {
+	var locks ResourceLocks
+	id, err := locks.Lock(NewLockInfo(), []string{"aws_instance.foo"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	data, err := json.Marshal(&locks)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var decoded ResourceLocks
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if decoded.Len() != 1 {
+		t.Fatalf("decoded %d locks; want 1", decoded.Len())
+	}
+	if _, err := decoded.Lock(NewLockInfo(), []string{"aws_instance.foo[0]"}); err == nil {
+		t.Fatal("conflicting lock succeeded; want error")
+	}
+	if addrs, err := decoded.Addrs(id); err != nil || len(addrs) != 1 || addrs[0] != "aws_instance.foo" {
+		t.Fatalf("wrong addrs %#v: %v", addrs, err)
+	}
+	if err := decoded.Unlock(id); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if decoded.Len() != 0 {
+		t.Fatalf("%d locks remain after unlock", decoded.Len())
+	}
+}
+
+func TestMergeResources(t *testing.T) {
+	dst := terraform.NewState()
+	dst.RootModule().Resources["test_instance.foo"] = testResourceState("dst")
+	dst.RootModule().Resources["test_instance.bar"] = testResourceState("dst")
+	dst.AddModule([]string{"root", "child"}).Resources["test_instance.foo"] = testResourceState("dst")
+
+	dst.RootModule().Outputs["kept"] = &terraform.OutputState{Type: "string", Value: "dst"}
+	dst.RootModule().Outputs["removed"] = &terraform.OutputState{Type: "string", Value: "dst"}
+	base := dst.DeepCopy()
+
+	// Another write changed an output after src was derived from base.
+	dst.RootModule().Outputs["kept"].Value = "other"
+
+	src := base.DeepCopy()
+	src.RootModule().Resources["test_instance.foo"] = testResourceState("src")
+	src.RootModule().Resources["test_instance.bar"] = testResourceState("src")
+	src.RootModule().Resources["test_instance.baz.0"] = testResourceState("src")
+	src.RootModule().Outputs["out"] = &terraform.OutputState{Type: "string", Value: "src"}
+	delete(src.RootModule().Outputs, "removed")
+	delete(src.ModuleByPath([]string{"root", "child"}).Resources, "test_instance.foo")
+
+	merged, err := MergeResources(dst, base, src, []string{"test_instance.foo", "test_instance.baz", "module.child"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	root := merged.RootModule()
+	if got := root.Resources["test_instance.foo"].Primary.ID; got != "src" {
+		t.Fatalf("test_instance.foo wasn't merged: %s", got)
+	}
+	if got := root.Resources["test_instance.bar"].Primary.ID; got != "dst" {
+		t.Fatalf("test_instance.bar was merged: %s", got)
+	}
+	if _, ok := root.Resources["test_instance.baz.0"]; !ok {
+		t.Fatal("test_instance.baz[0] wasn't added")
+	}
+	if _, ok := merged.ModuleByPath([]string{"root", "child"}).Resources["test_instance.foo"]; ok {
+		t.Fatal("module.child.test_instance.foo wasn't removed")
+	}
+	if _, ok := root.Outputs["out"]; !ok {
+		t.Fatal("the added output wasn't merged")
+	}
+	if got := root.Outputs["kept"].Value; got != "other" {
+		t.Fatalf("the unchanged output was overwritten: %v", got)
+	}
+	if _, ok := root.Outputs["removed"]; ok {
+		t.Fatal("the removed output wasn't removed")
+	}
+
+	// dst itself isn't changed.
+	if got := dst.RootModule().Resources["test_instance.foo"].Primary.ID; got != "dst" {
+		t.Fatalf("dst was changed: %s", got)
+	}
+
+	other := terraform.NewState()
+	if _, err := MergeResources(other, nil, src, []string{"test_instance.foo"}); err == nil || !strings.Contains(err.Error(), "lineage") {
+		t.Fatalf("wrong error for different lineages: %v", err)
+	}
+}
+
+func TestResourcesEqual(t *testing.T) {
+	a := terraform.NewState()
+	a.RootModule().Resources["test_instance.foo"] = testResourceState("a")
+	a.RootModule().Resources["test_instance.bar"] = testResourceState("a")
+
+	b := a.DeepCopy()
+	b.RootModule().Resources["test_instance.bar"] = testResourceState("b")
+
+	equal, err := ResourcesEqual(a, b, []string{"test_instance.foo"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !equal {
+		t.Fatal("the resources aren't equal; want equal")
+	}
+
+	equal, err = ResourcesEqual(a, b, []string{"test_instance.bar"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if equal {
+		t.Fatal("the resources are equal; want not equal")
+	}
+}
+
+func TestInmemState_writeResources(t *testing.T) {
+	s := new(InmemState)
+	initial := terraform.NewState()
+	initial.RootModule().Resources["test_instance.foo"] = testResourceState("initial")
+	initial.RootModule().Resources["test_instance.bar"] = testResourceState("initial")
+	initial.RootModule().Outputs["foo"] = &terraform.OutputState{Type: "string", Value: "initial"}
+	initial.RootModule().Outputs["bar"] = &terraform.OutputState{Type: "string", Value: "initial"}
+	if err := s.WriteState(initial); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	fooID, err := s.LockResources(NewLockInfo(), []string{"test_instance.foo"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	barID, err := s.LockResources(NewLockInfo(), []string{"test_instance.bar"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Each write is based on the initial state, and only changes the
+	// resources of its lock and the outputs that it changed.
+	foo := initial.DeepCopy()
+	foo.RootModule().Resources["test_instance.foo"] = testResourceState("foo")
+	foo.RootModule().Outputs["foo"].Value = "foo"
+	if err := s.WriteResources(fooID, initial, foo); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	bar := initial.DeepCopy()
+	bar.RootModule().Resources["test_instance.bar"] = testResourceState("bar")
+	bar.RootModule().Outputs["bar"].Value = "bar"
+	if err := s.WriteResources(barID, initial, bar); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := s.State()
+	if got := actual.RootModule().Resources["test_instance.foo"].Primary.ID; got != "foo" {
+		t.Fatalf("wrong test_instance.foo: %s", got)
+	}
+	if got := actual.RootModule().Resources["test_instance.bar"].Primary.ID; got != "bar" {
+		t.Fatalf("wrong test_instance.bar: %s", got)
+	}
+	for _, name := range []string{"foo", "bar"} {
+		if got := actual.RootModule().Outputs[name].Value; got != name {
+			t.Fatalf("wrong output %s: %v", name, got)
+		}
+	}
+	if actual.Serial != initial.Serial+2 {
+		t.Fatalf("wrong serial %d; want %d", actual.Serial, initial.Serial+2)
+	}
+
+	if err := s.UnlockResources(fooID); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.WriteResources(fooID, initial, foo); err == nil {
+		t.Fatal("write with a released lock succeeded; want error")
+	}
+}
+
+func testResourceState(id string) *terraform.ResourceState {
+	return &terraform.ResourceState{
+		Type:    "test_instance",
+		Primary: &terraform.InstanceState{ID: id},
+	}
+}
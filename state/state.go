@@ -14,6 +14,7 @@ import (
 	"time"
 
 	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/terraform/state/encryption"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/hashicorp/terraform/version"
 )
@@ -93,16 +94,39 @@ type Locker interface {
 	Unlock(id string) error
 }
 
+// Encrypter is implemented by state managers that can encrypt the state
+// they store. Once SetEncryption is called, the state is encrypted with the
+// given Encryption whenever it is stored, and decrypted when it is read.
+type Encrypter interface {
+	SetEncryption(*encryption.Encryption)
+}
+
 // test hook to verify that LockWithContext has attempted a lock
 var postLockHook func()
 
 // Lock the state, using the provided context for timeout and cancellation.
 // This backs off slightly to an upper limit.
 func LockWithContext(ctx context.Context, s State, info *LockInfo) (string, error) {
+	return retryLock(ctx, func() (string, error) {
+		return s.Lock(info)
+	})
+}
+
+// LockResourcesWithContext locks the resources with the given addresses
+// like LockWithContext locks the whole state.
+func LockResourcesWithContext(ctx context.Context, s ResourceLocker, info *LockInfo, addrs []string) (string, error) {
+	return retryLock(ctx, func() (string, error) {
+		return s.LockResources(info, addrs)
+	})
+}
+
+// retryLock calls lock until it succeeds, fails with an error other than
+// a *LockError of an existing lock, or the context is done.
+func retryLock(ctx context.Context, lock func() (string, error)) (string, error) {
 	delay := time.Second
 	maxDelay := 16 * time.Second
 	for {
-		id, err := s.Lock(info)
+		id, err := lock()
 		if err == nil {
 			return id, nil
 		}
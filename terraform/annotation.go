@@ -0,0 +1,23 @@
+package terraform
+
+// PlanAnnotation is a note that a plan analyzer attached to the planned
+// change of a resource instance, such as an estimate of what the change
+// costs or a security finding about it. Annotations are informational:
+// they don't affect how the plan is applied.
+type PlanAnnotation struct {
+	// Addr is the address of the resource instance whose change is
+	// annotated, such as "aws_instance.web[0]".
+	Addr string `json:"address"`
+
+	// Analyzer is the name of the analyzer that made the annotation.
+	Analyzer string `json:"analyzer"`
+
+	// Kind categorizes the annotation, such as "cost" or "security". It's
+	// chosen by the analyzer, and may be empty.
+	Kind string `json:"kind,omitempty"`
+
+	// Summary is a short description of the annotation, shown next to the
+	// change, and Detail optionally describes it further.
+	Summary string `json:"summary"`
+	Detail  string `json:"detail,omitempty"`
+}
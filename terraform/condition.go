@@ -0,0 +1,136 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/terraform/tfdiags"
+)
+
+// ConditionType is the kind of a condition: a precondition, a
+// postcondition or the validation of a variable.
+type ConditionType string
+
+const (
+	ConditionTypePrecondition  ConditionType = "precondition"
+	ConditionTypePostcondition ConditionType = "postcondition"
+
+	// ConditionTypeValidation is the type of the results of the validation
+	// blocks of variables, whose address is the address of the variable.
+	ConditionTypeValidation ConditionType = "validation"
+
+	// ConditionTypeCheckData is the type of the results recorded for the
+	// data sources of a check block that couldn't be read. The address of
+	// these results is the address of the check.
+	ConditionTypeCheckData ConditionType = "check_data"
+)
+
+// ConditionStatus is the outcome of checking a condition.
+type ConditionStatus string
+
+const (
+	// ConditionUnknown means that the condition could not be checked yet,
+	// because it depends on values that are only known after apply.
+	ConditionUnknown ConditionStatus = "unknown"
+
+	ConditionPass ConditionStatus = "pass"
+	ConditionFail ConditionStatus = "fail"
+)
+
+// ConditionResult is the result of checking one precondition or
+// postcondition of a resource instance or an output, or one validation of
+// a variable.
+type ConditionResult struct {
+	// Address is the address of the object the condition belongs to, such
+	// as "aws_instance.web[0]" or "module.foo.output.ip".
+	Address string
+
+	Type ConditionType
+
+	// Index is the position of the condition among the conditions of the
+	// same type on the object, starting at zero.
+	Index int
+
+	Status ConditionStatus
+
+	// ErrorMessage is the error message of the condition if it failed.
+	ErrorMessage string
+}
+
+// ConditionResults collects the results of checking conditions during a
+// graph walk. It is safe for concurrent use.
+type ConditionResults struct {
+	lock    sync.Mutex
+	results []*ConditionResult
+}
+
+// Add records the given result.
+func (r *ConditionResults) Add(result *ConditionResult) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.results = append(r.results, result)
+}
+
+// ForAddress returns the recorded results of the given type for the given
+// address, in the order they were added.
+func (r *ConditionResults) ForAddress(addr string, typ ConditionType) []*ConditionResult {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var results []*ConditionResult
+	for _, result := range r.results {
+		if result.Address == addr && result.Type == typ {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// List returns the recorded results, sorted by address, type and index.
+func (r *ConditionResults) List() []*ConditionResult {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(r.results) == 0 {
+		return nil
+	}
+
+	results := make([]*ConditionResult, len(r.results))
+	copy(results, r.results)
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Address != b.Address {
+			return a.Address < b.Address
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Index < b.Index
+	})
+	return results
+}
+
+// ConditionError is the error for a condition that failed.
+type ConditionError struct {
+	Address string
+	Type    ConditionType
+	Message string
+}
+
+func (e *ConditionError) Error() string {
+	return fmt.Sprintf("%s: %s failed: %s", e.Address, e.Type, e.Message)
+}
+
+// DiagnosticCode implements tfdiags.Coder.
+func (e *ConditionError) DiagnosticCode() tfdiags.Code {
+	switch e.Type {
+	case ConditionTypeValidation:
+		return tfdiags.CodeVariableValidation
+	case ConditionTypePostcondition:
+		return tfdiags.CodePostcondition
+	default:
+		return tfdiags.CodePrecondition
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/terraform/tfdiags"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/tracing"
 	"github.com/hashicorp/terraform/version"
 )
 
@@ -52,8 +54,10 @@ var (
 // NewContext.
 type ContextOpts struct {
 	Meta               *ContextMeta
+	Deferred           []*DeferredChange
 	Destroy            bool
 	Diff               *Diff
+	ForceReplace       []string
 	Hooks              []Hook
 	Module             *module.Tree
 	Parallelism        int
@@ -65,11 +69,34 @@ type ContextOpts struct {
 	Targets            []string
 	Variables          map[string]interface{}
 
+	// TargetModules are the addresses of modules, such as "module.foo",
+	// that the operation is scoped to: only the resources in them and in
+	// their descendant modules are planned and applied, along with the
+	// objects they depend on, including their providers.
+	TargetModules []string
+
+	// Secrets, if non-nil, reads the values of the secret variables of the
+	// root module.
+	Secrets SecretsSource
+
+	// Explain, if set, is the address of a resource, such as
+	// "aws_instance.web", whose planned values are explained by Plan. See
+	// Explanations.
+	Explain string
+
 	// If non-nil, will apply as additional constraints on the provider
 	// plugins that will be requested from the provider resolver.
 	ProviderSHA256s    map[string][]byte
 	SkipProviderVerify bool
 
+	// RefreshParallelism limits the concurrent operations in the refresh
+	// walk separately from Parallelism. If zero, Parallelism is used.
+	RefreshParallelism int
+
+	// Tracer, if non-nil, records spans of the graph walks, the actions on
+	// each resource instance, and the calls to providers.
+	Tracer *tracing.Tracer
+
 	UIInput UIInput
 }
 
@@ -91,24 +118,33 @@ type Context struct {
 	// that newShadowContext still does the right thing. Tests should
 	// fail regardless but putting this note here as well.
 
-	components contextComponentFactory
-	destroy    bool
-	diff       *Diff
-	diffLock   sync.RWMutex
-	hooks      []Hook
-	meta       *ContextMeta
-	module     *module.Tree
-	sh         *stopHook
-	shadow     bool
-	state      *State
-	stateLock  sync.RWMutex
-	targets    []string
-	uiInput    UIInput
-	variables  map[string]interface{}
+	components    contextComponentFactory
+	deferred      []*DeferredChange
+	destroy       bool
+	diff          *Diff
+	diffLock      sync.RWMutex
+	explain       *ResourceAddress
+	explanations  []*Explanation
+	fingerprint   *Fingerprint
+	forceReplace  []*ResourceAddress
+	hooks         []Hook
+	meta          *ContextMeta
+	module        *module.Tree
+	sh            *stopHook
+	th            *traceHook
+	shadow        bool
+	state         *State
+	stateLock     sync.RWMutex
+	targets       []string
+	targetModules []string
+	uiInput       UIInput
+	variables     map[string]interface{}
 
 	l                   sync.Mutex // Lock acquired during any task
 	parallelSem         Semaphore
+	refreshSem          Semaphore
 	providerInputConfig map[string]map[string]interface{}
+	sensitive           map[string]map[string]bool
 	providerSHA256s     map[string][]byte
 	runLock             sync.Mutex
 	runCond             *sync.Cond
@@ -133,9 +169,13 @@ func NewContext(opts *ContextOpts) (*Context, error) {
 	// Copy all the hooks and add our stop hook. We don't append directly
 	// to the Config so that we're not modifying that in-place.
 	sh := new(stopHook)
-	hooks := make([]Hook, len(opts.Hooks)+1)
+	hooks := make([]Hook, len(opts.Hooks), len(opts.Hooks)+2)
 	copy(hooks, opts.Hooks)
-	hooks[len(opts.Hooks)] = sh
+	th := newTraceHook(opts.Tracer)
+	if th != nil {
+		hooks = append(hooks, th)
+	}
+	hooks = append(hooks, sh)
 
 	state := opts.State
 	if state == nil {
@@ -167,6 +207,13 @@ func NewContext(opts *ContextOpts) (*Context, error) {
 		par = 10
 	}
 
+	// Refreshing only reads from providers, so it may be run with a
+	// different limit than operations that change infrastructure.
+	refreshPar := opts.RefreshParallelism
+	if refreshPar == 0 {
+		refreshPar = par
+	}
+
 	// Set up the variables in the following sequence:
 	//    0 - Take default values from the configuration
 	//    1 - Take values from TF_VAR_x environment variables
@@ -180,10 +227,14 @@ func NewContext(opts *ContextOpts) (*Context, error) {
 		if err != nil {
 			return nil, err
 		}
+		if err := secretVariables(opts.Module, variables, opts.Secrets); err != nil {
+			return nil, err
+		}
 	}
 
 	// Bind available provider plugins to the constraints in config
 	var providers map[string]ResourceProviderFactory
+	var providerFingerprints map[string]*ProviderFingerprint
 	if opts.ProviderResolver != nil {
 		var err error
 		deps := ModuleTreeDependencies(opts.Module, state)
@@ -195,35 +246,75 @@ func NewContext(opts *ContextOpts) (*Context, error) {
 		if err != nil {
 			return nil, err
 		}
+		if f, ok := opts.ProviderResolver.(ResourceProviderFingerprinter); ok {
+			providerFingerprints = f.ProviderFingerprints()
+		}
 	} else {
 		providers = make(map[string]ResourceProviderFactory)
 	}
 
+	fingerprint, err := newFingerprint(providerFingerprints, opts.ProviderSHA256s, variables)
+	if err != nil {
+		return nil, err
+	}
+
 	diff := opts.Diff
 	if diff == nil {
 		diff = &Diff{}
 	}
 
+	var forceReplace []*ResourceAddress
+	for _, raw := range opts.ForceReplace {
+		addr, err := ParseResourceAddress(raw)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid resource address %q to replace: %s", raw, err)
+		}
+		if !addr.HasResourceSpec() || addr.Mode != config.ManagedResourceMode {
+			return nil, fmt.Errorf("Invalid resource address %q to replace: only managed resources can be replaced", raw)
+		}
+		forceReplace = append(forceReplace, addr)
+	}
+
+	var explain *ResourceAddress
+	if opts.Explain != "" {
+		addr, err := ParseResourceAddress(opts.Explain)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid resource address %q to explain: %s", opts.Explain, err)
+		}
+		if !addr.HasResourceSpec() || addr.Mode == config.EphemeralResourceMode {
+			return nil, fmt.Errorf("Invalid resource address %q to explain: must be the address of a managed or data resource", opts.Explain)
+		}
+		explain = addr
+	}
+
 	return &Context{
 		components: &basicComponentFactory{
 			providers:    providers,
 			provisioners: opts.Provisioners,
 		},
-		destroy:   opts.Destroy,
-		diff:      diff,
-		hooks:     hooks,
-		meta:      opts.Meta,
-		module:    opts.Module,
-		shadow:    opts.Shadow,
-		state:     state,
-		targets:   opts.Targets,
-		uiInput:   opts.UIInput,
-		variables: variables,
+		deferred:      opts.Deferred,
+		destroy:       opts.Destroy,
+		diff:          diff,
+		explain:       explain,
+		fingerprint:   fingerprint,
+		forceReplace:  forceReplace,
+		hooks:         hooks,
+		meta:          opts.Meta,
+		module:        opts.Module,
+		shadow:        opts.Shadow,
+		state:         state,
+		targets:       opts.Targets,
+		targetModules: opts.TargetModules,
+		uiInput:       opts.UIInput,
+		variables:     variables,
 
 		parallelSem:         NewSemaphore(par),
+		refreshSem:          NewSemaphore(refreshPar),
 		providerInputConfig: make(map[string]map[string]interface{}),
+		sensitive:           sensitiveNames(opts.Module),
 		providerSHA256s:     opts.ProviderSHA256s,
 		sh:                  sh,
+		th:                  th,
 	}, nil
 }
 
@@ -235,6 +326,18 @@ type ContextGraphOpts struct {
 	Verbose bool
 }
 
+// graphTargets returns the targets that graphs are limited to: the
+// targeted resources, and the modules that the context is scoped to, whose
+// module addresses target everything within them.
+func (c *Context) graphTargets() []string {
+	if len(c.targetModules) == 0 {
+		return c.targets
+	}
+	ret := make([]string, 0, len(c.targets)+len(c.targetModules))
+	ret = append(ret, c.targets...)
+	return append(ret, c.targetModules...)
+}
+
 // Graph returns the graph used for the given operation type.
 //
 // The most extensive or complex graph type is GraphTypePlan.
@@ -252,7 +355,7 @@ func (c *Context) Graph(typ GraphType, opts *ContextGraphOpts) (*Graph, error) {
 			State:        c.state,
 			Providers:    c.components.ResourceProviders(),
 			Provisioners: c.components.ResourceProvisioners(),
-			Targets:      c.targets,
+			Targets:      c.graphTargets(),
 			Destroy:      c.destroy,
 			Validate:     opts.Validate,
 		}).Build(RootModulePath)
@@ -266,11 +369,12 @@ func (c *Context) Graph(typ GraphType, opts *ContextGraphOpts) (*Graph, error) {
 	case GraphTypePlan:
 		// Create the plan graph builder
 		p := &PlanGraphBuilder{
-			Module:    c.module,
-			State:     c.state,
-			Providers: c.components.ResourceProviders(),
-			Targets:   c.targets,
-			Validate:  opts.Validate,
+			Module:       c.module,
+			State:        c.state,
+			Providers:    c.components.ResourceProviders(),
+			Targets:      c.graphTargets(),
+			ForceReplace: c.forceReplace,
+			Validate:     opts.Validate,
 		}
 
 		// Some special cases for other graph types shared with plan currently
@@ -291,7 +395,7 @@ func (c *Context) Graph(typ GraphType, opts *ContextGraphOpts) (*Graph, error) {
 		return (&DestroyPlanGraphBuilder{
 			Module:   c.module,
 			State:    c.state,
-			Targets:  c.targets,
+			Targets:  c.graphTargets(),
 			Validate: opts.Validate,
 		}).Build(RootModulePath)
 
@@ -300,7 +404,7 @@ func (c *Context) Graph(typ GraphType, opts *ContextGraphOpts) (*Graph, error) {
 			Module:    c.module,
 			State:     c.state,
 			Providers: c.components.ResourceProviders(),
-			Targets:   c.targets,
+			Targets:   c.graphTargets(),
 			Validate:  opts.Validate,
 		}).Build(RootModulePath)
 	}
@@ -387,6 +491,12 @@ func (c *Context) Input(mode InputMode) error {
 			var valueType config.VariableType
 
 			v := m[n]
+
+			// Secret variables are always read from their backends.
+			if v.Secret != nil {
+				continue
+			}
+
 			switch valueType = v.Type(); valueType {
 			case config.VariableTypeUnknown:
 				continue
@@ -486,14 +596,16 @@ func (c *Context) Input(mode InputMode) error {
 // Context.State, rather than rely on the return value.
 //
 // TODO: Apply and Refresh should either always return a state, or rely on the
-//       State() method. Currently the helper/resource testing framework relies
-//       on the absence of a returned state to determine if Destroy can be
-//       called, so that will need to be refactored before this can be changed.
+//
+//	State() method. Currently the helper/resource testing framework relies
+//	on the absence of a returned state to determine if Destroy can be
+//	called, so that will need to be refactored before this can be changed.
 func (c *Context) Apply() (*State, error) {
 	defer c.acquireRun("apply")()
 
 	// Copy our own state
 	c.state = c.state.DeepCopy()
+	c.state.Fingerprint = c.fingerprint
 
 	// Build the graph.
 	graph, err := c.Graph(GraphTypeApply, nil)
@@ -514,11 +626,55 @@ func (c *Context) Apply() (*State, error) {
 	}
 
 	// Clean out any unused things
+	c.pruneChecks()
 	c.state.prune()
 
 	return c.state, err
 }
 
+// ValidateApply checks the diff of this context as Apply would apply it,
+// by walking the apply graph and giving each change to its provider to
+// check instead of applying it. The problems that the providers find are
+// returned. Nothing is applied and the state of the context isn't changed.
+func (c *Context) ValidateApply() tfdiags.Diagnostics {
+	defer c.acquireRun("validate-apply")()
+
+	var diags tfdiags.Diagnostics
+
+	// Some nodes of the apply graph, such as count boundaries, update the
+	// state even when nothing is applied, which must not be kept.
+	old := c.state
+	c.state = c.state.DeepCopy()
+	defer func() {
+		c.state = old
+	}()
+
+	graph, err := c.Graph(GraphTypeApply, nil)
+	if err != nil {
+		diags = diags.Append(err)
+		return diags
+	}
+
+	walker, err := c.walk(graph, walkValidateApply)
+	if err != nil {
+		diags = diags.Append(err)
+	}
+
+	sort.Strings(walker.ValidationWarnings)
+	sort.Slice(walker.ValidationErrors, func(i, j int) bool {
+		return walker.ValidationErrors[i].Error() < walker.ValidationErrors[j].Error()
+	})
+
+	for _, warn := range walker.ValidationWarnings {
+		diags = diags.Append(tfdiags.SimpleWarning(warn))
+	}
+	for _, err := range walker.ValidationErrors {
+		diags = diags.Append(err)
+	}
+
+	return diags
+}
+
 // Plan generates an execution plan for the given context.
 //
 // The execution plan encapsulates the context and can be stored
@@ -529,14 +685,26 @@ func (c *Context) Apply() (*State, error) {
 func (c *Context) Plan() (*Plan, error) {
 	defer c.acquireRun("plan")()
 
+	if err := c.checkTargetModules(); err != nil {
+		return nil, err
+	}
+
 	p := &Plan{
 		Module:  c.module,
-		Vars:    c.variables,
+		Vars:    planVariables(c.module, c.variables),
 		State:   c.state,
 		Targets: c.targets,
 
+		TargetModules: c.targetModules,
+
 		TerraformVersion: version.String(),
 		ProviderSHA256s:  c.providerSHA256s,
+		Fingerprint:      c.fingerprint,
+	}
+	if len(c.targetModules) > 0 && c.module != nil && !c.destroy {
+		// Destroying also destroys the dependents, so only other plans
+		// skip them.
+		p.ScopeDependents = scopeDependents(c.module, c.targetModules)
 	}
 
 	var operation walkOperation
@@ -558,6 +726,37 @@ func (c *Context) Plan() (*Plan, error) {
 			c.state = old
 		}()
 
+		// Objects are moved as requested by moved blocks, removed as
+		// requested by removed blocks, and those requested by import
+		// blocks are imported, in the temporary state first, so that the
+		// plan is made with them as their prior state. The plan records
+		// the moves, removals and imports and carries the resulting state,
+		// so nothing is persisted until it is applied.
+		moves, err := c.applyConfigMoves()
+		if err != nil {
+			return nil, err
+		}
+		removed, err := c.applyConfigRemovals()
+		if err != nil {
+			return nil, err
+		}
+		imports, err := c.configImports()
+		if err != nil {
+			return nil, err
+		}
+		if len(imports) > 0 {
+			if err := c.importState(imports, c.module); err != nil {
+				return nil, fmt.Errorf("Error importing: %s", err)
+			}
+		}
+		if len(moves) > 0 || len(removed) > 0 || len(imports) > 0 {
+			old = c.state.DeepCopy()
+			p.State = old
+			p.Moves = moves
+			p.Removed = removed
+			p.Imports = imports
+		}
+
 		operation = walkPlan
 	}
 
@@ -583,6 +782,17 @@ func (c *Context) Plan() (*Plan, error) {
 		return nil, err
 	}
 	p.Diff = c.diff
+	p.Conditions = walker.Conditions.List()
+	p.Deferred = walker.Deferred.List()
+	c.deferred = p.Deferred
+	c.explanations = walker.Explain.explain(c.module, p.Diff, c.state, p.Deferred)
+	walker.Unknowns.annotate(c.module, p.Diff)
+	if !c.destroy {
+		c.pruneChecks()
+		p.Checks = c.state.Checks()
+	} else {
+		p.UnavailableDestroyRefs = unavailableDestroyRefs(c.module, p.Diff)
+	}
 
 	// If this is true, it means we're running unit tests. In this case,
 	// we perform a deep copy just to ensure that all context tests also
@@ -614,6 +824,45 @@ func (c *Context) Plan() (*Plan, error) {
 	return p, errs
 }
 
+// PlanRefreshOnly refreshes the state and then returns a plan that makes
+// no changes to infrastructure, but instead updates the state to match the
+// refreshed remote objects when it is applied.
+//
+// The state of the context is updated with the refreshed state, as with
+// Refresh.
+func (c *Context) PlanRefreshOnly() (*Plan, error) {
+	if c.destroy {
+		return nil, fmt.Errorf("a refresh-only plan cannot also be a destroy plan")
+	}
+
+	if err := c.checkTargetModules(); err != nil {
+		return nil, err
+	}
+
+	state, err := c.Refresh()
+	if err != nil {
+		return nil, err
+	}
+
+	diff := new(Diff)
+	diff.init()
+	return &Plan{
+		Diff:    diff,
+		Module:  c.module,
+		Vars:    planVariables(c.module, c.variables),
+		State:   state.DeepCopy(),
+		Targets: c.targets,
+
+		TargetModules: c.targetModules,
+
+		TerraformVersion: version.String(),
+		ProviderSHA256s:  c.providerSHA256s,
+		Fingerprint:      c.fingerprint,
+
+		RefreshOnly: true,
+	}, nil
+}
+
 // Refresh goes through all the resources in the state and refreshes them
 // to their latest state. This will update the state that this context
 // works with, along with returning it.
@@ -625,6 +874,7 @@ func (c *Context) Refresh() (*State, error) {
 
 	// Copy our own state
 	c.state = c.state.DeepCopy()
+	c.state.Fingerprint = c.fingerprint
 
 	// Build the graph.
 	graph, err := c.Graph(GraphTypeRefresh, nil)
@@ -672,6 +922,34 @@ func (c *Context) Stop() {
 	log.Printf("[WARN] terraform: stop complete")
 }
 
+// Interrupt gracefully stops the running operation, if any: no more actions
+// are started, and the actions in progress are given the grace period to
+// complete before the operation is stopped as with Stop. It returns once
+// the operation has ended or has been stopped.
+func (c *Context) Interrupt(grace time.Duration) {
+	log.Printf("[WARN] terraform: Interrupt called, waiting up to %s for actions in progress", grace)
+
+	c.l.Lock()
+	if c.runContextCancel == nil {
+		c.l.Unlock()
+		return
+	}
+	c.sh.Interrupt()
+	done := c.runContext.Done()
+	c.l.Unlock()
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		log.Printf("[WARN] terraform: interrupt complete")
+	case <-timer.C:
+		log.Printf("[WARN] terraform: actions in progress didn't complete within %s", grace)
+		c.Stop()
+	}
+}
+
 // Validate validates the configuration and returns any warnings or errors.
 func (c *Context) Validate() tfdiags.Diagnostics {
 	defer c.acquireRun("validate")()
@@ -679,15 +957,17 @@ func (c *Context) Validate() tfdiags.Diagnostics {
 	var diags tfdiags.Diagnostics
 
 	// Validate the configuration itself
-	diags = diags.Append(c.module.Validate())
+	diags = diags.Append(c.module.Validate().WithCode(tfdiags.CodeConfigInvalid))
 
 	// This only needs to be done for the root module, since inter-module
 	// variables are validated in the module tree.
 	if config := c.module.Config(); config != nil {
 		// Validate the user variables
+		var varDiags tfdiags.Diagnostics
 		for _, err := range smcUserVariables(config, c.variables) {
-			diags = diags.Append(err)
+			varDiags = varDiags.Append(err)
 		}
+		diags = diags.Append(varDiags.WithCode(tfdiags.CodeVariableInvalid))
 	}
 
 	// If we have errors at this point, the graphing has no chance,
@@ -732,6 +1012,13 @@ func (c *Context) Module() *module.Tree {
 	return c.module
 }
 
+// Explanations returns the explanations of the planned values of the
+// instances of the resource given by ContextOpts.Explain, as of the last
+// call to Plan, or nil if none were planned.
+func (c *Context) Explanations() []*Explanation {
+	return c.explanations
+}
+
 // Variables will return the mapping of variables that were defined
 // for this Context. If Input was called, this mapping may be different
 // than what was given.
@@ -744,6 +1031,30 @@ func (c *Context) SetVariable(k string, v interface{}) {
 	c.variables[k] = v
 }
 
+// pruneChecks removes the status of checks that are no longer in the
+// configuration from the state. When destroying, the status of every check
+// is removed, since nothing is left for them to check.
+func (c *Context) pruneChecks() {
+	if c.state == nil {
+		return
+	}
+
+	for _, mod := range c.state.Modules {
+		if mod == nil || len(mod.Checks) == 0 {
+			continue
+		}
+
+		var cfg *config.Config
+		if tree := c.module.Child(mod.Path[1:]); tree != nil && !c.destroy {
+			cfg = tree.Config()
+		}
+
+		for _, k := range mod.RemovedChecks(cfg) {
+			delete(mod.Checks, k)
+		}
+	}
+}
+
 func (c *Context) acquireRun(phase string) func() {
 	// With the run lock held, grab the context lock to make changes
 	// to the run context.
@@ -811,16 +1122,35 @@ func (c *Context) walk(graph *Graph, operation walkOperation) (*ContextGraphWalk
 		StopContext: c.runContext,
 	}
 
+	if operation == walkPlan {
+		walker.Explain.target = c.explain
+	}
+
+	// When applying a plan, the resources whose changes it deferred are
+	// still deferred, so that references to them are unknown.
+	if operation == walkApply {
+		if err := walker.Deferred.init(c.deferred); err != nil {
+			return nil, err
+		}
+	}
+
 	// Watch for a stop so we can call the provider Stop() API.
 	watchStop, watchWait := c.watchStop(walker)
 
 	// Walk the real graph, this will block until it completes
+	c.th.startWalk(operation)
 	realErr := graph.Walk(walker)
+	c.th.finishWalk(realErr)
 
 	// Close the channel so the watcher stops, and wait for it to return.
 	close(watchStop)
 	<-watchWait
 
+	// Close any providers that were started only for their functions
+	if err := walker.Functions.Close(); err != nil {
+		log.Printf("[WARN] error closing providers started for their functions: %s", err)
+	}
+
 	return walker, realErr
 }
 
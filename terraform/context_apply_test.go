@@ -2,6 +2,7 @@ package terraform
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"reflect"
 	"runtime"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/tracing"
 )
 
 func TestContext2Apply_basic(t *testing.T) {
@@ -1854,6 +1856,189 @@ func TestContext2Apply_badDiff(t *testing.T) {
 	}
 }
 
+func TestContext2Apply_postconditionFail(t *testing.T) {
+	m := testModule(t, "apply-postcondition-fail")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(plan.Conditions) != 1 || plan.Conditions[0].Status != ConditionUnknown {
+		t.Fatalf("bad: %s", spew.Sdump(plan.Conditions))
+	}
+
+	state, err := ctx.Apply()
+
+	expectedErr := "aws_instance.foo: postcondition failed: The instance must be baz."
+	if !strings.Contains(fmt.Sprintf("%s", err), expectedErr) {
+		t.Fatalf("expected err would contain %q\nerr: %s", expectedErr, err)
+	}
+
+	// The object was created, so it must be in the state.
+	actual := strings.TrimSpace(state.String())
+	expected := strings.TrimSpace(`
+aws_instance.foo:
+  ID = foo
+  provider = provider.aws
+  foo = bar
+  type = aws_instance
+`)
+	if actual != expected {
+		t.Fatalf("expected:\n%s\n\ngot:\n%s", expected, actual)
+	}
+}
+
+func TestContext2Apply_check(t *testing.T) {
+	m := testModule(t, "apply-check")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+	p.ReadDataDiffReturn = &InstanceDiff{
+		Attributes: map[string]*ResourceAttrDiff{
+			"foo": &ResourceAttrDiff{New: "ok"},
+		},
+	}
+	p.ReadDataApplyReturn = &InstanceState{
+		ID:         "status",
+		Attributes: map[string]string{"foo": "ok"},
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]*CheckState{
+		"check.health": &CheckState{Status: ConditionPass},
+		"check.size":   &CheckState{Status: ConditionPass},
+	}
+	if !reflect.DeepEqual(plan.Checks, expected) {
+		t.Fatalf("bad: %s", spew.Sdump(plan.Checks))
+	}
+
+	// The status is only recorded in the state once the plan is applied.
+	if actual := ctx.State().Checks(); actual != nil {
+		t.Fatalf("bad: %s", spew.Sdump(actual))
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if actual := state.Checks(); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %s", spew.Sdump(actual))
+	}
+}
+
+func TestContext2Apply_checkFail(t *testing.T) {
+	m := testModule(t, "apply-check")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+	p.ReadDataDiffReturn = &InstanceDiff{
+		Attributes: map[string]*ResourceAttrDiff{
+			"foo": &ResourceAttrDiff{New: "ok"},
+		},
+	}
+	p.ReadDataApplyReturnError = fmt.Errorf("unavailable")
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Neither the failed read nor the failed check fails the run.
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]*CheckState{
+		"check.health": &CheckState{
+			Status:          ConditionFail,
+			FailureMessages: []string{"data.aws_data_source.status: unavailable"},
+		},
+		"check.size": &CheckState{Status: ConditionPass},
+	}
+	if actual := state.Checks(); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %s", spew.Sdump(actual))
+	}
+	if rs := state.RootModule().Resources["data.aws_data_source.status"]; rs != nil {
+		t.Fatalf("data source should not be in the state: %s", rs)
+	}
+}
+
+func TestContext2Apply_checkAssertFail(t *testing.T) {
+	m := testModule(t, "apply-check")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+	p.ReadDataDiffReturn = &InstanceDiff{
+		Attributes: map[string]*ResourceAttrDiff{
+			"foo": &ResourceAttrDiff{New: "ok"},
+		},
+	}
+	p.ReadDataApplyReturn = &InstanceState{
+		ID:         "status",
+		Attributes: map[string]string{"foo": "ok"},
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		Variables: map[string]interface{}{
+			"status": "degraded",
+		},
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &CheckState{
+		Status:          ConditionFail,
+		FailureMessages: []string{"The status must be degraded."},
+	}
+	if actual := state.Checks()["check.health"]; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %s", spew.Sdump(actual))
+	}
+}
+
 func TestContext2Apply_cancel(t *testing.T) {
 	stopped := false
 
@@ -1935,6 +2120,112 @@ func TestContext2Apply_cancel(t *testing.T) {
 	}
 }
 
+func TestContext2Apply_interrupt(t *testing.T) {
+	interrupted := false
+
+	m := testModule(t, "apply-cancel")
+	p := testProvider("aws")
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	p.ApplyFn = func(*InstanceInfo, *InstanceState, *InstanceDiff) (*InstanceState, error) {
+		if !interrupted {
+			interrupted = true
+			go ctx.Interrupt(time.Minute)
+
+			for {
+				if ctx.sh.Interrupted() {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+
+		return &InstanceState{
+			ID: "foo",
+			Attributes: map[string]string{
+				"num": "2",
+			},
+		}, nil
+	}
+	p.DiffFn = func(*InstanceInfo, *InstanceState, *ResourceConfig) (*InstanceDiff, error) {
+		return &InstanceDiff{
+			Attributes: map[string]*ResourceAttrDiff{
+				"num": &ResourceAttrDiff{
+					New: "bar",
+				},
+			},
+		}, nil
+	}
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The change in progress completed, and no other change was started.
+	actual := strings.TrimSpace(state.String())
+	expected := strings.TrimSpace(testTerraformApplyCancelStr)
+	if actual != expected {
+		t.Fatalf("bad: \n%s", actual)
+	}
+
+	if p.StopCalled {
+		t.Fatal("stop should not be called")
+	}
+}
+
+func TestContext2Apply_interruptGrace(t *testing.T) {
+	m := testModule(t, "apply-cancel-block")
+	p := testProvider("aws")
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	stoppedCh := make(chan struct{})
+	p.StopFn = func() error {
+		close(stoppedCh)
+		return nil
+	}
+	p.ApplyFn = func(*InstanceInfo, *InstanceState, *InstanceDiff) (*InstanceState, error) {
+		go ctx.Interrupt(10 * time.Millisecond)
+
+		// The change doesn't complete until the provider is stopped, once
+		// the grace period has passed.
+		<-stoppedCh
+		return nil, fmt.Errorf("stopped")
+	}
+	p.DiffFn = testDiffFn
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, _ := ctx.Apply()
+	if len(state.RootModule().Resources) != 0 {
+		t.Fatalf("bad: %s", state)
+	}
+
+	if !p.StopCalled {
+		t.Fatal("stop should be called")
+	}
+}
+
 func TestContext2Apply_cancelBlock(t *testing.T) {
 	m := testModule(t, "apply-cancel-block")
 	p := testProvider("aws")
@@ -2499,6 +2790,83 @@ func TestContext2Apply_moduleBasic(t *testing.T) {
 	}
 }
 
+func TestContext2Apply_lifecycleGroup(t *testing.T) {
+	m := testModule(t, "apply-lifecycle-group")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+
+	// The destroy is slowed down so that the creates would happen first if
+	// they didn't wait for it.
+	var order []string
+	var orderLock sync.Mutex
+	p.ApplyFn = func(
+		info *InstanceInfo,
+		is *InstanceState,
+		id *InstanceDiff) (*InstanceState, error) {
+		if id.Destroy {
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		orderLock.Lock()
+		defer orderLock.Unlock()
+
+		if id.Destroy {
+			order = append(order, info.Id+" (destroy)")
+			return nil, nil
+		}
+		order = append(order, info.Id)
+		return testApplyFn(info, is, id)
+	}
+
+	state := &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"aws_instance.old": &ResourceState{
+						Type:  "aws_instance",
+						Group: "legacy",
+						Primary: &InstanceState{
+							ID: "old",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: state,
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// aws_instance.other isn't in a group that waits, so it's created
+	// while aws_instance.old is being destroyed.
+	expected := []string{"aws_instance.other", "aws_instance.old (destroy)", "aws_instance.new"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Fatalf("wrong order\ngot:  %#v\nwant: %#v", order, expected)
+	}
+
+	rs := state.RootModule().Resources["aws_instance.new"]
+	if rs == nil || rs.Group != "replacement" {
+		t.Fatalf("group not recorded in state: %#v", rs)
+	}
+}
+
 func TestContext2Apply_moduleDestroyOrder(t *testing.T) {
 	m := testModule(t, "apply-module-destroy-order")
 	p := testProvider("aws")
@@ -6837,6 +7205,163 @@ func TestContext2Apply_errorPartial(t *testing.T) {
 	}
 }
 
+func TestContext2Apply_lifecycleTimeout(t *testing.T) {
+	m := testModule(t, "apply-lifecycle-timeout")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	p.ApplyContextFn = func(ctx context.Context, info *InstanceInfo, s *InstanceState, d *InstanceDiff) (*InstanceState, error) {
+		<-ctx.Done()
+		return &InstanceState{
+			ID: "foo",
+			Attributes: map[string]string{
+				"num": "2",
+			},
+		}, ctx.Err()
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err == nil {
+		t.Fatal("should have error")
+	}
+	if !strings.Contains(err.Error(), "timeout while creating: the lifecycle timeout of 10ms was reached") {
+		t.Fatalf("bad: %s", err)
+	}
+
+	// The partial state is recorded, and is tainted so that the object is
+	// replaced by the next apply.
+	rs := state.RootModule().Resources["aws_instance.foo"]
+	if rs == nil || rs.Primary == nil || rs.Primary.ID != "foo" || !rs.Primary.Tainted {
+		t.Fatalf("bad: %s", state)
+	}
+}
+
+func TestContext2Apply_lifecycleTimeoutAbandoned(t *testing.T) {
+	defer func(d time.Duration) { applyTimeoutGrace = d }(applyTimeoutGrace)
+	applyTimeoutGrace = 10 * time.Millisecond
+
+	m := testModule(t, "apply-lifecycle-timeout")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	p.ApplyFn = func(info *InstanceInfo, s *InstanceState, d *InstanceDiff) (*InstanceState, error) {
+		// The provider doesn't stop when the apply is cancelled.
+		<-doneCh
+		return nil, nil
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err == nil {
+		t.Fatal("should have error")
+	}
+	if !strings.Contains(err.Error(), "timeout while creating") {
+		t.Fatalf("bad: %s", err)
+	}
+	if rs := state.RootModule().Resources["aws_instance.foo"]; rs != nil {
+		t.Fatalf("bad: %s", state)
+	}
+}
+
+type testTraceExporter struct {
+	sync.Mutex
+	spans []*tracing.Span
+}
+
+func (e *testTraceExporter) Export(spans []*tracing.Span) error {
+	e.Lock()
+	defer e.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func TestContext2Apply_tracing(t *testing.T) {
+	m := testModule(t, "apply-good")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+	e := &testTraceExporter{}
+	tracer := tracing.NewTracer(e)
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		Tracer: tracer,
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := ctx.Apply(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := tracer.Shutdown(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Only the spans of the apply walk are checked, since the provider
+	// calls are named the same in each walk.
+	var walk *tracing.Span
+	for _, s := range e.spans {
+		if s.Name == "terraform.apply" {
+			walk = s
+		}
+	}
+	if walk == nil {
+		t.Fatal("no span for the apply walk")
+	}
+	spans := make(map[string]*tracing.Span)
+	for _, s := range e.spans {
+		if s.TraceID == walk.TraceID && s.Start.After(walk.Start) && s.End.Before(walk.End) {
+			spans[s.Name] = s
+		}
+	}
+
+	instance := spans["apply aws_instance.foo"]
+	if instance == nil {
+		t.Fatalf("no span for applying aws_instance.foo")
+	}
+	if instance.ParentID != walk.SpanID {
+		t.Fatalf("instance span has parent %q; want %q", instance.ParentID, walk.SpanID)
+	}
+	call := spans["provider.aws/Apply"]
+	if call == nil {
+		t.Fatalf("no span for the provider call")
+	}
+	if call.Kind != tracing.KindClient {
+		t.Fatalf("call span has kind %d", call.Kind)
+	}
+	if call.ParentID != instance.SpanID && call.ParentID != spans["apply aws_instance.bar"].SpanID {
+		t.Fatalf("call span has parent %q; want an instance span", call.ParentID)
+	}
+}
+
 func TestContext2Apply_hook(t *testing.T) {
 	m := testModule(t, "apply-good")
 	h := new(MockHook)
@@ -9206,3 +9731,225 @@ func TestContext2Apply_providersFromState(t *testing.T) {
 	}
 
 }
+
+func TestContext2Apply_ephemeral(t *testing.T) {
+	m := testModule(t, "apply-ephemeral")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+	var tokens []interface{}
+	p.ConfigureFn = func(c *ResourceConfig) error {
+		v, _ := c.Get("token")
+		tokens = append(tokens, v)
+		return nil
+	}
+
+	pVault := testProvider("vault")
+	opened := 0
+	pVault.OpenEphemeralFn = func(info *InstanceInfo, c *ResourceConfig) (*InstanceState, error) {
+		opened++
+		if info.Id != "ephemeral.vault_token.deploy" {
+			t.Fatalf("bad info: %#v", info)
+		}
+		if v, _ := c.Get("role"); v != "deploy" {
+			t.Fatalf("bad role: %#v", v)
+		}
+		return &InstanceState{
+			Attributes: map[string]string{"value": "s3cr3t"},
+		}, nil
+	}
+	closed := 0
+	pVault.CloseEphemeralFn = func(info *InstanceInfo, s *InstanceState) error {
+		closed++
+		if opened == 2 && !p.ApplyCalled {
+			t.Fatal("closed before the apply")
+		}
+		if s.Attributes["value"] != "s3cr3t" {
+			t.Fatalf("bad state: %#v", s)
+		}
+		return nil
+	}
+
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws":   testProviderFuncFixed(p),
+				"vault": testProviderFuncFixed(pVault),
+			},
+		),
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Opened and closed once each by the plan and the apply
+	if opened != 2 || closed != 2 {
+		t.Fatalf("opened %d times and closed %d times", opened, closed)
+	}
+	for _, v := range tokens {
+		if v != "s3cr3t" {
+			t.Fatalf("bad tokens: %#v", tokens)
+		}
+	}
+
+	actual := strings.TrimSpace(state.String())
+	if strings.Contains(actual, "s3cr3t") || strings.Contains(actual, "ephemeral") {
+		t.Fatalf("ephemeral value written to the state:\n%s", actual)
+	}
+	expected := strings.TrimSpace(`
+aws_instance.foo:
+  ID = foo
+  provider = provider.aws
+  num = 2
+  type = aws_instance
+`)
+	if actual != expected {
+		t.Fatalf("bad:\n%s", actual)
+	}
+}
+
+func TestContext2Apply_deferredCount(t *testing.T) {
+	m := testModule(t, "apply-deferred-count")
+	p := testProvider("aws")
+	p.DiffFn = func(info *InstanceInfo, s *InstanceState, c *ResourceConfig) (*InstanceDiff, error) {
+		// Nothing changes once created, so that the count is known after
+		// the first apply.
+		if s != nil && s.ID != "" {
+			return nil, nil
+		}
+		return testDiffFn(info, s, c)
+	}
+	p.ApplyFn = func(info *InstanceInfo, s *InstanceState, d *InstanceDiff) (*InstanceState, error) {
+		result, err := testApplyFn(info, s, d)
+		if err == nil && result != nil && info.Id == "aws_instance.foo" {
+			result.Attributes["foo"] = "2"
+		}
+		return result, err
+	}
+	providers := ResourceProviderResolverFixed(
+		map[string]ResourceProviderFactory{
+			"aws": testProviderFuncFixed(p),
+		},
+	)
+
+	ctx := testContext2(t, &ContextOpts{
+		Module:           m,
+		ProviderResolver: providers,
+	})
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(plan.Deferred) != 1 || plan.Deferred[0].Addr != "aws_instance.bar" {
+		t.Fatalf("bad deferred: %s", spew.Sdump(plan.Deferred))
+	}
+
+	// The deferral is kept by the plan, so that applying it leaves the
+	// references to the deferred resource unknown.
+	ctx, err = plan.Context(&ContextOpts{ProviderResolver: providers})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if rs := state.RootModule().Resources; len(rs) != 1 || rs["aws_instance.foo"] == nil {
+		t.Fatalf("bad state:\n%s", state)
+	}
+	// The output is unknown, and so it isn't saved.
+	if o, ok := state.RootModule().Outputs["bar_ids"]; ok {
+		t.Fatalf("bad output: %#v", o)
+	}
+
+	// The next plan has the changes that were deferred.
+	ctx = testContext2(t, &ContextOpts{
+		Module:           m,
+		State:            state,
+		ProviderResolver: providers,
+	})
+	plan, err = ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(plan.Deferred) != 0 {
+		t.Fatalf("bad deferred: %s", spew.Sdump(plan.Deferred))
+	}
+	rs := plan.Diff.RootModule().Resources
+	if rs["aws_instance.bar.0"] == nil || rs["aws_instance.bar.1"] == nil {
+		t.Fatalf("bad diff:\n%s", plan.Diff)
+	}
+}
+
+func TestContext2Apply_providerForEach(t *testing.T) {
+	m := testModule(t, "apply-provider-for-each")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+
+	var lock sync.Mutex
+	regions := make(map[string]interface{})
+	p.ConfigureFn = func(c *ResourceConfig) error {
+		name, _ := c.Get("name")
+		region, _ := c.Get("region")
+
+		lock.Lock()
+		defer lock.Unlock()
+		regions[name.(string)] = region
+		return nil
+	}
+
+	providers := ResourceProviderResolverFixed(
+		map[string]ResourceProviderFactory{
+			"aws": testProviderFuncFixed(p),
+		},
+	)
+	ctx := testContext2(t, &ContextOpts{
+		Module:           m,
+		ProviderResolver: providers,
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The expanded configurations survive saving the plan.
+	var buf bytes.Buffer
+	if err := WritePlan(plan, &buf); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	plan, err = ReadPlan(&buf)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	ctx, err = plan.Context(&ContextOpts{ProviderResolver: providers})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]interface{}{"east": "us-east-1", "west": "us-west-2"}
+	if !reflect.DeepEqual(regions, expected) {
+		t.Fatalf("bad regions: %#v", regions)
+	}
+
+	rs := state.RootModule().Resources
+	if v := rs["aws_instance.east"].Provider; v != "provider.aws.region[east]" {
+		t.Fatalf("bad provider for east: %s", v)
+	}
+	if v := rs["aws_instance.west"].Provider; v != "provider.aws.region[west]" {
+		t.Fatalf("bad provider for west: %s", v)
+	}
+}
@@ -52,7 +52,11 @@ func (c *basicComponentFactory) ResourceProvider(typ, uid string) (ResourceProvi
 		return nil, fmt.Errorf("unknown provider %q", typ)
 	}
 
-	return f()
+	p, err := f()
+	if err != nil {
+		return nil, err
+	}
+	return newCapabilityResourceProvider(typ, p), nil
 }
 
 func (c *basicComponentFactory) ResourceProvisioner(typ, uid string) (ResourceProvisioner, error) {
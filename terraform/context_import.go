@@ -1,6 +1,11 @@
 package terraform
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/configschema"
 	"github.com/hashicorp/terraform/config/module"
 )
 
@@ -44,6 +49,7 @@ func (c *Context) Import(opts *ImportOpts) (*State, error) {
 
 	// Copy our own state
 	c.state = c.state.DeepCopy()
+	c.state.Fingerprint = c.fingerprint
 
 	// If no module is given, default to the module configured with
 	// the Context.
@@ -52,9 +58,18 @@ func (c *Context) Import(opts *ImportOpts) (*State, error) {
 		module = c.module
 	}
 
+	err := c.importState(opts.Targets, module)
+	return c.state, err
+}
+
+// importState imports the given targets into the context's state, using the
+// given module for provider configuration. The caller must hold the run
+// lock and must already have copied the state if the original is to be
+// preserved.
+func (c *Context) importState(targets []*ImportTarget, module *module.Tree) error {
 	// Initialize our graph builder
 	builder := &ImportGraphBuilder{
-		ImportTargets: opts.Targets,
+		ImportTargets: targets,
 		Module:        module,
 		Providers:     c.components.ResourceProviders(),
 	}
@@ -62,16 +77,240 @@ func (c *Context) Import(opts *ImportOpts) (*State, error) {
 	// Build the graph!
 	graph, err := builder.Build(RootModulePath)
 	if err != nil {
-		return c.state, err
+		return err
 	}
 
 	// Walk it
 	if _, err := c.walk(graph, walkImport); err != nil {
-		return c.state, err
+		return err
 	}
 
 	// Clean the state
 	c.state.prune()
 
-	return c.state, nil
+	return nil
+}
+
+// configImports returns the import targets for the import blocks in the
+// root module configuration whose resource instances are not yet in the
+// context's state. Import blocks for instances that are already in the
+// state have been applied before, and so are ignored.
+func (c *Context) configImports() ([]*ImportTarget, error) {
+	if c.module == nil || c.module.Config() == nil {
+		return nil, nil
+	}
+	cfg := c.module.Config()
+
+	var ret []*ImportTarget
+	for _, i := range cfg.Imports {
+		addr, err := ParseResourceAddress(i.To)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid import address %q: %s", i.To, err)
+		}
+		if addr.Mode != config.ManagedResourceMode || len(addr.Path) > 0 {
+			return nil, fmt.Errorf("Invalid import address %q: only managed resources in the root module can be imported", i.To)
+		}
+
+		if c.state != nil {
+			if mod := c.state.ModuleByPath(rootModulePath); mod != nil {
+				if _, ok := mod.Resources[addr.stateId()]; ok {
+					continue
+				}
+			}
+		}
+
+		// Unless the import selects a provider, use the one that the
+		// resource itself is configured with.
+		provider := i.Provider
+		if provider == "" {
+			for _, r := range cfg.Resources {
+				if r.Mode == addr.Mode && r.Type == addr.Type && r.Name == addr.Name {
+					provider = r.Provider
+					break
+				}
+			}
+		}
+
+		ret = append(ret, &ImportTarget{
+			Addr:     i.To,
+			ID:       i.ID,
+			Provider: provider,
+		})
+	}
+	return ret, nil
+}
+
+// GenerateImportConfig returns configuration for the resources that the
+// import blocks of the root module import to but that aren't declared,
+// written from the schemas of their providers and the objects that would
+// be imported, after refreshing them. It returns nil if there are no such
+// resources.
+//
+// The context's state isn't changed: generating configuration only reads
+// the objects, which are imported when the plan is applied as usual.
+func (c *Context) GenerateImportConfig() ([]byte, error) {
+	defer c.acquireRun("generate-config")()
+
+	targets, err := c.undeclaredImports()
+	if err != nil || len(targets) == 0 {
+		return nil, err
+	}
+
+	// Objects that are already in the state are written as they are, and
+	// the others are imported into a copy of the state.
+	state := c.state
+	defer func() { c.state = state }()
+	c.state = c.state.DeepCopy()
+	if c.state == nil {
+		c.state = NewState()
+	}
+
+	var missing []*ImportTarget
+	for _, target := range targets {
+		if c.importedObject(target) == nil {
+			missing = append(missing, target)
+		}
+	}
+	if len(missing) > 0 {
+		if err := c.importState(missing, c.module); err != nil {
+			return nil, err
+		}
+	}
+
+	schemas, err := c.importSchemas(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	var w importConfigWriter
+	for _, target := range targets {
+		addr, _ := ParseResourceAddress(target.Addr)
+		obj := c.importedObject(target)
+		if obj == nil {
+			return nil, fmt.Errorf(
+				"Error generating configuration for %s: no object with the ID %q was imported",
+				target.Addr, target.ID)
+		}
+		w.Resource(addr, target, schemas[target.Addr], obj.Attributes)
+	}
+	return w.Bytes()
+}
+
+// undeclaredImports returns the import targets for the import blocks in the
+// root module configuration whose resources aren't declared.
+func (c *Context) undeclaredImports() ([]*ImportTarget, error) {
+	if c.module == nil || c.module.Config() == nil {
+		return nil, nil
+	}
+	cfg := c.module.Config()
+
+	var ret []*ImportTarget
+	for _, i := range cfg.Imports {
+		addr, err := ParseResourceAddress(i.To)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid import address %q: %s", i.To, err)
+		}
+		if addr.Mode != config.ManagedResourceMode || len(addr.Path) > 0 {
+			return nil, fmt.Errorf("Invalid import address %q: only managed resources in the root module can be imported", i.To)
+		}
+
+		declared := false
+		for _, r := range cfg.Resources {
+			if r.Mode == addr.Mode && r.Type == addr.Type && r.Name == addr.Name {
+				declared = true
+				break
+			}
+		}
+		if declared {
+			continue
+		}
+		if addr.Index != -1 {
+			return nil, fmt.Errorf(
+				"Error generating configuration for %s: configuration can only be generated for imports to resources without an index",
+				i.To)
+		}
+
+		ret = append(ret, &ImportTarget{
+			Addr:     i.To,
+			ID:       i.ID,
+			Provider: i.Provider,
+		})
+	}
+	return ret, nil
+}
+
+// importedObject returns the object of the given import target in the
+// context's state, or nil if it hasn't been imported.
+func (c *Context) importedObject(target *ImportTarget) *InstanceState {
+	addr, err := ParseResourceAddress(target.Addr)
+	if err != nil {
+		return nil
+	}
+	mod := c.state.ModuleByPath(rootModulePath)
+	if mod == nil {
+		return nil
+	}
+	rs := mod.Resources[addr.stateId()]
+	if rs == nil {
+		return nil
+	}
+	return rs.Primary
+}
+
+// importSchemas returns the schemas of the resource types of the given
+// import targets, by their addresses.
+func (c *Context) importSchemas(targets []*ImportTarget) (map[string]*configschema.Block, error) {
+	// Each provider is asked once for the schemas of all of its types.
+	types := make(map[string][]string)
+	for _, target := range targets {
+		addr, _ := ParseResourceAddress(target.Addr)
+		name := resourceProviderName(addr.Type, target.Provider)
+		types[name] = append(types[name], addr.Type)
+	}
+
+	schemas := make(map[string]*ProviderSchema)
+	for name, typeNames := range types {
+		schema, err := c.providerSchema(name, typeNames)
+		if err != nil {
+			return nil, fmt.Errorf("Error loading the schema of provider %q: %s", name, err)
+		}
+		schemas[name] = schema
+	}
+
+	ret := make(map[string]*configschema.Block)
+	for _, target := range targets {
+		addr, _ := ParseResourceAddress(target.Addr)
+		name := resourceProviderName(addr.Type, target.Provider)
+		schema := schemas[name]
+		if schema == nil || schema.ResourceTypes[addr.Type] == nil {
+			return nil, fmt.Errorf(
+				"Error generating configuration for %s: provider %q has no schema for %s",
+				target.Addr, name, addr.Type)
+		}
+		ret[target.Addr] = schema.ResourceTypes[addr.Type]
+	}
+	return ret, nil
+}
+
+// providerSchema returns the schema of the given resource types from a new
+// instance of the named provider.
+func (c *Context) providerSchema(name string, typeNames []string) (*ProviderSchema, error) {
+	p, err := c.components.ResourceProvider(name, "schema."+name)
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := p.(ResourceProviderCloser); ok {
+		defer closer.Close()
+	}
+	return p.GetSchema(&ProviderSchemaRequest{ResourceTypes: typeNames})
+}
+
+// resourceProviderName returns the name of the provider of a resource of
+// the given type, without the alias of the given provider configuration.
+func resourceProviderName(typeName, provider string) string {
+	name := resourceProvider(typeName, provider)
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[:i]
+	}
+	return name
 }
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+
+	"github.com/hashicorp/terraform/config/configschema"
+	"github.com/zclconf/go-cty/cty"
 )
 
 func TestContextImport_basic(t *testing.T) {
@@ -798,6 +801,115 @@ func TestContextImport_customProvider(t *testing.T) {
 	}
 }
 
+func TestContextImport_generateConfig(t *testing.T) {
+	p := testProvider("aws")
+	m := testModule(t, "import-generate-config")
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	p.GetSchemaReturn = &ProviderSchema{
+		ResourceTypes: map[string]*configschema.Block{
+			"aws_instance": {
+				Attributes: map[string]*configschema.Attribute{
+					"id":       {Type: cty.String, Computed: true},
+					"ami":      {Type: cty.String, Required: true},
+					"cpus":     {Type: cty.Number, Optional: true},
+					"user":     {Type: cty.String, Optional: true},
+					"arn":      {Type: cty.String, Computed: true},
+					"password": {Type: cty.String, Required: true, Sensitive: true},
+					"zones":    {Type: cty.List(cty.String), Optional: true},
+					"tags":     {Type: cty.Map(cty.String), Optional: true},
+				},
+				BlockTypes: map[string]*configschema.NestedBlock{
+					"disk": {
+						Nesting: configschema.NestingSet,
+						Block: configschema.Block{
+							Attributes: map[string]*configschema.Attribute{
+								"size": {Type: cty.Number, Required: true},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	p.ImportStateReturn = []*InstanceState{
+		&InstanceState{
+			ID:        "i-def456",
+			Ephemeral: EphemeralState{Type: "aws_instance"},
+		},
+	}
+	p.RefreshFn = func(info *InstanceInfo, s *InstanceState) (*InstanceState, error) {
+		return &InstanceState{
+			ID: s.ID,
+			Attributes: map[string]string{
+				"id":             s.ID,
+				"ami":            "ami-${abc}",
+				"cpus":           "2",
+				"user":           "",
+				"arn":            "arn:aws:ec2:i-def456",
+				"password":       "secret",
+				"zones.#":        "2",
+				"zones.0":        "us-east-1a",
+				"zones.1":        "us-east-1b",
+				"tags.%":         "1",
+				"tags.Name":      "web",
+				"disk.#":         "1",
+				"disk.1234.size": "8",
+			},
+		}, nil
+	}
+
+	src, err := ctx.GenerateImportConfig()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := strings.TrimSpace(string(src))
+	expected := strings.TrimSpace(testImportGenerateConfigStr)
+	if actual != expected {
+		t.Fatalf("wrong configuration\ngot:\n%s\nwant:\n%s", actual, expected)
+	}
+
+	if req := p.GetSchemaRequest; req == nil || len(req.ResourceTypes) != 1 || req.ResourceTypes[0] != "aws_instance" {
+		t.Fatalf("wrong schema request %#v", req)
+	}
+	if mod := ctx.State().RootModule(); len(mod.Resources) != 0 {
+		t.Fatalf("generating configuration changed the state:\n%s", ctx.State())
+	}
+}
+
+func TestContextImport_generateConfigNoSchema(t *testing.T) {
+	p := testProvider("aws")
+	m := testModule(t, "import-generate-config")
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	p.ImportStateReturn = []*InstanceState{
+		&InstanceState{
+			ID:        "i-def456",
+			Ephemeral: EphemeralState{Type: "aws_instance"},
+		},
+	}
+
+	_, err := ctx.GenerateImportConfig()
+	if err == nil || !strings.Contains(err.Error(), "has no schema for aws_instance") {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
+
 const testImportStr = `
 aws_instance.foo:
   ID = foo
@@ -883,3 +995,25 @@ aws_instance.foo:
   ID = foo
   provider = provider.aws.alias
 `
+
+const testImportGenerateConfigStr = `
+# __generated__ by Terraform
+# Please review these resources and move them into your main configuration files.
+
+# __generated__ by Terraform from "i-def456"
+resource "aws_instance" "web" {
+  ami      = "ami-$${abc}"
+  cpus     = 2
+  password = ""            # sensitive
+
+  tags = {
+    "Name" = "web"
+  }
+
+  zones = ["us-east-1a", "us-east-1b"]
+
+  disk {
+    size = 8
+  }
+}
+`
@@ -0,0 +1,180 @@
+package terraform
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/diffs"
+)
+
+// applyConfigMoves moves the objects in the context's state as requested by
+// the moved blocks in the root module configuration, returning a record of
+// each instance that was moved.
+//
+// Moves are applied in the order they are declared, and a moved block whose
+// old address has no objects in the state has no effect, so that moved
+// blocks can be left in the configuration after they have been applied.
+//
+// An object moved to a different resource type is converted by the
+// provider of the new type, which may be a different provider, so that
+// the object isn't destroyed and created again.
+func (c *Context) applyConfigMoves() ([]*diffs.Move, error) {
+	if c.module == nil || c.module.Config() == nil || c.state == nil {
+		return nil, nil
+	}
+	mod := c.state.ModuleByPath(rootModulePath)
+	if mod == nil {
+		return nil, nil
+	}
+
+	// The providers of the new resource types of moves between resource
+	// types are started once, and only if they're needed.
+	providers := make(map[string]ResourceProvider)
+	defer func() {
+		for _, p := range providers {
+			if closer, ok := p.(ResourceProviderCloser); ok {
+				closer.Close()
+			}
+		}
+	}()
+
+	var ret []*diffs.Move
+	for _, m := range c.module.Config().Moved {
+		from, err := ParseResourceAddress(m.From)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid moved address %q: %s", m.From, err)
+		}
+		to, err := ParseResourceAddress(m.To)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid moved address %q: %s", m.To, err)
+		}
+		for _, addr := range []*ResourceAddress{from, to} {
+			if addr.Mode != config.ManagedResourceMode || len(addr.Path) > 0 {
+				return nil, fmt.Errorf("Invalid moved address %q: only managed resources in the root module can be moved", addr)
+			}
+		}
+
+		// Sort the keys so that the moves are recorded in a predictable
+		// order.
+		keys := make([]string, 0, len(mod.Resources))
+		for k := range mod.Resources {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			addr, err := parseResourceAddressInternal(k)
+			if err != nil {
+				return nil, err
+			}
+			if addr.Mode != from.Mode || addr.Type != from.Type || addr.Name != from.Name {
+				continue
+			}
+			if from.Index >= 0 && addr.Index != from.Index {
+				continue
+			}
+
+			newAddr := to.Copy()
+			if from.Index < 0 {
+				if to.Index >= 0 && addr.Index >= 0 {
+					// A whole resource can only be moved to a single
+					// instance if it has no count.
+					continue
+				}
+				if to.Index < 0 {
+					newAddr.Index = addr.Index
+				}
+			}
+
+			newKey := newAddr.stateId()
+			if _, exists := mod.Resources[newKey]; exists {
+				return nil, fmt.Errorf(
+					"Cannot move %s to %s: an object already exists at the new address",
+					addr, newAddr)
+			}
+
+			rs := mod.Resources[k]
+			if addr.Type != newAddr.Type {
+				if err := c.moveResourceState(providers, rs, addr, newAddr); err != nil {
+					return nil, err
+				}
+			}
+
+			mod.Resources[newKey] = rs
+			delete(mod.Resources, k)
+			ret = append(ret, &diffs.Move{From: addr.String(), To: newAddr.String()})
+		}
+	}
+	return ret, nil
+}
+
+// moveResourceState converts the state of the given resource, whose objects
+// are moved from one address to another of a different resource type, into
+// the state of the new type, using its provider. The providers that are
+// started are added to the given map.
+func (c *Context) moveResourceState(
+	providers map[string]ResourceProvider,
+	rs *ResourceState,
+	from, to *ResourceAddress) error {
+	var rc *config.Resource
+	for _, r := range c.module.Config().Resources {
+		if r.Mode == to.Mode && r.Type == to.Type && r.Name == to.Name {
+			rc = r
+			break
+		}
+	}
+	if rc == nil {
+		return fmt.Errorf(
+			"Cannot move %s to %s: the new resource isn't declared in the configuration",
+			from, to)
+	}
+	if len(rs.Deposed) > 0 {
+		return fmt.Errorf(
+			"Cannot move %s to %s: it has deposed objects, which must be "+
+				"destroyed by an apply before it can be moved to another resource type",
+			from, to)
+	}
+
+	name := resourceProviderName(to.Type, rc.Provider)
+	p, ok := providers[name]
+	if !ok {
+		var err error
+		p, err = c.components.ResourceProvider(name, "move."+name)
+		if err != nil {
+			return fmt.Errorf("Cannot move %s to %s: %s", from, to, err)
+		}
+		providers[name] = p
+	}
+
+	m, ok := p.(ResourceProviderStateMover)
+	if !ok || !providerSupports(p, ProviderCapabilityMoveState) {
+		return fmt.Errorf("Cannot move %s to %s: %s",
+			from, to, errUnsupportedCapability(name, ProviderCapabilityMoveState))
+	}
+
+	if rs.Primary != nil {
+		state, err := m.MoveResourceState(&MoveResourceStateRequest{
+			SourceProvider: resourceProviderName(from.Type, strings.TrimPrefix(rs.Provider, "provider.")),
+			SourceType:     from.Type,
+			SourceState:    rs.Primary.DeepCopy(),
+			TargetType:     to.Type,
+		})
+		if err != nil {
+			return fmt.Errorf("Cannot move %s to %s: %s", from, to, err)
+		}
+		if state == nil {
+			return fmt.Errorf(
+				"Cannot move %s to %s: provider %q returned no state", from, to, name)
+		}
+		state.Tainted = rs.Primary.Tainted
+		rs.Primary = state
+	}
+
+	log.Printf("[INFO] moved the state of %s to %s using provider %q", from, to, name)
+	rs.Type = to.Type
+	rs.Provider = "provider." + resourceProvider(to.Type, rc.Provider)
+	return nil
+}
@@ -9,6 +9,10 @@ import (
 	"strings"
 	"sync"
 	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/hashicorp/hil/ast"
+	"github.com/hashicorp/terraform/tfdiags"
 )
 
 func TestContext2Plan_basic(t *testing.T) {
@@ -902,6 +906,169 @@ func TestContext2Plan_preventDestroy_bad(t *testing.T) {
 	}
 }
 
+func TestContext2Plan_conditions(t *testing.T) {
+	m := testModule(t, "plan-conditions")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []*ConditionResult{
+		{
+			Address: "aws_instance.foo",
+			Type:    ConditionTypePostcondition,
+			Status:  ConditionUnknown,
+		},
+		{
+			Address: "aws_instance.foo",
+			Type:    ConditionTypePrecondition,
+			Status:  ConditionPass,
+		},
+		{
+			Address: "output.num",
+			Type:    ConditionTypePrecondition,
+			Status:  ConditionPass,
+		},
+	}
+	if !reflect.DeepEqual(plan.Conditions, expected) {
+		t.Fatalf("bad: %s", spew.Sdump(plan.Conditions))
+	}
+}
+
+func TestContext2Plan_conditionsFail(t *testing.T) {
+	m := testModule(t, "plan-conditions")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		Variables: map[string]interface{}{
+			"size": 1,
+		},
+	})
+
+	_, err := ctx.Plan()
+
+	expectedErr := "aws_instance.foo: precondition failed: The size must be more than 1."
+	if !strings.Contains(fmt.Sprintf("%s", err), expectedErr) {
+		t.Fatalf("expected err would contain %q\nerr: %s", expectedErr, err)
+	}
+	if p.DiffCalled {
+		t.Fatal("diff should not be called when a precondition fails")
+	}
+}
+
+func TestContext2Plan_variableValidation(t *testing.T) {
+	m := testModule(t, "plan-var-validation")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		Variables: map[string]interface{}{
+			"enable_tls":  "true",
+			"certificate": "cert.pem",
+		},
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var got []*ConditionResult
+	for _, r := range plan.Conditions {
+		if r.Type == ConditionTypeValidation {
+			got = append(got, r)
+		}
+	}
+	expected := []*ConditionResult{
+		{
+			Address: "module.child.var.name",
+			Type:    ConditionTypeValidation,
+			Status:  ConditionPass,
+		},
+		{
+			Address: "var.certificate",
+			Type:    ConditionTypeValidation,
+			Status:  ConditionPass,
+		},
+		{
+			Address: "var.size",
+			Type:    ConditionTypeValidation,
+			Status:  ConditionPass,
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("bad: %s", spew.Sdump(got))
+	}
+}
+
+func TestContext2Plan_variableValidationFail(t *testing.T) {
+	cases := map[string]struct {
+		Variables map[string]interface{}
+		Err       string
+	}{
+		"other variable": {
+			map[string]interface{}{"enable_tls": "true"},
+			"var.certificate: validation failed: A certificate is required when enable_tls is true.",
+		},
+		"local value": {
+			map[string]interface{}{"size": "20"},
+			"var.size: validation failed: The size must be at most 10.",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			m := testModule(t, "plan-var-validation")
+			p := testProvider("aws")
+			p.DiffFn = testDiffFn
+			ctx := testContext2(t, &ContextOpts{
+				Module: m,
+				ProviderResolver: ResourceProviderResolverFixed(
+					map[string]ResourceProviderFactory{
+						"aws": testProviderFuncFixed(p),
+					},
+				),
+				Variables: tc.Variables,
+			})
+
+			_, err := ctx.Plan()
+			if !strings.Contains(fmt.Sprintf("%s", err), tc.Err) {
+				t.Fatalf("expected err would contain %q\nerr: %s", tc.Err, err)
+			}
+			var diags tfdiags.Diagnostics
+			diags = diags.Append(err)
+			if got, want := tfdiags.GetCode(diags[0]), tfdiags.CodeVariableValidation; got != want {
+				t.Fatalf("wrong code %q; want %q", got, want)
+			}
+			if p.DiffCalled {
+				t.Fatal("diff should not be called when a validation fails")
+			}
+		})
+	}
+}
+
 func TestContext2Plan_preventDestroy_good(t *testing.T) {
 	m := testModule(t, "plan-prevent-destroy-good")
 	p := testProvider("aws")
@@ -1152,6 +1319,37 @@ func TestContext2Plan_computed(t *testing.T) {
 	}
 }
 
+func TestContext2Plan_unknownSources(t *testing.T) {
+	m := testModule(t, "plan-computed")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	resources := plan.Diff.RootModule().Resources
+	want := map[string][]string{"foo": {"aws_instance.foo.foo"}}
+	if got := resources["aws_instance.bar"].UnknownSources; !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong unknown sources of aws_instance.bar\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	// The computed attributes of aws_instance.foo are computed by the
+	// provider, rather than derived from anything unknown.
+	if got := resources["aws_instance.foo"].UnknownSources; got != nil {
+		t.Fatalf("unexpected unknown sources of aws_instance.foo: %#v", got)
+	}
+}
+
 func TestContext2Plan_computedDataResource(t *testing.T) {
 	m := testModule(t, "plan-computed-data-resource")
 	p := testProvider("aws")
@@ -1523,9 +1721,24 @@ func TestContext2Plan_countComputed(t *testing.T) {
 		),
 	})
 
-	_, err := ctx.Plan()
-	if err == nil {
-		t.Fatal("should error")
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []*DeferredChange{
+		{Addr: "aws_instance.bar", Reason: DeferredCountUnknown},
+	}
+	if !reflect.DeepEqual(plan.Deferred, expected) {
+		t.Fatalf("bad deferred: %s", spew.Sdump(plan.Deferred))
+	}
+
+	actual := strings.TrimSpace(plan.Diff.String())
+	if strings.Contains(actual, "aws_instance.bar") {
+		t.Fatalf("deferred resource should not be in the diff:\n%s", actual)
+	}
+	if !strings.Contains(actual, "CREATE: aws_instance.foo") {
+		t.Fatalf("bad diff:\n%s", actual)
 	}
 }
 
@@ -1542,12 +1755,60 @@ func TestContext2Plan_countComputedModule(t *testing.T) {
 		),
 	})
 
-	_, err := ctx.Plan()
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
 
-	expectedErr := "aws_instance.bar: value of 'count'"
-	if !strings.Contains(fmt.Sprintf("%s", err), expectedErr) {
-		t.Fatalf("expected err would contain %q\nerr: %s\n",
-			expectedErr, err)
+	expected := []*DeferredChange{
+		{Addr: "module.child.aws_instance.bar", Reason: DeferredCountUnknown},
+	}
+	if !reflect.DeepEqual(plan.Deferred, expected) {
+		t.Fatalf("bad deferred: %s", spew.Sdump(plan.Deferred))
+	}
+}
+
+func TestContext2Plan_countComputedDependents(t *testing.T) {
+	m := testModule(t, "plan-count-computed-dependents")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Everything that depends on aws_instance.bar is deferred with it,
+	// whether through a reference, depends_on, a local or a module.
+	expected := []*DeferredChange{
+		{Addr: "aws_instance.bar", Reason: DeferredCountUnknown},
+		{Addr: "aws_instance.baz", Reason: DeferredDependency, DependsOn: "aws_instance.bar"},
+		{Addr: "aws_instance.local", Reason: DeferredDependency, DependsOn: "aws_instance.bar"},
+		{Addr: "aws_instance.output", Reason: DeferredDependency, DependsOn: "aws_instance.bar"},
+		{Addr: "aws_instance.qux", Reason: DeferredDependency, DependsOn: "aws_instance.baz"},
+		{Addr: "module.child.aws_instance.child", Reason: DeferredDependency, DependsOn: "aws_instance.bar"},
+	}
+	if !reflect.DeepEqual(plan.Deferred, expected) {
+		t.Fatalf("bad deferred: %s", spew.Sdump(plan.Deferred))
+	}
+
+	actual := strings.TrimSpace(plan.Diff.String())
+	expectedDiff := strings.TrimSpace(`
+CREATE: aws_instance.foo
+  foo:  "" => "<computed>"
+  type: "" => "aws_instance"
+CREATE: aws_instance.independent
+`)
+	if actual != expectedDiff {
+		t.Fatalf("bad diff:\n%s", actual)
 	}
 }
 
@@ -2114,6 +2375,62 @@ func TestContext2Plan_destroy(t *testing.T) {
 	}
 }
 
+func TestContext2Plan_destroyProvisionerRefs(t *testing.T) {
+	m := testModule(t, "plan-destroy-provisioner-refs")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	s := &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"aws_instance.foo": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID: "foo",
+						},
+					},
+					"aws_instance.bar": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID: "bar",
+						},
+					},
+				},
+			},
+		},
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State:   s,
+		Destroy: true,
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Only the first provisioner refers to values that destroying removes,
+	// since aws_instance.bar is destroyed after it.
+	want := []*DestroyProvisionerRefs{
+		{
+			Resource:    "aws_instance.foo",
+			Provisioner: "shell",
+			Index:       0,
+			Refs:        []string{"local.name", "module.child.addr"},
+		},
+	}
+	if !reflect.DeepEqual(plan.UnavailableDestroyRefs, want) {
+		t.Fatalf("wrong unavailable refs\ngot:  %s\nwant: %s", spew.Sdump(plan.UnavailableDestroyRefs), spew.Sdump(want))
+	}
+}
+
 func TestContext2Plan_moduleDestroy(t *testing.T) {
 	m := testModule(t, "plan-module-destroy")
 	p := testProvider("aws")
@@ -2557,10 +2874,9 @@ func TestContext2Plan_taint(t *testing.T) {
 	}
 }
 
-func TestContext2Apply_taintIgnoreChanges(t *testing.T) {
-	m := testModule(t, "plan-taint-ignore-changes")
+func TestContext2Plan_forceReplace(t *testing.T) {
+	m := testModule(t, "plan-taint")
 	p := testProvider("aws")
-	p.ApplyFn = testApplyFn
 	p.DiffFn = testDiffFn
 	s := &State{
 		Modules: []*ModuleState{
@@ -2570,12 +2886,21 @@ func TestContext2Apply_taintIgnoreChanges(t *testing.T) {
 					"aws_instance.foo": &ResourceState{
 						Type: "aws_instance",
 						Primary: &InstanceState{
-							ID: "foo",
+							ID: "bar",
 							Attributes: map[string]string{
-								"vars": "foo",
+								"num":  "2",
+								"type": "aws_instance",
+							},
+						},
+					},
+					"aws_instance.bar": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID: "baz",
+							Attributes: map[string]string{
+								"foo":  "2",
 								"type": "aws_instance",
 							},
-							Tainted: true,
 						},
 					},
 				},
@@ -2589,7 +2914,8 @@ func TestContext2Apply_taintIgnoreChanges(t *testing.T) {
 				"aws": testProviderFuncFixed(p),
 			},
 		),
-		State: s,
+		State:        s,
+		ForceReplace: []string{"aws_instance.foo"},
 	})
 
 	plan, err := ctx.Plan()
@@ -2598,34 +2924,103 @@ func TestContext2Apply_taintIgnoreChanges(t *testing.T) {
 	}
 
 	actual := strings.TrimSpace(plan.String())
-	expected := strings.TrimSpace(testTerraformPlanTaintIgnoreChangesStr)
+	expected := strings.TrimSpace(testTerraformPlanForceReplaceStr)
 	if actual != expected {
 		t.Fatalf("bad:\n%s", actual)
 	}
+
+	rd := plan.Diff.RootModule().Resources["aws_instance.foo"]
+	if !rd.GetReplaceRequested() {
+		t.Fatalf("replacement of aws_instance.foo not recorded as requested")
+	}
 }
 
-// Fails about 50% of the time before the fix for GH-4982, covers the fix.
-func TestContext2Plan_taintDestroyInterpolatedCountRace(t *testing.T) {
-	m := testModule(t, "plan-taint-interpolated-count")
+func TestContext2Plan_forceReplaceInvalid(t *testing.T) {
+	m := testModule(t, "plan-taint")
+	p := testProvider("aws")
+	_, err := NewContext(&ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		ForceReplace: []string{"data.aws_ami.foo"},
+	})
+	if err == nil {
+		t.Fatal("expected error for data resource address")
+	}
+}
+
+func TestContext2Apply_taintIgnoreChanges(t *testing.T) {
+	m := testModule(t, "plan-taint-ignore-changes")
 	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
 	p.DiffFn = testDiffFn
 	s := &State{
 		Modules: []*ModuleState{
 			&ModuleState{
 				Path: rootModulePath,
 				Resources: map[string]*ResourceState{
-					"aws_instance.foo.0": &ResourceState{
+					"aws_instance.foo": &ResourceState{
 						Type: "aws_instance",
 						Primary: &InstanceState{
-							ID:      "bar",
+							ID: "foo",
+							Attributes: map[string]string{
+								"vars": "foo",
+								"type": "aws_instance",
+							},
 							Tainted: true,
 						},
 					},
-					"aws_instance.foo.1": &ResourceState{
-						Type:    "aws_instance",
-						Primary: &InstanceState{ID: "bar"},
-					},
-					"aws_instance.foo.2": &ResourceState{
+				},
+			},
+		},
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: s,
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := strings.TrimSpace(plan.String())
+	expected := strings.TrimSpace(testTerraformPlanTaintIgnoreChangesStr)
+	if actual != expected {
+		t.Fatalf("bad:\n%s", actual)
+	}
+}
+
+// Fails about 50% of the time before the fix for GH-4982, covers the fix.
+func TestContext2Plan_taintDestroyInterpolatedCountRace(t *testing.T) {
+	m := testModule(t, "plan-taint-interpolated-count")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	s := &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"aws_instance.foo.0": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID:      "bar",
+							Tainted: true,
+						},
+					},
+					"aws_instance.foo.1": &ResourceState{
+						Type:    "aws_instance",
+						Primary: &InstanceState{ID: "bar"},
+					},
+					"aws_instance.foo.2": &ResourceState{
 						Type:    "aws_instance",
 						Primary: &InstanceState{ID: "bar"},
 					},
@@ -2750,6 +3145,173 @@ STATE:
 	}
 }
 
+func TestContext2Plan_targetModule(t *testing.T) {
+	m := testModule(t, "plan-target-module")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		TargetModules: []string{"module.A"},
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := strings.TrimSpace(plan.String())
+	expected := strings.TrimSpace(`
+DIFF:
+
+module.A:
+  CREATE: aws_instance.foo
+    foo:  "" => "bar"
+    type: "" => "aws_instance"
+module.A.C:
+  CREATE: aws_instance.baz
+    foo:  "" => "baz"
+    type: "" => "aws_instance"
+
+STATE:
+
+<no state>
+	`)
+	if actual != expected {
+		t.Fatalf("expected:\n%s\n\ngot:\n%s", expected, actual)
+	}
+
+	if len(plan.Targets) != 0 {
+		t.Fatalf("scoping to modules set targets %#v", plan.Targets)
+	}
+	if !reflect.DeepEqual(plan.TargetModules, []string{"module.A"}) {
+		t.Fatalf("wrong target modules %#v", plan.TargetModules)
+	}
+	want := []string{"aws_instance.root", "module.B"}
+	if !reflect.DeepEqual(plan.ScopeDependents, want) {
+		t.Fatalf("wrong scope dependents\ngot:  %#v\nwant: %#v", plan.ScopeDependents, want)
+	}
+}
+
+func TestContext2Plan_targetModuleNested(t *testing.T) {
+	m := testModule(t, "plan-target-module")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		TargetModules: []string{"module.A.module.C"},
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := strings.TrimSpace(plan.Diff.String())
+	expected := strings.TrimSpace(`
+module.A.C:
+  CREATE: aws_instance.baz
+    foo:  "" => "baz"
+    type: "" => "aws_instance"
+	`)
+	if actual != expected {
+		t.Fatalf("expected:\n%s\n\ngot:\n%s", expected, actual)
+	}
+	if len(plan.ScopeDependents) != 0 {
+		t.Fatalf("unexpected scope dependents %#v", plan.ScopeDependents)
+	}
+}
+
+func TestContext2Plan_targetModuleDestroy(t *testing.T) {
+	m := testModule(t, "plan-target-module")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: &State{
+			Modules: []*ModuleState{
+				&ModuleState{
+					Path: rootModulePath,
+					Resources: map[string]*ResourceState{
+						"aws_instance.unrelated": &ResourceState{
+							Type: "aws_instance",
+							Primary: &InstanceState{
+								ID: "i-abc123",
+							},
+						},
+					},
+				},
+				&ModuleState{
+					Path: []string{"root", "A"},
+					Resources: map[string]*ResourceState{
+						"aws_instance.foo": &ResourceState{
+							Type: "aws_instance",
+							Primary: &InstanceState{
+								ID: "i-def456",
+							},
+						},
+					},
+				},
+			},
+		},
+		Destroy:       true,
+		TargetModules: []string{"module.A"},
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := strings.TrimSpace(plan.Diff.String())
+	expected := strings.TrimSpace(`
+module.A:
+  DESTROY: aws_instance.foo
+	`)
+	if actual != expected {
+		t.Fatalf("expected:\n%s\n\ngot:\n%s", expected, actual)
+	}
+
+	// Destroying destroys the dependents too, so none are skipped.
+	if len(plan.ScopeDependents) != 0 {
+		t.Fatalf("unexpected scope dependents %#v", plan.ScopeDependents)
+	}
+}
+
+func TestContext2Plan_targetModuleUndeclared(t *testing.T) {
+	m := testModule(t, "plan-target-module")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		TargetModules: []string{"module.nope"},
+	})
+
+	_, err := ctx.Plan()
+	if err == nil || !strings.Contains(err.Error(), "isn't declared") {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
+
 func TestContext2Plan_targetedModuleWithProvider(t *testing.T) {
 	m := testModule(t, "plan-targeted-module-with-provider")
 	p := testProvider("null")
@@ -3639,3 +4201,577 @@ resource "aws_instance" "foo" {
 		t.Fatal("expected error")
 	}
 }
+
+func TestContext2Plan_importBlock(t *testing.T) {
+	m := testModule(t, "plan-import-block")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	p.ImportStateReturn = []*InstanceState{
+		&InstanceState{
+			ID:        "i-abc123",
+			Ephemeral: EphemeralState{Type: "aws_instance"},
+		},
+	}
+	p.RefreshFn = func(info *InstanceInfo, s *InstanceState) (*InstanceState, error) {
+		return s, nil
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !p.ImportStateCalled {
+		t.Fatal("ImportState should be called")
+	}
+	if p.ImportStateID != "i-abc123" {
+		t.Fatalf("bad import ID: %s", p.ImportStateID)
+	}
+	if len(plan.Imports) != 1 || plan.Imports[0].Addr != "aws_instance.foo" {
+		t.Fatalf("bad imports: %#v", plan.Imports)
+	}
+
+	rs := plan.State.RootModule().Resources["aws_instance.foo"]
+	if rs == nil || rs.Primary == nil || rs.Primary.ID != "i-abc123" {
+		t.Fatalf("imported object not in plan state:\n%s", plan.State)
+	}
+
+	// The imported object is updated to match the configuration rather
+	// than being created.
+	rd := plan.Diff.RootModule().Resources["aws_instance.foo"]
+	if rd == nil {
+		t.Fatalf("no diff for aws_instance.foo:\n%s", plan.Diff)
+	}
+	if rd.ChangeType() != DiffUpdate {
+		t.Fatalf("bad change type: %d", rd.ChangeType())
+	}
+}
+
+func TestContext2Plan_importBlockAlreadyImported(t *testing.T) {
+	m := testModule(t, "plan-import-block")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	s := &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"aws_instance.foo": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID: "i-abc123",
+							Attributes: map[string]string{
+								"foo": "bar",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: s,
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if p.ImportStateCalled {
+		t.Fatal("ImportState should not be called")
+	}
+	if len(plan.Imports) != 0 {
+		t.Fatalf("bad imports: %#v", plan.Imports)
+	}
+}
+
+func TestContext2Plan_movedBlock(t *testing.T) {
+	m := testModule(t, "plan-moved-block")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	s := &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"aws_instance.foo": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID: "i-abc123",
+							Attributes: map[string]string{
+								"foo":  "bar",
+								"type": "aws_instance",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: s,
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(plan.Moves) != 1 {
+		t.Fatalf("bad moves: %#v", plan.Moves)
+	}
+	if got, want := plan.Moves[0].String(), "aws_instance.foo has moved to aws_instance.bar"; got != want {
+		t.Fatalf("wrong move %q; want %q", got, want)
+	}
+
+	// The object is planned at its new address with no changes, rather
+	// than being destroyed and created again.
+	if !plan.Diff.Empty() {
+		t.Fatalf("unexpected diff:\n%s", plan.Diff)
+	}
+	root := plan.State.RootModule()
+	if _, ok := root.Resources["aws_instance.foo"]; ok {
+		t.Fatalf("object still at its old address:\n%s", plan.State)
+	}
+	if rs := root.Resources["aws_instance.bar"]; rs == nil || rs.Primary.ID != "i-abc123" {
+		t.Fatalf("object not at its new address:\n%s", plan.State)
+	}
+
+	// The state given to the context is not changed until the plan is
+	// applied.
+	if _, ok := s.RootModule().Resources["aws_instance.foo"]; !ok {
+		t.Fatalf("original state was modified:\n%s", s)
+	}
+}
+
+func TestContext2Plan_movedBlockCount(t *testing.T) {
+	m := testModule(t, "plan-moved-block-count")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	s := &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"aws_instance.foo.0": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID:         "i-abc123",
+							Attributes: map[string]string{"foo": "bar", "type": "aws_instance"},
+						},
+					},
+					"aws_instance.foo.1": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID:         "i-def456",
+							Attributes: map[string]string{"foo": "bar", "type": "aws_instance"},
+						},
+					},
+				},
+			},
+		},
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: s,
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var got []string
+	for _, m := range plan.Moves {
+		got = append(got, m.String())
+	}
+	want := []string{
+		"aws_instance.foo[0] has moved to aws_instance.bar[0]",
+		"aws_instance.foo[1] has moved to aws_instance.bar[1]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong moves\ngot:  %#v\nwant: %#v", got, want)
+	}
+	if !plan.Diff.Empty() {
+		t.Fatalf("unexpected diff:\n%s", plan.Diff)
+	}
+}
+
+func TestContext2Plan_movedBlockProvider(t *testing.T) {
+	m := testModule(t, "plan-moved-block-provider")
+	pa := testProvider("vendora")
+	pb := testProvider("vendorb")
+	pb.DiffFn = testDiffFn
+	pb.MoveResourceStateFn = func(req *MoveResourceStateRequest) (*InstanceState, error) {
+		return &InstanceState{
+			ID: req.SourceState.ID,
+			Attributes: map[string]string{
+				"foo":  req.SourceState.Attributes["foo"],
+				"type": req.TargetType,
+			},
+		}, nil
+	}
+	s := &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"vendora_instance.foo": &ResourceState{
+						Type:     "vendora_instance",
+						Provider: "provider.vendora",
+						Primary: &InstanceState{
+							ID: "i-abc123",
+							Attributes: map[string]string{
+								"foo":  "bar",
+								"type": "vendora_instance",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"vendora": testProviderFuncFixed(pa),
+				"vendorb": testProviderFuncFixed(pb),
+			},
+		),
+		State: s,
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if got, want := pb.MoveResourceStateRequest, (&MoveResourceStateRequest{
+		SourceProvider: "vendora",
+		SourceType:     "vendora_instance",
+		SourceState:    s.RootModule().Resources["vendora_instance.foo"].Primary,
+		TargetType:     "vendorb_instance",
+	}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong request\ngot:  %#v\nwant: %#v", got, want)
+	}
+	if len(plan.Moves) != 1 {
+		t.Fatalf("bad moves: %#v", plan.Moves)
+	}
+
+	// The converted object is planned at its new address with no changes.
+	if !plan.Diff.Empty() {
+		t.Fatalf("unexpected diff:\n%s", plan.Diff)
+	}
+	rs := plan.State.RootModule().Resources["vendorb_instance.foo"]
+	if rs == nil || rs.Type != "vendorb_instance" || rs.Provider != "provider.vendorb" {
+		t.Fatalf("object not at its new address:\n%s", plan.State)
+	}
+	if got := rs.Primary.Attributes["type"]; got != "vendorb_instance" {
+		t.Fatalf("state not converted:\n%s", plan.State)
+	}
+}
+
+func TestContext2Plan_movedBlockProviderUnsupported(t *testing.T) {
+	m := testModule(t, "plan-moved-block-provider")
+	pb := testProvider("vendorb")
+	s := &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"vendora_instance.foo": &ResourceState{
+						Type:    "vendora_instance",
+						Primary: &InstanceState{ID: "i-abc123"},
+					},
+				},
+			},
+		},
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"vendora": testProviderFuncFixed(testProvider("vendora")),
+				"vendorb": func() (ResourceProvider, error) {
+					// A provider built before state could be moved.
+					return &mockCapabilitiesResourceProvider{MockResourceProvider: pb}, nil
+				},
+			},
+		),
+		State: s,
+	})
+
+	_, err := ctx.Plan()
+	if err == nil || !strings.Contains(err.Error(), `provider "vendorb" doesn't support moving state`) {
+		t.Fatalf("bad: %v", err)
+	}
+	if pb.MoveResourceStateCalled {
+		t.Fatal("move resource state shouldn't be called")
+	}
+}
+
+func TestContext2Plan_removedBlock(t *testing.T) {
+	m := testModule(t, "plan-removed-block")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	s := &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"aws_instance.foo": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID: "i-abc123",
+							Attributes: map[string]string{
+								"foo":  "bar",
+								"type": "aws_instance",
+							},
+						},
+					},
+					"aws_instance.bar": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID: "i-def456",
+							Attributes: map[string]string{
+								"foo":  "bar",
+								"type": "aws_instance",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: s,
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(plan.Removed) != 1 {
+		t.Fatalf("bad removals: %#v", plan.Removed)
+	}
+	if r := plan.Removed[0]; r.Addr != "aws_instance.foo" || r.Primary == nil || r.Primary.ID != "i-abc123" {
+		t.Fatalf("wrong removal: %#v", r)
+	}
+
+	// The object is removed from the state rather than being destroyed.
+	if !plan.Diff.Empty() {
+		t.Fatalf("unexpected diff:\n%s", plan.Diff)
+	}
+	root := plan.State.RootModule()
+	if _, ok := root.Resources["aws_instance.foo"]; ok {
+		t.Fatalf("object still in the state:\n%s", plan.State)
+	}
+	if _, ok := root.Resources["aws_instance.bar"]; !ok {
+		t.Fatalf("other object removed from the state:\n%s", plan.State)
+	}
+
+	// The state given to the context is not changed until the plan is
+	// applied.
+	if _, ok := s.RootModule().Resources["aws_instance.foo"]; !ok {
+		t.Fatalf("original state was modified:\n%s", s)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, ok := state.RootModule().Resources["aws_instance.foo"]; ok {
+		t.Fatalf("object still in the applied state:\n%s", state)
+	}
+	if p.ApplyCalled {
+		t.Fatal("apply should not be called")
+	}
+}
+
+func TestContext2Plan_providerFunction(t *testing.T) {
+	m := testModule(t, "plan-provider-function")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+
+	pStrings := testProvider("strings")
+	pStrings.FunctionsReturn = []ProviderFunction{
+		{
+			Name: "split",
+			Parameters: []FunctionParameter{
+				{Name: "s", Type: ast.TypeString},
+			},
+			ReturnType: ast.TypeList,
+		},
+		{
+			Name: "upper",
+			Parameters: []FunctionParameter{
+				{Name: "s", Type: ast.TypeString},
+			},
+			ReturnType: ast.TypeString,
+		},
+	}
+	pStrings.CallFunctionFn = func(name string, args []interface{}) (interface{}, error) {
+		switch name {
+		case "split":
+			var result []interface{}
+			for _, s := range strings.Fields(args[0].(string)) {
+				result = append(result, s)
+			}
+			return result, nil
+		case "upper":
+			return strings.ToUpper(args[0].(string)), nil
+		default:
+			return nil, fmt.Errorf("unknown function %s", name)
+		}
+	}
+
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws":     testProviderFuncFixed(p),
+				"strings": testProviderFuncFixed(pStrings),
+			},
+		),
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	attrs := plan.Diff.RootModule().Resources["aws_instance.foo"].Attributes
+	if v := attrs["foo"].New; v != "HELLO" {
+		t.Fatalf("bad foo: %q", v)
+	}
+	if v := attrs["bar"].New; v != "a,b" {
+		t.Fatalf("bad bar: %q", v)
+	}
+
+	if !pStrings.CloseCalled {
+		t.Fatal("the provider started for its functions should be closed")
+	}
+}
+
+func TestContext2Plan_providerFunctionUnknown(t *testing.T) {
+	m := testModule(t, "plan-provider-function")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	pStrings := testProvider("strings")
+
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws":     testProviderFuncFixed(p),
+				"strings": testProviderFuncFixed(pStrings),
+			},
+		),
+	})
+
+	_, err := ctx.Plan()
+	if err == nil || !strings.Contains(err.Error(), `provider "strings" has no function named`) {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
+
+func TestContext2Plan_explain(t *testing.T) {
+	m := testModule(t, "plan-explain")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		Explain: "aws_instance.bar",
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expls := ctx.Explanations()
+	if len(expls) != 1 {
+		t.Fatalf("wrong number of explanations: %s", spew.Sdump(expls))
+	}
+	expl := expls[0]
+	if expl.Addr != "aws_instance.bar" {
+		t.Fatalf("wrong address: %s", expl.Addr)
+	}
+
+	args := make(map[string]*ArgumentExplanation)
+	for _, arg := range expl.Arguments {
+		args[arg.Name] = arg
+	}
+
+	ami := args["ami"]
+	if ami == nil || !ami.Configured || ami.Unknown || ami.Expression != "${var.ami}" {
+		t.Fatalf("wrong explanation of ami: %s", spew.Sdump(ami))
+	}
+	if ami.Range.Start.Line != 11 {
+		t.Fatalf("wrong range of ami: %#v", ami.Range)
+	}
+	if !reflect.DeepEqual(ami.References, []*ReferenceExplanation{{Text: "var.ami"}}) {
+		t.Fatalf("wrong references of ami: %s", spew.Sdump(ami.References))
+	}
+
+	foo := args["foo"]
+	if foo == nil || !foo.Unknown || foo.UnknownReason != "it refers to aws_instance.foo.foo, which will only be known after apply" {
+		t.Fatalf("wrong explanation of foo: %s", spew.Sdump(foo))
+	}
+	if !reflect.DeepEqual(foo.References, []*ReferenceExplanation{{Text: "aws_instance.foo.foo", Unknown: true}}) {
+		t.Fatalf("wrong references of foo: %s", spew.Sdump(foo.References))
+	}
+
+	if !reflect.DeepEqual(foo.Attributes, []string{"foo"}) {
+		t.Fatalf("wrong attributes of foo: %#v", foo.Attributes)
+	}
+}
+
+func TestContext2Plan_explainInvalid(t *testing.T) {
+	m := testModule(t, "plan-explain")
+	_, err := NewContext(&ContextOpts{
+		Module:  m,
+		Explain: "module.foo",
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
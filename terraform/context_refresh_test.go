@@ -1,12 +1,14 @@
 package terraform
 
 import (
+	"fmt"
 	"reflect"
 	"regexp"
 	"sort"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestContext2Refresh(t *testing.T) {
@@ -63,6 +65,293 @@ func TestContext2Refresh(t *testing.T) {
 	}
 }
 
+func TestContext2Refresh_lifecycleGroup(t *testing.T) {
+	p := testProvider("aws")
+	m := testModule(t, "refresh-lifecycle-group")
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: &State{
+			Modules: []*ModuleState{
+				&ModuleState{
+					Path: rootModulePath,
+					Resources: map[string]*ResourceState{
+						"aws_instance.web": &ResourceState{
+							Type: "aws_instance",
+							Primary: &InstanceState{
+								ID: "foo",
+							},
+						},
+						"aws_instance.orphan": &ResourceState{
+							Type:  "aws_instance",
+							Group: "legacy",
+							Primary: &InstanceState{
+								ID: "bar",
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	p.RefreshFn = func(info *InstanceInfo, s *InstanceState) (*InstanceState, error) {
+		return s, nil
+	}
+
+	s, err := ctx.Refresh()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The group of a resource in the configuration is recorded, and the
+	// group of one removed from it is kept.
+	mod := s.RootModule()
+	for _, name := range []string{"aws_instance.web", "aws_instance.orphan"} {
+		if got := mod.Resources[name].Group; got != "legacy" {
+			t.Fatalf("wrong group for %s: %q", name, got)
+		}
+	}
+}
+
+func TestContext2Refresh_parallelism(t *testing.T) {
+	p := testProvider("aws")
+	m := testModule(t, "refresh-parallelism")
+
+	resources := make(map[string]*ResourceState)
+	for i := 0; i < 4; i++ {
+		resources[fmt.Sprintf("aws_instance.web.%d", i)] = &ResourceState{
+			Type: "aws_instance",
+			Primary: &InstanceState{
+				ID: fmt.Sprintf("foo%d", i),
+			},
+		}
+	}
+
+	// The mock provider serializes its calls, so we count the concurrent
+	// refreshes from a hook instead.
+	h := new(concurrencyHook)
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		Hooks:  []Hook{h},
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: &State{
+			Modules: []*ModuleState{
+				&ModuleState{
+					Path:      rootModulePath,
+					Resources: resources,
+				},
+			},
+		},
+		Parallelism:        10,
+		RefreshParallelism: 1,
+	})
+
+	p.RefreshFn = func(info *InstanceInfo, s *InstanceState) (*InstanceState, error) {
+		return s, nil
+	}
+
+	if _, err := ctx.Refresh(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if h.max != 1 {
+		t.Fatalf("%d refreshes ran at once; want 1", h.max)
+	}
+}
+
+func TestContext2Refresh_batch(t *testing.T) {
+	p := testProvider("aws")
+	m := testModule(t, "refresh-parallelism")
+
+	resources := make(map[string]*ResourceState)
+	for i := 0; i < 4; i++ {
+		resources[fmt.Sprintf("aws_instance.web.%d", i)] = &ResourceState{
+			Type: "aws_instance",
+			Primary: &InstanceState{
+				ID: fmt.Sprintf("foo%d", i),
+			},
+		}
+	}
+
+	bp := &mockBatchRefreshResourceProvider{MockResourceProvider: p}
+	bp.RefreshBatchFn = func(infos []*InstanceInfo, states []*InstanceState) ([]*InstanceState, error) {
+		result := make([]*InstanceState, len(states))
+		for i, s := range states {
+			result[i] = &InstanceState{
+				ID:         s.ID,
+				Attributes: map[string]string{"refreshed": "true"},
+			}
+		}
+		return result, nil
+	}
+
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(bp),
+			},
+		),
+		State: &State{
+			Modules: []*ModuleState{
+				&ModuleState{
+					Path:      rootModulePath,
+					Resources: resources,
+				},
+			},
+		},
+	})
+
+	s, err := ctx.Refresh()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.RefreshCalled {
+		t.Fatal("refresh shouldn't be called")
+	}
+	if len(bp.RefreshBatchInfos) != 1 {
+		t.Fatalf("refresh batch called %d times; want 1", len(bp.RefreshBatchInfos))
+	}
+
+	var ids []string
+	for _, info := range bp.RefreshBatchInfos[0] {
+		ids = append(ids, info.Id)
+	}
+	want := []string{"aws_instance.web.0", "aws_instance.web.1", "aws_instance.web.2", "aws_instance.web.3"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Fatalf("wrong batch\ngot:  %#v\nwant: %#v", ids, want)
+	}
+
+	for i := 0; i < 4; i++ {
+		rs := s.RootModule().Resources[fmt.Sprintf("aws_instance.web.%d", i)]
+		if rs == nil || rs.Primary.Attributes["refreshed"] != "true" {
+			t.Fatalf("aws_instance.web.%d wasn't refreshed: %#v", i, rs)
+		}
+	}
+}
+
+func TestContext2Refresh_batchError(t *testing.T) {
+	p := testProvider("aws")
+	m := testModule(t, "refresh-parallelism")
+
+	resources := make(map[string]*ResourceState)
+	for i := 0; i < 4; i++ {
+		resources[fmt.Sprintf("aws_instance.web.%d", i)] = &ResourceState{
+			Type: "aws_instance",
+			Primary: &InstanceState{
+				ID: fmt.Sprintf("foo%d", i),
+			},
+		}
+	}
+
+	bp := &mockBatchRefreshResourceProvider{MockResourceProvider: p}
+	bp.RefreshBatchFn = func(infos []*InstanceInfo, states []*InstanceState) ([]*InstanceState, error) {
+		return nil, fmt.Errorf("throttled")
+	}
+
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(bp),
+			},
+		),
+		State: &State{
+			Modules: []*ModuleState{
+				&ModuleState{
+					Path:      rootModulePath,
+					Resources: resources,
+				},
+			},
+		},
+	})
+
+	_, err := ctx.Refresh()
+	if err == nil || !strings.Contains(err.Error(), "aws_instance.web.0 and 3 other instances: throttled") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+// concurrencyHook records the most refreshes running at once.
+type concurrencyHook struct {
+	NilHook
+
+	l            sync.Mutex
+	running, max int
+}
+
+func (h *concurrencyHook) PreRefresh(*InstanceInfo, *InstanceState) (HookAction, error) {
+	h.l.Lock()
+	h.running++
+	if h.running > h.max {
+		h.max = h.running
+	}
+	h.l.Unlock()
+
+	// Give any other refreshes a chance to start
+	time.Sleep(10 * time.Millisecond)
+	return HookActionContinue, nil
+}
+
+func (h *concurrencyHook) PostRefresh(*InstanceInfo, *InstanceState) (HookAction, error) {
+	h.l.Lock()
+	h.running--
+	h.l.Unlock()
+	return HookActionContinue, nil
+}
+
+func TestContext2Refresh_providerRetry(t *testing.T) {
+	p := testProvider("aws")
+	m := testModule(t, "refresh-provider-retry")
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: &State{
+			Modules: []*ModuleState{
+				&ModuleState{
+					Path: rootModulePath,
+					Resources: map[string]*ResourceState{
+						"aws_instance.web": &ResourceState{
+							Type: "aws_instance",
+							Primary: &InstanceState{
+								ID: "foo",
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	calls := 0
+	p.RefreshFn = func(info *InstanceInfo, s *InstanceState) (*InstanceState, error) {
+		calls++
+		if calls == 1 {
+			return nil, fmt.Errorf("throttled")
+		}
+		return s, nil
+	}
+
+	if _, err := ctx.Refresh(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if calls != 2 {
+		t.Fatalf("refresh called %d times; want 2", calls)
+	}
+}
+
 func TestContext2Refresh_dataComputedModuleVar(t *testing.T) {
 	p := testProvider("aws")
 	m := testModule(t, "refresh-data-module-var")
@@ -1157,3 +1446,66 @@ aws_instance.bar:
 		t.Fatalf("expected:\n%s\n\ngot:\n%s", expected, actual)
 	}
 }
+
+func TestContext2Refresh_planRefreshOnly(t *testing.T) {
+	p := testProvider("aws")
+	m := testModule(t, "refresh-basic")
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: &State{
+			Modules: []*ModuleState{
+				&ModuleState{
+					Path: rootModulePath,
+					Resources: map[string]*ResourceState{
+						"aws_instance.web": &ResourceState{
+							Type: "aws_instance",
+							Primary: &InstanceState{
+								ID: "foo",
+								Attributes: map[string]string{
+									"id":  "foo",
+									"foo": "bar",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	p.RefreshFn = nil
+	p.RefreshReturn = &InstanceState{
+		ID: "foo",
+		Attributes: map[string]string{
+			"id":  "foo",
+			"foo": "baz",
+		},
+	}
+
+	plan, err := ctx.PlanRefreshOnly()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !p.RefreshCalled {
+		t.Fatal("refresh should be called")
+	}
+	if p.DiffCalled {
+		t.Fatal("diff should not be called")
+	}
+	if !plan.RefreshOnly {
+		t.Fatal("plan should be refresh-only")
+	}
+	if !plan.Diff.Empty() {
+		t.Fatalf("plan should have an empty diff; got:\n%s", plan.Diff)
+	}
+
+	rs := plan.State.RootModule().Resources["aws_instance.web"]
+	if got, want := rs.Primary.Attributes["foo"], "baz"; got != want {
+		t.Fatalf("wrong refreshed value %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,76 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform/config"
+)
+
+// RemovedObject is a resource instance that a plan removes from the state
+// without destroying it, as requested by a removed block in the
+// configuration.
+type RemovedObject struct {
+	// Addr is the address of the instance, such as "aws_instance.foo[1]".
+	Addr string
+
+	// Primary is the state of the instance as of planning, which may be nil
+	// if the instance has only deposed objects.
+	Primary *InstanceState
+}
+
+// applyConfigRemovals removes the objects from the context's state as
+// requested by the removed blocks in the root module configuration,
+// returning a record of each instance that was removed.
+//
+// As with moved blocks, a removed block whose address has no objects in
+// the state has no effect, so that it can be left in the configuration
+// after it has been applied.
+func (c *Context) applyConfigRemovals() ([]*RemovedObject, error) {
+	if c.module == nil || c.module.Config() == nil || c.state == nil {
+		return nil, nil
+	}
+	mod := c.state.ModuleByPath(rootModulePath)
+	if mod == nil {
+		return nil, nil
+	}
+
+	var ret []*RemovedObject
+	for _, r := range c.module.Config().Removed {
+		from, err := ParseResourceAddress(r.From)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid removed address %q: %s", r.From, err)
+		}
+		if from.Mode != config.ManagedResourceMode || len(from.Path) > 0 {
+			return nil, fmt.Errorf("Invalid removed address %q: only managed resources in the root module can be removed", r.From)
+		}
+
+		// Sort the keys so that the removals are recorded in a predictable
+		// order.
+		keys := make([]string, 0, len(mod.Resources))
+		for k := range mod.Resources {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			addr, err := parseResourceAddressInternal(k)
+			if err != nil {
+				return nil, err
+			}
+			if addr.Mode != from.Mode || addr.Type != from.Type || addr.Name != from.Name {
+				continue
+			}
+			if from.Index >= 0 && addr.Index != from.Index {
+				continue
+			}
+
+			ret = append(ret, &RemovedObject{
+				Addr:    addr.String(),
+				Primary: mod.Resources[k].Primary,
+			})
+			delete(mod.Resources, k)
+		}
+	}
+	return ret, nil
+}
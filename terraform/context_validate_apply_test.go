@@ -0,0 +1,102 @@
+package terraform
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestContext2ValidateApply(t *testing.T) {
+	m := testModule(t, "validate-apply")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+
+	var lock sync.Mutex
+	var calls []string
+	p.ValidateApplyFn = func(
+		info *InstanceInfo, s *InstanceState, d *InstanceDiff) ([]string, []error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		kind := "update"
+		switch {
+		case d.GetDestroy():
+			kind = "destroy"
+		case s == nil:
+			kind = "create"
+		}
+		calls = append(calls, info.Id+" "+kind)
+
+		if info.Id == "aws_instance.bar" {
+			return nil, []error{errors.New("quota exceeded")}
+		}
+		return nil, nil
+	}
+
+	state := &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"aws_instance.foo": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID:         "foo",
+							Attributes: map[string]string{"require_new": "no"},
+						},
+					},
+					"aws_instance.baz": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID: "baz",
+						},
+					},
+				},
+			},
+		},
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: state,
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	before := ctx.State().String()
+
+	diags := ctx.ValidateApply()
+	if !diags.HasErrors() {
+		t.Fatal("should error")
+	}
+	if err := diags.Err().Error(); !strings.Contains(err, "aws_instance.bar: quota exceeded") {
+		t.Fatalf("bad: %s", err)
+	}
+
+	sort.Strings(calls)
+	expected := []string{
+		"aws_instance.bar create",
+		"aws_instance.baz destroy",
+		"aws_instance.foo create",
+		"aws_instance.foo destroy",
+	}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Fatalf("bad: %#v", calls)
+	}
+
+	if p.ApplyCalled {
+		t.Fatal("apply should not be called")
+	}
+	if after := ctx.State().String(); after != before {
+		t.Fatalf("state changed:\n%s", after)
+	}
+}
@@ -0,0 +1,189 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/terraform/config"
+)
+
+// DeferredReason is the reason that the changes of a resource were
+// deferred.
+type DeferredReason string
+
+const (
+	// DeferredCountUnknown means that the count of the resource depends on
+	// values that are only known after apply.
+	DeferredCountUnknown DeferredReason = "count_unknown"
+
+	// DeferredDependency means that the resource depends on a resource
+	// whose changes were deferred, directly or through variables, locals
+	// and outputs.
+	DeferredDependency DeferredReason = "dependency"
+)
+
+// DeferredChange is a resource whose changes couldn't be planned, and so
+// aren't in the diff of the plan. Applying the plan leaves the resource
+// as it is, and the changes are planned by the next plan, once the values
+// they depend on are known.
+type DeferredChange struct {
+	// Addr is the address of the resource, such as
+	// "module.foo.aws_instance.bar".
+	Addr string
+
+	Reason DeferredReason
+
+	// DependsOn is the address of the deferred resource that this one
+	// depends on, if Reason is DeferredDependency.
+	DependsOn string
+}
+
+func (c *DeferredChange) String() string {
+	switch c.Reason {
+	case DeferredCountUnknown:
+		return fmt.Sprintf("%s: count depends on values that are only known after apply", c.Addr)
+	case DeferredDependency:
+		return fmt.Sprintf("%s: depends on %s, whose changes were deferred", c.Addr, c.DependsOn)
+	default:
+		return c.Addr
+	}
+}
+
+// DeferredChanges collects the resources whose changes are deferred during
+// a graph walk, along with the variables, locals and outputs that depend on
+// them. It is safe for concurrent use.
+type DeferredChanges struct {
+	lock    sync.Mutex
+	changes []*DeferredChange
+
+	// deferred are the resources, variables, locals and outputs that are
+	// deferred, keyed by deferredKey, with the address of the deferred
+	// resource they depend on.
+	deferred map[string]string
+}
+
+// Add records the given change of the resource with the given id in the
+// module with the given path.
+func (d *DeferredChanges) Add(path []string, id string, change *DeferredChange) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.changes = append(d.changes, change)
+	d.set(path, id, change.Addr)
+}
+
+// Defer records that the variable, local or output with the given name,
+// such as "var.foo" or "output.bar", in the module with the given path
+// depends on the deferred resource with the given address.
+func (d *DeferredChanges) Defer(path []string, name string, addr string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.set(path, name, addr)
+}
+
+func (d *DeferredChanges) set(path []string, name string, addr string) {
+	if d.deferred == nil {
+		d.deferred = make(map[string]string)
+	}
+	d.deferred[deferredKey(path, name)] = addr
+}
+
+// Deferred returns true if the resource with the given id, or the
+// variable, local or output with the given name, in the module with the
+// given path is deferred. Nothing is deferred by a nil DeferredChanges.
+func (d *DeferredChanges) Deferred(path []string, name string) bool {
+	if d == nil {
+		return false
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	_, ok := d.deferred[deferredKey(path, name)]
+	return ok
+}
+
+// Dependency returns the address of a deferred resource that the given
+// configuration in the module with the given path refers to, directly or
+// through variables, locals and outputs, or "" if there is none.
+func (d *DeferredChanges) Dependency(path []string, c *config.RawConfig) string {
+	if d == nil || c == nil {
+		return ""
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	// Sort the variables so that the same dependency is reported each time.
+	keys := make([]string, 0, len(c.Variables))
+	for k := range c.Variables {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		var key string
+		switch v := c.Variables[k].(type) {
+		case *config.ResourceVariable:
+			key = deferredKey(path, v.ResourceId())
+		case *config.ModuleVariable:
+			child := append(append([]string(nil), normalizeModulePath(path)...), v.Name)
+			key = deferredKey(child, "output."+v.Field)
+		case *config.UserVariable:
+			key = deferredKey(path, "var."+v.Name)
+		case *config.LocalVariable:
+			key = deferredKey(path, "local."+v.Name)
+		default:
+			continue
+		}
+		if addr, ok := d.deferred[key]; ok {
+			return addr
+		}
+	}
+	return ""
+}
+
+// List returns the recorded changes, sorted by address.
+func (d *DeferredChanges) List() []*DeferredChange {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if len(d.changes) == 0 {
+		return nil
+	}
+
+	changes := make([]*DeferredChange, len(d.changes))
+	copy(changes, d.changes)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Addr < changes[j].Addr
+	})
+	return changes
+}
+
+// init records the given changes of a plan, so that the resources that
+// they were planned for are known to be deferred while the plan is
+// applied.
+func (d *DeferredChanges) init(changes []*DeferredChange) error {
+	for _, c := range changes {
+		addr, err := ParseResourceAddress(c.Addr)
+		if err != nil {
+			return fmt.Errorf("Invalid deferred address %q: %s", c.Addr, err)
+		}
+		id := addr.Type + "." + addr.Name
+		if addr.Mode == config.DataResourceMode {
+			id = "data." + id
+		}
+		d.Add(append([]string{"root"}, addr.Path...), id, c)
+	}
+	return nil
+}
+
+func deferredKey(path []string, name string) string {
+	path = normalizeModulePath(path)
+	if len(path) > 1 {
+		return modulePrefixStr(path) + "." + name
+	}
+	return name
+}
@@ -374,11 +374,22 @@ type InstanceDiff struct {
 	DestroyDeposed bool
 	DestroyTainted bool
 
+	// ReplaceRequested is true if the instance is being replaced because the
+	// user requested it when planning, rather than because of a change that
+	// requires a new resource.
+	ReplaceRequested bool
+
 	// Meta is a simple K/V map that is stored in a diff and persisted to
 	// plans but otherwise is completely ignored by Terraform core. It is
 	// meant to be used for additional data a resource may want to pass through.
 	// The value here must only contain Go primitives and collections.
 	Meta map[string]interface{}
+
+	// UnknownSources records, by attribute path, the references that the
+	// computed values of attributes were derived from, such as
+	// "aws_subnet.main.id", where those references were themselves unknown
+	// when the diff was planned. It is set only by Context.Plan.
+	UnknownSources map[string][]string
 }
 
 func (d *InstanceDiff) Lock()   { d.mu.Lock() }
@@ -578,6 +589,20 @@ func (d *InstanceDiff) GetDestroyTainted() bool {
 	return d.DestroyTainted
 }
 
+func (d *InstanceDiff) SetReplaceRequested(b bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.ReplaceRequested = b
+}
+
+func (d *InstanceDiff) GetReplaceRequested() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.ReplaceRequested
+}
+
 func (d *InstanceDiff) SetDestroy(b bool) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
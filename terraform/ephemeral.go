@@ -0,0 +1,55 @@
+package terraform
+
+import (
+	"sync"
+)
+
+// EphemeralValues holds the objects of the ephemeral resources that are
+// open during a graph walk. Unlike the objects of other resources, these
+// are never written to the state, so that their values, such as
+// short-lived credentials, don't outlive the walk. It is safe for
+// concurrent use.
+type EphemeralValues struct {
+	lock   sync.Mutex
+	values map[string]*InstanceState
+}
+
+// Set records the object of the ephemeral resource with the given id in
+// the module with the given path.
+func (v *EphemeralValues) Set(path []string, id string, s *InstanceState) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if v.values == nil {
+		v.values = make(map[string]*InstanceState)
+	}
+	v.values[ephemeralKey(path, id)] = s
+}
+
+// Get returns the object of the ephemeral resource with the given id in the
+// module with the given path, or nil if it isn't open.
+func (v *EphemeralValues) Get(path []string, id string) *InstanceState {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	return v.values[ephemeralKey(path, id)]
+}
+
+// Remove forgets the object of the ephemeral resource with the given id in
+// the module with the given path, returning it.
+func (v *EphemeralValues) Remove(path []string, id string) *InstanceState {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	s := v.values[ephemeralKey(path, id)]
+	delete(v.values, ephemeralKey(path, id))
+	return s
+}
+
+func ephemeralKey(path []string, id string) string {
+	path = normalizeModulePath(path)
+	if len(path) > 1 {
+		return modulePrefixStr(path) + "." + id
+	}
+	return id
+}
@@ -1,9 +1,11 @@
 package terraform
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strconv"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/terraform/config"
@@ -19,8 +21,17 @@ type EvalApply struct {
 	Output    **InstanceState
 	CreateNew *bool
 	Error     *error
+
+	// Timeouts are the timeouts from the lifecycle of the resource, which
+	// limit how long the provider may take to apply the diff.
+	Timeouts config.ResourceTimeouts
 }
 
+// applyTimeoutGrace is how long EvalApply waits for a provider to return
+// once the timeout of its Apply has passed and the Apply was cancelled,
+// so that the partial state of the instance can be recorded.
+var applyTimeoutGrace = 30 * time.Second
+
 // TODO: test
 func (n *EvalApply) Eval(ctx EvalContext) (interface{}, error) {
 	diff := *n.Diff
@@ -54,12 +65,24 @@ func (n *EvalApply) Eval(ctx EvalContext) (interface{}, error) {
 
 	// With the completed diff, apply!
 	log.Printf("[DEBUG] apply: %s: executing Apply", n.Info.Id)
-	state, err := provider.Apply(n.Info, state, diff)
+	timeout, verb := n.timeout(state, diff)
+	creating := state.ID == "" || diff.RequiresNew()
+	state, timedOut, err := applyWithTimeout(provider, n.Info, state, diff, timeout)
+	if timedOut {
+		err = multierror.Append(err, fmt.Errorf(
+			"timeout while %s: the lifecycle timeout of %s was reached", verb, timeout))
+	}
 	if state == nil {
 		state = new(InstanceState)
 	}
 	state.init()
 
+	// An object that timed out while being created may be incomplete, so
+	// it is replaced by the next apply.
+	if timedOut && creating && state.ID != "" {
+		state.Tainted = true
+	}
+
 	// Force the "id" attribute to be our ID
 	if state.ID != "" {
 		state.Attributes["id"] = state.ID
@@ -94,6 +117,120 @@ func (n *EvalApply) Eval(ctx EvalContext) (interface{}, error) {
 	return nil, nil
 }
 
+// timeout returns the lifecycle timeout that applies to the given diff of
+// the instance with the given state, and a description of the change for
+// messages. An instance that is replaced by a single Apply is limited by
+// the sum of its delete and create timeouts, and isn't limited unless both
+// are set.
+func (n *EvalApply) timeout(state *InstanceState, diff *InstanceDiff) (time.Duration, string) {
+	t := n.Timeouts
+	switch {
+	case diff.GetDestroy() && !diff.RequiresNew():
+		return t.Delete, "destroying"
+	case state.ID == "":
+		return t.Create, "creating"
+	case diff.RequiresNew():
+		if t.Delete == 0 || t.Create == 0 {
+			return 0, "replacing"
+		}
+		return t.Delete + t.Create, "replacing"
+	default:
+		return t.Update, "updating"
+	}
+}
+
+// applyWithTimeout calls the provider's Apply, giving up once the given
+// timeout has passed if it isn't zero, and reports whether it timed out.
+//
+// When the timeout passes, the Apply is cancelled if the provider
+// implements ResourceProviderContextApplier, and the provider's partial
+// state is returned if it returns within applyTimeoutGrace. Otherwise the
+// Apply is abandoned, and the given state is returned since the effect of
+// the Apply isn't known.
+func applyWithTimeout(
+	provider ResourceProvider,
+	info *InstanceInfo,
+	state *InstanceState,
+	diff *InstanceDiff,
+	timeout time.Duration) (*InstanceState, bool, error) {
+	if timeout == 0 {
+		newState, err := provider.Apply(info, state, diff)
+		return newState, false, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	type result struct {
+		state *InstanceState
+		err   error
+	}
+	doneCh := make(chan result, 1)
+	go func() {
+		// The provider gets its own copies, since it may still be using
+		// them after the Apply is abandoned.
+		s, d := state.DeepCopy(), diff.DeepCopy()
+		var r result
+		r.state, r.err = ApplyWithContext(ctx, provider, info, s, d)
+		doneCh <- r
+	}()
+
+	select {
+	case r := <-doneCh:
+		return r.state, false, r.err
+	case <-ctx.Done():
+	}
+
+	log.Printf("[WARN] apply: %s: timed out after %s, waiting up to %s for the provider to stop",
+		info.Id, timeout, applyTimeoutGrace)
+	select {
+	case r := <-doneCh:
+		return r.state, true, r.err
+	case <-time.After(applyTimeoutGrace):
+		log.Printf("[WARN] apply: %s: provider didn't stop, abandoning the apply", info.Id)
+		return state, true, nil
+	}
+}
+
+// EvalValidateApply is an EvalNode implementation that gives a diff to the
+// provider to check that it could be applied, without applying it.
+type EvalValidateApply struct {
+	Info     *InstanceInfo
+	State    **InstanceState
+	Diff     **InstanceDiff
+	Provider *ResourceProvider
+}
+
+func (n *EvalValidateApply) Eval(ctx EvalContext) (interface{}, error) {
+	diff := *n.Diff
+	provider := *n.Provider
+	state := *n.State
+
+	// If we have no diff, we have nothing to check!
+	if diff.Empty() {
+		return nil, nil
+	}
+
+	// Remove any output values from the diff, as for apply
+	diff = diff.DeepCopy()
+	for k, ad := range diff.CopyAttributes() {
+		if ad.Type == DiffAttrOutput {
+			diff.DelAttribute(k)
+		}
+	}
+
+	log.Printf("[DEBUG] apply: %s: executing ValidateApply", n.Info.Id)
+	warns, errs := provider.ValidateApply(n.Info, state, diff)
+	if len(warns) == 0 && len(errs) == 0 {
+		return nil, nil
+	}
+
+	return nil, &EvalValidateError{
+		Warnings: warns,
+		Errors:   errs,
+	}
+}
+
 // EvalApplyPre is an EvalNode implementation that does the pre-Apply work
 type EvalApplyPre struct {
 	Info  *InstanceInfo
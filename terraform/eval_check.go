@@ -0,0 +1,120 @@
+package terraform
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform/config"
+)
+
+// EvalCheck is an EvalNode implementation that checks the assertions of a
+// check block and writes the status of the check to the state. Unlike
+// preconditions and postconditions, a failing check never returns an
+// error.
+type EvalCheck struct {
+	Name    string
+	Addr    string
+	Asserts []*config.ConditionRule
+}
+
+func (n *EvalCheck) Eval(ctx EvalContext) (interface{}, error) {
+	cs := &CheckState{Status: ConditionPass}
+
+	// If any of the data sources of the check couldn't be read, the
+	// assertions would only fail on the missing values, so the errors
+	// reading them are reported instead.
+	for _, result := range ctx.Conditions().ForAddress(n.Addr, ConditionTypeCheckData) {
+		cs.Status = ConditionFail
+		cs.FailureMessages = append(cs.FailureMessages, result.ErrorMessage)
+	}
+
+	if cs.Status != ConditionFail {
+		for i, rule := range n.Asserts {
+			status, msg, err := checkCondition(ctx, rule, nil)
+			if err != nil {
+				status = ConditionFail
+				msg = fmt.Sprintf("assert #%d: %s", i+1, err)
+			}
+
+			switch status {
+			case ConditionFail:
+				cs.Status = ConditionFail
+				cs.FailureMessages = append(cs.FailureMessages, msg)
+			case ConditionUnknown:
+				if cs.Status == ConditionPass {
+					cs.Status = ConditionUnknown
+				}
+			}
+		}
+	}
+
+	if cs.Status == ConditionFail {
+		log.Printf("[WARN] %s failed: %v", n.Addr, cs.FailureMessages)
+	}
+
+	state, lock := ctx.State()
+	if state == nil {
+		return nil, fmt.Errorf("cannot write state to nil state")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	mod := state.ModuleByPath(ctx.Path())
+	if mod == nil {
+		mod = state.AddModule(ctx.Path())
+	}
+	if mod.Checks == nil {
+		mod.Checks = make(map[string]*CheckState)
+	}
+	mod.Checks[n.Name] = cs
+
+	return nil, nil
+}
+
+// EvalCheckData is an EvalNode implementation that wraps the evaluation of
+// a data source declared in a check block, so that an error reading the
+// data source fails the check instead of the run.
+type EvalCheckData struct {
+	Addr      string // Addr is the address of the data source
+	StateId   string
+	CheckAddr string
+
+	Node EvalNode
+}
+
+func (n *EvalCheckData) Eval(ctx EvalContext) (interface{}, error) {
+	_, err := EvalRaw(n.Node, ctx)
+	if err == nil {
+		return nil, nil
+	}
+	if _, ok := err.(EvalEarlyExitError); ok {
+		return nil, err
+	}
+
+	log.Printf("[WARN] %s: reading data source for %s failed: %s", n.Addr, n.CheckAddr, err)
+
+	// Stale values must not be used by the assertions.
+	state, lock := ctx.State()
+	if state != nil {
+		lock.Lock()
+		if mod := state.ModuleByPath(ctx.Path()); mod != nil {
+			delete(mod.Resources, n.StateId)
+		}
+		lock.Unlock()
+	}
+
+	ctx.Conditions().Add(&ConditionResult{
+		Address:      n.CheckAddr,
+		Type:         ConditionTypeCheckData,
+		Status:       ConditionFail,
+		ErrorMessage: err.Error(),
+	})
+
+	return nil, nil
+}
+
+// EvalNodeFilterable impl.
+func (n *EvalCheckData) Filter(fn EvalNodeFilterFunc) {
+	n.Node = EvalFilter(n.Node, fn)
+}
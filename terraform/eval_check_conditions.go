@@ -0,0 +1,103 @@
+package terraform
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/terraform/config"
+)
+
+// EvalCheckConditions is an EvalNode implementation that checks the
+// preconditions or postconditions of a resource instance or an output,
+// recording the results in the context and returning an error for each
+// condition that fails.
+type EvalCheckConditions struct {
+	Addr       string
+	Type       ConditionType
+	Conditions []*config.ConditionRule
+
+	// Resource is the resource that self variables refer to, if any.
+	Resource *Resource
+
+	// Defer records the conditions as unknown without checking them,
+	// because they can only be checked once changes have been applied.
+	Defer bool
+}
+
+func (n *EvalCheckConditions) Eval(ctx EvalContext) (interface{}, error) {
+	results := ctx.Conditions()
+
+	var errs error
+	for i, rule := range n.Conditions {
+		result := &ConditionResult{
+			Address: n.Addr,
+			Type:    n.Type,
+			Index:   i,
+			Status:  ConditionUnknown,
+		}
+
+		if !n.Defer {
+			status, msg, err := checkCondition(ctx, rule, n.Resource)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s #%d: %s", n.Addr, n.Type, i+1, err)
+			}
+
+			result.Status = status
+			if status == ConditionFail {
+				result.ErrorMessage = msg
+				errs = multierror.Append(errs, &ConditionError{
+					Address: n.Addr,
+					Type:    n.Type,
+					Message: msg,
+				})
+			}
+		}
+
+		results.Add(result)
+	}
+
+	return nil, errs
+}
+
+// checkCondition interpolates and checks a single condition, returning its
+// status and error message.
+func checkCondition(
+	ctx EvalContext, rule *config.ConditionRule, r *Resource) (ConditionStatus, string, error) {
+	rc, err := ctx.Interpolate(rule.RawConfig.Copy(), r)
+	if err != nil {
+		return "", "", err
+	}
+
+	if rc.IsComputed("condition") {
+		return ConditionUnknown, "", nil
+	}
+
+	raw, _ := rc.Get("condition")
+	var ok bool
+	switch v := raw.(type) {
+	case bool:
+		ok = v
+	case string:
+		ok, err = strconv.ParseBool(v)
+		if err != nil {
+			return "", "", fmt.Errorf("condition must be a boolean, got %q", v)
+		}
+	default:
+		return "", "", fmt.Errorf("condition must be a boolean, got %#v", raw)
+	}
+
+	if ok {
+		return ConditionPass, "", nil
+	}
+
+	// The message may refer to values that aren't known yet, in which
+	// case the message as written is the best we can do.
+	msg, _ := rule.RawConfig.Raw["error_message"].(string)
+	if v, ok := rc.Get("error_message"); ok {
+		if s, ok := v.(string); ok {
+			msg = s
+		}
+	}
+	return ConditionFail, msg, nil
+}
@@ -23,10 +23,11 @@ type EvalContext interface {
 	Input() UIInput
 
 	// InitProvider initializes the provider with the given type and name, and
-	// returns the implementation of the resource provider or an error.
+	// returns the implementation of the resource provider or an error. If
+	// policy is non-nil, the provider's calls are made according to it.
 	//
 	// It is an error to initialize the same provider more than once.
-	InitProvider(typ string, name string) (ResourceProvider, error)
+	InitProvider(typ string, name string, policy *ProviderPolicy) (ResourceProvider, error)
 
 	// Provider gets the provider instance with the given name (already
 	// initialized) or returns nil if the provider isn't initialized.
@@ -86,4 +87,20 @@ type EvalContext interface {
 	// State returns the global state as well as the lock that should
 	// be used to modify that state.
 	State() (*State, *sync.RWMutex)
+
+	// Conditions returns where the results of checking preconditions and
+	// postconditions are recorded during this walk.
+	Conditions() *ConditionResults
+
+	// Ephemeral returns where the objects of the ephemeral resources that
+	// are open during this walk are kept.
+	Ephemeral() *EphemeralValues
+
+	// Deferred returns where the resources whose changes are deferred
+	// during this walk are recorded.
+	Deferred() *DeferredChanges
+
+	// Sensitive returns the names of the values in the current module that
+	// are derived from secret variables, such as "var.password".
+	Sensitive() map[string]bool
 }
@@ -41,6 +41,14 @@ type BuiltinEvalContext struct {
 	DiffLock            *sync.RWMutex
 	StateValue          *State
 	StateLock           *sync.RWMutex
+	ConditionsValue     *ConditionResults
+	EphemeralValue      *EphemeralValues
+	DeferredValue       *DeferredChanges
+	SensitiveValue      map[string]map[string]bool
+
+	// TraceHook, if non-nil, records a span for each call of the providers
+	// that this context initializes.
+	TraceHook *traceHook
 
 	once sync.Once
 }
@@ -78,7 +86,7 @@ func (ctx *BuiltinEvalContext) Input() UIInput {
 	return ctx.InputValue
 }
 
-func (ctx *BuiltinEvalContext) InitProvider(typeName, name string) (ResourceProvider, error) {
+func (ctx *BuiltinEvalContext) InitProvider(typeName, name string, policy *ProviderPolicy) (ResourceProvider, error) {
 	ctx.once.Do(ctx.init)
 
 	// If we already initialized, it is an error
@@ -95,6 +103,12 @@ func (ctx *BuiltinEvalContext) InitProvider(typeName, name string) (ResourceProv
 	if err != nil {
 		return nil, err
 	}
+	if ctx.TraceHook != nil {
+		p = newTracingResourceProvider(name, p, ctx.TraceHook)
+	}
+	if policy != nil {
+		p = newPolicyResourceProvider(name, p, policy)
+	}
 
 	ctx.ProviderCache[name] = p
 	return p, nil
@@ -244,9 +258,13 @@ func (ctx *BuiltinEvalContext) Interpolate(
 		if err != nil {
 			return nil, err
 		}
+		funcs, err := ctx.Interpolater.ProviderFunctions(cfg.ProviderFunctions)
+		if err != nil {
+			return nil, err
+		}
 
 		// Do the interpolation
-		if err := cfg.Interpolate(vs); err != nil {
+		if err := cfg.InterpolateWithFunctions(vs, funcs); err != nil {
 			return nil, err
 		}
 	}
@@ -263,8 +281,9 @@ func (ctx *BuiltinEvalContext) InterpolateProvider(
 
 	if pc != nil && pc.RawConfig != nil {
 		scope := &InterpolationScope{
-			Path:     ctx.Path(),
-			Resource: r,
+			Path:           ctx.Path(),
+			Resource:       r,
+			ProviderConfig: pc,
 		}
 
 		cfg = pc.RawConfig
@@ -273,9 +292,13 @@ func (ctx *BuiltinEvalContext) InterpolateProvider(
 		if err != nil {
 			return nil, err
 		}
+		funcs, err := ctx.Interpolater.ProviderFunctions(cfg.ProviderFunctions)
+		if err != nil {
+			return nil, err
+		}
 
 		// Do the interpolation
-		if err := cfg.Interpolate(vs); err != nil {
+		if err := cfg.InterpolateWithFunctions(vs, funcs); err != nil {
 			return nil, err
 		}
 	}
@@ -317,5 +340,21 @@ func (ctx *BuiltinEvalContext) State() (*State, *sync.RWMutex) {
 	return ctx.StateValue, ctx.StateLock
 }
 
+func (ctx *BuiltinEvalContext) Conditions() *ConditionResults {
+	return ctx.ConditionsValue
+}
+
+func (ctx *BuiltinEvalContext) Ephemeral() *EphemeralValues {
+	return ctx.EphemeralValue
+}
+
+func (ctx *BuiltinEvalContext) Deferred() *DeferredChanges {
+	return ctx.DeferredValue
+}
+
+func (ctx *BuiltinEvalContext) Sensitive() map[string]bool {
+	return ctx.SensitiveValue[PathCacheKey(ctx.Path())]
+}
+
 func (ctx *BuiltinEvalContext) init() {
 }
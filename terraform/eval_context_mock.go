@@ -21,6 +21,7 @@ type MockEvalContext struct {
 
 	InitProviderCalled   bool
 	InitProviderName     string
+	InitProviderPolicy   *ProviderPolicy
 	InitProviderProvider ResourceProvider
 	InitProviderError    error
 
@@ -84,6 +85,18 @@ type MockEvalContext struct {
 	StateCalled bool
 	StateState  *State
 	StateLock   *sync.RWMutex
+
+	ConditionsCalled  bool
+	ConditionsResults *ConditionResults
+
+	EphemeralCalled bool
+	EphemeralValues *EphemeralValues
+
+	DeferredCalled  bool
+	DeferredChanges *DeferredChanges
+
+	SensitiveCalled bool
+	SensitiveNames  map[string]bool
 }
 
 func (c *MockEvalContext) Stopped() <-chan struct{} {
@@ -107,9 +120,10 @@ func (c *MockEvalContext) Input() UIInput {
 	return c.InputInput
 }
 
-func (c *MockEvalContext) InitProvider(t, n string) (ResourceProvider, error) {
+func (c *MockEvalContext) InitProvider(t, n string, policy *ProviderPolicy) (ResourceProvider, error) {
 	c.InitProviderCalled = true
 	c.InitProviderName = n
+	c.InitProviderPolicy = policy
 	return c.InitProviderProvider, c.InitProviderError
 }
 
@@ -198,3 +212,23 @@ func (c *MockEvalContext) State() (*State, *sync.RWMutex) {
 	c.StateCalled = true
 	return c.StateState, c.StateLock
 }
+
+func (c *MockEvalContext) Conditions() *ConditionResults {
+	c.ConditionsCalled = true
+	return c.ConditionsResults
+}
+
+func (c *MockEvalContext) Ephemeral() *EphemeralValues {
+	c.EphemeralCalled = true
+	return c.EphemeralValues
+}
+
+func (c *MockEvalContext) Deferred() *DeferredChanges {
+	c.DeferredCalled = true
+	return c.DeferredChanges
+}
+
+func (c *MockEvalContext) Sensitive() map[string]bool {
+	c.SensitiveCalled = true
+	return c.SensitiveNames
+}
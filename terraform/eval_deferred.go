@@ -0,0 +1,98 @@
+package terraform
+
+import (
+	"strings"
+
+	"github.com/hashicorp/terraform/config"
+)
+
+// EvalDeferResource is an EvalNode implementation that defers the changes
+// of a resource whose count can't be computed yet, or that depends on a
+// resource whose changes are deferred, rather than failing the walk.
+//
+// If the resource is deferred then this returns EvalEarlyExitError, and the
+// resource expands to no instances.
+type EvalDeferResource struct {
+	Addr     *ResourceAddress
+	Resource *config.Resource
+}
+
+func (n *EvalDeferResource) Eval(ctx EvalContext) (interface{}, error) {
+	deferred := ctx.Deferred()
+	path := ctx.Path()
+
+	change := &DeferredChange{Addr: n.Addr.String()}
+	if addr := n.dependency(path, deferred); addr != "" {
+		change.Reason = DeferredDependency
+		change.DependsOn = addr
+	} else if n.Resource.RawCount.Value() == unknownValue() {
+		change.Reason = DeferredCountUnknown
+	} else {
+		return nil, nil
+	}
+
+	deferred.Add(path, n.Resource.Id(), change)
+	return nil, EvalEarlyExitError{}
+}
+
+func (n *EvalDeferResource) dependency(path []string, deferred *DeferredChanges) string {
+	if addr := deferred.Dependency(path, n.Resource.RawCount); addr != "" {
+		return addr
+	}
+	if addr := deferred.Dependency(path, n.Resource.RawConfig); addr != "" {
+		return addr
+	}
+
+	// Explicit dependencies on resources count too, but dependencies on
+	// whole modules aren't tracked.
+	for _, d := range n.Resource.DependsOn {
+		if strings.HasPrefix(d, "module.") {
+			continue
+		}
+		if deferred.Deferred(path, d) {
+			return n.dependsOnAddr(path, d)
+		}
+	}
+	return ""
+}
+
+func (n *EvalDeferResource) dependsOnAddr(path []string, id string) string {
+	addr, err := parseResourceAddressInternal(id)
+	if err != nil {
+		return id
+	}
+	addr.Path = normalizeModulePath(path)[1:]
+	return addr.String()
+}
+
+// EvalDeferReferences is an EvalNode implementation that records that a
+// variable, local or output is deferred if its value refers to a resource
+// whose changes are deferred, so that the resources that use it are
+// deferred too.
+type EvalDeferReferences struct {
+	// Name is the name of the variable, local or output, such as
+	// "var.foo", "local.bar" or "output.baz".
+	Name string
+
+	// Path is the path of the module of the variable, local or output, if
+	// it's different from the path that its value is interpolated in, as it
+	// is for module variables.
+	Path []string
+
+	Value *config.RawConfig
+}
+
+func (n *EvalDeferReferences) Eval(ctx EvalContext) (interface{}, error) {
+	deferred := ctx.Deferred()
+	addr := deferred.Dependency(ctx.Path(), n.Value)
+	if addr == "" {
+		return nil, nil
+	}
+
+	path := n.Path
+	if path == nil {
+		path = ctx.Path()
+	}
+	deferred.Defer(path, n.Name, addr)
+	return nil, nil
+}
@@ -3,10 +3,12 @@ package terraform
 import (
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 
 	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/version"
+	"github.com/zclconf/go-cty/cty"
 )
 
 // EvalCompareDiff is an EvalNode implementation that compares two diffs
@@ -88,6 +90,10 @@ type EvalDiff struct {
 	// computed paths off of, but not as an actual diff where resouces should be
 	// counted, and not as a diff that should be acted on.
 	Stub bool
+
+	// ForceReplace, if set, causes an existing instance to be replaced even
+	// if the provider reports no differences, because the user requested it.
+	ForceReplace bool
 }
 
 // TODO: test
@@ -106,9 +112,14 @@ func (n *EvalDiff) Eval(ctx EvalContext) (interface{}, error) {
 		}
 	}
 
-	// The state for the diff must never be nil
+	// A forced replacement only applies to an instance that exists.
+	replace := n.ForceReplace && state != nil && state.ID != ""
+
+	// The state for the diff must never be nil. For a forced replacement we
+	// diff against an empty state, which is what the replacement will be
+	// created from once the existing instance is destroyed during apply.
 	diffState := state
-	if diffState == nil {
+	if diffState == nil || replace {
 		diffState = new(InstanceState)
 	}
 	diffState.init()
@@ -140,12 +151,15 @@ func (n *EvalDiff) Eval(ctx EvalContext) (interface{}, error) {
 	}
 
 	// Require a destroy if there is an ID and it requires new.
-	if diff.RequiresNew() && state != nil && state.ID != "" {
+	if replace || (diff.RequiresNew() && state != nil && state.ID != "") {
 		diff.SetDestroy(true)
 	}
+	if replace {
+		diff.SetReplaceRequested(true)
+	}
 
 	// If we're creating a new resource, compute its ID
-	if diff.RequiresNew() || state == nil || state.ID == "" {
+	if replace || diff.RequiresNew() || state == nil || state.ID == "" {
 		var oldID string
 		if state != nil {
 			oldID = state.Attributes["id"]
@@ -166,6 +180,11 @@ func (n *EvalDiff) Eval(ctx EvalContext) (interface{}, error) {
 		return nil, err
 	}
 
+	// Attributes set from values derived from secrets aren't shown
+	if n.Resource != nil {
+		markSensitiveAttributes(diff, sensitiveKeys(n.Resource.RawConfig, ctx.Sensitive()))
+	}
+
 	// Call post-refresh hook
 	if !n.Stub {
 		err = ctx.Hook(func(h Hook) (HookAction, error) {
@@ -220,13 +239,25 @@ func (n *EvalDiff) processIgnoreChanges(diff *InstanceDiff) error {
 
 	// get the complete set of keys we want to ignore
 	ignorableAttrKeys := make(map[string]bool)
+	var ignoredPaths []config.IgnorePath
 	for _, ignoredKey := range ignoreChanges {
+		if config.IsIgnorePath(ignoredKey) {
+			p, err := config.ParseIgnorePath(ignoredKey)
+			if err != nil {
+				return err
+			}
+			ignoredPaths = append(ignoredPaths, p)
+			continue
+		}
 		for k := range attrs {
 			if ignoredKey == "*" || strings.HasPrefix(k, ignoredKey) {
 				ignorableAttrKeys[k] = true
 			}
 		}
 	}
+	if len(ignoredPaths) > 0 {
+		ignoreFlatmapPaths(diff, attrs, ignoredPaths, ignorableAttrKeys)
+	}
 
 	// If the resource was being destroyed, check to see if we can ignore the
 	// reason for it being destroyed.
@@ -291,6 +322,117 @@ func (n *EvalDiff) processIgnoreChanges(diff *InstanceDiff) error {
 	return nil
 }
 
+// ignoreFlatmapPaths adds the keys of the given attributes of the diff that
+// are matched by any of the given ignore_changes paths to ignored.
+//
+// Ignoring some of the elements of a container leaves its count behind, so
+// the count is ignored too if none of its elements still change, and the
+// count of a map is recalculated from the elements that are left.
+func ignoreFlatmapPaths(diff *InstanceDiff, attrs map[string]*ResourceAttrDiff, paths []config.IgnorePath, ignored map[string]bool) {
+	matched := make(map[string]bool)
+	for k := range attrs {
+		segments := strings.Split(k, ".")
+		for _, p := range paths {
+			if ignorePathMatches(p, 0, segments) {
+				matched[k] = true
+				break
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+	for k := range matched {
+		ignored[k] = true
+	}
+
+	for k, v := range attrs {
+		if ignored[k] || !(strings.HasSuffix(k, ".%") || strings.HasSuffix(k, ".#")) {
+			continue
+		}
+		prefix := k[:len(k)-1]
+
+		emptied := false
+		var remaining []*ResourceAttrDiff
+		for ck, cv := range attrs {
+			if ck == k || !strings.HasPrefix(ck, prefix) {
+				continue
+			}
+			if matched[ck] {
+				emptied = true
+				continue
+			}
+			if !ignored[ck] {
+				remaining = append(remaining, cv)
+			}
+		}
+		if !emptied {
+			continue
+		}
+		if len(remaining) == 0 {
+			ignored[k] = true
+			continue
+		}
+		if !strings.HasSuffix(k, ".%") {
+			continue
+		}
+
+		count, err := strconv.Atoi(v.Old)
+		if err != nil {
+			continue
+		}
+		for _, cv := range remaining {
+			switch {
+			case cv.NewRemoved:
+				count--
+			case cv.Old == "":
+				count++
+			}
+		}
+		if newCount := strconv.Itoa(count); newCount == v.Old {
+			ignored[k] = true
+		} else {
+			updated := *v
+			updated.New = newCount
+			diff.SetAttribute(k, &updated)
+		}
+	}
+}
+
+// ignorePathMatches returns true if the steps of the given ignore path from
+// position i match the given segments of a flatmapped key, or a prefix of
+// them. Map keys can contain dots, so an index step can match several
+// segments.
+func ignorePathMatches(p config.IgnorePath, i int, segments []string) bool {
+	if i == len(p) {
+		return true
+	}
+	if len(segments) == 0 {
+		return false
+	}
+
+	if p[i].Name != "" {
+		return p.MatchStep(i, cty.GetAttrStep{Name: segments[0]}) &&
+			ignorePathMatches(p, i+1, segments[1:])
+	}
+
+	// The counts of containers aren't elements of them.
+	if segments[0] == "#" || segments[0] == "%" {
+		return false
+	}
+	for n := 1; n <= len(segments); n++ {
+		key := strings.Join(segments[:n], ".")
+		match := p.MatchStep(i, cty.IndexStep{Key: cty.StringVal(key)})
+		if idx, err := strconv.Atoi(key); err == nil && !match {
+			match = p.MatchStep(i, cty.IndexStep{Key: cty.NumberIntVal(int64(idx))})
+		}
+		if match && ignorePathMatches(p, i+1, segments[n:]) {
+			return true
+		}
+	}
+	return false
+}
+
 // a group of key-*ResourceAttrDiff pairs from the same flatmapped container
 type flatAttrDiff map[string]*ResourceAttrDiff
 
@@ -144,3 +144,104 @@ func TestProcessIgnoreChangesOnResourceIgnoredWithRequiresNew(t *testing.T) {
 		t.Fatalf("Expected 2 resource to be found, found %d", len(instanceDiff.Attributes))
 	}
 }
+
+func TestProcessIgnoreChangesPaths(t *testing.T) {
+	cases := map[string]struct {
+		Ignore []string
+		Attrs  map[string]*ResourceAttrDiff
+		Want   map[string]string
+	}{
+		"map keys by glob": {
+			[]string{`tags["kubernetes.io/*"]`},
+			map[string]*ResourceAttrDiff{
+				"tags.%":                   {Old: "2", New: "4"},
+				"tags.kubernetes.io/role":  {Old: "", New: "node"},
+				"tags.kubernetes.io/owned": {Old: "", New: "true"},
+				"tags.Name":                {Old: "foo", New: "bar"},
+			},
+			map[string]string{
+				"tags.Name": "bar",
+			},
+		},
+		"map count recalculated": {
+			[]string{`tags["kubernetes.io/*"]`},
+			map[string]*ResourceAttrDiff{
+				"tags.%":                  {Old: "1", New: "3"},
+				"tags.kubernetes.io/role": {Old: "", New: "node"},
+				"tags.Owner":              {Old: "", New: "ops"},
+			},
+			map[string]string{
+				"tags.%":     "2",
+				"tags.Owner": "ops",
+			},
+		},
+		"only changes of matched keys": {
+			[]string{`tags["kubernetes.io/*"]`},
+			map[string]*ResourceAttrDiff{
+				"tags.%":    {Old: "1", New: "2"},
+				"tags.Name": {Old: "", New: "foo"},
+			},
+			map[string]string{
+				"tags.%":    "2",
+				"tags.Name": "foo",
+			},
+		},
+		"list elements by wildcard": {
+			[]string{`rule[*].description`},
+			map[string]*ResourceAttrDiff{
+				"rule.0.description": {Old: "a", New: "b"},
+				"rule.1.description": {Old: "c", New: "d"},
+				"rule.1.port":        {Old: "80", New: "443"},
+			},
+			map[string]string{
+				"rule.1.port": "443",
+			},
+		},
+		"list element by index": {
+			[]string{`rule[1]`},
+			map[string]*ResourceAttrDiff{
+				"rule.0.port":  {Old: "22", New: "2222"},
+				"rule.1.port":  {Old: "80", New: "443"},
+				"rule.10.port": {Old: "8080", New: "8443"},
+			},
+			map[string]string{
+				"rule.0.port":  "2222",
+				"rule.10.port": "8443",
+			},
+		},
+		"quoted key with dots": {
+			[]string{`labels["app.kubernetes.io/name"]`},
+			map[string]*ResourceAttrDiff{
+				"labels.app.kubernetes.io/name":    {Old: "a", New: "b"},
+				"labels.app.kubernetes.io/version": {Old: "1", New: "2"},
+			},
+			map[string]string{
+				"labels.app.kubernetes.io/version": "2",
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			n := &EvalDiff{
+				Resource: &config.Resource{
+					Lifecycle: config.ResourceLifecycle{
+						IgnoreChanges: tc.Ignore,
+					},
+				},
+			}
+			diff := &InstanceDiff{Attributes: tc.Attrs}
+			if err := n.processIgnoreChanges(diff); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			got := make(map[string]string)
+			for k, v := range diff.Attributes {
+				got[k] = v.New
+			}
+			if !reflect.DeepEqual(got, tc.Want) {
+				t.Fatalf("wrong attributes\ngot:  %#v\nwant: %#v", got, tc.Want)
+			}
+		})
+	}
+}
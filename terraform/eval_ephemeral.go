@@ -0,0 +1,55 @@
+package terraform
+
+import (
+	"fmt"
+	"log"
+)
+
+// EvalOpenEphemeral is an EvalNode implementation that opens an ephemeral
+// resource and keeps its object for the rest of the walk. If the
+// configuration isn't known yet, the resource isn't opened and so its
+// attributes are unknown.
+type EvalOpenEphemeral struct {
+	Info     *InstanceInfo
+	Provider *ResourceProvider
+	Config   **ResourceConfig
+}
+
+func (n *EvalOpenEphemeral) Eval(ctx EvalContext) (interface{}, error) {
+	config := *n.Config
+	if len(config.ComputedKeys) > 0 {
+		log.Printf("[TRACE] %s: configuration is unknown, not opening", n.Info.Id)
+		return nil, nil
+	}
+
+	state, err := (*n.Provider).OpenEphemeral(n.Info, config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", n.Info.Id, err)
+	}
+	if state == nil {
+		state = &InstanceState{}
+	}
+
+	ctx.Ephemeral().Set(ctx.Path(), n.Info.Id, state)
+	return nil, nil
+}
+
+// EvalCloseEphemeral is an EvalNode implementation that closes an ephemeral
+// resource opened by EvalOpenEphemeral, if it was opened, and forgets its
+// object.
+type EvalCloseEphemeral struct {
+	Info     *InstanceInfo
+	Provider *ResourceProvider
+}
+
+func (n *EvalCloseEphemeral) Eval(ctx EvalContext) (interface{}, error) {
+	state := ctx.Ephemeral().Remove(ctx.Path(), n.Info.Id)
+	if state == nil {
+		return nil, nil
+	}
+
+	if err := (*n.Provider).CloseEphemeral(n.Info, state); err != nil {
+		return nil, fmt.Errorf("%s: error closing: %s", n.Info.Id, err)
+	}
+	return nil, nil
+}
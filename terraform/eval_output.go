@@ -81,6 +81,10 @@ func (n *EvalWriteOutput) Eval(ctx EvalContext) (interface{}, error) {
 		return nil, err
 	}
 
+	// Outputs derived from secrets are sensitive even if they aren't marked
+	// as such in the configuration.
+	sensitive := n.Sensitive || ctx.Sensitive()["output."+n.Name]
+
 	// Get the value from the config
 	var valueRaw interface{} = config.UnknownVariableValue
 	if cfg != nil {
@@ -98,19 +102,19 @@ func (n *EvalWriteOutput) Eval(ctx EvalContext) (interface{}, error) {
 	case string:
 		mod.Outputs[n.Name] = &OutputState{
 			Type:      "string",
-			Sensitive: n.Sensitive,
+			Sensitive: sensitive,
 			Value:     valueTyped,
 		}
 	case []interface{}:
 		mod.Outputs[n.Name] = &OutputState{
 			Type:      "list",
-			Sensitive: n.Sensitive,
+			Sensitive: sensitive,
 			Value:     valueTyped,
 		}
 	case map[string]interface{}:
 		mod.Outputs[n.Name] = &OutputState{
 			Type:      "map",
-			Sensitive: n.Sensitive,
+			Sensitive: sensitive,
 			Value:     valueTyped,
 		}
 	case []map[string]interface{}:
@@ -119,7 +123,7 @@ func (n *EvalWriteOutput) Eval(ctx EvalContext) (interface{}, error) {
 		if len(valueTyped) == 1 {
 			mod.Outputs[n.Name] = &OutputState{
 				Type:      "map",
-				Sensitive: n.Sensitive,
+				Sensitive: sensitive,
 				Value:     valueTyped[0],
 			}
 			break
@@ -54,10 +54,11 @@ func (n *EvalConfigProvider) Eval(ctx EvalContext) (interface{}, error) {
 type EvalInitProvider struct {
 	TypeName string
 	Name     string
+	Policy   *ProviderPolicy
 }
 
 func (n *EvalInitProvider) Eval(ctx EvalContext) (interface{}, error) {
-	return ctx.InitProvider(n.TypeName, n.Name)
+	return ctx.InitProvider(n.TypeName, n.Name, n.Policy)
 }
 
 // EvalCloseProvider is an EvalNode implementation that closes provider
@@ -53,3 +53,76 @@ func (n *EvalRefresh) Eval(ctx EvalContext) (interface{}, error) {
 
 	return nil, nil
 }
+
+// EvalRefreshBatch is an EvalNode implementation that refreshes several
+// instances of a resource in one call of a provider that supports
+// refreshing in batches, and one at a time with others.
+type EvalRefreshBatch struct {
+	Provider *ResourceProvider
+	Infos    []*InstanceInfo
+
+	// States are the states of the instances, and are replaced by their
+	// refreshed states.
+	States []*InstanceState
+}
+
+// TODO: test
+func (n *EvalRefreshBatch) Eval(ctx EvalContext) (interface{}, error) {
+	provider := *n.Provider
+
+	// Only the instances that have state are refreshed
+	var infos []*InstanceInfo
+	var states []*InstanceState
+	var index []int
+	for i, state := range n.States {
+		if state == nil {
+			log.Printf("[DEBUG] refresh: %s: no state, not refreshing", n.Infos[i].Id)
+			continue
+		}
+
+		infos = append(infos, n.Infos[i])
+		states = append(states, state)
+		index = append(index, i)
+	}
+	if len(states) == 0 {
+		return nil, nil
+	}
+
+	// Call pre-refresh hook
+	for i, info := range infos {
+		err := ctx.Hook(func(h Hook) (HookAction, error) {
+			return h.PreRefresh(info, states[i])
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Refresh!
+	log.Printf("[DEBUG] refresh: refreshing %d instances in a batch, from %s", len(infos), infos[0].Id)
+	result, err := refreshBatch(provider, infos, states)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) != len(states) {
+		return nil, fmt.Errorf(
+			"%s: provider returned %d states for a batch of %d instances",
+			infos[0].Id, len(result), len(states))
+	}
+
+	// Call post-refresh hook
+	for i, info := range infos {
+		err := ctx.Hook(func(h Hook) (HookAction, error) {
+			return h.PostRefresh(info, result[i])
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i, j := range index {
+		n.States[j] = result[i]
+	}
+
+	return nil, nil
+}
@@ -133,12 +133,20 @@ type EvalWriteState struct {
 	Provider     string
 	Dependencies []string
 	State        **InstanceState
+
+	// Group, if non-nil, is recorded as the ordering group of the
+	// resource. It's nil for nodes that don't have the configuration of the
+	// resource, which leave the recorded group as it is.
+	Group *string
 }
 
 func (n *EvalWriteState) Eval(ctx EvalContext) (interface{}, error) {
 	return writeInstanceToState(ctx, n.Name, n.ResourceType, n.Provider, n.Dependencies,
 		func(rs *ResourceState) error {
 			rs.Primary = *n.State
+			if n.Group != nil {
+				rs.Group = *n.Group
+			}
 			return nil
 		},
 	)
@@ -18,6 +18,7 @@ func ProviderEvalTree(n *NodeApplyableProvider, config *config.ProviderConfig) E
 	seq = append(seq, &EvalInitProvider{
 		TypeName: typeName,
 		Name:     n.Name(),
+		Policy:   NewProviderPolicy(config),
 	})
 
 	// Input stuff
@@ -74,7 +75,7 @@ func ProviderEvalTree(n *NodeApplyableProvider, config *config.ProviderConfig) E
 
 	// Apply stuff
 	seq = append(seq, &EvalOpFilter{
-		Ops: []walkOperation{walkRefresh, walkPlan, walkApply, walkDestroy, walkImport},
+		Ops: []walkOperation{walkRefresh, walkPlan, walkApply, walkDestroy, walkImport, walkValidateApply},
 		Node: &EvalSequence{
 			Nodes: []EvalNode{
 				&EvalGetProvider{
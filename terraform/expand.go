@@ -0,0 +1,437 @@
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/hashicorp/hil"
+	"github.com/hashicorp/hil/ast"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/module"
+)
+
+// ExpandOpts are the options of Expand.
+type ExpandOpts struct {
+	Module *module.Tree
+
+	// State is the current state, which the expansion is compared with.
+	// It may be nil.
+	State *State
+
+	// Variables are the values of the variables of the root module, as
+	// returned by Variables.
+	Variables map[string]interface{}
+
+	// Workspace is the value of terraform.workspace.
+	Workspace string
+}
+
+// ExpandStatus is how an expanded resource instance compares with the
+// state.
+type ExpandStatus string
+
+const (
+	// ExpandExisting is an instance that's configured and in the state.
+	ExpandExisting ExpandStatus = "existing"
+
+	// ExpandNew is an instance that's configured but not in the state, and
+	// so would be created by a plan.
+	ExpandNew ExpandStatus = "new"
+
+	// ExpandRemoved is an instance that's in the state but no longer
+	// configured, and so would be destroyed by a plan.
+	ExpandRemoved ExpandStatus = "removed"
+
+	// ExpandUnknown is an instance in the state of a resource whose count
+	// isn't known, which may or may not be kept.
+	ExpandUnknown ExpandStatus = "unknown"
+)
+
+// Expansion is the result of Expand.
+type Expansion struct {
+	// Instances are the resource instances of the configuration and the
+	// state, in order of address.
+	Instances []*ExpandedInstance
+
+	// Unknown are the resources whose count isn't known without planning,
+	// in order of address.
+	Unknown []*UnknownExpansion
+}
+
+// ExpandedInstance is a resource instance of an Expansion.
+type ExpandedInstance struct {
+	Addr   *ResourceAddress
+	Status ExpandStatus
+}
+
+// UnknownExpansion is a resource whose count isn't known.
+type UnknownExpansion struct {
+	Addr *ResourceAddress
+
+	// References are the references that the count depends on, directly or
+	// through local values, variables and outputs, whose values are only
+	// known once planned, sorted.
+	References []string
+}
+
+// Expand evaluates the counts of the resources of the configuration and
+// returns the resource instances that it expands to, compared with the
+// state, without planning and without starting any providers. Counts can
+// refer to variables, local values, module outputs and terraform.workspace;
+// a count that refers to a resource, a data source or a provider function
+// isn't known until a plan is made.
+//
+// An instance with the index 0 is the same instance as the one without an
+// index, since a plan renames it when the count changes between 1 and more
+// than 1. Ephemeral resources are left out, since they're never in the
+// state.
+func Expand(opts *ExpandOpts) (*Expansion, error) {
+	e := &expander{
+		opts:     opts,
+		values:   make(map[string]*expandValue),
+		visiting: make(map[string]bool),
+	}
+
+	configured := make(map[string]*ResourceAddress)
+	unknownResources := make(map[string]bool)
+	result := &Expansion{}
+
+	var walk func(path []string) error
+	walk = func(path []string) error {
+		mod := opts.Module.Child(path)
+		for _, r := range mod.Config().Resources {
+			if r.Mode == config.EphemeralResourceMode {
+				continue
+			}
+
+			addr := &ResourceAddress{
+				Path:         path,
+				Mode:         r.Mode,
+				Type:         r.Type,
+				Name:         r.Name,
+				Index:        -1,
+				InstanceType: TypePrimary,
+			}
+
+			count, refs, err := e.count(path, r)
+			if err != nil {
+				return fmt.Errorf("%s: %s", addr, err)
+			}
+			if refs != nil {
+				unknownResources[expandResourceKey(addr)] = true
+				result.Unknown = append(result.Unknown, &UnknownExpansion{Addr: addr, References: refs})
+				continue
+			}
+
+			for i := 0; i < count; i++ {
+				ia := addr.Copy()
+				if count > 1 {
+					ia.Index = i
+				}
+				configured[expandInstanceKey(ia)] = ia
+			}
+		}
+
+		names := make([]string, 0, len(mod.Children()))
+		for n := range mod.Children() {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			if err := walk(append(append([]string{}, path...), n)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(nil); err != nil {
+		return nil, err
+	}
+
+	inState := make(map[string]bool)
+	if opts.State != nil {
+		for _, ms := range opts.State.Modules {
+			for k := range ms.Resources {
+				addr, err := parseResourceAddressInternal(k)
+				if err != nil {
+					return nil, err
+				}
+				addr.Path = ms.Path[1:]
+
+				key := expandInstanceKey(addr)
+				if inState[key] {
+					continue
+				}
+				inState[key] = true
+
+				status := ExpandRemoved
+				switch {
+				case configured[key] != nil:
+					status = ExpandExisting
+					addr = configured[key]
+				case unknownResources[expandResourceKey(addr)]:
+					status = ExpandUnknown
+				}
+				result.Instances = append(result.Instances, &ExpandedInstance{Addr: addr, Status: status})
+			}
+		}
+	}
+	for key, addr := range configured {
+		if !inState[key] {
+			result.Instances = append(result.Instances, &ExpandedInstance{Addr: addr, Status: ExpandNew})
+		}
+	}
+
+	sort.Slice(result.Instances, func(i, j int) bool {
+		return result.Instances[i].Addr.Less(result.Instances[j].Addr)
+	})
+	sort.Slice(result.Unknown, func(i, j int) bool {
+		return result.Unknown[i].Addr.Less(result.Unknown[j].Addr)
+	})
+	return result, nil
+}
+
+// expandResourceKey returns the key of the resource of the given address,
+// ignoring the index.
+func expandResourceKey(addr *ResourceAddress) string {
+	return modulePrefixStr(addr.Path) + "\x00" + addrConfigKey(addr)
+}
+
+// expandInstanceKey returns the key of the instance of the given address,
+// where no index is the same as the index 0.
+func expandInstanceKey(addr *ResourceAddress) string {
+	idx := addr.Index
+	if idx < 0 {
+		idx = 0
+	}
+	return fmt.Sprintf("%s\x00%d", expandResourceKey(addr), idx)
+}
+
+// expander evaluates the values that the counts of resources depend on.
+type expander struct {
+	opts *ExpandOpts
+
+	// values are the evaluated values, by module path and reference.
+	values   map[string]*expandValue
+	visiting map[string]bool
+}
+
+// expandValue is a value evaluated by an expander. If it's unknown,
+// unknown are the references that it depends on whose values are unknown.
+type expandValue struct {
+	value   ast.Variable
+	unknown []string
+}
+
+// count returns the count of the given resource of the module with the
+// given path, or, if it isn't known, the references it depends on that
+// aren't known.
+func (e *expander) count(path []string, r *config.Resource) (int, []string, error) {
+	rc := r.RawCount.Copy()
+	unknown, err := e.interpolate(path, rc)
+	if err != nil {
+		return 0, nil, fmt.Errorf("count: %s", err)
+	}
+	if unknown != nil {
+		return 0, unknown, nil
+	}
+
+	rr := r.Copy()
+	rr.RawCount = rc
+	count, err := rr.Count()
+	if err != nil {
+		return 0, nil, fmt.Errorf("count: %s", err)
+	}
+	if count < 0 {
+		return 0, nil, fmt.Errorf("count can't be negative")
+	}
+	return count, nil, nil
+}
+
+// interpolate interpolates the given configuration in the module with the
+// given path. If a value it depends on isn't known, it returns the unknown
+// references, sorted, which are never empty.
+func (e *expander) interpolate(path []string, rc *config.RawConfig) ([]string, error) {
+	set := make(map[string]struct{})
+	for _, f := range rc.ProviderFunctions {
+		set[f+"()"] = struct{}{}
+	}
+	if len(set) > 0 {
+		return expandSorted(set), nil
+	}
+
+	vs := make(map[string]ast.Variable, len(rc.Variables))
+	for k, v := range rc.Variables {
+		ev, err := e.value(path, k, v)
+		if err != nil {
+			return nil, err
+		}
+		vs[k] = ev.value
+		for _, ref := range ev.unknown {
+			set[ref] = struct{}{}
+		}
+	}
+	if len(set) > 0 {
+		return expandSorted(set), nil
+	}
+
+	if err := rc.Interpolate(vs); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// value returns the value of the given reference in the module with the
+// given path.
+func (e *expander) value(path []string, k string, v config.InterpolatedVariable) (*expandValue, error) {
+	key := modulePrefixStr(path) + "\x00" + k
+	if ev, ok := e.values[key]; ok {
+		return ev, nil
+	}
+	if e.visiting[key] {
+		return nil, fmt.Errorf("%s: cycle between values", k)
+	}
+	e.visiting[key] = true
+	defer delete(e.visiting, key)
+
+	ev, err := e.evaluate(path, k, v)
+	if err != nil {
+		return nil, err
+	}
+	e.values[key] = ev
+	return ev, nil
+}
+
+func (e *expander) evaluate(path []string, k string, v config.InterpolatedVariable) (*expandValue, error) {
+	mod := e.opts.Module.Child(path)
+	switch v := v.(type) {
+	case *config.UserVariable:
+		return e.userVariable(path, k, v)
+	case *config.LocalVariable:
+		for _, l := range mod.Config().Locals {
+			if l.Name == v.Name {
+				return e.configValue(path, l.RawConfig, "value")
+			}
+		}
+		return nil, fmt.Errorf("%s: no local value of this name has been declared", k)
+	case *config.ModuleVariable:
+		childPath := append(append([]string{}, path...), v.Name)
+		child := e.opts.Module.Child(childPath)
+		if child == nil {
+			return nil, fmt.Errorf("%s: module %q isn't declared", k, v.Name)
+		}
+		for _, o := range child.Config().Outputs {
+			if o.Name == v.Field {
+				return e.configValue(childPath, o.RawConfig, "value")
+			}
+		}
+		return nil, fmt.Errorf("%s: module %q has no output %q", k, v.Name, v.Field)
+	case *config.PathVariable:
+		var s string
+		switch v.Type {
+		case config.PathValueCwd:
+			wd, err := os.Getwd()
+			if err != nil {
+				return nil, fmt.Errorf("Couldn't get cwd for var %s: %s", k, err)
+			}
+			s = wd
+		case config.PathValueModule:
+			s = mod.Config().Dir
+		case config.PathValueRoot:
+			s = e.opts.Module.Config().Dir
+		default:
+			return nil, fmt.Errorf("%s: unknown path type: %#v", k, v.Type)
+		}
+		return &expandValue{value: ast.Variable{Type: ast.TypeString, Value: s}}, nil
+	case *config.TerraformVariable:
+		if v.Field != "workspace" && v.Field != "env" {
+			return nil, fmt.Errorf(
+				"%s: only supported key for 'terraform.X' interpolations is 'workspace'", k)
+		}
+		return &expandValue{value: ast.Variable{Type: ast.TypeString, Value: e.opts.Workspace}}, nil
+	case *config.ResourceVariable:
+		// Resources and data sources are only known once planned, as are
+		// the ephemeral resources that are opened then.
+		return &expandValue{value: unknownVariable(), unknown: []string{k}}, nil
+	default:
+		return nil, fmt.Errorf("%s: can't be used in count", k)
+	}
+}
+
+// userVariable returns the value of the given variable of the module with
+// the given path, which for a child module is set by its module call.
+func (e *expander) userVariable(path []string, k string, v *config.UserVariable) (*expandValue, error) {
+	mod := e.opts.Module.Child(path)
+	var cv *config.Variable
+	for _, c := range mod.Config().Variables {
+		if c.Name == v.Name {
+			cv = c
+			break
+		}
+	}
+	if cv == nil {
+		return nil, fmt.Errorf("%s: no variable of this name has been declared", k)
+	}
+
+	var raw interface{}
+	var ok bool
+	if len(path) == 0 {
+		raw, ok = e.opts.Variables[v.Name]
+	} else {
+		parentPath := path[:len(path)-1]
+		for _, m := range e.opts.Module.Child(parentPath).Config().Modules {
+			if m.Name != path[len(path)-1] {
+				continue
+			}
+			if _, set := m.RawConfig.Raw[v.Name]; set {
+				rc, err := config.NewRawConfig(map[string]interface{}{v.Name: m.RawConfig.Raw[v.Name]})
+				if err != nil {
+					return nil, err
+				}
+				return e.configValue(parentPath, rc, v.Name)
+			}
+		}
+	}
+	if !ok {
+		if cv.Default == nil {
+			return nil, fmt.Errorf("%s: the variable isn't set and has no default", k)
+		}
+		raw = cv.Default
+	}
+
+	value, err := hil.InterfaceToVariable(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", k, err)
+	}
+	return &expandValue{value: value}, nil
+}
+
+// configValue returns the value of the given key of the given
+// configuration, such as that of a local value or an output, interpolated
+// in the module with the given path.
+func (e *expander) configValue(path []string, rc *config.RawConfig, key string) (*expandValue, error) {
+	rc = rc.Copy()
+	unknown, err := e.interpolate(path, rc)
+	if err != nil {
+		return nil, err
+	}
+	if unknown != nil {
+		return &expandValue{value: unknownVariable(), unknown: unknown}, nil
+	}
+
+	value, err := hil.InterfaceToVariable(rc.Config()[key])
+	if err != nil {
+		return nil, err
+	}
+	return &expandValue{value: value}, nil
+}
+
+func expandSorted(set map[string]struct{}) []string {
+	result := make([]string, 0, len(set))
+	for k := range set {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
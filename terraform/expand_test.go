@@ -0,0 +1,95 @@
+package terraform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	state := &State{
+		Modules: []*ModuleState{
+			{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"aws_instance.a.0":  {Type: "aws_instance", Primary: &InstanceState{ID: "a0"}},
+					"aws_instance.a.1":  {Type: "aws_instance", Primary: &InstanceState{ID: "a1"}},
+					"aws_instance.a.2":  {Type: "aws_instance", Primary: &InstanceState{ID: "a2"}},
+					"aws_instance.c":    {Type: "aws_instance", Primary: &InstanceState{ID: "c"}},
+					"aws_instance.e.0":  {Type: "aws_instance", Primary: &InstanceState{ID: "e0"}},
+					"aws_instance.gone": {Type: "aws_instance", Primary: &InstanceState{ID: "gone"}},
+				},
+			},
+			{
+				Path: []string{"root", "child"},
+				Resources: map[string]*ResourceState{
+					"aws_instance.x.0": {Type: "aws_instance", Primary: &InstanceState{ID: "x0"}},
+				},
+			},
+		},
+	}
+
+	result, err := Expand(&ExpandOpts{
+		Module:    testModule(t, "expand"),
+		State:     state,
+		Workspace: "default",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	got := make(map[string]ExpandStatus)
+	var order []string
+	for _, i := range result.Instances {
+		got[i.Addr.String()] = i.Status
+		order = append(order, i.Addr.String())
+	}
+	expected := map[string]ExpandStatus{
+		"data.aws_data_source.d":      ExpandNew,
+		"aws_instance.a[0]":           ExpandExisting,
+		"aws_instance.a[1]":           ExpandExisting,
+		"aws_instance.a[2]":           ExpandRemoved,
+		"aws_instance.b[0]":           ExpandNew,
+		"aws_instance.b[1]":           ExpandNew,
+		"aws_instance.b[2]":           ExpandNew,
+		"aws_instance.c":              ExpandExisting,
+		"aws_instance.e[0]":           ExpandUnknown,
+		"aws_instance.f[0]":           ExpandNew,
+		"aws_instance.f[1]":           ExpandNew,
+		"aws_instance.gone":           ExpandRemoved,
+		"module.child.aws_instance.x": ExpandExisting,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("wrong instances\ngot:  %#v\nwant: %#v", got, expected)
+	}
+	if order[0] != "data.aws_data_source.d" || order[len(order)-1] != "module.child.aws_instance.x" {
+		t.Fatalf("wrong order: %#v", order)
+	}
+
+	if len(result.Unknown) != 1 {
+		t.Fatalf("wrong unknown: %#v", result.Unknown)
+	}
+	u := result.Unknown[0]
+	if u.Addr.String() != "aws_instance.e" || !reflect.DeepEqual(u.References, []string{"data.aws_data_source.d.ids"}) {
+		t.Fatalf("wrong unknown: %s %#v", u.Addr, u.References)
+	}
+}
+
+func TestExpand_variables(t *testing.T) {
+	result, err := Expand(&ExpandOpts{
+		Module:    testModule(t, "expand"),
+		Variables: map[string]interface{}{"n": "1"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var got []string
+	for _, i := range result.Instances {
+		if i.Addr.Type == "aws_instance" && i.Addr.Name == "a" {
+			got = append(got, i.Addr.String())
+		}
+	}
+	if !reflect.DeepEqual(got, []string{"aws_instance.a"}) {
+		t.Fatalf("wrong instances: %#v", got)
+	}
+}
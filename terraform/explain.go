@@ -0,0 +1,300 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/hil/ast"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/tfdiags"
+)
+
+// Explanation describes where the planned values of the attributes of a
+// resource instance came from: the expressions of the arguments of its
+// configuration, what they refer to, and why the values that aren't known
+// until apply are unknown. See ContextOpts.Explain.
+type Explanation struct {
+	// Addr is the address of the resource instance, such as
+	// "aws_instance.web[0]".
+	Addr string
+
+	// Deferred is the reason that the changes of the resource were
+	// deferred, in which case it has no planned values, or empty.
+	Deferred string
+
+	Arguments []*ArgumentExplanation
+}
+
+// ArgumentExplanation describes where the planned values of a top-level
+// argument or attribute of a resource instance came from.
+type ArgumentExplanation struct {
+	Name string
+
+	// Configured is set if the argument is set in the configuration, in
+	// which case Expression is its value as written, in which
+	// interpolations are still strings such as "${var.ami}", and Range is
+	// where it's set, if that's known.
+	Configured bool
+	Expression interface{}
+	Range      tfdiags.SourceRange
+
+	// References are what the expression refers to, sorted by their text.
+	References []*ReferenceExplanation
+
+	// Attributes are the paths of the planned changes of the attributes
+	// within the argument, such as "tags.Name", sorted.
+	Attributes []string
+
+	// Unknown is set if a planned value within the argument is only known
+	// after apply, and UnknownReason explains why.
+	Unknown       bool
+	UnknownReason string
+}
+
+// ReferenceExplanation is a reference of the expression of an argument.
+type ReferenceExplanation struct {
+	// Text is the reference as written, such as "var.ami" or
+	// "aws_subnet.main.id".
+	Text string
+
+	// Unknown is set if the value of the reference was unknown when the
+	// plan was made.
+	Unknown bool
+}
+
+// ExplainValues records the values of the references of the configuration
+// of the resources explained during a plan walk. It is safe for concurrent
+// use.
+type ExplainValues struct {
+	lock   sync.Mutex
+	target *ResourceAddress
+
+	// unknown is whether the value of each reference was unknown, by
+	// instance index and then reference text. A reference that was
+	// interpolated more than once is unknown if it was ever unknown.
+	unknown map[int]map[string]bool
+}
+
+// record records the given values of the given variables, interpolated in
+// the given scope, if the scope is that of an explained resource instance.
+func (e *ExplainValues) record(scope *InterpolationScope, vars map[string]config.InterpolatedVariable, values map[string]ast.Variable) {
+	if e == nil || e.target == nil || scope == nil || scope.Resource == nil || scope.ProviderConfig != nil {
+		return
+	}
+	r := scope.Resource
+	path := normalizeModulePath(scope.Path)[1:]
+	t := e.target
+	if t.Type != r.Type || t.Name != r.Name || !explainPathEqual(t.Path, path) {
+		return
+	}
+	if t.Index >= 0 && t.Index != r.CountIndex {
+		return
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.unknown == nil {
+		e.unknown = make(map[int]map[string]bool)
+	}
+	refs, ok := e.unknown[r.CountIndex]
+	if !ok {
+		refs = make(map[string]bool)
+		e.unknown[r.CountIndex] = refs
+	}
+	for n := range vars {
+		v, ok := values[n]
+		refs[n] = refs[n] || (ok && ast.IsUnknown(v))
+	}
+}
+
+// explain returns the explanations of the planned values of the resource
+// instances matching the recorded target, in order of address.
+func (e *ExplainValues) explain(mod *module.Tree, diff *Diff, state *State, deferred []*DeferredChange) []*Explanation {
+	if e == nil || e.target == nil {
+		return nil
+	}
+	t := e.target
+
+	var rc *config.Resource
+	if child := mod.Child(t.Path); child != nil {
+		for _, r := range child.Config().Resources {
+			if r.Mode == t.Mode && r.Type == t.Type && r.Name == t.Name {
+				rc = r
+				break
+			}
+		}
+	}
+	if rc == nil {
+		return nil
+	}
+
+	var deferredReason string
+	resourceAddr := &ResourceAddress{Path: t.Path, Mode: t.Mode, Type: t.Type, Name: t.Name, Index: -1}
+	for _, d := range deferred {
+		if d.Addr == resourceAddr.String() {
+			deferredReason = d.String()
+		}
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	// The configuration of a deferred resource isn't interpolated, so
+	// there's nothing recorded of it.
+	if len(e.unknown) == 0 && deferredReason != "" {
+		return []*Explanation{{
+			Addr:      resourceAddr.String(),
+			Deferred:  deferredReason,
+			Arguments: explainArguments(rc, nil, nil),
+		}}
+	}
+
+	var result []*Explanation
+	for idx, refs := range e.unknown {
+		addr := resourceAddr.Copy()
+		addr.Index = idx
+
+		d, indexed := explainInstance(diff, state, addr)
+		if !indexed {
+			addr.Index = -1
+		}
+
+		expl := &Explanation{
+			Addr:     addr.String(),
+			Deferred: deferredReason,
+		}
+		expl.Arguments = explainArguments(rc, d, refs)
+		result = append(result, expl)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Addr < result[j].Addr })
+	return result
+}
+
+// explainArguments returns the explanations of the arguments of the given
+// resource configuration and of the attributes of the given planned diff,
+// with the given unknown references.
+func explainArguments(rc *config.Resource, d *InstanceDiff, unknown map[string]bool) []*ArgumentExplanation {
+	args := make(map[string]*ArgumentExplanation)
+	if rc.RawConfig != nil {
+		for k, v := range rc.RawConfig.Raw {
+			arg := &ArgumentExplanation{
+				Name:       k,
+				Configured: true,
+				Expression: v,
+				Range:      rc.Ranges.Attrs[k],
+				References: explainReferences(k, v, unknown),
+			}
+			args[k] = arg
+		}
+	}
+
+	computed := make(map[string]bool)
+	if d != nil {
+		for path, attr := range d.CopyAttributes() {
+			name := path
+			if i := strings.Index(path, "."); i >= 0 {
+				name = path[:i]
+			}
+			arg, ok := args[name]
+			if !ok {
+				arg = &ArgumentExplanation{Name: name}
+				args[name] = arg
+			}
+			arg.Attributes = append(arg.Attributes, path)
+			if attr.NewComputed {
+				computed[name] = true
+			}
+		}
+	}
+
+	result := make([]*ArgumentExplanation, 0, len(args))
+	for name, arg := range args {
+		sort.Strings(arg.Attributes)
+
+		var unknownRefs []string
+		for _, ref := range arg.References {
+			if ref.Unknown {
+				unknownRefs = append(unknownRefs, ref.Text)
+			}
+		}
+		arg.Unknown = computed[name] || (len(arg.Attributes) == 0 && len(unknownRefs) > 0)
+		switch {
+		case !arg.Unknown:
+		case len(unknownRefs) > 0:
+			arg.UnknownReason = fmt.Sprintf("it refers to %s, which will only be known after apply", strings.Join(unknownRefs, ", "))
+		case arg.Configured:
+			arg.UnknownReason = "the provider computes it during apply"
+		default:
+			arg.UnknownReason = "it isn't set in the configuration, and the provider computes it during apply"
+		}
+
+		result = append(result, arg)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// explainReferences returns the references of the expression of the
+// argument with the given name and value.
+func explainReferences(name string, value interface{}, unknown map[string]bool) []*ReferenceExplanation {
+	result := []*ReferenceExplanation{}
+
+	// The references are found by interpolating the single argument.
+	rc, err := config.NewRawConfig(map[string]interface{}{name: value})
+	if err != nil {
+		// The configuration was already loaded from the same value, so this
+		// can't fail.
+		return result
+	}
+	for text := range rc.Variables {
+		result = append(result, &ReferenceExplanation{
+			Text:    text,
+			Unknown: unknown[text],
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Text < result[j].Text })
+	return result
+}
+
+// explainInstance returns the planned diff of the resource instance with
+// the given address, if it has one, and whether the instance is indexed,
+// which is found from its key in the diff or the state. An instance with
+// the index 0 that's in neither is taken to be a resource without count.
+func explainInstance(diff *Diff, state *State, addr *ResourceAddress) (*InstanceDiff, bool) {
+	path := normalizeModulePath(addr.Path)
+	indexedId := addr.stateId()
+	unindexed := addr.Copy()
+	unindexed.Index = -1
+	id := unindexed.stateId()
+
+	if md := diff.ModuleByPath(path); md != nil {
+		if d, ok := md.Resources[indexedId]; ok {
+			return d, true
+		}
+		if d, ok := md.Resources[id]; ok && addr.Index == 0 {
+			return d, false
+		}
+	}
+	if ms := state.ModuleByPath(path); ms != nil {
+		if _, ok := ms.Resources[indexedId]; ok {
+			return nil, true
+		}
+	}
+	return nil, addr.Index != 0
+}
+
+func explainPathEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
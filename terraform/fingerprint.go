@@ -0,0 +1,101 @@
+package terraform
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"runtime"
+
+	"github.com/hashicorp/terraform/version"
+)
+
+// Fingerprint describes the environment of the run that produced a plan or
+// a state: the Terraform version and platform, the providers, and the
+// values of the root module variables. It's recorded so that it can later
+// be shown what exactly produced a change.
+//
+// Variable values are recorded only as hashes, but the hash of a value that
+// is easily guessed, such as a short password, still reveals it to anyone
+// who tries the right guess.
+type Fingerprint struct {
+	// TerraformVersion is the version of Terraform, and Platform is the
+	// operating system and architecture it ran on, such as "linux_amd64".
+	TerraformVersion string `json:"terraform_version"`
+	Platform         string `json:"platform"`
+
+	// Providers describes the provider plugins that were used, by
+	// provider name.
+	Providers map[string]*ProviderFingerprint `json:"providers,omitempty"`
+
+	// Variables are the hex-encoded SHA256 hashes of the values of the
+	// root module variables, by variable name. See VariableHash.
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+// ProviderFingerprint describes a provider plugin in a Fingerprint.
+type ProviderFingerprint struct {
+	// Version is the version of the plugin, if it's known.
+	Version string `json:"version,omitempty"`
+
+	// SHA256 is the hex-encoded SHA256 hash of the plugin's executable. It's
+	// empty for providers that are built in to Terraform.
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// ResourceProviderFingerprinter is an optional interface that a
+// ResourceProviderResolver can implement to describe the providers that it
+// resolved, for the fingerprints of the plans and states produced with
+// them.
+type ResourceProviderFingerprinter interface {
+	// ProviderFingerprints returns the providers last resolved by
+	// ResolveProviders, by provider name.
+	ProviderFingerprints() map[string]*ProviderFingerprint
+}
+
+// VariableHash returns the hash of the given variable value that's
+// recorded in a Fingerprint, which is the hex-encoded SHA256 hash of the
+// JSON encoding of the value. Map keys are sorted in that encoding, so the
+// hash only depends on the value.
+func VariableHash(v interface{}) (string, error) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(js)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// newFingerprint returns the fingerprint of a run of this version of
+// Terraform with the given providers and variable values. The SHA256 hashes
+// are used for the providers that aren't described by providers.
+func newFingerprint(providers map[string]*ProviderFingerprint, sums map[string][]byte, variables map[string]interface{}) (*Fingerprint, error) {
+	fp := &Fingerprint{
+		TerraformVersion: version.String(),
+		Platform:         runtime.GOOS + "_" + runtime.GOARCH,
+	}
+
+	if len(providers) > 0 || len(sums) > 0 {
+		fp.Providers = make(map[string]*ProviderFingerprint)
+		for name, sum := range sums {
+			fp.Providers[name] = &ProviderFingerprint{SHA256: hex.EncodeToString(sum)}
+		}
+		for name, p := range providers {
+			copied := *p
+			fp.Providers[name] = &copied
+		}
+	}
+
+	if len(variables) > 0 {
+		fp.Variables = make(map[string]string, len(variables))
+		for name, v := range variables {
+			h, err := VariableHash(v)
+			if err != nil {
+				return nil, fmt.Errorf("failed to hash the value of variable %q: %s", name, err)
+			}
+			fp.Variables[name] = h
+		}
+	}
+
+	return fp, nil
+}
@@ -0,0 +1,123 @@
+package terraform
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/hashicorp/terraform/version"
+)
+
+func TestContext2Plan_fingerprint(t *testing.T) {
+	m := testModule(t, "apply-vars")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: &testFingerprintResolver{
+			ResourceProviderResolver: ResourceProviderResolverFixed(
+				map[string]ResourceProviderFactory{
+					"aws": testProviderFuncFixed(p),
+				},
+			),
+			fingerprints: map[string]*ProviderFingerprint{
+				"aws": {Version: "1.2.0", SHA256: "0102"},
+			},
+		},
+		ProviderSHA256s: map[string][]byte{
+			"aws":  []byte{1, 2},
+			"null": []byte{3, 4},
+		},
+		Variables: map[string]interface{}{
+			"foo":       "us-west-2",
+			"test_list": []interface{}{"Hello", "World"},
+			"test_map":  map[string]interface{}{"Hello": "World"},
+		},
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	fp := plan.Fingerprint
+	if fp == nil {
+		t.Fatal("plan has no fingerprint")
+	}
+	if fp.TerraformVersion != version.String() {
+		t.Fatalf("wrong version %q", fp.TerraformVersion)
+	}
+	if want := runtime.GOOS + "_" + runtime.GOARCH; fp.Platform != want {
+		t.Fatalf("wrong platform %q; want %q", fp.Platform, want)
+	}
+
+	// The resolver describes the providers it resolved, and the others are
+	// described by their hashes.
+	wantProviders := map[string]*ProviderFingerprint{
+		"aws":  {Version: "1.2.0", SHA256: "0102"},
+		"null": {SHA256: "0304"},
+	}
+	if !reflect.DeepEqual(fp.Providers, wantProviders) {
+		t.Fatalf("wrong providers %#v", fp.Providers)
+	}
+
+	// Variables are hashed, including those set by their defaults.
+	for name, v := range map[string]interface{}{
+		"foo": "us-west-2",
+		"bar": "baz",
+	} {
+		want, err := VariableHash(v)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if got := fp.Variables[name]; got != want {
+			t.Fatalf("wrong hash of %s %q; want %q", name, got, want)
+		}
+	}
+	if len(fp.Variables) != 5 {
+		t.Fatalf("wrong variables %#v", fp.Variables)
+	}
+
+	// The state written by applying is fingerprinted too.
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(state.Fingerprint, fp) {
+		t.Fatalf("wrong state fingerprint %#v; want %#v", state.Fingerprint, fp)
+	}
+}
+
+func TestVariableHash(t *testing.T) {
+	a, err := VariableHash(map[string]interface{}{"a": "1", "b": []interface{}{"2"}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	b, err := VariableHash(map[string]interface{}{"b": []interface{}{"2"}, "a": "1"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if a != b {
+		t.Fatalf("hashes of equal values differ: %s, %s", a, b)
+	}
+
+	c, err := VariableHash("1")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if a == c {
+		t.Fatal("hashes of different values are equal")
+	}
+}
+
+// testFingerprintResolver is a resolver that describes the providers that
+// it resolves with the given fingerprints.
+type testFingerprintResolver struct {
+	ResourceProviderResolver
+	fingerprints map[string]*ProviderFingerprint
+}
+
+func (r *testFingerprintResolver) ProviderFingerprints() map[string]*ProviderFingerprint {
+	return r.fingerprints
+}
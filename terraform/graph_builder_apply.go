@@ -87,6 +87,9 @@ func (b *ApplyGraphBuilder) Steps() []GraphTransformer {
 		// Attach the state
 		&AttachStateTransformer{State: b.State},
 
+		// Add the ephemeral resources, which need providers
+		&EphemeralTransformer{Module: b.Module},
+
 		// add providers
 		TransformProviders(b.Providers, concreteProvider, b.Module),
 
@@ -97,6 +100,9 @@ func (b *ApplyGraphBuilder) Steps() []GraphTransformer {
 			&CBDEdgeTransformer{Module: b.Module, State: b.State},
 		),
 
+		// Order creates after the destroys of the groups they wait for
+		&GroupOrderTransformer{},
+
 		// Provisioner-related transformations
 		&MissingProvisionerTransformer{Provisioners: b.Provisioners},
 		&ProvisionerTransformer{},
@@ -110,6 +116,19 @@ func (b *ApplyGraphBuilder) Steps() []GraphTransformer {
 		// Add the outputs
 		&OutputTransformer{Module: b.Module},
 
+		// Add the check blocks, which aren't checked when destroying
+		GraphTransformIf(
+			func() bool { return !b.Destroy },
+			&CheckTransformer{Module: b.Module},
+		),
+
+		// Add the variable validations, which also aren't checked when
+		// destroying
+		GraphTransformIf(
+			func() bool { return !b.Destroy },
+			&VariableValidationTransformer{Module: b.Module},
+		),
+
 		// Add module variables
 		&ModuleVariableTransformer{Module: b.Module},
 
@@ -119,6 +138,9 @@ func (b *ApplyGraphBuilder) Steps() []GraphTransformer {
 		// Connect references so ordering is correct
 		&ReferenceTransformer{},
 
+		// Use variables only once their validations have passed
+		&VariableValidationReferenceTransformer{},
+
 		// Reverse the edges to outputs and locals, so that
 		// interpolations don't fail during destroy.
 		GraphTransformIf(
@@ -136,6 +158,9 @@ func (b *ApplyGraphBuilder) Steps() []GraphTransformer {
 		&CloseProviderTransformer{},
 		&CloseProvisionerTransformer{},
 
+		// Close the ephemeral resources before their providers
+		&CloseEphemeralTransformer{},
+
 		// Single root
 		&RootTransformer{},
 	}
@@ -34,6 +34,9 @@ type PlanGraphBuilder struct {
 	// Targets are resources to target
 	Targets []string
 
+	// ForceReplace are resources to replace even if they have no changes
+	ForceReplace []*ResourceAddress
+
 	// DisableReduce, if true, will not reduce the graph. Great for testing.
 	DisableReduce bool
 
@@ -77,6 +80,15 @@ func (b *PlanGraphBuilder) Steps() []GraphTransformer {
 		// Add the outputs
 		&OutputTransformer{Module: b.Module},
 
+		// Add the check blocks
+		&CheckTransformer{Module: b.Module},
+
+		// Add the variable validations
+		&VariableValidationTransformer{Module: b.Module},
+
+		// Add the ephemeral resources
+		&EphemeralTransformer{Module: b.Module},
+
 		// Add orphan resources
 		&OrphanResourceTransformer{
 			Concrete: b.ConcreteResourceOrphan,
@@ -122,6 +134,9 @@ func (b *PlanGraphBuilder) Steps() []GraphTransformer {
 		// have to connect again later for providers and so on.
 		&ReferenceTransformer{},
 
+		// Use variables only once their validations have passed
+		&VariableValidationReferenceTransformer{},
+
 		// Add the node to fix the state count boundaries
 		&CountBoundaryTransformer{},
 
@@ -140,6 +155,9 @@ func (b *PlanGraphBuilder) Steps() []GraphTransformer {
 		&CloseProviderTransformer{},
 		&CloseProvisionerTransformer{},
 
+		// Close the ephemeral resources before their providers
+		&CloseEphemeralTransformer{},
+
 		// Single root
 		&RootTransformer{},
 	}
@@ -170,6 +188,7 @@ func (b *PlanGraphBuilder) init() {
 			NodeAbstractCountResource: &NodeAbstractCountResource{
 				NodeAbstractResource: a,
 			},
+			ForceReplace: b.ForceReplace,
 		}
 	}
 
@@ -126,6 +126,9 @@ func (b *RefreshGraphBuilder) Steps() []GraphTransformer {
 		// Add root variables
 		&RootVariableTransformer{Module: b.Module},
 
+		// Add the ephemeral resources, which need providers
+		&EphemeralTransformer{Module: b.Module},
+
 		TransformProviders(b.Providers, concreteProvider, b.Module),
 
 		// Add the local values
@@ -155,6 +158,9 @@ func (b *RefreshGraphBuilder) Steps() []GraphTransformer {
 		// Close opened plugin connections
 		&CloseProviderTransformer{},
 
+		// Close the ephemeral resources before their providers
+		&CloseEphemeralTransformer{},
+
 		// Single root
 		&RootTransformer{},
 	}
@@ -0,0 +1,216 @@
+package terraform
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/hashicorp/terraform/dag"
+)
+
+// GraphJSONNode is a vertex in the JSON representation of a graph.
+type GraphJSONNode struct {
+	ID string `json:"id"`
+
+	// Kind is one of "resource", "provider", "provisioner", "module",
+	// "variable", "output", "check" or "other".
+	Kind string `json:"kind"`
+
+	// Address is the resource address, set only for resources.
+	Address string `json:"address,omitempty"`
+
+	// Module is the module the node belongs to, such as "module.foo",
+	// and is empty for the root module.
+	Module string `json:"module,omitempty"`
+
+	// Provider is the name of the provider node for providers and for
+	// nodes that use a provider, such as "provider.aws".
+	Provider string `json:"provider,omitempty"`
+}
+
+// GraphJSONEdge is a dependency in the JSON representation of a graph:
+// Source depends on Target.
+type GraphJSONEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+
+	// Type is "provider" if Target is the provider used by Source,
+	// "destroy" if either node destroys something, and "dependency"
+	// otherwise.
+	Type string `json:"type"`
+}
+
+// GraphJSONDoc is the JSON representation of a graph.
+type GraphJSONDoc struct {
+	Nodes []*GraphJSONNode `json:"nodes"`
+	Edges []*GraphJSONEdge `json:"edges"`
+}
+
+// GraphJSON returns the JSON representation of the given Terraform graph.
+//
+// Only the MaxDepth and Verbose options are used. As for GraphDot, nodes
+// that aren't drawn are left out unless Verbose is set. Nodes in modules
+// nested deeper than MaxDepth are merged into a single node of kind
+// "module" for the module at that depth.
+func GraphJSON(g *Graph, opts *dag.DotOpts) (string, error) {
+	doc := graphJSONDoc(g, opts)
+	js, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(js), nil
+}
+
+func graphJSONDoc(g *Graph, opts *dag.DotOpts) *GraphJSONDoc {
+	if opts == nil {
+		opts = &dag.DotOpts{MaxDepth: -1}
+	}
+
+	doc := &GraphJSONDoc{
+		Nodes: make([]*GraphJSONNode, 0),
+		Edges: make([]*GraphJSONEdge, 0),
+	}
+
+	// ids maps each included vertex to the ID of the node it is shown as,
+	// which differs for vertices merged into a module node.
+	ids := make(map[dag.Vertex]string)
+	nodes := make(map[string]*GraphJSONNode)
+	for _, v := range g.Vertices() {
+		if !opts.Verbose && !graphJSONDrawn(v, opts) {
+			continue
+		}
+
+		path := graphJSONModulePath(v)
+		if opts.MaxDepth >= 0 && len(path) > opts.MaxDepth+1 {
+			n := &GraphJSONNode{
+				ID:     modulePrefixStr(path[:opts.MaxDepth+2]),
+				Kind:   "module",
+				Module: modulePrefixStr(path[:opts.MaxDepth+1]),
+			}
+			ids[v] = n.ID
+			nodes[n.ID] = n
+			continue
+		}
+
+		n := &GraphJSONNode{
+			ID:     dag.VertexName(v),
+			Kind:   graphJSONKind(v),
+			Module: modulePrefixStr(path),
+		}
+		if rn, ok := v.(GraphNodeResource); ok {
+			if addr := rn.ResourceAddr(); addr != nil {
+				n.Address = addr.String()
+			}
+		}
+		switch pn := v.(type) {
+		case GraphNodeProvider:
+			n.Provider = pn.Name()
+		case GraphNodeCloseProvider:
+			n.Provider = graphJSONProviderName(pn.CloseProviderName(), path)
+		case GraphNodeProviderConsumer:
+			// The provider edge may have been removed by a transitive
+			// reduction, so use the name the node asks for unless an
+			// edge shows which provider the node was given.
+			n.Provider = graphJSONProviderName(pn.ProvidedBy(), path)
+		}
+		ids[v] = n.ID
+		nodes[n.ID] = n
+	}
+
+	edges := make(map[GraphJSONEdge]struct{})
+	for _, e := range g.Edges() {
+		source, sok := ids[e.Source()]
+		target, tok := ids[e.Target()]
+		if !sok || !tok || source == target {
+			continue
+		}
+
+		typ := "dependency"
+		if pn, ok := e.Target().(GraphNodeProvider); ok {
+			typ = "provider"
+			if _, ok := e.Source().(GraphNodeProvider); !ok && nodes[source].Kind != "module" {
+				nodes[source].Provider = pn.Name()
+			}
+		} else if graphJSONDestroyer(e.Source()) || graphJSONDestroyer(e.Target()) {
+			typ = "destroy"
+		}
+
+		edges[GraphJSONEdge{Source: source, Target: target, Type: typ}] = struct{}{}
+	}
+
+	for _, n := range nodes {
+		doc.Nodes = append(doc.Nodes, n)
+	}
+	sort.Slice(doc.Nodes, func(i, j int) bool {
+		return doc.Nodes[i].ID < doc.Nodes[j].ID
+	})
+
+	for e := range edges {
+		e := e
+		doc.Edges = append(doc.Edges, &e)
+	}
+	sort.Slice(doc.Edges, func(i, j int) bool {
+		a, b := doc.Edges[i], doc.Edges[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		return a.Type < b.Type
+	})
+
+	return doc
+}
+
+// graphJSONModulePath returns the path of the module a vertex belongs to,
+// starting with "root".
+func graphJSONModulePath(v dag.Vertex) []string {
+	switch n := v.(type) {
+	case *NodeApplyableModuleVariable:
+		// These are evaluated in the parent module, but belong to the
+		// module that declares the variable.
+		return normalizeModulePath(n.PathValue)
+	case GraphNodeSubPath:
+		return normalizeModulePath(n.Path())
+	}
+	return RootModulePath
+}
+
+// graphJSONProviderName resolves a provider name used in the module with
+// the given path.
+func graphJSONProviderName(name string, path []string) string {
+	if len(path) <= len(RootModulePath) {
+		path = nil
+	}
+	return ResolveProviderName(name, path)
+}
+
+// graphJSONDrawn returns whether GraphDot draws the vertex with the given
+// options.
+func graphJSONDrawn(v dag.Vertex, opts *dag.DotOpts) bool {
+	dn, ok := v.(dag.GraphNodeDotter)
+	return ok && dn.DotNode(dag.VertexName(v), opts) != nil
+}
+
+func graphJSONKind(v dag.Vertex) string {
+	switch v.(type) {
+	case GraphNodeResource:
+		return "resource"
+	case GraphNodeProvider, GraphNodeCloseProvider:
+		return "provider"
+	case GraphNodeProvisioner, GraphNodeCloseProvisioner:
+		return "provisioner"
+	case *NodeRootVariable, *NodeApplyableModuleVariable:
+		return "variable"
+	case *NodeApplyableOutput, *NodeOutputOrphan:
+		return "output"
+	case *NodeApplyableCheck:
+		return "check"
+	}
+	return "other"
+}
+
+func graphJSONDestroyer(v dag.Vertex) bool {
+	_, ok := v.(GraphNodeDestroyer)
+	return ok
+}
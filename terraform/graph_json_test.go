@@ -0,0 +1,121 @@
+package terraform
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/dag"
+)
+
+func TestGraphJSON(t *testing.T) {
+	cases := map[string]struct {
+		MaxDepth int
+		Expected *GraphJSONDoc
+	}{
+		"all modules": {
+			-1,
+			&GraphJSONDoc{
+				Nodes: []*GraphJSONNode{
+					{ID: "aws_instance.web", Kind: "resource", Address: "aws_instance.web", Provider: "provider.aws"},
+					{ID: "aws_security_group.firewall", Kind: "resource", Address: "aws_security_group.firewall", Provider: "provider.aws"},
+					{ID: "module.consul.aws_instance.server", Kind: "resource", Address: "module.consul.aws_instance.server", Module: "module.consul", Provider: "module.consul.provider.aws"},
+					{ID: "module.consul.provider.aws", Kind: "provider", Module: "module.consul", Provider: "module.consul.provider.aws"},
+					{ID: "provider.aws", Kind: "provider", Provider: "provider.aws"},
+				},
+				Edges: []*GraphJSONEdge{
+					{Source: "aws_instance.web", Target: "aws_security_group.firewall", Type: "dependency"},
+					{Source: "aws_security_group.firewall", Target: "provider.aws", Type: "provider"},
+					{Source: "module.consul.aws_instance.server", Target: "module.consul.provider.aws", Type: "provider"},
+					{Source: "module.consul.provider.aws", Target: "provider.aws", Type: "provider"},
+				},
+			},
+		},
+
+		"root module only": {
+			0,
+			&GraphJSONDoc{
+				Nodes: []*GraphJSONNode{
+					{ID: "aws_instance.web", Kind: "resource", Address: "aws_instance.web", Provider: "provider.aws"},
+					{ID: "aws_security_group.firewall", Kind: "resource", Address: "aws_security_group.firewall", Provider: "provider.aws"},
+					{ID: "module.consul", Kind: "module"},
+					{ID: "provider.aws", Kind: "provider", Provider: "provider.aws"},
+				},
+				Edges: []*GraphJSONEdge{
+					{Source: "aws_instance.web", Target: "aws_security_group.firewall", Type: "dependency"},
+					{Source: "aws_security_group.firewall", Target: "provider.aws", Type: "provider"},
+					{Source: "module.consul", Target: "provider.aws", Type: "provider"},
+				},
+			},
+		},
+	}
+
+	b := &PlanGraphBuilder{
+		Module:    testModule(t, "graph-builder-modules"),
+		Providers: []string{"aws"},
+	}
+	g, err := b.Build(RootModulePath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			js, err := GraphJSON(g, &dag.DotOpts{MaxDepth: tc.MaxDepth})
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			var actual GraphJSONDoc
+			if err := json.Unmarshal([]byte(js), &actual); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if !reflect.DeepEqual(&actual, tc.Expected) {
+				t.Fatalf("bad:\n%s", js)
+			}
+		})
+	}
+}
+
+func TestGraphJSON_destroy(t *testing.T) {
+	b := &DestroyPlanGraphBuilder{
+		Module: testModule(t, "graph-builder-modules"),
+		State: &State{
+			Modules: []*ModuleState{
+				&ModuleState{
+					Path: rootModulePath,
+					Resources: map[string]*ResourceState{
+						"aws_instance.web": &ResourceState{
+							Type:    "aws_instance",
+							Primary: &InstanceState{ID: "foo"},
+						},
+						"aws_security_group.firewall": &ResourceState{
+							Type:    "aws_security_group",
+							Primary: &InstanceState{ID: "bar"},
+						},
+					},
+				},
+			},
+		},
+	}
+	g, err := b.Build(RootModulePath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	js, err := GraphJSON(g, &dag.DotOpts{MaxDepth: -1, Verbose: true})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var doc GraphJSONDoc
+	if err := json.Unmarshal([]byte(js), &doc); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, e := range doc.Edges {
+		if e.Type == "destroy" {
+			return
+		}
+	}
+	t.Fatalf("no destroy edges:\n%s", js)
+}
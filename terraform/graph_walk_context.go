@@ -24,6 +24,12 @@ type ContextGraphWalker struct {
 	// is being walked.
 	ValidationWarnings []string
 	ValidationErrors   []error
+	Conditions         ConditionResults
+	Ephemeral          EphemeralValues
+	Deferred           DeferredChanges
+	Explain            ExplainValues
+	Unknowns           UnknownValues
+	Functions          ProviderFunctions
 
 	errorLock           sync.Mutex
 	once                sync.Once
@@ -74,12 +80,17 @@ func (w *ContextGraphWalker) EnterPath(path []string) EvalContext {
 		ProviderCache:       w.providerCache,
 		ProviderInputConfig: w.Context.providerInputConfig,
 		ProviderLock:        &w.providerLock,
+		TraceHook:           w.Context.th,
 		ProvisionerCache:    w.provisionerCache,
 		ProvisionerLock:     &w.provisionerLock,
 		DiffValue:           w.Context.diff,
 		DiffLock:            &w.Context.diffLock,
 		StateValue:          w.Context.state,
 		StateLock:           &w.Context.stateLock,
+		ConditionsValue:     &w.Conditions,
+		EphemeralValue:      &w.Ephemeral,
+		DeferredValue:       &w.Deferred,
+		SensitiveValue:      w.Context.sensitive,
 		Interpolater: &Interpolater{
 			Operation:          w.Operation,
 			Meta:               w.Context.meta,
@@ -88,6 +99,11 @@ func (w *ContextGraphWalker) EnterPath(path []string) EvalContext {
 			StateLock:          &w.Context.stateLock,
 			VariableValues:     variables,
 			VariableValuesLock: &w.interpolaterVarLock,
+			Ephemeral:          &w.Ephemeral,
+			Deferred:           &w.Deferred,
+			Explain:            &w.Explain,
+			Unknowns:           &w.Unknowns,
+			Functions:          &w.Functions,
 		},
 		InterpolaterVars:    w.interpolaterVars,
 		InterpolaterVarLock: &w.interpolaterVarLock,
@@ -102,7 +118,7 @@ func (w *ContextGraphWalker) EnterEvalTree(v dag.Vertex, n EvalNode) EvalNode {
 		w.Operation, dag.VertexName(v))
 
 	// Acquire a lock on the semaphore
-	w.Context.parallelSem.Acquire()
+	w.semaphore().Acquire()
 
 	// We want to filter the evaluation tree to only include operations
 	// that belong in this operation.
@@ -115,7 +131,7 @@ func (w *ContextGraphWalker) ExitEvalTree(
 		w.Operation, dag.VertexName(v))
 
 	// Release the semaphore
-	w.Context.parallelSem.Release()
+	w.semaphore().Release()
 
 	if err == nil {
 		return nil
@@ -146,9 +162,19 @@ func (w *ContextGraphWalker) ExitEvalTree(
 	return nil
 }
 
+// semaphore returns the semaphore limiting the concurrent operations of
+// this walk.
+func (w *ContextGraphWalker) semaphore() Semaphore {
+	if w.Operation == walkRefresh {
+		return w.Context.refreshSem
+	}
+	return w.Context.parallelSem
+}
+
 func (w *ContextGraphWalker) init() {
 	w.contexts = make(map[string]*BuiltinEvalContext, 5)
 	w.providerCache = make(map[string]ResourceProvider, 5)
 	w.provisionerCache = make(map[string]ResourceProvisioner, 5)
 	w.interpolaterVars = make(map[string]map[string]interface{}, 5)
+	w.Functions.Components = w.Context.components
 }
@@ -15,4 +15,5 @@ const (
 	walkValidate
 	walkDestroy
 	walkImport
+	walkValidateApply
 )
@@ -6,12 +6,17 @@ import (
 
 // stopHook is a private Hook implementation that Terraform uses to
 // signal when to stop or cancel actions.
+//
+// Once interrupted, the hook halts only before actions start, so that the
+// actions in progress complete and are recorded. Once stopped, it halts
+// everything.
 type stopHook struct {
-	stop uint32
+	stop      uint32
+	interrupt uint32
 }
 
 func (h *stopHook) PreApply(*InstanceInfo, *InstanceState, *InstanceDiff) (HookAction, error) {
-	return h.hook()
+	return h.start()
 }
 
 func (h *stopHook) PostApply(*InstanceInfo, *InstanceState, error) (HookAction, error) {
@@ -19,7 +24,7 @@ func (h *stopHook) PostApply(*InstanceInfo, *InstanceState, error) (HookAction,
 }
 
 func (h *stopHook) PreDiff(*InstanceInfo, *InstanceState) (HookAction, error) {
-	return h.hook()
+	return h.start()
 }
 
 func (h *stopHook) PostDiff(*InstanceInfo, *InstanceDiff) (HookAction, error) {
@@ -46,7 +51,7 @@ func (h *stopHook) ProvisionOutput(*InstanceInfo, string, string) {
 }
 
 func (h *stopHook) PreRefresh(*InstanceInfo, *InstanceState) (HookAction, error) {
-	return h.hook()
+	return h.start()
 }
 
 func (h *stopHook) PostRefresh(*InstanceInfo, *InstanceState) (HookAction, error) {
@@ -54,7 +59,7 @@ func (h *stopHook) PostRefresh(*InstanceInfo, *InstanceState) (HookAction, error
 }
 
 func (h *stopHook) PreImportState(*InstanceInfo, string) (HookAction, error) {
-	return h.hook()
+	return h.start()
 }
 
 func (h *stopHook) PostImportState(*InstanceInfo, []*InstanceState) (HookAction, error) {
@@ -65,6 +70,15 @@ func (h *stopHook) PostStateUpdate(*State) (HookAction, error) {
 	return h.hook()
 }
 
+// start is called before an action starts.
+func (h *stopHook) start() (HookAction, error) {
+	if h.Interrupted() {
+		return HookActionHalt, nil
+	}
+
+	return HookActionContinue, nil
+}
+
 func (h *stopHook) hook() (HookAction, error) {
 	if h.Stopped() {
 		return HookActionHalt, nil
@@ -76,6 +90,15 @@ func (h *stopHook) hook() (HookAction, error) {
 // reset should be called within the lock context
 func (h *stopHook) Reset() {
 	atomic.StoreUint32(&h.stop, 0)
+	atomic.StoreUint32(&h.interrupt, 0)
+}
+
+func (h *stopHook) Interrupt() {
+	atomic.StoreUint32(&h.interrupt, 1)
+}
+
+func (h *stopHook) Interrupted() bool {
+	return atomic.LoadUint32(&h.interrupt) == 1 || h.Stopped()
 }
 
 func (h *stopHook) Stop() {
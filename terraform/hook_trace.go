@@ -0,0 +1,150 @@
+package terraform
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform/tracing"
+)
+
+// errSpanIncomplete is the error of the spans of actions that were halted
+// before they completed, such as when the operation was stopped.
+var errSpanIncomplete = errors.New("the action didn't complete")
+
+// traceHook is a private Hook implementation that records a span for each
+// graph walk of a Context, and for each plan, apply and refresh of a
+// resource instance within it.
+//
+// A nil *traceHook records nothing.
+type traceHook struct {
+	NilHook
+
+	tracer *tracing.Tracer
+
+	mu   sync.Mutex
+	walk *tracing.Span
+
+	// instances are the spans in progress of the resource instances, keyed
+	// by their addresses. A resource instance has only one action in
+	// progress at a time.
+	instances map[string]*tracing.Span
+}
+
+func newTraceHook(tracer *tracing.Tracer) *traceHook {
+	if tracer == nil {
+		return nil
+	}
+	return &traceHook{
+		tracer:    tracer,
+		instances: make(map[string]*tracing.Span),
+	}
+}
+
+func (h *traceHook) PreApply(info *InstanceInfo, s *InstanceState, d *InstanceDiff) (HookAction, error) {
+	h.startInstance(info, "apply")
+	return HookActionContinue, nil
+}
+
+func (h *traceHook) PostApply(info *InstanceInfo, s *InstanceState, err error) (HookAction, error) {
+	h.finishInstance(info, err)
+	return HookActionContinue, nil
+}
+
+func (h *traceHook) PreDiff(info *InstanceInfo, s *InstanceState) (HookAction, error) {
+	h.startInstance(info, "plan")
+	return HookActionContinue, nil
+}
+
+func (h *traceHook) PostDiff(info *InstanceInfo, d *InstanceDiff) (HookAction, error) {
+	h.finishInstance(info, nil)
+	return HookActionContinue, nil
+}
+
+func (h *traceHook) PreRefresh(info *InstanceInfo, s *InstanceState) (HookAction, error) {
+	h.startInstance(info, "refresh")
+	return HookActionContinue, nil
+}
+
+func (h *traceHook) PostRefresh(info *InstanceInfo, s *InstanceState) (HookAction, error) {
+	h.finishInstance(info, nil)
+	return HookActionContinue, nil
+}
+
+// startWalk starts the span of a graph walk for the given operation.
+func (h *traceHook) startWalk(operation walkOperation) {
+	if h == nil {
+		return
+	}
+
+	name := strings.ToLower(strings.TrimPrefix(operation.String(), "walk"))
+	span := h.tracer.Start(nil, "terraform."+name,
+		tracing.String("terraform.operation", name))
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.walk = span
+}
+
+// finishWalk ends the span of the graph walk in progress, along with the
+// spans of any actions that didn't complete.
+func (h *traceHook) finishWalk(err error) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for addr, span := range h.instances {
+		span.Finish(errSpanIncomplete)
+		delete(h.instances, addr)
+	}
+	h.walk.Finish(err)
+	h.walk = nil
+}
+
+func (h *traceHook) startInstance(info *InstanceInfo, action string) {
+	addr := info.ResourceAddress().String()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// An action that was never finished, such as a plan that failed, is
+	// ended by the next action of the instance.
+	if prev, ok := h.instances[addr]; ok {
+		prev.Finish(errSpanIncomplete)
+	}
+
+	h.instances[addr] = h.tracer.Start(h.walk, action+" "+addr,
+		tracing.String("terraform.action", action),
+		tracing.String("terraform.resource.address", addr),
+		tracing.String("terraform.resource.type", info.Type))
+}
+
+func (h *traceHook) finishInstance(info *InstanceInfo, err error) {
+	addr := info.ResourceAddress().String()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if span, ok := h.instances[addr]; ok {
+		span.Finish(err)
+		delete(h.instances, addr)
+	}
+}
+
+// parent returns the span that the calls for the given resource instance
+// belong to: the span of its action in progress if there is one, and
+// otherwise the span of the graph walk.
+func (h *traceHook) parent(info *InstanceInfo) *tracing.Span {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if info != nil {
+		if span, ok := h.instances[info.ResourceAddress().String()]; ok {
+			return span
+		}
+	}
+	return h.walk
+}
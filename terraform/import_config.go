@@ -0,0 +1,231 @@
+package terraform
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/hcl/printer"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/configschema"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// importConfigHeader starts each file of configuration generated for
+// imports, so that it can be told apart from hand-written configuration.
+const importConfigHeader = "# __generated__ by Terraform\n" +
+	"# Please review these resources and move them into your main configuration files.\n"
+
+// importConfigWriter writes the configuration of imported resources from
+// their schemas and the attributes of their imported objects.
+type importConfigWriter struct {
+	buf bytes.Buffer
+}
+
+// Resource writes the configuration of a resource with the given schema,
+// whose imported object has the given flatmapped attributes.
+//
+// Attributes that are only computed by the provider are left out, as are
+// optional attributes without a value. Required attributes are always
+// written, so that the configuration is complete even if the provider
+// didn't return a value, and sensitive ones are written with an empty value
+// to be filled in, rather than with the secret itself.
+func (w *importConfigWriter) Resource(addr *ResourceAddress, target *ImportTarget, schema *configschema.Block, attrs map[string]string) {
+	fmt.Fprintf(&w.buf, "\n# __generated__ by Terraform from %q\n", target.ID)
+	fmt.Fprintf(&w.buf, "resource %q %q {\n", addr.Type, addr.Name)
+	if target.Provider != "" {
+		fmt.Fprintf(&w.buf, "provider = %q\n", target.Provider)
+	}
+	w.block(schema, attrs, "")
+	w.buf.WriteString("}\n")
+}
+
+// Bytes returns the configuration written so far, formatted.
+func (w *importConfigWriter) Bytes() ([]byte, error) {
+	src := append([]byte(importConfigHeader), w.buf.Bytes()...)
+	out, err := printer.Format(src)
+	if err != nil {
+		// The configuration is written from schemas, so this is a bug.
+		return nil, fmt.Errorf("generated configuration is invalid: %s", err)
+	}
+	return out, nil
+}
+
+func (w *importConfigWriter) block(schema *configschema.Block, attrs map[string]string, prefix string) {
+	names := make([]string, 0, len(schema.Attributes))
+	for name := range schema.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		attr := schema.Attributes[name]
+		if name == "id" && prefix == "" || !attr.Required && !attr.Optional {
+			continue
+		}
+		if _, ok := importReservedNames[name]; ok && prefix == "" {
+			// The argument would be taken as the meta-argument.
+			fmt.Fprintf(&w.buf, "# %s can't be generated, since it's a meta-argument\n", name)
+			continue
+		}
+
+		switch {
+		case attr.Sensitive && attr.Required:
+			fmt.Fprintf(&w.buf, "%s = %s # sensitive\n", name, importZeroValue(attr.Type))
+			continue
+		case attr.Sensitive:
+			continue
+		}
+
+		v, ok := importValue(attr.Type, attrs, prefix+name)
+		switch {
+		case ok:
+			fmt.Fprintf(&w.buf, "%s = %s\n", name, v)
+		case attr.Required:
+			fmt.Fprintf(&w.buf, "%s = %s\n", name, importZeroValue(attr.Type))
+		}
+	}
+
+	names = names[:0]
+	for name := range schema.BlockTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		nested := schema.BlockTypes[name]
+		switch nested.Nesting {
+		case configschema.NestingSingle, configschema.NestingList, configschema.NestingSet:
+			for _, idx := range importIndexes(attrs, prefix+name) {
+				fmt.Fprintf(&w.buf, "%s {\n", name)
+				w.block(&nested.Block, attrs, prefix+name+"."+idx+".")
+				w.buf.WriteString("}\n")
+			}
+		default:
+			// Providers don't yet use blocks nested as maps, which have no
+			// flatmap representation to write them from.
+		}
+	}
+}
+
+// importReservedNames are the meta-arguments of resources, which attributes
+// of the same name can't be written as.
+var importReservedNames = map[string]struct{}{
+	"count":      {},
+	"depends_on": {},
+	"lifecycle":  {},
+	"provider":   {},
+}
+
+// importValue returns the HCL of the value of the given type at the given
+// flatmap key, and false if there is no value, it's empty or it's unknown.
+func importValue(ty cty.Type, attrs map[string]string, key string) (string, bool) {
+	switch {
+	case ty.IsPrimitiveType():
+		v, ok := attrs[key]
+		if !ok || v == "" || v == config.UnknownVariableValue {
+			return "", false
+		}
+		if ty == cty.String {
+			return importString(v), true
+		}
+		return v, true
+
+	case ty.IsListType() || ty.IsSetType():
+		var elems []string
+		for _, idx := range importIndexes(attrs, key) {
+			if v, ok := importValue(ty.ElementType(), attrs, key+"."+idx); ok {
+				elems = append(elems, v)
+			}
+		}
+		if len(elems) == 0 {
+			return "", false
+		}
+		return "[" + strings.Join(elems, ", ") + "]", true
+
+	case ty.IsMapType():
+		var keys []string
+		for k := range attrs {
+			if strings.HasPrefix(k, key+".") && k != key+".%" {
+				keys = append(keys, strings.TrimPrefix(k, key+"."))
+			}
+		}
+		sort.Strings(keys)
+
+		var buf bytes.Buffer
+		for _, k := range keys {
+			if v, ok := importValue(ty.ElementType(), attrs, key+"."+k); ok {
+				fmt.Fprintf(&buf, "%s = %s\n", importString(k), v)
+			}
+		}
+		if buf.Len() == 0 {
+			return "", false
+		}
+		return "{\n" + buf.String() + "}", true
+
+	default:
+		// Attributes of other types are written as nested blocks by the
+		// providers, and aren't in their attributes.
+		return "", false
+	}
+}
+
+// importIndexes returns the indexes of the elements of the list or set at
+// the given flatmap key: positions for lists, and hashes for sets.
+func importIndexes(attrs map[string]string, key string) []string {
+	if n, _ := strconv.Atoi(attrs[key+".#"]); n == 0 {
+		return nil
+	}
+
+	seen := make(map[string]struct{})
+	var idxs []string
+	for k := range attrs {
+		if !strings.HasPrefix(k, key+".") {
+			continue
+		}
+		idx := strings.TrimPrefix(k, key+".")
+		if i := strings.IndexByte(idx, '.'); i >= 0 {
+			idx = idx[:i]
+		}
+		if _, ok := seen[idx]; ok || idx == "#" {
+			continue
+		}
+		seen[idx] = struct{}{}
+		idxs = append(idxs, idx)
+	}
+
+	// List positions are sorted numerically, to keep the list's order.
+	sort.Slice(idxs, func(i, j int) bool {
+		a, aErr := strconv.Atoi(idxs[i])
+		b, bErr := strconv.Atoi(idxs[j])
+		if aErr != nil || bErr != nil {
+			return idxs[i] < idxs[j]
+		}
+		return a < b
+	})
+	return idxs
+}
+
+// importZeroValue returns the HCL of the empty value of the given type.
+func importZeroValue(ty cty.Type) string {
+	switch {
+	case ty == cty.Number:
+		return "0"
+	case ty == cty.Bool:
+		return "false"
+	case ty.IsListType() || ty.IsSetType():
+		return "[]"
+	case ty.IsMapType():
+		return "{}"
+	default:
+		return `""`
+	}
+}
+
+// importString returns the given string quoted, with interpolation
+// sequences escaped so that it's written literally.
+func importString(s string) string {
+	return strings.Replace(strconv.Quote(s), "${", "$${", -1)
+}
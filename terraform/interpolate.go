@@ -31,6 +31,39 @@ type Interpolater struct {
 	StateLock          *sync.RWMutex
 	VariableValues     map[string]interface{}
 	VariableValuesLock *sync.Mutex
+
+	// Ephemeral holds the objects of the open ephemeral resources. It may
+	// be nil outside of a graph walk, in which case they're all unknown.
+	Ephemeral *EphemeralValues
+
+	// Functions supplies the functions exported by providers. It may be
+	// nil outside of a graph walk, in which case they can't be called.
+	Functions *ProviderFunctions
+
+	// Deferred holds the resources whose changes are deferred, which are
+	// unknown. It may be nil outside of a graph walk.
+	Deferred *DeferredChanges
+
+	// Explain records the values interpolated for the resource explained
+	// by a plan. It may be nil.
+	Explain *ExplainValues
+
+	// Unknowns records which of the values interpolated for resources
+	// were unknown when planning. It may be nil.
+	Unknowns *UnknownValues
+}
+
+// ProviderFunctions returns the interpolation functions for the given
+// provider function names, as called by a RawConfig.
+func (i *Interpolater) ProviderFunctions(names []string) (map[string]ast.Function, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	if i.Functions == nil {
+		return nil, fmt.Errorf("provider functions can't be called here: %s", names[0])
+	}
+
+	return i.Functions.Functions(names)
 }
 
 // InterpolationScope is the current scope of execution. This is required
@@ -39,6 +72,10 @@ type Interpolater struct {
 type InterpolationScope struct {
 	Path     []string
 	Resource *Resource
+
+	// ProviderConfig is the provider configuration being interpolated, if
+	// any, which has the values of the each variables.
+	ProviderConfig *config.ProviderConfig
 }
 
 // Values returns the values for all the variables in the given map.
@@ -78,6 +115,8 @@ func (i *Interpolater) Values(
 		switch v := rawV.(type) {
 		case *config.CountVariable:
 			err = i.valueCountVar(scope, n, v, result)
+		case *config.EachVariable:
+			err = i.valueEachVar(scope, n, v, result)
 		case *config.ModuleVariable:
 			err = i.valueModuleVar(scope, n, v, result)
 		case *config.PathVariable:
@@ -103,6 +142,11 @@ func (i *Interpolater) Values(
 		}
 	}
 
+	if i.Operation == walkPlan {
+		i.Explain.record(scope, vars, result)
+		i.Unknowns.record(scope, vars, result)
+	}
+
 	return result, nil
 }
 
@@ -126,6 +170,35 @@ func (i *Interpolater) valueCountVar(
 	}
 }
 
+func (i *Interpolater) valueEachVar(
+	scope *InterpolationScope,
+	n string,
+	v *config.EachVariable,
+	result map[string]ast.Variable) error {
+	pc := scope.ProviderConfig
+	if pc == nil || pc.ForEachKey == "" {
+		return fmt.Errorf("%s: each variables are only valid in provider blocks with for_each", n)
+	}
+
+	switch v.Type {
+	case config.EachValueKey:
+		result[n] = ast.Variable{
+			Value: pc.ForEachKey,
+			Type:  ast.TypeString,
+		}
+		return nil
+	case config.EachValueValue:
+		variable, err := hil.InterfaceToVariable(pc.ForEachValue)
+		if err != nil {
+			return fmt.Errorf("%s: %s", n, err)
+		}
+		result[n] = variable
+		return nil
+	default:
+		return fmt.Errorf("%s: unknown each type: %#v", n, v.Type)
+	}
+}
+
 func unknownVariable() ast.Variable {
 	return ast.Variable{
 		Type:  ast.TypeUnknown,
@@ -243,6 +316,10 @@ func (i *Interpolater) valueResourceVar(
 		return nil
 	}
 
+	if v.Mode == config.EphemeralResourceMode {
+		return i.valueEphemeralVar(scope, n, v, result)
+	}
+
 	var variable *ast.Variable
 	var err error
 
@@ -275,6 +352,63 @@ func (i *Interpolater) valueResourceVar(
 	return nil
 }
 
+func (i *Interpolater) valueEphemeralVar(
+	scope *InterpolationScope,
+	n string,
+	v *config.ResourceVariable,
+	result map[string]ast.Variable) error {
+	var path []string
+	if scope != nil {
+		path = scope.Path
+	}
+
+	var s *InstanceState
+	if i.Ephemeral != nil {
+		s = i.Ephemeral.Get(path, v.ResourceId())
+	}
+	if s == nil {
+		// An ephemeral resource isn't opened while its own configuration
+		// is unknown, so neither are its attributes. That can't happen
+		// during apply, when everything it could depend on is known.
+		if i.Operation == walkApply || i.Operation == walkDestroy {
+			return fmt.Errorf(
+				"Ephemeral resource '%s' is not open for variable '%s'",
+				v.ResourceId(),
+				v.FullKey())
+		}
+
+		result[n] = unknownVariable()
+		return nil
+	}
+
+	if attr, ok := s.Attributes[v.Field]; ok {
+		variable, err := hil.InterfaceToVariable(attr)
+		if err != nil {
+			return err
+		}
+		result[n] = variable
+		return nil
+	}
+
+	_, isList := s.Attributes[v.Field+".#"]
+	_, isMap := s.Attributes[v.Field+".%"]
+	if isList || isMap {
+		variable, err := i.interpolateComplexTypeAttribute(v.Field, s.Attributes)
+		if err != nil {
+			return err
+		}
+		result[n] = variable
+		return nil
+	}
+
+	return fmt.Errorf(
+		"Ephemeral resource '%s' does not have attribute '%s' "+
+			"for variable '%s'",
+		v.ResourceId(),
+		v.Field,
+		v.FullKey())
+}
+
 func (i *Interpolater) valueSelfVar(
 	scope *InterpolationScope,
 	n string,
@@ -450,6 +584,12 @@ func (i *Interpolater) computeResourceVariable(
 
 	unknownVariable := unknownVariable()
 
+	// The changes of a deferred resource are only planned by a later plan,
+	// so nothing is known about it until then.
+	if i.Deferred.Deferred(scope.Path, v.ResourceId()) {
+		return &unknownVariable, nil
+	}
+
 	// These variables must be declared early because of the use of GOTO
 	var isList bool
 	var isMap bool
@@ -577,7 +717,10 @@ MISSING:
 	//
 	// For an input walk, computed values are okay to return because we're only
 	// looking for missing variables to prompt the user for.
-	if i.Operation == walkRefresh || i.Operation == walkPlanDestroy || i.Operation == walkInput {
+	//
+	// For a dry-run apply, nothing is applied, so the values that applying
+	// would set are unknown.
+	if i.Operation == walkRefresh || i.Operation == walkPlanDestroy || i.Operation == walkInput || i.Operation == walkValidateApply {
 		return &unknownVariable, nil
 	}
 
@@ -604,6 +747,11 @@ func (i *Interpolater) computeResourceMultiVariable(
 		return &unknownVariable, nil
 	}
 
+	// As above, nothing is known about a deferred resource.
+	if i.Deferred.Deferred(scope.Path, v.ResourceId()) {
+		return &unknownVariable, nil
+	}
+
 	// Get the information about this resource variable, and verify
 	// that it exists and such.
 	module, cr, err := i.resourceVariableInfo(scope, v)
@@ -680,7 +828,7 @@ func (i *Interpolater) computeResourceMultiVariable(
 		//
 		// For an input walk, computed values are okay to return because we're only
 		// looking for missing variables to prompt the user for.
-		if i.Operation == walkRefresh || i.Operation == walkPlanDestroy || i.Operation == walkDestroy || i.Operation == walkInput {
+		if i.Operation == walkRefresh || i.Operation == walkPlanDestroy || i.Operation == walkDestroy || i.Operation == walkInput || i.Operation == walkValidateApply {
 			return &unknownVariable, nil
 		}
 
@@ -68,8 +68,12 @@ func moduleTreeConfigDependencies(root *module.Tree, inheritProviders map[string
 
 		// Each resource in the configuration creates an *implicit* provider
 		// dependency, though we'll only record it if there isn't already
-		// an explicit dependency on the same provider.
-		for _, rc := range cfg.Resources {
+		// an explicit dependency on the same provider. Ephemeral resources
+		// need their providers just the same.
+		resources := make([]*config.Resource, 0, len(cfg.Resources)+len(cfg.EphemeralResources))
+		resources = append(resources, cfg.Resources...)
+		resources = append(resources, cfg.EphemeralResources...)
+		for _, rc := range resources {
 			fullName := rc.ProviderFullName()
 			inst := moduledeps.ProviderInstance(fullName)
 			if _, exists := providers[inst]; exists {
@@ -88,6 +92,39 @@ func moduleTreeConfigDependencies(root *module.Tree, inheritProviders map[string
 			}
 		}
 
+		// Calling a provider's functions needs the provider, even if
+		// nothing else in the configuration uses it.
+		for _, name := range cfg.FunctionProviders() {
+			inst := moduledeps.ProviderInstance(name)
+			if _, exists := providers[inst]; exists {
+				continue
+			}
+
+			providers[inst] = moduledeps.ProviderDependency{
+				Constraints: discovery.AllVersions,
+				Reason:      moduledeps.ProviderDependencyImplicit,
+			}
+		}
+
+		// Imports need their providers too, including those to resources
+		// that aren't declared yet, whose configuration is generated from
+		// the provider's schema.
+		for _, i := range cfg.Imports {
+			addr, err := ParseResourceAddress(i.To)
+			if err != nil {
+				continue
+			}
+			inst := moduledeps.ProviderInstance(config.ResourceProviderFullName(addr.Type, i.Provider))
+			if _, exists := providers[inst]; exists {
+				continue
+			}
+
+			providers[inst] = moduledeps.ProviderDependency{
+				Constraints: discovery.AllVersions,
+				Reason:      moduledeps.ProviderDependencyImplicit,
+			}
+		}
+
 		ret.Providers = providers
 	}
 
@@ -0,0 +1,242 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/module"
+)
+
+// ParseTargetModule parses the address of a module that an operation is
+// scoped to with -target-module, such as "module.foo" or
+// "module.foo.module.bar", returning its path without the root.
+func ParseTargetModule(s string) ([]string, error) {
+	addr, err := ParseResourceAddress(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(addr.Path) == 0 || addr.HasResourceSpec() {
+		return nil, fmt.Errorf("must be the address of a module, such as module.foo")
+	}
+	return addr.Path, nil
+}
+
+// checkTargetModules returns an error if one of the modules that the
+// context is scoped to is in neither the configuration nor the state.
+// Destroying can be scoped to a module that's only in the state, since it
+// may have been removed from the configuration.
+func (c *Context) checkTargetModules() error {
+	for _, s := range c.targetModules {
+		path, err := ParseTargetModule(s)
+		if err != nil {
+			return fmt.Errorf("Invalid module scope %q: %s", s, err)
+		}
+		if c.module != nil && c.module.Child(path) != nil {
+			continue
+		}
+		if c.destroy && c.state != nil && c.state.ModuleByPath(normalizeModulePath(path)) != nil {
+			continue
+		}
+		return fmt.Errorf("Invalid module scope %q: the module isn't declared in the configuration", s)
+	}
+	return nil
+}
+
+// scopeDependents returns the addresses of the resources and module calls
+// outside of the given module scopes that depend on one of the scoped
+// modules, directly or through other objects. Their changes aren't part of
+// a scoped plan, though they may need to change once it's applied.
+//
+// Dependents are found from the references in the configuration, since the
+// graph of a scoped plan leaves them out.
+func scopeDependents(root *module.Tree, scopes []string) []string {
+	var paths [][]string
+	for _, s := range scopes {
+		if path, err := ParseTargetModule(s); err == nil {
+			paths = append(paths, path)
+		}
+	}
+
+	seen := make(map[string]struct{})
+	var ret []string
+	for _, path := range paths {
+		// Each module whose outputs depend on the scope makes the objects
+		// that refer to it in its own parent dependents too, up to the
+		// root module.
+		for i := len(path); i > 0; i-- {
+			parent := root.Child(path[:i-1])
+			if parent == nil {
+				break
+			}
+			deps := configDependents(parent.Config(), "module."+path[i-1])
+
+			prefix := modulePrefixStr(path[:i-1])
+			outputs := false
+			for _, key := range deps {
+				switch {
+				case strings.HasPrefix(key, "output."):
+					outputs = true
+					continue
+				case strings.HasPrefix(key, "local."), strings.HasPrefix(key, "ephemeral."):
+					continue
+				}
+
+				addr := key
+				if prefix != "" {
+					addr = prefix + "." + key
+				}
+				if _, ok := seen[addr]; ok || inModuleScopes(addr, paths) {
+					continue
+				}
+				seen[addr] = struct{}{}
+				ret = append(ret, addr)
+			}
+			if !outputs {
+				break
+			}
+		}
+	}
+
+	sort.Strings(ret)
+	return ret
+}
+
+// inModuleScopes returns true if the given address of a resource or a
+// module call is within one of the given module paths.
+func inModuleScopes(addr string, paths [][]string) bool {
+	for _, path := range paths {
+		prefix := modulePrefixStr(path)
+		if addr == prefix || strings.HasPrefix(addr, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// configDependents returns the keys of the objects of the given module
+// configuration that depend on the object with the given key, such as
+// "module.foo", directly or through other objects. Keys are the addresses
+// of resources and module calls within the module, and "local.NAME" and
+// "output.NAME" for local values and outputs.
+func configDependents(cfg *config.Config, key string) []string {
+	if cfg == nil {
+		return nil
+	}
+	return configReach(configReferrers(cfg), key)
+}
+
+// configDependencies is the opposite of configDependents, returning the
+// keys of the objects of the given module configuration that the object
+// with the given key depends on, directly or through other objects.
+func configDependencies(cfg *config.Config, key string) []string {
+	if cfg == nil {
+		return nil
+	}
+
+	refs := make(map[string][]string)
+	for to, froms := range configReferrers(cfg) {
+		for _, from := range froms {
+			refs[from] = append(refs[from], to)
+		}
+	}
+	return configReach(refs, key)
+}
+
+// configReferrers returns the keys of the objects of the given module
+// configuration that refer to each object directly, by the key of the
+// object they refer to.
+func configReferrers(cfg *config.Config) map[string][]string {
+	refs := make(map[string][]string)
+	add := func(from string, raw ...*config.RawConfig) {
+		for _, rc := range raw {
+			if rc == nil {
+				continue
+			}
+			for _, ref := range ReferencesFromConfig(rc) {
+				if to := configRefKey(ref); to != "" {
+					refs[to] = append(refs[to], from)
+				}
+			}
+		}
+	}
+	addDependsOn := func(from string, deps []string) {
+		for _, dep := range deps {
+			if to := configRefKey(dep); to != "" {
+				refs[to] = append(refs[to], from)
+			}
+		}
+	}
+
+	for _, r := range cfg.Resources {
+		id := r.Id()
+		add(id, r.RawCount, r.RawConfig)
+		for _, p := range r.Provisioners {
+			add(id, p.RawConfig, p.ConnInfo)
+		}
+		addDependsOn(id, r.DependsOn)
+	}
+	for _, r := range cfg.EphemeralResources {
+		add(r.Id(), r.RawConfig)
+	}
+	for _, m := range cfg.Modules {
+		add("module."+m.Name, m.RawConfig)
+	}
+	for _, l := range cfg.Locals {
+		add("local."+l.Name, l.RawConfig)
+	}
+	for _, o := range cfg.Outputs {
+		add("output."+o.Name, o.RawConfig)
+		addDependsOn("output."+o.Name, o.DependsOn)
+	}
+	return refs
+}
+
+// configReach returns the keys that can be reached from the given key in
+// the given map of keys, in the order that they're reached.
+func configReach(refs map[string][]string, key string) []string {
+	seen := map[string]struct{}{key: {}}
+	queue := []string{key}
+	var ret []string
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, from := range refs[next] {
+			if _, ok := seen[from]; ok {
+				continue
+			}
+			seen[from] = struct{}{}
+			ret = append(ret, from)
+			queue = append(queue, from)
+		}
+	}
+	return ret
+}
+
+// configRefKey returns the key of the object that the given reference, as
+// returned by ReferencesFromConfig or given in depends_on, refers to, or ""
+// if it doesn't refer to an object of the module.
+func configRefKey(ref string) string {
+	if i := strings.IndexByte(ref, '/'); i >= 0 {
+		ref = ref[:i]
+	}
+	parts := strings.Split(ref, ".")
+	if len(parts) < 2 {
+		return ""
+	}
+
+	switch parts[0] {
+	case "var", "count", "path", "self", "terraform":
+		return ""
+	case "module", "local":
+		return parts[0] + "." + parts[1]
+	case "data", "ephemeral":
+		if len(parts) < 3 {
+			return ""
+		}
+		return strings.Join(parts[:3], ".")
+	default:
+		return parts[0] + "." + parts[1]
+	}
+}
@@ -0,0 +1,61 @@
+package terraform
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/config"
+)
+
+// NodeApplyableCheck represents a check block, whose assertions are checked
+// during plan and apply.
+type NodeApplyableCheck struct {
+	PathValue []string
+	Config    *config.Check // Config is the check in the config
+}
+
+func (n *NodeApplyableCheck) Name() string {
+	return checkAddr(n.PathValue, n.Config.Name)
+}
+
+// GraphNodeSubPath
+func (n *NodeApplyableCheck) Path() []string {
+	return n.PathValue
+}
+
+// RemovableIfNotTargeted
+func (n *NodeApplyableCheck) RemoveIfNotTargeted() bool {
+	return true
+}
+
+// GraphNodeReferencer
+func (n *NodeApplyableCheck) References() []string {
+	var result []string
+	for _, c := range n.Config.Asserts {
+		result = append(result, ReferencesFromConfig(c.RawConfig)...)
+	}
+
+	return result
+}
+
+// GraphNodeEvalable
+func (n *NodeApplyableCheck) EvalTree() EvalNode {
+	return &EvalOpFilter{
+		Ops: []walkOperation{walkPlan, walkApply},
+		Node: &EvalCheck{
+			Name:    n.Config.Name,
+			Addr:    n.Name(),
+			Asserts: n.Config.Asserts,
+		},
+	}
+}
+
+// checkAddr returns the address of the check with the given name in the
+// module with the given path.
+func checkAddr(path []string, name string) string {
+	result := fmt.Sprintf("check.%s", name)
+	if len(path) > 1 {
+		result = fmt.Sprintf("%s.%s", modulePrefixStr(path), result)
+	}
+
+	return result
+}
@@ -17,6 +17,11 @@ func (n *NodeRefreshableDataResource) DynamicExpand(ctx EvalContext) (*Graph, er
 	lock.RLock()
 	defer lock.RUnlock()
 
+	// A deferred resource has no instances until a later plan
+	if ctx.Deferred().Deferred(ctx.Path(), n.Config.Id()) {
+		return nil, nil
+	}
+
 	// Expand the resource count which must be available by now from EvalTree
 	count, err := n.Config.Count()
 	if err != nil {
@@ -139,7 +144,7 @@ func (n *NodeRefreshableDataResourceInstance) EvalTree() EvalNode {
 	var provider ResourceProvider
 	var state *InstanceState
 
-	return &EvalSequence{
+	return n.checkDataEvalTree(addr, stateId, &EvalSequence{
 		Nodes: []EvalNode{
 			// Always destroy the existing state first, since we must
 			// make sure that values from a previous read will not
@@ -217,5 +222,5 @@ func (n *NodeRefreshableDataResourceInstance) EvalTree() EvalNode {
 
 			&EvalUpdateStateHook{},
 		},
-	}
+	})
 }
@@ -0,0 +1,133 @@
+package terraform
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/config"
+)
+
+// NodeEphemeralResource represents an ephemeral resource, which is opened
+// during each walk that needs its values and closed by its
+// nodeCloseEphemeralResource at the end of the walk.
+type NodeEphemeralResource struct {
+	PathValue        []string
+	Config           *config.Resource // Config is the ephemeral resource in the config
+	ResolvedProvider string
+}
+
+func (n *NodeEphemeralResource) Name() string {
+	result := n.Config.Id()
+	if len(n.PathValue) > 1 {
+		result = fmt.Sprintf("%s.%s", modulePrefixStr(n.PathValue), result)
+	}
+
+	return result
+}
+
+// GraphNodeSubPath
+func (n *NodeEphemeralResource) Path() []string {
+	return n.PathValue
+}
+
+// RemovableIfNotTargeted
+func (n *NodeEphemeralResource) RemoveIfNotTargeted() bool {
+	return true
+}
+
+// GraphNodeReferenceable
+func (n *NodeEphemeralResource) ReferenceableName() []string {
+	// An ephemeral resource has exactly one instance, but references to it
+	// are made in the same form as to resources with a count.
+	id := n.Config.Id()
+	return []string{id, id + ".0", id + ".N", id + ".*"}
+}
+
+// GraphNodeReferencer
+func (n *NodeEphemeralResource) References() []string {
+	var result []string
+	result = append(result, n.Config.DependsOn...)
+	result = append(result, ReferencesFromConfig(n.Config.RawConfig)...)
+
+	return uniqueStrings(result)
+}
+
+// GraphNodeProviderConsumer
+func (n *NodeEphemeralResource) ProvidedBy() string {
+	return resourceProvider(n.Config.Type, n.Config.Provider)
+}
+
+// GraphNodeProviderConsumer
+func (n *NodeEphemeralResource) SetProvider(p string) {
+	n.ResolvedProvider = p
+}
+
+func (n *NodeEphemeralResource) instanceInfo() *InstanceInfo {
+	return &InstanceInfo{
+		Id:         n.Config.Id(),
+		ModulePath: normalizeModulePath(n.PathValue),
+		Type:       n.Config.Type,
+	}
+}
+
+// GraphNodeEvalable
+func (n *NodeEphemeralResource) EvalTree() EvalNode {
+	var provider ResourceProvider
+	var config *ResourceConfig
+
+	return &EvalOpFilter{
+		Ops: []walkOperation{walkRefresh, walkPlan, walkApply, walkDestroy},
+		Node: &EvalSequence{
+			Nodes: []EvalNode{
+				&EvalGetProvider{
+					Name:   n.ResolvedProvider,
+					Output: &provider,
+				},
+				&EvalInterpolate{
+					Config: n.Config.RawConfig.Copy(),
+					Output: &config,
+				},
+				&EvalOpenEphemeral{
+					Info:     n.instanceInfo(),
+					Provider: &provider,
+					Config:   &config,
+				},
+			},
+		},
+	}
+}
+
+// nodeCloseEphemeralResource closes an ephemeral resource once everything
+// else in the graph, other than the closing of providers, is done.
+type nodeCloseEphemeralResource struct {
+	Open *NodeEphemeralResource
+}
+
+func (n *nodeCloseEphemeralResource) Name() string {
+	return n.Open.Name() + " (close)"
+}
+
+// GraphNodeSubPath
+func (n *nodeCloseEphemeralResource) Path() []string {
+	return n.Open.Path()
+}
+
+// GraphNodeEvalable
+func (n *nodeCloseEphemeralResource) EvalTree() EvalNode {
+	var provider ResourceProvider
+
+	return &EvalOpFilter{
+		Ops: []walkOperation{walkRefresh, walkPlan, walkApply, walkDestroy},
+		Node: &EvalSequence{
+			Nodes: []EvalNode{
+				&EvalGetProvider{
+					Name:   n.Open.ResolvedProvider,
+					Output: &provider,
+				},
+				&EvalCloseEphemeral{
+					Info:     n.Open.instanceInfo(),
+					Provider: &provider,
+				},
+			},
+		},
+	}
+}
@@ -61,6 +61,13 @@ func (n *NodeLocal) References() []string {
 func (n *NodeLocal) EvalTree() EvalNode {
 	return &EvalSequence{
 		Nodes: []EvalNode{
+			&EvalOpFilter{
+				Ops: []walkOperation{walkRefresh, walkPlan},
+				Node: &EvalDeferReferences{
+					Name:  "local." + n.Config.Name,
+					Value: n.Config.RawConfig,
+				},
+			},
 			&EvalOpFilter{
 				Ops: []walkOperation{
 					walkInput,
@@ -68,6 +75,7 @@ func (n *NodeLocal) EvalTree() EvalNode {
 					walkRefresh,
 					walkPlan,
 					walkApply,
+					walkValidateApply,
 				},
 				Node: &EvalSequence{
 					Nodes: []EvalNode{
@@ -103,9 +103,17 @@ func (n *NodeApplyableModuleVariable) EvalTree() EvalNode {
 					ContinueOnErr: true,
 				},
 			},
+			&EvalOpFilter{
+				Ops: []walkOperation{walkRefresh, walkPlan},
+				Node: &EvalDeferReferences{
+					Name:  "var." + n.Config.Name,
+					Path:  n.PathValue,
+					Value: n.Value,
+				},
+			},
 			&EvalOpFilter{
 				Ops: []walkOperation{walkRefresh, walkPlan, walkApply,
-					walkDestroy, walkValidate},
+					walkDestroy, walkValidate, walkValidateApply},
 				Node: &EvalInterpolate{
 					Config: n.Value,
 					Output: &config,
@@ -55,6 +55,9 @@ func (n *NodeApplyableOutput) References() []string {
 	var result []string
 	result = append(result, n.Config.DependsOn...)
 	result = append(result, ReferencesFromConfig(n.Config.RawConfig)...)
+	for _, c := range n.Config.Preconditions {
+		result = append(result, ReferencesFromConfig(c.RawConfig)...)
+	}
 	for _, v := range result {
 		split := strings.Split(v, "/")
 		for i, s := range split {
@@ -82,6 +85,21 @@ func (n *NodeApplyableOutput) EvalTree() EvalNode {
 					ContinueOnErr: true,
 				},
 			},
+			&EvalOpFilter{
+				Ops: []walkOperation{walkRefresh, walkPlan},
+				Node: &EvalDeferReferences{
+					Name:  "output." + n.Config.Name,
+					Value: n.Config.RawConfig,
+				},
+			},
+			&EvalOpFilter{
+				Ops: []walkOperation{walkPlan, walkApply},
+				Node: &EvalCheckConditions{
+					Addr:       n.Name(),
+					Type:       ConditionTypePrecondition,
+					Conditions: n.Config.Preconditions,
+				},
+			},
 			&EvalOpFilter{
 				Ops: []walkOperation{walkRefresh, walkPlan, walkApply, walkValidate},
 				Node: &EvalWriteOutput{
@@ -104,6 +104,12 @@ func (n *NodeAbstractResource) References() []string {
 				result = append(result, ReferencesFromConfig(p.RawConfig)...)
 			}
 		}
+		for _, cr := range c.Lifecycle.Preconditions {
+			result = append(result, ReferencesFromConfig(cr.RawConfig)...)
+		}
+		for _, cr := range c.Lifecycle.Postconditions {
+			result = append(result, ReferencesFromConfig(cr.RawConfig)...)
+		}
 
 		return uniqueStrings(result)
 	}
@@ -173,6 +179,23 @@ func (n *NodeAbstractResource) StateReferences() []string {
 	return deps
 }
 
+// checkDataEvalTree wraps the eval tree of a data source declared in a
+// check block with EvalCheckData, and returns the tree unchanged for other
+// resources.
+func (n *NodeAbstractResource) checkDataEvalTree(
+	addr *ResourceAddress, stateId string, node EvalNode) EvalNode {
+	if n.Config == nil || n.Config.Check == "" {
+		return node
+	}
+
+	return &EvalCheckData{
+		Addr:      addr.String(),
+		StateId:   stateId,
+		CheckAddr: checkAddr(normalizeModulePath(addr.Path), n.Config.Check),
+		Node:      node,
+	}
+}
+
 func (n *NodeAbstractResource) SetProvider(p string) {
 	n.ResolvedProvider = p
 }
@@ -193,6 +216,37 @@ func (n *NodeAbstractResource) ProvidedBy() string {
 	return resourceProvider(n.Addr.Type, "")
 }
 
+// GraphNodeLifecycleGroup
+func (n *NodeAbstractResource) LifecycleGroup() string {
+	// The configuration is preferred, and the state has the group of a
+	// resource that was removed from the configuration.
+	if n.Config != nil {
+		return n.Config.Lifecycle.Group
+	}
+	if n.ResourceState != nil {
+		return n.ResourceState.Group
+	}
+	return ""
+}
+
+// GraphNodeLifecycleGroup
+func (n *NodeAbstractResource) CreateAfterDestroyGroups() []string {
+	if n.Config == nil {
+		return nil
+	}
+	return n.Config.Lifecycle.CreateAfterDestroy
+}
+
+// stateGroup returns the ordering group to record in the state for the
+// resource, or nil if the resource has no configuration.
+func (n *NodeAbstractResource) stateGroup() *string {
+	if n.Config == nil {
+		return nil
+	}
+	group := n.Config.Lifecycle.Group
+	return &group
+}
+
 // GraphNodeProvisionerConsumer
 func (n *NodeAbstractResource) ProvisionedBy() []string {
 	// If we have no configuration, then we have no provisioners
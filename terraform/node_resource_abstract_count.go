@@ -16,10 +16,14 @@ type NodeAbstractCountResource struct {
 func (n *NodeAbstractCountResource) EvalTree() EvalNode {
 	// We only check if the count is computed if we're not validating.
 	// If we're validating we allow computed counts since they just turn
-	// into more computed values.
-	var evalCountCheckComputed EvalNode
+	// into more computed values. Otherwise a resource with a computed
+	// count is deferred, along with the resources that depend on it.
+	var evalDeferResource EvalNode
 	if !n.Validate {
-		evalCountCheckComputed = &EvalCountCheckComputed{Resource: n.Config}
+		evalDeferResource = &EvalDeferResource{
+			Addr:     n.ResourceAddr(),
+			Resource: n.Config,
+		}
 	}
 
 	return &EvalSequence{
@@ -32,8 +36,8 @@ func (n *NodeAbstractCountResource) EvalTree() EvalNode {
 			// into the proper number of instances.
 			&EvalInterpolate{Config: n.Config.RawCount},
 
-			// Check if the count is computed
-			evalCountCheckComputed,
+			// Defer the resource if the count is computed
+			evalDeferResource,
 
 			// If validation is enabled, perform the validation
 			&EvalIf{
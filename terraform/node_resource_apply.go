@@ -76,19 +76,97 @@ func (n *NodeApplyableResource) EvalTree() EvalNode {
 	// Eval info is different depending on what kind of resource this is
 	switch n.Config.Mode {
 	case config.ManagedResourceMode:
-		return n.evalTreeManagedResource(
-			stateId, info, resource, stateDeps,
-		)
+		return &EvalSequence{
+			Nodes: []EvalNode{
+				n.evalTreeValidateApply(stateId, info, false),
+				&EvalOpFilter{
+					Ops: []walkOperation{walkApply, walkDestroy},
+					Node: n.evalTreeManagedResource(
+						addr, stateId, info, resource, stateDeps,
+					),
+				},
+			},
+		}
 	case config.DataResourceMode:
-		return n.evalTreeDataResource(
-			stateId, info, resource, stateDeps)
+		// Data sources aren't read by a dry-run apply.
+		return &EvalOpFilter{
+			Ops: []walkOperation{walkApply, walkDestroy},
+			Node: n.checkDataEvalTree(addr, stateId, n.evalTreeDataResource(
+				addr, stateId, info, resource, stateDeps)),
+		}
 	default:
 		panic(fmt.Errorf("unsupported resource mode %s", n.Config.Mode))
 	}
 }
 
+// evalTreeValidateApply returns the evaluation tree of a dry-run apply of
+// the resource, which gives its planned change to the provider to check
+// without applying it. If destroy is true, only the destroy of the change
+// is checked, as by the destroy node of the resource, and otherwise the
+// rest of it.
+func (n *NodeAbstractResource) evalTreeValidateApply(
+	stateId string, info *InstanceInfo, destroy bool) EvalNode {
+	var provider ResourceProvider
+	var diff *InstanceDiff
+	var state *InstanceState
+
+	return &EvalOpFilter{
+		Ops: []walkOperation{walkValidateApply},
+		Node: &EvalSequence{
+			Nodes: []EvalNode{
+				&EvalInstanceInfo{Info: info},
+				&EvalReadDiff{
+					Name: stateId,
+					Diff: &diff,
+				},
+				&EvalGetProvider{
+					Name:   n.ResolvedProvider,
+					Output: &provider,
+				},
+				&EvalReadState{
+					Name:   stateId,
+					Output: &state,
+				},
+				&EvalIf{
+					If: func(ctx EvalContext) (bool, error) {
+						if diff.Empty() || diff.GetDestroyDeposed() {
+							return true, EvalEarlyExitError{}
+						}
+
+						replace := diff.RequiresNew()
+						switch {
+						case destroy:
+							if !diff.GetDestroy() && !replace {
+								return true, EvalEarlyExitError{}
+							}
+							diff = &InstanceDiff{Destroy: true}
+						case diff.GetDestroy() && !replace:
+							return true, EvalEarlyExitError{}
+						case replace:
+							// The new object of a replacement is
+							// created after the old one is destroyed.
+							diff = diff.DeepCopy()
+							diff.SetDestroy(false)
+							state = nil
+						}
+
+						return true, nil
+					},
+					Then: EvalNoop{},
+				},
+				&EvalValidateApply{
+					Info:     info,
+					State:    &state,
+					Diff:     &diff,
+					Provider: &provider,
+				},
+			},
+		},
+	}
+}
+
 func (n *NodeApplyableResource) evalTreeDataResource(
-	stateId string, info *InstanceInfo,
+	addr *ResourceAddress, stateId string, info *InstanceInfo,
 	resource *Resource, stateDeps []string) EvalNode {
 	var provider ResourceProvider
 	var config *ResourceConfig
@@ -124,6 +202,13 @@ func (n *NodeApplyableResource) evalTreeDataResource(
 				Then: EvalNoop{},
 			},
 
+			&EvalCheckConditions{
+				Addr:       addr.String(),
+				Type:       ConditionTypePrecondition,
+				Conditions: n.Config.Lifecycle.Preconditions,
+				Resource:   resource,
+			},
+
 			// We need to re-interpolate the config here, rather than
 			// just using the diff's values directly, because we've
 			// potentially learned more variable values during the
@@ -170,13 +255,20 @@ func (n *NodeApplyableResource) evalTreeDataResource(
 				Diff: nil,
 			},
 
+			&EvalCheckConditions{
+				Addr:       addr.String(),
+				Type:       ConditionTypePostcondition,
+				Conditions: n.Config.Lifecycle.Postconditions,
+				Resource:   resource,
+			},
+
 			&EvalUpdateStateHook{},
 		},
 	}
 }
 
 func (n *NodeApplyableResource) evalTreeManagedResource(
-	stateId string, info *InstanceInfo,
+	addr *ResourceAddress, stateId string, info *InstanceInfo,
 	resource *Resource, stateDeps []string) EvalNode {
 	// Declare a bunch of variables that are used for state during
 	// evaluation. Most of this are written to by-address below.
@@ -218,6 +310,13 @@ func (n *NodeApplyableResource) evalTreeManagedResource(
 				Then: EvalNoop{},
 			},
 
+			&EvalCheckConditions{
+				Addr:       addr.String(),
+				Type:       ConditionTypePrecondition,
+				Conditions: n.Config.Lifecycle.Preconditions,
+				Resource:   resource,
+			},
+
 			&EvalIf{
 				If: func(ctx EvalContext) (bool, error) {
 					destroy := false
@@ -304,6 +403,7 @@ func (n *NodeApplyableResource) evalTreeManagedResource(
 				Output:    &state,
 				Error:     &err,
 				CreateNew: &createNew,
+				Timeouts:  n.Config.Lifecycle.Timeouts,
 			},
 			&EvalWriteState{
 				Name:         stateId,
@@ -311,6 +411,7 @@ func (n *NodeApplyableResource) evalTreeManagedResource(
 				Provider:     n.ResolvedProvider,
 				Dependencies: stateDeps,
 				State:        &state,
+				Group:        n.stateGroup(),
 			},
 			&EvalApplyProvisioners{
 				Info:           info,
@@ -335,6 +436,7 @@ func (n *NodeApplyableResource) evalTreeManagedResource(
 					Provider:     n.ResolvedProvider,
 					Dependencies: stateDeps,
 					State:        &state,
+					Group:        n.stateGroup(),
 				},
 			},
 
@@ -352,6 +454,15 @@ func (n *NodeApplyableResource) evalTreeManagedResource(
 				Error: &err,
 			},
 			&EvalUpdateStateHook{},
+
+			// The new state is saved, so a failed postcondition is
+			// reported without losing track of the applied object.
+			&EvalCheckConditions{
+				Addr:       addr.String(),
+				Type:       ConditionTypePostcondition,
+				Conditions: n.Config.Lifecycle.Postconditions,
+				Resource:   resource,
+			},
 		},
 	}
 }
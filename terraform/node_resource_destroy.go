@@ -44,6 +44,15 @@ func (n *NodeDestroyResource) ModifyCreateBeforeDestroy(v bool) error {
 	return nil
 }
 
+// timeouts returns the lifecycle timeouts of the resource, which are unset
+// if it has been removed from the configuration.
+func (n *NodeDestroyResource) timeouts() config.ResourceTimeouts {
+	if n.Config == nil {
+		return config.ResourceTimeouts{}
+	}
+	return n.Config.Lifecycle.Timeouts
+}
+
 // GraphNodeReferenceable, overriding NodeAbstractResource
 func (n *NodeDestroyResource) ReferenceableName() []string {
 	// We modify our referenceable name to have the suffix of ".destroy"
@@ -73,8 +82,10 @@ func (n *NodeDestroyResource) References() []string {
 		var result []string
 		for _, p := range c.Provisioners {
 			// We include conn info and config for destroy time provisioners
-			// as dependencies that we have.
+			// as dependencies that we have, along with the dependencies
+			// they declare, so that those are destroyed only after them.
 			if p.When == config.ProvisionerWhenDestroy {
+				result = append(result, p.DependsOn...)
 				result = append(result, ReferencesFromConfig(p.ConnInfo)...)
 				result = append(result, ReferencesFromConfig(p.RawConfig)...)
 			}
@@ -158,7 +169,7 @@ func (n *NodeDestroyResource) EvalTree() EvalNode {
 	var provider ResourceProvider
 	var state *InstanceState
 	var err error
-	return &EvalOpFilter{
+	apply := &EvalOpFilter{
 		Ops: []walkOperation{walkApply, walkDestroy},
 		Node: &EvalSequence{
 			Nodes: []EvalNode{
@@ -270,6 +281,7 @@ func (n *NodeDestroyResource) EvalTree() EvalNode {
 						Provider: &provider,
 						Output:   &state,
 						Error:    &err,
+						Timeouts: n.timeouts(),
 					},
 				},
 				&EvalWriteState{
@@ -288,4 +300,17 @@ func (n *NodeDestroyResource) EvalTree() EvalNode {
 			},
 		},
 	}
+
+	// Data sources are only removed from the state, which a dry-run apply
+	// has nothing to check for.
+	if n.Addr.Mode == config.DataResourceMode {
+		return apply
+	}
+
+	return &EvalSequence{
+		Nodes: []EvalNode{
+			n.evalTreeValidateApply(stateId, info, true),
+			apply,
+		},
+	}
 }
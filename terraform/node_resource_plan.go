@@ -8,6 +8,10 @@ import (
 // it is ready to be planned in order to create a diff.
 type NodePlannableResource struct {
 	*NodeAbstractCountResource
+
+	// ForceReplace are the addresses of resources that the user requested
+	// to replace, which are passed on to the expanded instances.
+	ForceReplace []*ResourceAddress
 }
 
 // GraphNodeDynamicExpandable
@@ -17,6 +21,11 @@ func (n *NodePlannableResource) DynamicExpand(ctx EvalContext) (*Graph, error) {
 	lock.RLock()
 	defer lock.RUnlock()
 
+	// A deferred resource has no instances until a later plan
+	if ctx.Deferred().Deferred(ctx.Path(), n.Config.Id()) {
+		return nil, nil
+	}
+
 	// Expand the resource count which must be available by now from EvalTree
 	count, err := n.Config.Count()
 	if err != nil {
@@ -31,6 +40,7 @@ func (n *NodePlannableResource) DynamicExpand(ctx EvalContext) (*Graph, error) {
 
 		return &NodePlannableResourceInstance{
 			NodeAbstractResource: a,
+			ForceReplace:         n.ForceReplace,
 		}
 	}
 
@@ -11,6 +11,11 @@ import (
 // count index, for example.
 type NodePlannableResourceInstance struct {
 	*NodeAbstractResource
+
+	// ForceReplace are the addresses of resources that the user requested
+	// to replace. If any of them contains this instance's address then the
+	// instance is replaced even if it has no changes.
+	ForceReplace []*ResourceAddress
 }
 
 // GraphNodeEvalable
@@ -44,18 +49,18 @@ func (n *NodePlannableResourceInstance) EvalTree() EvalNode {
 	switch n.Config.Mode {
 	case config.ManagedResourceMode:
 		return n.evalTreeManagedResource(
-			stateId, info, resource, stateDeps,
+			addr, stateId, info, resource, stateDeps,
 		)
 	case config.DataResourceMode:
-		return n.evalTreeDataResource(
-			stateId, info, resource, stateDeps)
+		return n.checkDataEvalTree(addr, stateId, n.evalTreeDataResource(
+			addr, stateId, info, resource, stateDeps))
 	default:
 		panic(fmt.Errorf("unsupported resource mode %s", n.Config.Mode))
 	}
 }
 
 func (n *NodePlannableResourceInstance) evalTreeDataResource(
-	stateId string, info *InstanceInfo,
+	addr *ResourceAddress, stateId string, info *InstanceInfo,
 	resource *Resource, stateDeps []string) EvalNode {
 	var provider ResourceProvider
 	var config *ResourceConfig
@@ -79,6 +84,35 @@ func (n *NodePlannableResourceInstance) evalTreeDataResource(
 				Output:   &config,
 			},
 
+			&EvalCheckConditions{
+				Addr:       addr.String(),
+				Type:       ConditionTypePrecondition,
+				Conditions: n.Config.Lifecycle.Preconditions,
+				Resource:   resource,
+			},
+
+			// If the data source was already read during refresh, its
+			// postconditions can be checked now. Otherwise it is read
+			// during apply, and they're checked then.
+			&EvalIf{
+				If: func(ctx EvalContext) (bool, error) {
+					computed := config.ComputedKeys != nil && len(config.ComputedKeys) > 0
+					return !computed && state != nil, nil
+				},
+				Then: &EvalCheckConditions{
+					Addr:       addr.String(),
+					Type:       ConditionTypePostcondition,
+					Conditions: n.Config.Lifecycle.Postconditions,
+					Resource:   resource,
+				},
+				Else: &EvalCheckConditions{
+					Addr:       addr.String(),
+					Type:       ConditionTypePostcondition,
+					Conditions: n.Config.Lifecycle.Postconditions,
+					Defer:      true,
+				},
+			},
+
 			&EvalIf{
 				If: func(ctx EvalContext) (bool, error) {
 					computed := config.ComputedKeys != nil && len(config.ComputedKeys) > 0
@@ -126,7 +160,7 @@ func (n *NodePlannableResourceInstance) evalTreeDataResource(
 }
 
 func (n *NodePlannableResourceInstance) evalTreeManagedResource(
-	stateId string, info *InstanceInfo,
+	addr *ResourceAddress, stateId string, info *InstanceInfo,
 	resource *Resource, stateDeps []string) EvalNode {
 	// Declare a bunch of variables that are used for state during
 	// evaluation. Most of this are written to by-address below.
@@ -160,20 +194,48 @@ func (n *NodePlannableResourceInstance) evalTreeManagedResource(
 				Name:   stateId,
 				Output: &state,
 			},
+			&EvalCheckConditions{
+				Addr:       addr.String(),
+				Type:       ConditionTypePrecondition,
+				Conditions: n.Config.Lifecycle.Preconditions,
+				Resource:   resource,
+			},
 			&EvalDiff{
-				Name:        stateId,
-				Info:        info,
-				Config:      &resourceConfig,
-				Resource:    n.Config,
-				Provider:    &provider,
-				State:       &state,
-				OutputDiff:  &diff,
-				OutputState: &state,
+				Name:         stateId,
+				Info:         info,
+				Config:       &resourceConfig,
+				Resource:     n.Config,
+				Provider:     &provider,
+				State:        &state,
+				OutputDiff:   &diff,
+				OutputState:  &state,
+				ForceReplace: n.replaceRequested(),
 			},
 			&EvalCheckPreventDestroy{
 				Resource: n.Config,
 				Diff:     &diff,
 			},
+
+			// Postconditions can only be checked now if nothing will
+			// change, since they may refer to the resource's new values.
+			// Otherwise they're checked when the plan is applied.
+			&EvalIf{
+				If: func(ctx EvalContext) (bool, error) {
+					return diff.Empty(), nil
+				},
+				Then: &EvalCheckConditions{
+					Addr:       addr.String(),
+					Type:       ConditionTypePostcondition,
+					Conditions: n.Config.Lifecycle.Postconditions,
+					Resource:   resource,
+				},
+				Else: &EvalCheckConditions{
+					Addr:       addr.String(),
+					Type:       ConditionTypePostcondition,
+					Conditions: n.Config.Lifecycle.Postconditions,
+					Defer:      true,
+				},
+			},
 			&EvalWriteState{
 				Name:         stateId,
 				ResourceType: n.Config.Type,
@@ -188,3 +250,14 @@ func (n *NodePlannableResourceInstance) evalTreeManagedResource(
 		},
 	}
 }
+
+// replaceRequested returns true if the user requested that this instance be
+// replaced.
+func (n *NodePlannableResourceInstance) replaceRequested() bool {
+	for _, addr := range n.ForceReplace {
+		if addr.Contains(n.Addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -20,12 +20,26 @@ func (n *NodeRefreshableManagedResource) DynamicExpand(ctx EvalContext) (*Graph,
 	lock.RLock()
 	defer lock.RUnlock()
 
+	// A deferred resource has no instances until a later plan
+	if ctx.Deferred().Deferred(ctx.Path(), n.Config.Id()) {
+		return nil, nil
+	}
+
 	// Expand the resource count which must be available by now from EvalTree
 	count, err := n.Config.Count()
 	if err != nil {
 		return nil, err
 	}
 
+	// If the provider can refresh several instances in one call, the
+	// instances are refreshed in batches
+	batchSize := 0
+	if p := ctx.Provider(n.ResolvedProvider); p != nil {
+		if _, ok := p.(ResourceProviderBatchRefresher); ok && providerSupports(p, ProviderCapabilityBatchRefresh) {
+			batchSize = refreshBatchSize
+		}
+	}
+
 	// The concrete resource factory we'll use
 	concreteResource := func(a *NodeAbstractResource) dag.Vertex {
 		// Add the config and state since we don't do that via transforms
@@ -61,6 +75,9 @@ func (n *NodeRefreshableManagedResource) DynamicExpand(ctx EvalContext) (*Graph,
 		// Targeting
 		&TargetsTransformer{ParsedTargets: n.Targets},
 
+		// Refresh the instances in batches if the provider supports it
+		&RefreshBatchTransformer{Size: batchSize},
+
 		// Connect references so ordering is correct
 		&ReferenceTransformer{},
 
@@ -78,6 +95,11 @@ func (n *NodeRefreshableManagedResource) DynamicExpand(ctx EvalContext) (*Graph,
 	return b.Build(ctx.Path())
 }
 
+// refreshBatchSize is the largest number of instances of a resource that
+// are refreshed in one call of a provider that supports refreshing in
+// batches.
+const refreshBatchSize = 100
+
 // NodeRefreshableManagedResourceInstance represents a resource that is "applyable":
 // it is ready to be applied and is represented by a diff.
 type NodeRefreshableManagedResourceInstance struct {
@@ -169,6 +191,7 @@ func (n *NodeRefreshableManagedResourceInstance) evalTreeManagedResource() EvalN
 				Provider:     n.ResolvedProvider,
 				Dependencies: n.ResourceState.Dependencies,
 				State:        &state,
+				Group:        n.stateGroup(),
 			},
 		},
 	}
@@ -254,7 +277,67 @@ func (n *NodeRefreshableManagedResourceInstance) evalTreeManagedResourceNoState(
 				Provider:     n.ResolvedProvider,
 				Dependencies: stateDeps,
 				State:        &state,
+				Group:        n.stateGroup(),
 			},
 		},
 	}
 }
+
+// NodeRefreshableManagedResourceBatch represents a batch of instances of a
+// managed resource that have state, which are refreshed together, in one
+// call of a provider that supports refreshing in batches. It's created by
+// RefreshBatchTransformer.
+type NodeRefreshableManagedResourceBatch struct {
+	Instances []*NodeRefreshableManagedResourceInstance
+}
+
+func (n *NodeRefreshableManagedResourceBatch) Name() string {
+	return fmt.Sprintf("%s (batch of %d)", n.Instances[0].Name(), len(n.Instances))
+}
+
+// GraphNodeSubPath
+func (n *NodeRefreshableManagedResourceBatch) Path() []string {
+	return n.Instances[0].Path()
+}
+
+// GraphNodeEvalable
+func (n *NodeRefreshableManagedResourceBatch) EvalTree() EvalNode {
+	var provider ResourceProvider
+	infos := make([]*InstanceInfo, len(n.Instances))
+	states := make([]*InstanceState, len(n.Instances))
+
+	nodes := []EvalNode{
+		&EvalGetProvider{
+			Name:   n.Instances[0].ResolvedProvider,
+			Output: &provider,
+		},
+	}
+	for i, inst := range n.Instances {
+		stateId := inst.Addr.stateId()
+		infos[i] = &InstanceInfo{
+			Id:   stateId,
+			Type: inst.Addr.Type,
+		}
+		nodes = append(nodes, &EvalReadState{
+			Name:   stateId,
+			Output: &states[i],
+		})
+	}
+	nodes = append(nodes, &EvalRefreshBatch{
+		Provider: &provider,
+		Infos:    infos,
+		States:   states,
+	})
+	for i, inst := range n.Instances {
+		nodes = append(nodes, &EvalWriteState{
+			Name:         infos[i].Id,
+			ResourceType: inst.ResourceState.Type,
+			Provider:     inst.ResolvedProvider,
+			Dependencies: inst.ResourceState.Dependencies,
+			State:        &states[i],
+			Group:        inst.stateGroup(),
+		})
+	}
+
+	return &EvalSequence{Nodes: nodes}
+}
@@ -0,0 +1,62 @@
+package terraform
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/config"
+)
+
+// NodeVariableValidation represents the validation blocks of a variable,
+// which are checked during plan and apply once the variable and the other
+// values that they refer to are known.
+type NodeVariableValidation struct {
+	PathValue []string
+	Config    *config.Variable // Config is the variable in the config
+}
+
+func (n *NodeVariableValidation) Name() string {
+	return fmt.Sprintf("%s (validation)", variableAddr(n.PathValue, n.Config.Name))
+}
+
+// GraphNodeSubPath
+func (n *NodeVariableValidation) Path() []string {
+	return n.PathValue
+}
+
+// RemovableIfNotTargeted
+func (n *NodeVariableValidation) RemoveIfNotTargeted() bool {
+	return true
+}
+
+// GraphNodeReferencer
+func (n *NodeVariableValidation) References() []string {
+	var result []string
+	for _, c := range n.Config.Validations {
+		result = append(result, ReferencesFromConfig(c.RawConfig)...)
+	}
+
+	return result
+}
+
+// GraphNodeEvalable
+func (n *NodeVariableValidation) EvalTree() EvalNode {
+	return &EvalOpFilter{
+		Ops: []walkOperation{walkPlan, walkApply},
+		Node: &EvalCheckConditions{
+			Addr:       variableAddr(n.PathValue, n.Config.Name),
+			Type:       ConditionTypeValidation,
+			Conditions: n.Config.Validations,
+		},
+	}
+}
+
+// variableAddr returns the address of the variable with the given name in
+// the module with the given path.
+func variableAddr(path []string, name string) string {
+	result := fmt.Sprintf("var.%s", name)
+	if len(path) > 1 {
+		result = fmt.Sprintf("%s.%s", modulePrefixStr(path), result)
+	}
+
+	return result
+}
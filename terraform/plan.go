@@ -10,6 +10,7 @@ import (
 	"sync"
 
 	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/diffs"
 	"github.com/hashicorp/terraform/version"
 )
 
@@ -53,6 +54,17 @@ type Plan struct {
 	// indirectly targeted via dependencies is excluded from the graph.
 	Targets []string
 
+	// TargetModules, if non-empty, contains the addresses of the modules
+	// that the plan is scoped to. Like Targets, the graph is limited to the
+	// resources within them and their dependencies.
+	TargetModules []string
+
+	// ScopeDependents are the resources and module calls outside of
+	// TargetModules that depend on the scoped modules. The plan includes
+	// none of their changes, even though the changes within the scope may
+	// require some.
+	ScopeDependents []string
+
 	// TerraformVersion is the version of Terraform that was used to create
 	// this plan.
 	//
@@ -69,12 +81,63 @@ type Plan struct {
 	// different attributes or attribute value constraints.
 	ProviderSHA256s map[string][]byte
 
+	// Fingerprint describes the environment of the run that created this
+	// plan.
+	Fingerprint *Fingerprint
+
 	// Backend is the backend that this plan should use and store data with.
 	Backend *BackendState
 
 	// Destroy indicates that this plan was created for a full destroy operation
 	Destroy bool
 
+	// Imports are the imports requested by import blocks in the
+	// configuration that this plan includes. State already contains the
+	// imported objects, so they are added to the persisted state only when
+	// the plan is applied.
+	Imports []*ImportTarget
+
+	// Moves are the moves of resource instances to new addresses requested
+	// by moved blocks in the configuration. As with Imports, State already
+	// has the objects at their new addresses.
+	Moves []*diffs.Move
+
+	// Removed are the resource instances that removed blocks in the
+	// configuration remove from the state without destroying them. As with
+	// Imports, State already lacks them.
+	Removed []*RemovedObject
+
+	// RefreshOnly indicates that this plan was created for a refresh-only
+	// operation, and so its diff is always empty. Applying such a plan
+	// changes no infrastructure and instead replaces the current state with
+	// State, which was refreshed when the plan was created.
+	RefreshOnly bool
+
+	// Conditions are the results of checking the preconditions and
+	// postconditions in the configuration while planning. Conditions that
+	// can only be checked once the plan is applied have an unknown status.
+	Conditions []*ConditionResult
+
+	// Deferred are the resources whose changes couldn't be planned, because
+	// they depend on values that are only known after apply. Their changes
+	// aren't in Diff, and are planned by the next plan once the plan is
+	// applied.
+	Deferred []*DeferredChange
+
+	// UnavailableDestroyRefs are the destroy-time provisioners of a destroy
+	// plan that refer to values which destroying removes before they run.
+	UnavailableDestroyRefs []*DestroyProvisionerRefs
+
+	// Checks is the status of each check block in the configuration as of
+	// planning, keyed by the address of the check. The status is only
+	// recorded in the state when the plan is applied.
+	Checks map[string]*CheckState
+
+	// Annotations are the notes that plan analyzers attached to the changes
+	// of the plan after it was created, in the order the analyzers made
+	// them.
+	Annotations []*PlanAnnotation
+
 	once sync.Once
 }
 
@@ -101,8 +164,10 @@ func (p *Plan) contextOpts(base *ContextOpts) (*ContextOpts, error) {
 	opts := base
 
 	opts.Diff = p.Diff
+	opts.Deferred = p.Deferred
 	opts.Module = p.Module
 	opts.Targets = p.Targets
+	opts.TargetModules = p.TargetModules
 	opts.ProviderSHA256s = p.ProviderSHA256s
 	opts.Destroy = p.Destroy
 
@@ -0,0 +1,310 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/config"
+)
+
+// Split removes the changes of the resource instances matching the given
+// addresses from the plan, so that they aren't applied with it, and returns
+// a residual plan of the removed changes, which can be applied later, along
+// with the addresses of the instances whose changes were removed, in order.
+//
+// An address can also be that of a module, matching all of the instances
+// within it. Since a change can't be applied without the changes that it
+// depends on, the changes that depend on a removed change are removed too,
+// and a removed destroy also keeps what the destroyed object depends on
+// from being destroyed. Dependencies are found from the references in the
+// configuration, and from the dependencies recorded in the state for
+// objects that are no longer configured.
+//
+// The residual plan has no state, since it can only be applied to the
+// state that applying the split plan results in, which the caller must set
+// once it's known.
+func (p *Plan) Split(addrs []string) (*Plan, []string, error) {
+	var skip []*ResourceAddress
+	for _, s := range addrs {
+		addr, err := ParseResourceAddress(s)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid address %q: %s", s, err)
+		}
+		skip = append(skip, addr)
+	}
+
+	s := &planSplit{
+		plan:         p,
+		skipped:      make(map[*planSplitChange]struct{}),
+		dependents:   make(map[string]struct{}),
+		dependencies: make(map[string]struct{}),
+	}
+	if p.Diff != nil {
+		for _, md := range p.Diff.Modules {
+			for key, d := range md.Resources {
+				if d.Empty() {
+					continue
+				}
+				addr, err := ParseResourceAddressForInstanceDiff(md.Path[1:], key)
+				if err != nil {
+					return nil, nil, err
+				}
+				s.changes = append(s.changes, &planSplitChange{
+					Module: md,
+					Key:    key,
+					Addr:   addr,
+					Diff:   d,
+				})
+			}
+		}
+	}
+
+	for _, c := range s.changes {
+		for _, addr := range skip {
+			if addr.Contains(c.Addr) {
+				s.skip(c)
+				break
+			}
+		}
+	}
+
+	residual := &Plan{
+		Diff:             new(Diff),
+		Module:           p.Module,
+		Vars:             p.Vars,
+		Targets:          p.Targets,
+		TargetModules:    p.TargetModules,
+		ScopeDependents:  p.ScopeDependents,
+		TerraformVersion: p.TerraformVersion,
+		ProviderSHA256s:  p.ProviderSHA256s,
+		Fingerprint:      p.Fingerprint,
+		Backend:          p.Backend,
+		Destroy:          p.Destroy,
+	}
+
+	seen := make(map[string]struct{})
+	var skipped []string
+	for _, c := range s.changes {
+		if _, ok := s.skipped[c]; !ok {
+			continue
+		}
+
+		md := residual.Diff.ModuleByPath(c.Module.Path)
+		if md == nil {
+			md = residual.Diff.AddModule(c.Module.Path)
+			md.Destroy = c.Module.Destroy
+		}
+		md.Resources[c.Key] = c.Diff
+		delete(c.Module.Resources, c.Key)
+
+		addr := c.Addr.String()
+		if _, ok := seen[addr]; !ok {
+			seen[addr] = struct{}{}
+			skipped = append(skipped, addr)
+		}
+	}
+	sort.Strings(skipped)
+
+	// The annotations of the removed changes go with them.
+	var kept []*PlanAnnotation
+	for _, a := range p.Annotations {
+		if _, ok := seen[a.Addr]; ok {
+			residual.Annotations = append(residual.Annotations, a)
+			continue
+		}
+		kept = append(kept, a)
+	}
+	p.Annotations = kept
+
+	return residual, skipped, nil
+}
+
+// planSplit tracks the changes that Plan.Split removes from a plan.
+type planSplit struct {
+	plan    *Plan
+	changes []*planSplitChange
+	skipped map[*planSplitChange]struct{}
+
+	// dependents and dependencies are the objects whose dependents, and
+	// whose dependencies that would be destroyed, have been removed, by
+	// module prefix and key.
+	dependents   map[string]struct{}
+	dependencies map[string]struct{}
+}
+
+// planSplitChange is the change of a resource instance in a plan.
+type planSplitChange struct {
+	Module *ModuleDiff
+	Key    string
+	Addr   *ResourceAddress
+	Diff   *InstanceDiff
+}
+
+// ConfigKey returns the key of the changed resource in the configuration of
+// its module, as used by configDependents.
+func (c *planSplitChange) ConfigKey() string {
+	return addrConfigKey(c.Addr)
+}
+
+// Destroys returns true if the change destroys an object, including by
+// replacing it.
+func (c *planSplitChange) Destroys() bool {
+	d := c.Diff
+	return d.GetDestroy() || d.GetDestroyDeposed() || d.GetDestroyTainted()
+}
+
+// Creates returns true if the change creates or updates an object, which
+// the objects that refer to it may depend on.
+func (c *planSplitChange) Creates() bool {
+	switch c.Diff.ChangeType() {
+	case DiffCreate, DiffUpdate, DiffDestroyCreate:
+		return true
+	}
+	return false
+}
+
+func (s *planSplit) skip(c *planSplitChange) {
+	if _, ok := s.skipped[c]; ok {
+		return
+	}
+	s.skipped[c] = struct{}{}
+
+	path := c.Module.Path[1:]
+	if c.Creates() {
+		s.skipDependents(path, c.ConfigKey())
+	}
+	if c.Destroys() {
+		s.skipDependencies(path, c.ConfigKey())
+	}
+}
+
+// skipDependents removes the changes of the objects that depend on the
+// object with the given key in the module with the given path.
+func (s *planSplit) skipDependents(path []string, key string) {
+	id := modulePrefixStr(path) + "\x00" + key
+	if _, ok := s.dependents[id]; ok {
+		return
+	}
+	s.dependents[id] = struct{}{}
+
+	for _, dep := range configDependents(s.config(path), key) {
+		switch {
+		case strings.HasPrefix(dep, "output."):
+			// The objects that refer to the module's outputs depend on it
+			// too.
+			if len(path) > 0 {
+				s.skipDependents(path[:len(path)-1], "module."+path[len(path)-1])
+			}
+		case strings.HasPrefix(dep, "local."), strings.HasPrefix(dep, "ephemeral."):
+		case strings.HasPrefix(dep, "module."):
+			s.skipModule(path, dep, false)
+		default:
+			s.skipResource(path, dep, false)
+		}
+	}
+}
+
+// skipDependencies removes the destroys of the objects that the object with
+// the given key in the module with the given path depends on.
+func (s *planSplit) skipDependencies(path []string, key string) {
+	id := modulePrefixStr(path) + "\x00" + key
+	if _, ok := s.dependencies[id]; ok {
+		return
+	}
+	s.dependencies[id] = struct{}{}
+
+	deps := configDependencies(s.config(path), key)
+	if ms := s.plan.State.ModuleByPath(normalizeModulePath(path)); ms != nil {
+		for k, rs := range ms.Resources {
+			if rs == nil || stateConfigKey(k) != key {
+				continue
+			}
+			for _, ref := range rs.Dependencies {
+				if to := configRefKey(ref); to != "" {
+					deps = append(deps, to)
+				}
+			}
+		}
+	}
+
+	for _, dep := range deps {
+		switch {
+		case strings.HasPrefix(dep, "output."), strings.HasPrefix(dep, "local."), strings.HasPrefix(dep, "ephemeral."):
+		case strings.HasPrefix(dep, "module."):
+			s.skipModule(path, dep, true)
+		default:
+			s.skipResource(path, dep, true)
+		}
+	}
+
+	// An object of a child module may depend on the objects that its
+	// module call refers to.
+	if len(path) > 0 {
+		s.skipDependencies(path[:len(path)-1], "module."+path[len(path)-1])
+	}
+}
+
+// skipResource removes the changes of the instances of the resource with
+// the given key in the module with the given path, or only their destroys
+// if destroys is true.
+func (s *planSplit) skipResource(path []string, key string, destroys bool) {
+	prefix := modulePrefixStr(path)
+	for _, c := range s.changes {
+		if destroys && !c.Destroys() {
+			continue
+		}
+		if modulePrefixStr(c.Module.Path) == prefix && c.ConfigKey() == key {
+			s.skip(c)
+		}
+	}
+}
+
+// skipModule removes the changes within the module called by the module
+// call with the given key, such as "module.foo", in the module with the
+// given path, or only the destroys within it if destroys is true.
+func (s *planSplit) skipModule(path []string, key string, destroys bool) {
+	child := append(append([]string{}, path...), strings.TrimPrefix(key, "module."))
+	addr := &ResourceAddress{Path: child, Index: -1}
+	for _, c := range s.changes {
+		if destroys && !c.Destroys() {
+			continue
+		}
+		if addr.Contains(c.Addr) {
+			s.skip(c)
+		}
+	}
+}
+
+// config returns the configuration of the module with the given path, or
+// nil if it isn't configured.
+func (s *planSplit) config(path []string) *config.Config {
+	if s.plan.Module == nil {
+		return nil
+	}
+	child := s.plan.Module.Child(path)
+	if child == nil {
+		return nil
+	}
+	return child.Config()
+}
+
+// stateConfigKey returns the key of the resource with the given key in the
+// state, such as "aws_instance.foo.0", in the configuration of its module.
+func stateConfigKey(k string) string {
+	addr, err := parseResourceAddressInternal(k)
+	if err != nil {
+		return ""
+	}
+	return addrConfigKey(addr)
+}
+
+// addrConfigKey returns the key of the resource with the given address in
+// the configuration of its module.
+func addrConfigKey(addr *ResourceAddress) string {
+	key := addr.Type + "." + addr.Name
+	if addr.Mode == config.DataResourceMode {
+		key = "data." + key
+	}
+	return key
+}
@@ -0,0 +1,215 @@
+package terraform
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestPlanSplit(t *testing.T) {
+	create := func() *InstanceDiff {
+		return &InstanceDiff{
+			Attributes: map[string]*ResourceAttrDiff{
+				"id": {NewComputed: true, RequiresNew: true},
+			},
+		}
+	}
+	destroy := func() *InstanceDiff {
+		return &InstanceDiff{Destroy: true}
+	}
+
+	cases := map[string]struct {
+		Root  map[string]*InstanceDiff
+		Child map[string]*InstanceDiff
+		State *State
+		Skip  []string
+
+		Skipped []string
+		Applied []string
+	}{
+		"dependents": {
+			Root: map[string]*InstanceDiff{
+				"aws_instance.a": create(),
+				"aws_instance.b": create(),
+				"aws_instance.c": create(),
+			},
+			Skip:    []string{"aws_instance.a"},
+			Skipped: []string{"aws_instance.a", "aws_instance.b"},
+			Applied: []string{"aws_instance.c"},
+		},
+		"module output": {
+			Root: map[string]*InstanceDiff{
+				"aws_instance.c": create(),
+				"aws_instance.d": create(),
+			},
+			Child: map[string]*InstanceDiff{
+				"aws_instance.e": create(),
+			},
+			Skip:    []string{"module.child.aws_instance.e"},
+			Skipped: []string{"aws_instance.d", "module.child.aws_instance.e"},
+			Applied: []string{"aws_instance.c"},
+		},
+		"module input": {
+			Root: map[string]*InstanceDiff{
+				"aws_instance.a": create(),
+				"aws_instance.c": create(),
+				"aws_instance.d": create(),
+			},
+			Child: map[string]*InstanceDiff{
+				"aws_instance.e": create(),
+			},
+			Skip:    []string{"aws_instance.c"},
+			Skipped: []string{"aws_instance.c", "aws_instance.d", "module.child.aws_instance.e"},
+			Applied: []string{"aws_instance.a"},
+		},
+		"module": {
+			Root: map[string]*InstanceDiff{
+				"aws_instance.a": create(),
+				"aws_instance.d": create(),
+			},
+			Child: map[string]*InstanceDiff{
+				"aws_instance.e": create(),
+			},
+			Skip:    []string{"module.child"},
+			Skipped: []string{"aws_instance.d", "module.child.aws_instance.e"},
+			Applied: []string{"aws_instance.a"},
+		},
+		"destroy dependencies": {
+			Root: map[string]*InstanceDiff{
+				"aws_instance.a": destroy(),
+				"aws_instance.b": destroy(),
+				"aws_instance.c": destroy(),
+			},
+			Skip:    []string{"aws_instance.b"},
+			Skipped: []string{"aws_instance.a", "aws_instance.b"},
+			Applied: []string{"aws_instance.c"},
+		},
+		"destroy dependents": {
+			Root: map[string]*InstanceDiff{
+				"aws_instance.a": destroy(),
+				"aws_instance.b": destroy(),
+			},
+			Skip:    []string{"aws_instance.a"},
+			Skipped: []string{"aws_instance.a"},
+			Applied: []string{"aws_instance.b"},
+		},
+		"destroy dependencies of module": {
+			Root: map[string]*InstanceDiff{
+				"aws_instance.a": destroy(),
+				"aws_instance.c": destroy(),
+			},
+			Child: map[string]*InstanceDiff{
+				"aws_instance.e": destroy(),
+			},
+			Skip:    []string{"module.child.aws_instance.e"},
+			Skipped: []string{"aws_instance.c", "module.child.aws_instance.e"},
+			Applied: []string{"aws_instance.a"},
+		},
+		"destroy dependencies in state": {
+			Root: map[string]*InstanceDiff{
+				"aws_instance.a":    destroy(),
+				"aws_instance.c":    destroy(),
+				"aws_instance.gone": destroy(),
+			},
+			State: &State{
+				Modules: []*ModuleState{
+					{
+						Path: rootModulePath,
+						Resources: map[string]*ResourceState{
+							"aws_instance.gone": {
+								Type:         "aws_instance",
+								Dependencies: []string{"aws_instance.c"},
+								Primary:      &InstanceState{ID: "gone"},
+							},
+						},
+					},
+				},
+			},
+			Skip:    []string{"aws_instance.gone"},
+			Skipped: []string{"aws_instance.c", "aws_instance.gone"},
+			Applied: []string{"aws_instance.a"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			diff := new(Diff)
+			root := diff.AddModule(rootModulePath)
+			root.Resources = tc.Root
+			if tc.Child != nil {
+				child := diff.AddModule([]string{"root", "child"})
+				child.Resources = tc.Child
+			}
+			plan := &Plan{
+				Diff:   diff,
+				Module: testModule(t, "plan-split"),
+				State:  tc.State,
+			}
+
+			residual, skipped, err := plan.Split(tc.Skip)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if !reflect.DeepEqual(skipped, tc.Skipped) {
+				t.Fatalf("wrong skipped changes\ngot:  %#v\nwant: %#v", skipped, tc.Skipped)
+			}
+			if got := testPlanSplitAddrs(t, residual.Diff); !reflect.DeepEqual(got, tc.Skipped) {
+				t.Fatalf("wrong residual changes\ngot:  %#v\nwant: %#v", got, tc.Skipped)
+			}
+			if got := testPlanSplitAddrs(t, plan.Diff); !reflect.DeepEqual(got, tc.Applied) {
+				t.Fatalf("wrong remaining changes\ngot:  %#v\nwant: %#v", got, tc.Applied)
+			}
+		})
+	}
+}
+
+func TestPlanSplit_annotations(t *testing.T) {
+	diff := new(Diff)
+	root := diff.AddModule(rootModulePath)
+	root.Resources = map[string]*InstanceDiff{
+		"aws_instance.a": {Destroy: true},
+		"aws_instance.c": {Destroy: true},
+	}
+	a := &PlanAnnotation{Addr: "aws_instance.a", Analyzer: "cost", Summary: "-$10/month"}
+	c := &PlanAnnotation{Addr: "aws_instance.c", Analyzer: "cost", Summary: "-$20/month"}
+	plan := &Plan{
+		Diff:        diff,
+		Module:      testModule(t, "plan-split"),
+		Annotations: []*PlanAnnotation{a, c},
+	}
+
+	residual, _, err := plan.Split([]string{"aws_instance.c"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if got, want := residual.Annotations, []*PlanAnnotation{c}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong residual annotations\ngot:  %#v\nwant: %#v", got, want)
+	}
+	if got, want := plan.Annotations, []*PlanAnnotation{a}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong remaining annotations\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestPlanSplit_invalid(t *testing.T) {
+	plan := &Plan{Diff: new(Diff)}
+	if _, _, err := plan.Split([]string{"not an address"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// testPlanSplitAddrs returns the addresses of the changes of the given
+// diff, in order.
+func testPlanSplitAddrs(t *testing.T, d *Diff) []string {
+	var ret []string
+	for _, md := range d.Modules {
+		for key := range md.Resources {
+			addr, err := ParseResourceAddressForInstanceDiff(md.Path[1:], key)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			ret = append(ret, addr.String())
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
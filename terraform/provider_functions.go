@@ -0,0 +1,185 @@
+package terraform
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/hashicorp/hil"
+	"github.com/hashicorp/hil/ast"
+	"github.com/hashicorp/terraform/config"
+)
+
+// ProviderFunctions supplies the functions exported by providers to the
+// interpolations of a graph walk, such as provider::aws::arn_parse. Each
+// provider whose functions are called is started once per walk, apart
+// from the provider instances that manage resources, and is never
+// configured. It is safe for concurrent use.
+type ProviderFunctions struct {
+	Components contextComponentFactory
+
+	lock      sync.Mutex
+	providers map[string]ResourceProvider
+	functions map[string][]ProviderFunction
+}
+
+// Functions returns the interpolation functions for the given provider
+// function names, keyed by those names.
+func (f *ProviderFunctions) Functions(names []string) (map[string]ast.Function, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	result := make(map[string]ast.Function, len(names))
+	for _, name := range names {
+		fn, err := f.function(name)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = fn
+	}
+
+	return result, nil
+}
+
+func (f *ProviderFunctions) function(name string) (ast.Function, error) {
+	providerName, funcName, err := config.ParseProviderFunctionName(name)
+	if err != nil {
+		return ast.Function{}, err
+	}
+
+	p, defs, err := f.provider(providerName)
+	if err != nil {
+		return ast.Function{}, fmt.Errorf("%s: %s", name, err)
+	}
+
+	var def *ProviderFunction
+	for i := range defs {
+		if defs[i].Name == funcName {
+			def = &defs[i]
+			break
+		}
+	}
+	if def == nil {
+		if !providerSupports(p, ProviderCapabilityFunctions) {
+			return ast.Function{}, fmt.Errorf(
+				"%s: %s", name, errUnsupportedCapability(providerName, ProviderCapabilityFunctions))
+		}
+		return ast.Function{}, fmt.Errorf(
+			"%s: provider %q has no function named %q", name, providerName, funcName)
+	}
+
+	argTypes := make([]ast.Type, len(def.Parameters))
+	for i, param := range def.Parameters {
+		argTypes[i] = param.Type
+	}
+	fn := ast.Function{
+		ArgTypes:   argTypes,
+		ReturnType: def.ReturnType,
+		Callback: func(args []interface{}) (interface{}, error) {
+			values := make([]interface{}, len(args))
+			for i, arg := range args {
+				t := def.VariadicParameter
+				if i < len(def.Parameters) {
+					t = &def.Parameters[i]
+				}
+
+				v, err := functionValue(t.Type, arg)
+				if err != nil {
+					return nil, fmt.Errorf("%s: argument %s: %s", name, t.Name, err)
+				}
+				values[i] = v
+			}
+
+			result, err := p.CallFunction(funcName, values)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", name, err)
+			}
+
+			return functionResult(def.ReturnType, result)
+		},
+	}
+	if def.VariadicParameter != nil {
+		fn.Variadic = true
+		fn.VariadicType = def.VariadicParameter.Type
+	}
+
+	return fn, nil
+}
+
+// provider returns the provider with the given name, starting it if it
+// hasn't been already in this walk, along with its functions.
+func (f *ProviderFunctions) provider(name string) (ResourceProvider, []ProviderFunction, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if p, ok := f.providers[name]; ok {
+		return p, f.functions[name], nil
+	}
+
+	if f.Components == nil {
+		return nil, nil, fmt.Errorf("provider functions can't be called here")
+	}
+
+	log.Printf("[TRACE] starting provider %q for its functions", name)
+	p, err := f.Components.ResourceProvider(name, fmt.Sprintf("provider.%s (functions)", name))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if f.providers == nil {
+		f.providers = make(map[string]ResourceProvider)
+		f.functions = make(map[string][]ProviderFunction)
+	}
+	f.providers[name] = p
+	f.functions[name] = p.Functions()
+	return p, f.functions[name], nil
+}
+
+// Close closes the providers that were started for their functions.
+func (f *ProviderFunctions) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var err error
+	for name, p := range f.providers {
+		if c, ok := p.(ResourceProviderCloser); ok {
+			if cerr := c.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+		delete(f.providers, name)
+		delete(f.functions, name)
+	}
+
+	return err
+}
+
+// functionValue converts an argument of an interpolation function call to
+// the value given to a provider's function.
+func functionValue(t ast.Type, v interface{}) (interface{}, error) {
+	switch t {
+	case ast.TypeList, ast.TypeMap:
+		return hil.VariableToInterface(ast.Variable{Type: t, Value: v})
+	default:
+		return v, nil
+	}
+}
+
+// functionResult converts the result of a provider's function to the
+// result of an interpolation function call.
+func functionResult(t ast.Type, v interface{}) (interface{}, error) {
+	switch t {
+	case ast.TypeList, ast.TypeMap:
+		result, err := hil.InterfaceToVariable(v)
+		if err != nil {
+			return nil, err
+		}
+		if result.Type != t {
+			return nil, fmt.Errorf("function returned %s, expected %s", result.Type, t)
+		}
+		return result.Value, nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,145 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/module"
+)
+
+// DestroyProvisionerRefs is a destroy-time provisioner of a resource being
+// destroyed whose configuration refers to values that destroying removes
+// before it runs, so that they'll be unknown to the provisioner.
+type DestroyProvisionerRefs struct {
+	// Resource is the address of the resource, without an index.
+	Resource string
+
+	// Provisioner is the type of the provisioner, and Index its position
+	// among the provisioners of the resource.
+	Provisioner string
+	Index       int
+
+	// Refs are the references that will be unavailable, such as
+	// "local.name" or "module.network.vpc_id".
+	Refs []string
+}
+
+// unavailableDestroyRefs returns the destroy-time provisioners of the
+// resources that the given diff of a destroy plan destroys which refer to
+// local values, module outputs or data sources. Destroying removes these
+// from the state as it goes, and unlike resources nothing orders their
+// removal after the provisioners that refer to them.
+func unavailableDestroyRefs(root *module.Tree, diff *Diff) []*DestroyProvisionerRefs {
+	if root == nil || diff == nil {
+		return nil
+	}
+
+	seen := make(map[string]struct{})
+	var ret []*DestroyProvisionerRefs
+	for _, md := range diff.Modules {
+		path := normalizeModulePath(md.Path)[1:]
+		tree := root.Child(path)
+		if tree == nil {
+			continue
+		}
+
+		for k, rd := range md.Resources {
+			if rd == nil || !rd.GetDestroy() {
+				continue
+			}
+			key, err := ParseResourceStateKey(k)
+			if err != nil || key.Mode != config.ManagedResourceMode {
+				continue
+			}
+			rc := resourceConfig(tree.Config(), key)
+			if rc == nil {
+				continue
+			}
+
+			addr := &ResourceAddress{
+				Path:  path,
+				Mode:  key.Mode,
+				Type:  key.Type,
+				Name:  key.Name,
+				Index: -1,
+			}
+			for i, p := range rc.Provisioners {
+				if p.When != config.ProvisionerWhenDestroy {
+					continue
+				}
+				id := fmt.Sprintf("%s/%d", addr, i)
+				if _, ok := seen[id]; ok {
+					continue
+				}
+				seen[id] = struct{}{}
+
+				refs := removedByDestroy(p.RawConfig, p.ConnInfo)
+				if len(refs) == 0 {
+					continue
+				}
+				ret = append(ret, &DestroyProvisionerRefs{
+					Resource:    addr.String(),
+					Provisioner: p.Type,
+					Index:       i,
+					Refs:        refs,
+				})
+			}
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Resource != ret[j].Resource {
+			return ret[i].Resource < ret[j].Resource
+		}
+		return ret[i].Index < ret[j].Index
+	})
+	return ret
+}
+
+// resourceConfig returns the configuration of the resource with the given
+// key in the given module configuration, or nil if it isn't declared.
+func resourceConfig(cfg *config.Config, key *ResourceStateKey) *config.Resource {
+	if cfg == nil {
+		return nil
+	}
+	for _, r := range cfg.Resources {
+		if r.Mode == key.Mode && r.Type == key.Type && r.Name == key.Name {
+			return r
+		}
+	}
+	return nil
+}
+
+// removedByDestroy returns the sorted references of the given
+// configurations to values that destroying removes: local values, module
+// outputs and data sources.
+func removedByDestroy(raw ...*config.RawConfig) []string {
+	seen := make(map[string]struct{})
+	var ret []string
+	for _, rc := range raw {
+		if rc == nil {
+			continue
+		}
+		for _, v := range rc.Variables {
+			switch v := v.(type) {
+			case *config.LocalVariable, *config.ModuleVariable:
+			case *config.ResourceVariable:
+				if v.Mode != config.DataResourceMode {
+					continue
+				}
+			default:
+				continue
+			}
+
+			ref := v.FullKey()
+			if _, ok := seen[ref]; ok {
+				continue
+			}
+			seen[ref] = struct{}{}
+			ret = append(ret, ref)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
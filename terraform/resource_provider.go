@@ -1,9 +1,11 @@
 package terraform
 
 import (
+	"context"
 	"fmt"
 
 	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hil/ast"
 	"github.com/hashicorp/terraform/plugin/discovery"
 )
 
@@ -109,6 +111,18 @@ type ResourceProvider interface {
 		*InstanceState,
 		*InstanceDiff) (*InstanceState, error)
 
+	// ValidateApply checks that the given diff could be applied to the
+	// resource, without applying it or changing anything else. It is
+	// called for every change of a dry-run apply, to find errors that
+	// planning can't see, such as exceeded quotas or missing permissions.
+	//
+	// The state is nil if the resource is being created. Providers that
+	// can't check a change return no errors for it.
+	ValidateApply(
+		*InstanceInfo,
+		*InstanceState,
+		*InstanceDiff) ([]string, []error)
+
 	// Diff diffs a resource versus a desired state and returns
 	// a diff.
 	Diff(
@@ -168,6 +182,43 @@ type ResourceProvider interface {
 	// ReadDataApply initializes a data instance using the configuration
 	// in a diff produced by ReadDataDiff.
 	ReadDataApply(*InstanceInfo, *InstanceDiff) (*InstanceState, error)
+
+	/*********************************************************************
+	* Functions related to ephemeral resources
+	*********************************************************************/
+
+	// OpenEphemeral opens an ephemeral resource with the given fully
+	// interpolated configuration, such as by issuing a short-lived
+	// credential, and returns its attributes.
+	//
+	// Ephemeral resources are opened again for each plan and apply, and
+	// the returned state is never persisted, nor compared with earlier
+	// results.
+	OpenEphemeral(*InstanceInfo, *ResourceConfig) (*InstanceState, error)
+
+	// CloseEphemeral closes an ephemeral resource that was opened by
+	// OpenEphemeral, such as by revoking the credential, once nothing
+	// else in the run needs it.
+	CloseEphemeral(*InstanceInfo, *InstanceState) error
+
+	/*********************************************************************
+	* Functions related to provider-defined functions
+	*********************************************************************/
+
+	// Functions returns all of the functions that this provider exports
+	// for use in configuration expressions, which call them as
+	// provider::NAME::FUNCTION(...).
+	Functions() []ProviderFunction
+
+	// CallFunction calls the function with the given name (without any
+	// prefix) with the given arguments, which have already been checked
+	// against the function's parameters. Lists are given as []interface{}
+	// and maps as map[string]interface{}.
+	//
+	// Functions are called during plan as well as apply, so they must not
+	// have side effects, and should return the same result for the same
+	// arguments.
+	CallFunction(string, []interface{}) (interface{}, error)
 }
 
 // ResourceProviderError may be returned when creating a Context if the
@@ -188,6 +239,68 @@ type ResourceProviderCloser interface {
 	Close() error
 }
 
+// ResourceProviderContextApplier is an interface that providers that can
+// cancel an Apply in progress must implement. When the given context is
+// cancelled, the provider should stop applying the diff as soon as it
+// safely can, without affecting any of its other operations, and return
+// the partial state of the instance along with an error.
+type ResourceProviderContextApplier interface {
+	ApplyContext(context.Context, *InstanceInfo, *InstanceState, *InstanceDiff) (*InstanceState, error)
+}
+
+// ApplyWithContext calls the ApplyContext method of the given provider with
+// the given context if it implements ResourceProviderContextApplier, and
+// its Apply method otherwise.
+func ApplyWithContext(
+	ctx context.Context,
+	p ResourceProvider,
+	info *InstanceInfo,
+	s *InstanceState,
+	d *InstanceDiff) (*InstanceState, error) {
+	if ca, ok := p.(ResourceProviderContextApplier); ok {
+		return ca.ApplyContext(ctx, info, s, d)
+	}
+	return p.Apply(info, s, d)
+}
+
+// ResourceProviderStateMover is an interface that providers that can
+// convert the state of an object of another resource type, which may belong
+// to another provider, into the state of one of their own resource types
+// must implement, so that moved blocks can move objects between resource
+// types without destroying them. The provider isn't configured when it's
+// called.
+type ResourceProviderStateMover interface {
+	MoveResourceState(*MoveResourceStateRequest) (*InstanceState, error)
+}
+
+// ResourceProviderBatchRefresher is an interface that providers that can
+// refresh several instances in one call must implement, so that refreshing
+// a resource with many instances takes fewer calls. The returned states
+// are those of the given instances, in the same order, and an error fails
+// the refresh of all of them.
+type ResourceProviderBatchRefresher interface {
+	RefreshBatch([]*InstanceInfo, []*InstanceState) ([]*InstanceState, error)
+}
+
+// MoveResourceStateRequest is a request to move the state of an object of
+// one resource type to another.
+type MoveResourceStateRequest struct {
+	// SourceProvider is the name of the provider of the resource type that
+	// the object is moved from, such as "aws", and SourceType is its
+	// resource type, such as "aws_instance".
+	SourceProvider string
+	SourceType     string
+
+	// SourceState is the state of the object, which was written by the
+	// source provider, so its Meta includes the schema version of the
+	// source resource type.
+	SourceState *InstanceState
+
+	// TargetType is the resource type that the object is moved to, which
+	// belongs to the provider that the request is made of.
+	TargetType string
+}
+
 // ResourceType is a type of resource that a resource provider can manage.
 type ResourceType struct {
 	Name       string // Name of the resource, example "instance" (no provider prefix)
@@ -213,6 +326,27 @@ type DataSource struct {
 	SchemaAvailable bool
 }
 
+// ProviderFunction is a function that a resource provider exports for use
+// in configuration expressions.
+type ProviderFunction struct {
+	Name        string // Name of the function, example "arn_parse" (no prefix)
+	Description string
+
+	// Parameters are the parameters of the function in order, and
+	// VariadicParameter, if set, describes any arguments after those.
+	Parameters        []FunctionParameter
+	VariadicParameter *FunctionParameter
+
+	ReturnType ast.Type
+}
+
+// FunctionParameter is a parameter of a ProviderFunction.
+type FunctionParameter struct {
+	Name        string
+	Description string
+	Type        ast.Type
+}
+
 // ResourceProviderResolver is an interface implemented by objects that are
 // able to resolve a given set of resource provider version constraints
 // into ResourceProviderFactory callbacks.
@@ -0,0 +1,269 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// ProviderCapability is an optional feature of the provider protocol, which
+// providers built before it was added don't support.
+type ProviderCapability string
+
+const (
+	// ProviderCapabilityBatchRefresh is support for refreshing several
+	// instances in one call, as by ResourceProviderBatchRefresher.
+	ProviderCapabilityBatchRefresh ProviderCapability = "batch_refresh"
+
+	// ProviderCapabilityEphemeralResources is support for OpenEphemeral and
+	// CloseEphemeral.
+	ProviderCapabilityEphemeralResources ProviderCapability = "ephemeral_resources"
+
+	// ProviderCapabilityFunctions is support for Functions and
+	// CallFunction.
+	ProviderCapabilityFunctions ProviderCapability = "functions"
+
+	// ProviderCapabilityCancelApply is support for cancelling an apply in
+	// progress, as by ResourceProviderContextApplier.
+	ProviderCapabilityCancelApply ProviderCapability = "cancel_apply"
+
+	// ProviderCapabilityMoveState is support for moving the state of
+	// objects of other resource types to the provider's own, as by
+	// ResourceProviderStateMover.
+	ProviderCapabilityMoveState ProviderCapability = "move_state"
+)
+
+// AllProviderCapabilities are the capabilities known to this version of
+// Terraform, sorted.
+var AllProviderCapabilities = []ProviderCapability{
+	ProviderCapabilityBatchRefresh,
+	ProviderCapabilityCancelApply,
+	ProviderCapabilityEphemeralResources,
+	ProviderCapabilityFunctions,
+	ProviderCapabilityMoveState,
+}
+
+// ResourceProviderCapabilities is an interface that providers that declare
+// which optional features of the protocol they support must implement. A
+// provider that doesn't implement it is taken to support all of them,
+// except for cancelling applies if it doesn't implement
+// ResourceProviderContextApplier, moving state if it doesn't implement
+// ResourceProviderStateMover and refreshing in batches if it doesn't
+// implement ResourceProviderBatchRefresher, since it's built with this
+// version of Terraform.
+//
+// Providers that run as plugins always implement it, and report no
+// capabilities if the plugin was built before capabilities could be
+// negotiated. Capabilities that the provider doesn't know are ignored, and
+// so are capabilities that aren't known here.
+type ResourceProviderCapabilities interface {
+	Capabilities() ([]ProviderCapability, error)
+}
+
+// ProviderCapabilitiesOf returns the capabilities that the given provider
+// supports, sorted, as described by ResourceProviderCapabilities.
+func ProviderCapabilitiesOf(p ResourceProvider) ([]ProviderCapability, error) {
+	if c, ok := p.(ResourceProviderCapabilities); ok {
+		return c.Capabilities()
+	}
+
+	result := make([]ProviderCapability, 0, len(AllProviderCapabilities))
+	for _, c := range AllProviderCapabilities {
+		if _, ok := p.(ResourceProviderContextApplier); !ok && c == ProviderCapabilityCancelApply {
+			continue
+		}
+		if _, ok := p.(ResourceProviderStateMover); !ok && c == ProviderCapabilityMoveState {
+			continue
+		}
+		if _, ok := p.(ResourceProviderBatchRefresher); !ok && c == ProviderCapabilityBatchRefresh {
+			continue
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+// errUnsupportedCapability returns the error of a call of an operation of
+// the provider with the given name that needs the given capability.
+func errUnsupportedCapability(name string, c ProviderCapability) error {
+	var feature string
+	switch c {
+	case ProviderCapabilityEphemeralResources:
+		feature = "ephemeral resources"
+	case ProviderCapabilityFunctions:
+		feature = "provider functions"
+	case ProviderCapabilityMoveState:
+		feature = "moving state from other resource types"
+	default:
+		feature = string(c)
+	}
+	return fmt.Errorf(
+		"provider %q doesn't support %s, since it was built for an older version of "+
+			"Terraform; a newer version of the provider is required", name, feature)
+}
+
+// capabilityResourceProvider is a ResourceProvider that offers only the
+// capabilities that another provider supports, as negotiated when it was
+// started, so that the operations that it doesn't support fail with a clear
+// error, or fall back to operations that it does support, instead of
+// failing in the plugin protocol.
+type capabilityResourceProvider struct {
+	ResourceProvider
+
+	Name      string
+	supported map[ProviderCapability]bool
+}
+
+// newCapabilityResourceProvider negotiates the capabilities of the given
+// provider. If they can't be read, the provider is taken to support none.
+func newCapabilityResourceProvider(name string, p ResourceProvider) *capabilityResourceProvider {
+	caps, err := ProviderCapabilitiesOf(p)
+	if err != nil {
+		log.Printf("[WARN] provider %q: error reading capabilities, assuming none: %s", name, err)
+		caps = nil
+	}
+
+	supported := make(map[ProviderCapability]bool, len(caps))
+	var names []string
+	for _, c := range caps {
+		supported[c] = true
+		names = append(names, string(c))
+	}
+	sort.Strings(names)
+	log.Printf("[DEBUG] provider %q: capabilities: %s", name, strings.Join(names, ", "))
+
+	return &capabilityResourceProvider{
+		ResourceProvider: p,
+		Name:             name,
+		supported:        supported,
+	}
+}
+
+// Capabilities returns the negotiated capabilities, sorted.
+func (p *capabilityResourceProvider) Capabilities() ([]ProviderCapability, error) {
+	var result []ProviderCapability
+	for _, c := range AllProviderCapabilities {
+		if p.supported[c] {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+func (p *capabilityResourceProvider) Close() error {
+	if c, ok := p.ResourceProvider.(ResourceProviderCloser); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// ApplyContext only passes the given context to the underlying provider if
+// it supports cancelling applies.
+func (p *capabilityResourceProvider) ApplyContext(
+	ctx context.Context,
+	info *InstanceInfo,
+	s *InstanceState,
+	d *InstanceDiff) (*InstanceState, error) {
+	if !p.supported[ProviderCapabilityCancelApply] {
+		return p.ResourceProvider.Apply(info, s, d)
+	}
+	return ApplyWithContext(ctx, p.ResourceProvider, info, s, d)
+}
+
+func (p *capabilityResourceProvider) OpenEphemeral(
+	info *InstanceInfo,
+	c *ResourceConfig) (*InstanceState, error) {
+	if !p.supported[ProviderCapabilityEphemeralResources] {
+		return nil, errUnsupportedCapability(p.Name, ProviderCapabilityEphemeralResources)
+	}
+	return p.ResourceProvider.OpenEphemeral(info, c)
+}
+
+func (p *capabilityResourceProvider) CloseEphemeral(
+	info *InstanceInfo,
+	s *InstanceState) error {
+	if !p.supported[ProviderCapabilityEphemeralResources] {
+		return errUnsupportedCapability(p.Name, ProviderCapabilityEphemeralResources)
+	}
+	return p.ResourceProvider.CloseEphemeral(info, s)
+}
+
+func (p *capabilityResourceProvider) MoveResourceState(req *MoveResourceStateRequest) (*InstanceState, error) {
+	m, ok := p.ResourceProvider.(ResourceProviderStateMover)
+	if !ok || !p.supported[ProviderCapabilityMoveState] {
+		return nil, errUnsupportedCapability(p.Name, ProviderCapabilityMoveState)
+	}
+	return m.MoveResourceState(req)
+}
+
+// RefreshBatch refreshes the given instances in one call if the underlying
+// provider supports it, and one at a time otherwise.
+func (p *capabilityResourceProvider) RefreshBatch(
+	infos []*InstanceInfo,
+	states []*InstanceState) ([]*InstanceState, error) {
+	b, ok := p.ResourceProvider.(ResourceProviderBatchRefresher)
+	if !ok || !p.supported[ProviderCapabilityBatchRefresh] {
+		return refreshEach(p.ResourceProvider, infos, states)
+	}
+	return b.RefreshBatch(infos, states)
+}
+
+// Functions returns no functions if the underlying provider doesn't support
+// them.
+func (p *capabilityResourceProvider) Functions() []ProviderFunction {
+	if !p.supported[ProviderCapabilityFunctions] {
+		return nil
+	}
+	return p.ResourceProvider.Functions()
+}
+
+func (p *capabilityResourceProvider) CallFunction(name string, args []interface{}) (interface{}, error) {
+	if !p.supported[ProviderCapabilityFunctions] {
+		return nil, errUnsupportedCapability(p.Name, ProviderCapabilityFunctions)
+	}
+	return p.ResourceProvider.CallFunction(name, args)
+}
+
+// providerSupports returns true if the given provider supports the given
+// capability.
+func providerSupports(p ResourceProvider, c ProviderCapability) bool {
+	caps, err := ProviderCapabilitiesOf(p)
+	if err != nil {
+		return false
+	}
+	for _, cc := range caps {
+		if cc == c {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshBatch refreshes the given instances with the given provider, in
+// one call if it supports ResourceProviderBatchRefresher and one at a time
+// otherwise.
+func refreshBatch(p ResourceProvider, infos []*InstanceInfo, states []*InstanceState) ([]*InstanceState, error) {
+	if b, ok := p.(ResourceProviderBatchRefresher); ok && providerSupports(p, ProviderCapabilityBatchRefresh) {
+		result, err := b.RefreshBatch(infos, states)
+		if err != nil {
+			return nil, fmt.Errorf("%s and %d other instances: %s", infos[0].Id, len(infos)-1, err)
+		}
+		return result, nil
+	}
+	return refreshEach(p, infos, states)
+}
+
+// refreshEach refreshes the given instances one at a time.
+func refreshEach(p ResourceProvider, infos []*InstanceInfo, states []*InstanceState) ([]*InstanceState, error) {
+	result := make([]*InstanceState, len(states))
+	for i, s := range states {
+		var err error
+		result[i], err = p.Refresh(infos[i], s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", infos[i].Id, err)
+		}
+	}
+	return result, nil
+}
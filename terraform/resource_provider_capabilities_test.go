@@ -0,0 +1,200 @@
+package terraform
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mockCapabilitiesResourceProvider is a MockResourceProvider that supports
+// the given capabilities.
+type mockCapabilitiesResourceProvider struct {
+	*MockResourceProvider
+
+	Caps []ProviderCapability
+	Err  error
+}
+
+func (p *mockCapabilitiesResourceProvider) Capabilities() ([]ProviderCapability, error) {
+	return p.Caps, p.Err
+}
+
+// mockBatchRefreshResourceProvider is a MockResourceProvider that can
+// refresh instances in batches.
+type mockBatchRefreshResourceProvider struct {
+	*MockResourceProvider
+
+	RefreshBatchCalled bool
+	RefreshBatchInfos  [][]*InstanceInfo
+	RefreshBatchFn     func([]*InstanceInfo, []*InstanceState) ([]*InstanceState, error)
+}
+
+func (p *mockBatchRefreshResourceProvider) RefreshBatch(
+	infos []*InstanceInfo,
+	states []*InstanceState) ([]*InstanceState, error) {
+	p.Lock()
+	p.RefreshBatchCalled = true
+	p.RefreshBatchInfos = append(p.RefreshBatchInfos, infos)
+	p.Unlock()
+
+	if p.RefreshBatchFn != nil {
+		return p.RefreshBatchFn(infos, states)
+	}
+	return states, nil
+}
+
+func TestProviderCapabilitiesOf(t *testing.T) {
+	cases := map[string]struct {
+		Provider ResourceProvider
+		Want     []ProviderCapability
+	}{
+		"all": {
+			&mockBatchRefreshResourceProvider{MockResourceProvider: new(MockResourceProvider)},
+			AllProviderCapabilities,
+		},
+		"no batch refresh": {
+			new(MockResourceProvider),
+			[]ProviderCapability{
+				ProviderCapabilityCancelApply,
+				ProviderCapabilityEphemeralResources,
+				ProviderCapabilityFunctions,
+				ProviderCapabilityMoveState,
+			},
+		},
+		"no optional interfaces": {
+			struct{ ResourceProvider }{new(MockResourceProvider)},
+			[]ProviderCapability{
+				ProviderCapabilityEphemeralResources,
+				ProviderCapabilityFunctions,
+			},
+		},
+		"declared": {
+			&mockCapabilitiesResourceProvider{
+				MockResourceProvider: new(MockResourceProvider),
+				Caps:                 []ProviderCapability{ProviderCapabilityFunctions},
+			},
+			[]ProviderCapability{ProviderCapabilityFunctions},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ProviderCapabilitiesOf(tc.Provider)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if !reflect.DeepEqual(got, tc.Want) {
+				t.Fatalf("wrong capabilities\ngot:  %#v\nwant: %#v", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestCapabilityResourceProvider_unsupported(t *testing.T) {
+	mock := new(MockResourceProvider)
+	mock.FunctionsReturn = []ProviderFunction{{Name: "upper"}}
+	p := newCapabilityResourceProvider("aws", &mockCapabilitiesResourceProvider{
+		MockResourceProvider: mock,
+	})
+
+	if _, err := p.OpenEphemeral(&InstanceInfo{Type: "aws_token"}, nil); err == nil || !strings.Contains(err.Error(), "doesn't support ephemeral resources") {
+		t.Fatalf("bad: %v", err)
+	}
+	if mock.OpenEphemeralCalled {
+		t.Fatal("open ephemeral shouldn't be called")
+	}
+	if err := p.CloseEphemeral(&InstanceInfo{Type: "aws_token"}, nil); err == nil {
+		t.Fatal("should error")
+	}
+
+	if fs := p.Functions(); fs != nil {
+		t.Fatalf("bad: %#v", fs)
+	}
+	if _, err := p.CallFunction("upper", nil); err == nil || !strings.Contains(err.Error(), "doesn't support provider functions") {
+		t.Fatalf("bad: %v", err)
+	}
+	if mock.CallFunctionCalled {
+		t.Fatal("call function shouldn't be called")
+	}
+
+	// Without cancel_apply, the context isn't passed on
+	mock.ApplyContextFn = func(context.Context, *InstanceInfo, *InstanceState, *InstanceDiff) (*InstanceState, error) {
+		t.Fatal("apply context shouldn't be called")
+		return nil, nil
+	}
+	if _, err := p.ApplyContext(context.Background(), &InstanceInfo{Type: "aws_instance"}, nil, &InstanceDiff{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !mock.ApplyCalled {
+		t.Fatal("apply should be called")
+	}
+
+	// Without batch_refresh, the instances are refreshed one at a time
+	batch := &mockBatchRefreshResourceProvider{MockResourceProvider: mock}
+	p = newCapabilityResourceProvider("aws", struct {
+		*mockBatchRefreshResourceProvider
+		ResourceProviderCapabilities
+	}{batch, &mockCapabilitiesResourceProvider{MockResourceProvider: mock}})
+	infos := []*InstanceInfo{{Id: "aws_instance.foo.0"}, {Id: "aws_instance.foo.1"}}
+	states := []*InstanceState{{ID: "foo0"}, {ID: "foo1"}}
+	if _, err := p.RefreshBatch(infos, states); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !mock.RefreshCalled {
+		t.Fatal("refresh should be called")
+	}
+	if batch.RefreshBatchCalled {
+		t.Fatal("refresh batch shouldn't be called")
+	}
+
+	caps, _ := p.Capabilities()
+	if len(caps) != 0 {
+		t.Fatalf("bad: %#v", caps)
+	}
+}
+
+func TestCapabilityResourceProvider_supported(t *testing.T) {
+	mock := new(MockResourceProvider)
+	mock.FunctionsReturn = []ProviderFunction{{Name: "upper"}}
+	mock.CallFunctionReturn = "FOO"
+	p := newCapabilityResourceProvider("aws", mock)
+
+	if _, err := p.OpenEphemeral(&InstanceInfo{Type: "aws_token"}, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !mock.OpenEphemeralCalled {
+		t.Fatal("open ephemeral should be called")
+	}
+	if fs := p.Functions(); len(fs) != 1 {
+		t.Fatalf("bad: %#v", fs)
+	}
+	if v, err := p.CallFunction("upper", []interface{}{"foo"}); err != nil || v != "FOO" {
+		t.Fatalf("bad: %#v, %v", v, err)
+	}
+
+	called := false
+	mock.ApplyContextFn = func(context.Context, *InstanceInfo, *InstanceState, *InstanceDiff) (*InstanceState, error) {
+		called = true
+		return nil, nil
+	}
+	if _, err := p.ApplyContext(context.Background(), &InstanceInfo{Type: "aws_instance"}, nil, &InstanceDiff{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !called {
+		t.Fatal("apply context should be called")
+	}
+}
+
+func TestCapabilityResourceProvider_error(t *testing.T) {
+	// A provider whose capabilities can't be read is taken to support none.
+	p := newCapabilityResourceProvider("aws", &mockCapabilitiesResourceProvider{
+		MockResourceProvider: new(MockResourceProvider),
+		Caps:                 AllProviderCapabilities,
+		Err:                  errors.New("broken"),
+	})
+	if _, err := p.CallFunction("upper", nil); err == nil {
+		t.Fatal("should error")
+	}
+}
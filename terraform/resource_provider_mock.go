@@ -1,6 +1,7 @@
 package terraform
 
 import (
+	"context"
 	"sync"
 )
 
@@ -29,6 +30,7 @@ type MockResourceProvider struct {
 	ApplyState                     *InstanceState
 	ApplyDiff                      *InstanceDiff
 	ApplyFn                        func(*InstanceInfo, *InstanceState, *InstanceDiff) (*InstanceState, error)
+	ApplyContextFn                 func(context.Context, *InstanceInfo, *InstanceState, *InstanceDiff) (*InstanceState, error)
 	ApplyReturn                    *InstanceState
 	ApplyReturnError               error
 	ConfigureCalled                bool
@@ -84,6 +86,13 @@ type MockResourceProvider struct {
 	ValidateDataSourceConfig       *ResourceConfig
 	ValidateDataSourceReturnWarns  []string
 	ValidateDataSourceReturnErrors []error
+	ValidateApplyFn                func(*InstanceInfo, *InstanceState, *InstanceDiff) ([]string, []error)
+	ValidateApplyCalled            bool
+	ValidateApplyInfo              *InstanceInfo
+	ValidateApplyState             *InstanceState
+	ValidateApplyDiff              *InstanceDiff
+	ValidateApplyReturnWarns       []string
+	ValidateApplyReturnErrors      []error
 
 	ImportStateCalled      bool
 	ImportStateInfo        *InstanceInfo
@@ -91,6 +100,33 @@ type MockResourceProvider struct {
 	ImportStateReturn      []*InstanceState
 	ImportStateReturnError error
 	ImportStateFn          func(*InstanceInfo, string) ([]*InstanceState, error)
+
+	OpenEphemeralCalled       bool
+	OpenEphemeralInfo         *InstanceInfo
+	OpenEphemeralConfig       *ResourceConfig
+	OpenEphemeralFn           func(*InstanceInfo, *ResourceConfig) (*InstanceState, error)
+	OpenEphemeralReturn       *InstanceState
+	OpenEphemeralReturnError  error
+	CloseEphemeralCalled      bool
+	CloseEphemeralInfo        *InstanceInfo
+	CloseEphemeralState       *InstanceState
+	CloseEphemeralFn          func(*InstanceInfo, *InstanceState) error
+	CloseEphemeralReturnError error
+
+	FunctionsCalled         bool
+	FunctionsReturn         []ProviderFunction
+	CallFunctionCalled      bool
+	CallFunctionName        string
+	CallFunctionArgs        []interface{}
+	CallFunctionFn          func(string, []interface{}) (interface{}, error)
+	CallFunctionReturn      interface{}
+	CallFunctionReturnError error
+
+	MoveResourceStateCalled      bool
+	MoveResourceStateRequest     *MoveResourceStateRequest
+	MoveResourceStateFn          func(*MoveResourceStateRequest) (*InstanceState, error)
+	MoveResourceStateReturn      *InstanceState
+	MoveResourceStateReturnError error
 }
 
 func (p *MockResourceProvider) Close() error {
@@ -192,6 +228,45 @@ func (p *MockResourceProvider) Apply(
 	return p.ApplyReturn.DeepCopy(), p.ApplyReturnError
 }
 
+// ApplyContext calls ApplyContextFn if it is set, and is otherwise the same
+// as Apply.
+func (p *MockResourceProvider) ApplyContext(
+	ctx context.Context,
+	info *InstanceInfo,
+	state *InstanceState,
+	diff *InstanceDiff) (*InstanceState, error) {
+	if p.ApplyContextFn == nil {
+		return p.Apply(info, state, diff)
+	}
+
+	p.Lock()
+	p.ApplyCalled = true
+	p.ApplyInfo = info
+	p.ApplyState = state
+	p.ApplyDiff = diff
+	p.Unlock()
+
+	return p.ApplyContextFn(ctx, info, state, diff)
+}
+
+func (p *MockResourceProvider) ValidateApply(
+	info *InstanceInfo,
+	state *InstanceState,
+	diff *InstanceDiff) ([]string, []error) {
+	p.Lock()
+	p.ValidateApplyCalled = true
+	p.ValidateApplyInfo = info
+	p.ValidateApplyState = state
+	p.ValidateApplyDiff = diff
+	p.Unlock()
+
+	if p.ValidateApplyFn != nil {
+		return p.ValidateApplyFn(info, state, diff)
+	}
+
+	return p.ValidateApplyReturnWarns, p.ValidateApplyReturnErrors
+}
+
 func (p *MockResourceProvider) Diff(
 	info *InstanceInfo,
 	state *InstanceState,
@@ -306,6 +381,79 @@ func (p *MockResourceProvider) ReadDataApply(
 	return p.ReadDataApplyReturn.DeepCopy(), p.ReadDataApplyReturnError
 }
 
+func (p *MockResourceProvider) OpenEphemeral(
+	info *InstanceInfo,
+	c *ResourceConfig) (*InstanceState, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.OpenEphemeralCalled = true
+	p.OpenEphemeralInfo = info
+	p.OpenEphemeralConfig = c
+
+	if p.OpenEphemeralFn != nil {
+		return p.OpenEphemeralFn(info, c)
+	}
+
+	return p.OpenEphemeralReturn.DeepCopy(), p.OpenEphemeralReturnError
+}
+
+func (p *MockResourceProvider) CloseEphemeral(
+	info *InstanceInfo,
+	s *InstanceState) error {
+	p.Lock()
+	defer p.Unlock()
+
+	p.CloseEphemeralCalled = true
+	p.CloseEphemeralInfo = info
+	p.CloseEphemeralState = s
+
+	if p.CloseEphemeralFn != nil {
+		return p.CloseEphemeralFn(info, s)
+	}
+
+	return p.CloseEphemeralReturnError
+}
+
+func (p *MockResourceProvider) Functions() []ProviderFunction {
+	p.Lock()
+	defer p.Unlock()
+
+	p.FunctionsCalled = true
+	return p.FunctionsReturn
+}
+
+func (p *MockResourceProvider) CallFunction(
+	name string,
+	args []interface{}) (interface{}, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.CallFunctionCalled = true
+	p.CallFunctionName = name
+	p.CallFunctionArgs = args
+
+	if p.CallFunctionFn != nil {
+		return p.CallFunctionFn(name, args)
+	}
+
+	return p.CallFunctionReturn, p.CallFunctionReturnError
+}
+
+func (p *MockResourceProvider) MoveResourceState(req *MoveResourceStateRequest) (*InstanceState, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.MoveResourceStateCalled = true
+	p.MoveResourceStateRequest = req
+
+	if p.MoveResourceStateFn != nil {
+		return p.MoveResourceStateFn(req)
+	}
+
+	return p.MoveResourceStateReturn, p.MoveResourceStateReturnError
+}
+
 func (p *MockResourceProvider) DataSources() []DataSource {
 	p.Lock()
 	defer p.Unlock()
@@ -0,0 +1,265 @@
+package terraform
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform/config"
+)
+
+// ProviderPolicy controls how Terraform calls the operations of a provider
+// that read and change infrastructure: Diff, Apply, Refresh, ReadDataDiff
+// and ReadDataApply.
+type ProviderPolicy struct {
+	// MaxAttempts is the number of times an operation that returns an error
+	// is attempted before that error is returned. Values below 2 disable
+	// retries.
+	MaxAttempts int
+
+	// MinBackoff and MaxBackoff bound the wait before each retry. The wait
+	// starts at MinBackoff and doubles for each retry, up to MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// RateLimit is the most operations to start per second, including
+	// retries. Zero means no limit.
+	RateLimit float64
+}
+
+// NewProviderPolicy returns the policy set in the given provider
+// configuration, or nil if the configuration doesn't set one.
+func NewProviderPolicy(c *config.ProviderConfig) *ProviderPolicy {
+	if c == nil || (c.Retry == nil && c.RateLimit == 0) {
+		return nil
+	}
+
+	policy := &ProviderPolicy{RateLimit: c.RateLimit}
+	if c.Retry != nil {
+		// The durations were checked when the config was validated.
+		policy.MaxAttempts = c.Retry.MaxAttempts
+		policy.MinBackoff, _ = time.ParseDuration(c.Retry.MinBackoff)
+		policy.MaxBackoff, _ = time.ParseDuration(c.Retry.MaxBackoff)
+	}
+	return policy
+}
+
+// errProviderStopped is returned for operations that were waiting to start
+// when the provider was stopped.
+var errProviderStopped = errors.New("provider was stopped before the operation started")
+
+// policyResourceProvider is a ResourceProvider that applies a
+// ProviderPolicy to the calls of another provider.
+type policyResourceProvider struct {
+	ResourceProvider
+
+	Name   string
+	Policy *ProviderPolicy
+
+	// next is the earliest time the next operation may start, for the
+	// rate limit.
+	nextLock sync.Mutex
+	next     time.Time
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+func newPolicyResourceProvider(name string, p ResourceProvider, policy *ProviderPolicy) *policyResourceProvider {
+	return &policyResourceProvider{
+		ResourceProvider: p,
+		Name:             name,
+		Policy:           policy,
+		stopCh:           make(chan struct{}),
+	}
+}
+
+func (p *policyResourceProvider) Stop() error {
+	p.stopOnce.Do(func() { close(p.stopCh) })
+	return p.ResourceProvider.Stop()
+}
+
+func (p *policyResourceProvider) Close() error {
+	if c, ok := p.ResourceProvider.(ResourceProviderCloser); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (p *policyResourceProvider) Capabilities() ([]ProviderCapability, error) {
+	return ProviderCapabilitiesOf(p.ResourceProvider)
+}
+
+func (p *policyResourceProvider) Apply(
+	info *InstanceInfo,
+	s *InstanceState,
+	d *InstanceDiff) (*InstanceState, error) {
+	var result *InstanceState
+	err := p.do("apply", info, func() (bool, error) {
+		var err error
+		result, err = p.ResourceProvider.Apply(info, s, d)
+
+		// Applying is only retried if the provider reports that nothing
+		// changed, since otherwise the object may have been partly
+		// created or updated.
+		return result == nil || result.Equal(s), err
+	})
+	return result, err
+}
+
+// ApplyContext is retried as Apply is, except that no retries are made once
+// the given context is done.
+func (p *policyResourceProvider) ApplyContext(
+	ctx context.Context,
+	info *InstanceInfo,
+	s *InstanceState,
+	d *InstanceDiff) (*InstanceState, error) {
+	var result *InstanceState
+	err := p.do("apply", info, func() (bool, error) {
+		var err error
+		result, err = ApplyWithContext(ctx, p.ResourceProvider, info, s, d)
+		return ctx.Err() == nil && (result == nil || result.Equal(s)), err
+	})
+	return result, err
+}
+
+func (p *policyResourceProvider) Diff(
+	info *InstanceInfo,
+	s *InstanceState,
+	c *ResourceConfig) (*InstanceDiff, error) {
+	var result *InstanceDiff
+	err := p.do("diff", info, func() (bool, error) {
+		var err error
+		result, err = p.ResourceProvider.Diff(info, s, c)
+		return true, err
+	})
+	return result, err
+}
+
+func (p *policyResourceProvider) Refresh(
+	info *InstanceInfo,
+	s *InstanceState) (*InstanceState, error) {
+	var result *InstanceState
+	err := p.do("refresh", info, func() (bool, error) {
+		var err error
+		result, err = p.ResourceProvider.Refresh(info, s)
+		return true, err
+	})
+	return result, err
+}
+
+// RefreshBatch counts a batch as a single operation for the rate limit and
+// retries the whole batch if the underlying provider implements
+// ResourceProviderBatchRefresher, and applies the policy to each instance
+// otherwise.
+func (p *policyResourceProvider) RefreshBatch(
+	infos []*InstanceInfo,
+	states []*InstanceState) ([]*InstanceState, error) {
+	b, ok := p.ResourceProvider.(ResourceProviderBatchRefresher)
+	if !ok {
+		return refreshEach(p, infos, states)
+	}
+
+	var result []*InstanceState
+	err := p.do("batch refresh", infos[0], func() (bool, error) {
+		var err error
+		result, err = b.RefreshBatch(infos, states)
+		return true, err
+	})
+	return result, err
+}
+
+func (p *policyResourceProvider) ReadDataDiff(
+	info *InstanceInfo,
+	c *ResourceConfig) (*InstanceDiff, error) {
+	var result *InstanceDiff
+	err := p.do("read data diff", info, func() (bool, error) {
+		var err error
+		result, err = p.ResourceProvider.ReadDataDiff(info, c)
+		return true, err
+	})
+	return result, err
+}
+
+func (p *policyResourceProvider) ReadDataApply(
+	info *InstanceInfo,
+	d *InstanceDiff) (*InstanceState, error) {
+	var result *InstanceState
+	err := p.do("read data", info, func() (bool, error) {
+		var err error
+		result, err = p.ResourceProvider.ReadDataApply(info, d)
+		return true, err
+	})
+	return result, err
+}
+
+// do calls f, waiting for the rate limit before each call and retrying it
+// as allowed by the policy. f returns whether it may be retried if it
+// fails.
+func (p *policyResourceProvider) do(op string, info *InstanceInfo, f func() (bool, error)) error {
+	backoff := p.Policy.MinBackoff
+	for attempt := 1; ; attempt++ {
+		if !p.wait(p.rateLimitDelay()) {
+			return errProviderStopped
+		}
+
+		retryable, err := f()
+		if err == nil || !retryable || attempt >= p.Policy.MaxAttempts {
+			return err
+		}
+
+		log.Printf("[WARN] %s: %s of %s failed, retrying in %s: %s",
+			p.Name, op, info.HumanId(), backoff, err)
+		if !p.wait(backoff) {
+			return err
+		}
+
+		backoff *= 2
+		if backoff > p.Policy.MaxBackoff {
+			backoff = p.Policy.MaxBackoff
+		}
+	}
+}
+
+// rateLimitDelay reserves the next start time allowed by the rate limit and
+// returns how long to wait for it.
+func (p *policyResourceProvider) rateLimitDelay() time.Duration {
+	if p.Policy.RateLimit <= 0 {
+		return 0
+	}
+
+	p.nextLock.Lock()
+	defer p.nextLock.Unlock()
+
+	now := time.Now()
+	start := p.next
+	if start.Before(now) {
+		start = now
+	}
+	p.next = start.Add(time.Duration(float64(time.Second) / p.Policy.RateLimit))
+	return start.Sub(now)
+}
+
+// wait waits for the given duration, returning false if the provider was
+// stopped first.
+func (p *policyResourceProvider) wait(d time.Duration) bool {
+	if d <= 0 {
+		select {
+		case <-p.stopCh:
+			return false
+		default:
+			return true
+		}
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-p.stopCh:
+		return false
+	}
+}
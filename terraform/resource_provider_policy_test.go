@@ -0,0 +1,165 @@
+package terraform
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform/config"
+)
+
+func TestNewProviderPolicy(t *testing.T) {
+	cases := map[string]struct {
+		Config *config.ProviderConfig
+		Want   *ProviderPolicy
+	}{
+		"no policy": {
+			&config.ProviderConfig{Name: "aws"},
+			nil,
+		},
+		"retry": {
+			&config.ProviderConfig{
+				Name: "aws",
+				Retry: &config.ProviderRetry{
+					MaxAttempts: 3,
+					MinBackoff:  "1s",
+					MaxBackoff:  "1m",
+				},
+			},
+			&ProviderPolicy{
+				MaxAttempts: 3,
+				MinBackoff:  time.Second,
+				MaxBackoff:  time.Minute,
+			},
+		},
+		"rate limit": {
+			&config.ProviderConfig{Name: "aws", RateLimit: 5},
+			&ProviderPolicy{RateLimit: 5},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := NewProviderPolicy(tc.Config)
+			if (got == nil) != (tc.Want == nil) || (got != nil && *got != *tc.Want) {
+				t.Fatalf("wrong policy\ngot:  %#v\nwant: %#v", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestPolicyResourceProvider_retry(t *testing.T) {
+	p := new(MockResourceProvider)
+	calls := 0
+	p.RefreshFn = func(info *InstanceInfo, s *InstanceState) (*InstanceState, error) {
+		calls++
+		if calls < 3 {
+			return nil, errors.New("throttled")
+		}
+		return s, nil
+	}
+
+	wrapped := newPolicyResourceProvider("provider.aws", p, &ProviderPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+	})
+
+	state := &InstanceState{ID: "foo"}
+	got, err := wrapped.Refresh(&InstanceInfo{Id: "aws_instance.foo"}, state)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if got != state {
+		t.Fatalf("wrong state: %#v", got)
+	}
+	if calls != 3 {
+		t.Fatalf("refresh called %d times; want 3", calls)
+	}
+
+	// Fails when out of attempts
+	calls = 0
+	p.RefreshFn = func(info *InstanceInfo, s *InstanceState) (*InstanceState, error) {
+		calls++
+		return nil, errors.New("throttled")
+	}
+	if _, err := wrapped.Refresh(&InstanceInfo{Id: "aws_instance.foo"}, state); err == nil {
+		t.Fatal("should error")
+	}
+	if calls != 3 {
+		t.Fatalf("refresh called %d times; want 3", calls)
+	}
+}
+
+func TestPolicyResourceProvider_applyChanged(t *testing.T) {
+	p := new(MockResourceProvider)
+	calls := 0
+	p.ApplyFn = func(info *InstanceInfo, s *InstanceState, d *InstanceDiff) (*InstanceState, error) {
+		calls++
+
+		// The object was created before the error, so it is not safe to
+		// apply again.
+		return &InstanceState{ID: "foo"}, errors.New("throttled")
+	}
+
+	wrapped := newPolicyResourceProvider("provider.aws", p, &ProviderPolicy{
+		MaxAttempts: 3,
+	})
+
+	_, err := wrapped.Apply(&InstanceInfo{Id: "aws_instance.foo"}, &InstanceState{}, &InstanceDiff{})
+	if err == nil {
+		t.Fatal("should error")
+	}
+	if calls != 1 {
+		t.Fatalf("apply called %d times; want 1", calls)
+	}
+}
+
+func TestPolicyResourceProvider_rateLimit(t *testing.T) {
+	p := new(MockResourceProvider)
+	p.RefreshFn = func(info *InstanceInfo, s *InstanceState) (*InstanceState, error) {
+		return s, nil
+	}
+
+	wrapped := newPolicyResourceProvider("provider.aws", p, &ProviderPolicy{
+		RateLimit: 20,
+	})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := wrapped.Refresh(&InstanceInfo{Id: "aws_instance.foo"}, &InstanceState{ID: "foo"}); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// The first call starts at once and each after that waits 50ms.
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Fatalf("3 calls took %s; want at least 100ms", d)
+	}
+}
+
+func TestPolicyResourceProvider_stop(t *testing.T) {
+	p := new(MockResourceProvider)
+	p.RefreshFn = func(info *InstanceInfo, s *InstanceState) (*InstanceState, error) {
+		return nil, errors.New("throttled")
+	}
+
+	wrapped := newPolicyResourceProvider("provider.aws", p, &ProviderPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Hour,
+		MaxBackoff:  time.Hour,
+	})
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		wrapped.Stop()
+	}()
+
+	// Stopping interrupts the wait before retrying
+	if _, err := wrapped.Refresh(&InstanceInfo{Id: "aws_instance.foo"}, &InstanceState{ID: "foo"}); err == nil {
+		t.Fatal("should error")
+	}
+	if !p.StopCalled {
+		t.Fatal("stop should be called")
+	}
+}
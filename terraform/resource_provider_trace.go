@@ -0,0 +1,160 @@
+package terraform
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform/tracing"
+)
+
+// tracingResourceProvider is a ResourceProvider that records a span for
+// each call of another provider that configures it or reads or changes
+// infrastructure. The span of a call for a resource instance is a child of
+// the span of the instance's action in progress.
+type tracingResourceProvider struct {
+	ResourceProvider
+
+	Name string
+	Hook *traceHook
+}
+
+func newTracingResourceProvider(name string, p ResourceProvider, h *traceHook) *tracingResourceProvider {
+	return &tracingResourceProvider{
+		ResourceProvider: p,
+		Name:             name,
+		Hook:             h,
+	}
+}
+
+func (p *tracingResourceProvider) Close() error {
+	if c, ok := p.ResourceProvider.(ResourceProviderCloser); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (p *tracingResourceProvider) Capabilities() ([]ProviderCapability, error) {
+	return ProviderCapabilitiesOf(p.ResourceProvider)
+}
+
+func (p *tracingResourceProvider) Configure(c *ResourceConfig) error {
+	span := p.start("Configure", nil)
+	err := p.ResourceProvider.Configure(c)
+	span.Finish(err)
+	return err
+}
+
+func (p *tracingResourceProvider) Apply(
+	info *InstanceInfo,
+	s *InstanceState,
+	d *InstanceDiff) (*InstanceState, error) {
+	span := p.start("Apply", info)
+	result, err := p.ResourceProvider.Apply(info, s, d)
+	span.Finish(err)
+	return result, err
+}
+
+// ApplyContext is traced as an Apply.
+func (p *tracingResourceProvider) ApplyContext(
+	ctx context.Context,
+	info *InstanceInfo,
+	s *InstanceState,
+	d *InstanceDiff) (*InstanceState, error) {
+	span := p.start("Apply", info)
+	result, err := ApplyWithContext(ctx, p.ResourceProvider, info, s, d)
+	span.Finish(err)
+	return result, err
+}
+
+func (p *tracingResourceProvider) ValidateApply(
+	info *InstanceInfo,
+	s *InstanceState,
+	d *InstanceDiff) ([]string, []error) {
+	span := p.start("ValidateApply", info)
+	ws, es := p.ResourceProvider.ValidateApply(info, s, d)
+	if len(es) > 0 {
+		span.Finish(es[0])
+	} else {
+		span.Finish(nil)
+	}
+	return ws, es
+}
+
+func (p *tracingResourceProvider) Diff(
+	info *InstanceInfo,
+	s *InstanceState,
+	c *ResourceConfig) (*InstanceDiff, error) {
+	span := p.start("Diff", info)
+	result, err := p.ResourceProvider.Diff(info, s, c)
+	span.Finish(err)
+	return result, err
+}
+
+func (p *tracingResourceProvider) Refresh(
+	info *InstanceInfo,
+	s *InstanceState) (*InstanceState, error) {
+	span := p.start("Refresh", info)
+	result, err := p.ResourceProvider.Refresh(info, s)
+	span.Finish(err)
+	return result, err
+}
+
+// RefreshBatch records a single span for the batch if the underlying
+// provider implements ResourceProviderBatchRefresher, and one for each
+// instance otherwise.
+func (p *tracingResourceProvider) RefreshBatch(
+	infos []*InstanceInfo,
+	states []*InstanceState) ([]*InstanceState, error) {
+	b, ok := p.ResourceProvider.(ResourceProviderBatchRefresher)
+	if !ok {
+		return refreshEach(p, infos, states)
+	}
+
+	span := p.start("RefreshBatch", nil)
+	result, err := b.RefreshBatch(infos, states)
+	span.Finish(err)
+	return result, err
+}
+
+func (p *tracingResourceProvider) ImportState(
+	info *InstanceInfo,
+	id string) ([]*InstanceState, error) {
+	span := p.start("ImportState", info)
+	result, err := p.ResourceProvider.ImportState(info, id)
+	span.Finish(err)
+	return result, err
+}
+
+func (p *tracingResourceProvider) ReadDataDiff(
+	info *InstanceInfo,
+	c *ResourceConfig) (*InstanceDiff, error) {
+	span := p.start("ReadDataDiff", info)
+	result, err := p.ResourceProvider.ReadDataDiff(info, c)
+	span.Finish(err)
+	return result, err
+}
+
+func (p *tracingResourceProvider) ReadDataApply(
+	info *InstanceInfo,
+	d *InstanceDiff) (*InstanceState, error) {
+	span := p.start("ReadDataApply", info)
+	result, err := p.ResourceProvider.ReadDataApply(info, d)
+	span.Finish(err)
+	return result, err
+}
+
+// start starts the span of a call of the given method, for the resource
+// instance described by info if it isn't nil.
+func (p *tracingResourceProvider) start(method string, info *InstanceInfo) *tracing.Span {
+	attrs := []tracing.Attribute{
+		tracing.String("rpc.system", "terraform-provider"),
+		tracing.String("rpc.method", method),
+		tracing.String("terraform.provider", p.Name),
+	}
+	if info != nil {
+		attrs = append(attrs, tracing.String("terraform.resource.address", info.ResourceAddress().String()))
+	}
+
+	span := p.Hook.tracer.Start(p.Hook.parent(info), p.Name+"/"+method, attrs...)
+	span.SetKind(tracing.KindClient)
+	return span
+}
@@ -24,6 +24,12 @@ type ProviderSchema struct {
 	Provider      *configschema.Block
 	ResourceTypes map[string]*configschema.Block
 	DataSources   map[string]*configschema.Block
+
+	// ResourceTypeSchemaVersions are the current versions of the schemas
+	// of the resource types, which the objects of those types in the state
+	// are upgraded to when refreshed. It's nil if the provider doesn't
+	// report them.
+	ResourceTypeSchemaVersions map[string]uint64
 }
 
 // ProviderSchemaRequest is used to describe to a ResourceProvider which
@@ -0,0 +1,215 @@
+package terraform
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/module"
+)
+
+// SecretsSource reads the values of secret variables, which are variables
+// of the root module with a "secret" block, from the secrets backends that
+// they name.
+type SecretsSource interface {
+	// Secret returns the value of the secret with the given key in the
+	// backend with the given name.
+	Secret(backend, key string) (string, error)
+}
+
+// secretVariables reads the values of the secret variables of the root
+// module of the given tree into the given variables.
+//
+// The values of secret variables are always read from their backends, so
+// it's an error for one to be set already, such as with -var or a variable
+// file. If there's no source then secret variables are left unset.
+func secretVariables(m *module.Tree, vs map[string]interface{}, src SecretsSource) error {
+	if m == nil || m.Config() == nil {
+		return nil
+	}
+
+	for _, v := range m.Config().Variables {
+		if v.Secret == nil {
+			continue
+		}
+		if _, ok := vs[v.Name]; ok {
+			return fmt.Errorf(
+				"variable %q is read from the %q secrets backend and can't be set with -var, a variable file or the environment",
+				v.Name, v.Secret.Backend)
+		}
+		if src == nil {
+			continue
+		}
+
+		value, err := src.Secret(v.Secret.Backend, v.Secret.Key)
+		if err != nil {
+			return fmt.Errorf("variable %q: failed to read secret from %q: %s", v.Name, v.Secret.Backend, err)
+		}
+		vs[v.Name] = value
+	}
+
+	return nil
+}
+
+// planVariables returns the given variables without the values of the
+// secret variables of the root module of the given tree, which are read
+// again when the plan is applied rather than being saved with it.
+func planVariables(m *module.Tree, vs map[string]interface{}) map[string]interface{} {
+	if m == nil || m.Config() == nil {
+		return vs
+	}
+
+	result := make(map[string]interface{}, len(vs))
+	for k, v := range vs {
+		result[k] = v
+	}
+	for _, v := range m.Config().Variables {
+		if v.Secret != nil {
+			delete(result, v.Name)
+		}
+	}
+	return result
+}
+
+// sensitiveNames returns the names of the values in each module of the
+// given tree that are derived from secret variables, such as
+// "var.password", "local.dsn" or "module.db.url", keyed by PathCacheKey of
+// the module path. The outputs of a module that are derived from secrets
+// are included as, for example, "output.url".
+//
+// Values derived from secrets through references to the attributes of
+// resources aren't included.
+func sensitiveNames(m *module.Tree) map[string]map[string]bool {
+	result := make(map[string]map[string]bool)
+	if m == nil || m.Config() == nil {
+		return result
+	}
+
+	names := make(map[string]bool)
+	for _, v := range m.Config().Variables {
+		if v.Secret != nil {
+			names["var."+v.Name] = true
+		}
+	}
+	sensitiveModuleNames(m, RootModulePath, names, result)
+	return result
+}
+
+// sensitiveModuleNames adds the sensitive names of the module of the given
+// tree at the given path and its descendents to the given result, starting
+// with the given names of its sensitive variables.
+func sensitiveModuleNames(m *module.Tree, path []string, names map[string]bool, result map[string]map[string]bool) {
+	children := m.Children()
+	conf := m.Config()
+
+	// Locals can refer to module outputs and module blocks can refer to
+	// locals, so we go around until there are no new sensitive names.
+	for {
+		before := len(names)
+
+		for _, l := range conf.Locals {
+			if referencesSensitive(l.RawConfig, names) {
+				names["local."+l.Name] = true
+			}
+		}
+
+		for _, mc := range conf.Modules {
+			child, ok := children[mc.Name]
+			if !ok {
+				continue
+			}
+			childNames := make(map[string]bool)
+			for _, k := range sensitiveKeys(mc.RawConfig, names) {
+				childNames["var."+k] = true
+			}
+
+			childPath := make([]string, len(path), len(path)+1)
+			copy(childPath, path)
+			childPath = append(childPath, mc.Name)
+			sensitiveModuleNames(child, childPath, childNames, result)
+
+			for name := range childNames {
+				if strings.HasPrefix(name, "output.") {
+					names["module."+mc.Name+"."+strings.TrimPrefix(name, "output.")] = true
+				}
+			}
+		}
+
+		if len(names) == before {
+			break
+		}
+	}
+
+	for _, o := range conf.Outputs {
+		if referencesSensitive(o.RawConfig, names) {
+			names["output."+o.Name] = true
+		}
+	}
+
+	result[PathCacheKey(path)] = names
+}
+
+// sensitiveKeys returns the top-level keys of the given configuration whose
+// values refer to any of the given sensitive names.
+func sensitiveKeys(raw *config.RawConfig, names map[string]bool) []string {
+	if raw == nil || len(names) == 0 {
+		return nil
+	}
+
+	var keys []string
+	for k, v := range raw.Raw {
+		rc, err := config.NewRawConfig(map[string]interface{}{k: v})
+		if err != nil {
+			continue
+		}
+		if referencesSensitive(rc, names) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// referencesSensitive returns true if the given configuration refers to
+// any of the given sensitive names.
+func referencesSensitive(raw *config.RawConfig, names map[string]bool) bool {
+	if raw == nil || len(names) == 0 {
+		return false
+	}
+
+	for _, v := range raw.Variables {
+		var name string
+		switch v := v.(type) {
+		case *config.UserVariable:
+			name = "var." + v.Name
+		case *config.LocalVariable:
+			name = "local." + v.Name
+		case *config.ModuleVariable:
+			name = "module." + v.Name + "." + v.Field
+		default:
+			continue
+		}
+		if names[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// markSensitiveAttributes marks the attributes of the given diff that are
+// set from the given keys of the resource configuration as sensitive, so
+// that their values aren't shown.
+func markSensitiveAttributes(diff *InstanceDiff, keys []string) {
+	if diff == nil || len(keys) == 0 {
+		return
+	}
+
+	for name, attr := range diff.CopyAttributes() {
+		for _, k := range keys {
+			if name == k || strings.HasPrefix(name, k+".") {
+				attr.Sensitive = true
+				diff.SetAttribute(name, attr)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package terraform
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testSecretsSource map[string]map[string]string
+
+func (s testSecretsSource) Secret(backend, key string) (string, error) {
+	return s[backend][key], nil
+}
+
+func TestContext2Plan_secretVariable(t *testing.T) {
+	m := testModule(t, "plan-secret-var")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		Secrets: testSecretsSource{"test": {"db": "hunter2"}},
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The secret must be read again when the plan is applied.
+	if _, ok := plan.Vars["password"]; ok {
+		t.Fatalf("plan contains the secret variable: %#v", plan.Vars)
+	}
+	if plan.Vars["size"] != "2" {
+		t.Fatalf("plan doesn't contain the other variables: %#v", plan.Vars)
+	}
+
+	attrs := plan.Diff.RootModule().Resources["aws_instance.foo"].Attributes
+	if attr := attrs["foo"]; attr == nil || attr.New != "hunter2" || !attr.Sensitive {
+		t.Fatalf("wrong diff for attribute set from a secret: %#v", attr)
+	}
+	if attr := attrs["num"]; attr == nil || attr.Sensitive {
+		t.Fatalf("wrong diff for attribute not set from a secret: %#v", attr)
+	}
+}
+
+func TestContext2Apply_secretVariable(t *testing.T) {
+	m := testModule(t, "plan-secret-var")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		Secrets: testSecretsSource{"test": {"db": "hunter2"}},
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	outputs := state.RootModule().Outputs
+	if o := outputs["password"]; o == nil || o.Value != "hunter2" || !o.Sensitive {
+		t.Fatalf("wrong output derived from a secret: %#v", o)
+	}
+	if o := outputs["size"]; o == nil || o.Sensitive {
+		t.Fatalf("wrong output not derived from a secret: %#v", o)
+	}
+}
+
+func TestNewContext_secretVariableSet(t *testing.T) {
+	m := testModule(t, "plan-secret-var")
+	_, err := NewContext(&ContextOpts{
+		Module:    m,
+		Variables: map[string]interface{}{"password": "oops"},
+		Secrets:   testSecretsSource{"test": {"db": "hunter2"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "can't be set with -var") {
+		t.Fatalf("wrong error for setting a secret variable: %v", err)
+	}
+}
+
+func TestSensitiveNames(t *testing.T) {
+	m := testModule(t, "secret-var-module")
+
+	got := sensitiveNames(m)
+	want := map[string]map[string]bool{
+		PathCacheKey(RootModulePath): {
+			"var.password":     true,
+			"local.dsn":        true,
+			"module.child.url": true,
+			"output.url":       true,
+		},
+		PathCacheKey([]string{"root", "child"}): {
+			"var.dsn":    true,
+			"output.url": true,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
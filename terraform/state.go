@@ -3,6 +3,7 @@ package terraform
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	"io/ioutil"
 	"log"
 	"reflect"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -86,6 +88,15 @@ type State struct {
 	// configuration.
 	Backend *BackendState `json:"backend,omitempty"`
 
+	// Metadata is arbitrary key/value metadata of the workspace that this
+	// state belongs to, such as its owner or environment. Terraform doesn't
+	// interpret it; see the backend package for setting it.
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Fingerprint describes the environment of the run that last wrote
+	// this state.
+	Fingerprint *Fingerprint `json:"fingerprint,omitempty"`
+
 	// Modules contains all the modules in a breadth-first order
 	Modules []*ModuleState `json:"modules"`
 
@@ -493,6 +504,37 @@ func (s *State) RootModule() *ModuleState {
 	return root
 }
 
+// Checks returns the status of every check block in the state, keyed by
+// the address of the check, such as "check.health" or
+// "module.foo.check.health".
+func (s *State) Checks() map[string]*CheckState {
+	if s == nil {
+		return nil
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	var result map[string]*CheckState
+	for _, m := range s.Modules {
+		for k, v := range m.Checks {
+			if result == nil {
+				result = make(map[string]*CheckState)
+			}
+
+			addr := "check." + k
+			if len(m.Path) > 1 {
+				addr = modulePrefixStr(m.Path) + "." + addr
+			}
+
+			cs := *v
+			result[addr] = &cs
+		}
+	}
+
+	return result
+}
+
 // Equal tests if one state is equal to another.
 func (s *State) Equal(other *State) bool {
 	// If one is nil, we do a direct check
@@ -515,6 +557,12 @@ func (s *State) equal(other *State) bool {
 		return false
 	}
 
+	if len(s.Metadata) > 0 || len(other.Metadata) > 0 {
+		if !reflect.DeepEqual(s.Metadata, other.Metadata) {
+			return false
+		}
+	}
+
 	// If any of the modules are not equal, then this state isn't equal
 	if len(s.Modules) != len(other.Modules) {
 		return false
@@ -554,22 +602,24 @@ func (s *State) MarshalEqual(other *State) bool {
 		return false
 	}
 
-	recvBuf := &bytes.Buffer{}
-	otherBuf := &bytes.Buffer{}
+	// The states are compared by the sums of their encodings, so that
+	// neither has to be held in memory.
+	recvSum := sha256.New()
+	otherSum := sha256.New()
 
-	err := WriteState(s, recvBuf)
+	err := WriteState(s, recvSum)
 	if err != nil {
-		// should never happen, since we're writing to a buffer
+		// should never happen, since we're writing to a hash
 		panic(err)
 	}
 
-	err = WriteState(other, otherBuf)
+	err = WriteState(other, otherSum)
 	if err != nil {
-		// should never happen, since we're writing to a buffer
+		// should never happen, since we're writing to a hash
 		panic(err)
 	}
 
-	return bytes.Equal(recvBuf.Bytes(), otherBuf.Bytes())
+	return bytes.Equal(recvSum.Sum(nil), otherSum.Sum(nil))
 }
 
 type StateAgeComparison int
@@ -971,6 +1021,17 @@ func (s *OutputState) deepcopy() *OutputState {
 	return stateCopy.(*OutputState)
 }
 
+// CheckState is used to track the status of a single check block.
+type CheckState struct {
+	// Status is "pass" if all the assertions of the check passed, "fail"
+	// if any failed or a data source of the check couldn't be read, and
+	// "unknown" if any couldn't be checked yet.
+	Status ConditionStatus `json:"status"`
+
+	// FailureMessages are the error messages of the failures, if any.
+	FailureMessages []string `json:"failure_messages,omitempty"`
+}
+
 // ModuleState is used to track all the state relevant to a single
 // module. Previous to Terraform 0.3, all state belonged to the "root"
 // module.
@@ -994,6 +1055,10 @@ type ModuleState struct {
 	// about the 1:1 case.
 	Resources map[string]*ResourceState `json:"resources"`
 
+	// Checks is the status of each check block in the module, keyed by
+	// the name of the check, as of the last plan or apply.
+	Checks map[string]*CheckState `json:"checks,omitempty"`
+
 	// Dependencies are a list of things that this module relies on
 	// existing to remain intact. For example: an module may depend
 	// on a VPC ID given by an aws_vpc resource.
@@ -1034,6 +1099,16 @@ func (m *ModuleState) Equal(other *ModuleState) bool {
 		}
 	}
 
+	// Checks must be equal
+	if len(m.Checks) != len(other.Checks) {
+		return false
+	}
+	for k, v := range m.Checks {
+		if !reflect.DeepEqual(v, other.Checks[k]) {
+			return false
+		}
+	}
+
 	// Dependencies must be equal. This sorts these in place but
 	// this shouldn't cause any problems.
 	sort.Strings(m.Dependencies)
@@ -1138,6 +1213,31 @@ func (m *ModuleState) RemovedOutputs(c *config.Config) []string {
 	return result
 }
 
+// RemovedChecks returns a list of checks that are in the State but aren't
+// present in the configuration itself.
+func (m *ModuleState) RemovedChecks(c *config.Config) []string {
+	m.Lock()
+	defer m.Unlock()
+
+	keys := make(map[string]struct{})
+	for k := range m.Checks {
+		keys[k] = struct{}{}
+	}
+
+	if c != nil {
+		for _, ch := range c.Checks {
+			delete(keys, ch.Name)
+		}
+	}
+
+	result := make([]string, 0, len(keys))
+	for k := range keys {
+		result = append(result, k)
+	}
+
+	return result
+}
+
 // View returns a view with the given resource prefix.
 func (m *ModuleState) View(id string) *ModuleState {
 	if m == nil {
@@ -1479,6 +1579,12 @@ type ResourceState struct {
 	// If the resource block contained a "provider" key, that value will be set here.
 	Provider string `json:"provider"`
 
+	// Group is the ordering group of the resource, from the "group"
+	// lifecycle setting of its configuration. It's kept so that the
+	// resource is still ordered with its group when it's destroyed after
+	// being removed from the configuration.
+	Group string `json:"group,omitempty"`
+
 	mu sync.Mutex
 }
 
@@ -1498,6 +1604,10 @@ func (s *ResourceState) Equal(other *ResourceState) bool {
 		return false
 	}
 
+	if s.Group != other.Group {
+		return false
+	}
+
 	// Dependencies must be equal
 	sort.Strings(s.Dependencies)
 	sort.Strings(other.Dependencies)
@@ -1883,8 +1993,18 @@ func ReadState(src io.Reader) (*State, error) {
 		return nil, err
 	}
 
-	// If we are JSON we buffer the whole thing in memory so we can read it twice.
-	// This is suboptimal, but will work for now.
+	if peekStateVersion(buf) == StateVersion {
+		// A current state is decoded as it's read, without holding the
+		// whole file in memory.
+		v3State, err := readStateV3(buf)
+		if err != nil {
+			return nil, err
+		}
+		return finishReadState(v3State)
+	}
+
+	// Older and unrecognized states are buffered in memory so that they
+	// can be read twice, first to find their version.
 	jsonBytes, err := ioutil.ReadAll(buf)
 	if err != nil {
 		return nil, fmt.Errorf("Reading state file failed: %v", err)
@@ -1947,6 +2067,11 @@ func ReadState(src io.Reader) (*State, error) {
 		panic("resulting state in load not set, assertion failed")
 	}
 
+	return finishReadState(result)
+}
+
+// finishReadState prunes and validates a state that has been read.
+func finishReadState(result *State) (*State, error) {
 	// Prune the state when read it. Its possible to write unpruned states or
 	// for a user to make a state unpruned (nil-ing a module state for example).
 	result.prune()
@@ -2009,11 +2134,60 @@ func ReadStateV2(jsonBytes []byte) (*State, error) {
 }
 
 func ReadStateV3(jsonBytes []byte) (*State, error) {
+	return readStateV3(bytes.NewReader(jsonBytes))
+}
+
+// stateVersionPrefix matches the start of a state that begins with its
+// version, as those written by WriteState do.
+var stateVersionPrefix = regexp.MustCompile(`^\s*\{\s*"version"\s*:\s*(\d+)\s*[,}]`)
+
+// peekStateVersion returns the version of the state being read from the
+// given reader if the state begins with it, without consuming any of it,
+// or -1 if it doesn't.
+func peekStateVersion(buf *bufio.Reader) int {
+	// Peek returns what it can along with an error for a short state.
+	start, _ := buf.Peek(64)
+	m := stateVersionPrefix.FindSubmatch(start)
+	if m == nil {
+		return -1
+	}
+	v, err := strconv.Atoi(string(m[1]))
+	if err != nil {
+		return -1
+	}
+	return v
+}
+
+// readStateV3 decodes a version 3 state as it's read from the given reader.
+func readStateV3(src io.Reader) (*State, error) {
+	// The sum of what's read is compared with that of the state written
+	// back out, to detect any changes in normalization.
+	readSum := sha256.New()
+	tee := io.TeeReader(src, readSum)
+
 	state := &State{}
-	if err := json.Unmarshal(jsonBytes, state); err != nil {
+	dec := json.NewDecoder(tee)
+	if err := dec.Decode(state); err != nil {
 		return nil, fmt.Errorf("Decoding state file failed: %v", err)
 	}
 
+	// As with json.Unmarshal, only whitespace can follow the state.
+	rest := bufio.NewReader(io.MultiReader(dec.Buffered(), tee))
+	for {
+		c, err := rest.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Reading state file failed: %v", err)
+		}
+		switch c {
+		case ' ', '\t', '\r', '\n':
+		default:
+			return nil, fmt.Errorf("Decoding state file failed: invalid character %q after top-level value", c)
+		}
+	}
+
 	// Check the version, this to ensure we don't read a future
 	// version that we don't understand
 	if state.Version > StateVersion {
@@ -2043,13 +2217,13 @@ func ReadStateV3(jsonBytes []byte) (*State, error) {
 	// Now we write the state back out to detect any changes in normaliztion.
 	// If our state is now written out differently, bump the serial number to
 	// prevent conflicts.
-	var buf bytes.Buffer
-	err := WriteState(state, &buf)
+	writeSum := sha256.New()
+	err := WriteState(state, writeSum)
 	if err != nil {
 		return nil, err
 	}
 
-	if !bytes.Equal(jsonBytes, buf.Bytes()) {
+	if !bytes.Equal(readSum.Sum(nil), writeSum.Sum(nil)) {
 		log.Println("[INFO] state modified during read or write. incrementing serial number")
 		state.Serial++
 	}
@@ -2058,49 +2232,20 @@ func ReadStateV3(jsonBytes []byte) (*State, error) {
 }
 
 // WriteState writes a state somewhere in a binary format.
+//
+// The state is encoded a part at a time as it is written, rather than in
+// memory first. Use a StateEncoder to write a state repeatedly.
 func WriteState(d *State, dst io.Writer) error {
 	// writing a nil state is a noop.
 	if d == nil {
 		return nil
 	}
 
-	// make sure we have no uninitialized fields
-	d.init()
-
-	// Make sure it is sorted
-	d.sort()
-
-	// Ensure the version is set
-	d.Version = StateVersion
-
-	// If the TFVersion is set, verify it. We used to just set the version
-	// here, but this isn't safe since it changes the MD5 sum on some remote
-	// state storage backends such as Atlas. We now leave it be if needed.
-	if d.TFVersion != "" {
-		if _, err := version.NewVersion(d.TFVersion); err != nil {
-			return fmt.Errorf(
-				"Error writing state, invalid version: %s\n\n"+
-					"The Terraform version when writing the state must be a semantic\n"+
-					"version.",
-				d.TFVersion)
-		}
-	}
-
-	// Encode the data in a human-friendly way
-	data, err := json.MarshalIndent(d, "", "    ")
-	if err != nil {
-		return fmt.Errorf("Failed to encode state: %s", err)
-	}
-
-	// We append a newline to the data because MarshalIndent doesn't
-	data = append(data, '\n')
-
-	// Write the data out to the dst
-	if _, err := io.Copy(dst, bytes.NewReader(data)); err != nil {
-		return fmt.Errorf("Failed to write state: %v", err)
+	if err := prepareStateWrite(d); err != nil {
+		return err
 	}
 
-	return nil
+	return newStateWriter(dst).write(d)
 }
 
 // resourceNameSort implements the sort.Interface to sort name parts lexically for
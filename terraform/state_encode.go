@@ -0,0 +1,414 @@
+package terraform
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-version"
+)
+
+// stateIndent is the indentation of each level of the JSON encoding of a
+// state.
+const stateIndent = "    "
+
+// StateEncoder writes states in the same format as WriteState.
+//
+// An encoder remembers the encoding of each resource instance that it has
+// written, and only encodes again the instances that have changed since the
+// last state it wrote. This makes writing large states repeatedly, as is
+// done after each resource is applied, much cheaper than with WriteState.
+// An encoder is safe for concurrent use.
+type StateEncoder struct {
+	mu    sync.Mutex
+	cache map[string]*encodedInstance
+}
+
+// encodedInstance is the encoding of a resource instance, along with the
+// sum of its content that it was encoded from.
+type encodedInstance struct {
+	sum  [sha256.Size]byte
+	data []byte
+}
+
+// NewStateEncoder returns a StateEncoder that hasn't written any states.
+func NewStateEncoder() *StateEncoder {
+	return &StateEncoder{
+		cache: make(map[string]*encodedInstance),
+	}
+}
+
+// Encode writes the given state to the given writer, in the same way as
+// WriteState.
+func (e *StateEncoder) Encode(d *State, dst io.Writer) error {
+	// writing a nil state is a noop.
+	if d == nil {
+		return nil
+	}
+	if err := prepareStateWrite(d); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	w := newStateWriter(dst)
+	w.cache = e.cache
+	w.seen = make(map[string]*encodedInstance)
+	if err := w.write(d); err != nil {
+		// The cache can't be trusted to match what was written.
+		e.cache = make(map[string]*encodedInstance)
+		return err
+	}
+
+	// Instances that weren't in this state are forgotten.
+	e.cache = w.seen
+	return nil
+}
+
+// prepareStateWrite prepares the given state to be written, and verifies
+// that it can be.
+func prepareStateWrite(d *State) error {
+	// make sure we have no uninitialized fields
+	d.init()
+
+	// Make sure it is sorted
+	d.sort()
+
+	// Ensure the version is set
+	d.Version = StateVersion
+
+	// If the TFVersion is set, verify it. We used to just set the version
+	// here, but this isn't safe since it changes the MD5 sum on some remote
+	// state storage backends such as Atlas. We now leave it be if needed.
+	if d.TFVersion != "" {
+		if _, err := version.NewVersion(d.TFVersion); err != nil {
+			return fmt.Errorf(
+				"Error writing state, invalid version: %s\n\n"+
+					"The Terraform version when writing the state must be a semantic\n"+
+					"version.",
+				d.TFVersion)
+		}
+	}
+
+	return nil
+}
+
+// stateWriter streams the JSON encoding of a state to a writer, one part at
+// a time, producing exactly the same document as encoding the whole state
+// with json.MarshalIndent.
+type stateWriter struct {
+	w   *bufio.Writer
+	err error
+
+	// cache holds the encodings of resource instances that can be reused,
+	// keyed by the module, resource and position of each instance, and seen
+	// is filled with the encodings of the instances that are written. Both are nil if instances aren't
+	// cached.
+	cache map[string]*encodedInstance
+	seen  map[string]*encodedInstance
+}
+
+func newStateWriter(dst io.Writer) *stateWriter {
+	return &stateWriter{w: bufio.NewWriterSize(dst, 64*1024)}
+}
+
+// write writes the given state, followed by a newline, and flushes the
+// writer.
+func (w *stateWriter) write(d *State) error {
+	w.state(d)
+	w.raw("\n")
+	if w.err != nil {
+		return fmt.Errorf("Failed to encode state: %s", w.err)
+	}
+	if err := w.w.Flush(); err != nil {
+		return fmt.Errorf("Failed to write state: %v", err)
+	}
+	return nil
+}
+
+func (w *stateWriter) state(d *State) {
+	p := stateIndent
+	o := w.object()
+	o.field(p, "version", d.Version)
+	if d.TFVersion != "" {
+		o.field(p, "terraform_version", d.TFVersion)
+	}
+	o.field(p, "serial", d.Serial)
+	o.field(p, "lineage", d.Lineage)
+	if d.Remote != nil {
+		o.field(p, "remote", d.Remote)
+	}
+	if d.Backend != nil {
+		o.field(p, "backend", d.Backend)
+	}
+	if len(d.Metadata) > 0 {
+		o.field(p, "metadata", d.Metadata)
+	}
+	if d.Fingerprint != nil {
+		o.field(p, "fingerprint", d.Fingerprint)
+	}
+
+	o.name(p, "modules")
+	switch {
+	case d.Modules == nil:
+		w.raw("null")
+	case len(d.Modules) == 0:
+		w.raw("[]")
+	default:
+		w.raw("[")
+		for i, m := range d.Modules {
+			if i > 0 {
+				w.raw(",")
+			}
+			w.raw("\n" + p + stateIndent)
+			w.module(p+stateIndent, m)
+		}
+		w.raw("\n" + p + "]")
+	}
+	o.end("")
+}
+
+func (w *stateWriter) module(prefix string, m *ModuleState) {
+	if m == nil {
+		w.raw("null")
+		return
+	}
+
+	p := prefix + stateIndent
+	o := w.object()
+	o.field(p, "path", m.Path)
+	o.field(p, "outputs", m.Outputs)
+
+	o.name(p, "resources")
+	switch {
+	case m.Resources == nil:
+		w.raw("null")
+	case len(m.Resources) == 0:
+		w.raw("{}")
+	default:
+		keys := make([]string, 0, len(m.Resources))
+		for k := range m.Resources {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		path := strings.Join(m.Path, ".")
+		ro := w.object()
+		for _, k := range keys {
+			ro.name(p+stateIndent, k)
+			w.resource(p+stateIndent, path+"\x00"+k, m.Resources[k])
+		}
+		ro.end(p)
+	}
+
+	if len(m.Checks) > 0 {
+		o.field(p, "checks", m.Checks)
+	}
+	o.field(p, "depends_on", m.Dependencies)
+	o.end(prefix)
+}
+
+func (w *stateWriter) resource(prefix, key string, r *ResourceState) {
+	if r == nil {
+		w.raw("null")
+		return
+	}
+
+	p := prefix + stateIndent
+	o := w.object()
+	o.field(p, "type", r.Type)
+	o.field(p, "depends_on", r.Dependencies)
+
+	o.name(p, "primary")
+	w.instance(p, key+"\x00primary", r.Primary)
+
+	o.name(p, "deposed")
+	switch {
+	case r.Deposed == nil:
+		w.raw("null")
+	case len(r.Deposed) == 0:
+		w.raw("[]")
+	default:
+		w.raw("[")
+		for i, is := range r.Deposed {
+			if i > 0 {
+				w.raw(",")
+			}
+			w.raw("\n" + p + stateIndent)
+			w.instance(p+stateIndent, key+"\x00deposed."+strconv.Itoa(i), is)
+		}
+		w.raw("\n" + p + "]")
+	}
+
+	o.field(p, "provider", r.Provider)
+	if r.Group != "" {
+		o.field(p, "group", r.Group)
+	}
+	o.end(prefix)
+}
+
+// instance writes the given resource instance, reusing its cached encoding
+// if it hasn't changed. The key identifies the instance within the state,
+// and so also determines the indentation of its encoding.
+func (w *stateWriter) instance(prefix, key string, is *InstanceState) {
+	if is == nil || w.cache == nil {
+		w.value(prefix, is)
+		return
+	}
+
+	sum, ok := instanceSum(is)
+	if !ok {
+		w.value(prefix, is)
+		return
+	}
+	if c := w.cache[key]; c != nil && c.sum == sum {
+		w.seen[key] = c
+		w.rawBytes(c.data)
+		return
+	}
+
+	data, err := json.MarshalIndent(is, prefix, stateIndent)
+	if err != nil {
+		w.fail(err)
+		return
+	}
+	w.seen[key] = &encodedInstance{sum: sum, data: data}
+	w.rawBytes(data)
+}
+
+// instanceSum returns a sum of the persisted content of the given instance,
+// which changes whenever its encoding does. It returns false if the sum
+// can't be calculated, so the instance must always be encoded.
+//
+// Since a matching sum means the cached encoding is written as is, the sum
+// is a SHA-256 hash, and each field is prefixed by its length so that no two
+// different instances hash the same content.
+func instanceSum(is *InstanceState) ([sha256.Size]byte, bool) {
+	var sum [sha256.Size]byte
+	h := sha256.New()
+	writeSumField(h, is.ID)
+	if is.Tainted {
+		h.Write([]byte{1})
+	} else {
+		h.Write([]byte{0})
+	}
+
+	if is.Attributes == nil {
+		h.Write([]byte{0})
+	} else {
+		h.Write([]byte{1})
+	}
+	keys := make([]string, 0, len(is.Attributes))
+	for k := range is.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	writeSumLen(h, len(keys))
+	for _, k := range keys {
+		writeSumField(h, k)
+		writeSumField(h, is.Attributes[k])
+	}
+
+	meta, err := json.Marshal(is.Meta)
+	if err != nil {
+		return sum, false
+	}
+	writeSumField(h, string(meta))
+
+	h.Sum(sum[:0])
+	return sum, true
+}
+
+// writeSumField writes the given field to the hash of instanceSum, prefixed
+// by its length.
+func writeSumField(h hash.Hash, s string) {
+	writeSumLen(h, len(s))
+	io.WriteString(h, s)
+}
+
+// writeSumLen writes the given length to the hash of instanceSum.
+func writeSumLen(h hash.Hash, n int) {
+	var buf [binary.MaxVarintLen64]byte
+	h.Write(buf[:binary.PutUvarint(buf[:], uint64(n))])
+}
+
+func (w *stateWriter) raw(s string) {
+	if w.err == nil {
+		_, w.err = w.w.WriteString(s)
+	}
+}
+
+func (w *stateWriter) rawBytes(b []byte) {
+	if w.err == nil {
+		_, w.err = w.w.Write(b)
+	}
+}
+
+func (w *stateWriter) fail(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// value writes the JSON encoding of the given value, with each line after
+// the first indented with the given prefix.
+func (w *stateWriter) value(prefix string, v interface{}) {
+	if w.err != nil {
+		return
+	}
+	data, err := json.MarshalIndent(v, prefix, stateIndent)
+	if err != nil {
+		w.fail(err)
+		return
+	}
+	w.rawBytes(data)
+}
+
+// object starts writing a JSON object.
+func (w *stateWriter) object() *objectWriter {
+	w.raw("{")
+	return &objectWriter{w: w}
+}
+
+// objectWriter writes the members of a JSON object.
+type objectWriter struct {
+	w *stateWriter
+	n int
+}
+
+// name writes the name of the next member, indented with the given prefix,
+// to be followed by its value.
+func (o *objectWriter) name(prefix, name string) {
+	if o.n > 0 {
+		o.w.raw(",")
+	}
+	o.n++
+	o.w.raw("\n" + prefix)
+	o.w.value("", name)
+	o.w.raw(": ")
+}
+
+// field writes a member with the given value.
+func (o *objectWriter) field(prefix, name string, v interface{}) {
+	o.name(prefix, name)
+	o.w.value(prefix, v)
+}
+
+// end finishes the object, with its closing brace indented with the given
+// prefix.
+func (o *objectWriter) end(prefix string) {
+	if o.n == 0 {
+		o.w.raw("}")
+		return
+	}
+	o.w.raw("\n" + prefix + "}")
+}
@@ -0,0 +1,229 @@
+package terraform
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testStateEncodeFixture() *State {
+	return &State{
+		Version:   StateVersion,
+		TFVersion: "0.11.0",
+		Serial:    3,
+		Lineage:   "abc",
+		Backend: &BackendState{
+			Type:   "local",
+			Config: map[string]interface{}{"path": "x.tfstate"},
+		},
+		Metadata: map[string]string{"owner": "<ops & dev>"},
+		Fingerprint: &Fingerprint{
+			TerraformVersion: "0.11.0",
+			Platform:         "linux_amd64",
+			Providers: map[string]*ProviderFingerprint{
+				"aws": {Version: "1.0.0", SHA256: "abc"},
+			},
+			Variables: map[string]string{"region": "def"},
+		},
+		Modules: []*ModuleState{
+			{
+				Path: rootModulePath,
+				Outputs: map[string]*OutputState{
+					"ip": {Type: "string", Value: "10.0.0.1", Sensitive: true},
+				},
+				Resources: map[string]*ResourceState{
+					"aws_instance.web.10": {
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID: "i-10",
+							Attributes: map[string]string{
+								"id":        "i-10",
+								"tags.%":    "1",
+								"tags.Name": "web \"10\"",
+							},
+							Meta: map[string]interface{}{"schema_version": "1"},
+						},
+						Provider: "provider.aws",
+					},
+					"aws_instance.web.2": {
+						Type:         "aws_instance",
+						Dependencies: []string{"aws_vpc.main"},
+						Primary: &InstanceState{
+							ID:         "i-2",
+							Attributes: map[string]string{"id": "i-2"},
+							Tainted:    true,
+						},
+						Deposed: []*InstanceState{
+							{ID: "i-old", Attributes: map[string]string{"id": "i-old"}},
+							{ID: "i-older"},
+						},
+						Provider: "provider.aws",
+						Group:    "cluster",
+					},
+				},
+			},
+			{
+				Path: []string{"root", "child"},
+				Checks: map[string]*CheckState{
+					"ok": {Status: ConditionPass},
+				},
+			},
+		},
+	}
+}
+
+func TestWriteState_matchesMarshalIndent(t *testing.T) {
+	states := map[string]*State{
+		"full":  testStateEncodeFixture(),
+		"empty": &State{},
+		"new":   NewState(),
+	}
+
+	for name, s := range states {
+		t.Run(name, func(t *testing.T) {
+			var got bytes.Buffer
+			if err := WriteState(s, &got); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			want, err := json.MarshalIndent(s, "", "    ")
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			want = append(want, '\n')
+
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Fatalf("wrong encoding\ngot:\n%s\nwant:\n%s", got.String(), want)
+			}
+		})
+	}
+}
+
+func TestStateEncoder(t *testing.T) {
+	e := NewStateEncoder()
+	s := testStateEncodeFixture()
+
+	encode := func() string {
+		var buf bytes.Buffer
+		if err := e.Encode(s, &buf); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		var want bytes.Buffer
+		if err := WriteState(s, &want); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if buf.String() != want.String() {
+			t.Fatalf("wrong encoding\ngot:\n%s\nwant:\n%s", buf.String(), want.String())
+		}
+		return buf.String()
+	}
+
+	encode()
+	if got, want := len(e.cache), 4; got != want {
+		t.Fatalf("wrong number of cached instances %d; want %d", got, want)
+	}
+	cached := e.cache["root\x00aws_instance.web.2\x00primary"]
+
+	// Unchanged instances are reused, and changed ones encoded again.
+	s.Modules[0].Resources["aws_instance.web.10"].Primary.Attributes["tags.Name"] = "changed"
+	if out := encode(); !strings.Contains(out, `"tags.Name": "changed"`) {
+		t.Fatalf("changed attribute not written:\n%s", out)
+	}
+	if e.cache["root\x00aws_instance.web.2\x00primary"] != cached {
+		t.Fatal("unchanged instance was encoded again")
+	}
+
+	// Tainting is a change too.
+	s.Modules[0].Resources["aws_instance.web.2"].Primary.Tainted = false
+	encode()
+	if e.cache["root\x00aws_instance.web.2\x00primary"] == cached {
+		t.Fatal("changed instance wasn't encoded again")
+	}
+
+	// Removed instances are forgotten.
+	delete(s.Modules[0].Resources, "aws_instance.web.2")
+	encode()
+	if got, want := len(e.cache), 1; got != want {
+		t.Fatalf("wrong number of cached instances %d; want %d", got, want)
+	}
+}
+
+func TestInstanceSum_delimiters(t *testing.T) {
+	// Each pair encodes differently, even though the concatenation of their
+	// fields is the same.
+	cases := map[string][2]*InstanceState{
+		"attributes": {
+			{ID: "foo", Attributes: map[string]string{"a\x00b": "c"}},
+			{ID: "foo", Attributes: map[string]string{"a": "b\x00c"}},
+		},
+		"id": {
+			{ID: "foo\x00", Attributes: map[string]string{"a": "b"}},
+			{ID: "foo", Attributes: map[string]string{"\x00a": "b"}},
+		},
+		"nil attributes": {
+			{ID: "foo"},
+			{ID: "foo", Attributes: map[string]string{}},
+		},
+	}
+	for name, tc := range cases {
+		a, ok := instanceSum(tc[0])
+		if !ok {
+			t.Fatalf("%s: no sum", name)
+		}
+		b, ok := instanceSum(tc[1])
+		if !ok {
+			t.Fatalf("%s: no sum", name)
+		}
+		if a == b {
+			t.Errorf("%s: different instances have the same sum", name)
+		}
+	}
+}
+
+func TestReadState_stream(t *testing.T) {
+	s := testStateEncodeFixture()
+	var buf bytes.Buffer
+	if err := WriteState(s, &buf); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ReadState(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !actual.Equal(s) {
+		t.Fatalf("bad:\n%s", actual)
+	}
+
+	// The state was already normalized, so its serial is unchanged.
+	if actual.Serial != s.Serial {
+		t.Fatalf("wrong serial %d; want %d", actual.Serial, s.Serial)
+	}
+
+	// The state is normalized differently, so its serial is incremented.
+	compact := &bytes.Buffer{}
+	if err := json.Compact(compact, buf.Bytes()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	actual, err = ReadState(compact)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual.Serial != s.Serial+1 {
+		t.Fatalf("wrong serial %d; want %d", actual.Serial, s.Serial+1)
+	}
+}
+
+func TestReadState_streamTrailingData(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteState(NewState(), &buf); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	buf.WriteString("{}\n")
+
+	if _, err := ReadState(&buf); err == nil {
+		t.Fatal("expected error for data after the state")
+	}
+}
@@ -0,0 +1,25 @@
+variable "status" {
+    default = "ok"
+}
+
+resource "aws_instance" "foo" {
+    num = "2"
+}
+
+check "health" {
+    data "aws_data_source" "status" {
+        foo = "${aws_instance.foo.id}"
+    }
+
+    assert {
+        condition     = "${data.aws_data_source.status.foo == var.status}"
+        error_message = "The status must be ${var.status}."
+    }
+}
+
+check "size" {
+    assert {
+        condition     = "${aws_instance.foo.num > 1}"
+        error_message = "The instance must have more than one."
+    }
+}
@@ -0,0 +1,11 @@
+resource "aws_instance" "foo" {
+  compute = "foo"
+}
+
+resource "aws_instance" "bar" {
+  count = "${aws_instance.foo.foo}"
+}
+
+output "bar_ids" {
+  value = "${join(",", aws_instance.bar.*.id)}"
+}
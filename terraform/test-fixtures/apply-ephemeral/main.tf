@@ -0,0 +1,11 @@
+ephemeral "vault_token" "deploy" {
+  role = "deploy"
+}
+
+provider "aws" {
+  token = "${ephemeral.vault_token.deploy.value}"
+}
+
+resource "aws_instance" "foo" {
+  num = "2"
+}
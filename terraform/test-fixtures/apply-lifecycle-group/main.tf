@@ -0,0 +1,8 @@
+resource "aws_instance" "new" {
+  lifecycle {
+    group                = "replacement"
+    create_after_destroy = ["legacy"]
+  }
+}
+
+resource "aws_instance" "other" {}
@@ -0,0 +1,9 @@
+resource "aws_instance" "foo" {
+    num = "2"
+
+    lifecycle {
+        timeouts {
+            create = "10ms"
+        }
+    }
+}
@@ -0,0 +1,10 @@
+resource "aws_instance" "foo" {
+    foo = "bar"
+
+    lifecycle {
+        postcondition {
+            condition     = "${self.foo == "baz"}"
+            error_message = "The instance must be baz."
+        }
+    }
+}
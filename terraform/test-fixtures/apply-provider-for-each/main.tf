@@ -0,0 +1,18 @@
+provider "aws" {
+  alias  = "region"
+  region = "${each.value}"
+  name   = "${each.key}"
+
+  for_each = {
+    east = "us-east-1"
+    west = "us-west-2"
+  }
+}
+
+resource "aws_instance" "east" {
+  provider = "aws.region[east]"
+}
+
+resource "aws_instance" "west" {
+  provider = "aws.region[west]"
+}
@@ -0,0 +1,9 @@
+variable "n" {}
+
+resource "aws_instance" "x" {
+  count = "${var.n - 2}"
+}
+
+output "n" {
+  value = "${var.n - 1}"
+}
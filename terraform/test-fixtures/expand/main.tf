@@ -0,0 +1,32 @@
+variable "n" {
+  default = 2
+}
+
+locals {
+  m = "${var.n + 1}"
+}
+
+resource "aws_instance" "a" {
+  count = "${var.n}"
+}
+
+resource "aws_instance" "b" {
+  count = "${local.m}"
+}
+
+resource "aws_instance" "c" {}
+
+data "aws_data_source" "d" {}
+
+resource "aws_instance" "e" {
+  count = "${length(data.aws_data_source.d.ids)}"
+}
+
+module "child" {
+  source = "./child"
+  n      = "${local.m}"
+}
+
+resource "aws_instance" "f" {
+  count = "${module.child.n}"
+}
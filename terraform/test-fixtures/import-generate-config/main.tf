@@ -0,0 +1,15 @@
+provider "aws" {}
+
+resource "aws_instance" "foo" {
+  foo = "bar"
+}
+
+import {
+  to = "aws_instance.foo"
+  id = "i-abc123"
+}
+
+import {
+  to = "aws_instance.web"
+  id = "i-def456"
+}
@@ -0,0 +1,28 @@
+variable "size" {
+    default = 2
+}
+
+resource "aws_instance" "foo" {
+    num = "${var.size}"
+
+    lifecycle {
+        precondition {
+            condition     = "${var.size > 1}"
+            error_message = "The size must be more than 1."
+        }
+
+        postcondition {
+            condition     = "${self.num == 2}"
+            error_message = "The instance must have the given size."
+        }
+    }
+}
+
+output "num" {
+    value = "${aws_instance.foo.num}"
+
+    precondition {
+        condition     = "${var.size < 10}"
+        error_message = "The size must be less than 10."
+    }
+}
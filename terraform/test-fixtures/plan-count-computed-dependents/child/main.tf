@@ -0,0 +1,9 @@
+variable "value" {}
+
+resource "aws_instance" "child" {
+  value = "${var.value}"
+}
+
+output "value" {
+  value = "${var.value}"
+}
@@ -0,0 +1,34 @@
+resource "aws_instance" "foo" {
+  compute = "foo"
+}
+
+resource "aws_instance" "bar" {
+  count = "${aws_instance.foo.foo}"
+}
+
+resource "aws_instance" "baz" {
+  value = "${join(",", aws_instance.bar.*.id)}"
+}
+
+resource "aws_instance" "qux" {
+  depends_on = ["aws_instance.baz"]
+}
+
+locals {
+  bar_ids = "${join(",", aws_instance.bar.*.id)}"
+}
+
+resource "aws_instance" "local" {
+  value = "${local.bar_ids}"
+}
+
+module "child" {
+  source = "./child"
+  value  = "${aws_instance.bar.0.id}"
+}
+
+resource "aws_instance" "output" {
+  value = "${module.child.value}"
+}
+
+resource "aws_instance" "independent" {}
@@ -0,0 +1,3 @@
+output "addr" {
+  value = "10.0.0.1"
+}
@@ -0,0 +1,21 @@
+locals {
+  name = "web"
+}
+
+module "child" {
+  source = "./child"
+}
+
+resource "aws_instance" "bar" {}
+
+resource "aws_instance" "foo" {
+  provisioner "shell" {
+    command = "deregister ${local.name} ${module.child.addr} ${self.id}"
+    when    = "destroy"
+  }
+
+  provisioner "shell" {
+    command = "notify ${aws_instance.bar.id}"
+    when    = "destroy"
+  }
+}
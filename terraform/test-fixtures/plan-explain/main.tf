@@ -0,0 +1,13 @@
+variable "ami" {
+  default = "ami-1"
+}
+
+resource "aws_instance" "foo" {
+  num     = "2"
+  compute = "foo"
+}
+
+resource "aws_instance" "bar" {
+  ami = "${var.ami}"
+  foo = "${aws_instance.foo.foo}"
+}
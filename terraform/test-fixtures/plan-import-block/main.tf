@@ -0,0 +1,8 @@
+resource "aws_instance" "foo" {
+  foo = "bar"
+}
+
+import {
+  to = "aws_instance.foo"
+  id = "i-abc123"
+}
@@ -0,0 +1,9 @@
+resource "aws_instance" "bar" {
+  count = 2
+  foo   = "bar"
+}
+
+moved {
+  from = "aws_instance.foo"
+  to   = "aws_instance.bar"
+}
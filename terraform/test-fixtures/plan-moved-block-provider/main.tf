@@ -0,0 +1,8 @@
+resource "vendorb_instance" "foo" {
+  foo = "bar"
+}
+
+moved {
+  from = "vendora_instance.foo"
+  to   = "vendorb_instance.foo"
+}
@@ -0,0 +1,4 @@
+resource "aws_instance" "foo" {
+  foo = "${provider::strings::upper("hello")}"
+  bar = "${join(",", provider::strings::split("a b"))}"
+}
@@ -0,0 +1,7 @@
+resource "aws_instance" "bar" {
+  foo = "bar"
+}
+
+removed {
+  from = "aws_instance.foo"
+}
@@ -0,0 +1,23 @@
+variable "password" {
+  secret {
+    backend = "test"
+    key     = "db"
+  }
+}
+
+variable "size" {
+  default = "2"
+}
+
+resource "aws_instance" "foo" {
+  foo = "${var.password}"
+  num = "${var.size}"
+}
+
+output "password" {
+  value = "${var.password}"
+}
+
+output "size" {
+  value = "${var.size}"
+}
@@ -0,0 +1,9 @@
+variable "value" {}
+
+resource "aws_instance" "e" {
+  foo = "${var.value}"
+}
+
+output "id" {
+  value = "${aws_instance.e.id}"
+}
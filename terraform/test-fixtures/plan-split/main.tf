@@ -0,0 +1,16 @@
+resource "aws_instance" "a" {}
+
+resource "aws_instance" "b" {
+  foo = "${aws_instance.a.id}"
+}
+
+resource "aws_instance" "c" {}
+
+module "child" {
+  source = "./child"
+  value  = "${aws_instance.c.id}"
+}
+
+resource "aws_instance" "d" {
+  foo = "${module.child.id}"
+}
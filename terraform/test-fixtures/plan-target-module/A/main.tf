@@ -0,0 +1,9 @@
+resource "aws_instance" "foo" {
+    foo = "bar"
+}
+
+module "C" {
+    source = "./C"
+}
+
+output "value" { value = "${aws_instance.foo.id}" }
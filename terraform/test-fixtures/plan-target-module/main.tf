@@ -0,0 +1,20 @@
+module "A" {
+    source = "./A"
+}
+
+module "B" {
+    source = "./B"
+    input  = "${module.A.value}"
+}
+
+locals {
+    a = "${module.A.value}"
+}
+
+resource "aws_instance" "root" {
+    foo = "${local.a}"
+}
+
+resource "aws_instance" "unrelated" {
+    foo = "bar"
+}
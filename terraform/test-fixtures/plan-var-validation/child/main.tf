@@ -0,0 +1,10 @@
+variable "name" {
+  validation {
+    condition     = "${length(var.name) > 0}"
+    error_message = "The name must not be empty."
+  }
+}
+
+output "name" {
+  value = "${var.name}"
+}
@@ -0,0 +1,36 @@
+variable "enable_tls" {
+  default = "false"
+}
+
+variable "certificate" {
+  default = ""
+
+  validation {
+    condition     = "${var.enable_tls == "false" || var.certificate != ""}"
+    error_message = "A certificate is required when enable_tls is true."
+  }
+}
+
+variable "size" {
+  default = "2"
+
+  validation {
+    condition     = "${local.size <= local.max_size}"
+    error_message = "The size must be at most ${local.max_size}."
+  }
+}
+
+locals {
+  max_size = 10
+  size     = "${var.size}"
+}
+
+resource "aws_instance" "foo" {
+  foo = "${var.certificate}"
+  num = "${local.size}"
+}
+
+module "child" {
+  source = "./child"
+  name   = "web"
+}
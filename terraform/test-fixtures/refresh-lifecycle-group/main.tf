@@ -0,0 +1,5 @@
+resource "aws_instance" "web" {
+  lifecycle {
+    group = "legacy"
+  }
+}
@@ -0,0 +1,9 @@
+provider "aws" {
+  retry {
+    max_attempts = 2
+    min_backoff  = "0s"
+    max_backoff  = "0s"
+  }
+}
+
+resource "aws_instance" "web" {}
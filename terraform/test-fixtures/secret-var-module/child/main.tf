@@ -0,0 +1,10 @@
+variable "dsn" {}
+variable "name" {}
+
+output "url" {
+  value = "${var.dsn}/${var.name}"
+}
+
+output "name" {
+  value = "${var.name}"
+}
@@ -0,0 +1,24 @@
+variable "password" {
+  secret {
+    backend = "test"
+    key     = "db"
+  }
+}
+
+locals {
+  dsn = "postgres://admin:${var.password}@db"
+}
+
+module "child" {
+  source = "./child"
+  dsn    = "${local.dsn}"
+  name   = "app"
+}
+
+output "url" {
+  value = "${module.child.url}"
+}
+
+output "name" {
+  value = "${module.child.name}"
+}
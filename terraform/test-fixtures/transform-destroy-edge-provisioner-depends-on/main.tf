@@ -0,0 +1,9 @@
+resource "test" "A" {
+  provisioner "local-exec" {
+    command    = "deregister"
+    when       = "destroy"
+    depends_on = ["test.B"]
+  }
+}
+
+resource "test" "B" {}
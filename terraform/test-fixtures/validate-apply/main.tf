@@ -0,0 +1,7 @@
+resource "aws_instance" "foo" {
+    require_new = "yes"
+}
+
+resource "aws_instance" "bar" {
+    num = "2"
+}
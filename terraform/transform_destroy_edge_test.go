@@ -78,6 +78,26 @@ func TestDestroyEdgeTransformer_selfRef(t *testing.T) {
 	}
 }
 
+func TestDestroyEdgeTransformer_provisionerDependsOn(t *testing.T) {
+	g := Graph{Path: RootModulePath}
+	g.Add(&graphNodeDestroyerTest{AddrString: "test.A"})
+	g.Add(&graphNodeDestroyerTest{AddrString: "test.B"})
+	tf := &DestroyEdgeTransformer{
+		Module: testModule(t, "transform-destroy-edge-provisioner-depends-on"),
+	}
+	if err := tf.Transform(&g); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// test.B is destroyed only once the destroy provisioner of test.A,
+	// which depends on it, has run.
+	actual := strings.TrimSpace(g.String())
+	expected := strings.TrimSpace(testTransformDestroyEdgeProvisionerDependsOnStr)
+	if actual != expected {
+		t.Fatalf("bad:\n\n%s", actual)
+	}
+}
+
 func TestDestroyEdgeTransformer_module(t *testing.T) {
 	g := Graph{Path: RootModulePath}
 	g.Add(&graphNodeDestroyerTest{AddrString: "module.child.aws_instance.b"})
@@ -176,6 +196,12 @@ test.A (destroy)
 test.B (destroy)
 `
 
+const testTransformDestroyEdgeProvisionerDependsOnStr = `
+test.A (destroy)
+test.B (destroy)
+  test.A (destroy)
+`
+
 const testTransformDestroyEdgeCreatorStr = `
 test.A
   test.A (destroy)
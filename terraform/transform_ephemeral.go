@@ -0,0 +1,88 @@
+package terraform
+
+import (
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/dag"
+)
+
+// EphemeralTransformer is a GraphTransformer that adds all the ephemeral
+// resources in the configuration to the graph. It must run before the
+// providers are connected.
+type EphemeralTransformer struct {
+	Module *module.Tree
+}
+
+func (t *EphemeralTransformer) Transform(g *Graph) error {
+	return t.transform(g, t.Module)
+}
+
+func (t *EphemeralTransformer) transform(g *Graph, m *module.Tree) error {
+	// If no config, no ephemeral resources
+	if m == nil {
+		return nil
+	}
+
+	for _, c := range m.Children() {
+		if err := t.transform(g, c); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range m.Config().EphemeralResources {
+		g.Add(&NodeEphemeralResource{
+			PathValue: normalizeModulePath(m.Path()),
+			Config:    r,
+		})
+	}
+
+	return nil
+}
+
+// CloseEphemeralTransformer is a GraphTransformer that adds a node to close
+// each ephemeral resource in the graph after everything else, so that its
+// values are available for the whole walk. It must run after the
+// CloseProviderTransformer, since the provider of an ephemeral resource
+// must stay open until it is closed.
+type CloseEphemeralTransformer struct{}
+
+func (t *CloseEphemeralTransformer) Transform(g *Graph) error {
+	var closers []*nodeCloseEphemeralResource
+	for _, v := range g.Vertices() {
+		if n, ok := v.(*NodeEphemeralResource); ok {
+			closers = append(closers, &nodeCloseEphemeralResource{Open: n})
+		}
+	}
+	if len(closers) == 0 {
+		return nil
+	}
+
+	vertices := g.Vertices()
+	for _, closer := range closers {
+		g.Add(closer)
+	}
+
+	for _, closer := range closers {
+		for _, v := range vertices {
+			switch v.(type) {
+			case *graphNodeCloseProvider, *graphNodeCloseProvisioner:
+				// These must wait for the closer instead
+			default:
+				g.Connect(dag.BasicEdge(closer, v))
+			}
+		}
+
+		// Keep the provider open until the closer is done
+		for _, p := range g.DownEdges(closer.Open).List() {
+			if _, ok := p.(GraphNodeProvider); !ok {
+				continue
+			}
+			for _, v := range g.UpEdges(p).List() {
+				if cp, ok := v.(*graphNodeCloseProvider); ok {
+					g.Connect(dag.BasicEdge(cp, closer))
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package terraform
+
+import (
+	"log"
+	"strings"
+
+	"github.com/hashicorp/terraform/dag"
+)
+
+// GraphNodeLifecycleGroup is implemented by resource nodes that may belong
+// to an ordering group, as declared by the "group" and
+// "create_after_destroy" lifecycle settings.
+type GraphNodeLifecycleGroup interface {
+	// LifecycleGroup returns the ordering group of the resource, or an
+	// empty string if it has none.
+	LifecycleGroup() string
+
+	// CreateAfterDestroyGroups returns the groups whose resources must
+	// all be destroyed before this resource is created or updated.
+	CreateAfterDestroyGroups() []string
+}
+
+// GroupOrderTransformer is a GraphTransformer that makes the creators of
+// resources with create_after_destroy groups depend on the destroyers of
+// every resource in those groups, in the same module.
+//
+// This must be applied after the DestroyEdgeTransformer and the
+// CBDEdgeTransformer, since it adds to the destroy ordering that they
+// create.
+type GroupOrderTransformer struct{}
+
+func (t *GroupOrderTransformer) Transform(g *Graph) error {
+	// Gather the destroyers of each group, keyed by module path and group
+	destroyers := make(map[string][]dag.Vertex)
+	for _, v := range g.Vertices() {
+		dn, ok := v.(GraphNodeDestroyer)
+		if !ok {
+			continue
+		}
+		gn, ok := v.(GraphNodeLifecycleGroup)
+		if !ok {
+			continue
+		}
+
+		addr := dn.DestroyAddr()
+		group := gn.LifecycleGroup()
+		if addr == nil || group == "" {
+			continue
+		}
+
+		key := groupOrderKey(addr.Path, group)
+		destroyers[key] = append(destroyers[key], v)
+	}
+
+	// If nothing in a group is being destroyed, there is nothing to order
+	if len(destroyers) == 0 {
+		return nil
+	}
+
+	for _, v := range g.Vertices() {
+		cn, ok := v.(GraphNodeCreator)
+		if !ok {
+			continue
+		}
+		gn, ok := v.(GraphNodeLifecycleGroup)
+		if !ok {
+			continue
+		}
+
+		addr := cn.CreateAddr()
+		if addr == nil {
+			continue
+		}
+
+		for _, group := range gn.CreateAfterDestroyGroups() {
+			for _, d := range destroyers[groupOrderKey(addr.Path, group)] {
+				log.Printf("[TRACE] GroupOrderTransformer: %q depends on %q (group %q)",
+					dag.VertexName(v), dag.VertexName(d), group)
+				g.Connect(dag.BasicEdge(v, d))
+			}
+		}
+	}
+
+	return nil
+}
+
+func groupOrderKey(path []string, group string) string {
+	return strings.Join(path, ".") + "/" + group
+}
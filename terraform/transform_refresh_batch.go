@@ -0,0 +1,60 @@
+package terraform
+
+import (
+	"log"
+	"sort"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/dag"
+)
+
+// RefreshBatchTransformer is a GraphTransformer that replaces the nodes that
+// refresh the instances of a managed resource that have state with nodes
+// that refresh up to Size of them at a time, so that a provider that
+// supports refreshing in batches is called once for each batch rather than
+// once for each instance.
+//
+// The instances are batched in the order of their addresses.
+type RefreshBatchTransformer struct {
+	Size int
+}
+
+func (t *RefreshBatchTransformer) Transform(g *Graph) error {
+	if t.Size < 2 {
+		return nil
+	}
+
+	var nodes []*NodeRefreshableManagedResourceInstance
+	for _, v := range g.Vertices() {
+		n, ok := v.(*NodeRefreshableManagedResourceInstance)
+		if !ok || n.Addr.Mode != config.ManagedResourceMode || n.ResourceState == nil {
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+	if len(nodes) < 2 {
+		return nil
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Addr.Less(nodes[j].Addr)
+	})
+
+	for len(nodes) > 0 {
+		size := t.Size
+		if size > len(nodes) {
+			size = len(nodes)
+		}
+
+		batch := &NodeRefreshableManagedResourceBatch{Instances: nodes[:size]}
+		for _, n := range batch.Instances {
+			g.Remove(n)
+		}
+		log.Printf("[TRACE] RefreshBatch: refreshing %d instances as %s", size, dag.VertexName(batch))
+		g.Add(batch)
+
+		nodes = nodes[size:]
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package terraform
+
+import (
+	"reflect"
+
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/dag"
+)
+
+// VariableValidationTransformer is a GraphTransformer that adds the
+// validation blocks of all the variables in the configuration to the graph.
+//
+// It must run before ModuleVariableTransformer, so that the module
+// variables that validations refer to aren't pruned.
+type VariableValidationTransformer struct {
+	Module *module.Tree
+}
+
+func (t *VariableValidationTransformer) Transform(g *Graph) error {
+	return t.transform(g, t.Module)
+}
+
+func (t *VariableValidationTransformer) transform(g *Graph, m *module.Tree) error {
+	// If no config, no validations
+	if m == nil {
+		return nil
+	}
+
+	for _, c := range m.Children() {
+		if err := t.transform(g, c); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range m.Config().Variables {
+		if len(v.Validations) == 0 {
+			continue
+		}
+
+		g.Add(&NodeVariableValidation{
+			PathValue: normalizeModulePath(m.Path()),
+			Config:    v,
+		})
+	}
+
+	return nil
+}
+
+// VariableValidationReferenceTransformer is a GraphTransformer that makes
+// the nodes that depend on a variable with validations, directly or through
+// other values such as locals, depend on those validations too, so that the
+// value of a variable isn't used before it's known to be valid. It must run
+// after ReferenceTransformer.
+//
+// The values that a validation itself refers to, such as a local value
+// derived from the variable it validates, must be evaluated before the
+// validation, so they aren't made to depend on it. Neither are other
+// validations, which may refer to each other's variables.
+type VariableValidationReferenceTransformer struct{}
+
+func (t *VariableValidationReferenceTransformer) Transform(g *Graph) error {
+	for _, v := range g.Vertices() {
+		vn, ok := v.(*NodeVariableValidation)
+		if !ok {
+			continue
+		}
+
+		deps, err := g.Ancestors(vn)
+		if err != nil {
+			return err
+		}
+
+		for _, target := range g.Vertices() {
+			if !isValidatedVariable(target, vn) {
+				continue
+			}
+
+			users, err := g.Descendents(target)
+			if err != nil {
+				return err
+			}
+			for _, u := range users.List() {
+				if _, ok := u.(*NodeVariableValidation); ok {
+					continue
+				}
+				if deps.Include(u) {
+					continue
+				}
+
+				g.Connect(dag.BasicEdge(u, vn))
+			}
+		}
+	}
+
+	return nil
+}
+
+// isValidatedVariable returns true if the given vertex is the node of the
+// variable that the given validation node validates.
+func isValidatedVariable(v dag.Vertex, vn *NodeVariableValidation) bool {
+	switch n := v.(type) {
+	case *NodeRootVariable:
+		return len(vn.PathValue) <= 1 && n.Config.Name == vn.Config.Name
+	case *NodeApplyableModuleVariable:
+		return reflect.DeepEqual(normalizeModulePath(n.PathValue), vn.PathValue) &&
+			n.Config.Name == vn.Config.Name
+	default:
+		return false
+	}
+}
@@ -0,0 +1,131 @@
+package terraform
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/hil/ast"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/module"
+)
+
+// UnknownValues records which of the references of the configuration of
+// each resource instance had unknown values during a plan walk, so that the
+// computed attributes of the planned diffs can be traced back to what they
+// were derived from. It is safe for concurrent use.
+type UnknownValues struct {
+	lock sync.Mutex
+
+	// refs is whether the value of each reference was unknown, by instance
+	// and then reference text. A reference that was interpolated more than
+	// once is unknown if it was ever unknown.
+	refs map[string]map[string]bool
+}
+
+// record records the given values of the given variables, interpolated in
+// the given scope, if the scope is that of a resource instance.
+func (u *UnknownValues) record(scope *InterpolationScope, vars map[string]config.InterpolatedVariable, values map[string]ast.Variable) {
+	if u == nil || scope == nil || scope.Resource == nil || scope.ProviderConfig != nil {
+		return
+	}
+	r := scope.Resource
+	key := unknownValuesKey(normalizeModulePath(scope.Path)[1:], r.Type, r.Name, r.CountIndex)
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if u.refs == nil {
+		u.refs = make(map[string]map[string]bool)
+	}
+	refs, ok := u.refs[key]
+	if !ok {
+		refs = make(map[string]bool)
+		u.refs[key] = refs
+	}
+	for n := range vars {
+		v, ok := values[n]
+		refs[n] = refs[n] || (ok && ast.IsUnknown(v))
+	}
+}
+
+// annotate sets the UnknownSources of the instance diffs of the given diff
+// from the recorded references, for each computed attribute that is within
+// an argument set in the configuration of its resource.
+func (u *UnknownValues) annotate(mod *module.Tree, diff *Diff) {
+	if u == nil || diff == nil {
+		return
+	}
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if len(u.refs) == 0 {
+		return
+	}
+
+	for _, md := range diff.Modules {
+		child := mod.Child(md.Path[1:])
+		if child == nil {
+			continue
+		}
+		for k, d := range md.Resources {
+			addr, err := ParseResourceAddressForInstanceDiff(md.Path[1:], k)
+			if err != nil {
+				continue
+			}
+			index := addr.Index
+			if index < 0 {
+				index = 0
+			}
+			refs := u.refs[unknownValuesKey(addr.Path, addr.Type, addr.Name, index)]
+			if len(refs) == 0 {
+				continue
+			}
+			rc := unknownValuesResource(child, addr)
+			if rc == nil || rc.RawConfig == nil {
+				continue
+			}
+
+			for path, attr := range d.Attributes {
+				if !attr.NewComputed {
+					continue
+				}
+				name := path
+				if i := strings.Index(path, "."); i >= 0 {
+					name = path[:i]
+				}
+				v, ok := rc.RawConfig.Raw[name]
+				if !ok {
+					continue
+				}
+				var sources []string
+				for _, ref := range explainReferences(name, v, refs) {
+					if ref.Unknown {
+						sources = append(sources, ref.Text)
+					}
+				}
+				if len(sources) == 0 {
+					continue
+				}
+				if d.UnknownSources == nil {
+					d.UnknownSources = make(map[string][]string)
+				}
+				d.UnknownSources[path] = sources
+			}
+		}
+	}
+}
+
+// unknownValuesResource returns the configuration of the resource with the
+// given address in the given module, or nil if there is none.
+func unknownValuesResource(mod *module.Tree, addr *ResourceAddress) *config.Resource {
+	for _, r := range mod.Config().Resources {
+		if r.Mode == addr.Mode && r.Type == addr.Type && r.Name == addr.Name {
+			return r
+		}
+	}
+	return nil
+}
+
+func unknownValuesKey(path []string, typ, name string, index int) string {
+	return fmt.Sprintf("%s|%s.%s[%d]", strings.Join(path, "."), typ, name, index)
+}
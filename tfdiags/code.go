@@ -0,0 +1,131 @@
+package tfdiags
+
+import (
+	"github.com/hashicorp/errwrap"
+)
+
+// Code identifies the kind of problem that a diagnostic describes, so that
+// tools such as editors and CI systems can handle particular problems
+// without matching on their messages, which may change.
+//
+// Codes are dot-separated, starting with the broad category of the problem.
+// Once a code is added it must not change meaning.
+type Code string
+
+const (
+	// CodeUnclassified is the code of the diagnostics that haven't been
+	// given a more specific one.
+	CodeUnclassified Code = "unclassified"
+
+	// CodeConfigLoad means that the configuration couldn't be loaded,
+	// such as because of a syntax error or an unknown argument.
+	CodeConfigLoad Code = "config.load"
+
+	// CodeConfigModules means that a module of the configuration couldn't
+	// be loaded, such as because it hasn't been installed.
+	CodeConfigModules Code = "config.modules"
+
+	// CodeConfigInvalid means that the configuration was loaded but is not
+	// valid, such as because it refers to something that doesn't exist.
+	CodeConfigInvalid Code = "config.invalid"
+
+	// CodeConfigImportUndeclared means that an import block imports to a
+	// resource that isn't declared in the configuration.
+	CodeConfigImportUndeclared Code = "config.import_undeclared"
+
+	// CodeVariableInvalid means that the value given for an input variable
+	// is missing or has the wrong type.
+	CodeVariableInvalid Code = "variable.invalid"
+
+	// CodeVariableValidation means that the value of an input variable
+	// failed one of its validation blocks.
+	CodeVariableValidation Code = "variable.validation_failed"
+
+	// CodePrecondition and CodePostcondition mean that a precondition or
+	// postcondition of a resource or an output failed.
+	CodePrecondition  Code = "condition.precondition_failed"
+	CodePostcondition Code = "condition.postcondition_failed"
+
+	// CodePlanTargeted and CodePlanDeferred are the warnings for plans
+	// that are incomplete because of resource targeting or because some
+	// changes were deferred.
+	CodePlanTargeted Code = "plan.targeted"
+	CodePlanDeferred Code = "plan.deferred"
+
+	// CodePlanModuleScoped is the warning for plans that are scoped to
+	// modules, and for the dependents outside of the scope that they skip.
+	CodePlanModuleScoped Code = "plan.module_scoped"
+
+	// CodePlanDestroyRefs is the warning for destroy-time provisioners that
+	// refer to values which destroying removes before they run.
+	CodePlanDestroyRefs Code = "plan.destroy_refs_unavailable"
+
+	// CodePlanAnalysis is the warning for a plan analyzer that failed, or
+	// whose annotations were ignored.
+	CodePlanAnalysis Code = "plan.analysis"
+
+	// CodeCheckFailed is the warning for an assertion of a check block
+	// that failed.
+	CodeCheckFailed Code = "check.failed"
+)
+
+// Coder is implemented by errors and diagnostics that know their code.
+type Coder interface {
+	DiagnosticCode() Code
+}
+
+// GetCode returns the code of the given diagnostic, or CodeUnclassified if
+// it doesn't have one.
+func GetCode(diag Diagnostic) Code {
+	if code := codeOf(diag); code != "" {
+		return code
+	}
+	return CodeUnclassified
+}
+
+func codeOf(diag Diagnostic) Code {
+	switch d := diag.(type) {
+	case Coder:
+		return d.DiagnosticCode()
+	case nativeError:
+		// Errors can carry a code through APIs that don't know about
+		// diagnostics, and may have been wrapped along the way.
+		var code Code
+		errwrap.Walk(d.err, func(err error) {
+			if c, ok := err.(Coder); ok && code == "" {
+				code = c.DiagnosticCode()
+			}
+		})
+		return code
+	default:
+		return ""
+	}
+}
+
+// codedDiagnostic is a Diagnostic with a code.
+type codedDiagnostic struct {
+	Diagnostic
+	code Code
+}
+
+func (d codedDiagnostic) DiagnosticCode() Code {
+	return d.code
+}
+
+// WithCode returns a copy of the receiving diagnostics in which those
+// that don't already have a code have the given code.
+func (diags Diagnostics) WithCode(code Code) Diagnostics {
+	if len(diags) == 0 {
+		return nil
+	}
+
+	ret := make(Diagnostics, len(diags))
+	for i, diag := range diags {
+		if codeOf(diag) != "" {
+			ret[i] = diag
+			continue
+		}
+		ret[i] = codedDiagnostic{Diagnostic: diag, code: code}
+	}
+	return ret
+}
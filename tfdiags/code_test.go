@@ -0,0 +1,58 @@
+package tfdiags
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/errwrap"
+)
+
+type testCodedError struct{}
+
+func (testCodedError) Error() string {
+	return "coded"
+}
+
+func (testCodedError) DiagnosticCode() Code {
+	return CodeVariableValidation
+}
+
+func TestGetCode(t *testing.T) {
+	tests := map[string]struct {
+		Diags Diagnostics
+		Want  Code
+	}{
+		"plain error": {
+			Diagnostics(nil).Append(fmt.Errorf("bad")),
+			CodeUnclassified,
+		},
+		"coded error": {
+			Diagnostics(nil).Append(testCodedError{}),
+			CodeVariableValidation,
+		},
+		"wrapped coded error": {
+			Diagnostics(nil).Append(errwrap.Wrapf("outer: {{err}}", testCodedError{})),
+			CodeVariableValidation,
+		},
+		"with code": {
+			Diagnostics(nil).Append(fmt.Errorf("bad")).WithCode(CodeConfigInvalid),
+			CodeConfigInvalid,
+		},
+		"with code keeps existing code": {
+			Diagnostics(nil).Append(testCodedError{}).WithCode(CodeConfigInvalid),
+			CodeVariableValidation,
+		},
+		"for RPC": {
+			Diagnostics(nil).Append(testCodedError{}).ForRPC(),
+			CodeVariableValidation,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := GetCode(test.Diags[0]); got != test.Want {
+				t.Fatalf("wrong code %q; want %q", got, test.Want)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ type rpcFriendlyDiag struct {
 	Detail_   string
 	Subject_  *SourceRange
 	Context_  *SourceRange
+	Code_     Code
 }
 
 // rpcFriendlyDiag transforms a given diagnostic so that is more friendly to
@@ -27,6 +28,7 @@ func makeRPCFriendlyDiag(diag Diagnostic) Diagnostic {
 		Detail_:   desc.Detail,
 		Subject_:  source.Subject,
 		Context_:  source.Context,
+		Code_:     codeOf(diag),
 	}
 }
 
@@ -48,6 +50,10 @@ func (d *rpcFriendlyDiag) Source() Source {
 	}
 }
 
+func (d *rpcFriendlyDiag) DiagnosticCode() Code {
+	return d.Code_
+}
+
 func init() {
 	gob.Register((*rpcFriendlyDiag)(nil))
 }
@@ -0,0 +1,12 @@
+// Package tracing records how long the steps of a Terraform run take, as
+// spans of a trace that are exported to an OpenTelemetry collector with
+// the OTLP protocol.
+//
+// A run is traced when the CLI configuration has a tracing block. The root
+// span is the command, with a span for each graph walk, a span for each
+// plan, apply or refresh of a resource instance, and a span for each
+// provider call within them.
+//
+// A nil *Tracer and a nil *Span are valid and record nothing, so that code
+// doesn't need to check whether tracing is enabled.
+package tracing
@@ -0,0 +1,205 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/terraform/version"
+)
+
+// otlpTracesPath is the path that traces are sent to when the endpoint of
+// an OTLP exporter has no path.
+const otlpTracesPath = "/v1/traces"
+
+type otlpExporter struct {
+	endpoint    string
+	headers     map[string]string
+	serviceName string
+	client      *http.Client
+}
+
+// OTLP returns an Exporter that sends spans to the OpenTelemetry collector
+// at the given endpoint, such as "http://localhost:4318", with the JSON
+// encoding of the OTLP/HTTP protocol. The given headers are added to each
+// request, such as for authentication, and the spans are attributed to a
+// service of the given name, or "terraform" if it is empty.
+func OTLP(endpoint string, headers map[string]string, serviceName string) (Exporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: %s", endpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: must be an http or https URL", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = otlpTracesPath
+	}
+	if serviceName == "" {
+		serviceName = "terraform"
+	}
+
+	return &otlpExporter{
+		endpoint:    u.String(),
+		headers:     headers,
+		serviceName: serviceName,
+		client:      cleanhttp.DefaultClient(),
+	}, nil
+}
+
+func (e *otlpExporter) Export(spans []*Span) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s returned %s: %s", e.endpoint, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// The OTLP/HTTP request types, in the JSON encoding of the protocol.
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              Kind           `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// The status codes of spans.
+const (
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+func (e *otlpExporter) request(spans []*Span) *otlpRequest {
+	scope := otlpScopeSpans{
+		Scope: otlpScope{
+			Name:    "github.com/hashicorp/terraform",
+			Version: version.String(),
+		},
+		Spans: make([]otlpSpan, len(spans)),
+	}
+	for i, s := range spans {
+		span := otlpSpan{
+			TraceID:           s.TraceID,
+			SpanID:            s.SpanID,
+			ParentSpanID:      s.ParentID,
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.Attributes),
+			Status:            otlpStatus{Code: otlpStatusOK},
+		}
+		if s.Error != "" {
+			span.Status = otlpStatus{Code: otlpStatusError, Message: s.Error}
+		}
+		scope.Spans[i] = span
+	}
+
+	return &otlpRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource: otlpResource{
+					Attributes: otlpAttributes([]Attribute{
+						String("service.name", e.serviceName),
+						String("service.version", version.String()),
+					}),
+				},
+				ScopeSpans: []otlpScopeSpans{scope},
+			},
+		},
+	}
+}
+
+func otlpAttributes(attrs []Attribute) []otlpKeyValue {
+	result := make([]otlpKeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		var v otlpAnyValue
+		switch tv := attr.Value.(type) {
+		case string:
+			v.StringValue = &tv
+		case bool:
+			v.BoolValue = &tv
+		case int:
+			s := strconv.Itoa(tv)
+			v.IntValue = &s
+		case float64:
+			v.DoubleValue = &tv
+		default:
+			s := fmt.Sprint(tv)
+			v.StringValue = &s
+		}
+		result = append(result, otlpKeyValue{Key: attr.Key, Value: v})
+	}
+	return result
+}
@@ -0,0 +1,238 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+)
+
+// batchSize is how many ended spans are buffered before they're exported,
+// so that the spans of a long run are exported as it progresses.
+const batchSize = 256
+
+// Exporter exports ended spans.
+type Exporter interface {
+	Export(spans []*Span) error
+}
+
+// Kind is the kind of a span, as in OpenTelemetry.
+type Kind int
+
+const (
+	// KindInternal is for a step within Terraform.
+	KindInternal Kind = 1
+
+	// KindClient is for a call to another process, such as a provider.
+	KindClient Kind = 3
+)
+
+// Attribute is a key and value describing a span. The value is a string,
+// bool, int or float64.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an int attribute.
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool returns a bool attribute.
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Tracer records the spans of one trace and gives them to its exporter.
+// It is safe for concurrent use.
+type Tracer struct {
+	exporter Exporter
+	traceID  string
+
+	mu      sync.Mutex
+	root    *Span
+	pending []*Span
+
+	// exports tracks the batches being exported, so that Shutdown can
+	// wait for them.
+	exports sync.WaitGroup
+}
+
+// NewTracer returns a Tracer that exports the spans of a new trace with
+// the given exporter.
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{
+		exporter: exporter,
+		traceID:  randomID(16),
+	}
+}
+
+// StartRoot starts the root span of the trace, which is the parent of
+// spans started without one.
+func (t *Tracer) StartRoot(name string, attrs ...Attribute) *Span {
+	if t == nil {
+		return nil
+	}
+
+	s := t.Start(nil, name, attrs...)
+	t.mu.Lock()
+	t.root = s
+	t.mu.Unlock()
+	return s
+}
+
+// Start starts a span with the given parent, or with the root span as its
+// parent if parent is nil.
+func (t *Tracer) Start(parent *Span, name string, attrs ...Attribute) *Span {
+	if t == nil {
+		return nil
+	}
+
+	if parent == nil {
+		t.mu.Lock()
+		parent = t.root
+		t.mu.Unlock()
+	}
+
+	s := &Span{
+		tracer:     t,
+		TraceID:    t.traceID,
+		SpanID:     randomID(8),
+		Name:       name,
+		Kind:       KindInternal,
+		Start:      time.Now(),
+		Attributes: attrs,
+	}
+	if parent != nil {
+		s.ParentID = parent.SpanID
+	}
+	return s
+}
+
+// Shutdown exports the spans that have ended and not yet been exported,
+// and waits for all exports to finish. Spans that haven't ended aren't
+// exported.
+func (t *Tracer) Shutdown() error {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	batch := t.pending
+	t.pending = nil
+	t.mu.Unlock()
+
+	t.exports.Wait()
+	if len(batch) == 0 {
+		return nil
+	}
+	return t.exporter.Export(batch)
+}
+
+func (t *Tracer) ended(s *Span) {
+	t.mu.Lock()
+	t.pending = append(t.pending, s)
+	if len(t.pending) < batchSize {
+		t.mu.Unlock()
+		return
+	}
+	batch := t.pending
+	t.pending = nil
+	t.exports.Add(1)
+	t.mu.Unlock()
+
+	go func() {
+		defer t.exports.Done()
+		if err := t.exporter.Export(batch); err != nil {
+			log.Printf("[WARN] tracing: failed to export %d spans: %s", len(batch), err)
+		}
+	}()
+}
+
+// Span is a step of a run. Its fields must not be modified once it has
+// ended.
+type Span struct {
+	tracer *Tracer
+
+	TraceID  string
+	SpanID   string
+	ParentID string
+
+	Name       string
+	Kind       Kind
+	Start      time.Time
+	End        time.Time
+	Attributes []Attribute
+
+	// Error is the error that the step ended with, if any.
+	Error string
+
+	mu    sync.Mutex
+	ended bool
+}
+
+// SetKind sets the kind of the span, which is KindInternal by default.
+func (s *Span) SetKind(kind Kind) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Kind = kind
+}
+
+// SetAttributes adds the given attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Attributes = append(s.Attributes, attrs...)
+}
+
+// Finish ends the span, with the given error if it isn't nil. Only the
+// first call has any effect.
+func (s *Span) Finish(err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.End = time.Now()
+	if err != nil {
+		s.Error = err.Error()
+	}
+	s.mu.Unlock()
+
+	s.tracer.ended(s)
+}
+
+// randomID returns a random ID of n bytes in hex, as used for the IDs of
+// traces and spans.
+func randomID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		// This is only for correlating spans, so a poor ID is better
+		// than failing the run.
+		log.Printf("[WARN] tracing: failed to generate a random ID: %s", err)
+		for i := range b {
+			b[i] = byte(time.Now().UnixNano() >> uint(i))
+		}
+	}
+	return hex.EncodeToString(b)
+}
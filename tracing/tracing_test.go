@@ -0,0 +1,169 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+	calls int
+}
+
+func (e *recordingExporter) Export(spans []*Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	e.calls++
+	return nil
+}
+
+func TestTracer(t *testing.T) {
+	e := &recordingExporter{}
+	tracer := NewTracer(e)
+
+	root := tracer.StartRoot("terraform apply")
+	walk := tracer.Start(nil, "terraform.apply")
+	call := tracer.Start(walk, "aws/Apply", String("rpc.method", "Apply"))
+	call.SetKind(KindClient)
+	call.Finish(errors.New("boom"))
+	walk.Finish(nil)
+	walk.Finish(errors.New("ignored"))
+	root.Finish(nil)
+
+	if err := tracer.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(e.spans) != 3 {
+		t.Fatalf("exported %d spans; want 3", len(e.spans))
+	}
+	if walk.ParentID != root.SpanID {
+		t.Errorf("walk span has parent %q; want root %q", walk.ParentID, root.SpanID)
+	}
+	if call.ParentID != walk.SpanID {
+		t.Errorf("call span has parent %q; want walk %q", call.ParentID, walk.SpanID)
+	}
+	if root.ParentID != "" {
+		t.Errorf("root span has parent %q", root.ParentID)
+	}
+	for _, s := range e.spans {
+		if s.TraceID != root.TraceID {
+			t.Errorf("span %q has trace %q; want %q", s.Name, s.TraceID, root.TraceID)
+		}
+	}
+	if call.Kind != KindClient || call.Error != "boom" {
+		t.Errorf("wrong call span: kind %d, error %q", call.Kind, call.Error)
+	}
+	if walk.Error != "" {
+		t.Errorf("walk span has error %q after a second Finish", walk.Error)
+	}
+}
+
+func TestTracer_batch(t *testing.T) {
+	e := &recordingExporter{}
+	tracer := NewTracer(e)
+
+	for i := 0; i < batchSize+1; i++ {
+		tracer.Start(nil, "span").Finish(nil)
+	}
+	if err := tracer.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(e.spans) != batchSize+1 {
+		t.Fatalf("exported %d spans; want %d", len(e.spans), batchSize+1)
+	}
+	if e.calls != 2 {
+		t.Fatalf("exported in %d batches; want 2", e.calls)
+	}
+}
+
+func TestTracer_nil(t *testing.T) {
+	var tracer *Tracer
+
+	span := tracer.StartRoot("terraform plan")
+	if span != nil {
+		t.Fatalf("nil tracer started span %#v", span)
+	}
+	tracer.Start(span, "terraform.plan").Finish(nil)
+	span.SetKind(KindClient)
+	span.SetAttributes(Int("terraform.exit_code", 0))
+	span.Finish(nil)
+	if err := tracer.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOTLP(t *testing.T) {
+	var got otlpRequest
+	var gotHeader, gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotHeader = r.Header.Get("X-Api-Key")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	e, err := OTLP(server.URL, map[string]string{"X-Api-Key": "secret"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := NewTracer(e)
+	root := tracer.StartRoot("terraform apply", Int("terraform.exit_code", 1))
+	tracer.Start(root, "aws/Apply").Finish(errors.New("boom"))
+	root.Finish(nil)
+	if err := tracer.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+
+	if gotPath != otlpTracesPath {
+		t.Errorf("wrong path %q; want %q", gotPath, otlpTracesPath)
+	}
+	if gotHeader != "secret" {
+		t.Errorf("wrong header %q", gotHeader)
+	}
+	if len(got.ResourceSpans) != 1 || len(got.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("wrong request %#v", got)
+	}
+	service := got.ResourceSpans[0].Resource.Attributes[0]
+	if service.Key != "service.name" || *service.Value.StringValue != "terraform" {
+		t.Errorf("wrong service attribute %#v", service)
+	}
+
+	spans := got.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("sent %d spans; want 2", len(spans))
+	}
+	call, rootSpan := spans[0], spans[1]
+	if call.TraceID != root.TraceID || len(call.TraceID) != 32 || len(call.SpanID) != 16 {
+		t.Errorf("wrong ids: trace %q, span %q", call.TraceID, call.SpanID)
+	}
+	if call.ParentSpanID != rootSpan.SpanID {
+		t.Errorf("call span has parent %q; want %q", call.ParentSpanID, rootSpan.SpanID)
+	}
+	if call.Status.Code != otlpStatusError || call.Status.Message != "boom" {
+		t.Errorf("wrong call status %#v", call.Status)
+	}
+	if rootSpan.Status.Code != otlpStatusOK {
+		t.Errorf("wrong root status %#v", rootSpan.Status)
+	}
+	if v := rootSpan.Attributes[0].Value.IntValue; v == nil || *v != "1" {
+		t.Errorf("wrong exit code attribute %#v", rootSpan.Attributes[0])
+	}
+}
+
+func TestOTLP_invalidEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"localhost:4318", "grpc://localhost:4317", "%zz"} {
+		if _, err := OTLP(endpoint, nil, ""); err == nil {
+			t.Errorf("%q: succeeded; want error", endpoint)
+		}
+	}
+}